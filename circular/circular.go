@@ -109,13 +109,11 @@ func (this *bufferStruct) Retrieve(buf []float64) error {
  */
 func CreateBuffer(size int) Buffer {
 	values := make([]float64, size)
-	m := sync.RWMutex{}
 
 	/*
 	 * Create circular buffer.
 	 */
 	buf := bufferStruct{
-		mutex:   m,
 		values:  values,
 		pointer: 0,
 	}
@@ -164,3 +164,56 @@ func TestBuffer(t *testing.T) {
 	}
 
 }
+
+/*
+ * Check that enqueueing and retrieving elements does not allocate memory.
+ */
+func TestBufferAllocations(t *testing.T) {
+
+	/*
+	 * Sizes of the buffers.
+	 */
+	bufSizes := []int{
+		1,
+		64,
+		1000,
+	}
+
+	/*
+	 * Number of elements enqueued at once.
+	 */
+	numElems := []int{
+		1,
+		48,
+		1500,
+	}
+
+	/*
+	 * Fill each buffer.
+	 */
+	for i, bufSize := range bufSizes {
+		n := numElems[i]
+		buffer := CreateBuffer(bufSize)
+		elems := make([]float64, n)
+		result := make([]float64, bufSize)
+
+		/*
+		 * Enqueue elements and retrieve the buffer contents once.
+		 */
+		process := func() {
+			buffer.Enqueue(elems...)
+			buffer.Retrieve(result)
+		}
+
+		allocs := testing.AllocsPerRun(100, process)
+
+		/*
+		 * Check if buffer operations allocated memory.
+		 */
+		if allocs != 0 {
+			t.Errorf("Enqueueing %d elements into buffer of size %d allocated memory. Expected %d allocations, got %f.", n, bufSize, 0, allocs)
+		}
+
+	}
+
+}
@@ -0,0 +1,331 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+/*
+ * Creates a response of the v2 API.
+ */
+func (this *controllerStruct) createApiResponse(status int, success bool, reason string, result json.RawMessage) webserver.HttpResponse {
+
+	/*
+	 * The structured API response.
+	 */
+	apiResponse := apiResponseStruct{
+		Success: success,
+		Reason:  reason,
+		Result:  result,
+	}
+
+	mimeType, buffer := this.createJSON(apiResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Status: status,
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Parses the JSON object in the body of a v2 API request into parameters.
+ *
+ * Strings, numbers and booleans are converted into their textual
+ * representation, while nested objects and arrays are passed on as JSON.
+ */
+func (this *controllerStruct) parseApiBody(body []byte) (map[string]string, error) {
+	params := make(map[string]string)
+	bodyString := string(body)
+	bodyTrimmed := strings.TrimSpace(bodyString)
+
+	/*
+	 * An empty body carries no parameters.
+	 */
+	if bodyTrimmed == "" {
+		return params, nil
+	} else {
+		values := map[string]interface{}{}
+		reader := bytes.NewReader(body)
+		decoder := json.NewDecoder(reader)
+		decoder.UseNumber()
+		err := decoder.Decode(&values)
+
+		/*
+		 * Check if the body could be decoded.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Request body is not a valid JSON object: %s", msg)
+		} else {
+
+			/*
+			 * Convert each value into a parameter.
+			 */
+			for key, value := range values {
+
+				/*
+				 * Convert the value depending on its type.
+				 */
+				switch typedValue := value.(type) {
+				case nil:
+				case string:
+					params[key] = typedValue
+				case json.Number:
+					params[key] = typedValue.String()
+				case bool:
+					params[key] = strconv.FormatBool(typedValue)
+				default:
+					buf, err := json.Marshal(typedValue)
+
+					/*
+					 * Check if the value could be encoded.
+					 */
+					if err != nil {
+						return nil, fmt.Errorf("Failed to encode value of parameter '%s'.", key)
+					} else {
+						params[key] = string(buf)
+					}
+
+				}
+
+			}
+
+			return params, nil
+		}
+
+	}
+
+}
+
+/*
+ * Returns the status code for a failure of a handler, given the reason it
+ * reported.
+ *
+ * Failures which refer to channels, units or other entities which do not
+ * exist map to 404, failures to create or write files map to 500, since
+ * they are not caused by the request. All other failures are caused by
+ * invalid requests and map to 400.
+ */
+func apiFailureStatus(reason string) int {
+
+	/*
+	 * Reasons telling that something does not exist.
+	 */
+	notFound := []string{
+		"out of range",
+		"No channel",
+		"No library entry",
+		"No scene",
+		"No unit",
+		"Unknown",
+	}
+
+	/*
+	 * Reasons telling that something failed on our side.
+	 */
+	internal := []string{
+		"Failed to create",
+		"Failed to encode",
+		"Failed to write",
+	}
+
+	/*
+	 * Check if the reason tells that something does not exist.
+	 */
+	for _, marker := range notFound {
+
+		/*
+		 * Check if we found the marker.
+		 */
+		if strings.Contains(reason, marker) {
+			return http.StatusNotFound
+		}
+
+	}
+
+	/*
+	 * Check if the reason tells that something failed on our side.
+	 */
+	for _, marker := range internal {
+
+		/*
+		 * Check if we found the marker.
+		 */
+		if strings.Contains(reason, marker) {
+			return http.StatusInternalServerError
+		}
+
+	}
+
+	return http.StatusBadRequest
+}
+
+/*
+ * Converts the response of a CGI handler into a response of the v2 API.
+ */
+func (this *controllerStruct) convertApiResponse(response webserver.HttpResponse) webserver.HttpResponse {
+	header := response.Header
+	mimeType := header["Content-type"]
+	body := response.Body
+
+	/*
+	 * Handlers only fail to produce JSON if encoding the response failed.
+	 */
+	if !strings.HasPrefix(mimeType, "application/json") {
+		reason := string(body)
+		return this.createApiResponse(http.StatusInternalServerError, false, reason, nil)
+	} else {
+		probe := apiProbeStruct{}
+		err := json.Unmarshal(body, &probe)
+
+		/*
+		 * If the handler responded with a success indicator, translate it
+		 * into a status code, otherwise its response is the result.
+		 */
+		if err != nil || probe.Success == nil {
+			result := json.RawMessage(body)
+			return this.createApiResponse(http.StatusOK, true, "", result)
+		} else {
+			success := *probe.Success
+			reason := ""
+
+			/*
+			 * Check if the handler provided a reason.
+			 */
+			if probe.Reason != nil {
+				reason = *probe.Reason
+			}
+
+			/*
+			 * Translate failures into a status code according to
+			 * their reason.
+			 */
+			if success {
+				return this.createApiResponse(http.StatusOK, true, reason, nil)
+			} else {
+				statusCode := apiFailureStatus(reason)
+				return this.createApiResponse(statusCode, false, reason, nil)
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Restores a patch sent as the 'Patch' parameter of a v2 API request.
+ */
+func (this *controllerStruct) apiRestoreHandler(params map[string]string) webserver.HttpResponse {
+	patch, hasPatch := params["Patch"]
+
+	/*
+	 * Make sure that a patch was sent in request.
+	 */
+	if !hasPatch {
+		return this.createApiResponse(http.StatusBadRequest, false, "Field 'Patch' not defined in request body.", nil)
+	} else {
+		patchBytes := []byte(patch)
+		before := this.createPatch()
+		err := this.restorePatch(patchBytes)
+
+		/*
+		 * Check if patch was restored successfully.
+		 */
+		if err != nil {
+			reason := err.Error()
+			return this.createApiResponse(http.StatusBadRequest, false, reason, nil)
+		} else {
+			this.recordEdit("", before)
+			return this.createApiResponse(http.StatusOK, true, "", nil)
+		}
+
+	}
+
+}
+
+/*
+ * Dispatch v2 API requests to the corresponding CGI handlers.
+ *
+ * The endpoint is taken from the request path, while parameters are taken
+ * from the query string and the JSON object in the request body.
+ */
+func (this *controllerStruct) dispatchApiRequest(request webserver.HttpRequest) webserver.HttpResponse {
+	method := request.Method
+	path := request.Path
+	endpoint := strings.TrimPrefix(path, API_PREFIX)
+	body := request.Body
+	bodyParams, err := this.parseApiBody(body)
+
+	/*
+	 * Check if the request can be handled.
+	 */
+	if (method != http.MethodGet) && (method != http.MethodPost) {
+		return this.createApiResponse(http.StatusMethodNotAllowed, false, "Only GET and POST requests are supported.", nil)
+	} else if err != nil {
+		reason := err.Error()
+		return this.createApiResponse(http.StatusBadRequest, false, reason, nil)
+	} else {
+		params := request.Params
+
+		/*
+		 * Parameters in the request body override query parameters.
+		 */
+		for key, value := range bodyParams {
+			params[key] = value
+		}
+
+		params["cgi"] = endpoint
+
+		/*
+		 * Patches are sent as part of the request body instead of as a
+		 * file upload.
+		 */
+		if endpoint == "persistence-restore" {
+			return this.apiRestoreHandler(params)
+		} else if endpoint == "persistence-validate" {
+			return this.apiValidateHandler(params)
+		} else {
+			handler := this.handler(endpoint)
+
+			/*
+			 * Check if there is a handler for the endpoint.
+			 */
+			if handler == nil {
+				reason := fmt.Sprintf("Unknown endpoint '%s'.", endpoint)
+				return this.createApiResponse(http.StatusNotFound, false, reason, nil)
+			} else {
+				request.Params = params
+				response := this.invoke(handler, request)
+				return this.convertApiResponse(response)
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Dispatch v2 API requests and translate the reason for a failure into the
+ * language of the request.
+ *
+ * Translation happens after the status code has been derived from the
+ * reason, since the status code depends on the reason in English.
+ */
+func (this *controllerStruct) dispatchApi(request webserver.HttpRequest) webserver.HttpResponse {
+	response := this.dispatchApiRequest(request)
+	language := this.requestLanguage(request.Params)
+	return this.localizeResponse(response, language)
+}
@@ -0,0 +1,1254 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/effects"
+	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/signal"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+ * Constants for the automation of numeric parameters.
+ */
+const (
+	AUTOMATION_MAX_LANES          = 32
+	AUTOMATION_MAX_POINTS         = 256
+	AUTOMATION_MAX_TIME           = 3600000
+	AUTOMATION_SOURCE_LFO         = "lfo"
+	AUTOMATION_SOURCE_ENVELOPE    = "envelope"
+	AUTOMATION_WAVEFORM_SINE      = "sine"
+	AUTOMATION_WAVEFORM_TRIANGLE  = "triangle"
+	AUTOMATION_WAVEFORM_SQUARE    = "square"
+	AUTOMATION_WAVEFORM_SAWTOOTH  = "sawtooth"
+	AUTOMATION_DEFAULT_RATE       = 1.0
+	AUTOMATION_MIN_RATE           = 0.01
+	AUTOMATION_MAX_RATE           = 20.0
+	AUTOMATION_DEFAULT_DEPTH      = 50
+	AUTOMATION_MIN_DEPTH          = 0
+	AUTOMATION_MAX_DEPTH          = 100
+	AUTOMATION_MILLISECONDS_PER_S = 1000.0
+)
+
+/*
+ * A point of an envelope, which reaches a value (in units of the parameter)
+ * at a time (in milliseconds) after the start of the envelope.
+ */
+type automationPointStruct struct {
+	time  uint32
+	value int32
+}
+
+/*
+ * An automation lane, which modulates a numeric parameter of a unit with an
+ * LFO or an envelope.
+ *
+ * The LFO swings around the base value of the parameter. Its depth is given
+ * in percent of the range of the parameter, so that a depth of 100 sweeps
+ * the entire range. The envelope follows its points, holding the value of
+ * the last point at its end unless it loops.
+ *
+ * The type of the unit is remembered, so that a lane pauses instead of
+ * modulating another unit, should its unit be replaced.
+ */
+type automationLaneStruct struct {
+	chain    int
+	unit     int
+	unitType int
+	param    string
+	minimum  int32
+	maximum  int32
+	source   string
+	waveform string
+	rate     float64
+	depth    int32
+	base     int32
+	loop     bool
+	points   []automationPointStruct
+	phase    float64
+	position float64
+	current  int32
+	applied  bool
+}
+
+/*
+ * Data structure holding the automation lanes.
+ *
+ * The audio thread advances the lanes and applies their values at the start
+ * of each block, so all fields are protected by the mutex.
+ */
+type automationStruct struct {
+	mutex sync.Mutex
+	lanes []automationLaneStruct
+}
+
+/*
+ * A data structure encoding a point of an envelope.
+ */
+type webAutomationPointStruct struct {
+	Time  uint32
+	Value int32
+}
+
+/*
+ * A data structure encoding an automation lane.
+ */
+type webAutomationLaneStruct struct {
+	Chain    int
+	Unit     int
+	Param    string
+	Minimum  int32
+	Maximum  int32
+	Source   string
+	Waveform string
+	Rate     float64
+	Depth    int32
+	Base     int32
+	Loop     bool
+	Points   []webAutomationPointStruct
+	Value    int32
+}
+
+/*
+ * A data structure encoding the automation lanes.
+ */
+type webAutomationStruct struct {
+	Lanes []webAutomationLaneStruct
+}
+
+/*
+ * Evaluates a waveform of the LFO at a phase between zero and one. The result
+ * is between -1 and 1.
+ */
+func automationWaveform(waveform string, phase float64) float64 {
+
+	/*
+	 * Check which waveform the LFO produces.
+	 */
+	switch waveform {
+	case AUTOMATION_WAVEFORM_TRIANGLE:
+		return 1.0 - (4.0 * math.Abs(phase-0.5))
+	case AUTOMATION_WAVEFORM_SQUARE:
+
+		/*
+		 * The first half of the period is high.
+		 */
+		if phase < 0.5 {
+			return 1.0
+		} else {
+			return -1.0
+		}
+
+	case AUTOMATION_WAVEFORM_SAWTOOTH:
+		return (2.0 * phase) - 1.0
+	default:
+		arg := 2.0 * math.Pi * phase
+		return math.Sin(arg)
+	}
+
+}
+
+/*
+ * Checks whether the LFO knows a waveform.
+ */
+func isAutomationWaveform(waveform string) bool {
+	known := (waveform == AUTOMATION_WAVEFORM_SINE) || (waveform == AUTOMATION_WAVEFORM_TRIANGLE) || (waveform == AUTOMATION_WAVEFORM_SQUARE) || (waveform == AUTOMATION_WAVEFORM_SAWTOOTH)
+	return known
+}
+
+/*
+ * Parses the points of an envelope, given as a comma-separated list of pairs
+ * of a time (in milliseconds) and a value, e. g. "0:20,500:80,1000:20".
+ *
+ * The times must increase from point to point.
+ */
+func parseAutomationPoints(pointsString string) ([]automationPointStruct, error) {
+	pointsTrimmed := strings.TrimSpace(pointsString)
+	points := []automationPointStruct{}
+
+	/*
+	 * An empty list contains no points.
+	 */
+	if pointsTrimmed == "" {
+		return points, nil
+	} else {
+		pairs := strings.Split(pointsTrimmed, ",")
+		numPairs := len(pairs)
+
+		/*
+		 * Check if there are too many points.
+		 */
+		if numPairs > AUTOMATION_MAX_POINTS {
+			return nil, fmt.Errorf("Envelope must not have more than %d points.", AUTOMATION_MAX_POINTS)
+		} else {
+
+			/*
+			 * Parse each point.
+			 */
+			for i, pair := range pairs {
+				fields := strings.Split(pair, ":")
+
+				/*
+				 * Each point consists of a time and a value.
+				 */
+				if len(fields) != 2 {
+					return nil, fmt.Errorf("Point %d of envelope must be a pair of time and value, e. g. '500:80'.", i)
+				} else {
+					timeString := strings.TrimSpace(fields[0])
+					valueString := strings.TrimSpace(fields[1])
+					time64, errTime := strconv.ParseUint(timeString, 10, 32)
+					value64, errValue := strconv.ParseInt(valueString, 10, 32)
+
+					/*
+					 * Check if time and value are valid.
+					 */
+					if errTime != nil {
+						return nil, fmt.Errorf("Failed to decode time of point %d of envelope.", i)
+					} else if errValue != nil {
+						return nil, fmt.Errorf("Failed to decode value of point %d of envelope.", i)
+					} else if time64 > AUTOMATION_MAX_TIME {
+						return nil, fmt.Errorf("Time of point %d of envelope must not exceed %d ms.", i, AUTOMATION_MAX_TIME)
+					} else if (i > 0) && (uint32(time64) <= points[i-1].time) {
+						return nil, fmt.Errorf("Time of point %d of envelope must be later than the time of the previous point.", i)
+					} else {
+
+						/*
+						 * Create point of the envelope.
+						 */
+						point := automationPointStruct{
+							time:  uint32(time64),
+							value: int32(value64),
+						}
+
+						points = append(points, point)
+					}
+
+				}
+
+			}
+
+			return points, nil
+		}
+
+	}
+
+}
+
+/*
+ * Limits a value to the range of the parameter.
+ */
+func (this *automationLaneStruct) limit(value int32) int32 {
+
+	/*
+	 * Keep the value within the range of the parameter.
+	 */
+	if value < this.minimum {
+		value = this.minimum
+	} else if value > this.maximum {
+		value = this.maximum
+	}
+
+	return value
+}
+
+/*
+ * Evaluates the envelope at its current position.
+ */
+func (this *automationLaneStruct) envelope() float64 {
+	points := this.points
+	numPoints := len(points)
+
+	/*
+	 * Without points, the parameter keeps its base value.
+	 */
+	if numPoints == 0 {
+		baseFloat := float64(this.base)
+		return baseFloat
+	} else {
+		position := this.position
+		result := float64(points[numPoints-1].value)
+		found := false
+
+		/*
+		 * Find the segment of the envelope containing the position.
+		 */
+		for i := 0; (i < numPoints) && !found; i++ {
+			point := points[i]
+			pointTime := float64(point.time)
+
+			/*
+			 * Check if the position is before this point.
+			 */
+			if position < pointTime {
+				found = true
+				result = float64(point.value)
+
+				/*
+				 * Interpolate between this point and the previous
+				 * one, unless this is the first point.
+				 */
+				if i > 0 {
+					previous := points[i-1]
+					previousTime := float64(previous.time)
+					previousValue := float64(previous.value)
+					fraction := (position - previousTime) / (pointTime - previousTime)
+					result = interpolate(previousValue, result, fraction)
+				}
+
+			}
+
+		}
+
+		return result
+	}
+
+}
+
+/*
+ * Returns the value the lane currently sets its parameter to.
+ */
+func (this *automationLaneStruct) value() int32 {
+	valueFloat := float64(this.base)
+
+	/*
+	 * Check which source modulates the parameter.
+	 */
+	if this.source == AUTOMATION_SOURCE_ENVELOPE {
+		valueFloat = this.envelope()
+	} else {
+		depth := 0.01 * float64(this.depth)
+		span := float64(this.maximum - this.minimum)
+		wave := automationWaveform(this.waveform, this.phase)
+		valueFloat += 0.5 * depth * span * wave
+	}
+
+	valueRounded := math.Round(valueFloat)
+	value := int32(valueRounded)
+	value = this.limit(value)
+	return value
+}
+
+/*
+ * Advances the LFO and the envelope of a lane by a number of seconds.
+ */
+func (this *automationLaneStruct) advance(seconds float64) {
+	phase := this.phase + (this.rate * seconds)
+	this.phase = phase - math.Floor(phase)
+	position := this.position + (AUTOMATION_MILLISECONDS_PER_S * seconds)
+	points := this.points
+	numPoints := len(points)
+
+	/*
+	 * A looping envelope starts over after its last point.
+	 */
+	if this.loop && (numPoints > 0) {
+		length := float64(points[numPoints-1].time)
+
+		/*
+		 * An envelope without length stays at its start.
+		 */
+		if length > 0.0 {
+			position = math.Mod(position, length)
+		} else {
+			position = 0.0
+		}
+
+	}
+
+	this.position = position
+}
+
+/*
+ * Restarts the LFO and the envelope of a lane.
+ */
+func (this *automationLaneStruct) restart() {
+	this.phase = 0.0
+	this.position = 0.0
+	this.applied = false
+}
+
+/*
+ * Returns the chain with a certain ID, where the aux buses follow the
+ * channels, or nil if there is no such chain.
+ *
+ * Unlike chains, this does not allocate, so it may be called from the audio
+ * thread.
+ */
+func (this *controllerStruct) automationChain(chainId int) signal.Chain {
+	fx := this.effects
+	buses := this.buses
+	numChannels := len(fx)
+	numBuses := len(buses)
+
+	/*
+	 * Check whether the ID refers to a channel or an aux bus.
+	 */
+	if (chainId >= 0) && (chainId < numChannels) {
+		return fx[chainId]
+	} else if (chainId >= numChannels) && (chainId < numChannels+numBuses) {
+		busId := chainId - numChannels
+		return buses[busId]
+	} else {
+		return nil
+	}
+
+}
+
+/*
+ * Creates a lane for a numeric parameter of a unit, which modulates the
+ * parameter around its current value.
+ */
+func (this *controllerStruct) createAutomationLane(chainId int, unitId int, param string) (automationLaneStruct, error) {
+	chain := this.automationChain(chainId)
+	return createChainAutomationLane(chain, chainId, unitId, param)
+}
+
+/*
+ * Creates a lane for a numeric parameter of a unit inside a certain chain,
+ * which may be nil if the chain ID is out of range.
+ */
+func createChainAutomationLane(chain signal.Chain, chainId int, unitId int, param string) (automationLaneStruct, error) {
+
+	/*
+	 * Check if the chain exists.
+	 */
+	if chain == nil {
+		return automationLaneStruct{}, fmt.Errorf("Chain ID %d out of range.", chainId)
+	} else {
+		unitType, err := chain.UnitType(unitId)
+
+		/*
+		 * Check if the unit exists.
+		 */
+		if err != nil {
+			return automationLaneStruct{}, err
+		} else {
+			params, _ := chain.Parameters(unitId)
+			found := false
+			lane := automationLaneStruct{}
+
+			/*
+			 * Search for the parameter.
+			 */
+			for _, current := range params {
+
+				/*
+				 * Check if this is the numeric parameter we look for.
+				 */
+				if (current.Name == param) && (current.Type == effects.PARAMETER_TYPE_NUMERIC) {
+					found = true
+
+					/*
+					 * Create automation lane.
+					 */
+					lane = automationLaneStruct{
+						chain:    chainId,
+						unit:     unitId,
+						unitType: unitType,
+						param:    param,
+						minimum:  current.Minimum,
+						maximum:  current.Maximum,
+						source:   AUTOMATION_SOURCE_LFO,
+						waveform: AUTOMATION_WAVEFORM_SINE,
+						rate:     AUTOMATION_DEFAULT_RATE,
+						depth:    AUTOMATION_DEFAULT_DEPTH,
+						base:     current.NumericValue,
+					}
+
+				}
+
+			}
+
+			/*
+			 * Check if the parameter was found.
+			 */
+			if !found {
+				return automationLaneStruct{}, fmt.Errorf("Unit %d has no numeric parameter '%s'.", unitId, param)
+			} else {
+				return lane, nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Advances all automation lanes by one block and sets their parameters.
+ *
+ * This is called from the audio thread at the start of each block.
+ * Parameters are only set when their value changes.
+ */
+func (this *controllerStruct) processAutomation(frames int, sampleRate uint32) {
+	automation := &this.automation
+	automation.mutex.Lock()
+	lanes := automation.lanes
+
+	/*
+	 * Only process lanes if there are any and time passes.
+	 */
+	if (len(lanes) > 0) && (sampleRate > 0) {
+		framesFloat := float64(frames)
+		sampleRateFloat := float64(sampleRate)
+		seconds := framesFloat / sampleRateFloat
+
+		/*
+		 * Process each lane.
+		 */
+		for i := range lanes {
+			lane := &lanes[i]
+			chain := this.automationChain(lane.chain)
+
+			/*
+			 * Check if the chain of the lane exists.
+			 */
+			if chain != nil {
+				unitType, err := chain.UnitType(lane.unit)
+
+				/*
+				 * Only set the parameter if the unit is still there.
+				 */
+				if (err == nil) && (unitType == lane.unitType) {
+					value := lane.value()
+
+					/*
+					 * Check if the value changed.
+					 */
+					if !lane.applied || (value != lane.current) {
+						chain.SetNumericValue(lane.unit, lane.param, value)
+						lane.current = value
+						lane.applied = true
+					}
+
+				}
+
+			}
+
+			lane.advance(seconds)
+		}
+
+	}
+
+	automation.mutex.Unlock()
+}
+
+/*
+ * Moves the automation lanes along with the units they modulate, after
+ * channels or units were added, removed or moved. The mapping returns the new
+ * chain and unit ID for a unit and whether the unit still exists.
+ */
+func (this *controllerStruct) remapAutomation(mapping func(chainId int, unitId int) (int, int, bool)) {
+	automation := &this.automation
+	automation.mutex.Lock()
+	lanes := []automationLaneStruct{}
+
+	/*
+	 * Remap each lane.
+	 */
+	for _, lane := range automation.lanes {
+		chainId, unitId, exists := mapping(lane.chain, lane.unit)
+
+		/*
+		 * Lanes of units which are gone are removed.
+		 */
+		if exists {
+			lane.chain = chainId
+			lane.unit = unitId
+			lanes = append(lanes, lane)
+		}
+
+	}
+
+	automation.lanes = lanes
+	automation.mutex.Unlock()
+}
+
+/*
+ * Moves the automation lanes after a unit moved up or down within a chain,
+ * swapping places with its neighbour.
+ */
+func (this *controllerStruct) swapAutomation(chainId int, unitA int, unitB int) {
+
+	/*
+	 * Lanes of both units trade places.
+	 */
+	this.remapAutomation(func(laneChain int, laneUnit int) (int, int, bool) {
+
+		/*
+		 * Check if the lane belongs to one of the units.
+		 */
+		if (laneChain == chainId) && (laneUnit == unitA) {
+			return laneChain, unitB, true
+		} else if (laneChain == chainId) && (laneUnit == unitB) {
+			return laneChain, unitA, true
+		} else {
+			return laneChain, laneUnit, true
+		}
+
+	})
+
+}
+
+/*
+ * Returns the automation lanes for storage in a patch.
+ */
+func (this *controllerStruct) persistAutomation() []persistence.AutomationLane {
+	automation := &this.automation
+	automation.mutex.Lock()
+	lanes := automation.lanes
+	numLanes := len(lanes)
+	persistedLanes := make([]persistence.AutomationLane, numLanes)
+
+	/*
+	 * Store each lane.
+	 */
+	for i, lane := range lanes {
+		numPoints := len(lane.points)
+		points := make([]persistence.AutomationPoint, numPoints)
+
+		/*
+		 * Store each point of the envelope.
+		 */
+		for j, point := range lane.points {
+
+			/*
+			 * Create point of the envelope.
+			 */
+			points[j] = persistence.AutomationPoint{
+				Time:  point.time,
+				Value: point.value,
+			}
+
+		}
+
+		/*
+		 * Create automation lane.
+		 */
+		persistedLanes[i] = persistence.AutomationLane{
+			Chain:    lane.chain,
+			Unit:     lane.unit,
+			Param:    lane.param,
+			Source:   lane.source,
+			Waveform: lane.waveform,
+			Rate:     lane.rate,
+			Depth:    lane.depth,
+			Base:     lane.base,
+			Loop:     lane.loop,
+			Points:   points,
+		}
+
+	}
+
+	automation.mutex.Unlock()
+	return persistedLanes
+}
+
+/*
+ * Replaces the automation lanes by those stored in a patch.
+ *
+ * Lanes referring to parameters which do not exist are dropped and settings
+ * outside their limits are corrected.
+ */
+func (this *controllerStruct) restoreAutomation(persistedLanes []persistence.AutomationLane) {
+	lanes := []automationLaneStruct{}
+
+	/*
+	 * Restore each lane.
+	 */
+	for _, persistedLane := range persistedLanes {
+		lane, err := this.createAutomationLane(persistedLane.Chain, persistedLane.Unit, persistedLane.Param)
+
+		/*
+		 * Only restore lanes for parameters which exist.
+		 */
+		if (err == nil) && (len(lanes) < AUTOMATION_MAX_LANES) {
+
+			/*
+			 * Patches may only contain known sources.
+			 */
+			if persistedLane.Source == AUTOMATION_SOURCE_ENVELOPE {
+				lane.source = AUTOMATION_SOURCE_ENVELOPE
+			}
+
+			/*
+			 * Patches may only contain known waveforms.
+			 */
+			if isAutomationWaveform(persistedLane.Waveform) {
+				lane.waveform = persistedLane.Waveform
+			}
+
+			rate := persistedLane.Rate
+
+			/*
+			 * Keep the rate within limits.
+			 */
+			if !(rate >= AUTOMATION_MIN_RATE) {
+				rate = AUTOMATION_MIN_RATE
+			} else if rate > AUTOMATION_MAX_RATE {
+				rate = AUTOMATION_MAX_RATE
+			}
+
+			depth := persistedLane.Depth
+
+			/*
+			 * Keep the depth within limits.
+			 */
+			if depth < AUTOMATION_MIN_DEPTH {
+				depth = AUTOMATION_MIN_DEPTH
+			} else if depth > AUTOMATION_MAX_DEPTH {
+				depth = AUTOMATION_MAX_DEPTH
+			}
+
+			lane.rate = rate
+			lane.depth = depth
+			lane.base = lane.limit(persistedLane.Base)
+			lane.loop = persistedLane.Loop
+			previousTime := int64(-1)
+
+			/*
+			 * Restore the points of the envelope, as long as their
+			 * times increase.
+			 */
+			for _, persistedPoint := range persistedLane.Points {
+				pointTime := int64(persistedPoint.Time)
+				numPoints := len(lane.points)
+
+				/*
+				 * Check if the point follows the previous one.
+				 */
+				if (pointTime > previousTime) && (pointTime <= AUTOMATION_MAX_TIME) && (numPoints < AUTOMATION_MAX_POINTS) {
+
+					/*
+					 * Create point of the envelope.
+					 */
+					point := automationPointStruct{
+						time:  persistedPoint.Time,
+						value: lane.limit(persistedPoint.Value),
+					}
+
+					lane.points = append(lane.points, point)
+					previousTime = pointTime
+				}
+
+			}
+
+			lanes = append(lanes, lane)
+		}
+
+	}
+
+	automation := &this.automation
+	automation.mutex.Lock()
+	automation.lanes = lanes
+	automation.mutex.Unlock()
+}
+
+/*
+ * Returns the automation lanes along with the value each of them currently
+ * sets its parameter to.
+ */
+func (this *controllerStruct) getAutomationHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	automation := &this.automation
+	automation.mutex.Lock()
+	lanes := automation.lanes
+	numLanes := len(lanes)
+	webLanes := make([]webAutomationLaneStruct, numLanes)
+
+	/*
+	 * Encode each lane.
+	 */
+	for i, lane := range lanes {
+		numPoints := len(lane.points)
+		webPoints := make([]webAutomationPointStruct, numPoints)
+
+		/*
+		 * Encode each point of the envelope.
+		 */
+		for j, point := range lane.points {
+
+			/*
+			 * Create point of the envelope.
+			 */
+			webPoints[j] = webAutomationPointStruct{
+				Time:  point.time,
+				Value: point.value,
+			}
+
+		}
+
+		/*
+		 * Create automation lane.
+		 */
+		webLanes[i] = webAutomationLaneStruct{
+			Chain:    lane.chain,
+			Unit:     lane.unit,
+			Param:    lane.param,
+			Minimum:  lane.minimum,
+			Maximum:  lane.maximum,
+			Source:   lane.source,
+			Waveform: lane.waveform,
+			Rate:     lane.rate,
+			Depth:    lane.depth,
+			Base:     lane.base,
+			Loop:     lane.loop,
+			Points:   webPoints,
+			Value:    lane.value(),
+		}
+
+	}
+
+	automation.mutex.Unlock()
+
+	/*
+	 * Create automation information.
+	 */
+	webAutomation := webAutomationStruct{
+		Lanes: webLanes,
+	}
+
+	mimeType, buffer := this.createJSON(webAutomation)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Adds an automation lane, which modulates a numeric parameter of a unit.
+ */
+func (this *controllerStruct) addAutomationLaneHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := request.Params["unit"]
+	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	param := request.Params["param"]
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain ID and unit ID are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errUnitId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode unit ID.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		unitId := int(unitId64)
+		lane, err := this.createAutomationLane(chainId, unitId, param)
+
+		/*
+		 * Check if the parameter may be automated.
+		 */
+		if err != nil {
+			reason := err.Error()
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		} else {
+			automation := &this.automation
+			automation.mutex.Lock()
+			numLanes := len(automation.lanes)
+
+			/*
+			 * Check if there is room for another lane.
+			 */
+			if numLanes >= AUTOMATION_MAX_LANES {
+				automation.mutex.Unlock()
+				reason := fmt.Sprintf("Cannot add more than %d automation lanes.", AUTOMATION_MAX_LANES)
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+				automation.lanes = append(automation.lanes, lane)
+				automation.mutex.Unlock()
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Removes an automation lane and returns its parameter to its base value.
+ */
+func (this *controllerStruct) removeAutomationLaneHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	laneIdString := request.Params["lane"]
+	laneId64, errLaneId := strconv.ParseUint(laneIdString, 10, 32)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if lane ID is valid.
+	 */
+	if errLaneId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode lane ID.",
+		}
+
+	} else {
+		laneId := int(laneId64)
+		automation := &this.automation
+		automation.mutex.Lock()
+		lanes := automation.lanes
+		numLanes := len(lanes)
+
+		/*
+		 * Check if lane ID is out of range.
+		 */
+		if laneId >= numLanes {
+			automation.mutex.Unlock()
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Automation lane ID out of range.",
+			}
+
+		} else {
+			lane := lanes[laneId]
+			laneIdNext := laneId + 1
+			lanesNew := append([]automationLaneStruct{}, lanes[:laneId]...)
+			lanesNew = append(lanesNew, lanes[laneIdNext:]...)
+			automation.lanes = lanesNew
+			automation.mutex.Unlock()
+			chain := this.automationChain(lane.chain)
+
+			/*
+			 * Return the parameter to its base value if its unit
+			 * is still there.
+			 */
+			if chain != nil {
+				unitType, err := chain.UnitType(lane.unit)
+
+				/*
+				 * Check if the unit was not replaced.
+				 */
+				if (err == nil) && (unitType == lane.unitType) {
+					chain.SetNumericValue(lane.unit, lane.param, lane.base)
+				}
+
+			}
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Restarts the LFOs and envelopes of all automation lanes, e. g. at the start
+ * of a song.
+ */
+func (this *controllerStruct) restartAutomationHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	automation := &this.automation
+	automation.mutex.Lock()
+
+	/*
+	 * Restart each lane.
+	 */
+	for i := range automation.lanes {
+		automation.lanes[i].restart()
+	}
+
+	automation.mutex.Unlock()
+
+	/*
+	 * Indicate success.
+	 */
+	webResponse := webResponseStruct{
+		Success: true,
+		Reason:  "",
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Changes a setting of an automation lane.
+ *
+ * The parameter 'source' selects the LFO ('lfo') or the envelope
+ * ('envelope'), 'waveform', 'rate' (in Hz) and 'depth' (in percent of the
+ * range of the parameter) configure the LFO, 'base' sets the value the LFO
+ * swings around, 'loop' tells whether the envelope starts over after its last
+ * point and 'points' replaces the points of the envelope.
+ */
+func (this *controllerStruct) setAutomationValueHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	laneIdString := request.Params["lane"]
+	laneId64, errLaneId := strconv.ParseUint(laneIdString, 10, 32)
+	param := request.Params["param"]
+	valueString := request.Params["value"]
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if lane ID is valid.
+	 */
+	if errLaneId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode lane ID.",
+		}
+
+	} else {
+		laneId := int(laneId64)
+		automation := &this.automation
+		automation.mutex.Lock()
+		numLanes := len(automation.lanes)
+		err := error(nil)
+
+		/*
+		 * Check if lane ID is out of range.
+		 */
+		if laneId >= numLanes {
+			err = fmt.Errorf("%s", "Automation lane ID out of range.")
+		} else {
+			lane := &automation.lanes[laneId]
+
+			/*
+			 * Find out which setting should be changed.
+			 */
+			switch param {
+			case "source":
+
+				/*
+				 * Check if the source is known.
+				 */
+				if (valueString != AUTOMATION_SOURCE_LFO) && (valueString != AUTOMATION_SOURCE_ENVELOPE) {
+					err = fmt.Errorf("Automation source must be '%s' or '%s'.", AUTOMATION_SOURCE_LFO, AUTOMATION_SOURCE_ENVELOPE)
+				} else {
+					lane.source = valueString
+					lane.restart()
+				}
+
+			case "waveform":
+
+				/*
+				 * Check if the waveform is known.
+				 */
+				if !isAutomationWaveform(valueString) {
+					err = fmt.Errorf("Waveform must be '%s', '%s', '%s' or '%s'.", AUTOMATION_WAVEFORM_SINE, AUTOMATION_WAVEFORM_TRIANGLE, AUTOMATION_WAVEFORM_SQUARE, AUTOMATION_WAVEFORM_SAWTOOTH)
+				} else {
+					lane.waveform = valueString
+				}
+
+			case "rate":
+				rate, errRate := strconv.ParseFloat(valueString, 64)
+
+				/*
+				 * Check if the rate is valid.
+				 */
+				if errRate != nil {
+					err = fmt.Errorf("%s", "Failed to decode rate.")
+				} else if !(rate >= AUTOMATION_MIN_RATE) || (rate > AUTOMATION_MAX_RATE) {
+					err = fmt.Errorf("Rate must be between %.2f and %.2f Hz.", AUTOMATION_MIN_RATE, AUTOMATION_MAX_RATE)
+				} else {
+					lane.rate = rate
+				}
+
+			case "depth":
+				depth64, errDepth := strconv.ParseInt(valueString, 10, 32)
+
+				/*
+				 * Check if the depth is valid.
+				 */
+				if errDepth != nil {
+					err = fmt.Errorf("%s", "Failed to decode depth.")
+				} else if (depth64 < AUTOMATION_MIN_DEPTH) || (depth64 > AUTOMATION_MAX_DEPTH) {
+					err = fmt.Errorf("Depth must be between %d and %d percent.", AUTOMATION_MIN_DEPTH, AUTOMATION_MAX_DEPTH)
+				} else {
+					lane.depth = int32(depth64)
+				}
+
+			case "base":
+				baseValue64, errBase := strconv.ParseInt(valueString, 10, 32)
+
+				/*
+				 * Check if the base value is valid.
+				 */
+				if errBase != nil {
+					err = fmt.Errorf("%s", "Failed to decode base value.")
+				} else if (baseValue64 < int64(lane.minimum)) || (baseValue64 > int64(lane.maximum)) {
+					err = fmt.Errorf("Base value must be between %d and %d.", lane.minimum, lane.maximum)
+				} else {
+					lane.base = int32(baseValue64)
+				}
+
+			case "loop":
+				loop, errLoop := strconv.ParseBool(valueString)
+
+				/*
+				 * Check if the loop flag is valid.
+				 */
+				if errLoop != nil {
+					err = fmt.Errorf("%s", "Failed to decode loop flag.")
+				} else {
+					lane.loop = loop
+				}
+
+			case "points":
+				points, errPoints := parseAutomationPoints(valueString)
+
+				/*
+				 * Check if the points are valid.
+				 */
+				if errPoints != nil {
+					err = errPoints
+				} else {
+
+					/*
+					 * Keep the values within the range of the
+					 * parameter.
+					 */
+					for i, point := range points {
+						points[i].value = lane.limit(point.value)
+					}
+
+					lane.points = points
+				}
+
+			default:
+				err = fmt.Errorf("Cannot set automation value '%s'.", param)
+			}
+
+		}
+
+		automation.mutex.Unlock()
+
+		/*
+		 * Check if the setting was changed.
+		 */
+		if err != nil {
+			reason := err.Error()
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		} else {
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
@@ -0,0 +1,780 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"math"
+	"strconv"
+	"sync"
+)
+
+/*
+ * Constants for the input calibration and the re-amping output.
+ */
+const (
+	CALIBRATION_DEFAULT_TIME      = 3000
+	CALIBRATION_MIN_TIME          = 500
+	CALIBRATION_MAX_TIME          = 30000
+	CALIBRATION_DEFAULT_REFERENCE = -12
+	CALIBRATION_MIN_REFERENCE     = -60
+	CALIBRATION_MAX_REFERENCE     = 0
+	CALIBRATION_DETECTOR_PEAK     = "peak"
+	CALIBRATION_DETECTOR_RMS      = "rms"
+	CALIBRATION_MIN_LEVEL         = -200.0
+	CHANNEL_TRIM_MIN              = -24
+	CHANNEL_TRIM_MAX              = 24
+	REAMP_LEVEL_MIN               = -60
+	REAMP_LEVEL_MAX               = 0
+)
+
+/*
+ * Data structure holding the state of the input calibration, which measures
+ * the peak and RMS level of the signal entering a channel, before its input
+ * trim is applied.
+ *
+ * It is written by the audio thread while a measurement runs, so all fields
+ * are protected by the mutex.
+ */
+type calibrationStruct struct {
+	mutex      sync.Mutex
+	started    bool
+	channel    int
+	reference  int32
+	detector   string
+	duration   uint32
+	samples    uint64
+	measured   uint64
+	peak       float64
+	sumSquares float64
+	count      uint64
+}
+
+/*
+ * A data structure encoding the state and the results of the input
+ * calibration.
+ */
+type webInputCalibrationStruct struct {
+	Channel       int
+	Running       bool
+	Progress      float64
+	Duration      uint32
+	Detector      string
+	Reference     int32
+	Signal        bool
+	Peak          float64
+	RMS           float64
+	ChannelTrim   int32
+	SuggestedTrim int32
+}
+
+/*
+ * Converts a gain (in decibels) into a linear factor.
+ */
+func decibelsToFactor(gain int32) float64 {
+	gainFloat := float64(gain)
+	exponent := gainFloat / 20.0
+	factor := math.Pow(10.0, exponent)
+	return factor
+}
+
+/*
+ * Converts a linear level into decibels relative to full scale, rounded to a
+ * tenth of a decibel.
+ */
+func levelToDecibels(level float64) float64 {
+	decibels := 20.0 * math.Log10(level)
+	decibelsNaN := math.IsNaN(decibels)
+
+	/*
+	 * Make sure the level can be encoded.
+	 */
+	if decibelsNaN || decibels < CALIBRATION_MIN_LEVEL {
+		decibels = CALIBRATION_MIN_LEVEL
+	}
+
+	decibelsTenths := 10.0 * decibels
+	decibelsRounded := math.Round(decibelsTenths)
+	result := 0.1 * decibelsRounded
+	return result
+}
+
+/*
+ * Multiplies all samples in a buffer by a factor, unless it is unity.
+ */
+func applyGain(buffer []float64, factor float64) {
+
+	/*
+	 * Only touch the buffer if the gain changes the signal.
+	 */
+	if factor != 1.0 {
+
+		/*
+		 * Scale each sample.
+		 */
+		for i, sample := range buffer {
+			buffer[i] = factor * sample
+		}
+
+	}
+
+}
+
+/*
+ * Measures the level of the channel under calibration.
+ *
+ * This is called from the audio thread before the input trim is applied.
+ */
+func (this *controllerStruct) processCalibration(inputBuffers [][]float64) {
+	calibration := &this.calibration
+	calibration.mutex.Lock()
+	channel := calibration.channel
+	nChannels := len(this.channelPorts)
+	nIn := len(inputBuffers)
+
+	/*
+	 * Check if a measurement is running for a channel which exists.
+	 */
+	if calibration.started && (calibration.measured < calibration.samples) && (channel >= 0) && (channel < nChannels) {
+		port, portRight := this.channelPortRange(channel)
+
+		/*
+		 * Only measure channels which have all their ports available.
+		 */
+		if portRight < nIn {
+			remaining := calibration.samples - calibration.measured
+			numFrames := uint64(len(inputBuffers[port]))
+
+			/*
+			 * Do not measure beyond the end of the measurement.
+			 */
+			if numFrames > remaining {
+				numFrames = remaining
+			}
+
+			/*
+			 * Measure each port of the channel.
+			 */
+			for p := port; p <= portRight; p++ {
+				inputBuffer := inputBuffers[p][0:numFrames]
+
+				/*
+				 * Accumulate each sample.
+				 */
+				for _, sample := range inputBuffer {
+					sampleAbs := math.Abs(sample)
+					calibration.peak = math.Max(calibration.peak, sampleAbs)
+					calibration.sumSquares += sample * sample
+				}
+
+				calibration.count += numFrames
+			}
+
+			calibration.measured += numFrames
+		}
+
+	}
+
+	calibration.mutex.Unlock()
+}
+
+/*
+ * Applies the input trim of a channel, which is passed along with the task,
+ * to the input of its signal chain.
+ *
+ * This is called from the worker processing the channel.
+ */
+func (this *controllerStruct) trimInput(task processingTask) {
+	channelTrim := task.channelTrim
+
+	/*
+	 * Check if the input should be trimmed.
+	 */
+	if channelTrim != 0 {
+		factor := decibelsToFactor(channelTrim)
+		applyGain(task.inputBuffer, factor)
+
+		/*
+		 * Stereo chains also trim the right input.
+		 */
+		if task.chain.Stereo() {
+			applyGain(task.inputBufferRight, factor)
+		}
+
+	}
+
+}
+
+/*
+ * Attenuates the output of each channel according to its re-amping level.
+ *
+ * This happens after the master output is mixed, so that it only affects the
+ * signal sent to the outputs of the channels, e. g. back to a real amp.
+ */
+func (this *controllerStruct) attenuateChannels(mix *mixSnapshotStruct, outputBuffers [][]float64, nIn int) {
+	metadata := mix.channels
+	numMetadata := len(metadata)
+
+	/*
+	 * Iterate over the channels.
+	 */
+	for i := range this.channelPorts {
+		reampLevel := int32(0)
+		port, portRight := this.channelPortRange(i)
+
+		/*
+		 * Check if the channel has settings.
+		 */
+		if i < numMetadata {
+			reampLevel = metadata[i].reampLevel
+		}
+
+		/*
+		 * Only attenuate ports which are available.
+		 */
+		if (reampLevel != 0) && (portRight < nIn) {
+			factor := decibelsToFactor(reampLevel)
+
+			/*
+			 * Attenuate each port of the channel.
+			 */
+			for p := port; p <= portRight; p++ {
+				outputBuffer := outputBuffers[p]
+				applyGain(outputBuffer, factor)
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Returns the input trim (in decibels) which brings the level measured by the
+ * input calibration to its reference level, along with whether any signal was
+ * measured at all.
+ *
+ * The caller must hold the mutex of the calibration.
+ */
+func (this *calibrationStruct) suggestedTrim() (int32, bool) {
+	level := this.peak
+
+	/*
+	 * Check if the RMS level should be used.
+	 */
+	if (this.detector == CALIBRATION_DETECTOR_RMS) && (this.count > 0) {
+		countFloat := float64(this.count)
+		meanSquare := this.sumSquares / countFloat
+		level = math.Sqrt(meanSquare)
+	}
+
+	/*
+	 * Without a signal, there is nothing to calibrate to.
+	 */
+	if !(level > 0.0) {
+		return 0, false
+	} else {
+		levelDecibels := 20.0 * math.Log10(level)
+		referenceFloat := float64(this.reference)
+		trimFloat := math.Round(referenceFloat - levelDecibels)
+		trimFloat = math.Max(trimFloat, CHANNEL_TRIM_MIN)
+		trimFloat = math.Min(trimFloat, CHANNEL_TRIM_MAX)
+		trim := int32(trimFloat)
+		return trim, true
+	}
+
+}
+
+/*
+ * Starts measuring the level of the signal entering a channel.
+ *
+ * Optionally, the duration of the measurement (in milliseconds), the
+ * reference level (in dBFS) and the detector ('peak' or 'rms') the input trim
+ * is suggested for can be selected.
+ */
+func (this *controllerStruct) startInputCalibrationHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	params := request.Params
+	channelIdString := params["channel"]
+	channelId64, errChannelId := strconv.ParseUint(channelIdString, 10, 32)
+	durationString := params["time"]
+	referenceString := params["reference"]
+	detector := params["detector"]
+	duration := uint32(CALIBRATION_DEFAULT_TIME)
+	reference := int32(CALIBRATION_DEFAULT_REFERENCE)
+	nChannels := uint64(len(this.effects))
+	err := error(nil)
+
+	/*
+	 * Check if channel ID is valid.
+	 */
+	if errChannelId != nil {
+		err = fmt.Errorf("%s", "Failed to decode channel ID.")
+	} else if channelId64 >= nChannels {
+		err = fmt.Errorf("%s", "Channel ID out of range.")
+	}
+
+	/*
+	 * The duration of the measurement is optional.
+	 */
+	if durationString != "" {
+		duration64, errDuration := strconv.ParseUint(durationString, 10, 32)
+
+		/*
+		 * Check if duration is valid.
+		 */
+		if errDuration != nil {
+			err = fmt.Errorf("%s", "Failed to decode duration of calibration.")
+		} else if (duration64 < CALIBRATION_MIN_TIME) || (duration64 > CALIBRATION_MAX_TIME) {
+			err = fmt.Errorf("Duration of calibration must be between %d and %d ms.", CALIBRATION_MIN_TIME, CALIBRATION_MAX_TIME)
+		} else {
+			duration = uint32(duration64)
+		}
+
+	}
+
+	/*
+	 * The reference level is optional.
+	 */
+	if referenceString != "" {
+		reference64, errReference := strconv.ParseInt(referenceString, 10, 32)
+
+		/*
+		 * Check if reference level is valid.
+		 */
+		if errReference != nil {
+			err = fmt.Errorf("%s", "Failed to decode reference level.")
+		} else if (reference64 < CALIBRATION_MIN_REFERENCE) || (reference64 > CALIBRATION_MAX_REFERENCE) {
+			err = fmt.Errorf("Reference level must be between %d and %d dBFS.", CALIBRATION_MIN_REFERENCE, CALIBRATION_MAX_REFERENCE)
+		} else {
+			reference = int32(reference64)
+		}
+
+	}
+
+	/*
+	 * The detector is optional.
+	 */
+	if detector == "" {
+		detector = CALIBRATION_DETECTOR_PEAK
+	} else if (detector != CALIBRATION_DETECTOR_PEAK) && (detector != CALIBRATION_DETECTOR_RMS) {
+		err = fmt.Errorf("Detector must be '%s' or '%s'.", CALIBRATION_DETECTOR_PEAK, CALIBRATION_DETECTOR_RMS)
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if the calibration can be started.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+		channelId := int(channelId64)
+		sampleRate := uint64(this.sampleRate)
+		duration64 := uint64(duration)
+		samples := (duration64 * sampleRate) / 1000
+		calibration := &this.calibration
+		calibration.mutex.Lock()
+		calibration.started = true
+		calibration.channel = channelId
+		calibration.reference = reference
+		calibration.detector = detector
+		calibration.duration = duration
+		calibration.samples = samples
+		calibration.measured = 0
+		calibration.peak = 0.0
+		calibration.sumSquares = 0.0
+		calibration.count = 0
+		calibration.mutex.Unlock()
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Returns the state and the results of the input calibration.
+ */
+func (this *controllerStruct) getInputCalibrationHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	calibration := &this.calibration
+	calibration.mutex.Lock()
+	started := calibration.started
+	channelId := calibration.channel
+	samples := calibration.samples
+	measured := calibration.measured
+	peak := calibration.peak
+	sumSquares := calibration.sumSquares
+	count := calibration.count
+	suggestedTrim, signal := calibration.suggestedTrim()
+
+	/*
+	 * Create calibration result structure.
+	 */
+	result := webInputCalibrationStruct{
+		Channel:       channelId,
+		Running:       measured < samples,
+		Duration:      calibration.duration,
+		Detector:      calibration.detector,
+		Reference:     calibration.reference,
+		Signal:        signal,
+		SuggestedTrim: suggestedTrim,
+	}
+
+	calibration.mutex.Unlock()
+	mimeType := ""
+	buffer := []byte{}
+
+	/*
+	 * Check if a calibration was started.
+	 */
+	if !started {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse := webResponseStruct{
+			Success: false,
+			Reason:  "Input calibration was not started.",
+		}
+
+		mimeType, buffer = this.createJSON(webResponse)
+	} else {
+		measuredFloat := float64(measured)
+		samplesFloat := float64(samples)
+		result.Progress = 1.0
+
+		/*
+		 * Report how much of the measurement is done.
+		 */
+		if samples > 0 {
+			result.Progress = measuredFloat / samplesFloat
+		}
+
+		result.Peak = levelToDecibels(peak)
+		result.RMS = CALIBRATION_MIN_LEVEL
+
+		/*
+		 * Calculate the RMS level of the samples measured so far.
+		 */
+		if count > 0 {
+			countFloat := float64(count)
+			meanSquare := sumSquares / countFloat
+			rms := math.Sqrt(meanSquare)
+			result.RMS = levelToDecibels(rms)
+		}
+
+		mix := this.mixSnapshot()
+		metadata := mix.channels
+
+		/*
+		 * Report the current input trim of the channel.
+		 */
+		if channelId < len(metadata) {
+			result.ChannelTrim = metadata[channelId].channelTrim
+		}
+
+		mimeType, buffer = this.createJSON(result)
+	}
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets the input trim of the channel under calibration to the one suggested
+ * by the finished measurement.
+ */
+func (this *controllerStruct) applyInputCalibrationHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	calibration := &this.calibration
+	calibration.mutex.Lock()
+	started := calibration.started
+	channelId := calibration.channel
+	running := calibration.measured < calibration.samples
+	suggestedTrim, signal := calibration.suggestedTrim()
+	calibration.mutex.Unlock()
+	nChannels := len(this.effects)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if the measurement finished and found a signal.
+	 */
+	if !started {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Input calibration was not started.",
+		}
+
+	} else if running {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Input calibration is still running.",
+		}
+
+	} else if !signal {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Input calibration did not measure any signal.",
+		}
+
+	} else if channelId >= nChannels {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Channel ID out of range.",
+		}
+
+	} else {
+
+		/*
+		 * Apply the suggested input trim.
+		 */
+		this.changeMix(func(mix *mixSnapshotStruct) {
+			mix.channels[channelId].channelTrim = suggestedTrim
+		})
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets the trim (in decibels) applied to the input of a channel.
+ */
+func (this *controllerStruct) setChannelTrimHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	channelIdString := request.Params["channel"]
+	channelId64, errChannelId := strconv.ParseUint(channelIdString, 10, 32)
+	valueString := request.Params["value"]
+	value64, errValue := strconv.ParseInt(valueString, 10, 32)
+	nChannels := uint64(len(this.effects))
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if channel ID and value are valid.
+	 */
+	if errChannelId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode channel ID.",
+		}
+
+	} else if channelId64 >= nChannels {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Channel ID out of range.",
+		}
+
+	} else if errValue != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode channel trim.",
+		}
+
+	} else if (value64 < CHANNEL_TRIM_MIN) || (value64 > CHANNEL_TRIM_MAX) {
+		reason := fmt.Sprintf("Channel trim must be between %d and %d dB.", CHANNEL_TRIM_MIN, CHANNEL_TRIM_MAX)
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+		channelId := int(channelId64)
+
+		/*
+		 * Set the input trim of the channel.
+		 */
+		this.changeMix(func(mix *mixSnapshotStruct) {
+			mix.channels[channelId].channelTrim = int32(value64)
+		})
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets the level (in decibels) at which the output of a channel is sent to
+ * its ports, e. g. to attenuate it for re-amping.
+ */
+func (this *controllerStruct) setReampLevelHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	channelIdString := request.Params["channel"]
+	channelId64, errChannelId := strconv.ParseUint(channelIdString, 10, 32)
+	valueString := request.Params["value"]
+	value64, errValue := strconv.ParseInt(valueString, 10, 32)
+	nChannels := uint64(len(this.effects))
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if channel ID and value are valid.
+	 */
+	if errChannelId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode channel ID.",
+		}
+
+	} else if channelId64 >= nChannels {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Channel ID out of range.",
+		}
+
+	} else if errValue != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode re-amping level.",
+		}
+
+	} else if (value64 < REAMP_LEVEL_MIN) || (value64 > REAMP_LEVEL_MAX) {
+		reason := fmt.Sprintf("Re-amping level must be between %d and %d dB.", REAMP_LEVEL_MIN, REAMP_LEVEL_MAX)
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+		channelId := int(channelId64)
+
+		/*
+		 * Set the re-amping level of the channel.
+		 */
+		this.changeMix(func(mix *mixSnapshotStruct) {
+			mix.channels[channelId].reampLevel = int32(value64)
+		})
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
@@ -0,0 +1,592 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/filter"
+	"github.com/andrepxx/go-dsp-guitar/hwio"
+	"github.com/andrepxx/go-dsp-guitar/locale"
+	"github.com/andrepxx/go-dsp-guitar/resample"
+	"github.com/andrepxx/go-dsp-guitar/spatializer"
+	"github.com/andrepxx/go-dsp-guitar/tuner"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+ * The problems found in the configuration.
+ *
+ * Errors prevent the software from starting or from being reachable, while
+ * warnings only disable a feature or cause a setting to be ignored.
+ */
+type diagnosticsStruct struct {
+	errors   []string
+	warnings []string
+}
+
+/*
+ * Records an error.
+ */
+func (this *diagnosticsStruct) fail(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	this.errors = append(this.errors, msg)
+}
+
+/*
+ * Records a warning.
+ */
+func (this *diagnosticsStruct) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	this.warnings = append(this.warnings, msg)
+}
+
+/*
+ * Prints all problems found.
+ */
+func (this *diagnosticsStruct) print() {
+
+	/*
+	 * Print each error.
+	 */
+	for _, msg := range this.errors {
+		fmt.Printf("ERROR: %s\n", msg)
+	}
+
+	/*
+	 * Print each warning.
+	 */
+	for _, msg := range this.warnings {
+		fmt.Printf("WARNING: %s\n", msg)
+	}
+
+}
+
+/*
+ * Converts an offset into a file into a line and column, both starting at
+ * one.
+ */
+func filePosition(content []byte, offset int64) (int, int) {
+	numBytes := int64(len(content))
+
+	/*
+	 * Make sure the offset is within the file.
+	 */
+	if offset > numBytes {
+		offset = numBytes
+	}
+
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	column := len(before) - lineStart + 1
+	return line, column
+}
+
+/*
+ * Reads and decodes the config file, recording why it could not be read or
+ * decoded. Fields which are not known are reported as warnings, since they
+ * are most likely misspelled and therefore ignored.
+ */
+func (this *diagnosticsStruct) checkConfigFile() (configStruct, bool) {
+	config := configStruct{}
+	content, err := os.ReadFile(CONFIG_PATH)
+
+	/*
+	 * Check if file could be read.
+	 */
+	if err != nil {
+		msg := err.Error()
+		this.fail("Failed to read config file '%s': %s - Run the software from the directory containing the 'config' directory.", CONFIG_PATH, msg)
+		return config, false
+	} else {
+		err = json.Unmarshal(content, &config)
+		syntaxErr := &json.SyntaxError{}
+		typeErr := &json.UnmarshalTypeError{}
+
+		/*
+		 * Check if file could be decoded and point to the problem.
+		 */
+		if errors.As(err, &syntaxErr) {
+			line, column := filePosition(content, syntaxErr.Offset)
+			msg := syntaxErr.Error()
+			this.fail("Config file '%s' is not valid JSON (line %d, column %d): %s", CONFIG_PATH, line, column, msg)
+			return config, false
+		} else if errors.As(err, &typeErr) {
+			line, column := filePosition(content, typeErr.Offset)
+			this.fail("Field '%s' in config file '%s' (line %d, column %d) must be of type %s, not %s.", typeErr.Field, CONFIG_PATH, line, column, typeErr.Type, typeErr.Value)
+			return config, false
+		} else if err != nil {
+			msg := err.Error()
+			this.fail("Failed to decode config file '%s': %s", CONFIG_PATH, msg)
+			return config, false
+		} else {
+			reader := bytes.NewReader(content)
+			decoder := json.NewDecoder(reader)
+			decoder.DisallowUnknownFields()
+			strict := configStruct{}
+			err = decoder.Decode(&strict)
+
+			/*
+			 * Report fields which are ignored.
+			 */
+			if err != nil {
+				msg := err.Error()
+				this.warn("Config file '%s' contains a field which is ignored (%s). Check its spelling.", CONFIG_PATH, msg)
+			}
+
+			return config, true
+		}
+
+	}
+
+}
+
+/*
+ * Checks whether a port number is valid.
+ */
+func (this *diagnosticsStruct) checkPort(section string, field string, port string) bool {
+	value, err := strconv.ParseUint(port, 10, 16)
+
+	/*
+	 * Check if the port is a number between 1 and 65535.
+	 */
+	if (err != nil) || (value == 0) {
+		this.fail("'%s' in section '%s' is not a valid port: '%s' - Use a number between 1 and 65535.", field, section, port)
+		return false
+	} else {
+		return true
+	}
+
+}
+
+/*
+ * Checks the configuration of the web server, including its key pair.
+ */
+func (this *diagnosticsStruct) checkWebServer(cfg webserver.Config) {
+	this.checkPort("WebServer", "Port", cfg.Port)
+	tlsDisabled := cfg.TLSDisabled
+	acmeCfg := cfg.Acme
+
+	/*
+	 * Check the TLS settings unless TLS is disabled.
+	 */
+	if !tlsDisabled {
+		validPort := this.checkPort("WebServer", "TLSPort", cfg.TLSPort)
+
+		/*
+		 * Both servers cannot listen on the same port.
+		 */
+		if validPort && (cfg.Port == cfg.TLSPort) {
+			this.fail("'Port' and 'TLSPort' in section 'WebServer' are both set to '%s'. - Use different ports.", cfg.Port)
+		}
+
+		publicKey := cfg.TLSPublicKey
+		privateKey := cfg.TLSPrivateKey
+		certificate, err := tls.LoadX509KeyPair(publicKey, privateKey)
+
+		/*
+		 * Check if the key pair could be loaded. Without ACME, the
+		 * server cannot serve any client without it.
+		 */
+		if err != nil {
+			msg := err.Error()
+
+			/*
+			 * Check if certificates may be obtained via ACME.
+			 */
+			if acmeCfg.Enabled {
+				this.warn("Failed to load key pair '%s' and '%s': %s - Clients not addressing one of the domains listed for ACME cannot connect. Run 'make keys' to create a self-signed key pair.", publicKey, privateKey, msg)
+			} else {
+				this.fail("Failed to load key pair '%s' and '%s': %s - Run 'make keys' to create a self-signed key pair or set 'TLSDisabled'.", publicKey, privateKey, msg)
+			}
+
+		} else {
+			leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+			now := time.Now()
+
+			/*
+			 * Check if the certificate is still valid.
+			 */
+			if (err == nil) && now.After(leaf.NotAfter) {
+				expiry := leaf.NotAfter.Format("2006-01-02")
+				this.warn("Certificate '%s' expired on %s. - Run 'make keys' again to create a new key pair.", publicKey, expiry)
+			}
+
+		}
+
+		/*
+		 * Check the configuration of ACME.
+		 */
+		if acmeCfg.Enabled {
+			numDomains := len(acmeCfg.Domains)
+
+			/*
+			 * Certificates are only issued for domains listed and
+			 * only if the terms of service are accepted.
+			 */
+			if numDomains == 0 {
+				this.warn("%s", "ACME is enabled, but no 'Domains' are listed in section 'Acme', so no certificates will be obtained.")
+			} else if !acmeCfg.AcceptTOS {
+				this.warn("%s", "ACME is enabled, but 'AcceptTOS' is not set in section 'Acme', so no certificates will be obtained.")
+			}
+
+		}
+
+	}
+
+	webRoot := cfg.WebRoot
+	info, err := os.Stat(webRoot)
+
+	/*
+	 * Check if the web root is a directory.
+	 */
+	if err != nil {
+		msg := err.Error()
+		this.fail("Web root '%s' cannot be accessed: %s - Set 'WebRoot' in section 'WebServer' to the 'webroot' directory.", webRoot, msg)
+	} else if !info.IsDir() {
+		this.fail("Web root '%s' is not a directory. - Set 'WebRoot' in section 'WebServer' to the 'webroot' directory.", webRoot)
+	} else {
+		index := filepath.Join(webRoot, cfg.Index)
+		_, err = os.Stat(index)
+
+		/*
+		 * Check if the user interface can be loaded.
+		 */
+		if err != nil {
+			this.warn("Index '%s' does not exist in web root '%s', so the user interface will not load.", cfg.Index, webRoot)
+		}
+
+	}
+
+}
+
+/*
+ * Checks the impulse responses and the optional files referenced by the
+ * configuration.
+ */
+func (this *diagnosticsStruct) checkFiles(config configStruct) {
+	problems, errDescriptor := filter.Check(config.ImpulseResponses)
+
+	/*
+	 * The software cannot start without the descriptor file, while
+	 * impulse responses which cannot be loaded are skipped.
+	 */
+	if errDescriptor != nil {
+		msg := errDescriptor.Error()
+		this.fail("%s - Set 'ImpulseResponses' to the descriptor file of the impulse responses, e. g. 'ir/index.json'.", msg)
+	} else {
+
+		/*
+		 * Report each impulse response which is skipped.
+		 */
+		for _, problem := range problems {
+			msg := problem.Error()
+			this.warn("%s - The impulse response is not available.", msg)
+		}
+
+	}
+
+	/*
+	 * Check head-related transfer functions, if they are configured.
+	 */
+	if config.Hrtf != "" {
+		_, err := spatializer.ImportHrtf(config.Hrtf)
+
+		/*
+		 * Binaural rendering falls back to the spherical head model.
+		 */
+		if err != nil {
+			msg := err.Error()
+			this.warn("%s - Binaural rendering uses the spherical head model.", msg)
+		}
+
+	}
+
+	/*
+	 * Controller to load the setlist into.
+	 */
+	setlistController := controllerStruct{
+		config: config,
+	}
+
+	err := setlistController.loadSetlist()
+
+	/*
+	 * A broken setlist is replaced once a scene is stored.
+	 */
+	if err != nil {
+		msg := err.Error()
+		this.warn("%s - The setlist starts out empty.", msg)
+	}
+
+	err = setlistController.loadLibrary()
+
+	/*
+	 * A broken library is replaced once an entry is saved.
+	 */
+	if err != nil {
+		msg := err.Error()
+		this.warn("%s - The unit library starts out empty.", msg)
+	}
+
+	recordings := config.Recordings
+
+	/*
+	 * Use the default directory if none is configured.
+	 */
+	if recordings == "" {
+		recordings = DEFAULT_RECORDINGS_DIRECTORY
+	}
+
+	info, err := os.Stat(recordings)
+
+	/*
+	 * The directory is created when recording, but something else may be
+	 * in its way.
+	 */
+	if (err == nil) && !info.IsDir() {
+		this.warn("Recordings directory '%s' is not a directory, so nothing can be recorded.", recordings)
+	}
+
+}
+
+/*
+ * Checks the settings of the signal processing and the optional interfaces.
+ */
+func (this *diagnosticsStruct) checkSettings(config configStruct) {
+	internalRate := config.SampleRate
+
+	/*
+	 * Check if the internal sample rate is supported.
+	 */
+	if (internalRate != 0) && ((internalRate < INTERNAL_SAMPLE_RATE_MIN) || (internalRate > INTERNAL_SAMPLE_RATE_MAX)) {
+		this.warn("Internal sample rate of %d Hz is ignored, since it is not between %d Hz and %d Hz. - Set 'SampleRate' to 0 to process at the rate of the hardware.", internalRate, INTERNAL_SAMPLE_RATE_MIN, INTERNAL_SAMPLE_RATE_MAX)
+	}
+
+	/*
+	 * Check if the resampling quality is known.
+	 */
+	if config.Resampling != "" {
+		_, err := resample.ParseQuality(config.Resampling)
+
+		/*
+		 * An unknown quality is ignored.
+		 */
+		if err != nil {
+			msg := err.Error()
+			this.warn("Resampling quality is ignored: %s", msg)
+		}
+
+	}
+
+	language := config.Language
+
+	/*
+	 * Check if the language is supported.
+	 */
+	if (language != "") && !locale.IsLanguage(language) {
+		languages := locale.Languages()
+		list := strings.Join(languages, "', '")
+		this.warn("Language '%s' is not supported, so responses are in English. - Use one of '%s'.", language, list)
+	}
+
+	notation := config.Notation
+
+	/*
+	 * Check if the tuner knows the notation.
+	 */
+	if notation != "" {
+		notations := tuner.Notations()
+		known := false
+
+		/*
+		 * Look for the notation.
+		 */
+		for _, name := range notations {
+
+			/*
+			 * Check if we found the notation.
+			 */
+			if name == notation {
+				known = true
+			}
+
+		}
+
+		/*
+		 * An unknown notation is ignored.
+		 */
+		if !known {
+			list := strings.Join(notations, "', '")
+			this.warn("Notation '%s' is ignored, since the tuner does not know it. - Use one of '%s'.", notation, list)
+		}
+
+	}
+
+	/*
+	 * Check the color of each channel.
+	 */
+	for i, channelConfig := range config.Channels {
+		color := channelConfig.Color
+
+		/*
+		 * Colors which are not in hexadecimal notation are ignored.
+		 */
+		if (color != "") && !isColor(color) {
+			this.warn("Color '%s' of channel %d is ignored. - Use hexadecimal notation, e. g. '#ff8000'.", color, i)
+		}
+
+	}
+
+	/*
+	 * Check each connection.
+	 */
+	for i, connection := range config.Connections {
+
+		/*
+		 * A connection needs both of its ports.
+		 */
+		if (connection.From == "") || (connection.To == "") {
+			this.warn("Connection %d lacks 'From' or 'To' and is ignored.", i)
+		}
+
+	}
+
+	grpcCfg := config.Grpc
+
+	/*
+	 * Check the port of the gRPC interface, if it is enabled.
+	 */
+	if grpcCfg.Enabled {
+		this.checkPort("Grpc", "Port", grpcCfg.Port)
+	}
+
+	gpioCfg := config.Gpio
+
+	/*
+	 * Check the footswitches, if they are enabled.
+	 */
+	if gpioCfg.Enabled {
+		pins := map[uint32]bool{}
+
+		/*
+		 * Check each footswitch.
+		 */
+		for _, footswitchCfg := range gpioCfg.Footswitches {
+			pin := footswitchCfg.Pin
+			action := footswitchCfg.Action
+
+			/*
+			 * Check if the action is known and the pin is unique.
+			 */
+			if !isFootswitchAction(action) {
+				this.warn("Unknown action '%s' for GPIO pin %d, so no footswitch will work. - Use '%s', '%s', '%s' or '%s'.", action, pin, FOOTSWITCH_ACTION_NEXT_SCENE, FOOTSWITCH_ACTION_PREVIOUS_SCENE, FOOTSWITCH_ACTION_TOGGLE_BYPASS, FOOTSWITCH_ACTION_TAP_TEMPO)
+			} else if pins[pin] {
+				this.warn("GPIO pin %d is used by more than one footswitch, so no footswitch will work.", pin)
+			}
+
+			pins[pin] = true
+		}
+
+	}
+
+}
+
+/*
+ * Checks whether the audio backend is available.
+ */
+func (this *diagnosticsStruct) checkAudio(cfg hwio.Config) {
+	backend := cfg.Backend
+	err := hwio.Supported(cfg)
+
+	/*
+	 * Check if the backend is supported, then whether it could be opened,
+	 * and tell how to fix it.
+	 */
+	if err != nil {
+		msg := err.Error()
+		this.fail("%s - Select another 'Backend' in section 'Audio'.", msg)
+	} else {
+		err = hwio.Probe(cfg)
+
+		/*
+		 * Give advice depending on the backend.
+		 */
+		if err != nil {
+			msg := err.Error()
+
+			/*
+			 * Decide which advice to give.
+			 */
+			switch backend {
+			case "", hwio.BACKEND_JACK:
+				this.fail("%s - Start the JACK server (e. g. using 'jackd' or 'qjackctl') as the same user or select another 'Backend' in section 'Audio'.", msg)
+			case hwio.BACKEND_ALSA:
+				this.fail("%s - Check the devices in section 'Alsa' (e. g. using 'aplay -l' and 'arecord -l') and make sure no other software uses them.", msg)
+			default:
+				this.fail("%s - Check the default recording and playback devices in the sound settings.", msg)
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Checks the configuration and, optionally, the audio hardware.
+ */
+func checkConfiguration(probeHardware bool) diagnosticsStruct {
+	diagnostics := diagnosticsStruct{}
+	config, ok := diagnostics.checkConfigFile()
+
+	/*
+	 * The remaining checks depend on the configuration.
+	 */
+	if ok {
+		diagnostics.checkWebServer(config.WebServer)
+		diagnostics.checkFiles(config)
+		diagnostics.checkSettings(config)
+
+		/*
+		 * Only access the audio hardware if requested.
+		 */
+		if probeHardware {
+			diagnostics.checkAudio(config.Audio)
+		}
+
+	}
+
+	return diagnostics
+}
+
+/*
+ * Checks the configuration, the files it references and the availability of
+ * the audio hardware, printing all problems found. Returns whether the
+ * software is able to start.
+ */
+func Check() bool {
+	diagnostics := checkConfiguration(true)
+	diagnostics.print()
+	numErrors := len(diagnostics.errors)
+	numWarnings := len(diagnostics.warnings)
+
+	/*
+	 * Summarize the problems found.
+	 */
+	if (numErrors == 0) && (numWarnings == 0) {
+		fmt.Printf("%s\n", "Configuration OK.")
+	} else {
+		fmt.Printf("Found %d errors and %d warnings.\n", numErrors, numWarnings)
+	}
+
+	return numErrors == 0
+}
@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/hwio"
+	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"strings"
+)
+
+/*
+ * Checks whether a port belongs to this application.
+ */
+func isOwnPort(name string) bool {
+	prefix := hwio.CLIENT_NAME + ":"
+	result := strings.HasPrefix(name, prefix)
+	return result
+}
+
+/*
+ * Checks whether a list of port names contains a certain name.
+ */
+func containsPort(names []string, name string) bool {
+
+	/*
+	 * Compare each name.
+	 */
+	for _, currentName := range names {
+
+		/*
+		 * Check if we found the name.
+		 */
+		if currentName == name {
+			return true
+		}
+
+	}
+
+	return false
+}
+
+/*
+ * Checks whether two ports may be connected, returning an error if they may
+ * not.
+ */
+func (this *controllerStruct) checkPorts(source string, destination string) error {
+	sources, destinations := hwio.Ports()
+
+	/*
+	 * Check if both ports exist and at least one of them is ours.
+	 */
+	if !containsPort(sources, source) {
+		return fmt.Errorf("Unknown source port '%s'.", source)
+	} else if !containsPort(destinations, destination) {
+		return fmt.Errorf("Unknown destination port '%s'.", destination)
+	} else if !isOwnPort(source) && !isOwnPort(destination) {
+		return fmt.Errorf("Cannot connect '%s' to '%s': Neither port belongs to %s.", source, destination, hwio.CLIENT_NAME)
+	} else {
+		return nil
+	}
+
+}
+
+/*
+ * Creates a description of all connections to or from our ports for a patch
+ * file.
+ */
+func (this *controllerStruct) persistConnections() []persistence.Connection {
+	connections := hwio.Connections()
+	result := []persistence.Connection{}
+
+	/*
+	 * Only store connections which involve our ports.
+	 */
+	for _, connection := range connections {
+		source := connection.Source
+		destination := connection.Destination
+
+		/*
+		 * Check if one of the ports is ours.
+		 */
+		if isOwnPort(source) || isOwnPort(destination) {
+
+			/*
+			 * The connection as stored in the patch.
+			 */
+			connectionP := persistence.Connection{
+				From: source,
+				To:   destination,
+			}
+
+			result = append(result, connectionP)
+		}
+
+	}
+
+	return result
+}
+
+/*
+ * Restores the connections stored in a patch file, removing all other
+ * connections to or from our ports.
+ */
+func (this *controllerStruct) restoreConnections(connections []persistence.Connection) {
+	current := hwio.Connections()
+
+	/*
+	 * Remove each connection of our ports which is not part of the patch.
+	 */
+	for _, connection := range current {
+		source := connection.Source
+		destination := connection.Destination
+		keep := false
+
+		/*
+		 * Check if the patch contains the connection.
+		 */
+		for _, connectionP := range connections {
+
+			/*
+			 * Check if we found the connection.
+			 */
+			if (connectionP.From == source) && (connectionP.To == destination) {
+				keep = true
+			}
+
+		}
+
+		/*
+		 * Only remove connections to or from our ports.
+		 */
+		if !keep && (isOwnPort(source) || isOwnPort(destination)) {
+			hwio.Disconnect(source, destination)
+		}
+
+	}
+
+	/*
+	 * Establish each connection stored in the patch.
+	 */
+	for _, connectionP := range connections {
+		source := connectionP.From
+		destination := connectionP.To
+		err := this.checkPorts(source, destination)
+
+		/*
+		 * Connections to ports which are gone are only reported.
+		 */
+		if err != nil {
+			msg := err.Error()
+			fmt.Printf("Failed to restore connection: %s\n", msg)
+		} else {
+			hwio.Connect(source, destination)
+		}
+
+	}
+
+}
+
+/*
+ * Lists the ports which may be connected and the current connections.
+ */
+func (this *controllerStruct) getPortsHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	sources, destinations := hwio.Ports()
+	connections := hwio.Connections()
+	webConnections := make([]webConnectionStruct, len(connections))
+
+	/*
+	 * Describe each connection.
+	 */
+	for i, connection := range connections {
+
+		/*
+		 * The connection between both ports.
+		 */
+		webConnections[i] = webConnectionStruct{
+			From: connection.Source,
+			To:   connection.Destination,
+		}
+
+	}
+
+	/*
+	 * The ports and connections.
+	 */
+	result := webPortsStruct{
+		Sources:      sources,
+		Destinations: destinations,
+		Connections:  webConnections,
+	}
+
+	mimeType, buffer := this.createJSON(result)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Connects or disconnects two ports.
+ */
+func (this *controllerStruct) changeConnection(request webserver.HttpRequest, connect bool) webserver.HttpResponse {
+	source := request.Params["from"]
+	destination := request.Params["to"]
+	err := this.checkPorts(source, destination)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if the ports may be connected.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Check whether we should connect or disconnect the ports.
+		 */
+		if connect {
+			hwio.Connect(source, destination)
+		} else {
+			hwio.Disconnect(source, destination)
+		}
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Connects a source port to a destination port.
+ */
+func (this *controllerStruct) connectPortsHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	response := this.changeConnection(request, true)
+	return response
+}
+
+/*
+ * Disconnects a source port from a destination port.
+ */
+func (this *controllerStruct) disconnectPortsHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	response := this.changeConnection(request, false)
+	return response
+}
@@ -7,34 +7,83 @@ import (
 	"fmt"
 	"github.com/andrepxx/go-dsp-guitar/effects"
 	"github.com/andrepxx/go-dsp-guitar/filter"
+	"github.com/andrepxx/go-dsp-guitar/gpio"
 	"github.com/andrepxx/go-dsp-guitar/hwio"
 	"github.com/andrepxx/go-dsp-guitar/level"
+	"github.com/andrepxx/go-dsp-guitar/master"
 	"github.com/andrepxx/go-dsp-guitar/metronome"
-	"github.com/andrepxx/go-dsp-guitar/path"
 	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/player"
+	"github.com/andrepxx/go-dsp-guitar/recorder"
 	"github.com/andrepxx/go-dsp-guitar/resample"
 	"github.com/andrepxx/go-dsp-guitar/signal"
 	"github.com/andrepxx/go-dsp-guitar/spatializer"
+	"github.com/andrepxx/go-dsp-guitar/spectrum"
 	"github.com/andrepxx/go-dsp-guitar/tuner"
 	"github.com/andrepxx/go-dsp-guitar/wave"
 	"github.com/andrepxx/go-dsp-guitar/webserver"
 	"io"
 	"math"
+	"math/cmplx"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 /*
  * Constants for the controller.
  */
 const (
-	ARCHIVE_TIME_STAMP       = "20060102-150405"
-	CONFIG_PATH              = "config/config.json"
-	DEFAULT_SAMPLE_RATE      = 96000
-	BLOCK_SIZE               = 8192
-	MORE_OUTPUTS_THAN_INPUTS = 3
+	ARCHIVE_TIME_STAMP           = "20060102-150405"
+	CONFIG_PATH                  = "config/config.json"
+	DEFAULT_SAMPLE_RATE          = 96000
+	BLOCK_SIZE                   = 8192
+	CAPTURE_BIT_DEPTH            = 32
+	CAPTURE_DEFAULT_LENGTH       = 200
+	MORE_OUTPUTS_THAN_INPUTS     = 5
+	API_PREFIX                   = "/api/v2/"
+	METRICS_MIME_TYPE            = "text/plain; version=0.0.4; charset=utf-8"
+	METRICS_PATH                 = "/metrics"
+	UPLOAD_PATH                  = "/cgi-bin/dsp-upload"
+	UPLOAD_IR_DIRECTORY          = "upload"
+	UPLOAD_IR_DIRECTORY_MODE     = 0755
+	DEFAULT_RECORDINGS_DIRECTORY = "recordings/"
+	HISTORY_COALESCE_TIME        = time.Second
+	HISTORY_LENGTH               = 100
+	MIDI_MAX_PROGRAM             = 127
+	SCENE_NO_PROGRAM             = -1
+	SNAPSHOT_COUNT               = 2
+	SNAPSHOT_MAX_MORPH_TIME      = 10000
+	SNAPSHOT_MORPH_STEP          = 10
+	CHANNEL_NAME_MAX_LENGTH      = 64
+	UNIT_LABEL_MAX_LENGTH        = 64
+	UNIT_NOTE_MAX_LENGTH         = 1024
+	INTERNAL_SAMPLE_RATE_MIN     = 8000
+	INTERNAL_SAMPLE_RATE_MAX     = 384000
+	DEFAULT_PREROLL              = 10.0
+	FREEZE_DEFAULT_LENGTH        = 2000
+	FREEZE_MAX_LENGTH            = 60000
+	PREVIEW_MAX_LENGTH           = 30000
+	PREVIEW_MIME_TYPE            = "audio/wav"
+	RESPONSE_DEFAULT_POINTS      = 128
+	RESPONSE_MAX_POINTS          = 1024
+	RESPONSE_MIN_FREQUENCY       = 20.0
+	RESPONSE_MAX_FREQUENCY       = 20000.0
+	RESPONSE_MIN_LEVEL           = -200.0
+)
+
+/*
+ * Output format code for FLAC files, which extends the sample formats of the
+ * wave package.
+ */
+const (
+	AUDIO_FLAC = 0xf1ac // uint16
 )
 
 /*
@@ -45,15 +94,146 @@ type connectionStruct struct {
 	To   string
 }
 
+/*
+ * The configuration of a single input channel.
+ */
+type channelConfigStruct struct {
+	Stereo bool
+	Name   string
+	Color  string
+}
+
+/*
+ * The name and color a user assigned to a channel, along with the level at
+ * which the metronome click is mixed into its output, the trim applied to its
+ * input and the level its output is sent to its ports at (both in decibels).
+ */
+type channelMetadataStruct struct {
+	name        string
+	color       string
+	click       float64
+	channelTrim int32
+	reampLevel  int32
+}
+
+/*
+ * The configuration of the gRPC control interface.
+ */
+type grpcConfigStruct struct {
+	Enabled     bool
+	Port        string
+	TLSDisabled bool
+}
+
 /*
  * The configuration for the controller.
  */
 type configStruct struct {
 	ImpulseResponses string
+	Hrtf             string
+	Recordings       string
+	Setlist          string
+	Library          string
+	MidiInput        string
+	SampleRate       uint32
+	Resampling       string
+	Language         string
+	Notation         string
 	WebServer        webserver.Config
+	Grpc             grpcConfigStruct
+	Gpio             gpioConfigStruct
+	Audio            hwio.Config
+	Channels         []channelConfigStruct
 	Connections      []connectionStruct
 }
 
+/*
+ * A data structure associating an input port with the channel of the wave
+ * file it is read from.
+ *
+ * Inputs at the target sample rate are decoded block by block while
+ * processing. Inputs which have to be resampled are decoded up front.
+ */
+type inputFileStruct struct {
+	name      string
+	file      *os.File
+	reader    wave.Reader
+	channel   uint16
+	resampled bool
+	samples   []float64
+	block     [][]float64
+}
+
+/*
+ * A data structure associating output ports with the wave file they are
+ * written to, one channel for each port.
+ */
+type outputFileStruct struct {
+	name   string
+	ports  []int
+	file   *os.File
+	writer wave.Writer
+}
+
+/*
+ * A data structure describing an input of a batch job.
+ */
+type jobInputStruct struct {
+	Port    string
+	File    string
+	Channel uint16
+}
+
+/*
+ * A data structure describing an output of a batch job.
+ */
+type jobOutputStruct struct {
+	Port string
+	File string
+}
+
+/*
+ * A data structure describing a batch job.
+ *
+ * The channels of the file given as input feed the input ports in order,
+ * unless an input port is assigned a file of its own. The file given as
+ * output receives the listed output ports (or all of them), one channel for
+ * each port.
+ */
+type jobStruct struct {
+	Channels    []channelConfigStruct
+	SampleRate  uint32
+	Patch       string
+	Format      string
+	BitDepth    uint16
+	Container   string
+	Start       float64
+	End         float64
+	Preroll     float64
+	Input       string
+	Inputs      []jobInputStruct
+	Output      string
+	OutputPorts []string
+	Outputs     []jobOutputStruct
+}
+
+/*
+ * A data structure describing the capture of an impulse response from the
+ * recorded response of a system to a reference sweep.
+ *
+ * The length of the impulse response is given in milliseconds.
+ */
+type captureJobStruct struct {
+	Sweep           string
+	SweepChannel    uint16
+	Response        string
+	ResponseChannel uint16
+	Length          uint32
+	Name            string
+	Output          string
+	Descriptor      string
+}
+
 /*
  * A data structure that tells whether an operation was successful or not.
  */
@@ -62,34 +242,70 @@ type webResponseStruct struct {
 	Reason  string
 }
 
+/*
+ * Data structure representing a response of the v2 API.
+ */
+type apiResponseStruct struct {
+	Success bool
+	Reason  string
+	Result  json.RawMessage
+}
+
+/*
+ * Data structure used to find out whether a handler responded with a
+ * webResponseStruct.
+ */
+type apiProbeStruct struct {
+	Success *bool
+	Reason  *string
+}
+
 /*
  * A data structure encoding a parameter for an effects unit.
  */
 type webParameterStruct struct {
-	Name               string
-	Type               string
-	PhysicalUnit       string
-	Minimum            int32
-	Maximum            int32
-	NumericValue       int32
-	DiscreteValueIndex int
-	DiscreteValues     []string
+	Name                      string
+	Type                      string
+	PhysicalUnit              string
+	Minimum                   int32
+	Maximum                   int32
+	NumericValue              int32
+	DiscreteValueIndex        int
+	DiscreteValues            []string
+	DefaultValue              int32
+	DefaultDiscreteValueIndex int
+	Taper                     string
+	Scale                     float64
+	Precision                 int32
+	DisplayUnit               string
 }
 
 /*
  * A data structure encoding an effects unit.
  */
 type webUnitStruct struct {
-	Type       int
-	Bypass     bool
-	Parameters []webParameterStruct
+	Type        int
+	Name        string
+	Label       string
+	Note        string
+	Bypass      bool
+	InputTrim   int32
+	OutputLevel int32
+	Parameters  []webParameterStruct
 }
 
 /*
  * A data structure encoding a signal chain.
+ *
+ * Name and color are only set for the chains of channels.
  */
 type webChainStruct struct {
-	Units []webUnitStruct
+	Name        string
+	Color       string
+	ChannelTrim int32
+	ReampLevel  int32
+	Stereo      bool
+	Units       []webUnitStruct
 }
 
 /*
@@ -99,32 +315,71 @@ type webSpatializerChannelStruct struct {
 	Azimuth  float64
 	Distance float64
 	Level    float64
+	Mute     bool
+	Solo     bool
+	Sends    []float64
+}
+
+/*
+ * A data structure encoding the configuration of an aux bus. The chain of the
+ * bus is addressed by chain ID (number of channels + bus ID).
+ */
+type webBusStruct struct {
+	Chain  webChainStruct
+	Return float64
 }
 
 /*
  * A data structure encoding the spatializer configuration.
  */
 type webSpatializerStruct struct {
-	Channels []webSpatializerChannelStruct
+	Mode          string
+	SpeakerLayout string
+	Channels      []webSpatializerChannelStruct
+	Buses         []webBusStruct
 }
 
 /*
  * A data structure encoding the metronome configuration.
  */
 type webMetronomeStruct struct {
+	Accents        string
 	BeatsPerPeriod uint32
+	CountIn        uint32
 	MasterOutput   bool
+	Outputs        []float64
 	Speed          uint32
+	Subdivision    uint32
 	Sounds         []string
 	TickSound      string
+	TickPitch      float64
+	TickDecay      float64
 	TockSound      string
+	TockPitch      float64
+	TockDecay      float64
+}
+
+/*
+ * A data structure encoding the configuration of the master section.
+ */
+type webMasterStruct struct {
+	Enabled    bool
+	Parameters []master.Parameter
 }
 
 /*
  * A data structure encoding the tuner configuration.
  */
 type webTunerStruct struct {
-	Channel int
+	Channel      int
+	Mute         bool
+	Reference    float64
+	Notation     string
+	Notations    []string
+	Temperament  string
+	Temperaments []string
+	Tuning       string
+	Tunings      []string
 }
 
 /*
@@ -136,11 +391,24 @@ type webTunerResultStruct struct {
 	Note      string
 }
 
+/*
+ * A data structure encoding the results of the high-resolution analysis
+ * performed by a tuner.
+ */
+type webTunerStrobeResultStruct struct {
+	Cents     float64
+	Frequency float64
+	Note      string
+	Phase     float64
+}
+
 /*
  * A data structure encoding the current status of the level meter.
  */
 type webLevelMeterStruct struct {
-	Enabled bool
+	Enabled      bool
+	Ballistics   string
+	PeakHoldTime float64
 }
 
 /*
@@ -148,599 +416,955 @@ type webLevelMeterStruct struct {
  */
 type webLevelMeterResultStruct struct {
 	ChannelName string
+	Color       string
 	Level       int32
 	Peak        int32
+	Clips       uint64
+	RMS         float64
+	ShortTerm   float64
+	Integrated  float64
+}
+
+/*
+ * A data structure encoding the gain reduction applied by an effects unit.
+ */
+type webGainReductionStruct struct {
+	Chain     int
+	Unit      int
+	Reduction int32
+}
+
+/*
+ * A data structure identifying an effects unit whose output clipped.
+ */
+type webClipStruct struct {
+	Chain int
+	Unit  int
+}
+
+/*
+ * A data structure encoding the buffer over- and underruns reported by the
+ * hardware interface.
+ *
+ * The time of the last xrun is empty if no xrun occured since startup.
+ */
+type webXrunsStruct struct {
+	DSPLoad    int32
+	Xruns      uint32
+	Underruns  uint32
+	LastXrun   string
+	SecondsAgo float64
+}
+
+/*
+ * A data structure encoding the phase correlation between the left and right
+ * master output and the levels of their mid and side signal.
+ */
+type webCorrelationStruct struct {
+	Correlation float64
+	Mid         float64
+	Side        float64
 }
 
 /*
  * A data structure encoding the results of the analysis performed by the level meters.
  */
 type webLevelMetersResultStruct struct {
-	DSPLoad  int32
-	Channels []webLevelMeterResultStruct
+	DSPLoad       int32
+	Channels      []webLevelMeterResultStruct
+	GainReduction []webGainReductionStruct
+	Clipping      []webClipStruct
+	Xruns         webXrunsStruct
+	Correlation   webCorrelationStruct
+}
+
+/*
+ * Data structure representing a connection between two ports.
+ */
+type webConnectionStruct struct {
+	From string
+	To   string
+}
+
+/*
+ * Data structure representing the ports which may be connected and the
+ * connections between them.
+ */
+type webPortsStruct struct {
+	Sources      []string
+	Destinations []string
+	Connections  []webConnectionStruct
+}
+
+/*
+ * A data structure encoding the current status of the spectrum analyzer.
+ */
+type webSpectrumAnalyzerStruct struct {
+	Enabled bool
+}
+
+/*
+ * A data structure encoding the spectrum of a channel.
+ */
+type webSpectrumStruct struct {
+	ChannelName string
+	Magnitudes  []float64
+}
+
+/*
+ * A data structure encoding the results of the analysis performed by the
+ * spectrum analyzer.
+ */
+type webSpectrumAnalysisStruct struct {
+	SampleRate uint32
+	FFTSize    uint32
+	Window     string
+	BinWidth   float64
+	Channels   []webSpectrumStruct
+}
+
+/*
+ * A data structure encoding the frequency response of an effects unit or a
+ * signal chain.
+ */
+type webFrequencyResponseStruct struct {
+	SampleRate  uint32
+	Frequencies []float64
+	Magnitudes  []float64
+	Phases      []float64
+}
+
+/*
+ * A data structure encoding the status of the recorder.
+ */
+type webRecordingStatusStruct struct {
+	Recording bool
+	Files     []string
+	Frames    uint64
+	Dropped   uint64
+	Error     string
+}
+
+/*
+ * A data structure encoding the configuration of the backing track player.
+ */
+type webPlayerStruct struct {
+	Level        float64
+	MasterOutput bool
+}
+
+/*
+ * A data structure encoding the status of the backing track player.
+ */
+type webPlayerStatusStruct struct {
+	Loaded   bool
+	Playing  bool
+	Length   float64
+	Position float64
+	Tempo    float64
+}
+
+/*
+ * A data structure encoding the result of a tap on the tap tempo control.
+ */
+type webTapTempoStruct struct {
+	Speed   uint32
+	Updated bool
+}
+
+/*
+ * A data structure encoding the state of the A/B snapshots.
+ */
+type webSnapshotsStruct struct {
+	Active   string
+	Stored   []string
+	Morphing bool
+}
+
+/*
+ * A data structure encoding a scene of the setlist.
+ */
+type webSceneStruct struct {
+	Name    string
+	Program int32
+}
+
+/*
+ * A data structure encoding the setlist and the index of the active scene,
+ * which is -1 if no scene is active.
+ */
+type webSetlistStruct struct {
+	Active int
+	Scenes []webSceneStruct
+}
+
+/*
+ * A data structure encoding the number of edits which can be undone and
+ * redone.
+ */
+type webHistoryStruct struct {
+	Undo int
+	Redo int
+}
+
+/*
+ * A data structure encoding the latency (in samples) of a signal chain and the
+ * delay added to align it with the other channels.
+ */
+type webChainLatencyStruct struct {
+	Chain        int
+	Latency      uint32
+	Compensation uint32
+}
+
+/*
+ * A data structure encoding the description of an impulse response.
+ */
+type webImpulseResponseStruct struct {
+	Name         string
+	Category     string
+	Author       string
+	MicPosition  string
+	License      string
+	Compensation int32
+	MaxLength    uint32
+	FadeOut      uint32
+	MinimumPhase bool
+}
+
+/*
+ * A data structure encoding the available impulse responses, together with
+ * all categories they belong to.
+ */
+type webImpulseResponsesStruct struct {
+	Categories       []string
+	ImpulseResponses []webImpulseResponseStruct
+}
+
+/*
+ * A data structure encoding the latency of the signal processing. All values
+ * are given in samples at the sample rate of the signal processing, except for
+ * the sample rate of the hardware and the total latency in milliseconds.
+ */
+type webLatencyStruct struct {
+	SampleRate   uint32
+	HardwareRate uint32
+	Period       uint32
+	Processing   uint32
+	Conversion   uint32
+	Total        uint32
+	Milliseconds float64
+	Chains       []webChainLatencyStruct
+}
+
+/*
+ * A data structure encoding the time (in microseconds) it takes to process a
+ * block in an effects unit and the share (in percent) of the period this
+ * amounts to.
+ */
+type webUnitLoadStruct struct {
+	Unit         int
+	Type         string
+	Microseconds float64
+	Load         float64
+}
+
+/*
+ * A data structure encoding the time (in microseconds) it takes to process a
+ * block in a signal chain and the share (in percent) of the period this
+ * amounts to, broken down into the units of the chain.
+ */
+type webChainLoadStruct struct {
+	Chain        int
+	Microseconds float64
+	Load         float64
+	Units        []webUnitLoadStruct
+}
+
+/*
+ * A data structure encoding the DSP load reported by the hardware interface,
+ * the duration of a period (in microseconds) and the load of each chain.
+ */
+type webDspLoadStruct struct {
+	DSPLoad int32
+	Period  float64
+	Chains  []webChainLoadStruct
 }
 
 /*
  * A data structure encoding the entire DSP configuration.
  */
 type webConfigurationStruct struct {
-	FramesPerPeriod uint32
-	Chains          []webChainStruct
-	Tuner           webTunerStruct
-	Spatializer     webSpatializerStruct
-	Metronome       webMetronomeStruct
-	LevelMeter      webLevelMeterStruct
-	BatchProcessing bool
+	FramesPerPeriod    uint32
+	Chains             []webChainStruct
+	Tuner              webTunerStruct
+	Spatializer        webSpatializerStruct
+	Metronome          webMetronomeStruct
+	Player             webPlayerStruct
+	Master             webMasterStruct
+	LevelMeter         webLevelMeterStruct
+	SpectrumAnalyzer   webSpectrumAnalyzerStruct
+	ParameterSmoothing bool
+	SmoothingTime      uint32
+	SpilloverTime      uint32
+	BatchProcessing    bool
 }
 
 /*
  * A task for asynchronous signal processing.
  */
 type processingTask struct {
-	chain        signal.Chain
-	inputBuffer  []float64
-	outputBuffer []float64
-	sampleRate   uint32
+	channel           int
+	chain             signal.Chain
+	channelTrim       int32
+	inputBuffer       []float64
+	inputBufferRight  []float64
+	outputBuffer      []float64
+	outputBufferRight []float64
+	sampleRate        uint32
 }
 
 /*
  * The controller for the DSP.
+ *
+ * Requests are handled one at a time, while blocks of audio are processed
+ * concurrently. Settings read while processing are never changed in place.
+ * Signal chains and the settings of the channels (see mixSnapshotStruct) are
+ * published as snapshots, which the audio thread reads without locking, and
+ * the spatializer and the units guard their own settings. Changing the number
+ * of channels holds the layout mutex, which processing holds for each block.
  */
 type controllerStruct struct {
 	binding                 *hwio.Binding
 	config                  configStruct
+	layoutMutex             sync.RWMutex
 	effects                 []signal.Chain
+	buses                   []signal.Chain
+	channelPorts            []int
+	channelPortIds          []string
+	mixMutex                sync.Mutex
+	mix                     atomic.Value
+	defaultChannelMetadata  []channelMetadataStruct
+	inputPortNames          []string
+	outputPortNames         []string
 	impulseResponses        filter.ImpulseResponses
 	buffers                 [][]float64
+	masterBuffers           [][]float64
 	levelMeter              level.Meter
+	correlationMeter        level.CorrelationMeter
+	spectrumAnalyzer        spectrum.Analyzer
 	metr                    metronome.Metronome
-	metrMasterOutput        bool
+	trackPlayer             player.Player
+	masterSection           master.Master
 	running                 bool
 	sampleRate              uint32
+	hardwareRate            uint32
+	internalRate            uint32
+	resampleQuality         resample.Quality
+	converter               *rateConverterStruct
 	spat                    spatializer.Spatializer
 	tuner                   tuner.Tuner
-	tunerChannel            int
+	recorder                recorder.Recorder
+	snapshotMutex           sync.Mutex
+	snapshots               [SNAPSHOT_COUNT]*persistence.Configuration
+	activeSnapshot          int
+	morphStop               chan bool
+	morphDone               chan bool
+	historyMutex            sync.Mutex
+	undoHistory             []persistence.Configuration
+	redoHistory             []persistence.Configuration
+	lastEdit                string
+	lastEditTime            time.Time
+	scenes                  []persistence.Scene
+	activeScene             int
+	library                 []persistence.LibraryEntry
+	programChanges          <-chan uint8
+	footswitches            gpio.Footswitches
 	processingTaskChannel   chan processingTask
 	processingResultChannel chan bool
+	processingTimes         []uint32
+	processingTime          uint32
+	calibration             calibrationStruct
+	automation              automationStruct
+	render                  renderJobStruct
 }
 
 /*
  * The controller interface.
  */
 type Controller interface {
+	CaptureImpulseResponse(jobPath string) error
 	Operate(numChannels uint32)
+	ProcessJob(jobPath string) error
 }
 
 /*
- * Marshals an object into a JSON representation or an error.
- * Returns the appropriate MIME type and binary representation.
+ * Returns all signal chains, the chains of the channels followed by the chains
+ * of the aux buses.
  */
-func (this *controllerStruct) createJSON(obj interface{}) (string, []byte) {
-	buffer, err := json.MarshalIndent(obj, "", "\t")
+func (this *controllerStruct) chains() []signal.Chain {
+	fx := this.effects
+	buses := this.buses
+	numChannels := len(fx)
+	numBuses := len(buses)
+	numChains := numChannels + numBuses
+	chains := make([]signal.Chain, numChains)
+	copy(chains, fx)
+	copy(chains[numChannels:], buses)
+	return chains
+}
+
+/*
+ * Converts a channel name into a form which may be used as part of a port
+ * name. Letters and digits are converted to lower case, while any other
+ * characters are replaced by a single underscore.
+ */
+func portNameSuffix(name string) string {
+	lower := strings.ToLower(name)
+	runes := []rune{}
+	underscore := false
 
 	/*
-	 * Check if we got an error during marshalling.
+	 * Iterate over the characters of the name.
 	 */
-	if err != nil {
-		conf := this.config
-		confServer := conf.WebServer
-		contentType := confServer.ErrorMime
-		errString := err.Error()
-		bufString := bytes.NewBufferString(errString)
-		bufBytes := bufString.Bytes()
-		return contentType, bufBytes
-	} else {
-		return "application/json; charset=utf-8", buffer
+	for _, r := range lower {
+		isLetter := (r >= 'a') && (r <= 'z')
+		isDigit := (r >= '0') && (r <= '9')
+
+		/*
+		 * Keep letters and digits and replace everything else.
+		 */
+		if isLetter || isDigit {
+			runes = append(runes, r)
+			underscore = false
+		} else if !underscore {
+			runes = append(runes, '_')
+			underscore = true
+		}
+
 	}
 
+	suffix := string(runes)
+	suffix = strings.Trim(suffix, "_")
+	return suffix
 }
 
 /*
- * Adds a new unit to a rack.
+ * Checks whether a value is a color in hexadecimal notation (#rrggbb).
  */
-func (this *controllerStruct) addUnitHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	unitTypeString := request.Params["type"]
-	unitType64, errUnitType := strconv.ParseUint(unitTypeString, 10, 32)
-	chainIdString := request.Params["chain"]
-	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
-	webResponse := webResponseStruct{}
+func isColor(value string) bool {
+	n := len(value)
 
 	/*
-	 * Check if unit type and chain ID are valid.
+	 * Check the length and the prefix before decoding the digits.
 	 */
-	if errUnitType != nil {
-
-		/*
-		 * Indicate failure.
-		 */
-		webResponse = webResponseStruct{
-			Success: false,
-			Reason:  "Failed to decode unit type.",
-		}
+	if (n != 7) || (value[0] != '#') {
+		return false
+	} else {
+		digits := value[1:]
+		_, err := strconv.ParseUint(digits, 16, 32)
+		return err == nil
+	}
 
-	} else if errChainId != nil {
+}
 
-		/*
-		 * Indicate failure.
-		 */
-		webResponse = webResponseStruct{
-			Success: false,
-			Reason:  "Failed to decode chain ID.",
-		}
+/*
+ * Returns the names of the input and output ports of a channel, given the
+ * identifier its port names are derived from.
+ */
+func channelPortNames(id string, stereo bool) ([]string, []string) {
 
+	/*
+	 * Stereo channels get a pair of ports, mono channels a single one.
+	 */
+	if stereo {
+		inputNames := []string{"in_" + id + "_left", "in_" + id + "_right"}
+		outputNames := []string{"out_" + id + "_left", "out_" + id + "_right"}
+		return inputNames, outputNames
 	} else {
-		unitType := int(unitType64)
-		chainId := int(chainId64)
-		fx := this.effects
-		nChains := len(fx)
+		inputNames := []string{"in_" + id}
+		outputNames := []string{"out_" + id}
+		return inputNames, outputNames
+	}
+
+}
+
+/*
+ * Returns an identifier for the ports of a new channel, which no other channel
+ * uses, so that the ports of the existing channels keep their names.
+ */
+func (this *controllerStruct) newChannelPortId(name string) string {
+	ids := this.channelPortIds
+	id := uint64(0)
+	idString := "0"
+	used := true
+
+	/*
+	 * Find the lowest number which no other channel uses.
+	 */
+	for used {
+		idString = strconv.FormatUint(id, 10)
+		prefix := idString + "_"
+		used = false
 
 		/*
-		 * Check if chain ID is out of range.
+		 * Compare with the identifier of each channel.
 		 */
-		if (chainId < 0) || (chainId >= nChains) {
+		for _, current := range ids {
 
 			/*
-			 * Indicate failure.
+			 * Check if the number is taken, with or without a name.
 			 */
-			webResponse = webResponseStruct{
-				Success: false,
-				Reason:  "Chain ID out of range.",
+			if (current == idString) || strings.HasPrefix(current, prefix) {
+				used = true
 			}
 
-		} else {
-			_, err := fx[chainId].AppendUnit(unitType)
+		}
 
-			/*
-			 * Check if unit was successfully appended.
-			 */
-			if err != nil {
-				reason := err.Error()
+		id++
+	}
 
-				/*
-				 * Indicate failure.
-				 */
-				webResponse = webResponseStruct{
-					Success: false,
-					Reason:  reason,
-				}
-
-			} else {
-
-				/*
-				 * Indicate success.
-				 */
-				webResponse = webResponseStruct{
-					Success: true,
-					Reason:  "",
-				}
-
-			}
-
-		}
-
-	}
-
-	mimeType, buffer := this.createJSON(webResponse)
+	suffix := portNameSuffix(name)
 
 	/*
-	 * Create HTTP response.
+	 * Name hardware ports after the channel, just as on startup.
 	 */
-	response := webserver.HttpResponse{
-		Header: map[string]string{"Content-type": mimeType},
-		Body:   buffer,
+	if (this.binding != nil) && (suffix != "") {
+		idString += "_" + suffix
 	}
 
-	return response
+	return idString
 }
 
 /*
- * Returns the current rack configuration.
+ * Returns the number of ports occupied by a channel.
  */
-func (this *controllerStruct) getConfigurationHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	fx := this.effects
-	numChannels := len(fx)
-	framesPerPeriod := uint32(0)
-	binding := this.binding
+func (this *controllerStruct) channelPortCount(channelId int) int {
+	chain := this.effects[channelId]
 
 	/*
-	 * If we are bound to a hardware interface, query frames per period.
+	 * Stereo channels occupy two consecutive ports.
 	 */
-	if binding != nil {
-		framesPerPeriod = hwio.FramesPerPeriod()
+	if chain.Stereo() {
+		return 2
+	} else {
+		return 1
 	}
 
-	webChains := make([]webChainStruct, numChannels)
-	spatChannels := make([]webSpatializerChannelStruct, numChannels)
-	parameterTypes := effects.ParameterTypes()
+}
+
+/*
+ * Names the channels of the level meter after the channels their ports belong
+ * to. Ports of channels without a name are named after the port.
+ */
+func (this *controllerStruct) updateLevelMeterNames() {
+	levelMeter := this.levelMeter
+	inputPortNames := this.inputPortNames
+	outputPortNames := this.outputPortNames
+	numInputs := len(inputPortNames)
+	mix := this.mixSnapshot()
+	metadata := mix.channels
 
 	/*
-	 * Iterate over the channels and the associated signal chains.
+	 * Iterate over the channels.
 	 */
-	for idChannel, chain := range fx {
-		numUnits := chain.Length()
-		webUnits := make([]webUnitStruct, numUnits)
+	for i, port := range this.channelPorts {
+		name := metadata[i].name
+		numPorts := this.channelPortCount(i)
 
 		/*
-		 * Iterate over the units in each channel.
+		 * Name the input and output of each port of the channel.
 		 */
-		for idUnit := 0; idUnit < numUnits; idUnit++ {
-			unitType, _ := chain.UnitType(idUnit)
-			bypass, _ := chain.GetBypass(idUnit)
-			parameters, _ := chain.Parameters(idUnit)
-			numParameters := len(parameters)
-			webParameters := make([]webParameterStruct, numParameters)
+		for j := 0; j < numPorts; j++ {
+			currentPort := port + j
+			inputName := inputPortNames[currentPort]
+			outputName := outputPortNames[currentPort]
 
 			/*
-			 * Iterate over the parameters.
+			 * Use the name of the channel if it has one.
 			 */
-			for idParameter, parameter := range parameters {
-				name := parameter.Name
-				parameterTypeId := parameter.Type
-				parameterType := parameterTypes[parameterTypeId]
-				physicalUnit := parameter.PhysicalUnit
-				minimum := parameter.Minimum
-				maximum := parameter.Maximum
-				numericValue := parameter.NumericValue
-				discreteValueIndex := parameter.DiscreteValueIndex
-				discreteValuesSource := parameter.DiscreteValues
-				numDiscreteValues := len(discreteValuesSource)
-				discreteValues := make([]string, numDiscreteValues)
-				copy(discreteValues, discreteValuesSource)
+			if name != "" {
+				side := ""
 
 				/*
-				 * Create data structure for parameter.
+				 * Tell the sides of stereo channels apart.
 				 */
-				webParameter := webParameterStruct{
-					Name:               name,
-					Type:               parameterType,
-					PhysicalUnit:       physicalUnit,
-					Minimum:            minimum,
-					Maximum:            maximum,
-					NumericValue:       numericValue,
-					DiscreteValueIndex: discreteValueIndex,
-					DiscreteValues:     discreteValues,
+				if (numPorts > 1) && (j == 0) {
+					side = " left"
+				} else if numPorts > 1 {
+					side = " right"
 				}
 
-				webParameters[idParameter] = webParameter
-			}
-
-			/*
-			 * Create data structure for unit.
-			 */
-			webUnit := webUnitStruct{
-				Type:       unitType,
-				Bypass:     bypass,
-				Parameters: webParameters,
+				inputName = name + " in" + side
+				outputName = name + " out" + side
 			}
 
-			webUnits[idUnit] = webUnit
+			inputId := uint32(currentPort)
+			outputId := uint32(numInputs + currentPort)
+			levelMeter.SetChannelName(inputId, inputName)
+			levelMeter.SetChannelName(outputId, outputName)
 		}
 
-		webChains[idChannel].Units = webUnits
-		spat := this.spat
+	}
 
-		/*
-		 * Check if spatializer exists.
-		 */
-		if spat != nil {
-			idChannel32 := uint32(idChannel)
-			azimuth, _ := spat.GetAzimuth(idChannel32)
-			distance, _ := spat.GetDistance(idChannel32)
-			level, _ := spat.GetLevel(idChannel32)
+}
 
-			/*
-			 * Create data structure for spatializer channel.
-			 */
-			spatChannel := webSpatializerChannelStruct{
-				Azimuth:  azimuth,
-				Distance: distance,
-				Level:    level,
-			}
+/*
+ * Returns the color of the channel each channel of the level meter belongs to.
+ * Channels of the level meter which do not belong to a channel have no color.
+ */
+func (this *controllerStruct) levelMeterColors() []string {
+	levelMeter := this.levelMeter
+	numMeters := levelMeter.ChannelCount()
+	colors := make([]string, numMeters)
+	numInputs := len(this.inputPortNames)
+	mix := this.mixSnapshot()
+	metadata := mix.channels
 
-			spatChannels[idChannel] = spatChannel
+	/*
+	 * Iterate over the channels.
+	 */
+	for i, port := range this.channelPorts {
+		color := metadata[i].color
+		numPorts := this.channelPortCount(i)
+
+		/*
+		 * Assign the color to the input and output of each port.
+		 */
+		for j := 0; j < numPorts; j++ {
+			currentPort := port + j
+			outputId := numInputs + currentPort
+			colors[currentPort] = color
+			colors[outputId] = color
 		}
 
 	}
 
-	tunerChannel := this.tunerChannel
+	return colors
+}
 
-	/*
-	 * Create tuner structure.
-	 */
-	tuner := webTunerStruct{
-		Channel: tunerChannel,
-	}
+/*
+ * Returns the names of the master outputs the speaker layout of the
+ * spatializer requires, starting with the front left and right speaker.
+ */
+func (this *controllerStruct) masterPortNames() []string {
+	layout := this.spat.GetSpeakerLayout()
+	speakerNames, _ := spatializer.SpeakerNames(layout)
+	numSpeakers := len(speakerNames)
+	names := make([]string, numSpeakers)
 
 	/*
-	 * Create spatializer structure.
+	 * Derive the name of the port of each speaker.
 	 */
-	spat := webSpatializerStruct{
-		Channels: spatChannels,
+	for i, speakerName := range speakerNames {
+		names[i] = "master_" + speakerName
 	}
 
-	currentMetronome := this.metr
-	irs := this.impulseResponses
-	beatsPerPeriod := uint32(0)
-	speed := uint32(0)
-	preSounds := irs.Names()
-	numSounds := len(preSounds)
-	numSoundsInc := numSounds + 1
-	sounds := make([]string, numSoundsInc)
-	sounds[0] = "- NONE -"
-	copy(sounds[1:], preSounds)
-	tickSound := ""
-	tockSound := ""
-	metrMasterOutput := this.metrMasterOutput
+	return names
+}
 
-	/*
-	 * Check if we have a metronome.
-	 */
-	if currentMetronome != nil {
-		beatsPerPeriod = currentMetronome.BeatsPerPeriod()
-		speed = currentMetronome.Speed()
-		tickSound, _ = currentMetronome.Tick()
-		tockSound, _ = currentMetronome.Tock()
-	}
+/*
+ * Replaces the channels by a new set of signal chains along with their port
+ * identifiers and metadata, recreates level meter and spectrum analyzer for
+ * the new ports and changes the ports of the hardware binding accordingly.
+ *
+ * The spatializer must already have a channel for each signal chain.
+ */
+func (this *controllerStruct) setChannels(fx []signal.Chain, portIds []string, metadata []channelMetadataStruct, defaultMetadata []channelMetadataStruct) error {
+	numChannels := len(fx)
+	channelPorts := make([]int, numChannels)
+	inputPortNames := []string{}
+	outputPortNames := []string{}
 
 	/*
-	 * Create metronome structure.
+	 * Derive the ports of each channel.
 	 */
-	metr := webMetronomeStruct{
-		BeatsPerPeriod: beatsPerPeriod,
-		MasterOutput:   metrMasterOutput,
-		Speed:          speed,
-		Sounds:         sounds,
-		TickSound:      tickSound,
-		TockSound:      tockSound,
+	for i, chain := range fx {
+		id := portIds[i]
+		stereo := chain.Stereo()
+		channelPorts[i] = len(inputPortNames)
+		channelInputNames, channelOutputNames := channelPortNames(id, stereo)
+		inputPortNames = append(inputPortNames, channelInputNames...)
+		outputPortNames = append(outputPortNames, channelOutputNames...)
 	}
 
-	levelMeter := this.levelMeter
-	levelMeterEnabled := levelMeter.Enabled()
+	masterNames := this.masterPortNames()
+	numMasterOutputs := len(masterNames)
+	surroundNames := masterNames[spatializer.OUTPUT_COUNT:]
+	portNames := []string{}
+	portNames = append(portNames, inputPortNames...)
+	portNames = append(portNames, outputPortNames...)
+	portNames = append(portNames, "metronome", "master_left", "master_right", "player_left", "player_right")
+	portNames = append(portNames, surroundNames...)
+	numPorts := uint32(len(portNames))
+	outputPortNames = append(outputPortNames, "master_left", "master_right", "metronome", "player_left", "player_right")
+	outputPortNames = append(outputPortNames, surroundNames...)
+	levelMeter, err := level.CreateMeter(numPorts, portNames)
+	spectrumAnalyzer, errSpectrum := spectrum.CreateAnalyzer(numPorts, portNames)
 
 	/*
-	 * Create level meters structure.
+	 * Check if level meter and spectrum analyzer were created.
 	 */
-	meter := webLevelMeterStruct{
-		Enabled: levelMeterEnabled,
-	}
-
-	batchProcessing := (binding == nil)
+	if err != nil {
+		msg := err.Error()
+		return fmt.Errorf("Failed to create level meter: %s", msg)
+	} else if errSpectrum != nil {
+		msg := errSpectrum.Error()
+		return fmt.Errorf("Failed to create spectrum analyzer: %s", msg)
+	} else {
+		levelMeterEnabled := this.levelMeter.Enabled()
+		levelMeter.SetEnabled(levelMeterEnabled)
+		levelMeterBallistics := this.levelMeter.Ballistics()
+		levelMeter.SetBallistics(levelMeterBallistics)
+		levelMeterPeakHoldTime := this.levelMeter.PeakHoldTime()
+		levelMeter.SetPeakHoldTime(levelMeterPeakHoldTime)
+		spectrumAnalyzerEnabled := this.spectrumAnalyzer.Enabled()
+		spectrumAnalyzer.SetEnabled(spectrumAnalyzerEnabled)
+		buffers := make([][]float64, numPorts)
+		masterBuffers := make([][]float64, numMasterOutputs)
+		processingTimes := make([]uint32, numChannels)
+		this.layoutMutex.Lock()
+		this.effects = fx
+		this.channelPorts = channelPorts
+		this.channelPortIds = portIds
 
-	/*
-	 * Create configuration structure.
-	 */
-	cfg := webConfigurationStruct{
-		Chains:          webChains,
-		FramesPerPeriod: framesPerPeriod,
-		Tuner:           tuner,
-		Spatializer:     spat,
-		Metronome:       metr,
-		LevelMeter:      meter,
-		BatchProcessing: batchProcessing,
-	}
+		/*
+		 * Publish the settings of the new channels along with them.
+		 */
+		this.changeMix(func(mix *mixSnapshotStruct) {
+			mix.channels = metadata
+		})
+
+		this.defaultChannelMetadata = defaultMetadata
+		this.inputPortNames = inputPortNames
+		this.outputPortNames = outputPortNames
+		this.buffers = buffers
+		this.masterBuffers = masterBuffers
+		this.levelMeter = levelMeter
+		this.spectrumAnalyzer = spectrumAnalyzer
+		this.processingTimes = processingTimes
+		this.layoutMutex.Unlock()
+		this.updateLevelMeterNames()
+		binding := this.binding
 
-	mimeType, buffer := this.createJSON(cfg)
+		/*
+		 * Without hardware, there are no ports to change.
+		 */
+		if binding == nil {
+			return nil
+		} else {
+			err = hwio.Rebind(binding, inputPortNames, outputPortNames)
+			return err
+		}
 
-	/*
-	 * Create HTTP response.
-	 */
-	response := webserver.HttpResponse{
-		Header: map[string]string{"Content-type": mimeType},
-		Body:   buffer,
 	}
 
-	return response
 }
 
 /*
- * Returns the results of the level analysis of the channels.
+ * Checks whether channels may be added or removed right now and stops any
+ * morph in progress, since it refers to the current channels.
  */
-func (this *controllerStruct) getLevelAnalysisHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	dspLoad := hwio.DSPLoad()
-	dspLoad64 := float64(dspLoad)
-	dspLoadRounded := math.Round(dspLoad64)
-	dspLoad32 := int32(dspLoadRounded)
-	levelMeter := this.levelMeter
-	channelCount := levelMeter.ChannelCount()
-	results := make([]webLevelMeterResultStruct, channelCount)
+func (this *controllerStruct) prepareChannelChange() error {
+	rec := this.recorder
 
 	/*
-	 * Iterate over all channels and obtain results.
+	 * The tracks of a recording refer to the current ports.
 	 */
-	for i := range results {
-		channelId := uint32(i)
-		channelName, err := levelMeter.ChannelName(channelId)
+	if rec != nil {
+		status := rec.Status()
 
 		/*
-		 * Check if channel name could be obtained
+		 * Check if recorder is recording.
 		 */
-		if err == nil {
-			result, err := levelMeter.Analyze(channelId)
-
-			/*
-			 * Check if level analysis was successful.
-			 */
-			if err == nil {
-				level := result.Level()
-				peak := result.Peak()
-
-				/*
-				 * Fill in web result data structure.
-				 */
-				r := webLevelMeterResultStruct{
-					ChannelName: channelName,
-					Level:       level,
-					Peak:        peak,
-				}
-
-				results[i] = r
-			}
-
+		if status.Recording() {
+			return fmt.Errorf("%s", "Cannot add or remove channels while recording.")
 		}
 
 	}
 
-	/*
-	 * Create level meters result structure.
-	 */
-	result := webLevelMetersResultStruct{
-		DSPLoad:  dspLoad32,
-		Channels: results,
-	}
+	this.haltMorph()
+	return nil
+}
 
-	mimeType, buffer := this.createJSON(result)
+/*
+ * Marshals an object into a JSON representation or an error.
+ * Returns the appropriate MIME type and binary representation.
+ */
+func (this *controllerStruct) createJSON(obj interface{}) (string, []byte) {
+	buffer, err := json.MarshalIndent(obj, "", "\t")
 
 	/*
-	 * Create HTTP response.
+	 * Check if we got an error during marshalling.
 	 */
-	response := webserver.HttpResponse{
-		Header: map[string]string{"Content-type": mimeType},
-		Body:   buffer,
+	if err != nil {
+		conf := this.config
+		confServer := conf.WebServer
+		contentType := confServer.ErrorMime
+		errString := err.Error()
+		bufString := bytes.NewBufferString(errString)
+		bufBytes := bufString.Bytes()
+		return contentType, bufBytes
+	} else {
+		return "application/json; charset=utf-8", buffer
 	}
 
-	return response
 }
 
 /*
- * Returns a list of all supported types of effects units.
+ * Adds an input channel after the existing ones, along with its signal chain,
+ * ports, level meters and position in the spatializer.
  */
-func (this *controllerStruct) getUnitTypesHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	unitTypes := effects.UnitTypes()
-	mimeType, buffer := this.createJSON(unitTypes)
+func (this *controllerStruct) addChannelHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	stereoString := request.Params["stereo"]
+	stereo := false
+	errStereo := error(nil)
 
 	/*
-	 * Create HTTP response.
+	 * Channels are mono unless requested otherwise.
 	 */
-	response := webserver.HttpResponse{
-		Header: map[string]string{"Content-type": mimeType},
-		Body:   buffer,
+	if stereoString != "" {
+		stereo, errStereo = strconv.ParseBool(stereoString)
 	}
 
-	return response
-}
-
-/*
- * Perform a pitch analysis via the tuner and return the results.
- */
-func (this *controllerStruct) getTunerAnalysisHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	currentTuner := this.tuner
-	analysis, err := currentTuner.Analyze()
-	response := webserver.HttpResponse{}
+	nameString := request.Params["name"]
+	name := strings.TrimSpace(nameString)
+	colorString := request.Params["color"]
+	color := strings.TrimSpace(colorString)
+	webResponse := webResponseStruct{}
 
 	/*
-	 * Check if analysis was successful.
+	 * Check if the parameters are valid.
 	 */
-	if err != nil {
-		msg := err.Error()
-		reason := fmt.Sprintf("Failed to perform analysis: %s", msg)
+	if errStereo != nil {
 
 		/*
 		 * Indicate failure.
 		 */
-		errResponse := webResponseStruct{
+		webResponse = webResponseStruct{
 			Success: false,
-			Reason:  reason,
+			Reason:  "Failed to decode stereo flag.",
 		}
 
-		mimeType, buffer := this.createJSON(errResponse)
+	} else if utf8.RuneCountInString(name) > CHANNEL_NAME_MAX_LENGTH {
 
 		/*
-		 * Create HTTP response.
+		 * Indicate failure.
 		 */
-		response = webserver.HttpResponse{
-			Header: map[string]string{"Content-type": mimeType},
-			Body:   buffer,
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Channel name too long.",
 		}
 
-	} else {
-		cents := analysis.Cents()
-		frequency := analysis.Frequency()
-		note := analysis.Note()
+	} else if (color != "") && !isColor(color) {
 
 		/*
-		 * Fill the results of the tuner into a data structure.
+		 * Indicate failure.
 		 */
-		result := webTunerResultStruct{
-			Cents:     cents,
-			Frequency: frequency,
-			Note:      note,
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Color must be in hexadecimal notation (#rrggbb).",
 		}
 
-		mimeType, buffer := this.createJSON(result)
+	} else {
+		err := this.prepareChannelChange()
 
 		/*
-		 * Create HTTP response.
+		 * Check if channels may be changed.
 		 */
-		response = webserver.HttpResponse{
-			Header: map[string]string{"Content-type": mimeType},
-			Body:   buffer,
-		}
-
-	}
+		if err != nil {
+			reason := err.Error()
 
-	return response
-}
-
-/*
- * Moves a unit down in a rack.
- */
-func (this *controllerStruct) moveDownHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	chainIdString := request.Params["chain"]
-	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
-	unitIdString := request.Params["unit"]
-	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
-	webResponse := webResponseStruct{}
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
 
-	/*
-	 * Check if chain and unit ID are valid.
-	 */
-	if errChainId != nil {
+		} else {
+			ir := this.impulseResponses
+			chain := signal.CreateChain(ir)
 
-		/*
-		 * Indicate failure.
-		 */
-		webResponse = webResponseStruct{
-			Success: false,
-			Reason:  "Failed to decode chain ID.",
-		}
+			/*
+			 * Create a chain matching the number of ports.
+			 */
+			if stereo {
+				chain = signal.CreateStereoChain(ir)
+			}
 
-	} else if errUnitId != nil {
+			/*
+			 * Take over the smoothing and spillover settings of the
+			 * other chains.
+			 */
+			if len(this.buses) > 0 {
+				bus := this.buses[0]
+				smoothing := bus.Smoothing()
+				smoothingTime := bus.SmoothingTime()
+				spilloverTime := bus.SpilloverTime()
+				chain.SetSmoothing(smoothing)
+				chain.SetSmoothingTime(smoothingTime)
+				chain.SetSpilloverTime(spilloverTime)
+			}
 
-		/*
-		 * Indicate failure.
-		 */
-		webResponse = webResponseStruct{
-			Success: false,
-			Reason:  "Failed to decode unit ID.",
-		}
+			/*
+			 * Let units prepare their filters for the frames per period.
+			 */
+			if this.binding != nil {
+				framesPerPeriod := hwio.FramesPerPeriod()
+				chain.SetBlockSize(framesPerPeriod)
+			}
 
-	} else {
-		chainId := int(chainId64)
-		unitId := int(unitId64)
-		fx := this.effects
-		nChains := len(fx)
+			/*
+			 * The name and color of the channel.
+			 */
+			metadata := channelMetadataStruct{
+				name:  name,
+				color: color,
+			}
 
-		/*
-		 * Check if chain ID is out of range.
-		 */
-		if (chainId < 0) || (chainId >= nChains) {
+			portId := this.newChannelPortId(name)
+			fx := append([]signal.Chain{}, this.effects...)
+			fx = append(fx, chain)
+			portIds := append([]string{}, this.channelPortIds...)
+			portIds = append(portIds, portId)
+			mix := this.mixSnapshot()
+			channelMetadata := append([]channelMetadataStruct{}, mix.channels...)
+			channelMetadata = append(channelMetadata, metadata)
+			defaultChannelMetadata := append([]channelMetadataStruct{}, this.defaultChannelMetadata...)
+			defaultChannelMetadata = append(defaultChannelMetadata, metadata)
+			numChannelsOld := len(this.effects)
+			this.spat.AddChannel(stereo)
+			err = this.setChannels(fx, portIds, channelMetadata, defaultChannelMetadata)
 
 			/*
-			 * Indicate failure.
+			 * The aux buses follow the channels, so their lanes move
+			 * down by one.
 			 */
-			webResponse = webResponseStruct{
-				Success: false,
-				Reason:  "Chain ID out of range.",
-			}
+			this.remapAutomation(func(laneChain int, laneUnit int) (int, int, bool) {
 
-		} else {
-			err := fx[chainId].MoveDown(unitId)
+				/*
+				 * Check if the lane belongs to an aux bus.
+				 */
+				if laneChain >= numChannelsOld {
+					return laneChain + 1, laneUnit, true
+				} else {
+					return laneChain, laneUnit, true
+				}
+
+			})
+
+			this.updateTempo()
+			this.clearHistory()
+			go this.processAsync()
 
 			/*
-			 * Check if unit was successfully moved downwards.
+			 * Check if the ports were changed.
 			 */
 			if err != nil {
-				reason := err.Error()
+				msg := err.Error()
+				reason := "Channel was added, but its ports could not be registered: " + msg
 
 				/*
 				 * Indicate failure.
@@ -780,42 +1404,42 @@ func (this *controllerStruct) moveDownHandler(request webserver.HttpRequest) web
 }
 
 /*
- * Moves a unit up in a rack.
+ * Adds a new unit to a rack.
  */
-func (this *controllerStruct) moveUpHandler(request webserver.HttpRequest) webserver.HttpResponse {
+func (this *controllerStruct) addUnitHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	unitTypeString := request.Params["type"]
+	unitType64, errUnitType := strconv.ParseUint(unitTypeString, 10, 32)
 	chainIdString := request.Params["chain"]
 	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
-	unitIdString := request.Params["unit"]
-	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
 	webResponse := webResponseStruct{}
 
 	/*
-	 * Check if chain and unit ID are valid.
+	 * Check if unit type and chain ID are valid.
 	 */
-	if errChainId != nil {
+	if errUnitType != nil {
 
 		/*
 		 * Indicate failure.
 		 */
 		webResponse = webResponseStruct{
 			Success: false,
-			Reason:  "Failed to decode chain ID.",
+			Reason:  "Failed to decode unit type.",
 		}
 
-	} else if errUnitId != nil {
+	} else if errChainId != nil {
 
 		/*
 		 * Indicate failure.
 		 */
 		webResponse = webResponseStruct{
 			Success: false,
-			Reason:  "Failed to decode unit ID.",
+			Reason:  "Failed to decode chain ID.",
 		}
 
 	} else {
+		unitType := int(unitType64)
 		chainId := int(chainId64)
-		unitId := int(unitId64)
-		fx := this.effects
+		fx := this.chains()
 		nChains := len(fx)
 
 		/*
@@ -832,10 +1456,11 @@ func (this *controllerStruct) moveUpHandler(request webserver.HttpRequest) webse
 			}
 
 		} else {
-			err := fx[chainId].MoveUp(unitId)
+			this.haltMorph()
+			_, err := fx[chainId].AppendUnit(unitType)
 
 			/*
-			 * Check if unit was successfully moved upwards.
+			 * Check if unit was successfully appended.
 			 */
 			if err != nil {
 				reason := err.Error()
@@ -878,305 +1503,397 @@ func (this *controllerStruct) moveUpHandler(request webserver.HttpRequest) webse
 }
 
 /*
- * Restore (import) current configuration from JSON file.
+ * Creates a description of a signal chain for the web interface.
  */
-func (this *controllerStruct) persistenceRestoreHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	patchFiles := request.Files["patchfile"]
-	webResponse := webResponseStruct{}
+func (this *controllerStruct) createWebChain(chain signal.Chain) webChainStruct {
+	parameterTypes := effects.ParameterTypes()
+	tapers := effects.Tapers()
+	numUnits := chain.Length()
+	webUnits := make([]webUnitStruct, numUnits)
 
 	/*
-	 * Make sure that patch files are not nil.
+	 * Iterate over the units in each channel.
 	 */
-	if patchFiles == nil {
+	for idUnit := 0; idUnit < numUnits; idUnit++ {
+		unitType, _ := chain.UnitType(idUnit)
+		bypass, _ := chain.GetBypass(idUnit)
+		inputTrim, _ := chain.GetInputTrim(idUnit)
+		outputLevel, _ := chain.GetOutputLevel(idUnit)
+		label, _ := chain.GetLabel(idUnit)
+		note, _ := chain.GetNote(idUnit)
+		parameters, _ := chain.Parameters(idUnit)
+		numParameters := len(parameters)
+		webParameters := make([]webParameterStruct, numParameters)
+		unitName := ""
+		composite, err := chain.Composite(idUnit)
 
 		/*
-		 * Indicate failure.
+		 * Only composite units have a name.
 		 */
-		webResponse = webResponseStruct{
-			Success: false,
-			Reason:  "Field 'patchfile' not defined as a multipart field.",
+		if err == nil {
+			unitName = composite.Name()
 		}
 
-	} else {
-		numPatchFiles := len(patchFiles)
-
 		/*
-		 * Make sure that exactly one patch file is sent in request.
+		 * Iterate over the parameters.
 		 */
-		if numPatchFiles == 0 {
+		for idParameter, parameter := range parameters {
+			name := parameter.Name
+			parameterTypeId := parameter.Type
+			parameterType := parameterTypes[parameterTypeId]
+			physicalUnit := parameter.PhysicalUnit
+			minimum := parameter.Minimum
+			maximum := parameter.Maximum
+			numericValue := parameter.NumericValue
+			discreteValueIndex := parameter.DiscreteValueIndex
+			discreteValuesSource := parameter.DiscreteValues
+			numDiscreteValues := len(discreteValuesSource)
+			discreteValues := make([]string, numDiscreteValues)
+			copy(discreteValues, discreteValuesSource)
+			taperId := parameter.Taper
+			taper := tapers[taperId]
 
 			/*
-			 * Indicate failure.
+			 * Create data structure for parameter.
 			 */
-			webResponse = webResponseStruct{
-				Success: false,
-				Reason:  "No patch file sent in request.",
+			webParameter := webParameterStruct{
+				Name:                      name,
+				Type:                      parameterType,
+				PhysicalUnit:              physicalUnit,
+				Minimum:                   minimum,
+				Maximum:                   maximum,
+				NumericValue:              numericValue,
+				DiscreteValueIndex:        discreteValueIndex,
+				DiscreteValues:            discreteValues,
+				DefaultValue:              parameter.DefaultValue,
+				DefaultDiscreteValueIndex: parameter.DefaultDiscreteValueIndex,
+				Taper:                     taper,
+				Scale:                     parameter.Scale,
+				Precision:                 parameter.Precision,
+				DisplayUnit:               parameter.DisplayUnit,
 			}
 
-		} else if numPatchFiles != 1 {
-
-			/*
-			 * Indicate failure.
-			 */
-			webResponse = webResponseStruct{
-				Success: false,
-				Reason:  "Multiple patch files sent in request.",
-			}
+			webParameters[idParameter] = webParameter
+		}
 
-		} else {
-			patchFile := patchFiles[0]
-			patchBytes, err := io.ReadAll(patchFile)
+		/*
+		 * Create data structure for unit.
+		 */
+		webUnit := webUnitStruct{
+			Type:        unitType,
+			Name:        unitName,
+			Label:       label,
+			Note:        note,
+			Bypass:      bypass,
+			InputTrim:   inputTrim,
+			OutputLevel: outputLevel,
+			Parameters:  webParameters,
+		}
 
-			/*
-			 * Check if patch file could be successfully read.
-			 */
-			if err != nil {
+		webUnits[idUnit] = webUnit
+	}
 
-				/*
-				 * Indicate failure.
-				 */
-				webResponse = webResponseStruct{
-					Success: false,
-					Reason:  "Failed to read patch file.",
-				}
+	/*
+	 * Create data structure for chain.
+	 */
+	webChain := webChainStruct{
+		Stereo: chain.Stereo(),
+		Units:  webUnits,
+	}
 
-			} else {
-				configuration := persistence.Configuration{}
-				err := json.Unmarshal(patchBytes, &configuration)
+	return webChain
+}
 
-				/*
-				 * Check if unmarshalling was successful.
-				 */
-				if err != nil {
-					msg := err.Error()
-					reason := fmt.Sprintf("Error during unmarshalling: %s", msg)
+/*
+ * Returns the current rack configuration.
+ */
+func (this *controllerStruct) getConfigurationHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	fx := this.effects
+	numChannels := len(fx)
+	mix := this.mixSnapshot()
+	framesPerPeriod := uint32(0)
+	binding := this.binding
 
-					/*
-					 * Indicate failure.
-					 */
-					webResponse = webResponseStruct{
-						Success: false,
-						Reason:  reason,
-					}
+	/*
+	 * If we are bound to a hardware interface, query frames per period.
+	 */
+	if binding != nil {
+		framesPerPeriod = hwio.FramesPerPeriod()
+	}
 
-				} else {
-					fileFormat := configuration.FileFormat
-					fileType := fileFormat.Type
-					fileVersion := fileFormat.Version
-					majorVersion := fileVersion.Major
-					minorVersion := fileVersion.Minor
+	webChains := make([]webChainStruct, numChannels)
+	spatChannels := make([]webSpatializerChannelStruct, numChannels)
 
-					/*
-					 * Ensure that file format is compatible.
-					 */
-					if fileType != "patch" {
+	/*
+	 * Iterate over the channels and the associated signal chains.
+	 */
+	for idChannel, chain := range fx {
+		webChain := this.createWebChain(chain)
+		metadata := mix.channels[idChannel]
+		webChain.Name = metadata.name
+		webChain.Color = metadata.color
+		webChain.ChannelTrim = metadata.channelTrim
+		webChain.ReampLevel = metadata.reampLevel
+		webChains[idChannel] = webChain
+		spat := this.spat
 
-						/*
-						 * Indicate failure.
-						 */
-						webResponse = webResponseStruct{
-							Success: false,
-							Reason:  "Uploaded file is not a patch file.",
-						}
+		/*
+		 * Check if spatializer exists.
+		 */
+		if spat != nil {
+			idChannel32 := uint32(idChannel)
+			azimuth, _ := spat.GetAzimuth(idChannel32)
+			distance, _ := spat.GetDistance(idChannel32)
+			level, _ := spat.GetLevel(idChannel32)
+			mute, _ := spat.GetMute(idChannel32)
+			solo, _ := spat.GetSolo(idChannel32)
+			numBuses := spat.GetBusCount()
+			sends := make([]float64, numBuses)
 
-					} else if majorVersion != 1 || minorVersion < 0 {
+			/*
+			 * Query the send level for each aux bus.
+			 */
+			for idBus := uint32(0); idBus < numBuses; idBus++ {
+				sends[idBus], _ = spat.GetSend(idChannel32, idBus)
+			}
 
-						/*
-						 * Indicate failure.
-						 */
-						webResponse = webResponseStruct{
-							Success: false,
-							Reason:  "Incompatible version of file format.",
-						}
+			/*
+			 * Create data structure for spatializer channel.
+			 */
+			spatChannel := webSpatializerChannelStruct{
+				Azimuth:  azimuth,
+				Distance: distance,
+				Level:    level,
+				Mute:     mute,
+				Solo:     solo,
+				Sends:    sends,
+			}
 
-					} else {
+			spatChannels[idChannel] = spatChannel
+		}
 
-						/*
-						 * If we are bound to a hardware interface, restore frames per period.
-						 */
-						if this.binding != nil {
-							framesPerPeriod := configuration.FramesPerPeriod
-							hwio.SetFramesPerPeriod(framesPerPeriod)
-						}
+	}
 
-						channels := configuration.Channels
-						numChannels := len(channels)
-						signalChains := this.effects
-						numChains := len(signalChains)
+	tunerChannel := mix.tunerChannel
+	tunerMute := mix.tunerMute
+	currentTuner := this.tuner
+	tunerReference := float64(tuner.REFERENCE_DEFAULT)
+	tunerNotation := tuner.NOTATION_DEFAULT
+	tunerTemperament := tuner.TEMPERAMENT_DEFAULT
+	tunerTuning := tuner.TUNING_DEFAULT
 
-						/*
-						 * Verify that the configuration file does not contain
-						 * more channels than we have.
-						 */
-						if numChannels > numChains {
-							msg := fmt.Sprintf("WARNING: Restored file contains %d channels, but we currently have only %d. Restore may be incomplete.", numChannels, numChains)
+	/*
+	 * Check if we have a tuner.
+	 */
+	if currentTuner != nil {
+		tunerReference = currentTuner.Reference()
+		tunerNotation = currentTuner.Notation()
+		tunerTemperament = currentTuner.Temperament()
+		tunerTuning = currentTuner.Tuning()
+	}
 
-							/*
-							 * Indicate failure.
-							 */
-							webResponse = webResponseStruct{
-								Success: false,
-								Reason:  msg,
-							}
+	notations := tuner.Notations()
+	temperaments := tuner.Temperaments()
+	tunings := tuner.Tunings()
 
-						}
+	/*
+	 * Create tuner structure.
+	 */
+	webTuner := webTunerStruct{
+		Channel:      tunerChannel,
+		Mute:         tunerMute,
+		Reference:    tunerReference,
+		Notation:     tunerNotation,
+		Notations:    notations,
+		Temperament:  tunerTemperament,
+		Temperaments: temperaments,
+		Tuning:       tunerTuning,
+		Tunings:      tunings,
+	}
 
-						spat := this.spat
-						unitTypes := effects.UnitTypes()
+	buses := this.buses
+	numBuses := len(buses)
+	webBuses := make([]webBusStruct, numBuses)
 
-						/*
-						 * Restore each channel.
-						 */
-						for channelId, channel := range channels {
-							signalChain := signalChains[channelId]
-							numUnits := signalChain.Length()
+	/*
+	 * Iterate over the aux buses.
+	 */
+	for idBus, chain := range buses {
+		idBus32 := uint32(idBus)
+		returnLevel, _ := this.spat.GetReturn(idBus32)
 
-							/*
-							 * Remove all units from the signal chain.
-							 */
-							for numUnits > 0 {
-								unitId := numUnits - 1
-								signalChain.RemoveUnit(unitId)
-								numUnits = signalChain.Length()
-							}
+		/*
+		 * Create data structure for aux bus.
+		 */
+		webBuses[idBus] = webBusStruct{
+			Chain:  this.createWebChain(chain),
+			Return: returnLevel,
+		}
 
-							units := channel.Units
+	}
 
-							/*
-							 * Restore each processing unit.
-							 */
-							for _, unit := range units {
-								unitType := unit.Type
-								unitTypeId := int(-1)
-								unitTypeFound := false
+	/*
+	 * Create spatializer structure.
+	 */
+	spat := webSpatializerStruct{
+		Mode:          this.spat.GetMode(),
+		SpeakerLayout: this.spat.GetSpeakerLayout(),
+		Channels:      spatChannels,
+		Buses:         webBuses,
+	}
 
-								/*
-								 * Search for the right unit type.
-								 */
-								for id, currentUnitType := range unitTypes {
-
-									/*
-									 * If we found the correct unit type,
-									 * store its ID.
-									 */
-									if unitType == currentUnitType {
-										unitTypeId = id
-										unitTypeFound = true
-									}
-
-								}
-
-								/*
-								 * If we found the unit type, restore the unit.
-								 */
-								if unitTypeFound {
-									signalChain.AppendUnit(unitTypeId)
-									numUnits := signalChain.Length()
-									lastUnitId := numUnits - 1
-
-									/*
-									 * Restore each discrete parameter.
-									 */
-									for _, param := range unit.DiscreteParams {
-										key := param.Key
-										value := param.Value
-										signalChain.SetDiscreteValue(lastUnitId, key, value)
-									}
-
-									/*
-									 * Restore each numeric parameter.
-									 */
-									for _, param := range unit.NumericParams {
-										key := param.Key
-										value := param.Value
-										signalChain.SetNumericValue(lastUnitId, key, value)
-									}
-
-									bypass := unit.Bypass
-									signalChain.SetBypass(lastUnitId, bypass)
-								}
-
-							}
+	currentMetronome := this.metr
+	irs := this.impulseResponses
+	accents := ""
+	beatsPerPeriod := uint32(0)
+	countIn := uint32(0)
+	speed := uint32(0)
+	subdivision := uint32(0)
+	synthSounds := metronome.Sounds()
+	numSynthSounds := len(synthSounds)
+	preSounds := irs.Names()
+	numSounds := len(preSounds)
+	numSoundsInc := numSounds + numSynthSounds + 1
+	sounds := make([]string, numSoundsInc)
+	sounds[0] = "- NONE -"
+	copy(sounds[1:], synthSounds)
+	lBoundSounds := numSynthSounds + 1
+	copy(sounds[lBoundSounds:], preSounds)
+	tickSound := ""
+	tickPitch := float64(0.0)
+	tickDecay := float64(0.0)
+	tockSound := ""
+	tockPitch := float64(0.0)
+	tockDecay := float64(0.0)
+	metrMasterOutput := mix.metrMasterOutput
+	metrOutputs := make([]float64, numChannels)
 
-							channelId32 := uint32(channelId)
-							persistedSpat := channel.Spatializer
-							azimuth := persistedSpat.Azimuth
-							distance := persistedSpat.Distance
-							level := persistedSpat.Level
-							spat.SetAzimuth(channelId32, azimuth)
-							spat.SetDistance(channelId32, distance)
-							spat.SetLevel(channelId32, level)
-						}
+	/*
+	 * The level of the click in the output of each channel.
+	 */
+	for i, metadata := range mix.channels {
+		metrOutputs[i] = metadata.click
+	}
 
-						irs := this.impulseResponses
-						sampleRate := this.sampleRate
-						metr := this.metr
-						persistedMetr := configuration.Metronome
-						masterOutput := persistedMetr.Master
-						this.metrMasterOutput = masterOutput
-						beatsPerPeriod := persistedMetr.BeatsPerPeriod
-						metr.SetBeatsPerPeriod(beatsPerPeriod)
-						speed := persistedMetr.Speed
-						metr.SetSpeed(speed)
-						tickSound := persistedMetr.TickSound
+	/*
+	 * Check if we have a metronome.
+	 */
+	if currentMetronome != nil {
+		accents = currentMetronome.Accents()
+		beatsPerPeriod = currentMetronome.BeatsPerPeriod()
+		countIn = currentMetronome.CountIn()
+		speed = currentMetronome.Speed()
+		subdivision = currentMetronome.Subdivision()
+		tickSound, _ = currentMetronome.Tick()
+		tickPitch, tickDecay = currentMetronome.TickSynthesis()
+		tockSound, _ = currentMetronome.Tock()
+		tockPitch, tockDecay = currentMetronome.TockSynthesis()
+	}
 
-						/*
-						 * Check if we should disable the tick sound.
-						 */
-						if tickSound == "- NONE -" {
-							metr.SetTick(tickSound, nil)
-						} else {
-							flt := irs.CreateFilter(tickSound, sampleRate)
+	/*
+	 * Create metronome structure.
+	 */
+	metr := webMetronomeStruct{
+		Accents:        accents,
+		BeatsPerPeriod: beatsPerPeriod,
+		CountIn:        countIn,
+		MasterOutput:   metrMasterOutput,
+		Outputs:        metrOutputs,
+		Speed:          speed,
+		Subdivision:    subdivision,
+		Sounds:         sounds,
+		TickSound:      tickSound,
+		TickPitch:      tickPitch,
+		TickDecay:      tickDecay,
+		TockSound:      tockSound,
+		TockPitch:      tockPitch,
+		TockDecay:      tockDecay,
+	}
 
-							/*
-							 * Check if filter was successfully loaded.
-							 */
-							if flt != nil {
-								coeffs := flt.Coefficients()
-								metr.SetTick(tickSound, coeffs)
-							}
+	trackPlayer := this.trackPlayer
+	playerLevel := trackPlayer.Level()
+	playerMasterOutput := mix.playerMasterOutput
 
-						}
+	/*
+	 * Create player structure.
+	 */
+	webPlayer := webPlayerStruct{
+		Level:        playerLevel,
+		MasterOutput: playerMasterOutput,
+	}
 
-						tockSound := persistedMetr.TockSound
+	masterSection := this.masterSection
+	masterEnabled := masterSection.Enabled()
+	masterParameters := masterSection.Parameters()
 
-						/*
-						 * Check if we should disable the tock sound.
-						 */
-						if tockSound == "- NONE -" {
-							metr.SetTock(tockSound, nil)
-						} else {
-							flt := irs.CreateFilter(tockSound, sampleRate)
+	/*
+	 * Create master section structure.
+	 */
+	webMaster := webMasterStruct{
+		Enabled:    masterEnabled,
+		Parameters: masterParameters,
+	}
 
-							/*
-							 * Check if filter was successfully loaded.
-							 */
-							if flt != nil {
-								coeffs := flt.Coefficients()
-								metr.SetTock(tockSound, coeffs)
-							}
+	levelMeter := this.levelMeter
+	levelMeterEnabled := levelMeter.Enabled()
+	levelMeterBallistics := levelMeter.Ballistics()
+	levelMeterPeakHoldTime := levelMeter.PeakHoldTime()
 
-						}
+	/*
+	 * Create level meters structure.
+	 */
+	meter := webLevelMeterStruct{
+		Enabled:      levelMeterEnabled,
+		Ballistics:   levelMeterBallistics,
+		PeakHoldTime: levelMeterPeakHoldTime,
+	}
 
-						/*
-						 * Indicate success.
-						 */
-						webResponse = webResponseStruct{
-							Success: true,
-							Reason:  "",
-						}
+	spectrumAnalyzer := this.spectrumAnalyzer
+	spectrumAnalyzerEnabled := spectrumAnalyzer.Enabled()
 
-					}
+	/*
+	 * Create spectrum analyzer structure.
+	 */
+	analyzer := webSpectrumAnalyzerStruct{
+		Enabled: spectrumAnalyzerEnabled,
+	}
 
-				}
+	parameterSmoothing := true
+	smoothingTime := uint32(effects.SMOOTHING_TIME_DEFAULT)
+	spilloverTime := uint32(0)
 
-			}
+	/*
+	 * All chains share the same smoothing and spillover settings, so query
+	 * the first one.
+	 */
+	if numChannels > 0 {
+		parameterSmoothing = fx[0].Smoothing()
+		smoothingTime = fx[0].SmoothingTime()
+		spilloverTime = fx[0].SpilloverTime()
+	}
 
-		}
+	batchProcessing := (binding == nil)
 
+	/*
+	 * Create configuration structure.
+	 */
+	cfg := webConfigurationStruct{
+		Chains:             webChains,
+		FramesPerPeriod:    framesPerPeriod,
+		Tuner:              webTuner,
+		Spatializer:        spat,
+		Metronome:          metr,
+		Player:             webPlayer,
+		Master:             webMaster,
+		LevelMeter:         meter,
+		SpectrumAnalyzer:   analyzer,
+		ParameterSmoothing: parameterSmoothing,
+		SmoothingTime:      smoothingTime,
+		SpilloverTime:      spilloverTime,
+		BatchProcessing:    batchProcessing,
 	}
 
-	mimeType, buffer := this.createJSON(webResponse)
+	mimeType, buffer := this.createJSON(cfg)
 
 	/*
 	 * Create HTTP response.
@@ -1190,205 +1907,2201 @@ func (this *controllerStruct) persistenceRestoreHandler(request webserver.HttpRe
 }
 
 /*
- * Save (export) current configuration to JSON file.
+ * Returns the results of the level analysis of the channels.
  */
-func (this *controllerStruct) persistenceSaveHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	cfg := this.config
-	svr := cfg.WebServer
-	appName := svr.Name
-	framesPerPeriod := uint32(BLOCK_SIZE)
+func (this *controllerStruct) getLevelAnalysisHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	dspLoad32 := this.dspLoad()
+	levelMeter := this.levelMeter
+	channelCount := levelMeter.ChannelCount()
+	results := make([]webLevelMeterResultStruct, channelCount)
+	colors := this.levelMeterColors()
 
 	/*
-	 * If we are bound to a hardware interface, query frames per period.
+	 * Iterate over all channels and obtain results.
 	 */
-	if this.binding != nil {
-		framesPerPeriod = hwio.FramesPerPeriod()
-	}
+	for i := range results {
+		channelId := uint32(i)
+		channelName, err := levelMeter.ChannelName(channelId)
 
-	/*
-	 * Create file format version.
-	 */
-	version := persistence.Version{
-		Major: 1,
-		Minor: 0,
-	}
+		/*
+		 * Check if channel name could be obtained
+		 */
+		if err == nil {
+			result, err := levelMeter.Analyze(channelId)
+
+			/*
+			 * Check if level analysis was successful.
+			 */
+			if err == nil {
+				level := result.Level()
+				peak := result.Peak()
+				clips := result.Clips()
+				rmsTenths := 10.0 * result.RMS()
+				rmsRounded := math.Round(rmsTenths)
+				shortTermTenths := 10.0 * result.ShortTermLoudness()
+				shortTermRounded := math.Round(shortTermTenths)
+				integratedTenths := 10.0 * result.IntegratedLoudness()
+				integratedRounded := math.Round(integratedTenths)
+
+				/*
+				 * Fill in web result data structure.
+				 */
+				r := webLevelMeterResultStruct{
+					ChannelName: channelName,
+					Color:       colors[i],
+					Level:       level,
+					Peak:        peak,
+					Clips:       clips,
+					RMS:         0.1 * rmsRounded,
+					ShortTerm:   0.1 * shortTermRounded,
+					Integrated:  0.1 * integratedRounded,
+				}
+
+				results[i] = r
+			}
+
+		}
 
-	/*
-	 * Create file format.
-	 */
-	fileFormat := persistence.FileFormat{
-		Application: appName,
-		Type:        "patch",
-		Version:     version,
 	}
 
-	channels := []persistence.Channel{}
-	spat := this.spat
-	unitTypes := effects.UnitTypes()
+	gainReductions := []webGainReductionStruct{}
 
 	/*
-	 * Iterate over the signal chains.
+	 * Obtain the gain reduction of all units which report it.
 	 */
-	for chainId, chain := range this.effects {
+	for chainId, chain := range this.chains() {
 		numUnits := chain.Length()
-		units := make([]persistence.Unit, numUnits)
 
 		/*
-		 * Iterate over all units in the current chain.
+		 * Query each unit in the chain.
 		 */
 		for unitId := 0; unitId < numUnits; unitId++ {
-			bypass, _ := chain.GetBypass(unitId)
-			unitType, _ := chain.UnitType(unitId)
-			unitTypeString := unitTypes[unitType]
-			discreteParams := []persistence.DiscreteParam{}
-			numericParams := []persistence.NumericParam{}
-			params, _ := chain.Parameters(unitId)
+			reduction, metering, err := chain.GainReduction(unitId)
 
 			/*
-			 * Iterate over all parameters.
+			 * Check if unit reports its gain reduction.
 			 */
-			for _, param := range params {
-				paramName := param.Name
-				paramType := param.Type
+			if err == nil && metering {
 
 				/*
-				 * Handle both discrete and numeric parameters.
+				 * Fill in web gain reduction data structure.
 				 */
-				switch paramType {
-				case effects.PARAMETER_TYPE_DISCRETE:
-					idx := param.DiscreteValueIndex
-					discreteValues := param.DiscreteValues
-					discreteValue := discreteValues[idx]
+				r := webGainReductionStruct{
+					Chain:     chainId,
+					Unit:      unitId,
+					Reduction: reduction,
+				}
 
-					/*
-					 * Create description for discrete parameter.
-					 */
-					discreteParam := persistence.DiscreteParam{
-						Key:   paramName,
-						Value: discreteValue,
-					}
+				gainReductions = append(gainReductions, r)
+			}
 
-					discreteParams = append(discreteParams, discreteParam)
-				case effects.PARAMETER_TYPE_NUMERIC:
-					numericValue := param.NumericValue
+		}
 
-					/*
-					 * Create description for numeric parameter.
-					 */
-					numericParam := persistence.NumericParam{
-						Key:   paramName,
-						Value: numericValue,
-					}
+	}
 
-					numericParams = append(numericParams, numericParam)
-				}
+	clipping := []webClipStruct{}
 
-			}
+	/*
+	 * Find all units whose output clipped since the last analysis.
+	 */
+	for chainId, chain := range this.chains() {
+		numUnits := chain.Length()
+
+		/*
+		 * Query each unit in the chain.
+		 */
+		for unitId := 0; unitId < numUnits; unitId++ {
+			clipped, err := chain.Clipped(unitId)
 
 			/*
-			 * Create data structure describing a signal processing unit.
+			 * Check if output of unit clipped.
 			 */
-			unit := persistence.Unit{
-				Type:           unitTypeString,
-				Bypass:         bypass,
-				DiscreteParams: discreteParams,
-				NumericParams:  numericParams,
-			}
-
-			units[unitId] = unit
-		}
+			if err == nil && clipped {
 
-		chainId32 := uint32(chainId)
-		azimuth, _ := spat.GetAzimuth(chainId32)
-		distance, _ := spat.GetDistance(chainId32)
-		level, _ := spat.GetLevel(chainId32)
+				/*
+				 * Fill in web clip data structure.
+				 */
+				c := webClipStruct{
+					Chain: chainId,
+					Unit:  unitId,
+				}
 
-		/*
-		 * Create data structure describing spatializer settings for this channel.
-		 */
-		pSpat := persistence.Spatializer{
-			Azimuth:  azimuth,
-			Distance: distance,
-			Level:    level,
-		}
+				clipping = append(clipping, c)
+			}
 
-		/*
-		 * Create data structure describing audio channel.
-		 */
-		channel := persistence.Channel{
-			Units:       units,
-			Spatializer: pSpat,
 		}
 
-		channels = append(channels, channel)
 	}
 
-	metrMasterOutput := this.metrMasterOutput
-	metr := this.metr
-	beatsPerPeriod := uint32(0)
-	speed := uint32(0)
-	tickSound := ""
-	tockSound := ""
+	correlation := webCorrelationStruct{}
+	correlationMeter := this.correlationMeter
 
 	/*
-	 * Check if we have a metronome.
+	 * Check if there is a correlation meter.
 	 */
-	if metr != nil {
-		beatsPerPeriod = metr.BeatsPerPeriod()
-		speed = metr.Speed()
-		tickSound, _ = metr.Tick()
-		tockSound, _ = metr.Tock()
-	}
+	if correlationMeter != nil {
+		correlationResult := correlationMeter.Analyze()
+		correlationHundredths := 100.0 * correlationResult.Correlation()
+		correlationRounded := math.Round(correlationHundredths)
+		midTenths := 10.0 * correlationResult.Mid()
+		midRounded := math.Round(midTenths)
+		sideTenths := 10.0 * correlationResult.Side()
+		sideRounded := math.Round(sideTenths)
+
+		/*
+		 * Fill in web correlation data structure.
+		 */
+		correlation = webCorrelationStruct{
+			Correlation: 0.01 * correlationRounded,
+			Mid:         0.1 * midRounded,
+			Side:        0.1 * sideRounded,
+		}
 
-	/*
-	 * Create metronome information.
-	 */
-	metrP := persistence.Metronome{
-		Master:         metrMasterOutput,
-		BeatsPerPeriod: beatsPerPeriod,
-		Speed:          speed,
-		TickSound:      tickSound,
-		TockSound:      tockSound,
 	}
 
 	/*
-	 * Create configuration.
+	 * Create level meters result structure.
 	 */
-	configuration := persistence.Configuration{
-		FileFormat:      fileFormat,
-		FramesPerPeriod: framesPerPeriod,
-		Channels:        channels,
-		Metronome:       metrP,
+	result := webLevelMetersResultStruct{
+		DSPLoad:       dspLoad32,
+		Channels:      results,
+		GainReduction: gainReductions,
+		Clipping:      clipping,
+		Xruns:         this.xruns(),
+		Correlation:   correlation,
 	}
 
-	mimeType, buffer := this.createJSON(configuration)
-	creationTime := time.Now()
-	timeStamp := creationTime.Format(ARCHIVE_TIME_STAMP)
-	fileName := fmt.Sprintf("patch-%s.json", timeStamp)
-	disposition := fmt.Sprintf("attachment; filename=\"%s\"", fileName)
+	mimeType, buffer := this.createJSON(result)
 
 	/*
 	 * Create HTTP response.
 	 */
 	response := webserver.HttpResponse{
-		Header: map[string]string{
-			"Content-type":        mimeType,
-			"Content-disposition": disposition,
-		},
-		Body: buffer,
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
 	}
 
 	return response
 }
 
 /*
- * Cause processing of a file in batch mode.
+ * Returns the DSP load as reported by the hardware interface, rounded to
+ * full percent.
  */
-func (this *controllerStruct) processHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	this.running = false
+func (this *controllerStruct) dspLoad() int32 {
+	dspLoad := hwio.DSPLoad()
+	dspLoad64 := float64(dspLoad)
+	dspLoadRounded := math.Round(dspLoad64)
+	dspLoad32 := int32(dspLoadRounded)
+	return dspLoad32
+}
+
+/*
+ * Collects the buffer over- and underruns reported by the hardware interface.
+ */
+func (this *controllerStruct) xruns() webXrunsStruct {
+	lastXrun := hwio.LastXrun()
+	lastXrunString := ""
+	secondsAgo := 0.0
 
 	/*
-	 * Indicate success.
+	 * Only report a time if an xrun occured at all.
+	 */
+	if !lastXrun.IsZero() {
+		lastXrunString = lastXrun.Format(time.RFC3339Nano)
+		elapsed := time.Since(lastXrun)
+		secondsAgo = elapsed.Seconds()
+	}
+
+	/*
+	 * Create web xruns data structure.
+	 */
+	result := webXrunsStruct{
+		DSPLoad:    this.dspLoad(),
+		Xruns:      hwio.Xruns(),
+		Underruns:  hwio.Underruns(),
+		LastXrun:   lastXrunString,
+		SecondsAgo: secondsAgo,
+	}
+
+	return result
+}
+
+/*
+ * Returns the number of buffer over- and underruns along with the time of the
+ * last one, so that audio glitches can be correlated with the DSP load.
+ */
+func (this *controllerStruct) getXrunsHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	result := this.xruns()
+	mimeType, buffer := this.createJSON(result)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Returns the magnitude spectra of the channels.
+ *
+ * Optionally, a single channel, the size of the Fourier transform and the
+ * window function can be selected.
+ */
+func (this *controllerStruct) getSpectrumAnalysisHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	params := request.Params
+	channelString := params["channel"]
+	fftSizeString := params["fft_size"]
+	window := params["window"]
+	analyzer := this.spectrumAnalyzer
+	channelCount := analyzer.ChannelCount()
+	lBound := uint32(0)
+	uBound := channelCount
+	fftSize := uint32(spectrum.DEFAULT_FFT_SIZE)
+	err := error(nil)
+
+	/*
+	 * The channel is optional.
+	 */
+	if channelString != "" {
+		channel64, errChannel := strconv.ParseUint(channelString, 10, 32)
+		channel := uint32(channel64)
+
+		/*
+		 * Check if channel is valid.
+		 */
+		if errChannel != nil {
+			err = fmt.Errorf("%s", "Failed to decode channel.")
+		} else if channel >= channelCount {
+			err = fmt.Errorf("No channel %d.", channel)
+		} else {
+			lBound = channel
+			uBound = channel + 1
+		}
+
+	}
+
+	/*
+	 * The size of the transform is optional.
+	 */
+	if fftSizeString != "" {
+		fftSize64, errSize := strconv.ParseUint(fftSizeString, 10, 32)
+
+		/*
+		 * Check if size of transform could be parsed.
+		 */
+		if errSize != nil {
+			err = fmt.Errorf("%s", "Failed to decode size of transform.")
+		} else {
+			fftSize = uint32(fftSize64)
+		}
+
+	}
+
+	/*
+	 * The window function is optional.
+	 */
+	if window == "" {
+		window = spectrum.DEFAULT_WINDOW
+	}
+
+	sampleRate := uint32(0)
+	binWidth := float64(0.0)
+	channels := []webSpectrumStruct{}
+
+	/*
+	 * Analyze each selected channel.
+	 */
+	for channelId := lBound; (err == nil) && (channelId < uBound); channelId++ {
+		channelName, errName := analyzer.ChannelName(channelId)
+		result, errAnalyze := analyzer.Analyze(channelId, fftSize, window)
+
+		/*
+		 * Check if spectral analysis was successful.
+		 */
+		if errName != nil {
+			err = errName
+		} else if errAnalyze != nil {
+			err = errAnalyze
+		} else {
+			sampleRate = result.SampleRate()
+			binWidth = result.BinWidth()
+			magnitudes := result.Magnitudes()
+
+			/*
+			 * Round magnitudes to a tenth of a decibel.
+			 */
+			for i, magnitude := range magnitudes {
+				magnitudeTenths := 10.0 * magnitude
+				magnitudeRounded := math.Round(magnitudeTenths)
+				magnitudes[i] = 0.1 * magnitudeRounded
+			}
+
+			/*
+			 * Create spectrum structure.
+			 */
+			channel := webSpectrumStruct{
+				ChannelName: channelName,
+				Magnitudes:  magnitudes,
+			}
+
+			channels = append(channels, channel)
+		}
+
+	}
+
+	mimeType := ""
+	buffer := []byte{}
+
+	/*
+	 * Check if spectral analysis was successful.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse := webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+		mimeType, buffer = this.createJSON(webResponse)
+	} else {
+
+		/*
+		 * Create spectrum analysis result structure.
+		 */
+		result := webSpectrumAnalysisStruct{
+			SampleRate: sampleRate,
+			FFTSize:    fftSize,
+			Window:     window,
+			BinWidth:   binWidth,
+			Channels:   channels,
+		}
+
+		mimeType, buffer = this.createJSON(result)
+	}
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Returns the frequency response of an effects unit or an entire signal chain,
+ * so that it can be plotted.
+ *
+ * The response is evaluated at logarithmically spaced frequencies between
+ * 20 Hz and 20 kHz or the Nyquist frequency, whichever is lower. If no unit
+ * is selected, the response of the entire chain is returned. Optionally, the
+ * number of points can be selected.
+ */
+func (this *controllerStruct) getFrequencyResponseHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	params := request.Params
+	chainIdString := params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := params["unit"]
+	unitId := int(-1)
+	pointsString := params["points"]
+	points := uint64(RESPONSE_DEFAULT_POINTS)
+	sampleRate := this.sampleRate
+	err := error(nil)
+
+	/*
+	 * Check if chain ID is valid.
+	 */
+	if errChainId != nil {
+		err = fmt.Errorf("%s", "Failed to decode chain ID.")
+	}
+
+	/*
+	 * The unit is optional.
+	 */
+	if unitIdString != "" {
+		unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+
+		/*
+		 * Check if unit ID is valid.
+		 */
+		if errUnitId != nil {
+			err = fmt.Errorf("%s", "Failed to decode unit ID.")
+		} else {
+			unitId = int(unitId64)
+		}
+
+	}
+
+	/*
+	 * The number of points is optional.
+	 */
+	if pointsString != "" {
+		points64, errPoints := strconv.ParseUint(pointsString, 10, 32)
+
+		/*
+		 * Check if number of points is valid.
+		 */
+		if errPoints != nil {
+			err = fmt.Errorf("%s", "Failed to decode number of points.")
+		} else if (points64 < 2) || (points64 > RESPONSE_MAX_POINTS) {
+			err = fmt.Errorf("Number of points must be between %d and %d.", 2, RESPONSE_MAX_POINTS)
+		} else {
+			points = points64
+		}
+
+	}
+
+	frequencies := make([]float64, points)
+	magnitudes := make([]float64, points)
+	phases := make([]float64, points)
+
+	/*
+	 * Obtain the frequency response of the unit or chain.
+	 */
+	if err == nil {
+		chainId := int(chainId64)
+		fx := this.chains()
+		nChains := len(fx)
+		sampleRateFloat := float64(sampleRate)
+		lowFrequency := float64(RESPONSE_MIN_FREQUENCY)
+		highFrequency := math.Min(RESPONSE_MAX_FREQUENCY, 0.5*sampleRateFloat)
+		ratio := highFrequency / lowFrequency
+		lastIdx := float64(points - 1)
+
+		/*
+		 * Space the frequencies logarithmically.
+		 */
+		for i := range frequencies {
+			iFloat := float64(i)
+			exponent := iFloat / lastIdx
+			frequencies[i] = lowFrequency * math.Pow(ratio, exponent)
+		}
+
+		/*
+		 * Check if chain ID is out of range.
+		 */
+		if (chainId < 0) || (chainId >= nChains) {
+			err = fmt.Errorf("%s", "Chain ID out of range.")
+		} else {
+			chain := fx[chainId]
+			response := []complex128(nil)
+			errResponse := error(nil)
+
+			/*
+			 * Select the response of a single unit or the entire chain.
+			 */
+			if unitId >= 0 {
+				response, errResponse = chain.FrequencyResponse(unitId, frequencies, sampleRate)
+			} else {
+				response, errResponse = chain.ChainResponse(frequencies, sampleRate)
+			}
+
+			/*
+			 * Check if frequency response could be obtained.
+			 */
+			if errResponse != nil {
+				err = errResponse
+			} else {
+
+				/*
+				 * Convert the response into magnitude (in decibels)
+				 * and phase (in degrees), rounded to a tenth.
+				 */
+				for i, value := range response {
+					magnitudeLinear := cmplx.Abs(value)
+					magnitude := 20.0 * math.Log10(magnitudeLinear)
+					magnitudeNaN := math.IsNaN(magnitude)
+
+					/*
+					 * Make sure the magnitude can be encoded.
+					 */
+					if magnitudeNaN || magnitude < RESPONSE_MIN_LEVEL {
+						magnitude = RESPONSE_MIN_LEVEL
+					}
+
+					magnitudeTenths := 10.0 * magnitude
+					magnitudeRounded := math.Round(magnitudeTenths)
+					magnitudes[i] = 0.1 * magnitudeRounded
+					phase := cmplx.Phase(value)
+					phaseDegrees := (180.0 * phase) / math.Pi
+					phaseTenths := 10.0 * phaseDegrees
+					phaseRounded := math.Round(phaseTenths)
+					phases[i] = 0.1 * phaseRounded
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType := ""
+	buffer := []byte{}
+
+	/*
+	 * Check if frequency response was obtained.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse := webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+		mimeType, buffer = this.createJSON(webResponse)
+	} else {
+
+		/*
+		 * Create frequency response result structure.
+		 */
+		result := webFrequencyResponseStruct{
+			SampleRate:  sampleRate,
+			Frequencies: frequencies,
+			Magnitudes:  magnitudes,
+			Phases:      phases,
+		}
+
+		mimeType, buffer = this.createJSON(result)
+	}
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Derives the name of the file an uploaded impulse response is stored in from
+ * its name, replacing all characters except letters, digits, dashes and
+ * underscores.
+ */
+func impulseResponseFileName(name string) string {
+
+	/*
+	 * Replace characters which are not safe in file names.
+	 */
+	mapping := func(r rune) rune {
+
+		/*
+		 * Check if character is safe.
+		 */
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		} else {
+			return '_'
+		}
+
+	}
+
+	base := strings.Map(mapping, name)
+	fileName := base + ".wav"
+	return fileName
+}
+
+/*
+ * Stores an impulse response as a wave file next to the descriptor file and
+ * adds it to the descriptor file.
+ */
+func (this *controllerStruct) writeImpulseResponse(name string, compensation int32, samples []float64, sampleRate uint32) error {
+	descriptor := this.config.ImpulseResponses
+	directory := filepath.Dir(descriptor)
+	directory = filepath.Join(directory, UPLOAD_IR_DIRECTORY)
+	err := os.MkdirAll(directory, UPLOAD_IR_DIRECTORY_MODE)
+
+	/*
+	 * Check if directory could be created.
+	 */
+	if err != nil {
+		return fmt.Errorf("Failed to create directory '%s'.", directory)
+	} else {
+		fileName := impulseResponseFileName(name)
+		output := filepath.Join(directory, fileName)
+		outputFile, err := createOutputFile(output, []int{0}, sampleRate, wave.CONTAINER_RIFF, wave.AUDIO_IEEE_FLOAT, CAPTURE_BIT_DEPTH)
+
+		/*
+		 * Check if output file was created.
+		 */
+		if err != nil {
+			return err
+		} else {
+
+			/*
+			 * The impulse response is a single channel.
+			 */
+			channels := [][]float64{
+				samples,
+			}
+
+			errWrite := outputFile.writer.Write(channels)
+			outputFiles := []*outputFileStruct{outputFile}
+			errClose := closeOutputFiles(outputFiles)
+
+			/*
+			 * Check if impulse response was written.
+			 */
+			if errWrite != nil {
+				msg := errWrite.Error()
+				return fmt.Errorf("Failed to write impulse response '%s': %s", output, msg)
+			} else if errClose != nil {
+				return errClose
+			} else {
+				err = filter.AddDescriptor(descriptor, name, output, compensation)
+				return err
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Stores an uploaded impulse response as a wave file next to the descriptor
+ * file and adds it to the descriptor file.
+ *
+ * Only the first channel of the wave file is kept.
+ */
+func (this *controllerStruct) storeImpulseResponse(name string, compensation int32, content []byte) error {
+	file, err := wave.FromBuffer(content)
+
+	/*
+	 * Check if wave file could be decoded.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return fmt.Errorf("Failed to decode impulse response: %s", msg)
+	} else {
+		channel, err := file.Channel(0)
+
+		/*
+		 * Check if wave file has a channel.
+		 */
+		if err != nil {
+			return fmt.Errorf("%s", "Impulse response contains no audio channel.")
+		} else {
+			samples := channel.Floats()
+			sampleRate := file.SampleRate()
+			err = this.writeImpulseResponse(name, compensation, samples, sampleRate)
+			return err
+		}
+
+	}
+
+}
+
+/*
+ * Stores an uploaded impulse response and reloads the impulse responses, so
+ * that it becomes available to power amps and convolution reverbs.
+ */
+func (this *controllerStruct) uploadImpulseResponseHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	name := request.Params["name"]
+	name = strings.TrimSpace(name)
+	compensationString := request.Params["compensation"]
+	compensation64 := int64(0)
+	errCompensation := error(nil)
+
+	/*
+	 * The gain compensation is optional.
+	 */
+	if compensationString != "" {
+		compensation64, errCompensation = strconv.ParseInt(compensationString, 10, 32)
+	}
+
+	compensation := int32(compensation64)
+	irFiles := request.Files["irfile"]
+	numIrFiles := len(irFiles)
+	reason := ""
+
+	/*
+	 * Make sure that a name and exactly one impulse response are sent.
+	 */
+	if name == "" {
+		reason = "No name given for impulse response."
+	} else if errCompensation != nil {
+		reason = "Failed to decode gain compensation."
+	} else if numIrFiles == 0 {
+		reason = "No impulse response sent in request."
+	} else if numIrFiles != 1 {
+		reason = "Multiple impulse responses sent in request."
+	} else {
+		irFile := irFiles[0]
+		irBytes, err := io.ReadAll(irFile)
+
+		/*
+		 * Check if impulse response could be read and stored.
+		 */
+		if err != nil {
+			reason = "Failed to read impulse response."
+		} else {
+			err = this.storeImpulseResponse(name, compensation, irBytes)
+
+			/*
+			 * Check if impulse response was stored.
+			 */
+			if err != nil {
+				msg := err.Error()
+				reason = fmt.Sprintf("Failed to store impulse response: %s", msg)
+			}
+
+		}
+
+	}
+
+	/*
+	 * Reload impulse responses if the new one was stored.
+	 */
+	if reason == "" {
+		return this.reloadImpulseResponsesHandler(request)
+	} else {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse := webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+		mimeType, buffer := this.createJSON(webResponse)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response := webserver.HttpResponse{
+			Header: map[string]string{"Content-type": mimeType},
+			Body:   buffer,
+		}
+
+		return response
+	}
+
+}
+
+/*
+ * Sets the options an impulse response is loaded with, i. e. its maximum
+ * length, the length of its fade-out and whether it is converted to minimum
+ * phase, and reloads the impulse responses.
+ *
+ * Options which are not given keep their current value.
+ */
+func (this *controllerStruct) setImpulseResponseOptionsHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	name := request.Params["name"]
+	maxLengthString := request.Params["max-length"]
+	fadeOutString := request.Params["fade-out"]
+	minimumPhaseString := request.Params["minimum-phase"]
+	irs := this.impulseResponses
+	infos := irs.Infos()
+	found := false
+	info := filter.Info{}
+
+	/*
+	 * Find the description of the impulse response.
+	 */
+	for _, currentInfo := range infos {
+
+		/*
+		 * Check if we found the impulse response.
+		 */
+		if currentInfo.Name == name {
+			info = currentInfo
+			found = true
+		}
+
+	}
+
+	maxLength64 := uint64(info.MaxLength)
+	fadeOut64 := uint64(info.FadeOut)
+	minimumPhase := info.MinimumPhase
+	errMaxLength := error(nil)
+	errFadeOut := error(nil)
+	errMinimumPhase := error(nil)
+
+	/*
+	 * Decode the maximum length if it is given.
+	 */
+	if maxLengthString != "" {
+		maxLength64, errMaxLength = strconv.ParseUint(maxLengthString, 10, 32)
+	}
+
+	/*
+	 * Decode the length of the fade-out if it is given.
+	 */
+	if fadeOutString != "" {
+		fadeOut64, errFadeOut = strconv.ParseUint(fadeOutString, 10, 32)
+	}
+
+	/*
+	 * Decode the minimum-phase conversion if it is given.
+	 */
+	if minimumPhaseString != "" {
+		minimumPhase, errMinimumPhase = strconv.ParseBool(minimumPhaseString)
+	}
+
+	reason := ""
+
+	/*
+	 * Check if parameters are valid.
+	 */
+	if !found {
+		reason = fmt.Sprintf("No impulse response '%s'.", name)
+	} else if errMaxLength != nil {
+		reason = "Failed to decode maximum length."
+	} else if errFadeOut != nil {
+		reason = "Failed to decode fade-out."
+	} else if errMinimumPhase != nil {
+		reason = "Failed to decode boolean value."
+	} else {
+		descriptor := this.config.ImpulseResponses
+		maxLength := uint32(maxLength64)
+		fadeOut := uint32(fadeOut64)
+		err := filter.SetOptions(descriptor, name, maxLength, fadeOut, minimumPhase)
+
+		/*
+		 * Check if options were stored.
+		 */
+		if err != nil {
+			msg := err.Error()
+			reason = fmt.Sprintf("Failed to set impulse response options: %s", msg)
+		}
+
+	}
+
+	/*
+	 * Reload impulse responses if the options were stored.
+	 */
+	if reason == "" {
+		return this.reloadImpulseResponsesHandler(request)
+	} else {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse := webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+		mimeType, buffer := this.createJSON(webResponse)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response := webserver.HttpResponse{
+			Header: map[string]string{"Content-type": mimeType},
+			Body:   buffer,
+		}
+
+		return response
+	}
+
+}
+
+/*
+ * Returns a list of all supported types of effects units.
+ */
+func (this *controllerStruct) getUnitTypesHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	unitTypes := effects.UnitTypes()
+	mimeType, buffer := this.createJSON(unitTypes)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Moves a unit down in a rack.
+ */
+func (this *controllerStruct) moveDownHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := request.Params["unit"]
+	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain and unit ID are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errUnitId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode unit ID.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		unitId := int(unitId64)
+		fx := this.chains()
+		nChains := len(fx)
+
+		/*
+		 * Check if chain ID is out of range.
+		 */
+		if (chainId < 0) || (chainId >= nChains) {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Chain ID out of range.",
+			}
+
+		} else {
+			this.haltMorph()
+			err := fx[chainId].MoveDown(unitId)
+
+			/*
+			 * The automation lanes follow the unit.
+			 */
+			if err == nil {
+				unitIdNext := unitId + 1
+				this.swapAutomation(chainId, unitId, unitIdNext)
+			}
+
+			/*
+			 * Check if unit was successfully moved downwards.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Moves a unit up in a rack.
+ */
+func (this *controllerStruct) moveUpHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := request.Params["unit"]
+	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain and unit ID are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errUnitId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode unit ID.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		unitId := int(unitId64)
+		fx := this.chains()
+		nChains := len(fx)
+
+		/*
+		 * Check if chain ID is out of range.
+		 */
+		if (chainId < 0) || (chainId >= nChains) {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Chain ID out of range.",
+			}
+
+		} else {
+			this.haltMorph()
+			err := fx[chainId].MoveUp(unitId)
+
+			/*
+			 * The automation lanes follow the unit.
+			 */
+			if err == nil {
+				unitIdPrevious := unitId - 1
+				this.swapAutomation(chainId, unitId, unitIdPrevious)
+			}
+
+			/*
+			 * Check if unit was successfully moved upwards.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Replaces all units in a signal chain with units restored from a patch file.
+ */
+func (this *controllerStruct) restoreChain(signalChain signal.Chain, units []persistence.Unit) {
+	diag := diagnosticsStruct{}
+	this.restoreUnits(signalChain, units, "Chain", &diag)
+}
+
+/*
+ * Replaces all units in a signal chain with units restored from a patch file
+ * and records the units and settings which could not be restored.
+ *
+ * The location describes the chain in the messages, e. g. 'Channel 0'.
+ */
+func (this *controllerStruct) restoreUnits(signalChain signal.Chain, units []persistence.Unit, location string, diag *diagnosticsStruct) {
+	unitTypes := effects.UnitTypes()
+	numUnits := signalChain.Length()
+
+	/*
+	 * Remove all units from the signal chain.
+	 */
+	for numUnits > 0 {
+		unitId := numUnits - 1
+		signalChain.RemoveUnit(unitId)
+		numUnits = signalChain.Length()
+	}
+
+	/*
+	 * Restore each processing unit.
+	 */
+	for id, unit := range units {
+		unitType := unit.Type
+		unitTypeId := int(-1)
+		unitTypeFound := false
+		unitLocation := fmt.Sprintf("%s, unit %d", location, id)
+
+		/*
+		 * Search for the right unit type.
+		 */
+		for id, currentUnitType := range unitTypes {
+
+			/*
+			 * If we found the correct unit type,
+			 * store its ID.
+			 */
+			if unitType == currentUnitType {
+				unitTypeId = id
+				unitTypeFound = true
+			}
+
+		}
+
+		/*
+		 * If we found the unit type, restore the unit.
+		 */
+		if !unitTypeFound {
+			diag.fail("%s: Unknown unit type '%s'.", unitLocation, unitType)
+		} else {
+			lastUnitId, err := signalChain.AppendUnit(unitTypeId)
+
+			/*
+			 * Check if unit was created.
+			 */
+			if err != nil {
+				msg := err.Error()
+				diag.fail("%s: %s", unitLocation, msg)
+			} else {
+
+				/*
+				 * Composite units hold units instead of parameters.
+				 */
+				if unitTypeId == effects.UNIT_COMPOSITE {
+					composite, err := signalChain.Composite(lastUnitId)
+
+					/*
+					 * Check if composite unit was created.
+					 */
+					if err == nil {
+						composite.SetName(unit.Name)
+						innerChain := composite.Units()
+						this.restoreUnits(innerChain, unit.Units, unitLocation, diag)
+					}
+
+				}
+
+				errs := []error{}
+
+				/*
+				 * Restore each discrete parameter.
+				 */
+				for _, param := range unit.DiscreteParams {
+					key := param.Key
+					value := param.Value
+					err := signalChain.SetDiscreteValue(lastUnitId, key, value)
+					errs = append(errs, err)
+				}
+
+				/*
+				 * Restore each numeric parameter.
+				 */
+				for _, param := range unit.NumericParams {
+					key := param.Key
+					value := param.Value
+					err := signalChain.SetNumericValue(lastUnitId, key, value)
+					errs = append(errs, err)
+				}
+
+				inputTrim := unit.InputTrim
+				err = signalChain.SetInputTrim(lastUnitId, inputTrim)
+				errs = append(errs, err)
+				outputLevel := unit.OutputLevel
+				err = signalChain.SetOutputLevel(lastUnitId, outputLevel)
+				errs = append(errs, err)
+				err = signalChain.SetLabel(lastUnitId, unit.Label)
+				errs = append(errs, err)
+				err = signalChain.SetNote(lastUnitId, unit.Note)
+				errs = append(errs, err)
+				bypass := unit.Bypass
+				signalChain.SetBypass(lastUnitId, bypass)
+
+				/*
+				 * Settings which could not be restored are
+				 * ignored.
+				 */
+				for _, err := range errs {
+
+					/*
+					 * Check if setting was restored.
+					 */
+					if err != nil {
+						msg := err.Error()
+						diag.warn("%s: %s", unitLocation, msg)
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Restores a configuration described by a patch.
+ */
+func (this *controllerStruct) restoreConfiguration(configuration persistence.Configuration) error {
+	fileFormat := configuration.FileFormat
+	fileType := fileFormat.Type
+	fileVersion := fileFormat.Version
+	majorVersion := fileVersion.Major
+	minorVersion := fileVersion.Minor
+
+	/*
+	 * Ensure that file format is compatible.
+	 */
+	if fileType != "patch" {
+		return fmt.Errorf("%s", "File is not a patch file.")
+	} else if majorVersion != 1 || minorVersion < 0 {
+		return fmt.Errorf("%s", "Incompatible version of file format.")
+	} else {
+
+		/*
+		 * If we are bound to a hardware interface, restore frames per period.
+		 */
+		if this.binding != nil {
+			framesPerPeriod := configuration.FramesPerPeriod
+			hwio.SetFramesPerPeriod(framesPerPeriod)
+			this.setBlockSize(framesPerPeriod)
+		}
+
+		channels := configuration.Channels
+		numChannels := len(channels)
+		signalChains := this.effects
+		numChains := len(signalChains)
+		errResult := error(nil)
+
+		/*
+		 * Verify that the configuration file does not contain
+		 * more channels than we have.
+		 */
+		if numChannels > numChains {
+			errResult = fmt.Errorf("WARNING: Restored file contains %d channels, but we currently have only %d. Restore may be incomplete.", numChannels, numChains)
+			channels = channels[0:numChains]
+		}
+
+		spat := this.spat
+
+		/*
+		 * Restore each channel.
+		 */
+		for channelId, channel := range channels {
+			signalChain := signalChains[channelId]
+			units := channel.Units
+			this.restoreChain(signalChain, units)
+
+			/*
+			 * The name and color of the channel stored in the patch.
+			 */
+			metadata := channelMetadataStruct{
+				name:        channel.Name,
+				color:       channel.Color,
+				click:       channel.Click,
+				channelTrim: channel.ChannelTrim,
+				reampLevel:  channel.ReampLevel,
+			}
+
+			defaultMetadata := this.defaultChannelMetadata[channelId]
+
+			/*
+			 * Patches without a channel name or color, like those
+			 * created by older versions, keep the configured ones.
+			 */
+			if metadata.name == "" {
+				metadata.name = defaultMetadata.name
+			}
+
+			/*
+			 * Also fall back to the configured color if the color
+			 * is not in hexadecimal notation.
+			 */
+			if !isColor(metadata.color) {
+				metadata.color = defaultMetadata.color
+			}
+
+			/*
+			 * Keep the click level within limits.
+			 */
+			if !(metadata.click >= 0.0) {
+				metadata.click = 0.0
+			} else if metadata.click > 1.0 {
+				metadata.click = 1.0
+			}
+
+			/*
+			 * Keep the channel trim within limits.
+			 */
+			if metadata.channelTrim < CHANNEL_TRIM_MIN {
+				metadata.channelTrim = CHANNEL_TRIM_MIN
+			} else if metadata.channelTrim > CHANNEL_TRIM_MAX {
+				metadata.channelTrim = CHANNEL_TRIM_MAX
+			}
+
+			/*
+			 * Keep the re-amping level within limits.
+			 */
+			if metadata.reampLevel < REAMP_LEVEL_MIN {
+				metadata.reampLevel = REAMP_LEVEL_MIN
+			} else if metadata.reampLevel > REAMP_LEVEL_MAX {
+				metadata.reampLevel = REAMP_LEVEL_MAX
+			}
+
+			/*
+			 * Replace the settings of the channel.
+			 */
+			this.changeMix(func(mix *mixSnapshotStruct) {
+				mix.channels[channelId] = metadata
+			})
+
+			channelId32 := uint32(channelId)
+			persistedSpat := channel.Spatializer
+			azimuth := persistedSpat.Azimuth
+			distance := persistedSpat.Distance
+			level := persistedSpat.Level
+			spat.SetAzimuth(channelId32, azimuth)
+			spat.SetDistance(channelId32, distance)
+			spat.SetLevel(channelId32, level)
+			spat.SetMute(channelId32, persistedSpat.Mute)
+			spat.SetSolo(channelId32, persistedSpat.Solo)
+
+			/*
+			 * Restore the send level for each aux bus.
+			 */
+			for busId, send := range persistedSpat.Sends {
+				busId32 := uint32(busId)
+				spat.SetSend(channelId32, busId32, send)
+			}
+
+		}
+
+		buses := configuration.Buses
+		busChains := this.buses
+		numBusChains := len(busChains)
+
+		/*
+		 * Restore each aux bus.
+		 */
+		for busId, bus := range buses {
+
+			/*
+			 * Only restore buses which exist.
+			 */
+			if busId < numBusChains {
+				busChain := busChains[busId]
+				units := bus.Units
+				this.restoreChain(busChain, units)
+				busId32 := uint32(busId)
+				returnLevel := bus.Return
+				spat.SetReturn(busId32, returnLevel)
+			}
+
+		}
+
+		this.updateLevelMeterNames()
+		persistedMetr := configuration.Metronome
+		this.restoreMetronome(persistedMetr)
+		persistedMaster := configuration.Master
+		this.restoreMaster(persistedMaster)
+		persistedAutomation := configuration.Automation
+		this.restoreAutomation(persistedAutomation)
+		return errResult
+	}
+
+}
+
+/*
+ * Restores the configuration stored in a patch file.
+ *
+ * The patch is checked before anything is changed. If it contains any errors,
+ * nothing is restored and all errors are reported at once.
+ */
+func (this *controllerStruct) restorePatch(patchBytes []byte) error {
+	configuration, diag := this.checkPatchBytes(patchBytes)
+
+	/*
+	 * Check if the patch can be restored.
+	 */
+	if len(diag.errors) > 0 {
+		return patchError(diag)
+	} else {
+		this.haltMorph()
+		err := this.restoreConfiguration(configuration)
+		layout := configuration.SpeakerLayout
+
+		/*
+		 * Restore the speaker layout stored in the patch, if any,
+		 * before the wiring, which may refer to its ports.
+		 */
+		if (err == nil) && (layout != "") {
+			err = this.setSpeakerLayout(layout)
+		}
+
+		/*
+		 * Restore the wiring stored in the patch, if any, unless we
+		 * are processing files in batch mode.
+		 */
+		if (err == nil) && (configuration.Connections != nil) && (this.binding != nil) {
+			this.restoreConnections(configuration.Connections)
+		}
+
+		return err
+	}
+
+}
+
+/*
+ * Restore (import) current configuration from JSON file.
+ */
+func (this *controllerStruct) persistenceRestoreHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	patchFiles := request.Files["patchfile"]
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Make sure that patch files are not nil.
+	 */
+	if patchFiles == nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Field 'patchfile' not defined as a multipart field.",
+		}
+
+	} else {
+		numPatchFiles := len(patchFiles)
+
+		/*
+		 * Make sure that exactly one patch file is sent in request.
+		 */
+		if numPatchFiles == 0 {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "No patch file sent in request.",
+			}
+
+		} else if numPatchFiles != 1 {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Multiple patch files sent in request.",
+			}
+
+		} else {
+			patchFile := patchFiles[0]
+			patchBytes, err := io.ReadAll(patchFile)
+
+			/*
+			 * Check if patch file could be successfully read.
+			 */
+			if err != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to read patch file.",
+				}
+
+			} else {
+				err := this.restorePatch(patchBytes)
+
+				/*
+				 * Check if patch was restored successfully.
+				 */
+				if err != nil {
+					reason := err.Error()
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  reason,
+					}
+
+				} else {
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Creates a description of all units in a signal chain for a patch file.
+ */
+func (this *controllerStruct) persistChain(chain signal.Chain) []persistence.Unit {
+	unitTypes := effects.UnitTypes()
+	numUnits := chain.Length()
+	units := make([]persistence.Unit, numUnits)
+
+	/*
+	 * Iterate over all units in the current chain.
+	 */
+	for unitId := 0; unitId < numUnits; unitId++ {
+		bypass, _ := chain.GetBypass(unitId)
+		inputTrim, _ := chain.GetInputTrim(unitId)
+		outputLevel, _ := chain.GetOutputLevel(unitId)
+		label, _ := chain.GetLabel(unitId)
+		note, _ := chain.GetNote(unitId)
+		unitType, _ := chain.UnitType(unitId)
+		unitTypeString := unitTypes[unitType]
+		discreteParams := []persistence.DiscreteParam{}
+		numericParams := []persistence.NumericParam{}
+		params, _ := chain.Parameters(unitId)
+		name := ""
+		innerUnits := []persistence.Unit(nil)
+
+		/*
+		 * Composite units hold units instead of parameters.
+		 */
+		if unitType == effects.UNIT_COMPOSITE {
+			composite, err := chain.Composite(unitId)
+
+			/*
+			 * Check if composite unit still exists.
+			 */
+			if err == nil {
+				name = composite.Name()
+				innerChain := composite.Units()
+				innerUnits = this.persistChain(innerChain)
+			}
+
+			params = []effects.Parameter{}
+		}
+
+		/*
+		 * Iterate over all parameters.
+		 */
+		for _, param := range params {
+			paramName := param.Name
+			paramType := param.Type
+
+			/*
+			 * Handle both discrete and numeric parameters.
+			 */
+			switch paramType {
+			case effects.PARAMETER_TYPE_DISCRETE:
+				idx := param.DiscreteValueIndex
+				discreteValues := param.DiscreteValues
+				discreteValue := discreteValues[idx]
+
+				/*
+				 * Create description for discrete parameter.
+				 */
+				discreteParam := persistence.DiscreteParam{
+					Key:   paramName,
+					Value: discreteValue,
+				}
+
+				discreteParams = append(discreteParams, discreteParam)
+			case effects.PARAMETER_TYPE_NUMERIC:
+				numericValue := param.NumericValue
+
+				/*
+				 * Create description for numeric parameter.
+				 */
+				numericParam := persistence.NumericParam{
+					Key:   paramName,
+					Value: numericValue,
+				}
+
+				numericParams = append(numericParams, numericParam)
+			}
+
+		}
+
+		/*
+		 * Create data structure describing a signal processing unit.
+		 */
+		unit := persistence.Unit{
+			Type:           unitTypeString,
+			Name:           name,
+			Label:          label,
+			Note:           note,
+			Bypass:         bypass,
+			InputTrim:      inputTrim,
+			OutputLevel:    outputLevel,
+			DiscreteParams: discreteParams,
+			NumericParams:  numericParams,
+			Units:          innerUnits,
+		}
+
+		units[unitId] = unit
+	}
+	return units
+}
+
+/*
+ * Creates a patch describing the current configuration.
+ */
+func (this *controllerStruct) createPatch() persistence.Configuration {
+	cfg := this.config
+	svr := cfg.WebServer
+	appName := svr.Name
+	framesPerPeriod := uint32(BLOCK_SIZE)
+
+	/*
+	 * If we are bound to a hardware interface, query frames per period.
+	 */
+	if this.binding != nil {
+		framesPerPeriod = hwio.FramesPerPeriod()
+	}
+
+	/*
+	 * Create file format version.
+	 */
+	version := persistence.Version{
+		Major: 1,
+		Minor: 8,
+	}
+
+	/*
+	 * Create file format.
+	 */
+	fileFormat := persistence.FileFormat{
+		Application: appName,
+		Type:        "patch",
+		Version:     version,
+	}
+
+	channels := []persistence.Channel{}
+	spat := this.spat
+	mix := this.mixSnapshot()
+
+	/*
+	 * Iterate over the signal chains.
+	 */
+	for chainId, chain := range this.effects {
+		units := this.persistChain(chain)
+		metadata := mix.channels[chainId]
+		chainId32 := uint32(chainId)
+		azimuth, _ := spat.GetAzimuth(chainId32)
+		distance, _ := spat.GetDistance(chainId32)
+		level, _ := spat.GetLevel(chainId32)
+		mute, _ := spat.GetMute(chainId32)
+		solo, _ := spat.GetSolo(chainId32)
+		numBuses := spat.GetBusCount()
+		sends := make([]float64, numBuses)
+
+		/*
+		 * Query the send level for each aux bus.
+		 */
+		for busId := uint32(0); busId < numBuses; busId++ {
+			sends[busId], _ = spat.GetSend(chainId32, busId)
+		}
+
+		/*
+		 * Create data structure describing spatializer settings for this channel.
+		 */
+		pSpat := persistence.Spatializer{
+			Azimuth:  azimuth,
+			Distance: distance,
+			Level:    level,
+			Mute:     mute,
+			Solo:     solo,
+			Sends:    sends,
+		}
+
+		/*
+		 * Create data structure describing audio channel.
+		 */
+		channel := persistence.Channel{
+			Name:        metadata.name,
+			Color:       metadata.color,
+			Click:       metadata.click,
+			ChannelTrim: metadata.channelTrim,
+			ReampLevel:  metadata.reampLevel,
+			Units:       units,
+			Spatializer: pSpat,
+		}
+
+		channels = append(channels, channel)
+	}
+
+	buses := []persistence.Bus{}
+
+	/*
+	 * Iterate over the signal chains of the aux buses.
+	 */
+	for busId, chain := range this.buses {
+		units := this.persistChain(chain)
+		busId32 := uint32(busId)
+		returnLevel, _ := spat.GetReturn(busId32)
+
+		/*
+		 * Create data structure describing aux bus.
+		 */
+		bus := persistence.Bus{
+			Units:  units,
+			Return: returnLevel,
+		}
+
+		buses = append(buses, bus)
+	}
+
+	metrMasterOutput := mix.metrMasterOutput
+	metr := this.metr
+	beatsPerPeriod := uint32(0)
+	speed := uint32(0)
+	subdivision := uint32(0)
+	accents := ""
+	countIn := uint32(0)
+	tickSound := ""
+	tickPitch := float64(0.0)
+	tickDecay := float64(0.0)
+	tockSound := ""
+	tockPitch := float64(0.0)
+	tockDecay := float64(0.0)
+
+	/*
+	 * Check if we have a metronome.
+	 */
+	if metr != nil {
+		beatsPerPeriod = metr.BeatsPerPeriod()
+		speed = metr.Speed()
+		subdivision = metr.Subdivision()
+		accents = metr.Accents()
+		countIn = metr.CountIn()
+		tickSound, _ = metr.Tick()
+		tickPitch, tickDecay = metr.TickSynthesis()
+		tockSound, _ = metr.Tock()
+		tockPitch, tockDecay = metr.TockSynthesis()
+	}
+
+	/*
+	 * Create metronome information.
+	 */
+	metrP := persistence.Metronome{
+		Master:         metrMasterOutput,
+		BeatsPerPeriod: beatsPerPeriod,
+		Speed:          speed,
+		Subdivision:    subdivision,
+		Accents:        accents,
+		CountIn:        countIn,
+		TickSound:      tickSound,
+		TickPitch:      tickPitch,
+		TickDecay:      tickDecay,
+		TockSound:      tockSound,
+		TockPitch:      tockPitch,
+		TockDecay:      tockDecay,
+	}
+
+	masterSection := this.masterSection
+	masterEnabled := masterSection.Enabled()
+	masterParameters := masterSection.Parameters()
+	numMasterParameters := len(masterParameters)
+	masterParams := make([]persistence.NumericParam, numMasterParameters)
+
+	/*
+	 * Store the value of each parameter of the master section.
+	 */
+	for i, param := range masterParameters {
+
+		/*
+		 * Create numeric parameter.
+		 */
+		masterParams[i] = persistence.NumericParam{
+			Key:   param.Name,
+			Value: param.Value,
+		}
+
+	}
+
+	/*
+	 * Create master section information.
+	 */
+	masterP := persistence.Master{
+		Enabled:       masterEnabled,
+		NumericParams: masterParams,
+	}
+
+	/*
+	 * Create configuration.
+	 */
+	configuration := persistence.Configuration{
+		FileFormat:      fileFormat,
+		FramesPerPeriod: framesPerPeriod,
+		Channels:        channels,
+		Buses:           buses,
+		Metronome:       metrP,
+		Master:          masterP,
+		Automation:      this.persistAutomation(),
+	}
+
+	return configuration
+}
+
+/*
+ * Save (export) current configuration to JSON file.
+ */
+func (this *controllerStruct) persistenceSaveHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	configuration := this.createPatch()
+	configuration.SpeakerLayout = this.spat.GetSpeakerLayout()
+	configuration.Connections = this.persistConnections()
+	mimeType, buffer := this.createJSON(configuration)
+	creationTime := time.Now()
+	timeStamp := creationTime.Format(ARCHIVE_TIME_STAMP)
+	fileName := fmt.Sprintf("patch-%s.json", timeStamp)
+	disposition := fmt.Sprintf("attachment; filename=\"%s\"", fileName)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{
+			"Content-type":        mimeType,
+			"Content-disposition": disposition,
+		},
+		Body: buffer,
+	}
+
+	return response
+}
+
+/*
+ * Cause processing of a file in batch mode.
+ */
+func (this *controllerStruct) processHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	status := this.render.status()
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Only one rendering may run at a time.
+	 */
+	if status.Running {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "A rendering is already in progress.",
+		}
+
+	} else {
+		this.running = false
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Reloads the collection of impulse responses from disk.
+ */
+func (this *controllerStruct) reloadImpulseResponsesHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	irs := this.impulseResponses
+	err := irs.Reload()
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if impulse responses were reloaded.
+	 */
+	if err != nil {
+		msg := err.Error()
+		reason := fmt.Sprintf("Failed to reload impulse responses: %s", msg)
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+		reason := ""
+
+		/*
+		 * Update the units in each signal chain.
+		 */
+		for chainId, chain := range this.chains() {
+			err := chain.UpdateImpulseResponses()
+
+			/*
+			 * Report the first unit which failed to update.
+			 */
+			if err != nil && reason == "" {
+				msg := err.Error()
+				reason = fmt.Sprintf("Failed to update chain %d: %s", chainId, msg)
+			}
+
+		}
+
+		metr := this.metr
+
+		/*
+		 * Check if we have a metronome.
+		 */
+		if metr != nil {
+			tickSound, _ := metr.Tick()
+			tockSound, _ := metr.Tock()
+
+			/*
+			 * Reload the tick sound unless it is disabled.
+			 */
+			if tickSound != "- NONE -" {
+				pitch, decay := metr.TickSynthesis()
+				coeffs := this.metronomeSound(tickSound, pitch, decay)
+
+				/*
+				 * Disable the tick sound if it is no longer available.
+				 */
+				if coeffs == nil {
+					metr.SetTick("- NONE -", nil)
+				} else {
+					metr.SetTick(tickSound, coeffs)
+				}
+
+			}
+
+			/*
+			 * Reload the tock sound unless it is disabled.
+			 */
+			if tockSound != "- NONE -" {
+				pitch, decay := metr.TockSynthesis()
+				coeffs := this.metronomeSound(tockSound, pitch, decay)
+
+				/*
+				 * Disable the tock sound if it is no longer available.
+				 */
+				if coeffs == nil {
+					metr.SetTock("- NONE -", nil)
+				} else {
+					metr.SetTock(tockSound, coeffs)
+				}
+
+			}
+
+		}
+
+		success := (reason == "")
+
+		/*
+		 * Indicate success or failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: success,
+			Reason:  reason,
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Clears the peak indicators and clip counters of the level meters, either
+ * of a single channel or of all channels.
+ */
+func (this *controllerStruct) resetLevelMeterHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	channelString := request.Params["channel"]
+	meter := this.levelMeter
+	webResponse := webResponseStruct{}
+
+	/*
+	 * The channel is optional.
+	 */
+	if channelString == "" {
+		meter.ResetPeaks()
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	} else {
+		channel64, err := strconv.ParseUint(channelString, 10, 32)
+
+		/*
+		 * Check if channel number is valid.
+		 */
+		if err != nil {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Failed to decode channel number.",
+			}
+
+		} else {
+			channel := uint32(channel64)
+			err = meter.ResetPeak(channel)
+
+			/*
+			 * Check if the channel could be reset.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Restarts the loudness measurement of the level meters.
+ */
+func (this *controllerStruct) resetLoudnessHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	meter := this.levelMeter
+	meter.ResetLoudness()
+
+	/*
+	 * Indicate success.
 	 */
 	webResponse := webResponseStruct{
 		Success: true,
@@ -1409,17 +4122,1284 @@ func (this *controllerStruct) processHandler(request webserver.HttpRequest) webs
 }
 
 /*
- * Removes a unit from a rack.
+ * Restores a parameter of an effects unit to its default value.
+ */
+func resetParameter(chain signal.Chain, unitId int, name string) error {
+	params, err := chain.Parameters(unitId)
+
+	/*
+	 * Check if the parameters could be obtained.
+	 */
+	if err != nil {
+		return err
+	} else {
+		idx := -1
+
+		/*
+		 * Look for the parameter.
+		 */
+		for i, param := range params {
+
+			/*
+			 * If we got the right one, store its index.
+			 */
+			if param.Name == name {
+				idx = i
+			}
+
+		}
+
+		/*
+		 * Check if the parameter was found.
+		 */
+		if idx < 0 {
+			return fmt.Errorf("Cannot reset parameter: Unknown parameter '%s'.", name)
+		} else {
+			param := params[idx]
+
+			/*
+			 * Check which type of parameter we have.
+			 */
+			switch param.Type {
+			case effects.PARAMETER_TYPE_NUMERIC:
+				return chain.SetNumericValue(unitId, name, param.DefaultValue)
+			case effects.PARAMETER_TYPE_DISCRETE:
+				values := param.DiscreteValues
+				numValues := len(values)
+				defaultIdx := param.DefaultDiscreteValueIndex
+
+				/*
+				 * Check if the default value exists.
+				 */
+				if (defaultIdx < 0) || (defaultIdx >= numValues) {
+					return fmt.Errorf("Cannot reset parameter: Parameter '%s' has no default value.", name)
+				} else {
+					value := values[defaultIdx]
+					return chain.SetDiscreteValue(unitId, name, value)
+				}
+
+			default:
+				return fmt.Errorf("Cannot reset parameter: Parameter '%s' has an invalid type.", name)
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Restores a parameter of an effects unit to its default value.
+ */
+func (this *controllerStruct) resetParameterHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := request.Params["unit"]
+	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	param := request.Params["param"]
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain ID and unit ID are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errUnitId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode unit ID.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		unitId := int(unitId64)
+		fx := this.chains()
+		nChains := len(fx)
+
+		/*
+		 * Check if chain ID is out of range.
+		 */
+		if chainId >= nChains {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Chain ID out of range.",
+			}
+
+		} else {
+			err := resetParameter(fx[chainId], unitId, param)
+
+			/*
+			 * Check if parameter was reset.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Removes an input channel along with its signal chain, ports, level meters
+ * and position in the spatializer. The channels after it move down by one.
+ */
+func (this *controllerStruct) removeChannelHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	channelIdString := request.Params["channel"]
+	channelId64, errChannelId := strconv.ParseUint(channelIdString, 10, 32)
+	webResponse := webResponseStruct{}
+	fx := this.effects
+	nChannels := uint64(len(fx))
+
+	/*
+	 * Check if channel ID is valid.
+	 */
+	if errChannelId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode channel ID.",
+		}
+
+	} else if channelId64 >= nChannels {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Channel ID out of range.",
+		}
+
+	} else if nChannels == 1 {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Cannot remove the last channel.",
+		}
+
+	} else {
+		err := this.prepareChannelChange()
+
+		/*
+		 * Check if channels may be changed.
+		 */
+		if err != nil {
+			reason := err.Error()
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		} else {
+			channelId := int(channelId64)
+			channelIdNext := channelId + 1
+			fxNew := append([]signal.Chain{}, fx[:channelId]...)
+			fxNew = append(fxNew, fx[channelIdNext:]...)
+			portIds := this.channelPortIds
+			portIdsNew := append([]string{}, portIds[:channelId]...)
+			portIdsNew = append(portIdsNew, portIds[channelIdNext:]...)
+			mix := this.mixSnapshot()
+			metadata := mix.channels
+			metadataNew := append([]channelMetadataStruct{}, metadata[:channelId]...)
+			metadataNew = append(metadataNew, metadata[channelIdNext:]...)
+			defaultMetadata := this.defaultChannelMetadata
+			defaultMetadataNew := append([]channelMetadataStruct{}, defaultMetadata[:channelId]...)
+			defaultMetadataNew = append(defaultMetadataNew, defaultMetadata[channelIdNext:]...)
+
+			/*
+			 * The tuner stops listening to a removed channel and
+			 * follows channels which move down.
+			 */
+			this.changeMix(func(mix *mixSnapshotStruct) {
+				tunerChannel := mix.tunerChannel
+
+				/*
+				 * Check if the tuner listens to the removed
+				 * channel or to one after it.
+				 */
+				if tunerChannel == channelId {
+					mix.tunerChannel = -1
+				} else if tunerChannel > channelId {
+					mix.tunerChannel = tunerChannel - 1
+				}
+
+			})
+
+			channelId32 := uint32(channelId)
+			this.spat.RemoveChannel(channelId32)
+			err = this.setChannels(fxNew, portIdsNew, metadataNew, defaultMetadataNew)
+
+			/*
+			 * Remove the lanes of the channel, while the lanes of
+			 * the chains after it move up by one.
+			 */
+			this.remapAutomation(func(laneChain int, laneUnit int) (int, int, bool) {
+
+				/*
+				 * Check if the lane belongs to a chain after the
+				 * removed one.
+				 */
+				if laneChain > channelId {
+					return laneChain - 1, laneUnit, true
+				} else {
+					return laneChain, laneUnit, laneChain != channelId
+				}
+
+			})
+
+			this.clearHistory()
+
+			/*
+			 * Check if the ports were changed.
+			 */
+			if err != nil {
+				msg := err.Error()
+				reason := "Channel was removed, but its ports could not be unregistered: " + msg
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Removes a unit from a rack.
+ */
+func (this *controllerStruct) removeUnitHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := request.Params["unit"]
+	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain and unit ID are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errUnitId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode unit ID.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		unitId := int(unitId64)
+		fx := this.chains()
+		nChains := len(fx)
+
+		/*
+		 * Check if chain ID is out of range.
+		 */
+		if (chainId < 0) || (chainId >= nChains) {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Chain ID out of range.",
+			}
+
+		} else {
+			this.haltMorph()
+			err := fx[chainId].RemoveUnit(unitId)
+
+			/*
+			 * Remove the lanes of the unit and move those of the
+			 * units after it up by one.
+			 */
+			if err == nil {
+
+				/*
+				 * Remap the lanes of the chain.
+				 */
+				this.remapAutomation(func(laneChain int, laneUnit int) (int, int, bool) {
+
+					/*
+					 * Check if the lane belongs to the chain.
+					 */
+					if laneChain != chainId {
+						return laneChain, laneUnit, true
+					} else if laneUnit > unitId {
+						return laneChain, laneUnit - 1, true
+					} else {
+						return laneChain, laneUnit, laneUnit != unitId
+					}
+
+				})
+
+			}
+
+			/*
+			 * Check if unit was successfully removed.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets the azimuth of a channel in the spatializer.
+ */
+func (this *controllerStruct) setAzimuthHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	valueString := request.Params["value"]
+	valueInt, errValue := strconv.ParseInt(valueString, 10, 64)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain ID and azimuth value are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errValue != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode azimuth value.",
+		}
+
+	} else {
+		chainId32 := uint32(chainId64)
+		value := float64(valueInt)
+		spat := this.spat
+		err := spat.SetAzimuth(chainId32, value)
+
+		/*
+		 * Check if azimuth was set successfully.
+		 */
+		if err != nil {
+			reason := err.Error()
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		} else {
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Enables or disables bypass for an effects unit.
+ */
+func (this *controllerStruct) setBypassHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := request.Params["unit"]
+	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	valueString := request.Params["value"]
+	value, errValue := strconv.ParseBool(valueString)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain ID, unit ID and value are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errUnitId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode unit ID.",
+		}
+
+	} else if errValue != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode value.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		unitId := int(unitId64)
+		fx := this.chains()
+		nChains := len(fx)
+
+		/*
+		 * Check if chain ID is out of range.
+		 */
+		if (chainId < 0) || (chainId >= nChains) {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Chain ID out of range.",
+			}
+
+		} else {
+			err := fx[chainId].SetBypass(unitId, value)
+
+			/*
+			 * Check if bypass value was successfully set.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Assigns a color (in hexadecimal notation) to a channel. An empty value
+ * restores the color configured for the channel.
+ */
+func (this *controllerStruct) setChannelColorHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	channelIdString := request.Params["channel"]
+	channelId64, errChannelId := strconv.ParseUint(channelIdString, 10, 32)
+	valueString := request.Params["value"]
+	value := strings.TrimSpace(valueString)
+	webResponse := webResponseStruct{}
+	fx := this.effects
+	nChannels := uint64(len(fx))
+
+	/*
+	 * Check if channel ID and value are valid.
+	 */
+	if errChannelId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode channel ID.",
+		}
+
+	} else if channelId64 >= nChannels {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Channel ID out of range.",
+		}
+
+	} else if (value != "") && !isColor(value) {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Color must be given in hexadecimal notation (#rrggbb).",
+		}
+
+	} else {
+		channelId := int(channelId64)
+
+		/*
+		 * Restore the configured color if no color was given.
+		 */
+		if value == "" {
+			value = this.defaultChannelMetadata[channelId].color
+		}
+
+		/*
+		 * Set the color of the channel.
+		 */
+		this.changeMix(func(mix *mixSnapshotStruct) {
+			mix.channels[channelId].color = value
+		})
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Assigns a name to a channel. An empty value restores the name configured
+ * for the channel.
+ */
+func (this *controllerStruct) setChannelNameHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	channelIdString := request.Params["channel"]
+	channelId64, errChannelId := strconv.ParseUint(channelIdString, 10, 32)
+	valueString := request.Params["value"]
+	value := strings.TrimSpace(valueString)
+	webResponse := webResponseStruct{}
+	fx := this.effects
+	nChannels := uint64(len(fx))
+
+	/*
+	 * Check if channel ID and value are valid.
+	 */
+	if errChannelId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode channel ID.",
+		}
+
+	} else if channelId64 >= nChannels {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Channel ID out of range.",
+		}
+
+	} else if utf8.RuneCountInString(value) > CHANNEL_NAME_MAX_LENGTH {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Channel name too long.",
+		}
+
+	} else {
+		channelId := int(channelId64)
+
+		/*
+		 * Restore the configured name if no name was given.
+		 */
+		if value == "" {
+			value = this.defaultChannelMetadata[channelId].name
+		}
+
+		/*
+		 * Set the name of the channel.
+		 */
+		this.changeMix(func(mix *mixSnapshotStruct) {
+			mix.channels[channelId].name = value
+		})
+
+		this.updateLevelMeterNames()
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets the label of an effects unit, which is shown instead of the name of its type.
+ */
+func (this *controllerStruct) setUnitLabelHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := request.Params["unit"]
+	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	valueString := request.Params["value"]
+	value := strings.TrimSpace(valueString)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain ID, unit ID and value are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errUnitId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode unit ID.",
+		}
+
+	} else if utf8.RuneCountInString(value) > UNIT_LABEL_MAX_LENGTH {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Unit label too long.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		unitId := int(unitId64)
+		fx := this.chains()
+		nChains := len(fx)
+
+		/*
+		 * Check if chain ID is out of range.
+		 */
+		if (chainId < 0) || (chainId >= nChains) {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Chain ID out of range.",
+			}
+
+		} else {
+			err := fx[chainId].SetLabel(unitId, value)
+
+			/*
+			 * Check if label was successfully set.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets the note attached to an effects unit.
+ */
+func (this *controllerStruct) setUnitNoteHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := request.Params["unit"]
+	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	valueString := request.Params["value"]
+	value := strings.TrimSpace(valueString)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain ID, unit ID and value are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errUnitId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode unit ID.",
+		}
+
+	} else if utf8.RuneCountInString(value) > UNIT_NOTE_MAX_LENGTH {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Unit note too long.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		unitId := int(unitId64)
+		fx := this.chains()
+		nChains := len(fx)
+
+		/*
+		 * Check if chain ID is out of range.
+		 */
+		if (chainId < 0) || (chainId >= nChains) {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Chain ID out of range.",
+			}
+
+		} else {
+			err := fx[chainId].SetNote(unitId, value)
+
+			/*
+			 * Check if note was successfully set.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets a discrete value as a parameter in an effects unit.
+ */
+func (this *controllerStruct) setDiscreteValueHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := request.Params["unit"]
+	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	param := request.Params["param"]
+	value := request.Params["value"]
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain ID, unit ID and value are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errUnitId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode unit ID.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		unitId := int(unitId64)
+		fx := this.chains()
+		nChains := len(fx)
+
+		/*
+		 * Check if chain ID is out of range.
+		 */
+		if (chainId < 0) || (chainId >= nChains) {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Chain ID out of range.",
+			}
+
+		} else {
+			err := fx[chainId].SetDiscreteValue(unitId, param, value)
+
+			/*
+			 * Check if bypass value was successfully set.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets the distance of a channel in the spatializer.
+ */
+func (this *controllerStruct) setDistanceHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	valueString := request.Params["value"]
+	value, errDistance := strconv.ParseFloat(valueString, 64)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain ID and distance value are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errDistance != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode distance value.",
+		}
+
+	} else {
+		chainId32 := uint32(chainId64)
+		spat := this.spat
+		err := spat.SetDistance(chainId32, value)
+
+		/*
+		 * Check if distance was set successfully.
+		 */
+		if err != nil {
+			reason := err.Error()
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		} else {
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets the frames per period for the hardware interface.
  */
-func (this *controllerStruct) removeUnitHandler(request webserver.HttpRequest) webserver.HttpResponse {
+func (this *controllerStruct) setFramesPerPeriodHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	valueString := request.Params["value"]
+	value64, err := strconv.ParseUint(valueString, 10, 32)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if value is valid.
+	 */
+	if err != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to parse frame count.",
+		}
+
+	} else {
+		value32 := uint32(value64)
+		hwio.SetFramesPerPeriod(value32)
+		this.setBlockSize(value32)
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets the level of a channel in the spatializer.
+ */
+func (this *controllerStruct) setLevelHandler(request webserver.HttpRequest) webserver.HttpResponse {
 	chainIdString := request.Params["chain"]
 	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
-	unitIdString := request.Params["unit"]
-	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	valueString := request.Params["value"]
+	value, errDistance := strconv.ParseFloat(valueString, 64)
 	webResponse := webResponseStruct{}
 
 	/*
-	 * Check if chain and unit ID are valid.
+	 * Check if chain ID and distance value are valid.
 	 */
 	if errChainId != nil {
 
@@ -1431,60 +5411,233 @@ func (this *controllerStruct) removeUnitHandler(request webserver.HttpRequest) w
 			Reason:  "Failed to decode chain ID.",
 		}
 
-	} else if errUnitId != nil {
+	} else if errDistance != nil {
 
 		/*
 		 * Indicate failure.
 		 */
 		webResponse = webResponseStruct{
 			Success: false,
-			Reason:  "Failed to decode unit ID.",
+			Reason:  "Failed to decode level value.",
 		}
 
 	} else {
-		chainId := int(chainId64)
-		unitId := int(unitId64)
-		fx := this.effects
-		nChains := len(fx)
+		chainId32 := uint32(chainId64)
+		spat := this.spat
+		err := spat.SetLevel(chainId32, value)
 
 		/*
-		 * Check if chain ID is out of range.
+		 * Check if distance was set successfully.
 		 */
-		if (chainId < 0) || (chainId >= nChains) {
+		if err != nil {
+			reason := err.Error()
 
 			/*
 			 * Indicate failure.
 			 */
 			webResponse = webResponseStruct{
 				Success: false,
-				Reason:  "Chain ID out of range.",
+				Reason:  reason,
 			}
 
 		} else {
-			err := fx[chainId].RemoveUnit(unitId)
 
 			/*
-			 * Check if unit was successfully removed.
+			 * Indicate success.
 			 */
-			if err != nil {
-				reason := err.Error()
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
 
-				/*
-				 * Indicate failure.
-				 */
-				webResponse = webResponseStruct{
-					Success: false,
-					Reason:  reason,
-				}
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets whether a channel is muted in the spatializer.
+ */
+func (this *controllerStruct) setMuteHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	valueString := request.Params["value"]
+	value, errValue := strconv.ParseBool(valueString)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain ID and mute flag are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errValue != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode mute flag.",
+		}
+
+	} else {
+		chainId32 := uint32(chainId64)
+		spat := this.spat
+		err := spat.SetMute(chainId32, value)
+
+		/*
+		 * Check if mute flag was set successfully.
+		 */
+		if err != nil {
+			reason := err.Error()
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		} else {
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Selects whether the spatializer renders the channels for loudspeakers or
+ * for headphones.
+ */
+func (this *controllerStruct) setSpatializerModeHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	value := request.Params["value"]
+	spat := this.spat
+	err := spat.SetMode(value)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if the mode could be changed.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
 
+/*
+ * Selects the speaker layout of the spatializer and registers the master
+ * outputs it requires.
+ */
+func (this *controllerStruct) setSpeakerLayout(layout string) error {
+	spat := this.spat
+	previousLayout := spat.GetSpeakerLayout()
+
+	/*
+	 * Only change the ports if the layout changes.
+	 */
+	if layout == previousLayout {
+		return nil
+	} else {
+		_, err := spatializer.SpeakerNames(layout)
+
+		/*
+		 * Check if the layout is supported and if the ports may be
+		 * changed.
+		 */
+		if err != nil {
+			return err
+		} else {
+			err = this.prepareChannelChange()
+
+			/*
+			 * Check if channels may be changed.
+			 */
+			if err != nil {
+				return err
 			} else {
+				spat.SetSpeakerLayout(layout)
+				mix := this.mixSnapshot()
+				err = this.setChannels(this.effects, this.channelPortIds, mix.channels, this.defaultChannelMetadata)
 
 				/*
-				 * Indicate success.
+				 * Check if the ports were changed.
 				 */
-				webResponse = webResponseStruct{
-					Success: true,
-					Reason:  "",
+				if err != nil {
+					msg := err.Error()
+					return fmt.Errorf("Speaker layout was changed, but its ports could not be registered: %s", msg)
+				} else {
+					return nil
 				}
 
 			}
@@ -1493,6 +5646,42 @@ func (this *controllerStruct) removeUnitHandler(request webserver.HttpRequest) w
 
 	}
 
+}
+
+/*
+ * Selects the speaker layout of the spatializer.
+ */
+func (this *controllerStruct) setSpeakerLayoutHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	value := request.Params["value"]
+	err := this.setSpeakerLayout(value)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if the layout could be changed.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
 	mimeType, buffer := this.createJSON(webResponse)
 
 	/*
@@ -1507,17 +5696,17 @@ func (this *controllerStruct) removeUnitHandler(request webserver.HttpRequest) w
 }
 
 /*
- * Sets the azimuth of a channel in the spatializer.
+ * Sets whether a channel is soloed in the spatializer.
  */
-func (this *controllerStruct) setAzimuthHandler(request webserver.HttpRequest) webserver.HttpResponse {
+func (this *controllerStruct) setSoloHandler(request webserver.HttpRequest) webserver.HttpResponse {
 	chainIdString := request.Params["chain"]
 	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
 	valueString := request.Params["value"]
-	valueInt, errValue := strconv.ParseInt(valueString, 10, 64)
+	value, errValue := strconv.ParseBool(valueString)
 	webResponse := webResponseStruct{}
 
 	/*
-	 * Check if chain ID and azimuth value are valid.
+	 * Check if chain ID and solo flag are valid.
 	 */
 	if errChainId != nil {
 
@@ -1536,17 +5725,16 @@ func (this *controllerStruct) setAzimuthHandler(request webserver.HttpRequest) w
 		 */
 		webResponse = webResponseStruct{
 			Success: false,
-			Reason:  "Failed to decode azimuth value.",
+			Reason:  "Failed to decode solo flag.",
 		}
 
 	} else {
 		chainId32 := uint32(chainId64)
-		value := float64(valueInt)
 		spat := this.spat
-		err := spat.SetAzimuth(chainId32, value)
+		err := spat.SetSolo(chainId32, value)
 
 		/*
-		 * Check if azimuth was set successfully.
+		 * Check if solo flag was set successfully.
 		 */
 		if err != nil {
 			reason := err.Error()
@@ -1587,96 +5775,101 @@ func (this *controllerStruct) setAzimuthHandler(request webserver.HttpRequest) w
 }
 
 /*
- * Enables or disables bypass for an effects unit.
+ * Selects the ballistics of the RMS measurement of the level meters.
  */
-func (this *controllerStruct) setBypassHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	chainIdString := request.Params["chain"]
-	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
-	unitIdString := request.Params["unit"]
-	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
-	valueString := request.Params["value"]
-	value, errValue := strconv.ParseBool(valueString)
+func (this *controllerStruct) setLevelMeterBallisticsHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	value := request.Params["value"]
+	meter := this.levelMeter
+	err := meter.SetBallistics(value)
 	webResponse := webResponseStruct{}
 
 	/*
-	 * Check if chain ID, unit ID and value are valid.
+	 * Check if the ballistics could be changed.
 	 */
-	if errChainId != nil {
+	if err != nil {
+		reason := err.Error()
 
 		/*
 		 * Indicate failure.
 		 */
 		webResponse = webResponseStruct{
 			Success: false,
-			Reason:  "Failed to decode chain ID.",
+			Reason:  reason,
 		}
 
-	} else if errUnitId != nil {
+	} else {
 
 		/*
-		 * Indicate failure.
+		 * Indicate success.
 		 */
 		webResponse = webResponseStruct{
-			Success: false,
-			Reason:  "Failed to decode unit ID.",
+			Success: true,
+			Reason:  "",
 		}
 
-	} else if errValue != nil {
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Changes the time (in seconds) the peak indicators of the level meters are
+ * held. A hold time of zero holds them until they are reset.
+ */
+func (this *controllerStruct) setPeakHoldTimeHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	valueString := request.Params["value"]
+	value, err := strconv.ParseFloat(valueString, 64)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if hold time is valid.
+	 */
+	if err != nil {
 
 		/*
 		 * Indicate failure.
 		 */
 		webResponse = webResponseStruct{
 			Success: false,
-			Reason:  "Failed to decode value.",
+			Reason:  "Failed to decode peak hold time.",
 		}
 
 	} else {
-		chainId := int(chainId64)
-		unitId := int(unitId64)
-		fx := this.effects
-		nChains := len(fx)
+		meter := this.levelMeter
+		err = meter.SetPeakHoldTime(value)
 
 		/*
-		 * Check if chain ID is out of range.
+		 * Check if the hold time could be changed.
 		 */
-		if (chainId < 0) || (chainId >= nChains) {
+		if err != nil {
+			reason := err.Error()
 
 			/*
 			 * Indicate failure.
 			 */
 			webResponse = webResponseStruct{
 				Success: false,
-				Reason:  "Chain ID out of range.",
+				Reason:  reason,
 			}
 
 		} else {
-			err := fx[chainId].SetBypass(unitId, value)
 
 			/*
-			 * Check if bypass value was successfully set.
+			 * Indicate success.
 			 */
-			if err != nil {
-				reason := err.Error()
-
-				/*
-				 * Indicate failure.
-				 */
-				webResponse = webResponseStruct{
-					Success: false,
-					Reason:  reason,
-				}
-
-			} else {
-
-				/*
-				 * Indicate success.
-				 */
-				webResponse = webResponseStruct{
-					Success: true,
-					Reason:  "",
-				}
-
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
 			}
 
 		}
@@ -1697,90 +5890,70 @@ func (this *controllerStruct) setBypassHandler(request webserver.HttpRequest) we
 }
 
 /*
- * Sets a discrete value as a parameter in an effects unit.
+ * Sets the level of a channel in the spatializer.
  */
-func (this *controllerStruct) setDiscreteValueHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	chainIdString := request.Params["chain"]
-	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
-	unitIdString := request.Params["unit"]
-	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
-	param := request.Params["param"]
-	value := request.Params["value"]
+func (this *controllerStruct) setLevelMeterEnabledHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	valueString := request.Params["value"]
+	value, err := strconv.ParseBool(valueString)
 	webResponse := webResponseStruct{}
 
 	/*
-	 * Check if chain ID, unit ID and value are valid.
+	 * Check if boolean value is valud.
 	 */
-	if errChainId != nil {
-
-		/*
-		 * Indicate failure.
-		 */
-		webResponse = webResponseStruct{
-			Success: false,
-			Reason:  "Failed to decode chain ID.",
-		}
-
-	} else if errUnitId != nil {
+	if err != nil {
 
 		/*
 		 * Indicate failure.
 		 */
 		webResponse = webResponseStruct{
-			Success: false,
-			Reason:  "Failed to decode unit ID.",
+			Success: true,
+			Reason:  "Failed to decode boolean value.",
 		}
 
 	} else {
-		chainId := int(chainId64)
-		unitId := int(unitId64)
-		fx := this.effects
-		nChains := len(fx)
+		meter := this.levelMeter
+		meter.SetEnabled(value)
 
 		/*
-		 * Check if chain ID is out of range.
+		 * If level meters should be disabled, clear buffers and
+		 * correlation meter as well.
 		 */
-		if (chainId < 0) || (chainId >= nChains) {
+		if !value {
+			correlationMeter := this.correlationMeter
 
 			/*
-			 * Indicate failure.
+			 * Check if there is a correlation meter.
 			 */
-			webResponse = webResponseStruct{
-				Success: false,
-				Reason:  "Chain ID out of range.",
+			if correlationMeter != nil {
+				correlationMeter.Reset()
 			}
 
-		} else {
-			err := fx[chainId].SetDiscreteValue(unitId, param, value)
+			buffers := this.buffers
 
 			/*
-			 * Check if bypass value was successfully set.
+			 * Iterate over all buffers.
 			 */
-			if err != nil {
-				reason := err.Error()
-
-				/*
-				 * Indicate failure.
-				 */
-				webResponse = webResponseStruct{
-					Success: false,
-					Reason:  reason,
-				}
-
-			} else {
+			for _, buffer := range buffers {
 
 				/*
-				 * Indicate success.
+				 * Clear the buffer.
 				 */
-				webResponse = webResponseStruct{
-					Success: true,
-					Reason:  "",
+				for i := range buffer {
+					buffer[i] = 0.0
 				}
 
 			}
 
 		}
 
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
 	}
 
 	mimeType, buffer := this.createJSON(webResponse)
@@ -1797,67 +5970,52 @@ func (this *controllerStruct) setDiscreteValueHandler(request webserver.HttpRequ
 }
 
 /*
- * Sets the distance of a channel in the spatializer.
+ * Enables or disables smoothing of changes to input trim, output level and
+ * bypass state in all signal chains.
  */
-func (this *controllerStruct) setDistanceHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	chainIdString := request.Params["chain"]
-	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+func (this *controllerStruct) setParameterSmoothingHandler(request webserver.HttpRequest) webserver.HttpResponse {
 	valueString := request.Params["value"]
-	value, errDistance := strconv.ParseFloat(valueString, 64)
+	value, err := strconv.ParseBool(valueString)
 	webResponse := webResponseStruct{}
 
 	/*
-	 * Check if chain ID and distance value are valid.
+	 * Check if boolean value is valid.
 	 */
-	if errChainId != nil {
+	if err != nil {
 
 		/*
 		 * Indicate failure.
 		 */
 		webResponse = webResponseStruct{
 			Success: false,
-			Reason:  "Failed to decode chain ID.",
+			Reason:  "Failed to decode boolean value.",
 		}
 
-	} else if errDistance != nil {
+	} else {
+		signalChains := this.effects
 
 		/*
-		 * Indicate failure.
+		 * Apply the setting to the signal chain of each channel.
 		 */
-		webResponse = webResponseStruct{
-			Success: false,
-			Reason:  "Failed to decode distance value.",
+		for _, chain := range signalChains {
+			chain.SetSmoothing(value)
 		}
 
-	} else {
-		chainId32 := uint32(chainId64)
-		spat := this.spat
-		err := spat.SetDistance(chainId32, value)
+		busChains := this.buses
 
 		/*
-		 * Check if distance was set successfully.
+		 * Apply the setting to the signal chain of each bus.
 		 */
-		if err != nil {
-			reason := err.Error()
-
-			/*
-			 * Indicate failure.
-			 */
-			webResponse = webResponseStruct{
-				Success: false,
-				Reason:  reason,
-			}
-
-		} else {
-
-			/*
-			 * Indicate success.
-			 */
-			webResponse = webResponseStruct{
-				Success: true,
-				Reason:  "",
-			}
+		for _, chain := range busChains {
+			chain.SetSmoothing(value)
+		}
 
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
 		}
 
 	}
@@ -1876,9 +6034,10 @@ func (this *controllerStruct) setDistanceHandler(request webserver.HttpRequest)
 }
 
 /*
- * Sets the frames per period for the hardware interface.
+ * Sets the time (in milliseconds) over which units in all signal chains ramp
+ * changes to their numeric parameters.
  */
-func (this *controllerStruct) setFramesPerPeriodHandler(request webserver.HttpRequest) webserver.HttpResponse {
+func (this *controllerStruct) setSmoothingTimeHandler(request webserver.HttpRequest) webserver.HttpResponse {
 	valueString := request.Params["value"]
 	value64, err := strconv.ParseUint(valueString, 10, 32)
 	webResponse := webResponseStruct{}
@@ -1893,19 +6052,54 @@ func (this *controllerStruct) setFramesPerPeriodHandler(request webserver.HttpRe
 		 */
 		webResponse = webResponseStruct{
 			Success: false,
-			Reason:  "Failed to parse frame count.",
+			Reason:  "Failed to decode value.",
 		}
 
 	} else {
-		value32 := uint32(value64)
-		hwio.SetFramesPerPeriod(value32)
+		value := uint32(value64)
+		fx := this.chains()
+		errResult := error(nil)
 
 		/*
-		 * Indicate success.
+		 * Apply the setting to each signal chain and keep the first
+		 * error.
 		 */
-		webResponse = webResponseStruct{
-			Success: true,
-			Reason:  "",
+		for _, chain := range fx {
+			err = chain.SetSmoothingTime(value)
+
+			/*
+			 * Check if an error occured.
+			 */
+			if err != nil && errResult == nil {
+				errResult = err
+			}
+
+		}
+
+		/*
+		 * Check if smoothing time was successfully set.
+		 */
+		if errResult != nil {
+			reason := errResult.Error()
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		} else {
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
 		}
 
 	}
@@ -1924,48 +6118,53 @@ func (this *controllerStruct) setFramesPerPeriodHandler(request webserver.HttpRe
 }
 
 /*
- * Sets the level of a channel in the spatializer.
+ * Sets the time (in milliseconds) for which delays and reverbs in all signal
+ * chains ring out after they were bypassed or removed.
  */
-func (this *controllerStruct) setLevelHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	chainIdString := request.Params["chain"]
-	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+func (this *controllerStruct) setSpilloverTimeHandler(request webserver.HttpRequest) webserver.HttpResponse {
 	valueString := request.Params["value"]
-	value, errDistance := strconv.ParseFloat(valueString, 64)
+	value64, err := strconv.ParseUint(valueString, 10, 32)
 	webResponse := webResponseStruct{}
 
 	/*
-	 * Check if chain ID and distance value are valid.
+	 * Check if value is valid.
 	 */
-	if errChainId != nil {
+	if err != nil {
 
 		/*
 		 * Indicate failure.
 		 */
 		webResponse = webResponseStruct{
 			Success: false,
-			Reason:  "Failed to decode chain ID.",
+			Reason:  "Failed to decode value.",
 		}
 
-	} else if errDistance != nil {
+	} else {
+		value := uint32(value64)
+		fx := this.chains()
+		errResult := error(nil)
 
 		/*
-		 * Indicate failure.
+		 * Apply the setting to each signal chain and keep the first
+		 * error.
 		 */
-		webResponse = webResponseStruct{
-			Success: false,
-			Reason:  "Failed to decode level value.",
-		}
+		for _, chain := range fx {
+			err = chain.SetSpilloverTime(value)
 
-	} else {
-		chainId32 := uint32(chainId64)
-		spat := this.spat
-		err := spat.SetLevel(chainId32, value)
+			/*
+			 * Check if an error occured.
+			 */
+			if err != nil && errResult == nil {
+				errResult = err
+			}
+
+		}
 
 		/*
-		 * Check if distance was set successfully.
+		 * Check if spillover time was successfully set.
 		 */
-		if err != nil {
-			reason := err.Error()
+		if errResult != nil {
+			reason := errResult.Error()
 
 			/*
 			 * Indicate failure.
@@ -2003,15 +6202,15 @@ func (this *controllerStruct) setLevelHandler(request webserver.HttpRequest) web
 }
 
 /*
- * Sets the level of a channel in the spatializer.
+ * Enables or disables the spectrum analyzer.
  */
-func (this *controllerStruct) setLevelMeterEnabledHandler(request webserver.HttpRequest) webserver.HttpResponse {
+func (this *controllerStruct) setSpectrumAnalyzerEnabledHandler(request webserver.HttpRequest) webserver.HttpResponse {
 	valueString := request.Params["value"]
 	value, err := strconv.ParseBool(valueString)
 	webResponse := webResponseStruct{}
 
 	/*
-	 * Check if boolean value is valud.
+	 * Check if boolean value is valid.
 	 */
 	if err != nil {
 
@@ -2019,35 +6218,13 @@ func (this *controllerStruct) setLevelMeterEnabledHandler(request webserver.Http
 		 * Indicate failure.
 		 */
 		webResponse = webResponseStruct{
-			Success: true,
+			Success: false,
 			Reason:  "Failed to decode boolean value.",
 		}
 
 	} else {
-		meter := this.levelMeter
-		meter.SetEnabled(value)
-
-		/*
-		 * If level meters should be disabled, clear buffers as well.
-		 */
-		if !value {
-			buffers := this.buffers
-
-			/*
-			 * Iterate over all buffers.
-			 */
-			for _, buffer := range buffers {
-
-				/*
-				 * Clear the buffer.
-				 */
-				for i := range buffer {
-					buffer[i] = 0.0
-				}
-
-			}
-
-		}
+		analyzer := this.spectrumAnalyzer
+		analyzer.SetEnabled(value)
 
 		/*
 		 * Indicate success.
@@ -2073,260 +6250,140 @@ func (this *controllerStruct) setLevelMeterEnabledHandler(request webserver.Http
 }
 
 /*
- * Sets a value for the metronome.
+ * Sets the return level of an aux bus.
  */
-func (this *controllerStruct) setMetronomeValueHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	metr := this.metr
+func (this *controllerStruct) setReturnHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	busIdString := request.Params["bus"]
+	busId64, errBusId := strconv.ParseUint(busIdString, 10, 32)
+	valueString := request.Params["value"]
+	value, errValue := strconv.ParseFloat(valueString, 64)
 	webResponse := webResponseStruct{}
 
 	/*
-	 * Check if we have a metronome.
+	 * Check if bus ID and return level are valid.
 	 */
-	if metr != nil {
-		param := request.Params["param"]
-		value := request.Params["value"]
+	if errBusId != nil {
 
 		/*
-		 * Check which parameter should be edited.
+		 * Indicate failure.
 		 */
-		switch param {
-		case "beats-per-period":
-			rawValue, errParse := strconv.ParseUint(value, 10, 32)
-
-			/*
-			 * Check if value failed to parse.
-			 */
-			if errParse != nil {
-
-				/*
-				 * Indicate failure.
-				 */
-				webResponse = webResponseStruct{
-					Success: false,
-					Reason:  "Failed to decode metronome beats per period.",
-				}
-
-			} else {
-				value := uint32(rawValue)
-				errSet := metr.SetBeatsPerPeriod(value)
-
-				/*
-				 * Check if value could be set.
-				 */
-				if errSet != nil {
-					msg := errSet.Error()
-					reason := fmt.Sprintf("Failed to set metronome beats per period: %s", msg)
-
-					/*
-					 * Indicate failure.
-					 */
-					webResponse = webResponseStruct{
-						Success: false,
-						Reason:  reason,
-					}
-
-				} else {
-
-					/*
-					 * Indicate success.
-					 */
-					webResponse = webResponseStruct{
-						Success: true,
-						Reason:  "",
-					}
-
-				}
-
-			}
-
-		case "master-output":
-			value, err := strconv.ParseBool(value)
-
-			/*
-			 * Check if value failed to parse.
-			 */
-			if err != nil {
-
-				/*
-				 * Indicate failure.
-				 */
-				webResponse = webResponseStruct{
-					Success: false,
-					Reason:  "Failed to decode metronome master output flag.",
-				}
-
-			} else {
-				this.metrMasterOutput = value
-
-				/*
-				 * Indicate success.
-				 */
-				webResponse = webResponseStruct{
-					Success: true,
-					Reason:  "",
-				}
-
-			}
-
-		case "speed":
-			rawValue, errParse := strconv.ParseUint(value, 10, 32)
-
-			/*
-			 * Check if value failed to parse.
-			 */
-			if errParse != nil {
-
-				/*
-				 * Indicate failure.
-				 */
-				webResponse = webResponseStruct{
-					Success: false,
-					Reason:  "Failed to decode metronome speed.",
-				}
-
-			} else {
-				value := uint32(rawValue)
-				errSet := metr.SetSpeed(value)
-
-				/*
-				 * Check if value could be set.
-				 */
-				if errSet != nil {
-					msg := errSet.Error()
-					reason := fmt.Sprintf("Failed to set metronome speed: %s", msg)
-
-					/*
-					 * Indicate failure.
-					 */
-					webResponse = webResponseStruct{
-						Success: false,
-						Reason:  reason,
-					}
-
-				} else {
-
-					/*
-					 * Indicate success.
-					 */
-					webResponse = webResponseStruct{
-						Success: true,
-						Reason:  "",
-					}
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode bus ID.",
+		}
 
-				}
+	} else if errValue != nil {
 
-				/*
-				 * Indicate success.
-				 */
-				webResponse = webResponseStruct{
-					Success: true,
-					Reason:  "",
-				}
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode return level.",
+		}
 
-			}
+	} else {
+		busId32 := uint32(busId64)
+		spat := this.spat
+		err := spat.SetReturn(busId32, value)
 
-		case "tick-sound":
-			irs := this.impulseResponses
+		/*
+		 * Check if return level was set successfully.
+		 */
+		if err != nil {
+			reason := err.Error()
 
 			/*
-			 * Check if we should disable the tick sound.
+			 * Indicate failure.
 			 */
-			if value == "- NONE -" {
-				metr.SetTick(value, nil)
-
-				/*
-				 * Indicate success.
-				 */
-				webResponse = webResponseStruct{
-					Success: true,
-					Reason:  "",
-				}
-
-			} else {
-				sampleRate := this.sampleRate
-				flt := irs.CreateFilter(value, sampleRate)
-
-				/*
-				 * Check if filter was successfully loaded.
-				 */
-				if flt == nil {
-
-					/*
-					 * Indicate failure.
-					 */
-					webResponse = webResponseStruct{
-						Success: false,
-						Reason:  "Failed to load impulse response for metronome tick sound.",
-					}
-
-				} else {
-					coeffs := flt.Coefficients()
-					metr.SetTick(value, coeffs)
-
-					/*
-					 * Indicate success.
-					 */
-					webResponse = webResponseStruct{
-						Success: true,
-						Reason:  "",
-					}
-
-				}
-
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
 			}
 
-		case "tock-sound":
-			irs := this.impulseResponses
+		} else {
 
 			/*
-			 * Check if we should disable the tock sound.
+			 * Indicate success.
 			 */
-			if value == "- NONE -" {
-				metr.SetTock(value, nil)
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
 
-				/*
-				 * Indicate success.
-				 */
-				webResponse = webResponseStruct{
-					Success: true,
-					Reason:  "",
-				}
+		}
 
-			} else {
-				sampleRate := this.sampleRate
-				flt := irs.CreateFilter(value, sampleRate)
+	}
 
-				/*
-				 * Check if filter was successfully loaded.
-				 */
-				if flt == nil {
+	mimeType, buffer := this.createJSON(webResponse)
 
-					/*
-					 * Indicate failure.
-					 */
-					webResponse = webResponseStruct{
-						Success: false,
-						Reason:  "Failed to load impulse response for metronome tick sound.",
-					}
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
 
-				} else {
-					coeffs := flt.Coefficients()
-					metr.SetTock(value, coeffs)
+	return response
+}
 
-					/*
-					 * Indicate success.
-					 */
-					webResponse = webResponseStruct{
-						Success: true,
-						Reason:  "",
-					}
+/*
+ * Sets the level at which a channel is sent to an aux bus.
+ */
+func (this *controllerStruct) setSendHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	busIdString := request.Params["bus"]
+	busId64, errBusId := strconv.ParseUint(busIdString, 10, 32)
+	valueString := request.Params["value"]
+	value, errValue := strconv.ParseFloat(valueString, 64)
+	webResponse := webResponseStruct{}
 
-				}
+	/*
+	 * Check if chain ID, bus ID and send level are valid.
+	 */
+	if errChainId != nil {
 
-			}
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errBusId != nil {
 
-		default:
-			reason := fmt.Sprintf("Unknown metronome parameter: '%s'", param)
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode bus ID.",
+		}
+
+	} else if errValue != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode send level.",
+		}
+
+	} else {
+		chainId32 := uint32(chainId64)
+		busId32 := uint32(busId64)
+		spat := this.spat
+		err := spat.SetSend(chainId32, busId32, value)
+
+		/*
+		 * Check if send level was set successfully.
+		 */
+		if err != nil {
+			reason := err.Error()
 
 			/*
 			 * Indicate failure.
@@ -2336,6 +6393,16 @@ func (this *controllerStruct) setMetronomeValueHandler(request webserver.HttpReq
 				Reason:  reason,
 			}
 
+		} else {
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
 		}
 
 	}
@@ -2354,41 +6421,89 @@ func (this *controllerStruct) setMetronomeValueHandler(request webserver.HttpReq
 }
 
 /*
- * Sets a value for the tuner.
+ * Sets a numeric value as a parameter in an effects unit.
  */
-func (this *controllerStruct) setTunerValueHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	currentTuner := this.tuner
+func (this *controllerStruct) setNumericValueHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := request.Params["unit"]
+	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	param := request.Params["param"]
+	valueString := request.Params["value"]
+	value64, errValue := strconv.ParseInt(valueString, 10, 32)
 	webResponse := webResponseStruct{}
 
 	/*
-	 * Check if we have a tuner.
+	 * Check if chain ID, unit ID and value are valid.
 	 */
-	if currentTuner != nil {
-		param := request.Params["param"]
-		value := request.Params["value"]
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errUnitId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode unit ID.",
+		}
+
+	} else if errValue != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode value.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		unitId := int(unitId64)
+		value := int32(value64)
+		fx := this.chains()
+		nChains := len(fx)
 
 		/*
-		 * Check which parameter should be edited.
+		 * Check if chain ID is out of range.
 		 */
-		switch param {
-		case "channel":
-			rawValue, err := strconv.ParseInt(value, 10, 64)
+		if (chainId < 0) || (chainId >= nChains) {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Chain ID out of range.",
+			}
+
+		} else {
+			err := fx[chainId].SetNumericValue(unitId, param, value)
 
 			/*
-			 * Check if value failed to parse.
+			 * Check if bypass value was successfully set.
 			 */
 			if err != nil {
+				reason := err.Error()
 
 				/*
 				 * Indicate failure.
 				 */
 				webResponse = webResponseStruct{
 					Success: false,
-					Reason:  "Failed to decode tuner channel.",
+					Reason:  reason,
 				}
 
 			} else {
-				this.tunerChannel = int(rawValue)
 
 				/*
 				 * Indicate success.
@@ -2399,16 +6514,6 @@ func (this *controllerStruct) setTunerValueHandler(request webserver.HttpRequest
 				}
 
 			}
-		default:
-			reason := fmt.Sprintf("Unknown tuner parameter: '%s'", param)
-
-			/*
-			 * Indicate failure.
-			 */
-			webResponse = webResponseStruct{
-				Success: false,
-				Reason:  reason,
-			}
 
 		}
 
@@ -2428,14 +6533,13 @@ func (this *controllerStruct) setTunerValueHandler(request webserver.HttpRequest
 }
 
 /*
- * Sets a numeric value as a parameter in an effects unit.
+ * Sets the input trim (in decibels) of an effects unit.
  */
-func (this *controllerStruct) setNumericValueHandler(request webserver.HttpRequest) webserver.HttpResponse {
+func (this *controllerStruct) setInputTrimHandler(request webserver.HttpRequest) webserver.HttpResponse {
 	chainIdString := request.Params["chain"]
 	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
 	unitIdString := request.Params["unit"]
 	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
-	param := request.Params["param"]
 	valueString := request.Params["value"]
 	value64, errValue := strconv.ParseInt(valueString, 10, 32)
 	webResponse := webResponseStruct{}
@@ -2477,7 +6581,7 @@ func (this *controllerStruct) setNumericValueHandler(request webserver.HttpReque
 		chainId := int(chainId64)
 		unitId := int(unitId64)
 		value := int32(value64)
-		fx := this.effects
+		fx := this.chains()
 		nChains := len(fx)
 
 		/*
@@ -2494,10 +6598,10 @@ func (this *controllerStruct) setNumericValueHandler(request webserver.HttpReque
 			}
 
 		} else {
-			err := fx[chainId].SetNumericValue(unitId, param, value)
+			err := fx[chainId].SetInputTrim(unitId, value)
 
 			/*
-			 * Check if bypass value was successfully set.
+			 * Check if input trim was successfully set.
 			 */
 			if err != nil {
 				reason := err.Error()
@@ -2540,796 +6644,1132 @@ func (this *controllerStruct) setNumericValueHandler(request webserver.HttpReque
 }
 
 /*
- * Handles CGI requests that could not be dispatched to other CGIs.
+ * Sets the output level (in decibels) of an effects unit.
  */
-func (this *controllerStruct) errorHandler(request webserver.HttpRequest) webserver.HttpResponse {
-	conf := this.config
-	confServer := conf.WebServer
-	contentType := confServer.ErrorMime
-	msgBuf := bytes.NewBufferString("This CGI call is not implemented.")
-	msgBytes := msgBuf.Bytes()
+func (this *controllerStruct) setOutputLevelHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	unitIdString := request.Params["unit"]
+	unitId64, errUnitId := strconv.ParseUint(unitIdString, 10, 32)
+	valueString := request.Params["value"]
+	value64, errValue := strconv.ParseInt(valueString, 10, 32)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if chain ID, unit ID and value are valid.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if errUnitId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode unit ID.",
+		}
+
+	} else if errValue != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode value.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		unitId := int(unitId64)
+		value := int32(value64)
+		fx := this.chains()
+		nChains := len(fx)
+
+		/*
+		 * Check if chain ID is out of range.
+		 */
+		if (chainId < 0) || (chainId >= nChains) {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Chain ID out of range.",
+			}
+
+		} else {
+			err := fx[chainId].SetOutputLevel(unitId, value)
+
+			/*
+			 * Check if output level was successfully set.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
 
-	/*
-	 * Create HTTP response.
-	 */
-	response := webserver.HttpResponse{
-		Header: map[string]string{"Content-type": contentType},
-		Body:   msgBytes,
-	}
+			} else {
 
-	return response
-}
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
 
-/*
- * Dispatch CGI requests to the corresponding CGI handlers.
- */
-func (this *controllerStruct) dispatch(request webserver.HttpRequest) webserver.HttpResponse {
-	cgi := request.Params["cgi"]
-	response := webserver.HttpResponse{}
+			}
 
-	/*
-	 * Find the right CGI to handle the request.
-	 */
-	switch cgi {
-	case "add-unit":
-		response = this.addUnitHandler(request)
-	case "get-configuration":
-		response = this.getConfigurationHandler(request)
-	case "get-level-analysis":
-		response = this.getLevelAnalysisHandler(request)
-	case "get-unit-types":
-		response = this.getUnitTypesHandler(request)
-	case "get-tuner-analysis":
-		response = this.getTunerAnalysisHandler(request)
-	case "move-down":
-		response = this.moveDownHandler(request)
-	case "move-up":
-		response = this.moveUpHandler(request)
-	case "persistence-restore":
-		response = this.persistenceRestoreHandler(request)
-	case "persistence-save":
-		response = this.persistenceSaveHandler(request)
-	case "process":
-		response = this.processHandler(request)
-	case "remove-unit":
-		response = this.removeUnitHandler(request)
-	case "set-azimuth":
-		response = this.setAzimuthHandler(request)
-	case "set-bypass":
-		response = this.setBypassHandler(request)
-	case "set-discrete-value":
-		response = this.setDiscreteValueHandler(request)
-	case "set-distance":
-		response = this.setDistanceHandler(request)
-	case "set-frames-per-period":
-		response = this.setFramesPerPeriodHandler(request)
-	case "set-level":
-		response = this.setLevelHandler(request)
-	case "set-level-meter-enabled":
-		response = this.setLevelMeterEnabledHandler(request)
-	case "set-metronome-value":
-		response = this.setMetronomeValueHandler(request)
-	case "set-tuner-value":
-		response = this.setTunerValueHandler(request)
-	case "set-numeric-value":
-		response = this.setNumericValueHandler(request)
-	default:
-		response = this.errorHandler(request)
-	}
+		}
 
-	return response
-}
+	}
 
-/*
- * Perform asynchronous signal processing.
- */
-func (this *controllerStruct) processAsync() {
-	requests := this.processingTaskChannel
-	responses := this.processingResultChannel
+	mimeType, buffer := this.createJSON(webResponse)
 
 	/*
-	 * Process tasks as long as channel is open.
+	 * Create HTTP response.
 	 */
-	for task := range requests {
-		chain := task.chain
-		inputBuffer := task.inputBuffer
-		outputBuffer := task.outputBuffer
-		sampleRate := task.sampleRate
-		chain.Process(inputBuffer, outputBuffer, sampleRate)
-		responses <- true
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
 	}
 
-	close(responses)
+	return response
 }
 
 /*
- * Process audio data.
+ * Returns the descriptions of the available impulse responses, optionally
+ * filtered by category and by a search term, which must occur in the name.
+ * Both are matched regardless of case.
  */
-func (this *controllerStruct) process(inputBuffers [][]float64, outputBuffers [][]float64, sampleRate uint32) {
-	nIn := len(inputBuffers)
-	nOut := len(outputBuffers)
-	nMinOut := nIn + (spatializer.OUTPUT_COUNT + metronome.OUTPUT_COUNT)
-	buffers := this.buffers
-	levelMeter := this.levelMeter
-	levelMeterEnabled := false
-
-	/*
-	 * Check if there is a level meter and if it is enabled.
-	 */
-	if levelMeter != nil {
-		levelMeterEnabled = levelMeter.Enabled()
-	}
-
-	tunerChannel := this.tunerChannel
-
-	/*
-	 * Check if an input channel should be passed to the tuner.
-	 */
-	if (tunerChannel >= 0) && (tunerChannel < nIn) {
-		tunerInput := inputBuffers[tunerChannel]
-		currentTuner := this.tuner
-		currentTuner.Process(tunerInput, sampleRate)
-	}
+func (this *controllerStruct) getImpulseResponsesHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	category := request.Params["category"]
+	search := request.Params["search"]
+	searchLower := strings.ToLower(search)
+	irs := this.impulseResponses
+	infos := irs.Infos()
+	categories := []string{}
+	webResponses := []webImpulseResponseStruct{}
 
 	/*
-	 * Ensure that there are at least as many outputs as inputs registered.
+	 * Iterate over all impulse responses.
 	 */
-	if (nOut >= nIn) && (nIn >= 0) {
+	for _, info := range infos {
+		infoCategory := info.Category
+		contained := false
 
 		/*
-		 * Start processing for each input channel.
+		 * Check whether we already know the category.
 		 */
-		for i := 0; i < nIn; i++ {
-			chain := this.effects[i]
-			inputBuffer := inputBuffers[i]
-			outputBuffer := outputBuffers[i]
+		for _, currentCategory := range categories {
 
 			/*
-			 * Create a new signal processing task.
+			 * If categories match, the category is already known.
 			 */
-			task := processingTask{
-				chain:        chain,
-				inputBuffer:  inputBuffer,
-				outputBuffer: outputBuffer,
-				sampleRate:   sampleRate,
+			if currentCategory == infoCategory {
+				contained = true
 			}
 
-			this.processingTaskChannel <- task
 		}
 
 		/*
-		 * Wait for processing of each channel to finish.
+		 * If this category is not already known, add it to the list.
 		 */
-		for i := 0; i < nIn; i++ {
-			<-this.processingResultChannel
+		if !contained && infoCategory != "" {
+			categories = append(categories, infoCategory)
 		}
 
+		nameLower := strings.ToLower(info.Name)
+		categoryMatches := (category == "") || strings.EqualFold(category, infoCategory)
+		searchMatches := strings.Contains(nameLower, searchLower)
+
 		/*
-		 * If level meter is enabled, save input and output buffers.
+		 * Only include impulse responses matching the filter.
 		 */
-		if levelMeterEnabled {
-			copy(buffers[0:nIn], inputBuffers)
-			uBound := 2 * nIn
-			copy(buffers[nIn:uBound], outputBuffers)
+		if categoryMatches && searchMatches {
+
+			/*
+			 * Create impulse response structure.
+			 */
+			webResponse := webImpulseResponseStruct{
+				Name:         info.Name,
+				Category:     infoCategory,
+				Author:       info.Author,
+				MicPosition:  info.MicPosition,
+				License:      info.License,
+				Compensation: info.Compensation,
+				MaxLength:    info.MaxLength,
+				FadeOut:      info.FadeOut,
+				MinimumPhase: info.MinimumPhase,
+			}
+
+			webResponses = append(webResponses, webResponse)
 		}
 
 	}
 
 	/*
-	 * Check if there are enough output channels for a spatializer and a metronome.
+	 * Create impulse responses structure.
 	 */
-	if nOut >= nMinOut {
-		lastIdx := nOut - 1
-		auxBuffer := outputBuffers[lastIdx]
-		metr := this.metr
+	result := webImpulseResponsesStruct{
+		Categories:       categories,
+		ImpulseResponses: webResponses,
+	}
 
-		/*
-		 * Check if there is a metronome.
-		 */
-		if metr == nil {
-			auxBuffer = nil
-		} else {
-			metr.Process(auxBuffer)
+	mimeType, buffer := this.createJSON(result)
 
-			/*
-			 * If there level meter is enabled, save auxiliary buffer.
-			 */
-			if levelMeterEnabled {
-				idx := 2 * nIn
-				buffers[idx] = auxBuffer
-			}
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
 
-		}
+	return response
+}
 
-		spat := this.spat
+/*
+ * Returns the latency of the signal processing.
+ */
+func (this *controllerStruct) getLatencyHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	sampleRate := this.sampleRate
+	framesPerPeriod := uint32(0)
+	binding := this.binding
+
+	/*
+	 * If we are bound to a hardware interface, query frames per period.
+	 */
+	if binding != nil {
+		framesPerPeriod = hwio.FramesPerPeriod()
+	}
+
+	processing := this.compensateLatency(sampleRate)
+	chainLatencies := []webChainLatencyStruct{}
+
+	/*
+	 * Query the latency of each signal chain.
+	 */
+	for chainId, chain := range this.chains() {
+		latency := chain.Latency(sampleRate)
+		compensation := chain.Compensation()
 
 		/*
-		 * Check if there is a spatializer.
+		 * Fill in web chain latency data structure.
 		 */
-		if spat != nil {
-
-			/*
-			 * Check if metronome output should be excluded from the master output.
-			 */
-			if !this.metrMasterOutput {
-				auxBuffer = nil
-			}
+		chainLatency := webChainLatencyStruct{
+			Chain:        chainId,
+			Latency:      latency,
+			Compensation: compensation,
+		}
 
-			uBound := nIn + spatializer.OUTPUT_COUNT
-			spatializerInputs := outputBuffers[0:nIn]
-			spatializerOutputs := outputBuffers[nIn:uBound]
-			spat.Process(spatializerInputs, auxBuffer, spatializerOutputs)
-			lBoundBuf := (2 * nIn) + 1
-			uBoundBuf := lBoundBuf + spatializer.OUTPUT_COUNT
+		chainLatencies = append(chainLatencies, chainLatency)
+	}
 
-			/*
-			 * If level Meter is enabled, save spatializer output.
-			 */
-			if levelMeterEnabled {
-				copy(buffers[lBoundBuf:uBoundBuf], spatializerOutputs)
-			}
+	period := this.internalFrames(framesPerPeriod)
+	conversion := this.conversionLatency()
+	totalLatency := period + processing + conversion
+	milliseconds := 0.0
 
-		}
+	/*
+	 * Convert the latency into milliseconds.
+	 */
+	if sampleRate != 0 {
+		totalLatencyFloat := float64(totalLatency)
+		sampleRateFloat := float64(sampleRate)
+		hundredths := math.Round((100000.0 * totalLatencyFloat) / sampleRateFloat)
+		milliseconds = 0.01 * hundredths
+	}
 
+	/*
+	 * Create latency structure.
+	 */
+	result := webLatencyStruct{
+		SampleRate:   sampleRate,
+		HardwareRate: this.hardwareRate,
+		Period:       period,
+		Processing:   processing,
+		Conversion:   conversion,
+		Total:        totalLatency,
+		Milliseconds: milliseconds,
+		Chains:       chainLatencies,
 	}
 
+	mimeType, buffer := this.createJSON(result)
+
 	/*
-	 * Feed buffers to level meter, if enabled.
+	 * Create HTTP response.
 	 */
-	if levelMeterEnabled {
-		levelMeter.Process(buffers, sampleRate)
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
 	}
 
+	return response
 }
 
 /*
- * This is called when the hardware changes the sample rate.
+ * Converts a processing time (in nanoseconds) into microseconds and into the
+ * share (in percent) of a period of the given duration (in nanoseconds), both
+ * rounded to hundredths.
  */
-func (this *controllerStruct) sampleRateListener(rate uint32) {
-	this.sampleRate = rate
-	spat := this.spat
-	spat.SetSampleRate(rate)
-	metr := this.metr
-	metr.SetSampleRate(rate)
-}
+func processingLoad(nanoseconds uint32, period float64) (float64, float64) {
+	nanosecondsFloat := float64(nanoseconds)
+	microseconds := 0.01 * math.Round(0.1*nanosecondsFloat)
+	load := 0.0
 
-/*
- * Get input from the user.
- */
-func (this *controllerStruct) getInput(scanner *bufio.Scanner, prompt string) string {
-	fmt.Printf("%s", prompt)
-	scanner.Scan()
-	s := scanner.Text()
-	return s
+	/*
+	 * Only calculate the load if the duration of a period is known.
+	 */
+	if period > 0.0 {
+		hundredths := math.Round((10000.0 * nanosecondsFloat) / period)
+		load = 0.01 * hundredths
+	}
+
+	return microseconds, load
 }
 
 /*
- * Process files for batch processing.
+ * Returns the time it takes to process a block in each signal chain and in
+ * each unit inside them, so that users can find out which unit consumes most
+ * of the processing time.
+ *
+ * Times are averaged over several periods.
  */
-func (this *controllerStruct) processFiles(scanner *bufio.Scanner, targetRate uint32) {
-	effects := this.effects
-	numChannels := len(effects)
-	fmt.Printf("Web interface initiated batch processing for %d channels.\n", numChannels)
-	inputs := make([][]float64, numChannels)
-	sampleRates := make([]uint32, numChannels)
-	outputFormat := uint16(wave.AUDIO_PCM)
-	validFormat := false
+func (this *controllerStruct) getDspLoadHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	framesPerPeriod := uint32(0)
+	binding := this.binding
 
 	/*
-	 * Query the user for a target format.
+	 * If we are bound to a hardware interface, query frames per period.
 	 */
-	for !validFormat {
-		targetFormat := this.getInput(scanner, "Please enter target format ('lpcm' or 'float'): ")
+	if binding != nil {
+		framesPerPeriod = hwio.FramesPerPeriod()
+	}
 
-		/*
-		 * Find out about the target format.
-		 */
-		switch targetFormat {
-		case "lpcm":
-			outputFormat = wave.AUDIO_PCM
-			validFormat = true
-		case "float":
-			outputFormat = wave.AUDIO_IEEE_FLOAT
-			validFormat = true
-		}
+	period := 0.0
+	hardwareRate := this.hardwareRate
 
+	/*
+	 * Calculate the duration of a period in nanoseconds. The frames per
+	 * period are counted at the sample rate of the hardware.
+	 */
+	if hardwareRate != 0 {
+		framesPerPeriodFloat := float64(framesPerPeriod)
+		hardwareRateFloat := float64(hardwareRate)
+		period = (1e9 * framesPerPeriodFloat) / hardwareRateFloat
 	}
 
-	bitDepth := uint16(wave.DEFAULT_BIT_DEPTH)
-	validBitDepth := false
+	unitTypes := effects.UnitTypes()
+	numUnitTypes := len(unitTypes)
+	chainLoads := []webChainLoadStruct{}
 
 	/*
-	 * Query the user for a target bit depth.
+	 * Query the processing time of each signal chain.
 	 */
-	for !validBitDepth {
+	for chainId, chain := range this.chains() {
+		numUnits := chain.Length()
+		unitLoads := []webUnitLoadStruct{}
 
 		/*
-		 * Different formats support different bit depths.
+		 * Query the processing time of each unit in the chain.
 		 */
-		switch outputFormat {
-		case wave.AUDIO_PCM:
-			targetBitDepthString := this.getInput(scanner, "Please enter target bit depth (8 or 16 or 24 or 32): ")
-			targetBitDepth64, _ := strconv.ParseUint(targetBitDepthString, 10, 64)
+		for unitId := 0; unitId < numUnits; unitId++ {
+			unitType, errType := chain.UnitType(unitId)
+			nanoseconds, errTime := chain.UnitProcessingTime(unitId)
 
 			/*
-			 * Check if the target bit depth is valid.
+			 * The chain may have changed in the meantime.
 			 */
-			if targetBitDepth64 == 8 || targetBitDepth64 == 16 || targetBitDepth64 == 24 || targetBitDepth64 == 32 {
-				bitDepth = uint16(targetBitDepth64)
-				validBitDepth = true
-			}
+			if errType == nil && errTime == nil && unitType >= 0 && unitType < numUnitTypes {
+				unitTypeString := unitTypes[unitType]
+				microseconds, load := processingLoad(nanoseconds, period)
 
-		case wave.AUDIO_IEEE_FLOAT:
-			targetBitDepthString := this.getInput(scanner, "Please enter target bit depth (32 or 64): ")
-			targetBitDepth64, _ := strconv.ParseUint(targetBitDepthString, 10, 64)
+				/*
+				 * Fill in web unit load data structure.
+				 */
+				unitLoad := webUnitLoadStruct{
+					Unit:         unitId,
+					Type:         unitTypeString,
+					Microseconds: microseconds,
+					Load:         load,
+				}
 
-			/*
-			 * Check if the target bit depth is valid.
-			 */
-			if targetBitDepth64 == 32 || targetBitDepth64 == 64 {
-				bitDepth = uint16(targetBitDepth64)
-				validBitDepth = true
+				unitLoads = append(unitLoads, unitLoad)
 			}
 
-		default:
-			fmt.Printf("WARNING! Unrecognized format code: %#04x\n - Continuing with default bit depth: %d (This should not happen!)\n", outputFormat, bitDepth)
-			validBitDepth = true
 		}
 
-	}
-
-	/*
-	 * Query file name and channel number for each input.
-	 */
-	for fileId := 0; fileId < numChannels; fileId++ {
-		fmt.Printf("%s\n", "Enter name/path of the wave file for input.")
-		prompt := fmt.Sprintf("File for input %d: ", fileId)
-		fileName := this.getInput(scanner, prompt)
-		fileName = path.Sanitize(fileName)
+		nanoseconds := chain.ProcessingTime()
+		microseconds, load := processingLoad(nanoseconds, period)
 
 		/*
-		 * Abort if file name is empty.
+		 * Fill in web chain load data structure.
 		 */
-		if fileName == "" {
-			fmt.Printf("Leaving channel %d empty.\n", fileId)
-			inputs[fileId] = make([]float64, 0)
-			sampleRates[fileId] = DEFAULT_SAMPLE_RATE
-		} else {
-			buf, err := os.ReadFile(fileName)
-
-			/*
-			 * Check if file could be read.
-			 */
-			if err != nil {
-				fmt.Printf("Failed to read wave file. Leaving channel %d empty.\n", fileId)
-				inputs[fileId] = make([]float64, 0)
-				sampleRates[fileId] = DEFAULT_SAMPLE_RATE
-			} else {
-				f, err := wave.FromBuffer(buf)
-
-				/*
-				 * Check if file could be parsed.
-				 */
-				if err != nil {
-					msg := err.Error()
-					fmt.Printf("Failed to parse wave file: %s\n", msg)
-					inputs[fileId] = make([]float64, 0)
-					sampleRates[fileId] = DEFAULT_SAMPLE_RATE
-				} else {
-					numChannels := f.ChannelCount()
+		chainLoad := webChainLoadStruct{
+			Chain:        chainId,
+			Microseconds: microseconds,
+			Load:         load,
+			Units:        unitLoads,
+		}
 
-					/*
-					 * If file contains only one channel, take first,
-					 * otherwise ask which one to use.
-					 */
-					if numChannels == 1 {
-						c, err := f.Channel(0)
+		chainLoads = append(chainLoads, chainLoad)
+	}
 
-						/*
-						 * Check if channel could be loaded.
-						 */
-						if err != nil {
-							inputs[fileId] = make([]float64, 0)
-							sampleRates[fileId] = DEFAULT_SAMPLE_RATE
-						} else {
-							inputs[fileId] = c.Floats()
-							sampleRates[fileId] = f.SampleRate()
-						}
+	periodMicroseconds := 0.01 * math.Round(0.1*period)
 
-					} else {
-						loadedChan := false
+	/*
+	 * Create DSP load structure.
+	 */
+	result := webDspLoadStruct{
+		DSPLoad: this.dspLoad(),
+		Period:  periodMicroseconds,
+		Chains:  chainLoads,
+	}
 
-						/*
-						 * Do this until the channel has been loaded.
-						 */
-						for !loadedChan {
-							uBound := numChannels - 1
-							prompt := fmt.Sprintf("File contains %d channels. Which channel [%d, %d] to use? ", numChannels, 0, uBound)
-							channelString := this.getInput(scanner, prompt)
-							n, err := strconv.ParseUint(channelString, 10, 16)
+	mimeType, buffer := this.createJSON(result)
 
-							/*
-							 * If input is valid, load this channel.
-							 */
-							if err != nil {
-								fmt.Printf("%s\n", "Not a valid channel number.")
-							} else {
-								id := uint16(n)
-								c, err := f.Channel(id)
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
 
-								/*
-								 * Check if channel could be loaded.
-								 */
-								if err != nil {
-									msg := err.Error()
-									fmt.Printf("Failed to load channel: %s\n", msg)
-									inputs[fileId] = make([]float64, 0)
-									sampleRates[fileId] = DEFAULT_SAMPLE_RATE
-								} else {
-									inputs[fileId] = c.Floats()
-									sampleRates[fileId] = f.SampleRate()
-									loadedChan = true
-								}
+	return response
+}
 
-							}
+/*
+ * Handles CGI requests that could not be dispatched to other CGIs.
+ */
+func (this *controllerStruct) errorHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	conf := this.config
+	confServer := conf.WebServer
+	contentType := confServer.ErrorMime
+	msgBuf := bytes.NewBufferString("This CGI call is not implemented.")
+	msgBytes := msgBuf.Bytes()
 
-						}
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": contentType},
+		Body:   msgBytes,
+	}
 
-					}
+	return response
+}
 
-				}
+/*
+ * Returns the handler for a CGI or nil if there is no such CGI.
+ */
+func (this *controllerStruct) handler(cgi string) func(webserver.HttpRequest) webserver.HttpResponse {
 
-			}
+	/*
+	 * Find the right CGI to handle the request.
+	 */
+	switch cgi {
+	case "add-automation-lane":
+		return this.addAutomationLaneHandler
+	case "add-channel":
+		return this.addChannelHandler
+	case "add-scene":
+		return this.addSceneHandler
+	case "add-unit":
+		return this.addUnitHandler
+	case "apply-input-calibration":
+		return this.applyInputCalibrationHandler
+	case "cancel-process":
+		return this.cancelProcessHandler
+	case "collapse-units":
+		return this.collapseUnitsHandler
+	case "connect-ports":
+		return this.connectPortsHandler
+	case "expand-unit":
+		return this.expandUnitHandler
+	case "export-chain":
+		return this.exportChainHandler
+	case "freeze-channel":
+		return this.freezeChannelHandler
+	case "get-automation":
+		return this.getAutomationHandler
+	case "get-configuration":
+		return this.getConfigurationHandler
+	case "get-display-names":
+		return this.getDisplayNamesHandler
+	case "get-dsp-load":
+		return this.getDspLoadHandler
+	case "disconnect-ports":
+		return this.disconnectPortsHandler
+	case "get-level-analysis":
+		return this.getLevelAnalysisHandler
+	case "get-history":
+		return this.getHistoryHandler
+	case "get-impulse-responses":
+		return this.getImpulseResponsesHandler
+	case "get-input-calibration":
+		return this.getInputCalibrationHandler
+	case "get-latency":
+		return this.getLatencyHandler
+	case "get-library":
+		return this.getLibraryHandler
+	case "get-player-status":
+		return this.getPlayerStatusHandler
+	case "get-process-status":
+		return this.getProcessStatusHandler
+	case "get-ports":
+		return this.getPortsHandler
+	case "get-recording-status":
+		return this.getRecordingStatusHandler
+	case "get-setlist":
+		return this.getSetlistHandler
+	case "get-snapshots":
+		return this.getSnapshotsHandler
+	case "get-frequency-response":
+		return this.getFrequencyResponseHandler
+	case "get-spectrum-analysis":
+		return this.getSpectrumAnalysisHandler
+	case "get-unit-types":
+		return this.getUnitTypesHandler
+	case "get-tuner-analysis":
+		return this.getTunerAnalysisHandler
+	case "get-tuner-strings":
+		return this.getTunerStringsHandler
+	case "get-tuner-strobe":
+		return this.getTunerStrobeHandler
+	case "get-xruns":
+		return this.getXrunsHandler
+	case "load-player-track":
+		return this.loadPlayerTrackHandler
+	case "upload-impulse-response":
+		return this.uploadImpulseResponseHandler
+	case "import-chain":
+		return this.importChainHandler
+	case "insert-library-entry":
+		return this.insertLibraryEntryHandler
+	case "move-down":
+		return this.moveDownHandler
+	case "move-up":
+		return this.moveUpHandler
+	case "next-scene":
+		return this.nextSceneHandler
+	case "persistence-restore":
+		return this.persistenceRestoreHandler
+	case "persistence-save":
+		return this.persistenceSaveHandler
+	case "persistence-validate":
+		return this.persistenceValidateHandler
+	case "previous-scene":
+		return this.previousSceneHandler
+	case "preview-channel":
+		return this.previewChannelHandler
+	case "process":
+		return this.processHandler
+	case "program-change":
+		return this.programChangeHandler
+	case "redo":
+		return this.redoHandler
+	case "reload-impulse-responses":
+		return this.reloadImpulseResponsesHandler
+	case "reset-level-meter":
+		return this.resetLevelMeterHandler
+	case "reset-loudness":
+		return this.resetLoudnessHandler
+	case "reset-parameter":
+		return this.resetParameterHandler
+	case "remove-automation-lane":
+		return this.removeAutomationLaneHandler
+	case "remove-channel":
+		return this.removeChannelHandler
+	case "remove-library-entry":
+		return this.removeLibraryEntryHandler
+	case "remove-scene":
+		return this.removeSceneHandler
+	case "remove-unit":
+		return this.removeUnitHandler
+	case "render-channel-stem":
+		return this.renderChannelStemHandler
+	case "restart-automation":
+		return this.restartAutomationHandler
+	case "save-library-entry":
+		return this.saveLibraryEntryHandler
+	case "set-automation-value":
+		return this.setAutomationValueHandler
+	case "set-azimuth":
+		return this.setAzimuthHandler
+	case "set-bypass":
+		return this.setBypassHandler
+	case "set-channel-color":
+		return this.setChannelColorHandler
+	case "set-channel-name":
+		return this.setChannelNameHandler
+	case "set-channel-trim":
+		return this.setChannelTrimHandler
+	case "set-discrete-value":
+		return this.setDiscreteValueHandler
+	case "set-distance":
+		return this.setDistanceHandler
+	case "set-frames-per-period":
+		return this.setFramesPerPeriodHandler
+	case "set-impulse-response-options":
+		return this.setImpulseResponseOptionsHandler
+	case "set-input-trim":
+		return this.setInputTrimHandler
+	case "set-level":
+		return this.setLevelHandler
+	case "set-level-meter-ballistics":
+		return this.setLevelMeterBallisticsHandler
+	case "set-level-meter-enabled":
+		return this.setLevelMeterEnabledHandler
+	case "set-master-value":
+		return this.setMasterValueHandler
+	case "set-metronome-output":
+		return this.setMetronomeOutputHandler
+	case "set-metronome-value":
+		return this.setMetronomeValueHandler
+	case "set-mute":
+		return this.setMuteHandler
+	case "seek-player":
+		return this.seekPlayerHandler
+	case "select-scene":
+		return this.selectSceneHandler
+	case "set-player-value":
+		return this.setPlayerValueHandler
+	case "set-return":
+		return this.setReturnHandler
+	case "set-scene-program":
+		return this.setSceneProgramHandler
+	case "set-send":
+		return this.setSendHandler
+	case "set-smoothing-time":
+		return this.setSmoothingTimeHandler
+	case "set-solo":
+		return this.setSoloHandler
+	case "set-spatializer-mode":
+		return this.setSpatializerModeHandler
+	case "set-speaker-layout":
+		return this.setSpeakerLayoutHandler
+	case "set-spectrum-analyzer-enabled":
+		return this.setSpectrumAnalyzerEnabledHandler
+	case "set-spillover-time":
+		return this.setSpilloverTimeHandler
+	case "set-unit-label":
+		return this.setUnitLabelHandler
+	case "set-unit-note":
+		return this.setUnitNoteHandler
+	case "set-tuner-value":
+		return this.setTunerValueHandler
+	case "set-numeric-value":
+		return this.setNumericValueHandler
+	case "set-output-level":
+		return this.setOutputLevelHandler
+	case "set-reamp-level":
+		return this.setReampLevelHandler
+	case "set-parameter-smoothing":
+		return this.setParameterSmoothingHandler
+	case "set-peak-hold-time":
+		return this.setPeakHoldTimeHandler
+	case "start-input-calibration":
+		return this.startInputCalibrationHandler
+	case "start-player":
+		return this.startPlayerHandler
+	case "start-recording":
+		return this.startRecordingHandler
+	case "stop-player":
+		return this.stopPlayerHandler
+	case "stop-recording":
+		return this.stopRecordingHandler
+	case "store-scene":
+		return this.storeSceneHandler
+	case "store-snapshot":
+		return this.storeSnapshotHandler
+	case "tap-tempo":
+		return this.tapTempoHandler
+	case "toggle-snapshot":
+		return this.toggleSnapshotHandler
+	case "undo":
+		return this.undoHandler
+	default:
+		return nil
+	}
 
-		}
+}
 
-	}
+/*
+ * Passes a request to its handler. If the handler successfully edits the
+ * configuration, the configuration before the edit is added to the undo
+ * history.
+ */
+func (this *controllerStruct) invoke(handler func(webserver.HttpRequest) webserver.HttpResponse, request webserver.HttpRequest) webserver.HttpResponse {
+	params := request.Params
+	cgi := params["cgi"]
+	undoable, coalesce := editType(cgi)
 
 	/*
-	 * Resample all inputs to the target sample rate.
+	 * Check if the request edits the configuration.
 	 */
-	for i, input := range inputs {
-		sampleRate := sampleRates[i]
+	if !undoable {
+		return handler(request)
+	} else {
+		before := this.createPatch()
+		response := handler(request)
+		body := response.Body
+		probe := apiProbeStruct{}
+		err := json.Unmarshal(body, &probe)
 
 		/*
-		 * Check if resampling is necessary.
+		 * Only record edits which succeeded.
 		 */
-		if sampleRate != targetRate {
-			fmt.Printf("Resampling input channel %d from %d Hz to %d Hz, please wait ...\n", i, sampleRate, targetRate)
-			inputs[i] = resample.Time(input, sampleRate, targetRate)
-			runtime.GC()
+		if (err == nil) && (probe.Success != nil) && *probe.Success {
+			key := ""
+
+			/*
+			 * Check if consecutive edits should be merged.
+			 */
+			if coalesce {
+				key = editKey(params)
+			}
+
+			this.recordEdit(key, before)
 		}
 
+		return response
 	}
 
-	maxLength := int(0)
+}
+
+/*
+ * Dispatch CGI requests to the corresponding CGI handlers.
+ */
+func (this *controllerStruct) dispatch(request webserver.HttpRequest) webserver.HttpResponse {
+	cgi := request.Params["cgi"]
+	handler := this.handler(cgi)
+	language := this.requestLanguage(request.Params)
+	response := webserver.HttpResponse{}
 
 	/*
-	 * Find the length of the longest input stream.
+	 * Check if there is a handler for the CGI.
 	 */
-	for _, input := range inputs {
-		size := len(input)
+	if handler == nil {
+		response = this.errorHandler(request)
+	} else {
+		response = this.invoke(handler, request)
+	}
 
-		/*
-		 * If we found a longer input stream, store its length.
-		 */
-		if size > maxLength {
-			maxLength = size
-		}
+	return this.localizeResponse(response, language)
+}
 
-	}
+/*
+ * Dispatch CGI requests uploading files to the corresponding CGI handlers.
+ *
+ * Since these requests may be larger than others, only CGIs which receive
+ * files are accepted.
+ */
+func (this *controllerStruct) dispatchUpload(request webserver.HttpRequest) webserver.HttpResponse {
+	cgi := request.Params["cgi"]
 
 	/*
-	 * Length must be a multiple of the block size.
+	 * Check if the CGI receives files.
 	 */
-	if (maxLength % BLOCK_SIZE) != 0 {
-		maxLength = BLOCK_SIZE * ((maxLength / BLOCK_SIZE) + 1)
+	switch cgi {
+	case "load-player-track", "preview-channel", "render-channel-stem", "upload-impulse-response":
+		return this.dispatch(request)
+	default:
+		return this.errorHandler(request)
 	}
 
+}
+
+/*
+ * Formats a metric in the text exposition format of Prometheus.
+ *
+ * Each value is labeled with the name of the label given and its index, unless
+ * the label name is empty, in which case there should only be a single value.
+ */
+func formatMetric(name string, help string, metricType string, label string, values []float64) string {
+	lines := []string{}
+	lines = append(lines, "# HELP "+name+" "+help)
+	lines = append(lines, "# TYPE "+name+" "+metricType)
+
 	/*
-	 * Extend each input stream to equal length.
+	 * Add a sample for each value.
 	 */
-	for i, input := range inputs {
-		size := len(input)
+	for i, value := range values {
+		valueString := strconv.FormatFloat(value, 'g', -1, 64)
+		labels := ""
 
 		/*
-		 * If size of input stream doesn't already match, extend it.
+		 * Label the sample with its index, if required.
 		 */
-		if size != maxLength {
-			inputNew := make([]float64, maxLength)
-			copy(inputNew, input)
-			inputs[i] = inputNew
-			runtime.GC()
+		if label != "" {
+			i64 := int64(i)
+			idx := strconv.FormatInt(i64, 10)
+			labels = "{" + label + "=\"" + idx + "\"}"
 		}
 
+		lines = append(lines, name+labels+" "+valueString)
 	}
 
-	numInputs := len(inputs)
-	numOutputs := numInputs + MORE_OUTPUTS_THAN_INPUTS
-	outputs := make([][]float64, numOutputs)
-	inputBuffers := make([][]float64, numInputs)
-	outputBuffers := make([][]float64, numOutputs)
+	metric := strings.Join(lines, "\n") + "\n"
+	return metric
+}
 
-	/*
-	 * Create each inner output buffer.
-	 */
-	for i := 0; i < numOutputs; i++ {
-		outputs[i] = make([]float64, maxLength)
-		outputBuffers[i] = make([]float64, BLOCK_SIZE)
-	}
+/*
+ * Reports the health of the signal processing in the text exposition format
+ * of Prometheus, so that it can be monitored with standard tooling.
+ */
+func (this *controllerStruct) metricsHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	dspLoad := hwio.DSPLoad()
+	dspLoadFloat := float64(dspLoad)
+	xruns := hwio.Xruns()
+	xrunsFloat := float64(xruns)
+	framesPerPeriod := hwio.FramesPerPeriod()
+	framesPerPeriodFloat := float64(framesPerPeriod)
+	sampleRate := this.sampleRate
+	sampleRateFloat := float64(sampleRate)
+	processingTime := atomic.LoadUint32(&this.processingTime)
+	processingTimeFloat := 1e-9 * float64(processingTime)
+	processingTimes := this.processingTimes
+	numChains := len(processingTimes)
+	chainTimes := make([]float64, numChains)
 
 	/*
-	 * Create each inner input buffer.
+	 * Read the processing time of each signal chain.
 	 */
-	for i := 0; i < numInputs; i++ {
-		inputBuffers[i] = make([]float64, BLOCK_SIZE)
+	for i := range processingTimes {
+		chainTime := atomic.LoadUint32(&processingTimes[i])
+		chainTimes[i] = 1e-9 * float64(chainTime)
 	}
 
-	numBlocks := maxLength / BLOCK_SIZE
-	numBlocksFloat := float64(numBlocks)
-	fmt.Printf("%s\n", "Processing audio data ...")
-	oldPercents := int(0)
+	goroutines := runtime.NumGoroutine()
+	goroutinesFloat := float64(goroutines)
 
 	/*
-	 * Process each block.
+	 * All metrics reported.
 	 */
-	for block := 0; block < numBlocks; block++ {
-		blockFloat := float64(block)
-		percents := int((100.0 * blockFloat) / numBlocksFloat)
-
-		/*
-		 * Check if percentage changed.
-		 */
-		if percents != oldPercents {
-			fmt.Printf(" %d", percents)
-			oldPercents = percents
-		}
-
-		offsetStart := BLOCK_SIZE * block
-		offsetEnd := offsetStart + BLOCK_SIZE
-
-		/*
-		 * Copy part of each input stream into the input buffers.
-		 */
-		for i, input := range inputs {
-			copy(inputBuffers[i], input[offsetStart:offsetEnd])
-		}
-
-		this.process(inputBuffers, outputBuffers, targetRate)
-
-		/*
-		 * Copy the output buffers into the right place in the output streams.
-		 */
-		for i, output := range outputs {
-			copy(output[offsetStart:offsetEnd], outputBuffers[i])
-		}
-
+	metrics := []string{
+		formatMetric("dsp_load_percent", "Load of the real-time thread as reported by the audio backend.", "gauge", "", []float64{dspLoadFloat}),
+		formatMetric("dsp_xruns_total", "Buffer over- and underruns since startup.", "counter", "", []float64{xrunsFloat}),
+		formatMetric("dsp_block_size_frames", "Number of frames processed per period.", "gauge", "", []float64{framesPerPeriodFloat}),
+		formatMetric("dsp_sample_rate_hertz", "Sample rate of the signal processing.", "gauge", "", []float64{sampleRateFloat}),
+		formatMetric("dsp_processing_seconds", "Time spent processing the last period.", "gauge", "", []float64{processingTimeFloat}),
+		formatMetric("dsp_chain_processing_seconds", "Time spent processing the last period in the signal chain of each channel.", "gauge", "chain", chainTimes),
+		formatMetric("go_goroutines", "Number of goroutines that currently exist.", "gauge", "", []float64{goroutinesFloat}),
 	}
 
-	fmt.Printf("\n")
+	body := strings.Join(metrics, "")
+	buffer := []byte(body)
 
 	/*
-	 * Discard the input streams to free memory.
+	 * Create HTTP response.
 	 */
-	for i := 0; i < numInputs; i++ {
-		inputs[i] = nil
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": METRICS_MIME_TYPE},
+		Body:   buffer,
 	}
 
-	runtime.GC()
+	return response
+}
+
+/*
+ * Initialize the controller.
+ */
+func (this *controllerStruct) initialize(configPath string, nInputs uint32, channelConfigs []channelConfigStruct, useHardware bool) error {
+	content, err := os.ReadFile(configPath)
 
 	/*
-	 * Write each output into a wave file.
+	 * Check if file could be read.
 	 */
-	for i, output := range outputs {
-		f, err := wave.CreateEmpty(targetRate, outputFormat, bitDepth, 1)
+	if err != nil {
+		return fmt.Errorf("Failed to open config file: '%s'", configPath)
+	} else {
+		config := configStruct{}
+		err = json.Unmarshal(content, &config)
+		this.config = config
 
 		/*
-		 * Check whether we were able to create a wave file.
+		 * Check if file failed to unmarshal.
 		 */
 		if err != nil {
-			msg := err.Error()
-			fmt.Printf("Failed to create wave file: %s", msg)
+			return fmt.Errorf("Failed to decode config file: '%s'", configPath)
 		} else {
-			c, err := f.Channel(0)
+			ir, err := filter.Import(config.ImpulseResponses)
 
 			/*
-			 * Check whether we were able to obtain the channel.
+			 * Check if impulse responses failed to load.
 			 */
 			if err != nil {
-				msg := err.Error()
-				fmt.Printf("Failed to create output %d: %s\n", i, msg)
+				return err
 			} else {
-				c.WriteFloats(output)
-				buf, err := f.Bytes()
-				f = nil
-				runtime.GC()
+				this.impulseResponses = ir
+				fx := make([]signal.Chain, nInputs)
+				spat := spatializer.Create(nInputs)
 
 				/*
-				 * Check whether we were able to serialize the channel.
+				 * Unless the channel configuration is overridden, take it
+				 * from the config file.
 				 */
-				if err != nil {
-					msg := err.Error()
-					fmt.Printf("Failed to serialize output %d: %s\n", i, msg)
-				} else {
-					iLong := uint64(i)
-					iString := strconv.FormatUint(iLong, 10)
-					channelName := "out_" + iString
+				if channelConfigs == nil {
+					channelConfigs = config.Channels
+				}
 
-					/*
-					 * Check whether output channel is "special".
-					 */
-					switch i {
-					case numInputs:
-						channelName = "master_left"
-					case numInputs + 1:
-						channelName = "master_right"
-					case numInputs + 2:
-						channelName = "metronome"
-					}
+				internalRate := config.SampleRate
+
+				/*
+				 * An invalid internal sample rate should not prevent us
+				 * from starting, so process at the rate of the hardware.
+				 */
+				if (internalRate != 0) && ((internalRate < INTERNAL_SAMPLE_RATE_MIN) || (internalRate > INTERNAL_SAMPLE_RATE_MAX)) {
+					fmt.Printf("Ignoring internal sample rate of %d Hz, which is not between %d Hz and %d Hz.\n", internalRate, INTERNAL_SAMPLE_RATE_MIN, INTERNAL_SAMPLE_RATE_MAX)
+					internalRate = 0
+				}
+
+				this.internalRate = internalRate
+				quality := resample.Quality(resample.QUALITY_MEDIUM)
 
-					prompt := fmt.Sprintf("Output file for channel '%s': ", channelName)
-					fileName := this.getInput(scanner, prompt)
-					fileName = path.Sanitize(fileName)
+				/*
+				 * Take the quality of the conversion from the config
+				 * file, if it is given there.
+				 */
+				if config.Resampling != "" {
+					quality, err = resample.ParseQuality(config.Resampling)
 
 					/*
-					 * Check if file name is empty.
+					 * An unknown quality should not prevent us from
+					 * starting either.
 					 */
-					if fileName == "" {
-						fmt.Printf("%s\n", "Skipping output due to empty file name.")
-					} else {
-						fd, err := os.Create(fileName)
+					if err != nil {
+						fmt.Printf("Ignoring resampling quality: %s\n", err.Error())
+					}
 
-						/*
-						 * Check if file was successfully created.
-						 */
-						if err != nil {
-							fmt.Printf("%s\n", "Failed to create output file.")
-						} else {
-							_, err = fd.Write(buf)
+				}
 
-							/*
-							 * Check if buffer was written successfully.
-							 */
-							if err != nil {
-								fmt.Printf("%s\n", "Failed to write to output file.")
-							}
+				this.resampleQuality = quality
+				numChannelConfigs := uint32(len(channelConfigs))
+				channelPorts := make([]int, nInputs)
+				channelPortIds := make([]string, nInputs)
+				channelMetadata := make([]channelMetadataStruct, nInputs)
+				inputPortNames := []string{}
+				outputPortNames := []string{}
 
-							err = fd.Close()
-							buf = nil
-							runtime.GC()
+				/*
+				 * Create an effects chain and the associated ports for each input.
+				 */
+				for i := uint32(0); i < nInputs; i++ {
+					stereo := false
+					name := ""
+					color := ""
 
-							/*
-							 * Check if file was closed successfully.
-							 */
-							if err != nil {
-								msg := err.Error()
-								fmt.Printf("%s\n", "Failed to close output file.", msg)
-							}
+					/*
+					 * Check if the channel is configured as stereo and
+					 * whether it has a name and color.
+					 */
+					if i < numChannelConfigs {
+						channelConfig := channelConfigs[i]
+						stereo = channelConfig.Stereo
+						name = channelConfig.Name
+						color = channelConfig.Color
+					}
 
-						}
+					/*
+					 * Ignore colors which are not in hexadecimal notation.
+					 */
+					if !isColor(color) {
+						color = ""
+					}
 
+					/*
+					 * The name and color of the channel.
+					 */
+					channelMetadata[i] = channelMetadataStruct{
+						name:  name,
+						color: color,
 					}
 
-				}
+					i64 := uint64(i)
+					idString := strconv.FormatUint(i64, 10)
+					suffix := portNameSuffix(name)
 
-			}
+					/*
+					 * Hardware ports cannot be renamed later on, so they
+					 * are named after the configured channel names.
+					 * Batch jobs keep referring to ports by index.
+					 */
+					if useHardware && (suffix != "") {
+						idString += "_" + suffix
+					}
 
-		}
+					channelPorts[i] = len(inputPortNames)
+					channelPortIds[i] = idString
+					channelInputNames, channelOutputNames := channelPortNames(idString, stereo)
+					inputPortNames = append(inputPortNames, channelInputNames...)
+					outputPortNames = append(outputPortNames, channelOutputNames...)
 
-	}
+					/*
+					 * Stereo channels get a stereo chain, mono channels a mono one.
+					 */
+					if stereo {
+						fx[i] = signal.CreateStereoChain(ir)
+						spat.SetStereo(i, true)
+					} else {
+						fx[i] = signal.CreateChain(ir)
+					}
 
-	/*
-	 * Discard the output streams to free memory.
-	 */
-	for i := 0; i < numOutputs; i++ {
-		outputs[i] = nil
-		runtime.GC()
-	}
+				}
 
-}
+				numBuses := spat.GetBusCount()
+				buses := make([]signal.Chain, numBuses)
 
-/*
- * Initialize the controller.
- */
-func (this *controllerStruct) initialize(nInputs uint32, useHardware bool) error {
-	content, err := os.ReadFile(CONFIG_PATH)
+				/*
+				 * Create a stereo signal chain for each aux bus.
+				 */
+				for i := uint32(0); i < numBuses; i++ {
+					bus := signal.CreateStereoChain(ir)
+					spat.SetBusProcessor(i, bus)
+					buses[i] = bus
+				}
 
-	/*
-	 * Check if file could be read.
-	 */
-	if err != nil {
-		return fmt.Errorf("Failed to open config file: '%s'", CONFIG_PATH)
-	} else {
-		config := configStruct{}
-		err = json.Unmarshal(content, &config)
-		this.config = config
+				this.effects = fx
+				this.buses = buses
+				this.processingTimes = make([]uint32, nInputs)
+				this.channelPorts = channelPorts
+				this.channelPortIds = channelPortIds
 
-		/*
-		 * Check if file failed to unmarshal.
-		 */
-		if err != nil {
-			return fmt.Errorf("Failed to decode config file: '%s'", CONFIG_PATH)
-		} else {
-			ir, err := filter.Import(config.ImpulseResponses)
+				/*
+				 * The settings of the channels and of the routing.
+				 */
+				mix := mixSnapshotStruct{
+					channels:     channelMetadata,
+					tunerChannel: -1,
+				}
 
-			/*
-			 * Check if impulse responses failed to load.
-			 */
-			if err != nil {
-				return err
-			} else {
-				this.impulseResponses = ir
-				fx := make([]signal.Chain, nInputs)
+				this.mix.Store(&mix)
+				defaultChannelMetadata := make([]channelMetadataStruct, nInputs)
+				copy(defaultChannelMetadata, channelMetadata)
+				this.defaultChannelMetadata = defaultChannelMetadata
+				this.sampleRate = DEFAULT_SAMPLE_RATE
 
 				/*
-				 * Create an effects chain for each input.
+				 * Load head-related transfer functions for binaural
+				 * rendering, if configured. Otherwise, the spatializer
+				 * uses a spherical head model.
 				 */
-				for i := uint32(0); i < nInputs; i++ {
-					fx[i] = signal.CreateChain(ir)
+				if config.Hrtf != "" {
+					hrtf, err := spatializer.ImportHrtf(config.Hrtf)
+
+					/*
+					 * Failing to load them should not prevent us from
+					 * starting.
+					 */
+					if err != nil {
+						msg := err.Error()
+						fmt.Printf("Using spherical head model for binaural rendering: %s\n", msg)
+					} else {
+						spat.SetHrtf(hrtf)
+					}
+
 				}
 
-				this.effects = fx
-				this.sampleRate = DEFAULT_SAMPLE_RATE
-				spat := spatializer.Create(nInputs)
 				this.spat = spat
 				metr := metronome.Create()
 				metr.SetTick("- NONE -", nil)
 				metr.SetTock("- NONE -", nil)
 				this.metr = metr
-				this.tuner = tuner.Create()
-				this.tunerChannel = -1
-				numPorts := (2 * nInputs) + (1 + spatializer.OUTPUT_COUNT)
-				portNames := make([]string, numPorts)
+				this.masterSection = master.Create()
+				this.updateTempo()
+				currentTuner := tuner.Create()
 
 				/*
-				 * Calculate names of all input ports.
+				 * Take the note naming convention from the config file,
+				 * if it is given there.
 				 */
-				for i := uint32(0); i < nInputs; i++ {
-					i64 := uint64(i)
-					idString := strconv.FormatUint(i64, 10)
-					portNames[i] = "in_" + idString
-				}
+				if config.Notation != "" {
+					err = currentTuner.SetNotation(config.Notation)
+
+					/*
+					 * An unknown notation should not prevent us from
+					 * starting.
+					 */
+					if err != nil {
+						fmt.Printf("Ignoring notation: %s\n", err.Error())
+					}
 
-				/*
-				 * Calculate names of all output ports.
-				 */
-				for i := uint32(0); i < nInputs; i++ {
-					i64 := uint64(i)
-					idString := strconv.FormatUint(i64, 10)
-					idx := nInputs + i
-					portNames[idx] = "out_" + idString
 				}
 
+				this.tuner = currentTuner
+				this.recorder = recorder.CreateRecorder()
+				this.trackPlayer = player.CreatePlayer()
+				err = this.loadSetlist()
+
 				/*
-				 * Calculate name of metronome port.
+				 * A broken setlist should not prevent us from starting.
 				 */
-				if metr != nil {
-					idx := numPorts - 3
-					portNames[idx] = "metronome"
+				if err != nil {
+					msg := err.Error()
+					fmt.Printf("%s\n", msg)
 				}
 
+				err = this.loadLibrary()
+
 				/*
-				 * Calculate name of master outputs.
+				 * A broken library should not prevent us from starting.
 				 */
-				if spat != nil {
-					idxLeft := numPorts - 2
-					portNames[idxLeft] = "master_left"
-					idxRight := numPorts - 1
-					portNames[idxRight] = "master right"
+				if err != nil {
+					msg := err.Error()
+					fmt.Printf("%s\n", msg)
 				}
 
+				masterNames := this.masterPortNames()
+				numMasterOutputs := len(masterNames)
+				surroundNames := masterNames[spatializer.OUTPUT_COUNT:]
+				portNames := []string{}
+				portNames = append(portNames, inputPortNames...)
+				portNames = append(portNames, outputPortNames...)
+				portNames = append(portNames, "metronome", "master_left", "master_right", "player_left", "player_right")
+				portNames = append(portNames, surroundNames...)
+				numPorts := uint32(len(portNames))
+				outputPortNames = append(outputPortNames, "master_left", "master_right", "metronome", "player_left", "player_right")
+				outputPortNames = append(outputPortNames, surroundNames...)
+				this.inputPortNames = inputPortNames
+				this.outputPortNames = outputPortNames
 				buffers := make([][]float64, numPorts)
 				this.buffers = buffers
+				this.masterBuffers = make([][]float64, numMasterOutputs)
 				levelMeter, err := level.CreateMeter(numPorts, portNames)
 				this.levelMeter = levelMeter
+				this.correlationMeter = level.CreateCorrelationMeter()
+				spectrumAnalyzer, errSpectrum := spectrum.CreateAnalyzer(numPorts, portNames)
+				this.spectrumAnalyzer = spectrumAnalyzer
 
 				/*
-				 * Check if level meter was created.
+				 * Check if level meter and spectrum analyzer were created.
 				 */
 				if err != nil {
 					msg := err.Error()
 					return fmt.Errorf("Failed to create level meter: %s", msg)
+				} else if errSpectrum != nil {
+					msg := errSpectrum.Error()
+					return fmt.Errorf("Failed to create spectrum analyzer: %s", msg)
 				} else {
+					this.updateLevelMeterNames()
 					this.processingTaskChannel = make(chan processingTask, nInputs)
 					this.processingResultChannel = make(chan bool, nInputs)
 
@@ -3346,18 +7786,58 @@ func (this *controllerStruct) initialize(nInputs uint32, useHardware bool) error
 					if !useHardware {
 						return nil
 					} else {
-						this.binding, err = hwio.Register(this.process, this.sampleRateListener)
+						err = hwio.Configure(config.Audio)
 
 						/*
-						 * Setup JACK connections.
+						 * Check if hardware interface was configured.
 						 */
-						for _, connection := range config.Connections {
-							source := connection.From
-							destination := connection.To
-							hwio.Connect(source, destination)
+						if err != nil {
+							msg := err.Error()
+							return fmt.Errorf("Failed to configure hardware interface: %s", msg)
+						} else {
+							this.binding, err = hwio.Register(inputPortNames, outputPortNames, this.processLive, this.sampleRateListener)
+
+							/*
+							 * Let units prepare their filters for the
+							 * frames per period.
+							 */
+							if err == nil {
+								framesPerPeriod := hwio.FramesPerPeriod()
+								this.setBlockSize(framesPerPeriod)
+							}
+
+							midiInput := config.MidiInput
+
+							/*
+							 * If a MIDI input is configured, listen for program changes.
+							 */
+							if (err == nil) && (midiInput != "") {
+								programChanges, errMidi := hwio.ListenProgramChanges(this.binding, midiInput)
+
+								/*
+								 * MIDI is optional, so only report errors.
+								 */
+								if errMidi != nil {
+									msg := errMidi.Error()
+									fmt.Printf("Failed to open MIDI input: %s\n", msg)
+								} else {
+									this.programChanges = programChanges
+								}
+
+							}
+
+							/*
+							 * Setup connections between ports.
+							 */
+							for _, connection := range config.Connections {
+								source := connection.From
+								destination := connection.To
+								hwio.Connect(source, destination)
+							}
+
+							return err
 						}
 
-						return err
 					}
 
 				}
@@ -3377,6 +7857,33 @@ func (this *controllerStruct) finalize() {
 	this.running = false
 	binding := this.binding
 	hwio.Unregister(binding)
+	rec := this.recorder
+
+	/*
+	 * If we are still recording, finalize the files.
+	 */
+	if rec != nil {
+		status := rec.Status()
+
+		/*
+		 * Check if recorder is recording.
+		 */
+		if status.Recording() {
+			rec.Stop()
+		}
+
+	}
+
+	footswitches := this.footswitches
+
+	/*
+	 * Release the GPIO pins of the footswitches.
+	 */
+	if footswitches != nil {
+		footswitches.Close()
+		this.footswitches = nil
+	}
+
 	ptc := this.processingTaskChannel
 	close(ptc)
 }
@@ -3387,14 +7894,21 @@ func (this *controllerStruct) finalize() {
 func (this *controllerStruct) Operate(numChannels uint32) {
 	batch := numChannels > 0
 	err := fmt.Errorf("")
+	diagnostics := checkConfiguration(false)
+	numErrors := len(diagnostics.errors)
 
 	/*
-	 * If we are not in batch processing mode, acquire hardware channels.
+	 * Report all problems with the configuration at once instead of
+	 * failing on the first one. If we are not in batch processing mode,
+	 * acquire hardware channels.
 	 */
-	if !batch {
-		err = this.initialize(hwio.INPUT_CHANNELS, true)
+	if numErrors > 0 {
+		diagnostics.print()
+		err = fmt.Errorf("Found %d errors in the configuration. Run with '-check' to check the audio hardware as well.", numErrors)
+	} else if !batch {
+		err = this.initialize(CONFIG_PATH, hwio.INPUT_CHANNELS, nil, true)
 	} else {
-		err = this.initialize(numChannels, false)
+		err = this.initialize(CONFIG_PATH, numChannels, nil, false)
 	}
 
 	/*
@@ -3415,7 +7929,12 @@ func (this *controllerStruct) Operate(numChannels uint32) {
 			fmt.Printf("%s\n", "Web server did not enter message loop.")
 		} else {
 			requests := server.RegisterCgi("/cgi-bin/dsp")
+			metricsRequests := server.RegisterCgi(METRICS_PATH)
+			uploadRequests := server.RegisterUploadCgi(UPLOAD_PATH)
+			apiRequests := server.RegisterApi(API_PREFIX)
 			server.Run()
+			grpcRequests := this.startGrpcServer()
+			footswitchPresses := this.startFootswitches()
 			in := os.Stdin
 			scanner := bufio.NewScanner(in)
 
@@ -3491,10 +8010,38 @@ func (this *controllerStruct) Operate(numChannels uint32) {
 				 * This is the actual message pump.
 				 */
 				for this.running {
-					request := <-requests
-					response := this.dispatch(request)
-					respond := request.Respond
-					respond <- response
+
+					/*
+					 * Handle CGI and API requests, program changes and
+					 * footswitches.
+					 */
+					select {
+					case request := <-requests:
+						response := this.dispatch(request)
+						respond := request.Respond
+						respond <- response
+					case request := <-uploadRequests:
+						response := this.dispatchUpload(request)
+						respond := request.Respond
+						respond <- response
+					case request := <-apiRequests:
+						response := this.dispatchApi(request)
+						respond := request.Respond
+						respond <- response
+					case request := <-grpcRequests:
+						response := this.dispatchApi(request)
+						respond := request.Respond
+						respond <- response
+					case request := <-metricsRequests:
+						response := this.metricsHandler(request)
+						respond := request.Respond
+						respond <- response
+					case program := <-this.programChanges:
+						this.handleProgramChange(program)
+					case idx := <-footswitchPresses:
+						this.handleFootswitch(idx)
+					}
+
 				}
 
 				/*
@@ -0,0 +1,2607 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/effects"
+	"github.com/andrepxx/go-dsp-guitar/filter"
+	"github.com/andrepxx/go-dsp-guitar/level"
+	"github.com/andrepxx/go-dsp-guitar/master"
+	"github.com/andrepxx/go-dsp-guitar/metronome"
+	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/signal"
+	"github.com/andrepxx/go-dsp-guitar/spatializer"
+	"github.com/andrepxx/go-dsp-guitar/wave"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+ * Creates a controller with a single mono channel holding an overdrive,
+ * without binding it to hardware.
+ */
+func createTestController(t *testing.T) *controllerStruct {
+	spat := spatializer.Create(1)
+	chain := signal.CreateChain(nil)
+	_, err := chain.AppendUnit(effects.UNIT_OVERDRIVE)
+
+	/*
+	 * Check if unit was added.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to append unit: %s", err.Error())
+	}
+
+	numBuses := spat.GetBusCount()
+	buses := make([]signal.Chain, numBuses)
+
+	/*
+	 * Create a stereo signal chain for each aux bus.
+	 */
+	for i := uint32(0); i < numBuses; i++ {
+		bus := signal.CreateStereoChain(nil)
+		spat.SetBusProcessor(i, bus)
+		buses[i] = bus
+	}
+
+	portNames := []string{"in_0", "out_0"}
+	levelMeter, err := level.CreateMeter(2, portNames)
+
+	/*
+	 * Check if level meter was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create level meter: %s", err.Error())
+	}
+
+	metr := metronome.Create()
+	metr.SetTick("- NONE -", nil)
+	metr.SetTock("- NONE -", nil)
+
+	/*
+	 * The controller under test.
+	 */
+	controller := controllerStruct{
+		effects:                []signal.Chain{chain},
+		buses:                  buses,
+		spat:                   spat,
+		channelPorts:           []int{0},
+		channelPortIds:         []string{"0"},
+		defaultChannelMetadata: make([]channelMetadataStruct, 1),
+		inputPortNames:         []string{"in_0"},
+		outputPortNames:        []string{"out_0"},
+		masterBuffers:          make([][]float64, spatializer.OUTPUT_COUNT),
+		levelMeter:             levelMeter,
+		metr:                   metr,
+		masterSection:          master.Create(),
+		processingTimes:        make([]uint32, 1),
+	}
+
+	/*
+	 * The settings of the channel and of the routing.
+	 */
+	mix := mixSnapshotStruct{
+		channels:     make([]channelMetadataStruct, 1),
+		tunerChannel: -1,
+	}
+
+	controller.mix.Store(&mix)
+	return &controller
+}
+
+/*
+ * Verify that restoring a patch stops a morph which is still in progress, so
+ * that the morph does not overwrite the restored values.
+ */
+func TestRestorePatchStopsMorph(t *testing.T) {
+	controller := createTestController(t)
+	chain := controller.effects[0]
+	chain.SetNumericValue(0, "drive", 0)
+	from := controller.createPatch()
+	chain.SetNumericValue(0, "drive", 100)
+	to := controller.createPatch()
+	chain.SetNumericValue(0, "drive", 50)
+	restored := controller.createPatch()
+	patchBytes, err := json.Marshal(restored)
+
+	/*
+	 * Check if patch was marshalled.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to marshal patch: %s", err.Error())
+	}
+
+	chain.SetNumericValue(0, "drive", 0)
+	stop := make(chan bool)
+	done := make(chan bool)
+	controller.snapshotMutex.Lock()
+	controller.morphStop = stop
+	controller.morphDone = done
+	controller.snapshotMutex.Unlock()
+	go controller.morph(from, to, 10000, stop, done)
+	stepDuration := SNAPSHOT_MORPH_STEP * time.Millisecond
+	time.Sleep(5 * stepDuration)
+	err = controller.restorePatch(patchBytes)
+
+	/*
+	 * Check if patch was restored.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to restore patch: %s", err.Error())
+	}
+
+	/*
+	 * The morph must have finished.
+	 */
+	select {
+	case <-done:
+	default:
+		t.Errorf("%s", "Morph is still running after restoring a patch.")
+	}
+
+	time.Sleep(5 * stepDuration)
+	drive, _ := chain.GetNumericValue(0, "drive")
+
+	/*
+	 * The restored value must be kept.
+	 */
+	if drive != 50 {
+		t.Errorf("Drive should be %d after restoring the patch, but is %d.", 50, drive)
+	}
+
+}
+
+/*
+ * Verify that failures of handlers are translated into status codes
+ * according to their reason.
+ */
+func TestApiFailureStatus(t *testing.T) {
+
+	/*
+	 * Reasons given by handlers.
+	 */
+	reasons := []string{
+		"Failed to decode chain ID.",
+		"Chain ID out of range.",
+		"Cannot set numeric value: No unit 7.",
+		"Unknown snapshot: 'z'",
+		"Failed to write setlist 'setlist.json': disk full",
+		"Cannot remove the last channel.",
+	}
+
+	/*
+	 * Status codes expected for each reason.
+	 */
+	expected := []int{
+		http.StatusBadRequest,
+		http.StatusNotFound,
+		http.StatusNotFound,
+		http.StatusNotFound,
+		http.StatusInternalServerError,
+		http.StatusBadRequest,
+	}
+
+	/*
+	 * Translate each reason.
+	 */
+	for i, reason := range reasons {
+		statusCode := apiFailureStatus(reason)
+
+		/*
+		 * Check if reason was translated as expected.
+		 */
+		if statusCode != expected[i] {
+			t.Errorf("Reason '%s' should map to status %d, but maps to %d.", reason, expected[i], statusCode)
+		}
+
+	}
+
+}
+
+/*
+ * Verify that converting the sample rate in live mode passes the signal
+ * through, only delayed by the conversion.
+ */
+func TestProcessLive(t *testing.T) {
+	controller := createTestController(t)
+	chain := controller.effects[0]
+	chain.RemoveUnit(0)
+	controller.internalRate = 96000
+	controller.hardwareRate = 48000
+	controller.sampleRate = 96000
+	controller.processingTaskChannel = make(chan processingTask, 1)
+	controller.processingResultChannel = make(chan bool, 1)
+	go controller.processAsync()
+
+	t.Cleanup(func() {
+		close(controller.processingTaskChannel)
+	})
+
+	blockSize := 256
+	numBlocks := 20
+	n := blockSize * numBlocks
+	in := make([]float64, n)
+	out := make([]float64, n)
+
+	/*
+	 * Generate a sine wave.
+	 */
+	for i := range in {
+		iFloat := float64(i)
+		arg := (2.0 * math.Pi * 1000.0 * iFloat) / 48000.0
+		in[i] = 0.5 * math.Sin(arg)
+	}
+
+	/*
+	 * Process the signal period by period.
+	 */
+	for lBound := 0; lBound < n; lBound += blockSize {
+		uBound := lBound + blockSize
+		inputBuffers := [][]float64{in[lBound:uBound]}
+		outputBuffers := [][]float64{out[lBound:uBound]}
+		controller.processLive(inputBuffers, outputBuffers, 48000)
+	}
+
+	delay := conversionDelay(48000, 96000, controller.resampleQuality)
+
+	/*
+	 * The output follows the input after the delay of the conversion.
+	 */
+	for i := delay + 16; i < n; i++ {
+		expected := in[i-delay]
+
+		/*
+		 * Check if we found a significant difference.
+		 */
+		if math.Abs(out[i]-expected) > 1e-2 {
+			t.Errorf("Sample %d should be %f, but is %f.", i, expected, out[i])
+			break
+		}
+
+	}
+
+	latency := controller.conversionLatency()
+	expectedLatency := uint32(2 * delay)
+
+	/*
+	 * The latency is reported at the internal sample rate.
+	 */
+	if latency != expectedLatency {
+		t.Errorf("Latency should be %d, but is %d.", expectedLatency, latency)
+	}
+
+}
+
+/*
+ * Renders an input file through an overdrive and a delay into an output file,
+ * either entirely or only a region of it, and returns the samples written.
+ */
+func renderTestFile(t *testing.T, inputName string, outputName string, regionStart int, regionEnd int, preroll int) []float64 {
+	controller := createTestController(t)
+	chain := controller.effects[0]
+	id, _ := chain.AppendUnit(effects.UNIT_DELAY)
+	chain.SetNumericValue(id, "delay_time", 20)
+	chain.SetNumericValue(id, "feedback", -60)
+	chain.SetBypass(id, false)
+	controller.processingTaskChannel = make(chan processingTask, 1)
+	controller.processingResultChannel = make(chan bool, 1)
+	go controller.processAsync()
+
+	t.Cleanup(func() {
+		close(controller.processingTaskChannel)
+	})
+
+	inputFile, err := openInputFile(inputName)
+
+	/*
+	 * Check if input file was opened.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to open input file: %s", err.Error())
+	}
+
+	outputFile, err := createOutputFile(outputName, []int{0}, 96000, wave.CONTAINER_RIFF, wave.AUDIO_IEEE_FLOAT, 64)
+
+	/*
+	 * Check if output file was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create output file: %s", err.Error())
+	}
+
+	inputFiles := []*inputFileStruct{inputFile}
+	outputFiles := []*outputFileStruct{outputFile}
+	err = controller.renderFiles(inputFiles, 96000, outputFiles, regionStart, regionEnd, preroll)
+	closeInputFiles(inputFiles)
+	closeOutputFiles(outputFiles)
+
+	/*
+	 * Check if rendering was successful.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to render files: %s", err.Error())
+	}
+
+	samples, _, err := readWaveChannel(outputName, 0)
+
+	/*
+	 * Check if output file was read back.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to read output file: %s", err.Error())
+	}
+
+	return samples
+}
+
+/*
+ * Verify that rendering a region of the inputs produces exactly the frames of
+ * the region, which match the ones of rendering the inputs entirely.
+ */
+func TestRenderRegion(t *testing.T) {
+	dir := t.TempDir()
+	inputName := filepath.Join(dir, "input.wav")
+	fd, err := os.Create(inputName)
+
+	/*
+	 * Check if input file was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create input file: %s", err.Error())
+	}
+
+	writer, _ := wave.CreateWriter(fd, 96000, wave.AUDIO_IEEE_FLOAT, 64, 1)
+	n := 5 * BLOCK_SIZE
+	in := make([]float64, n)
+
+	/*
+	 * Generate a sine wave.
+	 */
+	for i := range in {
+		iFloat := float64(i)
+		arg := (2.0 * math.Pi * 440.0 * iFloat) / 96000.0
+		in[i] = 0.5 * math.Sin(arg)
+	}
+
+	writer.Write([][]float64{in})
+	writer.Close()
+	fd.Close()
+	full := renderTestFile(t, inputName, filepath.Join(dir, "full.wav"), 0, 0, 0)
+	numFull := len(full)
+
+	/*
+	 * The whole input is rendered.
+	 */
+	if numFull != n {
+		t.Fatalf("Length of full rendering should be %d, but is %d.", n, numFull)
+	}
+
+	regionStart := (2 * BLOCK_SIZE) + 1234
+	regionEnd := (3 * BLOCK_SIZE) + 567
+	region := renderTestFile(t, inputName, filepath.Join(dir, "region.wav"), regionStart, regionEnd, BLOCK_SIZE)
+	numRegion := len(region)
+	expectedLength := regionEnd - regionStart
+
+	/*
+	 * Only the region is rendered.
+	 */
+	if numRegion != expectedLength {
+		t.Fatalf("Length of region should be %d, but is %d.", expectedLength, numRegion)
+	}
+
+	/*
+	 * After priming, the region matches the full rendering.
+	 */
+	for i, sample := range region {
+		expected := full[regionStart+i]
+
+		/*
+		 * Check if we found a significant difference.
+		 */
+		if math.Abs(sample-expected) > 1e-6 {
+			t.Errorf("Sample %d of region should be %f, but is %f.", i, expected, sample)
+			break
+		}
+
+	}
+
+}
+
+/*
+ * Verify that the channels of a file feed consecutive inputs and that outputs
+ * are written into the channels of a single file.
+ */
+func TestRenderChannels(t *testing.T) {
+	dir := t.TempDir()
+	inputName := filepath.Join(dir, "input.wav")
+	fd, err := os.Create(inputName)
+
+	/*
+	 * Check if input file was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create input file: %s", err.Error())
+	}
+
+	writer, _ := wave.CreateWriter(fd, 96000, wave.AUDIO_IEEE_FLOAT, 64, 2)
+	n := 2 * BLOCK_SIZE
+	silence := make([]float64, n)
+	sine := make([]float64, n)
+
+	/*
+	 * Generate a sine wave.
+	 */
+	for i := range sine {
+		iFloat := float64(i)
+		arg := (2.0 * math.Pi * 440.0 * iFloat) / 96000.0
+		sine[i] = 0.5 * math.Sin(arg)
+	}
+
+	writer.Write([][]float64{silence, sine})
+	writer.Close()
+	fd.Close()
+	limited, err := openInputChannels(inputName, 1)
+
+	/*
+	 * Only as many channels as requested are opened.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to open channels of input file: %s", err.Error())
+	} else if len(limited) != 1 {
+		t.Errorf("Should open %d channels, but opened %d.", 1, len(limited))
+	}
+
+	closeInputFiles(limited)
+	channelFiles, err := openInputChannels(inputName, 4)
+
+	/*
+	 * Each channel of the file is opened once.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to open channels of input file: %s", err.Error())
+	} else if len(channelFiles) != 2 {
+		t.Fatalf("Should open %d channels, but opened %d.", 2, len(channelFiles))
+	}
+
+	/*
+	 * Each input reads its own channel.
+	 */
+	for i, channelFile := range channelFiles {
+		channel := channelFile.channel
+
+		/*
+		 * Check if the input reads the right channel.
+		 */
+		if channel != uint16(i) {
+			t.Errorf("Input %d should read channel %d, but reads channel %d.", i, i, channel)
+		}
+
+	}
+
+	controller := createTestController(t)
+	controller.processingTaskChannel = make(chan processingTask, 1)
+	controller.processingResultChannel = make(chan bool, 1)
+	go controller.processAsync()
+
+	t.Cleanup(func() {
+		close(controller.processingTaskChannel)
+	})
+
+	outputName := filepath.Join(dir, "output.wav")
+	outputFile, err := createOutputFile(outputName, []int{0, 1}, 96000, wave.CONTAINER_RIFF, wave.AUDIO_IEEE_FLOAT, 64)
+
+	/*
+	 * Check if output file was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create output file: %s", err.Error())
+	}
+
+	inputFiles := []*inputFileStruct{channelFiles[1]}
+	outputFiles := []*outputFileStruct{outputFile}
+	err = controller.renderFiles(inputFiles, 96000, outputFiles, 0, 0, 0)
+	closeInputFiles(channelFiles)
+	closeOutputFiles(outputFiles)
+
+	/*
+	 * Check if rendering was successful.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to render files: %s", err.Error())
+	}
+
+	result, err := openInputFile(outputName)
+
+	/*
+	 * Check if output file was opened.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to open output file: %s", err.Error())
+	}
+
+	channelCount := result.reader.ChannelCount()
+	length := result.reader.Length()
+	closeInputFiles([]*inputFileStruct{result})
+
+	/*
+	 * Each port is written to a channel.
+	 */
+	if (channelCount != 2) || (length != uint64(n)) {
+		t.Errorf("Output should hold %d channels of %d samples, but holds %d channels of %d samples.", 2, n, channelCount, length)
+	}
+
+	samples, _, err := readWaveChannel(outputName, 0)
+
+	/*
+	 * Check if output channel was read.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to read output channel: %s", err.Error())
+	}
+
+	peak := float64(0.0)
+
+	/*
+	 * Find the peak of the output.
+	 */
+	for _, sample := range samples {
+		peak = math.Max(peak, math.Abs(sample))
+	}
+
+	/*
+	 * The channel fed by the sine wave should not be silent.
+	 */
+	if peak == 0.0 {
+		t.Errorf("%s", "Output of the channel fed by the second channel of the input is silent.")
+	}
+
+}
+
+/*
+ * Verify that the progress of a rendering is reported, that a rendering can be
+ * cancelled and that no second rendering is started while one is running.
+ */
+func TestRenderProgress(t *testing.T) {
+	dir := t.TempDir()
+	inputName := filepath.Join(dir, "input.wav")
+	fd, err := os.Create(inputName)
+
+	/*
+	 * Check if input file was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create input file: %s", err.Error())
+	}
+
+	writer, _ := wave.CreateWriter(fd, 96000, wave.AUDIO_IEEE_FLOAT, 64, 1)
+	in := make([]float64, 5*BLOCK_SIZE)
+	writer.Write([][]float64{in})
+	writer.Close()
+	fd.Close()
+	controller := createTestController(t)
+	controller.processingTaskChannel = make(chan processingTask, 1)
+	controller.processingResultChannel = make(chan bool, 1)
+	go controller.processAsync()
+
+	t.Cleanup(func() {
+		close(controller.processingTaskChannel)
+	})
+
+	/*
+	 * Renders the input file and returns the error rendering failed with.
+	 */
+	render := func(name string) error {
+		inputFile, err := openInputFile(inputName)
+
+		/*
+		 * Check if input file was opened.
+		 */
+		if err != nil {
+			t.Fatalf("Failed to open input file: %s", err.Error())
+		}
+
+		outputName := filepath.Join(dir, name)
+		outputFile, err := createOutputFile(outputName, []int{0}, 96000, wave.CONTAINER_RIFF, wave.AUDIO_IEEE_FLOAT, 64)
+
+		/*
+		 * Check if output file was created.
+		 */
+		if err != nil {
+			t.Fatalf("Failed to create output file: %s", err.Error())
+		}
+
+		inputFiles := []*inputFileStruct{inputFile}
+		outputFiles := []*outputFileStruct{outputFile}
+		err = controller.renderFiles(inputFiles, 96000, outputFiles, 0, 0, 0)
+		closeInputFiles(inputFiles)
+		closeOutputFiles(outputFiles)
+		return err
+	}
+
+	/*
+	 * Requests the status of the rendering.
+	 */
+	getStatus := func() webProcessStatusStruct {
+		request := webserver.HttpRequest{}
+		response := controller.getProcessStatusHandler(request)
+		status := webProcessStatusStruct{}
+		err := json.Unmarshal(response.Body, &status)
+
+		/*
+		 * Check if status was decoded.
+		 */
+		if err != nil {
+			t.Fatalf("Failed to decode status of rendering: %s", err.Error())
+		}
+
+		return status
+	}
+
+	status := getStatus()
+
+	/*
+	 * Nothing was rendered yet.
+	 */
+	if status.Started || status.Running {
+		t.Errorf("Rendering should not have started, but status is %v.", status)
+	}
+
+	request := webserver.HttpRequest{}
+	response := controller.cancelProcessHandler(request)
+	webResponse := webResponseStruct{}
+	json.Unmarshal(response.Body, &webResponse)
+
+	/*
+	 * Only a running rendering can be cancelled.
+	 */
+	if webResponse.Success {
+		t.Errorf("%s", "Cancelling should fail while nothing is rendered.")
+	}
+
+	controller.render.start()
+	err = render("full.wav")
+	controller.render.finish(err)
+	status = getStatus()
+
+	/*
+	 * The rendering should be complete.
+	 */
+	if err != nil {
+		t.Errorf("Failed to render files: %s", err.Error())
+	} else if !status.Started || status.Running || status.Cancelled || (status.Percent != 100.0) || (status.Error != "") {
+		t.Errorf("Rendering should be complete, but status is %v.", status)
+	}
+
+	controller.render.start()
+	controller.running = true
+	response = controller.processHandler(request)
+	webResponse = webResponseStruct{}
+	json.Unmarshal(response.Body, &webResponse)
+
+	/*
+	 * No second rendering may be started.
+	 */
+	if webResponse.Success || !controller.running {
+		t.Errorf("%s", "Processing should not start while a rendering is running.")
+	}
+
+	response = controller.cancelProcessHandler(request)
+	webResponse = webResponseStruct{}
+	json.Unmarshal(response.Body, &webResponse)
+
+	/*
+	 * A running rendering can be cancelled.
+	 */
+	if !webResponse.Success {
+		t.Errorf("Failed to cancel rendering: %s", webResponse.Reason)
+	}
+
+	err = render("cancelled.wav")
+	controller.render.finish(err)
+	status = getStatus()
+
+	/*
+	 * The rendering should stop.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Cancelled rendering should fail.")
+	} else if status.Running || !status.Cancelled || (status.Percent == 100.0) {
+		t.Errorf("Rendering should be cancelled, but status is %v.", status)
+	}
+
+}
+
+/*
+ * Verify that a frozen chain, convolved with a signal, produces the same
+ * output as the chain itself, and that freezing leaves the chain untouched.
+ */
+func TestFreezeChain(t *testing.T) {
+	controller := createTestController(t)
+	chain := controller.effects[0]
+	id, _ := chain.AppendUnit(effects.UNIT_DELAY)
+	chain.SetNumericValue(id, "delay_time", 5)
+	chain.SetNumericValue(id, "feedback", -6)
+	chain.SetBypass(id, false)
+	before := controller.persistChain(chain)
+	n := 4800
+	impulse := make([]float64, n)
+	impulse[0] = 1.0
+	inputs := [][]float64{impulse}
+	outputs := controller.renderChain(chain, inputs, n, 96000)
+	numOutputs := len(outputs)
+
+	/*
+	 * A mono chain has a single output.
+	 */
+	if numOutputs != 1 {
+		t.Fatalf("Number of outputs should be %d, but is %d.", 1, numOutputs)
+	}
+
+	ir := outputs[0]
+	numIr := len(ir)
+
+	/*
+	 * The impulse response has the length requested.
+	 */
+	if numIr != n {
+		t.Fatalf("Length of impulse response should be %d, but is %d.", n, numIr)
+	}
+
+	/*
+	 * The first echo arrives after the delay time.
+	 */
+	if math.Abs(ir[480]) < 0.1 {
+		t.Errorf("Sample %d of impulse response should be significant, but is %f.", 480, ir[480])
+	}
+
+	in := make([]float64, n)
+
+	/*
+	 * Generate a decaying sine wave.
+	 */
+	for i := range in {
+		iFloat := float64(i)
+		arg := (2.0 * math.Pi * 440.0 * iFloat) / 96000.0
+		in[i] = 0.2 * math.Sin(arg) * math.Exp(-iFloat/1000.0)
+	}
+
+	inputs = [][]float64{in}
+	outputs = controller.renderChain(chain, inputs, n, 96000)
+	out := outputs[0]
+
+	/*
+	 * Convolving with the impulse response matches the output of the
+	 * chain.
+	 */
+	for i := 0; i < n; i++ {
+		expected := float64(0.0)
+
+		/*
+		 * Calculate the convolution at this sample.
+		 */
+		for j := 0; j <= i; j++ {
+			expected += in[j] * ir[i-j]
+		}
+
+		/*
+		 * Check if we found a significant difference.
+		 */
+		if math.Abs(out[i]-expected) > 1e-6 {
+			t.Errorf("Sample %d of output should be %f, but is %f.", i, expected, out[i])
+			break
+		}
+
+	}
+
+	after := controller.persistChain(chain)
+
+	/*
+	 * Rendering must not change the original chain.
+	 */
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("Chain should be %v, but is %v.", before, after)
+	}
+
+}
+
+/*
+ * A clip uploaded in a multipart request.
+ */
+type clipFileStruct struct {
+	*bytes.Reader
+}
+
+/*
+ * Closing a clip has no effect.
+ */
+func (this *clipFileStruct) Close() error {
+	return nil
+}
+
+/*
+ * Encodes a sine wave of a certain length as a mono wave file.
+ */
+func createClip(t *testing.T, sampleRate uint32, length int) []byte {
+	file, err := wave.CreateEmpty(sampleRate, wave.AUDIO_IEEE_FLOAT, 32, 1)
+
+	/*
+	 * Check if wave file was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create wave file: %s", err.Error())
+	}
+
+	samples := make([]float64, length)
+	sampleRateFloat := float64(sampleRate)
+
+	/*
+	 * Generate a sine wave.
+	 */
+	for i := range samples {
+		iFloat := float64(i)
+		arg := (2.0 * math.Pi * 220.0 * iFloat) / sampleRateFloat
+		samples[i] = 0.25 * math.Sin(arg)
+	}
+
+	channel, _ := file.Channel(0)
+	channel.WriteFloats(samples)
+	buffer, err := file.Bytes()
+
+	/*
+	 * Check if wave file was encoded.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to encode wave file: %s", err.Error())
+	}
+
+	return buffer
+}
+
+/*
+ * Verify that previewing a channel returns a wave file holding the output of
+ * its chain for an uploaded clip at the current sample rate, followed by the
+ * tail, and that clips which are missing or too long are rejected.
+ */
+func TestPreviewChannel(t *testing.T) {
+	controller := createTestController(t)
+	controller.sampleRate = 96000
+	chain := controller.effects[0]
+	id, _ := chain.AppendUnit(effects.UNIT_DELAY)
+	chain.SetNumericValue(id, "delay_time", 5)
+	chain.SetBypass(id, false)
+	clip := createClip(t, 48000, 4800)
+	reader := bytes.NewReader(clip)
+
+	/*
+	 * Request to preview the channel.
+	 */
+	request := webserver.HttpRequest{
+		Params: map[string]string{
+			"cgi":   "preview-channel",
+			"chain": "0",
+			"tail":  "100",
+		},
+		Files: map[string][]multipart.File{
+			"clipfile": []multipart.File{&clipFileStruct{reader}},
+		},
+	}
+
+	response := controller.dispatchUpload(request)
+	contentType := response.Header["Content-type"]
+
+	/*
+	 * The response holds audio.
+	 */
+	if contentType != PREVIEW_MIME_TYPE {
+		t.Fatalf("Content type should be '%s', but is '%s': %s", PREVIEW_MIME_TYPE, contentType, string(response.Body))
+	}
+
+	file, err := wave.FromBuffer(response.Body)
+
+	/*
+	 * Check if the response is a wave file.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to decode preview: %s", err.Error())
+	}
+
+	sampleRate := file.SampleRate()
+
+	/*
+	 * The preview is rendered at the current sample rate.
+	 */
+	if sampleRate != 96000 {
+		t.Errorf("Sample rate should be %d, but is %d.", 96000, sampleRate)
+	}
+
+	inputs, length, _ := controller.decodeClip(clip)
+	length += 9600
+	outputs := controller.renderChain(chain, inputs, length, 96000)
+	expected := outputs[0]
+	channel, _ := file.Channel(0)
+	actual := channel.Floats()
+	numActual := len(actual)
+
+	/*
+	 * The preview contains the clip followed by the tail.
+	 */
+	if numActual != length {
+		t.Fatalf("Length of preview should be %d, but is %d.", length, numActual)
+	}
+
+	/*
+	 * The preview matches the output of the chain.
+	 */
+	for i, sample := range actual {
+
+		/*
+		 * Check if we found a significant difference.
+		 */
+		if math.Abs(sample-expected[i]) > 1e-6 {
+			t.Errorf("Sample %d of preview should be %f, but is %f.", i, expected[i], sample)
+			break
+		}
+
+	}
+
+	controller.sampleRate = 8000
+	clip = createClip(t, 8000, 8*(PREVIEW_MAX_LENGTH+1))
+	reader = bytes.NewReader(clip)
+	request.Files["clipfile"] = []multipart.File{&clipFileStruct{reader}}
+	response = controller.dispatchUpload(request)
+	webResponse := webResponseStruct{}
+	json.Unmarshal(response.Body, &webResponse)
+	expectedReason := fmt.Sprintf("Failed to render preview: Clip must not exceed %d ms.", PREVIEW_MAX_LENGTH)
+
+	/*
+	 * Clips which are too long are rejected.
+	 */
+	if webResponse.Success || (webResponse.Reason != expectedReason) {
+		t.Errorf("Previewing a long clip should fail with '%s', but returned %v.", expectedReason, webResponse)
+	}
+
+	delete(request.Files, "clipfile")
+	response = controller.dispatchUpload(request)
+	webResponse = webResponseStruct{}
+	json.Unmarshal(response.Body, &webResponse)
+
+	/*
+	 * Requests without a clip are rejected.
+	 */
+	if webResponse.Success || (webResponse.Reason != "No clip sent in request.") {
+		t.Errorf("Previewing without a clip should fail, but returned %v.", webResponse)
+	}
+
+}
+
+/*
+ * Verify that the options of an impulse response are set through the API,
+ * reported along with its description and applied when it is loaded.
+ */
+func TestImpulseResponseOptions(t *testing.T) {
+	controller := createTestController(t)
+	dir := t.TempDir()
+	irPath := filepath.Join(dir, "ir.json")
+	wavePath := filepath.Join(dir, "cabinet.wav")
+	file, _ := wave.CreateEmpty(48000, wave.AUDIO_IEEE_FLOAT, 32, 1)
+	samples := make([]float64, 4800)
+
+	/*
+	 * Generate a decaying impulse response.
+	 */
+	for i := range samples {
+		iFloat := float64(i)
+		samples[i] = 0.5 * math.Exp(-iFloat/480.0)
+	}
+
+	channel, _ := file.Channel(0)
+	channel.WriteFloats(samples)
+	waveBytes, _ := file.Bytes()
+	os.WriteFile(wavePath, waveBytes, 0644)
+	descriptor := fmt.Sprintf(`[{"Name": "Cabinet", "Path": "%s"}]`, wavePath)
+	os.WriteFile(irPath, []byte(descriptor), 0644)
+	irs, err := filter.Import(irPath)
+
+	/*
+	 * Check if impulse responses were imported.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to import impulse responses: %s", err.Error())
+	}
+
+	controller.config.ImpulseResponses = irPath
+	controller.impulseResponses = irs
+	controller.effects = []signal.Chain{signal.CreateChain(irs)}
+	controller.buses = nil
+
+	/*
+	 * Request to truncate the impulse response and convert it to
+	 * minimum phase.
+	 */
+	request := webserver.HttpRequest{
+		Params: map[string]string{
+			"cgi":           "set-impulse-response-options",
+			"name":          "Cabinet",
+			"max-length":    "50",
+			"minimum-phase": "true",
+		},
+	}
+
+	response := controller.dispatch(request)
+	webResponse := webResponseStruct{}
+	json.Unmarshal(response.Body, &webResponse)
+
+	/*
+	 * Check if options were set.
+	 */
+	if !webResponse.Success {
+		t.Fatalf("Setting impulse response options failed: %s", webResponse.Reason)
+	}
+
+	request.Params = map[string]string{
+		"cgi":      "set-impulse-response-options",
+		"name":     "Cabinet",
+		"fade-out": "10",
+	}
+
+	controller.dispatch(request)
+	request.Params = map[string]string{"cgi": "get-impulse-responses"}
+	response = controller.dispatch(request)
+	result := webImpulseResponsesStruct{}
+	json.Unmarshal(response.Body, &result)
+
+	/*
+	 * The expected description.
+	 */
+	expected := []webImpulseResponseStruct{
+		webImpulseResponseStruct{
+			Name:         "Cabinet",
+			MaxLength:    50,
+			FadeOut:      10,
+			MinimumPhase: true,
+		},
+	}
+
+	/*
+	 * Options which are not given keep their value.
+	 */
+	if !reflect.DeepEqual(result.ImpulseResponses, expected) {
+		t.Errorf("Impulse responses should be %v, but are %v.", expected, result.ImpulseResponses)
+	}
+
+	flt := irs.CreateFilter("Cabinet", 48000)
+	coeffs := flt.Coefficients()
+	numCoeffs := len(coeffs)
+
+	/*
+	 * The impulse response is truncated to 50 ms when it is loaded.
+	 */
+	if numCoeffs > 2400 {
+		t.Errorf("Impulse response should have at most %d coefficients, but has %d.", 2400, numCoeffs)
+	}
+
+	request.Params = map[string]string{
+		"cgi":  "set-impulse-response-options",
+		"name": "Missing",
+	}
+
+	response = controller.dispatch(request)
+	webResponse = webResponseStruct{}
+	json.Unmarshal(response.Body, &webResponse)
+
+	/*
+	 * Options cannot be set for unknown impulse responses.
+	 */
+	if webResponse.Success {
+		t.Errorf("%s", "Setting options of an unknown impulse response did not fail.")
+	}
+
+}
+
+/*
+ * Verify that the input calibration suggests the trim which brings the input
+ * to the reference level, that applying it trims the input of the channel and
+ * that the re-amping level attenuates its output.
+ */
+func TestInputCalibration(t *testing.T) {
+	controller := createTestController(t)
+	chain := controller.effects[0]
+	chain.RemoveUnit(0)
+	controller.sampleRate = 48000
+	controller.processingTaskChannel = make(chan processingTask, 1)
+	controller.processingResultChannel = make(chan bool, 1)
+	go controller.processAsync()
+
+	t.Cleanup(func() {
+		close(controller.processingTaskChannel)
+	})
+
+	blockSize := 256
+	in := make([]float64, blockSize)
+	out := make([]float64, blockSize)
+	phase := 0
+
+	/*
+	 * Process a block of a sine wave with a peak level of -20 dBFS and
+	 * return the peak of the output.
+	 */
+	processBlock := func() float64 {
+
+		/*
+		 * Generate the next block of the sine wave.
+		 */
+		for i := range in {
+			phaseFloat := float64(phase)
+			arg := (2.0 * math.Pi * phaseFloat) / 48.0
+			in[i] = 0.1 * math.Sin(arg)
+			phase++
+		}
+
+		inputBuffers := [][]float64{in}
+		outputBuffers := [][]float64{out}
+		controller.process(inputBuffers, outputBuffers, 48000)
+		peak := float64(0.0)
+
+		/*
+		 * Find the peak of the output.
+		 */
+		for _, sample := range out {
+			peak = math.Max(peak, math.Abs(sample))
+		}
+
+		return peak
+	}
+
+	/*
+	 * The request starting the calibration.
+	 */
+	request := webserver.HttpRequest{
+		Params: map[string]string{
+			"channel":   "0",
+			"time":      "500",
+			"reference": "-12",
+		},
+	}
+
+	controller.startInputCalibrationHandler(request)
+
+	/*
+	 * Process half a second of audio.
+	 */
+	for i := 0; i < 100; i++ {
+		processBlock()
+	}
+
+	request = webserver.HttpRequest{}
+	response := controller.getInputCalibrationHandler(request)
+	result := webInputCalibrationStruct{}
+	err := json.Unmarshal(response.Body, &result)
+
+	/*
+	 * Check if result was decoded.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to decode calibration result: %s", err.Error())
+	}
+
+	/*
+	 * The measurement should have finished.
+	 */
+	if result.Running {
+		t.Errorf("%s", "Input calibration should have finished, but is still running.")
+	}
+
+	/*
+	 * The peak of the input is at -20 dBFS.
+	 */
+	if math.Abs(result.Peak+20.0) > 0.1 {
+		t.Errorf("Peak level should be %f dBFS, but is %f dBFS.", -20.0, result.Peak)
+	}
+
+	/*
+	 * The RMS level of a sine wave is 3 dB below its peak.
+	 */
+	if math.Abs(result.RMS+23.0) > 0.1 {
+		t.Errorf("RMS level should be %f dBFS, but is %f dBFS.", -23.0, result.RMS)
+	}
+
+	/*
+	 * The suggested trim brings the peak to the reference level.
+	 */
+	if result.SuggestedTrim != 8 {
+		t.Errorf("Suggested trim should be %d dB, but is %d dB.", 8, result.SuggestedTrim)
+	}
+
+	controller.applyInputCalibrationHandler(request)
+	peak := processBlock()
+	expected := 0.1 * math.Pow(10.0, 8.0/20.0)
+
+	/*
+	 * The input of the channel should be trimmed.
+	 */
+	if math.Abs(peak-expected) > 1e-3 {
+		t.Errorf("Peak of trimmed output should be %f, but is %f.", expected, peak)
+	}
+
+	/*
+	 * The request setting the re-amping level.
+	 */
+	request = webserver.HttpRequest{
+		Params: map[string]string{
+			"channel": "0",
+			"value":   "-20",
+		},
+	}
+
+	controller.setReampLevelHandler(request)
+	peak = processBlock()
+	expected *= 0.1
+
+	/*
+	 * The output of the channel should be attenuated.
+	 */
+	if math.Abs(peak-expected) > 1e-4 {
+		t.Errorf("Peak of attenuated output should be %f, but is %f.", expected, peak)
+	}
+
+}
+
+/*
+ * Verify that automation lanes modulate a parameter with an LFO or an
+ * envelope and that removing a lane returns the parameter to its base value.
+ */
+func TestAutomation(t *testing.T) {
+	controller := createTestController(t)
+	chain := controller.effects[0]
+
+	/*
+	 * Sends a request to a handler and reports failures.
+	 */
+	call := func(handler func(webserver.HttpRequest) webserver.HttpResponse, params map[string]string) bool {
+
+		/*
+		 * The request to the handler.
+		 */
+		request := webserver.HttpRequest{
+			Params: params,
+		}
+
+		response := handler(request)
+		webResponse := webResponseStruct{}
+		json.Unmarshal(response.Body, &webResponse)
+		return webResponse.Success
+	}
+
+	/*
+	 * Discrete parameters cannot be automated.
+	 */
+	if call(controller.addAutomationLaneHandler, map[string]string{"chain": "0", "unit": "0", "param": "oversampling"}) {
+		t.Errorf("Adding an automation lane for discrete parameter '%s' did not fail.", "oversampling")
+	}
+
+	/*
+	 * Settings of the lane to apply.
+	 */
+	settings := [][]string{
+		[]string{"base", "50"},
+		[]string{"waveform", "square"},
+		[]string{"depth", "100"},
+		[]string{"rate", "1"},
+	}
+
+	/*
+	 * Create an LFO modulating the drive.
+	 */
+	if !call(controller.addAutomationLaneHandler, map[string]string{"chain": "0", "unit": "0", "param": "drive"}) {
+		t.Fatalf("Adding an automation lane for parameter '%s' failed.", "drive")
+	}
+
+	/*
+	 * Apply each setting.
+	 */
+	for _, setting := range settings {
+
+		/*
+		 * Check if the setting was applied.
+		 */
+		if !call(controller.setAutomationValueHandler, map[string]string{"lane": "0", "param": setting[0], "value": setting[1]}) {
+			t.Errorf("Setting automation value '%s' to '%s' failed.", setting[0], setting[1])
+		}
+
+	}
+
+	/*
+	 * Depths beyond the range must be rejected.
+	 */
+	if call(controller.setAutomationValueHandler, map[string]string{"lane": "0", "param": "depth", "value": "101"}) {
+		t.Errorf("Setting automation depth to %d did not fail.", 101)
+	}
+
+	controller.processAutomation(100, 1000)
+	drive, _ := chain.GetNumericValue(0, "drive")
+
+	/*
+	 * A square wave starts at its maximum.
+	 */
+	if drive != 100 {
+		t.Errorf("Drive at the start of the LFO period should be %d, but is %d.", 100, drive)
+	}
+
+	controller.processAutomation(500, 1000)
+	controller.processAutomation(100, 1000)
+	drive, _ = chain.GetNumericValue(0, "drive")
+
+	/*
+	 * In the second half of its period, a square wave is at its minimum.
+	 */
+	if drive != 0 {
+		t.Errorf("Drive in the second half of the LFO period should be %d, but is %d.", 0, drive)
+	}
+
+	/*
+	 * Switch to an envelope ramping up within a second.
+	 */
+	if !call(controller.setAutomationValueHandler, map[string]string{"lane": "0", "param": "points", "value": "0:0,1000:100"}) || !call(controller.setAutomationValueHandler, map[string]string{"lane": "0", "param": "source", "value": "envelope"}) {
+		t.Fatalf("%s", "Switching automation lane to an envelope failed.")
+	}
+
+	controller.processAutomation(500, 1000)
+	controller.processAutomation(500, 1000)
+	drive, _ = chain.GetNumericValue(0, "drive")
+
+	/*
+	 * Halfway through, the envelope should be halfway up.
+	 */
+	if drive != 50 {
+		t.Errorf("Drive halfway through the envelope should be %d, but is %d.", 50, drive)
+	}
+
+	patch := controller.createPatch()
+	lanes := patch.Automation
+
+	/*
+	 * The lane should be stored in the patch.
+	 */
+	if len(lanes) != 1 || lanes[0].Source != AUTOMATION_SOURCE_ENVELOPE || len(lanes[0].Points) != 2 {
+		t.Errorf("Automation lanes stored in patch do not match: %v", lanes)
+	}
+
+	controller.processAutomation(1000, 1000)
+
+	/*
+	 * Remove the lane.
+	 */
+	if !call(controller.removeAutomationLaneHandler, map[string]string{"lane": "0"}) {
+		t.Errorf("%s", "Removing automation lane failed.")
+	}
+
+	drive, _ = chain.GetNumericValue(0, "drive")
+
+	/*
+	 * The parameter should return to its base value.
+	 */
+	if drive != 50 {
+		t.Errorf("Drive after removing the automation lane should be %d, but is %d.", 50, drive)
+	}
+
+}
+
+/*
+ * Verify that checking the settings reports each setting which is ignored,
+ * without treating it as an error, and that problems in the config file are
+ * located by line and column.
+ */
+func TestCheckSettings(t *testing.T) {
+
+	/*
+	 * Footswitches sharing a pin.
+	 */
+	footswitches := []footswitchConfigStruct{
+		footswitchConfigStruct{
+			Pin:    17,
+			Action: FOOTSWITCH_ACTION_NEXT_SCENE,
+		},
+		footswitchConfigStruct{
+			Pin:    17,
+			Action: FOOTSWITCH_ACTION_TAP_TEMPO,
+		},
+	}
+
+	/*
+	 * Configuration with an unsupported sample rate, an unknown
+	 * resampling quality, an unsupported language, an unknown notation,
+	 * a color which is not in hexadecimal notation and two footswitches
+	 * sharing a pin.
+	 */
+	config := configStruct{
+		SampleRate: 1000,
+		Resampling: "perfect",
+		Language:   "fr",
+		Notation:   "dutch",
+		Channels: []channelConfigStruct{
+			channelConfigStruct{
+				Color: "#ff8000",
+			},
+			channelConfigStruct{
+				Color: "red",
+			},
+		},
+		Gpio: gpioConfigStruct{
+			Enabled:      true,
+			Footswitches: footswitches,
+		},
+	}
+
+	diagnostics := diagnosticsStruct{}
+	diagnostics.checkSettings(config)
+	numErrors := len(diagnostics.errors)
+	numWarnings := len(diagnostics.warnings)
+
+	/*
+	 * Each ignored setting should cause a warning.
+	 */
+	if (numErrors != 0) || (numWarnings != 6) {
+		t.Errorf("Checking settings found %d errors and %d warnings, expected %d errors and %d warnings: %v", numErrors, numWarnings, 0, 6, diagnostics.warnings)
+	}
+
+	content := []byte("{\n\t\"Port\": 80,\n}")
+	line, column := filePosition(content, 15)
+
+	/*
+	 * The offset points to the third line.
+	 */
+	if (line != 3) || (column != 1) {
+		t.Errorf("Offset is at line %d, column %d, expected line %d, column %d.", line, column, 3, 1)
+	}
+
+}
+
+/*
+ * Verify that the reason for a failure is translated into the language of
+ * the request, while the status code of the v2 API still depends on the
+ * reason in English.
+ */
+func TestLocalizeResponse(t *testing.T) {
+	controller := createTestController(t)
+
+	/*
+	 * Request setting the bypass of a chain which does not exist.
+	 */
+	request := webserver.HttpRequest{
+		Params: map[string]string{
+			"cgi":   "set-bypass",
+			"chain": "9",
+			"unit":  "0",
+			"value": "true",
+			"lang":  "de",
+		},
+	}
+
+	response := controller.dispatch(request)
+	webResponse := webResponseStruct{}
+	err := json.Unmarshal(response.Body, &webResponse)
+	expected := "Kette außerhalb des gültigen Bereichs."
+
+	/*
+	 * Check if the reason was translated.
+	 */
+	if err != nil {
+		t.Errorf("Failed to decode response: %s", err.Error())
+	} else if webResponse.Success || (webResponse.Reason != expected) {
+		t.Errorf("Response should fail with reason '%s', but is: %s", expected, string(response.Body))
+	}
+
+	/*
+	 * Request to the v2 API without a language, which falls back to the
+	 * default language.
+	 */
+	apiRequest := webserver.HttpRequest{
+		Method: http.MethodGet,
+		Path:   API_PREFIX + "set-bypass",
+		Params: map[string]string{
+			"chain": "9",
+			"unit":  "0",
+			"value": "true",
+		},
+	}
+
+	response = controller.dispatchApi(apiRequest)
+	apiResponse := apiResponseStruct{}
+	err = json.Unmarshal(response.Body, &apiResponse)
+	expected = "Chain ID out of range."
+
+	/*
+	 * Check if the reason was left in English.
+	 */
+	if err != nil {
+		t.Errorf("Failed to decode API response: %s", err.Error())
+	} else if apiResponse.Reason != expected {
+		t.Errorf("API response should fail with reason '%s', but is: %s", expected, string(response.Body))
+	}
+
+	apiRequest.Params["lang"] = "de"
+	response = controller.dispatchApi(apiRequest)
+	apiResponse = apiResponseStruct{}
+	err = json.Unmarshal(response.Body, &apiResponse)
+	expected = "Kette außerhalb des gültigen Bereichs."
+
+	/*
+	 * Check if the reason was translated and the status code is kept.
+	 */
+	if err != nil {
+		t.Errorf("Failed to decode API response: %s", err.Error())
+	} else if apiResponse.Reason != expected {
+		t.Errorf("API response should fail with reason '%s', but is: %s", expected, string(response.Body))
+	} else if response.Status != http.StatusNotFound {
+		t.Errorf("API response should have status %d, but has status %d.", http.StatusNotFound, response.Status)
+	}
+
+}
+
+/*
+ * Verify that a parameter is restored to its default value and that
+ * unknown parameters are reported.
+ */
+func TestResetParameter(t *testing.T) {
+	controller := createTestController(t)
+	chain := controller.effects[0]
+	err := chain.SetNumericValue(0, "drive", 40)
+
+	/*
+	 * Check if value was set.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to set numeric value: %s", err.Error())
+	}
+
+	err = chain.SetDiscreteValue(0, "valve", "ECC82 (12AU7)")
+
+	/*
+	 * Check if value was set.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to set discrete value: %s", err.Error())
+	}
+
+	/*
+	 * Parameters to reset.
+	 */
+	names := []string{
+		"drive",
+		"valve",
+	}
+
+	/*
+	 * Reset each parameter.
+	 */
+	for _, name := range names {
+
+		/*
+		 * Request resetting the parameter.
+		 */
+		request := webserver.HttpRequest{
+			Params: map[string]string{
+				"cgi":   "reset-parameter",
+				"chain": "0",
+				"unit":  "0",
+				"param": name,
+			},
+		}
+
+		response := controller.dispatch(request)
+		webResponse := webResponseStruct{}
+		err = json.Unmarshal(response.Body, &webResponse)
+
+		/*
+		 * Check if the parameter was reset.
+		 */
+		if err != nil {
+			t.Errorf("Failed to decode response: %s", err.Error())
+		} else if !webResponse.Success {
+			t.Errorf("Failed to reset parameter '%s': %s", name, webResponse.Reason)
+		}
+
+	}
+
+	drive, _ := chain.GetNumericValue(0, "drive")
+	valve, _ := chain.GetDiscreteValue(0, "valve")
+
+	/*
+	 * Check if the defaults were restored.
+	 */
+	if drive != 100 {
+		t.Errorf("Parameter 'drive' should be reset to %d, but is %d.", 100, drive)
+	} else if valve != "ECC83 (12AX7)" {
+		t.Errorf("Parameter 'valve' should be reset to '%s', but is '%s'.", "ECC83 (12AX7)", valve)
+	}
+
+	err = resetParameter(chain, 0, "fuzziness")
+
+	/*
+	 * Unknown parameters cannot be reset.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Resetting an unknown parameter should fail.")
+	} else if apiFailureStatus(err.Error()) != http.StatusNotFound {
+		t.Errorf("Resetting an unknown parameter should map to status %d: %s", http.StatusNotFound, err.Error())
+	}
+
+}
+
+/*
+ * Verify that the units of a chain are exported as a preset and that
+ * importing the preset restores them.
+ */
+func TestChainPreset(t *testing.T) {
+	controller := createTestController(t)
+	chain := controller.effects[0]
+	chain.SetNumericValue(0, "drive", 40)
+
+	/*
+	 * Request exporting the chain.
+	 */
+	exportRequest := webserver.HttpRequest{
+		Params: map[string]string{
+			"cgi":   "export-chain",
+			"chain": "0",
+			"name":  "Lead",
+		},
+	}
+
+	response := controller.dispatch(exportRequest)
+	preset := persistence.ChainPreset{}
+	err := json.Unmarshal(response.Body, &preset)
+
+	/*
+	 * Check if the preset describes the chain.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to decode preset: %s", err.Error())
+	} else if (preset.FileFormat.Type != "chain") || (preset.Name != "Lead") {
+		t.Errorf("Preset should be of type '%s' and named '%s', but is of type '%s' and named '%s'.", "chain", "Lead", preset.FileFormat.Type, preset.Name)
+	} else if (len(preset.Units) != 1) || (preset.Units[0].Type != "overdrive") {
+		t.Errorf("Preset should hold a single overdrive, but holds: %v", preset.Units)
+	}
+
+	chain.SetNumericValue(0, "drive", 70)
+	chain.AppendUnit(effects.UNIT_DELAY)
+
+	/*
+	 * Request importing the preset.
+	 */
+	importRequest := webserver.HttpRequest{
+		Params: map[string]string{
+			"cgi":    "import-chain",
+			"chain":  "0",
+			"preset": string(response.Body),
+		},
+	}
+
+	response = controller.dispatch(importRequest)
+	webResponse := webResponseStruct{}
+	err = json.Unmarshal(response.Body, &webResponse)
+
+	/*
+	 * Check if the preset was imported.
+	 */
+	if err != nil {
+		t.Errorf("Failed to decode response: %s", err.Error())
+	} else if !webResponse.Success {
+		t.Errorf("Failed to import preset: %s", webResponse.Reason)
+	}
+
+	numUnits := chain.Length()
+	drive, _ := chain.GetNumericValue(0, "drive")
+
+	/*
+	 * Check if the units of the preset replaced those of the chain.
+	 */
+	if (numUnits != 1) || (drive != 40) {
+		t.Errorf("Chain should hold %d unit with drive %d, but holds %d units with drive %d.", 1, 40, numUnits, drive)
+	}
+
+	patch := controller.createPatch()
+	patchBytes, _ := json.Marshal(patch)
+	err = controller.restoreChainPreset(0, patchBytes)
+
+	/*
+	 * Patches are not chain presets.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Importing a patch as a chain preset should fail.")
+	}
+
+}
+
+/*
+ * Verify that units stored in the library can be inserted into a chain as a
+ * composite unit, which can be expanded and collapsed again.
+ */
+func TestLibrary(t *testing.T) {
+	controller := createTestController(t)
+	dir := t.TempDir()
+	controller.config.Library = filepath.Join(dir, "library.json")
+	chain := controller.effects[0]
+	chain.SetNumericValue(0, "drive", 40)
+	chain.AppendUnit(effects.UNIT_DELAY)
+
+	/*
+	 * Requests to send, in order.
+	 */
+	requests := []map[string]string{
+		map[string]string{"cgi": "save-library-entry", "chain": "0", "unit": "0", "count": "2", "name": "Crunch"},
+		map[string]string{"cgi": "insert-library-entry", "chain": "0", "name": "Crunch"},
+		map[string]string{"cgi": "set-numeric-value", "chain": "0", "unit": "2", "param": "0/drive", "value": "60"},
+		map[string]string{"cgi": "expand-unit", "chain": "0", "unit": "2"},
+		map[string]string{"cgi": "collapse-units", "chain": "0", "unit": "0", "count": "2", "name": "Stack"},
+		map[string]string{"cgi": "insert-library-entry", "chain": "0", "name": "Clean"},
+		map[string]string{"cgi": "expand-unit", "chain": "0", "unit": "1"},
+		map[string]string{"cgi": "collapse-units", "chain": "0", "unit": "1", "count": "3", "name": "Too many"},
+	}
+
+	/*
+	 * Whether each request should succeed.
+	 */
+	expected := []bool{true, true, true, true, true, false, false, false}
+
+	/*
+	 * Number of units in the chain after each request.
+	 */
+	lengths := []int{2, 3, 3, 4, 3, 3, 3, 3}
+
+	/*
+	 * Send each request.
+	 */
+	for i, params := range requests {
+		request := webserver.HttpRequest{
+			Params: params,
+		}
+
+		response := controller.dispatch(request)
+		webResponse := webResponseStruct{}
+		err := json.Unmarshal(response.Body, &webResponse)
+		numUnits := chain.Length()
+
+		/*
+		 * Check if request succeeded as expected.
+		 */
+		if err != nil {
+			t.Errorf("Request %d: Failed to decode response: %s", i, err.Error())
+		} else if webResponse.Success != expected[i] {
+			t.Errorf("Request %d ('%s'): Success should be %t, but is %t: %s", i, params["cgi"], expected[i], webResponse.Success, webResponse.Reason)
+		} else if numUnits != lengths[i] {
+			t.Errorf("Request %d ('%s'): Chain should hold %d units, but holds %d.", i, params["cgi"], lengths[i], numUnits)
+		}
+
+	}
+
+	composite, err := chain.Composite(0)
+
+	/*
+	 * The units collapsed last form a composite unit.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to get composite unit: %s", err.Error())
+	}
+
+	name := composite.Name()
+	drive, _ := chain.GetNumericValue(0, "0/drive")
+	expandedDrive, _ := chain.GetNumericValue(1, "drive")
+
+	/*
+	 * Check if the units kept their parameters.
+	 */
+	if (name != "Stack") || (drive != 40) || (expandedDrive != 60) {
+		t.Errorf("Expected composite unit '%s' with drive %d followed by drive %d, but got '%s' with drive %d followed by drive %d.", "Stack", 40, 60, name, drive, expandedDrive)
+	}
+
+	reloaded := createTestController(t)
+	reloaded.config.Library = controller.config.Library
+	err = reloaded.loadLibrary()
+	library := reloaded.library
+
+	/*
+	 * Check if the library was stored on disk.
+	 */
+	if err != nil {
+		t.Errorf("Failed to load library: %s", err.Error())
+	} else if (len(library) != 1) || (library[0].Name != "Crunch") || (len(library[0].Units) != 2) {
+		t.Errorf("Library should hold entry '%s' with %d units, but holds: %v", "Crunch", 2, library)
+	}
+
+}
+
+/*
+ * Verify that labels and notes stay with their units when units are moved and
+ * are restored from patches.
+ */
+func TestUnitAnnotations(t *testing.T) {
+	controller := createTestController(t)
+	chain := controller.effects[0]
+	chain.AppendUnit(effects.UNIT_DELAY)
+	longLabel := strings.Repeat("x", UNIT_LABEL_MAX_LENGTH+1)
+
+	/*
+	 * Requests to send, in order.
+	 */
+	requests := []map[string]string{
+		map[string]string{"cgi": "set-unit-label", "chain": "0", "unit": "0", "value": " Solo boost "},
+		map[string]string{"cgi": "set-unit-note", "chain": "0", "unit": "0", "value": "Set drive to taste."},
+		map[string]string{"cgi": "move-up", "chain": "0", "unit": "1"},
+		map[string]string{"cgi": "set-unit-label", "chain": "0", "unit": "0", "value": longLabel},
+		map[string]string{"cgi": "set-unit-note", "chain": "0", "unit": "2", "value": "Missing"},
+	}
+
+	/*
+	 * Whether each request should succeed.
+	 */
+	expected := []bool{true, true, true, false, false}
+
+	/*
+	 * Send each request.
+	 */
+	for i, params := range requests {
+		request := webserver.HttpRequest{
+			Params: params,
+		}
+
+		response := controller.dispatch(request)
+		webResponse := webResponseStruct{}
+		err := json.Unmarshal(response.Body, &webResponse)
+
+		/*
+		 * Check if request succeeded as expected.
+		 */
+		if err != nil {
+			t.Errorf("Request %d: Failed to decode response: %s", i, err.Error())
+		} else if webResponse.Success != expected[i] {
+			t.Errorf("Request %d ('%s'): Success should be %t, but is %t: %s", i, params["cgi"], expected[i], webResponse.Success, webResponse.Reason)
+		}
+
+	}
+
+	webChain := controller.createWebChain(chain)
+	units := webChain.Units
+
+	/*
+	 * The overdrive moved down along with its label and note.
+	 */
+	if (units[0].Label != "") || (units[1].Label != "Solo boost") || (units[1].Note != "Set drive to taste.") {
+		t.Errorf("Unexpected labels and notes: %v", units)
+	}
+
+	patch := controller.createPatch()
+	patchBytes, _ := json.Marshal(patch)
+	chain.SetLabel(1, "Changed")
+	chain.SetNote(1, "")
+	err := controller.restorePatch(patchBytes)
+	label, _ := chain.GetLabel(1)
+	note, _ := chain.GetNote(1)
+
+	/*
+	 * Check if the patch restored the label and note.
+	 */
+	if err != nil {
+		t.Errorf("Failed to restore patch: %s", err.Error())
+	} else if (label != "Solo boost") || (note != "Set drive to taste.") {
+		t.Errorf("Label and note should be '%s' and '%s', but are '%s' and '%s'.", "Solo boost", "Set drive to taste.", label, note)
+	}
+
+}
+
+/*
+ * Verify that a malformed patch is rejected without changing anything and
+ * that checking it reports all of its problems.
+ */
+func TestRestoreInvalidPatch(t *testing.T) {
+	controller := createTestController(t)
+	chain := controller.effects[0]
+	chain.SetNumericValue(0, "drive", 30)
+	patch := controller.createPatch()
+	channel := patch.Channels[0]
+	channel.Color = "red"
+
+	/*
+	 * Units of a type which does not exist.
+	 */
+	unknownUnits := []persistence.Unit{
+		persistence.Unit{Type: "flux_capacitor"},
+		persistence.Unit{
+			Type: "composite",
+			Units: []persistence.Unit{
+				persistence.Unit{Type: "overdrive"},
+				persistence.Unit{Type: "time_machine"},
+			},
+		},
+	}
+
+	channel.Units = append(channel.Units, unknownUnits...)
+	unit := &channel.Units[0]
+
+	/*
+	 * A numeric parameter which does not exist.
+	 */
+	unit.NumericParams = append(unit.NumericParams, persistence.NumericParam{Key: "warp", Value: 1})
+	patch.Channels = []persistence.Channel{channel, channel}
+
+	/*
+	 * An automation lane for a parameter which does not exist.
+	 */
+	lane := persistence.AutomationLane{
+		Chain: 0,
+		Unit:  0,
+		Param: "warp",
+	}
+
+	patch.Automation = []persistence.AutomationLane{lane}
+	patchBytes, err := json.Marshal(patch)
+
+	/*
+	 * Check if patch was marshalled.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to marshal patch: %s", err.Error())
+	}
+
+	chain.SetNumericValue(0, "drive", 60)
+	err = controller.restorePatch(patchBytes)
+	numUnits := chain.Length()
+	drive, _ := chain.GetNumericValue(0, "drive")
+
+	/*
+	 * The patch must be rejected and nothing may be restored.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Restoring a malformed patch should fail.")
+	} else if (numUnits != 1) || (drive != 60) {
+		t.Errorf("Chain should be unchanged with %d unit and drive %d, but has %d units and drive %d.", 1, 60, numUnits, drive)
+	}
+
+	/*
+	 * The parameters of the request.
+	 */
+	params := map[string]string{
+		"Patch": string(patchBytes),
+	}
+
+	body, _ := json.Marshal(params)
+
+	/*
+	 * Check the patch without restoring it.
+	 */
+	request := webserver.HttpRequest{
+		Method: http.MethodPost,
+		Path:   API_PREFIX + "persistence-validate",
+		Params: map[string]string{},
+		Body:   body,
+	}
+
+	response := controller.dispatchApiRequest(request)
+	apiResponse := apiResponseStruct{}
+	report := webPatchCheckStruct{}
+	err = json.Unmarshal(response.Body, &apiResponse)
+
+	/*
+	 * Check if the response could be decoded.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to decode response: %s", err.Error())
+	} else if !apiResponse.Success {
+		t.Fatalf("Checking patch failed: %s", apiResponse.Reason)
+	}
+
+	err = json.Unmarshal(apiResponse.Result, &report)
+
+	/*
+	 * Check if the report could be decoded.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to decode report: %s", err.Error())
+	}
+
+	numErrors := len(report.Errors)
+	numWarnings := len(report.Warnings)
+
+	/*
+	 * The surplus channel and both unknown unit types are errors, while
+	 * the unknown parameter, color and automation lane are warnings.
+	 */
+	if report.Valid || (numErrors != 3) || (numWarnings != 3) {
+		t.Errorf("Report should be invalid with %d errors and %d warnings, but is %v", 3, 3, report)
+	}
+
+	patch.Channels = patch.Channels[0:1]
+	patch.Channels[0].Units = patch.Channels[0].Units[0:1]
+	patchBytes, _ = json.Marshal(patch)
+	err = controller.restorePatch(patchBytes)
+	drive, _ = chain.GetNumericValue(0, "drive")
+
+	/*
+	 * A patch with warnings only is restored.
+	 */
+	if err != nil {
+		t.Errorf("Failed to restore patch: %s", err.Error())
+	} else if drive != 30 {
+		t.Errorf("Drive should be %d after restoring the patch, but is %d.", 30, drive)
+	}
+
+}
+
+/*
+ * Creates an engine with a certain number of channels from a minimal
+ * configuration without impulse responses.
+ */
+func createTestEngine(tb testing.TB, numChannels uint32) Engine {
+	dir := tb.TempDir()
+	irPath := filepath.Join(dir, "ir.json")
+	configPath := filepath.Join(dir, "config.json")
+	os.WriteFile(irPath, []byte("[]"), 0644)
+
+	/*
+	 * A minimal configuration.
+	 */
+	config := configStruct{
+		ImpulseResponses: irPath,
+		Setlist:          filepath.Join(dir, "setlist.json"),
+		Library:          filepath.Join(dir, "library.json"),
+	}
+
+	configBytes, _ := json.Marshal(config)
+	os.WriteFile(configPath, configBytes, 0644)
+	engine, err := CreateEngine(configPath, numChannels)
+
+	/*
+	 * Check if engine was created.
+	 */
+	if err != nil {
+		tb.Fatalf("Failed to create engine: %s", err.Error())
+	}
+
+	return engine
+}
+
+/*
+ * Verify that an embedded engine can be set up through its API and processes
+ * blocks of audio without audio hardware or web server.
+ */
+func TestEngine(t *testing.T) {
+	engine := createTestEngine(t, 1)
+	defer engine.Close()
+	unitType := fmt.Sprintf("%d", effects.UNIT_PARAMETRIC_EQ)
+
+	/*
+	 * Endpoints to call.
+	 */
+	endpoints := []string{
+		"add-unit",
+		"set-bypass",
+		"set-output-level",
+		"no-such-endpoint",
+	}
+
+	/*
+	 * Parameters to pass.
+	 */
+	params := []map[string]string{
+		map[string]string{"chain": "0", "type": unitType},
+		map[string]string{"chain": "0", "unit": "0", "value": "false"},
+		map[string]string{"chain": "0", "unit": "0", "value": "6"},
+		map[string]string{},
+	}
+
+	/*
+	 * Whether the calls should succeed.
+	 */
+	expected := []bool{
+		true,
+		true,
+		true,
+		false,
+	}
+
+	/*
+	 * Call each endpoint.
+	 */
+	for i, endpoint := range endpoints {
+		_, err := engine.Invoke(endpoint, params[i])
+		success := err == nil
+
+		/*
+		 * Check if call succeeded as expected.
+		 */
+		if success != expected[i] {
+			t.Errorf("Call %d ('%s'): Success should be %t, but is %t: %v", i, endpoint, expected[i], success, err)
+		}
+
+	}
+
+	err := engine.SetSampleRate(12345)
+
+	/*
+	 * Unsupported sample rates are rejected.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Setting sample rate of 12345 Hz should fail.")
+	}
+
+	engine.SetSampleRate(48000)
+	inputPorts := engine.InputPorts()
+	outputPorts := engine.OutputPorts()
+	numInputs := len(inputPorts)
+	numOutputs := len(outputPorts)
+	n := 256
+	engine.SetBlockSize(uint32(n))
+	inputs := make([][]float64, numInputs)
+	outputs := make([][]float64, numOutputs)
+
+	/*
+	 * Feed each input with a constant signal.
+	 */
+	for i := range inputs {
+		inputs[i] = make([]float64, n)
+
+		/*
+		 * Fill the input.
+		 */
+		for j := range inputs[i] {
+			inputs[i][j] = 0.1
+		}
+
+	}
+
+	/*
+	 * Allocate the outputs.
+	 */
+	for i := range outputs {
+		outputs[i] = make([]float64, n)
+	}
+
+	/*
+	 * Process a few blocks, so that the unit is faded in.
+	 */
+	for block := 0; block < 4; block++ {
+		engine.Process(inputs, outputs)
+	}
+
+	expectedLevel := 0.1 * math.Pow(10.0, 0.3)
+	sample := outputs[0][n-1]
+
+	/*
+	 * The output of the channel is raised by the output level of the unit.
+	 */
+	if (numInputs != 1) || (math.Abs(sample-expectedLevel) > 1e-3) {
+		t.Errorf("Channel output should be %f, but is %f (%d inputs).", expectedLevel, sample, numInputs)
+	}
+
+}
+
+/*
+ * Adds a typical set of units for guitar to each channel of an engine and
+ * prepares it to process blocks of a certain size at a certain sample rate.
+ *
+ * Returns the controller of the engine along with input and output buffers
+ * holding a block each.
+ */
+func prepareProcessing(tb testing.TB, engine Engine, sampleRate uint32, frames int) (*controllerStruct, [][]float64, [][]float64) {
+
+	/*
+	 * The units in each chain.
+	 */
+	unitTypes := []int{
+		effects.UNIT_NOISEGATE,
+		effects.UNIT_COMPRESSOR,
+		effects.UNIT_OVERDRIVE,
+		effects.UNIT_AMP_MODEL,
+		effects.UNIT_PARAMETRIC_EQ,
+		effects.UNIT_CHORUS,
+		effects.UNIT_DELAY,
+		effects.UNIT_ALGORITHMIC_REVERB,
+	}
+
+	inputPorts := engine.InputPorts()
+
+	/*
+	 * Set up the chain of each channel.
+	 */
+	for channel := range inputPorts {
+		chainId := fmt.Sprintf("%d", channel)
+
+		/*
+		 * Add each unit and enable it.
+		 */
+		for unitId, unitType := range unitTypes {
+			unitTypeString := fmt.Sprintf("%d", unitType)
+			unitIdString := fmt.Sprintf("%d", unitId)
+			_, errAdd := engine.Invoke("add-unit", map[string]string{"chain": chainId, "type": unitTypeString})
+			_, errBypass := engine.Invoke("set-bypass", map[string]string{"chain": chainId, "unit": unitIdString, "value": "false"})
+
+			/*
+			 * Check if unit was set up.
+			 */
+			if errAdd != nil {
+				tb.Fatalf("Failed to add unit: %s", errAdd.Error())
+			} else if errBypass != nil {
+				tb.Fatalf("Failed to enable unit: %s", errBypass.Error())
+			}
+
+		}
+
+	}
+
+	err := engine.SetSampleRate(sampleRate)
+
+	/*
+	 * Check if sample rate was set.
+	 */
+	if err != nil {
+		tb.Fatalf("Failed to set sample rate: %s", err.Error())
+	}
+
+	framesUint := uint32(frames)
+	engine.SetBlockSize(framesUint)
+	outputPorts := engine.OutputPorts()
+	numInputs := len(inputPorts)
+	numOutputs := len(outputPorts)
+	inputs := make([][]float64, numInputs)
+	outputs := make([][]float64, numOutputs)
+	sampleRateFloat := float64(sampleRate)
+
+	/*
+	 * Feed each input with a sine wave.
+	 */
+	for i := range inputs {
+		inputs[i] = make([]float64, frames)
+
+		/*
+		 * Fill the input.
+		 */
+		for j := range inputs[i] {
+			jFloat := float64(j)
+			arg := (2.0 * math.Pi * 220.0 * jFloat) / sampleRateFloat
+			inputs[i][j] = 0.5 * math.Sin(arg)
+		}
+
+	}
+
+	/*
+	 * Allocate the outputs.
+	 */
+	for i := range outputs {
+		outputs[i] = make([]float64, frames)
+	}
+
+	engineImpl := engine.(*engineStruct)
+	controller := engineImpl.controller
+
+	/*
+	 * Process a few blocks, so that the units are faded in.
+	 */
+	for block := 0; block < 16; block++ {
+		controller.process(inputs, outputs, sampleRate)
+	}
+
+	return controller, inputs, outputs
+}
+
+/*
+ * Measure the time it takes to process a block of audio through two channels
+ * with a typical set of units and report it as a share of the duration of the
+ * block.
+ */
+func benchmarkProcess(b *testing.B, sampleRate uint32, frames int) {
+	engine := createTestEngine(b, 2)
+	defer engine.Close()
+	controller, inputs, outputs := prepareProcessing(b, engine, sampleRate, frames)
+	b.ResetTimer()
+	start := time.Now()
+
+	/*
+	 * Process the block b.N times.
+	 */
+	for i := 0; i < b.N; i++ {
+		controller.process(inputs, outputs, sampleRate)
+	}
+
+	elapsed := time.Since(start)
+	b.StopTimer()
+	elapsedFloat := elapsed.Seconds()
+	nFloat := float64(b.N)
+	framesFloat := float64(frames)
+	sampleRateFloat := float64(sampleRate)
+	duration := framesFloat / sampleRateFloat
+	load := (100.0 * elapsedFloat) / (nFloat * duration)
+	b.ReportMetric(load, "%realtime")
+}
+
+/*
+ * Benchmark the entire processing path at common block sizes and sample
+ * rates.
+ */
+func BenchmarkProcess(b *testing.B) {
+	blockSizes := []int{64, 256, 1024}
+	sampleRates := []uint32{44100, 48000, 96000}
+
+	/*
+	 * Benchmark each sample rate.
+	 */
+	for _, sampleRate := range sampleRates {
+
+		/*
+		 * Benchmark each block size.
+		 */
+		for _, frames := range blockSizes {
+			name := fmt.Sprintf("%d/%d", sampleRate, frames)
+
+			/*
+			 * Benchmark the processing path.
+			 */
+			benchmark := func(b *testing.B) {
+				benchmarkProcess(b, sampleRate, frames)
+			}
+
+			b.Run(name, benchmark)
+		}
+
+	}
+
+}
+
+/*
+ * Verify that processing a block of audio through two channels with a typical
+ * set of units stays within a budget, given as a share of the duration of
+ * the block in percent.
+ *
+ * The budget defaults to 100 percent, so that the test fails whenever the
+ * processing cannot keep up, and may be lowered in the environment variable
+ * DSP_PROCESSING_BUDGET to match the machine the tests run on. The median time of many blocks is compared, so that an occasional
+ * delay caused by the scheduler does not fail the test.
+ */
+func TestProcessingBudget(t *testing.T) {
+
+	/*
+	 * Measuring takes a while.
+	 */
+	if testing.Short() {
+		t.Skip("Skipping measurement of processing time in short mode.")
+	}
+
+	budget := 100.0
+	budgetString := os.Getenv("DSP_PROCESSING_BUDGET")
+
+	/*
+	 * Check if a budget is configured.
+	 */
+	if budgetString != "" {
+		value, err := strconv.ParseFloat(budgetString, 64)
+
+		/*
+		 * Check if budget is valid.
+		 */
+		if (err != nil) || (value <= 0.0) {
+			t.Fatalf("Invalid processing budget: '%s'", budgetString)
+		}
+
+		budget = value
+	}
+
+	sampleRate := uint32(48000)
+	frames := 256
+	numBlocks := 500
+	engine := createTestEngine(t, 2)
+	defer engine.Close()
+	controller, inputs, outputs := prepareProcessing(t, engine, sampleRate, frames)
+	times := make([]float64, numBlocks)
+
+	/*
+	 * Measure the time it takes to process each block.
+	 */
+	for i := range times {
+		start := time.Now()
+		controller.process(inputs, outputs, sampleRate)
+		elapsed := time.Since(start)
+		times[i] = elapsed.Seconds()
+	}
+
+	sort.Float64s(times)
+	idx := numBlocks / 2
+	median := times[idx]
+	framesFloat := float64(frames)
+	sampleRateFloat := float64(sampleRate)
+	duration := framesFloat / sampleRateFloat
+	load := (100.0 * median) / duration
+
+	/*
+	 * Check if processing stays within the budget.
+	 */
+	if load > budget {
+		t.Errorf("Processing a block of %d frames at %d Hz takes %f %% of its duration, which exceeds the budget of %f %%.", frames, sampleRate, load, budget)
+	}
+
+}
+
+/*
+ * Verify that requests changing the state of the controller may be handled
+ * while blocks of audio are processed.
+ *
+ * Run with 'go test -race' to detect unsynchronized access.
+ */
+func TestConcurrentRequests(t *testing.T) {
+	engine := createTestEngine(t, 2)
+	defer engine.Close()
+	inputPorts := engine.InputPorts()
+	outputPorts := engine.OutputPorts()
+	numInputs := len(inputPorts)
+	numOutputs := len(outputPorts)
+	frames := 256
+	inputs := make([][]float64, numInputs)
+	outputs := make([][]float64, numOutputs+4)
+	engine.SetBlockSize(uint32(frames))
+
+	/*
+	 * Allocate the inputs.
+	 */
+	for i := range inputs {
+		inputs[i] = make([]float64, frames)
+	}
+
+	/*
+	 * Allocate the outputs, including those of a surround layout.
+	 */
+	for i := range outputs {
+		outputs[i] = make([]float64, frames)
+	}
+
+	patch, err := engine.Invoke("persistence-save", map[string]string{})
+
+	/*
+	 * Check if patch was saved.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to save patch: %s", err.Error())
+	}
+
+	patchString := string(patch)
+	unitType := fmt.Sprintf("%d", effects.UNIT_DELAY)
+
+	/*
+	 * Endpoints to call, in order.
+	 */
+	endpoints := []string{
+		"add-unit",
+		"set-bypass",
+		"set-numeric-value",
+		"set-tuner-value",
+		"set-tuner-value",
+		"set-metronome-value",
+		"set-metronome-output",
+		"set-player-value",
+		"set-channel-trim",
+		"set-reamp-level",
+		"set-azimuth",
+		"set-send",
+		"set-master-value",
+		"set-level-meter-enabled",
+		"set-spectrum-analyzer-enabled",
+		"set-speaker-layout",
+		"set-speaker-layout",
+		"add-channel",
+		"remove-channel",
+		"remove-unit",
+		"persistence-restore",
+	}
+
+	/*
+	 * Parameters to pass.
+	 */
+	params := []map[string]string{
+		map[string]string{"chain": "0", "type": unitType},
+		map[string]string{"chain": "0", "unit": "0", "value": "false"},
+		map[string]string{"chain": "0", "unit": "0", "param": "feedback", "value": "-6"},
+		map[string]string{"param": "channel", "value": "1"},
+		map[string]string{"param": "mute", "value": "true"},
+		map[string]string{"param": "master-output", "value": "true"},
+		map[string]string{"channel": "1", "value": "0.5"},
+		map[string]string{"param": "master-output", "value": "true"},
+		map[string]string{"channel": "0", "value": "6"},
+		map[string]string{"channel": "1", "value": "-12"},
+		map[string]string{"chain": "0", "value": "30"},
+		map[string]string{"chain": "0", "bus": "0", "value": "0.5"},
+		map[string]string{"param": "enabled", "value": "true"},
+		map[string]string{"value": "true"},
+		map[string]string{"value": "true"},
+		map[string]string{"value": "quad"},
+		map[string]string{"value": "stereo"},
+		map[string]string{"stereo": "false"},
+		map[string]string{"channel": "2"},
+		map[string]string{"chain": "0", "unit": "0"},
+		map[string]string{"Patch": patchString},
+	}
+
+	done := make(chan bool)
+	stopped := make(chan bool)
+
+	/*
+	 * Process blocks until the requests are done.
+	 */
+	go func() {
+		running := true
+
+		/*
+		 * Process a block unless the requests are done.
+		 */
+		for running {
+
+			select {
+			case <-done:
+				running = false
+			default:
+				engine.Process(inputs, outputs)
+			}
+
+		}
+
+		stopped <- true
+	}()
+
+	settingEndpoints := endpoints[3:10]
+	settingParams := params[3:10]
+
+	/*
+	 * Call the endpoints changing settings read by each block many times,
+	 * since few calls rarely overlap with reading them.
+	 */
+	for round := 0; round < 100; round++ {
+
+		/*
+		 * Call each endpoint.
+		 */
+		for i, endpoint := range settingEndpoints {
+			_, err := engine.Invoke(endpoint, settingParams[i])
+
+			/*
+			 * Each call should succeed.
+			 */
+			if err != nil {
+				t.Errorf("Round %d, call %d ('%s') failed: %s", round, i, endpoint, err.Error())
+			}
+
+		}
+
+	}
+
+	/*
+	 * Call all endpoints a few times while blocks are processed.
+	 */
+	for round := 0; round < 5; round++ {
+
+		/*
+		 * Call each endpoint.
+		 */
+		for i, endpoint := range endpoints {
+			_, err := engine.Invoke(endpoint, params[i])
+
+			/*
+			 * Each call should succeed.
+			 */
+			if err != nil {
+				t.Errorf("Round %d, call %d ('%s') failed: %s", round, i, endpoint, err.Error())
+			}
+
+		}
+
+	}
+
+	done <- true
+	<-stopped
+}
@@ -0,0 +1,301 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/filter"
+	"github.com/andrepxx/go-dsp-guitar/master"
+	"github.com/andrepxx/go-dsp-guitar/metronome"
+	"github.com/andrepxx/go-dsp-guitar/signal"
+	"github.com/andrepxx/go-dsp-guitar/spatializer"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"net/http"
+	"sync"
+)
+
+/*
+ * Interface type for the signal processing engine, embedded into another Go
+ * program instead of being driven by the web server and the audio hardware.
+ *
+ * The host feeds blocks of audio to Process from its own audio thread. All
+ * other operations of the web interface and the API are available through
+ * Invoke, which may be called from any goroutine. The signal chains,
+ * spatializer, metronome and master section may also be accessed directly,
+ * which bypasses the undo history.
+ */
+type Engine interface {
+	Chains() []signal.Chain
+	Close()
+	InputPorts() []string
+	Invoke(endpoint string, params map[string]string) (json.RawMessage, error)
+	Master() master.Master
+	Metronome() metronome.Metronome
+	OutputPorts() []string
+	Process(inputs [][]float64, outputs [][]float64)
+	Render(jobPath string) error
+	SampleRate() uint32
+	SetBlockSize(frames uint32)
+	SetSampleRate(rate uint32) error
+	Spatializer() spatializer.Spatializer
+}
+
+/*
+ * Data structure representing an embedded engine.
+ *
+ * Requests are serialized by the mutex, like the message pump of the
+ * standalone application does.
+ */
+type engineStruct struct {
+	mutex      sync.Mutex
+	controller *controllerStruct
+}
+
+/*
+ * Returns all signal chains, the chains of the channels followed by the chains
+ * of the aux buses.
+ */
+func (this *engineStruct) Chains() []signal.Chain {
+	this.mutex.Lock()
+	chains := this.controller.chains()
+	this.mutex.Unlock()
+	return chains
+}
+
+/*
+ * Stops the engine and frees its resources. The engine must not be used
+ * afterwards.
+ */
+func (this *engineStruct) Close() {
+	this.mutex.Lock()
+	this.controller.finalize()
+	this.mutex.Unlock()
+}
+
+/*
+ * Returns the names of the input ports, one per input channel.
+ */
+func (this *engineStruct) InputPorts() []string {
+	this.mutex.Lock()
+	names := this.controller.inputPortNames
+	numNames := len(names)
+	result := make([]string, numNames)
+	copy(result, names)
+	this.mutex.Unlock()
+	return result
+}
+
+/*
+ * Calls an endpoint of the v2 API, e. g. 'set-bypass', with its parameters
+ * and returns the result, if the endpoint returns one.
+ *
+ * If the request fails, the reason is returned as an error.
+ */
+func (this *engineStruct) Invoke(endpoint string, params map[string]string) (json.RawMessage, error) {
+	requestParams := map[string]string{}
+
+	/*
+	 * Copy the parameters, since the request adds its own.
+	 */
+	for key, value := range params {
+		requestParams[key] = value
+	}
+
+	/*
+	 * The request to the API.
+	 */
+	request := webserver.HttpRequest{
+		Method: http.MethodPost,
+		Path:   API_PREFIX + endpoint,
+		Params: requestParams,
+	}
+
+	this.mutex.Lock()
+	response := this.controller.dispatchApiRequest(request)
+	this.mutex.Unlock()
+	apiResponse := apiResponseStruct{}
+	err := json.Unmarshal(response.Body, &apiResponse)
+
+	/*
+	 * Check if the response could be decoded and the request succeeded.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, fmt.Errorf("Failed to decode response: %s", msg)
+	} else if !apiResponse.Success {
+		return nil, fmt.Errorf("%s", apiResponse.Reason)
+	} else {
+		return apiResponse.Result, nil
+	}
+
+}
+
+/*
+ * Returns the master section.
+ */
+func (this *engineStruct) Master() master.Master {
+	return this.controller.masterSection
+}
+
+/*
+ * Returns the metronome.
+ */
+func (this *engineStruct) Metronome() metronome.Metronome {
+	return this.controller.metr
+}
+
+/*
+ * Returns the names of the output ports. The outputs of the channels come
+ * first, followed by the master output, the metronome, the player and the
+ * surround speakers, if any.
+ */
+func (this *engineStruct) OutputPorts() []string {
+	this.mutex.Lock()
+	names := this.controller.outputPortNames
+	numNames := len(names)
+	result := make([]string, numNames)
+	copy(result, names)
+	this.mutex.Unlock()
+	return result
+}
+
+/*
+ * Processes a block of audio at the current sample rate, one buffer per input
+ * and output port.
+ *
+ * This is meant to be called from the audio thread of the host and does not
+ * wait for requests to complete.
+ */
+func (this *engineStruct) Process(inputs [][]float64, outputs [][]float64) {
+	c := this.controller
+	c.processLive(inputs, outputs, c.hardwareRate)
+}
+
+/*
+ * Processes files unattended, as described in a job file, through the
+ * channels of this engine. The channels defined in the job file are ignored.
+ *
+ * The engine keeps the sample rate and patch of the job afterwards. Process
+ * must not be called while the job is rendered.
+ */
+func (this *engineStruct) Render(jobPath string) error {
+	job, err := loadJob(jobPath)
+
+	/*
+	 * Check if job file could be loaded.
+	 */
+	if err != nil {
+		return err
+	} else {
+		this.mutex.Lock()
+		err = this.controller.runJob(job)
+		this.mutex.Unlock()
+		return err
+	}
+
+}
+
+/*
+ * Returns the sample rate blocks are passed to Process at.
+ */
+func (this *engineStruct) SampleRate() uint32 {
+	this.mutex.Lock()
+	rate := this.controller.hardwareRate
+	this.mutex.Unlock()
+	return rate
+}
+
+/*
+ * Sets the largest number of frames passed to Process at once, so that units
+ * can prepare their filters ahead of time.
+ */
+func (this *engineStruct) SetBlockSize(frames uint32) {
+	this.mutex.Lock()
+	this.controller.setBlockSize(frames)
+	this.mutex.Unlock()
+}
+
+/*
+ * Sets the sample rate blocks are passed to Process at.
+ *
+ * If an internal sample rate is configured, the signal processing runs at
+ * that rate and blocks are converted.
+ */
+func (this *engineStruct) SetSampleRate(rate uint32) error {
+	sampleRates := filter.SampleRates()
+	correctRate := false
+
+	/*
+	 * Check if sample rate is supported.
+	 */
+	for _, currentRate := range sampleRates {
+
+		/*
+		 * Check if sample rate matches.
+		 */
+		if currentRate == rate {
+			correctRate = true
+		}
+
+	}
+
+	/*
+	 * Reject sample rates which are not supported.
+	 */
+	if !correctRate {
+		return fmt.Errorf("Sample rate not supported: %d", rate)
+	} else {
+		this.mutex.Lock()
+		this.controller.sampleRateListener(rate)
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Returns the spatializer, which mixes the channels into the master output.
+ */
+func (this *engineStruct) Spatializer() spatializer.Spatializer {
+	return this.controller.spat
+}
+
+/*
+ * Creates an engine with a certain number of input channels, configured by a
+ * config file, without binding it to audio hardware or starting the web
+ * server.
+ *
+ * Paths inside the config file are relative to the working directory. The
+ * engine processes at the default sample rate until another one is set.
+ */
+func CreateEngine(configPath string, numChannels uint32) (Engine, error) {
+
+	/*
+	 * An engine needs at least one channel.
+	 */
+	if numChannels == 0 {
+		return nil, fmt.Errorf("%s", "Engine needs at least one channel.")
+	} else {
+		c := &controllerStruct{}
+		err := c.initialize(configPath, numChannels, nil, false)
+
+		/*
+		 * Check if initialization was successful.
+		 */
+		if err != nil {
+			return nil, err
+		} else {
+			c.sampleRateListener(DEFAULT_SAMPLE_RATE)
+
+			/*
+			 * Create the engine.
+			 */
+			engine := engineStruct{
+				controller: c,
+			}
+
+			return &engine, nil
+		}
+
+	}
+
+}
@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/gpio"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"strconv"
+)
+
+/*
+ * Actions a footswitch may trigger.
+ */
+const (
+	FOOTSWITCH_ACTION_NEXT_SCENE     = "next-scene"
+	FOOTSWITCH_ACTION_PREVIOUS_SCENE = "previous-scene"
+	FOOTSWITCH_ACTION_TOGGLE_BYPASS  = "toggle-bypass"
+	FOOTSWITCH_ACTION_TAP_TEMPO      = "tap-tempo"
+)
+
+/*
+ * The configuration of a footswitch, with the GPIO pin it is connected to and
+ * the action it triggers. The chain and unit select the unit whose bypass is
+ * toggled.
+ */
+type footswitchConfigStruct struct {
+	Pin       uint32
+	ActiveLow bool
+	Action    string
+	Chain     uint32
+	Unit      uint32
+}
+
+/*
+ * The configuration of the footswitches connected to GPIO pins.
+ */
+type gpioConfigStruct struct {
+	Enabled      bool
+	Path         string
+	Debounce     uint32
+	Footswitches []footswitchConfigStruct
+}
+
+/*
+ * Checks whether a footswitch may trigger a certain action.
+ */
+func isFootswitchAction(action string) bool {
+
+	/*
+	 * Check for each action.
+	 */
+	switch action {
+	case FOOTSWITCH_ACTION_NEXT_SCENE, FOOTSWITCH_ACTION_PREVIOUS_SCENE, FOOTSWITCH_ACTION_TOGGLE_BYPASS, FOOTSWITCH_ACTION_TAP_TEMPO:
+		return true
+	default:
+		return false
+	}
+
+}
+
+/*
+ * Opens the footswitches, if they are enabled, and returns the channel their
+ * presses arrive on. The channel is nil if the footswitches are disabled or
+ * could not be opened, so that it never delivers a press.
+ */
+func (this *controllerStruct) startFootswitches() <-chan int {
+	cfg := this.config
+	gpioCfg := cfg.Gpio
+
+	/*
+	 * Check if the footswitches are enabled.
+	 */
+	if !gpioCfg.Enabled {
+		return nil
+	} else {
+		footswitchCfgs := gpioCfg.Footswitches
+		numFootswitches := len(footswitchCfgs)
+		pins := make([]gpio.Pin, numFootswitches)
+		err := error(nil)
+
+		/*
+		 * Check the action of each footswitch.
+		 */
+		for i, footswitchCfg := range footswitchCfgs {
+			action := footswitchCfg.Action
+
+			/*
+			 * Check if the action is known.
+			 */
+			if !isFootswitchAction(action) && (err == nil) {
+				err = fmt.Errorf("Unknown action '%s' for GPIO pin %d.", action, footswitchCfg.Pin)
+			}
+
+			/*
+			 * Create pin configuration.
+			 */
+			pins[i] = gpio.Pin{
+				Number:    footswitchCfg.Pin,
+				ActiveLow: footswitchCfg.ActiveLow,
+			}
+
+		}
+
+		/*
+		 * Only open the pins if all actions are known.
+		 */
+		if err == nil {
+
+			/*
+			 * Create GPIO configuration.
+			 */
+			config := gpio.Config{
+				Path:     gpioCfg.Path,
+				Debounce: gpioCfg.Debounce,
+				Pins:     pins,
+			}
+
+			this.footswitches, err = gpio.Open(config)
+		}
+
+		/*
+		 * Check if the footswitches could be opened.
+		 */
+		if err != nil {
+			msg := err.Error()
+			fmt.Printf("Failed to open footswitches: %s\n", msg)
+			return nil
+		} else {
+			fmt.Printf("Footswitches ready: %d connected\n", numFootswitches)
+			footswitches := this.footswitches
+			return footswitches.Presses()
+		}
+
+	}
+
+}
+
+/*
+ * Triggers the action of the footswitch at a certain index of the
+ * configuration.
+ *
+ * The action is passed to the same handler as a request from the web
+ * interface, so that it is recorded in the undo history.
+ */
+func (this *controllerStruct) handleFootswitch(idx int) {
+	cfg := this.config
+	gpioCfg := cfg.Gpio
+	footswitchCfgs := gpioCfg.Footswitches
+	footswitchCfg := footswitchCfgs[idx]
+	pin := footswitchCfg.Pin
+	action := footswitchCfg.Action
+	params := map[string]string{}
+	err := error(nil)
+
+	/*
+	 * Translate the action into a request.
+	 */
+	if action != FOOTSWITCH_ACTION_TOGGLE_BYPASS {
+		params["cgi"] = action
+	} else {
+		chainId := int(footswitchCfg.Chain)
+		unitId := int(footswitchCfg.Unit)
+		chains := this.chains()
+		numChains := len(chains)
+
+		/*
+		 * Check if chain ID is out of range.
+		 */
+		if chainId >= numChains {
+			err = fmt.Errorf("%s", "Chain ID out of range.")
+		} else {
+			chain := chains[chainId]
+			bypass, errBypass := chain.GetBypass(unitId)
+			err = errBypass
+
+			/*
+			 * Request the opposite bypass state.
+			 */
+			if err == nil {
+				params["cgi"] = "set-bypass"
+				params["chain"] = strconv.FormatUint(uint64(chainId), 10)
+				params["unit"] = strconv.FormatUint(uint64(unitId), 10)
+				params["value"] = strconv.FormatBool(!bypass)
+			}
+
+		}
+
+	}
+
+	/*
+	 * Pass the request to its handler.
+	 */
+	if err == nil {
+		cgi := params["cgi"]
+		handler := this.handler(cgi)
+
+		/*
+		 * Create request.
+		 */
+		request := webserver.HttpRequest{
+			Params: params,
+		}
+
+		response := this.invoke(handler, request)
+		body := response.Body
+		probe := apiProbeStruct{}
+		errProbe := json.Unmarshal(body, &probe)
+
+		/*
+		 * Check if the handler succeeded.
+		 */
+		if errProbe != nil {
+			err = errProbe
+		} else if (probe.Success != nil) && !*probe.Success && (probe.Reason != nil) {
+			reason := *probe.Reason
+			err = fmt.Errorf("%s", reason)
+		}
+
+	}
+
+	/*
+	 * Report failures on the console, since there is nobody else to
+	 * tell.
+	 */
+	if err != nil {
+		msg := err.Error()
+		fmt.Printf("Footswitch on GPIO pin %d failed: %s\n", pin, msg)
+	}
+
+}
@@ -0,0 +1,692 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/resample"
+	"github.com/andrepxx/go-dsp-guitar/signal"
+	"github.com/andrepxx/go-dsp-guitar/wave"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*
+ * Creates a copy of a signal chain with the same units and parameters, which
+ * may be used for offline processing without touching the state of the
+ * original chain.
+ *
+ * Parameter smoothing is disabled, so that the copy starts with its final
+ * settings right away.
+ */
+func (this *controllerStruct) copyChain(chain signal.Chain) signal.Chain {
+	responses := this.impulseResponses
+	result := signal.Chain(nil)
+
+	/*
+	 * Create a stereo chain if the original chain is stereo.
+	 */
+	if chain.Stereo() {
+		result = signal.CreateStereoChain(responses)
+	} else {
+		result = signal.CreateChain(responses)
+	}
+
+	result.SetSmoothing(false)
+	units := this.persistChain(chain)
+	this.restoreChain(result, units)
+	metr := this.metr
+
+	/*
+	 * Synchronize tempo-dependent units to the metronome.
+	 */
+	if metr != nil {
+		speed := metr.Speed()
+		result.SetTempo(speed)
+	}
+
+	return result
+}
+
+/*
+ * Renders a certain number of frames of the output of a copy of a signal
+ * chain fed with the input signals given, which are padded with silence.
+ *
+ * A mono chain processes the first input, a stereo chain the first two inputs
+ * or the first input on both sides. The latency of the chain is compensated,
+ * so that the output is aligned with the input. Returns one output signal for
+ * a mono and two output signals for a stereo chain.
+ */
+func (this *controllerStruct) renderChain(chain signal.Chain, inputs [][]float64, length int, sampleRate uint32) [][]float64 {
+	chainCopy := this.copyChain(chain)
+	chainCopy.SetBlockSize(BLOCK_SIZE)
+	stereo := chainCopy.Stereo()
+	numChannels := 1
+
+	/*
+	 * Stereo chains have two channels.
+	 */
+	if stereo {
+		numChannels = 2
+	}
+
+	numInputs := len(inputs)
+	inputSignals := make([][]float64, numChannels)
+
+	/*
+	 * Assign an input signal to each channel.
+	 */
+	for i := range inputSignals {
+
+		/*
+		 * Repeat the first input if there are too few.
+		 */
+		if i < numInputs {
+			inputSignals[i] = inputs[i]
+		} else if numInputs > 0 {
+			inputSignals[i] = inputs[0]
+		}
+
+	}
+
+	latency32 := chainCopy.Latency(sampleRate)
+	latency := int(latency32)
+	totalLength := length + latency
+	inputBuffers := make([][]float64, numChannels)
+	outputBuffers := make([][]float64, numChannels)
+	outputs := make([][]float64, numChannels)
+
+	/*
+	 * Create buffers and outputs for each channel.
+	 */
+	for i := 0; i < numChannels; i++ {
+		inputBuffers[i] = make([]float64, BLOCK_SIZE)
+		outputBuffers[i] = make([]float64, BLOCK_SIZE)
+		outputs[i] = make([]float64, length)
+	}
+
+	/*
+	 * Process each block.
+	 */
+	for offset := 0; offset < totalLength; offset += BLOCK_SIZE {
+
+		/*
+		 * Fill the input buffer of each channel.
+		 */
+		for i, inputBuffer := range inputBuffers {
+			samples := inputSignals[i]
+			size := len(samples)
+			lBound := offset
+			uBound := offset + BLOCK_SIZE
+
+			/*
+			 * Do not read beyond the end of the samples.
+			 */
+			if lBound > size {
+				lBound = size
+			}
+
+			/*
+			 * Do not read beyond the end of the samples.
+			 */
+			if uBound > size {
+				uBound = size
+			}
+
+			n := copy(inputBuffer, samples[lBound:uBound])
+
+			/*
+			 * Pad the rest of the buffer with silence.
+			 */
+			for j := n; j < BLOCK_SIZE; j++ {
+				inputBuffer[j] = 0.0
+			}
+
+		}
+
+		/*
+		 * Process the block in mono or stereo.
+		 */
+		if stereo {
+			chainCopy.ProcessStereo(inputBuffers[0], inputBuffers[1], outputBuffers[0], outputBuffers[1], sampleRate)
+		} else {
+			chainCopy.Process(inputBuffers[0], outputBuffers[0], sampleRate)
+		}
+
+		/*
+		 * Copy the output of each channel, dropping the latency.
+		 */
+		for i, outputBuffer := range outputBuffers {
+			lBound := latency - offset
+
+			/*
+			 * Only frames after the latency produce output.
+			 */
+			if lBound < 0 {
+				lBound = 0
+			}
+
+			/*
+			 * Check if this block reaches beyond the latency.
+			 */
+			if lBound < BLOCK_SIZE {
+				target := offset + lBound - latency
+				copy(outputs[i][target:], outputBuffer[lBound:])
+			}
+
+		}
+
+	}
+
+	return outputs
+}
+
+/*
+ * Parses an optional length in milliseconds and converts it into frames at a
+ * certain sample rate.
+ */
+func parseFreezeLength(value string, sampleRate uint32) (int, error) {
+	length := uint64(FREEZE_DEFAULT_LENGTH)
+
+	/*
+	 * The length is optional.
+	 */
+	if value != "" {
+		length64, err := strconv.ParseUint(value, 10, 32)
+
+		/*
+		 * Check if length could be decoded.
+		 */
+		if err != nil {
+			return 0, fmt.Errorf("%s", "Failed to decode length.")
+		} else {
+			length = length64
+		}
+
+	}
+
+	/*
+	 * Check if the length is within range.
+	 */
+	if length > FREEZE_MAX_LENGTH {
+		return 0, fmt.Errorf("Length must not exceed %d ms.", FREEZE_MAX_LENGTH)
+	} else {
+		sampleRate64 := uint64(sampleRate)
+		frames := (length * sampleRate64) / 1000
+		return int(frames), nil
+	}
+
+}
+
+/*
+ * Renders the response of a channel's signal chain to a unit impulse and
+ * stores it as an impulse response, so that it can replace the chain, e. g.
+ * in a single convolution unit.
+ *
+ * This is only accurate for linear chains. Only the left channel of a stereo
+ * chain is kept.
+ */
+func (this *controllerStruct) freezeChannelHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	name := request.Params["name"]
+	name = strings.TrimSpace(name)
+	lengthString := request.Params["length"]
+	sampleRate := this.sampleRate
+	length, errLength := parseFreezeLength(lengthString, sampleRate)
+	chainId := int(chainId64)
+	fx := this.effects
+	numChannels := len(fx)
+	reason := ""
+
+	/*
+	 * Check if parameters are valid.
+	 */
+	if errChainId != nil {
+		reason = "Failed to decode chain ID."
+	} else if chainId >= numChannels {
+		reason = fmt.Sprintf("No channel with index %d.", chainId)
+	} else if name == "" {
+		reason = "No name given for impulse response."
+	} else if errLength != nil {
+		reason = errLength.Error()
+	} else if length == 0 {
+		reason = "Impulse response must not be empty."
+	} else {
+		chain := fx[chainId]
+		impulse := make([]float64, length)
+		impulse[0] = 1.0
+		inputs := [][]float64{impulse}
+		outputs := this.renderChain(chain, inputs, length, sampleRate)
+		err := this.writeImpulseResponse(name, 0, outputs[0], sampleRate)
+
+		/*
+		 * Check if impulse response was stored.
+		 */
+		if err != nil {
+			msg := err.Error()
+			reason = fmt.Sprintf("Failed to store impulse response: %s", msg)
+		}
+
+	}
+
+	/*
+	 * Reload impulse responses if the new one was stored.
+	 */
+	if reason == "" {
+		return this.reloadImpulseResponsesHandler(request)
+	} else {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse := webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+		mimeType, buffer := this.createJSON(webResponse)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response := webserver.HttpResponse{
+			Header: map[string]string{"Content-type": mimeType},
+			Body:   buffer,
+		}
+
+		return response
+	}
+
+}
+
+/*
+ * Decodes the samples of each channel of a wave file and converts them to the
+ * current sample rate.
+ *
+ * Returns the samples of each channel along with the length of the longest
+ * channel in frames.
+ */
+func (this *controllerStruct) decodeClip(content []byte) ([][]float64, int, error) {
+	file, err := wave.FromBuffer(content)
+
+	/*
+	 * Check if wave file could be decoded.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, 0, fmt.Errorf("Failed to decode input file: %s", msg)
+	} else {
+		channelCount := file.ChannelCount()
+		inputRate := file.SampleRate()
+		sampleRate := this.sampleRate
+		inputs := make([][]float64, channelCount)
+		length := 0
+
+		/*
+		 * Extract the samples of each channel and convert them to the
+		 * current sample rate.
+		 */
+		for i := range inputs {
+			channel, _ := file.Channel(uint16(i))
+			samples := channel.Floats()
+
+			/*
+			 * Check if resampling is necessary.
+			 */
+			if inputRate != sampleRate {
+				samples = resample.Sinc(samples, inputRate, sampleRate, resample.QUALITY_BEST)
+			}
+
+			inputs[i] = samples
+			size := len(samples)
+
+			/*
+			 * If we found a longer channel, store its length.
+			 */
+			if size > length {
+				length = size
+			}
+
+		}
+
+		/*
+		 * Make sure that there is something to render.
+		 */
+		if channelCount == 0 {
+			return nil, 0, fmt.Errorf("%s", "Input file contains no audio channel.")
+		} else {
+			return inputs, length, nil
+		}
+
+	}
+
+}
+
+/*
+ * Renders the output of a signal chain fed with the samples of a wave file and
+ * writes it into a file in the recordings directory, followed by a tail, so
+ * that reverbs and delays can decay.
+ */
+func (this *controllerStruct) renderStem(chain signal.Chain, name string, content []byte, tail int) error {
+	inputs, length, err := this.decodeClip(content)
+
+	/*
+	 * Check if wave file could be decoded.
+	 */
+	if err != nil {
+		return err
+	} else {
+		sampleRate := this.sampleRate
+		length += tail
+		outputs := this.renderChain(chain, inputs, length, sampleRate)
+		directory := this.config.Recordings
+
+		/*
+		 * Use the default directory if none is configured.
+		 */
+		if directory == "" {
+			directory = DEFAULT_RECORDINGS_DIRECTORY
+		}
+
+		err = os.MkdirAll(directory, UPLOAD_IR_DIRECTORY_MODE)
+
+		/*
+		 * Check if directory could be created.
+		 */
+		if err != nil {
+			return fmt.Errorf("Failed to create directory '%s'.", directory)
+		} else {
+			fileName := impulseResponseFileName(name)
+			output := filepath.Join(directory, fileName)
+			fd, err := os.Create(output)
+
+			/*
+			 * Check if output file was created.
+			 */
+			if err != nil {
+				return fmt.Errorf("Failed to create output file '%s'.", output)
+			} else {
+				numOutputs := uint16(len(outputs))
+				writer, err := wave.CreateWriter(fd, sampleRate, wave.AUDIO_IEEE_FLOAT, CAPTURE_BIT_DEPTH, numOutputs)
+
+				/*
+				 * Check if writer was created.
+				 */
+				if err != nil {
+					fd.Close()
+					msg := err.Error()
+					return fmt.Errorf("Failed to create wave file '%s': %s", output, msg)
+				} else {
+
+					/*
+					 * Create output file structure.
+					 */
+					outputFile := outputFileStruct{
+						name:   output,
+						ports:  []int{0},
+						file:   fd,
+						writer: writer,
+					}
+
+					errWrite := writer.Write(outputs)
+					outputFiles := []*outputFileStruct{&outputFile}
+					errClose := closeOutputFiles(outputFiles)
+
+					/*
+					 * Check if stem was written.
+					 */
+					if errWrite != nil {
+						msg := errWrite.Error()
+						return fmt.Errorf("Failed to write to output file '%s': %s", output, msg)
+					} else {
+						return errClose
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Renders a channel's signal chain applied to an uploaded wave file and
+ * stores the result as a stem in the recordings directory.
+ */
+func (this *controllerStruct) renderChannelStemHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	name := request.Params["name"]
+	name = strings.TrimSpace(name)
+	tailString := request.Params["tail"]
+	sampleRate := this.sampleRate
+	tail, errTail := parseFreezeLength(tailString, sampleRate)
+	chainId := int(chainId64)
+	fx := this.effects
+	numChannels := len(fx)
+	stemFiles := request.Files["stemfile"]
+	numStemFiles := len(stemFiles)
+	reason := ""
+
+	/*
+	 * Check if parameters are valid and exactly one input file is sent.
+	 */
+	if errChainId != nil {
+		reason = "Failed to decode chain ID."
+	} else if chainId >= numChannels {
+		reason = fmt.Sprintf("No channel with index %d.", chainId)
+	} else if name == "" {
+		reason = "No name given for stem."
+	} else if errTail != nil {
+		reason = errTail.Error()
+	} else if numStemFiles == 0 {
+		reason = "No input file sent in request."
+	} else if numStemFiles != 1 {
+		reason = "Multiple input files sent in request."
+	} else {
+		stemFile := stemFiles[0]
+		stemBytes, err := io.ReadAll(stemFile)
+
+		/*
+		 * Check if input file could be read and rendered.
+		 */
+		if err != nil {
+			reason = "Failed to read input file."
+		} else {
+			chain := fx[chainId]
+			err = this.renderStem(chain, name, stemBytes, tail)
+
+			/*
+			 * Check if stem was rendered.
+			 */
+			if err != nil {
+				msg := err.Error()
+				reason = fmt.Sprintf("Failed to render stem: %s", msg)
+			}
+
+		}
+
+	}
+
+	/*
+	 * Indicate success or failure.
+	 */
+	webResponse := webResponseStruct{
+		Success: reason == "",
+		Reason:  reason,
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Renders the output of a signal chain fed with the samples of a short wave
+ * file, followed by a tail, and returns it encoded as a wave file.
+ */
+func (this *controllerStruct) renderPreview(chain signal.Chain, content []byte, tail int) ([]byte, error) {
+	inputs, length, err := this.decodeClip(content)
+	sampleRate := this.sampleRate
+	sampleRate64 := uint64(sampleRate)
+	maxLength := (PREVIEW_MAX_LENGTH * sampleRate64) / 1000
+
+	/*
+	 * Check if wave file could be decoded and is short enough.
+	 */
+	if err != nil {
+		return nil, err
+	} else if uint64(length) > maxLength {
+		return nil, fmt.Errorf("Clip must not exceed %d ms.", PREVIEW_MAX_LENGTH)
+	} else {
+		length += tail
+		outputs := this.renderChain(chain, inputs, length, sampleRate)
+		numOutputs := uint16(len(outputs))
+		file, err := wave.CreateEmpty(sampleRate, wave.AUDIO_IEEE_FLOAT, CAPTURE_BIT_DEPTH, numOutputs)
+
+		/*
+		 * Check if wave file was created.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to create wave file: %s", msg)
+		} else {
+
+			/*
+			 * Store the samples of each output in a channel.
+			 */
+			for i, output := range outputs {
+				channel, _ := file.Channel(uint16(i))
+				channel.WriteFloats(output)
+			}
+
+			buffer, err := file.Bytes()
+
+			/*
+			 * Check if wave file was encoded.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to encode wave file: %s", msg)
+			} else {
+				return buffer, nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Renders a channel's signal chain applied to an uploaded wave file at the
+ * current settings and returns the result as a wave file, so that a patch can
+ * be auditioned without audio hardware.
+ */
+func (this *controllerStruct) previewChannelHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	tailString := request.Params["tail"]
+	sampleRate := this.sampleRate
+	tail, errTail := parseFreezeLength(tailString, sampleRate)
+	chainId := int(chainId64)
+	fx := this.effects
+	numChannels := len(fx)
+	clipFiles := request.Files["clipfile"]
+	numClipFiles := len(clipFiles)
+	reason := ""
+	buffer := []byte(nil)
+
+	/*
+	 * Check if parameters are valid and exactly one clip is sent.
+	 */
+	if errChainId != nil {
+		reason = "Failed to decode chain ID."
+	} else if chainId >= numChannels {
+		reason = fmt.Sprintf("No channel with index %d.", chainId)
+	} else if errTail != nil {
+		reason = errTail.Error()
+	} else if numClipFiles == 0 {
+		reason = "No clip sent in request."
+	} else if numClipFiles != 1 {
+		reason = "Multiple clips sent in request."
+	} else {
+		clipFile := clipFiles[0]
+		clipBytes, err := io.ReadAll(clipFile)
+
+		/*
+		 * Check if clip could be read and rendered.
+		 */
+		if err != nil {
+			reason = "Failed to read clip."
+		} else {
+			chain := fx[chainId]
+			buffer, err = this.renderPreview(chain, clipBytes, tail)
+
+			/*
+			 * Check if preview was rendered.
+			 */
+			if err != nil {
+				msg := err.Error()
+				reason = fmt.Sprintf("Failed to render preview: %s", msg)
+			}
+
+		}
+
+	}
+
+	/*
+	 * Return either the rendered audio or the reason of the failure.
+	 */
+	if reason == "" {
+
+		/*
+		 * Create HTTP response.
+		 */
+		response := webserver.HttpResponse{
+			Header: map[string]string{
+				"Content-type":        PREVIEW_MIME_TYPE,
+				"Content-disposition": "inline; filename=\"preview.wav\"",
+			},
+			Body: buffer,
+		}
+
+		return response
+	} else {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse := webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+		mimeType, buffer := this.createJSON(webResponse)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response := webserver.HttpResponse{
+			Header: map[string]string{"Content-type": mimeType},
+			Body:   buffer,
+		}
+
+		return response
+	}
+
+}
@@ -0,0 +1,768 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/rpc"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+/*
+ * Constants for the gRPC control interface.
+ *
+ * Stream intervals are given in milliseconds.
+ */
+const (
+	GRPC_PROTOCOL                = "gRPC"
+	GRPC_STREAM_DEFAULT_INTERVAL = 100
+	GRPC_STREAM_MIN_INTERVAL     = 10
+)
+
+/*
+ * Data structure representing the gRPC control interface.
+ *
+ * Calls are passed as v2 API requests to the message pump of the controller,
+ * so they are handled exactly like requests to the web interface.
+ */
+type grpcServerStruct struct {
+	rpc.UnimplementedDSPServer
+	requests chan<- webserver.HttpRequest
+}
+
+/*
+ * Converts an unsigned integer into its textual representation.
+ */
+func formatUint32(value uint32) string {
+	value64 := uint64(value)
+	return strconv.FormatUint(value64, 10)
+}
+
+/*
+ * Returns the interval between two updates of a stream.
+ */
+func streamInterval(request *rpc.StreamRequest) time.Duration {
+	interval := request.GetInterval()
+
+	/*
+	 * Use the default interval if none was given and make sure that
+	 * clients cannot flood the message pump.
+	 */
+	if interval == 0 {
+		interval = GRPC_STREAM_DEFAULT_INTERVAL
+	} else if interval < GRPC_STREAM_MIN_INTERVAL {
+		interval = GRPC_STREAM_MIN_INTERVAL
+	}
+
+	intervalDuration := time.Duration(interval)
+	return intervalDuration * time.Millisecond
+}
+
+/*
+ * Returns the address of the peer issuing a call, without its port.
+ */
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+
+	/*
+	 * Check if the peer is known.
+	 */
+	if !ok || p.Addr == nil {
+		return ""
+	} else {
+		address := p.Addr.String()
+		host, _, err := net.SplitHostPort(address)
+
+		/*
+		 * If the address carries no port, take it as is.
+		 */
+		if err != nil {
+			host = address
+		}
+
+		return host
+	}
+
+}
+
+/*
+ * Creates an interceptor which rejects unary calls from peers exceeding the
+ * request rate.
+ */
+func limitUnaryCalls(limiter webserver.Limiter) grpc.UnaryServerInterceptor {
+
+	/*
+	 * Check the request rate before handling the call.
+	 */
+	interceptor := func(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		client := peerAddress(ctx)
+
+		/*
+		 * Check if the peer may issue another call.
+		 */
+		if !limiter.Allow(client) {
+			return nil, status.Error(codes.ResourceExhausted, "Too many requests.")
+		} else {
+			return handler(ctx, request)
+		}
+
+	}
+
+	return interceptor
+}
+
+/*
+ * Creates an interceptor which rejects streaming calls from peers exceeding
+ * the request rate. Each stream counts as a single request.
+ */
+func limitStreamCalls(limiter webserver.Limiter) grpc.StreamServerInterceptor {
+
+	/*
+	 * Check the request rate before handling the call.
+	 */
+	interceptor := func(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := stream.Context()
+		client := peerAddress(ctx)
+
+		/*
+		 * Check if the peer may issue another call.
+		 */
+		if !limiter.Allow(client) {
+			return status.Error(codes.ResourceExhausted, "Too many requests.")
+		} else {
+			return handler(server, stream)
+		}
+
+	}
+
+	return interceptor
+}
+
+/*
+ * Passes a call to an endpoint of the v2 API to the message pump of the
+ * controller and waits for the response, unless the call is cancelled first.
+ *
+ * The response channel is buffered, so that the message pump does not block
+ * on calls which were cancelled while it handled them.
+ */
+func (this *grpcServerStruct) call(ctx context.Context, endpoint string, params map[string]string) (apiResponseStruct, error) {
+	respond := make(chan webserver.HttpResponse, 1)
+
+	/*
+	 * Create an API request.
+	 */
+	request := webserver.HttpRequest{
+		Protocol: GRPC_PROTOCOL,
+		Method:   http.MethodPost,
+		Path:     API_PREFIX + endpoint,
+		Params:   params,
+		Respond:  respond,
+	}
+
+	apiResponse := apiResponseStruct{}
+	done := ctx.Done()
+
+	/*
+	 * Pass the request to the message pump unless the call is cancelled.
+	 */
+	select {
+	case <-done:
+		err := ctx.Err()
+		return apiResponse, status.FromContextError(err).Err()
+	case this.requests <- request:
+	}
+
+	/*
+	 * Wait for the response unless the call is cancelled.
+	 */
+	select {
+	case <-done:
+		err := ctx.Err()
+		return apiResponse, status.FromContextError(err).Err()
+	case response := <-respond:
+		body := response.Body
+		err := json.Unmarshal(body, &apiResponse)
+
+		/*
+		 * Check if the response could be decoded.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return apiResponse, fmt.Errorf("Failed to decode response of endpoint '%s': %s", endpoint, msg)
+		} else {
+			return apiResponse, nil
+		}
+
+	}
+
+}
+
+/*
+ * Calls an endpoint of the v2 API which only reports success or failure.
+ */
+func (this *grpcServerStruct) callStatus(ctx context.Context, endpoint string, params map[string]string) (*rpc.Status, error) {
+	apiResponse, err := this.call(ctx, endpoint, params)
+
+	/*
+	 * Check if the endpoint could be called.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+
+		/*
+		 * Create the status of the operation.
+		 */
+		status := rpc.Status{
+			Success: apiResponse.Success,
+			Reason:  apiResponse.Reason,
+		}
+
+		return &status, nil
+	}
+
+}
+
+/*
+ * Calls an arbitrary endpoint of the v2 API.
+ */
+func (this *grpcServerStruct) Invoke(ctx context.Context, request *rpc.InvokeRequest) (*rpc.InvokeResponse, error) {
+	endpoint := request.GetEndpoint()
+	requestParams := request.GetParams()
+	params := make(map[string]string)
+
+	/*
+	 * Copy the parameters, since the controller adds its own.
+	 */
+	for key, value := range requestParams {
+		params[key] = value
+	}
+
+	apiResponse, err := this.call(ctx, endpoint, params)
+
+	/*
+	 * Check if the endpoint could be called.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		result := apiResponse.Result
+		resultString := string(result)
+
+		/*
+		 * Endpoints which only report success or failure return a null
+		 * result.
+		 */
+		if resultString == "null" {
+			resultString = ""
+		}
+
+		/*
+		 * Create the response of the endpoint.
+		 */
+		response := rpc.InvokeResponse{
+			Success: apiResponse.Success,
+			Reason:  apiResponse.Reason,
+			Result:  resultString,
+		}
+
+		return &response, nil
+	}
+
+}
+
+/*
+ * Returns the types of units which may be added to a chain.
+ */
+func (this *grpcServerStruct) GetUnitTypes(ctx context.Context, request *rpc.Empty) (*rpc.UnitTypes, error) {
+	params := make(map[string]string)
+	apiResponse, err := this.call(ctx, "get-unit-types", params)
+
+	/*
+	 * Check if the endpoint could be called.
+	 */
+	if err != nil {
+		return nil, err
+	} else if !apiResponse.Success {
+		reason := apiResponse.Reason
+		return nil, fmt.Errorf("Failed to obtain unit types: %s", reason)
+	} else {
+		result := apiResponse.Result
+		unitTypes := []string{}
+		err = json.Unmarshal(result, &unitTypes)
+
+		/*
+		 * Check if the unit types could be decoded.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to decode unit types: %s", msg)
+		} else {
+
+			/*
+			 * Create the list of unit types.
+			 */
+			response := rpc.UnitTypes{
+				Types: unitTypes,
+			}
+
+			return &response, nil
+		}
+
+	}
+
+}
+
+/*
+ * Adds a unit to the end of a chain.
+ */
+func (this *grpcServerStruct) AddUnit(ctx context.Context, request *rpc.AddUnitRequest) (*rpc.Status, error) {
+	chain := request.GetChain()
+	unitType := request.GetType()
+
+	/*
+	 * Parameters of the request.
+	 */
+	params := map[string]string{
+		"chain": formatUint32(chain),
+		"type":  formatUint32(unitType),
+	}
+
+	return this.callStatus(ctx, "add-unit", params)
+}
+
+/*
+ * Removes a unit from a chain.
+ */
+func (this *grpcServerStruct) RemoveUnit(ctx context.Context, request *rpc.UnitRequest) (*rpc.Status, error) {
+	chain := request.GetChain()
+	unit := request.GetUnit()
+
+	/*
+	 * Parameters of the request.
+	 */
+	params := map[string]string{
+		"chain": formatUint32(chain),
+		"unit":  formatUint32(unit),
+	}
+
+	return this.callStatus(ctx, "remove-unit", params)
+}
+
+/*
+ * Moves a unit one position towards the start of a chain.
+ */
+func (this *grpcServerStruct) MoveUp(ctx context.Context, request *rpc.UnitRequest) (*rpc.Status, error) {
+	chain := request.GetChain()
+	unit := request.GetUnit()
+
+	/*
+	 * Parameters of the request.
+	 */
+	params := map[string]string{
+		"chain": formatUint32(chain),
+		"unit":  formatUint32(unit),
+	}
+
+	return this.callStatus(ctx, "move-up", params)
+}
+
+/*
+ * Moves a unit one position towards the end of a chain.
+ */
+func (this *grpcServerStruct) MoveDown(ctx context.Context, request *rpc.UnitRequest) (*rpc.Status, error) {
+	chain := request.GetChain()
+	unit := request.GetUnit()
+
+	/*
+	 * Parameters of the request.
+	 */
+	params := map[string]string{
+		"chain": formatUint32(chain),
+		"unit":  formatUint32(unit),
+	}
+
+	return this.callStatus(ctx, "move-down", params)
+}
+
+/*
+ * Puts a unit into or out of bypass mode.
+ */
+func (this *grpcServerStruct) SetBypass(ctx context.Context, request *rpc.SetBypassRequest) (*rpc.Status, error) {
+	chain := request.GetChain()
+	unit := request.GetUnit()
+	value := request.GetValue()
+
+	/*
+	 * Parameters of the request.
+	 */
+	params := map[string]string{
+		"chain": formatUint32(chain),
+		"unit":  formatUint32(unit),
+		"value": strconv.FormatBool(value),
+	}
+
+	return this.callStatus(ctx, "set-bypass", params)
+}
+
+/*
+ * Sets a discrete parameter of a unit.
+ */
+func (this *grpcServerStruct) SetDiscreteValue(ctx context.Context, request *rpc.SetDiscreteValueRequest) (*rpc.Status, error) {
+	chain := request.GetChain()
+	unit := request.GetUnit()
+
+	/*
+	 * Parameters of the request.
+	 */
+	params := map[string]string{
+		"chain": formatUint32(chain),
+		"unit":  formatUint32(unit),
+		"param": request.GetParam(),
+		"value": request.GetValue(),
+	}
+
+	return this.callStatus(ctx, "set-discrete-value", params)
+}
+
+/*
+ * Sets a numeric parameter of a unit.
+ */
+func (this *grpcServerStruct) SetNumericValue(ctx context.Context, request *rpc.SetNumericValueRequest) (*rpc.Status, error) {
+	chain := request.GetChain()
+	unit := request.GetUnit()
+	value := request.GetValue()
+	value64 := int64(value)
+
+	/*
+	 * Parameters of the request.
+	 */
+	params := map[string]string{
+		"chain": formatUint32(chain),
+		"unit":  formatUint32(unit),
+		"param": request.GetParam(),
+		"value": strconv.FormatInt(value64, 10),
+	}
+
+	return this.callStatus(ctx, "set-numeric-value", params)
+}
+
+/*
+ * Obtains the results of the level meters.
+ */
+func (this *grpcServerStruct) levelUpdate(ctx context.Context) (*rpc.LevelUpdate, error) {
+	params := make(map[string]string)
+	apiResponse, err := this.call(ctx, "get-level-analysis", params)
+
+	/*
+	 * Check if the endpoint could be called.
+	 */
+	if err != nil {
+		return nil, err
+	} else if !apiResponse.Success {
+		reason := apiResponse.Reason
+		return nil, fmt.Errorf("Failed to obtain level analysis: %s", reason)
+	} else {
+		result := apiResponse.Result
+		analysis := webLevelMetersResultStruct{}
+		err = json.Unmarshal(result, &analysis)
+
+		/*
+		 * Check if the level analysis could be decoded.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to decode level analysis: %s", msg)
+		} else {
+			numChannels := len(analysis.Channels)
+			channels := make([]*rpc.ChannelLevel, numChannels)
+
+			/*
+			 * Convert the level of each channel.
+			 */
+			for i, channel := range analysis.Channels {
+
+				/*
+				 * The level of the channel.
+				 */
+				channels[i] = &rpc.ChannelLevel{
+					ChannelName: channel.ChannelName,
+					Level:       channel.Level,
+					Peak:        channel.Peak,
+				}
+
+			}
+
+			numReductions := len(analysis.GainReduction)
+			gainReduction := make([]*rpc.GainReduction, numReductions)
+
+			/*
+			 * Convert the gain reduction of each unit.
+			 */
+			for i, reduction := range analysis.GainReduction {
+
+				/*
+				 * The gain reduction of the unit.
+				 */
+				gainReduction[i] = &rpc.GainReduction{
+					Chain:     uint32(reduction.Chain),
+					Unit:      uint32(reduction.Unit),
+					Reduction: reduction.Reduction,
+				}
+
+			}
+
+			numClips := len(analysis.Clipping)
+			clipping := make([]*rpc.Clip, numClips)
+
+			/*
+			 * Convert each unit which clipped.
+			 */
+			for i, clip := range analysis.Clipping {
+
+				/*
+				 * The unit which clipped.
+				 */
+				clipping[i] = &rpc.Clip{
+					Chain: uint32(clip.Chain),
+					Unit:  uint32(clip.Unit),
+				}
+
+			}
+
+			/*
+			 * Create the level update.
+			 */
+			update := rpc.LevelUpdate{
+				DspLoad:       analysis.DSPLoad,
+				Channels:      channels,
+				GainReduction: gainReduction,
+				Clipping:      clipping,
+				Xruns:         analysis.Xruns.Xruns,
+			}
+
+			return &update, nil
+		}
+
+	}
+
+}
+
+/*
+ * Streams the results of the level meters until the call is cancelled.
+ */
+func (this *grpcServerStruct) StreamLevels(request *rpc.StreamRequest, stream rpc.DSP_StreamLevelsServer) error {
+	interval := streamInterval(request)
+	ticker := time.NewTicker(interval)
+	ctx := stream.Context()
+	done := ctx.Done()
+	running := true
+	err := error(nil)
+
+	/*
+	 * Send updates until the call is cancelled or fails.
+	 */
+	for running {
+
+		/*
+		 * Wait for the next update or the end of the call.
+		 */
+		select {
+		case <-done:
+			running = false
+		case <-ticker.C:
+			update, errUpdate := this.levelUpdate(ctx)
+
+			/*
+			 * Send the update if it could be obtained.
+			 */
+			if errUpdate != nil {
+				err = errUpdate
+				running = false
+			} else {
+				err = stream.Send(update)
+				running = (err == nil)
+			}
+
+		}
+
+	}
+
+	ticker.Stop()
+	return err
+}
+
+/*
+ * Streams the results of the tuner until the call is cancelled.
+ *
+ * No update is sent while the tuner cannot determine a note, e. g. because
+ * there is no signal.
+ */
+func (this *grpcServerStruct) StreamTuner(request *rpc.StreamRequest, stream rpc.DSP_StreamTunerServer) error {
+	interval := streamInterval(request)
+	ticker := time.NewTicker(interval)
+	ctx := stream.Context()
+	done := ctx.Done()
+	params := make(map[string]string)
+	running := true
+	err := error(nil)
+
+	/*
+	 * Send updates until the call is cancelled or fails.
+	 */
+	for running {
+
+		/*
+		 * Wait for the next update or the end of the call.
+		 */
+		select {
+		case <-done:
+			running = false
+		case <-ticker.C:
+			apiResponse, errCall := this.call(ctx, "get-tuner-analysis", params)
+			analysis := webTunerResultStruct{}
+
+			/*
+			 * Only send results of successful analyses.
+			 */
+			if errCall != nil {
+				err = errCall
+				running = false
+			} else if apiResponse.Success {
+				result := apiResponse.Result
+				errDecode := json.Unmarshal(result, &analysis)
+
+				/*
+				 * Check if the analysis could be decoded.
+				 */
+				if errDecode != nil {
+					msg := errDecode.Error()
+					err = fmt.Errorf("Failed to decode tuner analysis: %s", msg)
+					running = false
+				} else {
+
+					/*
+					 * Create the tuner update.
+					 */
+					update := rpc.TunerUpdate{
+						Cents:     int32(analysis.Cents),
+						Frequency: analysis.Frequency,
+						Note:      analysis.Note,
+					}
+
+					err = stream.Send(&update)
+					running = (err == nil)
+				}
+
+			}
+
+		}
+
+	}
+
+	ticker.Stop()
+	return err
+}
+
+/*
+ * Starts the gRPC control interface, if it is enabled, and returns the
+ * channel its requests arrive on. The channel is nil if the interface is
+ * disabled or could not be started, so that it never delivers a request.
+ */
+func (this *controllerStruct) startGrpcServer() <-chan webserver.HttpRequest {
+	cfg := this.config
+	grpcCfg := cfg.Grpc
+	serverCfg := cfg.WebServer
+
+	/*
+	 * Check if the gRPC interface is enabled.
+	 */
+	if !grpcCfg.Enabled {
+		return nil
+	} else {
+		port := grpcCfg.Port
+		address := ":" + port
+		listener, err := net.Listen("tcp", address)
+
+		/*
+		 * Check if we could listen on the port.
+		 */
+		if err != nil {
+			msg := err.Error()
+			fmt.Printf("Failed to start gRPC interface: %s\n", msg)
+			return nil
+		} else {
+			limits := serverCfg.Limits
+			limiter := webserver.CreateLimiter(limits)
+			unaryInterceptor := limitUnaryCalls(limiter)
+			streamInterceptor := limitStreamCalls(limiter)
+
+			/*
+			 * Limit the request rate of each peer like that of
+			 * the web interface.
+			 */
+			options := []grpc.ServerOption{
+				grpc.UnaryInterceptor(unaryInterceptor),
+				grpc.StreamInterceptor(streamInterceptor),
+			}
+
+			errCreds := error(nil)
+
+			/*
+			 * Use the key pair of the web server for TLS.
+			 */
+			if !grpcCfg.TLSDisabled {
+				publicKey := serverCfg.TLSPublicKey
+				privateKey := serverCfg.TLSPrivateKey
+				creds, err := credentials.NewServerTLSFromFile(publicKey, privateKey)
+				errCreds = err
+
+				/*
+				 * Check if the key pair could be loaded.
+				 */
+				if err == nil {
+					credsOption := grpc.Creds(creds)
+					options = append(options, credsOption)
+				}
+
+			}
+
+			/*
+			 * Check if the gRPC interface could be configured.
+			 */
+			if errCreds != nil {
+				msg := errCreds.Error()
+				listener.Close()
+				fmt.Printf("Failed to load key pair for gRPC interface: %s\n", msg)
+				return nil
+			} else {
+				requests := make(chan webserver.HttpRequest)
+
+				/*
+				 * Create the gRPC control interface.
+				 */
+				impl := grpcServerStruct{
+					requests: requests,
+				}
+
+				server := grpc.NewServer(options...)
+				rpc.RegisterDSPServer(server, &impl)
+				go server.Serve(listener)
+				fmt.Printf("gRPC interface ready: localhost:%s\n", port)
+				return requests
+			}
+
+		}
+
+	}
+
+}
@@ -0,0 +1,295 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+ * Clears the undo and redo history, since the configurations it contains no
+ * longer match the channels after channels were added or removed.
+ */
+func (this *controllerStruct) clearHistory() {
+	this.historyMutex.Lock()
+	this.undoHistory = nil
+	this.redoHistory = nil
+	this.lastEdit = ""
+	this.historyMutex.Unlock()
+}
+
+/*
+ * Finds out whether a CGI edits the configuration, so that it can be undone,
+ * and whether consecutive edits of the same value should be merged into a
+ * single step, like when turning a knob.
+ */
+func editType(cgi string) (bool, bool) {
+
+	/*
+	 * Check which kind of edit the CGI performs.
+	 */
+	switch cgi {
+	case "add-automation-lane", "add-unit", "apply-input-calibration", "collapse-units", "expand-unit", "import-chain", "insert-library-entry", "move-down", "move-up", "next-scene", "persistence-restore", "previous-scene", "program-change", "remove-automation-lane", "remove-unit", "reset-parameter", "select-scene", "set-bypass", "set-channel-color", "set-channel-name", "set-discrete-value", "set-mute", "set-solo", "set-unit-label", "set-unit-note", "toggle-snapshot":
+		return true, false
+	case "set-automation-value", "set-azimuth", "set-channel-trim", "set-distance", "set-input-trim", "set-level", "set-master-value", "set-metronome-output", "set-metronome-value", "set-numeric-value", "set-output-level", "set-reamp-level", "set-return", "set-send":
+		return true, true
+	default:
+		return false, false
+	}
+
+}
+
+/*
+ * Creates a key identifying the value a request edits. Requests which edit
+ * the same value differ only in the new value.
+ */
+func editKey(params map[string]string) string {
+	keys := []string{}
+
+	/*
+	 * Collect the names of all parameters except the value.
+	 */
+	for key := range params {
+
+		/*
+		 * Check if this is the value.
+		 */
+		if key != "value" {
+			keys = append(keys, key)
+		}
+
+	}
+
+	sort.Strings(keys)
+	n := len(keys)
+	pairs := make([]string, n)
+
+	/*
+	 * Combine each parameter name with its value.
+	 */
+	for i, key := range keys {
+		value := params[key]
+		pairs[i] = key + "=" + value
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+/*
+ * Adds the configuration before an edit to the undo history and clears the
+ * redo history.
+ *
+ * An edit with the same non-empty key as the previous one, which follows
+ * shortly after it, is merged into the previous step.
+ */
+func (this *controllerStruct) recordEdit(key string, before persistence.Configuration) {
+	this.historyMutex.Lock()
+	now := time.Now()
+	elapsed := now.Sub(this.lastEditTime)
+	merge := (key != "") && (key == this.lastEdit) && (elapsed < HISTORY_COALESCE_TIME)
+
+	/*
+	 * Only add a new step unless the edit is merged into the previous one.
+	 */
+	if !merge {
+		history := append(this.undoHistory, before)
+		numSteps := len(history)
+
+		/*
+		 * Only keep the most recent steps.
+		 */
+		if numSteps > HISTORY_LENGTH {
+			offset := numSteps - HISTORY_LENGTH
+			history = history[offset:numSteps]
+		}
+
+		this.undoHistory = history
+	}
+
+	this.redoHistory = nil
+	this.lastEdit = key
+	this.lastEditTime = now
+	this.historyMutex.Unlock()
+}
+
+/*
+ * Restores the configuration before the last edit (undo) or before the last
+ * undo (redo), moving the current configuration onto the opposite history.
+ *
+ * The latency is not part of the history, so it is left unchanged.
+ */
+func (this *controllerStruct) stepHistory(undo bool) error {
+	this.historyMutex.Lock()
+	source := this.redoHistory
+	destination := this.undoHistory
+	action := "redo"
+
+	/*
+	 * Check whether to undo or redo.
+	 */
+	if undo {
+		source = this.undoHistory
+		destination = this.redoHistory
+		action = "undo"
+	}
+
+	numSteps := len(source)
+
+	/*
+	 * Check if there is anything to restore.
+	 */
+	if numSteps == 0 {
+		this.historyMutex.Unlock()
+		return fmt.Errorf("Nothing to %s.", action)
+	} else {
+		this.haltMorph()
+		current := this.createPatch()
+		lastStep := numSteps - 1
+		target := source[lastStep]
+		target.FramesPerPeriod = current.FramesPerPeriod
+		source = source[0:lastStep]
+		destination = append(destination, current)
+
+		/*
+		 * Store both histories.
+		 */
+		if undo {
+			this.undoHistory = source
+			this.redoHistory = destination
+		} else {
+			this.redoHistory = source
+			this.undoHistory = destination
+		}
+
+		this.lastEdit = ""
+		err := this.restoreConfiguration(target)
+		this.historyMutex.Unlock()
+		return err
+	}
+
+}
+
+/*
+ * Returns the number of edits which can be undone and redone.
+ */
+func (this *controllerStruct) getHistoryHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	this.historyMutex.Lock()
+	numUndo := len(this.undoHistory)
+	numRedo := len(this.redoHistory)
+	this.historyMutex.Unlock()
+
+	/*
+	 * Create history structure.
+	 */
+	result := webHistoryStruct{
+		Undo: numUndo,
+		Redo: numRedo,
+	}
+
+	mimeType, buffer := this.createJSON(result)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Reverts the last edit of the configuration.
+ */
+func (this *controllerStruct) undoHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	err := this.stepHistory(true)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if the edit was undone.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Reapplies the last edit which was undone.
+ */
+func (this *controllerStruct) redoHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	err := this.stepHistory(false)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if the edit was redone.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
@@ -0,0 +1,840 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/effects"
+	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/signal"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"os"
+	"strconv"
+)
+
+/*
+ * A data structure encoding an entry of the unit library.
+ */
+type webLibraryEntryStruct struct {
+	Name  string
+	Units []string
+}
+
+/*
+ * Loads the unit library from disk.
+ *
+ * If no library is configured or the file does not exist yet, the library
+ * starts out empty.
+ */
+func (this *controllerStruct) loadLibrary() error {
+	fileName := this.config.Library
+	this.library = []persistence.LibraryEntry{}
+
+	/*
+	 * Check if a library is configured.
+	 */
+	if fileName == "" {
+		return nil
+	} else {
+		content, err := os.ReadFile(fileName)
+
+		/*
+		 * Check if file could be read.
+		 */
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to read unit library '%s': %s", fileName, msg)
+		} else {
+			library := persistence.Library{}
+			err = json.Unmarshal(content, &library)
+			fileFormat := library.FileFormat
+			fileVersion := fileFormat.Version
+
+			/*
+			 * Check if library could be decoded.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return fmt.Errorf("Failed to decode unit library '%s': %s", fileName, msg)
+			} else if fileFormat.Type != "library" {
+				return fmt.Errorf("File '%s' is not a unit library file.", fileName)
+			} else if fileVersion.Major != 1 {
+				return fmt.Errorf("Unit library '%s' has an incompatible version of file format.", fileName)
+			} else {
+				entries := library.Entries
+
+				/*
+				 * An empty library may be stored as null.
+				 */
+				if entries == nil {
+					entries = []persistence.LibraryEntry{}
+				}
+
+				this.library = entries
+				return nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Stores the unit library on disk, if a library is configured.
+ */
+func (this *controllerStruct) saveLibrary() error {
+	cfg := this.config
+	fileName := cfg.Library
+
+	/*
+	 * Check if a library is configured.
+	 */
+	if fileName == "" {
+		return nil
+	} else {
+		svr := cfg.WebServer
+		appName := svr.Name
+
+		/*
+		 * Create file format version.
+		 */
+		version := persistence.Version{
+			Major: 1,
+			Minor: 0,
+		}
+
+		/*
+		 * Create file format.
+		 */
+		fileFormat := persistence.FileFormat{
+			Application: appName,
+			Type:        "library",
+			Version:     version,
+		}
+
+		/*
+		 * Create library.
+		 */
+		library := persistence.Library{
+			FileFormat: fileFormat,
+			Entries:    this.library,
+		}
+
+		content, err := json.MarshalIndent(library, "", "\t")
+
+		/*
+		 * Check if library could be encoded.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to encode unit library: %s", msg)
+		} else {
+			err = os.WriteFile(fileName, content, 0644)
+
+			/*
+			 * Check if library was written.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return fmt.Errorf("Failed to write unit library '%s': %s", fileName, msg)
+			} else {
+				return nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Returns the index of the library entry with a certain name, or -1 if there
+ * is none.
+ */
+func (this *controllerStruct) libraryEntryIndex(name string) int {
+	result := -1
+
+	/*
+	 * Search for the entry.
+	 */
+	for i, entry := range this.library {
+
+		/*
+		 * Check if we found the entry.
+		 */
+		if entry.Name == name {
+			result = i
+		}
+
+	}
+
+	return result
+}
+
+/*
+ * Parses the ID of a signal chain and checks that the chain exists.
+ */
+func (this *controllerStruct) chainIndex(value string) (int, error) {
+	chainId64, err := strconv.ParseUint(value, 10, 32)
+	fx := this.chains()
+	numChains := uint64(len(fx))
+
+	/*
+	 * Check if chain ID could be parsed and is in range.
+	 */
+	if err != nil {
+		return -1, fmt.Errorf("%s", "Failed to decode chain ID.")
+	} else if chainId64 >= numChains {
+		return -1, fmt.Errorf("%s", "Chain ID out of range.")
+	} else {
+		chainId := int(chainId64)
+		return chainId, nil
+	}
+
+}
+
+/*
+ * Parses a range of units, given by the ID of the first unit and the number of
+ * units, and checks that all units exist in a signal chain. If no number is
+ * given, the range holds a single unit.
+ */
+func unitRange(chain signal.Chain, unitString string, countString string) (int, int, error) {
+	unitId64, errUnitId := strconv.ParseUint(unitString, 10, 32)
+	count64 := uint64(1)
+	errCount := error(nil)
+
+	/*
+	 * The number of units is optional.
+	 */
+	if countString != "" {
+		count64, errCount = strconv.ParseUint(countString, 10, 32)
+	}
+
+	numUnits := uint64(chain.Length())
+
+	/*
+	 * Check if the range could be parsed and is in range.
+	 */
+	if errUnitId != nil {
+		return -1, 0, fmt.Errorf("%s", "Failed to decode unit ID.")
+	} else if (errCount != nil) || (count64 == 0) {
+		return -1, 0, fmt.Errorf("%s", "Failed to decode unit count.")
+	} else if unitId64 >= numUnits {
+		return -1, 0, fmt.Errorf("No unit %d.", unitId64)
+	} else if (unitId64 + count64) > numUnits {
+		last := unitId64 + count64 - 1
+		return -1, 0, fmt.Errorf("No unit %d.", last)
+	} else {
+		unitId := int(unitId64)
+		count := int(count64)
+		return unitId, count, nil
+	}
+
+}
+
+/*
+ * Stores a range of units of a signal chain in the library, replacing the
+ * entry of the same name, if any.
+ */
+func (this *controllerStruct) saveLibraryEntry(chain signal.Chain, unitId int, count int, name string) error {
+
+	/*
+	 * Entries are referred to by their name.
+	 */
+	if name == "" {
+		return fmt.Errorf("%s", "Library entry needs a name.")
+	} else {
+		units := this.persistChain(chain)
+		end := unitId + count
+
+		/*
+		 * Create library entry.
+		 */
+		entry := persistence.LibraryEntry{
+			Name:  name,
+			Units: units[unitId:end],
+		}
+
+		idx := this.libraryEntryIndex(name)
+
+		/*
+		 * Check if an entry of the same name exists.
+		 */
+		if idx < 0 {
+			this.library = append(this.library, entry)
+		} else {
+			this.library[idx] = entry
+		}
+
+		err := this.saveLibrary()
+		return err
+	}
+
+}
+
+/*
+ * Appends a library entry to a signal chain as a composite unit.
+ */
+func (this *controllerStruct) insertLibraryEntry(chain signal.Chain, name string) error {
+	idx := this.libraryEntryIndex(name)
+
+	/*
+	 * Check if the entry exists.
+	 */
+	if idx < 0 {
+		return fmt.Errorf("No library entry '%s'.", name)
+	} else {
+		this.haltMorph()
+		entry := this.library[idx]
+		unitId, err := chain.AppendUnit(effects.UNIT_COMPOSITE)
+
+		/*
+		 * Check if composite unit was created.
+		 */
+		if err != nil {
+			return err
+		} else {
+			composite, err := chain.Composite(unitId)
+
+			/*
+			 * Check if composite unit still exists.
+			 */
+			if err != nil {
+				return err
+			} else {
+				composite.SetName(entry.Name)
+				innerChain := composite.Units()
+				this.restoreChain(innerChain, entry.Units)
+				return nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Replaces a composite unit inside a signal chain by the units it holds.
+ *
+ * A bypassed composite unit leaves all its units bypassed, while its input
+ * trim and output level are added to those of its first and last unit.
+ * Automation lanes of the composite unit are removed.
+ */
+func (this *controllerStruct) expandComposite(chainId int, unitId int) error {
+	fx := this.chains()
+	chain := fx[chainId]
+	units := this.persistChain(chain)
+	unit := units[unitId]
+	unitTypes := effects.UnitTypes()
+	compositeType := unitTypes[effects.UNIT_COMPOSITE]
+
+	/*
+	 * Check if the unit is a composite unit.
+	 */
+	if unit.Type != compositeType {
+		return fmt.Errorf("Unit %d is not a composite unit.", unitId)
+	} else {
+		innerUnits := unit.Units
+		numInnerUnits := len(innerUnits)
+
+		/*
+		 * The units inside remain bypassed along with their composite.
+		 */
+		if unit.Bypass {
+
+			/*
+			 * Bypass each unit inside.
+			 */
+			for i := range innerUnits {
+				innerUnits[i].Bypass = true
+			}
+
+		}
+
+		/*
+		 * Add the input trim and output level to the first and last unit.
+		 */
+		if numInnerUnits > 0 {
+			last := numInnerUnits - 1
+			inputTrim := innerUnits[0].InputTrim + unit.InputTrim
+			innerUnits[0].InputTrim = clampGain(inputTrim)
+			outputLevel := innerUnits[last].OutputLevel + unit.OutputLevel
+			innerUnits[last].OutputLevel = clampGain(outputLevel)
+		}
+
+		unitIdInc := unitId + 1
+		result := []persistence.Unit{}
+		result = append(result, units[:unitId]...)
+		result = append(result, innerUnits...)
+		result = append(result, units[unitIdInc:]...)
+		this.haltMorph()
+
+		/*
+		 * Units after the composite unit move along with the units it
+		 * held.
+		 */
+		this.remapAutomation(func(laneChain int, laneUnit int) (int, int, bool) {
+
+			/*
+			 * Check if the lane belongs to the chain.
+			 */
+			if (laneChain != chainId) || (laneUnit < unitId) {
+				return laneChain, laneUnit, true
+			} else if laneUnit == unitId {
+				return laneChain, laneUnit, false
+			} else {
+				shifted := laneUnit + numInnerUnits - 1
+				return laneChain, shifted, true
+			}
+
+		})
+
+		this.restoreChain(chain, result)
+		return nil
+	}
+
+}
+
+/*
+ * Replaces a range of units inside a signal chain by a composite unit holding
+ * them. Automation lanes of the units replaced are removed.
+ */
+func (this *controllerStruct) collapseUnits(chainId int, unitId int, count int, name string) {
+	fx := this.chains()
+	chain := fx[chainId]
+	units := this.persistChain(chain)
+	unitTypes := effects.UnitTypes()
+	compositeType := unitTypes[effects.UNIT_COMPOSITE]
+	end := unitId + count
+	innerUnits := []persistence.Unit{}
+	innerUnits = append(innerUnits, units[unitId:end]...)
+
+	/*
+	 * Create composite unit.
+	 */
+	composite := persistence.Unit{
+		Type:           compositeType,
+		Name:           name,
+		Bypass:         false,
+		InputTrim:      signal.GAIN_NEUTRAL,
+		OutputLevel:    signal.GAIN_NEUTRAL,
+		DiscreteParams: []persistence.DiscreteParam{},
+		NumericParams:  []persistence.NumericParam{},
+		Units:          innerUnits,
+	}
+
+	result := []persistence.Unit{}
+	result = append(result, units[:unitId]...)
+	result = append(result, composite)
+	result = append(result, units[end:]...)
+	this.haltMorph()
+
+	/*
+	 * Units after the range move up to the composite unit.
+	 */
+	this.remapAutomation(func(laneChain int, laneUnit int) (int, int, bool) {
+
+		/*
+		 * Check if the lane belongs to the chain.
+		 */
+		if (laneChain != chainId) || (laneUnit < unitId) {
+			return laneChain, laneUnit, true
+		} else if laneUnit < end {
+			return laneChain, laneUnit, false
+		} else {
+			shifted := laneUnit - count + 1
+			return laneChain, shifted, true
+		}
+
+	})
+
+	this.restoreChain(chain, result)
+}
+
+/*
+ * Keeps a gain (in decibels) within the range of input trims and output
+ * levels.
+ */
+func clampGain(gain int32) int32 {
+
+	/*
+	 * Check if gain is out of range.
+	 */
+	if gain < signal.GAIN_MINIMUM {
+		return signal.GAIN_MINIMUM
+	} else if gain > signal.GAIN_MAXIMUM {
+		return signal.GAIN_MAXIMUM
+	} else {
+		return gain
+	}
+
+}
+
+/*
+ * Returns the entries of the unit library along with the types of the units
+ * they hold.
+ */
+func (this *controllerStruct) getLibraryHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	entries := this.library
+	numEntries := len(entries)
+	webEntries := make([]webLibraryEntryStruct, numEntries)
+
+	/*
+	 * Describe each entry.
+	 */
+	for i, entry := range entries {
+		units := entry.Units
+		numUnits := len(units)
+		unitTypes := make([]string, numUnits)
+
+		/*
+		 * Collect the type of each unit.
+		 */
+		for j, unit := range units {
+			unitTypes[j] = unit.Type
+		}
+
+		/*
+		 * Create library entry structure.
+		 */
+		webEntries[i] = webLibraryEntryStruct{
+			Name:  entry.Name,
+			Units: unitTypes,
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webEntries)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Stores a range of units of a signal chain in the unit library.
+ */
+func (this *controllerStruct) saveLibraryEntryHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	params := request.Params
+	chainId, err := this.chainIndex(params["chain"])
+
+	/*
+	 * Store the units if the chain exists.
+	 */
+	if err == nil {
+		fx := this.chains()
+		chain := fx[chainId]
+		unitId, count, errRange := unitRange(chain, params["unit"], params["count"])
+		err = errRange
+
+		/*
+		 * Check if the units exist.
+		 */
+		if err == nil {
+			name := params["name"]
+			err = this.saveLibraryEntry(chain, unitId, count, name)
+		}
+
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if operation was successful.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Removes an entry from the unit library.
+ */
+func (this *controllerStruct) removeLibraryEntryHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	name := request.Params["name"]
+	idx := this.libraryEntryIndex(name)
+	err := error(nil)
+
+	/*
+	 * Check if the entry exists.
+	 */
+	if idx < 0 {
+		err = fmt.Errorf("No library entry '%s'.", name)
+	} else {
+		idxInc := idx + 1
+		this.library = append(this.library[:idx], this.library[idxInc:]...)
+		err = this.saveLibrary()
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if operation was successful.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Appends an entry of the unit library to a signal chain as a composite unit.
+ */
+func (this *controllerStruct) insertLibraryEntryHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	params := request.Params
+	chainId, err := this.chainIndex(params["chain"])
+
+	/*
+	 * Insert the entry if the chain exists.
+	 */
+	if err == nil {
+		fx := this.chains()
+		chain := fx[chainId]
+		name := params["name"]
+		err = this.insertLibraryEntry(chain, name)
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if operation was successful.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Replaces a composite unit inside a signal chain by the units it holds.
+ */
+func (this *controllerStruct) expandUnitHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	params := request.Params
+	chainId, err := this.chainIndex(params["chain"])
+
+	/*
+	 * Expand the unit if the chain exists.
+	 */
+	if err == nil {
+		fx := this.chains()
+		chain := fx[chainId]
+		unitId, _, errRange := unitRange(chain, params["unit"], "")
+		err = errRange
+
+		/*
+		 * Check if the unit exists.
+		 */
+		if err == nil {
+			err = this.expandComposite(chainId, unitId)
+		}
+
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if operation was successful.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Replaces a range of units inside a signal chain by a composite unit holding
+ * them.
+ */
+func (this *controllerStruct) collapseUnitsHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	params := request.Params
+	chainId, err := this.chainIndex(params["chain"])
+
+	/*
+	 * Collapse the units if the chain exists.
+	 */
+	if err == nil {
+		fx := this.chains()
+		chain := fx[chainId]
+		unitId, count, errRange := unitRange(chain, params["unit"], params["count"])
+		err = errRange
+
+		/*
+		 * Check if the units exist.
+		 */
+		if err == nil {
+			name := params["name"]
+			this.collapseUnits(chainId, unitId, count, name)
+		}
+
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if operation was successful.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
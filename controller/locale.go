@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/andrepxx/go-dsp-guitar/effects"
+	"github.com/andrepxx/go-dsp-guitar/locale"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+)
+
+/*
+ * A data structure encoding the display names of unit types and parameters
+ * in a certain language.
+ */
+type webDisplayNamesStruct struct {
+	Language   string
+	Languages  []string
+	UnitTypes  map[string]string
+	Parameters map[string]string
+}
+
+/*
+ * Returns the language a request should be answered in.
+ *
+ * The language is taken from the 'lang' parameter of the request, then from
+ * the config file. Unsupported languages are answered in the default
+ * language.
+ */
+func (this *controllerStruct) requestLanguage(params map[string]string) string {
+	language := params["lang"]
+
+	/*
+	 * Fall back to the language from the config file.
+	 */
+	if language == "" {
+		language = this.config.Language
+	}
+
+	/*
+	 * Check if the language is supported.
+	 */
+	if !locale.IsLanguage(language) {
+		return locale.LANGUAGE_DEFAULT
+	} else {
+		return language
+	}
+
+}
+
+/*
+ * Translates the reason a handler gives for a failure into a certain
+ * language.
+ *
+ * Only responses which tell whether an operation was successful are
+ * translated, all other responses are passed on as they are.
+ */
+func (this *controllerStruct) localizeResponse(response webserver.HttpResponse, language string) webserver.HttpResponse {
+	body := response.Body
+	probe := apiProbeStruct{}
+	err := json.Unmarshal(body, &probe)
+
+	/*
+	 * Check if there is a reason to translate.
+	 */
+	if (language == locale.LANGUAGE_ENGLISH) || (err != nil) || (probe.Success == nil) || (probe.Reason == nil) || (*probe.Reason == "") {
+		return response
+	} else {
+		fields := map[string]json.RawMessage{}
+		json.Unmarshal(body, &fields)
+		numFields := len(fields)
+		result, hasResult := fields["Result"]
+		reason := locale.Translate(language, *probe.Reason)
+		buffer := []byte(nil)
+
+		/*
+		 * Re-encode the response in the structure it was created from.
+		 */
+		if numFields == 2 {
+
+			/*
+			 * The translated web response.
+			 */
+			webResponse := webResponseStruct{
+				Success: *probe.Success,
+				Reason:  reason,
+			}
+
+			_, buffer = this.createJSON(webResponse)
+		} else if (numFields == 3) && hasResult {
+
+			/*
+			 * The translated API response.
+			 */
+			apiResponse := apiResponseStruct{
+				Success: *probe.Success,
+				Reason:  reason,
+				Result:  result,
+			}
+
+			_, buffer = this.createJSON(apiResponse)
+		}
+
+		/*
+		 * Check if the response was translated.
+		 */
+		if buffer != nil {
+			response.Body = buffer
+		}
+
+		return response
+	}
+
+}
+
+/*
+ * Returns the display names of all unit types and their parameters in the
+ * language of the request.
+ */
+func (this *controllerStruct) getDisplayNamesHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	language := this.requestLanguage(request.Params)
+	unitTypes := effects.UnitTypes()
+	unitTypeNames := map[string]string{}
+	parameterNames := map[string]string{}
+
+	/*
+	 * Look up the display name of each unit type and its parameters.
+	 */
+	for i, unitType := range unitTypes {
+		unitTypeNames[unitType] = locale.DisplayName(language, unitType)
+		unit := effects.CreateUnit(i)
+
+		/*
+		 * Composite units take their parameters from the units inside.
+		 */
+		if unit != nil {
+			params := unit.Parameters()
+
+			/*
+			 * Look up the display name of each parameter.
+			 */
+			for _, param := range params {
+				name := param.Name
+				parameterNames[name] = locale.DisplayName(language, name)
+			}
+
+		}
+
+	}
+
+	languages := locale.Languages()
+
+	/*
+	 * Create display names structure.
+	 */
+	displayNames := webDisplayNamesStruct{
+		Language:   language,
+		Languages:  languages,
+		UnitTypes:  unitTypeNames,
+		Parameters: parameterNames,
+	}
+
+	mimeType, buffer := this.createJSON(displayNames)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"strconv"
+)
+
+/*
+ * Restores the settings of the master section from a patch.
+ *
+ * Parameters missing from the patch, e. g. in patches created by older
+ * versions, are set to their default values.
+ */
+func (this *controllerStruct) restoreMaster(persistedMaster persistence.Master) {
+	masterSection := this.masterSection
+	masterSection.Reset()
+
+	/*
+	 * Restore each numeric parameter.
+	 */
+	for _, param := range persistedMaster.NumericParams {
+		key := param.Key
+		value := param.Value
+		masterSection.SetValue(key, value)
+	}
+
+	enabled := persistedMaster.Enabled
+	masterSection.SetEnabled(enabled)
+}
+
+/*
+ * Sets a value for the master section. The parameter 'enabled' switches the
+ * master section on or off, all other parameters are numeric.
+ */
+func (this *controllerStruct) setMasterValueHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	masterSection := this.masterSection
+	param := request.Params["param"]
+	valueString := request.Params["value"]
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check whether the master section should be switched on or off.
+	 */
+	if param == "enabled" {
+		value, err := strconv.ParseBool(valueString)
+
+		/*
+		 * Check if value failed to parse.
+		 */
+		if err != nil {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Failed to decode master section enabled flag.",
+			}
+
+		} else {
+			masterSection.SetEnabled(value)
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
+		}
+
+	} else {
+		value64, err := strconv.ParseInt(valueString, 10, 32)
+
+		/*
+		 * Check if value failed to parse.
+		 */
+		if err != nil {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Failed to decode master section value.",
+			}
+
+		} else {
+			value := int32(value64)
+			err = masterSection.SetValue(param, value)
+
+			/*
+			 * Check if value could be set.
+			 */
+			if err != nil {
+				msg := err.Error()
+				reason := fmt.Sprintf("Failed to set master section value: %s", msg)
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
@@ -0,0 +1,944 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/metronome"
+	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"strconv"
+	"time"
+)
+
+/*
+ * Creates the coefficients of a metronome sound, which is either synthesized
+ * with a pitch and a decay time or loaded from an impulse response. Returns
+ * nil if the sound is not available.
+ */
+func (this *controllerStruct) metronomeSound(name string, pitch float64, decay float64) []float64 {
+	sampleRate := this.sampleRate
+
+	/*
+	 * Check if the sound is synthesized.
+	 */
+	if metronome.IsSynthesized(name) {
+		coeffs, err := metronome.Synthesize(name, pitch, decay, sampleRate)
+
+		/*
+		 * Check if the sound was synthesized.
+		 */
+		if err != nil {
+			return nil
+		} else {
+			return coeffs
+		}
+
+	} else {
+		irs := this.impulseResponses
+		flt := irs.CreateFilter(name, sampleRate)
+
+		/*
+		 * Check if filter was successfully loaded.
+		 */
+		if flt == nil {
+			return nil
+		} else {
+			coeffs := flt.Coefficients()
+			return coeffs
+		}
+
+	}
+
+}
+
+/*
+ * Synthesizes the sounds of the metronome again, e. g. after their parameters
+ * or the sample rate changed. Sounds loaded from impulse responses are kept.
+ */
+func (this *controllerStruct) synthesizeMetronomeSounds() {
+	metr := this.metr
+
+	/*
+	 * Check if we have a metronome.
+	 */
+	if metr != nil {
+		tickSound, _ := metr.Tick()
+		tockSound, _ := metr.Tock()
+
+		/*
+		 * Check if the tick sound is synthesized.
+		 */
+		if metronome.IsSynthesized(tickSound) {
+			pitch, decay := metr.TickSynthesis()
+			coeffs := this.metronomeSound(tickSound, pitch, decay)
+			metr.SetTick(tickSound, coeffs)
+		}
+
+		/*
+		 * Check if the tock sound is synthesized.
+		 */
+		if metronome.IsSynthesized(tockSound) {
+			pitch, decay := metr.TockSynthesis()
+			coeffs := this.metronomeSound(tockSound, pitch, decay)
+			metr.SetTock(tockSound, coeffs)
+		}
+
+	}
+
+}
+
+/*
+ * Restores the settings of the metronome from a patch.
+ */
+func (this *controllerStruct) restoreMetronome(persistedMetr persistence.Metronome) {
+	metr := this.metr
+	masterOutput := persistedMetr.Master
+
+	/*
+	 * Restore whether the click is mixed into the master output.
+	 */
+	this.changeMix(func(mix *mixSnapshotStruct) {
+		mix.metrMasterOutput = masterOutput
+	})
+
+	beatsPerPeriod := persistedMetr.BeatsPerPeriod
+	metr.SetBeatsPerPeriod(beatsPerPeriod)
+	speed := persistedMetr.Speed
+	metr.SetSpeed(speed)
+	this.updateTempo()
+	subdivision := persistedMetr.Subdivision
+
+	/*
+	 * Patches from older versions do not subdivide the beat.
+	 */
+	if subdivision == 0 {
+		subdivision = metronome.DEFAULT_SUBDIVISION
+	}
+
+	metr.SetSubdivision(subdivision)
+	accents := persistedMetr.Accents
+	metr.SetAccents(accents)
+	countIn := persistedMetr.CountIn
+	metr.SetCountIn(countIn)
+	tickPitch := persistedMetr.TickPitch
+	tickDecay := persistedMetr.TickDecay
+
+	/*
+	 * Patches from older versions use the default synthesis parameters.
+	 */
+	if (tickPitch == 0.0) && (tickDecay == 0.0) {
+		tickPitch = metronome.DEFAULT_TICK_PITCH
+		tickDecay = metronome.DEFAULT_DECAY
+	}
+
+	metr.SetTickSynthesis(tickPitch, tickDecay)
+	tockPitch := persistedMetr.TockPitch
+	tockDecay := persistedMetr.TockDecay
+
+	/*
+	 * Patches from older versions use the default synthesis parameters.
+	 */
+	if (tockPitch == 0.0) && (tockDecay == 0.0) {
+		tockPitch = metronome.DEFAULT_TOCK_PITCH
+		tockDecay = metronome.DEFAULT_DECAY
+	}
+
+	metr.SetTockSynthesis(tockPitch, tockDecay)
+	tickSound := persistedMetr.TickSound
+
+	/*
+	 * Check if we should disable the tick sound.
+	 */
+	if tickSound == "- NONE -" {
+		metr.SetTick(tickSound, nil)
+	} else {
+		pitch, decay := metr.TickSynthesis()
+		coeffs := this.metronomeSound(tickSound, pitch, decay)
+
+		/*
+		 * Check if sound was successfully loaded.
+		 */
+		if coeffs != nil {
+			metr.SetTick(tickSound, coeffs)
+		}
+
+	}
+
+	tockSound := persistedMetr.TockSound
+
+	/*
+	 * Check if we should disable the tock sound.
+	 */
+	if tockSound == "- NONE -" {
+		metr.SetTock(tockSound, nil)
+	} else {
+		pitch, decay := metr.TockSynthesis()
+		coeffs := this.metronomeSound(tockSound, pitch, decay)
+
+		/*
+		 * Check if sound was successfully loaded.
+		 */
+		if coeffs != nil {
+			metr.SetTock(tockSound, coeffs)
+		}
+
+	}
+
+}
+
+/*
+ * Sets a value for the metronome.
+ */
+func (this *controllerStruct) setMetronomeValueHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	metr := this.metr
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if we have a metronome.
+	 */
+	if metr != nil {
+		param := request.Params["param"]
+		value := request.Params["value"]
+
+		/*
+		 * Check which parameter should be edited.
+		 */
+		switch param {
+		case "accents":
+			errSet := metr.SetAccents(value)
+
+			/*
+			 * Check if value could be set.
+			 */
+			if errSet != nil {
+				msg := errSet.Error()
+				reason := fmt.Sprintf("Failed to set metronome accents: %s", msg)
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		case "beats-per-period":
+			rawValue, errParse := strconv.ParseUint(value, 10, 32)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if errParse != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode metronome beats per period.",
+				}
+
+			} else {
+				value := uint32(rawValue)
+				errSet := metr.SetBeatsPerPeriod(value)
+
+				/*
+				 * Check if value could be set.
+				 */
+				if errSet != nil {
+					msg := errSet.Error()
+					reason := fmt.Sprintf("Failed to set metronome beats per period: %s", msg)
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  reason,
+					}
+
+				} else {
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+
+		case "count-in":
+			rawValue, errParse := strconv.ParseUint(value, 10, 32)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if errParse != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode metronome count-in.",
+				}
+
+			} else {
+				value := uint32(rawValue)
+				errSet := metr.SetCountIn(value)
+
+				/*
+				 * Check if value could be set.
+				 */
+				if errSet != nil {
+					msg := errSet.Error()
+					reason := fmt.Sprintf("Failed to set metronome count-in: %s", msg)
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  reason,
+					}
+
+				} else {
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+
+		case "master-output":
+			value, err := strconv.ParseBool(value)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if err != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode metronome master output flag.",
+				}
+
+			} else {
+
+				/*
+				 * Set whether the click is mixed into the master output.
+				 */
+				this.changeMix(func(mix *mixSnapshotStruct) {
+					mix.metrMasterOutput = value
+				})
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		case "speed":
+			rawValue, errParse := strconv.ParseUint(value, 10, 32)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if errParse != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode metronome speed.",
+				}
+
+			} else {
+				value := uint32(rawValue)
+				errSet := metr.SetSpeed(value)
+
+				/*
+				 * Check if value could be set.
+				 */
+				if errSet != nil {
+					msg := errSet.Error()
+					reason := fmt.Sprintf("Failed to set metronome speed: %s", msg)
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  reason,
+					}
+
+				} else {
+					this.updateTempo()
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		case "subdivision":
+			rawValue, errParse := strconv.ParseUint(value, 10, 32)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if errParse != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode metronome subdivision.",
+				}
+
+			} else {
+				value := uint32(rawValue)
+				errSet := metr.SetSubdivision(value)
+
+				/*
+				 * Check if value could be set.
+				 */
+				if errSet != nil {
+					msg := errSet.Error()
+					reason := fmt.Sprintf("Failed to set metronome subdivision: %s", msg)
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  reason,
+					}
+
+				} else {
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+
+		case "tick-decay":
+			value, errParse := strconv.ParseFloat(value, 64)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if errParse != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode metronome tick decay.",
+				}
+
+			} else {
+				pitch, _ := metr.TickSynthesis()
+				errSet := metr.SetTickSynthesis(pitch, value)
+
+				/*
+				 * Check if value could be set.
+				 */
+				if errSet != nil {
+					msg := errSet.Error()
+					reason := fmt.Sprintf("Failed to set metronome tick decay: %s", msg)
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  reason,
+					}
+
+				} else {
+					this.synthesizeMetronomeSounds()
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+
+		case "tick-pitch":
+			value, errParse := strconv.ParseFloat(value, 64)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if errParse != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode metronome tick pitch.",
+				}
+
+			} else {
+				_, decay := metr.TickSynthesis()
+				errSet := metr.SetTickSynthesis(value, decay)
+
+				/*
+				 * Check if value could be set.
+				 */
+				if errSet != nil {
+					msg := errSet.Error()
+					reason := fmt.Sprintf("Failed to set metronome tick pitch: %s", msg)
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  reason,
+					}
+
+				} else {
+					this.synthesizeMetronomeSounds()
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+
+		case "tick-sound":
+
+			/*
+			 * Check if we should disable the tick sound.
+			 */
+			if value == "- NONE -" {
+				metr.SetTick(value, nil)
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			} else {
+				pitch, decay := metr.TickSynthesis()
+				coeffs := this.metronomeSound(value, pitch, decay)
+
+				/*
+				 * Check if sound was successfully loaded.
+				 */
+				if coeffs == nil {
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  "Failed to load metronome tick sound.",
+					}
+
+				} else {
+					metr.SetTick(value, coeffs)
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+
+		case "tock-decay":
+			value, errParse := strconv.ParseFloat(value, 64)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if errParse != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode metronome tock decay.",
+				}
+
+			} else {
+				pitch, _ := metr.TockSynthesis()
+				errSet := metr.SetTockSynthesis(pitch, value)
+
+				/*
+				 * Check if value could be set.
+				 */
+				if errSet != nil {
+					msg := errSet.Error()
+					reason := fmt.Sprintf("Failed to set metronome tock decay: %s", msg)
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  reason,
+					}
+
+				} else {
+					this.synthesizeMetronomeSounds()
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+
+		case "tock-pitch":
+			value, errParse := strconv.ParseFloat(value, 64)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if errParse != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode metronome tock pitch.",
+				}
+
+			} else {
+				_, decay := metr.TockSynthesis()
+				errSet := metr.SetTockSynthesis(value, decay)
+
+				/*
+				 * Check if value could be set.
+				 */
+				if errSet != nil {
+					msg := errSet.Error()
+					reason := fmt.Sprintf("Failed to set metronome tock pitch: %s", msg)
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  reason,
+					}
+
+				} else {
+					this.synthesizeMetronomeSounds()
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+
+		case "tock-sound":
+
+			/*
+			 * Check if we should disable the tock sound.
+			 */
+			if value == "- NONE -" {
+				metr.SetTock(value, nil)
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			} else {
+				pitch, decay := metr.TockSynthesis()
+				coeffs := this.metronomeSound(value, pitch, decay)
+
+				/*
+				 * Check if sound was successfully loaded.
+				 */
+				if coeffs == nil {
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  "Failed to load metronome tock sound.",
+					}
+
+				} else {
+					metr.SetTock(value, coeffs)
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+
+		default:
+			reason := fmt.Sprintf("Unknown metronome parameter: '%s'", param)
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets the level at which the metronome click is mixed into the output of a
+ * channel. A level of zero removes the click from the channel.
+ */
+func (this *controllerStruct) setMetronomeOutputHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	channelIdString := request.Params["channel"]
+	channelId64, errChannelId := strconv.ParseUint(channelIdString, 10, 32)
+	valueString := request.Params["value"]
+	value, errValue := strconv.ParseFloat(valueString, 64)
+	webResponse := webResponseStruct{}
+	fx := this.effects
+	nChannels := uint64(len(fx))
+
+	/*
+	 * Check if channel ID and value are valid.
+	 */
+	if errChannelId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode channel ID.",
+		}
+
+	} else if channelId64 >= nChannels {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Channel ID out of range.",
+		}
+
+	} else if errValue != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode click level.",
+		}
+
+	} else if !(value >= 0.0) || (value > 1.0) {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Click level must be within [0, 1].",
+		}
+
+	} else {
+		channelId := int(channelId64)
+
+		/*
+		 * Set the level of the click in the output of the channel.
+		 */
+		this.changeMix(func(mix *mixSnapshotStruct) {
+			mix.channels[channelId].click = value
+		})
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Registers a tap on the tap tempo control and updates the speed of the
+ * metronome and the tempo of all signal chains from the average interval
+ * between taps.
+ */
+func (this *controllerStruct) tapTempoHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	metr := this.metr
+	mimeType := ""
+	buffer := []byte{}
+
+	/*
+	 * Check if we have a metronome.
+	 */
+	if metr == nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse := webResponseStruct{
+			Success: false,
+			Reason:  "No metronome present.",
+		}
+
+		mimeType, buffer = this.createJSON(webResponse)
+	} else {
+		now := time.Now()
+		speed, updated := metr.Tap(now)
+
+		/*
+		 * If the speed changed, pass the new tempo to the signal chains.
+		 */
+		if updated {
+			this.updateTempo()
+		}
+
+		/*
+		 * Create tap tempo result.
+		 */
+		result := webTapTempoStruct{
+			Speed:   speed,
+			Updated: updated,
+		}
+
+		mimeType, buffer = this.createJSON(result)
+	}
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
@@ -0,0 +1,60 @@
+package controller
+
+/*
+ * The settings of the channels and of the routing to the master output,
+ * which are read while processing audio.
+ *
+ * A snapshot is never changed once it is published. Changes are applied to a
+ * copy, which then replaces the snapshot, so that the audio thread reads
+ * consistent settings for each block without locking.
+ */
+type mixSnapshotStruct struct {
+	channels           []channelMetadataStruct
+	tunerChannel       int
+	tunerMute          bool
+	metrMasterOutput   bool
+	playerMasterOutput bool
+}
+
+/*
+ * Returns the most recently published settings of the channels and of the
+ * routing without locking.
+ */
+func (this *controllerStruct) mixSnapshot() *mixSnapshotStruct {
+	value := this.mix.Load()
+	snapshot, ok := value.(*mixSnapshotStruct)
+
+	/*
+	 * Nothing is routed before the settings are published.
+	 */
+	if !ok {
+
+		/*
+		 * Settings without any channels.
+		 */
+		snapshot = &mixSnapshotStruct{
+			tunerChannel: -1,
+		}
+
+	}
+
+	return snapshot
+}
+
+/*
+ * Applies a change to a copy of the settings of the channels and of the
+ * routing and publishes the copy.
+ *
+ * Changes are serialized, since they are not only made by requests, but also
+ * by a morph between snapshots.
+ */
+func (this *controllerStruct) changeMix(change func(snapshot *mixSnapshotStruct)) {
+	this.mixMutex.Lock()
+	current := this.mixSnapshot()
+	snapshot := *current
+	channels := current.channels
+	snapshot.channels = append([]channelMetadataStruct{}, channels...)
+	change(&snapshot)
+	this.mix.Store(&snapshot)
+	this.mixMutex.Unlock()
+}
@@ -0,0 +1,443 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/wave"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"io"
+	"strconv"
+)
+
+/*
+ * Returns the status of the backing track player.
+ */
+func (this *controllerStruct) getPlayerStatusHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	trackPlayer := this.trackPlayer
+	status := trackPlayer.Status()
+
+	/*
+	 * Create player status structure.
+	 */
+	result := webPlayerStatusStruct{
+		Loaded:   status.Loaded(),
+		Playing:  status.Playing(),
+		Length:   status.Length(),
+		Position: status.Position(),
+		Tempo:    status.Tempo(),
+	}
+
+	mimeType, buffer := this.createJSON(result)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Loads a wave file as the backing track of the player.
+ */
+func (this *controllerStruct) loadPlayerTrackHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	trackFiles := request.Files["trackfile"]
+	numTrackFiles := len(trackFiles)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Make sure that exactly one track file is sent in request.
+	 */
+	if trackFiles == nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Field 'trackfile' not defined as a multipart field.",
+		}
+
+	} else if numTrackFiles == 0 {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "No track file sent in request.",
+		}
+
+	} else if numTrackFiles != 1 {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Multiple track files sent in request.",
+		}
+
+	} else {
+		trackFile := trackFiles[0]
+		trackBytes, err := io.ReadAll(trackFile)
+
+		/*
+		 * Check if track file could be read.
+		 */
+		if err != nil {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Failed to read track file.",
+			}
+
+		} else {
+			file, err := wave.FromBuffer(trackBytes)
+
+			/*
+			 * Check if track file could be decoded.
+			 */
+			if err != nil {
+				msg := err.Error()
+				reason := fmt.Sprintf("Failed to decode track file: %s", msg)
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+				channelCount := file.ChannelCount()
+				channels := make([][]float64, channelCount)
+
+				/*
+				 * Extract the samples of each channel.
+				 */
+				for i := uint16(0); i < channelCount; i++ {
+					channel, _ := file.Channel(i)
+					channels[i] = channel.Floats()
+				}
+
+				sampleRate := file.SampleRate()
+				trackPlayer := this.trackPlayer
+				err = trackPlayer.Load(channels, sampleRate)
+
+				/*
+				 * Check if track was loaded.
+				 */
+				if err != nil {
+					msg := err.Error()
+					reason := fmt.Sprintf("Failed to load track: %s", msg)
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  reason,
+					}
+
+				} else {
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Moves the playback position of the backing track to a point given in
+ * seconds.
+ */
+func (this *controllerStruct) seekPlayerHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	positionString := request.Params["position"]
+	position, err := strconv.ParseFloat(positionString, 64)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if position is valid.
+	 */
+	if err != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode playback position.",
+		}
+
+	} else {
+		trackPlayer := this.trackPlayer
+		err = trackPlayer.Seek(position)
+
+		/*
+		 * Check if position was set.
+		 */
+		if err != nil {
+			msg := err.Error()
+			reason := fmt.Sprintf("Failed to seek: %s", msg)
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		} else {
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Sets a value for the backing track player.
+ */
+func (this *controllerStruct) setPlayerValueHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	trackPlayer := this.trackPlayer
+	param := request.Params["param"]
+	value := request.Params["value"]
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check which parameter should be edited.
+	 */
+	switch param {
+	case "level":
+		value, errParse := strconv.ParseFloat(value, 64)
+
+		/*
+		 * Check if value failed to parse.
+		 */
+		if errParse != nil {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Failed to decode player level.",
+			}
+
+		} else {
+			errSet := trackPlayer.SetLevel(value)
+
+			/*
+			 * Check if value could be set.
+			 */
+			if errSet != nil {
+				msg := errSet.Error()
+				reason := fmt.Sprintf("Failed to set player level: %s", msg)
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	case "master-output":
+		value, err := strconv.ParseBool(value)
+
+		/*
+		 * Check if value failed to parse.
+		 */
+		if err != nil {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Failed to decode player master output flag.",
+			}
+
+		} else {
+
+			/*
+			 * Set whether the backing track is mixed into the master output.
+			 */
+			this.changeMix(func(mix *mixSnapshotStruct) {
+				mix.playerMasterOutput = value
+			})
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
+		}
+
+	default:
+		reason := fmt.Sprintf("Unknown player parameter: '%s'", param)
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Starts playing the backing track from the current position.
+ */
+func (this *controllerStruct) startPlayerHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	trackPlayer := this.trackPlayer
+	err := trackPlayer.Start()
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if playback started.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Stops playing the backing track, keeping the current position.
+ */
+func (this *controllerStruct) stopPlayerHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	trackPlayer := this.trackPlayer
+	trackPlayer.Stop()
+
+	/*
+	 * Indicate success.
+	 */
+	webResponse := webResponseStruct{
+		Success: true,
+		Reason:  "",
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
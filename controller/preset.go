@@ -0,0 +1,299 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/signal"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"io"
+	"strconv"
+	"time"
+)
+
+/*
+ * Creates a preset holding the units of a signal chain.
+ */
+func (this *controllerStruct) createChainPreset(chain signal.Chain, name string) persistence.ChainPreset {
+	cfg := this.config
+	svr := cfg.WebServer
+	appName := svr.Name
+
+	/*
+	 * Create file format version.
+	 */
+	version := persistence.Version{
+		Major: 1,
+		Minor: 0,
+	}
+
+	/*
+	 * Create file format.
+	 */
+	fileFormat := persistence.FileFormat{
+		Application: appName,
+		Type:        "chain",
+		Version:     version,
+	}
+
+	units := this.persistChain(chain)
+
+	/*
+	 * Create chain preset.
+	 */
+	preset := persistence.ChainPreset{
+		FileFormat: fileFormat,
+		Name:       name,
+		Units:      units,
+	}
+
+	return preset
+}
+
+/*
+ * Replaces the units of a signal chain with those of a preset.
+ *
+ * Automation lanes of the units replaced are removed.
+ */
+func (this *controllerStruct) restoreChainPreset(chainId int, presetBytes []byte) error {
+	preset := persistence.ChainPreset{}
+	err := json.Unmarshal(presetBytes, &preset)
+
+	/*
+	 * Check if unmarshalling was successful.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return fmt.Errorf("Error during unmarshalling: %s", msg)
+	} else {
+		fileFormat := preset.FileFormat
+		fileVersion := fileFormat.Version
+		fx := this.chains()
+		numChains := len(fx)
+
+		/*
+		 * Ensure that file format is compatible and the chain exists.
+		 */
+		if fileFormat.Type != "chain" {
+			return fmt.Errorf("%s", "File is not a chain preset.")
+		} else if fileVersion.Major != 1 {
+			return fmt.Errorf("%s", "Incompatible version of file format.")
+		} else if (chainId < 0) || (chainId >= numChains) {
+			return fmt.Errorf("%s", "Chain ID out of range.")
+		} else {
+			this.haltMorph()
+			chain := fx[chainId]
+
+			/*
+			 * The lanes of the units replaced are removed.
+			 */
+			this.remapAutomation(func(laneChain int, laneUnit int) (int, int, bool) {
+				return laneChain, laneUnit, laneChain != chainId
+			})
+
+			this.restoreChain(chain, preset.Units)
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Exports the units of a signal chain as a preset.
+ */
+func (this *controllerStruct) exportChainHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, err := strconv.ParseUint(chainIdString, 10, 32)
+	fx := this.chains()
+	numChains := uint64(len(fx))
+	response := webserver.HttpResponse{}
+
+	/*
+	 * Check if chain ID is valid.
+	 */
+	if (err != nil) || (chainId64 >= numChains) {
+		reason := "Chain ID out of range."
+
+		/*
+		 * Check if chain ID failed to decode.
+		 */
+		if err != nil {
+			reason = "Failed to decode chain ID."
+		}
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse := webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+		mimeType, buffer := this.createJSON(webResponse)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response = webserver.HttpResponse{
+			Header: map[string]string{"Content-type": mimeType},
+			Body:   buffer,
+		}
+
+	} else {
+		chainId := int(chainId64)
+		name := request.Params["name"]
+		mix := this.mixSnapshot()
+		metadata := mix.channels
+		numChannels := len(metadata)
+
+		/*
+		 * Name the preset after the channel unless a name is given.
+		 */
+		if (name == "") && (chainId < numChannels) {
+			name = metadata[chainId].name
+		}
+
+		chain := fx[chainId]
+		preset := this.createChainPreset(chain, name)
+		mimeType, buffer := this.createJSON(preset)
+		creationTime := time.Now()
+		timeStamp := creationTime.Format(ARCHIVE_TIME_STAMP)
+		fileName := fmt.Sprintf("chain-%s.json", timeStamp)
+		disposition := fmt.Sprintf("attachment; filename=\"%s\"", fileName)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response = webserver.HttpResponse{
+			Header: map[string]string{
+				"Content-type":        mimeType,
+				"Content-disposition": disposition,
+			},
+			Body: buffer,
+		}
+
+	}
+
+	return response
+}
+
+/*
+ * Imports a preset into a signal chain, replacing its units.
+ *
+ * The preset is either uploaded as a file or passed as a parameter, like in
+ * the body of a request to the v2 API.
+ */
+func (this *controllerStruct) importChainHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	chainIdString := request.Params["chain"]
+	chainId64, errChainId := strconv.ParseUint(chainIdString, 10, 32)
+	presetFiles := request.Files["presetfile"]
+	numPresetFiles := len(presetFiles)
+	preset, hasPreset := request.Params["preset"]
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Make sure that the chain ID is valid and exactly one preset is sent
+	 * in request.
+	 */
+	if errChainId != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode chain ID.",
+		}
+
+	} else if (numPresetFiles == 0) && !hasPreset {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "No preset sent in request.",
+		}
+
+	} else if (numPresetFiles > 1) || ((numPresetFiles == 1) && hasPreset) {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Multiple presets sent in request.",
+		}
+
+	} else {
+		chainId := int(chainId64)
+		presetBytes := []byte(preset)
+		err := error(nil)
+
+		/*
+		 * Read the preset file, if one was uploaded.
+		 */
+		if numPresetFiles == 1 {
+			presetFile := presetFiles[0]
+			presetBytes, err = io.ReadAll(presetFile)
+		}
+
+		/*
+		 * Check if preset could be successfully read.
+		 */
+		if err != nil {
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  "Failed to read preset file.",
+			}
+
+		} else {
+			err = this.restoreChainPreset(chainId, presetBytes)
+
+			/*
+			 * Check if preset was restored successfully.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
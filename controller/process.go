@@ -0,0 +1,732 @@
+package controller
+
+import (
+	"github.com/andrepxx/go-dsp-guitar/metronome"
+	"github.com/andrepxx/go-dsp-guitar/player"
+	"github.com/andrepxx/go-dsp-guitar/spatializer"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * Perform asynchronous signal processing.
+ *
+ * There is a worker for each channel. They share the channel for the results,
+ * so it is not closed when a worker stops.
+ */
+func (this *controllerStruct) processAsync() {
+	requests := this.processingTaskChannel
+	responses := this.processingResultChannel
+
+	/*
+	 * Process tasks as long as channel is open.
+	 */
+	for task := range requests {
+		start := time.Now()
+		channel := task.channel
+		chain := task.chain
+		inputBuffer := task.inputBuffer
+		outputBuffer := task.outputBuffer
+		sampleRate := task.sampleRate
+		this.trimInput(task)
+
+		/*
+		 * Stereo chains process a pair of buffers.
+		 */
+		if chain.Stereo() {
+			inputBufferRight := task.inputBufferRight
+			outputBufferRight := task.outputBufferRight
+			chain.ProcessStereo(inputBuffer, inputBufferRight, outputBuffer, outputBufferRight, sampleRate)
+		} else {
+			chain.Process(inputBuffer, outputBuffer, sampleRate)
+		}
+
+		elapsed := time.Since(start)
+		elapsedNanoseconds := durationToNanoseconds(elapsed)
+		atomic.StoreUint32(&this.processingTimes[channel], elapsedNanoseconds)
+		responses <- true
+	}
+
+}
+
+/*
+ * Returns the first and the last port occupied by a channel.
+ *
+ * Stereo chains occupy two consecutive ports.
+ */
+func (this *controllerStruct) channelPortRange(channel int) (int, int) {
+	port := this.channelPorts[channel]
+	portRight := port
+	chain := this.effects[channel]
+
+	/*
+	 * Check if the chain is a stereo chain.
+	 */
+	if chain.Stereo() {
+		portRight = port + 1
+	}
+
+	return port, portRight
+}
+
+/*
+ * Passes the input of the channel the tuner listens to to the tuner.
+ */
+func (this *controllerStruct) processTuner(mix *mixSnapshotStruct, inputBuffers [][]float64, sampleRate uint32) {
+	tunerChannel := mix.tunerChannel
+	channelPorts := this.channelPorts
+	nChannels := len(channelPorts)
+	nIn := len(inputBuffers)
+
+	/*
+	 * Check if an input channel should be passed to the tuner.
+	 */
+	if (tunerChannel >= 0) && (tunerChannel < nChannels) {
+		tunerPort := channelPorts[tunerChannel]
+
+		/*
+		 * Make sure that the port exists.
+		 */
+		if tunerPort < nIn {
+			tunerInput := inputBuffers[tunerPort]
+			currentTuner := this.tuner
+			currentTuner.Process(tunerInput, sampleRate)
+		}
+
+	}
+
+}
+
+/*
+ * Processes the signal chain of each channel on the worker pool and waits for
+ * all of them to finish.
+ */
+func (this *controllerStruct) processChannels(mix *mixSnapshotStruct, inputBuffers [][]float64, outputBuffers [][]float64, sampleRate uint32) {
+	nIn := len(inputBuffers)
+	metadata := mix.channels
+	numMetadata := len(metadata)
+	numTasks := 0
+
+	/*
+	 * Start processing for each input channel.
+	 */
+	for i, chain := range this.effects {
+		port, portRight := this.channelPortRange(i)
+
+		/*
+		 * Only process channels which have all their ports available.
+		 */
+		if portRight < nIn {
+			channelTrim := int32(0)
+
+			/*
+			 * Check if the channel has settings.
+			 */
+			if i < numMetadata {
+				channelTrim = metadata[i].channelTrim
+			}
+
+			/*
+			 * Create a new signal processing task.
+			 */
+			task := processingTask{
+				channel:           i,
+				chain:             chain,
+				channelTrim:       channelTrim,
+				inputBuffer:       inputBuffers[port],
+				inputBufferRight:  inputBuffers[portRight],
+				outputBuffer:      outputBuffers[port],
+				outputBufferRight: outputBuffers[portRight],
+				sampleRate:        sampleRate,
+			}
+
+			this.processingTaskChannel <- task
+			numTasks++
+		}
+
+	}
+
+	/*
+	 * Wait for processing of each channel to finish.
+	 */
+	for i := 0; i < numTasks; i++ {
+		<-this.processingResultChannel
+	}
+
+}
+
+/*
+ * Like a tuner pedal, silences the output of the channel the tuner listens
+ * to, if requested, which also removes it from the master output.
+ */
+func (this *controllerStruct) muteTunerChannel(mix *mixSnapshotStruct, outputBuffers [][]float64, nIn int) {
+	tunerChannel := mix.tunerChannel
+	nChannels := len(this.channelPorts)
+
+	/*
+	 * Check if a channel should be muted.
+	 */
+	if mix.tunerMute && (tunerChannel >= 0) && (tunerChannel < nChannels) {
+		port, portRight := this.channelPortRange(tunerChannel)
+
+		/*
+		 * Only silence ports which are available.
+		 */
+		if portRight < nIn {
+
+			/*
+			 * Silence each port of the channel.
+			 */
+			for p := port; p <= portRight; p++ {
+				outputBuffer := outputBuffers[p]
+
+				/*
+				 * Silence each sample.
+				 */
+				for j := range outputBuffer {
+					outputBuffer[j] = 0.0
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Renders the backing track into the outputs of the player or silences them
+ * if there is no player.
+ *
+ * Returns whether a backing track was rendered.
+ */
+func (this *controllerStruct) processPlayer(playerOutputs [][]float64) bool {
+	trackPlayer := this.trackPlayer
+
+	/*
+	 * Check if there is a player.
+	 */
+	if trackPlayer == nil {
+
+		/*
+		 * Silence each output of the player.
+		 */
+		for _, playerOutput := range playerOutputs {
+
+			/*
+			 * Silence each sample.
+			 */
+			for j := range playerOutput {
+				playerOutput[j] = 0.0
+			}
+
+		}
+
+		return false
+	} else {
+		trackPlayer.Process(playerOutputs[0], playerOutputs[1])
+		return true
+	}
+
+}
+
+/*
+ * Limits the samples in a buffer to full scale.
+ *
+ * Signals may exceed full scale anywhere in the signal chains and the mix, so
+ * that no headroom is lost, and are only clipped here, at the final output.
+ */
+func clipBuffer(buffer []float64) {
+
+	/*
+	 * Check each sample.
+	 */
+	for i, sample := range buffer {
+
+		/*
+		 * Clip samples exceeding full scale.
+		 */
+		if sample > 1.0 {
+			buffer[i] = 1.0
+		} else if sample < -1.0 {
+			buffer[i] = -1.0
+		}
+
+	}
+
+}
+
+/*
+ * Mixes the channels, the click and the backing track into the master output,
+ * shapes it in the master section and limits it to full scale.
+ *
+ * Returns whether there is a master output.
+ */
+func (this *controllerStruct) processMaster(mix *mixSnapshotStruct, channelOutputs [][]float64, clickBuffer []float64, playerOutputs [][]float64, masterOutputs [][]float64, sampleRate uint32) bool {
+	spat := this.spat
+
+	/*
+	 * Check if there is a spatializer.
+	 */
+	if spat == nil {
+		return false
+	} else {
+
+		/*
+		 * Check if metronome output should be excluded from the master output.
+		 */
+		if !mix.metrMasterOutput {
+			clickBuffer = nil
+		}
+
+		spat.Process(channelOutputs, clickBuffer, masterOutputs)
+
+		/*
+		 * Mix the backing track into the master output, if requested.
+		 */
+		if (this.trackPlayer != nil) && mix.playerMasterOutput {
+
+			/*
+			 * Mix each side of the backing track.
+			 */
+			for i, playerOutput := range playerOutputs {
+				masterOutput := masterOutputs[i]
+
+				/*
+				 * Add each sample.
+				 */
+				for j, sample := range playerOutput {
+					masterOutput[j] += sample
+				}
+
+			}
+
+		}
+
+		masterSection := this.masterSection
+
+		/*
+		 * Shape the master output in the master section.
+		 */
+		if masterSection != nil {
+			masterSection.Process(masterOutputs[0], masterOutputs[1], sampleRate)
+		}
+
+		/*
+		 * Limit each master output to full scale.
+		 */
+		for _, masterOutput := range masterOutputs {
+			clipBuffer(masterOutput)
+		}
+
+		return true
+	}
+
+}
+
+/*
+ * Mixes the click into the output of each channel it is routed to.
+ *
+ * This happens after the master output is mixed, so that the click does not
+ * reach it through the channels.
+ */
+func (this *controllerStruct) routeClick(mix *mixSnapshotStruct, outputBuffers [][]float64, clickBuffer []float64, nIn int) {
+	metadata := mix.channels
+	numMetadata := len(metadata)
+
+	/*
+	 * Iterate over the channels which have settings.
+	 */
+	for i := range this.channelPorts {
+		click := 0.0
+		port, portRight := this.channelPortRange(i)
+
+		/*
+		 * Check if the channel has settings.
+		 */
+		if i < numMetadata {
+			click = metadata[i].click
+		}
+
+		/*
+		 * Only mix the click into ports which are available.
+		 */
+		if (click > 0.0) && (portRight < nIn) {
+
+			/*
+			 * Mix the click into each port of the channel.
+			 */
+			for p := port; p <= portRight; p++ {
+				outputBuffer := outputBuffers[p]
+
+				/*
+				 * Add the click to each sample.
+				 */
+				for j, sample := range clickBuffer {
+					outputBuffer[j] += click * sample
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Process audio data.
+ *
+ * The outputs are laid out as follows: one output per input port, then the
+ * master output, the metronome output, the outputs of the player and the
+ * master outputs of the surround speakers, if any. The buffers fed to level
+ * meter and spectrum analyzer hold the inputs, the outputs of the channels,
+ * the metronome, the master, the player and the surround speakers.
+ */
+func (this *controllerStruct) process(inputBuffers [][]float64, outputBuffers [][]float64, sampleRate uint32) {
+	start := time.Now()
+	this.layoutMutex.RLock()
+	mix := this.mixSnapshot()
+	nIn := len(inputBuffers)
+	nOut := len(outputBuffers)
+	masterBuffers := this.masterBuffers
+	nSurround := len(masterBuffers) - spatializer.OUTPUT_COUNT
+	nMinOut := nIn + (spatializer.OUTPUT_COUNT + metronome.OUTPUT_COUNT + player.OUTPUT_COUNT) + nSurround
+	buffers := this.buffers
+	numBuffers := len(buffers)
+	numBuffersExpected := (2 * nIn) + (spatializer.OUTPUT_COUNT + metronome.OUTPUT_COUNT + player.OUTPUT_COUNT) + nSurround
+	levelMeter := this.levelMeter
+	levelMeterEnabled := false
+
+	/*
+	 * Check if there is a level meter and if it is enabled.
+	 */
+	if levelMeter != nil {
+		levelMeterEnabled = levelMeter.Enabled()
+	}
+
+	spectrumAnalyzer := this.spectrumAnalyzer
+	spectrumAnalyzerEnabled := false
+
+	/*
+	 * Check if there is a spectrum analyzer and if it is enabled.
+	 */
+	if spectrumAnalyzer != nil {
+		spectrumAnalyzerEnabled = spectrumAnalyzer.Enabled()
+	}
+
+	/*
+	 * While the channel layout changes, the ports may not match the
+	 * buffers of level meter and spectrum analyzer for a period.
+	 */
+	if numBuffers != numBuffersExpected {
+		levelMeterEnabled = false
+		spectrumAnalyzerEnabled = false
+	}
+
+	buffered := levelMeterEnabled || spectrumAnalyzerEnabled
+	frames := 0
+
+	/*
+	 * Find out how many frames this block holds.
+	 */
+	if nOut > 0 {
+		frames = len(outputBuffers[0])
+	}
+
+	this.processAutomation(frames, sampleRate)
+	this.processTuner(mix, inputBuffers, sampleRate)
+	this.processCalibration(inputBuffers)
+
+	/*
+	 * Ensure that there are at least as many outputs as inputs registered.
+	 */
+	if (nOut >= nIn) && (nIn >= 0) {
+		this.compensateLatency(sampleRate)
+		this.processChannels(mix, inputBuffers, outputBuffers, sampleRate)
+		this.muteTunerChannel(mix, outputBuffers, nIn)
+
+		/*
+		 * If level meter or spectrum analyzer is enabled, save input
+		 * and output buffers.
+		 */
+		if buffered {
+			copy(buffers[0:nIn], inputBuffers)
+			uBound := 2 * nIn
+			copy(buffers[nIn:uBound], outputBuffers)
+		}
+
+	}
+
+	clickBuffer := []float64(nil)
+
+	/*
+	 * Check if there are enough output channels for a spatializer and a metronome.
+	 */
+	if nOut >= nMinOut {
+		uBoundMaster := nIn + spatializer.OUTPUT_COUNT
+		channelOutputs := outputBuffers[0:nIn]
+		auxBuffer := outputBuffers[uBoundMaster]
+		lBoundPlayer := uBoundMaster + metronome.OUTPUT_COUNT
+		uBoundPlayer := lBoundPlayer + player.OUTPUT_COUNT
+		playerOutputs := outputBuffers[lBoundPlayer:uBoundPlayer]
+		uBoundSurround := uBoundPlayer + nSurround
+		copy(masterBuffers[0:spatializer.OUTPUT_COUNT], outputBuffers[nIn:uBoundMaster])
+		copy(masterBuffers[spatializer.OUTPUT_COUNT:], outputBuffers[uBoundPlayer:uBoundSurround])
+		masterOutputs := masterBuffers
+		playing := this.processPlayer(playerOutputs)
+
+		/*
+		 * If level meter or spectrum analyzer is enabled, save player
+		 * output.
+		 */
+		if playing && buffered {
+			lBoundBuf := (2 * nIn) + metronome.OUTPUT_COUNT + spatializer.OUTPUT_COUNT
+			uBoundBuf := lBoundBuf + player.OUTPUT_COUNT
+			copy(buffers[lBoundBuf:uBoundBuf], playerOutputs)
+		}
+
+		metr := this.metr
+
+		/*
+		 * Check if there is a metronome.
+		 */
+		if metr != nil {
+			metr.Process(auxBuffer)
+			clickBuffer = auxBuffer
+
+			/*
+			 * If level meter or spectrum analyzer is enabled, save
+			 * auxiliary buffer.
+			 */
+			if buffered {
+				idx := 2 * nIn
+				buffers[idx] = auxBuffer
+			}
+
+		}
+
+		mixed := this.processMaster(mix, channelOutputs, clickBuffer, playerOutputs, masterOutputs, sampleRate)
+		correlationMeter := this.correlationMeter
+
+		/*
+		 * If level meter is enabled, measure the phase correlation
+		 * of the master output.
+		 */
+		if mixed && levelMeterEnabled && correlationMeter != nil {
+			correlationMeter.Process(masterOutputs[0], masterOutputs[1], sampleRate)
+		}
+
+		/*
+		 * If level meter or spectrum analyzer is enabled, save master
+		 * output.
+		 */
+		if mixed && buffered {
+			lBoundBuf := (2 * nIn) + metronome.OUTPUT_COUNT
+			uBoundBuf := lBoundBuf + spatializer.OUTPUT_COUNT
+			copy(buffers[lBoundBuf:uBoundBuf], masterOutputs)
+			lBoundSurround := numBuffersExpected - nSurround
+			copy(buffers[lBoundSurround:], masterOutputs[spatializer.OUTPUT_COUNT:])
+		}
+
+	}
+
+	/*
+	 * Attenuate the outputs of the channels for re-amping.
+	 */
+	if nOut >= nIn {
+		this.attenuateChannels(mix, outputBuffers, nIn)
+	}
+
+	/*
+	 * Route the click to the channels.
+	 */
+	if clickBuffer != nil {
+		this.routeClick(mix, outputBuffers, clickBuffer, nIn)
+	}
+
+	rec := this.recorder
+
+	/*
+	 * Check if there is a recorder.
+	 */
+	if rec != nil {
+		rec.Process(outputBuffers, sampleRate)
+	}
+
+	/*
+	 * Feed buffers to level meter, if enabled.
+	 */
+	if levelMeterEnabled {
+		levelMeter.Process(buffers, sampleRate)
+	}
+
+	/*
+	 * Feed buffers to spectrum analyzer, if enabled.
+	 */
+	if spectrumAnalyzerEnabled {
+		spectrumAnalyzer.Process(buffers, sampleRate)
+	}
+
+	this.layoutMutex.RUnlock()
+	elapsed := time.Since(start)
+	elapsedNanoseconds := durationToNanoseconds(elapsed)
+	atomic.StoreUint32(&this.processingTime, elapsedNanoseconds)
+}
+
+/*
+ * Passes the number of frames per period to all signal chains and the
+ * spatializer, so that they can prepare filters and buffers for it before they
+ * process audio.
+ *
+ * If the sample rate is converted, this is the largest number of frames per
+ * period at the internal sample rate.
+ */
+func (this *controllerStruct) setBlockSize(frames uint32) {
+	frames = this.internalFrames(frames)
+
+	/*
+	 * Pass the block size to each chain.
+	 */
+	for _, chain := range this.chains() {
+		chain.SetBlockSize(frames)
+	}
+
+	spat := this.spat
+
+	/*
+	 * Let the spatializer allocate the buffers of its aux buses.
+	 */
+	if spat != nil {
+		spat.SetBlockSize(frames)
+	}
+
+	rec := this.recorder
+
+	/*
+	 * Let the recorder allocate the buffers of a recording.
+	 */
+	if rec != nil {
+		rec.SetBlockSize(frames)
+	}
+
+}
+
+/*
+ * Passes the tempo of the metronome to all signal chains.
+ */
+func (this *controllerStruct) updateTempo() {
+	metr := this.metr
+
+	/*
+	 * Check if there is a metronome.
+	 */
+	if metr != nil {
+		speed := metr.Speed()
+
+		/*
+		 * Pass the tempo to each signal chain.
+		 */
+		for _, chain := range this.chains() {
+			chain.SetTempo(speed)
+		}
+
+	}
+
+}
+
+/*
+ * This is called when the hardware changes the sample rate.
+ *
+ * If an internal sample rate is configured, the signal processing keeps
+ * running at that rate.
+ */
+func (this *controllerStruct) sampleRateListener(rate uint32) {
+	this.hardwareRate = rate
+
+	/*
+	 * If an internal sample rate is configured, process at that rate.
+	 */
+	if this.internalRate != 0 {
+		rate = this.internalRate
+	}
+
+	this.sampleRate = rate
+	spat := this.spat
+	spat.SetSampleRate(rate)
+	metr := this.metr
+	metr.SetSampleRate(rate)
+	this.synthesizeMetronomeSounds()
+	trackPlayer := this.trackPlayer
+	trackPlayer.SetSampleRate(rate)
+}
+
+/*
+ * Delays the output of each channel, so that all channels are aligned with
+ * the one whose signal chain has the largest latency.
+ *
+ * Returns the latency (in samples) of the channels after compensation.
+ *
+ * This is called from the audio thread for each block. Signal chains cache
+ * their latency and only lock when the compensation actually changes.
+ */
+func (this *controllerStruct) compensateLatency(sampleRate uint32) uint32 {
+	fx := this.effects
+	maxLatency := uint32(0)
+
+	/*
+	 * Find the largest latency of all channels.
+	 */
+	for _, chain := range fx {
+		latency := chain.Latency(sampleRate)
+
+		/*
+		 * Keep the larger latency.
+		 */
+		if latency > maxLatency {
+			maxLatency = latency
+		}
+
+	}
+
+	/*
+	 * Delay each channel by the difference to the largest latency.
+	 */
+	for _, chain := range fx {
+		latency := chain.Latency(sampleRate)
+		compensation := uint32(0)
+
+		/*
+		 * The latency may have changed in the meantime.
+		 */
+		if latency < maxLatency {
+			compensation = maxLatency - latency
+		}
+
+		chain.SetCompensation(compensation)
+	}
+
+	return maxLatency
+}
+
+/*
+ * Converts a duration into nanoseconds, saturating at the largest value an
+ * unsigned 32-bit integer can hold.
+ */
+func durationToNanoseconds(duration time.Duration) uint32 {
+	nanoseconds := duration.Nanoseconds()
+
+	/*
+	 * Check if the duration is out of range.
+	 */
+	if nanoseconds < 0 {
+		return 0
+	} else if nanoseconds > math.MaxUint32 {
+		return math.MaxUint32
+	} else {
+		return uint32(nanoseconds)
+	}
+
+}
@@ -0,0 +1,274 @@
+package controller
+
+import (
+	"github.com/andrepxx/go-dsp-guitar/recorder"
+	"github.com/andrepxx/go-dsp-guitar/spatializer"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"strconv"
+)
+
+/*
+ * Returns the tracks to record, the master output and, optionally, the output
+ * of each channel.
+ */
+func (this *controllerStruct) recordingTracks(channels bool) []recorder.Track {
+	outputPortNames := this.outputPortNames
+	masterLeft := findPort(outputPortNames, "master_left")
+	masterRight := findPort(outputPortNames, "master_right")
+	tracks := []recorder.Track{}
+
+	/*
+	 * Check if the master output exists.
+	 */
+	if masterLeft >= 0 && masterRight >= 0 {
+
+		masterOutputs := []int{masterLeft, masterRight}
+		masterNames := this.masterPortNames()
+
+		/*
+		 * Surround layouts have more master outputs.
+		 */
+		for _, name := range masterNames[spatializer.OUTPUT_COUNT:] {
+			port := findPort(outputPortNames, name)
+
+			/*
+			 * Check if the port exists.
+			 */
+			if port >= 0 {
+				masterOutputs = append(masterOutputs, port)
+			}
+
+		}
+
+		/*
+		 * Track recording the master output.
+		 */
+		track := recorder.Track{
+			Name:    "master",
+			Outputs: masterOutputs,
+		}
+
+		tracks = append(tracks, track)
+	}
+
+	/*
+	 * Check if the output of each channel should be recorded as well.
+	 */
+	if channels {
+
+		/*
+		 * Create a track for each channel.
+		 */
+		for i, port := range this.channelPorts {
+			chain := this.effects[i]
+			outputs := []int{port}
+
+			/*
+			 * Stereo chains occupy two consecutive ports.
+			 */
+			if chain.Stereo() {
+				portRight := port + 1
+				outputs = append(outputs, portRight)
+			}
+
+			i64 := uint64(i)
+			idString := strconv.FormatUint(i64, 10)
+
+			/*
+			 * Track recording the output of the channel.
+			 */
+			track := recorder.Track{
+				Name:    "channel_" + idString,
+				Outputs: outputs,
+			}
+
+			tracks = append(tracks, track)
+		}
+
+	}
+
+	return tracks
+}
+
+/*
+ * Returns the status of the recorder.
+ */
+func (this *controllerStruct) getRecordingStatusHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	rec := this.recorder
+	status := rec.Status()
+
+	/*
+	 * Create recording status structure.
+	 */
+	result := webRecordingStatusStruct{
+		Recording: status.Recording(),
+		Files:     status.Files(),
+		Frames:    status.Frames(),
+		Dropped:   status.Dropped(),
+		Error:     status.Error(),
+	}
+
+	mimeType, buffer := this.createJSON(result)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Starts recording the master output and, optionally, the output of each
+ * channel to disk, either into wave or FLAC files.
+ */
+func (this *controllerStruct) startRecordingHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	channelsString := request.Params["channels"]
+	channels := false
+	err := error(nil)
+
+	/*
+	 * The output of each channel is only recorded on request.
+	 */
+	if channelsString != "" {
+		channels, err = strconv.ParseBool(channelsString)
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if boolean value is valid.
+	 */
+	if err != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode boolean value.",
+		}
+
+	} else {
+		directory := this.config.Recordings
+
+		/*
+		 * Use the default directory if none is configured.
+		 */
+		if directory == "" {
+			directory = DEFAULT_RECORDINGS_DIRECTORY
+		}
+
+		tracks := this.recordingTracks(channels)
+		sampleRate := this.sampleRate
+		format := request.Params["format"]
+
+		/*
+		 * Record into wave files unless requested otherwise.
+		 */
+		if format == "" {
+			format = recorder.FORMAT_WAVE
+		}
+
+		rec := this.recorder
+		err = rec.SetFormat(format)
+
+		/*
+		 * Only start recording if the format is known.
+		 */
+		if err == nil {
+			err = rec.Start(directory, tracks, sampleRate)
+		}
+
+		/*
+		 * Check if recording was started.
+		 */
+		if err != nil {
+			msg := err.Error()
+			reason := "Failed to start recording: " + msg
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		} else {
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Stops recording and finalizes the files.
+ */
+func (this *controllerStruct) stopRecordingHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	rec := this.recorder
+	err := rec.Stop()
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if recording was stopped.
+	 */
+	if err != nil {
+		msg := err.Error()
+		reason := "Failed to stop recording: " + msg
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
@@ -0,0 +1,1631 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/filter"
+	"github.com/andrepxx/go-dsp-guitar/flac"
+	"github.com/andrepxx/go-dsp-guitar/path"
+	"github.com/andrepxx/go-dsp-guitar/resample"
+	"github.com/andrepxx/go-dsp-guitar/spatializer"
+	"github.com/andrepxx/go-dsp-guitar/wave"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+/*
+ * Get input from the user.
+ */
+func (this *controllerStruct) getInput(scanner *bufio.Scanner, prompt string) string {
+	fmt.Printf("%s", prompt)
+	scanner.Scan()
+	s := scanner.Text()
+	return s
+}
+
+/*
+ * Opens a wave or FLAC file an input port is read from, initially reading its
+ * first channel.
+ */
+func openInputFile(fileName string) (*inputFileStruct, error) {
+	fd, err := os.Open(fileName)
+
+	/*
+	 * Check if file was successfully opened.
+	 */
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open input file '%s'.", fileName)
+	} else {
+		reader := wave.Reader(nil)
+		kind := "wave"
+
+		/*
+		 * Find out about the format of the file.
+		 */
+		if flac.Detect(fd) {
+			kind = "FLAC"
+			reader, err = flac.CreateReader(fd)
+		} else {
+			reader, err = wave.CreateReader(fd)
+		}
+
+		/*
+		 * Check if file could be parsed.
+		 */
+		if err != nil {
+			fd.Close()
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to parse %s file '%s': %s", kind, fileName, msg)
+		} else {
+			channelCount := reader.ChannelCount()
+			block := make([][]float64, channelCount)
+
+			/*
+			 * Create a block buffer for each channel.
+			 */
+			for i := range block {
+				block[i] = make([]float64, BLOCK_SIZE)
+			}
+
+			/*
+			 * Create input file structure.
+			 */
+			inputFile := inputFileStruct{
+				name:      fileName,
+				file:      fd,
+				reader:    reader,
+				channel:   0,
+				resampled: false,
+				samples:   nil,
+				block:     block,
+			}
+
+			return &inputFile, nil
+		}
+
+	}
+
+}
+
+/*
+ * Opens a wave file an input port is read from and selects one of its
+ * channels.
+ */
+func openInputChannel(fileName string, channel uint16) (*inputFileStruct, error) {
+	inputFile, err := openInputFile(fileName)
+
+	/*
+	 * Check if file could be opened.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		numChannels := inputFile.reader.ChannelCount()
+
+		/*
+		 * Check if channel exists.
+		 */
+		if channel >= numChannels {
+			inputFile.file.Close()
+			return nil, fmt.Errorf("Failed to load channel %d from wave file '%s': File only contains %d channels.", channel, fileName, numChannels)
+		} else {
+			inputFile.channel = channel
+			return inputFile, nil
+		}
+
+	}
+
+}
+
+/*
+ * Opens a wave file once for each of its channels, so that its channels feed
+ * consecutive input ports. At most the given number of channels is opened.
+ */
+func openInputChannels(fileName string, maxChannels int) ([]*inputFileStruct, error) {
+	inputFile, err := openInputFile(fileName)
+
+	/*
+	 * Check if file could be opened.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		channelCount := inputFile.reader.ChannelCount()
+		numChannels := int(channelCount)
+
+		/*
+		 * Do not open more channels than requested.
+		 */
+		if numChannels > maxChannels {
+			numChannels = maxChannels
+		}
+
+		inputFiles := []*inputFileStruct{inputFile}
+
+		/*
+		 * Open the file again for each further channel.
+		 */
+		for channel := 1; channel < numChannels; channel++ {
+			channel16 := uint16(channel)
+			channelFile, err := openInputChannel(fileName, channel16)
+
+			/*
+			 * Check if channel could be opened.
+			 */
+			if err != nil {
+				closeInputFiles(inputFiles)
+				return nil, err
+			} else {
+				inputFiles = append(inputFiles, channelFile)
+			}
+
+		}
+
+		return inputFiles, nil
+	}
+
+}
+
+/*
+ * Closes the wave files inputs are read from. Inputs without a file are
+ * skipped.
+ */
+func closeInputFiles(inputFiles []*inputFileStruct) {
+
+	/*
+	 * Close each file.
+	 */
+	for _, inputFile := range inputFiles {
+
+		/*
+		 * Check if input has a file.
+		 */
+		if inputFile != nil {
+			inputFile.file.Close()
+		}
+
+	}
+
+}
+
+/*
+ * Decodes the next block of an input into a buffer, padding it with silence
+ * once the input is exhausted.
+ */
+func readInputBlock(inputFile *inputFileStruct, buffer []float64) error {
+	block := inputFile.block
+	n, err := inputFile.reader.Read(block)
+
+	/*
+	 * Check if block was read. Reaching the end of the file is not an error.
+	 */
+	if err != nil && err != io.EOF {
+		msg := err.Error()
+		return fmt.Errorf("Failed to read from input file '%s': %s", inputFile.name, msg)
+	} else {
+		channel := block[inputFile.channel]
+		copy(buffer[0:n], channel[0:n])
+
+		/*
+		 * Pad the rest of the buffer with silence.
+		 */
+		for i := n; i < BLOCK_SIZE; i++ {
+			buffer[i] = 0.0
+		}
+
+		return nil
+	}
+
+}
+
+/*
+ * Decodes the entire channel of an input.
+ */
+func readInputChannel(inputFile *inputFileStruct) ([]float64, error) {
+	reader := inputFile.reader
+	length := reader.Length()
+	samples := make([]float64, length)
+	buffer := make([]float64, BLOCK_SIZE)
+	lengthInt := int(length)
+
+	/*
+	 * Decode the channel block by block.
+	 */
+	for offset := 0; offset < lengthInt; offset += BLOCK_SIZE {
+		err := readInputBlock(inputFile, buffer)
+
+		/*
+		 * Check if block was read.
+		 */
+		if err != nil {
+			return nil, err
+		} else {
+			copy(samples[offset:lengthInt], buffer)
+		}
+
+	}
+
+	return samples, nil
+}
+
+/*
+ * Decodes a channel of a wave file entirely. Returns the samples and the
+ * sample rate.
+ */
+func readWaveChannel(fileName string, channel uint16) ([]float64, uint32, error) {
+	inputFile, err := openInputChannel(fileName, channel)
+
+	/*
+	 * Check if file could be opened.
+	 */
+	if err != nil {
+		return nil, 0, err
+	} else {
+		sampleRate := inputFile.reader.SampleRate()
+		samples, err := readInputChannel(inputFile)
+		inputFile.file.Close()
+		return samples, sampleRate, err
+	}
+
+}
+
+/*
+ * Maps the name of a container to its code. An empty name selects RIFF wave
+ * files.
+ */
+func parseContainer(name string) (uint16, error) {
+
+	/*
+	 * Decide on the container.
+	 */
+	switch name {
+	case "", "wave":
+		return wave.CONTAINER_RIFF, nil
+	case "aiff":
+		return wave.CONTAINER_AIFF, nil
+	case "caf":
+		return wave.CONTAINER_CAF, nil
+	default:
+		return 0, fmt.Errorf("Unsupported target container: '%s'", name)
+	}
+
+}
+
+/*
+ * Creates a wave or FLAC file output ports are written to, with a channel for
+ * each port.
+ *
+ * The container is ignored for FLAC files.
+ */
+func createOutputFile(fileName string, ports []int, sampleRate uint32, container uint16, outputFormat uint16, bitDepth uint16) (*outputFileStruct, error) {
+	numPorts := len(ports)
+	channelCount := uint16(numPorts)
+	fd, err := os.Create(fileName)
+
+	/*
+	 * Check if file was successfully created.
+	 */
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create output file '%s'.", fileName)
+	} else {
+		writer := wave.Writer(nil)
+		kind := "wave"
+
+		/*
+		 * FLAC files are written by their own writer.
+		 */
+		if outputFormat == AUDIO_FLAC {
+			kind = "FLAC"
+			writer, err = flac.CreateWriter(fd, sampleRate, bitDepth, channelCount)
+		} else {
+			writer, err = wave.CreateContainerWriter(fd, container, sampleRate, outputFormat, bitDepth, channelCount)
+		}
+
+		/*
+		 * Check whether we were able to create the file.
+		 */
+		if err != nil {
+			fd.Close()
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to create %s file '%s': %s", kind, fileName, msg)
+		} else {
+
+			/*
+			 * Create output file structure.
+			 */
+			outputFile := outputFileStruct{
+				name:   fileName,
+				ports:  ports,
+				file:   fd,
+				writer: writer,
+			}
+
+			return &outputFile, nil
+		}
+
+	}
+
+}
+
+/*
+ * Finalizes and closes the wave files outputs are written to. Returns the
+ * first error which occured.
+ */
+func closeOutputFiles(outputFiles []*outputFileStruct) error {
+	err := error(nil)
+
+	/*
+	 * Finalize and close each file.
+	 */
+	for _, outputFile := range outputFiles {
+		errWriter := outputFile.writer.Close()
+		errClose := outputFile.file.Close()
+
+		/*
+		 * Keep the first error.
+		 */
+		if err == nil {
+
+			/*
+			 * Check which step failed.
+			 */
+			if errWriter != nil {
+				msg := errWriter.Error()
+				err = fmt.Errorf("Failed to finalize output file '%s': %s", outputFile.name, msg)
+			} else if errClose != nil {
+				msg := errClose.Error()
+				err = fmt.Errorf("Failed to close output file '%s': %s", outputFile.name, msg)
+			}
+
+		}
+
+	}
+
+	return err
+}
+
+/*
+ * Resamples all inputs to the target sample rate, pads them to equal length
+ * and passes them through the signal processing. Inputs without a file are
+ * silent.
+ *
+ * Inputs at the target sample rate are read from disk block by block, while
+ * each block of output is written to the output files as soon as it is
+ * processed.
+ *
+ * Only the frames from the start of the region up to (but not including) its
+ * end are written. A region without an end (zero) extends to the end of the
+ * inputs. To bring filters and delays into the state they would have at the
+ * start of the region, the processing starts a number of preroll frames
+ * before it, discarding the output.
+ */
+func (this *controllerStruct) renderFiles(inputFiles []*inputFileStruct, targetRate uint32, outputFiles []*outputFileStruct, regionStart int, regionEnd int, preroll int) error {
+	maxLength := int(0)
+
+	/*
+	 * Resample inputs if necessary and find the length of the longest
+	 * input stream.
+	 */
+	for i, inputFile := range inputFiles {
+
+		/*
+		 * Check if input has a file.
+		 */
+		if inputFile != nil {
+			reader := inputFile.reader
+			sampleRate := reader.SampleRate()
+			length := reader.Length()
+			size := int(length)
+
+			/*
+			 * Stop if the rendering was cancelled, otherwise check if
+			 * resampling is necessary. Empty inputs need no
+			 * resampling.
+			 */
+			if !this.render.proceed() {
+				return fmt.Errorf("%s", "Rendering was cancelled.")
+			} else if (size > 0) && (sampleRate != targetRate) {
+				fmt.Printf("Resampling input channel %d from %d Hz to %d Hz, please wait ...\n", i, sampleRate, targetRate)
+				samples, err := readInputChannel(inputFile)
+
+				/*
+				 * Check if input was read.
+				 */
+				if err != nil {
+					return err
+				} else {
+					samples = resample.Sinc(samples, sampleRate, targetRate, resample.QUALITY_BEST)
+					inputFile.samples = samples
+					inputFile.resampled = true
+					size = len(samples)
+					runtime.GC()
+				}
+
+			}
+
+			/*
+			 * If we found a longer input stream, store its length.
+			 */
+			if size > maxLength {
+				maxLength = size
+			}
+
+		}
+
+	}
+
+	/*
+	 * Length must be a multiple of the block size.
+	 */
+	if (maxLength % BLOCK_SIZE) != 0 {
+		maxLength = BLOCK_SIZE * ((maxLength / BLOCK_SIZE) + 1)
+	}
+
+	processStart := regionStart - preroll
+	processEnd := maxLength
+	writeStart := regionStart
+
+	/*
+	 * Processing cannot start before the beginning of the inputs.
+	 */
+	if processStart < 0 {
+		processStart = 0
+	}
+
+	/*
+	 * If the region has an end, stop there.
+	 */
+	if regionEnd > 0 {
+		processEnd = regionEnd
+	}
+
+	/*
+	 * Move each input read from disk to the beginning of the processing.
+	 */
+	for _, inputFile := range inputFiles {
+
+		/*
+		 * Check if input has a file which was not resampled.
+		 */
+		if inputFile != nil && !inputFile.resampled {
+			reader := inputFile.reader
+			position := uint64(processStart)
+			length := reader.Length()
+
+			/*
+			 * Do not seek beyond the end of the input.
+			 */
+			if position > length {
+				position = length
+			}
+
+			err := reader.Seek(position)
+
+			/*
+			 * Check if we seeked to the beginning of the processing.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return fmt.Errorf("Failed to seek in input file '%s': %s", inputFile.name, msg)
+			}
+
+		}
+
+	}
+
+	numInputs := len(inputFiles)
+	numSurround := len(this.masterBuffers) - spatializer.OUTPUT_COUNT
+	numOutputs := numInputs + MORE_OUTPUTS_THAN_INPUTS + numSurround
+	inputBuffers := make([][]float64, numInputs)
+	outputBuffers := make([][]float64, numOutputs)
+
+	/*
+	 * Create each inner output buffer.
+	 */
+	for i := 0; i < numOutputs; i++ {
+		outputBuffers[i] = make([]float64, BLOCK_SIZE)
+	}
+
+	/*
+	 * Create each inner input buffer.
+	 */
+	for i := 0; i < numInputs; i++ {
+		inputBuffers[i] = make([]float64, BLOCK_SIZE)
+	}
+
+	numBlocks := (processEnd - processStart + BLOCK_SIZE - 1) / BLOCK_SIZE
+	numBlocksFloat := float64(numBlocks)
+	fmt.Printf("%s\n", "Processing audio data ...")
+	oldPercents := int(0)
+
+	/*
+	 * Process each block.
+	 */
+	for block := 0; block < numBlocks; block++ {
+		proceed := this.render.advance(block, numBlocks)
+
+		/*
+		 * Stop if the rendering was cancelled.
+		 */
+		if !proceed {
+			fmt.Printf("\n")
+			return fmt.Errorf("%s", "Rendering was cancelled.")
+		}
+
+		blockFloat := float64(block)
+		percents := int((100.0 * blockFloat) / numBlocksFloat)
+
+		/*
+		 * Check if percentage changed.
+		 */
+		if percents != oldPercents {
+			fmt.Printf(" %d", percents)
+			oldPercents = percents
+		}
+
+		offsetStart := processStart + (BLOCK_SIZE * block)
+		offsetEnd := offsetStart + BLOCK_SIZE
+
+		/*
+		 * Fill the input buffers from each input stream.
+		 */
+		for i, inputFile := range inputFiles {
+			inputBuffer := inputBuffers[i]
+
+			/*
+			 * Check if input is silent, resampled or read from disk.
+			 */
+			if inputFile == nil {
+
+				/*
+				 * Fill the input buffer with silence.
+				 */
+				for j := range inputBuffer {
+					inputBuffer[j] = 0.0
+				}
+
+			} else if inputFile.resampled {
+				samples := inputFile.samples
+				size := len(samples)
+				lBound := offsetStart
+				uBound := offsetStart + BLOCK_SIZE
+
+				/*
+				 * Do not read beyond the end of the samples.
+				 */
+				if lBound > size {
+					lBound = size
+				}
+
+				/*
+				 * Do not read beyond the end of the samples.
+				 */
+				if uBound > size {
+					uBound = size
+				}
+
+				n := copy(inputBuffer, samples[lBound:uBound])
+
+				/*
+				 * Pad the rest of the buffer with silence.
+				 */
+				for j := n; j < BLOCK_SIZE; j++ {
+					inputBuffer[j] = 0.0
+				}
+
+			} else {
+				err := readInputBlock(inputFile, inputBuffer)
+
+				/*
+				 * Check if input was read.
+				 */
+				if err != nil {
+					fmt.Printf("\n")
+					return err
+				}
+
+			}
+
+		}
+
+		this.process(inputBuffers, outputBuffers, targetRate)
+		lBound := writeStart - offsetStart
+		uBound := processEnd - offsetStart
+
+		/*
+		 * Discard the output of the preroll.
+		 */
+		if lBound < 0 {
+			lBound = 0
+		}
+
+		/*
+		 * Do not write beyond the end of the region.
+		 */
+		if uBound > BLOCK_SIZE {
+			uBound = BLOCK_SIZE
+		}
+
+		/*
+		 * Only blocks reaching into the region produce output.
+		 */
+		if offsetEnd > writeStart {
+
+			/*
+			 * Write the output buffers into the output files.
+			 */
+			for _, outputFile := range outputFiles {
+				ports := outputFile.ports
+				numPorts := len(ports)
+				channels := make([][]float64, numPorts)
+
+				/*
+				 * Each port is written to a channel of the file.
+				 */
+				for i, port := range ports {
+					channels[i] = outputBuffers[port][lBound:uBound]
+				}
+
+				err := outputFile.writer.Write(channels)
+
+				/*
+				 * Check if output was written.
+				 */
+				if err != nil {
+					fmt.Printf("\n")
+					msg := err.Error()
+					return fmt.Errorf("Failed to write to output file '%s': %s", outputFile.name, msg)
+				}
+
+			}
+
+		}
+
+	}
+
+	this.render.advance(numBlocks, numBlocks)
+	fmt.Printf("\n")
+	return nil
+}
+
+/*
+ * Renders the files for batch processing, then closes them and reports the
+ * end of the rendering.
+ */
+func (this *controllerStruct) renderBatch(inputFiles []*inputFileStruct, targetRate uint32, outputFiles []*outputFileStruct) {
+	errRender := this.renderFiles(inputFiles, targetRate, outputFiles, 0, 0, 0)
+
+	/*
+	 * Check if outputs were written successfully.
+	 */
+	if errRender != nil {
+		msg := errRender.Error()
+		fmt.Printf("%s\n", msg)
+	}
+
+	closeInputFiles(inputFiles)
+	errClose := closeOutputFiles(outputFiles)
+
+	/*
+	 * Check if files were closed successfully.
+	 */
+	if errClose != nil {
+		msg := errClose.Error()
+		fmt.Printf("%s\n", msg)
+	}
+
+	/*
+	 * Report the first error which occured.
+	 */
+	if errRender != nil {
+		this.render.finish(errRender)
+	} else {
+		this.render.finish(errClose)
+	}
+
+}
+
+/*
+ * Process files for batch processing.
+ *
+ * The files are rendered in the background, so that the web interface can
+ * report the progress and cancel the rendering.
+ */
+func (this *controllerStruct) processFiles(scanner *bufio.Scanner, targetRate uint32) {
+	effects := this.effects
+	numChannels := len(effects)
+	fmt.Printf("Web interface initiated batch processing for %d channels.\n", numChannels)
+	inputPortNames := this.inputPortNames
+	outputPortNames := this.outputPortNames
+	numPorts := len(inputPortNames)
+	inputFiles := make([]*inputFileStruct, numPorts)
+	outputFormat := uint16(wave.AUDIO_PCM)
+	validFormat := false
+
+	/*
+	 * Query the user for a target format.
+	 */
+	for !validFormat {
+		targetFormat := this.getInput(scanner, "Please enter target format ('lpcm', 'float' or 'flac'): ")
+
+		/*
+		 * Find out about the target format.
+		 */
+		switch targetFormat {
+		case "lpcm":
+			outputFormat = wave.AUDIO_PCM
+			validFormat = true
+		case "float":
+			outputFormat = wave.AUDIO_IEEE_FLOAT
+			validFormat = true
+		case "flac":
+			outputFormat = AUDIO_FLAC
+			validFormat = true
+		}
+
+	}
+
+	container := uint16(wave.CONTAINER_RIFF)
+	validContainer := outputFormat == AUDIO_FLAC
+
+	/*
+	 * Query the user for a target container, unless we write FLAC files.
+	 */
+	for !validContainer {
+		targetContainer := this.getInput(scanner, "Please enter target container ('wave', 'aiff' or 'caf'): ")
+		code, err := parseContainer(targetContainer)
+
+		/*
+		 * Check if the target container is valid.
+		 */
+		if err == nil {
+			container = code
+			validContainer = true
+		}
+
+	}
+
+	bitDepth := uint16(wave.DEFAULT_BIT_DEPTH)
+	validBitDepth := false
+
+	/*
+	 * Query the user for a target bit depth.
+	 */
+	for !validBitDepth {
+
+		/*
+		 * Different formats support different bit depths.
+		 */
+		switch outputFormat {
+		case wave.AUDIO_PCM:
+			targetBitDepthString := this.getInput(scanner, "Please enter target bit depth (8 or 16 or 24 or 32): ")
+			targetBitDepth64, _ := strconv.ParseUint(targetBitDepthString, 10, 64)
+
+			/*
+			 * Check if the target bit depth is valid.
+			 */
+			if targetBitDepth64 == 8 || targetBitDepth64 == 16 || targetBitDepth64 == 24 || targetBitDepth64 == 32 {
+				bitDepth = uint16(targetBitDepth64)
+				validBitDepth = true
+			}
+
+		case wave.AUDIO_IEEE_FLOAT:
+			targetBitDepthString := this.getInput(scanner, "Please enter target bit depth (32 or 64): ")
+			targetBitDepth64, _ := strconv.ParseUint(targetBitDepthString, 10, 64)
+
+			/*
+			 * Check if the target bit depth is valid.
+			 */
+			if targetBitDepth64 == 32 || targetBitDepth64 == 64 {
+				bitDepth = uint16(targetBitDepth64)
+				validBitDepth = true
+			}
+
+		case AUDIO_FLAC:
+			targetBitDepthString := this.getInput(scanner, "Please enter target bit depth (8 or 16 or 24): ")
+			targetBitDepth64, _ := strconv.ParseUint(targetBitDepthString, 10, 64)
+
+			/*
+			 * Check if the target bit depth is valid.
+			 */
+			if targetBitDepth64 == 8 || targetBitDepth64 == 16 || targetBitDepth64 == 24 {
+				bitDepth = uint16(targetBitDepth64)
+				validBitDepth = true
+			}
+
+		default:
+			fmt.Printf("WARNING! Unrecognized format code: %#04x\n - Continuing with default bit depth: %d (This should not happen!)\n", outputFormat, bitDepth)
+			validBitDepth = true
+		}
+
+	}
+
+	/*
+	 * Query file name and channel number for each input, unless it is fed
+	 * by a file selected for a previous input.
+	 */
+	for fileId, portName := range inputPortNames {
+		mappedFile := inputFiles[fileId]
+
+		/*
+		 * Only ask for a file if the input is not fed yet.
+		 */
+		if mappedFile != nil {
+			fmt.Printf("Input '%s' is fed by channel %d of '%s'.\n", portName, mappedFile.channel, mappedFile.name)
+		} else {
+			fmt.Printf("%s\n", "Enter name/path of the wave, AIFF, CAF or FLAC file for input.")
+			prompt := fmt.Sprintf("File for input '%s': ", portName)
+			fileName := this.getInput(scanner, prompt)
+			fileName = path.Sanitize(fileName)
+
+			/*
+			 * Abort if file name is empty.
+			 */
+			if fileName == "" {
+				fmt.Printf("Leaving input '%s' empty.\n", portName)
+			} else {
+				inputFile, err := openInputFile(fileName)
+
+				/*
+				 * Check if file could be opened.
+				 */
+				if err != nil {
+					msg := err.Error()
+					fmt.Printf("%s\n", msg)
+					fmt.Printf("Leaving input '%s' empty.\n", portName)
+				} else {
+					numChannels := inputFile.reader.ChannelCount()
+					inputFiles[fileId] = inputFile
+
+					/*
+					 * If file contains more than one channel, ask
+					 * which one to use or whether its channels
+					 * feed this and the following inputs.
+					 */
+					if numChannels > 1 {
+						selectedChan := false
+
+						/*
+						 * Do this until a valid channel has been
+						 * selected.
+						 */
+						for !selectedChan {
+							uBound := numChannels - 1
+							prompt := fmt.Sprintf("File contains %d channels. Which channel [%d, %d] to use ('all' to feed this and the following inputs)? ", numChannels, 0, uBound)
+							channelString := this.getInput(scanner, prompt)
+
+							/*
+							 * Either map all channels or use the
+							 * selected one.
+							 */
+							if channelString == "all" {
+								numRemaining := numPorts - fileId
+								channelFiles, err := openInputChannels(fileName, numRemaining)
+
+								/*
+								 * Check if the channels could be
+								 * opened.
+								 */
+								if err != nil {
+									msg := err.Error()
+									fmt.Printf("%s\n", msg)
+								} else {
+									inputFile.file.Close()
+									copy(inputFiles[fileId:], channelFiles)
+									selectedChan = true
+								}
+
+							} else {
+								n, err := strconv.ParseUint(channelString, 10, 16)
+
+								/*
+								 * If input is valid, use this channel.
+								 */
+								if err != nil || n > uint64(uBound) {
+									fmt.Printf("%s\n", "Not a valid channel number.")
+								} else {
+									inputFile.channel = uint16(n)
+									selectedChan = true
+								}
+
+							}
+
+						}
+
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+	outputFiles := []*outputFileStruct{}
+	prompt := "Output file for all outputs (leave empty for one file per output): "
+	allFileName := this.getInput(scanner, prompt)
+	allFileName = path.Sanitize(allFileName)
+	allOutputs := false
+
+	/*
+	 * If a file name is given, write all outputs into one file.
+	 */
+	if allFileName != "" {
+		numOutputPorts := len(outputPortNames)
+		ports := make([]int, numOutputPorts)
+
+		/*
+		 * Each output is written to a channel of the file.
+		 */
+		for i := range ports {
+			ports[i] = i
+		}
+
+		outputFile, err := createOutputFile(allFileName, ports, targetRate, container, outputFormat, bitDepth)
+
+		/*
+		 * Check if file was created successfully, otherwise ask for
+		 * a file for each output.
+		 */
+		if err != nil {
+			msg := err.Error()
+			fmt.Printf("%s\n", msg)
+		} else {
+			outputFiles = append(outputFiles, outputFile)
+			allOutputs = true
+		}
+
+	}
+
+	/*
+	 * Create a wave file for each output, unless all outputs are written
+	 * into one file.
+	 */
+	for i, channelName := range outputPortNames {
+
+		/*
+		 * Only ask for a file for each output if there is no file for
+		 * all outputs.
+		 */
+		if !allOutputs {
+			prompt := fmt.Sprintf("Output file for channel '%s': ", channelName)
+			fileName := this.getInput(scanner, prompt)
+			fileName = path.Sanitize(fileName)
+
+			/*
+			 * Check if file name is empty.
+			 */
+			if fileName == "" {
+				fmt.Printf("%s\n", "Skipping output due to empty file name.")
+			} else {
+				outputFile, err := createOutputFile(fileName, []int{i}, targetRate, container, outputFormat, bitDepth)
+
+				/*
+				 * Check if file was created successfully.
+				 */
+				if err != nil {
+					msg := err.Error()
+					fmt.Printf("%s\n", msg)
+				} else {
+					outputFiles = append(outputFiles, outputFile)
+				}
+
+			}
+
+		}
+
+	}
+
+	err := this.render.start()
+
+	/*
+	 * Check if the rendering could be started.
+	 */
+	if err != nil {
+		msg := err.Error()
+		fmt.Printf("%s\n", msg)
+		closeInputFiles(inputFiles)
+		closeOutputFiles(outputFiles)
+	} else {
+		go this.renderBatch(inputFiles, targetRate, outputFiles)
+	}
+
+}
+
+/*
+ * Returns the index of a port name or -1 if it cannot be found.
+ */
+func findPort(portNames []string, name string) int {
+	idx := -1
+
+	/*
+	 * Search for the port name.
+	 */
+	for i, portName := range portNames {
+
+		/*
+		 * Check if we found the port.
+		 */
+		if portName == name {
+			idx = i
+		}
+
+	}
+
+	return idx
+}
+
+/*
+ * Runs the signal processing unattended, as described in a job file.
+ */
+func (this *controllerStruct) runJob(job jobStruct) error {
+	sampleRate := job.SampleRate
+	sampleRates := filter.SampleRates()
+	correctRate := false
+
+	/*
+	 * Check if sample rate is supported.
+	 */
+	for _, currentRate := range sampleRates {
+
+		/*
+		 * Check if sample rate matches.
+		 */
+		if currentRate == sampleRate {
+			correctRate = true
+		}
+
+	}
+
+	outputFormat := uint16(wave.AUDIO_PCM)
+	bitDepth := job.BitDepth
+	validBitDepth := false
+
+	/*
+	 * Find out about the target format and whether the bit depth is valid.
+	 */
+	switch job.Format {
+	case "lpcm":
+		outputFormat = wave.AUDIO_PCM
+		validBitDepth = bitDepth == 8 || bitDepth == 16 || bitDepth == 24 || bitDepth == 32
+	case "float":
+		outputFormat = wave.AUDIO_IEEE_FLOAT
+		validBitDepth = bitDepth == 32 || bitDepth == 64
+	case "flac":
+		outputFormat = AUDIO_FLAC
+		validBitDepth = bitDepth == 8 || bitDepth == 16 || bitDepth == 24
+	default:
+		return fmt.Errorf("Unsupported target format: '%s'", job.Format)
+	}
+
+	container, errContainer := parseContainer(job.Container)
+	preroll := job.Preroll
+
+	/*
+	 * Prime the signal chain for a default time.
+	 */
+	if preroll == 0.0 {
+		preroll = DEFAULT_PREROLL
+	}
+
+	/*
+	 * Check that sample rate, bit depth, container and region are valid.
+	 */
+	if !correctRate {
+		return fmt.Errorf("Sample rate not supported: %d", sampleRate)
+	} else if !validBitDepth {
+		return fmt.Errorf("Bit depth %d not supported for format '%s'.", bitDepth, job.Format)
+	} else if errContainer != nil {
+		return errContainer
+	} else if outputFormat == AUDIO_FLAC && job.Container != "" {
+		return fmt.Errorf("Container '%s' not supported for format '%s'.", job.Container, job.Format)
+	} else if job.Start < 0.0 || preroll < 0.0 {
+		return fmt.Errorf("%s", "Start and preroll of the region must not be negative.")
+	} else if job.End != 0.0 && job.End <= job.Start {
+		return fmt.Errorf("End of the region (%f s) must be after its start (%f s).", job.End, job.Start)
+	} else {
+		this.sampleRate = sampleRate
+		this.sampleRateListener(sampleRate)
+		patchPath := job.Patch
+
+		/*
+		 * If a patch file is given, restore it.
+		 */
+		if patchPath != "" {
+			patchBytes, err := os.ReadFile(patchPath)
+
+			/*
+			 * Check if patch file could be read.
+			 */
+			if err != nil {
+				return fmt.Errorf("Failed to read patch file '%s'.", patchPath)
+			} else {
+				err = this.restorePatch(patchBytes)
+
+				/*
+				 * Check if patch was restored.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return fmt.Errorf("Failed to restore patch file '%s': %s", patchPath, msg)
+				}
+
+			}
+
+		}
+
+		inputPortNames := this.inputPortNames
+		outputPortNames := this.outputPortNames
+		numPorts := len(inputPortNames)
+		inputFiles := make([]*inputFileStruct, numPorts)
+
+		/*
+		 * Verify that all input ports exist before opening any files.
+		 */
+		for _, input := range job.Inputs {
+			portName := input.Port
+			idx := findPort(inputPortNames, portName)
+
+			/*
+			 * Check if input port exists.
+			 */
+			if idx < 0 {
+				return fmt.Errorf("Unknown input port: '%s'", portName)
+			}
+
+		}
+
+		/*
+		 * Verify that all output ports exist before processing.
+		 */
+		for _, output := range job.Outputs {
+			portName := output.Port
+			idx := findPort(outputPortNames, portName)
+
+			/*
+			 * Check if output port exists.
+			 */
+			if idx < 0 {
+				return fmt.Errorf("Unknown output port: '%s'", portName)
+			}
+
+		}
+
+		allPorts := []int{}
+
+		/*
+		 * Find the ports written to the file for all outputs. If none
+		 * are given, all output ports are written.
+		 */
+		for _, portName := range job.OutputPorts {
+			idx := findPort(outputPortNames, portName)
+
+			/*
+			 * Check if output port exists.
+			 */
+			if idx < 0 {
+				return fmt.Errorf("Unknown output port: '%s'", portName)
+			} else {
+				allPorts = append(allPorts, idx)
+			}
+
+		}
+
+		numAllPorts := len(allPorts)
+
+		/*
+		 * Only the file for all outputs has ports to write.
+		 */
+		if (numAllPorts > 0) && (job.Output == "") {
+			return fmt.Errorf("%s", "Output ports are given, but no output file for them.")
+		}
+
+		/*
+		 * Write all output ports if none are given.
+		 */
+		if numAllPorts == 0 {
+
+			/*
+			 * Add each output port.
+			 */
+			for i := range outputPortNames {
+				allPorts = append(allPorts, i)
+			}
+
+		}
+
+		/*
+		 * If the job has a file for all inputs, its channels feed the
+		 * input ports in order.
+		 */
+		if job.Input != "" {
+			fileName := path.Sanitize(job.Input)
+			channelFiles, err := openInputChannels(fileName, numPorts)
+
+			/*
+			 * Check if the channels could be opened.
+			 */
+			if err != nil {
+				return err
+			} else {
+				copy(inputFiles, channelFiles)
+			}
+
+		}
+
+		/*
+		 * Open each input of the job, which replaces a channel of the
+		 * file for all inputs. All other inputs are silent.
+		 */
+		for _, input := range job.Inputs {
+			portName := input.Port
+			idx := findPort(inputPortNames, portName)
+			fileName := path.Sanitize(input.File)
+			channel := input.Channel
+			inputFile, err := openInputChannel(fileName, channel)
+
+			/*
+			 * Check if input could be opened.
+			 */
+			if err != nil {
+				closeInputFiles(inputFiles)
+				return err
+			} else {
+
+				/*
+				 * Close the file of an input which is assigned twice.
+				 */
+				if inputFiles[idx] != nil {
+					inputFiles[idx].file.Close()
+				}
+
+				inputFiles[idx] = inputFile
+			}
+
+		}
+
+		outputFiles := []*outputFileStruct{}
+
+		/*
+		 * If the job has a file for all outputs, create it.
+		 */
+		if job.Output != "" {
+			fileName := path.Sanitize(job.Output)
+			outputFile, err := createOutputFile(fileName, allPorts, sampleRate, container, outputFormat, bitDepth)
+
+			/*
+			 * Check if file was created successfully.
+			 */
+			if err != nil {
+				closeInputFiles(inputFiles)
+				return err
+			} else {
+				outputFiles = append(outputFiles, outputFile)
+			}
+
+		}
+
+		/*
+		 * Create a wave file for each output of the job.
+		 */
+		for _, output := range job.Outputs {
+			portName := output.Port
+			idx := findPort(outputPortNames, portName)
+			fileName := path.Sanitize(output.File)
+			outputFile, err := createOutputFile(fileName, []int{idx}, sampleRate, container, outputFormat, bitDepth)
+
+			/*
+			 * Check if file was created successfully.
+			 */
+			if err != nil {
+				closeInputFiles(inputFiles)
+				closeOutputFiles(outputFiles)
+				return err
+			} else {
+				outputFiles = append(outputFiles, outputFile)
+			}
+
+		}
+
+		sampleRateFloat := float64(sampleRate)
+		regionStart := int(math.Round(job.Start * sampleRateFloat))
+		regionEnd := int(math.Round(job.End * sampleRateFloat))
+		prerollFrames := int(math.Round(preroll * sampleRateFloat))
+
+		errRender := this.renderFiles(inputFiles, sampleRate, outputFiles, regionStart, regionEnd, prerollFrames)
+		closeInputFiles(inputFiles)
+		errClose := closeOutputFiles(outputFiles)
+
+		/*
+		 * Check if outputs were written successfully.
+		 */
+		if errRender != nil {
+			return errRender
+		} else if errClose != nil {
+			return errClose
+		} else {
+
+			/*
+			 * Report each output of the job.
+			 */
+			for _, outputFile := range outputFiles {
+				ports := outputFile.ports
+				numPorts := len(ports)
+
+				/*
+				 * Report each port written to the file.
+				 */
+				for channel, port := range ports {
+					portName := outputPortNames[port]
+
+					/*
+					 * Only name the channel if the file has
+					 * more than one.
+					 */
+					if numPorts == 1 {
+						fmt.Printf("Wrote output '%s' to '%s'.\n", portName, outputFile.name)
+					} else {
+						fmt.Printf("Wrote output '%s' to channel %d of '%s'.\n", portName, channel, outputFile.name)
+					}
+
+				}
+
+			}
+
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Reads a job file describing a batch job.
+ */
+func loadJob(jobPath string) (jobStruct, error) {
+	job := jobStruct{}
+	content, err := os.ReadFile(jobPath)
+
+	/*
+	 * Check if job file could be read.
+	 */
+	if err != nil {
+		return job, fmt.Errorf("Failed to open job file: '%s'", jobPath)
+	} else {
+		err = json.Unmarshal(content, &job)
+
+		/*
+		 * Check if job file failed to unmarshal.
+		 */
+		if err != nil {
+			return job, fmt.Errorf("Failed to decode job file: '%s'", jobPath)
+		} else {
+			return job, nil
+		}
+
+	}
+
+}
+
+/*
+ * Processes files unattended, as described in a job file.
+ */
+func (this *controllerStruct) ProcessJob(jobPath string) error {
+	job, err := loadJob(jobPath)
+
+	/*
+	 * Check if job file could be loaded.
+	 */
+	if err != nil {
+		return err
+	} else {
+		channelConfigs := job.Channels
+		numChannels := len(channelConfigs)
+
+		/*
+		 * A job needs at least one channel.
+		 */
+		if numChannels == 0 {
+			return fmt.Errorf("%s", "Job file does not define any channels.")
+		} else {
+			numChannels32 := uint32(numChannels)
+			err = this.initialize(CONFIG_PATH, numChannels32, channelConfigs, false)
+
+			/*
+			 * Check if initialization was successful.
+			 */
+			if err != nil {
+				return err
+			} else {
+				err = this.runJob(job)
+				ptc := this.processingTaskChannel
+				close(ptc)
+				return err
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Returns the path of the descriptor file of the impulse responses, as given
+ * in the config file.
+ */
+func configuredDescriptor() (string, error) {
+	content, err := os.ReadFile(CONFIG_PATH)
+
+	/*
+	 * Check if file could be read.
+	 */
+	if err != nil {
+		return "", fmt.Errorf("Failed to open config file: '%s'", CONFIG_PATH)
+	} else {
+		config := configStruct{}
+		err = json.Unmarshal(content, &config)
+
+		/*
+		 * Check if file failed to unmarshal.
+		 */
+		if err != nil {
+			return "", fmt.Errorf("Failed to decode config file: '%s'", CONFIG_PATH)
+		} else {
+			return config.ImpulseResponses, nil
+		}
+
+	}
+
+}
+
+/*
+ * Captures an impulse response, as described in a job file, and adds it to
+ * the descriptor file of the impulse responses.
+ *
+ * The impulse response is obtained by deconvolving the recorded response of a
+ * system (e. g. a cabinet and microphone) with the sweep it was excited with.
+ */
+func (this *controllerStruct) CaptureImpulseResponse(jobPath string) error {
+	content, err := os.ReadFile(jobPath)
+
+	/*
+	 * Check if job file could be read.
+	 */
+	if err != nil {
+		return fmt.Errorf("Failed to open job file: '%s'", jobPath)
+	} else {
+		job := captureJobStruct{}
+		err = json.Unmarshal(content, &job)
+
+		/*
+		 * Check if job file failed to unmarshal.
+		 */
+		if err != nil {
+			return fmt.Errorf("Failed to decode job file: '%s'", jobPath)
+		} else if job.Name == "" {
+			return fmt.Errorf("%s", "Job file does not define a name for the impulse response.")
+		} else if job.Output == "" {
+			return fmt.Errorf("%s", "Job file does not define an output file.")
+		} else {
+			descriptor := job.Descriptor
+
+			/*
+			 * Unless the descriptor file is given, take it from the
+			 * config file.
+			 */
+			if descriptor == "" {
+				descriptor, err = configuredDescriptor()
+			}
+
+			sweep, sweepRate, errSweep := readWaveChannel(job.Sweep, job.SweepChannel)
+
+			/*
+			 * Check if descriptor file is known and sweep could be read.
+			 */
+			if err != nil {
+				return err
+			} else if errSweep != nil {
+				return errSweep
+			} else {
+				response, responseRate, err := readWaveChannel(job.Response, job.ResponseChannel)
+
+				/*
+				 * Check if response could be read.
+				 */
+				if err != nil {
+					return err
+				} else {
+
+					/*
+					 * Bring the response to the sample rate of the sweep.
+					 */
+					if responseRate != sweepRate {
+						response = resample.Time(response, responseRate, sweepRate)
+					}
+
+					lengthMs := job.Length
+
+					/*
+					 * Use the default length unless it is given.
+					 */
+					if lengthMs == 0 {
+						lengthMs = CAPTURE_DEFAULT_LENGTH
+					}
+
+					lengthMs64 := uint64(lengthMs)
+					sweepRate64 := uint64(sweepRate)
+					lengthSamples64 := (lengthMs64 * sweepRate64) / 1000
+					lengthSamples := int(lengthSamples64)
+					coefficients, compensation, err := filter.Capture(response, sweep, lengthSamples)
+
+					/*
+					 * Check if impulse response was captured.
+					 */
+					if err != nil {
+						msg := err.Error()
+						return fmt.Errorf("Failed to capture impulse response: %s", msg)
+					} else {
+						output := job.Output
+						outputFile, err := createOutputFile(output, []int{0}, sweepRate, wave.CONTAINER_RIFF, wave.AUDIO_IEEE_FLOAT, CAPTURE_BIT_DEPTH)
+
+						/*
+						 * Check if output file was created.
+						 */
+						if err != nil {
+							return err
+						} else {
+
+							/*
+							 * The impulse response is a single channel.
+							 */
+							channels := [][]float64{
+								coefficients,
+							}
+
+							errWrite := outputFile.writer.Write(channels)
+							outputFiles := []*outputFileStruct{outputFile}
+							errClose := closeOutputFiles(outputFiles)
+
+							/*
+							 * Check if impulse response was written.
+							 */
+							if errWrite != nil {
+								msg := errWrite.Error()
+								return fmt.Errorf("Failed to write output file '%s': %s", output, msg)
+							} else if errClose != nil {
+								return errClose
+							} else {
+								name := job.Name
+								err = filter.AddDescriptor(descriptor, name, output, compensation)
+
+								/*
+								 * Check if descriptor file was updated.
+								 */
+								if err != nil {
+									return err
+								} else {
+									numCoefficients := len(coefficients)
+									fmt.Printf("Captured impulse response '%s' (%d samples at %d Hz, compensation %d dB) into file '%s'.\n", name, numCoefficients, sweepRate, compensation, output)
+									return nil
+								}
+
+							}
+
+						}
+
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+}
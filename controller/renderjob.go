@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"sync"
+	"time"
+)
+
+/*
+ * Data structure holding the state of the rendering of files in batch
+ * processing mode.
+ *
+ * It is written by the thread rendering the files and read by requests while
+ * the files are rendered, so all fields are protected by the mutex.
+ */
+type renderJobStruct struct {
+	mutex     sync.Mutex
+	started   bool
+	running   bool
+	cancelled bool
+	startTime time.Time
+	endTime   time.Time
+	numBlocks int
+	block     int
+	err       error
+}
+
+/*
+ * A data structure encoding the progress of the rendering of files.
+ *
+ * Elapsed and remaining time are given in seconds. The remaining time is zero
+ * while it cannot be estimated yet.
+ */
+type webProcessStatusStruct struct {
+	Started   bool
+	Running   bool
+	Cancelled bool
+	Percent   float64
+	Elapsed   float64
+	Remaining float64
+	Error     string
+}
+
+/*
+ * Marks the beginning of a rendering, unless one is already running.
+ */
+func (this *renderJobStruct) start() error {
+	err := error(nil)
+	this.mutex.Lock()
+
+	/*
+	 * Only one rendering may run at a time.
+	 */
+	if this.running {
+		err = fmt.Errorf("%s", "A rendering is already in progress.")
+	} else {
+		this.started = true
+		this.running = true
+		this.cancelled = false
+		this.startTime = time.Now()
+		this.numBlocks = 0
+		this.block = 0
+		this.err = nil
+	}
+
+	this.mutex.Unlock()
+	return err
+}
+
+/*
+ * Records how many blocks have been rendered and returns whether rendering
+ * should go on.
+ */
+func (this *renderJobStruct) advance(block int, numBlocks int) bool {
+	this.mutex.Lock()
+	this.block = block
+	this.numBlocks = numBlocks
+	cancelled := this.cancelled
+	this.mutex.Unlock()
+	return !cancelled
+}
+
+/*
+ * Returns whether rendering should go on.
+ */
+func (this *renderJobStruct) proceed() bool {
+	this.mutex.Lock()
+	cancelled := this.cancelled
+	this.mutex.Unlock()
+	return !cancelled
+}
+
+/*
+ * Asks a running rendering to stop after the block it is rendering.
+ */
+func (this *renderJobStruct) cancel() error {
+	err := error(nil)
+	this.mutex.Lock()
+
+	/*
+	 * Only a running rendering can be cancelled.
+	 */
+	if !this.running {
+		err = fmt.Errorf("%s", "No rendering in progress.")
+	} else {
+		this.cancelled = true
+	}
+
+	this.mutex.Unlock()
+	return err
+}
+
+/*
+ * Marks the end of a rendering and records the error it failed with, if any.
+ */
+func (this *renderJobStruct) finish(err error) {
+	this.mutex.Lock()
+	this.running = false
+	this.endTime = time.Now()
+	this.err = err
+	this.mutex.Unlock()
+}
+
+/*
+ * Returns the progress of the current or last rendering.
+ */
+func (this *renderJobStruct) status() webProcessStatusStruct {
+	this.mutex.Lock()
+	status := webProcessStatusStruct{}
+
+	/*
+	 * Only report progress once a rendering was started.
+	 */
+	if this.started {
+		endTime := time.Now()
+
+		/*
+		 * A finished rendering takes no more time.
+		 */
+		if !this.running {
+			endTime = this.endTime
+		}
+
+		elapsed := endTime.Sub(this.startTime)
+		elapsedSeconds := elapsed.Seconds()
+		status.Started = true
+		status.Running = this.running
+		status.Cancelled = this.cancelled
+		status.Elapsed = elapsedSeconds
+
+		/*
+		 * Estimate the remaining time from the blocks rendered so far.
+		 */
+		if this.numBlocks > 0 {
+			blockFloat := float64(this.block)
+			numBlocksFloat := float64(this.numBlocks)
+			status.Percent = (100.0 * blockFloat) / numBlocksFloat
+
+			/*
+			 * The rate can only be estimated once a block was
+			 * rendered.
+			 */
+			if this.running && (this.block > 0) {
+				remainingBlocks := numBlocksFloat - blockFloat
+				status.Remaining = (elapsedSeconds * remainingBlocks) / blockFloat
+			}
+
+		}
+
+		/*
+		 * A successful rendering is complete.
+		 */
+		if !this.running && !this.cancelled && (this.err == nil) {
+			status.Percent = 100.0
+		}
+
+		/*
+		 * Report why the rendering failed.
+		 */
+		if this.err != nil {
+			status.Error = this.err.Error()
+		}
+
+	}
+
+	this.mutex.Unlock()
+	return status
+}
+
+/*
+ * Reports the progress of the rendering of files in batch processing mode.
+ */
+func (this *controllerStruct) getProcessStatusHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	status := this.render.status()
+	mimeType, buffer := this.createJSON(status)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Cancels the rendering of files in batch processing mode.
+ *
+ * The files written so far are kept.
+ */
+func (this *controllerStruct) cancelProcessHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	err := this.render.cancel()
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if rendering was cancelled.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
@@ -0,0 +1,263 @@
+package controller
+
+import (
+	"github.com/andrepxx/go-dsp-guitar/resample"
+	"math"
+)
+
+/*
+ * Data structure converting the inputs from the hardware sample rate to the
+ * internal sample rate and the outputs back.
+ *
+ * The number of samples the resamplers produce varies slightly from period
+ * to period, so the outputs pass through a queue, which is primed with
+ * silence, so that it never runs dry.
+ */
+type rateConverterStruct struct {
+	hardwareRate uint32
+	internalRate uint32
+	quality      resample.Quality
+	upsamplers   []resample.Resampler
+	downsamplers []resample.Resampler
+	inputs       [][]float64
+	outputs      [][]float64
+	inputBlocks  [][]float64
+	outputBlocks [][]float64
+	queues       [][]float64
+	queueLength  int
+	delay        int
+}
+
+/*
+ * Returns the delay (in samples at the hardware rate) the conversion between
+ * two sample rates adds.
+ */
+func conversionDelay(hardwareRate uint32, internalRate uint32, quality resample.Quality) int {
+	hardwareRateFloat := float64(hardwareRate)
+	internalRateFloat := float64(internalRate)
+	ratio := hardwareRateFloat / internalRateFloat
+	upDelay := resample.StreamDelay(hardwareRate, internalRate, quality)
+	downDelay := resample.StreamDelay(internalRate, hardwareRate, quality)
+	upDelayFloat := float64(upDelay)
+	downDelayFloat := float64(downDelay)
+	delayFloat := math.Ceil(upDelayFloat + (ratio * downDelayFloat))
+	delay := int(delayFloat) + 2
+	return delay
+}
+
+/*
+ * Creates a rate converter for a certain number of inputs and outputs.
+ */
+func createRateConverter(hardwareRate uint32, internalRate uint32, quality resample.Quality, numInputs int, numOutputs int) *rateConverterStruct {
+	upsamplers := make([]resample.Resampler, numInputs)
+
+	/*
+	 * Create a resampler for each input.
+	 */
+	for i := range upsamplers {
+		upsamplers[i] = resample.NewStreamResampler(hardwareRate, internalRate, quality)
+	}
+
+	downsamplers := make([]resample.Resampler, numOutputs)
+
+	/*
+	 * Create a resampler for each output.
+	 */
+	for i := range downsamplers {
+		downsamplers[i] = resample.NewStreamResampler(internalRate, hardwareRate, quality)
+	}
+
+	delay := conversionDelay(hardwareRate, internalRate, quality)
+
+	/*
+	 * Create rate converter.
+	 */
+	c := rateConverterStruct{
+		hardwareRate: hardwareRate,
+		internalRate: internalRate,
+		quality:      quality,
+		upsamplers:   upsamplers,
+		downsamplers: downsamplers,
+		inputs:       make([][]float64, numInputs),
+		outputs:      make([][]float64, numOutputs),
+		inputBlocks:  make([][]float64, numInputs),
+		outputBlocks: make([][]float64, numOutputs),
+		queues:       make([][]float64, numOutputs),
+		queueLength:  delay,
+		delay:        delay,
+	}
+
+	return &c
+}
+
+/*
+ * Makes sure that each buffer can hold a certain number of samples, keeping
+ * its contents.
+ */
+func ensureCapacity(buffers [][]float64, size int) {
+
+	/*
+	 * Reallocate each buffer which is too small.
+	 */
+	for i, buffer := range buffers {
+
+		/*
+		 * Check if buffer is large enough.
+		 */
+		if cap(buffer) < size {
+			bufferNew := make([]float64, size)
+			copy(bufferNew, buffer)
+			buffers[i] = bufferNew
+		}
+
+	}
+
+}
+
+/*
+ * Returns the number of frames per period at the internal sample rate, given
+ * the number of frames per period of the hardware.
+ */
+func (this *controllerStruct) internalFrames(frames uint32) uint32 {
+	internalRate := this.internalRate
+	hardwareRate := this.hardwareRate
+
+	/*
+	 * Check if the sample rate is converted.
+	 */
+	if (internalRate == 0) || (hardwareRate == 0) || (internalRate == hardwareRate) {
+		return frames
+	} else {
+		framesFloat := float64(frames)
+		internalRateFloat := float64(internalRate)
+		hardwareRateFloat := float64(hardwareRate)
+		internalFramesFloat := math.Ceil((framesFloat * internalRateFloat) / hardwareRateFloat)
+		internalFrames := uint32(internalFramesFloat) + 1
+		return internalFrames
+	}
+
+}
+
+/*
+ * Returns the delay (in samples at the internal sample rate) the conversion
+ * of the sample rate adds.
+ */
+func (this *controllerStruct) conversionLatency() uint32 {
+	internalRate := this.internalRate
+	hardwareRate := this.hardwareRate
+
+	/*
+	 * Check if the sample rate is converted.
+	 */
+	if (internalRate == 0) || (hardwareRate == 0) || (internalRate == hardwareRate) {
+		return 0
+	} else {
+		quality := this.resampleQuality
+		delay := conversionDelay(hardwareRate, internalRate, quality)
+		delayFloat := float64(delay)
+		internalRateFloat := float64(internalRate)
+		hardwareRateFloat := float64(hardwareRate)
+		latencyFloat := math.Ceil((delayFloat * internalRateFloat) / hardwareRateFloat)
+		latency := uint32(latencyFloat)
+		return latency
+	}
+
+}
+
+/*
+ * Process audio data from the hardware, converting it to the internal sample
+ * rate and back, if one is configured.
+ */
+func (this *controllerStruct) processLive(inputBuffers [][]float64, outputBuffers [][]float64, sampleRate uint32) {
+	internalRate := this.internalRate
+	quality := this.resampleQuality
+	numInputs := len(inputBuffers)
+	numOutputs := len(outputBuffers)
+
+	/*
+	 * Only convert the sample rate if it differs from the internal one.
+	 */
+	if (internalRate == 0) || (internalRate == sampleRate) || (numInputs == 0) {
+		this.process(inputBuffers, outputBuffers, sampleRate)
+	} else {
+		c := this.converter
+
+		/*
+		 * Create a new converter when the hardware rate, the quality or
+		 * the ports change.
+		 */
+		if (c == nil) || (c.hardwareRate != sampleRate) || (c.quality != quality) || (len(c.upsamplers) != numInputs) || (len(c.downsamplers) != numOutputs) {
+			c = createRateConverter(sampleRate, internalRate, quality, numInputs, numOutputs)
+			this.converter = c
+		}
+
+		n := len(inputBuffers[0])
+		m := c.upsamplers[0].Length(n)
+		ensureCapacity(c.inputs, m)
+		ensureCapacity(c.outputs, m)
+
+		/*
+		 * Convert each input to the internal sample rate.
+		 */
+		for i, inputBuffer := range inputBuffers {
+			block := c.inputs[i][0:m]
+			c.upsamplers[i].Process(inputBuffer, block)
+			c.inputBlocks[i] = block
+		}
+
+		/*
+		 * Prepare a block for each output at the internal sample rate.
+		 */
+		for i, output := range c.outputs {
+			c.outputBlocks[i] = output[0:m]
+		}
+
+		this.process(c.inputBlocks, c.outputBlocks, internalRate)
+		queueLength := c.queueLength
+		written := 0
+
+		/*
+		 * Make sure the queues can hold the delay, the samples produced
+		 * and the samples left over from the last period.
+		 */
+		if len(c.downsamplers) > 0 {
+			produced := c.downsamplers[0].Length(m)
+			capacity := c.delay + queueLength + produced + n
+			ensureCapacity(c.queues, capacity)
+		}
+
+		/*
+		 * Convert each output back to the hardware sample rate.
+		 */
+		for i, outputBlock := range c.outputBlocks {
+			queue := c.queues[i]
+			queue = queue[0:cap(queue)]
+			written = c.downsamplers[i].Process(outputBlock, queue[queueLength:])
+			available := queueLength + written
+			outputBuffer := outputBuffers[i]
+			numCopied := copy(outputBuffer, queue[0:available])
+
+			/*
+			 * Should the queue run dry, fill up with silence.
+			 */
+			for j := numCopied; j < len(outputBuffer); j++ {
+				outputBuffer[j] = 0.0
+			}
+
+			copy(queue, queue[numCopied:available])
+			c.queues[i] = queue
+		}
+
+		queueLength += written - n
+
+		/*
+		 * A queue cannot hold less than nothing.
+		 */
+		if queueLength < 0 {
+			queueLength = 0
+		}
+
+		c.queueLength = queueLength
+	}
+
+}
@@ -0,0 +1,810 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/hwio"
+	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"os"
+	"strconv"
+)
+
+/*
+ * Loads the setlist from disk.
+ *
+ * If no setlist is configured or the file does not exist yet, the setlist
+ * starts out empty.
+ */
+func (this *controllerStruct) loadSetlist() error {
+	fileName := this.config.Setlist
+	this.scenes = []persistence.Scene{}
+	this.activeScene = -1
+
+	/*
+	 * Check if a setlist is configured.
+	 */
+	if fileName == "" {
+		return nil
+	} else {
+		content, err := os.ReadFile(fileName)
+
+		/*
+		 * Check if file could be read.
+		 */
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to read setlist '%s': %s", fileName, msg)
+		} else {
+			setlist := persistence.Setlist{}
+			err = json.Unmarshal(content, &setlist)
+			fileFormat := setlist.FileFormat
+			fileVersion := fileFormat.Version
+
+			/*
+			 * Check if setlist could be decoded.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return fmt.Errorf("Failed to decode setlist '%s': %s", fileName, msg)
+			} else if fileFormat.Type != "setlist" {
+				return fmt.Errorf("File '%s' is not a setlist file.", fileName)
+			} else if fileVersion.Major != 1 {
+				return fmt.Errorf("Setlist '%s' has an incompatible version of file format.", fileName)
+			} else {
+				scenes := setlist.Scenes
+
+				/*
+				 * An empty setlist may be stored as null.
+				 */
+				if scenes == nil {
+					scenes = []persistence.Scene{}
+				}
+
+				this.scenes = scenes
+				return nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Stores the setlist on disk, if a setlist is configured.
+ */
+func (this *controllerStruct) saveSetlist() error {
+	cfg := this.config
+	fileName := cfg.Setlist
+
+	/*
+	 * Check if a setlist is configured.
+	 */
+	if fileName == "" {
+		return nil
+	} else {
+		svr := cfg.WebServer
+		appName := svr.Name
+
+		/*
+		 * Create file format version.
+		 */
+		version := persistence.Version{
+			Major: 1,
+			Minor: 0,
+		}
+
+		/*
+		 * Create file format.
+		 */
+		fileFormat := persistence.FileFormat{
+			Application: appName,
+			Type:        "setlist",
+			Version:     version,
+		}
+
+		/*
+		 * Create setlist.
+		 */
+		setlist := persistence.Setlist{
+			FileFormat: fileFormat,
+			Scenes:     this.scenes,
+		}
+
+		content, err := json.MarshalIndent(setlist, "", "\t")
+
+		/*
+		 * Check if setlist could be encoded.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to encode setlist: %s", msg)
+		} else {
+			err = os.WriteFile(fileName, content, 0644)
+
+			/*
+			 * Check if setlist was written.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return fmt.Errorf("Failed to write setlist '%s': %s", fileName, msg)
+			} else {
+				return nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Parses the index of a scene and checks that the scene exists.
+ */
+func (this *controllerStruct) sceneIndex(value string) (int, error) {
+	idx64, err := strconv.ParseUint(value, 10, 32)
+	numScenes := len(this.scenes)
+
+	/*
+	 * Check if index could be parsed and is in range.
+	 */
+	if err != nil {
+		return -1, fmt.Errorf("%s", "Failed to decode scene index.")
+	} else if idx64 >= uint64(numScenes) {
+		return -1, fmt.Errorf("No scene %d.", idx64)
+	} else {
+		idx := int(idx64)
+		return idx, nil
+	}
+
+}
+
+/*
+ * Parses a MIDI program number. An empty value means no program.
+ */
+func parseProgram(value string) (int32, error) {
+
+	/*
+	 * The program number is optional.
+	 */
+	if value == "" {
+		return SCENE_NO_PROGRAM, nil
+	} else {
+		program64, err := strconv.ParseUint(value, 10, 8)
+
+		/*
+		 * Check if program number could be parsed and is in range.
+		 */
+		if err != nil || program64 > MIDI_MAX_PROGRAM {
+			return SCENE_NO_PROGRAM, fmt.Errorf("Program number must be between 0 and %d.", MIDI_MAX_PROGRAM)
+		} else {
+			program := int32(program64)
+			return program, nil
+		}
+
+	}
+
+}
+
+/*
+ * Restores a scene of the setlist, which becomes the active scene.
+ *
+ * The latency is not part of a scene, so it is left unchanged.
+ */
+func (this *controllerStruct) selectScene(idx int) error {
+	this.haltMorph()
+	scene := this.scenes[idx]
+	configuration := scene.Configuration
+
+	/*
+	 * If we are bound to a hardware interface, keep frames per period.
+	 */
+	if this.binding != nil {
+		configuration.FramesPerPeriod = hwio.FramesPerPeriod()
+	}
+
+	this.activeScene = idx
+	return this.restoreConfiguration(configuration)
+}
+
+/*
+ * Restores the first scene of the setlist which is bound to a MIDI program.
+ */
+func (this *controllerStruct) programChange(program int32) error {
+
+	/*
+	 * Search for the scene bound to this program.
+	 */
+	for idx, scene := range this.scenes {
+
+		/*
+		 * Check if we found the scene.
+		 */
+		if scene.Program == program {
+			return this.selectScene(idx)
+		}
+
+	}
+
+	return fmt.Errorf("No scene bound to program %d.", program)
+}
+
+/*
+ * Handles a program change received on the MIDI input.
+ *
+ * Switching scenes this way can be undone just like switching them from the
+ * web interface.
+ */
+func (this *controllerStruct) handleProgramChange(program uint8) {
+	before := this.createPatch()
+	program32 := int32(program)
+	err := this.programChange(program32)
+
+	/*
+	 * Only record an edit if a scene was restored. Program changes which
+	 * are not bound to a scene are ignored.
+	 */
+	if err == nil {
+		this.recordEdit("", before)
+	}
+
+}
+
+/*
+ * Returns the scenes of the setlist and which one is active.
+ */
+func (this *controllerStruct) getSetlistHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	scenes := this.scenes
+	numScenes := len(scenes)
+	webScenes := make([]webSceneStruct, numScenes)
+
+	/*
+	 * Describe each scene.
+	 */
+	for i, scene := range scenes {
+
+		/*
+		 * Create scene structure.
+		 */
+		webScenes[i] = webSceneStruct{
+			Name:    scene.Name,
+			Program: scene.Program,
+		}
+
+	}
+
+	/*
+	 * Create setlist structure.
+	 */
+	result := webSetlistStruct{
+		Active: this.activeScene,
+		Scenes: webScenes,
+	}
+
+	mimeType, buffer := this.createJSON(result)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Appends the current configuration to the setlist as a new scene, which
+ * becomes the active scene.
+ */
+func (this *controllerStruct) addSceneHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	params := request.Params
+	name := params["name"]
+	programString := params["program"]
+	program, err := parseProgram(programString)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if program number is valid.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+		configuration := this.createPatch()
+
+		/*
+		 * Create scene.
+		 */
+		scene := persistence.Scene{
+			Name:          name,
+			Program:       program,
+			Configuration: configuration,
+		}
+
+		this.scenes = append(this.scenes, scene)
+		this.activeScene = len(this.scenes) - 1
+		err = this.saveSetlist()
+
+		/*
+		 * Check if setlist was stored.
+		 */
+		if err != nil {
+			reason := err.Error()
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		} else {
+
+			/*
+			 * Indicate success.
+			 */
+			webResponse = webResponseStruct{
+				Success: true,
+				Reason:  "",
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Replaces the configuration of a scene with the current configuration,
+ * keeping its name and program number. The scene becomes the active scene.
+ */
+func (this *controllerStruct) storeSceneHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	sceneString := request.Params["scene"]
+	idx, err := this.sceneIndex(sceneString)
+
+	/*
+	 * Store the current configuration if the scene exists.
+	 */
+	if err == nil {
+		configuration := this.createPatch()
+		this.scenes[idx].Configuration = configuration
+		this.activeScene = idx
+		err = this.saveSetlist()
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if scene was stored.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Removes a scene from the setlist.
+ */
+func (this *controllerStruct) removeSceneHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	sceneString := request.Params["scene"]
+	idx, err := this.sceneIndex(sceneString)
+
+	/*
+	 * Remove the scene if it exists.
+	 */
+	if err == nil {
+		idxInc := idx + 1
+		this.scenes = append(this.scenes[:idx], this.scenes[idxInc:]...)
+		active := this.activeScene
+
+		/*
+		 * Keep track of the active scene.
+		 */
+		if active == idx {
+			this.activeScene = -1
+		} else if active > idx {
+			this.activeScene = active - 1
+		}
+
+		err = this.saveSetlist()
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if scene was removed.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Binds a scene to a MIDI program number, or unbinds it if no program number
+ * is given.
+ */
+func (this *controllerStruct) setSceneProgramHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	params := request.Params
+	sceneString := params["scene"]
+	programString := params["program"]
+	idx, err := this.sceneIndex(sceneString)
+
+	/*
+	 * Bind the scene if it exists.
+	 */
+	if err == nil {
+		program, errProgram := parseProgram(programString)
+
+		/*
+		 * Check if program number is valid.
+		 */
+		if errProgram != nil {
+			err = errProgram
+		} else {
+			this.scenes[idx].Program = program
+			err = this.saveSetlist()
+		}
+
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if scene was bound.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Restores a scene of the setlist given its index.
+ */
+func (this *controllerStruct) selectSceneHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	sceneString := request.Params["scene"]
+	idx, err := this.sceneIndex(sceneString)
+
+	/*
+	 * Restore the scene if it exists.
+	 */
+	if err == nil {
+		err = this.selectScene(idx)
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if scene was restored.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Restores the scene after the active one. If no scene is active, the first
+ * scene is restored.
+ */
+func (this *controllerStruct) nextSceneHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	next := this.activeScene + 1
+	numScenes := len(this.scenes)
+	err := error(nil)
+
+	/*
+	 * Check if there is a next scene.
+	 */
+	if numScenes == 0 {
+		err = fmt.Errorf("%s", "The setlist is empty.")
+	} else if next >= numScenes {
+		err = fmt.Errorf("%s", "Already at the last scene.")
+	} else {
+		err = this.selectScene(next)
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if scene was restored.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Restores the scene before the active one.
+ */
+func (this *controllerStruct) previousSceneHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	active := this.activeScene
+	previous := active - 1
+	err := error(nil)
+
+	/*
+	 * Check if there is a previous scene.
+	 */
+	if active < 0 {
+		err = fmt.Errorf("%s", "No scene is active.")
+	} else if previous < 0 {
+		err = fmt.Errorf("%s", "Already at the first scene.")
+	} else {
+		err = this.selectScene(previous)
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if scene was restored.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Restores the scene bound to a MIDI program number, as if a program change
+ * was received on the MIDI input.
+ */
+func (this *controllerStruct) programChangeHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	programString := request.Params["program"]
+	program, err := parseProgram(programString)
+
+	/*
+	 * Check if a program number was given.
+	 */
+	if program == SCENE_NO_PROGRAM {
+		err = fmt.Errorf("Program number must be between 0 and %d.", MIDI_MAX_PROGRAM)
+	} else {
+		err = this.programChange(program)
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if scene was restored.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
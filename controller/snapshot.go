@@ -0,0 +1,730 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/signal"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"math"
+	"strconv"
+	"time"
+)
+
+/*
+ * Returns the name of a snapshot slot.
+ */
+func snapshotName(idx int) string {
+	return string(rune('a' + idx))
+}
+
+/*
+ * Returns the index of a snapshot slot given its name.
+ */
+func snapshotIndex(name string) (int, error) {
+
+	/*
+	 * Search for the slot with this name.
+	 */
+	for idx := 0; idx < SNAPSHOT_COUNT; idx++ {
+
+		/*
+		 * Check if we found the slot.
+		 */
+		if snapshotName(idx) == name {
+			return idx, nil
+		}
+
+	}
+
+	return -1, fmt.Errorf("Unknown snapshot: '%s'", name)
+}
+
+/*
+ * Checks whether two lists of units contain the same types of units in the
+ * same order, so that one can be morphed into the other.
+ */
+func compatibleUnits(a []persistence.Unit, b []persistence.Unit) bool {
+
+	/*
+	 * Check if both lists have the same length.
+	 */
+	if len(a) != len(b) {
+		return false
+	} else {
+
+		/*
+		 * Compare the type of each unit.
+		 */
+		for i, unit := range a {
+
+			/*
+			 * Check if unit types match.
+			 */
+			if unit.Type != b[i].Type {
+				return false
+			}
+
+		}
+
+		return true
+	}
+
+}
+
+/*
+ * Checks whether two patches contain the same channels, aux buses and
+ * units, so that one can be morphed into the other.
+ */
+func compatiblePatches(a persistence.Configuration, b persistence.Configuration) bool {
+	channelsA := a.Channels
+	channelsB := b.Channels
+	busesA := a.Buses
+	busesB := b.Buses
+
+	/*
+	 * Check if both patches have the same number of channels and buses.
+	 */
+	if (len(channelsA) != len(channelsB)) || (len(busesA) != len(busesB)) {
+		return false
+	} else {
+
+		/*
+		 * Compare the units of each channel.
+		 */
+		for i, channel := range channelsA {
+
+			/*
+			 * Check if units match.
+			 */
+			if !compatibleUnits(channel.Units, channelsB[i].Units) {
+				return false
+			}
+
+		}
+
+		/*
+		 * Compare the units of each aux bus.
+		 */
+		for i, bus := range busesA {
+
+			/*
+			 * Check if units match.
+			 */
+			if !compatibleUnits(bus.Units, busesB[i].Units) {
+				return false
+			}
+
+		}
+
+		return true
+	}
+
+}
+
+/*
+ * Interpolates linearly between two values.
+ */
+func interpolate(from float64, to float64, fraction float64) float64 {
+	return from + (fraction * (to - from))
+}
+
+/*
+ * Sets the parameters of the units in a signal chain to a point between two
+ * lists of compatible units.
+ *
+ * Numeric parameters are interpolated. Discrete parameters and bypass states
+ * cannot be interpolated, so they are only switched on request.
+ */
+func morphChain(chain signal.Chain, from []persistence.Unit, to []persistence.Unit, fraction float64, switchDiscrete bool) {
+
+	/*
+	 * Morph each unit.
+	 */
+	for unitId, unitTo := range to {
+		unitFrom := from[unitId]
+
+		/*
+		 * Interpolate each numeric parameter.
+		 */
+		for _, paramTo := range unitTo.NumericParams {
+			key := paramTo.Key
+			valueTo := float64(paramTo.Value)
+			valueFrom := valueTo
+
+			/*
+			 * Find the value of the same parameter in the original unit.
+			 */
+			for _, paramFrom := range unitFrom.NumericParams {
+
+				/*
+				 * Check if we found the parameter.
+				 */
+				if paramFrom.Key == key {
+					valueFrom = float64(paramFrom.Value)
+				}
+
+			}
+
+			valueFloat := interpolate(valueFrom, valueTo, fraction)
+			valueRounded := math.Round(valueFloat)
+			value := int32(valueRounded)
+			chain.SetNumericValue(unitId, key, value)
+		}
+
+		inputTrimFrom := float64(unitFrom.InputTrim)
+		inputTrimTo := float64(unitTo.InputTrim)
+		inputTrimFloat := interpolate(inputTrimFrom, inputTrimTo, fraction)
+		inputTrimRounded := math.Round(inputTrimFloat)
+		inputTrim := int32(inputTrimRounded)
+		chain.SetInputTrim(unitId, inputTrim)
+		outputLevelFrom := float64(unitFrom.OutputLevel)
+		outputLevelTo := float64(unitTo.OutputLevel)
+		outputLevelFloat := interpolate(outputLevelFrom, outputLevelTo, fraction)
+		outputLevelRounded := math.Round(outputLevelFloat)
+		outputLevel := int32(outputLevelRounded)
+		chain.SetOutputLevel(unitId, outputLevel)
+
+		/*
+		 * Switch discrete parameters and bypass state on request.
+		 */
+		if switchDiscrete {
+
+			/*
+			 * Set each discrete parameter.
+			 */
+			for _, param := range unitTo.DiscreteParams {
+				chain.SetDiscreteValue(unitId, param.Key, param.Value)
+			}
+
+			chain.SetBypass(unitId, unitTo.Bypass)
+		}
+
+	}
+
+}
+
+/*
+ * Sets the configuration to a point between two compatible patches.
+ */
+func (this *controllerStruct) morphPatch(from persistence.Configuration, to persistence.Configuration, fraction float64, switchDiscrete bool) {
+	spat := this.spat
+	signalChains := this.effects
+	numChains := len(signalChains)
+
+	/*
+	 * Morph each channel.
+	 */
+	for channelId, channelTo := range to.Channels {
+
+		/*
+		 * Only morph channels which exist.
+		 */
+		if channelId < numChains {
+			channelFrom := from.Channels[channelId]
+			chain := signalChains[channelId]
+			morphChain(chain, channelFrom.Units, channelTo.Units, fraction, switchDiscrete)
+			channelId32 := uint32(channelId)
+			spatFrom := channelFrom.Spatializer
+			spatTo := channelTo.Spatializer
+			azimuth := interpolate(spatFrom.Azimuth, spatTo.Azimuth, fraction)
+			distance := interpolate(spatFrom.Distance, spatTo.Distance, fraction)
+			level := interpolate(spatFrom.Level, spatTo.Level, fraction)
+			spat.SetAzimuth(channelId32, azimuth)
+			spat.SetDistance(channelId32, distance)
+			spat.SetLevel(channelId32, level)
+			click := interpolate(channelFrom.Click, channelTo.Click, fraction)
+			channelTrimFrom := float64(channelFrom.ChannelTrim)
+			channelTrimTo := float64(channelTo.ChannelTrim)
+			channelTrim := interpolate(channelTrimFrom, channelTrimTo, fraction)
+			channelTrimRounded := math.Round(channelTrim)
+			reampLevelFrom := float64(channelFrom.ReampLevel)
+			reampLevelTo := float64(channelTo.ReampLevel)
+			reampLevel := interpolate(reampLevelFrom, reampLevelTo, fraction)
+			reampLevelRounded := math.Round(reampLevel)
+
+			/*
+			 * Change the levels of the channel at once.
+			 */
+			this.changeMix(func(mix *mixSnapshotStruct) {
+
+				/*
+				 * The channel may have been removed meanwhile.
+				 */
+				if channelId < len(mix.channels) {
+					metadata := &mix.channels[channelId]
+					metadata.click = click
+					metadata.channelTrim = int32(channelTrimRounded)
+					metadata.reampLevel = int32(reampLevelRounded)
+				}
+
+			})
+
+			/*
+			 * Mute and solo flags switch like discrete parameters.
+			 */
+			if switchDiscrete {
+				spat.SetMute(channelId32, spatTo.Mute)
+				spat.SetSolo(channelId32, spatTo.Solo)
+			}
+
+			sendsFrom := spatFrom.Sends
+			numSendsFrom := len(sendsFrom)
+
+			/*
+			 * Morph the send level for each aux bus.
+			 */
+			for busId, sendTo := range spatTo.Sends {
+				sendFrom := sendTo
+
+				/*
+				 * Check if the original patch has a send level for
+				 * this bus.
+				 */
+				if busId < numSendsFrom {
+					sendFrom = sendsFrom[busId]
+				}
+
+				busId32 := uint32(busId)
+				send := interpolate(sendFrom, sendTo, fraction)
+				spat.SetSend(channelId32, busId32, send)
+			}
+
+		}
+
+	}
+
+	busChains := this.buses
+	numBusChains := len(busChains)
+
+	/*
+	 * Morph each aux bus.
+	 */
+	for busId, busTo := range to.Buses {
+
+		/*
+		 * Only morph buses which exist.
+		 */
+		if busId < numBusChains {
+			busFrom := from.Buses[busId]
+			chain := busChains[busId]
+			morphChain(chain, busFrom.Units, busTo.Units, fraction, switchDiscrete)
+			busId32 := uint32(busId)
+			returnLevel := interpolate(busFrom.Return, busTo.Return, fraction)
+			spat.SetReturn(busId32, returnLevel)
+		}
+
+	}
+
+	masterSection := this.masterSection
+	masterFrom := from.Master
+	masterTo := to.Master
+
+	/*
+	 * Interpolate each parameter of the master section.
+	 */
+	for _, paramTo := range masterTo.NumericParams {
+		key := paramTo.Key
+		valueTo := float64(paramTo.Value)
+		valueFrom := valueTo
+
+		/*
+		 * Find the value of the same parameter in the original patch.
+		 */
+		for _, paramFrom := range masterFrom.NumericParams {
+
+			/*
+			 * Check if we found the parameter.
+			 */
+			if paramFrom.Key == key {
+				valueFrom = float64(paramFrom.Value)
+			}
+
+		}
+
+		valueFloat := interpolate(valueFrom, valueTo, fraction)
+		valueRounded := math.Round(valueFloat)
+		value := int32(valueRounded)
+		masterSection.SetValue(key, value)
+	}
+
+	/*
+	 * Switch the master section on or off on request.
+	 */
+	if switchDiscrete {
+		masterSection.SetEnabled(masterTo.Enabled)
+	}
+
+}
+
+/*
+ * Gradually morphs the configuration from one patch into another.
+ *
+ * Discrete parameters and bypass states switch halfway through, while the
+ * settings of the metronome switch at the end. Stops early when the stop
+ * channel is closed and closes the done channel when finished.
+ */
+func (this *controllerStruct) morph(from persistence.Configuration, to persistence.Configuration, duration int, stop chan bool, done chan bool) {
+	numSteps := duration / SNAPSHOT_MORPH_STEP
+
+	/*
+	 * Take at least one step.
+	 */
+	if numSteps < 1 {
+		numSteps = 1
+	}
+
+	numStepsFloat := float64(numSteps)
+	stepDuration := SNAPSHOT_MORPH_STEP * time.Millisecond
+	switched := false
+	stopped := false
+
+	/*
+	 * Perform each step of the morph.
+	 */
+	for step := 1; (step <= numSteps) && !stopped; step++ {
+		timer := time.NewTimer(stepDuration)
+
+		/*
+		 * Wait for the next step unless we got stopped.
+		 */
+		select {
+		case <-stop:
+			timer.Stop()
+			stopped = true
+		case <-timer.C:
+			stepFloat := float64(step)
+			fraction := stepFloat / numStepsFloat
+			switchDiscrete := !switched && (fraction >= 0.5)
+			this.morphPatch(from, to, fraction, switchDiscrete)
+
+			/*
+			 * Discrete parameters only switch once.
+			 */
+			if switchDiscrete {
+				switched = true
+			}
+
+		}
+
+	}
+
+	/*
+	 * If the morph completed, switch the metronome settings.
+	 */
+	if !stopped {
+		persistedMetr := to.Metronome
+		this.restoreMetronome(persistedMetr)
+	}
+
+	close(done)
+}
+
+/*
+ * Stops the current morph and waits for it to finish. Returns whether a morph
+ * was still in progress.
+ *
+ * The snapshot mutex must be held when calling this.
+ */
+func (this *controllerStruct) stopMorph() bool {
+	done := this.morphDone
+	inProgress := false
+
+	/*
+	 * Check if a morph was started.
+	 */
+	if done != nil {
+
+		/*
+		 * Check if the morph already finished.
+		 */
+		select {
+		case <-done:
+			inProgress = false
+		default:
+			inProgress = true
+		}
+
+		close(this.morphStop)
+		<-done
+		this.morphStop = nil
+		this.morphDone = nil
+	}
+
+	return inProgress
+}
+
+/*
+ * Stops the current morph, if any, before the configuration is changed in a
+ * way the morph does not expect, so that it does not overwrite the change.
+ */
+func (this *controllerStruct) haltMorph() {
+	this.snapshotMutex.Lock()
+	this.stopMorph()
+	this.snapshotMutex.Unlock()
+}
+
+/*
+ * Returns which snapshots are stored, which one is active and whether a morph
+ * is in progress.
+ */
+func (this *controllerStruct) getSnapshotsHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	this.snapshotMutex.Lock()
+	active := this.activeSnapshot
+	activeName := snapshotName(active)
+	stored := []string{}
+
+	/*
+	 * Find the snapshots which are stored.
+	 */
+	for idx, snapshot := range this.snapshots {
+
+		/*
+		 * Check if snapshot is stored.
+		 */
+		if snapshot != nil {
+			name := snapshotName(idx)
+			stored = append(stored, name)
+		}
+
+	}
+
+	done := this.morphDone
+	morphing := false
+
+	/*
+	 * Check if a morph is in progress.
+	 */
+	if done != nil {
+
+		/*
+		 * Check if the morph already finished.
+		 */
+		select {
+		case <-done:
+			morphing = false
+		default:
+			morphing = true
+		}
+
+	}
+
+	this.snapshotMutex.Unlock()
+
+	/*
+	 * Create snapshot state structure.
+	 */
+	result := webSnapshotsStruct{
+		Active:   activeName,
+		Stored:   stored,
+		Morphing: morphing,
+	}
+
+	mimeType, buffer := this.createJSON(result)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Stores the current configuration as a snapshot, which becomes the active
+ * snapshot.
+ */
+func (this *controllerStruct) storeSnapshotHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	slot := request.Params["slot"]
+	idx, err := snapshotIndex(slot)
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if snapshot slot is valid.
+	 */
+	if err != nil {
+		reason := err.Error()
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+		this.snapshotMutex.Lock()
+		this.stopMorph()
+		configuration := this.createPatch()
+		this.snapshots[idx] = &configuration
+		this.activeSnapshot = idx
+		this.snapshotMutex.Unlock()
+
+		/*
+		 * Indicate success.
+		 */
+		webResponse = webResponseStruct{
+			Success: true,
+			Reason:  "",
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Switches between the A and B snapshots.
+ *
+ * Changes made to the active snapshot are kept. Optionally, the parameters
+ * are crossfaded over a certain time (in milliseconds), if both snapshots
+ * contain the same units. Otherwise, the other snapshot is restored
+ * instantly.
+ */
+func (this *controllerStruct) toggleSnapshotHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	timeString := request.Params["time"]
+	duration := uint64(0)
+	err := error(nil)
+
+	/*
+	 * The morph time is optional.
+	 */
+	if timeString != "" {
+		duration, err = strconv.ParseUint(timeString, 10, 32)
+	}
+
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if morph time is valid.
+	 */
+	if err != nil {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  "Failed to decode morph time.",
+		}
+
+	} else if duration > SNAPSHOT_MAX_MORPH_TIME {
+		reason := fmt.Sprintf("Morph time must not exceed %d ms.", SNAPSHOT_MAX_MORPH_TIME)
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse = webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+	} else {
+		this.snapshotMutex.Lock()
+		active := this.activeSnapshot
+		other := (active + 1) % SNAPSHOT_COUNT
+		target := this.snapshots[other]
+
+		/*
+		 * Check if the other snapshot is stored.
+		 */
+		if target == nil {
+			this.snapshotMutex.Unlock()
+			name := snapshotName(other)
+			reason := fmt.Sprintf("Snapshot '%s' is empty.", name)
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		} else {
+			interrupted := this.stopMorph()
+			current := this.createPatch()
+
+			/*
+			 * Keep changes to the active snapshot, unless we
+			 * interrupted a morph towards it.
+			 */
+			if !interrupted {
+				this.snapshots[active] = &current
+			}
+
+			this.activeSnapshot = other
+			targetConfiguration := *target
+			err := error(nil)
+
+			/*
+			 * Check if we can morph into the other snapshot.
+			 */
+			if (duration > 0) && compatiblePatches(current, targetConfiguration) {
+				stop := make(chan bool)
+				done := make(chan bool)
+				this.morphStop = stop
+				this.morphDone = done
+				durationInt := int(duration)
+				go this.morph(current, targetConfiguration, durationInt, stop, done)
+			} else {
+				err = this.restoreConfiguration(targetConfiguration)
+			}
+
+			this.snapshotMutex.Unlock()
+
+			/*
+			 * Check if the snapshot was restored.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
@@ -0,0 +1,450 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"strconv"
+)
+
+/*
+ * Perform a pitch analysis via the tuner and return the results.
+ */
+func (this *controllerStruct) getTunerAnalysisHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	currentTuner := this.tuner
+	analysis, err := currentTuner.Analyze()
+	response := webserver.HttpResponse{}
+
+	/*
+	 * Check if analysis was successful.
+	 */
+	if err != nil {
+		msg := err.Error()
+		reason := fmt.Sprintf("Failed to perform analysis: %s", msg)
+
+		/*
+		 * Indicate failure.
+		 */
+		errResponse := webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+		mimeType, buffer := this.createJSON(errResponse)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response = webserver.HttpResponse{
+			Header: map[string]string{"Content-type": mimeType},
+			Body:   buffer,
+		}
+
+	} else {
+		cents := analysis.Cents()
+		frequency := analysis.Frequency()
+		note := analysis.Note()
+
+		/*
+		 * Fill the results of the tuner into a data structure.
+		 */
+		result := webTunerResultStruct{
+			Cents:     cents,
+			Frequency: frequency,
+			Note:      note,
+		}
+
+		mimeType, buffer := this.createJSON(result)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response = webserver.HttpResponse{
+			Header: map[string]string{"Content-type": mimeType},
+			Body:   buffer,
+		}
+
+	}
+
+	return response
+}
+
+/*
+ * Perform a pitch analysis of each string of the instrument via the tuner and
+ * return the results.
+ */
+func (this *controllerStruct) getTunerStringsHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	currentTuner := this.tuner
+	analyses, err := currentTuner.AnalyzeStrings()
+	response := webserver.HttpResponse{}
+
+	/*
+	 * Check if analysis was successful.
+	 */
+	if err != nil {
+		msg := err.Error()
+		reason := fmt.Sprintf("Failed to perform analysis: %s", msg)
+
+		/*
+		 * Indicate failure.
+		 */
+		errResponse := webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+		mimeType, buffer := this.createJSON(errResponse)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response = webserver.HttpResponse{
+			Header: map[string]string{"Content-type": mimeType},
+			Body:   buffer,
+		}
+
+	} else {
+		numAnalyses := len(analyses)
+		results := make([]webTunerResultStruct, numAnalyses)
+
+		/*
+		 * Fill the results for each string into a data structure.
+		 */
+		for i, analysis := range analyses {
+			cents := analysis.Cents()
+			frequency := analysis.Frequency()
+			note := analysis.Note()
+
+			/*
+			 * Fill the results of the tuner into a data structure.
+			 */
+			results[i] = webTunerResultStruct{
+				Cents:     cents,
+				Frequency: frequency,
+				Note:      note,
+			}
+
+		}
+
+		mimeType, buffer := this.createJSON(results)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response = webserver.HttpResponse{
+			Header: map[string]string{"Content-type": mimeType},
+			Body:   buffer,
+		}
+
+	}
+
+	return response
+}
+
+/*
+ * Perform a high-resolution pitch analysis via the tuner and return the
+ * results.
+ */
+func (this *controllerStruct) getTunerStrobeHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	currentTuner := this.tuner
+	analysis, err := currentTuner.AnalyzeStrobe()
+	response := webserver.HttpResponse{}
+
+	/*
+	 * Check if analysis was successful.
+	 */
+	if err != nil {
+		msg := err.Error()
+		reason := fmt.Sprintf("Failed to perform analysis: %s", msg)
+
+		/*
+		 * Indicate failure.
+		 */
+		errResponse := webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+		mimeType, buffer := this.createJSON(errResponse)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response = webserver.HttpResponse{
+			Header: map[string]string{"Content-type": mimeType},
+			Body:   buffer,
+		}
+
+	} else {
+		cents := analysis.Cents()
+		frequency := analysis.Frequency()
+		note := analysis.Note()
+		phase := analysis.Phase()
+
+		/*
+		 * Fill the results of the tuner into a data structure.
+		 */
+		result := webTunerStrobeResultStruct{
+			Cents:     cents,
+			Frequency: frequency,
+			Note:      note,
+			Phase:     phase,
+		}
+
+		mimeType, buffer := this.createJSON(result)
+
+		/*
+		 * Create HTTP response.
+		 */
+		response = webserver.HttpResponse{
+			Header: map[string]string{"Content-type": mimeType},
+			Body:   buffer,
+		}
+
+	}
+
+	return response
+}
+
+/*
+ * Sets a value for the tuner.
+ */
+func (this *controllerStruct) setTunerValueHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	currentTuner := this.tuner
+	webResponse := webResponseStruct{}
+
+	/*
+	 * Check if we have a tuner.
+	 */
+	if currentTuner != nil {
+		param := request.Params["param"]
+		value := request.Params["value"]
+
+		/*
+		 * Check which parameter should be edited.
+		 */
+		switch param {
+		case "channel":
+			rawValue, err := strconv.ParseInt(value, 10, 64)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if err != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode tuner channel.",
+				}
+
+			} else {
+
+				/*
+				 * Set the channel the tuner listens to.
+				 */
+				this.changeMix(func(mix *mixSnapshotStruct) {
+					mix.tunerChannel = int(rawValue)
+				})
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+		case "mute":
+			mute, err := strconv.ParseBool(value)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if err != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode tuner mute flag.",
+				}
+
+			} else {
+
+				/*
+				 * Set whether the channel the tuner listens to is muted.
+				 */
+				this.changeMix(func(mix *mixSnapshotStruct) {
+					mix.tunerMute = mute
+				})
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+		case "reference":
+			frequency, err := strconv.ParseFloat(value, 64)
+
+			/*
+			 * Check if value failed to parse.
+			 */
+			if err != nil {
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  "Failed to decode reference pitch.",
+				}
+
+			} else {
+				err = currentTuner.SetReference(frequency)
+
+				/*
+				 * Check if reference pitch could be set.
+				 */
+				if err != nil {
+					reason := err.Error()
+
+					/*
+					 * Indicate failure.
+					 */
+					webResponse = webResponseStruct{
+						Success: false,
+						Reason:  reason,
+					}
+
+				} else {
+
+					/*
+					 * Indicate success.
+					 */
+					webResponse = webResponseStruct{
+						Success: true,
+						Reason:  "",
+					}
+
+				}
+
+			}
+		case "notation":
+			err := currentTuner.SetNotation(value)
+
+			/*
+			 * Check if notation could be selected.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+		case "temperament":
+			err := currentTuner.SetTemperament(value)
+
+			/*
+			 * Check if temperament could be selected.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+		case "tuning":
+			err := currentTuner.SetTuning(value)
+
+			/*
+			 * Check if tuning could be selected.
+			 */
+			if err != nil {
+				reason := err.Error()
+
+				/*
+				 * Indicate failure.
+				 */
+				webResponse = webResponseStruct{
+					Success: false,
+					Reason:  reason,
+				}
+
+			} else {
+
+				/*
+				 * Indicate success.
+				 */
+				webResponse = webResponseStruct{
+					Success: true,
+					Reason:  "",
+				}
+
+			}
+		default:
+			reason := fmt.Sprintf("Unknown tuner parameter: '%s'", param)
+
+			/*
+			 * Indicate failure.
+			 */
+			webResponse = webResponseStruct{
+				Success: false,
+				Reason:  reason,
+			}
+
+		}
+
+	}
+
+	mimeType, buffer := this.createJSON(webResponse)
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
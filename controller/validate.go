@@ -0,0 +1,399 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/persistence"
+	"github.com/andrepxx/go-dsp-guitar/signal"
+	"github.com/andrepxx/go-dsp-guitar/spatializer"
+	"github.com/andrepxx/go-dsp-guitar/webserver"
+	"io"
+	"net/http"
+	"strings"
+)
+
+/*
+ * A data structure encoding the problems found in a patch.
+ *
+ * A patch is only restored if it has no errors. Warnings describe settings
+ * which are ignored or corrected when the patch is restored.
+ */
+type webPatchCheckStruct struct {
+	Valid    bool
+	Errors   []string
+	Warnings []string
+}
+
+/*
+ * Creates an empty signal chain to restore units into, which is stereo if the
+ * chain it stands in for is stereo.
+ */
+func (this *controllerStruct) createScratchChain(chain signal.Chain) signal.Chain {
+	responses := this.impulseResponses
+	result := signal.Chain(nil)
+
+	/*
+	 * Create a stereo chain if the original chain is stereo.
+	 */
+	if chain.Stereo() {
+		result = signal.CreateStereoChain(responses)
+	} else {
+		result = signal.CreateChain(responses)
+	}
+
+	result.SetSmoothing(false)
+	return result
+}
+
+/*
+ * Checks whether a patch can be restored, without changing any state, and
+ * records all problems found.
+ *
+ * The units of the patch are restored into scratch chains, so that exactly
+ * the units and settings which would be lost are reported.
+ */
+func (this *controllerStruct) checkPatch(configuration persistence.Configuration) diagnosticsStruct {
+	diag := diagnosticsStruct{}
+	fileFormat := configuration.FileFormat
+	fileType := fileFormat.Type
+	fileVersion := fileFormat.Version
+	majorVersion := fileVersion.Major
+	minorVersion := fileVersion.Minor
+
+	/*
+	 * Ensure that file format is compatible.
+	 */
+	if fileType != "patch" {
+		diag.fail("%s", "File is not a patch file.")
+	} else if majorVersion != 1 || minorVersion < 0 {
+		diag.fail("%s", "Incompatible version of file format.")
+	} else {
+		channels := configuration.Channels
+		numChannels := len(channels)
+		signalChains := this.effects
+		numChains := len(signalChains)
+		buses := configuration.Buses
+		numBuses := len(buses)
+		busChains := this.buses
+		numBusChains := len(busChains)
+
+		/*
+		 * A patch may not contain more channels than we have.
+		 */
+		if numChannels > numChains {
+			diag.fail("Patch contains %d channels, but only %d are available.", numChannels, numChains)
+			channels = channels[0:numChains]
+		}
+
+		/*
+		 * A patch may not contain more aux buses than we have.
+		 */
+		if numBuses > numBusChains {
+			diag.fail("Patch contains %d aux buses, but only %d are available.", numBuses, numBusChains)
+			buses = buses[0:numBusChains]
+		}
+
+		chains := make([]signal.Chain, numChains+numBusChains)
+		copy(chains, signalChains)
+		copy(chains[numChains:], busChains)
+
+		/*
+		 * Check each channel which exists.
+		 */
+		for channelId, channel := range channels {
+			location := fmt.Sprintf("Channel %d", channelId)
+			chain := this.createScratchChain(signalChains[channelId])
+			this.restoreUnits(chain, channel.Units, location, &diag)
+			chains[channelId] = chain
+
+			/*
+			 * Colors which are not in hexadecimal notation are
+			 * replaced by the configured ones.
+			 */
+			if (channel.Color != "") && !isColor(channel.Color) {
+				diag.warn("%s: Color '%s' is not in hexadecimal notation (#rrggbb) and will be ignored.", location, channel.Color)
+			}
+
+			/*
+			 * The click level is limited.
+			 */
+			if !(channel.Click >= 0.0) || (channel.Click > 1.0) {
+				diag.warn("%s: Click level %f is not within [0, 1] and will be limited.", location, channel.Click)
+			}
+
+			/*
+			 * The channel trim is limited.
+			 */
+			if (channel.ChannelTrim < CHANNEL_TRIM_MIN) || (channel.ChannelTrim > CHANNEL_TRIM_MAX) {
+				diag.warn("%s: Channel trim %d is not between %d and %d dB and will be limited.", location, channel.ChannelTrim, CHANNEL_TRIM_MIN, CHANNEL_TRIM_MAX)
+			}
+
+			/*
+			 * The re-amping level is limited.
+			 */
+			if (channel.ReampLevel < REAMP_LEVEL_MIN) || (channel.ReampLevel > REAMP_LEVEL_MAX) {
+				diag.warn("%s: Re-amping level %d is not between %d and %d and will be limited.", location, channel.ReampLevel, REAMP_LEVEL_MIN, REAMP_LEVEL_MAX)
+			}
+
+			numSends := len(channel.Spatializer.Sends)
+
+			/*
+			 * Sends to aux buses which do not exist are ignored.
+			 */
+			if numSends > numBusChains {
+				diag.warn("%s: Contains %d sends, but only %d aux buses are available. The remaining sends will be ignored.", location, numSends, numBusChains)
+			}
+
+		}
+
+		/*
+		 * Check each aux bus.
+		 */
+		for busId, bus := range buses {
+			location := fmt.Sprintf("Aux bus %d", busId)
+			chain := this.createScratchChain(busChains[busId])
+			this.restoreUnits(chain, bus.Units, location, &diag)
+			chains[numChains+busId] = chain
+		}
+
+		lanes := configuration.Automation
+		numLanes := len(lanes)
+
+		/*
+		 * Only a limited number of lanes is restored.
+		 */
+		if numLanes > AUTOMATION_MAX_LANES {
+			diag.warn("Patch contains %d automation lanes, but only %d will be restored.", numLanes, AUTOMATION_MAX_LANES)
+		}
+
+		numAllChains := len(chains)
+
+		/*
+		 * Check that each lane refers to a parameter which exists once
+		 * the patch is restored.
+		 */
+		for laneId, lane := range lanes {
+			chainId := lane.Chain
+			chain := signal.Chain(nil)
+
+			/*
+			 * Look up the chain of the lane.
+			 */
+			if (chainId >= 0) && (chainId < numAllChains) {
+				chain = chains[chainId]
+			}
+
+			_, err := createChainAutomationLane(chain, chainId, lane.Unit, lane.Param)
+
+			/*
+			 * Lanes referring to parameters which do not exist are
+			 * dropped.
+			 */
+			if err != nil {
+				msg := err.Error()
+				diag.warn("Automation lane %d: %s The lane will be dropped.", laneId, msg)
+			}
+
+		}
+
+		layout := configuration.SpeakerLayout
+		currentLayout := this.spat.GetSpeakerLayout()
+
+		/*
+		 * Check if the speaker layout changes.
+		 */
+		if (layout != "") && (layout != currentLayout) {
+			_, err := spatializer.SpeakerNames(layout)
+			rec := this.recorder
+
+			/*
+			 * Check if the layout is supported and if the ports may
+			 * be changed.
+			 */
+			if err != nil {
+				msg := err.Error()
+				diag.fail("Speaker layout: %s", msg)
+			} else if (rec != nil) && rec.Status().Recording() {
+				diag.fail("%s", "Cannot change the speaker layout while recording.")
+			}
+
+		}
+
+	}
+
+	return diag
+}
+
+/*
+ * Decodes a patch and checks whether it can be restored.
+ */
+func (this *controllerStruct) checkPatchBytes(patchBytes []byte) (persistence.Configuration, diagnosticsStruct) {
+	configuration := persistence.Configuration{}
+	err := json.Unmarshal(patchBytes, &configuration)
+
+	/*
+	 * Check if unmarshalling was successful.
+	 */
+	if err != nil {
+		msg := err.Error()
+		diag := diagnosticsStruct{}
+		diag.fail("Error during unmarshalling: %s", msg)
+		return configuration, diag
+	} else {
+		diag := this.checkPatch(configuration)
+		return configuration, diag
+	}
+
+}
+
+/*
+ * Creates the report of the problems found in a patch.
+ */
+func createPatchCheck(diag diagnosticsStruct) webPatchCheckStruct {
+	errs := diag.errors
+	warnings := diag.warnings
+
+	/*
+	 * Report an empty list instead of nothing.
+	 */
+	if errs == nil {
+		errs = []string{}
+	}
+
+	/*
+	 * Report an empty list instead of nothing.
+	 */
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	/*
+	 * The report of the problems found.
+	 */
+	report := webPatchCheckStruct{
+		Valid:    len(errs) == 0,
+		Errors:   errs,
+		Warnings: warnings,
+	}
+
+	return report
+}
+
+/*
+ * Checks whether a patch file can be restored, without restoring it, and
+ * reports all problems found.
+ */
+func (this *controllerStruct) persistenceValidateHandler(request webserver.HttpRequest) webserver.HttpResponse {
+	patchFiles := request.Files["patchfile"]
+	numPatchFiles := len(patchFiles)
+	reason := ""
+	report := webPatchCheckStruct{}
+
+	/*
+	 * Make sure that exactly one patch file is sent in request.
+	 */
+	if patchFiles == nil {
+		reason = "Field 'patchfile' not defined as a multipart field."
+	} else if numPatchFiles == 0 {
+		reason = "No patch file sent in request."
+	} else if numPatchFiles != 1 {
+		reason = "Multiple patch files sent in request."
+	} else {
+		patchFile := patchFiles[0]
+		patchBytes, err := io.ReadAll(patchFile)
+
+		/*
+		 * Check if patch file could be successfully read.
+		 */
+		if err != nil {
+			reason = "Failed to read patch file."
+		} else {
+			_, diag := this.checkPatchBytes(patchBytes)
+			report = createPatchCheck(diag)
+		}
+
+	}
+
+	mimeType := ""
+	buffer := []byte{}
+
+	/*
+	 * Report the problems found or why the patch could not be checked.
+	 */
+	if reason != "" {
+
+		/*
+		 * Indicate failure.
+		 */
+		webResponse := webResponseStruct{
+			Success: false,
+			Reason:  reason,
+		}
+
+		mimeType, buffer = this.createJSON(webResponse)
+	} else {
+		mimeType, buffer = this.createJSON(report)
+	}
+
+	/*
+	 * Create HTTP response.
+	 */
+	response := webserver.HttpResponse{
+		Header: map[string]string{"Content-type": mimeType},
+		Body:   buffer,
+	}
+
+	return response
+}
+
+/*
+ * Checks whether a patch sent as the 'Patch' parameter of a v2 API request can
+ * be restored, without restoring it, and reports all problems found.
+ */
+func (this *controllerStruct) apiValidateHandler(params map[string]string) webserver.HttpResponse {
+	patch, hasPatch := params["Patch"]
+
+	/*
+	 * Make sure that a patch was sent in request.
+	 */
+	if !hasPatch {
+		return this.createApiResponse(http.StatusBadRequest, false, "Field 'Patch' not defined in request body.", nil)
+	} else {
+		patchBytes := []byte(patch)
+		_, diag := this.checkPatchBytes(patchBytes)
+		report := createPatchCheck(diag)
+		result, err := json.Marshal(report)
+
+		/*
+		 * Check if the report could be encoded.
+		 */
+		if err != nil {
+			msg := err.Error()
+			reason := fmt.Sprintf("Failed to encode report: %s", msg)
+			return this.createApiResponse(http.StatusInternalServerError, false, reason, nil)
+		} else {
+			return this.createApiResponse(http.StatusOK, true, "", result)
+		}
+
+	}
+
+}
+
+/*
+ * Combines the errors found in a patch into a single error.
+ */
+func patchError(diag diagnosticsStruct) error {
+	errs := diag.errors
+	numErrors := len(errs)
+
+	/*
+	 * A single error is reported as it is.
+	 */
+	if numErrors == 1 {
+		return fmt.Errorf("%s", errs[0])
+	} else {
+		msg := strings.Join(errs, " ")
+		return fmt.Errorf("Patch was not restored, since it contains %d errors: %s", numErrors, msg)
+	}
+
+}
@@ -0,0 +1,453 @@
+package effects
+
+import (
+	"math"
+)
+
+/*
+ * Constants for the algorithmic reverb.
+ */
+const (
+	ALGORITHMIC_REVERB_LINES        = 4
+	ALGORITHMIC_REVERB_MAX_TONE     = 16000.0
+	ALGORITHMIC_REVERB_MIN_TONE     = 1000.0
+	ALGORITHMIC_REVERB_MAX_FRACTION = 0.45
+)
+
+/*
+ * Data structure describing a model of the algorithmic reverb. Delays are
+ * given in seconds, the bandwidth in hertz.
+ */
+type algorithmicReverbModel struct {
+	diffusionDelays   []float64
+	diffusionFeedback float64
+	lineDelays        [ALGORITHMIC_REVERB_LINES]float64
+	bandwidth         float64
+}
+
+/*
+ * Data structure representing one channel of the algorithmic reverb.
+ */
+type algorithmicReverbChannel struct {
+	bandwidthState float64
+	diffusers      []*reverbAllpass
+	lines          [ALGORITHMIC_REVERB_LINES][]float64
+	linePtrs       [ALGORITHMIC_REVERB_LINES]int
+	dampingStates  [ALGORITHMIC_REVERB_LINES]float64
+	frontBuffer    []float64
+	backBuffer     []float64
+}
+
+/*
+ * Data structure representing an algorithmic (spring or plate) reverb.
+ *
+ * The input is band-limited and diffused by a chain of allpass filters, then
+ * fed into a network of four delay lines, which feed back into each other
+ * through a Hadamard matrix. A lowpass filter in each feedback path damps
+ * the high frequencies.
+ */
+type algorithmicReverb struct {
+	unitStruct
+	model        string
+	sampleRate   uint32
+	channel      *algorithmicReverbChannel
+	channelRight *algorithmicReverbChannel
+}
+
+/*
+ * Returns the parameters of a model of the algorithmic reverb.
+ *
+ * A spring tank disperses the sound into chirps, which is modelled by a long
+ * chain of short allpass filters, and has a narrow bandwidth. A plate
+ * diffuses the sound quickly and has a wide bandwidth.
+ */
+func algorithmicReverbModelParameters(model string) algorithmicReverbModel {
+
+	/*
+	 * Decide on the model.
+	 */
+	switch model {
+	case "spring":
+
+		/*
+		 * Parameters of a spring reverb.
+		 */
+		m := algorithmicReverbModel{
+			diffusionDelays: []float64{
+				0.00037,
+				0.00053,
+				0.00071,
+				0.00089,
+				0.00107,
+				0.00131,
+				0.00149,
+				0.00173,
+			},
+			diffusionFeedback: 0.6,
+			lineDelays: [ALGORITHMIC_REVERB_LINES]float64{
+				0.03313,
+				0.03917,
+				0.04703,
+				0.05309,
+			},
+			bandwidth: 5000.0,
+		}
+
+		return m
+	default:
+
+		/*
+		 * Parameters of a plate reverb.
+		 */
+		m := algorithmicReverbModel{
+			diffusionDelays: []float64{
+				0.00477,
+				0.00360,
+				0.01274,
+				0.00930,
+			},
+			diffusionFeedback: 0.7,
+			lineDelays: [ALGORITHMIC_REVERB_LINES]float64{
+				0.02971,
+				0.03711,
+				0.04111,
+				0.04373,
+			},
+			bandwidth: 20000.0,
+		}
+
+		return m
+	}
+
+}
+
+/*
+ * Creates one channel of the algorithmic reverb. The spread (in seconds) is
+ * added to the delay lines in order to decorrelate the channels of a stereo
+ * reverb.
+ */
+func createAlgorithmicReverbChannel(model algorithmicReverbModel, sampleRate uint32, spread float64) *algorithmicReverbChannel {
+	sampleRateFloat := float64(sampleRate)
+	diffusionDelays := model.diffusionDelays
+	numDiffusers := len(diffusionDelays)
+	diffusers := make([]*reverbAllpass, numDiffusers)
+
+	/*
+	 * Create the diffusers.
+	 */
+	for i, delaySeconds := range diffusionDelays {
+		delaySamplesFloat := math.Round(delaySeconds * sampleRateFloat)
+		delaySamples := int(delaySamplesFloat)
+
+		/*
+		 * An allpass filter needs a delay of at least one sample.
+		 */
+		if delaySamples < 1 {
+			delaySamples = 1
+		}
+
+		buf := make([]float64, delaySamples)
+
+		/*
+		 * Create allpass filter.
+		 */
+		diffusers[i] = &reverbAllpass{
+			buffer:   buf,
+			ptr:      0,
+			feedback: model.diffusionFeedback,
+		}
+
+	}
+
+	/*
+	 * Create the channel.
+	 */
+	channel := algorithmicReverbChannel{
+		diffusers: diffusers,
+	}
+
+	/*
+	 * Create the delay lines.
+	 */
+	for i, delaySeconds := range model.lineDelays {
+		delaySpread := delaySeconds + spread
+		delaySamplesFloat := math.Round(delaySpread * sampleRateFloat)
+		delaySamples := int(delaySamplesFloat)
+		channel.lines[i] = make([]float64, delaySamples)
+	}
+
+	return &channel
+}
+
+/*
+ * Renders one channel of the algorithmic reverb.
+ */
+func (this *algorithmicReverbChannel) render(in []float64, out []float64, gains [ALGORITHMIC_REVERB_LINES]float64, bandwidthFactor float64, dampingFactor float64, wetFrac float64, sampleRate uint32) {
+	nIn := len(in)
+	dryFrac := 1.0 - wetFrac
+	frontBuffer := this.frontBuffer
+	backBuffer := this.backBuffer
+
+	/*
+	 * Ensure that the front buffer has the correct size.
+	 */
+	if len(frontBuffer) != nIn {
+		frontBuffer = make([]float64, nIn)
+	}
+
+	/*
+	 * Ensure that the back buffer has the correct size.
+	 */
+	if len(backBuffer) != nIn {
+		backBuffer = make([]float64, nIn)
+	}
+
+	bandwidthState := this.bandwidthState
+
+	/*
+	 * Limit the bandwidth of the input.
+	 */
+	for i, sample := range in {
+		bandwidthState += bandwidthFactor * (sample - bandwidthState)
+		frontBuffer[i] = bandwidthState
+	}
+
+	/*
+	 * Diffuse the input using the allpass filters.
+	 */
+	for _, diffuser := range this.diffusers {
+		diffuser.process(frontBuffer, backBuffer, sampleRate)
+		backBuffer, frontBuffer = frontBuffer, backBuffer
+	}
+
+	lines := &this.lines
+	linePtrs := &this.linePtrs
+	dampingStates := &this.dampingStates
+	outputs := [ALGORITHMIC_REVERB_LINES]float64{}
+	feedbacks := [ALGORITHMIC_REVERB_LINES]float64{}
+
+	/*
+	 * Process each sample through the feedback delay network.
+	 */
+	for i, diffused := range frontBuffer {
+
+		/*
+		 * Read and damp the output of each delay line.
+		 */
+		for j := range lines {
+			line := lines[j]
+			output := line[linePtrs[j]]
+			outputs[j] = output
+			dampingStates[j] += dampingFactor * (output - dampingStates[j])
+			feedbacks[j] = gains[j] * dampingStates[j]
+		}
+
+		sumFirst := feedbacks[0] + feedbacks[1]
+		diffFirst := feedbacks[0] - feedbacks[1]
+		sumSecond := feedbacks[2] + feedbacks[3]
+		diffSecond := feedbacks[2] - feedbacks[3]
+
+		/*
+		 * Mix the feedback through a (normalized) Hadamard matrix.
+		 */
+		feedbacks[0] = 0.5 * (sumFirst + sumSecond)
+		feedbacks[1] = 0.5 * (diffFirst + diffSecond)
+		feedbacks[2] = 0.5 * (sumFirst - sumSecond)
+		feedbacks[3] = 0.5 * (diffFirst - diffSecond)
+
+		/*
+		 * Write the input and the feedback into each delay line.
+		 */
+		for j := range lines {
+			line := lines[j]
+			ptr := linePtrs[j]
+			line[ptr] = diffused + feedbacks[j]
+			linePtrs[j] = (ptr + 1) % len(line)
+		}
+
+		wet := 0.5 * (outputs[0] - outputs[1] + outputs[2] - outputs[3])
+		drySample := in[i]
+		pre := (dryFrac * drySample) + (wetFrac * wet)
+		out[i] = limitSample(pre)
+	}
+
+	this.bandwidthState = bandwidthState
+	this.frontBuffer = frontBuffer
+	this.backBuffer = backBuffer
+}
+
+/*
+ * Reads the parameters of the algorithmic reverb and recreates its
+ * structures if the model or the sample rate has changed.
+ *
+ * Returns the gain of each delay line, the filter factors of the bandwidth
+ * and damping filters and the fraction of the wet signal.
+ */
+func (this *algorithmicReverb) prepare(sampleRate uint32) ([ALGORITHMIC_REVERB_LINES]float64, float64, float64, float64) {
+	params := this.processingParameters()
+	modelName, _ := params.discreteValue("model")
+	decay, _ := params.numericValue("decay")
+	tone, _ := params.numericValue("tone")
+	mix, _ := params.numericValue("mix")
+	model := algorithmicReverbModelParameters(modelName)
+
+	/*
+	 * If model or sample rate have changed, recreate all structures.
+	 */
+	if (this.model != modelName) || (this.sampleRate != sampleRate) || (this.channel == nil) {
+		this.channel = createAlgorithmicReverbChannel(model, sampleRate, 0.0)
+		this.channelRight = createAlgorithmicReverbChannel(model, sampleRate, REVERB_STEREO_SPREAD)
+		this.model = modelName
+		this.sampleRate = sampleRate
+	}
+
+	decayFloat := float64(decay)
+	decaySeconds := 0.1 * decayFloat
+	gains := [ALGORITHMIC_REVERB_LINES]float64{}
+
+	/*
+	 * Calculate the gain of each delay line, so that the reverb decays by
+	 * 60 dB within the decay time.
+	 */
+	for i, delaySeconds := range model.lineDelays {
+		exponent := (-3.0 * delaySeconds) / decaySeconds
+		gains[i] = math.Pow(10.0, exponent)
+	}
+
+	sampleRateFloat := float64(sampleRate)
+	maxFrequency := ALGORITHMIC_REVERB_MAX_FRACTION * sampleRateFloat
+	bandwidth := math.Min(model.bandwidth, maxFrequency)
+	bandwidthFactor := onePoleFactor(bandwidth, sampleRate)
+	toneFloat := float64(tone)
+	toneRatio := ALGORITHMIC_REVERB_MAX_TONE / ALGORITHMIC_REVERB_MIN_TONE
+	toneExponent := 0.01 * toneFloat
+	damping := ALGORITHMIC_REVERB_MIN_TONE * math.Pow(toneRatio, toneExponent)
+	damping = math.Min(damping, maxFrequency)
+	dampingFactor := onePoleFactor(damping, sampleRate)
+	mixFloat := float64(mix)
+	wetFrac := 0.01 * mixFloat
+	return gains, bandwidthFactor, dampingFactor, wetFrac
+}
+
+/*
+ * Algorithmic reverb audio processing.
+ */
+func (this *algorithmicReverb) Process(in []float64, out []float64, sampleRate uint32) {
+	nIn := len(in)
+	nOut := len(out)
+
+	/*
+	 * Ensure that the input and output buffers are of equal size.
+	 */
+	if nIn != nOut {
+
+		/*
+		 * Write zeros to output buffer.
+		 */
+		for i := range out {
+			out[i] = 0.0
+		}
+
+	} else {
+		gains, bandwidthFactor, dampingFactor, wetFrac := this.prepare(sampleRate)
+		this.channel.render(in, out, gains, bandwidthFactor, dampingFactor, wetFrac, sampleRate)
+	}
+
+}
+
+/*
+ * Algorithmic reverb audio processing for a stereo signal. The right channel
+ * uses slightly longer delays than the left channel to decorrelate both
+ * sides.
+ */
+func (this *algorithmicReverb) ProcessStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32) {
+	nIn := len(inLeft)
+
+	/*
+	 * Ensure that all buffers are of equal size.
+	 */
+	if (len(inRight) != nIn) || (len(outLeft) != nIn) || (len(outRight) != nIn) {
+
+		/*
+		 * Write zeros to left output buffer.
+		 */
+		for i := range outLeft {
+			outLeft[i] = 0.0
+		}
+
+		/*
+		 * Write zeros to right output buffer.
+		 */
+		for i := range outRight {
+			outRight[i] = 0.0
+		}
+
+	} else {
+		gains, bandwidthFactor, dampingFactor, wetFrac := this.prepare(sampleRate)
+		this.channel.render(inLeft, outLeft, gains, bandwidthFactor, dampingFactor, wetFrac, sampleRate)
+		this.channelRight.render(inRight, outRight, gains, bandwidthFactor, dampingFactor, wetFrac, sampleRate)
+	}
+
+}
+
+/*
+ * Create an algorithmic reverb effects unit.
+ */
+func createAlgorithmicReverb() Unit {
+
+	/*
+	 * Create effects unit.
+	 */
+	u := algorithmicReverb{
+		unitStruct: unitStruct{
+			unitType: UNIT_ALGORITHMIC_REVERB,
+			params: []Parameter{
+				Parameter{
+					Name:               "model",
+					Type:               PARAMETER_TYPE_DISCRETE,
+					PhysicalUnit:       "",
+					Minimum:            -1,
+					Maximum:            -1,
+					NumericValue:       -1,
+					DiscreteValueIndex: 0,
+					DiscreteValues: []string{
+						"spring",
+						"plate",
+					},
+				},
+				Parameter{
+					Name:               "decay",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "0.1 s",
+					Minimum:            1,
+					Maximum:            100,
+					NumericValue:       20,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "tone",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       50,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "mix",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       30,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+			},
+		},
+	}
+
+	return &u
+}
@@ -0,0 +1,111 @@
+package effects
+
+import (
+	"fmt"
+	"testing"
+)
+
+/*
+ * Calculates the energy of a signal.
+ */
+func signalEnergy(signal []float64) float64 {
+	energy := float64(0.0)
+
+	/*
+	 * Sum up the squares of all samples.
+	 */
+	for _, sample := range signal {
+		energy += sample * sample
+	}
+
+	return energy
+}
+
+/*
+ * Verify that an algorithmic reverb passes the dry signal through, decays
+ * according to the decay time and stays finite for noise.
+ */
+func TestAlgorithmicReverb(t *testing.T) {
+	n := 3 * 48000
+	second := 48000
+	impulse := make([]float64, n)
+	impulse[0] = 0.5
+	out := make([]float64, n)
+
+	/*
+	 * Models of the reverb.
+	 */
+	models := []string{
+		"spring",
+		"plate",
+	}
+
+	/*
+	 * Verify each model.
+	 */
+	for _, model := range models {
+		u := CreateUnit(UNIT_ALGORITHMIC_REVERB)
+		u.SetDiscreteValue("model", model)
+		u.SetNumericValue("mix", 0)
+		u.Process(impulse, out, TEST_SAMPLE_RATE)
+
+		/*
+		 * Without wet signal, the input should pass unaltered.
+		 */
+		for i, sample := range out {
+
+			/*
+			 * Check if sample matches the input.
+			 */
+			if sample != impulse[i] {
+				t.Errorf("%s: Dry sample %d should be %f, but is %f.", model, i, impulse[i], sample)
+				break
+			}
+
+		}
+
+		u = CreateUnit(UNIT_ALGORITHMIC_REVERB)
+		u.SetDiscreteValue("model", model)
+		u.SetNumericValue("mix", 100)
+		u.SetNumericValue("decay", 10)
+		u.Process(impulse, out, TEST_SAMPLE_RATE)
+		energyEarly := signalEnergy(out[0:second])
+		energyShort := signalEnergy(out[second : 2*second])
+
+		/*
+		 * The reverb should decay by about 60 dB within a second.
+		 */
+		if energyShort > (1e-4 * energyEarly) {
+			t.Errorf("%s: Energy after decay time should be at most %e, but is %e.", model, 1e-4*energyEarly, energyShort)
+		}
+
+		u = CreateUnit(UNIT_ALGORITHMIC_REVERB)
+		u.SetDiscreteValue("model", model)
+		u.SetNumericValue("mix", 100)
+		u.SetNumericValue("decay", 40)
+		u.Process(impulse, out, TEST_SAMPLE_RATE)
+		energyLong := signalEnergy(out[second : 2*second])
+
+		/*
+		 * A longer decay time should leave a longer tail.
+		 */
+		if energyLong < (100.0 * energyShort) {
+			t.Errorf("%s: Energy of long tail should be at least %e, but is %e.", model, 100.0*energyShort, energyLong)
+		}
+
+		u = CreateUnit(UNIT_ALGORITHMIC_REVERB)
+		u.SetDiscreteValue("model", model)
+		u.SetNumericValue("decay", 100)
+		u.SetNumericValue("tone", 100)
+		noise := createNoise(n, 1)
+		noiseRight := createNoise(n, 2)
+		outRight := make([]float64, n)
+		stereoUnit := u.(StereoUnit)
+		stereoUnit.ProcessStereo(noise, noiseRight, out, outRight, TEST_SAMPLE_RATE)
+		nameLeft := fmt.Sprintf("%s_left", model)
+		checkOutput(t, nameLeft, out)
+		nameRight := fmt.Sprintf("%s_right", model)
+		checkOutput(t, nameRight, outRight)
+	}
+
+}
@@ -0,0 +1,565 @@
+package effects
+
+import (
+	"github.com/andrepxx/go-dsp-guitar/oversampling"
+	"math"
+)
+
+/*
+ * Constants for the amp model.
+ */
+const (
+	AMP_MODEL_BASS_TAPER          = 3.4
+	AMP_MODEL_COUPLING_FREQUENCY  = 20.0
+	AMP_MODEL_MILLER_FREQUENCY    = 12000.0
+	AMP_MODEL_PRESENCE_FREQUENCY  = 2000.0
+	AMP_MODEL_RESONANCE_FREQUENCY = 100.0
+	AMP_MODEL_SHELF_MAX_GAIN      = 12
+	AMP_MODEL_STAGE_BIAS          = 0.3
+	AMP_MODEL_TONE_STACK_ORDER    = 3
+)
+
+/*
+ * Component values of a passive tone stack in the topology found in amps by
+ * Fender, Marshall and Vox. Capacitances are given in farads, resistances in
+ * ohms. R1, R2 and R3 are the treble, bass and middle potentiometers, R4 is
+ * the slope resistor.
+ */
+type toneStackComponents struct {
+	c1 float64
+	c2 float64
+	c3 float64
+	r1 float64
+	r2 float64
+	r3 float64
+	r4 float64
+}
+
+/*
+ * Data structure representing an amp model.
+ */
+type ampModel struct {
+	unitStruct
+	bufferIn         []float64
+	bufferOut        []float64
+	oversamplerTwo   oversampling.OversamplerDecimator
+	oversamplerFour  oversampling.OversamplerDecimator
+	oversamplerEight oversampling.OversamplerDecimator
+	gain             smoothedValue
+	level            smoothedValue
+	couplingStates   []float64
+	millerStates     []float64
+	toneStackStates  [AMP_MODEL_TONE_STACK_ORDER]float64
+	presenceState    float64
+	resonanceState   float64
+}
+
+/*
+ * Returns the tone stack components of an amp model.
+ */
+func ampModelComponents(model string) toneStackComponents {
+
+	/*
+	 * Select the components of the amp model.
+	 */
+	switch model {
+	case "Marshall":
+
+		/*
+		 * Components of a Marshall JCM800.
+		 */
+		components := toneStackComponents{
+			c1: 470e-12,
+			c2: 22e-9,
+			c3: 22e-9,
+			r1: 220e3,
+			r2: 1e6,
+			r3: 22e3,
+			r4: 33e3,
+		}
+
+		return components
+	case "Vox":
+
+		/*
+		 * Components which give the bright voicing with the shallow
+		 * mid scoop of a Vox.
+		 */
+		components := toneStackComponents{
+			c1: 100e-12,
+			c2: 22e-9,
+			c3: 22e-9,
+			r1: 1e6,
+			r2: 1e6,
+			r3: 100e3,
+			r4: 100e3,
+		}
+
+		return components
+	default:
+
+		/*
+		 * Components of a Fender Bassman.
+		 */
+		components := toneStackComponents{
+			c1: 250e-12,
+			c2: 20e-9,
+			c3: 20e-9,
+			r1: 250e3,
+			r2: 1e6,
+			r3: 25e3,
+			r4: 56e3,
+		}
+
+		return components
+	}
+
+}
+
+/*
+ * Calculates the coefficients of a digital filter modelling a tone stack at a
+ * certain sample rate.
+ *
+ * Treble (t), middle (m) and bass (l) are the positions of the potentiometers
+ * between zero and one. The analog transfer function is discretized using
+ * the bilinear transform. Returns the feed-forward coefficients b and the
+ * feedback coefficients a, normalized so that a[0] is one.
+ */
+func toneStackCoefficients(cmp toneStackComponents, t float64, m float64, l float64, sampleRate uint32) ([]float64, []float64) {
+	c1 := cmp.c1
+	c2 := cmp.c2
+	c3 := cmp.c3
+	r1 := cmp.r1
+	r2 := cmp.r2
+	r3 := cmp.r3
+	r4 := cmp.r4
+	mm := m * m
+	c1c2c3 := c1 * c2 * c3
+	b1 := (t * c1 * r1) + (m * c3 * r3) + (l * (c1*r2 + c2*r2)) + (c1*r3 + c2*r3)
+	b2 := (t * (c1*c2*r1*r4 + c1*c3*r1*r4)) - (mm * (c1*c3*r3*r3 + c2*c3*r3*r3)) + (m * (c1*c3*r1*r3 + c1*c3*r3*r3 + c2*c3*r3*r3)) + (l * (c1*c2*r1*r2 + c1*c2*r2*r4 + c1*c3*r2*r4)) + (l * m * (c1*c3*r2*r3 + c2*c3*r2*r3)) + (c1*c2*r1*r3 + c1*c2*r3*r4 + c1*c3*r3*r4)
+	b3 := (l * m * c1c2c3 * (r1*r2*r3 + r2*r3*r4)) - (mm * c1c2c3 * (r1*r3*r3 + r3*r3*r4)) + (m * c1c2c3 * (r1*r3*r3 + r3*r3*r4)) + (t * c1c2c3 * r1 * r3 * r4) - (t * m * c1c2c3 * r1 * r3 * r4) + (t * l * c1c2c3 * r1 * r2 * r4)
+	a0 := 1.0
+	a1 := (c1*r1 + c1*r3 + c2*r3 + c2*r4 + c3*r4) + (m * c3 * r3) + (l * (c1*r2 + c2*r2))
+	a2 := (m * (c1*c3*r1*r3 - c2*c3*r3*r4 + c1*c3*r3*r3 + c2*c3*r3*r3)) + (l * m * (c1*c3*r2*r3 + c2*c3*r2*r3)) - (mm * (c1*c3*r3*r3 + c2*c3*r3*r3)) + (l * (c1*c2*r2*r4 + c1*c2*r1*r2 + c1*c3*r2*r4 + c2*c3*r2*r4)) + (c1*c2*r1*r4 + c1*c3*r1*r4 + c1*c2*r3*r4 + c1*c2*r1*r3 + c1*c3*r3*r4 + c2*c3*r3*r4)
+	a3 := (l * m * c1c2c3 * (r1*r2*r3 + r2*r3*r4)) - (mm * c1c2c3 * (r1*r3*r3 + r3*r3*r4)) + (m * c1c2c3 * (r3*r3*r4 + r1*r3*r3 - r1*r3*r4)) + (l * c1c2c3 * r1 * r2 * r4) + (c1c2c3 * r1 * r3 * r4)
+	sampleRateFloat := float64(sampleRate)
+	c := 2.0 * sampleRateFloat
+	cc := c * c
+	ccc := cc * c
+
+	/*
+	 * Apply the bilinear transform. The analog numerator has no constant
+	 * term.
+	 */
+	b := []float64{
+		(b1 * c) + (b2 * cc) + (b3 * ccc),
+		(b1 * c) - (b2 * cc) - (3.0 * b3 * ccc),
+		-(b1 * c) - (b2 * cc) + (3.0 * b3 * ccc),
+		-(b1 * c) + (b2 * cc) - (b3 * ccc),
+	}
+
+	/*
+	 * Apply the bilinear transform to the denominator.
+	 */
+	a := []float64{
+		a0 + (a1 * c) + (a2 * cc) + (a3 * ccc),
+		(3.0 * a0) + (a1 * c) - (a2 * cc) - (3.0 * a3 * ccc),
+		(3.0 * a0) - (a1 * c) - (a2 * cc) + (3.0 * a3 * ccc),
+		a0 - (a1 * c) + (a2 * cc) - (a3 * ccc),
+	}
+
+	norm := 1.0 / a[0]
+
+	/*
+	 * Normalize the coefficients.
+	 */
+	for i := range a {
+		a[i] *= norm
+		b[i] *= norm
+	}
+
+	return b, a
+}
+
+/*
+ * Asymmetric transfer function of a triode gain stage, normalized to unity
+ * gain for small signals. The bias shifts the operating point, so that the
+ * stage clips harder on negative than on positive half-waves.
+ */
+func triodeStage(sample float64) float64 {
+	bias := math.Tanh(AMP_MODEL_STAGE_BIAS)
+	slope := 1.0 - (bias * bias)
+	x := math.Tanh(sample + AMP_MODEL_STAGE_BIAS)
+	result := (x - bias) / slope
+	return result
+}
+
+/*
+ * Internal (oversampled) processing of the gain stages of the amp model.
+ *
+ * The gain is spread evenly across all stages. Each stage inverts the phase,
+ * so that the asymmetry alternates between stages. The stages are coupled by
+ * a highpass filter, modelling the coupling capacitor, and a lowpass filter,
+ * modelling the Miller capacitance of the next triode.
+ */
+func (this *ampModel) processOversampled(in []float64, out []float64, sampleRate uint32) {
+	params := this.processingParameters()
+	gain, _ := params.numericValue("gain")
+	stagesString, _ := params.discreteValue("stages")
+	numStages := 1
+
+	/*
+	 * Select the number of gain stages.
+	 */
+	switch stagesString {
+	case "2":
+		numStages = 2
+	case "3":
+		numStages = 3
+	case "4":
+		numStages = 4
+	}
+
+	numStagesFloat := float64(numStages)
+	gainFloat := float64(gain)
+	stageGainTarget := math.Pow(10.0, (0.05*gainFloat)/numStagesFloat)
+	smoothingSamples := this.smoothingSamples(sampleRate)
+	gainRamp := &this.gain
+	gainRamp.setTarget(stageGainTarget, smoothingSamples)
+
+	/*
+	 * Allocate storage for the coupling filters if needed.
+	 */
+	if len(this.couplingStates) != numStages {
+		this.couplingStates = make([]float64, numStages)
+	}
+
+	/*
+	 * Allocate storage for the Miller filters if needed.
+	 */
+	if len(this.millerStates) != numStages {
+		this.millerStates = make([]float64, numStages)
+	}
+
+	couplingStates := this.couplingStates
+	millerStates := this.millerStates
+	couplingFactor := onePoleFactor(AMP_MODEL_COUPLING_FREQUENCY, sampleRate)
+	millerFactor := onePoleFactor(AMP_MODEL_MILLER_FREQUENCY, sampleRate)
+
+	/*
+	 * Process each sample.
+	 */
+	for i, sample := range in {
+		stageGain := gainRamp.next()
+		x := sample
+
+		/*
+		 * Pass the sample through each gain stage.
+		 */
+		for j := 0; j < numStages; j++ {
+			y := -triodeStage(stageGain * x)
+			couplingState := couplingStates[j]
+			couplingState += couplingFactor * (y - couplingState)
+			couplingStates[j] = couplingState
+			y -= couplingState
+			millerState := millerStates[j]
+			millerState += millerFactor * (y - millerState)
+			millerStates[j] = millerState
+			x = millerState
+		}
+
+		out[i] = x
+	}
+
+}
+
+/*
+ * Passes the output of the gain stages through the tone stack, the presence
+ * and resonance controls and the output level.
+ */
+func (this *ampModel) processTone(in []float64, out []float64, sampleRate uint32) {
+	params := this.processingParameters()
+	model, _ := params.discreteValue("model")
+	bass, _ := params.numericValue("bass")
+	middle, _ := params.numericValue("middle")
+	treble, _ := params.numericValue("treble")
+	presence, _ := params.numericValue("presence")
+	resonance, _ := params.numericValue("resonance")
+	level, _ := params.numericValue("level")
+	bassFloat := float64(bass)
+	middleFloat := float64(middle)
+	trebleFloat := float64(treble)
+	presenceFloat := float64(presence)
+	resonanceFloat := float64(resonance)
+	components := ampModelComponents(model)
+	t := 0.01 * trebleFloat
+	m := 0.01 * middleFloat
+	bassExp := AMP_MODEL_BASS_TAPER * ((0.01 * bassFloat) - 1.0)
+	l := math.Exp(bassExp)
+	b, a := toneStackCoefficients(components, t, m, l, sampleRate)
+	maxShelf := decibelsToFactor(AMP_MODEL_SHELF_MAX_GAIN) - 1.0
+	presenceFactor := 0.01 * presenceFloat * maxShelf
+	resonanceFactor := 0.01 * resonanceFloat * maxShelf
+	presenceCoefficient := onePoleFactor(AMP_MODEL_PRESENCE_FREQUENCY, sampleRate)
+	resonanceCoefficient := onePoleFactor(AMP_MODEL_RESONANCE_FREQUENCY, sampleRate)
+	levelTarget := decibelsToFactor(level)
+	smoothingSamples := this.smoothingSamples(sampleRate)
+	levelRamp := &this.level
+	levelRamp.setTarget(levelTarget, smoothingSamples)
+	states := &this.toneStackStates
+	presenceState := this.presenceState
+	resonanceState := this.resonanceState
+
+	/*
+	 * Process each sample.
+	 */
+	for i, sample := range in {
+		levelFactor := levelRamp.next()
+
+		/*
+		 * Apply the tone stack in transposed direct form II.
+		 */
+		y := (b[0] * sample) + states[0]
+		states[0] = (b[1] * sample) - (a[1] * y) + states[1]
+		states[1] = (b[2] * sample) - (a[2] * y) + states[2]
+		states[2] = (b[3] * sample) - (a[3] * y)
+
+		/*
+		 * Boost the treble above the presence frequency.
+		 */
+		presenceState += presenceCoefficient * (y - presenceState)
+		y += presenceFactor * (y - presenceState)
+
+		/*
+		 * Boost the bass below the resonance frequency.
+		 */
+		resonanceState += resonanceCoefficient * (y - resonanceState)
+		y += resonanceFactor * resonanceState
+		out[i] = limitSample(levelFactor * y)
+	}
+
+	this.presenceState = presenceState
+	this.resonanceState = resonanceState
+}
+
+/*
+ * Returns the latency (in samples) of the amp model.
+ */
+func (this *ampModel) Latency(sampleRate uint32) uint32 {
+	params := this.processingParameters()
+	oversampling, _ := params.discreteValue("oversampling")
+	oversamplerTwo := this.oversamplerTwo
+	oversamplerFour := this.oversamplerFour
+	oversamplerEight := this.oversamplerEight
+	latency := oversamplingLatency(oversampling, oversamplerTwo, oversamplerFour, oversamplerEight)
+	return latency
+}
+
+/*
+ * Amp model audio processing.
+ */
+func (this *ampModel) Process(in []float64, out []float64, sampleRate uint32) {
+	params := this.processingParameters()
+	oversampling, _ := params.discreteValue("oversampling")
+	factor := 1
+
+	/*
+	 * Enable two-, four- or eight-times oversampling.
+	 */
+	switch oversampling {
+	case "2":
+		factor = 2
+	case "4":
+		factor = 4
+	case "8":
+		factor = 8
+	}
+
+	/*
+	 * Check if we require oversampling.
+	 */
+	if factor > 1 {
+		numSamples := factor * len(in)
+		bufferIn := this.bufferIn
+
+		/*
+		 * Ensure that the oversampled input buffer has sufficient
+		 * size.
+		 */
+		if len(bufferIn) != numSamples {
+			bufferIn = make([]float64, numSamples)
+			this.bufferIn = bufferIn
+		}
+
+		bufferOut := this.bufferOut
+
+		/*
+		 * Ensure that the oversampled output buffer has sufficient
+		 * size.
+		 */
+		if len(bufferOut) != numSamples {
+			bufferOut = make([]float64, numSamples)
+			this.bufferOut = bufferOut
+		}
+
+		oversampler := this.oversamplerTwo
+
+		/*
+		 * Check oversampling factor.
+		 */
+		if factor == 4 {
+			oversampler = this.oversamplerFour
+		} else if factor == 8 {
+			oversampler = this.oversamplerEight
+		}
+
+		oversampler.Oversample(in, bufferIn)
+		factor32 := uint32(factor)
+		oversampledRate := factor32 * sampleRate
+		this.processOversampled(bufferIn, bufferOut, oversampledRate)
+		oversampler.Decimate(bufferOut, out)
+	} else {
+		this.processOversampled(in, out, sampleRate)
+	}
+
+	this.processTone(out, out, sampleRate)
+}
+
+/*
+ * Create an amp model effects unit.
+ */
+func createAmpModel() Unit {
+	oversamplerTwo := oversampling.CreateOversamplerDecimator(2)
+	oversamplerFour := oversampling.CreateOversamplerDecimator(4)
+	oversamplerEight := oversampling.CreateOversamplerDecimator(8)
+
+	/*
+	 * Create effects unit.
+	 */
+	u := ampModel{
+		unitStruct: unitStruct{
+			unitType: UNIT_AMP_MODEL,
+			params: []Parameter{
+				Parameter{
+					Name:               "model",
+					Type:               PARAMETER_TYPE_DISCRETE,
+					PhysicalUnit:       "",
+					Minimum:            -1,
+					Maximum:            -1,
+					NumericValue:       -1,
+					DiscreteValueIndex: 0,
+					DiscreteValues: []string{
+						"Fender",
+						"Marshall",
+						"Vox",
+					},
+				},
+				Parameter{
+					Name:               "stages",
+					Type:               PARAMETER_TYPE_DISCRETE,
+					PhysicalUnit:       "",
+					Minimum:            -1,
+					Maximum:            -1,
+					NumericValue:       -1,
+					DiscreteValueIndex: 2,
+					DiscreteValues: []string{
+						"1",
+						"2",
+						"3",
+						"4",
+					},
+				},
+				Parameter{
+					Name:               "gain",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            0,
+					Maximum:            60,
+					NumericValue:       20,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "bass",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       50,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "middle",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       50,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "treble",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       50,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "presence",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       0,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "resonance",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       0,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "level",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            -30,
+					Maximum:            0,
+					NumericValue:       -6,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "oversampling",
+					Type:               PARAMETER_TYPE_DISCRETE,
+					PhysicalUnit:       "",
+					Minimum:            -1,
+					Maximum:            -1,
+					NumericValue:       -1,
+					DiscreteValueIndex: 1,
+					DiscreteValues: []string{
+						"- NONE -",
+						"2",
+						"4",
+						"8",
+					},
+				},
+			},
+		},
+		oversamplerTwo:   oversamplerTwo,
+		oversamplerFour:  oversamplerFour,
+		oversamplerEight: oversamplerEight,
+	}
+
+	return &u
+}
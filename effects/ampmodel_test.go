@@ -0,0 +1,174 @@
+package effects
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+/*
+ * Returns the magnitude of the response of a digital filter at a certain
+ * frequency.
+ */
+func filterResponse(b []float64, a []float64, frequency float64, sampleRate uint32) float64 {
+	sampleRateFloat := float64(sampleRate)
+	arg := (-MATH_TWO_PI * frequency) / sampleRateFloat
+	z := cmplx.Exp(complex(0.0, arg))
+	num := complex(0.0, 0.0)
+	den := complex(0.0, 0.0)
+	zk := complex(1.0, 0.0)
+
+	/*
+	 * Evaluate the transfer function.
+	 */
+	for k := range b {
+		num += complex(b[k], 0.0) * zk
+		den += complex(a[k], 0.0) * zk
+		zk *= z
+	}
+
+	result := cmplx.Abs(num / den)
+	return result
+}
+
+/*
+ * Verify that the digital tone stack follows the response of the analog one
+ * and that it cuts the middle frequencies when the middle control is turned
+ * down.
+ */
+func TestToneStack(t *testing.T) {
+	models := []string{"Fender", "Marshall", "Vox"}
+	frequency := 1000.0
+
+	/*
+	 * Check each amp model.
+	 */
+	for _, model := range models {
+		components := ampModelComponents(model)
+		bUp, aUp := toneStackCoefficients(components, 0.5, 1.0, 0.5, TEST_SAMPLE_RATE)
+		up := filterResponse(bUp, aUp, frequency, TEST_SAMPLE_RATE)
+		bDown, aDown := toneStackCoefficients(components, 0.5, 0.0, 0.5, TEST_SAMPLE_RATE)
+		down := filterResponse(bDown, aDown, frequency, TEST_SAMPLE_RATE)
+
+		/*
+		 * The middle control must cut the middle frequencies.
+		 */
+		if down >= up {
+			t.Errorf("Model '%s': Response at %f Hz should drop below %f, but is %f.", model, frequency, up, down)
+		}
+
+	}
+
+	components := ampModelComponents("Fender")
+	b, a := toneStackCoefficients(components, 1.0, 1.0, 1.0, TEST_SAMPLE_RATE)
+	response := filterResponse(b, a, frequency, TEST_SAMPLE_RATE)
+	digital := factorToDecibels(response)
+	expected := -7.1
+
+	/*
+	 * With all controls fully up, the analog tone stack of a Fender
+	 * Bassman attenuates 1 kHz by about 7.1 dB.
+	 */
+	if math.Abs(digital-expected) > 0.1 {
+		t.Errorf("Response at %f Hz should be %f dB, but is %f dB.", frequency, expected, digital)
+	}
+
+}
+
+/*
+ * Verify that the amp model stays within range for a loud signal, does not
+ * add an offset and keeps silence silent.
+ */
+func TestAmpModel(t *testing.T) {
+	oversampling := []string{"- NONE -", "2", "4", "8"}
+	n := 8192
+
+	/*
+	 * Check each oversampling factor.
+	 */
+	for _, factor := range oversampling {
+		u := CreateUnit(UNIT_AMP_MODEL)
+		u.SetDiscreteValue("oversampling", factor)
+		u.SetDiscreteValue("stages", "4")
+		u.SetNumericValue("gain", 60)
+		u.SetNumericValue("presence", 100)
+		u.SetNumericValue("resonance", 100)
+		u.SetNumericValue("level", 0)
+		in := make([]float64, n)
+		out := make([]float64, n)
+		nFloat := float64(n)
+
+		/*
+		 * Generate a sine wave with ten periods in each half of the
+		 * signal.
+		 */
+		for i := range in {
+			iFloat := float64(i)
+			arg := (MATH_TWO_PI * 20.0 * iFloat) / nFloat
+			in[i] = math.Sin(arg)
+		}
+
+		u.Process(in, out, TEST_SAMPLE_RATE)
+		sum := float64(0.0)
+		peak := float64(0.0)
+
+		/*
+		 * Check each sample of the second half, after the filters
+		 * settled.
+		 */
+		for i := n / 2; i < n; i++ {
+			sample := out[i]
+
+			/*
+			 * Check if sample is within range.
+			 */
+			if math.Abs(sample) > 1.0 {
+				t.Errorf("Oversampling %s: Sample %d should be within range, but is %f.", factor, i, sample)
+				break
+			}
+
+			sum += sample
+			peak = math.Max(peak, math.Abs(sample))
+		}
+
+		halfFloat := float64(n / 2)
+		mean := sum / halfFloat
+
+		/*
+		 * The signal must be audible.
+		 */
+		if peak < 0.1 {
+			t.Errorf("Oversampling %s: Peak should be at least %f, but is %f.", factor, 0.1, peak)
+		}
+
+		/*
+		 * The coupling capacitors remove the offset of the asymmetric
+		 * stages.
+		 */
+		if math.Abs(mean) > 0.01 {
+			t.Errorf("Oversampling %s: Mean should be %f, but is %f.", factor, 0.0, mean)
+		}
+
+		silence := make([]float64, n)
+		u = CreateUnit(UNIT_AMP_MODEL)
+		u.SetDiscreteValue("oversampling", factor)
+		u.Process(silence, out, TEST_SAMPLE_RATE)
+
+		/*
+		 * Silence must stay silent.
+		 */
+		for i, sample := range out {
+
+			/*
+			 * Check if we found a significant difference.
+			 */
+			if math.Abs(sample) > 1e-9 {
+				t.Errorf("Oversampling %s: Sample %d of silence should be %f, but is %f.", factor, i, 0.0, sample)
+				break
+			}
+
+		}
+
+	}
+
+}
@@ -2,6 +2,7 @@ package effects
 
 import (
 	"math"
+	"sync/atomic"
 )
 
 /*
@@ -10,21 +11,35 @@ import (
 type autowah struct {
 	unitStruct
 	envelope            float64
+	phase               float64
+	tempo               uint32
 	highpassCapVoltages [NUM_FILTERS]float64
 	lowpassCapVoltages  [NUM_FILTERS]float64
 }
 
+/*
+ * Sets the tempo (in beats per minute) the modulation synchronizes to.
+ */
+func (this *autowah) SetTempo(bpm uint32) {
+	atomic.StoreUint32(&this.tempo, bpm)
+}
+
 /*
  * Auto wah audio processing.
+ *
+ * The filter either follows the level or envelope of the signal, or is swept
+ * between both frequencies by a low-frequency oscillator.
  */
 func (this *autowah) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	follow, _ := this.getDiscreteValue("follow")
-	levelA, _ := this.getNumericValue("level_1")
-	levelB, _ := this.getNumericValue("level_2")
-	frequencyA, _ := this.getNumericValue("frequency_1")
-	frequencyB, _ := this.getNumericValue("frequency_2")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	follow, _ := params.discreteValue("follow")
+	levelA, _ := params.numericValue("level_1")
+	levelB, _ := params.numericValue("level_2")
+	frequencyA, _ := params.numericValue("frequency_1")
+	frequencyB, _ := params.numericValue("frequency_2")
+	speed, _ := params.numericValue("speed")
+	sync, _ := params.discreteValue("sync")
+	tempo := atomic.LoadUint32(&this.tempo)
 
 	/*
 	 * If the first level is higher than the second, swap both levels and frequencies around.
@@ -43,7 +58,20 @@ func (this *autowah) Process(in []float64, out []float64, sampleRate uint32) {
 	dischargePerSampleEnvelopeArg := -20.0 / sampleRateFloat
 	dischargePerSampleEnvelopeInv := math.Exp(dischargePerSampleEnvelopeArg)
 	dischargePerSampleEnvelope := 1.0 - dischargePerSampleEnvelopeInv
+	speedFloat := float64(speed)
+	speedValue := 0.1 * speedFloat
+	syncedFrequency := noteValueToFrequency(sync, tempo)
+
+	/*
+	 * If the modulation is synchronized to the tempo, it ignores the speed.
+	 */
+	if syncedFrequency > 0.0 {
+		speedValue = syncedFrequency
+	}
+
+	phaseIncrement := (MATH_TWO_PI * speedValue) / sampleRateFloat
 	envelope := this.envelope
+	phase := this.phase
 	hcvs := this.highpassCapVoltages
 	lcvs := this.lowpassCapVoltages
 	gainCompensation := math.Pow(2.0, NUM_FILTERS)
@@ -72,6 +100,10 @@ func (this *autowah) Process(in []float64, out []float64, sampleRate uint32) {
 		case "level":
 			diff := sampleAbs - envelope
 			envelope += diff * dischargePerSampleEnvelope
+		case "lfo":
+			phaseChanged := phase + phaseIncrement
+			phase = math.Mod(phaseChanged, MATH_TWO_PI)
+			envelope = 1.0
 		default:
 			envelope = 1.0
 		}
@@ -81,9 +113,13 @@ func (this *autowah) Process(in []float64, out []float64, sampleRate uint32) {
 
 		/*
 		 * Calculate the current limit frequency of the filter as a piecewise
-		 * linear function of the signal level.
+		 * linear function of the signal level, unless it is swept by the
+		 * oscillator.
 		 */
-		if level <= levelAFloat {
+		if follow == "lfo" {
+			sweep := 0.5 - (0.5 * math.Cos(phase))
+			frequency = frequencyAFloat + (sweep * (frequencyBFloat - frequencyAFloat))
+		} else if level <= levelAFloat {
 			frequency = frequencyAFloat
 		} else if level >= levelBFloat {
 			frequency = frequencyBFloat
@@ -92,25 +128,20 @@ func (this *autowah) Process(in []float64, out []float64, sampleRate uint32) {
 			frequency = frequencyAFloat + (frequencySlope * excess)
 		}
 
-		arg := -frequency / sampleRateFloat
-		dischargePerSampleInv := 1.0 - math.Exp(arg)
-		lcv := sample
+		limitFrequency := frequency / MATH_TWO_PI
+		factor := trapezoidalFactor(limitFrequency, sampleRate)
+		stage := sample
 
 		/*
 		 * Evaluate the response of all filters.
 		 */
 		for j := 0; j < NUM_FILTERS; j++ {
-			hcv := hcvs[j]
-			diff := lcv - hcv
-			hcv += diff * dischargePerSampleInv
-			hcvs[j] = hcv
-			lcv = lcvs[j]
-			diff -= lcv
-			lcv += diff * dischargePerSampleInv
-			lcvs[j] = lcv
+			lowpass := trapezoidalLowpass(stage, &hcvs[j], factor)
+			highpass := stage - lowpass
+			stage = trapezoidalLowpass(highpass, &lcvs[j], factor)
 		}
 
-		pre := gainCompensation * lcv
+		pre := gainCompensation * stage
 
 		/*
 		 * Limit the output signal to the appropriate range.
@@ -125,6 +156,7 @@ func (this *autowah) Process(in []float64, out []float64, sampleRate uint32) {
 	}
 
 	this.envelope = envelope
+	this.phase = phase
 	this.highpassCapVoltages = hcvs
 	this.lowpassCapVoltages = lcvs
 }
@@ -152,6 +184,7 @@ func createAutoWah() Unit {
 					DiscreteValues: []string{
 						"envelope",
 						"level",
+						"lfo",
 					},
 				},
 				Parameter{
@@ -194,6 +227,17 @@ func createAutoWah() Unit {
 					DiscreteValueIndex: -1,
 					DiscreteValues:     nil,
 				},
+				Parameter{
+					Name:               "speed",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "0.1 Hz",
+					Minimum:            1,
+					Maximum:            100,
+					NumericValue:       10,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				createSyncParameter(),
 			},
 		},
 	}
@@ -17,12 +17,11 @@ type autoyoy struct {
  * Auto wah audio processing.
  */
 func (this *autoyoy) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	follow, _ := this.getDiscreteValue("follow")
-	levelA, _ := this.getNumericValue("level_1")
-	levelB, _ := this.getNumericValue("level_2")
-	depth, _ := this.getNumericValue("depth")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	follow, _ := params.discreteValue("follow")
+	levelA, _ := params.numericValue("level_1")
+	levelB, _ := params.numericValue("level_2")
+	depth, _ := params.numericValue("depth")
 	depthFloat := float64(depth)
 	depthA := float64(0.0)
 	depthB := 0.01 * depthFloat
@@ -1,7 +1,6 @@
 package effects
 
 import (
-	"math"
 	"strconv"
 )
 
@@ -18,11 +17,10 @@ type bandpass struct {
  * Bandpass audio processing.
  */
 func (this *bandpass) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	filterOrderString, _ := this.getDiscreteValue("filter_order")
-	frequencyA, _ := this.getNumericValue("frequency_1")
-	frequencyB, _ := this.getNumericValue("frequency_2")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	filterOrderString, _ := params.discreteValue("filter_order")
+	frequencyA, _ := params.numericValue("frequency_1")
+	frequencyB, _ := params.numericValue("frequency_2")
 	filterOrder, _ := strconv.ParseUint(filterOrderString, 10, 32)
 	halfOrderUint := filterOrder >> 1
 	halfOrder := int(halfOrderUint)
@@ -48,16 +46,10 @@ func (this *bandpass) Process(in []float64, out []float64, sampleRate uint32) {
 		this.lowpassCapVoltages = make([]float64, halfOrder)
 	}
 
-	sampleRateFloat := float64(sampleRate)
-	minusTwoPiOverSampleRate := -MATH_TWO_PI / sampleRateFloat
 	frequencyAFloat := float64(frequencyA)
 	frequencyBFloat := float64(frequencyB)
-	dischargePerSampleHPArg := minusTwoPiOverSampleRate * frequencyAFloat
-	dischargePerSampleHP := math.Exp(dischargePerSampleHPArg)
-	dischargePerSampleHPInv := 1.0 - dischargePerSampleHP
-	dischargePerSampleLPArg := minusTwoPiOverSampleRate * frequencyBFloat
-	dischargePerSampleLP := math.Exp(dischargePerSampleLPArg)
-	dischargePerSampleLPInv := 1.0 - dischargePerSampleLP
+	dischargePerSampleHPInv := onePoleFactor(frequencyAFloat, sampleRate)
+	dischargePerSampleLPInv := onePoleFactor(frequencyBFloat, sampleRate)
 
 	/*
 	 * Process each sample.
@@ -1,9 +1,5 @@
 package effects
 
-import (
-	"math"
-)
-
 const (
 	NUM_HIGHPASS_FILTERS = 3
 	NUM_LOWPASS_FILTERS  = 4
@@ -96,17 +92,13 @@ func (this *cabinet) Process(in []float64, out []float64, sampleRate uint32) {
 	}
 
 	copy(buffer, in)
-	sampleRateFloat := float64(sampleRate)
-	minusTwoPiOverSampleRate := -MATH_TWO_PI / sampleRateFloat
 
 	/*
 	 * Process all highpass filters.
 	 */
 	for i, f := range highpassLimitFrequencies {
 		hcv := this.highpassCapVoltages[i]
-		dischargePerSampleArg := minusTwoPiOverSampleRate * f
-		dischargePerSample := math.Exp(dischargePerSampleArg)
-		dischargePerSampleInv := 1.0 - dischargePerSample
+		dischargePerSampleInv := onePoleFactor(f, sampleRate)
 
 		/*
 		 * Process each sample.
@@ -125,9 +117,7 @@ func (this *cabinet) Process(in []float64, out []float64, sampleRate uint32) {
 	 */
 	for i, f := range lowpassLimitFrequencies {
 		lcv := this.lowpassCapVoltages[i]
-		dischargePerSampleArg := minusTwoPiOverSampleRate * f
-		dischargePerSample := math.Exp(dischargePerSampleArg)
-		dischargePerSampleInv := 1.0 - dischargePerSample
+		dischargePerSampleInv := onePoleFactor(f, sampleRate)
 
 		/*
 		 * Process each sample.
@@ -2,6 +2,7 @@ package effects
 
 import (
 	"math"
+	"sync/atomic"
 )
 
 /*
@@ -10,52 +11,24 @@ import (
 type chorus struct {
 	unitStruct
 	buffer        []float64
+	bufferRight   []float64
 	previousPhase float64
+	tempo         uint32
 }
 
 /*
- * Chorus audio processing.
+ * Processes a single channel of a chorus effect.
  */
-func (this *chorus) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	depth, _ := this.getNumericValue("depth")
-	speed, _ := this.getNumericValue("speed")
-	this.mutex.RUnlock()
-	depthFloat := 0.1 * float64(depth)
-
-	/*
-	 * Limit depth to [0.0; 10.0].
-	 */
-	if depthFloat < 0.0 {
-		depthFloat = 0.0
-	} else if depthFloat > 10.0 {
-		depthFloat = 10.0
-	}
-
-	speedFloat := float64(speed)
-	angularSpeed := MATH_PI_THOUSANDTH * speedFloat
-	sampleRateFloat := float64(sampleRate)
-	maxDelaySamplesFloat := math.Floor((0.05 * sampleRateFloat) + 0.5)
-	maxDelaySamples := int(maxDelaySamplesFloat)
-	buffer := this.buffer
+func (this *chorus) processChannel(in []float64, out []float64, buffer []float64, phaseOffset float64, depth float64, angularSpeed float64, sampleRate float64) {
 	bufferSize := len(buffer)
-	previousPhase := this.previousPhase
-
-	/*
-	 * Make sure the buffer has the appropriate size.
-	 */
-	if bufferSize != maxDelaySamples {
-		buffer = make([]float64, maxDelaySamples)
-		this.buffer = buffer
-		bufferSize = maxDelaySamples
-	}
+	previousPhase := this.previousPhase + phaseOffset
 
 	/*
 	 * Process each sample.
 	 */
 	for i, sample := range in {
 		iFloat := float64(i)
-		time := iFloat / sampleRateFloat
+		time := iFloat / sampleRate
 		phaseChange := angularSpeed * time
 		phaseChanged := previousPhase + phaseChange
 		zeroPhase := math.Mod(phaseChanged, MATH_TWO_PI)
@@ -66,12 +39,12 @@ func (this *chorus) Process(in []float64, out []float64, sampleRate uint32) {
 		 */
 		for j := 0; j < 5; j++ {
 			jFloat := float64(j)
-			phaseOffset := MATH_TWO_PI_FIFTH * jFloat
-			updatedPhase := zeroPhase + phaseOffset
+			subSignalOffset := MATH_TWO_PI_FIFTH * jFloat
+			updatedPhase := zeroPhase + subSignalOffset
 			phase := math.Mod(updatedPhase, MATH_TWO_PI)
-			offset := depthFloat * math.Sin(phase)
+			offset := depth * math.Sin(phase)
 			currentDelayTime := 0.001 * (40.0 + offset)
-			currentDelaySamples := currentDelayTime * sampleRateFloat
+			currentDelaySamples := currentDelayTime * sampleRate
 			currentDelaySamplesEarly := math.Floor(currentDelaySamples)
 			currentDelaySamplesEarlyInt := int(currentDelaySamplesEarly)
 			currentDelaySamplesLate := math.Ceil(currentDelaySamples)
@@ -100,7 +73,7 @@ func (this *chorus) Process(in []float64, out []float64, sampleRate uint32) {
 				delayedSampleLate = in[delayedIdxLate]
 			} else {
 				bufferPtr := bufferSize + delayedIdxLate
-				delayedSampleLate = this.buffer[bufferPtr]
+				delayedSampleLate = buffer[bufferPtr]
 			}
 
 			weightEarly := 1.0 - (currentDelaySamples - currentDelaySamplesEarly)
@@ -111,11 +84,6 @@ func (this *chorus) Process(in []float64, out []float64, sampleRate uint32) {
 		out[i] = (0.5 * sample) + (0.5 * effectedSample)
 	}
 
-	bufferSizeFloat := float64(bufferSize)
-	bufferTime := bufferSizeFloat / sampleRateFloat
-	phaseChange := angularSpeed * bufferTime
-	phaseChanged := previousPhase + phaseChange
-	this.previousPhase = math.Mod(phaseChanged, MATH_TWO_PI)
 	numSamples := len(in)
 	boundary := bufferSize - numSamples
 
@@ -131,6 +99,106 @@ func (this *chorus) Process(in []float64, out []float64, sampleRate uint32) {
 
 }
 
+/*
+ * Sets the tempo (in beats per minute) the modulation synchronizes to.
+ */
+func (this *chorus) SetTempo(bpm uint32) {
+	atomic.StoreUint32(&this.tempo, bpm)
+}
+
+/*
+ * Reads the parameters of a chorus effect and makes sure that the delay
+ * buffers have the appropriate size.
+ */
+func (this *chorus) prepare(sampleRate uint32) (float64, float64) {
+	params := this.processingParameters()
+	depth, _ := params.numericValue("depth")
+	speed, _ := params.numericValue("speed")
+	sync, _ := params.discreteValue("sync")
+	tempo := atomic.LoadUint32(&this.tempo)
+	depthFloat := 0.1 * float64(depth)
+
+	/*
+	 * Limit depth to [0.0; 10.0].
+	 */
+	if depthFloat < 0.0 {
+		depthFloat = 0.0
+	} else if depthFloat > 10.0 {
+		depthFloat = 10.0
+	}
+
+	speedFloat := float64(speed)
+	angularSpeed := MATH_PI_THOUSANDTH * speedFloat
+	syncedFrequency := noteValueToFrequency(sync, tempo)
+
+	/*
+	 * If the modulation is synchronized to the tempo, it ignores the speed.
+	 */
+	if syncedFrequency > 0.0 {
+		angularSpeed = MATH_TWO_PI * syncedFrequency
+	}
+
+	sampleRateFloat := float64(sampleRate)
+	maxDelaySamplesFloat := math.Floor((0.05 * sampleRateFloat) + 0.5)
+	maxDelaySamples := int(maxDelaySamplesFloat)
+
+	/*
+	 * Make sure the buffer has the appropriate size.
+	 */
+	if len(this.buffer) != maxDelaySamples {
+		this.buffer = make([]float64, maxDelaySamples)
+	}
+
+	/*
+	 * Make sure the buffer for the right channel has the appropriate size.
+	 */
+	if len(this.bufferRight) != maxDelaySamples {
+		this.bufferRight = make([]float64, maxDelaySamples)
+	}
+
+	return depthFloat, angularSpeed
+}
+
+/*
+ * Advances the phase of the chorus modulation by the duration of a block of
+ * samples.
+ */
+func (this *chorus) advancePhase(angularSpeed float64, numSamples int, sampleRate uint32) {
+	numSamplesFloat := float64(numSamples)
+	sampleRateFloat := float64(sampleRate)
+	blockTime := numSamplesFloat / sampleRateFloat
+	phaseChange := angularSpeed * blockTime
+	phaseChanged := this.previousPhase + phaseChange
+	this.previousPhase = math.Mod(phaseChanged, MATH_TWO_PI)
+}
+
+/*
+ * Chorus audio processing.
+ */
+func (this *chorus) Process(in []float64, out []float64, sampleRate uint32) {
+	depth, angularSpeed := this.prepare(sampleRate)
+	sampleRateFloat := float64(sampleRate)
+	buffer := this.buffer
+	this.processChannel(in, out, buffer, 0.0, depth, angularSpeed, sampleRateFloat)
+	numSamples := len(in)
+	this.advancePhase(angularSpeed, numSamples, sampleRate)
+}
+
+/*
+ * Chorus audio processing for a stereo signal. The modulation of the right
+ * channel runs in opposite phase to widen the stereo image.
+ */
+func (this *chorus) ProcessStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32) {
+	depth, angularSpeed := this.prepare(sampleRate)
+	sampleRateFloat := float64(sampleRate)
+	buffer := this.buffer
+	this.processChannel(inLeft, outLeft, buffer, 0.0, depth, angularSpeed, sampleRateFloat)
+	bufferRight := this.bufferRight
+	this.processChannel(inRight, outRight, bufferRight, math.Pi, depth, angularSpeed, sampleRateFloat)
+	numSamples := len(inLeft)
+	this.advancePhase(angularSpeed, numSamples, sampleRate)
+}
+
 /*
  * Create a chorus effects unit.
  */
@@ -163,6 +231,7 @@ func createChorus() Unit {
 					DiscreteValueIndex: -1,
 					DiscreteValues:     nil,
 				},
+				createSyncParameter(),
 			},
 		},
 	}
@@ -16,11 +16,10 @@ type compressor struct {
  * Compressor audio processing.
  */
 func (this *compressor) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	follow, _ := this.getDiscreteValue("follow")
-	gainLimit, _ := this.getNumericValue("gain_limit")
-	targetLevel, _ := this.getNumericValue("target_level")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	follow, _ := params.discreteValue("follow")
+	gainLimit, _ := params.numericValue("gain_limit")
+	targetLevel, _ := params.numericValue("target_level")
 	gainLimitFac := decibelsToFactor(gainLimit)
 	targetLevelFac := decibelsToFactor(targetLevel)
 	sampleRateFloat := float64(sampleRate)
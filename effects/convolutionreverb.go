@@ -0,0 +1,483 @@
+package effects
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/filter"
+	"math"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	CONVOLUTION_REVERB_STEREO_SPREAD = 0.0007
+)
+
+/*
+ * Data structure representing a convolution reverb.
+ *
+ * In a stereo chain, the right channel is convolved with the same impulse
+ * response, delayed by a fraction of a millisecond to decorrelate both sides.
+ */
+type convolutionReverb struct {
+	unitStruct
+	sampleRate         uint32
+	blockSize          uint32
+	impulseResponses   filter.ImpulseResponses
+	currentFilter      filter.Filter
+	currentFilterRight filter.Filter
+	wetBuffer          []float64
+	wetBufferRight     []float64
+}
+
+/*
+ * Compile new filters for the left and right channel of this convolution
+ * reverb.
+ */
+func (this *convolutionReverb) compile(sampleRate uint32) (filter.Filter, filter.Filter, error) {
+	irs := this.impulseResponses
+
+	/*
+	 * Verify that impulse responses are loaded.
+	 */
+	if irs == nil {
+		return nil, nil, fmt.Errorf("%s", "Could not compile filter: No impulse responses were loaded.")
+	} else {
+		name, errName := this.getDiscreteValue("impulse_response")
+		preDelay, errPreDelay := this.getNumericValue("pre_delay")
+		decayTrim, errDecayTrim := this.getNumericValue("decay_trim")
+
+		/*
+		 * Check if an error occured.
+		 */
+		if errName != nil || errPreDelay != nil || errDecayTrim != nil {
+			return nil, nil, fmt.Errorf("%s", "Error parsing values for convolution reverb.")
+		} else if name == STRING_NONE {
+			flt := filter.Empty(sampleRate)
+			fltRight := filter.Empty(sampleRate)
+			return flt, fltRight, nil
+		} else {
+			flt := irs.CreateFilter(name, sampleRate)
+
+			/*
+			 * Check if filter was found.
+			 */
+			if flt == nil {
+				return nil, nil, fmt.Errorf("Failed to load filter '%s' for sample rate '%d'.", name, sampleRate)
+			} else {
+				flt = flt.Normalize()
+				coeffs := flt.Coefficients()
+				numCoeffs := len(coeffs)
+				numCoeffsFloat := float64(numCoeffs)
+				decayTrimFloat := float64(decayTrim)
+				keepFloat := math.Ceil(0.01 * decayTrimFloat * numCoeffsFloat)
+				keep := int(keepFloat)
+
+				/*
+				 * Make sure that we do not keep more coefficients than we have.
+				 */
+				if keep > numCoeffs {
+					keep = numCoeffs
+				}
+
+				/*
+				 * If the impulse response gets trimmed, fade out its tail
+				 * to avoid an abrupt cut-off.
+				 */
+				if keep < numCoeffs {
+					fadeLength := keep / 10
+					fadeStart := keep - fadeLength
+					fadeLengthFloat := float64(fadeLength)
+
+					/*
+					 * Apply a raised-cosine window to the end of the
+					 * impulse response.
+					 */
+					for i := 0; i < fadeLength; i++ {
+						iFloat := float64(i)
+						arg := (math.Pi * iFloat) / fadeLengthFloat
+						fac := 0.5 * (1.0 + math.Cos(arg))
+						idx := fadeStart + i
+						coeffs[idx] *= fac
+					}
+
+				}
+
+				preDelayFloat := float64(preDelay)
+				sampleRateFloat := float64(sampleRate)
+				preDelaySamplesFloat := math.Round(0.001 * preDelayFloat * sampleRateFloat)
+				preDelaySamples := int(preDelaySamplesFloat)
+				numCoeffsResult := preDelaySamples + keep
+				coeffsResult := make([]float64, numCoeffsResult)
+				copy(coeffsResult[preDelaySamples:], coeffs[:keep])
+				fltResult := filter.FromCoefficients(coeffsResult, sampleRate, name)
+				spreadSamplesFloat := math.Round(CONVOLUTION_REVERB_STEREO_SPREAD * sampleRateFloat)
+				spreadSamples := int(spreadSamplesFloat)
+				numCoeffsRight := spreadSamples + numCoeffsResult
+				coeffsRight := make([]float64, numCoeffsRight)
+				copy(coeffsRight[spreadSamples:], coeffsResult)
+				fltRight := filter.FromCoefficients(coeffsRight, sampleRate, name)
+				blockSize := int(this.blockSize)
+				fltResult.Prepare(blockSize)
+				fltRight.Prepare(blockSize)
+				return fltResult, fltRight, nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Compile new filters and replace the current ones if this succeeded.
+ *
+ * Must be called with the mutex held.
+ */
+func (this *convolutionReverb) recompile(sampleRate uint32) error {
+	flt, fltRight, err := this.compile(sampleRate)
+
+	/*
+	 * Check if filters were compiled.
+	 */
+	if err == nil {
+		this.currentFilter = flt
+		this.currentFilterRight = fltRight
+	}
+
+	return err
+}
+
+/*
+ * Sets a discrete parameter value for a convolution reverb.
+ */
+func (this *convolutionReverb) SetDiscreteValue(name string, value string) error {
+	this.mutex.Lock()
+	err := this.unitStruct.setDiscreteValue(name, value)
+	sr := this.sampleRate
+
+	/*
+	 * If value was set and the sample rate is known, recompile filter.
+	 */
+	if err == nil && sr != 0 {
+		err = this.recompile(sr)
+	}
+
+	this.mutex.Unlock()
+	return err
+}
+
+/*
+ * Sets a numeric parameter value for a convolution reverb.
+ */
+func (this *convolutionReverb) SetNumericValue(name string, value int32) error {
+	this.mutex.Lock()
+	err := this.unitStruct.setNumericValue(name, value)
+
+	sr := this.sampleRate
+
+	/*
+	 * If a parameter affecting the filter was changed and the sample rate
+	 * is known, recompile it.
+	 */
+	if err == nil && name != "mix" && sr != 0 {
+		err = this.recompile(sr)
+	}
+
+	this.mutex.Unlock()
+	return err
+}
+
+/*
+ * Sets the number of frames the convolution reverb processes at once and
+ * prepares its filters for it.
+ *
+ * The filters are prepared outside of the lock and swapped in once they are
+ * ready.
+ */
+func (this *convolutionReverb) SetBlockSize(frames uint32) {
+	this.mutex.Lock()
+	changed := (frames != this.blockSize)
+	this.blockSize = frames
+	flt := this.currentFilter
+	fltRight := this.currentFilterRight
+	this.mutex.Unlock()
+
+	/*
+	 * Only prepare the filters again if the block size changed.
+	 */
+	if changed {
+		prepared := prepareFilter(flt, frames)
+		preparedRight := prepareFilter(fltRight, frames)
+		this.mutex.Lock()
+
+		/*
+		 * Only replace the filters if they were not recompiled in
+		 * the meantime.
+		 */
+		if (this.currentFilter == flt) && (this.currentFilterRight == fltRight) {
+			this.currentFilter = prepared
+			this.currentFilterRight = preparedRight
+		}
+
+		this.mutex.Unlock()
+	}
+
+}
+
+/*
+ * Recompile the filters if the sample rate changed and return the current
+ * filters and the fraction of the wet signal.
+ */
+func (this *convolutionReverb) prepare(sampleRate uint32) (filter.Filter, filter.Filter, float64) {
+
+	/*
+	 * Check if sampling rate changed.
+	 */
+	if sampleRate != this.sampleRate {
+		this.mutex.Lock()
+		this.sampleRate = sampleRate
+		this.recompile(sampleRate)
+		this.mutex.Unlock()
+	}
+
+	params := this.processingParameters()
+	mix, _ := params.numericValue("mix")
+	this.mutex.RLock()
+	flt := this.currentFilter
+	fltRight := this.currentFilterRight
+	this.mutex.RUnlock()
+	mixFloat := float64(mix)
+	wetFrac := 0.01 * mixFloat
+	return flt, fltRight, wetFrac
+}
+
+/*
+ * Put a signal through a filter and mix the dry and wet signal.
+ */
+func (this *convolutionReverb) render(in []float64, out []float64, flt filter.Filter, wetBuffer []float64, wetFrac float64) {
+	dryFrac := 1.0 - wetFrac
+
+	/*
+	 * If there is a filter, put the signal through it, otherwise the
+	 * wet signal is silent.
+	 */
+	if flt != nil {
+		flt.Process(in, wetBuffer)
+	} else {
+
+		/*
+		 * Write zeros to wet buffer.
+		 */
+		for i := range wetBuffer {
+			wetBuffer[i] = 0.0
+		}
+
+	}
+
+	/*
+	 * Mix the dry and wet signal.
+	 */
+	for i, drySample := range in {
+		wetSample := wetBuffer[i]
+		pre := (dryFrac * drySample) + (wetFrac * wetSample)
+
+		/*
+		 * Limit the output signal to the appropriate range.
+		 */
+		if pre < -1.0 {
+			out[i] = -1.0
+		} else if pre > 1.0 {
+			out[i] = 1.0
+		} else {
+			out[i] = pre
+		}
+
+	}
+
+}
+
+/*
+ * Convolution reverb audio processing.
+ */
+func (this *convolutionReverb) Process(in []float64, out []float64, sampleRate uint32) {
+	flt, _, wetFrac := this.prepare(sampleRate)
+	n := len(in)
+	wetBuffer := this.wetBuffer
+
+	/*
+	 * Make sure that the wet buffer is of appropriate size.
+	 */
+	if len(wetBuffer) != n {
+		wetBuffer = make([]float64, n)
+		this.wetBuffer = wetBuffer
+	}
+
+	this.render(in, out, flt, wetBuffer, wetFrac)
+}
+
+/*
+ * Convolution reverb audio processing for a stereo signal. The right channel
+ * is convolved with a slightly delayed copy of the impulse response to
+ * decorrelate both sides.
+ */
+func (this *convolutionReverb) ProcessStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32) {
+	n := len(inLeft)
+
+	/*
+	 * Ensure that all buffers are of equal size.
+	 */
+	if (len(inRight) != n) || (len(outLeft) != n) || (len(outRight) != n) {
+
+		/*
+		 * Write zeros to left output buffer.
+		 */
+		for i := range outLeft {
+			outLeft[i] = 0.0
+		}
+
+		/*
+		 * Write zeros to right output buffer.
+		 */
+		for i := range outRight {
+			outRight[i] = 0.0
+		}
+
+	} else {
+		flt, fltRight, wetFrac := this.prepare(sampleRate)
+		wetBuffer := this.wetBuffer
+		wetBufferRight := this.wetBufferRight
+
+		/*
+		 * Make sure that the wet buffers are of appropriate size.
+		 */
+		if (len(wetBuffer) != n) || (len(wetBufferRight) != n) {
+			wetBuffer = make([]float64, n)
+			wetBufferRight = make([]float64, n)
+			this.wetBuffer = wetBuffer
+			this.wetBufferRight = wetBufferRight
+		}
+
+		this.render(inLeft, outLeft, flt, wetBuffer, wetFrac)
+		this.render(inRight, outRight, fltRight, wetBufferRight, wetFrac)
+	}
+
+}
+
+/*
+ * Populate the parameters of a convolution reverb.
+ */
+func PrepareConvolutionReverb(unit Unit, responses filter.ImpulseResponses) error {
+	rev, isConvolutionReverb := unit.(*convolutionReverb)
+
+	/*
+	 * Check if the unit is a convolution reverb.
+	 */
+	if !isConvolutionReverb {
+		return fmt.Errorf("%s", "Cannot prepare convolution reverb: Unit is not a convolution reverb.")
+	} else if responses == nil {
+		return fmt.Errorf("%s", "Cannot prepare convolution reverb: Impulse responses are nil.")
+	} else {
+		names := responses.Names()
+		namesExtended := []string{STRING_NONE}
+		namesExtended = append(namesExtended, names...)
+
+		/*
+		 * Parameter for the impulse response.
+		 */
+		paramResponse := Parameter{
+			Name:               "impulse_response",
+			Type:               PARAMETER_TYPE_DISCRETE,
+			PhysicalUnit:       "",
+			Minimum:            -1,
+			Maximum:            -1,
+			NumericValue:       -1,
+			DiscreteValueIndex: 0,
+			DiscreteValues:     namesExtended,
+		}
+
+		rev.mutex.Lock()
+		params := []Parameter{paramResponse}
+		params = append(params, rev.unitStruct.params...)
+		rev.unitStruct.params = params
+		rev.unitStruct.publishParameters()
+		rev.impulseResponses = responses
+		rev.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Updates the list of impulse responses available to a convolution reverb
+ * after the collection has been reloaded.
+ */
+func (this *convolutionReverb) updateImpulseResponses(responses filter.ImpulseResponses) error {
+	names := responses.Names()
+	namesExtended := []string{STRING_NONE}
+	namesExtended = append(namesExtended, names...)
+	this.mutex.Lock()
+	this.unitStruct.replaceDiscreteValues("impulse_response", namesExtended)
+	this.impulseResponses = responses
+	sr := this.sampleRate
+	err := error(nil)
+
+	/*
+	 * Recompile filter if the sample rate is known.
+	 */
+	if sr != 0 {
+		err = this.recompile(sr)
+	}
+
+	this.mutex.Unlock()
+	return err
+}
+
+/*
+ * Create a convolution reverb effects unit.
+ */
+func createConvolutionReverb() Unit {
+
+	/*
+	 * Create effects unit.
+	 */
+	u := convolutionReverb{
+		unitStruct: unitStruct{
+			unitType: UNIT_CONVOLUTION_REVERB,
+			params: []Parameter{
+				Parameter{
+					Name:               "pre_delay",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "ms",
+					Minimum:            0,
+					Maximum:            250,
+					NumericValue:       0,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "decay_trim",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            1,
+					Maximum:            100,
+					NumericValue:       100,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "mix",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       30,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+			},
+		},
+	}
+
+	return &u
+}
@@ -0,0 +1,195 @@
+package effects
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/filter"
+	"github.com/andrepxx/go-dsp-guitar/wave"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+ * Name of the impulse response used in tests.
+ */
+const (
+	TEST_IMPULSE_RESPONSE = "Test: Delta"
+	TEST_SAMPLE_RATE      = 48000
+)
+
+/*
+ * Creates a collection holding a single impulse response with the given
+ * coefficients.
+ */
+func createTestImpulseResponses(t *testing.T, coeffs []float64) filter.ImpulseResponses {
+	dir, err := os.MkdirTemp("", "effects")
+
+	/*
+	 * Check if temporary directory was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err.Error())
+	}
+
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	file, _ := wave.CreateEmpty(TEST_SAMPLE_RATE, wave.AUDIO_IEEE_FLOAT, 32, 1)
+	channel, _ := file.Channel(0)
+	channel.WriteFloats(coeffs)
+	buffer, _ := file.Bytes()
+	wavePath := filepath.Join(dir, "delta.wav")
+	descriptorPath := filepath.Join(dir, "index.json")
+	descriptor := fmt.Sprintf("[ { \"Name\": %q, \"Path\": %q, \"Compensation\": 0 } ]", TEST_IMPULSE_RESPONSE, wavePath)
+	os.WriteFile(wavePath, buffer, 0644)
+	os.WriteFile(descriptorPath, []byte(descriptor), 0644)
+	irs, err := filter.Import(descriptorPath)
+
+	/*
+	 * Check if impulse responses were imported.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to import impulse responses: %s", err.Error())
+	}
+
+	return irs
+}
+
+/*
+ * Verify that a convolution reverb delays the right channel of a stereo
+ * signal, reports filters which fail to compile and stays finite for extreme
+ * parameters.
+ */
+func TestConvolutionReverb(t *testing.T) {
+	coeffs := make([]float64, 64)
+	coeffs[0] = 0.5
+	irs := createTestImpulseResponses(t, coeffs)
+	u := CreateUnit(UNIT_CONVOLUTION_REVERB)
+	PrepareConvolutionReverb(u, irs)
+	u.SetNumericValue("mix", 100)
+	err := u.SetDiscreteValue("impulse_response", TEST_IMPULSE_RESPONSE)
+
+	/*
+	 * The filter is only compiled once the sample rate is known.
+	 */
+	if err != nil {
+		t.Errorf("Selecting impulse response failed: %s", err.Error())
+	}
+
+	stereo := u.(StereoUnit)
+	n := 128
+	inLeft := make([]float64, n)
+	inRight := make([]float64, n)
+	outLeft := make([]float64, n)
+	outRight := make([]float64, n)
+	inLeft[0] = 0.5
+	inRight[0] = 0.5
+	stereo.ProcessStereo(inLeft, inRight, outLeft, outRight, TEST_SAMPLE_RATE)
+	spreadFloat := math.Round(CONVOLUTION_REVERB_STEREO_SPREAD * TEST_SAMPLE_RATE)
+	spread := int(spreadFloat)
+
+	/*
+	 * The normalized impulse response passes the impulse on unchanged on
+	 * the left channel and delayed on the right channel.
+	 */
+	if math.Abs(outLeft[0]-0.5) > 1e-6 {
+		t.Errorf("Left channel at sample %d should be %f, but is %f.", 0, 0.5, outLeft[0])
+	}
+
+	if math.Abs(outRight[0]) > 1e-6 {
+		t.Errorf("Right channel at sample %d should be %f, but is %f.", 0, 0.0, outRight[0])
+	}
+
+	if math.Abs(outRight[spread]-0.5) > 1e-6 {
+		t.Errorf("Right channel at sample %d should be %f, but is %f.", spread, 0.5, outRight[spread])
+	}
+
+	u.Process(inLeft, outLeft, 12345)
+	err = u.SetDiscreteValue("impulse_response", TEST_IMPULSE_RESPONSE)
+
+	/*
+	 * There is no impulse response for this sample rate.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Selecting impulse response at an unsupported sample rate should fail, but it did not.")
+	}
+
+	u.Process(inLeft, outLeft, TEST_SAMPLE_RATE)
+	u.SetNumericValue("pre_delay", 250)
+	u.SetNumericValue("decay_trim", 1)
+
+	/*
+	 * Feed full-scale signal through the reverb.
+	 */
+	for i := range inLeft {
+		inLeft[i] = 1.0
+		inRight[i] = -1.0
+	}
+
+	stereo.ProcessStereo(inLeft, inRight, outLeft, outRight, TEST_SAMPLE_RATE)
+
+	/*
+	 * The output must stay finite and within range.
+	 */
+	for i := range outLeft {
+		left := outLeft[i]
+		right := outRight[i]
+
+		/*
+		 * Check both channels.
+		 */
+		if math.IsNaN(left) || math.IsNaN(right) || math.Abs(left) > 1.0 || math.Abs(right) > 1.0 {
+			t.Errorf("Output at sample %d is out of range: (%f, %f)", i, left, right)
+		}
+
+	}
+
+}
+
+/*
+ * Verify that a convolution reverb still passes an impulse through its filter
+ * after its filters were prepared for another block size.
+ */
+func TestConvolutionReverbBlockSize(t *testing.T) {
+	coeffs := make([]float64, 64)
+	coeffs[3] = 0.5
+	irs := createTestImpulseResponses(t, coeffs)
+	u := CreateUnit(UNIT_CONVOLUTION_REVERB)
+	PrepareConvolutionReverb(u, irs)
+	u.SetNumericValue("mix", 100)
+	u.SetDiscreteValue("impulse_response", TEST_IMPULSE_RESPONSE)
+	in := make([]float64, 64)
+	out := make([]float64, 64)
+	u.Process(in, out, TEST_SAMPLE_RATE)
+	blockSizeUnit := u.(BlockSizeUnit)
+	blockSizeUnit.SetBlockSize(256)
+	in = make([]float64, 256)
+	out = make([]float64, 256)
+	in[0] = 0.5
+	u.Process(in, out, TEST_SAMPLE_RATE)
+
+	/*
+	 * The impulse must appear at the delay of the impulse response.
+	 */
+	for i, sample := range out {
+		expected := 0.0
+
+		/*
+		 * Only the delayed impulse is expected.
+		 */
+		if i == 3 {
+			expected = 0.5
+		}
+
+		/*
+		 * Check if we found a significant difference.
+		 */
+		if math.Abs(sample-expected) > 1e-6 {
+			t.Errorf("Sample %d should be %f, but is %f.", i, expected, sample)
+		}
+
+	}
+
+}
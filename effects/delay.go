@@ -4,88 +4,105 @@ import (
 	"math"
 )
 
+/*
+ * Global constants.
+ */
+const (
+	DELAY_MAX_TIME = 1000
+)
+
 /*
  * Data structure representing a delay effect.
+ *
+ * The delay time is ramped like any other parameter, so the delayed signal is
+ * read from a delay line, which can hold the maximum delay time, at a
+ * fractional position.
  */
 type delay struct {
 	unitStruct
-	buffer []float64
+	buffer       []float64
+	writePtr     int
+	delaySamples smoothedValue
+	feedback     smoothedValue
+	level        smoothedValue
+}
+
+/*
+ * Makes sure that the delay line can hold the maximum delay time.
+ */
+func (this *delay) prepareBuffer(sampleRate uint32) {
+	sampleRateFloat := float64(sampleRate)
+	maxTimeSeconds := 0.001 * float64(DELAY_MAX_TIME)
+	maxSamplesFloat := math.Ceil(maxTimeSeconds * sampleRateFloat)
+	bufferSize := int(maxSamplesFloat) + 2
+
+	/*
+	 * Make sure the buffer has the appropriate size.
+	 */
+	if len(this.buffer) != bufferSize {
+		this.buffer = make([]float64, bufferSize)
+		this.writePtr = 0
+	}
+
 }
 
 /*
  * Delay audio processing.
  */
 func (this *delay) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	delayTime, _ := this.getNumericValue("delay_time")
-	feedback, _ := this.getNumericValue("feedback")
-	level, _ := this.getNumericValue("level")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	delayTime, _ := params.numericValue("delay_time")
+	feedback, _ := params.numericValue("feedback")
+	level, _ := params.numericValue("level")
 	delayTimeFloat := float64(delayTime)
 	delayTimeSeconds := 0.001 * delayTimeFloat
 	sampleRateFloat := float64(sampleRate)
-	delaySamplesFloat := math.Floor((delayTimeSeconds * sampleRateFloat) + 0.5)
-	delaySamples := int(delaySamplesFloat)
-	feedbackFactor := decibelsToFactor(feedback)
-	levelFactor := decibelsToFactor(level)
+	delaySamplesTarget := math.Floor((delayTimeSeconds * sampleRateFloat) + 0.5)
+	feedbackTarget := decibelsToFactor(feedback)
+	levelTarget := decibelsToFactor(level)
+	this.prepareBuffer(sampleRate)
+	smoothingSamples := this.smoothingSamples(sampleRate)
+	delayRamp := &this.delaySamples
+	delayRamp.setTarget(delaySamplesTarget, smoothingSamples)
+	feedbackRamp := &this.feedback
+	feedbackRamp.setTarget(feedbackTarget, smoothingSamples)
+	levelRamp := &this.level
+	levelRamp.setTarget(levelTarget, smoothingSamples)
 	buffer := this.buffer
 	bufferSize := len(buffer)
-
-	/*
-	 * Make sure the buffer has the appropriate size.
-	 */
-	if bufferSize != delaySamples {
-		buffer = make([]float64, delaySamples)
-		bufferSize = delaySamples
-		this.buffer = buffer
-	}
+	bufferSizeFloat := float64(bufferSize)
+	writePtr := this.writePtr
 
 	/*
 	 * Mix the straight output with the delayed signal.
 	 */
 	for i, sample := range in {
-		delayedIdx := i - delaySamples
-		delayedSample := float64(0.0)
+		buffer[writePtr] = sample
+		delaySamples := delayRamp.next()
+		feedbackFactor := feedbackRamp.next()
+		levelFactor := levelRamp.next()
+		writePtrFloat := float64(writePtr)
+		readPos := writePtrFloat - delaySamples
 
 		/*
-		 * Check whether the delayed sample can be found in the current input
-		 * signal or the delay buffer.
+		 * Wrap around at the start of the delay line.
 		 */
-		if delayedIdx >= 0 {
-			delayedSample = in[delayedIdx]
-		} else {
-			bufferPtr := bufferSize + delayedIdx
-			delayedSample = buffer[bufferPtr]
+		if readPos < 0.0 {
+			readPos += bufferSizeFloat
 		}
 
+		readPosFloor := math.Floor(readPos)
+		fraction := readPos - readPosFloor
+		readPtr := int(readPosFloor) % bufferSize
+		readPtrNext := (readPtr + 1) % bufferSize
+		fractionInv := 1.0 - fraction
+		delayedSample := (fractionInv * buffer[readPtr]) + (fraction * buffer[readPtrNext])
+		writePtr = (writePtr + 1) % bufferSize
 		pre := levelFactor * (sample + (feedbackFactor * delayedSample))
-
-		/*
-		 * Limit the output signal to the appropriate range.
-		 */
-		if pre < -1.0 {
-			out[i] = -1.0
-		} else if pre > 1.0 {
-			out[i] = 1.0
-		} else {
-			out[i] = pre
-		}
-
-	}
-
-	numSamples := len(in)
-	boundary := bufferSize - numSamples
-
-	/*
-	 * Check whether our buffer is larger than the number of samples processed.
-	 */
-	if boundary >= 0 {
-		copy(buffer[0:boundary], buffer[numSamples:bufferSize])
-		copy(buffer[boundary:bufferSize], in)
-	} else {
-		copy(buffer, in[-boundary:numSamples])
+		out[i] = limitSample(pre)
 	}
 
+	this.writePtr = writePtr
 }
 
 /*
@@ -105,7 +122,7 @@ func createDelay() Unit {
 					Type:               PARAMETER_TYPE_NUMERIC,
 					PhysicalUnit:       "ms",
 					Minimum:            0,
-					Maximum:            1000,
+					Maximum:            DELAY_MAX_TIME,
 					NumericValue:       200,
 					DiscreteValueIndex: -1,
 					DiscreteValues:     nil,
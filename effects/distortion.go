@@ -9,29 +9,38 @@ import (
  */
 type distortion struct {
 	unitStruct
-	bufferIn        []float64
-	bufferOut       []float64
-	oversamplerTwo  oversampling.OversamplerDecimator
-	oversamplerFour oversampling.OversamplerDecimator
+	bufferIn         []float64
+	bufferOut        []float64
+	oversamplerTwo   oversampling.OversamplerDecimator
+	oversamplerFour  oversampling.OversamplerDecimator
+	oversamplerEight oversampling.OversamplerDecimator
+	gain             smoothedValue
+	level            smoothedValue
 }
 
 /*
  * Internal (oversampled) distortion audio processing.
  */
 func (this *distortion) processOversampled(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	boost, _ := this.getNumericValue("boost")
-	gain, _ := this.getNumericValue("gain")
-	level, _ := this.getNumericValue("level")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	boost, _ := params.numericValue("boost")
+	gain, _ := params.numericValue("gain")
+	level, _ := params.numericValue("level")
 	totalGain := boost + gain
-	gainFactor := decibelsToFactor(totalGain)
-	levelFactor := decibelsToFactor(level)
+	gainTarget := decibelsToFactor(totalGain)
+	levelTarget := decibelsToFactor(level)
+	smoothingSamples := this.smoothingSamples(sampleRate)
+	gainRamp := &this.gain
+	gainRamp.setTarget(gainTarget, smoothingSamples)
+	levelRamp := &this.level
+	levelRamp.setTarget(levelTarget, smoothingSamples)
 
 	/*
 	 * Process each sample.
 	 */
 	for i, sample := range in {
+		gainFactor := gainRamp.next()
+		levelFactor := levelRamp.next()
 		pre := gainFactor * sample
 
 		/*
@@ -48,23 +57,37 @@ func (this *distortion) processOversampled(in []float64, out []float64, sampleRa
 
 }
 
+/*
+ * Returns the latency (in samples) of the distortion.
+ */
+func (this *distortion) Latency(sampleRate uint32) uint32 {
+	params := this.processingParameters()
+	oversampling, _ := params.discreteValue("oversampling")
+	oversamplerTwo := this.oversamplerTwo
+	oversamplerFour := this.oversamplerFour
+	oversamplerEight := this.oversamplerEight
+	latency := oversamplingLatency(oversampling, oversamplerTwo, oversamplerFour, oversamplerEight)
+	return latency
+}
+
 /*
  * Distortion audio processing.
  */
 func (this *distortion) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	oversampling, _ := this.getDiscreteValue("oversampling")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	oversampling, _ := params.discreteValue("oversampling")
 	factor := 1
 
 	/*
-	 * Enable two- or four-times oversampling.
+	 * Enable two-, four- or eight-times oversampling.
 	 */
 	switch oversampling {
 	case "2":
 		factor = 2
 	case "4":
 		factor = 4
+	case "8":
+		factor = 8
 	}
 
 	/*
@@ -101,6 +124,8 @@ func (this *distortion) Process(in []float64, out []float64, sampleRate uint32)
 		 */
 		if factor == 4 {
 			oversampler = this.oversamplerFour
+		} else if factor == 8 {
+			oversampler = this.oversamplerEight
 		}
 
 		oversampler.Oversample(in, bufferIn)
@@ -120,6 +145,7 @@ func (this *distortion) Process(in []float64, out []float64, sampleRate uint32)
 func createDistortion() Unit {
 	oversamplerTwo := oversampling.CreateOversamplerDecimator(2)
 	oversamplerFour := oversampling.CreateOversamplerDecimator(4)
+	oversamplerEight := oversampling.CreateOversamplerDecimator(8)
 
 	/*
 	 * Create effects unit.
@@ -170,12 +196,14 @@ func createDistortion() Unit {
 						"- NONE -",
 						"2",
 						"4",
+						"8",
 					},
 				},
 			},
 		},
-		oversamplerTwo:  oversamplerTwo,
-		oversamplerFour: oversamplerFour,
+		oversamplerTwo:   oversamplerTwo,
+		oversamplerFour:  oversamplerFour,
+		oversamplerEight: oversamplerEight,
 	}
 
 	return &u
@@ -2,8 +2,13 @@ package effects
 
 import (
 	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/filter"
+	"github.com/andrepxx/go-dsp-guitar/oversampling"
 	"math"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 /*
@@ -15,6 +20,15 @@ const (
 	PARAMETER_TYPE_NUMERIC
 )
 
+/*
+ * Parameter tapers, which tell how a control should map its travel onto the
+ * range of a numeric parameter.
+ */
+const (
+	TAPER_LINEAR = iota
+	TAPER_LOGARITHMIC
+)
+
 /*
  * Effect unit types.
  */
@@ -40,6 +54,18 @@ const (
 	UNIT_REVERB
 	UNIT_POWERAMP
 	UNIT_CABINET
+	UNIT_CONVOLUTION_REVERB
+	UNIT_MULTITAP_DELAY
+	UNIT_PITCH_SHIFTER
+	UNIT_STUDIO_COMPRESSOR
+	UNIT_PLUGIN
+	UNIT_AMP_MODEL
+	UNIT_SUB_OCTAVER
+	UNIT_ENVELOPE_FILTER
+	UNIT_ALGORITHMIC_REVERB
+	UNIT_SLOW_GEAR
+	UNIT_PARAMETRIC_EQ
+	UNIT_COMPOSITE
 )
 
 /*
@@ -55,6 +81,14 @@ const (
 	MATH_TWO_PI_HUNDREDTH  = 0.02 * math.Pi
 )
 
+/*
+ * Highest cutoff frequency of a filter in trapezoidal form, relative to the
+ * sample rate.
+ */
+const (
+	TRAPEZOIDAL_MAX_CUTOFF = 0.45
+)
+
 /*
  * Other constants.
  */
@@ -63,18 +97,33 @@ const (
 	STRING_NONE = "- NONE -"
 )
 
+/*
+ * Time (in milliseconds) over which changes to numeric parameters are ramped.
+ */
+const (
+	SMOOTHING_TIME_DEFAULT = 20
+	SMOOTHING_TIME_MAXIMUM = 50
+	SMOOTHING_TIME_MINIMUM = 5
+)
+
 /*
  * Data structure representing a parameter for an effects unit.
  */
 type Parameter struct {
-	Name               string
-	Type               int32
-	PhysicalUnit       string
-	Minimum            int32
-	Maximum            int32
-	NumericValue       int32
-	DiscreteValueIndex int
-	DiscreteValues     []string
+	Name                      string
+	Type                      int32
+	PhysicalUnit              string
+	Minimum                   int32
+	Maximum                   int32
+	NumericValue              int32
+	DiscreteValueIndex        int
+	DiscreteValues            []string
+	DefaultValue              int32
+	DefaultDiscreteValueIndex int
+	Taper                     int32
+	Scale                     float64
+	Precision                 int32
+	DisplayUnit               string
 }
 
 /*
@@ -90,13 +139,138 @@ type Unit interface {
 	GetNumericValue(name string) (int32, error)
 }
 
+/*
+ * Interface type for an effects unit which is able to process a stereo
+ * signal with linked left and right channels.
+ */
+type StereoUnit interface {
+	Unit
+	ProcessStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32)
+}
+
+/*
+ * Interface type for an effects unit which is able to synchronize to a tempo
+ * given in beats per minute.
+ */
+type TempoUnit interface {
+	Unit
+	SetTempo(bpm uint32)
+}
+
+/*
+ * Interface type for an effects unit which reports the gain reduction (in
+ * decibels) it applies to the signal.
+ */
+type MeteringUnit interface {
+	Unit
+	GainReduction() int32
+}
+
+/*
+ * Interface type for an effects unit which calculates its complex frequency
+ * response at certain frequencies (in hertz) analytically, e. g. to plot the
+ * curve of an equalizer.
+ */
+type ResponseUnit interface {
+	Unit
+	FrequencyResponse(frequencies []float64, sampleRate uint32) []complex128
+}
+
+/*
+ * Interface type for an effects unit which delays the signal it processes.
+ *
+ * The latency is given in samples at the sample rate passed.
+ */
+type LatencyUnit interface {
+	Unit
+	Latency(sampleRate uint32) uint32
+}
+
+/*
+ * Interface type for an effects unit which ramps changes to its numeric
+ * parameters over a certain time (in milliseconds) to avoid zipper noise.
+ *
+ * A time of zero applies changes immediately.
+ */
+type SmoothingUnit interface {
+	Unit
+	SetSmoothingTime(ms uint32)
+}
+
+/*
+ * Interface type for an effects unit which prepares its filters for the
+ * number of frames it processes at once, so that this does not happen on the
+ * audio thread.
+ */
+type BlockSizeUnit interface {
+	Unit
+	SetBlockSize(frames uint32)
+}
+
+/*
+ * A function creating an effects unit of a type registered by another
+ * package. The unit must report the type passed to the function.
+ */
+type UnitFactory func(unitType int) Unit
+
+/*
+ * Data structure representing a unit type registered by another package.
+ */
+type registeredUnitStruct struct {
+	name    string
+	factory UnitFactory
+}
+
+var g_registryMutex sync.RWMutex                   // Mutex for registered unit types.
+var g_registeredUnits []registeredUnitStruct = nil // Unit types registered by other packages.
+
+/*
+ * A set of parameters of an effects unit.
+ */
+type parameterSet []Parameter
+
+/*
+ * Interface type for an effects unit which records the metadata of its
+ * parameters.
+ */
+type parameterDescriber interface {
+	describeParameters()
+}
+
+/*
+ * Interface type for an effects unit which publishes its parameters for
+ * processing.
+ */
+type parameterPublisher interface {
+	publishParameters()
+}
+
 /*
  * Data structure representing a generic effects unit.
+ *
+ * Parameters are changed under the mutex. After each change, a copy of all
+ * parameters is published as an immutable snapshot, which the audio thread
+ * reads without locking. This way, processing always sees a consistent set
+ * of parameters and changes take effect at the start of the next block.
  */
 type unitStruct struct {
-	unitType int
-	mutex    sync.RWMutex
-	params   []Parameter
+	unitType      int
+	mutex         sync.RWMutex
+	params        []Parameter
+	snapshot      atomic.Value
+	smoothingTime uint32
+}
+
+/*
+ * Data structure representing a value which moves linearly towards its
+ * target, sample by sample, instead of jumping.
+ */
+type smoothedValue struct {
+	current     float64
+	target      float64
+	step        float64
+	remaining   int
+	initialized bool
 }
 
 /*
@@ -121,6 +295,46 @@ func (this *unitStruct) parameters() []Parameter {
 	return params
 }
 
+/*
+ * Records the metadata of the parameters of an effects unit, taking their
+ * initial values as their defaults.
+ *
+ * Must be called right after the unit is created.
+ */
+func (this *unitStruct) describeParameters() {
+
+	/*
+	 * Describe each parameter.
+	 */
+	for i, param := range this.params {
+		this.params[i] = describeParameter(param)
+	}
+
+}
+
+/*
+ * Publishes a snapshot of the current parameters for processing.
+ *
+ * Must be called with the mutex held, whenever the parameters change.
+ */
+func (this *unitStruct) publishParameters() {
+	params := this.parameters()
+	snapshot := parameterSet(params)
+	this.snapshot.Store(snapshot)
+}
+
+/*
+ * Returns the most recently published snapshot of the parameters of an
+ * effects unit.
+ *
+ * This does not lock, so it is safe to call from the audio thread.
+ */
+func (this *unitStruct) processingParameters() parameterSet {
+	value := this.snapshot.Load()
+	snapshot, _ := value.(parameterSet)
+	return snapshot
+}
+
 /*
  * Returns the parameters of an effects unit.
  */
@@ -138,6 +352,27 @@ func (this *unitStruct) Type() int {
 	return this.unitType
 }
 
+/*
+ * Sets the time (in milliseconds) over which changes to numeric parameters
+ * are ramped.
+ */
+func (this *unitStruct) SetSmoothingTime(ms uint32) {
+	atomic.StoreUint32(&this.smoothingTime, ms)
+}
+
+/*
+ * Returns the number of samples at a certain sample rate over which changes
+ * to numeric parameters are ramped.
+ */
+func (this *unitStruct) smoothingSamples(sampleRate uint32) int {
+	ms := atomic.LoadUint32(&this.smoothingTime)
+	msFloat := float64(ms)
+	sampleRateFloat := float64(sampleRate)
+	samplesFloat := math.Floor((0.001 * msFloat * sampleRateFloat) + 0.5)
+	samples := int(samplesFloat)
+	return samples
+}
+
 /*
  * Sets a discrete parameter value for an effects unit.
  */
@@ -196,6 +431,7 @@ func (this *unitStruct) setDiscreteValue(name string, value string) error {
 				return fmt.Errorf("Failed to set discrete value: Value '%s' is not valid for parameter '%s'.", value, name)
 			} else {
 				this.params[idx].DiscreteValueIndex = valIdx
+				this.publishParameters()
 				return nil
 			}
 
@@ -216,15 +452,15 @@ func (this *unitStruct) SetDiscreteValue(name string, value string) error {
 }
 
 /*
- * Gets a discrete parameter value from an effects unit.
+ * Gets a discrete parameter value from a set of parameters.
  */
-func (this *unitStruct) getDiscreteValue(name string) (string, error) {
+func (this parameterSet) discreteValue(name string) (string, error) {
 	idx := int(-1)
 
 	/*
 	 * Iterate over all parameters.
 	 */
-	for i, param := range this.params {
+	for i, param := range this {
 
 		/*
 		 * If we got the right one, store its index.
@@ -241,7 +477,7 @@ func (this *unitStruct) getDiscreteValue(name string) (string, error) {
 	if idx == -1 {
 		return "", fmt.Errorf("Failed to get discrete value: Could not find parameter with name '%s'.", name)
 	} else {
-		param := this.params[idx]
+		param := this[idx]
 
 		/*
 		 * Check if parameter is discrete.
@@ -258,6 +494,15 @@ func (this *unitStruct) getDiscreteValue(name string) (string, error) {
 
 }
 
+/*
+ * Gets a discrete parameter value from an effects unit.
+ */
+func (this *unitStruct) getDiscreteValue(name string) (string, error) {
+	params := parameterSet(this.params)
+	value, err := params.discreteValue(name)
+	return value, err
+}
+
 /*
  * Gets a discrete parameter value from an effects unit.
  */
@@ -312,6 +557,7 @@ func (this *unitStruct) setNumericValue(name string, value int32) error {
 				return fmt.Errorf("Failed to set numeric value: Parameter '%s' must be between '%d' and '%d' - got '%d'.", name, min, max, value)
 			} else {
 				this.params[idx].NumericValue = value
+				this.publishParameters()
 				return nil
 			}
 
@@ -332,15 +578,15 @@ func (this *unitStruct) SetNumericValue(name string, value int32) error {
 }
 
 /*
- * Gets a numeric parameter value from an effects unit.
+ * Gets a numeric parameter value from a set of parameters.
  */
-func (this *unitStruct) getNumericValue(name string) (int32, error) {
+func (this parameterSet) numericValue(name string) (int32, error) {
 	idx := int(-1)
 
 	/*
 	 * Iterate over all parameters.
 	 */
-	for i, param := range this.params {
+	for i, param := range this {
 
 		/*
 		 * If we got the right one, store its index.
@@ -357,7 +603,7 @@ func (this *unitStruct) getNumericValue(name string) (int32, error) {
 	if idx == -1 {
 		return 0, fmt.Errorf("Failed to get numeric value: Could not find parameter with name '%s'.", name)
 	} else {
-		param := this.params[idx]
+		param := this[idx]
 
 		/*
 		 * Check if parameter is numeric.
@@ -373,6 +619,15 @@ func (this *unitStruct) getNumericValue(name string) (int32, error) {
 
 }
 
+/*
+ * Gets a numeric parameter value from an effects unit.
+ */
+func (this *unitStruct) getNumericValue(name string) (int32, error) {
+	params := parameterSet(this.params)
+	value, err := params.numericValue(name)
+	return value, err
+}
+
 /*
  * Gets a numeric parameter value from an effects unit.
  */
@@ -383,6 +638,122 @@ func (this *unitStruct) GetNumericValue(name string) (int32, error) {
 	return val, err
 }
 
+/*
+ * Replaces the list of values of a discrete parameter, keeping the current
+ * value selected if it is still available.
+ */
+func (this *unitStruct) replaceDiscreteValues(name string, values []string) error {
+	idx := int(-1)
+
+	/*
+	 * Iterate over all parameters.
+	 */
+	for i, param := range this.params {
+
+		/*
+		 * If we got the right one, store its index.
+		 */
+		if param.Name == name {
+			idx = i
+		}
+
+	}
+
+	/*
+	 * Check if parameter was found.
+	 */
+	if idx == -1 {
+		return fmt.Errorf("Failed to replace discrete values: Could not find parameter with name '%s'.", name)
+	} else {
+		param := this.params[idx]
+
+		/*
+		 * Check if parameter is discrete.
+		 */
+		if param.Type != PARAMETER_TYPE_DISCRETE {
+			return fmt.Errorf("Failed to replace discrete values: Parameter '%s' is not discrete.", name)
+		} else {
+			valIdx := param.DiscreteValueIndex
+			currentValue := param.DiscreteValues[valIdx]
+			newValIdx := int(0)
+
+			/*
+			 * Look for the current value in the new list.
+			 */
+			for i, value := range values {
+
+				/*
+				 * If we found it, keep it selected.
+				 */
+				if value == currentValue {
+					newValIdx = i
+				}
+
+			}
+
+			n := len(values)
+			valuesCopy := make([]string, n)
+			copy(valuesCopy, values)
+			this.params[idx].DiscreteValues = valuesCopy
+			this.params[idx].DiscreteValueIndex = newValIdx
+			this.publishParameters()
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Sets the value to move towards, which is reached after a certain number of
+ * samples. The first target set is applied immediately.
+ */
+func (this *smoothedValue) setTarget(target float64, samples int) {
+
+	/*
+	 * Jump to the target if there is nothing to ramp from or ramping is
+	 * disabled, otherwise start a new ramp if the target changed.
+	 */
+	if !this.initialized || samples <= 0 {
+		this.current = target
+		this.target = target
+		this.step = 0.0
+		this.remaining = 0
+		this.initialized = true
+	} else if target != this.target {
+		samplesFloat := float64(samples)
+		this.target = target
+		this.step = (target - this.current) / samplesFloat
+		this.remaining = samples
+	}
+
+}
+
+/*
+ * Advances the value by one sample and returns it.
+ */
+func (this *smoothedValue) next() float64 {
+
+	/*
+	 * Check if the value is still moving.
+	 */
+	if this.remaining > 0 {
+		this.remaining--
+
+		/*
+		 * Hit the target exactly at the end of the ramp.
+		 */
+		if this.remaining == 0 {
+			this.current = this.target
+		} else {
+			this.current += this.step
+		}
+
+	}
+
+	return this.current
+}
+
 /*
  * Turn gain (or attenuation) in decibels into a (linear) factor.
  */
@@ -393,6 +764,130 @@ func decibelsToFactor(decibels int32) float64 {
 	return result
 }
 
+/*
+ * Returns the factor by which a one-pole lowpass filter with a certain cutoff
+ * frequency moves towards its input with each sample.
+ *
+ * The factor is chosen so that the filter attenuates by 3 dB exactly at the
+ * cutoff frequency, whatever the sample rate. Approximating it from the time
+ * constant only holds for cutoff frequencies far below the sample rate.
+ * Cutoff frequencies beyond the Nyquist frequency are limited to it.
+ */
+func onePoleFactor(frequency float64, sampleRate uint32) float64 {
+	sampleRateFloat := float64(sampleRate)
+	omega := (MATH_TWO_PI * frequency) / sampleRateFloat
+
+	/*
+	 * Limit the cutoff frequency to the Nyquist frequency.
+	 */
+	if omega > math.Pi {
+		omega = math.Pi
+	}
+
+	/*
+	 * A filter without a cutoff frequency does not move at all.
+	 */
+	if !(omega > 0.0) {
+		return 0.0
+	} else {
+		c := 2.0 - math.Cos(omega)
+		pole := c - math.Sqrt((c*c)-1.0)
+		factor := 1.0 - pole
+		return factor
+	}
+
+}
+
+/*
+ * Returns the gain of a one-pole filter in trapezoidal form with a certain
+ * cutoff frequency.
+ *
+ * Unlike the simple form, the lowpass and the highpass output of this filter
+ * add up to the input in each sample, so that cascades of both keep their
+ * shape at low sample rates. Cutoff frequencies close to the Nyquist frequency
+ * are limited.
+ */
+func trapezoidalFactor(frequency float64, sampleRate uint32) float64 {
+	sampleRateFloat := float64(sampleRate)
+	limit := TRAPEZOIDAL_MAX_CUTOFF * sampleRateFloat
+
+	/*
+	 * Limit the cutoff frequency below the Nyquist frequency.
+	 */
+	if frequency > limit {
+		frequency = limit
+	}
+
+	/*
+	 * A filter without a cutoff frequency does not move at all.
+	 */
+	if !(frequency > 0.0) {
+		return 0.0
+	} else {
+		omega := (math.Pi * frequency) / sampleRateFloat
+		g := math.Tan(omega)
+		factor := g / (1.0 + g)
+		return factor
+	}
+
+}
+
+/*
+ * Processes a sample with a one-pole filter in trapezoidal form and returns
+ * its lowpass output.
+ *
+ * The highpass output is the input minus the lowpass output.
+ */
+func trapezoidalLowpass(sample float64, state *float64, factor float64) float64 {
+	s := *state
+	v := (sample - s) * factor
+	lowpass := v + s
+	*state = lowpass + v
+	return lowpass
+}
+
+/*
+ * Returns a copy of a filter, prepared for blocks of the given size, so that
+ * it can replace the filter in use once it is ready.
+ */
+func prepareFilter(flt filter.Filter, frames uint32) filter.Filter {
+
+	/*
+	 * There is nothing to prepare without a filter.
+	 */
+	if flt == nil {
+		return nil
+	} else {
+		blockSize := int(frames)
+		prepared := flt.Multiply(1.0)
+		prepared.Prepare(blockSize)
+		return prepared
+	}
+
+}
+
+/*
+ * Returns the latency (in samples) introduced by the oversampler selected by
+ * the value of an "oversampling" parameter (the oversampling factor).
+ */
+func oversamplingLatency(factor string, oversamplerTwo oversampling.OversamplerDecimator, oversamplerFour oversampling.OversamplerDecimator, oversamplerEight oversampling.OversamplerDecimator) uint32 {
+
+	/*
+	 * Select the oversampler in use.
+	 */
+	switch factor {
+	case "2":
+		return oversamplerTwo.Latency()
+	case "4":
+		return oversamplerFour.Latency()
+	case "8":
+		return oversamplerEight.Latency()
+	default:
+		return 0
+	}
+
+}
+
 /*
  * Turn a linear factor into a gain (or attenuation) value in decibels.
  */
@@ -438,9 +933,12 @@ func signFloat(number float64) float64 {
 }
 
 /*
- * Create a new effects unit.
+ * Create a new effects unit of a certain type.
+ *
+ * Composite units hold a signal chain of their own, so they are created by
+ * the signal chain they are part of instead.
  */
-func CreateUnit(unitType int) Unit {
+func createUnit(unitType int) Unit {
 
 	/*
 	 * Lookup, which effect unit to create.
@@ -509,12 +1007,217 @@ func CreateUnit(unitType int) Unit {
 	case UNIT_CABINET:
 		u := createCabinet()
 		return u
+	case UNIT_CONVOLUTION_REVERB:
+		u := createConvolutionReverb()
+		return u
+	case UNIT_MULTITAP_DELAY:
+		u := createMultitapDelay()
+		return u
+	case UNIT_PITCH_SHIFTER:
+		u := createPitchShifter()
+		return u
+	case UNIT_STUDIO_COMPRESSOR:
+		u := createStudioCompressor()
+		return u
+	case UNIT_PLUGIN:
+		u := createPlugin()
+		return u
+	case UNIT_AMP_MODEL:
+		u := createAmpModel()
+		return u
+	case UNIT_SUB_OCTAVER:
+		u := createSubOctaver()
+		return u
+	case UNIT_ENVELOPE_FILTER:
+		u := createEnvelopeFilter()
+		return u
+	case UNIT_ALGORITHMIC_REVERB:
+		u := createAlgorithmicReverb()
+		return u
+	case UNIT_SLOW_GEAR:
+		u := createSlowGear()
+		return u
+	case UNIT_PARAMETRIC_EQ:
+		u := createParametricEq()
+		return u
 	default:
-		return nil
+		idx := unitType - (UNIT_COMPOSITE + 1)
+		factory := UnitFactory(nil)
+		g_registryMutex.RLock()
+		numRegistered := len(g_registeredUnits)
+
+		/*
+		 * Check if another package registered this unit type.
+		 */
+		if (idx >= 0) && (idx < numRegistered) {
+			factory = g_registeredUnits[idx].factory
+		}
+
+		g_registryMutex.RUnlock()
+
+		/*
+		 * Check if there is a factory for this unit type.
+		 */
+		if factory == nil {
+			return nil
+		} else {
+			u := factory(unitType)
+			return u
+		}
+
 	}
 
 }
 
+/*
+ * Create a new effects unit, publish its initial parameters and enable
+ * smoothing of parameter changes.
+ */
+func CreateUnit(unitType int) Unit {
+	unit := createUnit(unitType)
+	describer, isDescriber := unit.(parameterDescriber)
+
+	/*
+	 * Record the defaults of the parameters.
+	 */
+	if isDescriber {
+		describer.describeParameters()
+	}
+
+	publisher, isPublisher := unit.(parameterPublisher)
+
+	/*
+	 * Publish the initial parameters for processing.
+	 */
+	if isPublisher {
+		publisher.publishParameters()
+	}
+
+	smoothingUnit, isSmoothingUnit := unit.(SmoothingUnit)
+
+	/*
+	 * Ramp parameter changes by default.
+	 */
+	if isSmoothingUnit {
+		smoothingUnit.SetSmoothingTime(SMOOTHING_TIME_DEFAULT)
+	}
+
+	return unit
+}
+
+/*
+ * Updates an effects unit after the collection of impulse responses has been
+ * reloaded. Units which do not depend on impulse responses are left untouched.
+ */
+func UpdateImpulseResponses(unit Unit, responses filter.ImpulseResponses) error {
+
+	/*
+	 * Check if the collection of impulse responses is nil.
+	 */
+	if responses == nil {
+		return fmt.Errorf("%s", "Cannot update impulse responses: Impulse responses are nil.")
+	} else {
+
+		/*
+		 * Check which type of unit we have.
+		 */
+		switch u := unit.(type) {
+		case *poweramp:
+			return u.updateImpulseResponses(responses)
+		case *convolutionReverb:
+			return u.updateImpulseResponses(responses)
+		default:
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Returns whether units of a certain type keep producing a signal, like the
+ * repeats of a delay or the decay of a reverb, after their input fell
+ * silent.
+ */
+func HasTail(unitType int) bool {
+
+	/*
+	 * Check which type of unit we have.
+	 */
+	switch unitType {
+	case UNIT_DELAY, UNIT_REVERB, UNIT_CONVOLUTION_REVERB, UNIT_MULTITAP_DELAY, UNIT_ALGORITHMIC_REVERB:
+		return true
+	default:
+		return false
+	}
+
+}
+
+/*
+ * Derives the metadata of a parameter, which tells clients how to display
+ * it, from its definition. Its current value is taken as its default.
+ *
+ * Physical units of numeric parameters may start with the value of one step,
+ * like '0.1 Hz', which is split into the scale and the unit to display.
+ * Frequencies and times spanning at least a decade are best controlled with
+ * a logarithmic taper.
+ */
+func describeParameter(param Parameter) Parameter {
+	param.DefaultValue = param.NumericValue
+	param.DefaultDiscreteValueIndex = param.DiscreteValueIndex
+	param.Scale = 1.0
+	param.Precision = 0
+	param.DisplayUnit = param.PhysicalUnit
+
+	/*
+	 * Only numeric parameters are displayed as numbers.
+	 */
+	if param.Type == PARAMETER_TYPE_NUMERIC {
+		fields := strings.SplitN(param.PhysicalUnit, " ", 2)
+		scale, err := strconv.ParseFloat(fields[0], 64)
+
+		/*
+		 * Check if the physical unit starts with the value of a step.
+		 */
+		if (err == nil) && (scale > 0.0) {
+			param.Scale = scale
+			param.DisplayUnit = ""
+
+			/*
+			 * Check if there is a unit following the step.
+			 */
+			if len(fields) > 1 {
+				param.DisplayUnit = fields[1]
+			}
+
+			/*
+			 * Steps below one need decimal places.
+			 */
+			if scale < 1.0 {
+				digits := math.Ceil(-math.Log10(scale) - 1e-9)
+				param.Precision = int32(digits)
+			}
+
+		}
+
+		displayUnit := param.DisplayUnit
+		isFrequency := displayUnit == "Hz"
+		isTime := (displayUnit == "ms") || (displayUnit == "s")
+		minimum := param.Minimum
+		maximum := param.Maximum
+
+		/*
+		 * Check if the parameter spans at least a decade.
+		 */
+		if (isFrequency || isTime) && (minimum > 0) && (maximum >= 10*minimum) {
+			param.Taper = TAPER_LOGARITHMIC
+		}
+
+	}
+
+	return param
+}
+
 /*
  * Returns a list of supported parameter types.
  */
@@ -533,12 +1236,28 @@ func ParameterTypes() []string {
 }
 
 /*
- * Returns a list of supported unit types.
+ * Returns a list of supported parameter tapers.
  */
-func UnitTypes() []string {
+func Tapers() []string {
+
+	/*
+	 * List of all supported tapers.
+	 */
+	tapers := []string{
+		"linear",
+		"logarithmic",
+	}
+
+	return tapers
+}
+
+/*
+ * Returns a list of the unit types built into this package.
+ */
+func builtinUnitTypes() []string {
 
 	/*
-	 * List of all supported unit types.
+	 * List of all built-in unit types.
 	 */
 	unitTypes := []string{
 		"signal_generator",
@@ -562,7 +1281,111 @@ func UnitTypes() []string {
 		"reverb",
 		"power_amp",
 		"cabinet",
+		"convolution_reverb",
+		"multitap_delay",
+		"pitch_shifter",
+		"studio_compressor",
+		"plugin",
+		"amp_model",
+		"sub_octaver",
+		"envelope_filter",
+		"algorithmic_reverb",
+		"slow_gear",
+		"parametric_eq",
+		"composite",
 	}
 
 	return unitTypes
 }
+
+/*
+ * Returns a list of supported unit types, the built-in ones followed by those
+ * registered by other packages.
+ */
+func UnitTypes() []string {
+	unitTypes := builtinUnitTypes()
+	g_registryMutex.RLock()
+
+	/*
+	 * Append the names of the registered unit types.
+	 */
+	for _, registered := range g_registeredUnits {
+		unitTypes = append(unitTypes, registered.name)
+	}
+
+	g_registryMutex.RUnlock()
+	return unitTypes
+}
+
+/*
+ * Registers a new type of effects unit, which is created by a factory, and
+ * returns the type assigned to it.
+ *
+ * This allows other packages to add their own units, which may then be
+ * added to signal chains, stored in patches and controlled from the web
+ * interface like the built-in ones. Since patches refer to units by the name
+ * of their type, units must be registered before the patches using them are
+ * loaded, ideally from an init function.
+ */
+func RegisterUnit(typeName string, factory UnitFactory) (int, error) {
+
+	/*
+	 * Check if name and factory are valid.
+	 */
+	if typeName == "" {
+		return -1, fmt.Errorf("%s", "Cannot register unit type: Name is empty.")
+	} else if factory == nil {
+		return -1, fmt.Errorf("Cannot register unit type '%s': Factory is nil.", typeName)
+	} else {
+		builtin := builtinUnitTypes()
+		g_registryMutex.Lock()
+		unitTypes := builtin
+
+		/*
+		 * Collect the names of the registered unit types.
+		 */
+		for _, registered := range g_registeredUnits {
+			unitTypes = append(unitTypes, registered.name)
+		}
+
+		exists := false
+
+		/*
+		 * Check if there is already a unit type with this name.
+		 */
+		for _, name := range unitTypes {
+
+			/*
+			 * Check if the name matches.
+			 */
+			if name == typeName {
+				exists = true
+			}
+
+		}
+
+		/*
+		 * Names of unit types must be unique.
+		 */
+		if exists {
+			g_registryMutex.Unlock()
+			return -1, fmt.Errorf("Cannot register unit type '%s': Unit type already exists.", typeName)
+		} else {
+			unitType := len(unitTypes)
+
+			/*
+			 * The new unit type.
+			 */
+			registered := registeredUnitStruct{
+				name:    typeName,
+				factory: factory,
+			}
+
+			g_registeredUnits = append(g_registeredUnits, registered)
+			g_registryMutex.Unlock()
+			return unitType, nil
+		}
+
+	}
+
+}
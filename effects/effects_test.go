@@ -0,0 +1,657 @@
+package effects
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/fft"
+	"github.com/andrepxx/go-dsp-guitar/filter"
+	"github.com/andrepxx/go-dsp-guitar/random"
+	"math"
+	"math/cmplx"
+	"testing"
+	"time"
+)
+
+/*
+ * Limits for comparing the frequency responses of units at different sample
+ * rates (in dB).
+ */
+const (
+	CONFORMANCE_FLOOR     = -120.0
+	CONFORMANCE_SILENCE   = -40.0
+	CONFORMANCE_TOLERANCE = 1.0
+)
+
+/*
+ * Verify that each unit type is created with the type it was asked for and
+ * that the types stored in existing configurations keep their meaning.
+ */
+func TestUnitTypes(t *testing.T) {
+	unitTypes := UnitTypes()
+
+	/*
+	 * Create a unit of each type.
+	 */
+	for i, name := range unitTypes {
+		u := CreateUnit(i)
+
+		/*
+		 * Verify that the unit has the right type. Composite units are
+		 * created by signal chains.
+		 */
+		if i == UNIT_COMPOSITE {
+
+			/*
+			 * Verify that no composite unit is created here.
+			 */
+			if u != nil {
+				t.Errorf("Unexpectedly created unit of type %d ('%s').", i, name)
+			}
+
+		} else if u == nil {
+			t.Errorf("Failed to create unit of type %d ('%s').", i, name)
+		} else if u.Type() != i {
+			t.Errorf("Unit of type %d ('%s') reports type %d.", i, name, u.Type())
+		}
+
+	}
+
+	/*
+	 * Types of the units which existed before new ones were added.
+	 */
+	stable := map[int]string{
+		UNIT_SIGNALGENERATOR: "signal_generator",
+		UNIT_COMPRESSOR:      "compressor",
+		UNIT_OCTAVER:         "octaver",
+		UNIT_DELAY:           "delay",
+		UNIT_REVERB:          "reverb",
+		UNIT_POWERAMP:        "power_amp",
+		UNIT_CABINET:         "cabinet",
+	}
+
+	/*
+	 * Verify that types are not renumbered.
+	 */
+	if UNIT_CABINET != 20 {
+		t.Errorf("Unit type of cabinet changed. Expected: %d Got: %d", 20, UNIT_CABINET)
+	}
+
+	/*
+	 * Verify that the names match the types.
+	 */
+	for unitType, name := range stable {
+
+		/*
+		 * Check that the type is within range and has the right name.
+		 */
+		if unitType >= len(unitTypes) {
+			t.Errorf("Unit type %d ('%s') is out of range.", unitType, name)
+		} else if unitTypes[unitType] != name {
+			t.Errorf("Unit type %d has wrong name. Expected: '%s' Got: '%s'", unitType, name, unitTypes[unitType])
+		}
+
+	}
+
+}
+
+/*
+ * Verify that the metadata of parameters is derived from their definition
+ * and that changing a parameter keeps its default.
+ */
+func TestParameterMetadata(t *testing.T) {
+	u := CreateUnit(UNIT_ALGORITHMIC_REVERB)
+	err := u.SetNumericValue("decay", 55)
+
+	/*
+	 * Check if value was set.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to set numeric value: %s", err.Error())
+	}
+
+	params := u.Parameters()
+	model := params[0]
+	decay := params[1]
+	tone := params[2]
+
+	/*
+	 * The defaults are the values the unit was created with.
+	 */
+	if (decay.NumericValue != 55) || (decay.DefaultValue != 20) {
+		t.Errorf("Parameter 'decay' should have value %d and default %d, but has value %d and default %d.", 55, 20, decay.NumericValue, decay.DefaultValue)
+	}
+
+	/*
+	 * A step of 0.1 s is displayed with one decimal place in seconds.
+	 */
+	if (decay.Scale != 0.1) || (decay.Precision != 1) || (decay.DisplayUnit != "s") {
+		t.Errorf("Parameter 'decay' should be displayed in steps of %f with %d decimals in '%s', but is displayed in steps of %f with %d decimals in '%s'.", 0.1, 1, "s", decay.Scale, decay.Precision, decay.DisplayUnit)
+	}
+
+	/*
+	 * Times spanning more than a decade use a logarithmic taper, while
+	 * percentages use a linear one.
+	 */
+	if decay.Taper != TAPER_LOGARITHMIC {
+		t.Errorf("Parameter 'decay' should have taper %d, but has taper %d.", TAPER_LOGARITHMIC, decay.Taper)
+	} else if tone.Taper != TAPER_LINEAR {
+		t.Errorf("Parameter 'tone' should have taper %d, but has taper %d.", TAPER_LINEAR, tone.Taper)
+	}
+
+	/*
+	 * Percentages are displayed as they are.
+	 */
+	if (tone.Scale != 1.0) || (tone.Precision != 0) || (tone.DisplayUnit != "%") {
+		t.Errorf("Parameter 'tone' should be displayed in steps of %f with %d decimals in '%s', but is displayed in steps of %f with %d decimals in '%s'.", 1.0, 0, "%", tone.Scale, tone.Precision, tone.DisplayUnit)
+	}
+
+	/*
+	 * Discrete parameters keep the index of their default value.
+	 */
+	if model.DefaultDiscreteValueIndex != 0 {
+		t.Errorf("Parameter 'model' should have default index %d, but has default index %d.", 0, model.DefaultDiscreteValueIndex)
+	}
+
+}
+
+/*
+ * A unit registered by a test, which wraps a built-in unit.
+ */
+type registeredUnitTest struct {
+	Unit
+	unitType int
+}
+
+/*
+ * Returns the type this unit was registered with.
+ */
+func (this *registeredUnitTest) Type() int {
+	return this.unitType
+}
+
+/*
+ * Verify that units registered by other packages are listed and created like
+ * built-in ones and that invalid registrations are rejected.
+ */
+func TestRegisterUnit(t *testing.T) {
+
+	/*
+	 * Creates a unit of the registered type.
+	 */
+	factory := func(unitType int) Unit {
+		inner := CreateUnit(UNIT_OVERDRIVE)
+
+		/*
+		 * The registered unit.
+		 */
+		u := registeredUnitTest{
+			Unit:     inner,
+			unitType: unitType,
+		}
+
+		return &u
+	}
+
+	numBuiltin := len(UnitTypes())
+	unitType, err := RegisterUnit("test_overdrive", factory)
+
+	/*
+	 * Check if the unit type was registered.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to register unit type: %s", err.Error())
+	}
+
+	/*
+	 * Registered unit types follow the built-in ones.
+	 */
+	if unitType != numBuiltin {
+		t.Errorf("Registered unit type should be %d, but is %d.", numBuiltin, unitType)
+	}
+
+	unitTypes := UnitTypes()
+	numUnitTypes := len(unitTypes)
+
+	/*
+	 * Verify that the unit type is listed under its name.
+	 */
+	if unitType >= numUnitTypes {
+		t.Errorf("Registered unit type %d is out of range.", unitType)
+	} else if unitTypes[unitType] != "test_overdrive" {
+		t.Errorf("Registered unit type has wrong name. Expected: '%s' Got: '%s'", "test_overdrive", unitTypes[unitType])
+	}
+
+	u := CreateUnit(unitType)
+
+	/*
+	 * Verify that the unit is created with the registered type.
+	 */
+	if u == nil {
+		t.Errorf("Failed to create unit of registered type %d.", unitType)
+	} else if u.Type() != unitType {
+		t.Errorf("Unit of registered type %d reports type %d.", unitType, u.Type())
+	}
+
+	/*
+	 * Names of built-in and registered types may not be taken again.
+	 */
+	names := []string{
+		"",
+		"delay",
+		"composite",
+		"test_overdrive",
+	}
+
+	/*
+	 * Try to register each name.
+	 */
+	for _, name := range names {
+		_, err := RegisterUnit(name, factory)
+
+		/*
+		 * Registration must fail.
+		 */
+		if err == nil {
+			t.Errorf("Registering unit type '%s' should fail.", name)
+		}
+
+	}
+
+	_, err = RegisterUnit("test_nil", nil)
+
+	/*
+	 * Units need a factory.
+	 */
+	if err == nil {
+		t.Errorf("Registering unit type '%s' without factory should fail.", "test_nil")
+	}
+
+	/*
+	 * Types beyond the registered ones do not exist.
+	 */
+	if CreateUnit(numUnitTypes) != nil {
+		t.Errorf("Unexpectedly created unit of type %d.", numUnitTypes)
+	}
+
+}
+
+/*
+ * Verifies that the output of a unit is finite and within range.
+ */
+func checkOutput(t *testing.T, name string, out []float64) {
+
+	/*
+	 * Check each sample.
+	 */
+	for i, sample := range out {
+
+		/*
+		 * Report the first sample out of range.
+		 */
+		if math.IsNaN(sample) || math.IsInf(sample, 0) || math.Abs(sample) > 1.0 {
+			t.Errorf("%s: Output at sample %d is out of range: %f", name, i, sample)
+			break
+		}
+
+	}
+
+}
+
+/*
+ * Creates a signal of uniformly distributed noise at full scale.
+ */
+func createNoise(n int, seed uint64) []float64 {
+	prng := random.CreatePRNG(seed)
+	signal := make([]float64, n)
+
+	/*
+	 * Draw each sample.
+	 */
+	for i := range signal {
+		value := prng.NextFloat()
+		signal[i] = (2.0 * value) - 1.0
+	}
+
+	return signal
+}
+
+/*
+ * Creates a signal of noise with equal energy at all frequencies within a
+ * band and none outside of it, so that it is the same at any sample rate.
+ */
+func createBandNoise(n int, sampleRate uint32, seed uint64) ([]float64, error) {
+	n64 := uint64(n)
+	size, _ := fft.NextPowerOfTwo(n64)
+	sizeInt := int(size)
+	halfSize := sizeInt / 2
+	spectrum := make([]complex128, sizeInt)
+	prng := random.CreatePRNG(seed)
+	sampleRateFloat := float64(sampleRate)
+	sizeFloat := float64(size)
+	binWidth := sampleRateFloat / sizeFloat
+
+	/*
+	 * Assign a random phase to each frequency within the band.
+	 */
+	for i := 1; i < halfSize; i++ {
+		iFloat := float64(i)
+		frequency := iFloat * binWidth
+
+		/*
+		 * Only frequencies within the band carry energy.
+		 */
+		if (frequency >= 20.0) && (frequency <= 8000.0) {
+			value := prng.NextFloat()
+			phase := MATH_TWO_PI * value
+			spectrum[i] = cmplx.Rect(1.0, phase)
+			spectrum[sizeInt-i] = cmplx.Rect(1.0, -phase)
+		}
+
+	}
+
+	signal := make([]float64, sizeInt)
+	ft := fft.CreateFourierTransform()
+	err := ft.RealInverseFourier(spectrum, signal, fft.SCALING_DEFAULT)
+
+	/*
+	 * Check if the signal was synthesized.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		energy := 0.0
+
+		/*
+		 * Sum up the energy of the signal.
+		 */
+		for _, sample := range signal {
+			energy += sample * sample
+		}
+
+		rms := math.Sqrt(energy / sizeFloat)
+		factor := 0.1 / rms
+
+		/*
+		 * Scale the signal to an RMS level of -20 dB.
+		 */
+		for i := range signal {
+			signal[i] *= factor
+		}
+
+		return signal[0:n], nil
+	}
+
+}
+
+/*
+ * Sums up the energy of a spectrum within an octave band.
+ */
+func bandEnergy(spectrum []complex128, center float64, binWidth float64) float64 {
+	lower := (center / math.Sqrt2) / binWidth
+	upper := (center * math.Sqrt2) / binWidth
+	first := int(math.Ceil(lower))
+	last := int(math.Floor(upper))
+	energy := 0.0
+
+	/*
+	 * Sum up the energy in each bin within the band.
+	 */
+	for i := first; i <= last; i++ {
+		value := cmplx.Abs(spectrum[i])
+		energy += value * value
+	}
+
+	return energy
+}
+
+/*
+ * Processes band-limited noise with a unit of a certain type, in its default
+ * settings, and measures its gain (in dB) in octave bands around the given
+ * center frequencies.
+ */
+func measureBandGains(unitType int, sampleRate uint32, centers []float64) ([]float64, error) {
+	u := CreateUnit(unitType)
+	frames := 256
+	framesUint := uint32(frames)
+	blockSizeUnit, isBlockSizeUnit := u.(BlockSizeUnit)
+
+	/*
+	 * Prepare the filters of the unit, like a signal chain would.
+	 */
+	if isBlockSizeUnit {
+		blockSizeUnit.SetBlockSize(framesUint)
+	}
+
+	sampleRateInt := int(sampleRate)
+	numBlocks := (2 * sampleRateInt) / frames
+	total := numBlocks * frames
+	settle := sampleRateInt / 2
+	in, err := createBandNoise(total, sampleRate, 1)
+
+	/*
+	 * Check if the noise was created.
+	 */
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, total)
+
+	/*
+	 * Process the noise block by block.
+	 */
+	for pos := 0; pos < total; pos += frames {
+		posEnd := pos + frames
+		u.Process(in[pos:posEnd], out[pos:posEnd], sampleRate)
+	}
+
+	n := uint64(total - settle)
+	size, _ := fft.NextPowerOfTwo(n)
+	bufferIn := make([]float64, size)
+	bufferOut := make([]float64, size)
+	copy(bufferIn, in[settle:total])
+	copy(bufferOut, out[settle:total])
+	spectrumIn := make([]complex128, size)
+	spectrumOut := make([]complex128, size)
+	ft := fft.CreateFourierTransform()
+	errIn := ft.RealFourier(bufferIn, spectrumIn, fft.SCALING_DEFAULT)
+	errOut := ft.RealFourier(bufferOut, spectrumOut, fft.SCALING_DEFAULT)
+
+	/*
+	 * Check if both spectra were calculated.
+	 */
+	if errIn != nil {
+		return nil, errIn
+	} else if errOut != nil {
+		return nil, errOut
+	} else {
+		sampleRateFloat := float64(sampleRate)
+		sizeFloat := float64(size)
+		binWidth := sampleRateFloat / sizeFloat
+		numCenters := len(centers)
+		gains := make([]float64, numCenters)
+
+		/*
+		 * Compare the energy of output and input in each band.
+		 */
+		for i, center := range centers {
+			energyIn := bandEnergy(spectrumIn, center, binWidth)
+			energyOut := bandEnergy(spectrumOut, center, binWidth)
+			ratio := energyOut / energyIn
+			gain := 10.0 * math.Log10(ratio)
+
+			/*
+			 * Silence in a band has no meaningful gain.
+			 */
+			if !(gain > CONFORMANCE_FLOOR) {
+				gain = CONFORMANCE_FLOOR
+			}
+
+			gains[i] = gain
+		}
+
+		return gains, nil
+	}
+
+}
+
+/*
+ * Verify that each unit, with identical settings, shows the same frequency
+ * response at each supported sample rate as it does at 96 kHz.
+ */
+func TestSampleRates(t *testing.T) {
+
+	/*
+	 * Measuring takes a while.
+	 */
+	if testing.Short() {
+		t.Skip("Skipping measurement of frequency responses in short mode.")
+	}
+
+	unitTypes := UnitTypes()
+	sampleRates := filter.SampleRates()
+	centers := []float64{250.0, 500.0, 1000.0, 2000.0, 4000.0}
+
+	/*
+	 * Measure each built-in unit type.
+	 */
+	for unitType := 0; unitType < UNIT_COMPOSITE; unitType++ {
+		name := unitTypes[unitType]
+
+		/*
+		 * The signal generator replaces its input, so it has no
+		 * frequency response.
+		 */
+		if unitType != UNIT_SIGNALGENERATOR {
+			reference, err := measureBandGains(unitType, 96000, centers)
+
+			/*
+			 * Check if the reference was measured.
+			 */
+			if err != nil {
+				t.Fatalf("Failed to measure unit '%s' at %d Hz: %s", name, 96000, err.Error())
+			}
+
+			/*
+			 * Compare each sample rate to the reference.
+			 */
+			for _, sampleRate := range sampleRates {
+				gains, err := measureBandGains(unitType, sampleRate, centers)
+
+				/*
+				 * Check if the gains were measured.
+				 */
+				if err != nil {
+					t.Fatalf("Failed to measure unit '%s' at %d Hz: %s", name, sampleRate, err.Error())
+				}
+
+				/*
+				 * Compare the gain in each band.
+				 */
+				for i, center := range centers {
+					expected := reference[i]
+					gain := gains[i]
+					deviation := math.Abs(gain - expected)
+
+					/*
+					 * Bands which are (almost) silenced at both
+					 * sample rates are equivalent.
+					 */
+					if (deviation > CONFORMANCE_TOLERANCE) && ((expected > CONFORMANCE_SILENCE) || (gain > CONFORMANCE_SILENCE)) {
+						t.Errorf("Unit '%s' at %d Hz has gain %.2f dB around %.0f Hz, but %.2f dB at %d Hz.", name, sampleRate, gain, center, expected, 96000)
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Measure the time it takes a unit of a certain type to process a block of
+ * noise and report it as a share of the duration of the block.
+ */
+func benchmarkUnit(b *testing.B, unitType int, frames int, sampleRate uint32) {
+	u := CreateUnit(unitType)
+	blockSizeUnit, isBlockSizeUnit := u.(BlockSizeUnit)
+
+	/*
+	 * Prepare the filters of the unit, like a signal chain would.
+	 */
+	if isBlockSizeUnit {
+		framesUint := uint32(frames)
+		blockSizeUnit.SetBlockSize(framesUint)
+	}
+
+	in := createNoise(frames, 1)
+	out := make([]float64, frames)
+	b.ResetTimer()
+	start := time.Now()
+
+	/*
+	 * Process the block b.N times.
+	 */
+	for i := 0; i < b.N; i++ {
+		u.Process(in, out, sampleRate)
+	}
+
+	elapsed := time.Since(start)
+	b.StopTimer()
+	elapsedFloat := elapsed.Seconds()
+	nFloat := float64(b.N)
+	framesFloat := float64(frames)
+	sampleRateFloat := float64(sampleRate)
+	duration := framesFloat / sampleRateFloat
+	load := (100.0 * elapsedFloat) / (nFloat * duration)
+	b.ReportMetric(load, "%realtime")
+}
+
+/*
+ * Benchmark each type of unit at common block sizes and sample rates.
+ */
+func BenchmarkUnits(b *testing.B) {
+	unitTypes := UnitTypes()
+	blockSizes := []int{64, 256, 1024}
+	sampleRates := []uint32{44100, 48000, 96000}
+
+	/*
+	 * Benchmark each unit type.
+	 */
+	for unitType, name := range unitTypes {
+
+		/*
+		 * Composite units are created by signal chains.
+		 */
+		if unitType != UNIT_COMPOSITE {
+
+			/*
+			 * Benchmark each sample rate.
+			 */
+			for _, sampleRate := range sampleRates {
+
+				/*
+				 * Benchmark each block size.
+				 */
+				for _, frames := range blockSizes {
+					benchmarkName := fmt.Sprintf("%s/%d/%d", name, sampleRate, frames)
+
+					/*
+					 * Benchmark the unit.
+					 */
+					benchmark := func(b *testing.B) {
+						benchmarkUnit(b, unitType, frames, sampleRate)
+					}
+
+					b.Run(benchmarkName, benchmark)
+				}
+
+			}
+
+		}
+
+	}
+
+}
@@ -0,0 +1,226 @@
+package effects
+
+import (
+	"math"
+)
+
+/*
+ * Constants for the envelope filter.
+ */
+const (
+	ENVELOPE_FILTER_MAX_Q = 10.0
+	ENVELOPE_FILTER_MIN_Q = 0.5
+)
+
+/*
+ * Data structure representing an envelope filter (touch filter), where the
+ * envelope of the signal sweeps the cutoff frequency of a state-variable
+ * filter.
+ */
+type envelopeFilter struct {
+	unitStruct
+	envelope float64
+	states   [2]float64
+}
+
+/*
+ * Envelope filter audio processing.
+ *
+ * The envelope, amplified by the sensitivity, moves the cutoff frequency
+ * exponentially from the first towards the second frequency when sweeping up
+ * and from the second towards the first when sweeping down. The filter is a
+ * state-variable filter in topology-preserving form, which stays stable while
+ * its cutoff frequency is modulated.
+ */
+func (this *envelopeFilter) Process(in []float64, out []float64, sampleRate uint32) {
+	params := this.processingParameters()
+	filterType, _ := params.discreteValue("filter_type")
+	direction, _ := params.discreteValue("direction")
+	sensitivity, _ := params.numericValue("sensitivity")
+	attackTime, _ := params.numericValue("attack_time")
+	releaseTime, _ := params.numericValue("release_time")
+	frequencyA, _ := params.numericValue("frequency_1")
+	frequencyB, _ := params.numericValue("frequency_2")
+	resonance, _ := params.numericValue("resonance")
+	sensitivityFactor := decibelsToFactor(sensitivity)
+	attackCoefficient := smoothingCoefficient(attackTime, sampleRate)
+	releaseCoefficient := smoothingCoefficient(releaseTime, sampleRate)
+	frequencyAFloat := float64(frequencyA)
+	frequencyBFloat := float64(frequencyB)
+	frequencyRatio := frequencyBFloat / frequencyAFloat
+	sampleRateFloat := float64(sampleRate)
+	maxFrequency := 0.49 * sampleRateFloat
+	resonanceFloat := float64(resonance)
+	q := ENVELOPE_FILTER_MIN_Q + (0.01 * resonanceFloat * (ENVELOPE_FILTER_MAX_Q - ENVELOPE_FILTER_MIN_Q))
+	k := 1.0 / q
+	envelope := this.envelope
+	states := &this.states
+
+	/*
+	 * Process each sample.
+	 */
+	for i, sample := range in {
+		sampleAbs := math.Abs(sample)
+		coefficient := releaseCoefficient
+
+		/*
+		 * Rise with the attack and fall with the release time.
+		 */
+		if sampleAbs > envelope {
+			coefficient = attackCoefficient
+		}
+
+		envelope = (coefficient * envelope) + ((1.0 - coefficient) * sampleAbs)
+		sweep := math.Min(sensitivityFactor*envelope, 1.0)
+
+		/*
+		 * When sweeping down, louder signals lower the cutoff.
+		 */
+		if direction == "down" {
+			sweep = 1.0 - sweep
+		}
+
+		frequency := frequencyAFloat * math.Pow(frequencyRatio, sweep)
+
+		/*
+		 * Keep the cutoff frequency below the Nyquist frequency.
+		 */
+		if frequency > maxFrequency {
+			frequency = maxFrequency
+		}
+
+		arg := (math.Pi * frequency) / sampleRateFloat
+		g := math.Tan(arg)
+		a1 := 1.0 / (1.0 + (g * (g + k)))
+		a2 := g * a1
+		a3 := g * a2
+		v3 := sample - states[1]
+		v1 := (a1 * states[0]) + (a2 * v3)
+		v2 := states[1] + (a2 * states[0]) + (a3 * v3)
+		states[0] = (2.0 * v1) - states[0]
+		states[1] = (2.0 * v2) - states[1]
+		pre := float64(0.0)
+
+		/*
+		 * Select the filter output.
+		 */
+		switch filterType {
+		case "bandpass":
+			pre = k * v1
+		case "highpass":
+			pre = sample - (k * v1) - v2
+		default:
+			pre = v2
+		}
+
+		out[i] = limitSample(pre)
+	}
+
+	this.envelope = envelope
+}
+
+/*
+ * Create an envelope filter effects unit.
+ */
+func createEnvelopeFilter() Unit {
+
+	/*
+	 * Create effects unit.
+	 */
+	u := envelopeFilter{
+		unitStruct: unitStruct{
+			unitType: UNIT_ENVELOPE_FILTER,
+			params: []Parameter{
+				Parameter{
+					Name:               "filter_type",
+					Type:               PARAMETER_TYPE_DISCRETE,
+					PhysicalUnit:       "",
+					Minimum:            -1,
+					Maximum:            -1,
+					NumericValue:       -1,
+					DiscreteValueIndex: 1,
+					DiscreteValues: []string{
+						"lowpass",
+						"bandpass",
+						"highpass",
+					},
+				},
+				Parameter{
+					Name:               "direction",
+					Type:               PARAMETER_TYPE_DISCRETE,
+					PhysicalUnit:       "",
+					Minimum:            -1,
+					Maximum:            -1,
+					NumericValue:       -1,
+					DiscreteValueIndex: 0,
+					DiscreteValues: []string{
+						"up",
+						"down",
+					},
+				},
+				Parameter{
+					Name:               "sensitivity",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            0,
+					Maximum:            40,
+					NumericValue:       20,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "attack_time",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "ms",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       5,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "release_time",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "ms",
+					Minimum:            0,
+					Maximum:            2000,
+					NumericValue:       150,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "frequency_1",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "Hz",
+					Minimum:            20,
+					Maximum:            20000,
+					NumericValue:       250,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "frequency_2",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "Hz",
+					Minimum:            20,
+					Maximum:            20000,
+					NumericValue:       3000,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "resonance",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       50,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+			},
+		},
+	}
+
+	return &u
+}
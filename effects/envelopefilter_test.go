@@ -0,0 +1,110 @@
+package effects
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+/*
+ * Calculates the gain of an effects unit for a sine wave with a certain
+ * frequency and amplitude, after the unit has settled.
+ */
+func sineGain(u Unit, frequency float64, amplitude float64) float64 {
+	n := 48000
+	in := make([]float64, n)
+	out := make([]float64, n)
+
+	/*
+	 * Generate a sine wave.
+	 */
+	for i := range in {
+		iFloat := float64(i)
+		arg := (2.0 * math.Pi * frequency * iFloat) / TEST_SAMPLE_RATE
+		in[i] = amplitude * math.Sin(arg)
+	}
+
+	u.Process(in, out, TEST_SAMPLE_RATE)
+	steady := out[n/2:]
+	peak := float64(0.0)
+
+	/*
+	 * Find the peak of the output.
+	 */
+	for _, sample := range steady {
+		peak = math.Max(peak, math.Abs(sample))
+	}
+
+	gain := peak / amplitude
+	return gain
+}
+
+/*
+ * Verify that the envelope of the signal opens a lowpass filter when sweeping
+ * up and closes it when sweeping down, and that all filter types stay finite
+ * for noise.
+ */
+func TestEnvelopeFilter(t *testing.T) {
+	frequency := 2000.0
+	u := CreateUnit(UNIT_ENVELOPE_FILTER)
+	u.SetDiscreteValue("filter_type", "lowpass")
+	u.SetDiscreteValue("direction", "up")
+	gainLoud := sineGain(u, frequency, 0.5)
+	u = CreateUnit(UNIT_ENVELOPE_FILTER)
+	u.SetDiscreteValue("filter_type", "lowpass")
+	u.SetDiscreteValue("direction", "up")
+	gainQuiet := sineGain(u, frequency, 0.005)
+
+	/*
+	 * Loud signals should open the filter when sweeping up.
+	 */
+	if gainLoud < (10.0 * gainQuiet) {
+		t.Errorf("Sweeping up: Gain for loud signal should be at least %f, but is %f.", 10.0*gainQuiet, gainLoud)
+	}
+
+	u = CreateUnit(UNIT_ENVELOPE_FILTER)
+	u.SetDiscreteValue("filter_type", "lowpass")
+	u.SetDiscreteValue("direction", "down")
+	gainLoud = sineGain(u, frequency, 0.5)
+	u = CreateUnit(UNIT_ENVELOPE_FILTER)
+	u.SetDiscreteValue("filter_type", "lowpass")
+	u.SetDiscreteValue("direction", "down")
+	gainQuiet = sineGain(u, frequency, 0.005)
+
+	/*
+	 * Loud signals should close the filter when sweeping down.
+	 */
+	if gainQuiet < (10.0 * gainLoud) {
+		t.Errorf("Sweeping down: Gain for quiet signal should be at least %f, but is %f.", 10.0*gainLoud, gainQuiet)
+	}
+
+	/*
+	 * Filter types to verify.
+	 */
+	filterTypes := []string{
+		"lowpass",
+		"bandpass",
+		"highpass",
+	}
+
+	n := 48000
+	noise := createNoise(n, 1)
+	out := make([]float64, n)
+
+	/*
+	 * Process noise with each filter type and maximum resonance.
+	 */
+	for _, filterType := range filterTypes {
+		name := fmt.Sprintf("noise_%s", filterType)
+		u = CreateUnit(UNIT_ENVELOPE_FILTER)
+		u.SetDiscreteValue("filter_type", filterType)
+		u.SetNumericValue("sensitivity", 40)
+		u.SetNumericValue("attack_time", 0)
+		u.SetNumericValue("release_time", 0)
+		u.SetNumericValue("frequency_2", 20000)
+		u.SetNumericValue("resonance", 100)
+		u.Process(noise, out, TEST_SAMPLE_RATE)
+		checkOutput(t, name, out)
+	}
+
+}
@@ -10,27 +10,36 @@ import (
  */
 type excess struct {
 	unitStruct
-	bufferIn        []float64
-	bufferOut       []float64
-	oversamplerTwo  oversampling.OversamplerDecimator
-	oversamplerFour oversampling.OversamplerDecimator
+	bufferIn         []float64
+	bufferOut        []float64
+	oversamplerTwo   oversampling.OversamplerDecimator
+	oversamplerFour  oversampling.OversamplerDecimator
+	oversamplerEight oversampling.OversamplerDecimator
+	gain             smoothedValue
+	level            smoothedValue
 }
 
 /*
  * Internal (oversampled) excess audio processing.
  */
 func (this *excess) processOversampled(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	gain, _ := this.getNumericValue("gain")
-	level, _ := this.getNumericValue("level")
-	this.mutex.RUnlock()
-	gainFactor := decibelsToFactor(gain)
-	levelFactor := decibelsToFactor(level)
+	params := this.processingParameters()
+	gain, _ := params.numericValue("gain")
+	level, _ := params.numericValue("level")
+	gainTarget := decibelsToFactor(gain)
+	levelTarget := decibelsToFactor(level)
+	smoothingSamples := this.smoothingSamples(sampleRate)
+	gainRamp := &this.gain
+	gainRamp.setTarget(gainTarget, smoothingSamples)
+	levelRamp := &this.level
+	levelRamp.setTarget(levelTarget, smoothingSamples)
 
 	/*
 	 * Process each sample.
 	 */
 	for i, sample := range in {
+		gainFactor := gainRamp.next()
+		levelFactor := levelRamp.next()
 		pre := gainFactor * sample
 		absPre := math.Abs(pre)
 		exceeded := absPre > 1.0
@@ -65,23 +74,37 @@ func (this *excess) processOversampled(in []float64, out []float64, sampleRate u
 
 }
 
+/*
+ * Returns the latency (in samples) of the excess.
+ */
+func (this *excess) Latency(sampleRate uint32) uint32 {
+	params := this.processingParameters()
+	oversampling, _ := params.discreteValue("oversampling")
+	oversamplerTwo := this.oversamplerTwo
+	oversamplerFour := this.oversamplerFour
+	oversamplerEight := this.oversamplerEight
+	latency := oversamplingLatency(oversampling, oversamplerTwo, oversamplerFour, oversamplerEight)
+	return latency
+}
+
 /*
  * Excess audio processing.
  */
 func (this *excess) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	oversampling, _ := this.getDiscreteValue("oversampling")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	oversampling, _ := params.discreteValue("oversampling")
 	factor := 1
 
 	/*
-	 * Enable two- or four-times oversampling.
+	 * Enable two-, four- or eight-times oversampling.
 	 */
 	switch oversampling {
 	case "2":
 		factor = 2
 	case "4":
 		factor = 4
+	case "8":
+		factor = 8
 	}
 
 	/*
@@ -118,6 +141,8 @@ func (this *excess) Process(in []float64, out []float64, sampleRate uint32) {
 		 */
 		if factor == 4 {
 			oversampler = this.oversamplerFour
+		} else if factor == 8 {
+			oversampler = this.oversamplerEight
 		}
 
 		oversampler.Oversample(in, bufferIn)
@@ -137,6 +162,7 @@ func (this *excess) Process(in []float64, out []float64, sampleRate uint32) {
 func createExcess() Unit {
 	oversamplerTwo := oversampling.CreateOversamplerDecimator(2)
 	oversamplerFour := oversampling.CreateOversamplerDecimator(4)
+	oversamplerEight := oversampling.CreateOversamplerDecimator(8)
 
 	/*
 	 * Create effects unit.
@@ -177,12 +203,14 @@ func createExcess() Unit {
 						"- NONE -",
 						"2",
 						"4",
+						"8",
 					},
 				},
 			},
 		},
-		oversamplerTwo:  oversamplerTwo,
-		oversamplerFour: oversamplerFour,
+		oversamplerTwo:   oversamplerTwo,
+		oversamplerFour:  oversamplerFour,
+		oversamplerEight: oversamplerEight,
 	}
 
 	return &u
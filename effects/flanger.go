@@ -2,6 +2,7 @@ package effects
 
 import (
 	"math"
+	"sync/atomic"
 )
 
 /*
@@ -11,16 +12,25 @@ type flanger struct {
 	unitStruct
 	buffer        []float64
 	previousPhase float64
+	tempo         uint32
+}
+
+/*
+ * Sets the tempo (in beats per minute) the modulation synchronizes to.
+ */
+func (this *flanger) SetTempo(bpm uint32) {
+	atomic.StoreUint32(&this.tempo, bpm)
 }
 
 /*
  * Flanger audio processing.
  */
 func (this *flanger) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	depth, _ := this.getNumericValue("depth")
-	speed, _ := this.getNumericValue("speed")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	depth, _ := params.numericValue("depth")
+	speed, _ := params.numericValue("speed")
+	sync, _ := params.discreteValue("sync")
+	tempo := atomic.LoadUint32(&this.tempo)
 	depthFloat := 0.01 * float64(depth)
 
 	/*
@@ -34,6 +44,15 @@ func (this *flanger) Process(in []float64, out []float64, sampleRate uint32) {
 
 	speedFloat := float64(speed)
 	angularSpeed := MATH_TWO_PI_HUNDREDTH * speedFloat
+	syncedFrequency := noteValueToFrequency(sync, tempo)
+
+	/*
+	 * If the modulation is synchronized to the tempo, it ignores the speed.
+	 */
+	if syncedFrequency > 0.0 {
+		angularSpeed = MATH_TWO_PI * syncedFrequency
+	}
+
 	sampleRateFloat := float64(sampleRate)
 	sampleRateFloatInv := 1.0 / sampleRateFloat
 	maxDelaySamplesFloat := math.Floor((0.002 * sampleRateFloat) + 0.5)
@@ -98,12 +117,12 @@ func (this *flanger) Process(in []float64, out []float64, sampleRate uint32) {
 		out[i] = (0.5 * sample) + (0.5 * delayedSample)
 	}
 
-	bufferSizeFloat := float64(bufferSize)
-	duration := bufferSizeFloat * sampleRateFloatInv
+	numSamples := len(in)
+	numSamplesFloat := float64(numSamples)
+	duration := numSamplesFloat * sampleRateFloatInv
 	phaseIncrement := angularSpeed * duration
 	updatedPhase := previousPhase + phaseIncrement
 	this.previousPhase = math.Mod(updatedPhase, MATH_TWO_PI)
-	numSamples := len(in)
 	boundary := bufferSize - numSamples
 
 	/*
@@ -150,6 +169,7 @@ func createFlanger() Unit {
 					DiscreteValueIndex: -1,
 					DiscreteValues:     nil,
 				},
+				createSyncParameter(),
 			},
 		},
 	}
@@ -14,29 +14,36 @@ type fuzz struct {
 	bufferOut                []float64
 	oversamplerTwo           oversampling.OversamplerDecimator
 	oversamplerFour          oversampling.OversamplerDecimator
+	oversamplerEight         oversampling.OversamplerDecimator
 	envelope                 float64
 	couplingCapacitorVoltage float64
+	gain                     smoothedValue
+	level                    smoothedValue
 }
 
 /*
  * Internal (oversampled) fuzz audio processing.
  */
 func (this *fuzz) processOversampled(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	follow, _ := this.getDiscreteValue("follow")
-	bias, _ := this.getNumericValue("bias")
-	boost, _ := this.getNumericValue("boost")
-	gain, _ := this.getNumericValue("gain")
-	fuzz, _ := this.getNumericValue("fuzz")
-	level, _ := this.getNumericValue("level")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	follow, _ := params.discreteValue("follow")
+	bias, _ := params.numericValue("bias")
+	boost, _ := params.numericValue("boost")
+	gain, _ := params.numericValue("gain")
+	fuzz, _ := params.numericValue("fuzz")
+	level, _ := params.numericValue("level")
 	biasFloat := float64(bias)
 	biasFactor := 0.01 * biasFloat
-	gainFactor := decibelsToFactor(boost + gain)
+	gainTarget := decibelsToFactor(boost + gain)
 	fuzzFloat := float64(fuzz)
 	fuzzFactor := 0.01 * fuzzFloat
 	fuzzFactorInv := 1.0 - fuzzFactor
-	levelFactor := decibelsToFactor(level)
+	levelTarget := decibelsToFactor(level)
+	smoothingSamples := this.smoothingSamples(sampleRate)
+	gainRamp := &this.gain
+	gainRamp.setTarget(gainTarget, smoothingSamples)
+	levelRamp := &this.level
+	levelRamp.setTarget(levelTarget, smoothingSamples)
 	envelope := this.envelope
 	couplingCapacitorVoltage := this.couplingCapacitorVoltage
 	sampleRateFloat := float64(sampleRate)
@@ -48,6 +55,8 @@ func (this *fuzz) processOversampled(in []float64, out []float64, sampleRate uin
 	 * Process each sample.
 	 */
 	for i, sample := range in {
+		gainFactor := gainRamp.next()
+		levelFactor := levelRamp.next()
 		sampleAbs := math.Abs(sample)
 
 		/*
@@ -107,23 +116,37 @@ func (this *fuzz) processOversampled(in []float64, out []float64, sampleRate uin
 	this.couplingCapacitorVoltage = couplingCapacitorVoltage
 }
 
+/*
+ * Returns the latency (in samples) of the fuzz.
+ */
+func (this *fuzz) Latency(sampleRate uint32) uint32 {
+	params := this.processingParameters()
+	oversampling, _ := params.discreteValue("oversampling")
+	oversamplerTwo := this.oversamplerTwo
+	oversamplerFour := this.oversamplerFour
+	oversamplerEight := this.oversamplerEight
+	latency := oversamplingLatency(oversampling, oversamplerTwo, oversamplerFour, oversamplerEight)
+	return latency
+}
+
 /*
  * Fuzz audio processing.
  */
 func (this *fuzz) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	oversampling, _ := this.getDiscreteValue("oversampling")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	oversampling, _ := params.discreteValue("oversampling")
 	factor := 1
 
 	/*
-	 * Enable two- or four-times oversampling.
+	 * Enable two-, four- or eight-times oversampling.
 	 */
 	switch oversampling {
 	case "2":
 		factor = 2
 	case "4":
 		factor = 4
+	case "8":
+		factor = 8
 	}
 
 	/*
@@ -160,6 +183,8 @@ func (this *fuzz) Process(in []float64, out []float64, sampleRate uint32) {
 		 */
 		if factor == 4 {
 			oversampler = this.oversamplerFour
+		} else if factor == 8 {
+			oversampler = this.oversamplerEight
 		}
 
 		oversampler.Oversample(in, bufferIn)
@@ -179,6 +204,7 @@ func (this *fuzz) Process(in []float64, out []float64, sampleRate uint32) {
 func createFuzz() Unit {
 	oversamplerTwo := oversampling.CreateOversamplerDecimator(2)
 	oversamplerFour := oversampling.CreateOversamplerDecimator(4)
+	oversamplerEight := oversampling.CreateOversamplerDecimator(8)
 
 	/*
 	 * Create effects unit.
@@ -262,12 +288,14 @@ func createFuzz() Unit {
 						"- NONE -",
 						"2",
 						"4",
+						"8",
 					},
 				},
 			},
 		},
-		oversamplerTwo:  oversamplerTwo,
-		oversamplerFour: oversamplerFour,
+		oversamplerTwo:   oversamplerTwo,
+		oversamplerFour:  oversamplerFour,
+		oversamplerEight: oversamplerEight,
 	}
 
 	return &u
@@ -0,0 +1,496 @@
+package effects
+
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	MULTITAP_DELAY_MAX_TIME = 2000
+	MULTITAP_DELAY_TAPS     = 4
+)
+
+/*
+ * Data structure representing a single tap of a multi-tap delay.
+ */
+type delayTapStruct struct {
+	delaySamples   int
+	levelFactor    float64
+	feedbackFactor float64
+}
+
+/*
+ * Data structure holding the names of the parameters of a single tap of a
+ * multi-tap delay.
+ */
+type delayTapNamesStruct struct {
+	time     string
+	level    string
+	feedback string
+}
+
+/*
+ * Global variables.
+ */
+var g_delayTapNames []delayTapNamesStruct = generateDelayTapNames() // Names of the parameters of each tap.
+
+/*
+ * Data structure representing a multi-tap delay effect.
+ */
+type multitapDelay struct {
+	unitStruct
+	tempo       uint32
+	taps        [MULTITAP_DELAY_TAPS]delayTapStruct
+	buffer      []float64
+	bufferRight []float64
+	writePtr    int
+	level       smoothedValue
+}
+
+/*
+ * Generates the names of the parameters of each tap of a multi-tap delay.
+ */
+func generateDelayTapNames() []delayTapNamesStruct {
+	names := make([]delayTapNamesStruct, MULTITAP_DELAY_TAPS)
+
+	/*
+	 * Name the parameters of each tap.
+	 */
+	for i := range names {
+		tapId := uint64(i + 1)
+		tapIdString := strconv.FormatUint(tapId, 10)
+		prefix := "tap_" + tapIdString + "_"
+		names[i].time = prefix + "time"
+		names[i].level = prefix + "level"
+		names[i].feedback = prefix + "feedback"
+	}
+
+	return names
+}
+
+/*
+ * Returns the length of a note value in beats (quarter notes).
+ */
+func noteValueToBeats(noteValue string) float64 {
+
+	/*
+	 * Look up the length of the note value.
+	 */
+	switch noteValue {
+	case "1/1":
+		return 4.0
+	case "1/2":
+		return 2.0
+	case "1/4":
+		return 1.0
+	case "1/4.":
+		return 1.5
+	case "1/8":
+		return 0.5
+	case "1/8.":
+		return 0.75
+	case "1/8T":
+		return 1.0 / 3.0
+	case "1/16":
+		return 0.25
+	default:
+		return 0.0
+	}
+
+}
+
+/*
+ * Returns the frequency (in Hz) at which a modulation repeats once per note
+ * value at a certain tempo (in beats per minute), or zero if the modulation is
+ * not synchronized to the tempo.
+ */
+func noteValueToFrequency(noteValue string, tempo uint32) float64 {
+	beats := noteValueToBeats(noteValue)
+
+	/*
+	 * Check if the modulation is synchronized to the tempo.
+	 */
+	if (beats <= 0.0) || (tempo == 0) {
+		return 0.0
+	} else {
+		tempoFloat := float64(tempo)
+		return tempoFloat / (60.0 * beats)
+	}
+
+}
+
+/*
+ * Creates a parameter which synchronizes an effects unit to the tempo.
+ */
+func createSyncParameter() Parameter {
+
+	/*
+	 * The parameter selects a note value or none.
+	 */
+	param := Parameter{
+		Name:               "sync",
+		Type:               PARAMETER_TYPE_DISCRETE,
+		PhysicalUnit:       "",
+		Minimum:            -1,
+		Maximum:            -1,
+		NumericValue:       -1,
+		DiscreteValueIndex: 0,
+		DiscreteValues: []string{
+			STRING_NONE,
+			"1/1",
+			"1/2",
+			"1/4",
+			"1/4.",
+			"1/8",
+			"1/8.",
+			"1/8T",
+			"1/16",
+		},
+	}
+
+	return param
+}
+
+/*
+ * Limit a sample to the appropriate range.
+ */
+func limitSample(sample float64) float64 {
+
+	/*
+	 * Limit the sample to the appropriate range.
+	 */
+	if sample < -1.0 {
+		return -1.0
+	} else if sample > 1.0 {
+		return 1.0
+	} else {
+		return sample
+	}
+
+}
+
+/*
+ * Sets the tempo (in beats per minute) the delay synchronizes to.
+ */
+func (this *multitapDelay) SetTempo(bpm uint32) {
+	atomic.StoreUint32(&this.tempo, bpm)
+}
+
+/*
+ * Calculates the taps of the delay, reports whether the delay operates in
+ * ping-pong mode and returns the master level.
+ *
+ * The taps are stored in the delay itself, so that no memory is allocated on
+ * the audio thread.
+ */
+func (this *multitapDelay) prepare(sampleRate uint32) ([]delayTapStruct, bool, float64) {
+	taps := this.taps[:]
+	sampleRateFloat := float64(sampleRate)
+	maxTimeFloat := float64(MULTITAP_DELAY_MAX_TIME)
+	params := this.processingParameters()
+	mode, _ := params.discreteValue("mode")
+	sync, _ := params.discreteValue("sync")
+	level, _ := params.numericValue("level")
+	tempo := atomic.LoadUint32(&this.tempo)
+	beats := noteValueToBeats(sync)
+
+	/*
+	 * Calculate the properties of each tap.
+	 */
+	for i := range taps {
+		tapId := uint64(i + 1)
+		names := g_delayTapNames[i]
+		delayTime, _ := params.numericValue(names.time)
+		tapLevel, _ := params.numericValue(names.level)
+		tapFeedback, _ := params.numericValue(names.feedback)
+		delayTimeFloat := float64(delayTime)
+
+		/*
+		 * If the delay is synchronized to the tempo, the n-th tap is
+		 * placed n note values after the original signal.
+		 */
+		if (beats > 0.0) && (tempo > 0) {
+			tempoFloat := float64(tempo)
+			tapIdFloat := float64(tapId)
+			delayTimeFloat = (60000.0 * tapIdFloat * beats) / tempoFloat
+
+			/*
+			 * Make sure that the delay time does not exceed the maximum.
+			 */
+			if delayTimeFloat > maxTimeFloat {
+				delayTimeFloat = maxTimeFloat
+			}
+
+		}
+
+		delayTimeSeconds := 0.001 * delayTimeFloat
+		delaySamplesFloat := math.Floor((delayTimeSeconds * sampleRateFloat) + 0.5)
+		delaySamples := int(delaySamplesFloat)
+
+		/*
+		 * A tap cannot read the sample which is currently being written.
+		 */
+		if delaySamples < 1 {
+			delaySamples = 1
+		}
+
+		taps[i].delaySamples = delaySamples
+		taps[i].levelFactor = decibelsToFactor(tapLevel)
+		taps[i].feedbackFactor = decibelsToFactor(tapFeedback)
+	}
+
+	pingPong := mode == "ping_pong"
+	levelFactor := decibelsToFactor(level)
+	return taps, pingPong, levelFactor
+}
+
+/*
+ * Makes sure that the delay lines can hold the maximum delay time.
+ */
+func (this *multitapDelay) prepareBuffers(sampleRate uint32) {
+	sampleRateFloat := float64(sampleRate)
+	maxTimeSeconds := 0.001 * float64(MULTITAP_DELAY_MAX_TIME)
+	maxSamplesFloat := math.Ceil(maxTimeSeconds * sampleRateFloat)
+	bufferSize := int(maxSamplesFloat) + 1
+
+	/*
+	 * Make sure the buffers have the appropriate size.
+	 */
+	if len(this.buffer) != bufferSize {
+		this.buffer = make([]float64, bufferSize)
+		this.bufferRight = make([]float64, bufferSize)
+		this.writePtr = 0
+	}
+
+}
+
+/*
+ * Multi-tap delay audio processing.
+ */
+func (this *multitapDelay) Process(in []float64, out []float64, sampleRate uint32) {
+	taps, _, levelTarget := this.prepare(sampleRate)
+	this.prepareBuffers(sampleRate)
+	smoothingSamples := this.smoothingSamples(sampleRate)
+	levelRamp := &this.level
+	levelRamp.setTarget(levelTarget, smoothingSamples)
+	buffer := this.buffer
+	bufferSize := len(buffer)
+	writePtr := this.writePtr
+
+	/*
+	 * Mix the straight output with the delayed signal.
+	 */
+	for i, sample := range in {
+		wet := float64(0.0)
+		feedback := float64(0.0)
+
+		/*
+		 * Read the delayed signal from each tap.
+		 */
+		for _, tap := range taps {
+			readPtr := (writePtr + bufferSize - tap.delaySamples) % bufferSize
+			delayedSample := buffer[readPtr]
+			wet += tap.levelFactor * delayedSample
+			feedback += tap.feedbackFactor * delayedSample
+		}
+
+		buffer[writePtr] = limitSample(sample + feedback)
+		writePtr = (writePtr + 1) % bufferSize
+		levelFactor := levelRamp.next()
+		pre := levelFactor * (sample + wet)
+		out[i] = limitSample(pre)
+	}
+
+	this.writePtr = writePtr
+}
+
+/*
+ * Multi-tap delay stereo audio processing.
+ *
+ * In ping-pong mode, both channels feed a common delay line and the taps
+ * alternate between the left and the right output, so that repeats bounce
+ * between both sides.
+ */
+func (this *multitapDelay) ProcessStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32) {
+	taps, pingPong, levelTarget := this.prepare(sampleRate)
+	this.prepareBuffers(sampleRate)
+	smoothingSamples := this.smoothingSamples(sampleRate)
+	levelRamp := &this.level
+	levelRamp.setTarget(levelTarget, smoothingSamples)
+	bufferLeft := this.buffer
+	bufferRight := this.bufferRight
+	bufferSize := len(bufferLeft)
+	writePtr := this.writePtr
+
+	/*
+	 * Mix the straight output with the delayed signal.
+	 */
+	for i, sampleLeft := range inLeft {
+		sampleRight := inRight[i]
+		wetLeft := float64(0.0)
+		wetRight := float64(0.0)
+		feedbackLeft := float64(0.0)
+		feedbackRight := float64(0.0)
+
+		/*
+		 * Read the delayed signal from each tap.
+		 */
+		for j, tap := range taps {
+			readPtr := (writePtr + bufferSize - tap.delaySamples) % bufferSize
+			delayedLeft := bufferLeft[readPtr]
+
+			/*
+			 * In ping-pong mode, even taps go to the left and odd taps
+			 * to the right output.
+			 */
+			if pingPong {
+				feedbackLeft += tap.feedbackFactor * delayedLeft
+
+				/*
+				 * Decide on which side the tap appears.
+				 */
+				if (j % 2) == 0 {
+					wetLeft += tap.levelFactor * delayedLeft
+				} else {
+					wetRight += tap.levelFactor * delayedLeft
+				}
+
+			} else {
+				delayedRight := bufferRight[readPtr]
+				wetLeft += tap.levelFactor * delayedLeft
+				wetRight += tap.levelFactor * delayedRight
+				feedbackLeft += tap.feedbackFactor * delayedLeft
+				feedbackRight += tap.feedbackFactor * delayedRight
+			}
+
+		}
+
+		/*
+		 * In ping-pong mode, both channels enter a common delay line.
+		 */
+		if pingPong {
+			mid := 0.5 * (sampleLeft + sampleRight)
+			bufferLeft[writePtr] = limitSample(mid + feedbackLeft)
+		} else {
+			bufferLeft[writePtr] = limitSample(sampleLeft + feedbackLeft)
+			bufferRight[writePtr] = limitSample(sampleRight + feedbackRight)
+		}
+
+		writePtr = (writePtr + 1) % bufferSize
+		levelFactor := levelRamp.next()
+		preLeft := levelFactor * (sampleLeft + wetLeft)
+		preRight := levelFactor * (sampleRight + wetRight)
+		outLeft[i] = limitSample(preLeft)
+		outRight[i] = limitSample(preRight)
+	}
+
+	this.writePtr = writePtr
+}
+
+/*
+ * Create a multi-tap delay effects unit.
+ */
+func createMultitapDelay() Unit {
+
+	/*
+	 * Parameters controlling the delay as a whole.
+	 */
+	params := []Parameter{
+		Parameter{
+			Name:               "mode",
+			Type:               PARAMETER_TYPE_DISCRETE,
+			PhysicalUnit:       "",
+			Minimum:            -1,
+			Maximum:            -1,
+			NumericValue:       -1,
+			DiscreteValueIndex: 0,
+			DiscreteValues: []string{
+				"normal",
+				"ping_pong",
+			},
+		},
+		createSyncParameter(),
+		Parameter{
+			Name:               "level",
+			Type:               PARAMETER_TYPE_NUMERIC,
+			PhysicalUnit:       "dB",
+			Minimum:            -30,
+			Maximum:            0,
+			NumericValue:       -5,
+			DiscreteValueIndex: -1,
+			DiscreteValues:     nil,
+		},
+	}
+
+	/*
+	 * Create the parameters for each tap.
+	 */
+	for i := 0; i < MULTITAP_DELAY_TAPS; i++ {
+		tapId := uint64(i + 1)
+		names := g_delayTapNames[i]
+		delayTime := int32(250 * tapId)
+		level := int32(-6 - (3 * i))
+		feedback := int32(-60)
+
+		/*
+		 * Only the last tap feeds back by default.
+		 */
+		if i == (MULTITAP_DELAY_TAPS - 1) {
+			feedback = -10
+		}
+
+		/*
+		 * Parameters controlling the current tap.
+		 */
+		tapParams := []Parameter{
+			Parameter{
+				Name:               names.time,
+				Type:               PARAMETER_TYPE_NUMERIC,
+				PhysicalUnit:       "ms",
+				Minimum:            0,
+				Maximum:            MULTITAP_DELAY_MAX_TIME,
+				NumericValue:       delayTime,
+				DiscreteValueIndex: -1,
+				DiscreteValues:     nil,
+			},
+			Parameter{
+				Name:               names.level,
+				Type:               PARAMETER_TYPE_NUMERIC,
+				PhysicalUnit:       "dB",
+				Minimum:            -60,
+				Maximum:            0,
+				NumericValue:       level,
+				DiscreteValueIndex: -1,
+				DiscreteValues:     nil,
+			},
+			Parameter{
+				Name:               names.feedback,
+				Type:               PARAMETER_TYPE_NUMERIC,
+				PhysicalUnit:       "dB",
+				Minimum:            -60,
+				Maximum:            0,
+				NumericValue:       feedback,
+				DiscreteValueIndex: -1,
+				DiscreteValues:     nil,
+			},
+		}
+
+		params = append(params, tapParams...)
+	}
+
+	/*
+	 * Create effects unit.
+	 */
+	u := multitapDelay{
+		unitStruct: unitStruct{
+			unitType: UNIT_MULTITAP_DELAY,
+			params:   params,
+		},
+	}
+
+	return &u
+}
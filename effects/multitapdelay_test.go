@@ -0,0 +1,122 @@
+package effects
+
+import (
+	"math"
+	"testing"
+)
+
+/*
+ * Verify that a multi-tap delay repeats an impulse after the time of its
+ * tap, bounces repeats between both sides in ping-pong mode and stays within
+ * range for extreme parameters.
+ */
+func TestMultitapDelay(t *testing.T) {
+	u := CreateUnit(UNIT_MULTITAP_DELAY)
+	u.SetNumericValue("level", 0)
+	u.SetNumericValue("tap_1_time", 10)
+	u.SetNumericValue("tap_1_level", 0)
+	u.SetNumericValue("tap_1_feedback", -60)
+
+	/*
+	 * Silence all other taps.
+	 */
+	for i := 1; i < MULTITAP_DELAY_TAPS; i++ {
+		names := g_delayTapNames[i]
+		u.SetNumericValue(names.level, -60)
+		u.SetNumericValue(names.feedback, -60)
+	}
+
+	n := 1024
+	in := make([]float64, n)
+	out := make([]float64, n)
+	in[0] = 0.5
+	u.Process(in, out, TEST_SAMPLE_RATE)
+	delay := 480
+
+	/*
+	 * The impulse passes through straight and is repeated once after ten
+	 * milliseconds, while the other taps are too late to appear.
+	 */
+	for i, sample := range out {
+		expected := 0.0
+
+		/*
+		 * Check if we expect the impulse or its repeat.
+		 */
+		if (i == 0) || (i == delay) {
+			expected = 0.5
+		}
+
+		/*
+		 * Check if we found a significant difference.
+		 */
+		if math.Abs(sample-expected) > 1e-3 {
+			t.Errorf("Sample %d should be %f, but is %f.", i, expected, sample)
+		}
+
+	}
+
+	stereo := CreateUnit(UNIT_MULTITAP_DELAY)
+	stereo.SetDiscreteValue("mode", "ping_pong")
+	stereo.SetNumericValue("level", 0)
+
+	/*
+	 * Let the first two taps repeat the impulse at full level.
+	 */
+	for i := 0; i < MULTITAP_DELAY_TAPS; i++ {
+		names := g_delayTapNames[i]
+		timeMs := int32(10 * (i + 1))
+		stereo.SetNumericValue(names.time, timeMs)
+		stereo.SetNumericValue(names.feedback, -60)
+
+		/*
+		 * Silence the other taps.
+		 */
+		if i < 2 {
+			stereo.SetNumericValue(names.level, 0)
+		} else {
+			stereo.SetNumericValue(names.level, -60)
+		}
+
+	}
+
+	stereoUnit := stereo.(StereoUnit)
+	inRight := make([]float64, n)
+	outRight := make([]float64, n)
+	inRight[0] = 0.5
+	stereoUnit.ProcessStereo(in, inRight, out, outRight, TEST_SAMPLE_RATE)
+
+	/*
+	 * The first repeat appears on the left, the second on the right.
+	 */
+	if math.Abs(out[delay]-0.5) > 1e-3 || math.Abs(outRight[delay]) > 1e-3 {
+		t.Errorf("First repeat should only appear on the left, but is (%f, %f).", out[delay], outRight[delay])
+	}
+
+	if math.Abs(out[2*delay]) > 1e-3 || math.Abs(outRight[2*delay]-0.5) > 1e-3 {
+		t.Errorf("Second repeat should only appear on the right, but is (%f, %f).", out[2*delay], outRight[2*delay])
+	}
+
+	extreme := CreateUnit(UNIT_MULTITAP_DELAY)
+	extreme.SetNumericValue("level", 0)
+
+	/*
+	 * Feed back all taps at full level after the shortest time.
+	 */
+	for i := 0; i < MULTITAP_DELAY_TAPS; i++ {
+		names := g_delayTapNames[i]
+		extreme.SetNumericValue(names.time, 0)
+		extreme.SetNumericValue(names.level, 0)
+		extreme.SetNumericValue(names.feedback, 0)
+	}
+
+	noise := createNoise(n, 1)
+	noiseRight := createNoise(n, 2)
+	extreme.Process(noise, out, TEST_SAMPLE_RATE)
+	checkOutput(t, "mono", out)
+	extreme.SetDiscreteValue("mode", "ping_pong")
+	extremeStereo := extreme.(StereoUnit)
+	extremeStereo.ProcessStereo(noise, noiseRight, out, outRight, TEST_SAMPLE_RATE)
+	checkOutput(t, "left", out)
+	checkOutput(t, "right", outRight)
+}
@@ -4,24 +4,60 @@ import (
 	"math"
 )
 
+/*
+ * Global constants.
+ */
+const (
+	NOISEGATE_SIDECHAIN_ORDER = 2
+)
+
 /*
  * Data structure representing a noise gate effect.
  */
 type noiseGate struct {
 	unitStruct
-	gateOpen    bool
-	onHoldSince uint32
+	gateOpen                     bool
+	onHoldSince                  uint32
+	gain                         float64
+	sidechainHighpassCapVoltages [NOISEGATE_SIDECHAIN_ORDER]float64
+}
+
+/*
+ * Calculates the change in gain per sample for a ramp of a certain length.
+ */
+func rampStep(timeMs int32, sampleRate uint32) float64 {
+	timeFloat := float64(timeMs)
+	timeSeconds := 0.001 * timeFloat
+	sampleRateFloat := float64(sampleRate)
+	samples := timeSeconds * sampleRateFloat
+
+	/*
+	 * A ramp shorter than a sample is a step.
+	 */
+	if samples < 1.0 {
+		return 1.0
+	} else {
+		return 1.0 / samples
+	}
+
 }
 
 /*
  * Noise gate audio processing.
+ *
+ * The gate opens when the (high-pass filtered) sidechain signal exceeds the
+ * opening threshold and closes once it stayed below the closing threshold
+ * for the hold time. The gain then ramps up over the attack time and down
+ * over the release time.
  */
 func (this *noiseGate) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	levelOpen, _ := this.getNumericValue("threshold_open")
-	levelClose, _ := this.getNumericValue("threshold_close")
-	holdTime, _ := this.getNumericValue("hold_time")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	levelOpen, _ := params.numericValue("threshold_open")
+	levelClose, _ := params.numericValue("threshold_close")
+	attackTime, _ := params.numericValue("attack_time")
+	holdTime, _ := params.numericValue("hold_time")
+	releaseTime, _ := params.numericValue("release_time")
+	sidechainCutoff, _ := params.numericValue("sidechain_cutoff")
 	facOpen := decibelsToFactor(levelOpen)
 	facClose := decibelsToFactor(levelClose)
 
@@ -33,20 +69,40 @@ func (this *noiseGate) Process(in []float64, out []float64, sampleRate uint32) {
 		copy(out, in)
 		this.gateOpen = true
 		this.onHoldSince = 0
+		this.gain = 1.0
 	} else {
 		holdTimeFloat := float64(holdTime)
 		holdTimeSeconds := 0.001 * holdTimeFloat
 		sampleRateFloat := float64(sampleRate)
 		holdSamplesFloat := math.Floor((holdTimeSeconds * sampleRateFloat) + 0.5)
 		holdSamples := uint32(holdSamplesFloat)
+		attackStep := rampStep(attackTime, sampleRate)
+		releaseStep := rampStep(releaseTime, sampleRate)
+		sidechainCutoffFloat := float64(sidechainCutoff)
+		dischargePerSampleArg := (-MATH_TWO_PI * sidechainCutoffFloat) / sampleRateFloat
+		dischargePerSample := math.Exp(dischargePerSampleArg)
+		dischargePerSampleInv := 1.0 - dischargePerSample
+		hcvs := this.sidechainHighpassCapVoltages
 		gateOpen := this.gateOpen
 		onHoldSince := this.onHoldSince
+		gain := this.gain
 
 		/*
 		 * Process each sample.
 		 */
 		for i, sample := range in {
-			amplitude := math.Abs(sample)
+			sidechain := sample
+
+			/*
+			 * Remove low frequencies (e. g. hum) from the sidechain signal.
+			 */
+			for j, hcv := range hcvs {
+				diff := sidechain - hcv
+				hcvs[j] = hcv + (diff * dischargePerSampleInv)
+				sidechain = diff
+			}
+
+			amplitude := math.Abs(sidechain)
 
 			/*
 			 * Check if amplitude is above opening threshold.
@@ -69,16 +125,17 @@ func (this *noiseGate) Process(in []float64, out []float64, sampleRate uint32) {
 				gateOpen = false
 			}
 
-			fac := float64(0.0)
-
 			/*
-			 * Check if gate is open.
+			 * Ramp the gain up while the gate is open and down while it is
+			 * closed.
 			 */
 			if gateOpen {
-				fac = 1.0
+				gain = math.Min(gain+attackStep, 1.0)
+			} else {
+				gain = math.Max(gain-releaseStep, 0.0)
 			}
 
-			out[i] = fac * sample
+			out[i] = gain * sample
 
 			/*
 			 * Increment time on hold, unless it overflows.
@@ -89,8 +146,10 @@ func (this *noiseGate) Process(in []float64, out []float64, sampleRate uint32) {
 
 		}
 
+		this.sidechainHighpassCapVoltages = hcvs
 		this.gateOpen = gateOpen
 		this.onHoldSince = onHoldSince
+		this.gain = gain
 	}
 
 }
@@ -127,6 +186,16 @@ func createNoiseGate() Unit {
 					DiscreteValueIndex: -1,
 					DiscreteValues:     nil,
 				},
+				Parameter{
+					Name:               "attack_time",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "ms",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       1,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
 				Parameter{
 					Name:               "hold_time",
 					Type:               PARAMETER_TYPE_NUMERIC,
@@ -137,6 +206,26 @@ func createNoiseGate() Unit {
 					DiscreteValueIndex: -1,
 					DiscreteValues:     nil,
 				},
+				Parameter{
+					Name:               "release_time",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "ms",
+					Minimum:            0,
+					Maximum:            2000,
+					NumericValue:       100,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "sidechain_cutoff",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "Hz",
+					Minimum:            0,
+					Maximum:            500,
+					NumericValue:       0,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
 			},
 		},
 	}
@@ -0,0 +1,107 @@
+package effects
+
+import (
+	"math"
+	"testing"
+)
+
+/*
+ * Verify that a noise gate passes loud signals unchanged, mutes quiet signals
+ * once the hold time elapsed and stays finite for extreme parameters.
+ */
+func TestNoiseGate(t *testing.T) {
+	u := CreateUnit(UNIT_NOISEGATE)
+	u.SetNumericValue("threshold_open", -20)
+	u.SetNumericValue("threshold_close", -40)
+	u.SetNumericValue("attack_time", 0)
+	u.SetNumericValue("hold_time", 10)
+	u.SetNumericValue("release_time", 0)
+	nLoud := 4800
+	nQuiet := 9600
+	n := nLoud + nQuiet
+	in := make([]float64, n)
+	out := make([]float64, n)
+
+	/*
+	 * Generate a loud sine wave, followed by a quiet one.
+	 */
+	for i := range in {
+		iFloat := float64(i)
+		arg := (2.0 * math.Pi * 1000.0 * iFloat) / TEST_SAMPLE_RATE
+		amplitude := 0.5
+
+		/*
+		 * The quiet part lies below the closing threshold.
+		 */
+		if i >= nLoud {
+			amplitude = 0.001
+		}
+
+		in[i] = amplitude * math.Sin(arg)
+	}
+
+	u.Process(in, out, TEST_SAMPLE_RATE)
+	closed := nLoud + 480
+
+	/*
+	 * The gate opens within the first period of the loud signal and
+	 * closes once the quiet signal lasted for the hold time.
+	 */
+	for i := 48; i < n; i++ {
+		expected := in[i]
+
+		/*
+		 * Check if the gate should be closed.
+		 */
+		if i >= closed {
+			expected = 0.0
+		}
+
+		/*
+		 * Allow the gate to close one sample early.
+		 */
+		if i == (closed - 1) {
+			continue
+		}
+
+		/*
+		 * Check if we found a significant difference.
+		 */
+		if math.Abs(out[i]-expected) > 1e-9 {
+			t.Errorf("Sample %d should be %f, but is %f.", i, expected, out[i])
+			break
+		}
+
+	}
+
+	quiet := CreateUnit(UNIT_NOISEGATE)
+	quietIn := in[nLoud:]
+	quietOut := out[nLoud:]
+	quiet.Process(quietIn, quietOut, TEST_SAMPLE_RATE)
+
+	/*
+	 * A quiet signal never opens the gate.
+	 */
+	for i, sample := range quietOut {
+
+		/*
+		 * Check if any signal passed the gate.
+		 */
+		if sample != 0.0 {
+			t.Errorf("Sample %d should be muted, but is %f.", i, sample)
+			break
+		}
+
+	}
+
+	extreme := CreateUnit(UNIT_NOISEGATE)
+	extreme.SetNumericValue("threshold_open", -60)
+	extreme.SetNumericValue("threshold_close", -60)
+	extreme.SetNumericValue("attack_time", 100)
+	extreme.SetNumericValue("hold_time", 1000)
+	extreme.SetNumericValue("release_time", 2000)
+	extreme.SetNumericValue("sidechain_cutoff", 500)
+	noise := createNoise(n, 1)
+	extreme.Process(noise, out, TEST_SAMPLE_RATE)
+	checkOutput(t, "extreme", out)
+}
@@ -19,15 +19,14 @@ type octaver struct {
  * Octaver audio processing.
  */
 func (this *octaver) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	follow, _ := this.getDiscreteValue("follow")
-	levelOctaveUp, _ := this.getNumericValue("level_octave_up")
-	levelClean, _ := this.getNumericValue("level_clean")
-	levelDist, _ := this.getNumericValue("level_dist")
-	levelOctaveDownFirst, _ := this.getNumericValue("level_octave_down_first")
-	levelOctaveDownSecond, _ := this.getNumericValue("level_octave_down_second")
-	levelHysteresis, _ := this.getNumericValue("level_hysteresis")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	follow, _ := params.discreteValue("follow")
+	levelOctaveUp, _ := params.numericValue("level_octave_up")
+	levelClean, _ := params.numericValue("level_clean")
+	levelDist, _ := params.numericValue("level_dist")
+	levelOctaveDownFirst, _ := params.numericValue("level_octave_down_first")
+	levelOctaveDownSecond, _ := params.numericValue("level_octave_down_second")
+	levelHysteresis, _ := params.numericValue("level_hysteresis")
 	facOctaveUp := decibelsToFactor(levelOctaveUp)
 	facClean := decibelsToFactor(levelClean)
 	facDist := decibelsToFactor(levelDist)
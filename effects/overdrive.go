@@ -16,29 +16,36 @@ const (
  */
 type overdrive struct {
 	unitStruct
-	bufferIn        []float64
-	bufferOut       []float64
-	oversamplerTwo  oversampling.OversamplerDecimator
-	oversamplerFour oversampling.OversamplerDecimator
+	bufferIn         []float64
+	bufferOut        []float64
+	oversamplerTwo   oversampling.OversamplerDecimator
+	oversamplerFour  oversampling.OversamplerDecimator
+	oversamplerEight oversampling.OversamplerDecimator
+	gain             smoothedValue
+	level            smoothedValue
 }
 
 /*
  * Internal (oversampled) overdrive audio processing.
  */
 func (this *overdrive) processOversampled(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	boost, _ := this.getNumericValue("boost")
-	gain, _ := this.getNumericValue("gain")
-	drive, _ := this.getNumericValue("drive")
-	level, _ := this.getNumericValue("level")
-	valve, _ := this.getDiscreteValue("valve")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	boost, _ := params.numericValue("boost")
+	gain, _ := params.numericValue("gain")
+	drive, _ := params.numericValue("drive")
+	level, _ := params.numericValue("level")
+	valve, _ := params.discreteValue("valve")
 	totalGain := boost + gain
-	gainFactor := decibelsToFactor(totalGain)
+	gainTarget := decibelsToFactor(totalGain)
 	driveFloat := float64(drive)
 	driveFactor := 0.01 * driveFloat
 	cleanFactor := 1.0 - driveFactor
-	levelFactor := decibelsToFactor(level)
+	levelTarget := decibelsToFactor(level)
+	smoothingSamples := this.smoothingSamples(sampleRate)
+	gainRamp := &this.gain
+	gainRamp.setTarget(gainTarget, smoothingSamples)
+	levelRamp := &this.level
+	levelRamp.setTarget(levelTarget, smoothingSamples)
 	valveType := int(VALVE_TYPE_INVALID)
 
 	/*
@@ -55,6 +62,8 @@ func (this *overdrive) processOversampled(in []float64, out []float64, sampleRat
 	 * Process each sample.
 	 */
 	for i, sample := range in {
+		gainFactor := gainRamp.next()
+		levelFactor := levelRamp.next()
 		arg := gainFactor * sample
 		dist := 0.0
 
@@ -77,23 +86,37 @@ func (this *overdrive) processOversampled(in []float64, out []float64, sampleRat
 
 }
 
+/*
+ * Returns the latency (in samples) of the overdrive.
+ */
+func (this *overdrive) Latency(sampleRate uint32) uint32 {
+	params := this.processingParameters()
+	oversampling, _ := params.discreteValue("oversampling")
+	oversamplerTwo := this.oversamplerTwo
+	oversamplerFour := this.oversamplerFour
+	oversamplerEight := this.oversamplerEight
+	latency := oversamplingLatency(oversampling, oversamplerTwo, oversamplerFour, oversamplerEight)
+	return latency
+}
+
 /*
  * Overdrive audio processing.
  */
 func (this *overdrive) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	oversampling, _ := this.getDiscreteValue("oversampling")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	oversampling, _ := params.discreteValue("oversampling")
 	factor := 1
 
 	/*
-	 * Enable two- or four-times oversampling.
+	 * Enable two-, four- or eight-times oversampling.
 	 */
 	switch oversampling {
 	case "2":
 		factor = 2
 	case "4":
 		factor = 4
+	case "8":
+		factor = 8
 	}
 
 	/*
@@ -130,6 +153,8 @@ func (this *overdrive) Process(in []float64, out []float64, sampleRate uint32) {
 		 */
 		if factor == 4 {
 			oversampler = this.oversamplerFour
+		} else if factor == 8 {
+			oversampler = this.oversamplerEight
 		}
 
 		oversampler.Oversample(in, bufferIn)
@@ -149,6 +174,7 @@ func (this *overdrive) Process(in []float64, out []float64, sampleRate uint32) {
 func createOverdrive() Unit {
 	oversamplerTwo := oversampling.CreateOversamplerDecimator(2)
 	oversamplerFour := oversampling.CreateOversamplerDecimator(4)
+	oversamplerEight := oversampling.CreateOversamplerDecimator(8)
 
 	/*
 	 * Create effects unit.
@@ -222,12 +248,14 @@ func createOverdrive() Unit {
 						"- NONE -",
 						"2",
 						"4",
+						"8",
 					},
 				},
 			},
 		},
-		oversamplerTwo:  oversamplerTwo,
-		oversamplerFour: oversamplerFour,
+		oversamplerTwo:   oversamplerTwo,
+		oversamplerFour:  oversamplerFour,
+		oversamplerEight: oversamplerEight,
 	}
 
 	return &u
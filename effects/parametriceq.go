@@ -0,0 +1,589 @@
+package effects
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+/*
+ * Constants for the parametric equalizer.
+ *
+ * The sections of the filter cascade are assigned to fixed slots: two for
+ * each of the low and high cut filters, one for each shelf and one for each
+ * peaking band.
+ */
+const (
+	PARAMETRIC_EQ_MAX_BANDS       = 8
+	PARAMETRIC_EQ_MAX_FRACTION    = 0.49
+	PARAMETRIC_EQ_CUT_SECTIONS    = 2
+	PARAMETRIC_EQ_SHELF_Q         = math.Sqrt2 / 2.0
+	PARAMETRIC_EQ_SLOT_LOW_CUT    = 0
+	PARAMETRIC_EQ_SLOT_HIGH_CUT   = PARAMETRIC_EQ_SLOT_LOW_CUT + PARAMETRIC_EQ_CUT_SECTIONS
+	PARAMETRIC_EQ_SLOT_LOW_SHELF  = PARAMETRIC_EQ_SLOT_HIGH_CUT + PARAMETRIC_EQ_CUT_SECTIONS
+	PARAMETRIC_EQ_SLOT_HIGH_SHELF = PARAMETRIC_EQ_SLOT_LOW_SHELF + 1
+	PARAMETRIC_EQ_SLOT_BANDS      = PARAMETRIC_EQ_SLOT_HIGH_SHELF + 1
+	PARAMETRIC_EQ_SECTIONS        = PARAMETRIC_EQ_SLOT_BANDS + PARAMETRIC_EQ_MAX_BANDS
+)
+
+/*
+ * Data structure representing a section of the filter cascade of the
+ * parametric equalizer, i. e. a biquad filter with coefficients normalized
+ * so that a0 is one. Inactive sections pass the signal unaltered.
+ */
+type parametricEqSection struct {
+	active bool
+	b0     float64
+	b1     float64
+	b2     float64
+	a1     float64
+	a2     float64
+}
+
+/*
+ * Data structure representing a parametric equalizer.
+ */
+type parametricEq struct {
+	unitStruct
+	frequencyNames [PARAMETRIC_EQ_MAX_BANDS]string
+	gainNames      [PARAMETRIC_EQ_MAX_BANDS]string
+	qNames         [PARAMETRIC_EQ_MAX_BANDS]string
+	states         [PARAMETRIC_EQ_SECTIONS][2]float64
+}
+
+/*
+ * Creates a filter section from unnormalized biquad coefficients.
+ */
+func createParametricEqSection(b0 float64, b1 float64, b2 float64, a0 float64, a1 float64, a2 float64) parametricEqSection {
+
+	/*
+	 * Create filter section.
+	 */
+	section := parametricEqSection{
+		active: true,
+		b0:     b0 / a0,
+		b1:     b1 / a0,
+		b2:     b2 / a0,
+		a1:     a1 / a0,
+		a2:     a2 / a0,
+	}
+
+	return section
+}
+
+/*
+ * Returns the complex frequency response of a filter section at a certain
+ * frequency.
+ */
+func (this *parametricEqSection) response(frequency float64, sampleRate uint32) complex128 {
+
+	/*
+	 * Inactive sections have unity gain.
+	 */
+	if !this.active {
+		return complex(1.0, 0.0)
+	} else {
+		sampleRateFloat := float64(sampleRate)
+		w := (MATH_TWO_PI * frequency) / sampleRateFloat
+		z1 := cmplx.Rect(1.0, -w)
+		z2 := z1 * z1
+		b0 := complex(this.b0, 0.0)
+		b1 := complex(this.b1, 0.0)
+		b2 := complex(this.b2, 0.0)
+		a1 := complex(this.a1, 0.0)
+		a2 := complex(this.a2, 0.0)
+		numerator := b0 + (b1 * z1) + (b2 * z2)
+		denominator := 1.0 + (a1 * z1) + (a2 * z2)
+		h := numerator / denominator
+		return h
+	}
+
+}
+
+/*
+ * Returns the angular frequency of a filter, keeping it below the Nyquist
+ * frequency.
+ */
+func parametricEqOmega(frequency int32, sampleRate uint32) float64 {
+	frequencyFloat := float64(frequency)
+	sampleRateFloat := float64(sampleRate)
+	maxFrequency := PARAMETRIC_EQ_MAX_FRACTION * sampleRateFloat
+	frequencyFloat = math.Min(frequencyFloat, maxFrequency)
+	omega := (MATH_TWO_PI * frequencyFloat) / sampleRateFloat
+	return omega
+}
+
+/*
+ * Designs the sections of a low or high cut filter with a certain slope (in
+ * decibels per octave), realized as a Butterworth filter of the matching
+ * order. Odd orders start with a first-order section.
+ */
+func parametricEqCut(sections []parametricEqSection, slope string, frequency int32, highpass bool, sampleRate uint32) {
+	order := 0
+
+	/*
+	 * Decide on the order of the filter.
+	 */
+	switch slope {
+	case "6":
+		order = 1
+	case "12":
+		order = 2
+	case "18":
+		order = 3
+	case "24":
+		order = 4
+	}
+
+	omega := parametricEqOmega(frequency, sampleRate)
+	cosOmega := math.Cos(omega)
+	sinOmega := math.Sin(omega)
+	idx := 0
+
+	/*
+	 * Odd orders require a first-order section.
+	 */
+	if (order % 2) != 0 {
+		k := math.Tan(0.5 * omega)
+
+		/*
+		 * Design either a highpass or a lowpass section.
+		 */
+		if highpass {
+			sections[idx] = createParametricEqSection(1.0, -1.0, 0.0, 1.0+k, k-1.0, 0.0)
+		} else {
+			sections[idx] = createParametricEqSection(k, k, 0.0, 1.0+k, k-1.0, 0.0)
+		}
+
+		idx++
+	}
+
+	numPairs := order / 2
+	orderFloat := float64(order)
+
+	/*
+	 * Design a second-order section for each pair of poles.
+	 */
+	for k := 1; k <= numPairs; k++ {
+		kFloat := float64(k)
+		angle := ((2.0*kFloat - 1.0) * math.Pi) / (2.0 * orderFloat)
+		q := 1.0 / (2.0 * math.Sin(angle))
+		alpha := sinOmega / (2.0 * q)
+		a0 := 1.0 + alpha
+		a1 := -2.0 * cosOmega
+		a2 := 1.0 - alpha
+
+		/*
+		 * Design either a highpass or a lowpass section.
+		 */
+		if highpass {
+			b0 := 0.5 * (1.0 + cosOmega)
+			sections[idx] = createParametricEqSection(b0, -2.0*b0, b0, a0, a1, a2)
+		} else {
+			b0 := 0.5 * (1.0 - cosOmega)
+			sections[idx] = createParametricEqSection(b0, 2.0*b0, b0, a0, a1, a2)
+		}
+
+		idx++
+	}
+
+}
+
+/*
+ * Designs a low or high shelving filter with a certain gain (in decibels).
+ */
+func parametricEqShelf(frequency int32, gain int32, high bool, sampleRate uint32) parametricEqSection {
+
+	/*
+	 * A shelf without gain is left out.
+	 */
+	if gain == 0 {
+		return parametricEqSection{}
+	} else {
+		gainFloat := float64(gain)
+		a := math.Pow(10.0, gainFloat/40.0)
+		omega := parametricEqOmega(frequency, sampleRate)
+		cosOmega := math.Cos(omega)
+		sinOmega := math.Sin(omega)
+		alpha := sinOmega / (2.0 * PARAMETRIC_EQ_SHELF_Q)
+		sq := 2.0 * math.Sqrt(a) * alpha
+		aPlus := a + 1.0
+		aMinus := a - 1.0
+
+		/*
+		 * Design either a high or a low shelf.
+		 */
+		if high {
+			b0 := a * (aPlus + (aMinus * cosOmega) + sq)
+			b1 := -2.0 * a * (aMinus + (aPlus * cosOmega))
+			b2 := a * (aPlus + (aMinus * cosOmega) - sq)
+			a0 := aPlus - (aMinus * cosOmega) + sq
+			a1 := 2.0 * (aMinus - (aPlus * cosOmega))
+			a2 := aPlus - (aMinus * cosOmega) - sq
+			section := createParametricEqSection(b0, b1, b2, a0, a1, a2)
+			return section
+		} else {
+			b0 := a * (aPlus - (aMinus * cosOmega) + sq)
+			b1 := 2.0 * a * (aMinus - (aPlus * cosOmega))
+			b2 := a * (aPlus - (aMinus * cosOmega) - sq)
+			a0 := aPlus + (aMinus * cosOmega) + sq
+			a1 := -2.0 * (aMinus + (aPlus * cosOmega))
+			a2 := aPlus + (aMinus * cosOmega) - sq
+			section := createParametricEqSection(b0, b1, b2, a0, a1, a2)
+			return section
+		}
+
+	}
+
+}
+
+/*
+ * Designs a peaking filter with a certain gain (in decibels) and quality
+ * factor (in tenths).
+ */
+func parametricEqPeak(frequency int32, gain int32, q int32, sampleRate uint32) parametricEqSection {
+
+	/*
+	 * A band without gain is left out.
+	 */
+	if gain == 0 {
+		return parametricEqSection{}
+	} else {
+		gainFloat := float64(gain)
+		a := math.Pow(10.0, gainFloat/40.0)
+		qFloat := float64(q)
+		qValue := 0.1 * qFloat
+		omega := parametricEqOmega(frequency, sampleRate)
+		cosOmega := math.Cos(omega)
+		sinOmega := math.Sin(omega)
+		alpha := sinOmega / (2.0 * qValue)
+		b0 := 1.0 + (alpha * a)
+		b1 := -2.0 * cosOmega
+		b2 := 1.0 - (alpha * a)
+		a0 := 1.0 + (alpha / a)
+		a1 := -2.0 * cosOmega
+		a2 := 1.0 - (alpha / a)
+		section := createParametricEqSection(b0, b1, b2, a0, a1, a2)
+		return section
+	}
+
+}
+
+/*
+ * Designs all sections of the filter cascade from the parameters of the
+ * equalizer.
+ */
+func (this *parametricEq) design(sampleRate uint32) [PARAMETRIC_EQ_SECTIONS]parametricEqSection {
+	params := this.processingParameters()
+	sections := [PARAMETRIC_EQ_SECTIONS]parametricEqSection{}
+	lowCut, _ := params.discreteValue("low_cut")
+	lowCutFrequency, _ := params.numericValue("low_cut_frequency")
+	highCut, _ := params.discreteValue("high_cut")
+	highCutFrequency, _ := params.numericValue("high_cut_frequency")
+	lowShelfFrequency, _ := params.numericValue("low_shelf_frequency")
+	lowShelfGain, _ := params.numericValue("low_shelf_gain")
+	highShelfFrequency, _ := params.numericValue("high_shelf_frequency")
+	highShelfGain, _ := params.numericValue("high_shelf_gain")
+	bands, _ := params.numericValue("bands")
+	lowCutSections := sections[PARAMETRIC_EQ_SLOT_LOW_CUT:PARAMETRIC_EQ_SLOT_HIGH_CUT]
+	parametricEqCut(lowCutSections, lowCut, lowCutFrequency, true, sampleRate)
+	highCutSections := sections[PARAMETRIC_EQ_SLOT_HIGH_CUT:PARAMETRIC_EQ_SLOT_LOW_SHELF]
+	parametricEqCut(highCutSections, highCut, highCutFrequency, false, sampleRate)
+	sections[PARAMETRIC_EQ_SLOT_LOW_SHELF] = parametricEqShelf(lowShelfFrequency, lowShelfGain, false, sampleRate)
+	sections[PARAMETRIC_EQ_SLOT_HIGH_SHELF] = parametricEqShelf(highShelfFrequency, highShelfGain, true, sampleRate)
+	numBands := int(bands)
+
+	/*
+	 * Design each active peaking band.
+	 */
+	for i := 0; i < numBands; i++ {
+		frequency, _ := params.numericValue(this.frequencyNames[i])
+		gain, _ := params.numericValue(this.gainNames[i])
+		q, _ := params.numericValue(this.qNames[i])
+		idx := PARAMETRIC_EQ_SLOT_BANDS + i
+		sections[idx] = parametricEqPeak(frequency, gain, q, sampleRate)
+	}
+
+	return sections
+}
+
+/*
+ * Returns the complex frequency response of the equalizer at each of the
+ * frequencies (in hertz) passed.
+ */
+func (this *parametricEq) FrequencyResponse(frequencies []float64, sampleRate uint32) []complex128 {
+	sections := this.design(sampleRate)
+	n := len(frequencies)
+	responses := make([]complex128, n)
+
+	/*
+	 * Calculate the response at each frequency.
+	 */
+	for i, frequency := range frequencies {
+		response := complex(1.0, 0.0)
+
+		/*
+		 * Multiply the responses of all sections.
+		 */
+		for j := range sections {
+			section := &sections[j]
+			response *= section.response(frequency, sampleRate)
+		}
+
+		responses[i] = response
+	}
+
+	return responses
+}
+
+/*
+ * Parametric equalizer audio processing.
+ */
+func (this *parametricEq) Process(in []float64, out []float64, sampleRate uint32) {
+	sections := this.design(sampleRate)
+	states := &this.states
+	copy(out, in)
+
+	/*
+	 * Apply each active section in transposed direct form II.
+	 */
+	for j := range sections {
+		section := &sections[j]
+
+		/*
+		 * Leave out inactive sections, but clear their state, so that
+		 * they start over once they are activated.
+		 */
+		if !section.active {
+			states[j] = [2]float64{}
+		} else {
+			b0 := section.b0
+			b1 := section.b1
+			b2 := section.b2
+			a1 := section.a1
+			a2 := section.a2
+			s0 := states[j][0]
+			s1 := states[j][1]
+
+			/*
+			 * Process each sample.
+			 */
+			for i, sample := range out {
+				y := (b0 * sample) + s0
+				s0 = (b1 * sample) - (a1 * y) + s1
+				s1 = (b2 * sample) - (a2 * y)
+				out[i] = y
+			}
+
+			states[j][0] = s0
+			states[j][1] = s1
+		}
+
+	}
+
+	/*
+	 * Limit the output signal to the appropriate range.
+	 */
+	for i, sample := range out {
+		out[i] = limitSample(sample)
+	}
+
+}
+
+/*
+ * Create a parametric equalizer effects unit.
+ */
+func createParametricEq() Unit {
+
+	/*
+	 * Default frequencies (in hertz) of the peaking bands.
+	 */
+	bandFrequencies := [PARAMETRIC_EQ_MAX_BANDS]int32{
+		100,
+		200,
+		400,
+		800,
+		1600,
+		3200,
+		6400,
+		12800,
+	}
+
+	/*
+	 * Parameters of the cut filters and shelves.
+	 */
+	params := []Parameter{
+		Parameter{
+			Name:               "low_cut",
+			Type:               PARAMETER_TYPE_DISCRETE,
+			PhysicalUnit:       "dB / oct",
+			Minimum:            -1,
+			Maximum:            -1,
+			NumericValue:       -1,
+			DiscreteValueIndex: 0,
+			DiscreteValues: []string{
+				"off",
+				"6",
+				"12",
+				"18",
+				"24",
+			},
+		},
+		Parameter{
+			Name:               "low_cut_frequency",
+			Type:               PARAMETER_TYPE_NUMERIC,
+			PhysicalUnit:       "Hz",
+			Minimum:            20,
+			Maximum:            1000,
+			NumericValue:       80,
+			DiscreteValueIndex: -1,
+			DiscreteValues:     nil,
+		},
+		Parameter{
+			Name:               "high_cut",
+			Type:               PARAMETER_TYPE_DISCRETE,
+			PhysicalUnit:       "dB / oct",
+			Minimum:            -1,
+			Maximum:            -1,
+			NumericValue:       -1,
+			DiscreteValueIndex: 0,
+			DiscreteValues: []string{
+				"off",
+				"6",
+				"12",
+				"18",
+				"24",
+			},
+		},
+		Parameter{
+			Name:               "high_cut_frequency",
+			Type:               PARAMETER_TYPE_NUMERIC,
+			PhysicalUnit:       "Hz",
+			Minimum:            1000,
+			Maximum:            20000,
+			NumericValue:       8000,
+			DiscreteValueIndex: -1,
+			DiscreteValues:     nil,
+		},
+		Parameter{
+			Name:               "low_shelf_frequency",
+			Type:               PARAMETER_TYPE_NUMERIC,
+			PhysicalUnit:       "Hz",
+			Minimum:            20,
+			Maximum:            2000,
+			NumericValue:       120,
+			DiscreteValueIndex: -1,
+			DiscreteValues:     nil,
+		},
+		Parameter{
+			Name:               "low_shelf_gain",
+			Type:               PARAMETER_TYPE_NUMERIC,
+			PhysicalUnit:       "dB",
+			Minimum:            -24,
+			Maximum:            24,
+			NumericValue:       0,
+			DiscreteValueIndex: -1,
+			DiscreteValues:     nil,
+		},
+		Parameter{
+			Name:               "high_shelf_frequency",
+			Type:               PARAMETER_TYPE_NUMERIC,
+			PhysicalUnit:       "Hz",
+			Minimum:            1000,
+			Maximum:            20000,
+			NumericValue:       5000,
+			DiscreteValueIndex: -1,
+			DiscreteValues:     nil,
+		},
+		Parameter{
+			Name:               "high_shelf_gain",
+			Type:               PARAMETER_TYPE_NUMERIC,
+			PhysicalUnit:       "dB",
+			Minimum:            -24,
+			Maximum:            24,
+			NumericValue:       0,
+			DiscreteValueIndex: -1,
+			DiscreteValues:     nil,
+		},
+		Parameter{
+			Name:               "bands",
+			Type:               PARAMETER_TYPE_NUMERIC,
+			PhysicalUnit:       "",
+			Minimum:            0,
+			Maximum:            PARAMETRIC_EQ_MAX_BANDS,
+			NumericValue:       4,
+			DiscreteValueIndex: -1,
+			DiscreteValues:     nil,
+		},
+	}
+
+	frequencyNames := [PARAMETRIC_EQ_MAX_BANDS]string{}
+	gainNames := [PARAMETRIC_EQ_MAX_BANDS]string{}
+	qNames := [PARAMETRIC_EQ_MAX_BANDS]string{}
+
+	/*
+	 * Add the parameters of each peaking band.
+	 */
+	for i, frequency := range bandFrequencies {
+		band := i + 1
+		frequencyName := fmt.Sprintf("band_%d_frequency", band)
+		gainName := fmt.Sprintf("band_%d_gain", band)
+		qName := fmt.Sprintf("band_%d_q", band)
+
+		/*
+		 * Parameters of the band.
+		 */
+		bandParams := []Parameter{
+			Parameter{
+				Name:               frequencyName,
+				Type:               PARAMETER_TYPE_NUMERIC,
+				PhysicalUnit:       "Hz",
+				Minimum:            20,
+				Maximum:            20000,
+				NumericValue:       frequency,
+				DiscreteValueIndex: -1,
+				DiscreteValues:     nil,
+			},
+			Parameter{
+				Name:               gainName,
+				Type:               PARAMETER_TYPE_NUMERIC,
+				PhysicalUnit:       "dB",
+				Minimum:            -24,
+				Maximum:            24,
+				NumericValue:       0,
+				DiscreteValueIndex: -1,
+				DiscreteValues:     nil,
+			},
+			Parameter{
+				Name:               qName,
+				Type:               PARAMETER_TYPE_NUMERIC,
+				PhysicalUnit:       "0.1",
+				Minimum:            1,
+				Maximum:            200,
+				NumericValue:       10,
+				DiscreteValueIndex: -1,
+				DiscreteValues:     nil,
+			},
+		}
+
+		params = append(params, bandParams...)
+		frequencyNames[i] = frequencyName
+		gainNames[i] = gainName
+		qNames[i] = qName
+	}
+
+	/*
+	 * Create effects unit.
+	 */
+	u := parametricEq{
+		unitStruct: unitStruct{
+			unitType: UNIT_PARAMETRIC_EQ,
+			params:   params,
+		},
+		frequencyNames: frequencyNames,
+		gainNames:      gainNames,
+		qNames:         qNames,
+	}
+
+	return &u
+}
@@ -0,0 +1,144 @@
+package effects
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+/*
+ * Converts a complex frequency response into magnitudes (in decibels).
+ */
+func responseDecibels(response []complex128) []float64 {
+	n := len(response)
+	magnitudes := make([]float64, n)
+
+	/*
+	 * Convert each value.
+	 */
+	for i, value := range response {
+		magnitude := cmplx.Abs(value)
+		magnitudes[i] = factorToDecibels(magnitude)
+	}
+
+	return magnitudes
+}
+
+/*
+ * Verify that the bands and cut filters of a parametric equalizer have the
+ * expected response and that the processed signal follows that response.
+ */
+func TestParametricEq(t *testing.T) {
+	u := CreateUnit(UNIT_PARAMETRIC_EQ)
+	u.SetNumericValue("bands", 1)
+	u.SetNumericValue("band_1_frequency", 1000)
+	u.SetNumericValue("band_1_gain", 12)
+	u.SetNumericValue("band_1_q", 20)
+	responseUnit := u.(ResponseUnit)
+
+	/*
+	 * Frequencies to evaluate the response at.
+	 */
+	frequencies := []float64{
+		1000.0,
+		50.0,
+		15000.0,
+	}
+
+	responseComplex := responseUnit.FrequencyResponse(frequencies, TEST_SAMPLE_RATE)
+	response := responseDecibels(responseComplex)
+
+	/*
+	 * The band should boost its center frequency by its gain.
+	 */
+	if math.Abs(response[0]-12.0) > 0.01 {
+		t.Errorf("Response at center frequency should be %f dB, but is %f dB.", 12.0, response[0])
+	}
+
+	/*
+	 * Frequencies far away from the band should pass unaltered.
+	 */
+	for i := 1; i < len(frequencies); i++ {
+
+		/*
+		 * Check if the response is flat.
+		 */
+		if math.Abs(response[i]) > 0.1 {
+			t.Errorf("Response at %f Hz should be %f dB, but is %f dB.", frequencies[i], 0.0, response[i])
+		}
+
+	}
+
+	amplitude := 0.1
+	gain := sineGain(u, 1000.0, amplitude)
+	gainDecibels := factorToDecibels(gain)
+
+	/*
+	 * The processed signal should follow the response.
+	 */
+	if math.Abs(gainDecibels-response[0]) > 0.1 {
+		t.Errorf("Gain of sine wave should be %f dB, but is %f dB.", response[0], gainDecibels)
+	}
+
+	u = CreateUnit(UNIT_PARAMETRIC_EQ)
+	u.SetNumericValue("bands", 0)
+	u.SetDiscreteValue("low_cut", "24")
+	u.SetNumericValue("low_cut_frequency", 200)
+	responseUnit = u.(ResponseUnit)
+
+	/*
+	 * Frequencies to evaluate the response at.
+	 */
+	frequencies = []float64{
+		200.0,
+		100.0,
+		2000.0,
+	}
+
+	responseComplex = responseUnit.FrequencyResponse(frequencies, TEST_SAMPLE_RATE)
+	response = responseDecibels(responseComplex)
+
+	/*
+	 * The low cut should attenuate its cutoff frequency by 3 dB.
+	 */
+	if math.Abs(response[0]+3.01) > 0.1 {
+		t.Errorf("Response at cutoff frequency should be %f dB, but is %f dB.", -3.01, response[0])
+	}
+
+	/*
+	 * One octave below, the low cut should attenuate by about 24 dB.
+	 */
+	if response[1] > -20.0 {
+		t.Errorf("Response one octave below cutoff should be at most %f dB, but is %f dB.", -20.0, response[1])
+	}
+
+	/*
+	 * Well above the cutoff, the signal should pass unaltered.
+	 */
+	if math.Abs(response[2]) > 0.1 {
+		t.Errorf("Response above cutoff should be %f dB, but is %f dB.", 0.0, response[2])
+	}
+
+	gain = sineGain(u, 100.0, amplitude)
+	gainDecibels = factorToDecibels(gain)
+
+	/*
+	 * The processed signal should follow the response.
+	 */
+	if math.Abs(gainDecibels-response[1]) > 0.5 {
+		t.Errorf("Gain of sine wave should be %f dB, but is %f dB.", response[1], gainDecibels)
+	}
+
+	u = CreateUnit(UNIT_PARAMETRIC_EQ)
+	u.SetDiscreteValue("low_cut", "18")
+	u.SetDiscreteValue("high_cut", "12")
+	u.SetNumericValue("low_shelf_gain", 12)
+	u.SetNumericValue("high_shelf_gain", -12)
+	u.SetNumericValue("bands", 8)
+	u.SetNumericValue("band_8_gain", 24)
+	u.SetNumericValue("band_8_q", 200)
+	noise := createNoise(48000, 1)
+	out := make([]float64, 48000)
+	u.Process(noise, out, TEST_SAMPLE_RATE)
+	checkOutput(t, "noise", out)
+}
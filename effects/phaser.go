@@ -2,6 +2,7 @@ package effects
 
 import (
 	"math"
+	"sync/atomic"
 )
 
 /*
@@ -11,17 +12,26 @@ type phaser struct {
 	unitStruct
 	buffer        []float64
 	previousPhase float64
+	tempo         uint32
+}
+
+/*
+ * Sets the tempo (in beats per minute) the modulation synchronizes to.
+ */
+func (this *phaser) SetTempo(bpm uint32) {
+	atomic.StoreUint32(&this.tempo, bpm)
 }
 
 /*
  * Phaser audio processing.
  */
 func (this *phaser) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	depth, _ := this.getNumericValue("depth")
-	speed, _ := this.getNumericValue("speed")
-	phase, _ := this.getNumericValue("phase")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	depth, _ := params.numericValue("depth")
+	speed, _ := params.numericValue("speed")
+	phase, _ := params.numericValue("phase")
+	sync, _ := params.discreteValue("sync")
+	tempo := atomic.LoadUint32(&this.tempo)
 	depthFloat := float64(depth)
 	depthValue := 0.01 * depthFloat
 
@@ -36,6 +46,15 @@ func (this *phaser) Process(in []float64, out []float64, sampleRate uint32) {
 
 	speedFloat := float64(speed)
 	angularSpeed := MATH_TWO_PI_HUNDREDTH * speedFloat
+	syncedFrequency := noteValueToFrequency(sync, tempo)
+
+	/*
+	 * If the modulation is synchronized to the tempo, it ignores the speed.
+	 */
+	if syncedFrequency > 0.0 {
+		angularSpeed = MATH_TWO_PI * syncedFrequency
+	}
+
 	phaseFloat := float64(phase)
 	phaseFloatRadians := MATH_DEGREE_TO_RADIANS * phaseFloat
 	phaseFac := 0.5 * math.Sin(phaseFloatRadians)
@@ -104,12 +123,12 @@ func (this *phaser) Process(in []float64, out []float64, sampleRate uint32) {
 		out[i] = (phaseFacInv * sample) + (phaseFac * delayedSample)
 	}
 
-	bufferSizeFloat := float64(bufferSize)
-	duration := bufferSizeFloat * sampleRateFloatInv
+	numSamples := len(in)
+	numSamplesFloat := float64(numSamples)
+	duration := numSamplesFloat * sampleRateFloatInv
 	phaseIncrement := angularSpeed * duration
 	updatedPhase := previousPhase + phaseIncrement
 	this.previousPhase = math.Mod(updatedPhase, MATH_TWO_PI)
-	numSamples := len(in)
 	boundary := bufferSize - numSamples
 
 	/*
@@ -166,6 +185,7 @@ func createPhaser() Unit {
 					DiscreteValueIndex: -1,
 					DiscreteValues:     nil,
 				},
+				createSyncParameter(),
 			},
 		},
 	}
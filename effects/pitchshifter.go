@@ -0,0 +1,417 @@
+package effects
+
+import (
+	"github.com/andrepxx/go-dsp-guitar/fft"
+	"math"
+	"math/cmplx"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	PITCH_SHIFTER_FRAME_SIZE   = 2048
+	PITCH_SHIFTER_OVERSAMPLING = 4
+	PITCH_SHIFTER_HOP_SIZE     = PITCH_SHIFTER_FRAME_SIZE / PITCH_SHIFTER_OVERSAMPLING
+	PITCH_SHIFTER_LATENCY      = PITCH_SHIFTER_FRAME_SIZE - PITCH_SHIFTER_HOP_SIZE
+)
+
+/*
+ * Data structure representing a voice synthesized by a phase vocoder.
+ */
+type pitchShifterVoiceStruct struct {
+	magnitudes  []float64
+	frequencies []float64
+	phases      []float64
+}
+
+/*
+ * Data structure representing a pitch shifter / harmonizer effect.
+ */
+type pitchShifter struct {
+	unitStruct
+	fourierTransform   fft.FourierTransform
+	window             []float64
+	inputFifo          []float64
+	outputFifo         []float64
+	outputAccumulator  []float64
+	frame              []float64
+	spectrum           []complex128
+	analysisMagnitudes []float64
+	analysisFrequency  []float64
+	previousPhases     []float64
+	voices             []pitchShifterVoiceStruct
+	fifoPtr            int
+}
+
+/*
+ * Converts an interval in semitones and cents into a frequency ratio.
+ */
+func intervalToRatio(semitones int32, cents int32) float64 {
+	semitonesFloat := float64(semitones)
+	centsFloat := float64(cents)
+	exponent := (semitonesFloat + (0.01 * centsFloat)) / 12.0
+	ratio := math.Pow(2.0, exponent)
+	return ratio
+}
+
+/*
+ * Wraps a phase into the range from -pi to pi.
+ */
+func wrapPhase(phase float64) float64 {
+	turns := math.Floor((phase + math.Pi) / (2.0 * math.Pi))
+	result := phase - (2.0 * math.Pi * turns)
+	return result
+}
+
+/*
+ * Allocates the buffers of the pitch shifter.
+ */
+func (this *pitchShifter) prepareBuffers() {
+
+	/*
+	 * Only allocate buffers once.
+	 */
+	if this.window == nil {
+		n := PITCH_SHIFTER_FRAME_SIZE
+		nHalf := n / 2
+		nFloat := float64(n)
+		window := make([]float64, n)
+
+		/*
+		 * Calculate a Hann window.
+		 */
+		for i := range window {
+			iFloat := float64(i)
+			arg := (2.0 * math.Pi * iFloat) / nFloat
+			window[i] = 0.5 * (1.0 - math.Cos(arg))
+		}
+
+		voices := make([]pitchShifterVoiceStruct, 2)
+
+		/*
+		 * Allocate the synthesis buffers of each voice.
+		 */
+		for i := range voices {
+			voices[i].magnitudes = make([]float64, nHalf+1)
+			voices[i].frequencies = make([]float64, nHalf+1)
+			voices[i].phases = make([]float64, nHalf+1)
+		}
+
+		this.fourierTransform = fft.CreateFourierTransform()
+		this.window = window
+		this.inputFifo = make([]float64, n)
+		this.outputFifo = make([]float64, n)
+		this.outputAccumulator = make([]float64, 2*n)
+		this.frame = make([]float64, n)
+		this.spectrum = make([]complex128, n)
+		this.analysisMagnitudes = make([]float64, nHalf+1)
+		this.analysisFrequency = make([]float64, nHalf+1)
+		this.previousPhases = make([]float64, nHalf+1)
+		this.voices = voices
+		this.fifoPtr = PITCH_SHIFTER_LATENCY
+	}
+
+}
+
+/*
+ * Analyzes the current frame, estimating the magnitude and the true
+ * frequency (in bins) of each frequency bin.
+ */
+func (this *pitchShifter) analyze() {
+	n := PITCH_SHIFTER_FRAME_SIZE
+	nHalf := n / 2
+	window := this.window
+	inputFifo := this.inputFifo
+	frame := this.frame
+	spectrum := this.spectrum
+	magnitudes := this.analysisMagnitudes
+	frequencies := this.analysisFrequency
+	previousPhases := this.previousPhases
+	oversamplingFloat := float64(PITCH_SHIFTER_OVERSAMPLING)
+	expectedPhaseDiff := (2.0 * math.Pi) / oversamplingFloat
+
+	/*
+	 * Apply the analysis window.
+	 */
+	for i, sample := range inputFifo {
+		frame[i] = window[i] * sample
+	}
+
+	this.fourierTransform.RealFourier(frame, spectrum, fft.SCALING_DEFAULT)
+
+	/*
+	 * Calculate magnitude and true frequency of each bin.
+	 */
+	for k := 0; k <= nHalf; k++ {
+		elem := spectrum[k]
+		magnitude, phase := cmplx.Polar(elem)
+		phaseDiff := phase - previousPhases[k]
+		previousPhases[k] = phase
+		kFloat := float64(k)
+		deviation := wrapPhase(phaseDiff - (kFloat * expectedPhaseDiff))
+		magnitudes[k] = magnitude
+		frequencies[k] = kFloat + ((oversamplingFloat * deviation) / (2.0 * math.Pi))
+	}
+
+}
+
+/*
+ * Synthesizes a voice shifted by a frequency ratio and adds it to the
+ * spectrum.
+ */
+func (this *pitchShifter) synthesize(voice *pitchShifterVoiceStruct, ratio float64, levelFactor float64) {
+	n := PITCH_SHIFTER_FRAME_SIZE
+	nHalf := n / 2
+	spectrum := this.spectrum
+	analysisMagnitudes := this.analysisMagnitudes
+	analysisFrequency := this.analysisFrequency
+	magnitudes := voice.magnitudes
+	frequencies := voice.frequencies
+	phases := voice.phases
+	oversamplingFloat := float64(PITCH_SHIFTER_OVERSAMPLING)
+	expectedPhaseDiff := (2.0 * math.Pi) / oversamplingFloat
+	fft.ZeroFloat(magnitudes)
+	fft.ZeroFloat(frequencies)
+
+	/*
+	 * Move each analysis bin to its shifted position.
+	 */
+	for k := 0; k <= nHalf; k++ {
+		kFloat := float64(k)
+		targetFloat := math.Floor((kFloat * ratio) + 0.5)
+		target := int(targetFloat)
+
+		/*
+		 * Discard bins which are shifted beyond the Nyquist frequency.
+		 */
+		if target <= nHalf {
+			magnitudes[target] += analysisMagnitudes[k]
+			frequencies[target] = ratio * analysisFrequency[k]
+		}
+
+	}
+
+	/*
+	 * Accumulate the phase of each bin and add it to the spectrum.
+	 */
+	for k := 0; k <= nHalf; k++ {
+		kFloat := float64(k)
+		deviation := frequencies[k] - kFloat
+		phaseDiff := (kFloat * expectedPhaseDiff) + ((2.0 * math.Pi * deviation) / oversamplingFloat)
+		phase := wrapPhase(phases[k] + phaseDiff)
+		phases[k] = phase
+		magnitude := levelFactor * magnitudes[k]
+		spectrum[k] += cmplx.Rect(magnitude, phase)
+	}
+
+}
+
+/*
+ * Resynthesizes the current frame from the spectrum and adds it to the
+ * output accumulator.
+ */
+func (this *pitchShifter) resynthesize() {
+	n := PITCH_SHIFTER_FRAME_SIZE
+	nHalf := n / 2
+	window := this.window
+	spectrum := this.spectrum
+	frame := this.frame
+	outputAccumulator := this.outputAccumulator
+
+	/*
+	 * The spectrum of a real signal is conjugate symmetric.
+	 */
+	for k := 1; k < nHalf; k++ {
+		elem := spectrum[k]
+		idx := n - k
+		spectrum[idx] = cmplx.Conj(elem)
+	}
+
+	this.fourierTransform.RealInverseFourier(spectrum, frame, fft.SCALING_DEFAULT)
+
+	/*
+	 * The squared Hann windows of overlapping frames add up to 3/8 of the
+	 * oversampling factor.
+	 */
+	oversamplingFloat := float64(PITCH_SHIFTER_OVERSAMPLING)
+	scaling := 8.0 / (3.0 * oversamplingFloat)
+
+	/*
+	 * Apply the synthesis window and overlap-add the frame.
+	 */
+	for i, sample := range frame {
+		outputAccumulator[i] += scaling * window[i] * sample
+	}
+
+}
+
+/*
+ * Returns the latency (in samples) of the pitch shifter.
+ *
+ * Only the processed signal is delayed, while the dry signal passes through
+ * immediately. Therefore, the latency is only reported if the output
+ * consists of the processed signal alone.
+ */
+func (this *pitchShifter) Latency(sampleRate uint32) uint32 {
+	params := this.processingParameters()
+	mix, _ := params.numericValue("mix")
+
+	/*
+	 * Check if the dry signal is mixed in.
+	 */
+	if mix < 100 {
+		return 0
+	} else {
+		return PITCH_SHIFTER_LATENCY
+	}
+
+}
+
+/*
+ * Pitch shifter audio processing.
+ */
+func (this *pitchShifter) Process(in []float64, out []float64, sampleRate uint32) {
+	params := this.processingParameters()
+	semitones, _ := params.numericValue("semitones")
+	cents, _ := params.numericValue("cents")
+	harmony, _ := params.discreteValue("harmony")
+	harmonyInterval, _ := params.numericValue("harmony_interval")
+	harmonyLevel, _ := params.numericValue("harmony_level")
+	mix, _ := params.numericValue("mix")
+	this.prepareBuffers()
+	ratio := intervalToRatio(semitones, cents)
+	harmonyRatio := intervalToRatio(harmonyInterval, 0)
+	harmonyFactor := decibelsToFactor(harmonyLevel)
+	harmonyEnabled := harmony == "on"
+	mixFloat := float64(mix)
+	wetFrac := 0.01 * mixFloat
+	dryFrac := 1.0 - wetFrac
+	inputFifo := this.inputFifo
+	outputFifo := this.outputFifo
+	outputAccumulator := this.outputAccumulator
+	spectrum := this.spectrum
+	voices := this.voices
+	fifoPtr := this.fifoPtr
+
+	/*
+	 * Process each sample.
+	 */
+	for i, sample := range in {
+		inputFifo[fifoPtr] = sample
+		idx := fifoPtr - PITCH_SHIFTER_LATENCY
+		wet := outputFifo[idx]
+		fifoPtr++
+
+		/*
+		 * Process a frame as soon as enough new samples have arrived.
+		 */
+		if fifoPtr >= PITCH_SHIFTER_FRAME_SIZE {
+			fifoPtr = PITCH_SHIFTER_LATENCY
+			this.analyze()
+			fft.ZeroComplex(spectrum)
+			this.synthesize(&voices[0], ratio, 1.0)
+
+			/*
+			 * Add the harmony voice if it is enabled.
+			 */
+			if harmonyEnabled {
+				this.synthesize(&voices[1], harmonyRatio, harmonyFactor)
+			}
+
+			this.resynthesize()
+			copy(outputFifo, outputAccumulator[:PITCH_SHIFTER_HOP_SIZE])
+			copy(outputAccumulator, outputAccumulator[PITCH_SHIFTER_HOP_SIZE:])
+			tail := outputAccumulator[len(outputAccumulator)-PITCH_SHIFTER_HOP_SIZE:]
+			fft.ZeroFloat(tail)
+			copy(inputFifo, inputFifo[PITCH_SHIFTER_HOP_SIZE:])
+		}
+
+		pre := (dryFrac * sample) + (wetFrac * wet)
+		out[i] = limitSample(pre)
+	}
+
+	this.fifoPtr = fifoPtr
+}
+
+/*
+ * Create a pitch shifter effects unit.
+ */
+func createPitchShifter() Unit {
+
+	/*
+	 * Create effects unit.
+	 */
+	u := pitchShifter{
+		unitStruct: unitStruct{
+			unitType: UNIT_PITCH_SHIFTER,
+			params: []Parameter{
+				Parameter{
+					Name:               "semitones",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "",
+					Minimum:            -12,
+					Maximum:            12,
+					NumericValue:       12,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "cents",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "ct",
+					Minimum:            -100,
+					Maximum:            100,
+					NumericValue:       0,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "harmony",
+					Type:               PARAMETER_TYPE_DISCRETE,
+					PhysicalUnit:       "",
+					Minimum:            -1,
+					Maximum:            -1,
+					NumericValue:       -1,
+					DiscreteValueIndex: 0,
+					DiscreteValues: []string{
+						"off",
+						"on",
+					},
+				},
+				Parameter{
+					Name:               "harmony_interval",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "",
+					Minimum:            -12,
+					Maximum:            12,
+					NumericValue:       7,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "harmony_level",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            -60,
+					Maximum:            0,
+					NumericValue:       -6,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "mix",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            0,
+					Maximum:            100,
+					NumericValue:       50,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+			},
+		},
+	}
+
+	return &u
+}
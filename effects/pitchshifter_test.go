@@ -0,0 +1,120 @@
+package effects
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+/*
+ * Estimates the frequency (in Hz) of a periodic signal by counting the
+ * rising zero crossings.
+ */
+func estimateFrequency(signal []float64, sampleRate uint32) float64 {
+	crossings := 0
+
+	/*
+	 * Count each rising zero crossing.
+	 */
+	for i := 1; i < len(signal); i++ {
+
+		/*
+		 * Check if signal crosses zero upwards.
+		 */
+		if (signal[i-1] < 0.0) && (signal[i] >= 0.0) {
+			crossings++
+		}
+
+	}
+
+	n := len(signal)
+	nFloat := float64(n)
+	crossingsFloat := float64(crossings)
+	sampleRateFloat := float64(sampleRate)
+	frequency := (crossingsFloat * sampleRateFloat) / nFloat
+	return frequency
+}
+
+/*
+ * Verify that a pitch shifter shifts a sine wave by the interval set, reports
+ * its latency only without dry signal and stays finite for extreme
+ * parameters.
+ */
+func TestPitchShifter(t *testing.T) {
+	n := 48000
+	in := make([]float64, n)
+	out := make([]float64, n)
+	frequency := 440.0
+
+	/*
+	 * Generate a sine wave.
+	 */
+	for i := range in {
+		iFloat := float64(i)
+		arg := (2.0 * math.Pi * frequency * iFloat) / TEST_SAMPLE_RATE
+		in[i] = 0.5 * math.Sin(arg)
+	}
+
+	/*
+	 * Intervals (in semitones) to shift by.
+	 */
+	intervals := []int32{
+		0,
+		12,
+		-12,
+		7,
+	}
+
+	/*
+	 * Shift the sine wave by each interval.
+	 */
+	for _, interval := range intervals {
+		name := fmt.Sprintf("interval%d", interval)
+		u := CreateUnit(UNIT_PITCH_SHIFTER)
+		u.SetNumericValue("semitones", interval)
+		u.SetNumericValue("mix", 100)
+		u.Process(in, out, TEST_SAMPLE_RATE)
+		steady := out[4*PITCH_SHIFTER_FRAME_SIZE:]
+		estimate := estimateFrequency(steady, TEST_SAMPLE_RATE)
+		ratio := intervalToRatio(interval, 0)
+		expected := ratio * frequency
+
+		/*
+		 * Allow for the resolution of the estimate.
+		 */
+		if math.Abs(estimate-expected) > (0.02 * expected) {
+			t.Errorf("%s: Frequency should be %f, but is %f.", name, expected, estimate)
+		}
+
+	}
+
+	u := CreateUnit(UNIT_PITCH_SHIFTER)
+	latencyUnit := u.(LatencyUnit)
+	latency := latencyUnit.Latency(TEST_SAMPLE_RATE)
+
+	/*
+	 * The dry signal passes through without delay.
+	 */
+	if latency != 0 {
+		t.Errorf("Latency with dry signal should be %d, but is %d.", 0, latency)
+	}
+
+	u.SetNumericValue("mix", 100)
+	latency = latencyUnit.Latency(TEST_SAMPLE_RATE)
+
+	/*
+	 * Without dry signal, the output is delayed by the frame.
+	 */
+	if latency != PITCH_SHIFTER_LATENCY {
+		t.Errorf("Latency without dry signal should be %d, but is %d.", PITCH_SHIFTER_LATENCY, latency)
+	}
+
+	u.SetNumericValue("semitones", 12)
+	u.SetNumericValue("cents", 100)
+	u.SetDiscreteValue("harmony", "on")
+	u.SetNumericValue("harmony_interval", -12)
+	u.SetNumericValue("harmony_level", 0)
+	noise := createNoise(n, 1)
+	u.Process(noise, out, TEST_SAMPLE_RATE)
+	checkOutput(t, "extreme", out)
+}
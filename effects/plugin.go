@@ -0,0 +1,372 @@
+package effects
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/ladspa"
+	"math"
+)
+
+/*
+ * Resolution of control ports which take continuous values. Their range is
+ * mapped onto this number of steps.
+ */
+const (
+	PLUGIN_RESOLUTION = 1000
+)
+
+/*
+ * Bounds of control ports which take whole numbers.
+ */
+const (
+	PLUGIN_INTEGER_MAXIMUM = 1000000
+	PLUGIN_INTEGER_MINIMUM = -1000000
+)
+
+/*
+ * Data structure representing a unit hosting a LADSPA plugin.
+ *
+ * The plugin, its instance and the names of the parameters its control ports
+ * map to are only changed while holding the mutex. The audio thread holds the
+ * mutex for reading while it runs the instance, so that it is never closed
+ * while in use.
+ */
+type plugin struct {
+	unitStruct
+	sampleRate uint32
+	plugins    []ladspa.Plugin
+	current    ladspa.Plugin
+	controls   []ladspa.Control
+	names      []string
+	values     []float64
+	instance   ladspa.Instance
+}
+
+/*
+ * Creates the parameter which a control port of a plugin maps to.
+ *
+ * Toggled ports map to discrete parameters, ports taking whole numbers map to
+ * numeric parameters holding that number and all other ports map to numeric
+ * parameters holding a fraction of their range.
+ */
+func pluginParameter(name string, control ladspa.Control) Parameter {
+
+	/*
+	 * Check the kind of control port.
+	 */
+	if control.Toggled {
+		idx := 0
+
+		/*
+		 * Check if port is on by default.
+		 */
+		if control.Default > 0.0 {
+			idx = 1
+		}
+
+		/*
+		 * Create discrete parameter.
+		 */
+		param := Parameter{
+			Name:               name,
+			Type:               PARAMETER_TYPE_DISCRETE,
+			PhysicalUnit:       "",
+			Minimum:            -1,
+			Maximum:            -1,
+			NumericValue:       -1,
+			DiscreteValueIndex: idx,
+			DiscreteValues: []string{
+				"off",
+				"on",
+			},
+		}
+
+		return param
+	} else if control.Integer && !control.SampleRate {
+		min := math.Max(math.Ceil(control.Minimum), PLUGIN_INTEGER_MINIMUM)
+		max := math.Min(math.Floor(control.Maximum), PLUGIN_INTEGER_MAXIMUM)
+		max = math.Max(min, max)
+		value := math.Max(min, math.Min(control.Default, max))
+
+		/*
+		 * Create numeric parameter holding whole numbers.
+		 */
+		param := Parameter{
+			Name:               name,
+			Type:               PARAMETER_TYPE_NUMERIC,
+			PhysicalUnit:       "",
+			Minimum:            int32(min),
+			Maximum:            int32(max),
+			NumericValue:       int32(value),
+			DiscreteValueIndex: -1,
+			DiscreteValues:     nil,
+		}
+
+		return param
+	} else {
+		fraction := control.Fraction(control.Default)
+		value := math.Round(PLUGIN_RESOLUTION * fraction)
+
+		/*
+		 * Create numeric parameter holding a fraction of the range.
+		 */
+		param := Parameter{
+			Name:               name,
+			Type:               PARAMETER_TYPE_NUMERIC,
+			PhysicalUnit:       "‰",
+			Minimum:            0,
+			Maximum:            PLUGIN_RESOLUTION,
+			NumericValue:       int32(value),
+			DiscreteValueIndex: -1,
+			DiscreteValues:     nil,
+		}
+
+		return param
+	}
+
+}
+
+/*
+ * Creates a new instance of the current plugin at the current sample rate and
+ * closes the previous one.
+ *
+ * Must be called with the mutex held.
+ */
+func (this *plugin) instantiate() error {
+	previous := this.instance
+	this.instance = nil
+
+	/*
+	 * Close the previous instance.
+	 */
+	if previous != nil {
+		previous.Close()
+	}
+
+	current := this.current
+	sampleRate := this.sampleRate
+
+	/*
+	 * Only instantiate if there is a plugin and the sample rate is known.
+	 */
+	if current == nil || sampleRate == 0 {
+		return nil
+	} else {
+		instance, err := current.Instantiate(sampleRate)
+
+		/*
+		 * Check if plugin was instantiated.
+		 */
+		if err != nil {
+			return err
+		} else {
+			this.instance = instance
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Loads a plugin by its name and replaces the parameters of the previous
+ * plugin with those of the new one.
+ *
+ * Must be called with the mutex held.
+ */
+func (this *plugin) load(name string) error {
+	current := ladspa.Plugin(nil)
+
+	/*
+	 * Look for the plugin.
+	 */
+	for _, p := range this.plugins {
+
+		/*
+		 * If we got the right one, store it.
+		 */
+		if p.Name() == name {
+			current = p
+		}
+
+	}
+
+	controls := []ladspa.Control{}
+
+	/*
+	 * Fetch the control ports of the plugin.
+	 */
+	if current != nil {
+		controls = current.Controls()
+	}
+
+	n := len(controls)
+	names := make([]string, n)
+	taken := map[string]bool{"plugin": true}
+	params := []Parameter{this.params[0]}
+
+	/*
+	 * Create a parameter with a unique name for each control port.
+	 */
+	for i, control := range controls {
+		paramName := control.Name
+
+		/*
+		 * Number ports whose name is already taken.
+		 */
+		for k := 2; taken[paramName]; k++ {
+			paramName = fmt.Sprintf("%s (%d)", control.Name, k)
+		}
+
+		taken[paramName] = true
+		names[i] = paramName
+		param := pluginParameter(paramName, control)
+		param = describeParameter(param)
+		params = append(params, param)
+	}
+
+	this.current = current
+	this.controls = controls
+	this.names = names
+	this.values = make([]float64, n)
+	this.params = params
+	this.publishParameters()
+	err := this.instantiate()
+	return err
+}
+
+/*
+ * Sets a discrete parameter value for a plugin unit.
+ */
+func (this *plugin) SetDiscreteValue(name string, value string) error {
+	this.mutex.Lock()
+	err := this.unitStruct.setDiscreteValue(name, value)
+
+	/*
+	 * If another plugin was selected, load it.
+	 */
+	if err == nil && name == "plugin" {
+		err = this.load(value)
+	}
+
+	this.mutex.Unlock()
+	return err
+}
+
+/*
+ * Plugin unit audio processing.
+ */
+func (this *plugin) Process(in []float64, out []float64, sampleRate uint32) {
+
+	/*
+	 * Check if sampling rate changed.
+	 */
+	if sampleRate != this.sampleRate {
+		this.mutex.Lock()
+		this.sampleRate = sampleRate
+		this.instantiate()
+		this.mutex.Unlock()
+	}
+
+	params := this.processingParameters()
+	this.mutex.RLock()
+	instance := this.instance
+
+	/*
+	 * Without an instance, pass the signal through.
+	 */
+	if instance == nil {
+		copy(out, in)
+	} else {
+		values := this.values
+		sampleRateFloat := float64(sampleRate)
+
+		/*
+		 * Translate the parameters into values for the control ports.
+		 */
+		for i, control := range this.controls {
+			name := this.names[i]
+
+			/*
+			 * Check the kind of control port.
+			 */
+			if control.Toggled {
+				state, _ := params.discreteValue(name)
+
+				/*
+				 * Check if port is switched on.
+				 */
+				if state == "on" {
+					values[i] = 1.0
+				} else {
+					values[i] = 0.0
+				}
+
+			} else if control.Integer && !control.SampleRate {
+				value, _ := params.numericValue(name)
+				values[i] = float64(value)
+			} else {
+				value, _ := params.numericValue(name)
+				valueFloat := float64(value)
+				fraction := valueFloat / PLUGIN_RESOLUTION
+				values[i] = control.Value(fraction)
+
+				/*
+				 * Scale values which are given relative to the
+				 * sample rate.
+				 */
+				if control.SampleRate {
+					values[i] *= sampleRateFloat
+				}
+
+			}
+
+		}
+
+		instance.Process(in, out, values)
+	}
+
+	this.mutex.RUnlock()
+}
+
+/*
+ * Create a plugin host effects unit.
+ *
+ * Plugins which cannot be found are not offered. If plugin support is not
+ * available, only the empty choice remains.
+ */
+func createPlugin() Unit {
+	plugins, _ := ladspa.Plugins()
+	names := []string{STRING_NONE}
+
+	/*
+	 * Offer each plugin by its name.
+	 */
+	for _, p := range plugins {
+		name := p.Name()
+		names = append(names, name)
+	}
+
+	/*
+	 * Create effects unit.
+	 */
+	u := plugin{
+		unitStruct: unitStruct{
+			unitType: UNIT_PLUGIN,
+			params: []Parameter{
+				Parameter{
+					Name:               "plugin",
+					Type:               PARAMETER_TYPE_DISCRETE,
+					PhysicalUnit:       "",
+					Minimum:            -1,
+					Maximum:            -1,
+					NumericValue:       -1,
+					DiscreteValueIndex: 0,
+					DiscreteValues:     names,
+				},
+			},
+		},
+		plugins: plugins,
+	}
+
+	return &u
+}
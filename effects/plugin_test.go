@@ -0,0 +1,59 @@
+package effects
+
+import (
+	"testing"
+)
+
+/*
+ * Verify that a plugin unit without a plugin passes the signal through and
+ * rejects plugins which do not exist.
+ */
+func TestPluginNone(t *testing.T) {
+	u := CreateUnit(UNIT_PLUGIN)
+	params := u.Parameters()
+
+	/*
+	 * The unit must start with only the plugin selection, set to none.
+	 */
+	if len(params) != 1 {
+		t.Errorf("Plugin unit should have %d parameter, but has %d.", 1, len(params))
+	} else {
+		name, err := u.GetDiscreteValue("plugin")
+
+		/*
+		 * Verify that no plugin is selected.
+		 */
+		if err != nil || name != STRING_NONE {
+			t.Errorf("Plugin unit should start without a plugin. Expected: '%s' Got: '%s'", STRING_NONE, name)
+		}
+
+	}
+
+	err := u.SetDiscreteValue("plugin", "This plugin does not exist")
+
+	/*
+	 * Selecting an unknown plugin must fail.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Selecting an unknown plugin should fail, but it did not.")
+	}
+
+	in := []float64{0.5, -0.25, 0.125, -1.0}
+	out := make([]float64, len(in))
+	u.Process(in, out, 48000)
+
+	/*
+	 * Without a plugin, the signal must pass unchanged.
+	 */
+	for i, expected := range in {
+
+		/*
+		 * Verify each sample.
+		 */
+		if out[i] != expected {
+			t.Errorf("Sample %d changed without a plugin. Expected: %f Got: %f", i, expected, out[i])
+		}
+
+	}
+
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/andrepxx/go-dsp-guitar/fft"
 	"github.com/andrepxx/go-dsp-guitar/filter"
+	"math"
 	"strconv"
 )
 
@@ -13,12 +14,40 @@ import (
 type poweramp struct {
 	unitStruct
 	sampleRate       uint32
+	blockSize        uint32
 	impulseResponses filter.ImpulseResponses
 	idCompiled       uint64
 	idReceived       uint64
 	currentFilter    filter.Filter
 }
 
+/*
+ * Calculates the factor by which the blend between the first two filters
+ * scales a filter.
+ *
+ * A negative blend fades out the second filter, a positive blend fades out
+ * the first filter, while all other filters are not affected.
+ */
+func blendFactor(idx int, blend int32) float64 {
+	blendFloat := float64(blend)
+	blendFraction := 0.01 * blendFloat
+
+	/*
+	 * Decide which filter is faded out.
+	 */
+	switch idx {
+	case 0:
+		fac := 1.0 - blendFraction
+		return math.Min(fac, 1.0)
+	case 1:
+		fac := 1.0 + blendFraction
+		return math.Min(fac, 1.0)
+	default:
+		return 1.0
+	}
+
+}
+
 /*
  * Compile a new filter for this power amplifier.
  */
@@ -51,6 +80,7 @@ func (this *poweramp) compile(sampleRate uint32) (filter.Filter, error) {
 
 		}
 
+		blend, _ := this.getNumericValue("blend")
 		filters := make([]filter.Filter, NUM_FILTERS)
 
 		/*
@@ -75,7 +105,9 @@ func (this *poweramp) compile(sampleRate uint32) (filter.Filter, error) {
 				 * Verify that this is actually a valid filter and not a dummy value.
 				 */
 				if name != STRING_NONE {
-					fac := decibelsToFactor(level)
+					levelFac := decibelsToFactor(level)
+					blendFac := blendFactor(i, blend)
+					fac := levelFac * blendFac
 					flt := irs.CreateFilter(name, sampleRate)
 
 					/*
@@ -121,6 +153,8 @@ func (this *poweramp) compile(sampleRate uint32) (filter.Filter, error) {
 
 		}
 
+		blockSize := int(this.blockSize)
+		fltComposite.Prepare(blockSize)
 		return fltComposite, nil
 	}
 
@@ -180,6 +214,61 @@ func (this *poweramp) SetNumericValue(name string, value int32) error {
 	return err
 }
 
+/*
+ * Sets the number of frames the power amplifier processes at once and
+ * prepares its filter for it.
+ *
+ * The filter is prepared outside of the lock, since this takes time for long
+ * impulse responses, and swapped in once it is ready.
+ */
+func (this *poweramp) SetBlockSize(frames uint32) {
+	this.mutex.Lock()
+	changed := (frames != this.blockSize)
+	this.blockSize = frames
+	flt := this.currentFilter
+	this.mutex.Unlock()
+
+	/*
+	 * Only prepare the filters again if the block size changed.
+	 */
+	if changed {
+		prepared := prepareFilter(flt, frames)
+		this.mutex.Lock()
+
+		/*
+		 * Only replace the filters if they were not recompiled in
+		 * the meantime.
+		 */
+		if this.currentFilter == flt {
+			this.currentFilter = prepared
+		}
+
+		this.mutex.Unlock()
+	}
+
+}
+
+/*
+ * Returns the latency (in samples) of the power amplifier, which is the
+ * delay of the direct sound in its impulse response.
+ */
+func (this *poweramp) Latency(sampleRate uint32) uint32 {
+	this.mutex.RLock()
+	flt := this.currentFilter
+	this.mutex.RUnlock()
+
+	/*
+	 * Check if there is a filter.
+	 */
+	if flt == nil {
+		return 0
+	} else {
+		delay := flt.Delay()
+		return delay
+	}
+
+}
+
 /*
  * Power amplifier audio processing.
  */
@@ -189,6 +278,7 @@ func (this *poweramp) Process(in []float64, out []float64, sampleRate uint32) {
 	 * Check if sampling rate changed.
 	 */
 	if sampleRate != this.sampleRate {
+		this.mutex.Lock()
 		this.sampleRate = sampleRate
 		sr := this.sampleRate
 		flt, err := this.compile(sr)
@@ -200,9 +290,12 @@ func (this *poweramp) Process(in []float64, out []float64, sampleRate uint32) {
 			this.currentFilter = flt
 		}
 
+		this.mutex.Unlock()
 	}
 
+	this.mutex.RLock()
 	flt := this.currentFilter
+	this.mutex.RUnlock()
 
 	/*
 	 * If there is a filter, put the signal through it, otherwise write zeros to output.
@@ -282,12 +375,48 @@ func PreparePowerAmp(unit Unit, responses filter.ImpulseResponses) error {
 		}
 
 		amp.unitStruct.params = params
+		amp.unitStruct.publishParameters()
 		amp.impulseResponses = responses
 		return nil
 	}
 
 }
 
+/*
+ * Updates the list of impulse responses available to a power amplifier after
+ * the collection has been reloaded.
+ */
+func (this *poweramp) updateImpulseResponses(responses filter.ImpulseResponses) error {
+	names := responses.Names()
+	namesExtended := []string{STRING_NONE}
+	namesExtended = append(namesExtended, names...)
+	this.mutex.Lock()
+
+	/*
+	 * Replace the list of names for each filter.
+	 */
+	for i := 0; i < NUM_FILTERS; i++ {
+		iInc := int64(i + 1)
+		sIdxInc := strconv.FormatInt(iInc, 10)
+		paramFilter := "filter_" + sIdxInc
+		this.unitStruct.replaceDiscreteValues(paramFilter, namesExtended)
+	}
+
+	this.impulseResponses = responses
+	sr := this.sampleRate
+	flt, err := this.compile(sr)
+
+	/*
+	 * Check if filter was compiled.
+	 */
+	if err == nil {
+		this.currentFilter = flt
+	}
+
+	this.mutex.Unlock()
+	return err
+}
+
 /*
  * Create a power amp effects unit.
  */
@@ -326,6 +455,16 @@ func createPowerAmp() Unit {
 						"1048576",
 					},
 				},
+				Parameter{
+					Name:               "blend",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "%",
+					Minimum:            -100,
+					Maximum:            100,
+					NumericValue:       0,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
 			},
 		},
 	}
@@ -4,6 +4,14 @@ import (
 	"math"
 )
 
+/*
+ * Additional delay (in seconds) applied to the right channel of a stereo
+ * reverb.
+ */
+const (
+	REVERB_STEREO_SPREAD = 0.00052
+)
+
 /*
  * Data structure representing an allpass filter.
  */
@@ -29,6 +37,8 @@ type reverb struct {
 	unitStruct
 	allpasses       []*reverbAllpass
 	delayLine       *reverbDelayLine
+	allpassesRight  []*reverbAllpass
+	delayLineRight  *reverbDelayLine
 	frontBuffer     []float64
 	backBuffer      []float64
 	delayLineBuffer []float64
@@ -174,165 +184,221 @@ func (this *reverb) createDelayLine(indices []uint32, factors []float64) *reverb
 }
 
 /*
- * Reverb audio processing.
+ * Creates the allpass filters and the tapped delay line for one channel of
+ * the reverb. The spread (in seconds) is added to all delay times in order
+ * to decorrelate the channels of a stereo reverb.
  */
-func (this *reverb) Process(in []float64, out []float64, sampleRate uint32) {
-	nIn := len(in)
-	nOut := len(out)
+func (this *reverb) createStructures(sampleRate uint32, spread float64) ([]*reverbAllpass, *reverbDelayLine) {
+	sampleRateFloat := float64(sampleRate)
 
 	/*
-	 * Ensure that the input and output buffers are of equal size.
+	 * Delays for the allpass filters in seconds.
 	 */
-	if nIn != nOut {
+	allpassDelays := [...]float64{
+		0.04204,
+		0.01348,
+		0.00452,
+	}
 
-		/*
-		 * Write zeros to output buffer.
-		 */
-		for i, _ := range out {
-			out[i] = 0.0
-		}
+	numAllpasses := len(allpassDelays)
+	allpasses := make([]*reverbAllpass, numAllpasses)
 
-	} else {
-		this.mutex.RLock()
-		mix, _ := this.getNumericValue("mix")
-		this.mutex.RUnlock()
-		mixFloat := float64(mix)
-		wetFrac := 0.01 * mixFloat
-		dryFrac := 1.0 - wetFrac
-		sampleRateFloat := float64(sampleRate)
+	/*
+	 * Create allpass filters.
+	 */
+	for i, delaySeconds := range allpassDelays {
+		delaySpread := delaySeconds + spread
+		delaySamplesFloat := math.Round(delaySpread * sampleRateFloat)
+		delaySamples := int(delaySamplesFloat)
+		allpass := this.createAllpass(delaySamples, 0.7)
+		allpasses[i] = allpass
+	}
 
-		/*
-		 * If sample rate has changed, recreate all filter structures.
-		 */
-		if this.sampleRate != sampleRate {
+	/*
+	 * Time of delay line taps in seconds.
+	 */
+	delayLineTapsTime := []float64{
+		0.19196,
+		0.19996,
+		0.21596,
+		0.23204,
+	}
 
-			/*
-			 * Delays for the allpass filters in seconds.
-			 */
-			allpassDelays := [...]float64{
-				0.04204,
-				0.01348,
-				0.00452,
-			}
+	numTaps := len(delayLineTapsTime)
+	delayLineTapsSamples := make([]uint32, numTaps)
 
-			numAllpasses := len(allpassDelays)
+	/*
+	 * Calculate delay line taps in samples.
+	 */
+	for i, tapSeconds := range delayLineTapsTime {
+		tapSpread := tapSeconds + spread
+		tapSamples := math.Round(tapSpread * sampleRateFloat)
+		delayLineTapsSamples[i] = uint32(tapSamples)
+	}
 
-			/*
-			 * Make sure that the allpass array has the right size.
-			 */
-			if len(this.allpasses) != numAllpasses {
-				this.allpasses = make([]*reverbAllpass, numAllpasses)
-			}
+	/*
+	 * Coefficients for delay line taps.
+	 */
+	delayLineTapsCoeffs := []float64{
+		0.1855,
+		0.18325,
+		0.17875,
+		0.17425,
+	}
 
-			/*
-			 * Create allpass filters.
-			 */
-			for i, delaySeconds := range allpassDelays {
-				delaySamplesFloat := math.Round(delaySeconds * sampleRateFloat)
-				delaySamples := int(delaySamplesFloat)
-				allpass := this.createAllpass(delaySamples, 0.7)
-				this.allpasses[i] = allpass
-			}
+	delayLine := this.createDelayLine(delayLineTapsSamples, delayLineTapsCoeffs)
+	return allpasses, delayLine
+}
 
-			/*
-			 * Time of delay line taps in seconds.
-			 */
-			delayLineTapsTime := []float64{
-				0.19196,
-				0.19996,
-				0.21596,
-				0.23204,
-			}
+/*
+ * Renders one channel of the reverb.
+ */
+func (this *reverb) render(in []float64, out []float64, allpasses []*reverbAllpass, delayLine *reverbDelayLine, wetFrac float64, sampleRate uint32) {
+	nIn := len(in)
+	dryFrac := 1.0 - wetFrac
+	frontBuffer := this.frontBuffer
+	backBuffer := this.backBuffer
+	delayLineBuffer := this.delayLineBuffer
 
-			numTaps := len(delayLineTapsTime)
-			delayLineTapsSamples := make([]uint32, numTaps)
+	/*
+	 * Ensure that the front buffer has the correct size.
+	 */
+	if len(frontBuffer) != nIn {
+		frontBuffer = make([]float64, nIn)
+	}
 
-			/*
-			 * Calculate delay line taps in samples.
-			 */
-			for i, tapSeconds := range delayLineTapsTime {
-				tapSamples := math.Round(tapSeconds * sampleRateFloat)
-				delayLineTapsSamples[i] = uint32(tapSamples)
-			}
+	/*
+	 * Ensure that the back buffer has the correct size.
+	 */
+	if len(backBuffer) != nIn {
+		backBuffer = make([]float64, nIn)
+	}
 
-			/*
-			 * Coefficients for delay line taps.
-			 */
-			delayLineTapsCoeffs := []float64{
-				0.1855,
-				0.18325,
-				0.17875,
-				0.17425,
-			}
+	/*
+	 * Ensure that the delay line buffer has the correct size.
+	 */
+	if len(delayLineBuffer) != nIn {
+		delayLineBuffer = make([]float64, nIn)
+	}
 
-			delayLine := this.createDelayLine(delayLineTapsSamples, delayLineTapsCoeffs)
-			this.delayLine = delayLine
-			this.sampleRate = sampleRate
-		}
+	delayLine.process(in, delayLineBuffer, sampleRate)
+	copy(frontBuffer, delayLineBuffer)
 
-		frontBuffer := this.frontBuffer
-		backBuffer := this.backBuffer
-		delayLineBuffer := this.delayLineBuffer
+	/*
+	 * Process the sound using the allpass filters.
+	 */
+	for _, allpass := range allpasses {
+		allpass.process(frontBuffer, backBuffer, sampleRate)
+		backBuffer, frontBuffer = frontBuffer, backBuffer
+	}
 
-		/*
-		 * Ensure that the front buffer has the correct size.
-		 */
-		if len(frontBuffer) != nIn {
-			frontBuffer = make([]float64, nIn)
-		}
+	halfWetFrac := 0.5 * wetFrac
+
+	/*
+	 * Mix the dry and wet signal.
+	 */
+	for i, drySample := range in {
+		delayedSample := delayLineBuffer[i]
+		wetSample := frontBuffer[i]
+		processedSampleSum := delayedSample + wetSample
+		pre := (dryFrac * drySample) + (halfWetFrac * processedSampleSum)
 
 		/*
-		 * Ensure that the back buffer has the correct size.
+		 * Limit the output signal to the appropriate range.
 		 */
-		if len(backBuffer) != nIn {
-			backBuffer = make([]float64, nIn)
+		if pre < -1.0 {
+			out[i] = -1.0
+		} else if pre > 1.0 {
+			out[i] = 1.0
+		} else {
+			out[i] = pre
 		}
 
+	}
+
+	this.frontBuffer = frontBuffer
+	this.backBuffer = backBuffer
+	this.delayLineBuffer = delayLineBuffer
+}
+
+/*
+ * Reads the mix parameter of the reverb and recreates all filter structures
+ * if the sample rate has changed.
+ */
+func (this *reverb) prepare(sampleRate uint32) float64 {
+	params := this.processingParameters()
+	mix, _ := params.numericValue("mix")
+	mixFloat := float64(mix)
+	wetFrac := 0.01 * mixFloat
+
+	/*
+	 * If sample rate has changed, recreate all filter structures.
+	 */
+	if this.sampleRate != sampleRate {
+		this.allpasses, this.delayLine = this.createStructures(sampleRate, 0.0)
+		this.allpassesRight, this.delayLineRight = this.createStructures(sampleRate, REVERB_STEREO_SPREAD)
+		this.sampleRate = sampleRate
+	}
+
+	return wetFrac
+}
+
+/*
+ * Reverb audio processing.
+ */
+func (this *reverb) Process(in []float64, out []float64, sampleRate uint32) {
+	nIn := len(in)
+	nOut := len(out)
+
+	/*
+	 * Ensure that the input and output buffers are of equal size.
+	 */
+	if nIn != nOut {
+
 		/*
-		 * Ensure that the delay line buffer has the correct size.
+		 * Write zeros to output buffer.
 		 */
-		if len(delayLineBuffer) != nIn {
-			delayLineBuffer = make([]float64, nIn)
+		for i, _ := range out {
+			out[i] = 0.0
 		}
 
-		this.delayLine.process(in, delayLineBuffer, sampleRate)
-		copy(frontBuffer, delayLineBuffer)
+	} else {
+		wetFrac := this.prepare(sampleRate)
+		this.render(in, out, this.allpasses, this.delayLine, wetFrac, sampleRate)
+	}
+
+}
+
+/*
+ * Reverb audio processing for a stereo signal. The right channel uses
+ * slightly longer delays than the left channel to decorrelate both sides.
+ */
+func (this *reverb) ProcessStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32) {
+	nIn := len(inLeft)
+
+	/*
+	 * Ensure that all buffers are of equal size.
+	 */
+	if (len(inRight) != nIn) || (len(outLeft) != nIn) || (len(outRight) != nIn) {
 
 		/*
-		 * Process the sound using the allpass filters.
+		 * Write zeros to left output buffer.
 		 */
-		for _, allpass := range this.allpasses {
-			allpass.process(frontBuffer, backBuffer, sampleRate)
-			backBuffer, frontBuffer = frontBuffer, backBuffer
+		for i := range outLeft {
+			outLeft[i] = 0.0
 		}
 
-		halfWetFrac := 0.5 * wetFrac
-
 		/*
-		 * Mix the dry and wet signal.
+		 * Write zeros to right output buffer.
 		 */
-		for i, drySample := range in {
-			delayedSample := delayLineBuffer[i]
-			wetSample := frontBuffer[i]
-			processedSampleSum := delayedSample + wetSample
-			pre := (dryFrac * drySample) + (halfWetFrac * processedSampleSum)
-
-			/*
-			 * Limit the output signal to the appropriate range.
-			 */
-			if pre < -1.0 {
-				out[i] = -1.0
-			} else if pre > 1.0 {
-				out[i] = 1.0
-			} else {
-				out[i] = pre
-			}
-
+		for i := range outRight {
+			outRight[i] = 0.0
 		}
 
-		this.frontBuffer = frontBuffer
-		this.backBuffer = backBuffer
-		this.delayLineBuffer = delayLineBuffer
+	} else {
+		wetFrac := this.prepare(sampleRate)
+		this.render(inLeft, outLeft, this.allpasses, this.delayLine, wetFrac, sampleRate)
+		this.render(inRight, outRight, this.allpassesRight, this.delayLineRight, wetFrac, sampleRate)
 	}
 
 }
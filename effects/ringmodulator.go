@@ -16,9 +16,8 @@ type ringModulator struct {
  * Ring modulator audio processing.
  */
 func (this *ringModulator) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	frequency, _ := this.getNumericValue("frequency")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	frequency, _ := params.numericValue("frequency")
 	phase := this.phase
 	sampleRateFloat := float64(sampleRate)
 	frequencyFloat := float64(frequency)
@@ -5,27 +5,75 @@ import (
 	"math"
 )
 
+/*
+ * Global constants.
+ */
+const (
+	SIGNAL_GENERATOR_PINK_GAIN = 0.11
+)
+
 /*
  * Data structure representing a signal generator.
  */
 type signalGenerator struct {
 	unitStruct
-	phase float64
-	prng  random.PseudoRandomNumberGenerator
+	phase         float64
+	prng          random.PseudoRandomNumberGenerator
+	pink          [7]float64
+	sweepPosition uint64
+}
+
+/*
+ * Returns the pseudo-random number generator of the signal generator,
+ * creating it if necessary.
+ */
+func (this *signalGenerator) random() random.PseudoRandomNumberGenerator {
+	prng := this.prng
+
+	/*
+	 * Check if pseudo-random number generator is initialized.
+	 */
+	if prng == nil {
+		prng = random.CreatePRNG(1337)
+		this.prng = prng
+	}
+
+	return prng
+}
+
+/*
+ * Filters white noise into pink noise, which has equal energy per octave.
+ *
+ * This uses the refined filter by Paul Kellet, a sum of first-order low-pass
+ * filters approximating a slope of -3 dB per octave.
+ */
+func (this *signalGenerator) pinkNoise(white float64) float64 {
+	b := &this.pink
+	b[0] = (0.99886 * b[0]) + (0.0555179 * white)
+	b[1] = (0.99332 * b[1]) + (0.0750759 * white)
+	b[2] = (0.96900 * b[2]) + (0.1538520 * white)
+	b[3] = (0.86650 * b[3]) + (0.3104856 * white)
+	b[4] = (0.55000 * b[4]) + (0.5329522 * white)
+	b[5] = (-0.7616 * b[5]) - (0.0168980 * white)
+	sum := b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + (0.5362 * white)
+	b[6] = 0.115926 * white
+	return SIGNAL_GENERATOR_PINK_GAIN * sum
 }
 
 /*
  * Signal generator audio processing.
  */
 func (this *signalGenerator) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	inputAmplitude, _ := this.getNumericValue("input_amplitude")
-	inputGain, _ := this.getNumericValue("input_gain")
-	signalType, _ := this.getDiscreteValue("signal_type")
-	signalFrequency, _ := this.getNumericValue("signal_frequency")
-	signalAmplitude, _ := this.getNumericValue("signal_amplitude")
-	signalGain, _ := this.getNumericValue("signal_gain")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	inputAmplitude, _ := params.numericValue("input_amplitude")
+	inputGain, _ := params.numericValue("input_gain")
+	signalType, _ := params.discreteValue("signal_type")
+	signalFrequency, _ := params.numericValue("signal_frequency")
+	signalAmplitude, _ := params.numericValue("signal_amplitude")
+	signalGain, _ := params.numericValue("signal_gain")
+	sweepStart, _ := params.numericValue("sweep_start")
+	sweepEnd, _ := params.numericValue("sweep_end")
+	sweepTime, _ := params.numericValue("sweep_time")
 	inputAmplitudeFloat := float64(inputAmplitude)
 	facInputGain := decibelsToFactor(inputGain)
 	facInput := (0.01 * inputAmplitudeFloat) * facInputGain
@@ -127,25 +175,66 @@ func (this *signalGenerator) Process(in []float64, out []float64, sampleRate uin
 		phase = math.Mod(phase, MATH_TWO_PI)
 		break
 	case "noise":
-		prng := this.prng
+		prng := this.random()
 
 		/*
-		 * Check if pseudo-random number generator is initialized.
+		 * Process each sample.
 		 */
-		if prng == nil {
-			prng = random.CreatePRNG(1337)
-			this.prng = prng
+		for i, sample := range in {
+			r := prng.NextFloat()
+			uniform := (1.0 - (2.0 * r))
+			out[i] = (facInput * sample) + (facSignal * uniform)
 		}
 
+		break
+	case "pink_noise":
+		prng := this.random()
+
 		/*
 		 * Process each sample.
 		 */
 		for i, sample := range in {
 			r := prng.NextFloat()
 			uniform := (1.0 - (2.0 * r))
-			out[i] = (facInput * sample) + (facSignal * uniform)
+			signal := this.pinkNoise(uniform)
+			out[i] = (facInput * sample) + (facSignal * signal)
+		}
+
+		break
+	case "sweep":
+		sweepStartFloat := float64(sweepStart)
+		sweepEndFloat := float64(sweepEnd)
+		sweepTimeFloat := float64(sweepTime)
+		sweepSamplesFloat := sweepTimeFloat * sampleRateFloat
+		sweepSamples := uint64(sweepSamplesFloat)
+		sweepRatio := math.Log(sweepEndFloat / sweepStartFloat)
+		position := this.sweepPosition
+
+		/*
+		 * Process each sample.
+		 */
+		for i, sample := range in {
+
+			/*
+			 * Start over at the start frequency after each sweep.
+			 */
+			if position >= sweepSamples {
+				position = 0
+				phase = 0.0
+			}
+
+			positionFloat := float64(position)
+			progress := positionFloat / sweepSamplesFloat
+			exponent := progress * sweepRatio
+			frequency := sweepStartFloat * math.Exp(exponent)
+			signal := math.Sin(phase)
+			out[i] = (facInput * sample) + (facSignal * signal)
+			phase += MATH_TWO_PI * (frequency / sampleRateFloat)
+			phase = math.Mod(phase, MATH_TWO_PI)
+			position++
 		}
 
+		this.sweepPosition = position
 		break
 	}
 
@@ -198,6 +287,8 @@ func createSignalGenerator() Unit {
 						"square",
 						"sawtooth",
 						"noise",
+						"pink_noise",
+						"sweep",
 					},
 				},
 				Parameter{
@@ -230,6 +321,36 @@ func createSignalGenerator() Unit {
 					DiscreteValueIndex: -1,
 					DiscreteValues:     nil,
 				},
+				Parameter{
+					Name:               "sweep_start",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "Hz",
+					Minimum:            1,
+					Maximum:            20000,
+					NumericValue:       20,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "sweep_end",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "Hz",
+					Minimum:            1,
+					Maximum:            20000,
+					NumericValue:       20000,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "sweep_time",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "s",
+					Minimum:            1,
+					Maximum:            60,
+					NumericValue:       10,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
 			},
 		},
 	}
@@ -0,0 +1,112 @@
+package effects
+
+import (
+	"math"
+	"testing"
+)
+
+/*
+ * Verify that a signal generator produces periodic waveforms at the frequency
+ * and amplitude set, passes its input through and stays within range for
+ * extreme parameters.
+ */
+func TestSignalGenerator(t *testing.T) {
+	n := 48000
+	in := make([]float64, n)
+	out := make([]float64, n)
+
+	/*
+	 * Periodic waveforms.
+	 */
+	waveforms := []string{
+		"sine",
+		"triangle",
+		"square",
+		"sawtooth",
+	}
+
+	/*
+	 * Generate each waveform on top of silence.
+	 */
+	for _, waveform := range waveforms {
+		u := CreateUnit(UNIT_SIGNALGENERATOR)
+		u.SetDiscreteValue("signal_type", waveform)
+		u.SetNumericValue("signal_frequency", 1000)
+		u.Process(in, out, TEST_SAMPLE_RATE)
+		estimate := estimateFrequency(out, TEST_SAMPLE_RATE)
+
+		/*
+		 * Allow for the resolution of the estimate.
+		 */
+		if math.Abs(estimate-1000.0) > 10.0 {
+			t.Errorf("%s: Frequency should be %f, but is %f.", waveform, 1000.0, estimate)
+		}
+
+		peak := 0.0
+
+		/*
+		 * Find the peak amplitude.
+		 */
+		for _, sample := range out {
+			peak = math.Max(peak, math.Abs(sample))
+		}
+
+		/*
+		 * The waveform spans the full range.
+		 */
+		if math.Abs(peak-1.0) > 1e-3 {
+			t.Errorf("%s: Peak amplitude should be %f, but is %f.", waveform, 1.0, peak)
+		}
+
+		checkOutput(t, waveform, out)
+	}
+
+	u := CreateUnit(UNIT_SIGNALGENERATOR)
+	u.SetNumericValue("signal_amplitude", 0)
+	noise := createNoise(n, 1)
+	u.Process(noise, out, TEST_SAMPLE_RATE)
+
+	/*
+	 * Without a signal, the input passes through unchanged.
+	 */
+	for i, sample := range out {
+
+		/*
+		 * Check if we found a difference.
+		 */
+		if sample != noise[i] {
+			t.Errorf("Sample %d should be %f, but is %f.", i, noise[i], sample)
+			break
+		}
+
+	}
+
+	/*
+	 * All waveforms.
+	 */
+	signalTypes := []string{
+		"sine",
+		"triangle",
+		"square",
+		"sawtooth",
+		"noise",
+		"pink_noise",
+		"sweep",
+	}
+
+	/*
+	 * Generate each waveform at the extremes of its parameters.
+	 */
+	for _, signalType := range signalTypes {
+		extreme := CreateUnit(UNIT_SIGNALGENERATOR)
+		extreme.SetDiscreteValue("signal_type", signalType)
+		extreme.SetNumericValue("signal_frequency", 20000)
+		extreme.SetNumericValue("sweep_start", 20000)
+		extreme.SetNumericValue("sweep_end", 1)
+		extreme.SetNumericValue("sweep_time", 1)
+		extreme.Process(in, out, TEST_SAMPLE_RATE)
+		extreme.Process(in, out, TEST_SAMPLE_RATE)
+		checkOutput(t, signalType, out)
+	}
+
+}
@@ -0,0 +1,169 @@
+package effects
+
+import (
+	"math"
+)
+
+/*
+ * Constants for the slow gear.
+ */
+const (
+	SLOW_GEAR_FADE_TIME    = 5
+	SLOW_GEAR_FAST_RELEASE = 10
+	SLOW_GEAR_ONSET_RATIO  = 2.0
+	SLOW_GEAR_SLOW_TIME    = 50
+)
+
+/*
+ * Data structure representing a slow gear (violin swell) effect.
+ */
+type slowGear struct {
+	unitStruct
+	envelopeFast float64
+	envelopeSlow float64
+	gain         float64
+	above        bool
+	onset        bool
+	falling      bool
+}
+
+/*
+ * Slow gear audio processing.
+ *
+ * A note attack is detected when the envelope rises above the threshold or
+ * suddenly rises well above its recent average while playing. On each
+ * attack, the gain quickly fades down to the minimum level and then swells
+ * back up to unity gain over the swell time. While the signal stays below
+ * the threshold, the gain is held at the minimum level.
+ */
+func (this *slowGear) Process(in []float64, out []float64, sampleRate uint32) {
+	params := this.processingParameters()
+	sensitivity, _ := params.numericValue("sensitivity")
+	swellTime, _ := params.numericValue("swell_time")
+	minimumLevel, _ := params.numericValue("minimum_level")
+	thresholdFactor := decibelsToFactor(-sensitivity)
+	minimumFactor := decibelsToFactor(minimumLevel)
+	gainRange := 1.0 - minimumFactor
+	swellStep := gainRange * rampStep(swellTime, sampleRate)
+	fadeStep := gainRange * rampStep(SLOW_GEAR_FADE_TIME, sampleRate)
+	fastCoefficient := smoothingCoefficient(SLOW_GEAR_FAST_RELEASE, sampleRate)
+	slowCoefficient := smoothingCoefficient(SLOW_GEAR_SLOW_TIME, sampleRate)
+	envelopeFast := this.envelopeFast
+	envelopeSlow := this.envelopeSlow
+	gain := this.gain
+	above := this.above
+	onset := this.onset
+	falling := this.falling
+
+	/*
+	 * Start at the minimum level.
+	 */
+	if gain < minimumFactor {
+		gain = minimumFactor
+	}
+
+	/*
+	 * Process each sample.
+	 */
+	for i, sample := range in {
+		sampleAbs := math.Abs(sample)
+		envelopeFast *= fastCoefficient
+
+		/*
+		 * If the absolute value of the current sample exceeds the
+		 * current envelope value, make it the new envelope value.
+		 */
+		if sampleAbs > envelopeFast {
+			envelopeFast = sampleAbs
+		}
+
+		envelopeSlow += (1.0 - slowCoefficient) * (envelopeFast - envelopeSlow)
+		wasAbove := above
+		wasOnset := onset
+		above = envelopeFast > thresholdFactor
+		onset = above && (envelopeFast > (SLOW_GEAR_ONSET_RATIO * envelopeSlow))
+
+		/*
+		 * Restart the swell on each attack.
+		 */
+		if (above && !wasAbove) || (onset && !wasOnset) {
+			falling = true
+		}
+
+		/*
+		 * Fade down to the minimum level after an attack or below the
+		 * threshold, otherwise swell up to unity gain.
+		 */
+		if falling || !above {
+			gain = math.Max(gain-fadeStep, minimumFactor)
+
+			/*
+			 * Swell up again once the minimum level is reached.
+			 */
+			if gain <= minimumFactor {
+				falling = false
+			}
+
+		} else {
+			gain = math.Min(gain+swellStep, 1.0)
+		}
+
+		out[i] = gain * sample
+	}
+
+	this.envelopeFast = envelopeFast
+	this.envelopeSlow = envelopeSlow
+	this.gain = gain
+	this.above = above
+	this.onset = onset
+	this.falling = falling
+}
+
+/*
+ * Create a slow gear effects unit.
+ */
+func createSlowGear() Unit {
+
+	/*
+	 * Create effects unit.
+	 */
+	u := slowGear{
+		unitStruct: unitStruct{
+			unitType: UNIT_SLOW_GEAR,
+			params: []Parameter{
+				Parameter{
+					Name:               "sensitivity",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            0,
+					Maximum:            60,
+					NumericValue:       40,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "swell_time",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "ms",
+					Minimum:            10,
+					Maximum:            3000,
+					NumericValue:       600,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "minimum_level",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            -60,
+					Maximum:            0,
+					NumericValue:       -60,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+			},
+		},
+	}
+
+	return &u
+}
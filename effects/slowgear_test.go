@@ -0,0 +1,105 @@
+package effects
+
+import (
+	"math"
+	"testing"
+)
+
+/*
+ * Finds the peak of a signal.
+ */
+func signalPeak(signal []float64) float64 {
+	peak := float64(0.0)
+
+	/*
+	 * Find the largest absolute value.
+	 */
+	for _, sample := range signal {
+		peak = math.Max(peak, math.Abs(sample))
+	}
+
+	return peak
+}
+
+/*
+ * Verify that a slow gear swells the volume up after a note attack and
+ * restarts the swell on the next attack.
+ */
+func TestSlowGear(t *testing.T) {
+	nSilence := 9600
+	nNote := 48000
+	n := nSilence + (2 * nNote)
+	in := make([]float64, n)
+	out := make([]float64, n)
+	frequency := 440.0
+
+	/*
+	 * Generate two notes after a pause, the second one much louder.
+	 */
+	for i := nSilence; i < n; i++ {
+		iFloat := float64(i)
+		arg := (2.0 * math.Pi * frequency * iFloat) / TEST_SAMPLE_RATE
+		amplitude := 0.05
+
+		/*
+		 * The second note is louder.
+		 */
+		if i >= (nSilence + nNote) {
+			amplitude = 0.5
+		}
+
+		in[i] = amplitude * math.Sin(arg)
+	}
+
+	u := CreateUnit(UNIT_SLOW_GEAR)
+	u.SetNumericValue("swell_time", 500)
+	u.Process(in, out, TEST_SAMPLE_RATE)
+	attackStart := nSilence
+	attackEnd := nSilence + 480
+	peakAttack := signalPeak(out[attackStart:attackEnd])
+
+	/*
+	 * The attack of the first note should be suppressed.
+	 */
+	if peakAttack > 0.005 {
+		t.Errorf("Peak of first attack should be at most %f, but is %f.", 0.005, peakAttack)
+	}
+
+	sustainStart := nSilence + nNote - 4800
+	sustainEnd := nSilence + nNote
+	peakSustain := signalPeak(out[sustainStart:sustainEnd])
+
+	/*
+	 * The first note should have swelled up to unity gain.
+	 */
+	if math.Abs(peakSustain-0.05) > 0.001 {
+		t.Errorf("Peak of first note should be %f, but is %f.", 0.05, peakSustain)
+	}
+
+	attackStart = nSilence + nNote + 480
+	attackEnd = nSilence + nNote + 960
+	peakAttack = signalPeak(out[attackStart:attackEnd])
+
+	/*
+	 * The attack of the second note should be suppressed as well.
+	 */
+	if peakAttack > 0.05 {
+		t.Errorf("Peak of second attack should be at most %f, but is %f.", 0.05, peakAttack)
+	}
+
+	sustainStart = n - 4800
+	peakSustain = signalPeak(out[sustainStart:n])
+
+	/*
+	 * The second note should have swelled up to unity gain.
+	 */
+	if math.Abs(peakSustain-0.5) > 0.01 {
+		t.Errorf("Peak of second note should be %f, but is %f.", 0.5, peakSustain)
+	}
+
+	u.SetNumericValue("swell_time", 10)
+	u.SetNumericValue("minimum_level", 0)
+	noise := createNoise(n, 1)
+	u.Process(noise, out, TEST_SAMPLE_RATE)
+	checkOutput(t, "noise", out)
+}
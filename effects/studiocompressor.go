@@ -0,0 +1,322 @@
+package effects
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	STUDIO_COMPRESSOR_MIN_LEVEL = -120.0
+)
+
+/*
+ * Data structure representing a studio compressor effect.
+ */
+type studioCompressor struct {
+	unitStruct
+	envelope      float64
+	history       []float64
+	bufferPre     []float64
+	gainReduction int32
+	makeupGain    smoothedValue
+}
+
+/*
+ * Calculates the coefficient of a one-pole smoothing filter with a certain
+ * time constant.
+ */
+func smoothingCoefficient(timeMs int32, sampleRate uint32) float64 {
+	timeFloat := float64(timeMs)
+	timeSeconds := 0.001 * timeFloat
+	sampleRateFloat := float64(sampleRate)
+	samples := timeSeconds * sampleRateFloat
+
+	/*
+	 * A time constant shorter than a sample means no smoothing.
+	 */
+	if samples < 1.0 {
+		return 0.0
+	} else {
+		arg := -1.0 / samples
+		return math.Exp(arg)
+	}
+
+}
+
+/*
+ * Calculates the gain reduction (in decibels) for a certain input level (in
+ * decibels).
+ *
+ * Within the knee, the ratio gradually increases from 1:1 to the full ratio.
+ */
+func compressorGainReduction(level float64, threshold float64, ratio float64, knee float64) float64 {
+	excess := level - threshold
+	slope := 1.0 - (1.0 / ratio)
+	halfKnee := 0.5 * knee
+
+	/*
+	 * Check whether the level is below, inside or above the knee.
+	 */
+	if excess <= -halfKnee {
+		return 0.0
+	} else if excess < halfKnee {
+		kneeExcess := excess + halfKnee
+		kneeExcessSquared := kneeExcess * kneeExcess
+		return (slope * kneeExcessSquared) / (2.0 * knee)
+	} else {
+		return slope * excess
+	}
+
+}
+
+/*
+ * Returns the gain reduction (in decibels) the compressor applied during the
+ * last period.
+ */
+func (this *studioCompressor) GainReduction() int32 {
+	result := atomic.LoadInt32(&this.gainReduction)
+	return result
+}
+
+/*
+ * Converts a lookahead time (in milliseconds) into a number of samples.
+ */
+func lookaheadToSamples(lookahead int32, sampleRate uint32) int {
+	lookaheadFloat := float64(lookahead)
+	lookaheadSeconds := 0.001 * lookaheadFloat
+	sampleRateFloat := float64(sampleRate)
+	lookaheadSamplesFloat := math.Floor((lookaheadSeconds * sampleRateFloat) + 0.5)
+	lookaheadSamples := int(lookaheadSamplesFloat)
+	return lookaheadSamples
+}
+
+/*
+ * Returns the latency (in samples) of the studio compressor, which is the
+ * lookahead.
+ */
+func (this *studioCompressor) Latency(sampleRate uint32) uint32 {
+	params := this.processingParameters()
+	lookahead, _ := params.numericValue("lookahead")
+	lookaheadSamples := lookaheadToSamples(lookahead, sampleRate)
+	latency := uint32(lookaheadSamples)
+	return latency
+}
+
+/*
+ * Studio compressor audio processing.
+ *
+ * With lookahead, the output is delayed, so that the detector sees the signal
+ * before it is processed. This works like the lookahead of the oversampler,
+ * prepending the tail of the previous input to the current input.
+ */
+func (this *studioCompressor) Process(in []float64, out []float64, sampleRate uint32) {
+	params := this.processingParameters()
+	threshold, _ := params.numericValue("threshold")
+	ratio, _ := params.numericValue("ratio")
+	knee, _ := params.numericValue("knee")
+	attackTime, _ := params.numericValue("attack_time")
+	releaseTime, _ := params.numericValue("release_time")
+	makeup, _ := params.discreteValue("makeup")
+	makeupGain, _ := params.numericValue("makeup_gain")
+	lookahead, _ := params.numericValue("lookahead")
+	thresholdFloat := float64(threshold)
+	ratioFloat := float64(ratio)
+	kneeFloat := float64(knee)
+	attackCoefficient := smoothingCoefficient(attackTime, sampleRate)
+	releaseCoefficient := smoothingCoefficient(releaseTime, sampleRate)
+	makeupGainFloat := float64(makeupGain)
+
+	/*
+	 * With automatic makeup gain, make up for the gain reduction at full
+	 * scale.
+	 */
+	if makeup == "auto" {
+		makeupGainFloat = compressorGainReduction(0.0, thresholdFloat, ratioFloat, kneeFloat)
+	}
+
+	smoothingSamples := this.smoothingSamples(sampleRate)
+	makeupGainRamp := &this.makeupGain
+	makeupGainRamp.setTarget(makeupGainFloat, smoothingSamples)
+	lookaheadSamples := lookaheadToSamples(lookahead, sampleRate)
+	history := this.history
+
+	/*
+	 * If the lookahead changed, start over with silence.
+	 */
+	if len(history) != lookaheadSamples {
+		history = make([]float64, lookaheadSamples)
+		this.history = history
+	}
+
+	n := len(in)
+	bufferPreSize := lookaheadSamples + n
+	bufferPre := this.bufferPre
+
+	/*
+	 * Make sure the pre-processing buffer has the correct size.
+	 */
+	if len(bufferPre) != bufferPreSize {
+		bufferPre = make([]float64, bufferPreSize)
+		this.bufferPre = bufferPre
+	}
+
+	copy(bufferPre[0:lookaheadSamples], history)
+	copy(bufferPre[lookaheadSamples:bufferPreSize], in)
+	tailStart := bufferPreSize - lookaheadSamples
+	copy(history, bufferPre[tailStart:bufferPreSize])
+	envelope := this.envelope
+	maxReduction := float64(0.0)
+
+	/*
+	 * Process each sample.
+	 */
+	for i, sample := range in {
+		sampleAbs := math.Abs(sample)
+		level := STUDIO_COMPRESSOR_MIN_LEVEL
+
+		/*
+		 * Only take the logarithm of non-zero samples.
+		 */
+		if sampleAbs > 0.0 {
+			sampleLevel := 20.0 * math.Log10(sampleAbs)
+			level = math.Max(sampleLevel, STUDIO_COMPRESSOR_MIN_LEVEL)
+		}
+
+		reduction := compressorGainReduction(level, thresholdFloat, ratioFloat, kneeFloat)
+		coefficient := releaseCoefficient
+
+		/*
+		 * Use the attack time while the gain reduction increases.
+		 */
+		if reduction > envelope {
+			coefficient = attackCoefficient
+		}
+
+		envelope = reduction + (coefficient * (envelope - reduction))
+
+		/*
+		 * Keep track of the maximum gain reduction.
+		 */
+		if envelope > maxReduction {
+			maxReduction = envelope
+		}
+
+		makeupGainCurrent := makeupGainRamp.next()
+		gain := makeupGainCurrent - envelope
+		exp := 0.05 * gain
+		gainFactor := math.Pow(10.0, exp)
+		delayed := bufferPre[i]
+		pre := gainFactor * delayed
+		out[i] = limitSample(pre)
+	}
+
+	this.envelope = envelope
+	maxReductionRounded := math.Round(maxReduction)
+	gainReduction := int32(maxReductionRounded)
+	atomic.StoreInt32(&this.gainReduction, gainReduction)
+}
+
+/*
+ * Create a studio compressor effects unit.
+ */
+func createStudioCompressor() Unit {
+
+	/*
+	 * Create effects unit.
+	 */
+	u := studioCompressor{
+		unitStruct: unitStruct{
+			unitType: UNIT_STUDIO_COMPRESSOR,
+			params: []Parameter{
+				Parameter{
+					Name:               "threshold",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            -60,
+					Maximum:            0,
+					NumericValue:       -20,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "ratio",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       ": 1",
+					Minimum:            1,
+					Maximum:            20,
+					NumericValue:       4,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "knee",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            0,
+					Maximum:            24,
+					NumericValue:       6,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "attack_time",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "ms",
+					Minimum:            0,
+					Maximum:            200,
+					NumericValue:       10,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "release_time",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "ms",
+					Minimum:            1,
+					Maximum:            2000,
+					NumericValue:       100,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "makeup",
+					Type:               PARAMETER_TYPE_DISCRETE,
+					PhysicalUnit:       "",
+					Minimum:            -1,
+					Maximum:            -1,
+					NumericValue:       -1,
+					DiscreteValueIndex: 0,
+					DiscreteValues: []string{
+						"auto",
+						"manual",
+					},
+				},
+				Parameter{
+					Name:               "makeup_gain",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            0,
+					Maximum:            30,
+					NumericValue:       0,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "lookahead",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "ms",
+					Minimum:            0,
+					Maximum:            10,
+					NumericValue:       0,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+			},
+		},
+	}
+
+	return &u
+}
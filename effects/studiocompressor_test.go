@@ -0,0 +1,126 @@
+package effects
+
+import (
+	"math"
+	"testing"
+)
+
+/*
+ * Verify that a studio compressor reduces the gain above the threshold
+ * according to its ratio, delays the signal by the lookahead and stays within
+ * range for extreme parameters.
+ */
+func TestStudioCompressor(t *testing.T) {
+	n := 1024
+
+	/*
+	 * Levels of the input signal.
+	 */
+	levels := []float64{
+		0.05,
+		0.5,
+	}
+
+	/*
+	 * The quiet signal lies below the threshold, while the loud one
+	 * exceeds it by about 14 dB, which a ratio of 4:1 compresses to
+	 * about 3.5 dB.
+	 */
+	expected := []float64{
+		0.05,
+		math.Pow(10.0, -1.0) * math.Pow(5.0, 0.25),
+	}
+
+	/*
+	 * Compress each level.
+	 */
+	for i, level := range levels {
+		u := CreateUnit(UNIT_STUDIO_COMPRESSOR)
+		u.SetNumericValue("threshold", -20)
+		u.SetNumericValue("ratio", 4)
+		u.SetNumericValue("knee", 0)
+		u.SetNumericValue("attack_time", 0)
+		u.SetDiscreteValue("makeup", "manual")
+		in := make([]float64, n)
+		out := make([]float64, n)
+
+		/*
+		 * Generate a constant signal.
+		 */
+		for j := range in {
+			in[j] = level
+		}
+
+		u.Process(in, out, TEST_SAMPLE_RATE)
+
+		/*
+		 * Compare each sample.
+		 */
+		for j, sample := range out {
+
+			/*
+			 * Check if we found a significant difference.
+			 */
+			if math.Abs(sample-expected[i]) > 1e-6 {
+				t.Errorf("Level %f: Sample %d should be %f, but is %f.", level, j, expected[i], sample)
+				break
+			}
+
+		}
+
+	}
+
+	u := CreateUnit(UNIT_STUDIO_COMPRESSOR)
+	u.SetDiscreteValue("makeup", "manual")
+	u.SetNumericValue("lookahead", 5)
+	latencyUnit := u.(LatencyUnit)
+	latency := latencyUnit.Latency(TEST_SAMPLE_RATE)
+	delay := 240
+
+	/*
+	 * The lookahead delays the signal.
+	 */
+	if latency != uint32(delay) {
+		t.Errorf("Latency should be %d, but is %d.", delay, latency)
+	}
+
+	in := make([]float64, n)
+	out := make([]float64, n)
+	in[0] = 0.05
+	u.Process(in, out, TEST_SAMPLE_RATE)
+
+	/*
+	 * The impulse below the threshold appears after the lookahead.
+	 */
+	for i, sample := range out {
+		expected := 0.0
+
+		/*
+		 * Check if we expect the impulse.
+		 */
+		if i == delay {
+			expected = 0.05
+		}
+
+		/*
+		 * Check if we found a significant difference.
+		 */
+		if math.Abs(sample-expected) > 1e-6 {
+			t.Errorf("Sample %d should be %f, but is %f.", i, expected, sample)
+		}
+
+	}
+
+	extreme := CreateUnit(UNIT_STUDIO_COMPRESSOR)
+	extreme.SetNumericValue("threshold", -60)
+	extreme.SetNumericValue("ratio", 20)
+	extreme.SetNumericValue("knee", 24)
+	extreme.SetNumericValue("attack_time", 200)
+	extreme.SetNumericValue("release_time", 2000)
+	extreme.SetDiscreteValue("makeup", "manual")
+	extreme.SetNumericValue("makeup_gain", 30)
+	extreme.SetNumericValue("lookahead", 10)
+	noise := createNoise(n, 1)
+	extreme.Process(noise, out, TEST_SAMPLE_RATE)
+	checkOutput(t, "extreme", out)
+}
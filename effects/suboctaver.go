@@ -0,0 +1,363 @@
+package effects
+
+import (
+	"github.com/andrepxx/go-dsp-guitar/fft"
+	"github.com/andrepxx/go-dsp-guitar/tuner"
+	"math"
+)
+
+/*
+ * Constants for the sub-octaver.
+ */
+const (
+	SUB_OCTAVER_CONFIDENCE        = 0.7
+	SUB_OCTAVER_GATE_FREQUENCY    = 20.0
+	SUB_OCTAVER_GLIDE_FREQUENCY   = 10.0
+	SUB_OCTAVER_HOP_RATE          = 100
+	SUB_OCTAVER_MAX_FREQUENCY     = 1500.0
+	SUB_OCTAVER_MIN_FREQUENCY     = 40.0
+	SUB_OCTAVER_RELEASE_FREQUENCY = 3.0
+	SUB_OCTAVER_WINDOW_RATE       = 20
+)
+
+/*
+ * Data structure representing a sub-octaver, which tracks the pitch of its
+ * input and synthesizes signals one and two octaves below it.
+ */
+type subOctaver struct {
+	unitStruct
+	fourierTransform      fft.FourierTransform
+	sampleRate            uint32
+	history               []float64
+	historyPtr            int
+	hopCounter            int
+	bufCorrelation        []float64
+	bufFFT                []complex128
+	voiced                bool
+	targetFrequency       float64
+	frequency             float64
+	phase                 float64
+	envelope              float64
+	gate                  float64
+	levelClean            smoothedValue
+	levelOctaveDownFirst  smoothedValue
+	levelOctaveDownSecond smoothedValue
+}
+
+/*
+ * Allocates the analysis buffers of the sub-octaver for a certain sample
+ * rate.
+ */
+func (this *subOctaver) prepareBuffers(sampleRate uint32) {
+
+	/*
+	 * Only allocate buffers if the sample rate changed.
+	 */
+	if (this.history == nil) || (this.sampleRate != sampleRate) {
+		n := sampleRate / SUB_OCTAVER_WINDOW_RATE
+		n64 := uint64(n)
+		fftSize, _ := fft.NextPowerOfTwo(2 * n64)
+		this.fourierTransform = fft.CreateFourierTransform()
+		this.sampleRate = sampleRate
+		this.history = make([]float64, n)
+		this.historyPtr = 0
+		this.hopCounter = 0
+		this.bufCorrelation = make([]float64, fftSize)
+		this.bufFFT = make([]complex128, fftSize)
+		this.voiced = false
+	}
+
+}
+
+/*
+ * Estimates the pitch of the most recent input samples.
+ *
+ * The pitch is only considered reliable if the signal is above the threshold
+ * and sufficiently periodic. Otherwise, the sub-octave signals fade out and
+ * the last frequency is held.
+ */
+func (this *subOctaver) analyze(thresholdFactor float64) {
+	history := this.history
+	historyPtr := this.historyPtr
+	n := len(history)
+	bufCorrelation := this.bufCorrelation
+	headLength := n - historyPtr
+	copy(bufCorrelation, history[historyPtr:n])
+	copy(bufCorrelation[headLength:n], history[0:historyPtr])
+	ft := this.fourierTransform
+	err := tuner.Autocorrelate(ft, bufCorrelation, n, this.bufFFT)
+	wasVoiced := this.voiced
+	this.voiced = false
+
+	/*
+	 * Only estimate the pitch if the auto-correlation was calculated.
+	 */
+	if err == nil {
+		nFloat := float64(n)
+		energy := bufCorrelation[0]
+		rms := math.Sqrt(energy / nFloat)
+
+		/*
+		 * Only track signals above the threshold.
+		 */
+		if (energy > 0.0) && (rms >= thresholdFactor) {
+			sampleRateFloat := float64(this.sampleRate)
+			lowIdx := int(sampleRateFloat / SUB_OCTAVER_MAX_FREQUENCY)
+			highIdx := int(sampleRateFloat/SUB_OCTAVER_MIN_FREQUENCY) + 1
+			maxIdx := n - 1
+
+			/*
+			 * Make sure the search range fits into the window.
+			 */
+			if lowIdx < 1 {
+				lowIdx = 1
+			}
+
+			/*
+			 * Make sure the search range fits into the window.
+			 */
+			if highIdx > maxIdx {
+				highIdx = maxIdx
+			}
+
+			/*
+			 * Skip the descent from the peak at zero lag, which would
+			 * otherwise dominate the search for low notes.
+			 */
+			for (lowIdx < highIdx) && (bufCorrelation[lowIdx] <= bufCorrelation[lowIdx-1]) {
+				lowIdx++
+			}
+
+			/*
+			 * Check if there is anything left to search.
+			 */
+			if lowIdx < highIdx {
+				period, peak := tuner.EstimatePeriod(bufCorrelation, lowIdx, highIdx)
+				overlap := (nFloat - period) / nFloat
+				expected := energy * overlap
+				confidence := peak / expected
+
+				/*
+				 * Only follow sufficiently periodic signals.
+				 */
+				if confidence >= SUB_OCTAVER_CONFIDENCE {
+					frequency := sampleRateFloat / period
+					this.targetFrequency = frequency
+					this.voiced = true
+
+					/*
+					 * Do not glide into a new note after a pause.
+					 */
+					if !wasVoiced {
+						this.frequency = frequency
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Returns the value of an oscillator at a certain phase, given in cycles.
+ */
+func subOctaverOscillator(waveform string, phase float64) float64 {
+
+	/*
+	 * Decide on the waveform.
+	 */
+	switch waveform {
+	case "square":
+
+		/*
+		 * The first half of each cycle is positive.
+		 */
+		if phase < 0.5 {
+			return 1.0
+		} else {
+			return -1.0
+		}
+
+	default:
+		arg := MATH_TWO_PI * phase
+		result := math.Sin(arg)
+		return result
+	}
+
+}
+
+/*
+ * Sub-octaver audio processing.
+ */
+func (this *subOctaver) Process(in []float64, out []float64, sampleRate uint32) {
+	params := this.processingParameters()
+	waveform, _ := params.discreteValue("waveform")
+	threshold, _ := params.numericValue("threshold")
+	levelClean, _ := params.numericValue("level_clean")
+	levelOctaveDownFirst, _ := params.numericValue("level_octave_down_first")
+	levelOctaveDownSecond, _ := params.numericValue("level_octave_down_second")
+	this.prepareBuffers(sampleRate)
+	thresholdFactor := decibelsToFactor(threshold)
+	smoothingSamples := this.smoothingSamples(sampleRate)
+	cleanRamp := &this.levelClean
+	cleanTarget := decibelsToFactor(levelClean)
+	cleanRamp.setTarget(cleanTarget, smoothingSamples)
+	firstRamp := &this.levelOctaveDownFirst
+	firstTarget := decibelsToFactor(levelOctaveDownFirst)
+	firstRamp.setTarget(firstTarget, smoothingSamples)
+	secondRamp := &this.levelOctaveDownSecond
+	secondTarget := decibelsToFactor(levelOctaveDownSecond)
+	secondRamp.setTarget(secondTarget, smoothingSamples)
+	sampleRateFloat := float64(sampleRate)
+	hopSize := int(sampleRate / SUB_OCTAVER_HOP_RATE)
+	gateFactor := onePoleFactor(SUB_OCTAVER_GATE_FREQUENCY, sampleRate)
+	glideFactor := onePoleFactor(SUB_OCTAVER_GLIDE_FREQUENCY, sampleRate)
+	releaseFactor := 1.0 - onePoleFactor(SUB_OCTAVER_RELEASE_FREQUENCY, sampleRate)
+	history := this.history
+	n := len(history)
+	frequency := this.frequency
+	phase := this.phase
+	envelope := this.envelope
+	gate := this.gate
+
+	/*
+	 * Process each sample.
+	 */
+	for i, sample := range in {
+		history[this.historyPtr] = sample
+		this.historyPtr = (this.historyPtr + 1) % n
+		this.hopCounter++
+
+		/*
+		 * Estimate the pitch as soon as enough new samples have arrived.
+		 */
+		if this.hopCounter >= hopSize {
+			this.hopCounter = 0
+			this.frequency = frequency
+			this.analyze(thresholdFactor)
+			frequency = this.frequency
+		}
+
+		gateTarget := float64(0.0)
+
+		/*
+		 * Open the gate while the pitch is tracked.
+		 */
+		if this.voiced {
+			gateTarget = 1.0
+		}
+
+		gate += gateFactor * (gateTarget - gate)
+		frequency += glideFactor * (this.targetFrequency - frequency)
+		sampleAbs := math.Abs(sample)
+		envelope *= releaseFactor
+
+		/*
+		 * If the absolute value of the current sample exceeds the
+		 * current envelope value, make it the new envelope value.
+		 */
+		if sampleAbs > envelope {
+			envelope = sampleAbs
+		}
+
+		/*
+		 * The phase runs at the second octave down, the first octave
+		 * down runs twice as fast and stays aligned with it.
+		 */
+		phase += frequency / (4.0 * sampleRateFloat)
+		phase -= math.Floor(phase)
+		phaseFirst := 2.0 * phase
+		phaseFirst -= math.Floor(phaseFirst)
+		first := subOctaverOscillator(waveform, phaseFirst)
+		second := subOctaverOscillator(waveform, phase)
+		amplitude := gate * envelope
+		cleanFactor := cleanRamp.next()
+		firstFactor := firstRamp.next()
+		secondFactor := secondRamp.next()
+		pre := cleanFactor * sample
+		pre += firstFactor * amplitude * first
+		pre += secondFactor * amplitude * second
+		out[i] = limitSample(pre)
+	}
+
+	this.frequency = frequency
+	this.phase = phase
+	this.envelope = envelope
+	this.gate = gate
+}
+
+/*
+ * Create a sub-octaver effects unit.
+ */
+func createSubOctaver() Unit {
+
+	/*
+	 * Create effects unit.
+	 */
+	u := subOctaver{
+		unitStruct: unitStruct{
+			unitType: UNIT_SUB_OCTAVER,
+			params: []Parameter{
+				Parameter{
+					Name:               "waveform",
+					Type:               PARAMETER_TYPE_DISCRETE,
+					PhysicalUnit:       "",
+					Minimum:            -1,
+					Maximum:            -1,
+					NumericValue:       -1,
+					DiscreteValueIndex: 0,
+					DiscreteValues: []string{
+						"sine",
+						"square",
+					},
+				},
+				Parameter{
+					Name:               "threshold",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            -90,
+					Maximum:            0,
+					NumericValue:       -50,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "level_clean",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            -60,
+					Maximum:            0,
+					NumericValue:       0,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "level_octave_down_first",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            -60,
+					Maximum:            0,
+					NumericValue:       -6,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+				Parameter{
+					Name:               "level_octave_down_second",
+					Type:               PARAMETER_TYPE_NUMERIC,
+					PhysicalUnit:       "dB",
+					Minimum:            -60,
+					Maximum:            0,
+					NumericValue:       -12,
+					DiscreteValueIndex: -1,
+					DiscreteValues:     nil,
+				},
+			},
+		},
+	}
+
+	return &u
+}
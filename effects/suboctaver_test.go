@@ -0,0 +1,120 @@
+package effects
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+/*
+ * Verify that a sub-octaver synthesizes signals one and two octaves below a
+ * sine wave, stays silent without input and stays finite for noise.
+ */
+func TestSubOctaver(t *testing.T) {
+	n := 4 * 48000
+	in := make([]float64, n)
+	out := make([]float64, n)
+
+	/*
+	 * Frequencies (in Hz) of the input signal.
+	 */
+	frequencies := []float64{
+		82.41,
+		196.0,
+		440.0,
+	}
+
+	/*
+	 * Generate a sine wave at each frequency.
+	 */
+	for _, frequency := range frequencies {
+
+		/*
+		 * Generate a sine wave.
+		 */
+		for i := range in {
+			iFloat := float64(i)
+			arg := (2.0 * math.Pi * frequency * iFloat) / TEST_SAMPLE_RATE
+			in[i] = 0.5 * math.Sin(arg)
+		}
+
+		/*
+		 * Isolate the first and then the second octave down.
+		 */
+		for octave := 1; octave <= 2; octave++ {
+			name := fmt.Sprintf("%.2fHz_octave%d", frequency, octave)
+			u := CreateUnit(UNIT_SUB_OCTAVER)
+			u.SetNumericValue("level_clean", -60)
+			u.SetNumericValue("level_octave_down_first", -60)
+			u.SetNumericValue("level_octave_down_second", -60)
+
+			/*
+			 * Enable the octave to verify.
+			 */
+			if octave == 1 {
+				u.SetNumericValue("level_octave_down_first", 0)
+			} else {
+				u.SetNumericValue("level_octave_down_second", 0)
+			}
+
+			u.Process(in, out, TEST_SAMPLE_RATE)
+			steady := out[n/4:]
+			estimate := estimateFrequency(steady, TEST_SAMPLE_RATE)
+			octaveFloat := float64(octave)
+			divisor := math.Pow(2.0, octaveFloat)
+			expected := frequency / divisor
+
+			/*
+			 * Allow for the resolution of the estimate.
+			 */
+			if math.Abs(estimate-expected) > (0.03 * expected) {
+				t.Errorf("%s: Frequency should be %f, but is %f.", name, expected, estimate)
+			}
+
+			peak := float64(0.0)
+
+			/*
+			 * Find the peak of the output.
+			 */
+			for _, sample := range steady {
+				peak = math.Max(peak, math.Abs(sample))
+			}
+
+			/*
+			 * The sub-octave follows the envelope of the input.
+			 */
+			if math.Abs(peak-0.5) > 0.1 {
+				t.Errorf("%s: Peak should be %f, but is %f.", name, 0.5, peak)
+			}
+
+		}
+
+	}
+
+	silence := make([]float64, n)
+	u := CreateUnit(UNIT_SUB_OCTAVER)
+	u.Process(silence, out, TEST_SAMPLE_RATE)
+
+	/*
+	 * Verify that silence stays silent.
+	 */
+	for i, sample := range out {
+
+		/*
+		 * Check if sample is zero.
+		 */
+		if sample != 0.0 {
+			t.Errorf("Sample %d should be %f, but is %f.", i, 0.0, sample)
+			break
+		}
+
+	}
+
+	u.SetDiscreteValue("waveform", "square")
+	u.SetNumericValue("threshold", -90)
+	u.SetNumericValue("level_octave_down_first", 0)
+	u.SetNumericValue("level_octave_down_second", 0)
+	noise := createNoise(n, 1)
+	u.Process(noise, out, TEST_SAMPLE_RATE)
+	checkOutput(t, "noise", out)
+}
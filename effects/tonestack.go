@@ -1,9 +1,5 @@
 package effects
 
-import (
-	"math"
-)
-
 /*
  * Data structure representing a tone stack effect.
  */
@@ -21,19 +17,17 @@ func (this *toneStack) Process(in []float64, out []float64, sampleRate uint32) {
 	facs := [...]float64{0.0, 0.0, 0.0, 0.0}
 	names := [...]string{"low", "middle", "presence", "high"}
 	numBands := len(facs)
-	this.mutex.RLock()
+	params := this.processingParameters()
 
 	/*
 	 * Read in levels and calculate factors.
 	 */
 	for i := 0; i < numBands; i++ {
 		name := names[i]
-		level, _ := this.getNumericValue(name)
+		level, _ := params.numericValue(name)
 		facs[i] = decibelsToFactor(level)
 	}
 
-	this.mutex.RUnlock()
-
 	/*
 	 * Allocate storage for highpass capacitor voltages if needed.
 	 */
@@ -48,8 +42,14 @@ func (this *toneStack) Process(in []float64, out []float64, sampleRate uint32) {
 		this.lowpassCapVoltages = make([]float64, numBands)
 	}
 
-	sampleRateFloat := float64(sampleRate)
-	minusTwoPiOverSampleRate := -MATH_TWO_PI / sampleRateFloat
+	factors := [...]float64{0.0, 0.0, 0.0, 0.0, 0.0}
+
+	/*
+	 * Calculate the filter gain for each corner frequency.
+	 */
+	for i, frequency := range frequencies {
+		factors[i] = trapezoidalFactor(frequency, sampleRate)
+	}
 
 	/*
 	 * Process each sample.
@@ -61,27 +61,12 @@ func (this *toneStack) Process(in []float64, out []float64, sampleRate uint32) {
 		 * Process each band and sum them all up.
 		 */
 		for j := 0; j < numBands; j++ {
-			jInc := j + 1
-			hcv := this.highpassCapVoltages[j]
-			lcv := this.lowpassCapVoltages[j]
-			frequencyA := frequencies[j]
-			frequencyAFloat := float64(frequencyA)
-			argHP := minusTwoPiOverSampleRate * frequencyAFloat
-			dischargePerSampleHP := math.Exp(argHP)
-			dischargePerSampleHPInv := 1.0 - dischargePerSampleHP
-			frequencyB := frequencies[jInc]
-			frequencyBFloat := float64(frequencyB)
-			argLP := minusTwoPiOverSampleRate * frequencyBFloat
-			dischargePerSampleLP := math.Exp(argLP)
-			dischargePerSampleLPInv := 1.0 - dischargePerSampleLP
-			diff := sample - hcv
-			hcv += diff * dischargePerSampleHPInv
-			diff -= lcv
-			pre := lcv
-			lcv += diff * dischargePerSampleLPInv
-			this.highpassCapVoltages[j] = hcv
-			this.lowpassCapVoltages[j] = lcv
-			sum += facs[j] * pre
+			factorHP := factors[j]
+			factorLP := factors[j+1]
+			lowpass := trapezoidalLowpass(sample, &this.highpassCapVoltages[j], factorHP)
+			highpass := sample - lowpass
+			band := trapezoidalLowpass(highpass, &this.lowpassCapVoltages[j], factorLP)
+			sum += facs[j] * band
 		}
 
 		/*
@@ -1,5 +1,9 @@
 package effects
 
+import (
+	"sync/atomic"
+)
+
 /*
  * Data structure representing a tremolo effect.
  */
@@ -7,20 +11,39 @@ type tremolo struct {
 	unitStruct
 	attenuated   bool
 	inStateSince uint32
+	tempo        uint32
+}
+
+/*
+ * Sets the tempo (in beats per minute) the modulation synchronizes to.
+ */
+func (this *tremolo) SetTempo(bpm uint32) {
+	atomic.StoreUint32(&this.tempo, bpm)
 }
 
 /*
  * Tremolo audio processing.
  */
 func (this *tremolo) Process(in []float64, out []float64, sampleRate uint32) {
-	this.mutex.RLock()
-	frequency, _ := this.getNumericValue("frequency")
-	phase, _ := this.getNumericValue("phase")
-	depth, _ := this.getNumericValue("depth")
-	this.mutex.RUnlock()
+	params := this.processingParameters()
+	frequency, _ := params.numericValue("frequency")
+	phase, _ := params.numericValue("phase")
+	depth, _ := params.numericValue("depth")
+	sync, _ := params.discreteValue("sync")
+	tempo := atomic.LoadUint32(&this.tempo)
 	sampleRateFloat := float64(sampleRate)
 	frequencyFloat := float64(frequency)
 	frequencyValue := 0.1 * frequencyFloat
+	syncedFrequency := noteValueToFrequency(sync, tempo)
+
+	/*
+	 * If the modulation is synchronized to the tempo, it ignores the
+	 * frequency.
+	 */
+	if syncedFrequency > 0.0 {
+		frequencyValue = syncedFrequency
+	}
+
 	periodLengthFloat := sampleRateFloat / frequencyValue
 	periodLength := uint32(periodLengthFloat)
 	phaseFloat := float64(phase)
@@ -106,6 +129,7 @@ func createTremolo() Unit {
 					DiscreteValueIndex: -1,
 					DiscreteValues:     nil,
 				},
+				createSyncParameter(),
 			},
 		},
 	}
@@ -29,17 +29,18 @@ const (
 /*
  * Global variables and mutexes.
  *
- * (1) Protecting the global data structures themselves.
- * (2) Protecting the large Fourier coefficients.
- * (3) Protecting the large permutation coefficients.
+ * The coefficients for small transforms are generated on startup and never
+ * modified, so they may be read without locking.
+ *
+ * (1) Protecting the large Fourier coefficients.
+ * (2) Protecting the large permutation coefficients.
  */
-var g_mutex sync.RWMutex                  // (1)
-var g_mutexCoefficientsLarge sync.RWMutex // (2)
-var g_coefficientsLarge map[int][]complex128
-var g_coefficientsSmall []complex128
-var g_mutexPermutationLarge sync.RWMutex // (3)
-var g_permutationLarge map[int][]int
-var g_permutationSmall []int
+var g_mutexCoefficientsLarge sync.RWMutex // (1)
+var g_coefficientsLarge map[int][]complex128 = make(map[int][]complex128)
+var g_coefficientsSmall []complex128 = generateFourierCoefficients()
+var g_mutexPermutationLarge sync.RWMutex // (2)
+var g_permutationLarge map[int][]int = make(map[int][]int)
+var g_permutationSmall []int = generatePermutationCoefficients()
 
 /*
  * A Fourier transform.
@@ -56,10 +57,17 @@ type FourierTransform interface {
 /*
  * Data structure representing a Fourier transform.
  *
+ * Each transform keeps its own copy of the references to the coefficients it
+ * used before, so that transforms running concurrently on different goroutines
+ * do not have to synchronize once they are warmed up.
+ *
  * This data structure is not safe for concurrent use!
  */
 type fourierTransformStruct struct {
-	scrapspace []complex128
+	scrapspace   []complex128
+	twiddles     []complex128
+	coefficients map[int][]complex128
+	permutations map[int][]int
 }
 
 /*
@@ -239,6 +247,60 @@ func permutationCoefficients(n int) []int {
 
 }
 
+/*
+ * Returns the Fourier coefficients for a Fourier transform of the specified
+ * size, only consulting the global coefficients if this transform did not use
+ * them before.
+ */
+func (this *fourierTransformStruct) fourierCoefficients(n int) []complex128 {
+	coefficients, ok := this.coefficients[n]
+
+	/*
+	 * Fetch the coefficients and remember them.
+	 */
+	if !ok {
+		coefficients = fourierCoefficients(n)
+
+		/*
+		 * Create the map on first use.
+		 */
+		if this.coefficients == nil {
+			this.coefficients = make(map[int][]complex128)
+		}
+
+		this.coefficients[n] = coefficients
+	}
+
+	return coefficients
+}
+
+/*
+ * Returns the permutation coefficients for an in-place Fourier transform of the
+ * specified size, only consulting the global coefficients if this transform did
+ * not use them before.
+ */
+func (this *fourierTransformStruct) permutationCoefficients(n int) []int {
+	coefficients, ok := this.permutations[n]
+
+	/*
+	 * Fetch the coefficients and remember them.
+	 */
+	if !ok {
+		coefficients = permutationCoefficients(n)
+
+		/*
+		 * Create the map on first use.
+		 */
+		if this.permutations == nil {
+			this.permutations = make(map[int][]int)
+		}
+
+		this.permutations[n] = coefficients
+	}
+
+	return coefficients
+}
+
 /*
  * Compute the fast Fourier transform using the recursive Cooley-Tukey algorithm.
  */
@@ -286,59 +348,6 @@ func cooleyTukey(vec []complex128) []complex128 {
 
 }
 
-/*
- * Initialize the computation of a Fourier transform.
- */
-func initialize() {
-	g_mutex.RLock()
-
-	/*
-	 * Generate the global Fourier coefficient slice.
-	 */
-	if g_coefficientsSmall == nil {
-		g_mutex.RUnlock()
-		g_mutex.Lock()
-		g_coefficientsSmall = generateFourierCoefficients()
-		g_mutex.Unlock()
-		g_mutex.RLock()
-	}
-
-	/*
-	 * Initialize the global Fourier coefficient map.
-	 */
-	if g_coefficientsLarge == nil {
-		g_mutex.RUnlock()
-		g_mutex.Lock()
-		g_coefficientsLarge = make(map[int][]complex128)
-		g_mutex.Unlock()
-		g_mutex.RLock()
-	}
-
-	/*
-	 * Initialize the global permutation coefficient slice.
-	 */
-	if g_permutationSmall == nil {
-		g_mutex.RUnlock()
-		g_mutex.Lock()
-		g_permutationSmall = generatePermutationCoefficients()
-		g_mutex.Unlock()
-		g_mutex.RLock()
-	}
-
-	/*
-	 * Initialize the global permutation coefficient map.
-	 */
-	if g_permutationLarge == nil {
-		g_mutex.RUnlock()
-		g_mutex.Lock()
-		g_permutationLarge = make(map[int][]int)
-		g_mutex.Unlock()
-		g_mutex.RLock()
-	}
-
-	g_mutex.RUnlock()
-}
-
 /*
  * Swap the real and imaginary parts of a complex-valued vector and return the new
  * vector.
@@ -527,7 +536,7 @@ func Shift(vec []complex128, inverse bool) {
  */
 func (this *fourierTransformStruct) permute(vec []complex128) {
 	n := len(vec)
-	coeff := permutationCoefficients(n)
+	coeff := this.permutationCoefficients(n)
 	scrap := this.scrapspace
 
 	/*
@@ -550,13 +559,71 @@ func (this *fourierTransformStruct) permute(vec []complex128) {
 
 }
 
+/*
+ * Perform the butterfly operations of a Fourier round on a pair of
+ * half-blocks.
+ *
+ * This is the portable implementation, which is used whenever no vectorized
+ * kernel is available.
+ */
+func butterfliesGeneric(lower []complex128, upper []complex128, twiddles []complex128) {
+
+	/*
+	 * Combine each element of the lower half-block with the corresponding
+	 * element of the upper half-block.
+	 */
+	for k, elem := range lower {
+		product := twiddles[k] * upper[k]
+		lower[k] = elem + product
+		upper[k] = elem - product
+	}
+
+}
+
+/*
+ * Gather the twiddle factors of a Fourier round into a contiguous slice, so
+ * that the butterfly operations can access them sequentially.
+ */
+func (this *fourierTransformStruct) gatherTwiddles(coeffs []complex128, half int, stride int) []complex128 {
+
+	/*
+	 * In the last round, the coefficients are already contiguous.
+	 */
+	if stride == 1 {
+		return coeffs[0:half]
+	} else {
+		scrap := this.twiddles
+
+		/*
+		 * Check if size for twiddle factors is sufficient.
+		 */
+		if len(scrap) < half {
+			scrap = make([]complex128, half)
+			this.twiddles = scrap
+		}
+
+		twiddles := scrap[0:half]
+
+		/*
+		 * Pick every stride-th coefficient.
+		 */
+		for k := range twiddles {
+			idx := k * stride
+			twiddles[k] = coeffs[idx]
+		}
+
+		return twiddles
+	}
+
+}
+
 /*
  * Compute the fast Fourier transform using an (unnamed?) in-place algorithm.
  */
 func (this *fourierTransformStruct) inplaceTransform(vec []complex128) {
 	this.permute(vec)
 	n := len(vec)
-	coeffs := fourierCoefficients(n)
+	coeffs := this.fourierCoefficients(n)
 	size := 1
 	stride := n
 	n64 := uint64(n)
@@ -570,36 +637,18 @@ func (this *fourierTransformStruct) inplaceTransform(vec []complex128) {
 	for i := 1; i <= pmm; i++ {
 		size <<= 1
 		stride >>= 1
-		blocks := n / size // The number of blocks.
+		half := size >> 1 // The length of a half-block.
+		twiddles := this.gatherTwiddles(coeffs, half, stride)
 
 		/*
 		 * Process each block.
 		 */
-		for j := 0; j < blocks; j++ {
-			halfBlocks := blocks << 1
-			half := n / halfBlocks // The length of a half-block.
-			dj := j << 1
-			offset := dj * half // The offset into the current block.
-
-			/*
-			 * Perform the butterfly operations.
-			 */
-			for k := 0; k < half; k++ {
-				i := k + offset
-				j := i + half
-				vi := vec[i]
-				vj := vec[j]
-				l := k * stride
-				m := half * stride
-				n := l + m
-				cl := coeffs[l]
-				cn := coeffs[n]
-				left := vi + (cl * vj)
-				right := vi + (cn * vj)
-				vec[i] = left
-				vec[j] = right
-			}
-
+		for offset := 0; offset < n; offset += size {
+			middle := offset + half
+			end := offset + size
+			lower := vec[offset:middle]
+			upper := vec[middle:end]
+			butterflies(lower, upper, twiddles)
 		}
 
 	}
@@ -610,7 +659,6 @@ func (this *fourierTransformStruct) inplaceTransform(vec []complex128) {
  * Calculates the Fourier transform of a vector.
  */
 func (this *fourierTransformStruct) Fourier(vec []complex128, scaling int, mode int) []complex128 {
-	initialize()
 	result := vec
 
 	/*
@@ -670,7 +718,6 @@ func (this *fourierTransformStruct) Fourier(vec []complex128, scaling int, mode
  * Calculates the inverse Fourier transform of a vector.
  */
 func (this *fourierTransformStruct) InverseFourier(vec []complex128, scaling int, mode int) []complex128 {
-	initialize()
 	n := len(vec)
 	nFloat := float64(n)
 	r := float64(0.0)
@@ -791,7 +838,7 @@ func (this *fourierTransformStruct) RealFourier(in []float64, out []complex128,
 			this.Fourier(lower, scaling, MODE_INPLACE)
 			copy(upper, lower)
 			j := complex(0.0, 1.0)
-			coeffs := fourierCoefficients(nIn)
+			coeffs := this.fourierCoefficients(nIn)
 
 			/*
 			 * Iterate over the upper half of the output sequence to perform
@@ -915,7 +962,7 @@ func (this *fourierTransformStruct) RealInverseFourier(in []complex128, out []fl
 			lower := in[0:nHalf]
 			upper := in[nHalf:nIn]
 			copy(upper, lower)
-			coeffs := fourierCoefficients(nIn)
+			coeffs := this.fourierCoefficients(nIn)
 			j := complex(0.0, 1.0)
 
 			/*
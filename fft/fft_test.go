@@ -2,6 +2,7 @@ package fft
 
 import (
 	"math"
+	"math/cmplx"
 	"testing"
 )
 
@@ -836,3 +837,313 @@ func TestShift(t *testing.T) {
 	}
 
 }
+
+/*
+ * Create a complex-valued test signal of the specified length.
+ */
+func createTestSignal(n int) []complex128 {
+	vec := make([]complex128, n)
+
+	/*
+	 * Fill the vector with a mixture of oscillations.
+	 */
+	for i := range vec {
+		iFloat := float64(i)
+		re := math.Sin(0.1*iFloat) + (0.5 * math.Cos(0.37*iFloat))
+		im := math.Cos(0.23*iFloat) - (0.25 * math.Sin(0.71*iFloat))
+		vec[i] = complex(re, im)
+	}
+
+	return vec
+}
+
+/*
+ * Check whether two complex-valued slices are close to each other.
+ */
+func areComplexSlicesClose(a []complex128, b []complex128, tolerance float64) bool {
+
+	/*
+	 * Check whether the two slices are of the same size.
+	 */
+	if len(a) != len(b) {
+		return false
+	} else {
+
+		/*
+		 * Iterate over the arrays to compare values.
+		 */
+		for i, elem := range a {
+			diff := elem - b[i]
+			diffAbs := cmplx.Abs(diff)
+
+			/*
+			 * Check if we found a significant difference.
+			 */
+			if diffAbs > tolerance {
+				return false
+			}
+
+		}
+
+		return true
+	}
+
+}
+
+/*
+ * Check that the (possibly vectorized) butterfly kernel yields the same
+ * results as the portable implementation.
+ */
+func TestButterflies(t *testing.T) {
+
+	/*
+	 * Test both even and odd lengths.
+	 */
+	for n := 1; n <= 17; n++ {
+		lower := createTestSignal(n)
+		upper := createTestSignal(n + 1)[1:]
+		twiddles := fourierCoefficients(2 * n)[0:n]
+		lowerExpected := make([]complex128, n)
+		upperExpected := make([]complex128, n)
+		copy(lowerExpected, lower)
+		copy(upperExpected, upper)
+		butterflies(lower, upper, twiddles)
+		butterfliesGeneric(lowerExpected, upperExpected, twiddles)
+		lowerOk := areComplexSlicesClose(lower, lowerExpected, 1e-12)
+		upperOk := areComplexSlicesClose(upper, upperExpected, 1e-12)
+
+		/*
+		 * Check if kernel returned the expected results.
+		 */
+		if !lowerOk || !upperOk {
+			t.Errorf("Butterflies of length %d did not return expected result.", n)
+		}
+
+	}
+
+}
+
+/*
+ * Check that the in-place transform agrees with the recursive transform for
+ * sizes beyond the precalculated coefficients.
+ */
+func TestLargeFFT(t *testing.T) {
+	sizes := []int{1024, 8192, 16384, 65536}
+	ft := CreateFourierTransform()
+
+	/*
+	 * Test each size.
+	 */
+	for _, n := range sizes {
+		vec := createTestSignal(n)
+		expected := ft.Fourier(vec, SCALING_ORTHONORMAL, MODE_STANDARD)
+		ft.Fourier(vec, SCALING_ORTHONORMAL, MODE_INPLACE)
+
+		/*
+		 * Check if in-place transform returned the expected result.
+		 */
+		if !areComplexSlicesClose(vec, expected, 1e-9) {
+			t.Errorf("In-place FFT of size %d did not return expected result.", n)
+		}
+
+	}
+
+}
+
+/*
+ * Measure the performance of a butterfly kernel.
+ */
+func benchmarkButterflies(b *testing.B, generic bool) {
+	n := 4096
+	lower := createTestSignal(n)
+	upper := createTestSignal(n)
+	twiddles := fourierCoefficients(2 * n)[0:n]
+	b.ResetTimer()
+
+	/*
+	 * Run the kernel repeatedly.
+	 */
+	for i := 0; i < b.N; i++ {
+
+		/*
+		 * Decide on which kernel to use.
+		 */
+		if generic {
+			butterfliesGeneric(lower, upper, twiddles)
+		} else {
+			butterflies(lower, upper, twiddles)
+		}
+
+	}
+
+}
+
+/*
+ * Measure the performance of an in-place Fourier transform of the specified
+ * size.
+ */
+func benchmarkFourier(b *testing.B, n int) {
+	vec := createTestSignal(n)
+	ft := CreateFourierTransform()
+	ft.Fourier(vec, SCALING_ORTHONORMAL, MODE_INPLACE)
+	b.ResetTimer()
+
+	/*
+	 * Transform the vector repeatedly.
+	 */
+	for i := 0; i < b.N; i++ {
+		ft.Fourier(vec, SCALING_ORTHONORMAL, MODE_INPLACE)
+	}
+
+}
+
+/*
+ * Benchmark the butterfly kernel selected for this processor.
+ */
+func BenchmarkButterflies(b *testing.B) {
+	benchmarkButterflies(b, false)
+}
+
+/*
+ * Benchmark the portable butterfly kernel.
+ */
+func BenchmarkButterfliesGeneric(b *testing.B) {
+	benchmarkButterflies(b, true)
+}
+
+/*
+ * Benchmark an in-place Fourier transform of 1024 elements.
+ */
+func BenchmarkFourier1024(b *testing.B) {
+	benchmarkFourier(b, 1024)
+}
+
+/*
+ * Benchmark an in-place Fourier transform of 16384 elements.
+ */
+func BenchmarkFourier16384(b *testing.B) {
+	benchmarkFourier(b, 16384)
+}
+
+/*
+ * Benchmark an in-place Fourier transform of 262144 elements, as used for
+ * long impulse responses at high sample rates.
+ */
+func BenchmarkFourier262144(b *testing.B) {
+	benchmarkFourier(b, 262144)
+}
+
+/*
+ * Transform a test signal using a separate Fourier transform and report
+ * whether the result matches the expected one.
+ */
+func transformConcurrently(n int, expected []complex128, results chan bool) {
+	ft := CreateFourierTransform()
+	vec := createTestSignal(n)
+	ft.Fourier(vec, SCALING_ORTHONORMAL, MODE_INPLACE)
+	results <- areComplexSlicesClose(vec, expected, 1e-9)
+}
+
+/*
+ * Check that separate Fourier transforms may be used concurrently.
+ */
+func TestConcurrentFFT(t *testing.T) {
+	n := 16384
+	numWorkers := 8
+	ft := CreateFourierTransform()
+	expected := createTestSignal(n)
+	ft.Fourier(expected, SCALING_ORTHONORMAL, MODE_INPLACE)
+	results := make(chan bool, numWorkers)
+
+	/*
+	 * Start the workers.
+	 */
+	for i := 0; i < numWorkers; i++ {
+		go transformConcurrently(n, expected, results)
+	}
+
+	/*
+	 * Collect the results of the workers.
+	 */
+	for i := 0; i < numWorkers; i++ {
+		ok := <-results
+
+		/*
+		 * Check if worker returned the expected result.
+		 */
+		if !ok {
+			t.Errorf("Concurrent FFT of size %d did not return expected result.", n)
+		}
+
+	}
+
+}
+
+/*
+ * Check that repeated transforms do not allocate memory once the coefficients
+ * are cached.
+ */
+func TestFourierAllocations(t *testing.T) {
+	ft := CreateFourierTransform()
+
+	/*
+	 * Sizes of the transforms.
+	 */
+	sizes := []int{
+		2,
+		64,
+		1024,
+		16384,
+	}
+
+	/*
+	 * Perform transforms of each size.
+	 */
+	for _, n := range sizes {
+		vec := createTestSignal(n)
+		samples := make([]float64, n)
+		spectrum := make([]complex128, n)
+
+		/*
+		 * Fill the real vector with the magnitude of the test signal.
+		 */
+		for i, elem := range vec {
+			samples[i] = cmplx.Abs(elem)
+		}
+
+		/*
+		 * Transform the complex vector in place once.
+		 */
+		fourier := func() {
+			ft.Fourier(vec, SCALING_ORTHONORMAL, MODE_INPLACE)
+		}
+
+		/*
+		 * Transform the real vector and transform it back once.
+		 */
+		realFourier := func() {
+			ft.RealFourier(samples, spectrum, SCALING_DEFAULT)
+			ft.RealInverseFourier(spectrum, samples, SCALING_DEFAULT)
+		}
+
+		allocs := testing.AllocsPerRun(100, fourier)
+
+		/*
+		 * Check if complex transform allocated memory.
+		 */
+		if allocs != 0 {
+			t.Errorf("In-place Fourier transform of size %d allocated memory. Expected %d allocations, got %f.", n, 0, allocs)
+		}
+
+		allocs = testing.AllocsPerRun(100, realFourier)
+
+		/*
+		 * Check if real transform allocated memory.
+		 */
+		if allocs != 0 {
+			t.Errorf("Real Fourier transform of size %d allocated memory. Expected %d allocations, got %f.", n, 0, allocs)
+		}
+
+	}
+
+}
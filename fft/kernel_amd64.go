@@ -0,0 +1,98 @@
+//go:build amd64
+// +build amd64
+
+package fft
+
+/*
+ * Flags reported by the CPUID instruction and the XGETBV instruction, which
+ * tell whether the vectorized kernels may be used.
+ */
+const (
+	CPUID_ECX_FMA     = 1 << 12
+	CPUID_ECX_OSXSAVE = 1 << 27
+	CPUID_ECX_AVX     = 1 << 28
+	CPUID_EBX_AVX2    = 1 << 5
+	XCR0_AVX_STATE    = 0x6
+)
+
+/*
+ * Global variables.
+ */
+var g_avx2 bool = detectAVX2() // Whether the processor and OS support AVX2 and FMA.
+
+/*
+ * Query the processor identification, implemented in assembly.
+ */
+func cpuid(leaf uint32, subleaf uint32) (uint32, uint32, uint32, uint32)
+
+/*
+ * Query the extended control register enabled by the OS, implemented in
+ * assembly.
+ */
+func xgetbv() (uint32, uint32)
+
+/*
+ * Perform the butterfly operations of a Fourier round on a pair of
+ * half-blocks, processing two complex elements at once using AVX2 and FMA
+ * instructions, implemented in assembly.
+ *
+ * Both half-blocks and the twiddle factors must be of the same length.
+ */
+func butterfliesAVX2(lower []complex128, upper []complex128, twiddles []complex128)
+
+/*
+ * Check whether the processor supports AVX2 and FMA instructions and whether
+ * the OS saves the AVX registers on context switches.
+ */
+func detectAVX2() bool {
+	maxLeaf, _, _, _ := cpuid(0, 0)
+
+	/*
+	 * The extended features are reported in leaf 7.
+	 */
+	if maxLeaf < 7 {
+		return false
+	} else {
+		_, _, features, _ := cpuid(1, 0)
+		osxsave := (features & CPUID_ECX_OSXSAVE) != 0
+		avx := (features & CPUID_ECX_AVX) != 0
+		fma := (features & CPUID_ECX_FMA) != 0
+
+		/*
+		 * The extended control register may only be queried if the OS
+		 * enabled it.
+		 */
+		if !(osxsave && avx && fma) {
+			return false
+		} else {
+			xcr0, _ := xgetbv()
+			avxState := (xcr0 & XCR0_AVX_STATE) == XCR0_AVX_STATE
+			_, extendedFeatures, _, _ := cpuid(7, 0)
+			avx2 := (extendedFeatures & CPUID_EBX_AVX2) != 0
+			return avxState && avx2
+		}
+
+	}
+
+}
+
+/*
+ * Perform the butterfly operations of a Fourier round on a pair of
+ * half-blocks.
+ *
+ * This uses the vectorized kernel if the processor supports it.
+ */
+func butterflies(lower []complex128, upper []complex128, twiddles []complex128) {
+	n := len(lower)
+
+	/*
+	 * The vectorized kernel does not check bounds, so only use it if all
+	 * slices are large enough.
+	 */
+	if g_avx2 && (len(upper) >= n) && (len(twiddles) >= n) {
+		butterfliesAVX2(lower, upper, twiddles)
+	} else {
+		butterfliesGeneric(lower, upper, twiddles)
+	}
+
+}
@@ -0,0 +1,81 @@
+//go:build amd64
+// +build amd64
+
+#include "textflag.h"
+
+// func cpuid(leaf uint32, subleaf uint32) (uint32, uint32, uint32, uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL leaf+0(FP), AX
+	MOVL subleaf+4(FP), CX
+	CPUID
+	MOVL AX, ret+8(FP)
+	MOVL BX, ret1+12(FP)
+	MOVL CX, ret2+16(FP)
+	MOVL DX, ret3+20(FP)
+	RET
+
+// func xgetbv() (uint32, uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, ret+0(FP)
+	MOVL DX, ret1+4(FP)
+	RET
+
+// func butterfliesAVX2(lower []complex128, upper []complex128, twiddles []complex128)
+//
+// For each element a of the lower half-block, b of the upper half-block and
+// twiddle factor w, calculate p = w * b, then store a + p in the lower and
+// a - p in the upper half-block.
+//
+// The complex product is calculated as (wr * br - wi * bi, wr * bi + wi * br)
+// by multiplying the swapped b with the imaginary part of w, then
+// alternately subtracting and adding this from the product of b with the
+// real part of w.
+TEXT ·butterfliesAVX2(SB), NOSPLIT, $0-72
+	MOVQ lower_base+0(FP), DI
+	MOVQ lower_len+8(FP), CX
+	MOVQ upper_base+24(FP), SI
+	MOVQ twiddles_base+48(FP), DX
+	MOVQ CX, BX
+	SHRQ $1, BX
+	JZ   single
+
+pairs:
+	VMOVUPD        (SI), Y0
+	VMOVUPD        (DX), Y1
+	VMOVDDUP       Y1, Y2
+	VPERMILPD      $0x0f, Y1, Y3
+	VPERMILPD      $0x05, Y0, Y4
+	VMULPD         Y4, Y3, Y3
+	VFMADDSUB231PD Y0, Y2, Y3
+	VMOVUPD        (DI), Y5
+	VADDPD         Y3, Y5, Y6
+	VSUBPD         Y3, Y5, Y7
+	VMOVUPD        Y6, (DI)
+	VMOVUPD        Y7, (SI)
+	ADDQ           $32, DI
+	ADDQ           $32, SI
+	ADDQ           $32, DX
+	DECQ           BX
+	JNZ            pairs
+
+single:
+	ANDQ           $1, CX
+	JZ             done
+	VMOVUPD        (SI), X0
+	VMOVUPD        (DX), X1
+	VMOVDDUP       X1, X2
+	VPERMILPD      $0x03, X1, X3
+	VPERMILPD      $0x01, X0, X4
+	VMULPD         X4, X3, X3
+	VFMADDSUB231PD X0, X2, X3
+	VMOVUPD        (DI), X5
+	VADDPD         X3, X5, X6
+	VSUBPD         X3, X5, X7
+	VMOVUPD        X6, (DI)
+	VMOVUPD        X7, (SI)
+
+done:
+	VZEROUPPER
+	RET
@@ -0,0 +1,16 @@
+//go:build !amd64
+// +build !amd64
+
+package fft
+
+/*
+ * Perform the butterfly operations of a Fourier round on a pair of
+ * half-blocks.
+ *
+ * There are no vectorized kernels for this architecture, so this always uses
+ * the portable implementation. This includes arm64, which has no NEON kernel
+ * yet.
+ */
+func butterflies(lower []complex128, upper []complex128, twiddles []complex128) {
+	butterfliesGeneric(lower, upper, twiddles)
+}
@@ -10,13 +10,24 @@ import (
 	"math/cmplx"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 /*
  * Global constants.
  */
 const (
-	CHANNEL_COUNT = 1
+	CHANNEL_COUNT           = 1
+	PARTITION_SIZE_MINIMUM  = 64
+	CAPTURE_FADE_OUT        = 0.1
+	CAPTURE_ONSET_THRESHOLD = 0.01
+	CAPTURE_PEAK            = 0.9
+	CAPTURE_REGULARIZATION  = 1e-6
+	CATEGORY_SEPARATOR      = ": "
+	DESCRIPTOR_FILE_MODE    = 0644
+	MINIMUM_PHASE_FLOOR     = 1e-10
+	MINIMUM_PHASE_PADDING   = 4
 )
 
 /*
@@ -34,11 +45,38 @@ var g_sampleRates = []uint32{
 
 /*
  * Data structure describing an FIR filter.
+ *
+ * The impulse response may be truncated to a maximum length and faded out
+ * towards its end (both in milliseconds, zero meaning no truncation or fade)
+ * and converted to minimum phase when it is loaded.
  */
 type filterDescriptorStruct struct {
 	Name         string
 	Path         string
 	Compensation int32
+	Category     string
+	Author       string
+	MicPosition  string
+	License      string
+	MaxLength    uint32
+	FadeOut      uint32
+	MinimumPhase bool
+}
+
+/*
+ * Data structure describing an impulse response, e. g. which speaker cabinet
+ * it was captured from and by whom, along with the options it is loaded with.
+ */
+type Info struct {
+	Name         string
+	Category     string
+	Author       string
+	MicPosition  string
+	License      string
+	Compensation int32
+	MaxLength    uint32
+	FadeOut      uint32
+	MinimumPhase bool
 }
 
 /*
@@ -46,6 +84,7 @@ type filterDescriptorStruct struct {
  */
 type impulseResponseStruct struct {
 	name             string
+	info             Info
 	sampleRate       uint32
 	gainCompensation float64
 	data             []float64
@@ -55,7 +94,9 @@ type impulseResponseStruct struct {
  * A collection of impulse responses.
  */
 type impulseResponsesStruct struct {
-	responses []impulseResponseStruct
+	descriptorFilePath string
+	mutex              sync.RWMutex
+	responses          []impulseResponseStruct
 }
 
 /*
@@ -63,7 +104,9 @@ type impulseResponsesStruct struct {
  */
 type ImpulseResponses interface {
 	CreateFilter(name string, sampleRate uint32) Filter
+	Infos() []Info
 	Names() []string
+	Reload() error
 }
 
 /*
@@ -72,13 +115,17 @@ type ImpulseResponses interface {
 type filterStruct struct {
 	impulseResponse     impulseResponseStruct
 	fourierTransform    fft.FourierTransform
-	filterComplex       []complex128
+	partitionSize       int
+	partitions          [][]complex128
+	spectra             [][]complex128
+	spectrumPosition    int
 	filteredComplex     []complex128
 	inputBuffer         []float64
 	inputBufferComplex  []complex128
 	outputBuffer        []float64
 	outputBufferComplex []complex128
 	tailBuffer          []float64
+	clipping            bool
 }
 
 /*
@@ -87,36 +134,27 @@ type filterStruct struct {
 type Filter interface {
 	Add(other Filter) (Filter, error)
 	Coefficients() []float64
+	Delay() uint32
 	Multiply(scalar float64) Filter
 	Normalize() Filter
+	Prepare(bufferSize int)
 	Process(inputBuffer []float64, outputBuffer []float64) error
 	Reduce(order uint32) Filter
 	SampleRate() uint32
+	SetClipping(enabled bool)
 }
 
 /*
- * Calculate the complex hadamard product of two vectors.
+ * Calculate the complex hadamard product of two vectors and add it to the
+ * result.
  */
-func hadamardComplex(result []complex128, a []complex128, b []complex128) error {
-	L := len(result)
-	N := len(a)
-	M := len(b)
+func multiplyAccumulateComplex(result []complex128, a []complex128, b []complex128) {
 
 	/*
-	 * Check if buffers are the same size.
+	 * Multiply the contents of the buffers and accumulate.
 	 */
-	if (N != M) || (L != N) {
-		return fmt.Errorf("%s", "Failed to calculate hadamard product: All buffers must be the same size.")
-	} else {
-
-		/*
-		 * Multiply the contents of the buffer
-		 */
-		for i, _ := range result {
-			result[i] = a[i] * b[i]
-		}
-
-		return nil
+	for i, elem := range a {
+		result[i] += elem * b[i]
 	}
 
 }
@@ -222,7 +260,8 @@ func (this *filterStruct) Add(other Filter) (Filter, error) {
 			}
 
 			ft := fft.CreateFourierTransform()
-			bufFilterC := make([]complex128, 0)
+			bufPartitions := make([][]complex128, 0)
+			bufSpectra := make([][]complex128, 0)
 			bufFilteredC := make([]complex128, 0)
 			bufInput := make([]float64, 0)
 			bufInputC := make([]complex128, 0)
@@ -236,13 +275,15 @@ func (this *filterStruct) Add(other Filter) (Filter, error) {
 			fltFilter := filterStruct{
 				impulseResponse:     irResult,
 				fourierTransform:    ft,
-				filterComplex:       bufFilterC,
+				partitions:          bufPartitions,
+				spectra:             bufSpectra,
 				filteredComplex:     bufFilteredC,
 				inputBuffer:         bufInput,
 				inputBufferComplex:  bufInputC,
 				outputBuffer:        bufOutput,
 				outputBufferComplex: bufOutputC,
 				tailBuffer:          bufTail,
+				clipping:            this.clipping,
 			}
 
 			return &fltFilter, nil
@@ -264,6 +305,37 @@ func (this *filterStruct) Coefficients() []float64 {
 	return coeffCopy
 }
 
+/*
+ * Returns the delay (in samples) the filter applies to the direct sound,
+ * which is the position of the coefficient with the largest magnitude.
+ *
+ * For a linear-phase filter, this equals its group delay.
+ */
+func (this *filterStruct) Delay() uint32 {
+	ir := this.impulseResponse
+	coeffs := ir.data
+	peak := 0.0
+	delay := uint32(0)
+
+	/*
+	 * Find the coefficient with the largest magnitude.
+	 */
+	for i, coeff := range coeffs {
+		abs := math.Abs(coeff)
+
+		/*
+		 * If we found a larger magnitude, keep its position.
+		 */
+		if abs > peak {
+			peak = abs
+			delay = uint32(i)
+		}
+
+	}
+
+	return delay
+}
+
 /*
  * Multiply the filter with a scalar factor.
  */
@@ -296,7 +368,8 @@ func (this *filterStruct) Multiply(scalar float64) Filter {
 	}
 
 	ft := fft.CreateFourierTransform()
-	bufFilterC := make([]complex128, 0)
+	bufPartitions := make([][]complex128, 0)
+	bufSpectra := make([][]complex128, 0)
 	bufFilteredC := make([]complex128, 0)
 	bufInput := make([]float64, 0)
 	bufInputC := make([]complex128, 0)
@@ -310,13 +383,15 @@ func (this *filterStruct) Multiply(scalar float64) Filter {
 	fltFilter := filterStruct{
 		impulseResponse:     irResult,
 		fourierTransform:    ft,
-		filterComplex:       bufFilterC,
+		partitions:          bufPartitions,
+		spectra:             bufSpectra,
 		filteredComplex:     bufFilteredC,
 		inputBuffer:         bufInput,
 		inputBufferComplex:  bufInputC,
 		outputBuffer:        bufOutput,
 		outputBufferComplex: bufOutputC,
 		tailBuffer:          bufTail,
+		clipping:            this.clipping,
 	}
 
 	return &fltFilter
@@ -335,9 +410,124 @@ func (this *filterStruct) Normalize() Filter {
 	return fltFilter
 }
 
+/*
+ * Choose the size of the partitions the impulse response is split into, given
+ * the number of samples processed at once and the length of the impulse
+ * response.
+ *
+ * The partitions may only be smaller than the impulse response if each
+ * buffer can be split into whole partitions, i. e. if the partition size
+ * divides the buffer size. Otherwise, the entire impulse response is put into
+ * a single partition.
+ */
+func choosePartitionSize(bufferSize int, responseSize int) int {
+	responseSize64 := uint64(responseSize)
+	blockSize64, _ := fft.NextPowerOfTwo(responseSize64)
+	blockSize := int(blockSize64)
+	divisor := bufferSize & -bufferSize // The largest power of two dividing the buffer size.
+
+	/*
+	 * Check if the impulse response fits into a single partition or can be
+	 * split up.
+	 */
+	if divisor >= blockSize {
+		return blockSize
+	} else if divisor >= PARTITION_SIZE_MINIMUM {
+		return divisor
+	} else {
+		return blockSize
+	}
+
+}
+
+/*
+ * Split the impulse response into partitions of the given size, calculate
+ * their spectra and allocate the buffers needed for processing.
+ */
+func (this *filterStruct) preparePartitions(partitionSize int) {
+	ir := this.impulseResponse
+	coefficients := ir.data
+	L := len(coefficients)
+	numPartitions := (L + partitionSize - 1) / partitionSize
+	fftSize := partitionSize << 1
+	ft := this.fourierTransform
+	coefficientsPadded := make([]float64, fftSize)
+	partitions := make([][]complex128, numPartitions)
+	spectra := make([][]complex128, numPartitions)
+
+	/*
+	 * Pre-calculate the FFT of each partition of the filter.
+	 */
+	for i := range partitions {
+		lBound := i * partitionSize
+		uBound := lBound + partitionSize
+
+		/*
+		 * Prevent exceeding upper bound.
+		 */
+		if uBound > L {
+			uBound = L
+		}
+
+		numCoefficients := uBound - lBound
+		copy(coefficientsPadded[0:numCoefficients], coefficients[lBound:uBound])
+		fft.ZeroFloat(coefficientsPadded[numCoefficients:])
+		partition := make([]complex128, fftSize)
+		ft.RealFourier(coefficientsPadded, partition, fft.SCALING_DEFAULT)
+		partitions[i] = partition
+		spectra[i] = make([]complex128, fftSize)
+	}
+
+	this.partitionSize = partitionSize
+	this.partitions = partitions
+	this.spectra = spectra
+	this.spectrumPosition = 0
+	this.filteredComplex = make([]complex128, fftSize)
+	this.inputBuffer = make([]float64, fftSize)
+	this.outputBuffer = make([]float64, fftSize)
+	this.tailBuffer = make([]float64, fftSize)
+}
+
+/*
+ * Splits the impulse response into partitions and calculates their spectra
+ * for buffers of the given size, so that the first call to Process with
+ * buffers of this size does not have to.
+ *
+ * Since this takes time for long impulse responses, call it before the filter
+ * is handed to the audio thread.
+ */
+func (this *filterStruct) Prepare(bufferSize int) {
+	ir := this.impulseResponse
+	coefficients := ir.data
+	L := len(coefficients)
+
+	/*
+	 * Only prepare non-empty filters for non-empty buffers.
+	 */
+	if (L > 0) && (bufferSize > 0) {
+		partitionSize := choosePartitionSize(bufferSize, L)
+
+		/*
+		 * Check if the partition size changed.
+		 */
+		if partitionSize != this.partitionSize {
+			this.preparePartitions(partitionSize)
+		}
+
+	}
+
+}
+
 /*
  * Reads samples from the input buffer, passes them through the filter and writes
  * samples to the output buffer.
+ *
+ * The output may exceed full scale, unless clipping is enabled.
+ *
+ * Long impulse responses are split into partitions of uniform size, which are
+ * convolved with the current and previous input blocks in the frequency
+ * domain. This keeps the size of the Fourier transforms bounded by the buffer
+ * size and does not add any latency.
  */
 func (this *filterStruct) Process(inputBuffer []float64, outputBuffer []float64) error {
 	N := len(inputBuffer)
@@ -361,148 +551,118 @@ func (this *filterStruct) Process(inputBuffer []float64, outputBuffer []float64)
 			L := len(coefficients)
 
 			/*
-			 * Check if filter is empty.
+			 * Check if filter or buffer is empty.
 			 */
 			if L == 0 {
 				fft.ZeroFloat(outputBuffer)
-			} else {
-				ft := this.fourierTransform
-				N64 := uint64(N)
-				L64 := uint64(L)
-				Npower, _ := fft.NextPowerOfTwo(N64)
-				blockSize, _ := fft.NextPowerOfTwo(L64)
-				numBlocks := Npower / blockSize
-				overflow := Npower % blockSize
+			} else if N > 0 {
+				partitionSize := choosePartitionSize(N, L)
 
 				/*
-				 * If there is overflow, add another block.
+				 * Split the impulse response again if the partition size
+				 * changed.
 				 */
-				if overflow != 0 {
-					numBlocks++
+				if partitionSize != this.partitionSize {
+					this.preparePartitions(partitionSize)
 				}
 
+				ft := this.fourierTransform
+				partitions := this.partitions
+				spectra := this.spectra
+				numPartitions := len(partitions)
+				filteredComplex := this.filteredComplex
+				filterInputBuffer := this.inputBuffer
+				filterOutputBuffer := this.outputBuffer
+				tailBuffer := this.tailBuffer
+				clipping := this.clipping
+				fftSize := partitionSize << 1
+
 				/*
-				 * Process each block
+				 * Process each block.
 				 */
-				for i := uint64(0); i < numBlocks; i++ {
-					fftSize64 := blockSize << 1
-					fftSize := int(fftSize64)
-					filterComplex := this.filterComplex
-
-					/*
-					 * Pre-calculate the FFT of the filter.
-					 */
-					if len(filterComplex) != fftSize {
-						coefficientsPadded := make([]float64, fftSize)
-						copy(coefficientsPadded[0:L], coefficients)
-						filterComplex = make([]complex128, fftSize)
-						ft.RealFourier(coefficientsPadded, filterComplex, fft.SCALING_DEFAULT)
-						this.filterComplex = filterComplex
-					}
-
-					filteredComplex := this.filteredComplex
-
-					/*
-					 * Check if complex-valued filtered (FFT) buffer is of correct size.
-					 */
-					if len(filteredComplex) != fftSize {
-						filteredComplex = make([]complex128, fftSize)
-						this.filteredComplex = filteredComplex
-					}
-
-					filterInputBuffer := this.inputBuffer
+				for lBound := 0; lBound < N; lBound += partitionSize {
+					uBound := lBound + partitionSize
 
 					/*
-					 * Check if real-valued input buffer is of the correct size.
+					 * Prevent exceeding upper bound.
 					 */
-					if len(filterInputBuffer) != fftSize {
-						filterInputBuffer = make([]float64, fftSize)
-						this.inputBuffer = filterInputBuffer
+					if uBound > N {
+						uBound = N
 					}
 
-					filterOutputBuffer := this.outputBuffer
+					currentInputBuffer := inputBuffer[lBound:uBound]
+					currentOutputBuffer := outputBuffer[lBound:uBound]
+					numSamples := uBound - lBound
+					copy(filterInputBuffer[0:numSamples], currentInputBuffer)
+					fft.ZeroFloat(filterInputBuffer[numSamples:])
+					position := this.spectrumPosition
+					spectrum := spectra[position]
+					ft.RealFourier(filterInputBuffer, spectrum, fft.SCALING_DEFAULT)
+					fft.ZeroComplex(filteredComplex)
 
 					/*
-					 * Check if real-valued output buffer is of the correct size.
+					 * Convolve each partition of the filter with the input
+					 * block delayed by the same number of blocks.
 					 */
-					if len(filterOutputBuffer) != fftSize {
-						filterOutputBuffer = make([]float64, fftSize)
-						this.outputBuffer = filterOutputBuffer
-					}
+					for i, partition := range partitions {
+						idx := position - i
 
-					tailBuffer := this.tailBuffer
+						/*
+						 * Wrap around the frequency-domain delay line.
+						 */
+						if idx < 0 {
+							idx += numPartitions
+						}
 
-					/*
-					 * Check if real-valued tail buffer is of the correct size.
-					 */
-					if len(tailBuffer) != fftSize {
-						tailBuffer = make([]float64, fftSize)
-						this.tailBuffer = tailBuffer
+						multiplyAccumulateComplex(filteredComplex, spectra[idx], partition)
 					}
 
-					lBound := i * blockSize
-					uBound := lBound + blockSize
+					position++
 
 					/*
-					 * Prevent exceeding upper bound.
+					 * Wrap around the frequency-domain delay line.
 					 */
-					if uBound > N64 {
-						uBound = N64
+					if position >= numPartitions {
+						position = 0
 					}
 
-					currentInputBuffer := inputBuffer[lBound:uBound]
-					currentOutputBuffer := outputBuffer[lBound:uBound]
-					numSamples := uBound - lBound
-					copy(filterInputBuffer[0:numSamples], currentInputBuffer)
-					fft.ZeroFloat(filterInputBuffer[numSamples:])
-					ft.RealFourier(filterInputBuffer, filteredComplex, fft.SCALING_DEFAULT)
-					err := hadamardComplex(filteredComplex, filteredComplex, filterComplex)
+					this.spectrumPosition = position
+					ft.RealInverseFourier(filteredComplex, filterOutputBuffer, fft.SCALING_DEFAULT)
 
 					/*
-					 * Check if hadamard product was calculated successfully.
+					 * Calculate the total output by overlapping with the tail of the
+					 * previous calculation.
 					 */
-					if err != nil {
-						return err
-					} else {
-						ft.RealInverseFourier(filteredComplex, filterOutputBuffer, fft.SCALING_DEFAULT)
+					for j, elem := range filterOutputBuffer {
+						tailElem := tailBuffer[j]
+						pre := elem + tailElem
 
 						/*
-						 * Calculate the total output by overlapping with the tail of the
-						 * previous calculation.
+						 * Write a portion to the current output buffer
+						 * and update tail buffer.
 						 */
-						for j, elem := range filterOutputBuffer {
-							tailElem := tailBuffer[j]
-							pre := elem + tailElem
-							j64 := uint64(j)
+						if j < numSamples {
 
 							/*
-							 * Write a portion to the current output buffer
-							 * and update tail buffer.
+							 * Limit the output to full scale, if requested.
 							 */
-							if j64 < numSamples {
-
-								/*
-								 * Ensure that the output is in range.
-								 */
-								if pre > 1.0 {
-									currentOutputBuffer[j] = 1.0
-								} else if pre < -1.0 {
-									currentOutputBuffer[j] = -1.0
-								} else {
-									currentOutputBuffer[j] = pre
-								}
-
+							if clipping && (pre > 1.0) {
+								currentOutputBuffer[j] = 1.0
+							} else if clipping && (pre < -1.0) {
+								currentOutputBuffer[j] = -1.0
 							} else {
-								idx := j64 - numSamples
-								tailBuffer[idx] = pre
+								currentOutputBuffer[j] = pre
 							}
 
+						} else {
+							idx := j - numSamples
+							tailBuffer[idx] = pre
 						}
 
-						tailSize64 := fftSize64 - numSamples
-						fft.ZeroFloat(tailBuffer[tailSize64:])
 					}
 
+					tailSize := fftSize - numSamples
+					fft.ZeroFloat(tailBuffer[tailSize:])
 				}
 
 			}
@@ -560,7 +720,9 @@ func (this *filterStruct) Reduce(order uint32) Filter {
 		targetResponse := make([]float64, nFftTarget)
 		ft.RealInverseFourier(frNew, targetResponse, fft.SCALING_DEFAULT)
 		coeffsNew := targetResponse[:order]
-		nameNew := ir.name + " (" + string(order) + ")"
+		orderLong := uint64(order)
+		orderString := strconv.FormatUint(orderLong, 10)
+		nameNew := ir.name + " (" + orderString + ")"
 		rate := ir.sampleRate
 		compensation := ir.gainCompensation
 
@@ -575,7 +737,8 @@ func (this *filterStruct) Reduce(order uint32) Filter {
 		}
 
 		ftNewFilter := fft.CreateFourierTransform()
-		bufFilterC := make([]complex128, 0)
+		bufPartitions := make([][]complex128, 0)
+		bufSpectra := make([][]complex128, 0)
 		bufFilteredC := make([]complex128, 0)
 		bufInput := make([]float64, 0)
 		bufInputC := make([]complex128, 0)
@@ -589,13 +752,15 @@ func (this *filterStruct) Reduce(order uint32) Filter {
 		fltFilter := filterStruct{
 			fourierTransform:    ftNewFilter,
 			impulseResponse:     irNew,
-			filterComplex:       bufFilterC,
+			partitions:          bufPartitions,
+			spectra:             bufSpectra,
 			filteredComplex:     bufFilteredC,
 			inputBuffer:         bufInput,
 			inputBufferComplex:  bufInputC,
 			outputBuffer:        bufOutput,
 			outputBufferComplex: bufOutputC,
 			tailBuffer:          bufTail,
+			clipping:            this.clipping,
 		}
 
 		return &fltFilter
@@ -612,23 +777,39 @@ func (this *filterStruct) SampleRate() uint32 {
 	return sampleRate
 }
 
+/*
+ * Sets whether the output of this filter is limited to full scale.
+ *
+ * Clipping is disabled by default, so that signals exceeding full scale
+ * within a signal chain keep their headroom and are only limited at the final
+ * output. Call this before the filter is handed to the audio thread. Filters
+ * derived from this one inherit the setting.
+ */
+func (this *filterStruct) SetClipping(enabled bool) {
+	this.clipping = enabled
+}
+
 /*
  * Retrieves an impulse response filter from a collection of impulse responses and
  * creates an FIR filter from it.
  */
 func (this *impulseResponsesStruct) CreateFilter(name string, sampleRate uint32) Filter {
+	this.mutex.RLock()
+	responses := this.responses
+	this.mutex.RUnlock()
 
 	/*
 	 * Iterate over the filter collection.
 	 */
-	for _, ir := range this.responses {
+	for _, ir := range responses {
 
 		/*
 		 * Check if both name and sample rate match.
 		 */
 		if (ir.name == name) && (ir.sampleRate == sampleRate) {
 			ft := fft.CreateFourierTransform()
-			bufFilterC := make([]complex128, 0)
+			bufPartitions := make([][]complex128, 0)
+			bufSpectra := make([][]complex128, 0)
 			bufFilteredC := make([]complex128, 0)
 			bufInput := make([]float64, 0)
 			bufInputC := make([]complex128, 0)
@@ -642,7 +823,8 @@ func (this *impulseResponsesStruct) CreateFilter(name string, sampleRate uint32)
 			fltFilter := filterStruct{
 				impulseResponse:     ir,
 				fourierTransform:    ft,
-				filterComplex:       bufFilterC,
+				partitions:          bufPartitions,
+				spectra:             bufSpectra,
 				filteredComplex:     bufFilteredC,
 				inputBuffer:         bufInput,
 				inputBufferComplex:  bufInputC,
@@ -664,11 +846,14 @@ func (this *impulseResponsesStruct) CreateFilter(name string, sampleRate uint32)
  */
 func (this *impulseResponsesStruct) Names() []string {
 	names := make([]string, 0)
+	this.mutex.RLock()
+	responses := this.responses
+	this.mutex.RUnlock()
 
 	/*
 	 * Iterate over the filter collection.
 	 */
-	for _, ir := range this.responses {
+	for _, ir := range responses {
 		name := ir.name
 		contained := false
 
@@ -699,9 +884,95 @@ func (this *impulseResponsesStruct) Names() []string {
 }
 
 /*
- * Imports a set of impulse responses using a descriptor file.
+ * Retrieves the descriptions of all impulse responses, in the order of the
+ * descriptor file.
  */
-func Import(descriptorFilePath string) (ImpulseResponses, error) {
+func (this *impulseResponsesStruct) Infos() []Info {
+	infos := make([]Info, 0)
+	this.mutex.RLock()
+	responses := this.responses
+	this.mutex.RUnlock()
+
+	/*
+	 * Iterate over the filter collection.
+	 */
+	for _, ir := range responses {
+		name := ir.name
+		contained := false
+
+		/*
+		 * Iterate over the descriptions to check whether it's still there.
+		 */
+		for _, currentInfo := range infos {
+
+			/*
+			 * If names match, we already know a version of this impulse response.
+			 */
+			if currentInfo.Name == name {
+				contained = true
+			}
+
+		}
+
+		/*
+		 * If this impulse response is not already known, add it to the list.
+		 */
+		if !contained {
+			infos = append(infos, ir.info)
+		}
+
+	}
+
+	return infos
+}
+
+/*
+ * Creates the description of an impulse response from its descriptor.
+ *
+ * If the descriptor does not specify a category, it is taken from the name,
+ * which is commonly prefixed by the category, like in "Guitar: Custom BP".
+ */
+func createInfo(descriptor filterDescriptorStruct) Info {
+	name := descriptor.Name
+	category := descriptor.Category
+
+	/*
+	 * Derive the category from the name, if necessary.
+	 */
+	if category == "" {
+		idx := strings.Index(name, CATEGORY_SEPARATOR)
+
+		/*
+		 * Check if name is prefixed by a category.
+		 */
+		if idx > 0 {
+			category = name[0:idx]
+		}
+
+	}
+
+	/*
+	 * Create impulse response description.
+	 */
+	info := Info{
+		Name:         name,
+		Category:     category,
+		Author:       descriptor.Author,
+		MicPosition:  descriptor.MicPosition,
+		License:      descriptor.License,
+		Compensation: descriptor.Compensation,
+		MaxLength:    descriptor.MaxLength,
+		FadeOut:      descriptor.FadeOut,
+		MinimumPhase: descriptor.MinimumPhase,
+	}
+
+	return info
+}
+
+/*
+ * Reads the descriptors from a descriptor file.
+ */
+func readDescriptors(descriptorFilePath string) ([]filterDescriptorStruct, error) {
 	content, err := os.ReadFile(descriptorFilePath)
 
 	/*
@@ -719,84 +990,425 @@ func Import(descriptorFilePath string) (ImpulseResponses, error) {
 		if err != nil {
 			return nil, fmt.Errorf("Failed to decode descriptor file: '%s'", descriptorFilePath)
 		} else {
-			impulseResponseList := []impulseResponseStruct{}
+			return descriptors, nil
+		}
+
+	}
+
+}
+
+/*
+ * Reads the FIR filter coefficients of an impulse response from a wave file,
+ * along with the sample rate they were recorded at.
+ */
+func readCoefficients(wavePath string) ([]float64, uint32, error) {
+	waveBuffer, err := os.ReadFile(wavePath)
+
+	/*
+	 * Check if file was read successfully.
+	 */
+	if err != nil {
+		return nil, 0, fmt.Errorf("Could not read file '%s'.", wavePath)
+	} else {
+		waveFile, err := wave.FromBuffer(waveBuffer)
+
+		/*
+		 * Check if file was parsed successfully.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, 0, fmt.Errorf("File '%s': %s", wavePath, msg)
+		} else {
+			channelCount := waveFile.ChannelCount()
+
+			/*
+			 * An FIR filter should have exactly one channel.
+			 */
+			if channelCount != CHANNEL_COUNT {
+				return nil, 0, fmt.Errorf("File '%s' contains %d channels, expected: %d", wavePath, channelCount, CHANNEL_COUNT)
+			} else {
+				sampleRate := waveFile.SampleRate()
+				channel, _ := waveFile.Channel(0)
+				content := channel.Floats()
+				return content, sampleRate, nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Converts an impulse response into the minimum-phase impulse response with
+ * the same magnitude response.
+ *
+ * This concentrates the energy of the impulse response at its start, so that
+ * it can be truncated with less loss and introduces less delay. The
+ * conversion uses the real cepstrum of the zero-padded impulse response,
+ * where the padding keeps aliasing of the cepstrum low.
+ */
+func minimumPhase(coefficients []float64) ([]float64, error) {
+	n := len(coefficients)
+	paddedLength := uint64(MINIMUM_PHASE_PADDING * n)
+	size64, _ := fft.NextPowerOfTwo(paddedLength)
+	size := int(size64)
+	padded := make([]float64, size)
+	copy(padded, coefficients)
+	spectrum := make([]complex128, size)
+	ft := fft.CreateFourierTransform()
+	err := ft.RealFourier(padded, spectrum, fft.SCALING_DEFAULT)
+
+	/*
+	 * Check if transform was successful.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, fmt.Errorf("Failed to transform impulse response: %s", msg)
+	} else {
+		peak := 0.0
+
+		/*
+		 * Find the largest magnitude in the spectrum.
+		 */
+		for _, elem := range spectrum {
+			magnitude := cmplx.Abs(elem)
+			peak = math.Max(peak, magnitude)
+		}
+
+		floor := MINIMUM_PHASE_FLOOR * peak
+
+		/*
+		 * Check if there is any response at all.
+		 */
+		if peak == 0.0 {
+			result := make([]float64, n)
+			return result, nil
+		} else {
+
+			/*
+			 * Replace the spectrum by its log-magnitude, keeping
+			 * frequencies which are (almost) absent above a floor.
+			 */
+			for i, elem := range spectrum {
+				magnitude := cmplx.Abs(elem)
+				magnitude = math.Max(magnitude, floor)
+				logMagnitude := math.Log(magnitude)
+				spectrum[i] = complex(logMagnitude, 0.0)
+			}
+
+			cepstrum := make([]float64, size)
+			err = ft.RealInverseFourier(spectrum, cepstrum, fft.SCALING_DEFAULT)
 
 			/*
-			 * Iterate over all filter descriptors and load the corresponding
-			 * FIR filter coefficients.
+			 * Check if inverse transform was successful.
 			 */
-			for _, descriptor := range descriptors {
-				filterName := descriptor.Name
-				wavePath := descriptor.Path
-				dc := descriptor.Compensation
-				dcFloat := float64(dc)
-				compensation := 0.05 * dcFloat
-				fac := math.Pow(10.0, compensation)
-				waveBuffer, err := os.ReadFile(wavePath)
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to calculate cepstrum: %s", msg)
+			} else {
+				half := size / 2
 
 				/*
-				 * Check if file was read successfully.
+				 * Fold the anti-causal part of the cepstrum onto its
+				 * causal part.
+				 */
+				for i := 1; i < half; i++ {
+					cepstrum[i] *= 2.0
+					cepstrum[size-i] = 0.0
+				}
+
+				err = ft.RealFourier(cepstrum, spectrum, fft.SCALING_DEFAULT)
+
+				/*
+				 * Check if transform was successful.
 				 */
 				if err != nil {
-					fmt.Printf("WARNING: During filter import: Could not read file '%s'. - Skipping.\n", wavePath)
+					msg := err.Error()
+					return nil, fmt.Errorf("Failed to transform cepstrum: %s", msg)
 				} else {
-					waveFile, err := wave.FromBuffer(waveBuffer)
 
 					/*
-					 * Check if file was parsed successfully.
+					 * Exponentiate the spectrum to obtain the
+					 * minimum-phase spectrum.
+					 */
+					for i, elem := range spectrum {
+						spectrum[i] = cmplx.Exp(elem)
+					}
+
+					result := make([]float64, size)
+					err = ft.RealInverseFourier(spectrum, result, fft.SCALING_DEFAULT)
+
+					/*
+					 * Check if inverse transform was successful.
 					 */
 					if err != nil {
-						fmt.Printf("WARNING: During filter import (file '%s'): %s\n", wavePath, err.Error())
+						msg := err.Error()
+						return nil, fmt.Errorf("Failed to calculate minimum-phase response: %s", msg)
 					} else {
-						channelCount := waveFile.ChannelCount()
+						return result[0:n], nil
+					}
 
-						/*
-						 * An FIR filter should have exactly one channel.
-						 */
-						if channelCount != CHANNEL_COUNT {
-							fmt.Printf("WARNING: During filter import: File '%s' contains %d channels, expected: %d - Skipping.\n", wavePath, channelCount, CHANNEL_COUNT)
-						} else {
-							sampleRate := waveFile.SampleRate()
-							channel, _ := waveFile.Channel(0)
-							content := channel.Floats()
+				}
 
-							/*
-							 * Iterate over the supported sample rates.
-							 */
-							for _, targetSampleRate := range g_sampleRates {
-								coefficients := resample.Time(content, sampleRate, targetSampleRate)
-
-								/*
-								 * Create impulse response structure.
-								 */
-								ir := impulseResponseStruct{
-									name:             filterName,
-									gainCompensation: fac,
-									sampleRate:       targetSampleRate,
-									data:             coefficients,
-								}
-
-								impulseResponseList = append(impulseResponseList, ir)
-							}
+			}
 
-						}
+		}
+
+	}
+
+}
+
+/*
+ * Applies the options of a descriptor to the coefficients of an impulse
+ * response recorded at a certain sample rate.
+ *
+ * The impulse response is converted to minimum phase first, so that as
+ * little as possible is lost when it is truncated. It is then truncated to
+ * the maximum length and finally faded out with half a cosine.
+ */
+func shapeCoefficients(coefficients []float64, sampleRate uint32, descriptor filterDescriptorStruct) ([]float64, error) {
+	result := coefficients
+	err := error(nil)
+
+	/*
+	 * Convert the impulse response to minimum phase if requested.
+	 */
+	if descriptor.MinimumPhase {
+		result, err = minimumPhase(coefficients)
+	}
+
+	/*
+	 * Check if conversion was successful.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		sampleRate64 := uint64(sampleRate)
+		maxLength64 := uint64(descriptor.MaxLength)
+		maxLength := (maxLength64 * sampleRate64) / 1000
+		length := len(result)
+		length64 := uint64(length)
+
+		/*
+		 * Truncate the impulse response if it exceeds the maximum
+		 * length.
+		 */
+		if (maxLength > 0) && (length64 > maxLength) {
+			length = int(maxLength)
+		}
+
+		shaped := make([]float64, length)
+		copy(shaped, result)
+		fadeOut64 := uint64(descriptor.FadeOut)
+		fadeLength64 := (fadeOut64 * sampleRate64) / 1000
+		fadeLength := length
+
+		/*
+		 * The fade-out cannot be longer than the impulse response.
+		 */
+		if fadeLength64 < uint64(length) {
+			fadeLength = int(fadeLength64)
+		}
+
+		fadeStart := length - fadeLength
+		fadeLengthFloat := float64(fadeLength)
+
+		/*
+		 * Fade out the tail with half a cosine.
+		 */
+		for i := fadeStart; i < length; i++ {
+			position := i - fadeStart
+			positionFloat := float64(position)
+			arg := (math.Pi * positionFloat) / fadeLengthFloat
+			fac := 0.5 + (0.5 * math.Cos(arg))
+			shaped[i] *= fac
+		}
+
+		return shaped, nil
+	}
+
+}
+
+/*
+ * Loads a set of impulse responses using a descriptor file.
+ */
+func loadResponses(descriptorFilePath string) ([]impulseResponseStruct, error) {
+	descriptors, err := readDescriptors(descriptorFilePath)
+
+	/*
+	 * Check if descriptors could be read.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		impulseResponseList := []impulseResponseStruct{}
+
+		/*
+		 * Iterate over all filter descriptors and load the corresponding
+		 * FIR filter coefficients.
+		 */
+		for _, descriptor := range descriptors {
+			filterName := descriptor.Name
+			info := createInfo(descriptor)
+			wavePath := descriptor.Path
+			dc := descriptor.Compensation
+			dcFloat := float64(dc)
+			compensation := 0.05 * dcFloat
+			fac := math.Pow(10.0, compensation)
+			content, sampleRate, err := readCoefficients(wavePath)
+
+			/*
+			 * Apply the options of the descriptor if the
+			 * coefficients were read successfully.
+			 */
+			if err == nil {
+				content, err = shapeCoefficients(content, sampleRate, descriptor)
+			}
+
+			/*
+			 * Check if coefficients were read and shaped successfully.
+			 */
+			if err != nil {
+				msg := err.Error()
+				fmt.Printf("WARNING: During filter import: %s - Skipping.\n", msg)
+			} else {
+
+				/*
+				 * Iterate over the supported sample rates.
+				 */
+				for _, targetSampleRate := range g_sampleRates {
+					coefficients := resample.Time(content, sampleRate, targetSampleRate)
 
+					/*
+					 * Create impulse response structure.
+					 */
+					ir := impulseResponseStruct{
+						name:             filterName,
+						info:             info,
+						gainCompensation: fac,
+						sampleRate:       targetSampleRate,
+						data:             coefficients,
 					}
 
+					impulseResponseList = append(impulseResponseList, ir)
 				}
 
 			}
 
+		}
+
+		return impulseResponseList, nil
+	}
+
+}
+
+/*
+ * Checks the impulse responses listed in a descriptor file, returning all
+ * problems found instead of stopping at the first one. Fails if the
+ * descriptor file itself cannot be read.
+ */
+func Check(descriptorFilePath string) ([]error, error) {
+	descriptors, err := readDescriptors(descriptorFilePath)
+
+	/*
+	 * Check if descriptors could be read.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		problems := []error{}
+		names := map[string]bool{}
+
+		/*
+		 * Check each descriptor and its wave file.
+		 */
+		for i, descriptor := range descriptors {
+			name := descriptor.Name
+			path := descriptor.Path
+
 			/*
-			 * Create data structure for impulse responses.
+			 * Check if the descriptor is complete and unique.
 			 */
-			impulseResponses := impulseResponsesStruct{
-				responses: impulseResponseList,
+			if name == "" {
+				err = fmt.Errorf("Descriptor %d in '%s' has no name.", i, descriptorFilePath)
+				problems = append(problems, err)
+			} else if names[name] {
+				err = fmt.Errorf("Impulse response '%s' is listed more than once in '%s'.", name, descriptorFilePath)
+				problems = append(problems, err)
+			}
+
+			names[name] = true
+
+			/*
+			 * Check if the wave file can be used.
+			 */
+			if path == "" {
+				err = fmt.Errorf("Impulse response '%s' in '%s' has no path.", name, descriptorFilePath)
+				problems = append(problems, err)
+			} else {
+				_, _, err = readCoefficients(path)
+
+				/*
+				 * Check if coefficients were read successfully.
+				 */
+				if err != nil {
+					problems = append(problems, err)
+				}
+
 			}
 
-			return &impulseResponses, nil
 		}
 
+		return problems, nil
+	}
+
+}
+
+/*
+ * Re-reads the descriptor file and replaces the impulse responses. On failure,
+ * the previously loaded impulse responses are kept.
+ */
+func (this *impulseResponsesStruct) Reload() error {
+	path := this.descriptorFilePath
+	responses, err := loadResponses(path)
+
+	/*
+	 * Check if impulse responses could be loaded.
+	 */
+	if err != nil {
+		return err
+	} else {
+		this.mutex.Lock()
+		this.responses = responses
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Imports a set of impulse responses using a descriptor file.
+ */
+func Import(descriptorFilePath string) (ImpulseResponses, error) {
+	responses, err := loadResponses(descriptorFilePath)
+
+	/*
+	 * Check if impulse responses could be loaded.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+
+		/*
+		 * Create data structure for impulse responses.
+		 */
+		impulseResponses := impulseResponsesStruct{
+			descriptorFilePath: descriptorFilePath,
+			responses:          responses,
+		}
+
+		return &impulseResponses, nil
 	}
 
 }
@@ -818,7 +1430,8 @@ func Empty(sampleRate uint32) Filter {
 	}
 
 	ft := fft.CreateFourierTransform()
-	bufFilterC := make([]complex128, 0)
+	bufPartitions := make([][]complex128, 0)
+	bufSpectra := make([][]complex128, 0)
 	bufFilteredC := make([]complex128, 0)
 	bufInput := make([]float64, 0)
 	bufInputC := make([]complex128, 0)
@@ -832,7 +1445,8 @@ func Empty(sampleRate uint32) Filter {
 	fltFilter := filterStruct{
 		impulseResponse:     ir,
 		fourierTransform:    ft,
-		filterComplex:       bufFilterC,
+		partitions:          bufPartitions,
+		spectra:             bufSpectra,
 		filteredComplex:     bufFilteredC,
 		inputBuffer:         bufInput,
 		inputBufferComplex:  bufInputC,
@@ -863,7 +1477,8 @@ func FromCoefficients(coeffs []float64, sampleRate uint32, name string) Filter {
 	}
 
 	ft := fft.CreateFourierTransform()
-	bufFilterC := make([]complex128, 0)
+	bufPartitions := make([][]complex128, 0)
+	bufSpectra := make([][]complex128, 0)
 	bufFilteredC := make([]complex128, 0)
 	bufInput := make([]float64, 0)
 	bufInputC := make([]complex128, 0)
@@ -877,7 +1492,8 @@ func FromCoefficients(coeffs []float64, sampleRate uint32, name string) Filter {
 	fltFilter := filterStruct{
 		impulseResponse:     ir,
 		fourierTransform:    ft,
-		filterComplex:       bufFilterC,
+		partitions:          bufPartitions,
+		spectra:             bufSpectra,
 		filteredComplex:     bufFilteredC,
 		inputBuffer:         bufInput,
 		inputBufferComplex:  bufInputC,
@@ -898,3 +1514,324 @@ func SampleRates() []uint32 {
 	copy(sampleRates, g_sampleRates)
 	return sampleRates
 }
+
+/*
+ * Calculates the impulse response of a system from its response to a
+ * reference signal (e. g. a sine sweep) by dividing the spectrum of the
+ * response by the spectrum of the reference.
+ *
+ * Frequencies which are (almost) absent from the reference are suppressed by
+ * regularization instead of being amplified without bounds.
+ */
+func Deconvolve(response []float64, reference []float64) ([]float64, error) {
+	numResponse := len(response)
+	numReference := len(reference)
+
+	/*
+	 * Check that both signals contain samples.
+	 */
+	if (numResponse == 0) || (numReference == 0) {
+		return nil, fmt.Errorf("%s", "Both the response and the reference must contain samples.")
+	} else {
+		numTotal := numResponse + numReference
+		numTotal64 := uint64(numTotal)
+		size64, _ := fft.NextPowerOfTwo(numTotal64)
+		size := int(size64)
+		responsePadded := make([]float64, size)
+		copy(responsePadded, response)
+		referencePadded := make([]float64, size)
+		copy(referencePadded, reference)
+		responseSpectrum := make([]complex128, size)
+		referenceSpectrum := make([]complex128, size)
+		ft := fft.CreateFourierTransform()
+		errResponse := ft.RealFourier(responsePadded, responseSpectrum, fft.SCALING_DEFAULT)
+		errReference := ft.RealFourier(referencePadded, referenceSpectrum, fft.SCALING_DEFAULT)
+
+		/*
+		 * Check if both transforms were successful.
+		 */
+		if errResponse != nil {
+			msg := errResponse.Error()
+			return nil, fmt.Errorf("Failed to transform response: %s", msg)
+		} else if errReference != nil {
+			msg := errReference.Error()
+			return nil, fmt.Errorf("Failed to transform reference: %s", msg)
+		} else {
+			maxPower := 0.0
+
+			/*
+			 * Find the maximum power in the spectrum of the reference.
+			 */
+			for _, elem := range referenceSpectrum {
+				magnitude := cmplx.Abs(elem)
+				power := magnitude * magnitude
+				maxPower = math.Max(maxPower, power)
+			}
+
+			regularization := CAPTURE_REGULARIZATION * maxPower
+
+			/*
+			 * Divide the spectrum of the response by the spectrum of
+			 * the reference.
+			 */
+			for i, elem := range referenceSpectrum {
+				magnitude := cmplx.Abs(elem)
+				power := magnitude * magnitude
+				denominator := complex(power+regularization, 0.0)
+
+				/*
+				 * Frequencies without any power remain silent.
+				 */
+				if denominator == 0.0 {
+					responseSpectrum[i] = 0.0
+				} else {
+					conj := cmplx.Conj(elem)
+					responseSpectrum[i] = (responseSpectrum[i] * conj) / denominator
+				}
+
+			}
+
+			result := make([]float64, size)
+			err := ft.RealInverseFourier(responseSpectrum, result, fft.SCALING_DEFAULT)
+
+			/*
+			 * Check if inverse transform was successful.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to calculate impulse response: %s", msg)
+			} else {
+				return result[0:numResponse], nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Captures an impulse response from the response of a system to a reference
+ * signal.
+ *
+ * The impulse response is trimmed to a certain length, starting at the onset
+ * of the direct sound, and faded out towards the end. It is normalized to a
+ * fixed peak value. The gain compensation (in decibels), which restores the
+ * measured gain when the impulse response is loaded, is returned as well.
+ */
+func Capture(response []float64, reference []float64, length int) ([]float64, int32, error) {
+	impulseResponse, err := Deconvolve(response, reference)
+
+	/*
+	 * Check if deconvolution was successful.
+	 */
+	if err != nil {
+		return nil, 0, err
+	} else {
+		peak := peakValue(impulseResponse)
+
+		/*
+		 * Check if there is any response at all.
+		 */
+		if peak == 0.0 {
+			return nil, 0, fmt.Errorf("%s", "The response does not contain any signal.")
+		} else if length <= 0 {
+			return nil, 0, fmt.Errorf("%s", "The length of the impulse response must be positive.")
+		} else {
+			threshold := CAPTURE_ONSET_THRESHOLD * peak
+			onset := 0
+
+			/*
+			 * Find the first sample which exceeds the threshold.
+			 */
+			for math.Abs(impulseResponse[onset]) < threshold {
+				onset++
+			}
+
+			numAvailable := len(impulseResponse) - onset
+
+			/*
+			 * The impulse response cannot be longer than the response.
+			 */
+			if length > numAvailable {
+				length = numAvailable
+			}
+
+			end := onset + length
+			trimmed := make([]float64, length)
+			copy(trimmed, impulseResponse[onset:end])
+			lengthFloat := float64(length)
+			fadeLengthFloat := math.Floor(CAPTURE_FADE_OUT * lengthFloat)
+			fadeLength := int(fadeLengthFloat)
+			fadeStart := length - fadeLength
+
+			/*
+			 * Fade out the tail with half a cosine.
+			 */
+			for i := fadeStart; i < length; i++ {
+				position := i - fadeStart
+				positionFloat := float64(position)
+				arg := (math.Pi * positionFloat) / fadeLengthFloat
+				fac := 0.5 + (0.5 * math.Cos(arg))
+				trimmed[i] *= fac
+			}
+
+			gain := estimateGain(trimmed)
+			gainDecibels := 20.0 * math.Log10(gain)
+			gainRounded := math.Round(gainDecibels)
+			compensation := int32(gainRounded)
+			trimmedPeak := peakValue(trimmed)
+			fac := CAPTURE_PEAK / trimmedPeak
+
+			/*
+			 * Normalize the impulse response.
+			 */
+			for i, sample := range trimmed {
+				trimmed[i] = fac * sample
+			}
+
+			return trimmed, compensation, nil
+		}
+
+	}
+
+}
+
+/*
+ * Writes descriptors to a descriptor file.
+ */
+func writeDescriptors(descriptorFilePath string, descriptors []filterDescriptorStruct) error {
+	buffer, err := json.MarshalIndent(descriptors, "", "\t")
+
+	/*
+	 * Check if descriptors could be encoded.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return fmt.Errorf("Failed to encode descriptor file: %s", msg)
+	} else {
+		buffer = append(buffer, '\n')
+		err = os.WriteFile(descriptorFilePath, buffer, DESCRIPTOR_FILE_MODE)
+
+		/*
+		 * Check if descriptor file was written.
+		 */
+		if err != nil {
+			return fmt.Errorf("Failed to write descriptor file: '%s'", descriptorFilePath)
+		} else {
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Adds an impulse response to a descriptor file, replacing any impulse
+ * response of the same name. The metadata and options of a replaced impulse
+ * response are kept.
+ */
+func AddDescriptor(descriptorFilePath string, name string, path string, compensation int32) error {
+	descriptors, err := readDescriptors(descriptorFilePath)
+
+	/*
+	 * Check if descriptors could be read.
+	 */
+	if err != nil {
+		return err
+	} else {
+
+		/*
+		 * Create filter descriptor.
+		 */
+		descriptor := filterDescriptorStruct{
+			Name:         name,
+			Path:         path,
+			Compensation: compensation,
+		}
+
+		replaced := false
+
+		/*
+		 * Replace the descriptor of the same name, if any, but keep its
+		 * metadata and options.
+		 */
+		for i, current := range descriptors {
+
+			/*
+			 * Check if we found the descriptor.
+			 */
+			if current.Name == name {
+				descriptor.Category = current.Category
+				descriptor.Author = current.Author
+				descriptor.MicPosition = current.MicPosition
+				descriptor.License = current.License
+				descriptor.MaxLength = current.MaxLength
+				descriptor.FadeOut = current.FadeOut
+				descriptor.MinimumPhase = current.MinimumPhase
+				descriptors[i] = descriptor
+				replaced = true
+			}
+
+		}
+
+		/*
+		 * Otherwise, add a new descriptor.
+		 */
+		if !replaced {
+			descriptors = append(descriptors, descriptor)
+		}
+
+		err = writeDescriptors(descriptorFilePath, descriptors)
+		return err
+	}
+
+}
+
+/*
+ * Sets the options an impulse response in a descriptor file is loaded with,
+ * i. e. the maximum length and the length of the fade-out (both in
+ * milliseconds, zero meaning no truncation or fade) and whether it is
+ * converted to minimum phase.
+ */
+func SetOptions(descriptorFilePath string, name string, maxLength uint32, fadeOut uint32, minimumPhase bool) error {
+	descriptors, err := readDescriptors(descriptorFilePath)
+
+	/*
+	 * Check if descriptors could be read.
+	 */
+	if err != nil {
+		return err
+	} else {
+		found := false
+
+		/*
+		 * Update the descriptor of the impulse response.
+		 */
+		for i, current := range descriptors {
+
+			/*
+			 * Check if we found the descriptor.
+			 */
+			if current.Name == name {
+				descriptors[i].MaxLength = maxLength
+				descriptors[i].FadeOut = fadeOut
+				descriptors[i].MinimumPhase = minimumPhase
+				found = true
+			}
+
+		}
+
+		/*
+		 * Check if the impulse response is listed.
+		 */
+		if !found {
+			return fmt.Errorf("No impulse response '%s' in descriptor file '%s'.", name, descriptorFilePath)
+		} else {
+			err = writeDescriptors(descriptorFilePath, descriptors)
+			return err
+		}
+
+	}
+
+}
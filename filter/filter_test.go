@@ -0,0 +1,500 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/random"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+ * Creates a signal of uniformly distributed noise within the given amplitude.
+ */
+func createNoise(prng random.PseudoRandomNumberGenerator, n int, amplitude float64) []float64 {
+	signal := make([]float64, n)
+
+	/*
+	 * Draw each sample.
+	 */
+	for i := range signal {
+		value := prng.NextFloat()
+		signal[i] = amplitude * ((2.0 * value) - 1.0)
+	}
+
+	return signal
+}
+
+/*
+ * Convolves a signal with an impulse response in the time domain, truncating
+ * the result to the length of the signal.
+ */
+func convolveDirect(signal []float64, coeffs []float64) []float64 {
+	n := len(signal)
+	result := make([]float64, n)
+
+	/*
+	 * Calculate each output sample.
+	 */
+	for i := range result {
+		sum := 0.0
+
+		/*
+		 * Sum the products of the coefficients and the delayed input.
+		 */
+		for j, coeff := range coeffs {
+
+			/*
+			 * Stop at the start of the signal.
+			 */
+			if j > i {
+				break
+			}
+
+			sum += coeff * signal[i-j]
+		}
+
+		result[i] = sum
+	}
+
+	return result
+}
+
+/*
+ * Verify that partitioned convolution matches direct convolution for impulse
+ * responses which are shorter than, as long as and much longer than a
+ * partition, and for buffer sizes which do and do not allow partitioning.
+ */
+func TestProcess(t *testing.T) {
+	responseSizes := []int{1, 17, 64, 100, 1000, 3000}
+	bufferSizes := []int{64, 100, 128, 256, 1024}
+	prng := random.CreatePRNG(1)
+
+	/*
+	 * Test each combination of impulse response and buffer size.
+	 */
+	for _, responseSize := range responseSizes {
+
+		/*
+		 * Keep the output within range, so that it is not limited.
+		 */
+		amplitude := 1.0 / float64(responseSize)
+		coeffs := createNoise(prng, responseSize, amplitude)
+
+		for _, bufferSize := range bufferSizes {
+			name := fmt.Sprintf("ir%d-buffer%d", responseSize, bufferSize)
+			numBuffers := 8
+			n := numBuffers * bufferSize
+			in := createNoise(prng, n, 0.5)
+			expected := convolveDirect(in, coeffs)
+			out := make([]float64, n)
+			flt := FromCoefficients(coeffs, 48000, name)
+			flt.Prepare(bufferSize)
+
+			/*
+			 * Process the signal buffer by buffer.
+			 */
+			for lBound := 0; lBound < n; lBound += bufferSize {
+				uBound := lBound + bufferSize
+				err := flt.Process(in[lBound:uBound], out[lBound:uBound])
+
+				/*
+				 * Check if processing failed.
+				 */
+				if err != nil {
+					t.Fatalf("%s: Processing failed: %s", name, err.Error())
+				}
+
+			}
+
+			/*
+			 * Compare the result with direct convolution.
+			 */
+			for i, value := range out {
+				diff := math.Abs(value - expected[i])
+
+				/*
+				 * Check if we found a significant difference.
+				 */
+				if diff > 1e-9 {
+					t.Errorf("%s: Sample %d differs. Expected: %e Got: %e", name, i, expected[i], value)
+					break
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Verify that the output of a filter keeps its headroom above full scale,
+ * unless clipping is enabled.
+ */
+func TestClipping(t *testing.T) {
+	n := 256
+	in := make([]float64, n)
+	out := make([]float64, n)
+
+	/*
+	 * Generate a sine wave.
+	 */
+	for i := range in {
+		iFloat := float64(i)
+		arg := 2.0 * math.Pi * iFloat / 64.0
+		in[i] = 0.5 * math.Sin(arg)
+	}
+
+	coeffs := []float64{4.0}
+	flt := FromCoefficients(coeffs, 48000, "boost")
+
+	/*
+	 * Whether clipping is enabled.
+	 */
+	clipping := []bool{
+		false,
+		true,
+	}
+
+	/*
+	 * Expected peak of the output.
+	 */
+	expectedPeaks := []float64{
+		2.0,
+		1.0,
+	}
+
+	/*
+	 * Process the signal with clipping disabled and enabled.
+	 */
+	for i, enabled := range clipping {
+		flt.SetClipping(enabled)
+		err := flt.Process(in, out)
+
+		/*
+		 * Check if processing failed.
+		 */
+		if err != nil {
+			t.Fatalf("Processing failed: %s", err.Error())
+		}
+
+		peak := 0.0
+
+		/*
+		 * Find the peak of the output.
+		 */
+		for _, sample := range out {
+			peak = math.Max(peak, math.Abs(sample))
+		}
+
+		expectedPeak := expectedPeaks[i]
+		diff := math.Abs(peak - expectedPeak)
+
+		/*
+		 * Check if the peak is where it should be.
+		 */
+		if diff > 1e-9 {
+			t.Errorf("Peak of output with clipping %t is wrong. Expected: %f Got: %f", enabled, expectedPeak, peak)
+		}
+
+	}
+
+	derived := flt.Multiply(2.0)
+	derived.Process(in, out)
+
+	/*
+	 * Filters derived from a clipping filter clip as well.
+	 */
+	for i, sample := range out {
+
+		/*
+		 * Report the first sample exceeding full scale.
+		 */
+		if math.Abs(sample) > 1.0 {
+			t.Errorf("Derived filter does not clip: Sample %d is %f.", i, sample)
+			break
+		}
+
+	}
+
+}
+
+/*
+ * Verify that processing a prepared filter does not allocate memory.
+ */
+func TestProcessAllocations(t *testing.T) {
+	bufferSizes := []int{64, 100, 256}
+	prng := random.CreatePRNG(2)
+	coeffs := createNoise(prng, 1000, 0.001)
+
+	/*
+	 * Test each buffer size.
+	 */
+	for _, bufferSize := range bufferSizes {
+		in := createNoise(prng, bufferSize, 0.5)
+		out := make([]float64, bufferSize)
+		flt := FromCoefficients(coeffs, 48000, "allocations")
+		flt.Prepare(bufferSize)
+
+		/*
+		 * Process a single buffer.
+		 */
+		process := func() {
+			flt.Process(in, out)
+		}
+
+		allocs := testing.AllocsPerRun(100, process)
+
+		/*
+		 * Processing must not allocate.
+		 */
+		if allocs != 0 {
+			t.Errorf("Processing buffers of size %d allocates %f times per run.", bufferSize, allocs)
+		}
+
+	}
+
+}
+
+/*
+ * Verify that checking a descriptor file reports every impulse response which
+ * cannot be loaded, but fails only if the descriptor file cannot be read.
+ */
+func TestCheck(t *testing.T) {
+	directory := t.TempDir()
+	missingDescriptor := filepath.Join(directory, "missing.json")
+	_, err := Check(missingDescriptor)
+
+	/*
+	 * A missing descriptor file is an error.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Checking a missing descriptor file did not fail.")
+	}
+
+	descriptorPath := filepath.Join(directory, "index.json")
+	wavePath := filepath.Join(directory, "broken.wav")
+	missingPath := filepath.Join(directory, "missing.wav")
+	os.WriteFile(wavePath, []byte("not a wave file"), 0644)
+	descriptor := fmt.Sprintf(`[{"Name": "a", "Path": "%s"}, {"Name": "b", "Path": "%s"}, {"Name": "b", "Path": ""}]`, wavePath, missingPath)
+	os.WriteFile(descriptorPath, []byte(descriptor), 0644)
+	problems, err := Check(descriptorPath)
+	numProblems := len(problems)
+
+	/*
+	 * The broken file, the missing file, the duplicate name and the
+	 * missing path must all be reported.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Checking descriptor file failed: %s", msg)
+	} else if numProblems != 4 {
+		t.Errorf("Checking descriptor file reported %d problems, expected: %d", numProblems, 4)
+	}
+
+}
+
+/*
+ * Verify that impulse responses are converted to minimum phase, truncated to
+ * their maximum length and faded out according to their descriptor.
+ */
+func TestShapeCoefficients(t *testing.T) {
+	coeffs := make([]float64, 64)
+	coeffs[10] = 0.5
+	coeffs[11] = 1.0
+	expected := make([]float64, 64)
+	expected[0] = 1.0
+	expected[1] = 0.5
+
+	/*
+	 * Descriptor requesting minimum phase.
+	 */
+	descriptor := filterDescriptorStruct{
+		Name:         "minimum phase",
+		MinimumPhase: true,
+	}
+
+	shaped, err := shapeCoefficients(coeffs, 1000, descriptor)
+
+	/*
+	 * A delayed filter with its zero outside the unit circle has the
+	 * undelayed mirrored filter as its minimum-phase equivalent.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Converting to minimum phase failed: %s", msg)
+	} else {
+
+		/*
+		 * Compare each coefficient.
+		 */
+		for i, coeff := range shaped {
+
+			/*
+			 * Check if we found a significant difference.
+			 */
+			if math.Abs(coeff-expected[i]) > 1e-3 {
+				t.Errorf("Coefficient %d should be %f, but is %f.", i, expected[i], coeff)
+				break
+			}
+
+		}
+
+	}
+
+	coeffs = make([]float64, 1000)
+
+	/*
+	 * Use a constant impulse response.
+	 */
+	for i := range coeffs {
+		coeffs[i] = 1.0
+	}
+
+	/*
+	 * Descriptor requesting truncation and a fade-out.
+	 */
+	descriptor = filterDescriptorStruct{
+		Name:      "truncated",
+		MaxLength: 500,
+		FadeOut:   100,
+	}
+
+	shaped, _ = shapeCoefficients(coeffs, 1000, descriptor)
+	numShaped := len(shaped)
+
+	/*
+	 * The impulse response is truncated to the maximum length.
+	 */
+	if numShaped != 500 {
+		t.Fatalf("Length of impulse response should be %d, but is %d.", 500, numShaped)
+	}
+
+	indices := []int{0, 400, 450, 499}
+	values := []float64{1.0, 1.0, 0.5, 0.0}
+
+	/*
+	 * The impulse response is faded out over its last 100 ms.
+	 */
+	for i, idx := range indices {
+		value := values[i]
+
+		/*
+		 * Check if we found a significant difference.
+		 */
+		if math.Abs(shaped[idx]-value) > 1e-3 {
+			t.Errorf("Coefficient %d should be %f, but is %f.", idx, value, shaped[idx])
+		}
+
+	}
+
+}
+
+/*
+ * Verify that the options of an impulse response are stored in the descriptor
+ * file and kept when the impulse response is replaced.
+ */
+func TestSetOptions(t *testing.T) {
+	directory := t.TempDir()
+	descriptorPath := filepath.Join(directory, "index.json")
+	os.WriteFile(descriptorPath, []byte(`[{"Name": "a", "Path": "a.wav"}]`), 0644)
+	err := SetOptions(descriptorPath, "b", 200, 20, true)
+
+	/*
+	 * Options can only be set for impulse responses listed.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Setting options of a missing impulse response did not fail.")
+	}
+
+	err = SetOptions(descriptorPath, "a", 200, 20, true)
+
+	/*
+	 * Check if options were set.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Fatalf("Setting options failed: %s", msg)
+	}
+
+	AddDescriptor(descriptorPath, "a", "b.wav", 3)
+	descriptors, _ := readDescriptors(descriptorPath)
+
+	/*
+	 * The expected descriptor.
+	 */
+	expected := filterDescriptorStruct{
+		Name:         "a",
+		Path:         "b.wav",
+		Compensation: 3,
+		MaxLength:    200,
+		FadeOut:      20,
+		MinimumPhase: true,
+	}
+
+	/*
+	 * The options are kept when the impulse response is replaced.
+	 */
+	if (len(descriptors) != 1) || (descriptors[0] != expected) {
+		t.Errorf("Descriptors should be %v, but are %v.", []filterDescriptorStruct{expected}, descriptors)
+	}
+
+}
+
+/*
+ * Measure the time it takes a prepared filter to convolve a block of noise
+ * with an impulse response of a certain length.
+ */
+func benchmarkProcess(b *testing.B, responseSize int, bufferSize int) {
+	prng := random.CreatePRNG(3)
+	coeffs := createNoise(prng, responseSize, 0.001)
+	in := createNoise(prng, bufferSize, 0.5)
+	out := make([]float64, bufferSize)
+	flt := FromCoefficients(coeffs, 48000, "benchmark")
+	flt.Prepare(bufferSize)
+	b.ResetTimer()
+
+	/*
+	 * Process the block b.N times.
+	 */
+	for i := 0; i < b.N; i++ {
+		flt.Process(in, out)
+	}
+
+}
+
+/*
+ * Benchmark the convolution with impulse responses of cabinets and rooms at
+ * common block sizes.
+ */
+func BenchmarkProcess(b *testing.B) {
+	responseSizes := []int{1024, 8192, 96000}
+	bufferSizes := []int{64, 256, 1024}
+
+	/*
+	 * Benchmark each impulse response size.
+	 */
+	for _, responseSize := range responseSizes {
+
+		/*
+		 * Benchmark each buffer size.
+		 */
+		for _, bufferSize := range bufferSizes {
+			name := fmt.Sprintf("ir%d-buffer%d", responseSize, bufferSize)
+
+			/*
+			 * Benchmark the filter.
+			 */
+			benchmark := func(b *testing.B) {
+				benchmarkProcess(b, responseSize, bufferSize)
+			}
+
+			b.Run(name, benchmark)
+		}
+
+	}
+
+}
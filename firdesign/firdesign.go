@@ -0,0 +1,268 @@
+package firdesign
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/filter"
+	"github.com/andrepxx/go-dsp-guitar/resample"
+	"math"
+)
+
+/*
+ * Types of filters which can be designed.
+ */
+const (
+	TYPE_LOWPASS = iota
+	TYPE_HIGHPASS
+	TYPE_BANDPASS
+	TYPE_LOW_SHELF
+	TYPE_HIGH_SHELF
+)
+
+/*
+ * Global constants.
+ */
+const (
+	ATTENUATION_KAISER = 21.0
+	MAX_COEFFICIENTS   = 65537
+)
+
+/*
+ * Global variables.
+ */
+var g_typeNames = []string{
+	"Lowpass",
+	"Highpass",
+	"Bandpass",
+	"Low shelf",
+	"High shelf",
+}
+
+/*
+ * Data structure describing the filter to design.
+ *
+ * All frequencies are given in hertz. The cutoff frequency lies in the middle
+ * of the transition band, which is the band over which the response changes
+ * from the passband to the stopband. A bandpass passes the band between the
+ * cutoff frequency and the upper cutoff frequency. The attenuation (in
+ * decibels) is the minimum attenuation of the stopband and also determines
+ * the ripple in the passband. Shelving filters boost or cut the frequencies
+ * below (low shelf) or above (high shelf) the cutoff frequency by a certain
+ * gain (in decibels) and pass all other frequencies.
+ */
+type Specification struct {
+	Type        int
+	Cutoff      float64
+	CutoffHigh  float64
+	Transition  float64
+	Attenuation float64
+	Gain        float64
+}
+
+/*
+ * Calculates the shape parameter of a Kaiser window achieving a certain
+ * stopband attenuation (in decibels).
+ */
+func kaiserBeta(attenuation float64) float64 {
+
+	/*
+	 * Use the empirical formula for the attenuation range.
+	 */
+	if attenuation > 50.0 {
+		return 0.1102 * (attenuation - 8.7)
+	} else if attenuation >= ATTENUATION_KAISER {
+		excess := attenuation - ATTENUATION_KAISER
+		return (0.5842 * math.Pow(excess, 0.4)) + (0.07886 * excess)
+	} else {
+		return 0.0
+	}
+
+}
+
+/*
+ * Calculates the number of coefficients of a filter with a Kaiser window
+ * achieving a certain stopband attenuation (in decibels) with a transition
+ * band of a certain width, given as a fraction of the sample rate.
+ *
+ * The number is always odd, so that the filter has an integer delay and can
+ * be turned into a highpass.
+ */
+func kaiserLength(attenuation float64, transition float64) int {
+	order := 0.0
+
+	/*
+	 * Below the range of the Kaiser window, the window is rectangular.
+	 */
+	if attenuation >= ATTENUATION_KAISER {
+		order = (attenuation - 7.95) / (2.285 * 2.0 * math.Pi * transition)
+	} else {
+		order = 0.9222 / transition
+	}
+
+	orderCeil := math.Ceil(order)
+	orderInt := int(orderCeil)
+
+	/*
+	 * Make the order even, so that the number of coefficients is odd.
+	 */
+	if (orderInt % 2) != 0 {
+		orderInt++
+	}
+
+	return orderInt + 1
+}
+
+/*
+ * Checks whether a specification describes a filter which can be designed at
+ * a certain sample rate.
+ */
+func validate(spec Specification, sampleRate uint32) error {
+	sampleRateFloat := float64(sampleRate)
+	nyquist := 0.5 * sampleRateFloat
+	numTypes := len(g_typeNames)
+
+	/*
+	 * Check each parameter of the specification.
+	 */
+	if sampleRate == 0 {
+		return fmt.Errorf("%s", "Sample rate must be positive.")
+	} else if (spec.Type < 0) || (spec.Type >= numTypes) {
+		return fmt.Errorf("Unknown filter type: %d", spec.Type)
+	} else if (spec.Cutoff <= 0.0) || (spec.Cutoff >= nyquist) {
+		return fmt.Errorf("Cutoff frequency must be between 0 and %f Hz.", nyquist)
+	} else if (spec.Type == TYPE_BANDPASS) && ((spec.CutoffHigh <= spec.Cutoff) || (spec.CutoffHigh >= nyquist)) {
+		return fmt.Errorf("Upper cutoff frequency must be between %f and %f Hz.", spec.Cutoff, nyquist)
+	} else if (spec.Transition <= 0.0) || (spec.Transition >= nyquist) {
+		return fmt.Errorf("Width of transition band must be between 0 and %f Hz.", nyquist)
+	} else if spec.Attenuation <= 0.0 {
+		return fmt.Errorf("%s", "Attenuation must be positive.")
+	} else {
+		return nil
+	}
+
+}
+
+/*
+ * Designs the coefficients of a linear-phase FIR filter meeting a
+ * specification at a certain sample rate.
+ *
+ * The filter is designed using the windowed sinc method with a Kaiser window,
+ * whose shape and length are derived from the attenuation and the width of
+ * the transition band. Highpass, bandpass and shelving filters are combined
+ * from lowpass filters of the same length.
+ */
+func Coefficients(spec Specification, sampleRate uint32) ([]float64, error) {
+	err := validate(spec, sampleRate)
+
+	/*
+	 * Check if the specification is valid.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		sampleRateFloat := float64(sampleRate)
+		transition := spec.Transition / sampleRateFloat
+		attenuation := spec.Attenuation
+		numCoeffs := kaiserLength(attenuation, transition)
+
+		/*
+		 * Check if the filter would become too long.
+		 */
+		if numCoeffs > MAX_COEFFICIENTS {
+			return nil, fmt.Errorf("Filter would need %d coefficients, but at most %d are supported.", numCoeffs, MAX_COEFFICIENTS)
+		} else {
+			beta := kaiserBeta(attenuation)
+			cutoff := spec.Cutoff / sampleRateFloat
+			lowpass := resample.Lowpass(numCoeffs, cutoff, beta)
+			center := (numCoeffs - 1) / 2
+			gainArg := 0.05 * spec.Gain
+			gain := math.Pow(10.0, gainArg)
+			coeffs := make([]float64, numCoeffs)
+
+			/*
+			 * Combine the filter from the lowpass.
+			 */
+			switch spec.Type {
+			case TYPE_LOWPASS:
+				copy(coeffs, lowpass)
+			case TYPE_HIGHPASS:
+
+				/*
+				 * Subtract the lowpass from a unit impulse.
+				 */
+				for i, coeff := range lowpass {
+					coeffs[i] = -coeff
+				}
+
+				coeffs[center] += 1.0
+			case TYPE_BANDPASS:
+				cutoffHigh := spec.CutoffHigh / sampleRateFloat
+				lowpassHigh := resample.Lowpass(numCoeffs, cutoffHigh, beta)
+
+				/*
+				 * Subtract the lower lowpass from the upper one.
+				 */
+				for i, coeff := range lowpass {
+					coeffs[i] = lowpassHigh[i] - coeff
+				}
+
+			case TYPE_LOW_SHELF:
+
+				/*
+				 * Add the scaled lowpass to a unit impulse.
+				 */
+				for i, coeff := range lowpass {
+					coeffs[i] = (gain - 1.0) * coeff
+				}
+
+				coeffs[center] += 1.0
+			case TYPE_HIGH_SHELF:
+
+				/*
+				 * Subtract the scaled lowpass from a scaled unit
+				 * impulse.
+				 */
+				for i, coeff := range lowpass {
+					coeffs[i] = (1.0 - gain) * coeff
+				}
+
+				coeffs[center] += gain
+			}
+
+			return coeffs, nil
+		}
+
+	}
+
+}
+
+/*
+ * Designs a linear-phase FIR filter meeting a specification at a certain
+ * sample rate.
+ *
+ * The filter delays the signal by half its length, which is reported by its
+ * Delay method.
+ */
+func Design(spec Specification, sampleRate uint32) (filter.Filter, error) {
+	coeffs, err := Coefficients(spec, sampleRate)
+
+	/*
+	 * Check if the filter could be designed.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		typeName := g_typeNames[spec.Type]
+		name := fmt.Sprintf("%s at %.0f Hz", typeName, spec.Cutoff)
+
+		/*
+		 * A bandpass has two cutoff frequencies.
+		 */
+		if spec.Type == TYPE_BANDPASS {
+			name = fmt.Sprintf("%s from %.0f to %.0f Hz", typeName, spec.Cutoff, spec.CutoffHigh)
+		}
+
+		flt := filter.FromCoefficients(coeffs, sampleRate, name)
+		return flt, nil
+	}
+
+}
@@ -0,0 +1,173 @@
+package firdesign
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+/*
+ * Evaluates the magnitude response (in decibels) of an FIR filter at a
+ * certain frequency.
+ */
+func levelAt(coeffs []float64, frequency float64, sampleRate uint32) float64 {
+	sampleRateFloat := float64(sampleRate)
+	omega := (2.0 * math.Pi * frequency) / sampleRateFloat
+	sum := complex(0.0, 0.0)
+
+	/*
+	 * Sum up the contribution of each coefficient.
+	 */
+	for i, coeff := range coeffs {
+		iFloat := float64(i)
+		arg := complex(0.0, -omega*iFloat)
+		sum += complex(coeff, 0.0) * cmplx.Exp(arg)
+	}
+
+	magnitude := cmplx.Abs(sum)
+	return 20.0 * math.Log10(magnitude)
+}
+
+/*
+ * Verify that designed filters pass, boost or cut the frequencies they
+ * should and attenuate their stopbands as specified.
+ */
+func TestDesign(t *testing.T) {
+	sampleRate := uint32(48000)
+
+	/*
+	 * The filters to design.
+	 */
+	specs := []Specification{
+		Specification{Type: TYPE_LOWPASS, Cutoff: 1000, Transition: 400, Attenuation: 80},
+		Specification{Type: TYPE_HIGHPASS, Cutoff: 1000, Transition: 400, Attenuation: 80},
+		Specification{Type: TYPE_BANDPASS, Cutoff: 1000, CutoffHigh: 3000, Transition: 400, Attenuation: 60},
+		Specification{Type: TYPE_LOW_SHELF, Cutoff: 1000, Transition: 400, Attenuation: 60, Gain: 6},
+		Specification{Type: TYPE_HIGH_SHELF, Cutoff: 1000, Transition: 400, Attenuation: 60, Gain: -12},
+	}
+
+	/*
+	 * Frequencies at which the level is checked.
+	 */
+	frequencies := [][]float64{
+		[]float64{100, 700, 1300, 10000},
+		[]float64{100, 700, 1300, 10000},
+		[]float64{500, 2000, 3500},
+		[]float64{100, 700, 1300, 10000},
+		[]float64{100, 700, 1300, 10000},
+	}
+
+	/*
+	 * Expected levels at these frequencies. Levels of stopbands are upper
+	 * bounds.
+	 */
+	levels := [][]float64{
+		[]float64{0, 0, -80, -80},
+		[]float64{-80, -80, 0, 0},
+		[]float64{-60, 0, -60},
+		[]float64{6, 6, 0, 0},
+		[]float64{0, 0, -12, -12},
+	}
+
+	/*
+	 * Whether each frequency lies in a stopband.
+	 */
+	stopbands := [][]bool{
+		[]bool{false, false, true, true},
+		[]bool{true, true, false, false},
+		[]bool{true, false, true},
+		[]bool{false, false, false, false},
+		[]bool{false, false, false, false},
+	}
+
+	/*
+	 * Design and check each filter.
+	 */
+	for i, spec := range specs {
+		flt, err := Design(spec, sampleRate)
+
+		/*
+		 * Check if filter was designed.
+		 */
+		if err != nil {
+			t.Errorf("Failed to design filter %d: %s", i, err.Error())
+		} else {
+			coeffs := flt.Coefficients()
+			numCoeffs := len(coeffs)
+			center := (numCoeffs - 1) / 2
+			delay := flt.Delay()
+
+			/*
+			 * Linear-phase filters have an odd length and are
+			 * delayed by half their length.
+			 */
+			if ((numCoeffs % 2) != 1) || (delay != uint32(center)) {
+				t.Errorf("Filter %d has %d coefficients and a delay of %d.", i, numCoeffs, delay)
+			}
+
+			/*
+			 * Linear-phase filters are symmetric.
+			 */
+			for j, coeff := range coeffs {
+				mirrored := coeffs[numCoeffs-j-1]
+
+				/*
+				 * Check if we found a significant difference.
+				 */
+				if math.Abs(coeff-mirrored) > 1e-12 {
+					t.Errorf("Filter %d is not symmetric at coefficient %d.", i, j)
+					break
+				}
+
+			}
+
+			/*
+			 * Check the level at each frequency.
+			 */
+			for j, frequency := range frequencies[i] {
+				expected := levels[i][j]
+				level := levelAt(coeffs, frequency, sampleRate)
+
+				/*
+				 * Stopbands must be attenuated at least as
+				 * specified, while passbands must match.
+				 */
+				if stopbands[i][j] && (level > expected+1.0) {
+					t.Errorf("Level of filter %d at %f Hz should be below %f dB, but is %f dB.", i, frequency, expected, level)
+				} else if !stopbands[i][j] && (math.Abs(level-expected) > 0.1) {
+					t.Errorf("Level of filter %d at %f Hz should be %f dB, but is %f dB.", i, frequency, expected, level)
+				}
+
+			}
+
+		}
+
+	}
+
+	/*
+	 * Specifications which cannot be met.
+	 */
+	invalidSpecs := []Specification{
+		Specification{Type: TYPE_LOWPASS, Cutoff: 30000, Transition: 400, Attenuation: 80},
+		Specification{Type: TYPE_BANDPASS, Cutoff: 1000, CutoffHigh: 500, Transition: 400, Attenuation: 80},
+		Specification{Type: TYPE_LOWPASS, Cutoff: 1000, Transition: 0, Attenuation: 80},
+		Specification{Type: TYPE_LOWPASS, Cutoff: 1000, Transition: 1, Attenuation: 120},
+		Specification{Type: 42, Cutoff: 1000, Transition: 400, Attenuation: 80},
+	}
+
+	/*
+	 * Verify that each invalid specification is rejected.
+	 */
+	for i, spec := range invalidSpecs {
+		_, err := Design(spec, sampleRate)
+
+		/*
+		 * Check if design failed.
+		 */
+		if err == nil {
+			t.Errorf("Designing invalid filter %d did not fail.", i)
+		}
+
+	}
+
+}
@@ -0,0 +1,648 @@
+package flac
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	BITS_PER_BYTE        = 8
+	BLOCK_SIZE           = 4096
+	MAX_CHANNELS         = 8
+	MAX_FIXED_ORDER      = 4
+	MAX_PARTITION_ORDER  = 8
+	MAX_SAMPLE_RATE      = 655350
+	SIZE_STREAMINFO      = 34
+	SIZE_METADATA_HEADER = 4
+)
+
+/*
+ * FLAC stream constants.
+ */
+const (
+	MAGIC                      = 0x664c6143 // uint32, "fLaC"
+	FRAME_SYNC                 = 0x3ffe     // uint16, 14 bits
+	METADATA_STREAMINFO        = 0x00       // uint8
+	METADATA_LAST              = 0x80       // uint8
+	CHANNELS_LEFT_SIDE         = 0x08       // uint8
+	CHANNELS_RIGHT_SIDE        = 0x09       // uint8
+	CHANNELS_MID_SIDE          = 0x0a       // uint8
+	SUBFRAME_CONSTANT          = 0x00       // uint8
+	SUBFRAME_VERBATIM          = 0x01       // uint8
+	SUBFRAME_FIXED             = 0x08       // uint8
+	SUBFRAME_LPC               = 0x20       // uint8
+	RESIDUAL_RICE              = 0x00       // uint8
+	RESIDUAL_RICE2             = 0x01       // uint8
+	RICE_ESCAPE                = 0x0f       // uint8
+	RICE2_ESCAPE               = 0x1f       // uint8
+	BLOCK_SIZE_CODE_8BIT       = 0x06       // uint8
+	BLOCK_SIZE_CODE_16BIT      = 0x07       // uint8
+	SAMPLE_RATE_CODE_KHZ       = 0x0c       // uint8
+	SAMPLE_RATE_CODE_HZ        = 0x0d       // uint8
+	SAMPLE_RATE_CODE_TENS      = 0x0e       // uint8
+	POLYNOMIAL_CRC8            = 0x07       // uint8
+	POLYNOMIAL_CRC16           = 0x8005     // uint16
+	MIN_BIT_DEPTH              = 4          // uint16
+	MAX_BIT_DEPTH              = 32         // uint16
+	MAX_RICE_PARAMETER         = 0x0e       // uint8
+	MAX_RICE2_PARAMETER        = 0x1e       // uint8
+	NUM_BITS_RICE_PARAMETER    = 4          // uint
+	NUM_BITS_RICE2_PARAMETER   = 5          // uint
+	NUM_BITS_ESCAPED_BIT_DEPTH = 5          // uint
+)
+
+/*
+ * Block sizes which are encoded directly in the frame header, indexed by
+ * their code. Zero marks codes which are reserved or need more bits.
+ */
+var g_blockSizes = []int{
+	0, 192, 576, 1152, 2304, 4608, 0, 0,
+	256, 512, 1024, 2048, 4096, 8192, 16384, 32768,
+}
+
+/*
+ * Sample rates which are encoded directly in the frame header, indexed by
+ * their code. Zero marks codes which refer to the stream info or need more
+ * bits.
+ */
+var g_sampleRates = []uint32{
+	0, 88200, 176400, 192000, 8000, 16000, 22050, 24000,
+	32000, 44100, 48000, 96000, 0, 0, 0, 0,
+}
+
+/*
+ * Bit depths which are encoded directly in the frame header, indexed by
+ * their code. Zero marks codes which refer to the stream info.
+ */
+var g_bitDepths = []uint16{
+	0, 8, 12, 0, 16, 20, 24, 32,
+}
+
+/*
+ * Lookup tables for the CRC-8 and CRC-16 checksums, which protect the
+ * frame headers and frames.
+ */
+var g_crc8Table = createCRC8Table()
+var g_crc16Table = createCRC16Table()
+
+/*
+ * Data structure representing the stream info, which describes the entire
+ * stream.
+ */
+type streamInfoStruct struct {
+	minBlockSize uint16
+	maxBlockSize uint16
+	minFrameSize uint32
+	maxFrameSize uint32
+	sampleRate   uint32
+	channelCount uint16
+	bitDepth     uint16
+	totalSamples uint64
+	md5          [16]byte
+}
+
+/*
+ * Data structure writing a stream of bits, most significant bit first.
+ */
+type bitWriterStruct struct {
+	data        []byte
+	accumulator uint64
+	numBits     uint
+}
+
+/*
+ * Data structure reading a stream of bits, most significant bit first, while
+ * keeping track of the checksums of all bytes read.
+ */
+type bitReaderStruct struct {
+	reader      *bufio.Reader
+	accumulator uint64
+	numBits     uint
+	crc8        uint8
+	crc16       uint16
+}
+
+/*
+ * Creates the lookup table for the CRC-8 checksum.
+ */
+func createCRC8Table() []uint8 {
+	table := make([]uint8, 256)
+
+	/*
+	 * Calculate the checksum of each byte.
+	 */
+	for i := range table {
+		crc := uint8(i)
+
+		/*
+		 * Process each bit.
+		 */
+		for j := 0; j < 8; j++ {
+
+			/*
+			 * Check if the most significant bit is set.
+			 */
+			if (crc & 0x80) != 0 {
+				crc = (crc << 1) ^ POLYNOMIAL_CRC8
+			} else {
+				crc <<= 1
+			}
+
+		}
+
+		table[i] = crc
+	}
+
+	return table
+}
+
+/*
+ * Creates the lookup table for the CRC-16 checksum.
+ */
+func createCRC16Table() []uint16 {
+	table := make([]uint16, 256)
+
+	/*
+	 * Calculate the checksum of each byte.
+	 */
+	for i := range table {
+		crc := uint16(i) << 8
+
+		/*
+		 * Process each bit.
+		 */
+		for j := 0; j < 8; j++ {
+
+			/*
+			 * Check if the most significant bit is set.
+			 */
+			if (crc & 0x8000) != 0 {
+				crc = (crc << 1) ^ POLYNOMIAL_CRC16
+			} else {
+				crc <<= 1
+			}
+
+		}
+
+		table[i] = crc
+	}
+
+	return table
+}
+
+/*
+ * Updates a CRC-8 checksum with a byte.
+ */
+func updateCRC8(crc uint8, byt byte) uint8 {
+	return g_crc8Table[crc^byt]
+}
+
+/*
+ * Updates a CRC-16 checksum with a byte.
+ */
+func updateCRC16(crc uint16, byt byte) uint16 {
+	idx := byte(crc>>8) ^ byt
+	return (crc << 8) ^ g_crc16Table[idx]
+}
+
+/*
+ * Calculates the CRC-8 checksum of a byte slice.
+ */
+func checksumCRC8(data []byte) uint8 {
+	crc := uint8(0)
+
+	/*
+	 * Process each byte.
+	 */
+	for _, byt := range data {
+		crc = updateCRC8(crc, byt)
+	}
+
+	return crc
+}
+
+/*
+ * Calculates the CRC-16 checksum of a byte slice.
+ */
+func checksumCRC16(data []byte) uint16 {
+	crc := uint16(0)
+
+	/*
+	 * Process each byte.
+	 */
+	for _, byt := range data {
+		crc = updateCRC16(crc, byt)
+	}
+
+	return crc
+}
+
+/*
+ * Returns the smallest number of bits a two's complement representation of
+ * a value needs, including the sign bit.
+ */
+func signedBits(value int64) uint {
+
+	/*
+	 * Negative values need as many bits as their complement.
+	 */
+	if value < 0 {
+		value = ^value
+	}
+
+	n := uint(1)
+
+	/*
+	 * Count the significant bits.
+	 */
+	for value != 0 {
+		value >>= 1
+		n++
+	}
+
+	return n
+}
+
+/*
+ * Maps a signed value to an unsigned one, interleaving positive and negative
+ * values, as the Rice code requires.
+ */
+func zigzag(value int64) uint64 {
+	return uint64((value << 1) ^ (value >> 63))
+}
+
+/*
+ * Maps an unsigned value back to the signed value it represents.
+ */
+func unzigzag(value uint64) int64 {
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+/*
+ * Converts floating-point samples to integers of a certain bit depth, mapping
+ * the range from -1 to 1 onto the full range of the integers.
+ */
+func samplesToInts(samples []float64, bitDepth uint16, ints []int64) {
+	maxValue := (int64(1) << (bitDepth - 1)) - 1
+	minValue := -(maxValue + 1)
+	delta := maxValue - minValue
+	scale := 0.5 * float64(delta)
+
+	/*
+	 * Convert each sample.
+	 */
+	for i, sample := range samples {
+
+		/*
+		 * Make sure that limits are not exceeded.
+		 */
+		if sample < -1.0 {
+			sample = -1.0
+		} else if sample > 1.0 {
+			sample = 1.0
+		}
+
+		value := int64(scale * sample)
+
+		/*
+		 * Make sure that limits are not exceeded.
+		 */
+		if value > maxValue {
+			value = maxValue
+		} else if value < minValue {
+			value = minValue
+		}
+
+		ints[i] = value
+	}
+
+}
+
+/*
+ * Converts integers of a certain bit depth to floating-point samples, mapping
+ * the full range of the integers onto the range from -1 to 1.
+ */
+func intsToSamples(ints []int64, bitDepth uint16, samples []float64) {
+	maxValue := (int64(1) << (bitDepth - 1)) - 1
+	minValue := -(maxValue + 1)
+	delta := maxValue - minValue
+	scaling := 2.0 / float64(delta)
+
+	/*
+	 * Convert each value.
+	 */
+	for i, value := range ints {
+		samples[i] = scaling * float64(value)
+	}
+
+}
+
+/*
+ * Appends the lowest bits of a value to the stream.
+ */
+func (this *bitWriterStruct) writeBits(value uint64, numBits uint) {
+
+	/*
+	 * Write long values in two parts, so that the accumulator does not
+	 * overflow.
+	 */
+	if numBits > 32 {
+		high := value >> 32
+		this.writeBits(high, numBits-32)
+		numBits = 32
+	}
+
+	mask := (uint64(1) << numBits) - 1
+	this.accumulator = (this.accumulator << numBits) | (value & mask)
+	this.numBits += numBits
+
+	/*
+	 * Move each complete byte into the buffer.
+	 */
+	for this.numBits >= 8 {
+		this.numBits -= 8
+		byt := byte(this.accumulator >> this.numBits)
+		this.data = append(this.data, byt)
+	}
+
+}
+
+/*
+ * Appends a signed value in two's complement representation to the stream.
+ */
+func (this *bitWriterStruct) writeSigned(value int64, numBits uint) {
+	this.writeBits(uint64(value), numBits)
+}
+
+/*
+ * Appends a value in unary representation, a number of zeros followed by a
+ * one, to the stream.
+ */
+func (this *bitWriterStruct) writeUnary(value uint64) {
+
+	/*
+	 * Write zeros in large groups.
+	 */
+	for value >= 32 {
+		this.writeBits(0, 32)
+		value -= 32
+	}
+
+	numBits := uint(value) + 1
+	this.writeBits(1, numBits)
+}
+
+/*
+ * Appends a signed value in Rice code with a certain parameter to the stream.
+ */
+func (this *bitWriterStruct) writeRice(value int64, parameter uint) {
+	folded := zigzag(value)
+	quotient := folded >> parameter
+	this.writeUnary(quotient)
+	this.writeBits(folded, parameter)
+}
+
+/*
+ * Pads the stream with zeros to the next byte boundary.
+ */
+func (this *bitWriterStruct) align() {
+
+	/*
+	 * Check if a byte is incomplete.
+	 */
+	if this.numBits > 0 {
+		padding := 8 - this.numBits
+		this.writeBits(0, padding)
+	}
+
+}
+
+/*
+ * Returns the bytes written so far, which only includes complete bytes.
+ */
+func (this *bitWriterStruct) bytes() []byte {
+	return this.data
+}
+
+/*
+ * Discards everything written, keeping the buffer for reuse.
+ */
+func (this *bitWriterStruct) reset() {
+	this.data = this.data[:0]
+	this.accumulator = 0
+	this.numBits = 0
+}
+
+/*
+ * Reads the next byte, updating the checksums.
+ */
+func (this *bitReaderStruct) readByte() (byte, error) {
+	byt, err := this.reader.ReadByte()
+
+	/*
+	 * Only bytes actually read enter the checksums.
+	 */
+	if err == nil {
+		this.crc8 = updateCRC8(this.crc8, byt)
+		this.crc16 = updateCRC16(this.crc16, byt)
+	}
+
+	return byt, err
+}
+
+/*
+ * Reads a number of bits, at most 32 at once, as an unsigned value.
+ */
+func (this *bitReaderStruct) readBits(numBits uint) (uint64, error) {
+
+	/*
+	 * Fill the accumulator with enough bits.
+	 */
+	for this.numBits < numBits {
+		byt, err := this.readByte()
+
+		/*
+		 * Check if byte was read.
+		 */
+		if err != nil {
+			return 0, err
+		}
+
+		this.accumulator = (this.accumulator << 8) | uint64(byt)
+		this.numBits += 8
+	}
+
+	this.numBits -= numBits
+	mask := (uint64(1) << numBits) - 1
+	value := (this.accumulator >> this.numBits) & mask
+	return value, nil
+}
+
+/*
+ * Reads a number of bits as a signed value in two's complement
+ * representation.
+ */
+func (this *bitReaderStruct) readSigned(numBits uint) (int64, error) {
+	value, err := this.readBits(numBits)
+
+	/*
+	 * Check if value was read.
+	 */
+	if err != nil {
+		return 0, err
+	} else if numBits == 0 {
+		return 0, nil
+	} else {
+		shift := 64 - numBits
+		result := int64(value<<shift) >> shift
+		return result, nil
+	}
+
+}
+
+/*
+ * Reads a value in unary representation, counting the zeros before the next
+ * one.
+ */
+func (this *bitReaderStruct) readUnary() (uint64, error) {
+	value := uint64(0)
+
+	/*
+	 * Count the zeros, a byte at a time.
+	 */
+	for {
+
+		/*
+		 * Refill the accumulator if it is empty.
+		 */
+		if this.numBits == 0 {
+			byt, err := this.readByte()
+
+			/*
+			 * Check if byte was read.
+			 */
+			if err != nil {
+				return 0, err
+			}
+
+			this.accumulator = (this.accumulator << 8) | uint64(byt)
+			this.numBits = 8
+		}
+
+		numBits := this.numBits
+		mask := (uint64(1) << numBits) - 1
+		available := this.accumulator & mask
+
+		/*
+		 * Check if the remaining bits hold the terminating one.
+		 */
+		if available == 0 {
+			value += uint64(numBits)
+			this.numBits = 0
+		} else {
+			significant := uint(bits.Len64(available))
+			zeros := numBits - significant
+			value += uint64(zeros)
+			this.numBits = significant - 1
+			return value, nil
+		}
+
+	}
+
+}
+
+/*
+ * Reads a signed value in Rice code with a certain parameter.
+ */
+func (this *bitReaderStruct) readRice(parameter uint) (int64, error) {
+	quotient, err := this.readUnary()
+
+	/*
+	 * Check if quotient was read.
+	 */
+	if err != nil {
+		return 0, err
+	} else {
+		remainder, err := this.readBits(parameter)
+
+		/*
+		 * Check if remainder was read.
+		 */
+		if err != nil {
+			return 0, err
+		} else {
+			folded := (quotient << parameter) | remainder
+			value := unzigzag(folded)
+			return value, nil
+		}
+
+	}
+
+}
+
+/*
+ * Discards the bits up to the next byte boundary.
+ */
+func (this *bitReaderStruct) align() {
+	this.numBits -= this.numBits % 8
+}
+
+/*
+ * Restarts the checksums, e. g. at the beginning of a frame.
+ */
+func (this *bitReaderStruct) resetChecksums() {
+	this.crc8 = 0
+	this.crc16 = 0
+}
+
+/*
+ * Checks whether a stream starts with the FLAC signature, then moves back to
+ * where it started.
+ */
+func Detect(r io.ReadSeeker) bool {
+	offset, err := r.Seek(0, io.SeekCurrent)
+
+	/*
+	 * Check if we found the current position.
+	 */
+	if err != nil {
+		return false
+	} else {
+		signature := make([]byte, 4)
+		_, err = io.ReadFull(r, signature)
+		r.Seek(offset, io.SeekStart)
+
+		/*
+		 * Check if signature was read.
+		 */
+		if err != nil {
+			return false
+		} else {
+			magic := (uint32(signature[0]) << 24) | (uint32(signature[1]) << 16) | (uint32(signature[2]) << 8) | uint32(signature[3])
+			return magic == MAGIC
+		}
+
+	}
+
+}
+
+/*
+ * Checks whether a bit depth is supported for encoding.
+ */
+func checkBitDepth(bitDepth uint16) error {
+
+	/*
+	 * Only whole bytes per sample are supported for encoding.
+	 */
+	if (bitDepth != 8) && (bitDepth != 16) && (bitDepth != 24) {
+		return fmt.Errorf("Bit depth must be either %d or %d or %d for FLAC.", 8, 16, 24)
+	} else {
+		return nil
+	}
+
+}
@@ -0,0 +1,589 @@
+package flac
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+ * Creates a temporary file, which is removed after the test.
+ */
+func createTestFile(t *testing.T) *os.File {
+	dir, err := os.MkdirTemp("", "flac")
+
+	/*
+	 * Check if temporary directory was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err.Error())
+	}
+
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	path := filepath.Join(dir, "test.flac")
+	fd, err := os.Create(path)
+
+	/*
+	 * Check if file was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create file: %s", err.Error())
+	}
+
+	t.Cleanup(func() {
+		fd.Close()
+	})
+
+	return fd
+}
+
+/*
+ * Creates the channels of a test signal: a sine wave, noise, silence and a
+ * constant value, one after another.
+ */
+func createSignal(channelCount int, n int) [][]float64 {
+	channels := make([][]float64, channelCount)
+	rng := rand.New(rand.NewSource(1))
+	quarter := n / 4
+
+	/*
+	 * Generate each channel.
+	 */
+	for i := range channels {
+		channel := make([]float64, n)
+		iFloat := float64(i)
+
+		/*
+		 * Generate each sample.
+		 */
+		for j := range channel {
+			jFloat := float64(j)
+
+			/*
+			 * Select the part of the signal.
+			 */
+			switch j / quarter {
+			case 0:
+				arg := (2.0 * math.Pi * (440.0 + (110.0 * iFloat)) * jFloat) / 48000.0
+				channel[j] = 0.5 * math.Sin(arg)
+			case 1:
+				channel[j] = (2.0 * rng.Float64()) - 1.0
+			case 2:
+				channel[j] = 0.0
+			default:
+				channel[j] = 0.25
+			}
+
+		}
+
+		channels[i] = channel
+	}
+
+	return channels
+}
+
+/*
+ * Returns the samples as they are expected after encoding them with a
+ * certain bit depth.
+ */
+func quantize(channels [][]float64, bitDepth uint16) [][]float64 {
+	result := make([][]float64, len(channels))
+
+	/*
+	 * Quantize each channel.
+	 */
+	for i, channel := range channels {
+		n := len(channel)
+		ints := make([]int64, n)
+		samples := make([]float64, n)
+		samplesToInts(channel, bitDepth, ints)
+		intsToSamples(ints, bitDepth, samples)
+		result[i] = samples
+	}
+
+	return result
+}
+
+/*
+ * Writes channels to a FLAC writer in blocks of varying size.
+ */
+func writeSignal(t *testing.T, writer Writer, channels [][]float64) {
+	n := len(channels[0])
+	sizes := []int{1, 100, 4095, 5000, 333}
+	offset := 0
+
+	/*
+	 * Write blocks until all samples are written.
+	 */
+	for i := 0; offset < n; i++ {
+		size := sizes[i%len(sizes)]
+		uBound := offset + size
+
+		/*
+		 * The last block may be shorter.
+		 */
+		if uBound > n {
+			uBound = n
+		}
+
+		block := make([][]float64, len(channels))
+
+		/*
+		 * Take the block from each channel.
+		 */
+		for j, channel := range channels {
+			block[j] = channel[offset:uBound]
+		}
+
+		err := writer.Write(block)
+
+		/*
+		 * Check if block was written.
+		 */
+		if err != nil {
+			t.Fatalf("Failed to write block: %s", err.Error())
+		}
+
+		offset = uBound
+	}
+
+	err := writer.Close()
+
+	/*
+	 * Check if stream was finalized.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to close writer: %s", err.Error())
+	}
+
+}
+
+/*
+ * Reads all channels from a FLAC reader in blocks of a certain size.
+ */
+func readSignal(t *testing.T, reader Reader, blockSize int) [][]float64 {
+	channelCount := int(reader.ChannelCount())
+	block := make([][]float64, channelCount)
+	result := make([][]float64, channelCount)
+
+	/*
+	 * Allocate a block for each channel.
+	 */
+	for i := range block {
+		block[i] = make([]float64, blockSize)
+	}
+
+	/*
+	 * Read blocks until the end of the stream.
+	 */
+	for {
+		n, err := reader.Read(block)
+
+		/*
+		 * Check if we reached the end of the stream.
+		 */
+		if err == io.EOF {
+			return result
+		} else if err != nil {
+			t.Fatalf("Failed to read block: %s", err.Error())
+		}
+
+		/*
+		 * Append the block to each channel.
+		 */
+		for i, channel := range block {
+			result[i] = append(result[i], channel[0:n]...)
+		}
+
+	}
+
+}
+
+/*
+ * Compares decoded channels with the expected ones.
+ */
+func compareSignal(t *testing.T, name string, result [][]float64, expected [][]float64) {
+
+	/*
+	 * Check if the number of channels matches.
+	 */
+	if len(result) != len(expected) {
+		t.Fatalf("%s: Expected %d channels, got %d.", name, len(expected), len(result))
+	}
+
+	/*
+	 * Compare each channel.
+	 */
+	for i, channel := range expected {
+		decoded := result[i]
+
+		/*
+		 * Check if the number of samples matches.
+		 */
+		if len(decoded) != len(channel) {
+			t.Errorf("%s: Channel %d should hold %d samples, but holds %d.", name, i, len(channel), len(decoded))
+		} else {
+
+			/*
+			 * Compare each sample.
+			 */
+			for j, sample := range channel {
+
+				/*
+				 * Check if sample was restored exactly.
+				 */
+				if decoded[j] != sample {
+					t.Errorf("%s: Channel %d differs at sample %d. Expected: %f Got: %f", name, i, j, sample, decoded[j])
+					break
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Verify that samples written to a FLAC file at each bit depth and channel
+ * count are read back exactly as they were quantized.
+ */
+func TestRoundTrip(t *testing.T) {
+	bitDepths := []uint16{8, 16, 24}
+	channelCounts := []int{1, 2, 3}
+	n := 20000
+
+	/*
+	 * Encode the signal at each bit depth.
+	 */
+	for _, bitDepth := range bitDepths {
+
+		/*
+		 * Encode the signal with each number of channels.
+		 */
+		for _, channelCount := range channelCounts {
+			name := fmt.Sprintf("%d bits, %d channels", bitDepth, channelCount)
+			fd := createTestFile(t)
+			channelCount16 := uint16(channelCount)
+			writer, err := CreateWriter(fd, 48000, bitDepth, channelCount16)
+
+			/*
+			 * Check if writer was created.
+			 */
+			if err != nil {
+				t.Fatalf("%s: Failed to create writer: %s", name, err.Error())
+			}
+
+			channels := createSignal(channelCount, n)
+			writeSignal(t, writer, channels)
+			reader, err := CreateReader(fd)
+
+			/*
+			 * Check if reader was created.
+			 */
+			if err != nil {
+				t.Fatalf("%s: Failed to create reader: %s", name, err.Error())
+			}
+
+			length := reader.Length()
+
+			/*
+			 * The stream info must state the length.
+			 */
+			if length != uint64(n) {
+				t.Errorf("%s: Length should be %d, but is %d.", name, n, length)
+			}
+
+			/*
+			 * Check the format of the stream.
+			 */
+			if (reader.BitDepth() != bitDepth) || (reader.ChannelCount() != channelCount16) || (reader.SampleRate() != 48000) {
+				t.Errorf("%s: Format is %d bits, %d channels at %d Hz.", name, reader.BitDepth(), reader.ChannelCount(), reader.SampleRate())
+			}
+
+			result := readSignal(t, reader, 1000)
+			expected := quantize(channels, bitDepth)
+			compareSignal(t, name, result, expected)
+		}
+
+	}
+
+}
+
+/*
+ * Verify that a FLAC file is smaller than the samples it holds, that it can
+ * be recognized and that reading can start at any sample frame.
+ */
+func TestCompressionAndSeek(t *testing.T) {
+	n := 48000
+	fd := createTestFile(t)
+	writer, err := CreateWriter(fd, 48000, 24, 2)
+
+	/*
+	 * Check if writer was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create writer: %s", err.Error())
+	}
+
+	channels := make([][]float64, 2)
+
+	/*
+	 * Generate a sine wave on each channel.
+	 */
+	for i := range channels {
+		channel := make([]float64, n)
+
+		/*
+		 * Generate each sample.
+		 */
+		for j := range channel {
+			jFloat := float64(j)
+			arg := (2.0 * math.Pi * 440.0 * jFloat) / 48000.0
+			channel[j] = 0.5 * math.Sin(arg)
+		}
+
+		channels[i] = channel
+	}
+
+	writeSignal(t, writer, channels)
+	size, _ := fd.Seek(0, io.SeekEnd)
+	rawSize := int64(n * 2 * 3)
+
+	/*
+	 * A sine wave compresses well.
+	 */
+	if size > (rawSize / 2) {
+		t.Errorf("File should be smaller than %d bytes, but has %d bytes.", rawSize/2, size)
+	}
+
+	fd.Seek(0, io.SeekStart)
+
+	/*
+	 * The signature must be recognized.
+	 */
+	if !Detect(fd) {
+		t.Errorf("%s", "FLAC file was not recognized.")
+	}
+
+	reader, err := CreateReader(fd)
+
+	/*
+	 * Check if reader was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create reader: %s", err.Error())
+	}
+
+	expected := quantize(channels, 24)
+	frames := []uint64{0, 1, 4095, 4096, 30000, 47999}
+	block := [][]float64{make([]float64, 10), make([]float64, 10)}
+
+	/*
+	 * Seek to each frame and read from there.
+	 */
+	for _, frame := range frames {
+		err = reader.Seek(frame)
+
+		/*
+		 * Check if we seeked to the frame.
+		 */
+		if err != nil {
+			t.Fatalf("Failed to seek to frame %d: %s", frame, err.Error())
+		}
+
+		numRead, err := reader.Read(block)
+
+		/*
+		 * Check if block was read.
+		 */
+		if err != nil {
+			t.Fatalf("Failed to read at frame %d: %s", frame, err.Error())
+		}
+
+		/*
+		 * Compare each sample read.
+		 */
+		for i := 0; i < numRead; i++ {
+			idx := int(frame) + i
+
+			/*
+			 * Check if sample matches.
+			 */
+			if block[1][i] != expected[1][idx] {
+				t.Errorf("After seeking to frame %d, sample %d should be %f, but is %f.", frame, idx, expected[1][idx], block[1][i])
+				break
+			}
+
+		}
+
+	}
+
+	err = reader.Seek(uint64(n + 1))
+
+	/*
+	 * Seeking beyond the end must fail.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Seeking beyond the end should fail, but it did not.")
+	}
+
+	wav := bytes.NewReader([]byte("RIFF\x00\x00\x00\x00WAVE"))
+
+	/*
+	 * Other files must not be recognized.
+	 */
+	if Detect(wav) {
+		t.Errorf("%s", "Wave file was recognized as FLAC.")
+	}
+
+}
+
+/*
+ * Verify that a stream written without seeking back, which does not state
+ * its length, can still be read.
+ */
+func TestUnknownLength(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer, err := CreateWriter(buf, 44100, 16, 1)
+
+	/*
+	 * Check if writer was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create writer: %s", err.Error())
+	}
+
+	n := 10000
+	channels := createSignal(1, n)
+	writeSignal(t, writer, channels)
+	data := buf.Bytes()
+	reader, err := CreateReader(bytes.NewReader(data))
+
+	/*
+	 * Check if reader was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create reader: %s", err.Error())
+	}
+
+	length := reader.Length()
+
+	/*
+	 * The length is found by decoding the stream.
+	 */
+	if length != uint64(n) {
+		t.Errorf("Length should be %d, but is %d.", n, length)
+	}
+
+	result := readSignal(t, reader, 4096)
+	expected := quantize(channels, 16)
+	compareSignal(t, "unknown length", result, expected)
+	data[len(data)-1] ^= 0xff
+	reader, err = CreateReader(bytes.NewReader(data))
+
+	/*
+	 * A corrupted frame must be detected.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Reading a corrupted stream should fail, but it did not.")
+	}
+
+	_, err = CreateWriter(buf, 44100, 12, 1)
+
+	/*
+	 * Only whole bytes per sample are supported for encoding.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Creating a writer with 12 bits should fail, but it did not.")
+	}
+
+}
+
+/*
+ * Verify that subframes using linear prediction, which the encoder does not
+ * produce, are decoded.
+ */
+func TestDecodeLPC(t *testing.T) {
+	n := 192
+	samples := make([]int64, n)
+
+	/*
+	 * Generate a sine wave.
+	 */
+	for i := range samples {
+		iFloat := float64(i)
+		samples[i] = int64(10000.0 * math.Sin(0.05*iFloat))
+	}
+
+	/*
+	 * Describe the stream.
+	 */
+	info := streamInfoStruct{
+		minBlockSize: uint16(n),
+		maxBlockSize: uint16(n),
+		sampleRate:   48000,
+		channelCount: 1,
+		bitDepth:     16,
+		totalSamples: uint64(n),
+	}
+
+	stream := encodeHeader(&info)
+	w := bitWriterStruct{}
+	w.writeBits(FRAME_SYNC, 14)
+	w.writeBits(0, 2)
+	w.writeBits(1, 4)
+	w.writeBits(10, 4)
+	w.writeBits(0, 4)
+	w.writeBits(4, 3)
+	w.writeBits(0, 1)
+	w.writeBits(0, 8)
+	w.writeBits(uint64(checksumCRC8(w.bytes())), 8)
+	w.writeBits(0, 1)
+	w.writeBits(SUBFRAME_LPC|1, 6)
+	w.writeBits(0, 1)
+	w.writeSigned(samples[0], 16)
+	w.writeSigned(samples[1], 16)
+	w.writeBits(3, 4)
+	w.writeSigned(1, 5)
+	w.writeSigned(4, 4)
+	w.writeSigned(-2, 4)
+	w.writeBits(RESIDUAL_RICE, 2)
+	w.writeBits(0, 4)
+	w.writeBits(RICE_ESCAPE, 4)
+	w.writeBits(16, 5)
+
+	/*
+	 * Store the residual of the second-order predictor verbatim.
+	 */
+	for i := 2; i < n; i++ {
+		prediction := ((4 * samples[i-1]) - (2 * samples[i-2])) >> 1
+		w.writeSigned(samples[i]-prediction, 16)
+	}
+
+	w.align()
+	w.writeBits(uint64(checksumCRC16(w.bytes())), 16)
+	stream = append(stream, w.bytes()...)
+	reader, err := CreateReader(bytes.NewReader(stream))
+
+	/*
+	 * Check if reader was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create reader: %s", err.Error())
+	}
+
+	result := readSignal(t, reader, 64)
+	expected := make([]float64, n)
+	intsToSamples(samples, 16, expected)
+	compareSignal(t, "lpc", result, [][]float64{expected})
+}
@@ -0,0 +1,1225 @@
+package flac
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/wave"
+	"io"
+	"math/bits"
+)
+
+/*
+ * An interface type representing a FLAC file which is decoded block by
+ * block.
+ *
+ * It provides the same methods as a wave reader, so that both can be used
+ * interchangeably.
+ */
+type Reader interface {
+	BitDepth() uint16
+	ChannelCount() uint16
+	Length() uint64
+	Read(channels [][]float64) (int, error)
+	SampleFormat() uint16
+	SampleRate() uint32
+	Seek(frame uint64) error
+}
+
+/*
+ * The internal data structure representing a FLAC file which is decoded
+ * block by block.
+ *
+ * The samples of the block decoded last are kept until they are read.
+ */
+type readerStruct struct {
+	reader        io.ReadSeeker
+	bits          bitReaderStruct
+	info          streamInfoStruct
+	dataOffset    int64
+	numFrames     uint64
+	position      uint64
+	block         [][]int64
+	blockSize     int
+	blockPosition int
+}
+
+/*
+ * Decodes the stream info.
+ */
+func (this *readerStruct) readStreamInfo() error {
+	r := &this.bits
+	info := &this.info
+
+	/*
+	 * Fields of the stream info and their sizes in bits.
+	 */
+	sizes := []uint{16, 16, 24, 24, 20, 3, 5, 36}
+	values := make([]uint64, len(sizes))
+
+	/*
+	 * Read each field.
+	 */
+	for i, size := range sizes {
+		high := uint64(0)
+
+		/*
+		 * Read long fields in two parts.
+		 */
+		if size > 32 {
+			high, _ = r.readBits(size - 32)
+			size = 32
+		}
+
+		low, err := r.readBits(size)
+
+		/*
+		 * Check if field was read.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to read stream info: %s", msg)
+		}
+
+		values[i] = (high << 32) | low
+	}
+
+	info.minBlockSize = uint16(values[0])
+	info.maxBlockSize = uint16(values[1])
+	info.minFrameSize = uint32(values[2])
+	info.maxFrameSize = uint32(values[3])
+	info.sampleRate = uint32(values[4])
+	info.channelCount = uint16(values[5] + 1)
+	info.bitDepth = uint16(values[6] + 1)
+	info.totalSamples = values[7]
+
+	/*
+	 * Read each byte of the MD5 sum.
+	 */
+	for i := range info.md5 {
+		byt, err := r.readBits(8)
+
+		/*
+		 * Check if byte was read.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to read stream info: %s", msg)
+		}
+
+		info.md5[i] = byte(byt)
+	}
+
+	return nil
+}
+
+/*
+ * Reads the signature and the metadata of the stream, leaving the reader at
+ * the first frame.
+ */
+func (this *readerStruct) readMetadata() error {
+	r := &this.bits
+	magic, err := r.readBits(32)
+
+	/*
+	 * Check if this is a FLAC stream.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return fmt.Errorf("Failed to read signature: %s", msg)
+	} else if magic != MAGIC {
+		return fmt.Errorf("%s", "Not a FLAC stream.")
+	} else {
+		offset := int64(4)
+		foundInfo := false
+		last := false
+
+		/*
+		 * Read each metadata block.
+		 */
+		for !last {
+			header, err := r.readBits(8)
+
+			/*
+			 * Check if header was read.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return fmt.Errorf("Failed to read metadata: %s", msg)
+			}
+
+			size, err := r.readBits(24)
+
+			/*
+			 * Check if size was read.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return fmt.Errorf("Failed to read metadata: %s", msg)
+			}
+
+			last = (header & METADATA_LAST) != 0
+			kind := header &^ METADATA_LAST
+			sizeInt := int(size)
+			size64 := int64(size)
+			offset += SIZE_METADATA_HEADER + size64
+
+			/*
+			 * Decode the stream info and skip all other blocks.
+			 */
+			if (kind == METADATA_STREAMINFO) && (size == SIZE_STREAMINFO) {
+				err = this.readStreamInfo()
+				foundInfo = true
+			} else {
+				_, err = r.reader.Discard(sizeInt)
+			}
+
+			/*
+			 * Check if block was read.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return fmt.Errorf("Failed to read metadata: %s", msg)
+			}
+
+		}
+
+		info := this.info
+
+		/*
+		 * Check if the stream can be decoded.
+		 */
+		if !foundInfo {
+			return fmt.Errorf("%s", "Stream info is missing.")
+		} else if info.sampleRate == 0 {
+			return fmt.Errorf("%s", "Sample rate is invalid.")
+		} else if (info.bitDepth < MIN_BIT_DEPTH) || (info.bitDepth > MAX_BIT_DEPTH) {
+			return fmt.Errorf("Bit depth must be between %d and %d, but is %d.", MIN_BIT_DEPTH, MAX_BIT_DEPTH, info.bitDepth)
+		} else {
+			this.dataOffset = offset
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Reads the number of a frame or sample in the variable-length code FLAC
+ * borrows from UTF-8.
+ */
+func (this *readerStruct) readCodedNumber() (uint64, error) {
+	r := &this.bits
+	first, err := r.readBits(8)
+
+	/*
+	 * Check if first byte was read.
+	 */
+	if err != nil {
+		return 0, err
+	} else {
+		inverted := ^uint8(first)
+		numBytes := bits.LeadingZeros8(inverted)
+
+		/*
+		 * Check if the number fits into a single byte.
+		 */
+		if numBytes == 0 {
+			return first, nil
+		} else if (numBytes == 1) || (numBytes > 7) {
+			return 0, fmt.Errorf("%s", "Invalid frame number.")
+		} else {
+			mask := uint64(0xff) >> uint(numBytes+1)
+			value := first & mask
+
+			/*
+			 * Read each continuation byte.
+			 */
+			for i := 1; i < numBytes; i++ {
+				continuation, err := r.readBits(8)
+
+				/*
+				 * Check if continuation byte is valid.
+				 */
+				if err != nil {
+					return 0, err
+				} else if (continuation & 0xc0) != 0x80 {
+					return 0, fmt.Errorf("%s", "Invalid frame number.")
+				}
+
+				value = (value << 6) | (continuation & 0x3f)
+			}
+
+			return value, nil
+		}
+
+	}
+
+}
+
+/*
+ * Decodes the residual of a subframe and stores it after the warm-up
+ * samples.
+ */
+func (this *readerStruct) readResidual(samples []int64, order int) error {
+	r := &this.bits
+	method, err := r.readBits(2)
+
+	/*
+	 * Check if coding method was read.
+	 */
+	if err != nil {
+		return err
+	} else if method > RESIDUAL_RICE2 {
+		return fmt.Errorf("Unknown residual coding method: %d", method)
+	} else {
+		parameterBits := uint(NUM_BITS_RICE_PARAMETER)
+		escape := uint64(RICE_ESCAPE)
+
+		/*
+		 * The extended coding method uses larger parameters.
+		 */
+		if method == RESIDUAL_RICE2 {
+			parameterBits = NUM_BITS_RICE2_PARAMETER
+			escape = RICE2_ESCAPE
+		}
+
+		partitionOrder, err := r.readBits(4)
+
+		/*
+		 * Check if partition order was read.
+		 */
+		if err != nil {
+			return err
+		} else {
+			n := len(samples)
+			numPartitions := 1 << uint(partitionOrder)
+			partitionSize := n >> uint(partitionOrder)
+
+			/*
+			 * Each partition must hold the same number of samples
+			 * and the first one must at least hold the warm-up.
+			 */
+			if ((n % numPartitions) != 0) || (partitionSize < order) {
+				return fmt.Errorf("Invalid partition order: %d", partitionOrder)
+			} else {
+				offset := order
+
+				/*
+				 * Decode each partition.
+				 */
+				for i := 0; i < numPartitions; i++ {
+					count := partitionSize
+
+					/*
+					 * The first partition does not hold the
+					 * warm-up.
+					 */
+					if i == 0 {
+						count -= order
+					}
+
+					parameter, err := r.readBits(parameterBits)
+
+					/*
+					 * Check if parameter was read.
+					 */
+					if err != nil {
+						return err
+					}
+
+					partition := samples[offset : offset+count]
+
+					/*
+					 * Escaped partitions store their values
+					 * verbatim.
+					 */
+					if parameter == escape {
+						numBits, err := r.readBits(NUM_BITS_ESCAPED_BIT_DEPTH)
+
+						/*
+						 * Check if bit depth was read.
+						 */
+						if err != nil {
+							return err
+						}
+
+						/*
+						 * Read each value.
+						 */
+						for j := range partition {
+							partition[j], err = r.readSigned(uint(numBits))
+
+							/*
+							 * Check if value was read.
+							 */
+							if err != nil {
+								return err
+							}
+
+						}
+
+					} else {
+						parameterUint := uint(parameter)
+
+						/*
+						 * Read each value.
+						 */
+						for j := range partition {
+							partition[j], err = r.readRice(parameterUint)
+
+							/*
+							 * Check if value was read.
+							 */
+							if err != nil {
+								return err
+							}
+
+						}
+
+					}
+
+					offset += count
+				}
+
+				return nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Restores the samples of a subframe from the warm-up and the residual,
+ * using a linear predictor.
+ */
+func predict(samples []int64, coefficients []int64, shift uint) {
+	order := len(coefficients)
+
+	/*
+	 * Add the prediction to the residual of each sample.
+	 */
+	for i := order; i < len(samples); i++ {
+		prediction := int64(0)
+
+		/*
+		 * Apply each coefficient.
+		 */
+		for j, coefficient := range coefficients {
+			prediction += coefficient * samples[i-j-1]
+		}
+
+		samples[i] += prediction >> shift
+	}
+
+}
+
+/*
+ * Reads the samples a predictor starts from.
+ */
+func (this *readerStruct) readWarmup(samples []int64, order int, bitDepth uint) error {
+
+	/*
+	 * The block must hold more samples than the predictor needs.
+	 */
+	if order > len(samples) {
+		return fmt.Errorf("Predictor order %d exceeds block size %d.", order, len(samples))
+	} else {
+
+		/*
+		 * Read each sample.
+		 */
+		for i := 0; i < order; i++ {
+			sample, err := this.bits.readSigned(bitDepth)
+
+			/*
+			 * Check if sample was read.
+			 */
+			if err != nil {
+				return err
+			}
+
+			samples[i] = sample
+		}
+
+		return nil
+	}
+
+}
+
+/*
+ * Decodes the subframe holding the samples of one channel.
+ */
+func (this *readerStruct) readSubframe(samples []int64, bitDepth uint) error {
+	r := &this.bits
+	header, err := r.readBits(8)
+
+	/*
+	 * Check if header was read.
+	 */
+	if err != nil {
+		return err
+	} else if (header & 0x80) != 0 {
+		return fmt.Errorf("%s", "Invalid subframe header.")
+	} else {
+		kind := uint8(header >> 1)
+		wastedBits := uint(0)
+
+		/*
+		 * Read the number of bits dropped from each sample.
+		 */
+		if (header & 0x01) != 0 {
+			k, err := r.readUnary()
+
+			/*
+			 * Check if number of bits was read.
+			 */
+			if err != nil {
+				return err
+			}
+
+			wastedBits = uint(k) + 1
+		}
+
+		/*
+		 * Check if the number of bits dropped is valid.
+		 */
+		if wastedBits >= bitDepth {
+			return fmt.Errorf("Invalid number of wasted bits: %d", wastedBits)
+		}
+
+		bitDepth -= wastedBits
+
+		/*
+		 * Decode the samples according to the type of the subframe.
+		 */
+		switch {
+		case kind == SUBFRAME_CONSTANT:
+			value, err := r.readSigned(bitDepth)
+
+			/*
+			 * Check if value was read.
+			 */
+			if err != nil {
+				return err
+			}
+
+			/*
+			 * Each sample holds the same value.
+			 */
+			for i := range samples {
+				samples[i] = value
+			}
+
+		case kind == SUBFRAME_VERBATIM:
+			err = this.readWarmup(samples, len(samples), bitDepth)
+		case (kind >= SUBFRAME_FIXED) && (kind <= SUBFRAME_FIXED+MAX_FIXED_ORDER):
+			order := int(kind - SUBFRAME_FIXED)
+			err = this.readWarmup(samples, order, bitDepth)
+
+			/*
+			 * Check if warm-up was read.
+			 */
+			if err == nil {
+				err = this.readResidual(samples, order)
+
+				/*
+				 * Check if residual was read.
+				 */
+				if err == nil {
+					predict(samples, g_fixedCoefficients[order], 0)
+				}
+
+			}
+
+		case kind >= SUBFRAME_LPC:
+			order := int(kind&0x1f) + 1
+			err = this.readWarmup(samples, order, bitDepth)
+
+			/*
+			 * Check if warm-up was read.
+			 */
+			if err != nil {
+				return err
+			}
+
+			precision, errPrecision := r.readBits(4)
+
+			/*
+			 * Check if precision is valid.
+			 */
+			if errPrecision != nil {
+				return errPrecision
+			} else if precision == 0x0f {
+				return fmt.Errorf("%s", "Invalid coefficient precision.")
+			}
+
+			shift, errShift := r.readSigned(5)
+
+			/*
+			 * Check if shift is valid.
+			 */
+			if errShift != nil {
+				return errShift
+			} else if shift < 0 {
+				return fmt.Errorf("Invalid coefficient shift: %d", shift)
+			}
+
+			coefficients := make([]int64, order)
+			precisionBits := uint(precision) + 1
+
+			/*
+			 * Read each coefficient.
+			 */
+			for i := range coefficients {
+				coefficients[i], err = r.readSigned(precisionBits)
+
+				/*
+				 * Check if coefficient was read.
+				 */
+				if err != nil {
+					return err
+				}
+
+			}
+
+			err = this.readResidual(samples, order)
+
+			/*
+			 * Check if residual was read.
+			 */
+			if err == nil {
+				predict(samples, coefficients, uint(shift))
+			}
+
+		default:
+			err = fmt.Errorf("Reserved subframe type: %#02x", kind)
+		}
+
+		/*
+		 * Check if samples were decoded.
+		 */
+		if err != nil {
+			return err
+		} else {
+
+			/*
+			 * Restore the bits dropped from each sample.
+			 */
+			if wastedBits > 0 {
+
+				/*
+				 * Shift each sample.
+				 */
+				for i, sample := range samples {
+					samples[i] = sample << wastedBits
+				}
+
+			}
+
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Decodes the header of a frame. Returns the block size, the channel
+ * assignment and the bit depth.
+ */
+func (this *readerStruct) readFrameHeader() (int, uint8, uint16, error) {
+	r := &this.bits
+	r.resetChecksums()
+	sync, err := r.readBits(14)
+
+	/*
+	 * Check if we reached the end of the stream.
+	 */
+	if err == io.EOF {
+		return 0, 0, 0, io.EOF
+	} else if err != nil {
+		return 0, 0, 0, err
+	} else if sync != FRAME_SYNC {
+		return 0, 0, 0, fmt.Errorf("%s", "Lost frame synchronization.")
+	} else {
+
+		/*
+		 * Fields of the frame header and their sizes in bits.
+		 */
+		sizes := []uint{2, 4, 4, 4, 3, 1}
+		values := make([]uint64, len(sizes))
+
+		/*
+		 * Read each field.
+		 */
+		for i, size := range sizes {
+			values[i], err = r.readBits(size)
+
+			/*
+			 * Check if field was read.
+			 */
+			if err != nil {
+				return 0, 0, 0, err
+			}
+
+		}
+
+		sizeCode := values[1]
+		rateCode := values[2]
+		assignment := uint8(values[3])
+		depthCode := values[4]
+		_, err = this.readCodedNumber()
+
+		/*
+		 * Check if frame number was read.
+		 */
+		if err != nil {
+			return 0, 0, 0, err
+		}
+
+		blockSize := g_blockSizes[sizeCode]
+
+		/*
+		 * Read the block size, if it has no code of its own.
+		 */
+		switch sizeCode {
+		case 0:
+			return 0, 0, 0, fmt.Errorf("%s", "Reserved block size.")
+		case BLOCK_SIZE_CODE_8BIT:
+			size, err := r.readBits(8)
+			blockSize = int(size) + 1
+
+			/*
+			 * Check if block size was read.
+			 */
+			if err != nil {
+				return 0, 0, 0, err
+			}
+
+		case BLOCK_SIZE_CODE_16BIT:
+			size, err := r.readBits(16)
+			blockSize = int(size) + 1
+
+			/*
+			 * Check if block size was read.
+			 */
+			if err != nil {
+				return 0, 0, 0, err
+			}
+
+		}
+
+		/*
+		 * Skip the sample rate, if it has no code of its own. The rate
+		 * of the stream info applies anyway.
+		 */
+		switch rateCode {
+		case SAMPLE_RATE_CODE_KHZ:
+			_, err = r.readBits(8)
+		case SAMPLE_RATE_CODE_HZ, SAMPLE_RATE_CODE_TENS:
+			_, err = r.readBits(16)
+		case 0x0f:
+			err = fmt.Errorf("%s", "Invalid sample rate.")
+		}
+
+		/*
+		 * Check if sample rate was read.
+		 */
+		if err != nil {
+			return 0, 0, 0, err
+		}
+
+		expected := r.crc8
+		crc8, err := r.readBits(8)
+
+		/*
+		 * Check if header is intact.
+		 */
+		if err != nil {
+			return 0, 0, 0, err
+		} else if uint8(crc8) != expected {
+			return 0, 0, 0, fmt.Errorf("%s", "Frame header is corrupted.")
+		} else {
+			bitDepth := this.info.bitDepth
+
+			/*
+			 * Check if the frame has a bit depth of its own.
+			 */
+			if depthCode == 0x03 {
+				return 0, 0, 0, fmt.Errorf("%s", "Reserved bit depth.")
+			} else if depthCode != 0 {
+				bitDepth = g_bitDepths[depthCode]
+			}
+
+			return blockSize, assignment, bitDepth, nil
+		}
+
+	}
+
+}
+
+/*
+ * Decodes the next frame into the block buffers. Returns io.EOF at the end
+ * of the stream.
+ */
+func (this *readerStruct) readFrame() error {
+	blockSize, assignment, bitDepth, err := this.readFrameHeader()
+
+	/*
+	 * Check if frame header was read.
+	 */
+	if err != nil {
+		return err
+	} else {
+		channelCount := int(this.info.channelCount)
+		frameChannels := int(assignment) + 1
+
+		/*
+		 * Stereo frames may store a side signal.
+		 */
+		if assignment >= CHANNELS_LEFT_SIDE {
+			frameChannels = 2
+		}
+
+		/*
+		 * Check if the channel assignment matches the stream.
+		 */
+		if (assignment > CHANNELS_MID_SIDE) || (frameChannels != channelCount) {
+			return fmt.Errorf("Invalid channel assignment: %d", assignment)
+		} else {
+
+			/*
+			 * Decode the subframe of each channel.
+			 */
+			for i, block := range this.block {
+
+				/*
+				 * Make sure the block buffer is large enough.
+				 */
+				if cap(block) < blockSize {
+					block = make([]int64, blockSize)
+				}
+
+				block = block[0:blockSize]
+				this.block[i] = block
+				channelDepth := uint(bitDepth)
+
+				/*
+				 * The side signal needs an extra bit.
+				 */
+				if ((assignment == CHANNELS_LEFT_SIDE) && (i == 1)) || ((assignment == CHANNELS_RIGHT_SIDE) && (i == 0)) || ((assignment == CHANNELS_MID_SIDE) && (i == 1)) {
+					channelDepth++
+				}
+
+				err = this.readSubframe(block, channelDepth)
+
+				/*
+				 * Check if subframe was decoded.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return fmt.Errorf("Failed to decode subframe: %s", msg)
+				}
+
+			}
+
+			r := &this.bits
+			r.align()
+			expected := r.crc16
+			crc16, err := r.readBits(16)
+
+			/*
+			 * Check if frame is intact.
+			 */
+			if err != nil {
+				return err
+			} else if uint16(crc16) != expected {
+				return fmt.Errorf("%s", "Frame is corrupted.")
+			} else {
+
+				/*
+				 * Restore left and right channel from the side
+				 * signal.
+				 */
+				switch assignment {
+				case CHANNELS_LEFT_SIDE:
+					left := this.block[0]
+					side := this.block[1]
+
+					/*
+					 * Restore each sample of the right channel.
+					 */
+					for i, l := range left {
+						side[i] = l - side[i]
+					}
+
+				case CHANNELS_RIGHT_SIDE:
+					side := this.block[0]
+					right := this.block[1]
+
+					/*
+					 * Restore each sample of the left channel.
+					 */
+					for i, r := range right {
+						side[i] += r
+					}
+
+				case CHANNELS_MID_SIDE:
+					mid := this.block[0]
+					side := this.block[1]
+
+					/*
+					 * Restore each sample of both channels.
+					 */
+					for i, m := range mid {
+						s := side[i]
+						m = (m << 1) | (s & 1)
+						mid[i] = (m + s) >> 1
+						side[i] = (m - s) >> 1
+					}
+
+				}
+
+				this.blockSize = blockSize
+				this.blockPosition = 0
+				return nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Moves back to the first frame.
+ */
+func (this *readerStruct) rewind() error {
+	_, err := this.reader.Seek(this.dataOffset, io.SeekStart)
+
+	/*
+	 * Check if we seeked to the first frame.
+	 */
+	if err != nil {
+		return err
+	} else {
+		this.bits.reader.Reset(this.reader)
+		this.bits.accumulator = 0
+		this.bits.numBits = 0
+		this.position = 0
+		this.blockSize = 0
+		this.blockPosition = 0
+		return nil
+	}
+
+}
+
+/*
+ * Returns the bit depth of the FLAC file.
+ */
+func (this *readerStruct) BitDepth() uint16 {
+	return this.info.bitDepth
+}
+
+/*
+ * Returns the number of channels of the FLAC file.
+ */
+func (this *readerStruct) ChannelCount() uint16 {
+	return this.info.channelCount
+}
+
+/*
+ * Returns the number of sample frames in the FLAC file.
+ */
+func (this *readerStruct) Length() uint64 {
+	return this.numFrames
+}
+
+/*
+ * Decodes the next block of samples into a slice for each channel.
+ *
+ * All channels must hold the same number of samples, which determines the
+ * size of the block. Returns the number of samples decoded into each channel,
+ * which is less than the size of the block at the end of the file. Returns
+ * io.EOF when no samples are left.
+ */
+func (this *readerStruct) Read(channels [][]float64) (int, error) {
+	channelCount := len(channels)
+	expectedChannelCount := int(this.info.channelCount)
+
+	/*
+	 * Check if we got the right number of channels.
+	 */
+	if channelCount != expectedChannelCount {
+		return 0, fmt.Errorf("Expected %d channels, but got %d.", expectedChannelCount, channelCount)
+	} else {
+		blockLength := len(channels[0])
+
+		/*
+		 * Make sure all channels hold the same number of samples.
+		 */
+		for i, channel := range channels {
+			channelLength := len(channel)
+
+			/*
+			 * Check if channel holds the right number of samples.
+			 */
+			if channelLength != blockLength {
+				return 0, fmt.Errorf("Channel %d holds %d samples, but channel 0 holds %d samples.", i, channelLength, blockLength)
+			}
+
+		}
+
+		bitDepth := this.info.bitDepth
+		written := 0
+		done := false
+
+		/*
+		 * Copy samples until the block is full or the file ends.
+		 */
+		for !done && (written < blockLength) && (this.position < this.numFrames) {
+
+			/*
+			 * Decode the next frame once all samples of the
+			 * current one are read.
+			 */
+			if this.blockPosition >= this.blockSize {
+				err := this.readFrame()
+
+				/*
+				 * Check if frame was decoded.
+				 */
+				if err == io.EOF {
+					done = true
+				} else if err != nil {
+					msg := err.Error()
+					return 0, fmt.Errorf("Failed to decode frame: %s", msg)
+				}
+
+			}
+
+			/*
+			 * Copy samples from the current frame.
+			 */
+			if !done {
+				count := this.blockSize - this.blockPosition
+				remaining := blockLength - written
+				remainingFrames := this.numFrames - this.position
+
+				/*
+				 * Do not copy more samples than requested.
+				 */
+				if count > remaining {
+					count = remaining
+				}
+
+				/*
+				 * Do not copy beyond the end of the file.
+				 */
+				if uint64(count) > remainingFrames {
+					count = int(remainingFrames)
+				}
+
+				lBound := this.blockPosition
+				uBound := lBound + count
+
+				/*
+				 * Convert the samples of each channel.
+				 */
+				for i, channel := range channels {
+					source := this.block[i][lBound:uBound]
+					target := channel[written : written+count]
+					intsToSamples(source, bitDepth, target)
+				}
+
+				this.blockPosition = uBound
+				this.position += uint64(count)
+				written += count
+			}
+
+		}
+
+		/*
+		 * Check if there were any samples left.
+		 */
+		if (written == 0) && (blockLength > 0) {
+			return 0, io.EOF
+		} else {
+			return written, nil
+		}
+
+	}
+
+}
+
+/*
+ * Returns the sample format of the FLAC file, which is always linear PCM.
+ */
+func (this *readerStruct) SampleFormat() uint16 {
+	return wave.AUDIO_PCM
+}
+
+/*
+ * Returns the sample rate of the FLAC file.
+ */
+func (this *readerStruct) SampleRate() uint32 {
+	return this.info.sampleRate
+}
+
+/*
+ * Moves to a certain sample frame, so that the next block is decoded starting
+ * from there.
+ *
+ * Since frames do not start at fixed offsets, this decodes the file from the
+ * beginning up to the frame.
+ */
+func (this *readerStruct) Seek(frame uint64) error {
+
+	/*
+	 * Check if the frame is inside the file.
+	 */
+	if frame > this.numFrames {
+		return fmt.Errorf("Cannot seek to frame %d, since file only holds %d frames.", frame, this.numFrames)
+	} else {
+		err := this.rewind()
+
+		/*
+		 * Check if we moved back to the first frame.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to seek to frame %d: %s", frame, msg)
+		} else {
+
+			/*
+			 * Decode frames until we reach the one holding the
+			 * sample frame.
+			 */
+			for this.position < frame {
+				err = this.readFrame()
+
+				/*
+				 * Check if frame was decoded.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return fmt.Errorf("Failed to seek to frame %d: %s", frame, msg)
+				}
+
+				blockSize64 := uint64(this.blockSize)
+				remaining := frame - this.position
+
+				/*
+				 * Check if the frame holds the sample frame.
+				 */
+				if remaining < blockSize64 {
+					this.blockPosition = int(remaining)
+					this.position = frame
+				} else {
+					this.blockPosition = this.blockSize
+					this.position += blockSize64
+				}
+
+			}
+
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Counts the sample frames of a stream which does not state its length, by
+ * decoding it entirely.
+ */
+func (this *readerStruct) countFrames() (uint64, error) {
+	numFrames := uint64(0)
+
+	/*
+	 * Decode each frame.
+	 */
+	for {
+		err := this.readFrame()
+
+		/*
+		 * Check if we reached the end of the stream.
+		 */
+		if err == io.EOF {
+			return numFrames, this.rewind()
+		} else if err != nil {
+			return 0, err
+		}
+
+		numFrames += uint64(this.blockSize)
+	}
+
+}
+
+/*
+ * Creates a FLAC file, which is decoded block by block from an underlying
+ * reader.
+ *
+ * Only the metadata is read immediately. Frames are decoded on demand, so
+ * that even very long files can be processed without holding all of their
+ * samples in memory.
+ */
+func CreateReader(r io.ReadSeeker) (Reader, error) {
+	_, err := r.Seek(0, io.SeekStart)
+
+	/*
+	 * Check if we seeked to the beginning.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, fmt.Errorf("Failed to seek to beginning of file: %s", msg)
+	} else {
+
+		/*
+		 * Create FLAC reader structure.
+		 */
+		reader := readerStruct{
+			reader: r,
+			bits: bitReaderStruct{
+				reader: bufio.NewReader(r),
+			},
+		}
+
+		err = reader.readMetadata()
+
+		/*
+		 * Check if metadata was read.
+		 */
+		if err != nil {
+			return nil, err
+		} else {
+			channelCount := reader.info.channelCount
+			reader.block = make([][]int64, channelCount)
+			numFrames := reader.info.totalSamples
+
+			/*
+			 * If the stream does not state its length, find out by
+			 * decoding it.
+			 */
+			if numFrames == 0 {
+				numFrames, err = reader.countFrames()
+			}
+
+			/*
+			 * Check if length was found.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to determine length of stream: %s", msg)
+			} else {
+				reader.numFrames = numFrames
+				return &reader, nil
+			}
+
+		}
+
+	}
+
+}
@@ -0,0 +1,1048 @@
+package flac
+
+import (
+	"crypto/md5"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"math/bits"
+)
+
+/*
+ * An interface type representing a FLAC file which is written incrementally.
+ */
+type Writer interface {
+	Close() error
+	Write(channels [][]float64) error
+}
+
+/*
+ * Data structure describing how a subframe, which holds the samples of one
+ * channel within a frame, is encoded.
+ */
+type subframeStruct struct {
+	kind           uint8
+	order          int
+	wastedBits     uint
+	bitDepth       uint
+	samples        []int64
+	residual       []int64
+	method         uint8
+	partitionOrder uint
+	parameters     []uint
+	size           uint64
+}
+
+/*
+ * The internal data structure representing a FLAC file which is written
+ * incrementally.
+ *
+ * Samples are collected until a block is complete, which is then encoded as
+ * a frame.
+ */
+type writerStruct struct {
+	writer      io.Writer
+	info        streamInfoStruct
+	hash        hash.Hash
+	pending     [][]int64
+	numPending  int
+	numBlocks   uint64
+	lastBlock   int
+	frame       bitWriterStruct
+	checksumBuf []byte
+}
+
+/*
+ * Coefficients of the fixed predictors, indexed by their order.
+ */
+var g_fixedCoefficients = [][]int64{
+	[]int64{},
+	[]int64{1},
+	[]int64{2, -1},
+	[]int64{3, -3, 1},
+	[]int64{4, -6, 4, -1},
+}
+
+/*
+ * Encodes the stream info, preceded by the signature of the stream.
+ */
+func encodeHeader(info *streamInfoStruct) []byte {
+	w := bitWriterStruct{}
+	w.writeBits(MAGIC, 32)
+	w.writeBits(METADATA_LAST|METADATA_STREAMINFO, 8)
+	w.writeBits(SIZE_STREAMINFO, 24)
+	w.writeBits(uint64(info.minBlockSize), 16)
+	w.writeBits(uint64(info.maxBlockSize), 16)
+	w.writeBits(uint64(info.minFrameSize), 24)
+	w.writeBits(uint64(info.maxFrameSize), 24)
+	w.writeBits(uint64(info.sampleRate), 20)
+	w.writeBits(uint64(info.channelCount-1), 3)
+	w.writeBits(uint64(info.bitDepth-1), 5)
+	w.writeBits(info.totalSamples, 36)
+
+	/*
+	 * Write each byte of the MD5 sum.
+	 */
+	for _, byt := range info.md5 {
+		w.writeBits(uint64(byt), 8)
+	}
+
+	return w.bytes()
+}
+
+/*
+ * Writes the number of a frame in the variable-length code FLAC borrows from
+ * UTF-8.
+ */
+func writeCodedNumber(w *bitWriterStruct, value uint64) {
+
+	/*
+	 * Small numbers fit into a single byte.
+	 */
+	if value < 0x80 {
+		w.writeBits(value, 8)
+	} else {
+		numBytes := uint(2)
+
+		/*
+		 * Each additional byte holds five more bits.
+		 */
+		for (value >> ((5 * numBytes) + 1)) != 0 {
+			numBytes++
+		}
+
+		numContinuation := numBytes - 1
+		prefix := uint64(0xff<<(8-numBytes)) & 0xff
+		first := prefix | (value >> (6 * numContinuation))
+		w.writeBits(first, 8)
+
+		/*
+		 * Write each continuation byte.
+		 */
+		for i := int(numContinuation) - 1; i >= 0; i-- {
+			shift := uint(6 * i)
+			continuation := 0x80 | ((value >> shift) & 0x3f)
+			w.writeBits(continuation, 8)
+		}
+
+	}
+
+}
+
+/*
+ * Finds the Rice parameters which encode the residual of a subframe in the
+ * fewest bits, trying each way of splitting the residual into partitions.
+ *
+ * The size of each partition is estimated from the sum of its values, so
+ * that the residual needs to be examined only once.
+ */
+func planResidual(sf *subframeStruct, blockSize int) {
+	order := sf.order
+	maxOrder := uint(0)
+
+	/*
+	 * Find the largest number of partitions the block can be split into.
+	 */
+	for p := uint(1); p <= MAX_PARTITION_ORDER; p++ {
+		numPartitions := 1 << p
+
+		/*
+		 * Each partition must hold the same number of samples, the first
+		 * one at least one sample after the warm-up.
+		 */
+		if ((blockSize % numPartitions) == 0) && ((blockSize >> p) > order) {
+			maxOrder = p
+		}
+
+	}
+
+	numFine := 1 << maxOrder
+	fineSize := blockSize >> maxOrder
+	sums := make([]uint64, numFine)
+	counts := make([]uint64, numFine)
+
+	/*
+	 * Sum up the residual of each of the smallest partitions.
+	 */
+	for i, value := range sf.residual {
+		idx := (i + order) / fineSize
+		sums[idx] += zigzag(value)
+		counts[idx]++
+	}
+
+	bestSize := uint64(math.MaxUint64)
+
+	/*
+	 * Try each number of partitions, merging neighbouring partitions
+	 * to go from one to the next.
+	 */
+	for p := int(maxOrder); p >= 0; p-- {
+		numPartitions := 1 << uint(p)
+
+		/*
+		 * Merge neighbouring partitions.
+		 */
+		if numPartitions < len(sums) {
+
+			/*
+			 * Merge each pair of partitions.
+			 */
+			for i := 0; i < numPartitions; i++ {
+				sums[i] = sums[2*i] + sums[(2*i)+1]
+				counts[i] = counts[2*i] + counts[(2*i)+1]
+			}
+
+			sums = sums[0:numPartitions]
+			counts = counts[0:numPartitions]
+		}
+
+		parameters := make([]uint, numPartitions)
+		method := uint8(RESIDUAL_RICE)
+		size := uint64(0)
+
+		/*
+		 * Find the best parameter for each partition.
+		 */
+		for i, sum := range sums {
+			count := counts[i]
+			bestParameter := uint(0)
+			bestPartitionSize := uint64(math.MaxUint64)
+
+			/*
+			 * Try each parameter.
+			 */
+			for k := uint(0); k <= MAX_RICE2_PARAMETER; k++ {
+				partitionSize := (count * uint64(k+1)) + (sum >> k)
+
+				/*
+				 * Check if parameter is better.
+				 */
+				if partitionSize < bestPartitionSize {
+					bestParameter = k
+					bestPartitionSize = partitionSize
+				}
+
+			}
+
+			/*
+			 * Large parameters need the extended coding method.
+			 */
+			if bestParameter > MAX_RICE_PARAMETER {
+				method = RESIDUAL_RICE2
+			}
+
+			parameters[i] = bestParameter
+			size += bestPartitionSize
+		}
+
+		parameterBits := uint64(NUM_BITS_RICE_PARAMETER)
+
+		/*
+		 * The extended coding method uses larger parameters.
+		 */
+		if method == RESIDUAL_RICE2 {
+			parameterBits = NUM_BITS_RICE2_PARAMETER
+		}
+
+		numPartitions64 := uint64(numPartitions)
+		size += 6 + (numPartitions64 * parameterBits)
+
+		/*
+		 * Check if this number of partitions is better.
+		 */
+		if size < bestSize {
+			bestSize = size
+			sf.method = method
+			sf.partitionOrder = uint(p)
+			sf.parameters = parameters
+		}
+
+	}
+
+	sf.size += bestSize
+}
+
+/*
+ * Decides how to encode the samples of one channel within a frame, trying a
+ * constant value, each fixed predictor and storing the samples verbatim.
+ */
+func planSubframe(samples []int64, bitDepth uint) *subframeStruct {
+	n := len(samples)
+	n64 := uint64(n)
+	first := samples[0]
+	constant := true
+	combined := int64(0)
+
+	/*
+	 * Check if the samples are constant and find the bits they share.
+	 */
+	for _, sample := range samples {
+		constant = constant && (sample == first)
+		combined |= sample
+	}
+
+	/*
+	 * A constant signal is stored as a single value.
+	 */
+	if constant {
+
+		/*
+		 * Encode a constant subframe.
+		 */
+		sf := subframeStruct{
+			kind:     SUBFRAME_CONSTANT,
+			bitDepth: bitDepth,
+			samples:  samples,
+			size:     8 + uint64(bitDepth),
+		}
+
+		return &sf
+	} else {
+		wastedBits := uint(bits.TrailingZeros64(uint64(combined)))
+		shifted := samples
+
+		/*
+		 * Drop the lowest bits if they are zero in all samples.
+		 */
+		if wastedBits > 0 {
+			shifted = make([]int64, n)
+
+			/*
+			 * Shift each sample.
+			 */
+			for i, sample := range samples {
+				shifted[i] = sample >> wastedBits
+			}
+
+		}
+
+		effectiveDepth := bitDepth - wastedBits
+		headerSize := 8 + uint64(wastedBits)
+
+		/*
+		 * Start with storing the samples verbatim.
+		 */
+		best := &subframeStruct{
+			kind:       SUBFRAME_VERBATIM,
+			wastedBits: wastedBits,
+			bitDepth:   effectiveDepth,
+			samples:    shifted,
+			size:       headerSize + (n64 * uint64(effectiveDepth)),
+		}
+
+		/*
+		 * Try each fixed predictor.
+		 */
+		for order, coefficients := range g_fixedCoefficients {
+
+			/*
+			 * The predictor needs more samples than its order.
+			 */
+			if order < n {
+				residual := make([]int64, n-order)
+				fits := true
+
+				/*
+				 * Calculate the difference between each sample
+				 * and its prediction.
+				 */
+				for i := order; i < n; i++ {
+					prediction := int64(0)
+
+					/*
+					 * Apply each coefficient.
+					 */
+					for j, coefficient := range coefficients {
+						prediction += coefficient * shifted[i-j-1]
+					}
+
+					value := shifted[i] - prediction
+					fits = fits && (value >= math.MinInt32) && (value <= math.MaxInt32)
+					residual[i-order] = value
+				}
+
+				/*
+				 * The residual must fit into 32 bits.
+				 */
+				if fits {
+					order64 := uint64(order)
+
+					/*
+					 * Encode a fixed subframe.
+					 */
+					sf := &subframeStruct{
+						kind:       SUBFRAME_FIXED,
+						order:      order,
+						wastedBits: wastedBits,
+						bitDepth:   effectiveDepth,
+						samples:    shifted,
+						residual:   residual,
+						size:       headerSize + (order64 * uint64(effectiveDepth)),
+					}
+
+					planResidual(sf, n)
+
+					/*
+					 * Check if predictor is better.
+					 */
+					if sf.size < best.size {
+						best = sf
+					}
+
+				}
+
+			}
+
+		}
+
+		return best
+	}
+
+}
+
+/*
+ * Writes a subframe as planned.
+ */
+func writeSubframe(w *bitWriterStruct, sf *subframeStruct) {
+	kind := uint64(sf.kind)
+
+	/*
+	 * The type of a fixed subframe includes the order of the predictor.
+	 */
+	if sf.kind == SUBFRAME_FIXED {
+		kind |= uint64(sf.order)
+	}
+
+	w.writeBits(0, 1)
+	w.writeBits(kind, 6)
+	wastedBits := sf.wastedBits
+
+	/*
+	 * Write the number of bits dropped from each sample.
+	 */
+	if wastedBits > 0 {
+		w.writeBits(1, 1)
+		w.writeUnary(uint64(wastedBits - 1))
+	} else {
+		w.writeBits(0, 1)
+	}
+
+	bitDepth := sf.bitDepth
+
+	/*
+	 * Write the samples according to the type of the subframe.
+	 */
+	switch sf.kind {
+	case SUBFRAME_CONSTANT:
+		w.writeSigned(sf.samples[0], bitDepth)
+	case SUBFRAME_VERBATIM:
+
+		/*
+		 * Write each sample.
+		 */
+		for _, sample := range sf.samples {
+			w.writeSigned(sample, bitDepth)
+		}
+
+	case SUBFRAME_FIXED:
+		order := sf.order
+
+		/*
+		 * Write the samples the predictor starts from.
+		 */
+		for _, sample := range sf.samples[0:order] {
+			w.writeSigned(sample, bitDepth)
+		}
+
+		method := sf.method
+		w.writeBits(uint64(method), 2)
+		w.writeBits(uint64(sf.partitionOrder), 4)
+		parameterBits := uint(NUM_BITS_RICE_PARAMETER)
+
+		/*
+		 * The extended coding method uses larger parameters.
+		 */
+		if method == RESIDUAL_RICE2 {
+			parameterBits = NUM_BITS_RICE2_PARAMETER
+		}
+
+		n := len(sf.samples)
+		partitionSize := n >> sf.partitionOrder
+		offset := 0
+
+		/*
+		 * Write each partition of the residual.
+		 */
+		for i, parameter := range sf.parameters {
+			count := partitionSize
+
+			/*
+			 * The first partition does not hold the warm-up.
+			 */
+			if i == 0 {
+				count -= order
+			}
+
+			w.writeBits(uint64(parameter), parameterBits)
+
+			/*
+			 * Write each value of the partition.
+			 */
+			for _, value := range sf.residual[offset : offset+count] {
+				w.writeRice(value, parameter)
+			}
+
+			offset += count
+		}
+
+	}
+
+}
+
+/*
+ * Returns the code of a block size in the frame header, and the number of
+ * bits the block size needs at the end of the header, if any.
+ */
+func blockSizeCode(blockSize int) (uint64, uint) {
+
+	/*
+	 * Look for a block size which has its own code.
+	 */
+	for code, size := range g_blockSizes {
+
+		/*
+		 * Check if we found the block size.
+		 */
+		if size == blockSize {
+			return uint64(code), 0
+		}
+
+	}
+
+	/*
+	 * Store the block size explicitly.
+	 */
+	if blockSize <= 256 {
+		return BLOCK_SIZE_CODE_8BIT, 8
+	} else {
+		return BLOCK_SIZE_CODE_16BIT, 16
+	}
+
+}
+
+/*
+ * Returns the code of a sample rate in the frame header, the value stored at
+ * the end of the header and the number of bits it needs, if any.
+ */
+func sampleRateCode(sampleRate uint32) (uint64, uint64, uint) {
+
+	/*
+	 * Look for a sample rate which has its own code.
+	 */
+	for code, rate := range g_sampleRates {
+
+		/*
+		 * Check if we found the sample rate.
+		 */
+		if (rate != 0) && (rate == sampleRate) {
+			return uint64(code), 0, 0
+		}
+
+	}
+
+	sampleRate64 := uint64(sampleRate)
+
+	/*
+	 * Store the sample rate explicitly, if possible.
+	 */
+	if ((sampleRate % 1000) == 0) && (sampleRate <= 255000) {
+		return SAMPLE_RATE_CODE_KHZ, sampleRate64 / 1000, 8
+	} else if sampleRate <= math.MaxUint16 {
+		return SAMPLE_RATE_CODE_HZ, sampleRate64, 16
+	} else if (sampleRate % 10) == 0 {
+		return SAMPLE_RATE_CODE_TENS, sampleRate64 / 10, 16
+	} else {
+		return 0, 0, 0
+	}
+
+}
+
+/*
+ * Returns the code of a bit depth in the frame header.
+ */
+func bitDepthCode(bitDepth uint16) uint64 {
+
+	/*
+	 * Look for the bit depth.
+	 */
+	for code, depth := range g_bitDepths {
+
+		/*
+		 * Check if we found the bit depth.
+		 */
+		if (depth != 0) && (depth == bitDepth) {
+			return uint64(code)
+		}
+
+	}
+
+	return 0
+}
+
+/*
+ * Decides how to encode the channels of a frame. Stereo frames may store
+ * the difference between both channels instead of one of them.
+ */
+func planChannels(channels [][]int64, bitDepth uint) (uint64, []*subframeStruct) {
+	channelCount := len(channels)
+	subframes := make([]*subframeStruct, channelCount)
+
+	/*
+	 * Plan each channel independently.
+	 */
+	for i, channel := range channels {
+		subframes[i] = planSubframe(channel, bitDepth)
+	}
+
+	assignment := uint64(channelCount - 1)
+
+	/*
+	 * Try to encode the channels of a stereo frame as mid and side
+	 * signal, or as one channel and the side signal.
+	 */
+	if channelCount == 2 {
+		left := channels[0]
+		right := channels[1]
+		n := len(left)
+		mid := make([]int64, n)
+		side := make([]int64, n)
+
+		/*
+		 * Calculate the mid and side signal.
+		 */
+		for i, l := range left {
+			r := right[i]
+			mid[i] = (l + r) >> 1
+			side[i] = l - r
+		}
+
+		leftPlan := subframes[0]
+		rightPlan := subframes[1]
+		midPlan := planSubframe(mid, bitDepth)
+		sidePlan := planSubframe(side, bitDepth+1)
+		bestSize := leftPlan.size + rightPlan.size
+
+		/*
+		 * Check if left and side signal are smaller.
+		 */
+		if (leftPlan.size + sidePlan.size) < bestSize {
+			bestSize = leftPlan.size + sidePlan.size
+			assignment = CHANNELS_LEFT_SIDE
+			subframes = []*subframeStruct{leftPlan, sidePlan}
+		}
+
+		/*
+		 * Check if side and right signal are smaller.
+		 */
+		if (sidePlan.size + rightPlan.size) < bestSize {
+			bestSize = sidePlan.size + rightPlan.size
+			assignment = CHANNELS_RIGHT_SIDE
+			subframes = []*subframeStruct{sidePlan, rightPlan}
+		}
+
+		/*
+		 * Check if mid and side signal are smaller.
+		 */
+		if (midPlan.size + sidePlan.size) < bestSize {
+			assignment = CHANNELS_MID_SIDE
+			subframes = []*subframeStruct{midPlan, sidePlan}
+		}
+
+	}
+
+	return assignment, subframes
+}
+
+/*
+ * Feeds the samples of a block to the MD5 sum, interleaved and in little
+ * endian byte order, as the stream info requires.
+ */
+func (this *writerStruct) updateHash(channels [][]int64) {
+	bytesPerSample := int(this.info.bitDepth / 8)
+	channelCount := len(channels)
+	n := len(channels[0])
+	size := n * channelCount * bytesPerSample
+	buf := this.checksumBuf
+
+	/*
+	 * Make sure the buffer has the correct size.
+	 */
+	if len(buf) != size {
+		buf = make([]byte, size)
+		this.checksumBuf = buf
+	}
+
+	offset := 0
+
+	/*
+	 * Write each sample frame.
+	 */
+	for i := 0; i < n; i++ {
+
+		/*
+		 * Write the sample of each channel.
+		 */
+		for _, channel := range channels {
+			sample := uint64(channel[i])
+
+			/*
+			 * Write each byte of the sample.
+			 */
+			for j := 0; j < bytesPerSample; j++ {
+				shift := uint(BITS_PER_BYTE * j)
+				buf[offset] = byte(sample >> shift)
+				offset++
+			}
+
+		}
+
+	}
+
+	this.hash.Write(buf)
+}
+
+/*
+ * Encodes the first samples of each channel as a frame and writes it.
+ */
+func (this *writerStruct) writeFrame(blockSize int) error {
+	info := &this.info
+	channels := make([][]int64, len(this.pending))
+
+	/*
+	 * Take the block from each channel.
+	 */
+	for i, pending := range this.pending {
+		channels[i] = pending[0:blockSize]
+	}
+
+	this.updateHash(channels)
+	bitDepth := uint(info.bitDepth)
+	assignment, subframes := planChannels(channels, bitDepth)
+	w := &this.frame
+	w.reset()
+	w.writeBits(FRAME_SYNC, 14)
+	w.writeBits(0, 1)
+	w.writeBits(0, 1)
+	sizeCode, sizeBits := blockSizeCode(blockSize)
+	rateCode, rateValue, rateBits := sampleRateCode(info.sampleRate)
+	w.writeBits(sizeCode, 4)
+	w.writeBits(rateCode, 4)
+	w.writeBits(assignment, 4)
+	w.writeBits(bitDepthCode(info.bitDepth), 3)
+	w.writeBits(0, 1)
+	writeCodedNumber(w, this.numBlocks)
+
+	/*
+	 * Write the block size, if it has no code of its own.
+	 */
+	if sizeBits > 0 {
+		blockSize64 := uint64(blockSize - 1)
+		w.writeBits(blockSize64, sizeBits)
+	}
+
+	/*
+	 * Write the sample rate, if it has no code of its own.
+	 */
+	if rateBits > 0 {
+		w.writeBits(rateValue, rateBits)
+	}
+
+	header := w.bytes()
+	crc8 := checksumCRC8(header)
+	w.writeBits(uint64(crc8), 8)
+
+	/*
+	 * Write the subframe of each channel.
+	 */
+	for _, sf := range subframes {
+		writeSubframe(w, sf)
+	}
+
+	w.align()
+	frame := w.bytes()
+	crc16 := checksumCRC16(frame)
+	w.writeBits(uint64(crc16), 16)
+	frame = w.bytes()
+	_, err := this.writer.Write(frame)
+
+	/*
+	 * Check if frame was written.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return fmt.Errorf("Failed to write frame: %s", msg)
+	} else {
+		frameSize := uint32(len(frame))
+
+		/*
+		 * Keep track of the smallest and largest frame.
+		 */
+		if (info.minFrameSize == 0) || (frameSize < info.minFrameSize) {
+			info.minFrameSize = frameSize
+		}
+
+		if frameSize > info.maxFrameSize {
+			info.maxFrameSize = frameSize
+		}
+
+		blockSize64 := uint64(blockSize)
+		info.totalSamples += blockSize64
+		this.numBlocks++
+		this.lastBlock = blockSize
+		return nil
+	}
+
+}
+
+/*
+ * Encodes the samples which are left as a final, shorter frame, then updates
+ * the stream info, if the underlying writer can seek.
+ *
+ * This does not close the underlying writer.
+ */
+func (this *writerStruct) Close() error {
+
+	/*
+	 * Write the samples which are left.
+	 */
+	if this.numPending > 0 {
+		err := this.writeFrame(this.numPending)
+		this.numPending = 0
+
+		/*
+		 * Check if frame was written.
+		 */
+		if err != nil {
+			return err
+		}
+
+	}
+
+	seeker, isSeeker := this.writer.(io.WriteSeeker)
+
+	/*
+	 * The stream info can only be updated if we can seek back to it.
+	 */
+	if !isSeeker {
+		return nil
+	} else {
+		info := &this.info
+
+		/*
+		 * A stream consisting of a single block has the size of that
+		 * block.
+		 */
+		if this.numBlocks == 1 {
+			blockSize := uint16(this.lastBlock)
+			info.minBlockSize = blockSize
+			info.maxBlockSize = blockSize
+		}
+
+		sum := this.hash.Sum(nil)
+		copy(info.md5[:], sum)
+		header := encodeHeader(info)
+		_, err := seeker.Seek(0, io.SeekStart)
+
+		/*
+		 * Check if we seeked to the stream info.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to seek to stream info: %s", msg)
+		} else {
+			_, err = seeker.Write(header)
+
+			/*
+			 * Check if stream info was written.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return fmt.Errorf("Failed to update stream info: %s", msg)
+			} else {
+				_, err = seeker.Seek(0, io.SeekEnd)
+
+				/*
+				 * Check if we seeked back to the end.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return fmt.Errorf("Failed to seek to end of file: %s", msg)
+				} else {
+					return nil
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Encodes a block of samples, one slice for each channel, and appends it to
+ * the FLAC file.
+ *
+ * All channels must hold the same number of samples.
+ */
+func (this *writerStruct) Write(channels [][]float64) error {
+	channelCount := len(channels)
+	expectedChannelCount := int(this.info.channelCount)
+
+	/*
+	 * Check if we got the right number of channels.
+	 */
+	if channelCount != expectedChannelCount {
+		return fmt.Errorf("Expected %d channels, but got %d.", expectedChannelCount, channelCount)
+	} else {
+		blockLength := len(channels[0])
+
+		/*
+		 * Make sure all channels hold the same number of samples.
+		 */
+		for i, channel := range channels {
+			channelLength := len(channel)
+
+			/*
+			 * Check if channel holds the right number of samples.
+			 */
+			if channelLength != blockLength {
+				return fmt.Errorf("Channel %d holds %d samples, but channel 0 holds %d samples.", i, channelLength, blockLength)
+			}
+
+		}
+
+		bitDepth := this.info.bitDepth
+		offset := 0
+
+		/*
+		 * Fill up the pending block and encode it once it is complete.
+		 */
+		for offset < blockLength {
+			numPending := this.numPending
+			count := BLOCK_SIZE - numPending
+			remaining := blockLength - offset
+
+			/*
+			 * Do not take more samples than we got.
+			 */
+			if count > remaining {
+				count = remaining
+			}
+
+			/*
+			 * Convert the samples of each channel.
+			 */
+			for i, channel := range channels {
+				source := channel[offset : offset+count]
+				target := this.pending[i][numPending : numPending+count]
+				samplesToInts(source, bitDepth, target)
+			}
+
+			offset += count
+			numPending += count
+			this.numPending = numPending
+
+			/*
+			 * Encode the block once it is complete.
+			 */
+			if numPending == BLOCK_SIZE {
+				this.numPending = 0
+				err := this.writeFrame(BLOCK_SIZE)
+
+				/*
+				 * Check if frame was written.
+				 */
+				if err != nil {
+					return err
+				}
+
+			}
+
+		}
+
+		return nil
+	}
+
+}
+
+/*
+ * Creates a FLAC file, which is written incrementally to an underlying
+ * writer, with the desired sample rate, bit depth and channel count.
+ *
+ * The stream info is written immediately. Since the length of the stream and
+ * its MD5 sum only become known after all samples are written, it is updated
+ * on close, which requires the underlying writer to be able to seek.
+ * Otherwise, both are left unknown, which decoders accept.
+ */
+func CreateWriter(w io.Writer, sampleRate uint32, bitDepth uint16, channelCount uint16) (Writer, error) {
+	err := checkBitDepth(bitDepth)
+
+	/*
+	 * Check if the format is valid.
+	 */
+	if err != nil {
+		return nil, err
+	} else if (channelCount == 0) || (channelCount > MAX_CHANNELS) {
+		return nil, fmt.Errorf("Channel count must be between %d and %d for FLAC.", 1, MAX_CHANNELS)
+	} else if (sampleRate == 0) || (sampleRate > MAX_SAMPLE_RATE) {
+		return nil, fmt.Errorf("Sample rate must be between %d and %d for FLAC.", 1, MAX_SAMPLE_RATE)
+	} else {
+
+		/*
+		 * Describe the stream.
+		 */
+		info := streamInfoStruct{
+			minBlockSize: BLOCK_SIZE,
+			maxBlockSize: BLOCK_SIZE,
+			sampleRate:   sampleRate,
+			channelCount: channelCount,
+			bitDepth:     bitDepth,
+		}
+
+		header := encodeHeader(&info)
+		_, err = w.Write(header)
+
+		/*
+		 * Check if stream info was written.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to write stream info: %s", msg)
+		} else {
+			pending := make([][]int64, channelCount)
+
+			/*
+			 * Allocate a block for each channel.
+			 */
+			for i := range pending {
+				pending[i] = make([]int64, BLOCK_SIZE)
+			}
+
+			/*
+			 * Create FLAC writer structure.
+			 */
+			writer := writerStruct{
+				writer:  w,
+				info:    info,
+				hash:    md5.New(),
+				pending: pending,
+			}
+
+			return &writer, nil
+		}
+
+	}
+
+}
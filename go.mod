@@ -2,4 +2,10 @@ module github.com/andrepxx/go-dsp-guitar
 
 go 1.16
 
-require github.com/andrepxx/go-jack v0.0.0-20220929171107-71a712d2f786
+require (
+	github.com/andrepxx/go-jack v0.0.0-20220929171107-71a712d2f786
+	golang.org/x/crypto v0.14.0
+	golang.org/x/net v0.17.0 // indirect
+	google.golang.org/grpc v1.47.0
+	google.golang.org/protobuf v1.28.1
+)
@@ -0,0 +1,117 @@
+package gpio
+
+import (
+	"time"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	DEFAULT_PATH     = "/sys/class/gpio"
+	DEFAULT_DEBOUNCE = 30
+	POLL_INTERVAL    = 5 * time.Millisecond
+	PRESS_LENGTH     = 16
+)
+
+/*
+ * The configuration of a GPIO pin a footswitch is connected to.
+ *
+ * Footswitches which connect the pin to ground when pressed, relying on a
+ * pull-up resistor, are active low.
+ */
+type Pin struct {
+	Number    uint32
+	ActiveLow bool
+}
+
+/*
+ * The configuration of the GPIO inputs, with the path of the GPIO interface
+ * in sysfs and the time (in milliseconds) a pin has to keep its state before
+ * a change is accepted.
+ */
+type Config struct {
+	Path     string
+	Debounce uint32
+	Pins     []Pin
+}
+
+/*
+ * Data structure filtering out the bouncing of a mechanical switch.
+ */
+type debouncerStruct struct {
+	duration    time.Duration
+	initialized bool
+	stable      bool
+	candidate   bool
+	since       time.Time
+}
+
+/*
+ * Interface type for a set of footswitches connected to GPIO pins.
+ */
+type Footswitches interface {
+	Presses() <-chan int
+	Close()
+}
+
+/*
+ * Feeds the state of a switch read at a certain time into the debouncer and
+ * returns whether the switch was pressed.
+ *
+ * A change of the state is only accepted after the switch kept it for the
+ * duration of the debouncer. The first reading is taken as the initial state,
+ * so that a switch held down at startup does not count as pressed.
+ */
+func (this *debouncerStruct) update(pressed bool, now time.Time) bool {
+	result := false
+
+	/*
+	 * Check if this is the first reading.
+	 */
+	if !this.initialized {
+		this.initialized = true
+		this.stable = pressed
+		this.candidate = pressed
+		this.since = now
+	} else {
+
+		/*
+		 * Restart the timer whenever the state changes.
+		 */
+		if pressed != this.candidate {
+			this.candidate = pressed
+			this.since = now
+		}
+
+		elapsed := now.Sub(this.since)
+
+		/*
+		 * Accept the new state once it settled.
+		 */
+		if (this.candidate != this.stable) && (elapsed >= this.duration) {
+			this.stable = this.candidate
+			result = this.stable
+		}
+
+	}
+
+	return result
+}
+
+/*
+ * Creates a debouncer accepting changes which last for a certain time (in
+ * milliseconds).
+ */
+func createDebouncer(milliseconds uint32) debouncerStruct {
+	duration := time.Duration(milliseconds) * time.Millisecond
+
+	/*
+	 * Create debouncer.
+	 */
+	debouncer := debouncerStruct{
+		duration: duration,
+	}
+
+	return debouncer
+}
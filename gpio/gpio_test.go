@@ -0,0 +1,85 @@
+package gpio
+
+import (
+	"testing"
+	"time"
+)
+
+/*
+ * Verify that the debouncer reports a press only once the switch settled
+ * and ignores the state of the switch at startup.
+ */
+func TestDebouncer(t *testing.T) {
+	debouncer := createDebouncer(DEFAULT_DEBOUNCE)
+	start := time.Unix(0, 0)
+
+	/*
+	 * Returns the time a number of milliseconds after the start.
+	 */
+	at := func(milliseconds int) time.Time {
+		offset := time.Duration(milliseconds) * time.Millisecond
+		return start.Add(offset)
+	}
+
+	/*
+	 * A switch held down at startup does not count as pressed.
+	 */
+	if debouncer.update(true, at(0)) || debouncer.update(true, at(100)) {
+		t.Errorf("%s", "Switch held down at startup was reported as pressed.")
+	}
+
+	debouncer.update(false, at(110))
+	debouncer.update(false, at(150))
+
+	/*
+	 * Readings of a bouncing switch.
+	 */
+	readings := []bool{
+		true,
+		false,
+		true,
+		false,
+		true,
+	}
+
+	presses := 0
+
+	/*
+	 * Feed the bouncing switch into the debouncer, one reading per
+	 * millisecond.
+	 */
+	for i, reading := range readings {
+
+		/*
+		 * Count the presses reported.
+		 */
+		if debouncer.update(reading, at(200+i)) {
+			presses++
+		}
+
+	}
+
+	/*
+	 * Bouncing must not be reported as presses.
+	 */
+	if presses != 0 {
+		t.Errorf("Bouncing switch was reported as pressed %d times.", presses)
+	}
+
+	/*
+	 * Once the switch settled, the press is reported exactly once.
+	 */
+	if !debouncer.update(true, at(240)) {
+		t.Errorf("%s", "Press of settled switch was not reported.")
+	} else if debouncer.update(true, at(260)) {
+		t.Errorf("%s", "Press of settled switch was reported twice.")
+	}
+
+	/*
+	 * Releasing the switch is not a press.
+	 */
+	if debouncer.update(false, at(300)) || debouncer.update(false, at(400)) {
+		t.Errorf("%s", "Release of switch was reported as press.")
+	}
+
+}
@@ -0,0 +1,339 @@
+//go:build gpio
+// +build gpio
+
+package gpio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+/*
+ * Constants for the sysfs GPIO interface.
+ */
+const (
+	EXPORT_RETRIES  = 50
+	EXPORT_INTERVAL = 10 * time.Millisecond
+)
+
+/*
+ * Data structure representing a GPIO pin opened through sysfs.
+ */
+type sysfsPinStruct struct {
+	number    uint32
+	exported  bool
+	file      *os.File
+	debouncer debouncerStruct
+}
+
+/*
+ * Data structure representing a set of footswitches read through the sysfs
+ * GPIO interface.
+ */
+type sysfsFootswitchesStruct struct {
+	path    string
+	pins    []*sysfsPinStruct
+	presses chan int
+	stop    chan bool
+	done    chan bool
+}
+
+/*
+ * Writes a value into an attribute file of the sysfs GPIO interface.
+ */
+func writeAttribute(path string, value string) error {
+	content := []byte(value)
+	err := os.WriteFile(path, content, 0644)
+	return err
+}
+
+/*
+ * Writes a value into an attribute file of a pin. Right after a pin is
+ * exported, udev may not have granted access to its attributes yet, so
+ * writing is retried for a while.
+ */
+func writePinAttribute(path string, value string) error {
+	err := writeAttribute(path, value)
+
+	/*
+	 * Retry until the attribute becomes writable.
+	 */
+	for i := 0; (err != nil) && (i < EXPORT_RETRIES); i++ {
+		time.Sleep(EXPORT_INTERVAL)
+		err = writeAttribute(path, value)
+	}
+
+	return err
+}
+
+/*
+ * Reads whether the footswitch connected to a pin is pressed.
+ */
+func (this *sysfsPinStruct) read() (bool, error) {
+	buf := make([]byte, 1)
+	_, err := this.file.ReadAt(buf, 0)
+
+	/*
+	 * Check if the value could be read.
+	 */
+	if err != nil {
+		return false, err
+	} else {
+		pressed := buf[0] == '1'
+		return pressed, nil
+	}
+
+}
+
+/*
+ * Closes the value file of a pin and unexports it, if we exported it.
+ */
+func (this *sysfsFootswitchesStruct) closePin(pin *sysfsPinStruct) {
+
+	/*
+	 * Check if the value file was opened.
+	 */
+	if pin.file != nil {
+		pin.file.Close()
+	}
+
+	/*
+	 * Only unexport pins we exported ourselves.
+	 */
+	if pin.exported {
+		numberString := strconv.FormatUint(uint64(pin.number), 10)
+		unexportPath := filepath.Join(this.path, "unexport")
+		writeAttribute(unexportPath, numberString)
+	}
+
+}
+
+/*
+ * Exports a pin, if necessary, configures it as an input and opens its value
+ * file.
+ */
+func (this *sysfsFootswitchesStruct) openPin(config Pin, debounce uint32) (*sysfsPinStruct, error) {
+	number := config.Number
+	numberString := strconv.FormatUint(uint64(number), 10)
+	pinPath := filepath.Join(this.path, "gpio"+numberString)
+
+	/*
+	 * The pin to open.
+	 */
+	pin := sysfsPinStruct{
+		number:    number,
+		debouncer: createDebouncer(debounce),
+	}
+
+	_, err := os.Stat(pinPath)
+
+	/*
+	 * Export the pin unless it is already exported.
+	 */
+	if err != nil {
+		exportPath := filepath.Join(this.path, "export")
+		err = writeAttribute(exportPath, numberString)
+
+		/*
+		 * Check if pin was exported.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to export GPIO pin %d: %s", number, msg)
+		}
+
+		pin.exported = true
+	}
+
+	directionPath := filepath.Join(pinPath, "direction")
+	err = writePinAttribute(directionPath, "in")
+
+	/*
+	 * Check if the pin was configured as an input.
+	 */
+	if err != nil {
+		this.closePin(&pin)
+		msg := err.Error()
+		return nil, fmt.Errorf("Failed to configure GPIO pin %d as input: %s", number, msg)
+	} else {
+		activeLowPath := filepath.Join(pinPath, "active_low")
+		activeLow := "0"
+
+		/*
+		 * Let the kernel invert the value of active low pins.
+		 */
+		if config.ActiveLow {
+			activeLow = "1"
+		}
+
+		err = writePinAttribute(activeLowPath, activeLow)
+
+		/*
+		 * Check if the polarity was configured.
+		 */
+		if err != nil {
+			this.closePin(&pin)
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to configure polarity of GPIO pin %d: %s", number, msg)
+		} else {
+			valuePath := filepath.Join(pinPath, "value")
+			file, err := os.Open(valuePath)
+
+			/*
+			 * Check if the value file was opened.
+			 */
+			if err != nil {
+				this.closePin(&pin)
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to open value of GPIO pin %d: %s", number, msg)
+			} else {
+				pin.file = file
+				return &pin, nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Polls the pins until the footswitches are closed and reports each press.
+ *
+ * Presses are dropped if they are not consumed fast enough.
+ */
+func (this *sysfsFootswitchesStruct) poll() {
+	ticker := time.NewTicker(POLL_INTERVAL)
+	stopped := false
+
+	/*
+	 * Read the pins until we get stopped.
+	 */
+	for !stopped {
+
+		/*
+		 * Wait for the next reading unless we got stopped.
+		 */
+		select {
+		case <-this.stop:
+			stopped = true
+		case now := <-ticker.C:
+
+			/*
+			 * Read each pin.
+			 */
+			for i, pin := range this.pins {
+				pressed, err := pin.read()
+
+				/*
+				 * Check if the switch was pressed.
+				 */
+				if (err == nil) && pin.debouncer.update(pressed, now) {
+
+					/*
+					 * Drop the press if the queue is full.
+					 */
+					select {
+					case this.presses <- i:
+					default:
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+	ticker.Stop()
+	close(this.done)
+}
+
+/*
+ * Returns a channel, which receives the index of a pin, in the order of the
+ * configuration, whenever the footswitch connected to it is pressed.
+ */
+func (this *sysfsFootswitchesStruct) Presses() <-chan int {
+	return this.presses
+}
+
+/*
+ * Stops reading the footswitches and releases the pins.
+ */
+func (this *sysfsFootswitchesStruct) Close() {
+	close(this.stop)
+	<-this.done
+
+	/*
+	 * Release each pin.
+	 */
+	for _, pin := range this.pins {
+		this.closePin(pin)
+	}
+
+}
+
+/*
+ * Opens footswitches connected to GPIO pins through the sysfs GPIO interface.
+ */
+func Open(config Config) (Footswitches, error) {
+	path := config.Path
+
+	/*
+	 * Use the default location of the interface.
+	 */
+	if path == "" {
+		path = DEFAULT_PATH
+	}
+
+	debounce := config.Debounce
+
+	/*
+	 * Use the default debounce time.
+	 */
+	if debounce == 0 {
+		debounce = DEFAULT_DEBOUNCE
+	}
+
+	/*
+	 * Create footswitches.
+	 */
+	footswitches := sysfsFootswitchesStruct{
+		path:    path,
+		pins:    []*sysfsPinStruct{},
+		presses: make(chan int, PRESS_LENGTH),
+		stop:    make(chan bool),
+		done:    make(chan bool),
+	}
+
+	/*
+	 * Open each pin.
+	 */
+	for _, pinConfig := range config.Pins {
+		pin, err := footswitches.openPin(pinConfig, debounce)
+
+		/*
+		 * Release the pins opened so far if a pin failed to open.
+		 */
+		if err != nil {
+
+			/*
+			 * Release each pin.
+			 */
+			for _, openedPin := range footswitches.pins {
+				footswitches.closePin(openedPin)
+			}
+
+			return nil, err
+		}
+
+		footswitches.pins = append(footswitches.pins, pin)
+	}
+
+	go footswitches.poll()
+	return &footswitches, nil
+}
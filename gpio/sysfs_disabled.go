@@ -0,0 +1,17 @@
+//go:build !gpio
+// +build !gpio
+
+package gpio
+
+import (
+	"fmt"
+)
+
+/*
+ * Opens footswitches connected to GPIO pins.
+ *
+ * This build does not include GPIO support.
+ */
+func Open(config Config) (Footswitches, error) {
+	return nil, fmt.Errorf("%s", "This build does not support GPIO. Rebuild with '-tags gpio' to enable it.")
+}
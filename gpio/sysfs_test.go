@@ -0,0 +1,89 @@
+//go:build gpio
+// +build gpio
+
+package gpio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/*
+ * Verify that a press of a footswitch connected to an exported pin is
+ * reported with the index of the pin.
+ */
+func TestSysfs(t *testing.T) {
+	path := t.TempDir()
+	pinPath := filepath.Join(path, "gpio17")
+	err := os.Mkdir(pinPath, 0755)
+
+	/*
+	 * Check if the pin directory was created.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Fatalf("Failed to create pin directory: %s", msg)
+	}
+
+	valuePath := filepath.Join(pinPath, "value")
+	writeAttribute(valuePath, "0\n")
+
+	/*
+	 * Create GPIO configuration.
+	 */
+	config := Config{
+		Path:     path,
+		Debounce: 10,
+		Pins: []Pin{
+			Pin{
+				Number:    17,
+				ActiveLow: true,
+			},
+		},
+	}
+
+	footswitches, err := Open(config)
+
+	/*
+	 * Check if the footswitches were opened.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Fatalf("Failed to open footswitches: %s", msg)
+	}
+
+	defer footswitches.Close()
+	activeLowPath := filepath.Join(pinPath, "active_low")
+	activeLow, _ := os.ReadFile(activeLowPath)
+
+	/*
+	 * The kernel has to invert the value of an active low pin.
+	 */
+	if string(activeLow) != "1" {
+		t.Errorf("Polarity of pin is '%s', expected '1'.", activeLow)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	writeAttribute(valuePath, "1\n")
+	presses := footswitches.Presses()
+
+	/*
+	 * Wait for the press.
+	 */
+	select {
+	case idx := <-presses:
+
+		/*
+		 * Check the index of the pin.
+		 */
+		if idx != 0 {
+			t.Errorf("Press reported for pin %d, expected 0.", idx)
+		}
+
+	case <-time.After(time.Second):
+		t.Errorf("%s", "Press was not reported.")
+	}
+
+}
@@ -0,0 +1,408 @@
+//go:build alsa
+// +build alsa
+
+package hwio
+
+/*
+#cgo LDFLAGS: -lasound
+#include <stdlib.h>
+#include <alsa/asoundlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+/*
+ * Default values for the ALSA backend.
+ */
+const (
+	ALSA_DEFAULT_DEVICE            = "default"
+	ALSA_DEFAULT_CHANNELS          = 2
+	ALSA_DEFAULT_SAMPLE_RATE       = 48000
+	ALSA_DEFAULT_FRAMES_PER_PERIOD = 256
+	ALSA_DEFAULT_PERIODS           = 2
+)
+
+/*
+ * Data structure representing the ALSA backend.
+ */
+type alsaBackend struct {
+	mutex            sync.Mutex
+	capture          *C.snd_pcm_t
+	playback         *C.snd_pcm_t
+	captureDevice    string
+	playbackDevice   string
+	captureChannels  int
+	playbackChannels int
+	sampleRate       uint32
+	framesPerPeriod  uint32
+	periods          uint32
+	load             float32
+	running          bool
+	router           *routerStruct
+}
+
+/*
+ * Converts an ALSA error code into an error.
+ */
+func alsaError(operation string, code C.int) error {
+	cMsg := C.snd_strerror(code)
+	msg := C.GoString(cMsg)
+	return fmt.Errorf("%s failed: %s", operation, msg)
+}
+
+/*
+ * Opens and configures a PCM device.
+ */
+func (this *alsaBackend) openDevice(device string, stream C.snd_pcm_stream_t, channels int) (*C.snd_pcm_t, error) {
+	pcm := (*C.snd_pcm_t)(nil)
+	cDevice := C.CString(device)
+	defer C.free(unsafe.Pointer(cDevice))
+	ret := C.snd_pcm_open(&pcm, cDevice, stream, 0)
+
+	/*
+	 * Check if device could be opened.
+	 */
+	if ret < 0 {
+		operation := fmt.Sprintf("Opening ALSA device '%s'", device)
+		return nil, alsaError(operation, ret)
+	} else {
+		rate := uint64(this.sampleRate)
+		frames := uint64(this.framesPerPeriod)
+		periods := uint64(this.periods)
+		latency := (1000000 * frames * periods) / rate
+		ret = C.snd_pcm_set_params(pcm, C.SND_PCM_FORMAT_FLOAT, C.SND_PCM_ACCESS_RW_INTERLEAVED, C.uint(channels), C.uint(rate), 1, C.uint(latency))
+
+		/*
+		 * Check if device could be configured.
+		 */
+		if ret < 0 {
+			C.snd_pcm_close(pcm)
+			operation := fmt.Sprintf("Configuring ALSA device '%s'", device)
+			return nil, alsaError(operation, ret)
+		} else {
+			return pcm, nil
+		}
+
+	}
+
+}
+
+/*
+ * Open the capture and playback devices and start processing.
+ */
+func (this *alsaBackend) open() error {
+	capture, err := this.openDevice(this.captureDevice, C.SND_PCM_STREAM_CAPTURE, this.captureChannels)
+
+	/*
+	 * Check if capture device was opened.
+	 */
+	if err != nil {
+		return err
+	} else {
+		playback, err := this.openDevice(this.playbackDevice, C.SND_PCM_STREAM_PLAYBACK, this.playbackChannels)
+
+		/*
+		 * Check if playback device was opened.
+		 */
+		if err != nil {
+			C.snd_pcm_close(capture)
+			return err
+		} else {
+			this.capture = capture
+			this.playback = playback
+			this.running = true
+			g_sampleRate = this.sampleRate
+			go this.run()
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Stop processing.
+ *
+ * The devices are closed by the processing thread once it notices that it
+ * should stop.
+ */
+func (this *alsaBackend) close() {
+	this.mutex.Lock()
+	this.running = false
+	this.mutex.Unlock()
+}
+
+/*
+ * Get DSP load.
+ */
+func (this *alsaBackend) cpuLoad() float32 {
+	this.mutex.Lock()
+	load := this.load
+	this.mutex.Unlock()
+	return load
+}
+
+/*
+ * Get frames per period.
+ */
+func (this *alsaBackend) bufferSize() uint32 {
+	this.mutex.Lock()
+	n := this.framesPerPeriod
+	this.mutex.Unlock()
+	return n
+}
+
+/*
+ * Set frames per period.
+ *
+ * This changes the number of frames processed at once, while the latency of
+ * the devices stays the same.
+ */
+func (this *alsaBackend) setBufferSize(n uint32) {
+
+	/*
+	 * Ignore invalid period sizes.
+	 */
+	if n > 0 {
+		this.mutex.Lock()
+		this.framesPerPeriod = n
+		this.mutex.Unlock()
+	}
+
+}
+
+/*
+ * Register the ports of a binding.
+ *
+ * ALSA has no notion of ports, so they only become routable by name.
+ */
+func (this *alsaBackend) registerPorts(binding *Binding) error {
+	return nil
+}
+
+/*
+ * Register the MIDI input of a binding.
+ *
+ * The ALSA backend does not support MIDI.
+ */
+func (this *alsaBackend) registerMidiInput(binding *Binding) error {
+	return fmt.Errorf("%s", "The ALSA backend does not support MIDI input.")
+}
+
+/*
+ * Change the ports of a binding, keeping the routes of ports which keep
+ * their names.
+ */
+func (this *alsaBackend) rebindPorts(binding *Binding, inputNames []string, outputNames []string) error {
+	this.router.rebindPorts(binding, inputNames, outputNames)
+	return nil
+}
+
+/*
+ * Remove all routes to or from the ports of a binding.
+ */
+func (this *alsaBackend) unregisterPorts(binding *Binding) {
+	this.router.removeBinding(binding)
+}
+
+/*
+ * Connects a source port to a destination port.
+ */
+func (this *alsaBackend) connect(sourcePort string, destinationPort string) {
+	this.router.connect(sourcePort, destinationPort)
+}
+
+/*
+ * Disconnects a source port from a destination port.
+ */
+func (this *alsaBackend) disconnect(sourcePort string, destinationPort string) {
+	this.router.disconnect(sourcePort, destinationPort)
+}
+
+/*
+ * Returns the names of all ports which may act as a source or as a
+ * destination of a connection.
+ */
+func (this *alsaBackend) ports() ([]string, []string) {
+	return this.router.ports()
+}
+
+/*
+ * Returns all connections between ports.
+ */
+func (this *alsaBackend) connections() []Connection {
+	return this.router.connections()
+}
+
+/*
+ * Writes a period of audio to the playback device, recovering from
+ * underruns.
+ */
+func (this *alsaBackend) write(playbackBuffer []float32, frames int) {
+	playback := this.playback
+	ptr := unsafe.Pointer(&playbackBuffer[0])
+	ret := C.snd_pcm_writei(playback, ptr, C.snd_pcm_uframes_t(frames))
+
+	/*
+	 * On underrun, recover and try again.
+	 */
+	if ret < 0 {
+		countUnderrun()
+		C.snd_pcm_recover(playback, C.int(ret), 1)
+		C.snd_pcm_writei(playback, ptr, C.snd_pcm_uframes_t(frames))
+	}
+
+}
+
+/*
+ * The processing thread, capturing, processing and playing back audio until
+ * the backend is closed.
+ */
+func (this *alsaBackend) run() {
+	runtime.LockOSThread()
+	capture := this.capture
+	playback := this.playback
+	captureChannels := this.captureChannels
+	playbackChannels := this.playbackChannels
+	sampleRateFloat := float64(this.sampleRate)
+	captureBuffer := []float32{}
+	playbackBuffer := []float32{}
+	load := float64(0.0)
+	running := true
+
+	/*
+	 * Process periods until the backend is closed.
+	 */
+	for running {
+		this.mutex.Lock()
+		frames := int(this.framesPerPeriod)
+		periods := int(this.periods)
+		this.mutex.Unlock()
+		captureSize := frames * captureChannels
+		playbackSize := frames * playbackChannels
+
+		/*
+		 * If the period size changed, reallocate buffers and fill the
+		 * playback device with silence to prevent underruns.
+		 */
+		if len(captureBuffer) != captureSize {
+			captureBuffer = make([]float32, captureSize)
+			playbackBuffer = make([]float32, playbackSize)
+
+			/*
+			 * Write a period of silence for each period of latency.
+			 */
+			for i := 0; i < periods; i++ {
+				this.write(playbackBuffer, frames)
+			}
+
+		}
+
+		captureBufferPtr := unsafe.Pointer(&captureBuffer[0])
+		ret := C.snd_pcm_readi(capture, captureBufferPtr, C.snd_pcm_uframes_t(frames))
+
+		/*
+		 * On overrun, recover, otherwise process the captured audio.
+		 */
+		if ret < 0 {
+			countXrun()
+			C.snd_pcm_recover(capture, C.int(ret), 1)
+		} else {
+			framesRead := int(ret)
+			start := time.Now()
+			this.router.process(captureBuffer, playbackBuffer, framesRead)
+			elapsed := time.Since(start)
+			this.write(playbackBuffer, framesRead)
+			elapsedSeconds := elapsed.Seconds()
+			framesReadFloat := float64(framesRead)
+			periodSeconds := framesReadFloat / sampleRateFloat
+
+			/*
+			 * Smooth the DSP load over several periods.
+			 */
+			if periodSeconds > 0.0 {
+				currentLoad := 100.0 * (elapsedSeconds / periodSeconds)
+				load += 0.1 * (currentLoad - load)
+			}
+
+		}
+
+		this.mutex.Lock()
+		this.load = float32(load)
+		running = this.running
+		this.mutex.Unlock()
+	}
+
+	C.snd_pcm_close(capture)
+	C.snd_pcm_close(playback)
+	runtime.UnlockOSThread()
+}
+
+/*
+ * Returns a value or a default if the value is zero.
+ */
+func valueOrDefault(value uint32, defaultValue uint32) uint32 {
+
+	/*
+	 * Check if value is zero.
+	 */
+	if value == 0 {
+		return defaultValue
+	} else {
+		return value
+	}
+
+}
+
+/*
+ * Creates an ALSA backend.
+ */
+func createAlsaBackend(cfg AlsaConfig) (backend, error) {
+	captureDevice := cfg.CaptureDevice
+	playbackDevice := cfg.PlaybackDevice
+
+	/*
+	 * Use the default capture device if none is configured.
+	 */
+	if captureDevice == "" {
+		captureDevice = ALSA_DEFAULT_DEVICE
+	}
+
+	/*
+	 * Use the default playback device if none is configured.
+	 */
+	if playbackDevice == "" {
+		playbackDevice = ALSA_DEFAULT_DEVICE
+	}
+
+	captureChannels := valueOrDefault(cfg.CaptureChannels, ALSA_DEFAULT_CHANNELS)
+	playbackChannels := valueOrDefault(cfg.PlaybackChannels, ALSA_DEFAULT_CHANNELS)
+	sampleRate := valueOrDefault(cfg.SampleRate, ALSA_DEFAULT_SAMPLE_RATE)
+	framesPerPeriod := valueOrDefault(cfg.FramesPerPeriod, ALSA_DEFAULT_FRAMES_PER_PERIOD)
+	periods := valueOrDefault(cfg.Periods, ALSA_DEFAULT_PERIODS)
+	captureChannelsInt := int(captureChannels)
+	playbackChannelsInt := int(playbackChannels)
+
+	/*
+	 * Create ALSA backend.
+	 */
+	b := alsaBackend{
+		captureDevice:    captureDevice,
+		playbackDevice:   playbackDevice,
+		captureChannels:  captureChannelsInt,
+		playbackChannels: playbackChannelsInt,
+		sampleRate:       sampleRate,
+		framesPerPeriod:  framesPerPeriod,
+		periods:          periods,
+		router:           createRouter(captureChannelsInt, playbackChannelsInt),
+	}
+
+	return &b, nil
+}
@@ -0,0 +1,17 @@
+//go:build !alsa
+// +build !alsa
+
+package hwio
+
+import (
+	"fmt"
+)
+
+/*
+ * Creates an ALSA backend.
+ *
+ * This build does not include ALSA support.
+ */
+func createAlsaBackend(cfg AlsaConfig) (backend, error) {
+	return nil, fmt.Errorf("%s", "This build does not support ALSA. Rebuild with '-tags alsa' to enable it.")
+}
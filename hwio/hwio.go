@@ -2,9 +2,9 @@ package hwio
 
 import (
 	"fmt"
-	"github.com/andrepxx/go-jack"
-	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 /*
@@ -17,222 +17,253 @@ type Processor func([][]float64, [][]float64, uint32)
  */
 type SampleRateListener func(uint32)
 
+/*
+ * Configuration for the ALSA backend.
+ */
+type AlsaConfig struct {
+	CaptureDevice    string
+	CaptureChannels  uint32
+	PlaybackDevice   string
+	PlaybackChannels uint32
+	SampleRate       uint32
+	FramesPerPeriod  uint32
+	Periods          uint32
+}
+
+/*
+ * Configuration for the WASAPI backend.
+ */
+type WasapiConfig struct {
+	FramesPerPeriod uint32
+	Periods         uint32
+}
+
+/*
+ * Configuration for the hardware interface.
+ */
+type Config struct {
+	Backend string
+	Alsa    AlsaConfig
+	Wasapi  WasapiConfig
+}
+
 /*
  * Data structure representing a handle to a hardware input and output and an
  * associated signal processor.
  */
 type Binding struct {
-	inputs    []*jack.Port
-	outputs   []*jack.Port
-	processor Processor
-	listener  SampleRateListener
+	inputNames     []string
+	outputNames    []string
+	ports          interface{}
+	inputBuffers   [][]float64
+	outputBuffers  [][]float64
+	processor      Processor
+	listener       SampleRateListener
+	midiInputName  string
+	programChanges chan uint8
+}
+
+/*
+ * A connection between a source port and a destination port.
+ */
+type Connection struct {
+	Source      string
+	Destination string
+}
+
+/*
+ * An audio backend, connecting bindings to the hardware.
+ */
+type backend interface {
+	open() error
+	close()
+	cpuLoad() float32
+	bufferSize() uint32
+	setBufferSize(n uint32)
+	registerPorts(binding *Binding) error
+	registerMidiInput(binding *Binding) error
+	rebindPorts(binding *Binding, inputNames []string, outputNames []string) error
+	unregisterPorts(binding *Binding)
+	connect(sourcePort string, destinationPort string)
+	disconnect(sourcePort string, destinationPort string)
+	ports() ([]string, []string)
+	connections() []Connection
 }
 
 /*
  * Global constants.
  */
 const (
-	INPUT_CHANNELS  = 2
-	OUTPUT_CHANNELS = INPUT_CHANNELS + 3
+	INPUT_CHANNELS        = 2
+	CLIENT_NAME           = "go-dsp-guitar"
+	BACKEND_JACK          = "jack"
+	BACKEND_ALSA          = "alsa"
+	BACKEND_WASAPI        = "wasapi"
+	MIDI_PROGRAM_CHANGE   = 0xc0
+	MIDI_STATUS_MASK      = 0xf0
+	PROGRAM_CHANGE_LENGTH = 16
 )
 
 /*
  * Global variables.
  */
-var g_client *jack.Client       // JACK client handle.
+var g_backend backend           // Audio backend.
+var g_config Config             // Configuration of the hardware interface.
 var g_mutex sync.RWMutex        // Mutex for bindings.
 var g_bindings []*Binding = nil // All currently active bindings.
-var g_inputBuffers [][]float64  // Input buffers.
-var g_outputBuffers [][]float64 // Output buffers.
 var g_sampleRate uint32         // Sample rate.
+var g_lastXrun int64            // Time of the last xrun in nanoseconds since the epoch.
+var g_xruns uint32              // Number of buffer over- and underruns.
+var g_underruns uint32          // Number of playback buffer underruns.
 
 /*
- * Convert audio samples to floating-point numbers.
+ * Interrupt handler called when the hardware adjusts the sample rate.
  */
-func samplesToFloats(in []jack.AudioSample, out []float64) error {
+func sampleRate(rate uint32) int {
+	g_sampleRate = rate
 
 	/*
-	 * Verify that the output buffer has an appropriate size
+	 * Notify each binding about the change.
 	 */
-	if len(out) < len(in) {
-		return fmt.Errorf("%s", "Cannot convert samples to floats: Output buffer is too small.")
-	} else {
+	for _, binding := range g_bindings {
+		binding.listener(rate)
+	}
 
-		/*
-		 * Convert each audio sample to a floating-point number.
-		 */
-		for i, sample := range in {
-			out[i] = float64(sample)
-		}
+	return 0
+}
 
-		return nil
-	}
+/*
+ * Counts a buffer over- or underrun.
+ *
+ * This is called from the real-time thread.
+ */
+func countXrun() {
+	now := time.Now()
+	nanoseconds := now.UnixNano()
+	atomic.StoreInt64(&g_lastXrun, nanoseconds)
+	atomic.AddUint32(&g_xruns, 1)
+}
 
+/*
+ * Counts a playback buffer underrun.
+ *
+ * Underruns are xruns as well, so they also count towards the total.
+ *
+ * This is called from the real-time thread.
+ */
+func countUnderrun() {
+	atomic.AddUint32(&g_underruns, 1)
+	countXrun()
 }
 
 /*
- * Convert floating-point numbers to audio samples.
+ * Creates the audio backend selected by configuration.
  */
-func floatsToSamples(in []float64, out []jack.AudioSample) error {
+func createBackend(cfg Config) (backend, error) {
+	name := cfg.Backend
 
 	/*
-	 * Verify that the output buffer has an appropriate size
+	 * Decide which backend to create.
 	 */
-	if len(out) < len(in) {
-		return fmt.Errorf("%s", "Cannot convert floats to samples: Output buffer is too small.")
-	} else {
-
-		/*
-		 * Convert each floating-point number to an audio sample.
-		 */
-		for i, sample := range in {
-			out[i] = jack.AudioSample(sample)
-		}
-
-		return nil
+	switch name {
+	case "", BACKEND_JACK:
+		return createJackBackend()
+	case BACKEND_ALSA:
+		alsaCfg := cfg.Alsa
+		return createAlsaBackend(alsaCfg)
+	case BACKEND_WASAPI:
+		wasapiCfg := cfg.Wasapi
+		return createWasapiBackend(wasapiCfg)
+	default:
+		return nil, fmt.Errorf("Unknown audio backend: '%s'", name)
 	}
 
 }
 
 /*
- * Interrupt handler called when the hardware has audio to process.
+ * Initialize the hardware for signal processing.
  */
-func process(nframes uint32) int {
-	g_mutex.RLock()
+func initialize() (backend, error) {
+	b, err := createBackend(g_config)
 
 	/*
-	 * Process audio for each binding.
+	 * Check if backend was created.
 	 */
-	for _, binding := range g_bindings {
-		inputs := binding.inputs
-		outputs := binding.outputs
-
-		/*
-		 * Read audio from each input channel.
-		 */
-		for i, input := range inputs {
-			hwInputBuffer := input.GetBuffer(nframes)
-			bufferSize := len(hwInputBuffer)
-
-			/*
-			 * Ensure the size of the current input buffer matches the size of the hardware buffer.
-			 */
-			if len(g_inputBuffers[i]) != bufferSize {
-				g_inputBuffers[i] = make([]float64, bufferSize)
-			}
-
-			err := samplesToFloats(hwInputBuffer, g_inputBuffers[i])
-
-			/*
-			 * If conversion failed, log error, otherwise perform processing.
-			 */
-			if err != nil {
-				msg := err.Error()
-				fmt.Printf("Error in real-time thread: %s", msg)
-			}
-
-		}
-
-		/*
-		 * Prepare output buffer for each output channel.
-		 */
-		for i, output := range outputs {
-			hwOutputBuffer := output.GetBuffer(nframes)
-			bufferSize := len(hwOutputBuffer)
-
-			/*
-			 * Ensure the size of the current output buffer matches the size of the hardware buffer.
-			 */
-			if len(g_outputBuffers[i]) != bufferSize {
-				g_outputBuffers[i] = make([]float64, bufferSize)
-			}
-
-		}
-
-		binding.processor(g_inputBuffers, g_outputBuffers, g_sampleRate)
+	if err != nil {
+		return nil, err
+	} else {
+		err = b.open()
 
 		/*
-		 * Write audio to each output channel.
+		 * Check if backend was opened.
 		 */
-		for i, output := range outputs {
-			hwOutputBuffer := output.GetBuffer(nframes)
-			err := floatsToSamples(g_outputBuffers[i], hwOutputBuffer)
-
-			/*
-			 * If conversion failed, log error.
-			 */
-			if err != nil {
-				msg := err.Error()
-				fmt.Printf("Error in real-time thread: %s", msg)
-			}
-
+		if err != nil {
+			return nil, err
+		} else {
+			return b, nil
 		}
 
 	}
 
-	g_mutex.RUnlock()
-	return 0
 }
 
 /*
- * Interrupt handler called when the hardware adjusts the sample rate.
+ * Configure the hardware interface.
+ *
+ * This must be done before the first binding is registered.
  */
-func sampleRate(rate uint32) int {
-	g_sampleRate = rate
+func Configure(cfg Config) error {
+	g_mutex.Lock()
+	bindings := g_bindings
+	err := error(nil)
 
 	/*
-	 * Notify each binding about the change.
+	 * The backend cannot be changed while it is in use.
 	 */
-	for _, binding := range g_bindings {
-		binding.listener(rate)
+	if bindings != nil {
+		err = fmt.Errorf("%s", "Cannot configure hardware interface while bindings are registered.")
+	} else {
+		g_config = cfg
 	}
 
-	return 0
+	g_mutex.Unlock()
+	return err
 }
 
 /*
- * Initialize the hardware for signal processing.
+ * Checks whether this build supports the audio backend selected by a
+ * configuration.
+ */
+func Supported(cfg Config) error {
+	_, err := createBackend(cfg)
+	return err
+}
+
+/*
+ * Checks whether the audio backend selected by a configuration is available
+ * by opening and closing it again.
+ *
+ * This must not be called while bindings are registered.
  */
-func initialize() (*jack.Client, error) {
-	client, _ := jack.ClientOpen("go-dsp-guitar", jack.NoStartServer)
+func Probe(cfg Config) error {
+	b, err := createBackend(cfg)
 
 	/*
-	 * Check if we are connected to the JACK server.
+	 * Check if backend was created.
 	 */
-	if client == nil {
-		return nil, fmt.Errorf("%s", "Could not connect to JACK server.")
+	if err != nil {
+		return err
 	} else {
-		statusProcess := client.SetProcessCallback(process)
+		err = b.open()
 
 		/*
-		 * Check if we could register our application as a signal processor.
+		 * Close the backend if it could be opened.
 		 */
-		if statusProcess != 0 {
-			return nil, fmt.Errorf("%s", "Failed to set process callback.")
-		} else {
-			statusSampleRate := client.SetSampleRateCallback(sampleRate)
-
-			/*
-			 * Check if we could register a sample rate callback.
-			 */
-			if statusSampleRate != 0 {
-				return nil, fmt.Errorf("%s", "Failed to set sample rate callback.")
-			} else {
-				statusActivate := client.Activate()
-
-				/*
-				 * Check if we could activate JACK.
-				 */
-				if statusActivate != 0 {
-					return nil, fmt.Errorf("%s", "Failed to activate client.")
-				} else {
-					return client, nil
-				}
-
-			}
-
+		if err == nil {
+			b.close()
 		}
 
+		return err
 	}
 
 }
@@ -245,16 +276,55 @@ func DSPLoad() float32 {
 	g_mutex.RLock()
 
 	/*
-	 * Check if client is registered.
+	 * Check if backend is open.
 	 */
-	if g_client != nil {
-		res = g_client.CPULoad()
+	if g_backend != nil {
+		res = g_backend.cpuLoad()
 	}
 
 	g_mutex.RUnlock()
 	return res
 }
 
+/*
+ * Get the number of buffer over- and underruns since startup.
+ */
+func Xruns() uint32 {
+	xruns := atomic.LoadUint32(&g_xruns)
+	return xruns
+}
+
+/*
+ * Get the number of playback buffer underruns since startup.
+ *
+ * Not all backends can tell underruns apart from other xruns, so this may be
+ * lower than the total number of xruns.
+ */
+func Underruns() uint32 {
+	underruns := atomic.LoadUint32(&g_underruns)
+	return underruns
+}
+
+/*
+ * Get the time of the last buffer over- or underrun.
+ *
+ * Returns the zero time if no xrun occured since startup.
+ */
+func LastXrun() time.Time {
+	nanoseconds := atomic.LoadInt64(&g_lastXrun)
+
+	/*
+	 * Check if an xrun occured at all.
+	 */
+	if nanoseconds == 0 {
+		return time.Time{}
+	} else {
+		t := time.Unix(0, nanoseconds)
+		return t
+	}
+
+}
+
 /*
  * Get frames per period.
  */
@@ -263,10 +333,10 @@ func FramesPerPeriod() uint32 {
 	g_mutex.RLock()
 
 	/*
-	 * Check if client is registered.
+	 * Check if backend is open.
 	 */
-	if g_client != nil {
-		res = g_client.GetBufferSize()
+	if g_backend != nil {
+		res = g_backend.bufferSize()
 	}
 
 	g_mutex.RUnlock()
@@ -274,9 +344,10 @@ func FramesPerPeriod() uint32 {
 }
 
 /*
- * Register a binding to a hardware interface.
+ * Register a binding to a hardware interface, creating an input port for each
+ * input name and an output port for each output name.
  */
-func Register(processor Processor, listener SampleRateListener) (*Binding, error) {
+func Register(inputNames []string, outputNames []string, processor Processor, listener SampleRateListener) (*Binding, error) {
 	err := error(nil)
 	g_mutex.RLock()
 
@@ -286,10 +357,15 @@ func Register(processor Processor, listener SampleRateListener) (*Binding, error
 	if g_bindings == nil {
 		g_mutex.RUnlock()
 		g_mutex.Lock()
-		g_client, err = initialize()
-		g_bindings = []*Binding{}
-		g_inputBuffers = make([][]float64, INPUT_CHANNELS)
-		g_outputBuffers = make([][]float64, OUTPUT_CHANNELS)
+		g_backend, err = initialize()
+
+		/*
+		 * Only keep track of bindings if hardware was initialized.
+		 */
+		if err == nil {
+			g_bindings = []*Binding{}
+		}
+
 		g_mutex.Unlock()
 		g_mutex.RLock()
 	}
@@ -302,56 +378,185 @@ func Register(processor Processor, listener SampleRateListener) (*Binding, error
 	if err != nil {
 		return nil, err
 	} else {
-		inputs := make([]*jack.Port, INPUT_CHANNELS)
-		outputs := make([]*jack.Port, OUTPUT_CHANNELS)
+		numInputs := len(inputNames)
+		numOutputs := len(outputNames)
 
 		/*
-		 * Create input and output for each input channel.
+		 * Create hardware binding.
 		 */
-		for idx, _ := range inputs {
-			idxLong := int64(idx)
-			sChannelNumber := strconv.FormatInt(idxLong, 10)
-			inputName := "in_" + sChannelNumber
-			inputs[idx] = g_client.PortRegister(inputName, jack.DEFAULT_AUDIO_TYPE, jack.PortIsInput, 0)
-			outputName := "out_" + sChannelNumber
-			outputs[idx] = g_client.PortRegister(outputName, jack.DEFAULT_AUDIO_TYPE, jack.PortIsOutput, 0)
+		binding := &Binding{
+			inputNames:    inputNames,
+			outputNames:   outputNames,
+			inputBuffers:  make([][]float64, numInputs),
+			outputBuffers: make([][]float64, numOutputs),
+			processor:     processor,
+			listener:      listener,
 		}
 
+		g_mutex.Lock()
+		err = g_backend.registerPorts(binding)
+
 		/*
-		 * Names of additional channels to register.
+		 * Only add binding if its ports were registered.
 		 */
-		additionalChannels := []string{
-			"master_left",
-			"master_right",
-			"metronome",
+		if err == nil {
+			g_bindings = append(g_bindings, binding)
 		}
 
-		nAdditional := len(additionalChannels)
-		baseIdx := OUTPUT_CHANNELS - nAdditional
+		g_mutex.Unlock()
 
 		/*
-		 * Register additional channels.
+		 * Check if ports were registered.
 		 */
-		for i, additionalChannel := range additionalChannels {
-			idx := baseIdx + i
-			outputs[idx] = g_client.PortRegister(additionalChannel, jack.DEFAULT_AUDIO_TYPE, jack.PortIsOutput, 0)
+		if err != nil {
+			return nil, err
+		} else {
+			sampleRate(g_sampleRate)
+			return binding, nil
 		}
 
+	}
+
+}
+
+/*
+ * Changes the port names of a binding, keeping the buffers of ports which
+ * keep their names.
+ *
+ * The global lock must be held for writing.
+ */
+func setPortNames(binding *Binding, inputNames []string, outputNames []string) {
+	numInputs := len(inputNames)
+	numOutputs := len(outputNames)
+	inputBuffers := make([][]float64, numInputs)
+	outputBuffers := make([][]float64, numOutputs)
+
+	/*
+	 * Take over the buffer of each input port which already existed.
+	 */
+	for i, name := range inputNames {
+		idx := portIndex(binding.inputNames, name)
+
 		/*
-		 * Create hardware binding.
+		 * Check if the port already existed.
 		 */
-		binding := &Binding{
-			inputs:    inputs,
-			outputs:   outputs,
-			processor: processor,
-			listener:  listener,
+		if idx >= 0 {
+			inputBuffers[i] = binding.inputBuffers[idx]
+		}
+
+	}
+
+	/*
+	 * Take over the buffer of each output port which already existed.
+	 */
+	for i, name := range outputNames {
+		idx := portIndex(binding.outputNames, name)
+
+		/*
+		 * Check if the port already existed.
+		 */
+		if idx >= 0 {
+			outputBuffers[i] = binding.outputBuffers[idx]
+		}
+
+	}
+
+	binding.inputNames = inputNames
+	binding.outputNames = outputNames
+	binding.inputBuffers = inputBuffers
+	binding.outputBuffers = outputBuffers
+}
+
+/*
+ * Returns the index of a port name in a list of port names or -1 if the list
+ * does not contain it.
+ */
+func portIndex(names []string, name string) int {
+
+	/*
+	 * Compare each name in the list.
+	 */
+	for i, currentName := range names {
+
+		/*
+		 * Check if we found the name.
+		 */
+		if currentName == name {
+			return i
+		}
+
+	}
+
+	return -1
+}
+
+/*
+ * Changes the ports of a registered binding, creating an input port for each
+ * input name and an output port for each output name.
+ *
+ * Ports which keep their names are not recreated, so their connections
+ * persist. Ports whose names are no longer present are removed.
+ */
+func Rebind(binding *Binding, inputNames []string, outputNames []string) error {
+	g_mutex.RLock()
+	backend := g_backend
+	g_mutex.RUnlock()
+
+	/*
+	 * Check if backend is open.
+	 */
+	if backend == nil {
+		return fmt.Errorf("%s", "Hardware interface is not initialized.")
+	} else {
+		err := backend.rebindPorts(binding, inputNames, outputNames)
+		return err
+	}
+
+}
+
+/*
+ * Register a MIDI input port for a binding and return a channel, which
+ * receives the program number of each program change arriving at the port.
+ *
+ * Program changes are delivered on any MIDI channel. If they are not
+ * consumed fast enough, further program changes are dropped.
+ */
+func ListenProgramChanges(binding *Binding, portName string) (<-chan uint8, error) {
+	programChanges := make(chan uint8, PROGRAM_CHANGE_LENGTH)
+	g_mutex.Lock()
+	backend := g_backend
+	err := error(nil)
+
+	/*
+	 * Check if backend is open.
+	 */
+	if backend == nil {
+		err = fmt.Errorf("%s", "Hardware interface is not initialized.")
+	} else if binding.programChanges != nil {
+		err = fmt.Errorf("%s", "Binding already has a MIDI input.")
+	} else {
+		binding.midiInputName = portName
+		binding.programChanges = programChanges
+		err = backend.registerMidiInput(binding)
+
+		/*
+		 * Do not deliver program changes if the port was not registered.
+		 */
+		if err != nil {
+			binding.programChanges = nil
 		}
 
-		g_mutex.Lock()
-		g_bindings = append(g_bindings, binding)
-		g_mutex.Unlock()
-		sampleRate(g_sampleRate)
-		return binding, nil
+	}
+
+	g_mutex.Unlock()
+
+	/*
+	 * Check if the MIDI input was registered.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		return programChanges, nil
 	}
 
 }
@@ -363,10 +568,10 @@ func SetFramesPerPeriod(n uint32) {
 	g_mutex.RLock()
 
 	/*
-	 * Check if client is registered.
+	 * Check if backend is open.
 	 */
-	if g_client != nil {
-		g_client.SetBufferSize(n)
+	if g_backend != nil {
+		g_backend.setBufferSize(n)
 	}
 
 	g_mutex.RUnlock()
@@ -397,39 +602,30 @@ func Unregister(binding *Binding) {
 	 * If we found the binding, remove it.
 	 */
 	if idx > 0 {
-		inputs := binding.inputs
-		outputs := binding.outputs
 		idxInc := idx + 1
 		g_mutex.RUnlock()
 		g_mutex.Lock()
-
-		/*
-		 * Unregister all input ports.
-		 */
-		for _, port := range inputs {
-			g_client.PortUnregister(port)
-		}
-
-		/*
-		 * Unregister all output ports.
-		 */
-		for _, port := range outputs {
-			g_client.PortUnregister(port)
-		}
-
+		g_backend.unregisterPorts(binding)
 		g_bindings = append(g_bindings[:idx], g_bindings[idxInc:]...)
 		g_mutex.Unlock()
 		g_mutex.RLock()
 	}
 
 	/*
-	 * If no bindings exist, terminate connection to JACK.
+	 * If no bindings exist, shut down the backend.
 	 */
 	if len(g_bindings) == 0 {
 		g_mutex.RUnlock()
 		g_mutex.Lock()
-		g_client.Close()
-		g_client = nil
+
+		/*
+		 * Check if backend is open.
+		 */
+		if g_backend != nil {
+			g_backend.close()
+		}
+
+		g_backend = nil
 		g_bindings = nil
 		g_mutex.Unlock()
 		g_mutex.RLock()
@@ -445,11 +641,66 @@ func Connect(sourcePort string, destinationPort string) {
 	g_mutex.RLock()
 
 	/*
-	 * Check if client is registered.
+	 * Check if backend is open.
+	 */
+	if g_backend != nil {
+		g_backend.connect(sourcePort, destinationPort)
+	}
+
+	g_mutex.RUnlock()
+}
+
+/*
+ * Disconnects a source port from a destination port.
+ */
+func Disconnect(sourcePort string, destinationPort string) {
+	g_mutex.RLock()
+
+	/*
+	 * Check if backend is open.
+	 */
+	if g_backend != nil {
+		g_backend.disconnect(sourcePort, destinationPort)
+	}
+
+	g_mutex.RUnlock()
+}
+
+/*
+ * Returns the names of all ports which may be connected, the ports which may
+ * act as a source first, followed by the ports which may act as a
+ * destination.
+ */
+func Ports() ([]string, []string) {
+	sources := []string{}
+	destinations := []string{}
+	g_mutex.RLock()
+
+	/*
+	 * Check if backend is open.
+	 */
+	if g_backend != nil {
+		sources, destinations = g_backend.ports()
+	}
+
+	g_mutex.RUnlock()
+	return sources, destinations
+}
+
+/*
+ * Returns all connections between ports.
+ */
+func Connections() []Connection {
+	connections := []Connection{}
+	g_mutex.RLock()
+
+	/*
+	 * Check if backend is open.
 	 */
-	if g_client != nil {
-		g_client.Connect(sourcePort, destinationPort)
+	if g_backend != nil {
+		connections = g_backend.connections()
 	}
 
 	g_mutex.RUnlock()
+	return connections
 }
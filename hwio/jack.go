@@ -0,0 +1,553 @@
+//go:build !nojack
+// +build !nojack
+
+package hwio
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-jack"
+)
+
+/*
+ * Data structure representing the JACK backend.
+ */
+type jackBackend struct {
+	client *jack.Client
+}
+
+/*
+ * Data structure representing the JACK ports registered for a binding.
+ */
+type jackPorts struct {
+	inputs    []*jack.Port
+	outputs   []*jack.Port
+	midiInput *jack.Port
+}
+
+/*
+ * Returns the JACK ports registered for a binding, creating an empty set of
+ * ports if there are none yet.
+ */
+func portsOf(binding *Binding) *jackPorts {
+	ports, ok := binding.ports.(*jackPorts)
+
+	/*
+	 * Create empty set of ports if binding has none.
+	 */
+	if !ok {
+		ports = &jackPorts{}
+		binding.ports = ports
+	}
+
+	return ports
+}
+
+/*
+ * Convert audio samples to floating-point numbers.
+ */
+func samplesToFloats(in []jack.AudioSample, out []float64) error {
+
+	/*
+	 * Verify that the output buffer has an appropriate size
+	 */
+	if len(out) < len(in) {
+		return fmt.Errorf("%s", "Cannot convert samples to floats: Output buffer is too small.")
+	} else {
+
+		/*
+		 * Convert each audio sample to a floating-point number.
+		 */
+		for i, sample := range in {
+			out[i] = float64(sample)
+		}
+
+		return nil
+	}
+
+}
+
+/*
+ * Convert floating-point numbers to audio samples.
+ */
+func floatsToSamples(in []float64, out []jack.AudioSample) error {
+
+	/*
+	 * Verify that the output buffer has an appropriate size
+	 */
+	if len(out) < len(in) {
+		return fmt.Errorf("%s", "Cannot convert floats to samples: Output buffer is too small.")
+	} else {
+
+		/*
+		 * Convert each floating-point number to an audio sample.
+		 */
+		for i, sample := range in {
+			out[i] = jack.AudioSample(sample)
+		}
+
+		return nil
+	}
+
+}
+
+/*
+ * Interrupt handler called when the hardware has audio to process.
+ */
+func process(nframes uint32) int {
+	g_mutex.RLock()
+
+	/*
+	 * Process audio for each binding.
+	 */
+	for _, binding := range g_bindings {
+		ports, _ := binding.ports.(*jackPorts)
+
+		/*
+		 * Skip bindings which have no ports yet.
+		 */
+		if ports == nil {
+			continue
+		}
+
+		inputs := ports.inputs
+		outputs := ports.outputs
+		inputBuffers := binding.inputBuffers
+		outputBuffers := binding.outputBuffers
+
+		/*
+		 * Read audio from each input channel.
+		 */
+		for i, input := range inputs {
+			hwInputBuffer := input.GetBuffer(nframes)
+			bufferSize := len(hwInputBuffer)
+
+			/*
+			 * Ensure the size of the current input buffer matches the size of the hardware buffer.
+			 */
+			if len(inputBuffers[i]) != bufferSize {
+				inputBuffers[i] = make([]float64, bufferSize)
+			}
+
+			err := samplesToFloats(hwInputBuffer, inputBuffers[i])
+
+			/*
+			 * If conversion failed, log error, otherwise perform processing.
+			 */
+			if err != nil {
+				msg := err.Error()
+				fmt.Printf("Error in real-time thread: %s", msg)
+			}
+
+		}
+
+		/*
+		 * Prepare output buffer for each output channel.
+		 */
+		for i, output := range outputs {
+			hwOutputBuffer := output.GetBuffer(nframes)
+			bufferSize := len(hwOutputBuffer)
+
+			/*
+			 * Ensure the size of the current output buffer matches the size of the hardware buffer.
+			 */
+			if len(outputBuffers[i]) != bufferSize {
+				outputBuffers[i] = make([]float64, bufferSize)
+			}
+
+		}
+
+		midiInput := ports.midiInput
+
+		/*
+		 * Pass program changes arriving at the MIDI input on to the
+		 * binding, without blocking the real-time thread.
+		 */
+		if midiInput != nil {
+			events := midiInput.GetMidiEvents(nframes)
+
+			/*
+			 * Look for program changes among the MIDI events.
+			 */
+			for _, event := range events {
+				data := event.Buffer
+
+				/*
+				 * Check if event is a program change.
+				 */
+				if (len(data) >= 2) && ((data[0] & MIDI_STATUS_MASK) == MIDI_PROGRAM_CHANGE) {
+					program := data[1]
+
+					/*
+					 * Drop the program change if it is not consumed.
+					 */
+					select {
+					case binding.programChanges <- program:
+					default:
+					}
+
+				}
+
+			}
+
+		}
+
+		binding.processor(inputBuffers, outputBuffers, g_sampleRate)
+
+		/*
+		 * Write audio to each output channel.
+		 */
+		for i, output := range outputs {
+			hwOutputBuffer := output.GetBuffer(nframes)
+			err := floatsToSamples(outputBuffers[i], hwOutputBuffer)
+
+			/*
+			 * If conversion failed, log error.
+			 */
+			if err != nil {
+				msg := err.Error()
+				fmt.Printf("Error in real-time thread: %s", msg)
+			}
+
+		}
+
+	}
+
+	g_mutex.RUnlock()
+	return 0
+}
+
+/*
+ * Interrupt handler called when JACK detects a buffer over- or underrun.
+ */
+func xrun() int {
+	countXrun()
+	return 0
+}
+
+/*
+ * Connect to the JACK server and register our callbacks.
+ */
+func (this *jackBackend) open() error {
+	client, _ := jack.ClientOpen(CLIENT_NAME, jack.NoStartServer)
+
+	/*
+	 * Check if we are connected to the JACK server.
+	 */
+	if client == nil {
+		return fmt.Errorf("%s", "Could not connect to JACK server.")
+	} else {
+		statusProcess := client.SetProcessCallback(process)
+
+		/*
+		 * Check if we could register our application as a signal processor.
+		 */
+		if statusProcess != 0 {
+			return fmt.Errorf("%s", "Failed to set process callback.")
+		} else {
+			statusSampleRate := client.SetSampleRateCallback(sampleRate)
+
+			/*
+			 * Check if we could register a sample rate callback.
+			 */
+			if statusSampleRate != 0 {
+				return fmt.Errorf("%s", "Failed to set sample rate callback.")
+			} else {
+
+				/*
+				 * Counting xruns is optional, so we do not care whether
+				 * the callback could be registered.
+				 */
+				client.SetXRunCallback(xrun)
+				statusActivate := client.Activate()
+
+				/*
+				 * Check if we could activate JACK.
+				 */
+				if statusActivate != 0 {
+					return fmt.Errorf("%s", "Failed to activate client.")
+				} else {
+					this.client = client
+					return nil
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Terminate the connection to the JACK server.
+ */
+func (this *jackBackend) close() {
+	this.client.Close()
+	this.client = nil
+}
+
+/*
+ * Get DSP load.
+ */
+func (this *jackBackend) cpuLoad() float32 {
+	return this.client.CPULoad()
+}
+
+/*
+ * Get frames per period.
+ */
+func (this *jackBackend) bufferSize() uint32 {
+	return this.client.GetBufferSize()
+}
+
+/*
+ * Set frames per period.
+ */
+func (this *jackBackend) setBufferSize(n uint32) {
+	this.client.SetBufferSize(n)
+}
+
+/*
+ * Register a JACK port for each input and output of a binding.
+ */
+func (this *jackBackend) registerPorts(binding *Binding) error {
+	client := this.client
+	inputNames := binding.inputNames
+	outputNames := binding.outputNames
+	numInputs := len(inputNames)
+	numOutputs := len(outputNames)
+	inputs := make([]*jack.Port, numInputs)
+	outputs := make([]*jack.Port, numOutputs)
+
+	/*
+	 * Register input ports.
+	 */
+	for i, inputName := range inputNames {
+		inputs[i] = client.PortRegister(inputName, jack.DEFAULT_AUDIO_TYPE, jack.PortIsInput, 0)
+	}
+
+	/*
+	 * Register output ports.
+	 */
+	for i, outputName := range outputNames {
+		outputs[i] = client.PortRegister(outputName, jack.DEFAULT_AUDIO_TYPE, jack.PortIsOutput, 0)
+	}
+
+	ports := portsOf(binding)
+	ports.inputs = inputs
+	ports.outputs = outputs
+	return nil
+}
+
+/*
+ * Returns a JACK port for each name, reusing the ports of a previous list of
+ * names and registering new ports for names which were not in that list.
+ *
+ * Also returns the previous ports which are not reused.
+ */
+func (this *jackBackend) reusePorts(previousNames []string, previousPorts []*jack.Port, names []string, flags uint64) ([]*jack.Port, []*jack.Port) {
+	client := this.client
+	numNames := len(names)
+	ports := make([]*jack.Port, numNames)
+	reused := make([]bool, len(previousPorts))
+
+	/*
+	 * Reuse or register a port for each name.
+	 */
+	for i, name := range names {
+		idx := portIndex(previousNames, name)
+
+		/*
+		 * Check if a port with this name already exists.
+		 */
+		if (idx >= 0) && (idx < len(previousPorts)) {
+			ports[i] = previousPorts[idx]
+			reused[idx] = true
+		} else {
+			ports[i] = client.PortRegister(name, jack.DEFAULT_AUDIO_TYPE, flags, 0)
+		}
+
+	}
+
+	unused := []*jack.Port{}
+
+	/*
+	 * Collect the ports which are no longer needed.
+	 */
+	for i, port := range previousPorts {
+
+		/*
+		 * Check if the port was not reused.
+		 */
+		if !reused[i] {
+			unused = append(unused, port)
+		}
+
+	}
+
+	return ports, unused
+}
+
+/*
+ * Change the JACK ports of a binding.
+ *
+ * Registering and unregistering ports may have to wait for the real-time
+ * thread, so this only holds the global lock while the binding is changed.
+ */
+func (this *jackBackend) rebindPorts(binding *Binding, inputNames []string, outputNames []string) error {
+	client := this.client
+	ports := portsOf(binding)
+	inputs, unusedInputs := this.reusePorts(binding.inputNames, ports.inputs, inputNames, jack.PortIsInput)
+	outputs, unusedOutputs := this.reusePorts(binding.outputNames, ports.outputs, outputNames, jack.PortIsOutput)
+	g_mutex.Lock()
+	ports.inputs = inputs
+	ports.outputs = outputs
+	setPortNames(binding, inputNames, outputNames)
+	g_mutex.Unlock()
+
+	/*
+	 * Unregister input ports which are no longer needed.
+	 */
+	for _, port := range unusedInputs {
+		client.PortUnregister(port)
+	}
+
+	/*
+	 * Unregister output ports which are no longer needed.
+	 */
+	for _, port := range unusedOutputs {
+		client.PortUnregister(port)
+	}
+
+	return nil
+}
+
+/*
+ * Register a JACK MIDI port as the MIDI input of a binding.
+ */
+func (this *jackBackend) registerMidiInput(binding *Binding) error {
+	client := this.client
+	name := binding.midiInputName
+	port := client.PortRegister(name, jack.DEFAULT_MIDI_TYPE, jack.PortIsInput, 0)
+
+	/*
+	 * Check if port was registered.
+	 */
+	if port == nil {
+		return fmt.Errorf("Failed to register MIDI input port '%s'.", name)
+	} else {
+		ports := portsOf(binding)
+		ports.midiInput = port
+		return nil
+	}
+
+}
+
+/*
+ * Unregister the JACK ports of a binding.
+ */
+func (this *jackBackend) unregisterPorts(binding *Binding) {
+	client := this.client
+	ports := portsOf(binding)
+
+	/*
+	 * Unregister all input ports.
+	 */
+	for _, port := range ports.inputs {
+		client.PortUnregister(port)
+	}
+
+	/*
+	 * Unregister all output ports.
+	 */
+	for _, port := range ports.outputs {
+		client.PortUnregister(port)
+	}
+
+	midiInput := ports.midiInput
+
+	/*
+	 * Unregister the MIDI input port, if any.
+	 */
+	if midiInput != nil {
+		client.PortUnregister(midiInput)
+		ports.midiInput = nil
+	}
+
+}
+
+/*
+ * Connects a source port to a destination port.
+ */
+func (this *jackBackend) connect(sourcePort string, destinationPort string) {
+	this.client.Connect(sourcePort, destinationPort)
+}
+
+/*
+ * Disconnects a source port from a destination port.
+ */
+func (this *jackBackend) disconnect(sourcePort string, destinationPort string) {
+	this.client.Disconnect(sourcePort, destinationPort)
+}
+
+/*
+ * Returns the names of all audio ports, the output ports first, followed by
+ * the input ports.
+ */
+func (this *jackBackend) ports() ([]string, []string) {
+	client := this.client
+	sources := client.GetPorts("", jack.DEFAULT_AUDIO_TYPE, jack.PortIsOutput)
+	destinations := client.GetPorts("", jack.DEFAULT_AUDIO_TYPE, jack.PortIsInput)
+	return sources, destinations
+}
+
+/*
+ * Returns all connections between audio ports.
+ */
+func (this *jackBackend) connections() []Connection {
+	client := this.client
+	sources := client.GetPorts("", jack.DEFAULT_AUDIO_TYPE, jack.PortIsOutput)
+	connections := []Connection{}
+
+	/*
+	 * Find the connections of each output port.
+	 */
+	for _, source := range sources {
+		port := client.GetPortByName(source)
+
+		/*
+		 * Check if port still exists.
+		 */
+		if port != nil {
+			destinations := port.GetConnections()
+
+			/*
+			 * Describe each connection.
+			 */
+			for _, destination := range destinations {
+
+				/*
+				 * The connection between both ports.
+				 */
+				connection := Connection{
+					Source:      source,
+					Destination: destination,
+				}
+
+				connections = append(connections, connection)
+			}
+
+		}
+
+	}
+
+	return connections
+}
+
+/*
+ * Creates a JACK backend.
+ */
+func createJackBackend() (backend, error) {
+	b := jackBackend{}
+	return &b, nil
+}
@@ -0,0 +1,17 @@
+//go:build nojack
+// +build nojack
+
+package hwio
+
+import (
+	"fmt"
+)
+
+/*
+ * Creates a JACK backend.
+ *
+ * This build does not include JACK support.
+ */
+func createJackBackend() (backend, error) {
+	return nil, fmt.Errorf("%s", "This build does not support JACK. Rebuild without '-tags nojack' to enable it.")
+}
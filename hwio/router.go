@@ -0,0 +1,597 @@
+package hwio
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	CAPTURE_PREFIX  = "system:capture_"
+	PLAYBACK_PREFIX = "system:playback_"
+)
+
+/*
+ * Data structure representing a route between a hardware channel and a port
+ * of a binding.
+ */
+type routeStruct struct {
+	binding *Binding
+	port    int
+}
+
+/*
+ * Data structure routing hardware channels to the ports of bindings and
+ * back, for backends which cannot connect ports themselves.
+ */
+type routerStruct struct {
+	mutex            sync.Mutex
+	captureChannels  int
+	playbackChannels int
+	inputRoutes      [][]routeStruct
+	outputRoutes     [][]routeStruct
+}
+
+/*
+ * Removes all routes belonging to a binding from a list of routes.
+ */
+func removeRoutes(routes []routeStruct, binding *Binding) []routeStruct {
+	routesNew := []routeStruct{}
+
+	/*
+	 * Keep all routes of other bindings.
+	 */
+	for _, route := range routes {
+
+		/*
+		 * Check if route belongs to another binding.
+		 */
+		if route.binding != binding {
+			routesNew = append(routesNew, route)
+		}
+
+	}
+
+	return routesNew
+}
+
+/*
+ * Remove all routes to or from the ports of a binding.
+ */
+func (this *routerStruct) removeBinding(binding *Binding) {
+	this.mutex.Lock()
+
+	/*
+	 * Remove the routes from each capture channel.
+	 */
+	for i, routes := range this.inputRoutes {
+		this.inputRoutes[i] = removeRoutes(routes, binding)
+	}
+
+	/*
+	 * Remove the routes to each playback channel.
+	 */
+	for i, routes := range this.outputRoutes {
+		this.outputRoutes[i] = removeRoutes(routes, binding)
+	}
+
+	this.mutex.Unlock()
+}
+
+/*
+ * Moves the routes of a binding to the ports which now carry the names of
+ * the ports they were connected to, dropping routes to ports which no longer
+ * exist.
+ */
+func remapRoutes(routes []routeStruct, binding *Binding, previousNames []string, names []string) []routeStruct {
+	routesNew := []routeStruct{}
+
+	/*
+	 * Remap each route belonging to the binding.
+	 */
+	for _, route := range routes {
+
+		/*
+		 * Keep routes of other bindings as they are.
+		 */
+		if route.binding != binding {
+			routesNew = append(routesNew, route)
+		} else if route.port < len(previousNames) {
+			name := previousNames[route.port]
+			port := portIndex(names, name)
+
+			/*
+			 * Check if the port still exists.
+			 */
+			if port >= 0 {
+				route.port = port
+				routesNew = append(routesNew, route)
+			}
+
+		}
+
+	}
+
+	return routesNew
+}
+
+/*
+ * Change the ports of a binding, keeping the routes of ports which keep
+ * their names.
+ */
+func (this *routerStruct) rebindPorts(binding *Binding, inputNames []string, outputNames []string) {
+	g_mutex.Lock()
+	this.mutex.Lock()
+	previousInputNames := binding.inputNames
+	previousOutputNames := binding.outputNames
+
+	/*
+	 * Remap the routes from each capture channel.
+	 */
+	for i, routes := range this.inputRoutes {
+		this.inputRoutes[i] = remapRoutes(routes, binding, previousInputNames, inputNames)
+	}
+
+	/*
+	 * Remap the routes to each playback channel.
+	 */
+	for i, routes := range this.outputRoutes {
+		this.outputRoutes[i] = remapRoutes(routes, binding, previousOutputNames, outputNames)
+	}
+
+	setPortNames(binding, inputNames, outputNames)
+	this.mutex.Unlock()
+	g_mutex.Unlock()
+}
+
+/*
+ * Parses the name of a system port into a zero-based channel index.
+ *
+ * Returns -1 if the name does not refer to a valid channel.
+ */
+func parseSystemPort(name string, prefix string, channels int) int {
+
+	/*
+	 * Check if the port belongs to the hardware.
+	 */
+	if !strings.HasPrefix(name, prefix) {
+		return -1
+	} else {
+		suffix := strings.TrimPrefix(name, prefix)
+		channel, err := strconv.Atoi(suffix)
+
+		/*
+		 * Channels are numbered starting at one.
+		 */
+		if (err != nil) || (channel < 1) || (channel > channels) {
+			return -1
+		} else {
+			return channel - 1
+		}
+
+	}
+
+}
+
+/*
+ * Finds a port of a binding by its full name.
+ *
+ * Returns a nil binding if the port cannot be found.
+ */
+func findBindingPort(name string, input bool) (*Binding, int) {
+	prefix := CLIENT_NAME + ":"
+
+	/*
+	 * Check if the port belongs to this client.
+	 */
+	if strings.HasPrefix(name, prefix) {
+		portName := strings.TrimPrefix(name, prefix)
+
+		/*
+		 * Search all bindings for the port.
+		 */
+		for _, binding := range g_bindings {
+			names := binding.outputNames
+
+			/*
+			 * Check if we look for an input port.
+			 */
+			if input {
+				names = binding.inputNames
+			}
+
+			/*
+			 * Compare the name of each port.
+			 */
+			for i, currentName := range names {
+
+				/*
+				 * Check if we found the port.
+				 */
+				if currentName == portName {
+					return binding, i
+				}
+
+			}
+
+		}
+
+	}
+
+	return nil, -1
+}
+
+/*
+ * Connects a source port to a destination port.
+ *
+ * Hardware channels are named 'system:capture_N' and 'system:playback_N',
+ * just as they are with JACK.
+ */
+func (this *routerStruct) connect(sourcePort string, destinationPort string) {
+	captureChannel := parseSystemPort(sourcePort, CAPTURE_PREFIX, this.captureChannels)
+	playbackChannel := parseSystemPort(destinationPort, PLAYBACK_PREFIX, this.playbackChannels)
+	this.mutex.Lock()
+
+	/*
+	 * Either route a capture channel to an input port or an output port to
+	 * a playback channel.
+	 */
+	if captureChannel >= 0 {
+		binding, port := findBindingPort(destinationPort, true)
+
+		/*
+		 * Check if the destination port exists.
+		 */
+		if binding == nil {
+			fmt.Printf("Cannot connect '%s' to '%s': No such input port.\n", sourcePort, destinationPort)
+		} else {
+
+			/*
+			 * The route to the input port.
+			 */
+			route := routeStruct{
+				binding: binding,
+				port:    port,
+			}
+
+			routes := this.inputRoutes[captureChannel]
+			this.inputRoutes[captureChannel] = append(routes, route)
+		}
+
+	} else if playbackChannel >= 0 {
+		binding, port := findBindingPort(sourcePort, false)
+
+		/*
+		 * Check if the source port exists.
+		 */
+		if binding == nil {
+			fmt.Printf("Cannot connect '%s' to '%s': No such output port.\n", sourcePort, destinationPort)
+		} else {
+
+			/*
+			 * The route from the output port.
+			 */
+			route := routeStruct{
+				binding: binding,
+				port:    port,
+			}
+
+			routes := this.outputRoutes[playbackChannel]
+			this.outputRoutes[playbackChannel] = append(routes, route)
+		}
+
+	} else {
+		fmt.Printf("Cannot connect '%s' to '%s': Connections must run from a capture channel or to a playback channel.\n", sourcePort, destinationPort)
+	}
+
+	this.mutex.Unlock()
+}
+
+/*
+ * Disconnects a source port from a destination port.
+ */
+func (this *routerStruct) disconnect(sourcePort string, destinationPort string) {
+	captureChannel := parseSystemPort(sourcePort, CAPTURE_PREFIX, this.captureChannels)
+	playbackChannel := parseSystemPort(destinationPort, PLAYBACK_PREFIX, this.playbackChannels)
+	this.mutex.Lock()
+
+	/*
+	 * Either remove a route from a capture channel or to a playback
+	 * channel.
+	 */
+	if captureChannel >= 0 {
+		binding, port := findBindingPort(destinationPort, true)
+		routes := this.inputRoutes[captureChannel]
+		this.inputRoutes[captureChannel] = removeRoute(routes, binding, port)
+	} else if playbackChannel >= 0 {
+		binding, port := findBindingPort(sourcePort, false)
+		routes := this.outputRoutes[playbackChannel]
+		this.outputRoutes[playbackChannel] = removeRoute(routes, binding, port)
+	}
+
+	this.mutex.Unlock()
+}
+
+/*
+ * Removes the routes to a certain port of a binding from a list of routes.
+ */
+func removeRoute(routes []routeStruct, binding *Binding, port int) []routeStruct {
+	routesNew := []routeStruct{}
+
+	/*
+	 * Keep all routes to other ports.
+	 */
+	for _, route := range routes {
+
+		/*
+		 * Check if route leads to another port.
+		 */
+		if (route.binding != binding) || (route.port != port) {
+			routesNew = append(routesNew, route)
+		}
+
+	}
+
+	return routesNew
+}
+
+/*
+ * Returns the names of all ports which may be connected, the ports which
+ * may act as a source first, followed by the ports which may act as a
+ * destination.
+ */
+func (this *routerStruct) ports() ([]string, []string) {
+	sources := []string{}
+	destinations := []string{}
+
+	/*
+	 * Each capture channel is a source.
+	 */
+	for i := 0; i < this.captureChannels; i++ {
+		name := fmt.Sprintf("%s%d", CAPTURE_PREFIX, i+1)
+		sources = append(sources, name)
+	}
+
+	/*
+	 * Each playback channel is a destination.
+	 */
+	for i := 0; i < this.playbackChannels; i++ {
+		name := fmt.Sprintf("%s%d", PLAYBACK_PREFIX, i+1)
+		destinations = append(destinations, name)
+	}
+
+	/*
+	 * The outputs of each binding are sources, its inputs destinations.
+	 */
+	for _, binding := range g_bindings {
+
+		/*
+		 * Add each output port.
+		 */
+		for _, name := range binding.outputNames {
+			fullName := CLIENT_NAME + ":" + name
+			sources = append(sources, fullName)
+		}
+
+		/*
+		 * Add each input port.
+		 */
+		for _, name := range binding.inputNames {
+			fullName := CLIENT_NAME + ":" + name
+			destinations = append(destinations, fullName)
+		}
+
+	}
+
+	return sources, destinations
+}
+
+/*
+ * Returns all connections between ports.
+ */
+func (this *routerStruct) connections() []Connection {
+	connections := []Connection{}
+	this.mutex.Lock()
+
+	/*
+	 * Describe the routes from each capture channel.
+	 */
+	for i, routes := range this.inputRoutes {
+		source := fmt.Sprintf("%s%d", CAPTURE_PREFIX, i+1)
+
+		/*
+		 * Describe each route.
+		 */
+		for _, route := range routes {
+			name := route.binding.inputNames[route.port]
+
+			/*
+			 * The connection the route implements.
+			 */
+			connection := Connection{
+				Source:      source,
+				Destination: CLIENT_NAME + ":" + name,
+			}
+
+			connections = append(connections, connection)
+		}
+
+	}
+
+	/*
+	 * Describe the routes to each playback channel.
+	 */
+	for i, routes := range this.outputRoutes {
+		destination := fmt.Sprintf("%s%d", PLAYBACK_PREFIX, i+1)
+
+		/*
+		 * Describe each route.
+		 */
+		for _, route := range routes {
+			name := route.binding.outputNames[route.port]
+
+			/*
+			 * The connection the route implements.
+			 */
+			connection := Connection{
+				Source:      CLIENT_NAME + ":" + name,
+				Destination: destination,
+			}
+
+			connections = append(connections, connection)
+		}
+
+	}
+
+	this.mutex.Unlock()
+	return connections
+}
+
+/*
+ * Makes sure that a buffer can hold a certain number of samples.
+ */
+func ensureBufferSize(buffer []float64, size int) []float64 {
+
+	/*
+	 * Only reallocate the buffer if its size does not match.
+	 */
+	if len(buffer) != size {
+		return make([]float64, size)
+	} else {
+		return buffer
+	}
+
+}
+
+/*
+ * Runs all bindings on a period of interleaved captured audio and creates
+ * the interleaved audio for playback.
+ *
+ * The routes must not change while a period is processed.
+ */
+func (this *routerStruct) process(captureBuffer []float32, playbackBuffer []float32, frames int) {
+	captureChannels := this.captureChannels
+	playbackChannels := this.playbackChannels
+	g_mutex.RLock()
+	this.mutex.Lock()
+	inputRoutes := this.inputRoutes
+	outputRoutes := this.outputRoutes
+
+	/*
+	 * Prepare the buffers of each binding.
+	 */
+	for _, binding := range g_bindings {
+		inputBuffers := binding.inputBuffers
+		outputBuffers := binding.outputBuffers
+
+		/*
+		 * Clear each input buffer.
+		 */
+		for i, buffer := range inputBuffers {
+			buffer = ensureBufferSize(buffer, frames)
+
+			/*
+			 * Write zeros to input buffer.
+			 */
+			for j := range buffer {
+				buffer[j] = 0.0
+			}
+
+			inputBuffers[i] = buffer
+		}
+
+		/*
+		 * Make sure that each output buffer has the appropriate size.
+		 */
+		for i, buffer := range outputBuffers {
+			outputBuffers[i] = ensureBufferSize(buffer, frames)
+		}
+
+	}
+
+	/*
+	 * Mix each capture channel into the input ports it is routed to.
+	 */
+	for channel, routes := range inputRoutes {
+
+		/*
+		 * Process each route from the capture channel.
+		 */
+		for _, route := range routes {
+			buffer := route.binding.inputBuffers[route.port]
+
+			/*
+			 * Deinterleave the samples of the capture channel.
+			 */
+			for i := range buffer {
+				idx := (i * captureChannels) + channel
+				sample := captureBuffer[idx]
+				buffer[i] += float64(sample)
+			}
+
+		}
+
+	}
+
+	/*
+	 * Run the signal processor of each binding.
+	 */
+	for _, binding := range g_bindings {
+		binding.processor(binding.inputBuffers, binding.outputBuffers, g_sampleRate)
+	}
+
+	/*
+	 * Write zeros to playback buffer.
+	 */
+	for i := range playbackBuffer {
+		playbackBuffer[i] = 0.0
+	}
+
+	/*
+	 * Mix the output ports into each playback channel they are routed to.
+	 */
+	for channel, routes := range outputRoutes {
+
+		/*
+		 * Process each route to the playback channel.
+		 */
+		for _, route := range routes {
+			buffer := route.binding.outputBuffers[route.port]
+
+			/*
+			 * Interleave the samples of the output port.
+			 */
+			for i, sample := range buffer {
+				idx := (i * playbackChannels) + channel
+				playbackBuffer[idx] += float32(sample)
+			}
+
+		}
+
+	}
+
+	this.mutex.Unlock()
+	g_mutex.RUnlock()
+}
+
+/*
+ * Creates a router for a certain number of capture and playback channels.
+ */
+func createRouter(captureChannels int, playbackChannels int) *routerStruct {
+
+	/*
+	 * Create router.
+	 */
+	r := routerStruct{
+		captureChannels:  captureChannels,
+		playbackChannels: playbackChannels,
+		inputRoutes:      make([][]routeStruct, captureChannels),
+		outputRoutes:     make([][]routeStruct, playbackChannels),
+	}
+
+	return &r
+}
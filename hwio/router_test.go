@@ -0,0 +1,127 @@
+package hwio
+
+import (
+	"testing"
+)
+
+/*
+ * Verify that a router lists its ports and connections and that
+ * connections can be removed again.
+ */
+func TestRouterConnections(t *testing.T) {
+
+	/*
+	 * A binding with a single input and output port.
+	 */
+	binding := Binding{
+		inputNames:  []string{"in_0"},
+		outputNames: []string{"out_0"},
+	}
+
+	g_mutex.Lock()
+	g_bindings = []*Binding{&binding}
+	g_mutex.Unlock()
+
+	t.Cleanup(func() {
+		g_mutex.Lock()
+		g_bindings = nil
+		g_mutex.Unlock()
+	})
+
+	router := createRouter(2, 2)
+	sources, destinations := router.ports()
+	expectedSources := []string{"system:capture_1", "system:capture_2", "go-dsp-guitar:out_0"}
+	expectedDestinations := []string{"system:playback_1", "system:playback_2", "go-dsp-guitar:in_0"}
+
+	/*
+	 * Compare the sources.
+	 */
+	if len(sources) != len(expectedSources) {
+		t.Fatalf("Router should have %d sources, but has %d.", len(expectedSources), len(sources))
+	}
+
+	/*
+	 * Compare each source.
+	 */
+	for i, source := range sources {
+
+		/*
+		 * Check if source has the expected name.
+		 */
+		if source != expectedSources[i] {
+			t.Errorf("Source %d should be '%s', but is '%s'.", i, expectedSources[i], source)
+		}
+
+	}
+
+	/*
+	 * Compare the destinations.
+	 */
+	if len(destinations) != len(expectedDestinations) {
+		t.Fatalf("Router should have %d destinations, but has %d.", len(expectedDestinations), len(destinations))
+	}
+
+	/*
+	 * Compare each destination.
+	 */
+	for i, destination := range destinations {
+
+		/*
+		 * Check if destination has the expected name.
+		 */
+		if destination != expectedDestinations[i] {
+			t.Errorf("Destination %d should be '%s', but is '%s'.", i, expectedDestinations[i], destination)
+		}
+
+	}
+
+	router.connect("system:capture_2", "go-dsp-guitar:in_0")
+	router.connect("go-dsp-guitar:out_0", "system:playback_1")
+	connections := router.connections()
+
+	/*
+	 * The connections expected.
+	 */
+	expected := []Connection{
+		Connection{
+			Source:      "system:capture_2",
+			Destination: "go-dsp-guitar:in_0",
+		},
+		Connection{
+			Source:      "go-dsp-guitar:out_0",
+			Destination: "system:playback_1",
+		},
+	}
+
+	/*
+	 * Compare the connections.
+	 */
+	if len(connections) != len(expected) {
+		t.Fatalf("Router should have %d connections, but has %d.", len(expected), len(connections))
+	}
+
+	/*
+	 * Compare each connection.
+	 */
+	for i, connection := range connections {
+
+		/*
+		 * Check if connection matches.
+		 */
+		if connection != expected[i] {
+			t.Errorf("Connection %d should be %v, but is %v.", i, expected[i], connection)
+		}
+
+	}
+
+	router.disconnect("system:capture_2", "go-dsp-guitar:in_0")
+	connections = router.connections()
+
+	/*
+	 * Only the connection to the playback channel is left.
+	 */
+	if (len(connections) != 1) || (connections[0] != expected[1]) {
+		t.Errorf("Router should only have connection %v left, but has %v.", expected[1], connections)
+	}
+
+}
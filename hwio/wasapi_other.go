@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package hwio
+
+import (
+	"fmt"
+)
+
+/*
+ * Creates a WASAPI backend.
+ *
+ * WASAPI is only available on Windows.
+ */
+func createWasapiBackend(cfg WasapiConfig) (backend, error) {
+	return nil, fmt.Errorf("%s", "WASAPI is only available on Windows.")
+}
@@ -0,0 +1,788 @@
+package hwio
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+/*
+ * Default values for the WASAPI backend.
+ */
+const (
+	WASAPI_DEFAULT_FRAMES_PER_PERIOD = 256
+	WASAPI_DEFAULT_PERIODS           = 2
+	WASAPI_WAIT_TIMEOUT              = 2000
+)
+
+/*
+ * Constants of the Windows API.
+ */
+const (
+	WASAPI_COINIT_MULTITHREADED              = 0x0
+	WASAPI_CLSCTX_ALL                        = 0x17
+	WASAPI_E_RENDER                          = 0
+	WASAPI_E_CAPTURE                         = 1
+	WASAPI_E_CONSOLE                         = 0
+	WASAPI_AUDCLNT_SHAREMODE_SHARED          = 0
+	WASAPI_AUDCLNT_STREAMFLAGS_EVENTCALLBACK = 0x40000
+	WASAPI_AUDCLNT_BUFFERFLAGS_SILENT        = 0x2
+	WASAPI_WAVE_FORMAT_IEEE_FLOAT            = 0x3
+	WASAPI_WAVE_FORMAT_EXTENSIBLE            = 0xfffe
+	WASAPI_REFTIMES_PER_SECOND               = 10000000
+)
+
+/*
+ * Indices of methods in the virtual method tables of COM interfaces.
+ */
+const (
+	WASAPI_METHOD_RELEASE                    = 2
+	WASAPI_METHOD_GET_DEFAULT_AUDIO_ENDPOINT = 4
+	WASAPI_METHOD_ACTIVATE                   = 3
+	WASAPI_METHOD_INITIALIZE                 = 3
+	WASAPI_METHOD_GET_BUFFER_SIZE            = 4
+	WASAPI_METHOD_GET_CURRENT_PADDING        = 6
+	WASAPI_METHOD_GET_MIX_FORMAT             = 8
+	WASAPI_METHOD_START                      = 10
+	WASAPI_METHOD_STOP                       = 11
+	WASAPI_METHOD_SET_EVENT_HANDLE           = 13
+	WASAPI_METHOD_GET_SERVICE                = 14
+	WASAPI_METHOD_GET_BUFFER                 = 3
+	WASAPI_METHOD_RELEASE_BUFFER             = 4
+	WASAPI_METHOD_GET_NEXT_PACKET_SIZE       = 5
+)
+
+/*
+ * A globally unique identifier.
+ */
+type guidStruct struct {
+	data1 uint32
+	data2 uint16
+	data3 uint16
+	data4 [8]byte
+}
+
+/*
+ * The (extensible) format of a wave stream.
+ */
+type waveFormatStruct struct {
+	formatTag          uint16
+	channels           uint16
+	samplesPerSec      uint32
+	avgBytesPerSec     uint32
+	blockAlign         uint16
+	bitsPerSample      uint16
+	size               uint16
+	validBitsPerSample uint16
+	channelMask        uint32
+	subFormat          guidStruct
+}
+
+/*
+ * A COM object, which starts with a pointer to its virtual method table.
+ */
+type comObject struct {
+	vtbl *[16]uintptr
+}
+
+/*
+ * Data structure representing a WASAPI audio stream.
+ */
+type wasapiStreamStruct struct {
+	device       *comObject
+	client       *comObject
+	service      *comObject
+	format       *waveFormatStruct
+	channels     int
+	sampleRate   uint32
+	bufferFrames uint32
+}
+
+/*
+ * Data structure representing the WASAPI backend.
+ */
+type wasapiBackend struct {
+	mutex            sync.Mutex
+	framesPerPeriod  uint32
+	periods          uint32
+	captureChannels  int
+	playbackChannels int
+	load             float32
+	running          bool
+	router           *routerStruct
+	done             chan error
+}
+
+/*
+ * Global variables.
+ */
+var g_ole32 = syscall.NewLazyDLL("ole32.dll")
+var g_kernel32 = syscall.NewLazyDLL("kernel32.dll")
+var g_coInitializeEx = g_ole32.NewProc("CoInitializeEx")
+var g_coUninitialize = g_ole32.NewProc("CoUninitialize")
+var g_coCreateInstance = g_ole32.NewProc("CoCreateInstance")
+var g_coTaskMemFree = g_ole32.NewProc("CoTaskMemFree")
+var g_createEventW = g_kernel32.NewProc("CreateEventW")
+var g_waitForSingleObject = g_kernel32.NewProc("WaitForSingleObject")
+var g_closeHandle = g_kernel32.NewProc("CloseHandle")
+
+/*
+ * Identifiers of COM classes and interfaces.
+ */
+var g_clsidMMDeviceEnumerator = guidStruct{0xbcde0395, 0xe52f, 0x467c, [8]byte{0x8e, 0x3d, 0xc4, 0x57, 0x92, 0x91, 0x69, 0x2e}}
+var g_iidMMDeviceEnumerator = guidStruct{0xa95664d2, 0x9614, 0x4f35, [8]byte{0xa7, 0x46, 0xde, 0x8d, 0xb6, 0x36, 0x17, 0xe6}}
+var g_iidAudioClient = guidStruct{0x1cb9ad4c, 0xdbfa, 0x4c32, [8]byte{0xb1, 0x78, 0xc2, 0xf5, 0x68, 0xa7, 0x03, 0xb2}}
+var g_iidAudioRenderClient = guidStruct{0xf294acfc, 0x3146, 0x4483, [8]byte{0xa7, 0xbf, 0xad, 0xdc, 0xa7, 0xc2, 0x60, 0xe2}}
+var g_iidAudioCaptureClient = guidStruct{0xc8adbd64, 0xe71e, 0x48a0, [8]byte{0xa4, 0xde, 0x18, 0x5c, 0x39, 0x5c, 0xd3, 0x17}}
+var g_subtypeIeeeFloat = guidStruct{0x00000003, 0x0000, 0x0010, [8]byte{0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}}
+
+/*
+ * Calls a method of a COM object and returns the resulting HRESULT.
+ */
+func (this *comObject) call(method int, args ...uintptr) int32 {
+	fn := this.vtbl[method]
+	self := uintptr(unsafe.Pointer(this))
+	a := [8]uintptr{}
+	copy(a[:], args)
+	numArgs := len(args) + 1
+	numArgsPtr := uintptr(numArgs)
+	ret, _, _ := syscall.Syscall9(fn, numArgsPtr, self, a[0], a[1], a[2], a[3], a[4], a[5], a[6], a[7])
+	return int32(ret)
+}
+
+/*
+ * Releases a COM object.
+ */
+func (this *comObject) release() {
+
+	/*
+	 * Make sure that the object exists.
+	 */
+	if this != nil {
+		this.call(WASAPI_METHOD_RELEASE)
+	}
+
+}
+
+/*
+ * Converts a 64-bit integer into arguments for a system call.
+ *
+ * On 32-bit systems, it occupies two arguments.
+ */
+func int64Args(value int64) []uintptr {
+	ptrSize := unsafe.Sizeof(uintptr(0))
+
+	/*
+	 * Check if the integer fits into a single argument.
+	 */
+	if ptrSize == 8 {
+		return []uintptr{uintptr(value)}
+	} else {
+		low := uintptr(uint32(value))
+		high := uintptr(uint32(value >> 32))
+		return []uintptr{low, high}
+	}
+
+}
+
+/*
+ * Converts an HRESULT into an error.
+ */
+func hresultError(operation string, hr int32) error {
+	code := uint32(hr)
+	return fmt.Errorf("%s failed with HRESULT 0x%08x.", operation, code)
+}
+
+/*
+ * Checks whether a wave format consists of 32-bit floating-point samples.
+ */
+func isFloatFormat(format *waveFormatStruct) bool {
+	tag := format.formatTag
+	bits := format.bitsPerSample
+
+	/*
+	 * Check the format tag and, for extensible formats, the subformat.
+	 */
+	if bits != 32 {
+		return false
+	} else if tag == WASAPI_WAVE_FORMAT_IEEE_FLOAT {
+		return true
+	} else if tag == WASAPI_WAVE_FORMAT_EXTENSIBLE {
+		return format.subFormat == g_subtypeIeeeFloat
+	} else {
+		return false
+	}
+
+}
+
+/*
+ * Creates a slice referencing samples in a buffer provided by WASAPI.
+ */
+func sampleSlice(data *float32, size int) []float32 {
+	arr := (*[1 << 28]float32)(unsafe.Pointer(data))
+	return arr[:size:size]
+}
+
+/*
+ * Opens a stream on the default audio endpoint for capture or render.
+ */
+func (this *wasapiBackend) openStream(enumerator *comObject, dataFlow uintptr, serviceId *guidStruct, bufferFrames int64, event uintptr) (*wasapiStreamStruct, error) {
+	stream := wasapiStreamStruct{}
+	device := (*comObject)(nil)
+	devicePtr := uintptr(unsafe.Pointer(&device))
+	hr := enumerator.call(WASAPI_METHOD_GET_DEFAULT_AUDIO_ENDPOINT, dataFlow, WASAPI_E_CONSOLE, devicePtr)
+
+	/*
+	 * Check if there is a default endpoint.
+	 */
+	if hr < 0 {
+		return nil, hresultError("Obtaining default audio endpoint", hr)
+	} else {
+		stream.device = device
+		client := (*comObject)(nil)
+		iidPtr := uintptr(unsafe.Pointer(&g_iidAudioClient))
+		clientPtr := uintptr(unsafe.Pointer(&client))
+		hr = device.call(WASAPI_METHOD_ACTIVATE, iidPtr, WASAPI_CLSCTX_ALL, 0, clientPtr)
+
+		/*
+		 * Check if audio client was activated.
+		 */
+		if hr < 0 {
+			this.closeStream(&stream)
+			return nil, hresultError("Activating audio client", hr)
+		} else {
+			stream.client = client
+			format := (*waveFormatStruct)(nil)
+			formatPtr := uintptr(unsafe.Pointer(&format))
+			hr = client.call(WASAPI_METHOD_GET_MIX_FORMAT, formatPtr)
+
+			/*
+			 * Check if mix format was obtained.
+			 */
+			if hr < 0 {
+				this.closeStream(&stream)
+				return nil, hresultError("Obtaining mix format", hr)
+			} else {
+				stream.format = format
+
+				/*
+				 * We only support floating-point samples.
+				 */
+				if !isFloatFormat(format) {
+					this.closeStream(&stream)
+					return nil, fmt.Errorf("%s", "Mix format of audio endpoint is not 32-bit floating-point.")
+				} else {
+					flags := uintptr(0)
+
+					/*
+					 * Check if the stream should signal an event.
+					 */
+					if event != 0 {
+						flags = WASAPI_AUDCLNT_STREAMFLAGS_EVENTCALLBACK
+					}
+
+					sampleRate := int64(format.samplesPerSec)
+					bufferDuration := (WASAPI_REFTIMES_PER_SECOND * bufferFrames) / sampleRate
+					args := []uintptr{WASAPI_AUDCLNT_SHAREMODE_SHARED, flags}
+					durationArgs := int64Args(bufferDuration)
+					periodicityArgs := int64Args(0)
+					formatArg := uintptr(unsafe.Pointer(format))
+					args = append(args, durationArgs...)
+					args = append(args, periodicityArgs...)
+					args = append(args, formatArg, 0)
+					hr = client.call(WASAPI_METHOD_INITIALIZE, args...)
+
+					/*
+					 * Check if audio client was initialized.
+					 */
+					if hr < 0 {
+						this.closeStream(&stream)
+						return nil, hresultError("Initializing audio client", hr)
+					} else {
+						bufferFrames := uint32(0)
+						bufferFramesPtr := uintptr(unsafe.Pointer(&bufferFrames))
+						client.call(WASAPI_METHOD_GET_BUFFER_SIZE, bufferFramesPtr)
+						stream.bufferFrames = bufferFrames
+
+						/*
+						 * Register the event if there is one.
+						 */
+						if event != 0 {
+							client.call(WASAPI_METHOD_SET_EVENT_HANDLE, event)
+						}
+
+						service := (*comObject)(nil)
+						serviceIdPtr := uintptr(unsafe.Pointer(serviceId))
+						servicePtr := uintptr(unsafe.Pointer(&service))
+						hr = client.call(WASAPI_METHOD_GET_SERVICE, serviceIdPtr, servicePtr)
+
+						/*
+						 * Check if service was obtained.
+						 */
+						if hr < 0 {
+							this.closeStream(&stream)
+							return nil, hresultError("Obtaining audio service", hr)
+						} else {
+							stream.service = service
+							stream.channels = int(format.channels)
+							stream.sampleRate = format.samplesPerSec
+							return &stream, nil
+						}
+
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Releases all resources associated with a stream.
+ */
+func (this *wasapiBackend) closeStream(stream *wasapiStreamStruct) {
+
+	/*
+	 * Stop the audio client if it exists.
+	 */
+	if stream.client != nil {
+		stream.client.call(WASAPI_METHOD_STOP)
+	}
+
+	/*
+	 * Free the mix format if it exists.
+	 */
+	if stream.format != nil {
+		formatPtr := uintptr(unsafe.Pointer(stream.format))
+		g_coTaskMemFree.Call(formatPtr)
+	}
+
+	stream.service.release()
+	stream.client.release()
+	stream.device.release()
+}
+
+/*
+ * Moves all available captured audio into a FIFO.
+ */
+func (this *wasapiBackend) readCapture(capture *wasapiStreamStruct, fifo []float32) []float32 {
+	service := capture.service
+	channels := capture.channels
+	packetFrames := uint32(1)
+
+	/*
+	 * Read packets as long as there are any.
+	 */
+	for packetFrames > 0 {
+		packetFramesPtr := uintptr(unsafe.Pointer(&packetFrames))
+		hr := service.call(WASAPI_METHOD_GET_NEXT_PACKET_SIZE, packetFramesPtr)
+
+		/*
+		 * Stop reading if there is an error or no packet.
+		 */
+		if (hr < 0) || (packetFrames == 0) {
+			packetFrames = 0
+		} else {
+			data := (*float32)(nil)
+			frames := uint32(0)
+			flags := uint32(0)
+			dataPtr := uintptr(unsafe.Pointer(&data))
+			framesPtr := uintptr(unsafe.Pointer(&frames))
+			flagsPtr := uintptr(unsafe.Pointer(&flags))
+			hr = service.call(WASAPI_METHOD_GET_BUFFER, dataPtr, framesPtr, flagsPtr, 0, 0)
+
+			/*
+			 * Check if buffer was obtained.
+			 */
+			if hr < 0 {
+				packetFrames = 0
+			} else {
+				size := int(frames) * channels
+				silent := (flags & WASAPI_AUDCLNT_BUFFERFLAGS_SILENT) != 0
+
+				/*
+				 * Silent buffers must not be read.
+				 */
+				if silent || (size == 0) {
+					silence := make([]float32, size)
+					fifo = append(fifo, silence...)
+				} else {
+					samples := sampleSlice(data, size)
+					fifo = append(fifo, samples...)
+				}
+
+				framesArg := uintptr(frames)
+				service.call(WASAPI_METHOD_RELEASE_BUFFER, framesArg)
+			}
+
+		}
+
+	}
+
+	return fifo
+}
+
+/*
+ * Writes a period of audio to the render stream.
+ */
+func (this *wasapiBackend) writeRender(render *wasapiStreamStruct, playbackBuffer []float32, frames int) {
+	service := render.service
+	data := (*float32)(nil)
+	framesArg := uintptr(frames)
+	dataPtr := uintptr(unsafe.Pointer(&data))
+	hr := service.call(WASAPI_METHOD_GET_BUFFER, framesArg, dataPtr)
+
+	/*
+	 * Check if buffer was obtained.
+	 */
+	if hr >= 0 {
+		size := frames * render.channels
+		samples := sampleSlice(data, size)
+		copy(samples, playbackBuffer)
+		service.call(WASAPI_METHOD_RELEASE_BUFFER, framesArg, 0)
+	}
+
+}
+
+/*
+ * Returns the number of frames which can be written to the render stream.
+ */
+func (this *wasapiBackend) renderSpace(render *wasapiStreamStruct) int {
+	padding := uint32(0)
+	paddingPtr := uintptr(unsafe.Pointer(&padding))
+	hr := render.client.call(WASAPI_METHOD_GET_CURRENT_PADDING, paddingPtr)
+
+	/*
+	 * Check if padding was obtained.
+	 */
+	if hr < 0 {
+		return 0
+	} else {
+		space := render.bufferFrames - padding
+		return int(space)
+	}
+
+}
+
+/*
+ * Captures, processes and renders audio until the backend is closed.
+ *
+ * Processing is driven by the capture stream. Captured audio is collected in
+ * a FIFO and processed in periods as soon as the render stream has space.
+ */
+func (this *wasapiBackend) loop(capture *wasapiStreamStruct, render *wasapiStreamStruct, event uintptr) {
+	captureChannels := capture.channels
+	playbackChannels := render.channels
+	sampleRateFloat := float64(capture.sampleRate)
+	fifo := []float32{}
+	playbackBuffer := []float32{}
+	load := float64(0.0)
+	running := true
+
+	/*
+	 * Process audio until the backend is closed.
+	 */
+	for running {
+		g_waitForSingleObject.Call(event, WASAPI_WAIT_TIMEOUT)
+		fifo = this.readCapture(capture, fifo)
+		this.mutex.Lock()
+		frames := int(this.framesPerPeriod)
+		periods := int(this.periods)
+		this.mutex.Unlock()
+		captureSize := frames * captureChannels
+		playbackSize := frames * playbackChannels
+		maxFifoSize := captureSize * periods * 2
+
+		/*
+		 * If the render stream cannot keep up, drop the oldest audio to
+		 * prevent the latency from growing.
+		 */
+		if len(fifo) > maxFifoSize {
+			countXrun()
+			excess := len(fifo) - maxFifoSize
+			excessFrames := (excess + captureChannels - 1) / captureChannels
+			excessSamples := excessFrames * captureChannels
+			fifo = fifo[excessSamples:]
+		}
+
+		/*
+		 * Make sure that the playback buffer has the appropriate size.
+		 */
+		if len(playbackBuffer) != playbackSize {
+			playbackBuffer = make([]float32, playbackSize)
+		}
+
+		processing := true
+
+		/*
+		 * Process periods as long as there is audio and space to render it.
+		 */
+		for processing {
+			space := this.renderSpace(render)
+
+			/*
+			 * Check if a complete period can be processed.
+			 */
+			if (len(fifo) < captureSize) || (space < frames) {
+				processing = false
+			} else {
+				start := time.Now()
+				this.router.process(fifo[:captureSize], playbackBuffer, frames)
+				elapsed := time.Since(start)
+				this.writeRender(render, playbackBuffer, frames)
+				remaining := copy(fifo, fifo[captureSize:])
+				fifo = fifo[:remaining]
+				elapsedSeconds := elapsed.Seconds()
+				framesFloat := float64(frames)
+				periodSeconds := framesFloat / sampleRateFloat
+				currentLoad := 100.0 * (elapsedSeconds / periodSeconds)
+				load += 0.1 * (currentLoad - load)
+			}
+
+		}
+
+		this.mutex.Lock()
+		this.load = float32(load)
+		running = this.running
+		this.mutex.Unlock()
+	}
+
+}
+
+/*
+ * Processes audio until the backend is closed.
+ *
+ * Each OS thread using COM must initialize it, so all WASAPI calls happen on
+ * this thread.
+ */
+func (this *wasapiBackend) run() {
+	runtime.LockOSThread()
+	g_coInitializeEx.Call(0, WASAPI_COINIT_MULTITHREADED)
+	enumerator := (*comObject)(nil)
+	clsidPtr := uintptr(unsafe.Pointer(&g_clsidMMDeviceEnumerator))
+	iidPtr := uintptr(unsafe.Pointer(&g_iidMMDeviceEnumerator))
+	enumeratorPtr := uintptr(unsafe.Pointer(&enumerator))
+	hrPtr, _, _ := g_coCreateInstance.Call(clsidPtr, 0, WASAPI_CLSCTX_ALL, iidPtr, enumeratorPtr)
+	hr := int32(hrPtr)
+
+	/*
+	 * Check if device enumerator was created.
+	 */
+	if hr < 0 {
+		this.done <- hresultError("Creating device enumerator", hr)
+	} else {
+		event, _, _ := g_createEventW.Call(0, 0, 0, 0)
+		this.mutex.Lock()
+		framesPerPeriod := int64(this.framesPerPeriod)
+		periods := int64(this.periods)
+		this.mutex.Unlock()
+		bufferFrames := framesPerPeriod * periods
+		capture, err := this.openStream(enumerator, WASAPI_E_CAPTURE, &g_iidAudioCaptureClient, bufferFrames, event)
+
+		/*
+		 * Check if capture stream was opened.
+		 */
+		if err != nil {
+			this.done <- err
+		} else {
+			render, err := this.openStream(enumerator, WASAPI_E_RENDER, &g_iidAudioRenderClient, bufferFrames, 0)
+
+			/*
+			 * Check if render stream was opened.
+			 */
+			if err != nil {
+				this.closeStream(capture)
+				this.done <- err
+			} else if capture.sampleRate != render.sampleRate {
+				this.closeStream(capture)
+				this.closeStream(render)
+				this.done <- fmt.Errorf("Sample rates of capture (%d Hz) and render (%d Hz) endpoint differ.", capture.sampleRate, render.sampleRate)
+			} else {
+				sampleRate := capture.sampleRate
+				captureChannels := capture.channels
+				playbackChannels := render.channels
+				this.captureChannels = captureChannels
+				this.playbackChannels = playbackChannels
+				this.router = createRouter(captureChannels, playbackChannels)
+				g_sampleRate = sampleRate
+				capture.client.call(WASAPI_METHOD_START)
+				render.client.call(WASAPI_METHOD_START)
+				this.done <- nil
+				this.loop(capture, render, event)
+				this.closeStream(capture)
+				this.closeStream(render)
+			}
+
+		}
+
+		g_closeHandle.Call(event)
+		enumerator.release()
+	}
+
+	g_coUninitialize.Call()
+	runtime.UnlockOSThread()
+}
+
+/*
+ * Open the default audio endpoints and start processing.
+ */
+func (this *wasapiBackend) open() error {
+	this.running = true
+	go this.run()
+	err := <-this.done
+	return err
+}
+
+/*
+ * Stop processing.
+ *
+ * The streams are closed by the processing thread once it notices that it
+ * should stop.
+ */
+func (this *wasapiBackend) close() {
+	this.mutex.Lock()
+	this.running = false
+	this.mutex.Unlock()
+}
+
+/*
+ * Get DSP load.
+ */
+func (this *wasapiBackend) cpuLoad() float32 {
+	this.mutex.Lock()
+	load := this.load
+	this.mutex.Unlock()
+	return load
+}
+
+/*
+ * Get frames per period.
+ */
+func (this *wasapiBackend) bufferSize() uint32 {
+	this.mutex.Lock()
+	n := this.framesPerPeriod
+	this.mutex.Unlock()
+	return n
+}
+
+/*
+ * Set frames per period.
+ *
+ * This changes the number of frames processed at once, while the latency of
+ * the streams stays the same.
+ */
+func (this *wasapiBackend) setBufferSize(n uint32) {
+
+	/*
+	 * Ignore invalid period sizes.
+	 */
+	if n > 0 {
+		this.mutex.Lock()
+		this.framesPerPeriod = n
+		this.mutex.Unlock()
+	}
+
+}
+
+/*
+ * Register the ports of a binding.
+ *
+ * WASAPI has no notion of ports, so they only become routable by name.
+ */
+func (this *wasapiBackend) registerPorts(binding *Binding) error {
+	return nil
+}
+
+/*
+ * Register the MIDI input of a binding.
+ *
+ * The WASAPI backend does not support MIDI.
+ */
+func (this *wasapiBackend) registerMidiInput(binding *Binding) error {
+	return fmt.Errorf("%s", "The WASAPI backend does not support MIDI input.")
+}
+
+/*
+ * Change the ports of a binding, keeping the routes of ports which keep
+ * their names.
+ */
+func (this *wasapiBackend) rebindPorts(binding *Binding, inputNames []string, outputNames []string) error {
+	this.router.rebindPorts(binding, inputNames, outputNames)
+	return nil
+}
+
+/*
+ * Remove all routes to or from the ports of a binding.
+ */
+func (this *wasapiBackend) unregisterPorts(binding *Binding) {
+	this.router.removeBinding(binding)
+}
+
+/*
+ * Connects a source port to a destination port.
+ */
+func (this *wasapiBackend) connect(sourcePort string, destinationPort string) {
+	this.router.connect(sourcePort, destinationPort)
+}
+
+/*
+ * Disconnects a source port from a destination port.
+ */
+func (this *wasapiBackend) disconnect(sourcePort string, destinationPort string) {
+	this.router.disconnect(sourcePort, destinationPort)
+}
+
+/*
+ * Returns the names of all ports which may act as a source or as a
+ * destination of a connection.
+ */
+func (this *wasapiBackend) ports() ([]string, []string) {
+	return this.router.ports()
+}
+
+/*
+ * Returns all connections between ports.
+ */
+func (this *wasapiBackend) connections() []Connection {
+	return this.router.connections()
+}
+
+/*
+ * Creates a WASAPI backend.
+ */
+func createWasapiBackend(cfg WasapiConfig) (backend, error) {
+	framesPerPeriod := cfg.FramesPerPeriod
+	periods := cfg.Periods
+
+	/*
+	 * Use the default period size if none is configured.
+	 */
+	if framesPerPeriod == 0 {
+		framesPerPeriod = WASAPI_DEFAULT_FRAMES_PER_PERIOD
+	}
+
+	/*
+	 * Use the default number of periods if none is configured.
+	 */
+	if periods == 0 {
+		periods = WASAPI_DEFAULT_PERIODS
+	}
+
+	/*
+	 * Create WASAPI backend.
+	 */
+	b := wasapiBackend{
+		framesPerPeriod: framesPerPeriod,
+		periods:         periods,
+		done:            make(chan error),
+	}
+
+	return &b, nil
+}
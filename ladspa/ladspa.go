@@ -0,0 +1,620 @@
+//go:build ladspa
+// +build ladspa
+
+package ladspa
+
+/*
+#cgo LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <ladspa.h>
+
+static const LADSPA_Descriptor *ladspa_descriptor_at(void *function, unsigned long index) {
+	LADSPA_Descriptor_Function descriptorFunction = (LADSPA_Descriptor_Function) function;
+	return descriptorFunction(index);
+}
+
+static LADSPA_Handle ladspa_instantiate(const LADSPA_Descriptor *descriptor, unsigned long sampleRate) {
+	return descriptor->instantiate(descriptor, sampleRate);
+}
+
+static void ladspa_connect_port(const LADSPA_Descriptor *descriptor, LADSPA_Handle handle, unsigned long port, LADSPA_Data *location) {
+	descriptor->connect_port(handle, port, location);
+}
+
+static void ladspa_activate(const LADSPA_Descriptor *descriptor, LADSPA_Handle handle) {
+	if (descriptor->activate != NULL) {
+		descriptor->activate(handle);
+	}
+}
+
+static void ladspa_run(const LADSPA_Descriptor *descriptor, LADSPA_Handle handle, unsigned long sampleCount) {
+	descriptor->run(handle, sampleCount);
+}
+
+static void ladspa_cleanup(const LADSPA_Descriptor *descriptor, LADSPA_Handle handle) {
+	if (descriptor->deactivate != NULL) {
+		descriptor->deactivate(handle);
+	}
+
+	descriptor->cleanup(handle);
+}
+
+static int ladspa_runnable(const LADSPA_Descriptor *descriptor) {
+	return (descriptor->instantiate != NULL) && (descriptor->connect_port != NULL) && (descriptor->run != NULL) && (descriptor->cleanup != NULL);
+}
+
+static LADSPA_PortDescriptor ladspa_port_descriptor(const LADSPA_Descriptor *descriptor, unsigned long port) {
+	return descriptor->PortDescriptors[port];
+}
+
+static const char *ladspa_port_name(const LADSPA_Descriptor *descriptor, unsigned long port) {
+	return descriptor->PortNames[port];
+}
+
+static LADSPA_PortRangeHint ladspa_port_range_hint(const LADSPA_Descriptor *descriptor, unsigned long port) {
+	return descriptor->PortRangeHints[port];
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+/*
+ * Kinds of ports of a plugin.
+ */
+const (
+	PORT_INPUT   = 0x1
+	PORT_OUTPUT  = 0x2
+	PORT_CONTROL = 0x4
+	PORT_AUDIO   = 0x8
+)
+
+/*
+ * The size of the largest array of samples which may be mapped into a slice.
+ */
+const (
+	MAX_SAMPLES = 1 << 28
+)
+
+/*
+ * Data structure representing a plugin from a shared library.
+ */
+type pluginStruct struct {
+	descriptor   *C.LADSPA_Descriptor
+	label        string
+	name         string
+	portCount    C.ulong
+	controls     []Control
+	controlPorts []C.ulong
+	audioInputs  []C.ulong
+	audioOutputs []C.ulong
+}
+
+/*
+ * Data structure representing a running instance of a plugin.
+ *
+ * All memory connected to the ports of the plugin is allocated in C, since
+ * the plugin keeps pointers to it between calls.
+ */
+type instanceStruct struct {
+	plugin       *pluginStruct
+	handle       C.LADSPA_Handle
+	portValues   *C.LADSPA_Data
+	inputBuffer  *C.LADSPA_Data
+	outputBuffer *C.LADSPA_Data
+	spareInputs  []*C.LADSPA_Data
+	spareOutputs []*C.LADSPA_Data
+	capacity     int
+}
+
+var g_mutex sync.Mutex     // Guards the plugins.
+var g_plugins []Plugin     // The plugins found on the LADSPA path.
+var g_scanned bool = false // Whether the LADSPA path was scanned.
+
+/*
+ * Maps an array of samples allocated in C into a slice.
+ */
+func samples(ptr *C.LADSPA_Data, n int) []C.LADSPA_Data {
+	arr := (*[MAX_SAMPLES]C.LADSPA_Data)(unsafe.Pointer(ptr))
+	return arr[:n:n]
+}
+
+/*
+ * Allocates an array of samples in C, initialized to zero.
+ */
+func allocateSamples(n int) *C.LADSPA_Data {
+	size := C.size_t(unsafe.Sizeof(C.LADSPA_Data(0)))
+	count := C.size_t(n)
+	ptr := C.calloc(count, size)
+	return (*C.LADSPA_Data)(ptr)
+}
+
+/*
+ * Returns the control ports of the plugin.
+ */
+func (this *pluginStruct) Controls() []Control {
+	n := len(this.controls)
+	controls := make([]Control, n)
+	copy(controls, this.controls)
+	return controls
+}
+
+/*
+ * Returns the short, unique label of the plugin.
+ */
+func (this *pluginStruct) Label() string {
+	return this.label
+}
+
+/*
+ * Returns the name of the plugin.
+ */
+func (this *pluginStruct) Name() string {
+	return this.name
+}
+
+/*
+ * Creates a running instance of the plugin at a certain sample rate.
+ *
+ * Instances which are no longer referenced are closed when they are garbage
+ * collected.
+ */
+func (this *pluginStruct) Instantiate(sampleRate uint32) (Instance, error) {
+	descriptor := this.descriptor
+	rate := C.ulong(sampleRate)
+	handle := C.ladspa_instantiate(descriptor, rate)
+
+	/*
+	 * Check if the plugin was instantiated.
+	 */
+	if handle == nil {
+		return nil, fmt.Errorf("Failed to instantiate plugin '%s' at %d Hz.", this.name, sampleRate)
+	} else {
+		portCount := int(this.portCount)
+		portValues := allocateSamples(portCount)
+		values := samples(portValues, portCount)
+
+		/*
+		 * Connect each control port to its value.
+		 */
+		for i := C.ulong(0); i < this.portCount; i++ {
+			portDescriptor := C.ladspa_port_descriptor(descriptor, i)
+
+			/*
+			 * Only control ports are connected to a single value.
+			 */
+			if (portDescriptor & PORT_CONTROL) != 0 {
+				C.ladspa_connect_port(descriptor, handle, i, &values[i])
+			}
+
+		}
+
+		numSpareInputs := len(this.audioInputs) - 1
+		numSpareOutputs := len(this.audioOutputs) - 1
+
+		/*
+		 * Create instance.
+		 */
+		instance := &instanceStruct{
+			plugin:       this,
+			handle:       handle,
+			portValues:   portValues,
+			spareInputs:  make([]*C.LADSPA_Data, numSpareInputs),
+			spareOutputs: make([]*C.LADSPA_Data, numSpareOutputs),
+		}
+
+		/*
+		 * Initialize each control port with its default value.
+		 */
+		for i, control := range this.controls {
+			port := this.controlPorts[i]
+			value := control.Default
+
+			/*
+			 * Scale values which are given relative to the sample rate.
+			 */
+			if control.SampleRate {
+				value *= float64(sampleRate)
+			}
+
+			values[port] = C.LADSPA_Data(value)
+		}
+
+		C.ladspa_activate(descriptor, handle)
+		runtime.SetFinalizer(instance, (*instanceStruct).Close)
+		return instance, nil
+	}
+
+}
+
+/*
+ * Frees the audio buffers of an instance.
+ */
+func (this *instanceStruct) freeBuffers() {
+	C.free(unsafe.Pointer(this.inputBuffer))
+	C.free(unsafe.Pointer(this.outputBuffer))
+	this.inputBuffer = nil
+	this.outputBuffer = nil
+
+	/*
+	 * Free all spare input buffers.
+	 */
+	for i, buffer := range this.spareInputs {
+		C.free(unsafe.Pointer(buffer))
+		this.spareInputs[i] = nil
+	}
+
+	/*
+	 * Free all spare output buffers.
+	 */
+	for i, buffer := range this.spareOutputs {
+		C.free(unsafe.Pointer(buffer))
+		this.spareOutputs[i] = nil
+	}
+
+	this.capacity = 0
+}
+
+/*
+ * Makes sure that the audio buffers of an instance hold a certain number of
+ * samples and connects them to the audio ports of the plugin.
+ *
+ * Audio ports other than the first input and output are connected to spare
+ * buffers, so that extra inputs stay silent and extra outputs are discarded.
+ */
+func (this *instanceStruct) prepareBuffers(n int) {
+
+	/*
+	 * Only allocate new buffers if the current ones are too small.
+	 */
+	if n > this.capacity {
+		this.freeBuffers()
+		plugin := this.plugin
+		descriptor := plugin.descriptor
+		handle := this.handle
+		this.inputBuffer = allocateSamples(n)
+		this.outputBuffer = allocateSamples(n)
+		inputPort := plugin.audioInputs[0]
+		outputPort := plugin.audioOutputs[0]
+		C.ladspa_connect_port(descriptor, handle, inputPort, this.inputBuffer)
+		C.ladspa_connect_port(descriptor, handle, outputPort, this.outputBuffer)
+
+		/*
+		 * Connect each spare input.
+		 */
+		for i := range this.spareInputs {
+			buffer := allocateSamples(n)
+			this.spareInputs[i] = buffer
+			port := plugin.audioInputs[i+1]
+			C.ladspa_connect_port(descriptor, handle, port, buffer)
+		}
+
+		/*
+		 * Connect each spare output.
+		 */
+		for i := range this.spareOutputs {
+			buffer := allocateSamples(n)
+			this.spareOutputs[i] = buffer
+			port := plugin.audioOutputs[i+1]
+			C.ladspa_connect_port(descriptor, handle, port, buffer)
+		}
+
+		this.capacity = n
+	}
+
+}
+
+/*
+ * Processes a block of samples with a certain value for each control port.
+ */
+func (this *instanceStruct) Process(in []float64, out []float64, controls []float64) {
+	handle := this.handle
+
+	/*
+	 * Only process if the instance is still open.
+	 */
+	if handle != nil {
+		n := len(in)
+		this.prepareBuffers(n)
+		plugin := this.plugin
+		portCount := int(plugin.portCount)
+		values := samples(this.portValues, portCount)
+		numControls := len(controls)
+
+		/*
+		 * Set each control port.
+		 */
+		for i, port := range plugin.controlPorts {
+
+			/*
+			 * Only set the ports a value was given for.
+			 */
+			if i < numControls {
+				values[port] = C.LADSPA_Data(controls[i])
+			}
+
+		}
+
+		input := samples(this.inputBuffer, n)
+
+		/*
+		 * Copy the input signal into the input buffer.
+		 */
+		for i, sample := range in {
+			input[i] = C.LADSPA_Data(sample)
+		}
+
+		count := C.ulong(n)
+		C.ladspa_run(plugin.descriptor, handle, count)
+		output := samples(this.outputBuffer, n)
+
+		/*
+		 * Copy the output signal from the output buffer.
+		 */
+		for i, sample := range output {
+			out[i] = float64(sample)
+		}
+
+	}
+
+}
+
+/*
+ * Deactivates the instance and releases all of its resources.
+ */
+func (this *instanceStruct) Close() {
+	handle := this.handle
+
+	/*
+	 * Only clean up once.
+	 */
+	if handle != nil {
+		plugin := this.plugin
+		C.ladspa_cleanup(plugin.descriptor, handle)
+		this.handle = nil
+		this.freeBuffers()
+		C.free(unsafe.Pointer(this.portValues))
+		this.portValues = nil
+	}
+
+}
+
+/*
+ * Creates a plugin from its descriptor.
+ *
+ * Only plugins with at least one audio input and output can be used.
+ */
+func createPlugin(descriptor *C.LADSPA_Descriptor) (*pluginStruct, error) {
+	label := C.GoString(descriptor.Label)
+	name := C.GoString(descriptor.Name)
+	portCount := descriptor.PortCount
+	controls := []Control{}
+	controlPorts := []C.ulong{}
+	audioInputs := []C.ulong{}
+	audioOutputs := []C.ulong{}
+
+	/*
+	 * Sort each port by its kind. Control outputs are only connected
+	 * when the plugin is instantiated.
+	 */
+	for i := C.ulong(0); i < portCount; i++ {
+		portDescriptor := C.ladspa_port_descriptor(descriptor, i)
+		isInput := (portDescriptor & PORT_INPUT) != 0
+		isControl := (portDescriptor & PORT_CONTROL) != 0
+
+		/*
+		 * Check the kind of port.
+		 */
+		if isControl && isInput {
+			cName := C.ladspa_port_name(descriptor, i)
+			portName := C.GoString(cName)
+			rangeHint := C.ladspa_port_range_hint(descriptor, i)
+			hints := int(rangeHint.HintDescriptor)
+			lower := float64(rangeHint.LowerBound)
+			upper := float64(rangeHint.UpperBound)
+			control := createControl(portName, hints, lower, upper)
+			controls = append(controls, control)
+			controlPorts = append(controlPorts, i)
+		} else if !isControl && isInput {
+			audioInputs = append(audioInputs, i)
+		} else if !isControl {
+			audioOutputs = append(audioOutputs, i)
+		}
+
+	}
+
+	/*
+	 * Check if the plugin can be used.
+	 */
+	if C.ladspa_runnable(descriptor) == 0 {
+		return nil, fmt.Errorf("Plugin '%s' cannot be run.", name)
+	} else if (len(audioInputs) == 0) || (len(audioOutputs) == 0) {
+		return nil, fmt.Errorf("Plugin '%s' does not process audio.", name)
+	} else {
+
+		/*
+		 * Create plugin.
+		 */
+		plugin := &pluginStruct{
+			descriptor:   descriptor,
+			label:        label,
+			name:         name,
+			portCount:    portCount,
+			controls:     controls,
+			controlPorts: controlPorts,
+			audioInputs:  audioInputs,
+			audioOutputs: audioOutputs,
+		}
+
+		return plugin, nil
+	}
+
+}
+
+/*
+ * Loads all usable plugins from a shared library.
+ *
+ * The library stays loaded, since its plugins may be used at any time.
+ */
+func loadLibrary(path string) ([]*pluginStruct, error) {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	library := C.dlopen(cPath, C.RTLD_NOW)
+
+	/*
+	 * Check if the library was loaded.
+	 */
+	if library == nil {
+		cMsg := C.dlerror()
+		msg := C.GoString(cMsg)
+		return nil, fmt.Errorf("Failed to load library '%s': %s", path, msg)
+	} else {
+		cSymbol := C.CString("ladspa_descriptor")
+		defer C.free(unsafe.Pointer(cSymbol))
+		function := C.dlsym(library, cSymbol)
+
+		/*
+		 * Check if the library contains LADSPA plugins.
+		 */
+		if function == nil {
+			C.dlclose(library)
+			return nil, fmt.Errorf("Library '%s' does not contain LADSPA plugins.", path)
+		} else {
+			plugins := []*pluginStruct{}
+			index := C.ulong(0)
+			descriptor := C.ladspa_descriptor_at(function, index)
+
+			/*
+			 * Iterate over all plugins in the library.
+			 */
+			for descriptor != nil {
+				plugin, err := createPlugin(descriptor)
+
+				/*
+				 * Only keep plugins which can be used.
+				 */
+				if err == nil {
+					plugins = append(plugins, plugin)
+				}
+
+				index++
+				descriptor = C.ladspa_descriptor_at(function, index)
+			}
+
+			return plugins, nil
+		}
+
+	}
+
+}
+
+/*
+ * Loads all plugins from the shared libraries in the directories of the
+ * LADSPA path.
+ *
+ * Plugins whose name is already taken by a plugin found before are skipped.
+ */
+func scan() []Plugin {
+	path := os.Getenv(PATH_VARIABLE)
+
+	/*
+	 * Use the default path if none is set.
+	 */
+	if path == "" {
+		path = PATH_DEFAULT
+	}
+
+	dirs := filepath.SplitList(path)
+	names := make(map[string]bool)
+	plugins := []Plugin{}
+
+	/*
+	 * Iterate over all directories.
+	 */
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+
+		/*
+		 * Skip directories which cannot be read.
+		 */
+		if err == nil {
+
+			/*
+			 * Iterate over all shared libraries.
+			 */
+			for _, entry := range entries {
+				fileName := entry.Name()
+				isLibrary := strings.HasSuffix(fileName, ".so")
+
+				/*
+				 * Only load shared libraries.
+				 */
+				if isLibrary && !entry.IsDir() {
+					libraryPath := filepath.Join(dir, fileName)
+					libraryPlugins, err := loadLibrary(libraryPath)
+
+					/*
+					 * Skip libraries which cannot be loaded.
+					 */
+					if err == nil {
+
+						/*
+						 * Add each plugin with a new name.
+						 */
+						for _, plugin := range libraryPlugins {
+							name := plugin.name
+
+							/*
+							 * Check if name is still available.
+							 */
+							if !names[name] {
+								names[name] = true
+								plugins = append(plugins, plugin)
+							}
+
+						}
+
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+	return plugins
+}
+
+/*
+ * Returns all plugins found on the LADSPA path.
+ *
+ * The path is scanned when this is first called.
+ */
+func Plugins() ([]Plugin, error) {
+	g_mutex.Lock()
+
+	/*
+	 * Scan the LADSPA path on first use.
+	 */
+	if !g_scanned {
+		g_plugins = scan()
+		g_scanned = true
+	}
+
+	n := len(g_plugins)
+	plugins := make([]Plugin, n)
+	copy(plugins, g_plugins)
+	g_mutex.Unlock()
+	return plugins, nil
+}
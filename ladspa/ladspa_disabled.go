@@ -0,0 +1,17 @@
+//go:build !ladspa
+// +build !ladspa
+
+package ladspa
+
+import (
+	"fmt"
+)
+
+/*
+ * Returns all plugins found on the LADSPA path.
+ *
+ * This build does not include LADSPA support.
+ */
+func Plugins() ([]Plugin, error) {
+	return nil, fmt.Errorf("%s", "This build does not support LADSPA plugins. Rebuild with '-tags ladspa' to enable them.")
+}
@@ -0,0 +1,26 @@
+//go:build !ladspa
+// +build !ladspa
+
+package ladspa
+
+import (
+	"testing"
+)
+
+/*
+ * Verify that builds without LADSPA support report that no plugins are
+ * available.
+ */
+func TestPluginsDisabled(t *testing.T) {
+	plugins, err := Plugins()
+
+	/*
+	 * The lookup must fail without returning plugins.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Looking up plugins without LADSPA support should fail, but it did not.")
+	} else if len(plugins) != 0 {
+		t.Errorf("Looking up plugins without LADSPA support returned %d plugins.", len(plugins))
+	}
+
+}
@@ -0,0 +1,42 @@
+//go:build ladspa
+// +build ladspa
+
+package ladspa
+
+import (
+	"os"
+	"testing"
+)
+
+/*
+ * Verify that scanning an empty LADSPA path succeeds without plugins and that
+ * the result of the scan is kept.
+ *
+ * This requires ladspa.h to be available to the C compiler.
+ */
+func TestPluginsEmptyPath(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv(PATH_VARIABLE, dir)
+	plugins, err := Plugins()
+
+	/*
+	 * An empty directory contains no plugins.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Scanning empty LADSPA path failed: %s", msg)
+	} else if len(plugins) != 0 {
+		t.Errorf("Scanning empty LADSPA path returned %d plugins.", len(plugins))
+	} else {
+		pluginsAgain, err := Plugins()
+
+		/*
+		 * Verify that the scan is not repeated with a different result.
+		 */
+		if err != nil || len(pluginsAgain) != 0 {
+			t.Errorf("%s", "Looking up plugins a second time returned a different result.")
+		}
+
+	}
+
+}
@@ -0,0 +1,261 @@
+package ladspa
+
+import (
+	"math"
+)
+
+/*
+ * Range hints of LADSPA control ports, as defined by the LADSPA API.
+ */
+const (
+	HINT_BOUNDED_BELOW   = 0x1
+	HINT_BOUNDED_ABOVE   = 0x2
+	HINT_TOGGLED         = 0x4
+	HINT_SAMPLE_RATE     = 0x8
+	HINT_LOGARITHMIC     = 0x10
+	HINT_INTEGER         = 0x20
+	HINT_DEFAULT_MASK    = 0x3C0
+	HINT_DEFAULT_MINIMUM = 0x40
+	HINT_DEFAULT_LOW     = 0x80
+	HINT_DEFAULT_MIDDLE  = 0xC0
+	HINT_DEFAULT_HIGH    = 0x100
+	HINT_DEFAULT_MAXIMUM = 0x140
+	HINT_DEFAULT_0       = 0x200
+	HINT_DEFAULT_1       = 0x240
+	HINT_DEFAULT_100     = 0x280
+	HINT_DEFAULT_440     = 0x2C0
+)
+
+/*
+ * Where to look for plugins.
+ */
+const (
+	PATH_DEFAULT  = "/usr/local/lib/ladspa:/usr/lib/ladspa:/usr/lib64/ladspa"
+	PATH_VARIABLE = "LADSPA_PATH"
+)
+
+/*
+ * Data structure describing a control port of a plugin.
+ *
+ * If SampleRate is set, the minimum, maximum and default value are fractions
+ * of the sample rate.
+ */
+type Control struct {
+	Name        string
+	Minimum     float64
+	Maximum     float64
+	Default     float64
+	Integer     bool
+	Logarithmic bool
+	SampleRate  bool
+	Toggled     bool
+}
+
+/*
+ * Interface type for a running instance of a plugin.
+ *
+ * Instances are not safe for concurrent use.
+ */
+type Instance interface {
+	Close()
+	Process(in []float64, out []float64, controls []float64)
+}
+
+/*
+ * Interface type for a plugin, which processes one audio input into one
+ * audio output, controlled by a number of control ports.
+ */
+type Plugin interface {
+	Controls() []Control
+	Instantiate(sampleRate uint32) (Instance, error)
+	Label() string
+	Name() string
+}
+
+/*
+ * Interpolates between the bounds of a control port, either linearly or
+ * logarithmically.
+ */
+func between(lower float64, upper float64, fraction float64, logarithmic bool) float64 {
+
+	/*
+	 * Check whether to interpolate logarithmically.
+	 */
+	if logarithmic {
+		logLower := math.Log(lower)
+		logUpper := math.Log(upper)
+		logResult := ((1.0 - fraction) * logLower) + (fraction * logUpper)
+		return math.Exp(logResult)
+	} else {
+		return ((1.0 - fraction) * lower) + (fraction * upper)
+	}
+
+}
+
+/*
+ * Creates the description of a control port from its name, its range hints
+ * and its bounds.
+ *
+ * Missing bounds are filled in so that the port always has a range, which
+ * includes its default value.
+ */
+func createControl(name string, hints int, lower float64, upper float64) Control {
+	boundedBelow := (hints & HINT_BOUNDED_BELOW) != 0
+	boundedAbove := (hints & HINT_BOUNDED_ABOVE) != 0
+	toggled := (hints & HINT_TOGGLED) != 0
+	integer := (hints & HINT_INTEGER) != 0
+	sampleRate := (hints & HINT_SAMPLE_RATE) != 0
+
+	/*
+	 * Toggled ports are either off or on.
+	 */
+	if toggled {
+		lower = 0.0
+		upper = 1.0
+	} else {
+
+		/*
+		 * A port without a lower bound starts at zero or one below its
+		 * upper bound.
+		 */
+		if !boundedBelow {
+			lower = math.Min(0.0, upper-1.0)
+
+			/*
+			 * Without any bounds, the range starts at zero.
+			 */
+			if !boundedAbove {
+				lower = 0.0
+			}
+
+		}
+
+		/*
+		 * A port without an upper bound ends one above its lower bound.
+		 */
+		if !boundedAbove || (upper < lower) {
+			upper = lower + 1.0
+		}
+
+	}
+
+	logarithmic := ((hints & HINT_LOGARITHMIC) != 0) && (lower > 0.0)
+	defaultHint := hints & HINT_DEFAULT_MASK
+	value := lower
+
+	/*
+	 * Find the default value.
+	 */
+	switch defaultHint {
+	case HINT_DEFAULT_MINIMUM:
+		value = lower
+	case HINT_DEFAULT_LOW:
+		value = between(lower, upper, 0.25, logarithmic)
+	case HINT_DEFAULT_MIDDLE:
+		value = between(lower, upper, 0.5, logarithmic)
+	case HINT_DEFAULT_HIGH:
+		value = between(lower, upper, 0.75, logarithmic)
+	case HINT_DEFAULT_MAXIMUM:
+		value = upper
+	case HINT_DEFAULT_0:
+		value = 0.0
+	case HINT_DEFAULT_1:
+		value = 1.0
+	case HINT_DEFAULT_100:
+		value = 100.0
+	case HINT_DEFAULT_440:
+		value = 440.0
+	default:
+
+		/*
+		 * Without a default, start at zero if it is in range.
+		 */
+		if !boundedBelow && (upper >= 0.0) {
+			value = 0.0
+		}
+
+	}
+
+	/*
+	 * Integer ports only take whole numbers.
+	 */
+	if integer || toggled {
+		value = math.Round(value)
+	}
+
+	/*
+	 * Make sure that the default value is in range.
+	 */
+	if value < lower {
+		lower = value
+	} else if value > upper {
+		upper = value
+	}
+
+	/*
+	 * Create control port description.
+	 */
+	control := Control{
+		Name:        name,
+		Minimum:     lower,
+		Maximum:     upper,
+		Default:     value,
+		Integer:     integer,
+		Logarithmic: logarithmic,
+		SampleRate:  sampleRate,
+		Toggled:     toggled,
+	}
+
+	return control
+}
+
+/*
+ * Returns the value at a certain fraction (between zero and one) of the range
+ * of the control port.
+ */
+func (this *Control) Value(fraction float64) float64 {
+
+	/*
+	 * Keep the value in range and hit the bounds exactly.
+	 */
+	if fraction <= 0.0 {
+		return this.Minimum
+	} else if fraction >= 1.0 {
+		return this.Maximum
+	} else {
+		value := between(this.Minimum, this.Maximum, fraction, this.Logarithmic)
+		return value
+	}
+}
+
+/*
+ * Returns the fraction (between zero and one) of the range of the control
+ * port at which a certain value lies.
+ */
+func (this *Control) Fraction(value float64) float64 {
+	lower := this.Minimum
+	upper := this.Maximum
+
+	/*
+	 * Compare logarithms on a logarithmic scale.
+	 */
+	if this.Logarithmic {
+		lower = math.Log(lower)
+		upper = math.Log(upper)
+		value = math.Log(math.Max(value, this.Minimum))
+	}
+
+	span := upper - lower
+
+	/*
+	 * A port without a range is always at its lower bound.
+	 */
+	if span <= 0.0 {
+		return 0.0
+	} else {
+		fraction := (value - lower) / span
+		fraction = math.Max(0.0, math.Min(fraction, 1.0))
+		return fraction
+	}
+
+}
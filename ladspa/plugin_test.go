@@ -0,0 +1,168 @@
+package ladspa
+
+import (
+	"math"
+	"testing"
+)
+
+/*
+ * Maximum deviation of values which are calculated in floating-point.
+ */
+const (
+	EPSILON = 1e-9
+)
+
+/*
+ * Perform a unit test on the creation of control port descriptions.
+ */
+func TestCreateControl(t *testing.T) {
+
+	/*
+	 * Data structure describing a test case.
+	 */
+	type testStruct struct {
+		hints   int
+		lower   float64
+		upper   float64
+		minimum float64
+		maximum float64
+		value   float64
+	}
+
+	/*
+	 * Test cases.
+	 */
+	tests := []testStruct{
+		testStruct{
+			hints:   HINT_BOUNDED_BELOW | HINT_BOUNDED_ABOVE | HINT_DEFAULT_1,
+			lower:   0.0,
+			upper:   4.0,
+			minimum: 0.0,
+			maximum: 4.0,
+			value:   1.0,
+		},
+		testStruct{
+			hints:   HINT_BOUNDED_BELOW | HINT_BOUNDED_ABOVE | HINT_DEFAULT_MIDDLE,
+			lower:   -10.0,
+			upper:   10.0,
+			minimum: -10.0,
+			maximum: 10.0,
+			value:   0.0,
+		},
+		testStruct{
+			hints:   HINT_BOUNDED_BELOW | HINT_BOUNDED_ABOVE | HINT_LOGARITHMIC | HINT_DEFAULT_MIDDLE,
+			lower:   20.0,
+			upper:   20000.0,
+			minimum: 20.0,
+			maximum: 20000.0,
+			value:   math.Sqrt(20.0 * 20000.0),
+		},
+		testStruct{
+			hints:   HINT_TOGGLED | HINT_DEFAULT_1,
+			lower:   5.0,
+			upper:   7.0,
+			minimum: 0.0,
+			maximum: 1.0,
+			value:   1.0,
+		},
+		testStruct{
+			hints:   HINT_DEFAULT_440,
+			lower:   0.0,
+			upper:   0.0,
+			minimum: 0.0,
+			maximum: 440.0,
+			value:   440.0,
+		},
+		testStruct{
+			hints:   HINT_BOUNDED_BELOW | HINT_BOUNDED_ABOVE | HINT_INTEGER | HINT_DEFAULT_LOW,
+			lower:   1.0,
+			upper:   8.0,
+			minimum: 1.0,
+			maximum: 8.0,
+			value:   3.0,
+		},
+	}
+
+	/*
+	 * Run each test case.
+	 */
+	for i, test := range tests {
+		control := createControl("port", test.hints, test.lower, test.upper)
+
+		/*
+		 * Verify the range and default value of the port.
+		 */
+		if math.Abs(control.Minimum-test.minimum) > EPSILON {
+			t.Errorf("Test case %d: Minimum incorrect. Expected: %f Got: %f", i, test.minimum, control.Minimum)
+		} else if math.Abs(control.Maximum-test.maximum) > EPSILON {
+			t.Errorf("Test case %d: Maximum incorrect. Expected: %f Got: %f", i, test.maximum, control.Maximum)
+		} else if math.Abs(control.Default-test.value) > EPSILON {
+			t.Errorf("Test case %d: Default incorrect. Expected: %f Got: %f", i, test.value, control.Default)
+		}
+
+	}
+
+}
+
+/*
+ * Perform a unit test on the mapping between values and fractions of the
+ * range of a control port.
+ */
+func TestControlFraction(t *testing.T) {
+
+	/*
+	 * Control ports to test.
+	 */
+	controls := []Control{
+		createControl("gain", HINT_BOUNDED_BELOW|HINT_BOUNDED_ABOVE, -24.0, 24.0),
+		createControl("frequency", HINT_BOUNDED_BELOW|HINT_BOUNDED_ABOVE|HINT_LOGARITHMIC, 20.0, 20000.0),
+	}
+
+	/*
+	 * Test each control port.
+	 */
+	for _, control := range controls {
+
+		/*
+		 * Map fractions to values and back.
+		 */
+		for i := 0; i <= 10; i++ {
+			fraction := 0.1 * float64(i)
+			value := control.Value(fraction)
+			result := control.Fraction(value)
+
+			/*
+			 * Verify that the value is in range and maps back to the
+			 * same fraction.
+			 */
+			if (value < control.Minimum-EPSILON) || (value > control.Maximum+EPSILON) {
+				t.Errorf("Port '%s': Value %f for fraction %f out of range.", control.Name, value, fraction)
+			} else if math.Abs(result-fraction) > EPSILON {
+				t.Errorf("Port '%s': Fraction incorrect. Expected: %f Got: %f", control.Name, fraction, result)
+			}
+
+		}
+
+		low := control.Value(-1.0)
+		high := control.Value(2.0)
+
+		/*
+		 * Verify that fractions out of range are limited.
+		 */
+		if (low != control.Minimum) || (high != control.Maximum) {
+			t.Errorf("Port '%s': Fractions out of range not limited. Expected: [%f, %f] Got: [%f, %f]", control.Name, control.Minimum, control.Maximum, low, high)
+		}
+
+	}
+
+	mid := controls[1].Value(0.5)
+	expected := math.Sqrt(20.0 * 20000.0)
+
+	/*
+	 * Verify that logarithmic ports are scaled geometrically.
+	 */
+	if math.Abs(mid-expected) > EPSILON {
+		t.Errorf("Logarithmic port not scaled geometrically. Expected: %f Got: %f", expected, mid)
+	}
+
+}
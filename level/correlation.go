@@ -0,0 +1,185 @@
+package level
+
+import (
+	"math"
+	"sync"
+)
+
+/*
+ * Constants for the correlation meter.
+ */
+const (
+	CORRELATION_TIME_CONSTANT = 0.3     // Integration time of the averages in seconds.
+	CORRELATION_MIN_POWER     = 1.0e-10 // Below -100 dBFS, the phase is not measured.
+)
+
+/*
+ * Data structure representing the result of a correlation measurement.
+ */
+type correlationResultStruct struct {
+	correlation float64
+	mid         float64
+	side        float64
+}
+
+/*
+ * The result of a correlation measurement.
+ */
+type CorrelationResult interface {
+	Correlation() float64
+	Mid() float64
+	Side() float64
+}
+
+/*
+ * Data structure representing a correlation meter for a stereo signal.
+ */
+type correlationMeterStruct struct {
+	mutex      sync.RWMutex
+	leftPower  float64
+	rightPower float64
+	crossPower float64
+}
+
+/*
+ * Interface type representing a correlation meter (or goniometer) for a
+ * stereo signal.
+ */
+type CorrelationMeter interface {
+	Analyze() CorrelationResult
+	Process(left []float64, right []float64, sampleRate uint32)
+	Reset()
+}
+
+/*
+ * Returns the correlation between the left and the right channel, from -1
+ * (opposite phase) over 0 (unrelated) to 1 (in phase).
+ */
+func (this *correlationResultStruct) Correlation() float64 {
+	value := this.correlation
+	return value
+}
+
+/*
+ * Returns the level of the mid signal (L + R) / 2 in dBFS.
+ */
+func (this *correlationResultStruct) Mid() float64 {
+	value := this.mid
+	return value
+}
+
+/*
+ * Returns the level of the side signal (L - R) / 2 in dBFS.
+ */
+func (this *correlationResultStruct) Side() float64 {
+	value := this.side
+	return value
+}
+
+/*
+ * Obtains the current correlation and the levels of the mid and side signal.
+ */
+func (this *correlationMeterStruct) Analyze() CorrelationResult {
+	this.mutex.RLock()
+	leftPower := this.leftPower
+	rightPower := this.rightPower
+	crossPower := this.crossPower
+	this.mutex.RUnlock()
+	productPower := leftPower * rightPower
+	correlation := 0.0
+
+	/*
+	 * Only measure the phase if both channels carry a signal.
+	 */
+	if leftPower > CORRELATION_MIN_POWER && rightPower > CORRELATION_MIN_POWER {
+		correlation = crossPower / math.Sqrt(productPower)
+
+		/*
+		 * Rounding errors may slightly exceed the range.
+		 */
+		if correlation > 1.0 {
+			correlation = 1.0
+		} else if correlation < -1.0 {
+			correlation = -1.0
+		}
+
+	}
+
+	midPower := 0.25 * (leftPower + rightPower + (2.0 * crossPower))
+	sidePower := 0.25 * (leftPower + rightPower - (2.0 * crossPower))
+	mid := 10.0 * math.Log10(midPower)
+	side := 10.0 * math.Log10(sidePower)
+
+	/*
+	 * Create result structure.
+	 */
+	result := correlationResultStruct{
+		correlation: correlation,
+		mid:         limitLevel(mid),
+		side:        limitLevel(side),
+	}
+
+	return &result
+}
+
+/*
+ * Feeds the left and right channel of a stereo signal through the correlation
+ * meter.
+ */
+func (this *correlationMeterStruct) Process(left []float64, right []float64, sampleRate uint32) {
+	numLeft := len(left)
+	numRight := len(right)
+	numSamples := numLeft
+
+	/*
+	 * Only process samples present in both channels.
+	 */
+	if numRight < numSamples {
+		numSamples = numRight
+	}
+
+	sampleRateFloat := float64(sampleRate)
+	factor := math.Exp(-1.0 / (CORRELATION_TIME_CONSTANT * sampleRateFloat))
+	complement := 1.0 - factor
+	this.mutex.RLock()
+	leftPower := this.leftPower
+	rightPower := this.rightPower
+	crossPower := this.crossPower
+	this.mutex.RUnlock()
+
+	/*
+	 * Process each pair of samples.
+	 */
+	for i := 0; i < numSamples; i++ {
+		l := left[i]
+		r := right[i]
+		leftPower = (factor * leftPower) + (complement * l * l)
+		rightPower = (factor * rightPower) + (complement * r * r)
+		crossPower = (factor * crossPower) + (complement * l * r)
+	}
+
+	this.mutex.Lock()
+	this.leftPower = leftPower
+	this.rightPower = rightPower
+	this.crossPower = crossPower
+	this.mutex.Unlock()
+}
+
+/*
+ * Clears the state of the correlation meter.
+ */
+func (this *correlationMeterStruct) Reset() {
+	this.mutex.Lock()
+	this.leftPower = 0.0
+	this.rightPower = 0.0
+	this.crossPower = 0.0
+	this.mutex.Unlock()
+}
+
+/*
+ * Creates a new correlation meter.
+ */
+func CreateCorrelationMeter() CorrelationMeter {
+	meter := correlationMeterStruct{}
+	return &meter
+}
@@ -0,0 +1,116 @@
+package level
+
+import (
+	"math"
+	"testing"
+)
+
+/*
+ * Check that the correlation meter tells signals in phase, in opposite phase
+ * and unrelated signals apart.
+ */
+func TestCorrelation(t *testing.T) {
+	sampleRate := uint32(DEFAULT_SAMPLE_RATE)
+	sampleRateFloat := float64(sampleRate)
+	left := make([]float64, sampleRate)
+	inPhase := make([]float64, sampleRate)
+	oppositePhase := make([]float64, sampleRate)
+	quadrature := make([]float64, sampleRate)
+
+	/*
+	 * Generate data series.
+	 */
+	for i := range left {
+		iFloat := float64(i)
+		arg := TWO_PI * TESTING_FREQUENCY * iFloat / sampleRateFloat
+		elem := 0.5 * math.Sin(arg)
+		left[i] = elem
+		inPhase[i] = 0.5 * elem
+		oppositePhase[i] = -elem
+		quadrature[i] = 0.5 * math.Cos(arg)
+	}
+
+	/*
+	 * Right channels to correlate with the left channel.
+	 */
+	rights := [][]float64{
+		inPhase,
+		oppositePhase,
+		quadrature,
+	}
+
+	/*
+	 * Expected correlation for each right channel.
+	 */
+	expected := []float64{
+		1.0,
+		-1.0,
+		0.0,
+	}
+
+	/*
+	 * Check the correlation of each signal.
+	 */
+	for i, right := range rights {
+		m := CreateCorrelationMeter()
+
+		/*
+		 * Feed a few seconds of the signal.
+		 */
+		for j := 0; j < 3; j++ {
+			m.Process(left, right, sampleRate)
+		}
+
+		res := m.Analyze()
+		correlation := res.Correlation()
+		expectedCorrelation := expected[i]
+
+		/*
+		 * Check if the correlation matches our expectations.
+		 */
+		if math.Abs(correlation-expectedCorrelation) > 0.01 {
+			t.Errorf("Correlation of signal %d does not match! Expected %f, got %f.", i, expectedCorrelation, correlation)
+		}
+
+	}
+
+	m := CreateCorrelationMeter()
+
+	/*
+	 * Feed a few seconds of signals in opposite phase.
+	 */
+	for j := 0; j < 3; j++ {
+		m.Process(left, oppositePhase, sampleRate)
+	}
+
+	res := m.Analyze()
+	mid := res.Mid()
+	side := res.Side()
+
+	/*
+	 * Signals in opposite phase cancel out in the mid signal.
+	 */
+	if mid != MIN_LEVEL {
+		t.Errorf("Mid level of signals in opposite phase does not match! Expected %f dBFS, got %f dBFS.", MIN_LEVEL, mid)
+	}
+
+	/*
+	 * The side signal has the level of the left channel.
+	 */
+	if math.Abs(side+9.03) > 0.1 {
+		t.Errorf("Side level of signals in opposite phase does not match! Expected %f dBFS, got %f dBFS.", -9.03, side)
+	}
+
+	m.Reset()
+	res = m.Analyze()
+	correlation := res.Correlation()
+	side = res.Side()
+
+	/*
+	 * Without a signal, there is neither correlation nor level.
+	 */
+	if correlation != 0.0 || side != MIN_LEVEL {
+		t.Errorf("Correlation meter was not reset! Expected %f and %f dBFS, got %f and %f dBFS.", 0.0, MIN_LEVEL, correlation, side)
+	}
+
+}
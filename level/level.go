@@ -10,38 +10,89 @@ import (
  * Global constants.
  */
 const (
-	PEAK_HOLD_TIME_SECONDS = 2
-	TIME_CONSTANT          = 1.7 // DIN IEC 60268-18
-	MIN_LEVEL              = -200.0
-	OUTPUT_COUNT           = 1
+	PEAK_HOLD_TIME_SECONDS        = 2
+	PEAK_HOLD_TIME_MAX            = 60.0
+	CLIP_LEVEL                    = 1.0
+	TIME_CONSTANT                 = 1.7 // DIN IEC 60268-18
+	MIN_LEVEL                     = -200.0
+	OUTPUT_COUNT                  = 1
+	BALLISTICS_PPM                = "ppm"
+	BALLISTICS_VU                 = "vu"
+	PPM_ATTACK_TIME               = 0.01   // 10 ms integration time
+	VU_TIME_CONSTANT              = 0.065  // 99 % of final reading after 300 ms
+	LOUDNESS_OFFSET               = -0.691 // ITU-R BS.1770
+	LOUDNESS_ABSOLUTE_GATE        = -70.0
+	LOUDNESS_RELATIVE_GATE        = -10.0
+	LOUDNESS_BLOCKS_PER_SECOND    = 10
+	LOUDNESS_GATING_BLOCKS        = 4  // 400 ms
+	LOUDNESS_SHORT_TERM_BLOCKS    = 30 // 3 s
+	LOUDNESS_HISTOGRAM_BINS       = 800
+	LOUDNESS_HISTOGRAM_RESOLUTION = 0.1
 )
 
 /*
  * Data structure representing the result of a level analysis.
  */
 type resultStruct struct {
-	level int32
-	peak  int32
+	level      int32
+	peak       int32
+	clips      uint64
+	rms        float64
+	shortTerm  float64
+	integrated float64
 }
 
 /*
  * The result of a level analysis.
  */
 type Result interface {
+	Clips() uint64
+	IntegratedLoudness() float64
 	Level() int32
 	Peak() int32
+	RMS() float64
+	ShortTermLoudness() float64
+}
+
+/*
+ * Data structure representing a second-order IIR filter section of the
+ * K-weighting filter.
+ */
+type biquadStruct struct {
+	b0 float64
+	b1 float64
+	b2 float64
+	a1 float64
+	a2 float64
+	z1 float64
+	z2 float64
 }
 
 /*
  * Data structure representing a level meter for a single channel.
  */
 type channelMeterStruct struct {
-	channelName   string
-	mutex         sync.RWMutex
-	enabled       bool
-	currentValue  float64
-	peakValue     float64
-	sampleCounter uint64
+	channelName       string
+	mutex             sync.RWMutex
+	enabled           bool
+	ballistics        string
+	peakHoldTime      float64
+	currentValue      float64
+	peakValue         float64
+	sampleCounter     uint64
+	clipCount         uint64
+	clipping          bool
+	meanSquare        float64
+	loudnessRate      uint32
+	shelf             biquadStruct
+	highPass          biquadStruct
+	blockEnergy       float64
+	blockSamples      uint32
+	subBlocks         [LOUDNESS_SHORT_TERM_BLOCKS]float64
+	subBlockIndex     int
+	subBlockCount     uint64
+	histogramCounts   [LOUDNESS_HISTOGRAM_BINS]uint64
+	histogramEnergies [LOUDNESS_HISTOGRAM_BINS]float64
 }
 
 /*
@@ -51,6 +102,8 @@ type meterStruct struct {
 	channelMeters []*channelMeterStruct
 	mutex         sync.RWMutex
 	enabled       bool
+	ballistics    string
+	peakHoldTime  float64
 }
 
 /*
@@ -58,11 +111,19 @@ type meterStruct struct {
  */
 type Meter interface {
 	Analyze(channelId uint32) (Result, error)
+	Ballistics() string
 	ChannelCount() uint32
 	ChannelName(channelId uint32) (string, error)
 	Enabled() bool
+	PeakHoldTime() float64
 	Process(inputBuffers [][]float64, sampleRate uint32) error
+	ResetLoudness()
+	ResetPeak(channelId uint32) error
+	ResetPeaks()
+	SetBallistics(name string) error
+	SetChannelName(channelId uint32, name string) error
 	SetEnabled(value bool)
+	SetPeakHoldTime(seconds float64) error
 }
 
 /*
@@ -73,6 +134,56 @@ func factorToDecibels(factor float64) float64 {
 	return result
 }
 
+/*
+ * Turn a mean square value into a loudness value in LUFS.
+ */
+func meanSquareToLoudness(meanSquare float64) float64 {
+	result := LOUDNESS_OFFSET + (10.0 * math.Log10(meanSquare))
+	return result
+}
+
+/*
+ * Ensure that a level is not below the minimum level.
+ */
+func limitLevel(level float64) float64 {
+	levelNaN := math.IsNaN(level)
+
+	/*
+	 * Ensure that the minimum level is not exceeded.
+	 */
+	if levelNaN || level < MIN_LEVEL {
+		level = MIN_LEVEL
+	}
+
+	return level
+}
+
+/*
+ * Feed a single sample through a filter section.
+ */
+func (this *biquadStruct) process(sample float64) float64 {
+	result := (this.b0 * sample) + this.z1
+	this.z1 = (this.b1 * sample) - (this.a1 * result) + this.z2
+	this.z2 = (this.b2 * sample) - (this.a2 * result)
+	return result
+}
+
+/*
+ * Returns the number of times the signal reached full scale.
+ */
+func (this *resultStruct) Clips() uint64 {
+	value := this.clips
+	return value
+}
+
+/*
+ * Returns the integrated (gated) loudness in LUFS.
+ */
+func (this *resultStruct) IntegratedLoudness() float64 {
+	value := this.integrated
+	return value
+}
+
 /*
  * Returns the current signal level.
  */
@@ -89,6 +200,22 @@ func (this *resultStruct) Peak() int32 {
 	return value
 }
 
+/*
+ * Returns the current RMS level in dBFS.
+ */
+func (this *resultStruct) RMS() float64 {
+	value := this.rms
+	return value
+}
+
+/*
+ * Returns the short-term loudness (over the last three seconds) in LUFS.
+ */
+func (this *resultStruct) ShortTermLoudness() float64 {
+	value := this.shortTerm
+	return value
+}
+
 /*
  * Perform analysis of signal level of a single channel.
  */
@@ -121,23 +248,226 @@ func (this *channelMeterStruct) analyze() Result {
 
 	peakLevelRounded := math.Round(peakLevel)
 	peakLevelInt := int32(peakLevelRounded)
+	this.mutex.RLock()
+	clipCount := this.clipCount
+	meanSquare := this.meanSquare
+	shortTerm := this.shortTermLoudness()
+	integrated := this.integratedLoudness()
+	this.mutex.RUnlock()
+	rms := 10.0 * math.Log10(meanSquare)
 
 	/*
 	 * Create result structure.
 	 */
 	result := resultStruct{
-		level: currentLevelInt,
-		peak:  peakLevelInt,
+		level:      currentLevelInt,
+		peak:       peakLevelInt,
+		clips:      clipCount,
+		rms:        limitLevel(rms),
+		shortTerm:  limitLevel(shortTerm),
+		integrated: limitLevel(integrated),
 	}
 
 	return &result
 }
 
+/*
+ * Clears the state of the loudness measurement.
+ *
+ * The caller must hold the write lock.
+ */
+func (this *channelMeterStruct) clearLoudness() {
+	this.shelf.z1 = 0.0
+	this.shelf.z2 = 0.0
+	this.highPass.z1 = 0.0
+	this.highPass.z2 = 0.0
+	this.blockEnergy = 0.0
+	this.blockSamples = 0
+	this.subBlocks = [LOUDNESS_SHORT_TERM_BLOCKS]float64{}
+	this.subBlockIndex = 0
+	this.subBlockCount = 0
+	this.histogramCounts = [LOUDNESS_HISTOGRAM_BINS]uint64{}
+	this.histogramEnergies = [LOUDNESS_HISTOGRAM_BINS]float64{}
+}
+
+/*
+ * Calculates the coefficients of the K-weighting filter (ITU-R BS.1770) for
+ * a certain sample rate and clears the loudness measurement.
+ *
+ * The caller must hold the write lock.
+ */
+func (this *channelMeterStruct) configureLoudness(sampleRate uint32) {
+	sampleRateFloat := float64(sampleRate)
+	shelfFrequency := 1681.974450955533
+	shelfGain := 3.999843853973347
+	shelfQ := 0.7071752369554196
+	k := math.Tan(math.Pi * shelfFrequency / sampleRateFloat)
+	kSquared := k * k
+	vh := math.Pow(10.0, shelfGain/20.0)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1.0 + (k / shelfQ) + kSquared
+	this.shelf.b0 = (vh + (vb * k / shelfQ) + kSquared) / a0
+	this.shelf.b1 = 2.0 * (kSquared - vh) / a0
+	this.shelf.b2 = (vh - (vb * k / shelfQ) + kSquared) / a0
+	this.shelf.a1 = 2.0 * (kSquared - 1.0) / a0
+	this.shelf.a2 = (1.0 - (k / shelfQ) + kSquared) / a0
+	highPassFrequency := 38.13547087602444
+	highPassQ := 0.5003270373238773
+	k = math.Tan(math.Pi * highPassFrequency / sampleRateFloat)
+	kSquared = k * k
+	a0 = 1.0 + (k / highPassQ) + kSquared
+	this.highPass.b0 = 1.0
+	this.highPass.b1 = -2.0
+	this.highPass.b2 = 1.0
+	this.highPass.a1 = 2.0 * (kSquared - 1.0) / a0
+	this.highPass.a2 = (1.0 - (k / highPassQ) + kSquared) / a0
+	this.loudnessRate = sampleRate
+	this.clearLoudness()
+}
+
+/*
+ * Stores the mean square of a completed 100 ms block and adds the 400 ms
+ * gating block ending with it to the loudness histogram.
+ *
+ * The caller must hold the write lock.
+ */
+func (this *channelMeterStruct) finishBlock(blockLength uint32) {
+	blockLengthFloat := float64(blockLength)
+	index := this.subBlockIndex
+	this.subBlocks[index] = this.blockEnergy / blockLengthFloat
+	this.subBlockIndex = (index + 1) % LOUDNESS_SHORT_TERM_BLOCKS
+	this.subBlockCount++
+	this.blockEnergy = 0.0
+	this.blockSamples = 0
+
+	/*
+	 * Only add a gating block once it is completely filled.
+	 */
+	if this.subBlockCount >= LOUDNESS_GATING_BLOCKS {
+		sum := 0.0
+
+		/*
+		 * Sum up the most recent blocks.
+		 */
+		for i := 0; i < LOUDNESS_GATING_BLOCKS; i++ {
+			idx := (index - i + LOUDNESS_SHORT_TERM_BLOCKS) % LOUDNESS_SHORT_TERM_BLOCKS
+			sum += this.subBlocks[idx]
+		}
+
+		meanSquare := sum / LOUDNESS_GATING_BLOCKS
+		loudness := meanSquareToLoudness(meanSquare)
+
+		/*
+		 * Blocks below the absolute gate do not contribute.
+		 */
+		if loudness >= LOUDNESS_ABSOLUTE_GATE {
+			binFloat := (loudness - LOUDNESS_ABSOLUTE_GATE) / LOUDNESS_HISTOGRAM_RESOLUTION
+			bin := int(binFloat)
+
+			/*
+			 * Louder blocks go into the last bin.
+			 */
+			if bin >= LOUDNESS_HISTOGRAM_BINS {
+				bin = LOUDNESS_HISTOGRAM_BINS - 1
+			}
+
+			this.histogramCounts[bin]++
+			this.histogramEnergies[bin] += meanSquare
+		}
+
+	}
+
+}
+
+/*
+ * Calculates the integrated loudness from the histogram of gating blocks,
+ * applying the absolute and relative gate.
+ *
+ * The caller must hold the read lock.
+ */
+func (this *channelMeterStruct) integratedLoudness() float64 {
+	count := uint64(0)
+	energy := 0.0
+
+	/*
+	 * Sum up all blocks above the absolute gate.
+	 */
+	for i, binCount := range this.histogramCounts {
+		count += binCount
+		energy += this.histogramEnergies[i]
+	}
+
+	/*
+	 * Without any blocks, there is no loudness.
+	 */
+	if count == 0 {
+		return MIN_LEVEL
+	} else {
+		countFloat := float64(count)
+		threshold := meanSquareToLoudness(energy/countFloat) + LOUDNESS_RELATIVE_GATE
+		startFloat := math.Ceil((threshold - LOUDNESS_ABSOLUTE_GATE) / LOUDNESS_HISTOGRAM_RESOLUTION)
+		start := int(startFloat)
+
+		/*
+		 * The relative gate is never below the absolute gate.
+		 */
+		if start < 0 {
+			start = 0
+		}
+
+		count = 0
+		energy = 0.0
+
+		/*
+		 * Sum up all blocks above the relative gate.
+		 */
+		for i := start; i < LOUDNESS_HISTOGRAM_BINS; i++ {
+			count += this.histogramCounts[i]
+			energy += this.histogramEnergies[i]
+		}
+
+		/*
+		 * Check if any blocks are left.
+		 */
+		if count == 0 {
+			return MIN_LEVEL
+		} else {
+			countFloat = float64(count)
+			result := meanSquareToLoudness(energy / countFloat)
+			return result
+		}
+
+	}
+
+}
+
+/*
+ * Calculates the loudness over the last three seconds.
+ *
+ * The caller must hold the read lock.
+ */
+func (this *channelMeterStruct) shortTermLoudness() float64 {
+	sum := 0.0
+
+	/*
+	 * Sum up the mean square of each block.
+	 */
+	for _, block := range this.subBlocks {
+		sum += block
+	}
+
+	meanSquare := sum / LOUDNESS_SHORT_TERM_BLOCKS
+	result := meanSquareToLoudness(meanSquare)
+	return result
+}
+
 /*
  * Returns the name of the channel measured by this channel meter.
  */
 func (this *channelMeterStruct) name() string {
+	this.mutex.RLock()
 	name := this.channelName
+	this.mutex.RUnlock()
 	return name
 }
 
@@ -157,11 +487,30 @@ func (this *channelMeterStruct) process(buffer []float64, sampleRate uint32) {
 		currentValue := this.currentValue
 		peakValue := this.peakValue
 		sampleCounter := this.sampleCounter
+		clipCount := this.clipCount
+		clipping := this.clipping
+		meanSquare := this.meanSquare
+		ballistics := this.ballistics
+		peakHoldTime := this.peakHoldTime
 		this.mutex.RUnlock()
 		sampleRateFloat := float64(sampleRate)
-		holdTimeSamples := uint64(PEAK_HOLD_TIME_SECONDS * sampleRateFloat)
+		holdTimeSamples := uint64(peakHoldTime * sampleRateFloat)
+		holdForever := peakHoldTime == 0.0
 		decayExp := -1.0 / (TIME_CONSTANT * sampleRateFloat)
 		decayFactor := math.Pow(10.0, decayExp)
+		ppm := ballistics == BALLISTICS_PPM
+		attackTime := VU_TIME_CONSTANT
+
+		/*
+		 * Peak programme meters integrate faster.
+		 */
+		if ppm {
+			attackTime = PPM_ATTACK_TIME
+		}
+
+		attackFactor := math.Exp(-1.0 / (attackTime * sampleRateFloat))
+		releaseExp := -2.0 / (TIME_CONSTANT * sampleRateFloat)
+		releaseFactor := math.Pow(10.0, releaseExp)
 
 		/*
 		 * Process each sample.
@@ -171,9 +520,10 @@ func (this *channelMeterStruct) process(buffer []float64, sampleRate uint32) {
 
 			/*
 			 * If we're above the hold time, let the peak indicator decay,
-			 * otherwise increment sample counter.
+			 * otherwise increment sample counter. A hold time of zero
+			 * holds the peak until it is reset.
 			 */
-			if sampleCounter > holdTimeSamples {
+			if !holdForever && sampleCounter > holdTimeSamples {
 				peakValue *= decayFactor
 			} else {
 				sampleCounter++
@@ -196,12 +546,79 @@ func (this *channelMeterStruct) process(buffer []float64, sampleRate uint32) {
 				sampleCounter = 0
 			}
 
+			/*
+			 * Count each run of samples at full scale as one clip.
+			 */
+			if sampleAbs >= CLIP_LEVEL {
+
+				/*
+				 * Check if this run of samples just started.
+				 */
+				if !clipping {
+					clipCount++
+				}
+
+				clipping = true
+			} else {
+				clipping = false
+			}
+
+			square := sample * sample
+
+			/*
+			 * A VU meter averages symmetrically, while a PPM falls back
+			 * at a constant rate (20 dB per 1.7 seconds).
+			 */
+			if ppm && square <= meanSquare {
+				meanSquare *= releaseFactor
+			} else {
+				meanSquare = (attackFactor * meanSquare) + ((1.0 - attackFactor) * square)
+			}
+
 		}
 
 		this.mutex.Lock()
 		this.currentValue = currentValue
 		this.peakValue = peakValue
 		this.sampleCounter = sampleCounter
+		this.clipCount = clipCount
+		this.clipping = clipping
+		this.meanSquare = meanSquare
+
+		blockLength := sampleRate / LOUDNESS_BLOCKS_PER_SECOND
+
+		/*
+		 * Loudness can only be measured at a sensible sample rate.
+		 */
+		if blockLength > 0 {
+
+			/*
+			 * Adjust the K-weighting filter to the sample rate.
+			 */
+			if sampleRate != this.loudnessRate {
+				this.configureLoudness(sampleRate)
+			}
+
+			/*
+			 * Feed the K-weighted signal into the loudness measurement.
+			 */
+			for _, sample := range buffer {
+				shelved := this.shelf.process(sample)
+				weighted := this.highPass.process(shelved)
+				this.blockEnergy += weighted * weighted
+				this.blockSamples++
+
+				/*
+				 * Check if a 100 ms block is complete.
+				 */
+				if this.blockSamples >= blockLength {
+					this.finishBlock(blockLength)
+				}
+
+			}
+
+		}
+
 		this.mutex.Unlock()
 	}
 
@@ -226,6 +643,10 @@ func (this *channelMeterStruct) setEnabled(value bool) {
 			this.currentValue = 0.0
 			this.peakValue = 0.0
 			this.sampleCounter = 0
+			this.clipCount = 0
+			this.clipping = false
+			this.meanSquare = 0.0
+			this.clearLoudness()
 		}
 
 		this.enabled = value
@@ -234,6 +655,45 @@ func (this *channelMeterStruct) setEnabled(value bool) {
 	this.mutex.Unlock()
 }
 
+/*
+ * Selects the ballistics of the RMS measurement for this channel.
+ */
+func (this *channelMeterStruct) setBallistics(name string) {
+	this.mutex.Lock()
+	this.ballistics = name
+	this.mutex.Unlock()
+}
+
+/*
+ * Changes the time the peak indicator of this channel is held.
+ */
+func (this *channelMeterStruct) setPeakHoldTime(seconds float64) {
+	this.mutex.Lock()
+	this.peakHoldTime = seconds
+	this.mutex.Unlock()
+}
+
+/*
+ * Clears the peak indicator and the clip counter of this channel.
+ */
+func (this *channelMeterStruct) resetPeak() {
+	this.mutex.Lock()
+	this.peakValue = 0.0
+	this.sampleCounter = 0
+	this.clipCount = 0
+	this.clipping = false
+	this.mutex.Unlock()
+}
+
+/*
+ * Restarts the loudness measurement for this channel.
+ */
+func (this *channelMeterStruct) resetLoudness() {
+	this.mutex.Lock()
+	this.clearLoudness()
+	this.mutex.Unlock()
+}
+
 /*
  * Analyze the level of a certain channel.
  */
@@ -255,6 +715,16 @@ func (this *meterStruct) Analyze(channelId uint32) (Result, error) {
 
 }
 
+/*
+ * Returns the ballistics of the RMS measurement.
+ */
+func (this *meterStruct) Ballistics() string {
+	this.mutex.RLock()
+	ballistics := this.ballistics
+	this.mutex.RUnlock()
+	return ballistics
+}
+
 /*
  * Returns the number of channels this meter is able to process.
  */
@@ -296,6 +766,26 @@ func (this *meterStruct) Enabled() bool {
 	return enabled
 }
 
+/*
+ * Returns the time (in seconds) the peak indicators are held, where zero
+ * means that they are held until they are reset.
+ */
+func (this *meterStruct) PeakHoldTime() float64 {
+	this.mutex.RLock()
+	seconds := this.peakHoldTime
+	this.mutex.RUnlock()
+	return seconds
+}
+
+/*
+ * Renames the channel measured by this channel meter.
+ */
+func (this *channelMeterStruct) setName(name string) {
+	this.mutex.Lock()
+	this.channelName = name
+	this.mutex.Unlock()
+}
+
 /*
  * Process input buffers for multiple channels.
  */
@@ -324,6 +814,106 @@ func (this *meterStruct) Process(buffers [][]float64, sampleRate uint32) error {
 
 }
 
+/*
+ * Restarts the loudness measurement of all channels.
+ */
+func (this *meterStruct) ResetLoudness() {
+	channelMeters := this.channelMeters
+
+	/*
+	 * Reset each channel meter.
+	 */
+	for _, channelMeter := range channelMeters {
+		channelMeter.resetLoudness()
+	}
+
+}
+
+/*
+ * Clears the peak indicator and the clip counter of a certain channel.
+ */
+func (this *meterStruct) ResetPeak(channelId uint32) error {
+	channelMeters := this.channelMeters
+	numMeters := len(channelMeters)
+	numMeters32 := uint32(numMeters)
+
+	/*
+	 * Check if channel number is within range.
+	 */
+	if channelId >= numMeters32 {
+		return fmt.Errorf("Requested to reset channel %d, but level meter only has %d channels.", channelId, numMeters)
+	} else {
+		channelMeter := channelMeters[channelId]
+		channelMeter.resetPeak()
+		return nil
+	}
+
+}
+
+/*
+ * Clears the peak indicators and the clip counters of all channels.
+ */
+func (this *meterStruct) ResetPeaks() {
+	channelMeters := this.channelMeters
+
+	/*
+	 * Reset each channel meter.
+	 */
+	for _, channelMeter := range channelMeters {
+		channelMeter.resetPeak()
+	}
+
+}
+
+/*
+ * Selects the ballistics of the RMS measurement, either "vu" or "ppm".
+ */
+func (this *meterStruct) SetBallistics(name string) error {
+
+	/*
+	 * Check if the ballistics are supported.
+	 */
+	if name != BALLISTICS_VU && name != BALLISTICS_PPM {
+		return fmt.Errorf("Ballistics must be '%s' or '%s', got '%s'.", BALLISTICS_VU, BALLISTICS_PPM, name)
+	} else {
+		this.mutex.Lock()
+		channelMeters := this.channelMeters
+
+		/*
+		 * Change the ballistics of each channel meter.
+		 */
+		for _, channelMeter := range channelMeters {
+			channelMeter.setBallistics(name)
+		}
+
+		this.ballistics = name
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Renames the channel with the provided id.
+ */
+func (this *meterStruct) SetChannelName(channelId uint32, name string) error {
+	channelMeters := this.channelMeters
+	numMeters := len(channelMeters)
+	numMeters32 := uint32(numMeters)
+
+	/*
+	 * Check if channel number is within range.
+	 */
+	if channelId >= numMeters32 {
+		return fmt.Errorf("Requested to rename channel %d, but level meter only has %d channels.", channelId, numMeters)
+	} else {
+		channelMeter := channelMeters[channelId]
+		channelMeter.setName(name)
+		return nil
+	}
+
+}
+
 /*
  * Enables or disables this level meter.
  */
@@ -350,6 +940,36 @@ func (this *meterStruct) SetEnabled(value bool) {
 	this.mutex.Unlock()
 }
 
+/*
+ * Changes the time (in seconds) the peak indicators are held. A hold time of
+ * zero holds them until they are reset.
+ */
+func (this *meterStruct) SetPeakHoldTime(seconds float64) error {
+	secondsNaN := math.IsNaN(seconds)
+
+	/*
+	 * Check if the hold time is within range.
+	 */
+	if secondsNaN || seconds < 0.0 || seconds > PEAK_HOLD_TIME_MAX {
+		return fmt.Errorf("Peak hold time must be between %f and %f seconds.", 0.0, PEAK_HOLD_TIME_MAX)
+	} else {
+		this.mutex.Lock()
+		channelMeters := this.channelMeters
+
+		/*
+		 * Change the hold time of each channel meter.
+		 */
+		for _, channelMeter := range channelMeters {
+			channelMeter.setPeakHoldTime(seconds)
+		}
+
+		this.peakHoldTime = seconds
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
 /*
  * Creates a new level meter for a certain number of channels.
  */
@@ -377,6 +997,8 @@ func CreateMeter(numChannels uint32, names []string) (Meter, error) {
 			channelMeter := &channelMeterStruct{
 				channelName:   name,
 				enabled:       false,
+				ballistics:    BALLISTICS_VU,
+				peakHoldTime:  PEAK_HOLD_TIME_SECONDS,
 				currentValue:  0.0,
 				peakValue:     0.0,
 				sampleCounter: 0,
@@ -391,6 +1013,8 @@ func CreateMeter(numChannels uint32, names []string) (Meter, error) {
 		meter := meterStruct{
 			channelMeters: channelMeters,
 			enabled:       false,
+			ballistics:    BALLISTICS_VU,
+			peakHoldTime:  PEAK_HOLD_TIME_SECONDS,
 		}
 
 		return &meter, nil
@@ -1,6 +1,7 @@
 package level
 
 import (
+	"fmt"
 	"math"
 	"testing"
 )
@@ -222,3 +223,468 @@ func TestMeters(t *testing.T) {
 	}
 
 }
+
+/*
+ * Check that processing audio in the level meter does not allocate memory.
+ */
+func TestMeterAllocations(t *testing.T) {
+
+	/*
+	 * Number of channels of each level meter.
+	 */
+	channelCounts := []uint32{
+		1,
+		2,
+		8,
+	}
+
+	/*
+	 * Number of samples per buffer.
+	 */
+	bufferSizes := []int{
+		64,
+		256,
+		4096,
+	}
+
+	/*
+	 * Feed each level meter.
+	 */
+	for i, numChannels := range channelCounts {
+		bufferSize := bufferSizes[i]
+		buffers := make([][]float64, numChannels)
+		names := make([]string, numChannels)
+
+		/*
+		 * Generate a sine wave of a different level for each channel.
+		 */
+		for j := range buffers {
+			buf := make([]float64, bufferSize)
+			jFloat := float64(j + 1)
+
+			/*
+			 * Generate data series.
+			 */
+			for k := range buf {
+				kFloat := float64(k)
+				arg := TWO_PI * kFloat / 64.0
+				buf[k] = math.Sin(arg) / jFloat
+			}
+
+			buffers[j] = buf
+			names[j] = fmt.Sprintf("channel_%d", j)
+		}
+
+		m, err := CreateMeter(numChannels, names)
+
+		/*
+		 * Check if level meter was sucessfully created.
+		 */
+		if err != nil {
+			msg := err.Error()
+			t.Errorf("Creating %d channel level meter failed: %s", numChannels, msg)
+		} else {
+			m.SetEnabled(true)
+
+			/*
+			 * Feed the buffers to the level meter once.
+			 */
+			process := func() {
+				m.Process(buffers, DEFAULT_SAMPLE_RATE)
+			}
+
+			allocs := testing.AllocsPerRun(100, process)
+
+			/*
+			 * Check if processing allocated memory.
+			 */
+			if allocs != 0 {
+				t.Errorf("Processing %d channels of %d samples allocated memory. Expected %d allocations, got %f.", numChannels, bufferSize, 0, allocs)
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Check that channels of the level meter can be renamed.
+ */
+func TestChannelRename(t *testing.T) {
+
+	/*
+	 * Channel names.
+	 */
+	names := []string{
+		"channel_a",
+		"channel_b",
+	}
+
+	m, err := CreateMeter(2, names)
+
+	/*
+	 * Check if level meter was sucessfully created.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Creating %d channel level meter failed: %s", 2, msg)
+	} else {
+		err = m.SetChannelName(1, "guitar")
+
+		/*
+		 * Check if channel could be renamed.
+		 */
+		if err != nil {
+			msg := err.Error()
+			t.Errorf("Renaming channel %d returned error: %s", 1, msg)
+		} else {
+			nameA, _ := m.ChannelName(0)
+			nameB, _ := m.ChannelName(1)
+
+			/*
+			 * Verify that only the renamed channel changed its name.
+			 */
+			if nameA != "channel_a" {
+				t.Errorf("Name of channel %d incorrect. Expected: '%s' Got: '%s'", 0, "channel_a", nameA)
+			} else if nameB != "guitar" {
+				t.Errorf("Name of channel %d incorrect. Expected: '%s' Got: '%s'", 1, "guitar", nameB)
+			}
+
+		}
+
+		err = m.SetChannelName(2, "bass")
+
+		/*
+		 * Renaming a channel which does not exist must fail.
+		 */
+		if err == nil {
+			t.Errorf("Renaming channel %d of a %d channel level meter did not return error.", 2, 2)
+		}
+
+	}
+
+}
+
+/*
+ * Check the RMS level and the loudness measurement against the reference
+ * values of ITU-R BS.1770.
+ */
+func TestLoudness(t *testing.T) {
+
+	/*
+	 * Channel names.
+	 */
+	names := []string{
+		"channel_a",
+		"channel_b",
+	}
+
+	m, err := CreateMeter(2, names)
+
+	/*
+	 * Check if level meter was sucessfully created.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Creating %d channel level meter failed: %s", 2, msg)
+	} else {
+		m.SetEnabled(true)
+		sampleRate := uint32(48000)
+		sampleRateFloat := float64(sampleRate)
+		bufferSize := 480
+		bufTone := make([]float64, bufferSize)
+		bufQuiet := make([]float64, bufferSize)
+		position := 0
+
+		/*
+		 * Feed the level meter with a number of buffers.
+		 */
+		feed := func(numBuffers int, tone bool) {
+
+			/*
+			 * Generate and process each buffer.
+			 */
+			for i := 0; i < numBuffers; i++ {
+
+				/*
+				 * Generate a sine wave at 997 Hz, at full scale
+				 * and at -20 dBFS.
+				 */
+				for j := range bufTone {
+					sample := 0.0
+
+					/*
+					 * Check if a tone should be generated.
+					 */
+					if tone {
+						positionFloat := float64(position)
+						arg := TWO_PI * 997.0 * positionFloat / sampleRateFloat
+						sample = math.Sin(arg)
+					}
+
+					bufTone[j] = sample
+					bufQuiet[j] = 0.1 * sample
+					position++
+				}
+
+				/*
+				 * Channel buffers.
+				 */
+				bufs := [][]float64{
+					bufTone,
+					bufQuiet,
+				}
+
+				m.Process(bufs, sampleRate)
+			}
+
+		}
+
+		feed(500, true)
+		resA, _ := m.Analyze(0)
+		resB, _ := m.Analyze(1)
+
+		/*
+		 * Expected results for each channel.
+		 */
+		expected := []float64{
+			-3.01,
+			-23.01,
+		}
+
+		results := []Result{
+			resA,
+			resB,
+		}
+
+		/*
+		 * Check the RMS level and loudness of each channel.
+		 */
+		for i, res := range results {
+			expectedValue := expected[i]
+			rms := res.RMS()
+			shortTerm := res.ShortTermLoudness()
+			integrated := res.IntegratedLoudness()
+
+			/*
+			 * Check the RMS level.
+			 */
+			if math.Abs(rms-expectedValue) > 0.1 {
+				t.Errorf("RMS level of channel %d does not match! Expected %f dBFS, got %f dBFS.", i, expectedValue, rms)
+			}
+
+			/*
+			 * Check the short-term loudness.
+			 */
+			if math.Abs(shortTerm-expectedValue) > 0.1 {
+				t.Errorf("Short-term loudness of channel %d does not match! Expected %f LUFS, got %f LUFS.", i, expectedValue, shortTerm)
+			}
+
+			/*
+			 * Check the integrated loudness.
+			 */
+			if math.Abs(integrated-expectedValue) > 0.1 {
+				t.Errorf("Integrated loudness of channel %d does not match! Expected %f LUFS, got %f LUFS.", i, expectedValue, integrated)
+			}
+
+		}
+
+		feed(500, false)
+		resA, _ = m.Analyze(0)
+		shortTerm := resA.ShortTermLoudness()
+		integrated := resA.IntegratedLoudness()
+
+		/*
+		 * After silence, the short-term loudness must drop.
+		 */
+		if shortTerm != MIN_LEVEL {
+			t.Errorf("Short-term loudness after silence does not match! Expected %f LUFS, got %f LUFS.", MIN_LEVEL, shortTerm)
+		}
+
+		/*
+		 * Silence must be gated from the integrated loudness, only the
+		 * blocks overlapping the end of the tone may lower it slightly.
+		 */
+		if math.Abs(integrated-expected[0]) > 0.2 {
+			t.Errorf("Integrated loudness after silence does not match! Expected %f LUFS, got %f LUFS.", expected[0], integrated)
+		}
+
+		err = m.SetBallistics(BALLISTICS_PPM)
+
+		/*
+		 * Check if ballistics could be changed.
+		 */
+		if err != nil {
+			msg := err.Error()
+			t.Errorf("Changing ballistics returned error: %s", msg)
+		} else {
+			feed(5, true)
+			resA, _ = m.Analyze(0)
+			rms := resA.RMS()
+
+			/*
+			 * A PPM should read close to the RMS level after 50 ms.
+			 */
+			if rms < -4.0 || rms > 0.0 {
+				t.Errorf("RMS level of PPM does not match! Expected %f dBFS, got %f dBFS.", expected[0], rms)
+			}
+
+		}
+
+		err = m.SetBallistics("digital")
+
+		/*
+		 * Unsupported ballistics must be rejected.
+		 */
+		if err == nil {
+			t.Errorf("Changing ballistics to '%s' did not return error.", "digital")
+		}
+
+		m.ResetLoudness()
+		resA, _ = m.Analyze(0)
+		integrated = resA.IntegratedLoudness()
+
+		/*
+		 * After a reset, there is no integrated loudness.
+		 */
+		if integrated != MIN_LEVEL {
+			t.Errorf("Integrated loudness after reset does not match! Expected %f LUFS, got %f LUFS.", MIN_LEVEL, integrated)
+		}
+
+	}
+
+}
+
+/*
+ * Check that peaks can be held until they are reset and that clips are
+ * counted.
+ */
+func TestPeakHold(t *testing.T) {
+
+	/*
+	 * Channel names.
+	 */
+	names := []string{
+		"channel_a",
+	}
+
+	m, err := CreateMeter(1, names)
+
+	/*
+	 * Check if level meter was sucessfully created.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Creating %d channel level meter failed: %s", 1, msg)
+	} else {
+		m.SetEnabled(true)
+		err = m.SetPeakHoldTime(-1.0)
+
+		/*
+		 * A negative hold time must be rejected.
+		 */
+		if err == nil {
+			t.Errorf("Setting peak hold time to %f did not return error.", -1.0)
+		}
+
+		err = m.SetPeakHoldTime(0.0)
+
+		/*
+		 * Check if hold time could be changed.
+		 */
+		if err != nil {
+			msg := err.Error()
+			t.Errorf("Setting peak hold time returned error: %s", msg)
+		} else {
+			buf := make([]float64, DEFAULT_SAMPLE_RATE)
+			buf[100] = 1.0
+			buf[101] = -1.0
+			buf[200] = 0.5
+			buf[300] = 1.5
+
+			/*
+			 * Channel buffers.
+			 */
+			bufs := [][]float64{
+				buf,
+			}
+
+			m.Process(bufs, DEFAULT_SAMPLE_RATE)
+			silence := make([]float64, DEFAULT_SAMPLE_RATE)
+
+			/*
+			 * Channel buffers.
+			 */
+			bufs = [][]float64{
+				silence,
+			}
+
+			/*
+			 * Feed ten seconds of silence.
+			 */
+			for i := 0; i < 10; i++ {
+				m.Process(bufs, DEFAULT_SAMPLE_RATE)
+			}
+
+			res, _ := m.Analyze(0)
+			peak := res.Peak()
+			clips := res.Clips()
+
+			/*
+			 * The peak must still be held.
+			 */
+			if peak != 4 {
+				t.Errorf("Peak level does not match! Expected %d, got %d.", 4, peak)
+			}
+
+			/*
+			 * Both runs of samples at full scale must be counted.
+			 */
+			if clips != 2 {
+				t.Errorf("Clip count does not match! Expected %d, got %d.", 2, clips)
+			}
+
+			err = m.ResetPeak(0)
+
+			/*
+			 * Check if the channel could be reset.
+			 */
+			if err != nil {
+				msg := err.Error()
+				t.Errorf("Resetting channel %d returned error: %s", 0, msg)
+			} else {
+				res, _ = m.Analyze(0)
+				peak = res.Peak()
+				clips = res.Clips()
+
+				/*
+				 * Check if the peak was cleared.
+				 */
+				if peak != -200 {
+					t.Errorf("Peak level after reset does not match! Expected %d, got %d.", -200, peak)
+				}
+
+				/*
+				 * Check if the clip count was cleared.
+				 */
+				if clips != 0 {
+					t.Errorf("Clip count after reset does not match! Expected %d, got %d.", 0, clips)
+				}
+
+			}
+
+			err = m.ResetPeak(1)
+
+			/*
+			 * Resetting a channel which does not exist must fail.
+			 */
+			if err == nil {
+				t.Errorf("Resetting channel %d of a %d channel level meter did not return error.", 1, 1)
+			}
+
+		}
+
+	}
+
+}
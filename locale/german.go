@@ -0,0 +1,236 @@
+package locale
+
+/*
+ * Returns the catalog of the German language.
+ *
+ * More specific format strings have to be listed before more general ones,
+ * since the first one matching a message is used to translate it.
+ */
+func germanCatalog() catalogStruct {
+
+	/*
+	 * Display names of unit types and parameters.
+	 */
+	names := map[string]string{
+		"algorithmic_reverb":       "Algorithmischer Hall",
+		"amp_model":                "Verstärkermodell",
+		"attack_time":              "Ansprechzeit",
+		"auto_wah":                 "Auto-Wah",
+		"auto_yoy":                 "Auto-Yoy",
+		"bandpass":                 "Bandpass",
+		"bands":                    "Bänder",
+		"bass":                     "Bass",
+		"bias":                     "Vorspannung",
+		"blend":                    "Mischung",
+		"boost":                    "Anhebung",
+		"cabinet":                  "Lautsprecherbox",
+		"cents":                    "Cent",
+		"chorus":                   "Chorus",
+		"composite":                "Zusammengesetzte Einheit",
+		"compressor":               "Kompressor",
+		"convolution_reverb":       "Faltungshall",
+		"decay":                    "Abklingen",
+		"decay_trim":               "Abklingkorrektur",
+		"delay":                    "Echo",
+		"delay_time":               "Verzögerungszeit",
+		"depth":                    "Tiefe",
+		"direction":                "Richtung",
+		"distortion":               "Verzerrer",
+		"drive":                    "Verzerrung",
+		"envelope_filter":          "Hüllkurvenfilter",
+		"excess":                   "Übersteuerung",
+		"feedback":                 "Rückkopplung",
+		"filter_order":             "Filterordnung",
+		"filter_type":              "Filtertyp",
+		"flanger":                  "Flanger",
+		"follow":                   "Folgen",
+		"frequency":                "Frequenz",
+		"frequency_1":              "Frequenz 1",
+		"frequency_2":              "Frequenz 2",
+		"fuzz":                     "Fuzz",
+		"gain":                     "Verstärkung",
+		"gain_limit":               "Verstärkungsgrenze",
+		"harmony":                  "Harmonie",
+		"harmony_interval":         "Harmonieintervall",
+		"harmony_level":            "Harmoniepegel",
+		"high":                     "Höhen",
+		"high_cut":                 "Hochschnitt",
+		"high_cut_frequency":       "Hochschnittfrequenz",
+		"high_shelf_frequency":     "Frequenz Höhenkuhschwanz",
+		"high_shelf_gain":          "Verstärkung Höhenkuhschwanz",
+		"hold_time":                "Haltezeit",
+		"impulse_response":         "Impulsantwort",
+		"input_amplitude":          "Eingangsamplitude",
+		"input_gain":               "Eingangsverstärkung",
+		"knee":                     "Knie",
+		"level":                    "Pegel",
+		"level_1":                  "Pegel 1",
+		"level_2":                  "Pegel 2",
+		"level_clean":              "Pegel unverzerrt",
+		"level_dist":               "Pegel verzerrt",
+		"level_hysteresis":         "Pegelhysterese",
+		"level_octave_down_first":  "Pegel erste Oktave abwärts",
+		"level_octave_down_second": "Pegel zweite Oktave abwärts",
+		"level_octave_up":          "Pegel Oktave aufwärts",
+		"lookahead":                "Vorausschau",
+		"low":                      "Tiefen",
+		"low_cut":                  "Tiefschnitt",
+		"low_cut_frequency":        "Tiefschnittfrequenz",
+		"low_shelf_frequency":      "Frequenz Tiefenkuhschwanz",
+		"low_shelf_gain":           "Verstärkung Tiefenkuhschwanz",
+		"makeup":                   "Aufholverstärkung",
+		"makeup_gain":              "Aufholverstärkung",
+		"middle":                   "Mitten",
+		"minimum_level":            "Mindestpegel",
+		"mix":                      "Mischung",
+		"mode":                     "Modus",
+		"model":                    "Modell",
+		"multitap_delay":           "Mehrfachecho",
+		"noise_gate":               "Rauschsperre",
+		"octaver":                  "Oktaver",
+		"overdrive":                "Overdrive",
+		"oversampling":             "Überabtastung",
+		"parametric_eq":            "Parametrischer Equalizer",
+		"phase":                    "Phase",
+		"phaser":                   "Phaser",
+		"pitch_shifter":            "Tonhöhenverschiebung",
+		"plugin":                   "Plugin",
+		"power_amp":                "Endstufe",
+		"pre_delay":                "Vorverzögerung",
+		"presence":                 "Präsenz",
+		"ratio":                    "Verhältnis",
+		"release_time":             "Abklingzeit",
+		"resonance":                "Resonanz",
+		"reverb":                   "Hall",
+		"ring_modulator":           "Ringmodulator",
+		"semitones":                "Halbtöne",
+		"sensitivity":              "Empfindlichkeit",
+		"sidechain_cutoff":         "Grenzfrequenz Seitenkette",
+		"signal_amplitude":         "Signalamplitude",
+		"signal_frequency":         "Signalfrequenz",
+		"signal_gain":              "Signalverstärkung",
+		"signal_generator":         "Signalgenerator",
+		"signal_type":              "Signaltyp",
+		"slow_gear":                "Slow Gear",
+		"speed":                    "Geschwindigkeit",
+		"stages":                   "Stufen",
+		"studio_compressor":        "Studiokompressor",
+		"sub_octaver":              "Suboktaver",
+		"sweep_end":                "Ende des Durchlaufs",
+		"sweep_start":              "Beginn des Durchlaufs",
+		"sweep_time":               "Dauer des Durchlaufs",
+		"swell_time":               "Einblendzeit",
+		"sync":                     "Synchronisation",
+		"target_level":             "Zielpegel",
+		"threshold":                "Schwellwert",
+		"threshold_close":          "Schwellwert Schließen",
+		"threshold_open":           "Schwellwert Öffnen",
+		"tone":                     "Klang",
+		"tone_stack":               "Klangregelung",
+		"treble":                   "Höhen",
+		"tremolo":                  "Tremolo",
+		"type":                     "Typ",
+		"valve":                    "Röhre",
+		"waveform":                 "Wellenform",
+	}
+
+	/*
+	 * Messages returned by the API.
+	 */
+	messages := []messageStruct{
+		messageStruct{message: "A rendering is already in progress.", translation: "Es läuft bereits eine Berechnung."},
+		messageStruct{message: "Automation lane ID out of range.", translation: "Automatisierungsspur außerhalb des gültigen Bereichs."},
+		messageStruct{message: "Cannot add more than %d automation lanes.", translation: "Es können nicht mehr als %d Automatisierungsspuren hinzugefügt werden."},
+		messageStruct{message: "Cannot add or remove channels while recording.", translation: "Während der Aufnahme können keine Kanäle hinzugefügt oder entfernt werden."},
+		messageStruct{message: "Cannot remove the last channel.", translation: "Der letzte Kanal kann nicht entfernt werden."},
+		messageStruct{message: "Chain ID %d out of range.", translation: "Kette %d außerhalb des gültigen Bereichs."},
+		messageStruct{message: "Chain ID out of range.", translation: "Kette außerhalb des gültigen Bereichs."},
+		messageStruct{message: "Channel ID out of range.", translation: "Kanal außerhalb des gültigen Bereichs."},
+		messageStruct{message: "Channel name too long.", translation: "Kanalname zu lang."},
+		messageStruct{message: "Channel trim must be between %d and %d dB.", translation: "Die Kanalanpassung muss zwischen %d und %d dB liegen."},
+		messageStruct{message: "Click level must be within [0, 1].", translation: "Der Klickpegel muss in [0, 1] liegen."},
+		messageStruct{message: "Color must be given in hexadecimal notation (#rrggbb).", translation: "Die Farbe muss hexadezimal angegeben werden (#rrggbb)."},
+		messageStruct{message: "Color must be in hexadecimal notation (#rrggbb).", translation: "Die Farbe muss hexadezimal angegeben werden (#rrggbb)."},
+		messageStruct{message: "Failed to decode boolean value.", translation: "Wahrheitswert konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to decode bus ID.", translation: "Bus konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to decode chain ID.", translation: "Kette konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to decode channel ID.", translation: "Kanal konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to decode lane ID.", translation: "Automatisierungsspur konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to decode fade-out.", translation: "Ausblendung konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to decode maximum length.", translation: "Maximale Länge konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to decode reference pitch.", translation: "Kammerton konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to decode unit ID.", translation: "Effekteinheit konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to decode unit count.", translation: "Anzahl der Effekteinheiten konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to decode unit type.", translation: "Typ der Effekteinheit konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to decode value.", translation: "Wert konnte nicht dekodiert werden."},
+		messageStruct{message: "Failed to read clip.", translation: "Ausschnitt konnte nicht gelesen werden."},
+		messageStruct{message: "File is not a chain preset.", translation: "Die Datei ist kein Ketten-Preset."},
+		messageStruct{message: "File is not a patch file.", translation: "Die Datei ist keine Patch-Datei."},
+		messageStruct{message: "Input calibration did not measure any signal.", translation: "Die Eingangskalibrierung hat kein Signal gemessen."},
+		messageStruct{message: "Input calibration is still running.", translation: "Die Eingangskalibrierung läuft noch."},
+		messageStruct{message: "Input calibration was not started.", translation: "Die Eingangskalibrierung wurde nicht gestartet."},
+		messageStruct{message: "Library entry needs a name.", translation: "Der Bibliothekseintrag braucht einen Namen."},
+		messageStruct{message: "Multiple clips sent in request.", translation: "Mehrere Ausschnitte in der Anfrage gesendet."},
+		messageStruct{message: "Multiple patch files sent in request.", translation: "Mehrere Patch-Dateien in der Anfrage gesendet."},
+		messageStruct{message: "Multiple track files sent in request.", translation: "Mehrere Spurdateien in der Anfrage gesendet."},
+		messageStruct{message: "Multiple presets sent in request.", translation: "Mehrere Presets in der Anfrage gesendet."},
+		messageStruct{message: "No clip sent in request.", translation: "Kein Ausschnitt in der Anfrage gesendet."},
+		messageStruct{message: "No metronome present.", translation: "Kein Metronom vorhanden."},
+		messageStruct{message: "No patch file sent in request.", translation: "Keine Patch-Datei in der Anfrage gesendet."},
+		messageStruct{message: "No preset sent in request.", translation: "Kein Preset in der Anfrage gesendet."},
+		messageStruct{message: "No rendering in progress.", translation: "Es läuft keine Berechnung."},
+		messageStruct{message: "No scene is active.", translation: "Keine Szene ist aktiv."},
+		messageStruct{message: "No track file sent in request.", translation: "Keine Spurdatei in der Anfrage gesendet."},
+		messageStruct{message: "Only GET and POST requests are supported.", translation: "Nur GET- und POST-Anfragen werden unterstützt."},
+		messageStruct{message: "The setlist is empty.", translation: "Die Setlist ist leer."},
+		messageStruct{message: "Unit label too long.", translation: "Bezeichnung der Effekteinheit zu lang."},
+		messageStruct{message: "Unit note too long.", translation: "Notiz zur Effekteinheit zu lang."},
+		messageStruct{message: "Already at the first scene.", translation: "Bereits bei der ersten Szene."},
+		messageStruct{message: "Already at the last scene.", translation: "Bereits bei der letzten Szene."},
+		messageStruct{message: "Reference pitch must be in [%.1f, %.1f] Hz.", translation: "Der Kammerton muss in [%.1f, %.1f] Hz liegen."},
+		messageStruct{message: "No library entry '%s'.", translation: "Kein Bibliothekseintrag '%s'."},
+		messageStruct{message: "No impulse response '%s'.", translation: "Keine Impulsantwort '%s'."},
+		messageStruct{message: "No unit %d.", translation: "Keine Effekteinheit %d."},
+		messageStruct{message: "Unit %d is not a composite unit.", translation: "Effekteinheit %d ist keine zusammengesetzte Einheit."},
+		messageStruct{message: "Snapshot '%s' is empty.", translation: "Schnappschuss '%s' ist leer."},
+		messageStruct{message: "Unknown endpoint '%s'.", translation: "Unbekannter Endpunkt '%s'."},
+		messageStruct{message: "Unknown metronome parameter: '%s'", translation: "Unbekannter Metronom-Parameter: '%s'"},
+		messageStruct{message: "Unknown notation: '%s'", translation: "Unbekannte Notenbenennung: '%s'"},
+		messageStruct{message: "Unknown player parameter: '%s'", translation: "Unbekannter Wiedergabe-Parameter: '%s'"},
+		messageStruct{message: "Unknown temperament: '%s'", translation: "Unbekannte Stimmung: '%s'"},
+		messageStruct{message: "Unknown tuner parameter: '%s'", translation: "Unbekannter Stimmgerät-Parameter: '%s'"},
+		messageStruct{message: "Unknown tuning: '%s'", translation: "Unbekannte Saitenstimmung: '%s'"},
+		messageStruct{message: "Cannot reset parameter: Unknown parameter '%s'.", translation: "Parameter kann nicht zurückgesetzt werden: Unbekannter Parameter '%s'."},
+		messageStruct{message: "Failed to set numeric value: Parameter '%s' must be between '%d' and '%d' - got '%d'.", translation: "Numerischer Wert konnte nicht gesetzt werden: Parameter '%s' muss zwischen '%d' und '%d' liegen - erhalten '%d'."},
+		messageStruct{message: "Failed to set numeric value: Parameter '%s' is not numeric.", translation: "Numerischer Wert konnte nicht gesetzt werden: Parameter '%s' ist nicht numerisch."},
+		messageStruct{message: "Failed to set numeric value: Could not find parameter with name '%s'.", translation: "Numerischer Wert konnte nicht gesetzt werden: Parameter '%s' nicht gefunden."},
+		messageStruct{message: "Failed to set discrete value: Value '%s' is not valid for parameter '%s'.", translation: "Diskreter Wert konnte nicht gesetzt werden: Wert '%s' ist für Parameter '%s' nicht gültig."},
+		messageStruct{message: "Failed to set discrete value: Parameter '%s' is not discrete.", translation: "Diskreter Wert konnte nicht gesetzt werden: Parameter '%s' ist nicht diskret."},
+		messageStruct{message: "Failed to set discrete value: Could not find parameter with name '%s'.", translation: "Diskreter Wert konnte nicht gesetzt werden: Parameter '%s' nicht gefunden."},
+		messageStruct{message: "Failed to load track: %s", translation: "Spur konnte nicht geladen werden: %s"},
+		messageStruct{message: "Failed to perform analysis: %s", translation: "Analyse fehlgeschlagen: %s"},
+		messageStruct{message: "Failed to render preview: %s", translation: "Vorschau konnte nicht berechnet werden: %s"},
+		messageStruct{message: "Failed to reload impulse responses: %s", translation: "Impulsantworten konnten nicht neu geladen werden: %s"},
+		messageStruct{message: "Failed to set impulse response options: %s", translation: "Optionen der Impulsantwort konnten nicht gesetzt werden: %s"},
+		messageStruct{message: "Failed to seek: %s", translation: "Positionierung fehlgeschlagen: %s"},
+		messageStruct{message: "Failed to set master section value: %s", translation: "Wert der Summensektion konnte nicht gesetzt werden: %s"},
+		messageStruct{message: "Failed to set player level: %s", translation: "Wiedergabepegel konnte nicht gesetzt werden: %s"},
+		messageStruct{message: "Failed to set metronome speed: %s", translation: "Metronomgeschwindigkeit konnte nicht gesetzt werden: %s"},
+		messageStruct{message: "Cannot change the speaker layout while recording.", translation: "Während der Aufnahme kann die Lautsprecheranordnung nicht geändert werden."},
+		messageStruct{message: "Incompatible version of file format.", translation: "Inkompatible Version des Dateiformats."},
+		messageStruct{message: "Patch contains %d channels, but only %d are available.", translation: "Der Patch enthält %d Kanäle, aber nur %d sind verfügbar."},
+		messageStruct{message: "Patch contains %d aux buses, but only %d are available.", translation: "Der Patch enthält %d Aux-Busse, aber nur %d sind verfügbar."},
+		messageStruct{message: "Patch was not restored, since it contains %d errors: %s", translation: "Der Patch wurde nicht wiederhergestellt, da er %d Fehler enthält: %s"},
+	}
+
+	/*
+	 * Create catalog.
+	 */
+	cat := catalogStruct{
+		language: LANGUAGE_GERMAN,
+		names:    names,
+		messages: messages,
+	}
+
+	return cat
+}
@@ -0,0 +1,302 @@
+package locale
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	LANGUAGE_DEFAULT = LANGUAGE_ENGLISH
+	LANGUAGE_ENGLISH = "en"
+	LANGUAGE_GERMAN  = "de"
+)
+
+/*
+ * The expression matching the verbs of a format string.
+ */
+var verbExpression = regexp.MustCompile(`%(\.[0-9]+)?[sdvfgq]`)
+
+/*
+ * A message and its translation.
+ *
+ * The message may be a format string, in which case the translation must
+ * contain the same number of verbs, in the same order.
+ */
+type messageStruct struct {
+	message     string
+	translation string
+}
+
+/*
+ * The strings of a language.
+ */
+type catalogStruct struct {
+	language string
+	names    map[string]string
+	messages []messageStruct
+}
+
+/*
+ * Returns the catalogs of all supported languages.
+ *
+ * Messages are written in English, so the English catalog does not need
+ * to translate any messages.
+ */
+func catalogs() []catalogStruct {
+
+	/*
+	 * The supported languages.
+	 */
+	cats := []catalogStruct{
+		catalogStruct{
+			language: LANGUAGE_ENGLISH,
+			names:    map[string]string{},
+			messages: []messageStruct{},
+		},
+		germanCatalog(),
+	}
+
+	return cats
+}
+
+/*
+ * Returns the index of the catalog of a language or -1 if the language is
+ * not supported.
+ */
+func catalogIndex(cats []catalogStruct, language string) int {
+
+	/*
+	 * Iterate over the catalogs.
+	 */
+	for i, cat := range cats {
+
+		/*
+		 * Check if we found the language.
+		 */
+		if cat.language == language {
+			return i
+		}
+
+	}
+
+	return -1
+}
+
+/*
+ * Turns an identifier into a display name by replacing underscores with
+ * spaces and capitalizing the first letter.
+ */
+func humanize(id string) string {
+	words := strings.Replace(id, "_", " ", -1)
+	words = strings.TrimSpace(words)
+	first, size := utf8.DecodeRuneInString(words)
+
+	/*
+	 * Check if there is anything to capitalize.
+	 */
+	if size == 0 {
+		return words
+	} else {
+		upper := unicode.ToUpper(first)
+		rest := words[size:]
+		return string(upper) + rest
+	}
+
+}
+
+/*
+ * Matches a message against a format string and returns the values filled
+ * into its verbs.
+ */
+func match(format string, msg string) ([]string, bool) {
+	literals := verbExpression.Split(format, -1)
+	numLiterals := len(literals)
+
+	/*
+	 * Check if the format string contains any verbs.
+	 */
+	if numLiterals < 2 {
+		return nil, false
+	} else {
+		quoted := make([]string, numLiterals)
+
+		/*
+		 * Quote the literal parts of the format string.
+		 */
+		for i, literal := range literals {
+			quoted[i] = regexp.QuoteMeta(literal)
+		}
+
+		pattern := "^" + strings.Join(quoted, "(.*?)") + "$"
+		expression, err := regexp.Compile(pattern)
+
+		/*
+		 * Check if the pattern compiled.
+		 */
+		if err != nil {
+			return nil, false
+		} else {
+			submatches := expression.FindStringSubmatch(msg)
+
+			/*
+			 * Check if the message matched.
+			 */
+			if submatches == nil {
+				return nil, false
+			} else {
+				values := submatches[1:]
+				return values, true
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Fills values into the verbs of a format string.
+ *
+ * The values are inserted as strings, since they were taken from an
+ * already formatted message.
+ */
+func fill(format string, values []string) string {
+	stringFormat := verbExpression.ReplaceAllString(format, "%s")
+	numValues := len(values)
+	args := make([]interface{}, numValues)
+
+	/*
+	 * Convert values into arguments.
+	 */
+	for i, value := range values {
+		args[i] = value
+	}
+
+	return fmt.Sprintf(stringFormat, args...)
+}
+
+/*
+ * Returns the names of all supported languages.
+ */
+func Languages() []string {
+	cats := catalogs()
+	numCats := len(cats)
+	languages := make([]string, numCats)
+
+	/*
+	 * Collect the language of each catalog.
+	 */
+	for i, cat := range cats {
+		languages[i] = cat.language
+	}
+
+	return languages
+}
+
+/*
+ * Checks whether a language is supported.
+ */
+func IsLanguage(language string) bool {
+	cats := catalogs()
+	idx := catalogIndex(cats, language)
+	return idx >= 0
+}
+
+/*
+ * Returns the display name of an identifier, like the type of an effects
+ * unit or the name of a parameter, in a certain language.
+ *
+ * Identifiers without a translation and identifiers in unsupported
+ * languages are turned into a display name as they are.
+ */
+func DisplayName(language string, id string) string {
+	cats := catalogs()
+	idx := catalogIndex(cats, language)
+
+	/*
+	 * Look up the identifier in the catalog of the language.
+	 */
+	if idx >= 0 {
+		cat := cats[idx]
+		name, ok := cat.names[id]
+
+		/*
+		 * Check if the identifier has a translation.
+		 */
+		if ok {
+			return name
+		}
+
+	}
+
+	return humanize(id)
+}
+
+/*
+ * Translates a message into a certain language.
+ *
+ * Messages are first looked up as they are, then matched against the
+ * format strings they may have been created from. Values filled into a
+ * format string are translated as well, since they are often messages
+ * themselves. Messages without a translation are returned as they are.
+ */
+func Translate(language string, msg string) string {
+	cats := catalogs()
+	idx := catalogIndex(cats, language)
+
+	/*
+	 * Check if the language is supported.
+	 */
+	if (idx < 0) || (msg == "") {
+		return msg
+	} else {
+		cat := cats[idx]
+		messages := cat.messages
+
+		/*
+		 * Look for an exact match first.
+		 */
+		for _, message := range messages {
+
+			/*
+			 * Check if the message matches as it is.
+			 */
+			if message.message == msg {
+				return message.translation
+			}
+
+		}
+
+		/*
+		 * Then match the message against format strings.
+		 */
+		for _, message := range messages {
+			values, ok := match(message.message, msg)
+
+			/*
+			 * Check if the message was created from this format string.
+			 */
+			if ok {
+
+				/*
+				 * Translate the values filled into the format string.
+				 */
+				for i, value := range values {
+					values[i] = Translate(language, value)
+				}
+
+				return fill(message.translation, values)
+			}
+
+		}
+
+		return msg
+	}
+
+}
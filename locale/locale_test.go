@@ -0,0 +1,165 @@
+package locale
+
+import (
+	"testing"
+)
+
+/*
+ * Verify that identifiers are turned into display names.
+ */
+func TestDisplayName(t *testing.T) {
+
+	/*
+	 * Languages to look up display names in.
+	 */
+	languages := []string{
+		LANGUAGE_ENGLISH,
+		LANGUAGE_GERMAN,
+		LANGUAGE_GERMAN,
+		"xx",
+		LANGUAGE_ENGLISH,
+	}
+
+	/*
+	 * Identifiers to look up.
+	 */
+	ids := []string{
+		"noise_gate",
+		"noise_gate",
+		"level_3",
+		"delay_time",
+		"",
+	}
+
+	/*
+	 * Expected display names.
+	 */
+	expectedNames := []string{
+		"Noise gate",
+		"Rauschsperre",
+		"Level 3",
+		"Delay time",
+		"",
+	}
+
+	/*
+	 * Look up each identifier.
+	 */
+	for i, id := range ids {
+		language := languages[i]
+		expected := expectedNames[i]
+		name := DisplayName(language, id)
+
+		/*
+		 * Check if the display name is the expected one.
+		 */
+		if name != expected {
+			t.Errorf("Display name of '%s' in '%s' should be '%s', but is '%s'.", id, language, expected, name)
+		}
+
+	}
+
+}
+
+/*
+ * Verify that messages and messages created from format strings are
+ * translated.
+ */
+func TestTranslate(t *testing.T) {
+
+	/*
+	 * Languages to translate messages into.
+	 */
+	languages := []string{
+		LANGUAGE_ENGLISH,
+		LANGUAGE_GERMAN,
+		LANGUAGE_GERMAN,
+		LANGUAGE_GERMAN,
+		LANGUAGE_GERMAN,
+		LANGUAGE_GERMAN,
+		LANGUAGE_GERMAN,
+		"xx",
+		LANGUAGE_GERMAN,
+	}
+
+	/*
+	 * Messages to translate.
+	 */
+	msgs := []string{
+		"Chain ID out of range.",
+		"Chain ID out of range.",
+		"Chain ID 7 out of range.",
+		"Unknown notation: 'dutch'",
+		"Reference pitch must be in [400.0, 480.0] Hz.",
+		"Failed to perform analysis: Chain ID out of range.",
+		"Something nobody translated.",
+		"Chain ID out of range.",
+		"",
+	}
+
+	/*
+	 * Expected translations.
+	 */
+	expectedTranslations := []string{
+		"Chain ID out of range.",
+		"Kette außerhalb des gültigen Bereichs.",
+		"Kette 7 außerhalb des gültigen Bereichs.",
+		"Unbekannte Notenbenennung: 'dutch'",
+		"Der Kammerton muss in [400.0, 480.0] Hz liegen.",
+		"Analyse fehlgeschlagen: Kette außerhalb des gültigen Bereichs.",
+		"Something nobody translated.",
+		"Chain ID out of range.",
+		"",
+	}
+
+	/*
+	 * Translate each message.
+	 */
+	for i, msg := range msgs {
+		language := languages[i]
+		expected := expectedTranslations[i]
+		translation := Translate(language, msg)
+
+		/*
+		 * Check if the translation is the expected one.
+		 */
+		if translation != expected {
+			t.Errorf("Translation of '%s' into '%s' should be '%s', but is '%s'.", msg, language, expected, translation)
+		}
+
+	}
+
+}
+
+/*
+ * Verify that each format string has as many verbs as its translation.
+ */
+func TestCatalogs(t *testing.T) {
+	cats := catalogs()
+
+	/*
+	 * Check the messages of each catalog.
+	 */
+	for _, cat := range cats {
+
+		/*
+		 * Check each message.
+		 */
+		for _, message := range cat.messages {
+			verbs := verbExpression.FindAllString(message.message, -1)
+			translatedVerbs := verbExpression.FindAllString(message.translation, -1)
+			numVerbs := len(verbs)
+			numTranslatedVerbs := len(translatedVerbs)
+
+			/*
+			 * The translation must take the same values.
+			 */
+			if numVerbs != numTranslatedVerbs {
+				t.Errorf("Translation of '%s' into '%s' has %d verbs instead of %d.", message.message, cat.language, numTranslatedVerbs, numVerbs)
+			}
+
+		}
+
+	}
+
+}
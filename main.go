@@ -4,13 +4,29 @@ import (
 	"flag"
 	"fmt"
 	"github.com/andrepxx/go-dsp-guitar/controller"
+	"github.com/andrepxx/go-dsp-guitar/remote"
+	"os"
 )
 
 /*
  * The entry point of our program.
  */
 func main() {
+	numArgs := len(os.Args)
+
+	/*
+	 * Remote control a running instance if invoked as 'dsp ctl ...'.
+	 */
+	if (numArgs > 1) && (os.Args[1] == "ctl") {
+		args := os.Args[2:]
+		code := remote.Run(args)
+		os.Exit(code)
+	}
+
 	numChannels := flag.Uint64("channels", 0, "Number of channels for batch processing")
+	batchJob := flag.String("batch-job", "", "Job file for unattended batch processing")
+	captureJob := flag.String("capture-ir", "", "Job file for capturing an impulse response from a sweep recording")
+	checkFlag := flag.Bool("check", false, "Check the configuration and the audio hardware and exit")
 	versionFlag := flag.Bool("version", false, "Print version information and exit")
 	flag.Parse()
 
@@ -29,6 +45,42 @@ func main() {
 		}
 
 		fmt.Printf("%s\n", msg)
+	} else if *checkFlag {
+		ok := controller.Check()
+
+		/*
+		 * Indicate failure if the software is unable to start.
+		 */
+		if !ok {
+			os.Exit(1)
+		}
+
+	} else if *batchJob != "" {
+		cn := controller.CreateController()
+		err := cn.ProcessJob(*batchJob)
+
+		/*
+		 * If an error occured, print error message and indicate failure.
+		 */
+		if err != nil {
+			msg := err.Error()
+			fmt.Printf("Batch job failed: %s\n", msg)
+			os.Exit(1)
+		}
+
+	} else if *captureJob != "" {
+		cn := controller.CreateController()
+		err := cn.CaptureImpulseResponse(*captureJob)
+
+		/*
+		 * If an error occured, print error message and indicate failure.
+		 */
+		if err != nil {
+			msg := err.Error()
+			fmt.Printf("Impulse response capture failed: %s\n", msg)
+			os.Exit(1)
+		}
+
 	} else {
 		numChannels32 := uint32(*numChannels)
 		cn := controller.CreateController()
@@ -0,0 +1,532 @@
+package master
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	BALANCE_MAXIMUM = 100
+	BALANCE_MINIMUM = -100
+	GAIN_MAXIMUM    = 12
+	GAIN_MINIMUM    = -12
+	HIGH_FREQUENCY  = 4000.0
+	LEVEL_MAXIMUM   = 12
+	LEVEL_MINIMUM   = -48
+	LOW_FREQUENCY   = 250.0
+	MATH_TWO_PI     = 2.0 * math.Pi
+	SOFT_CLIP_KNEE  = 0.75
+	WIDTH_DEFAULT   = 100
+	WIDTH_MAXIMUM   = 200
+	WIDTH_MINIMUM   = 0
+)
+
+/*
+ * Indices of the factors applied to the bands of the mid and side signals
+ * and to the left and right output.
+ */
+const (
+	FACTOR_MID_LOW = iota
+	FACTOR_MID_MIDDLE
+	FACTOR_MID_HIGH
+	FACTOR_SIDE_LOW
+	FACTOR_SIDE_MIDDLE
+	FACTOR_SIDE_HIGH
+	FACTOR_LEFT
+	FACTOR_RIGHT
+	FACTOR_COUNT
+)
+
+/*
+ * Data structure representing a parameter of the master section.
+ */
+type Parameter struct {
+	Name         string
+	PhysicalUnit string
+	Minimum      int32
+	Maximum      int32
+	Value        int32
+}
+
+/*
+ * Data structure representing the state of the band splitting filters for
+ * the mid or side signal.
+ */
+type bandSplitterStruct struct {
+	lowState  float64
+	highState float64
+}
+
+/*
+ * Data structure representing the master section.
+ */
+type masterStruct struct {
+	mutex       sync.RWMutex
+	enabled     bool
+	params      []Parameter
+	factors     [FACTOR_COUNT]float64
+	primed      bool
+	mid         bandSplitterStruct
+	side        bandSplitterStruct
+	targetCache [FACTOR_COUNT]float64
+}
+
+/*
+ * Interface type for the master section, which processes the stereo master
+ * output in mid/side representation after the spatializer and trims the
+ * level, balance and polarity of the final left and right output, which may
+ * be soft clipped.
+ */
+type Master interface {
+	Enabled() bool
+	Parameters() []Parameter
+	Process(left []float64, right []float64, sampleRate uint32)
+	Reset()
+	SetEnabled(value bool)
+	SetValue(name string, value int32) error
+	Value(name string) (int32, error)
+}
+
+/*
+ * Returns the parameters of the master section with their default values.
+ */
+func defaultParameters() []Parameter {
+
+	/*
+	 * The parameters of the master section.
+	 */
+	params := []Parameter{
+		Parameter{
+			Name:         "mid_gain",
+			PhysicalUnit: "dB",
+			Minimum:      GAIN_MINIMUM,
+			Maximum:      GAIN_MAXIMUM,
+			Value:        0,
+		},
+		Parameter{
+			Name:         "mid_low",
+			PhysicalUnit: "dB",
+			Minimum:      GAIN_MINIMUM,
+			Maximum:      GAIN_MAXIMUM,
+			Value:        0,
+		},
+		Parameter{
+			Name:         "mid_high",
+			PhysicalUnit: "dB",
+			Minimum:      GAIN_MINIMUM,
+			Maximum:      GAIN_MAXIMUM,
+			Value:        0,
+		},
+		Parameter{
+			Name:         "side_gain",
+			PhysicalUnit: "dB",
+			Minimum:      GAIN_MINIMUM,
+			Maximum:      GAIN_MAXIMUM,
+			Value:        0,
+		},
+		Parameter{
+			Name:         "side_low",
+			PhysicalUnit: "dB",
+			Minimum:      GAIN_MINIMUM,
+			Maximum:      GAIN_MAXIMUM,
+			Value:        0,
+		},
+		Parameter{
+			Name:         "side_high",
+			PhysicalUnit: "dB",
+			Minimum:      GAIN_MINIMUM,
+			Maximum:      GAIN_MAXIMUM,
+			Value:        0,
+		},
+		Parameter{
+			Name:         "width",
+			PhysicalUnit: "%",
+			Minimum:      WIDTH_MINIMUM,
+			Maximum:      WIDTH_MAXIMUM,
+			Value:        WIDTH_DEFAULT,
+		},
+		Parameter{
+			Name:         "level",
+			PhysicalUnit: "dB",
+			Minimum:      LEVEL_MINIMUM,
+			Maximum:      LEVEL_MAXIMUM,
+			Value:        0,
+		},
+		Parameter{
+			Name:         "balance",
+			PhysicalUnit: "%",
+			Minimum:      BALANCE_MINIMUM,
+			Maximum:      BALANCE_MAXIMUM,
+			Value:        0,
+		},
+		Parameter{
+			Name:         "invert_left",
+			PhysicalUnit: "",
+			Minimum:      0,
+			Maximum:      1,
+			Value:        0,
+		},
+		Parameter{
+			Name:         "invert_right",
+			PhysicalUnit: "",
+			Minimum:      0,
+			Maximum:      1,
+			Value:        0,
+		},
+		Parameter{
+			Name:         "soft_clip",
+			PhysicalUnit: "",
+			Minimum:      0,
+			Maximum:      1,
+			Value:        0,
+		},
+	}
+
+	return params
+}
+
+/*
+ * Converts a level in decibels into a factor.
+ */
+func decibelsToFactor(decibels int32) float64 {
+	decibelsFloat := float64(decibels)
+	exponent := 0.05 * decibelsFloat
+	factor := math.Pow(10.0, exponent)
+	return factor
+}
+
+/*
+ * Calculates the factor by which the output of a one-pole lowpass filter
+ * approaches its input with each sample.
+ */
+func lowpassCoefficient(frequency float64, sampleRate uint32) float64 {
+	sampleRateFloat := float64(sampleRate)
+	arg := -MATH_TWO_PI * (frequency / sampleRateFloat)
+	coefficient := 1.0 - math.Exp(arg)
+	return coefficient
+}
+
+/*
+ * Limits a sample to full scale with a smooth knee. Samples below the knee
+ * pass unchanged, while louder ones approach full scale asymptotically.
+ */
+func softClip(sample float64) float64 {
+	magnitude := math.Abs(sample)
+
+	/*
+	 * Only compress samples above the knee.
+	 */
+	if magnitude <= SOFT_CLIP_KNEE {
+		return sample
+	} else {
+		headroom := 1.0 - SOFT_CLIP_KNEE
+		excess := (magnitude - SOFT_CLIP_KNEE) / headroom
+		limited := SOFT_CLIP_KNEE + (headroom * math.Tanh(excess))
+		result := math.Copysign(limited, sample)
+		return result
+	}
+
+}
+
+/*
+ * Splits a sample into a low, a middle and a high band, which add up to the
+ * original sample again.
+ */
+func (this *bandSplitterStruct) split(sample float64, coefficientLow float64, coefficientHigh float64) (float64, float64, float64) {
+	this.lowState += coefficientLow * (sample - this.lowState)
+	this.highState += coefficientHigh * (sample - this.highState)
+	low := this.lowState
+	middle := this.highState - low
+	high := sample - this.highState
+	return low, middle, high
+}
+
+/*
+ * Calculates the factors applied to the bands of the mid and side signals
+ * and to the left and right output from the parameters.
+ *
+ * The balance attenuates the opposite side only, so that the centered
+ * balance leaves both sides at the output level. Inverting the polarity of a
+ * side negates its factor, so that the change is ramped through zero like any
+ * other.
+ *
+ * The mutex must be held when calling this.
+ */
+func (this *masterStruct) targets() [FACTOR_COUNT]float64 {
+	params := this.params
+	midGain := decibelsToFactor(params[0].Value)
+	midLow := decibelsToFactor(params[1].Value)
+	midHigh := decibelsToFactor(params[2].Value)
+	sideGain := decibelsToFactor(params[3].Value)
+	sideLow := decibelsToFactor(params[4].Value)
+	sideHigh := decibelsToFactor(params[5].Value)
+	widthFloat := float64(params[6].Value)
+	width := 0.01 * widthFloat
+	sideFactor := width * sideGain
+	level := decibelsToFactor(params[7].Value)
+	balanceFloat := float64(params[8].Value)
+	balance := 0.01 * balanceFloat
+	leftFactor := level
+	rightFactor := level
+
+	/*
+	 * Attenuate the side opposite to the balance.
+	 */
+	if balance > 0.0 {
+		leftFactor *= 1.0 - balance
+	} else if balance < 0.0 {
+		rightFactor *= 1.0 + balance
+	}
+
+	/*
+	 * Check if the polarity of the left side is inverted.
+	 */
+	if params[9].Value != 0 {
+		leftFactor = -leftFactor
+	}
+
+	/*
+	 * Check if the polarity of the right side is inverted.
+	 */
+	if params[10].Value != 0 {
+		rightFactor = -rightFactor
+	}
+
+	targets := [FACTOR_COUNT]float64{}
+	targets[FACTOR_MID_LOW] = midGain * midLow
+	targets[FACTOR_MID_MIDDLE] = midGain
+	targets[FACTOR_MID_HIGH] = midGain * midHigh
+	targets[FACTOR_SIDE_LOW] = sideFactor * sideLow
+	targets[FACTOR_SIDE_MIDDLE] = sideFactor
+	targets[FACTOR_SIDE_HIGH] = sideFactor * sideHigh
+	targets[FACTOR_LEFT] = leftFactor
+	targets[FACTOR_RIGHT] = rightFactor
+	return targets
+}
+
+/*
+ * Returns the index of a parameter or -1 if there is no such parameter.
+ *
+ * The mutex must be held when calling this.
+ */
+func (this *masterStruct) parameterIndex(name string) int {
+
+	/*
+	 * Compare the name of each parameter.
+	 */
+	for i, param := range this.params {
+
+		/*
+		 * Check if we found the parameter.
+		 */
+		if param.Name == name {
+			return i
+		}
+
+	}
+
+	return -1
+}
+
+/*
+ * Returns whether the master section processes the master output.
+ */
+func (this *masterStruct) Enabled() bool {
+	this.mutex.RLock()
+	enabled := this.enabled
+	this.mutex.RUnlock()
+	return enabled
+}
+
+/*
+ * Returns the parameters of the master section along with their values.
+ */
+func (this *masterStruct) Parameters() []Parameter {
+	this.mutex.RLock()
+	params := this.params
+	numParams := len(params)
+	result := make([]Parameter, numParams)
+	copy(result, params)
+	this.mutex.RUnlock()
+	return result
+}
+
+/*
+ * Processes the left and right master output in place.
+ *
+ * Changes to the parameters are ramped over the length of the buffers. If
+ * soft clipping is enabled, the output is limited to full scale as the last
+ * step.
+ */
+func (this *masterStruct) Process(left []float64, right []float64, sampleRate uint32) {
+	this.mutex.RLock()
+	enabled := this.enabled
+	targets := this.targetCache
+	soft := this.params[11].Value != 0
+	this.mutex.RUnlock()
+	numSamples := len(left)
+
+	/*
+	 * Only process if enabled and both buffers have the same size.
+	 */
+	if enabled && (numSamples == len(right)) && (numSamples > 0) {
+
+		/*
+		 * Start at the target values when processing for the first time.
+		 */
+		if !this.primed {
+			this.factors = targets
+			this.primed = true
+		}
+
+		factors := this.factors
+		steps := [FACTOR_COUNT]float64{}
+		numSamplesFloat := float64(numSamples)
+
+		/*
+		 * Calculate the increment of each factor per sample.
+		 */
+		for i, target := range targets {
+			steps[i] = (target - factors[i]) / numSamplesFloat
+		}
+
+		coefficientLow := lowpassCoefficient(LOW_FREQUENCY, sampleRate)
+		coefficientHigh := lowpassCoefficient(HIGH_FREQUENCY, sampleRate)
+
+		/*
+		 * Process each sample.
+		 */
+		for i, sampleLeft := range left {
+			sampleRight := right[i]
+
+			/*
+			 * Ramp each factor towards its target.
+			 */
+			for j, step := range steps {
+				factors[j] += step
+			}
+
+			mid := 0.5 * (sampleLeft + sampleRight)
+			side := 0.5 * (sampleLeft - sampleRight)
+			midLow, midMiddle, midHigh := this.mid.split(mid, coefficientLow, coefficientHigh)
+			sideLow, sideMiddle, sideHigh := this.side.split(side, coefficientLow, coefficientHigh)
+			midOut := (factors[FACTOR_MID_LOW] * midLow) + (factors[FACTOR_MID_MIDDLE] * midMiddle) + (factors[FACTOR_MID_HIGH] * midHigh)
+			sideOut := (factors[FACTOR_SIDE_LOW] * sideLow) + (factors[FACTOR_SIDE_MIDDLE] * sideMiddle) + (factors[FACTOR_SIDE_HIGH] * sideHigh)
+			outLeft := factors[FACTOR_LEFT] * (midOut + sideOut)
+			outRight := factors[FACTOR_RIGHT] * (midOut - sideOut)
+
+			/*
+			 * Limit the output to full scale, if requested.
+			 */
+			if soft {
+				outLeft = softClip(outLeft)
+				outRight = softClip(outRight)
+			}
+
+			left[i] = outLeft
+			right[i] = outRight
+		}
+
+		this.factors = targets
+	}
+
+}
+
+/*
+ * Restores the default values of all parameters. Does not change whether the
+ * master section is enabled.
+ */
+func (this *masterStruct) Reset() {
+	params := defaultParameters()
+	this.mutex.Lock()
+	this.params = params
+	this.targetCache = this.targets()
+	this.mutex.Unlock()
+}
+
+/*
+ * Sets whether the master section processes the master output.
+ */
+func (this *masterStruct) SetEnabled(value bool) {
+	this.mutex.Lock()
+	this.enabled = value
+	this.mutex.Unlock()
+}
+
+/*
+ * Sets the value of a parameter.
+ */
+func (this *masterStruct) SetValue(name string, value int32) error {
+	this.mutex.Lock()
+	idx := this.parameterIndex(name)
+	err := error(nil)
+
+	/*
+	 * Check if the parameter exists and the value is in range.
+	 */
+	if idx < 0 {
+		err = fmt.Errorf("Master section has no parameter '%s'.", name)
+	} else {
+		param := this.params[idx]
+		minimum := param.Minimum
+		maximum := param.Maximum
+
+		/*
+		 * Check if the value is in range.
+		 */
+		if (value < minimum) || (value > maximum) {
+			err = fmt.Errorf("Value for parameter '%s' must be in [%d, %d].", name, minimum, maximum)
+		} else {
+			this.params[idx].Value = value
+			this.targetCache = this.targets()
+		}
+
+	}
+
+	this.mutex.Unlock()
+	return err
+}
+
+/*
+ * Returns the value of a parameter.
+ */
+func (this *masterStruct) Value(name string) (int32, error) {
+	this.mutex.RLock()
+	idx := this.parameterIndex(name)
+	value := int32(0)
+	err := error(nil)
+
+	/*
+	 * Check if the parameter exists.
+	 */
+	if idx < 0 {
+		err = fmt.Errorf("Master section has no parameter '%s'.", name)
+	} else {
+		value = this.params[idx].Value
+	}
+
+	this.mutex.RUnlock()
+	return value, err
+}
+
+/*
+ * Creates a master section, which is disabled and transparent by default.
+ */
+func Create() Master {
+	params := defaultParameters()
+
+	/*
+	 * Create the master section.
+	 */
+	m := masterStruct{
+		enabled: false,
+		params:  params,
+	}
+
+	m.targetCache = m.targets()
+	return &m
+}
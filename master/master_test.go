@@ -0,0 +1,322 @@
+package master
+
+import (
+	"math"
+	"testing"
+)
+
+const (
+	DEFAULT_SAMPLE_RATE = 96000
+	TESTING_LENGTH      = 1024
+	TESTING_TOLERANCE   = 1e-9
+	TWO_PI              = 2.0 * math.Pi
+)
+
+/*
+ * Generates a stereo test signal with different content on both sides.
+ */
+func testSignal() ([]float64, []float64) {
+	left := make([]float64, TESTING_LENGTH)
+	right := make([]float64, TESTING_LENGTH)
+
+	/*
+	 * Generate data series.
+	 */
+	for i := range left {
+		iFloat := float64(i)
+		argLeft := TWO_PI * iFloat / 64.0
+		argRight := TWO_PI * iFloat / 20.0
+		left[i] = 0.5 * math.Sin(argLeft)
+		right[i] = 0.25 * math.Sin(argRight)
+	}
+
+	return left, right
+}
+
+/*
+ * Check that the master section does not change the signal with its default
+ * settings.
+ */
+func TestTransparent(t *testing.T) {
+	left, right := testSignal()
+	expectedLeft := make([]float64, TESTING_LENGTH)
+	expectedRight := make([]float64, TESTING_LENGTH)
+	copy(expectedLeft, left)
+	copy(expectedRight, right)
+	m := Create()
+	m.SetEnabled(true)
+	m.Process(left, right, DEFAULT_SAMPLE_RATE)
+
+	/*
+	 * Compare each sample.
+	 */
+	for i, sample := range left {
+		diffLeft := math.Abs(sample - expectedLeft[i])
+		diffRight := math.Abs(right[i] - expectedRight[i])
+
+		/*
+		 * Check if the samples were left unchanged.
+		 */
+		if (diffLeft > TESTING_TOLERANCE) || (diffRight > TESTING_TOLERANCE) {
+			t.Errorf("Sample %d changed. Expected (%f, %f), got (%f, %f).", i, expectedLeft[i], expectedRight[i], sample, right[i])
+		}
+
+	}
+
+}
+
+/*
+ * Check that a width of zero turns the signal into mono.
+ */
+func TestWidth(t *testing.T) {
+	left, right := testSignal()
+	m := Create()
+	m.SetEnabled(true)
+	err := m.SetValue("width", 0)
+
+	/*
+	 * Check if the width was set.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Setting width failed: %s", msg)
+	} else {
+		m.Process(left, right, DEFAULT_SAMPLE_RATE)
+
+		/*
+		 * Compare each sample.
+		 */
+		for i, sample := range left {
+			diff := math.Abs(sample - right[i])
+
+			/*
+			 * Check if both sides carry the same signal.
+			 */
+			if diff > TESTING_TOLERANCE {
+				t.Errorf("Sample %d differs between sides: (%f, %f)", i, sample, right[i])
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Check that the level, balance and polarity are applied to the left and
+ * right output.
+ */
+func TestOutputStage(t *testing.T) {
+	left, right := testSignal()
+	expectedLeft := make([]float64, TESTING_LENGTH)
+	expectedRight := make([]float64, TESTING_LENGTH)
+	level := decibelsToFactor(-6)
+
+	/*
+	 * Half the level on the left side and inverted polarity on the right.
+	 */
+	for i, sample := range left {
+		expectedLeft[i] = 0.5 * level * sample
+		expectedRight[i] = -level * right[i]
+	}
+
+	m := Create()
+	m.SetEnabled(true)
+	m.SetValue("level", -6)
+	m.SetValue("balance", 50)
+	m.SetValue("invert_right", 1)
+	m.Process(left, right, DEFAULT_SAMPLE_RATE)
+
+	/*
+	 * Compare each sample.
+	 */
+	for i, sample := range left {
+		diffLeft := math.Abs(sample - expectedLeft[i])
+		diffRight := math.Abs(right[i] - expectedRight[i])
+
+		/*
+		 * Check if the output stage was applied.
+		 */
+		if (diffLeft > TESTING_TOLERANCE) || (diffRight > TESTING_TOLERANCE) {
+			t.Errorf("Sample %d incorrect. Expected (%f, %f), got (%f, %f).", i, expectedLeft[i], expectedRight[i], sample, right[i])
+		}
+
+	}
+
+}
+
+/*
+ * Check that the output may exceed full scale, unless soft clipping is
+ * enabled, which leaves quiet samples unchanged.
+ */
+func TestSoftClip(t *testing.T) {
+
+	/*
+	 * Whether soft clipping is enabled.
+	 */
+	softClip := []int32{
+		0,
+		1,
+	}
+
+	/*
+	 * Process a loud signal with soft clipping disabled and enabled.
+	 */
+	for _, value := range softClip {
+		left, right := testSignal()
+		expectedLeft := make([]float64, TESTING_LENGTH)
+		level := decibelsToFactor(12)
+
+		/*
+		 * Boost the signal well above full scale.
+		 */
+		for i, sample := range left {
+			expectedLeft[i] = level * sample
+		}
+
+		m := Create()
+		m.SetEnabled(true)
+		m.SetValue("level", 12)
+		m.SetValue("soft_clip", value)
+		m.Process(left, right, DEFAULT_SAMPLE_RATE)
+		peak := 0.0
+
+		/*
+		 * Check each sample of the left side.
+		 */
+		for i, sample := range left {
+			expected := expectedLeft[i]
+			peak = math.Max(peak, math.Abs(sample))
+
+			/*
+			 * Samples below the knee must pass unchanged, as must all
+			 * samples when soft clipping is disabled.
+			 */
+			if (value == 0) || (math.Abs(expected) <= SOFT_CLIP_KNEE) {
+				diff := math.Abs(sample - expected)
+
+				/*
+				 * Check if the sample was left unchanged.
+				 */
+				if diff > TESTING_TOLERANCE {
+					t.Errorf("Soft clip %d: Sample %d incorrect. Expected %f, got %f.", value, i, expected, sample)
+				}
+
+			}
+
+		}
+
+		/*
+		 * The peak must only stay below full scale when soft clipping.
+		 */
+		if (value == 0) && (peak <= 1.0) {
+			t.Errorf("Soft clip %d: Peak should exceed full scale, but is %f.", value, peak)
+		} else if (value != 0) && (peak >= 1.0) {
+			t.Errorf("Soft clip %d: Peak should stay below full scale, but is %f.", value, peak)
+		}
+
+	}
+
+}
+
+/*
+ * Check that values out of range and unknown parameters are rejected.
+ */
+func TestSetValue(t *testing.T) {
+	m := Create()
+	err := m.SetValue("width", WIDTH_MAXIMUM+1)
+
+	/*
+	 * Check if the value was rejected.
+	 */
+	if err == nil {
+		t.Errorf("Setting width to %d should fail.", WIDTH_MAXIMUM+1)
+	}
+
+	err = m.SetValue("volume", 0)
+
+	/*
+	 * Check if the parameter was rejected.
+	 */
+	if err == nil {
+		t.Errorf("Setting parameter '%s' should fail.", "volume")
+	}
+
+	err = m.SetValue("side_gain", 6)
+
+	/*
+	 * Check if the value was set.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Setting side gain failed: %s", msg)
+	} else {
+		value, _ := m.Value("side_gain")
+
+		/*
+		 * Check if the value was stored.
+		 */
+		if value != 6 {
+			t.Errorf("Side gain incorrect. Expected %d, got %d.", 6, value)
+		}
+
+		m.Reset()
+		value, _ = m.Value("side_gain")
+
+		/*
+		 * Check if the default value was restored.
+		 */
+		if value != 0 {
+			t.Errorf("Side gain not reset. Expected %d, got %d.", 0, value)
+		}
+
+	}
+
+}
+
+/*
+ * Data structure holding the state required to feed the master section.
+ */
+type masterHarnessStruct struct {
+	master Master
+	left   []float64
+	right  []float64
+}
+
+/*
+ * Feed the buffers to the master section once.
+ */
+func (this *masterHarnessStruct) process() {
+	this.master.Process(this.left, this.right, DEFAULT_SAMPLE_RATE)
+}
+
+/*
+ * Check that processing audio in the master section does not allocate memory.
+ */
+func TestMasterAllocations(t *testing.T) {
+	left, right := testSignal()
+	m := Create()
+	m.SetEnabled(true)
+	m.SetValue("mid_low", 3)
+	m.SetValue("width", 150)
+
+	/*
+	 * State required to feed the master section.
+	 */
+	harness := &masterHarnessStruct{
+		master: m,
+		left:   left,
+		right:  right,
+	}
+
+	harness.process()
+	allocs := testing.AllocsPerRun(100, harness.process)
+
+	/*
+	 * Check if processing allocated memory.
+	 */
+	if allocs != 0 {
+		t.Errorf("Master section processing allocated memory. Expected %d allocations, got %f.", 0, allocs)
+	}
+
+}
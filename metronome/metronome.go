@@ -1,17 +1,34 @@
 package metronome
 
 import (
+	"fmt"
+	"math"
 	"sync"
+	"time"
 )
 
 /*
  * Global constants.
  */
 const (
+	ACCENT_NORMAL            = 'x'
+	ACCENT_SILENT            = '.'
+	ACCENT_SOFT              = 'o'
+	ACCENT_STRONG            = 'X'
 	DEFAULT_BEATS_PER_PERIOD = 4
 	DEFAULT_BPM_SPEED        = 120
 	DEFAULT_SAMPLE_RATE      = 96000
+	DEFAULT_SUBDIVISION      = 1
+	MAX_ACCENTS              = 32
+	MAX_BPM_SPEED            = 360
+	MAX_COUNT_IN             = 16
+	MAX_SUBDIVISION          = 4
+	MIN_BPM_SPEED            = 40
+	MIN_SUBDIVISION          = 1
 	OUTPUT_COUNT             = 1
+	SOFT_LEVEL               = 0.5
+	TAP_MAX_COUNT            = 8
+	TAP_TIMEOUT              = 2 * time.Second
 )
 
 /*
@@ -19,34 +36,67 @@ const (
  */
 type metronomeStruct struct {
 	sampleCounter    uint32
+	subCounter       uint32
 	tickCounter      uint32
+	beatCounter      uint32
+	countInStarted   uint32
 	mutex            sync.RWMutex
+	accents          string
 	beatsPerPeriod   uint32
 	bpmSpeed         uint32
+	countIn          uint32
+	countInRequested uint32
 	coefficientsTick []float64
 	coefficientsTock []float64
 	nameTick         string
 	nameTock         string
+	tickPitch        float64
+	tickDecay        float64
+	tockPitch        float64
+	tockDecay        float64
 	sampleRate       uint32
+	subdivision      uint32
+	taps             []time.Time
 }
 
 /*
  * Interface type representing a metronome.
  */
 type Metronome interface {
+	Accents() string
 	BeatsPerPeriod() uint32
+	CountIn() uint32
 	Process(outputBuffer []float64)
 	SampleRate() uint32
+	SetAccents(pattern string) error
 	SetBeatsPerPeriod(count uint32) error
+	SetCountIn(bars uint32) error
 	SetSampleRate(rate uint32)
 	SetSpeed(speed uint32) error
+	SetSubdivision(clicks uint32) error
 	SetTick(name string, coefficients []float64)
+	SetTickSynthesis(pitch float64, decay float64) error
 	SetTock(name string, coefficients []float64)
+	SetTockSynthesis(pitch float64, decay float64) error
+	Subdivision() uint32
+	Tap(t time.Time) (uint32, bool)
 	Tick() (string, []float64)
+	TickSynthesis() (float64, float64)
 	Tock() (string, []float64)
+	TockSynthesis() (float64, float64)
 	Speed() uint32
 }
 
+/*
+ * Returns the accent pattern of this metronome.
+ */
+func (this *metronomeStruct) Accents() string {
+	this.mutex.RLock()
+	pattern := this.accents
+	this.mutex.RUnlock()
+	return pattern
+}
+
 /*
  * Returns the number of beats per period for this metronome.
  */
@@ -57,6 +107,17 @@ func (this *metronomeStruct) BeatsPerPeriod() uint32 {
 	return bpm
 }
 
+/*
+ * Returns the number of bars the metronome plays before it stops, or zero
+ * if it plays continuously.
+ */
+func (this *metronomeStruct) CountIn() uint32 {
+	this.mutex.RLock()
+	bars := this.countIn
+	this.mutex.RUnlock()
+	return bars
+}
+
 /*
  * Generates the metronome signal and writes it into a buffer.
  */
@@ -66,14 +127,33 @@ func (this *metronomeStruct) Process(outputBuffer []float64) {
 	tockBuf := this.coefficientsTock
 	bpm := this.bpmSpeed
 	beatsPerPeriod := this.beatsPerPeriod
+	accents := this.accents
+	subdivision := this.subdivision
+	countIn := this.countIn
+	countInRequested := this.countInRequested
 	this.mutex.RUnlock()
+
+	/*
+	 * Start over if a new count-in was requested.
+	 */
+	if countInRequested != this.countInStarted {
+		this.sampleCounter = 0
+		this.subCounter = 0
+		this.tickCounter = 0
+		this.beatCounter = 0
+		this.countInStarted = countInRequested
+	}
+
 	sampleCounter := this.sampleCounter
+	subCounter := this.subCounter
 	tickCounter := this.tickCounter
+	beatCounter := this.beatCounter
 	sampleRate := this.sampleRate
 	tickSize := len(tickBuf)
 	tickSize32 := uint32(tickSize)
 	tockSize := len(tockBuf)
 	tockSize32 := uint32(tockSize)
+	numAccents := uint32(len(accents))
 	samplesPerBeat := (60 * sampleRate) / bpm
 
 	/*
@@ -83,6 +163,16 @@ func (this *metronomeStruct) Process(outputBuffer []float64) {
 		beatsPerPeriod = 1
 	}
 
+	/*
+	 * Prevent division by zero.
+	 */
+	if subdivision == 0 {
+		subdivision = 1
+	}
+
+	samplesPerClick := samplesPerBeat / subdivision
+	countInBeats := countIn * beatsPerPeriod
+
 	/*
 	 * Generate the output samples.
 	 */
@@ -90,45 +180,112 @@ func (this *metronomeStruct) Process(outputBuffer []float64) {
 		sample := float64(0.0)
 
 		/*
-		 * Decide whether a tick or a tock should be produced.
+		 * Stay silent once the count-in is over.
 		 */
-		if tickCounter == 0 {
+		if (countIn != 0) && (beatCounter >= countInBeats) {
+			outputBuffer[i] = sample
+		} else {
+			accent := byte(ACCENT_SOFT)
 
 			/*
-			 * Check if buffer is allocated and part of the tick
-			 * must be output.
+			 * Only the first click of each beat is accented.
 			 */
-			if (tickBuf != nil) && (sampleCounter < tickSize32) {
-				sample = tickBuf[sampleCounter]
-			}
+			if subCounter == 0 {
+
+				/*
+				 * Without a pattern, only the first beat of the
+				 * period gets the 'tick' sound.
+				 */
+				if numAccents == 0 {
+
+					/*
+					 * Check if this is the first beat.
+					 */
+					if tickCounter == 0 {
+						accent = ACCENT_STRONG
+					} else {
+						accent = ACCENT_NORMAL
+					}
+
+				} else {
+					idx := tickCounter % numAccents
+					accent = accents[idx]
+				}
 
-		} else {
+			}
 
 			/*
-			 * Check if buffer is allocated and part of the tock
-			 * must be output.
+			 * Decide whether a tick or a tock should be produced.
 			 */
-			if (tockBuf != nil) && (sampleCounter < tockSize32) {
-				sample = tockBuf[sampleCounter]
+			switch accent {
+			case ACCENT_STRONG:
+
+				/*
+				 * Check if buffer is allocated and part of the tick
+				 * must be output.
+				 */
+				if (tickBuf != nil) && (sampleCounter < tickSize32) {
+					sample = tickBuf[sampleCounter]
+				}
+
+			case ACCENT_NORMAL:
+
+				/*
+				 * Check if buffer is allocated and part of the tock
+				 * must be output.
+				 */
+				if (tockBuf != nil) && (sampleCounter < tockSize32) {
+					sample = tockBuf[sampleCounter]
+				}
+
+			case ACCENT_SOFT:
+
+				/*
+				 * Check if buffer is allocated and part of the tock
+				 * must be output.
+				 */
+				if (tockBuf != nil) && (sampleCounter < tockSize32) {
+					sample = SOFT_LEVEL * tockBuf[sampleCounter]
+				}
+
 			}
 
-		}
+			outputBuffer[i] = sample
+			sampleCounter++
 
-		outputBuffer[i] = sample
-		sampleCounter++
+			/*
+			 * Reset sample counter on every click.
+			 */
+			if sampleCounter >= samplesPerClick {
+				sampleCounter = 0
+				subCounter++
+
+				/*
+				 * Advance to the next beat after the last click.
+				 */
+				if subCounter >= subdivision {
+					subCounter = 0
+					tickCounter = (tickCounter + 1) % beatsPerPeriod
+
+					/*
+					 * Only count beats during a count-in.
+					 */
+					if countIn != 0 {
+						beatCounter++
+					}
+
+				}
+
+			}
 
-		/*
-		 * Reset sample counter on every beat.
-		 */
-		if sampleCounter >= samplesPerBeat {
-			sampleCounter = 0
-			tickCounter = (tickCounter + 1) % beatsPerPeriod
 		}
 
 	}
 
 	this.sampleCounter = sampleCounter
+	this.subCounter = subCounter
 	this.tickCounter = tickCounter
+	this.beatCounter = beatCounter
 }
 
 /*
@@ -141,6 +298,46 @@ func (this *metronomeStruct) SampleRate() uint32 {
 	return rate
 }
 
+/*
+ * Sets the accent pattern, one character per beat, which repeats across the
+ * period. 'X' plays the 'tick' sound, 'x' plays the 'tock' sound, 'o' plays
+ * the 'tock' sound at a lower level and '.' leaves the beat silent. An empty
+ * pattern only accents the first beat of each period.
+ */
+func (this *metronomeStruct) SetAccents(pattern string) error {
+	numAccents := len(pattern)
+
+	/*
+	 * Check if the pattern is too long.
+	 */
+	if numAccents > MAX_ACCENTS {
+		return fmt.Errorf("Accent pattern must not be longer than %d beats.", MAX_ACCENTS)
+	} else {
+
+		/*
+		 * Make sure that the pattern only contains known accents.
+		 */
+		for i := 0; i < numAccents; i++ {
+			accent := pattern[i]
+			known := (accent == ACCENT_NORMAL) || (accent == ACCENT_SILENT) || (accent == ACCENT_SOFT) || (accent == ACCENT_STRONG)
+
+			/*
+			 * Check if the accent is known.
+			 */
+			if !known {
+				return fmt.Errorf("Unknown accent '%c' in pattern.", accent)
+			}
+
+		}
+
+		this.mutex.Lock()
+		this.accents = pattern
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
 /*
  * Sets the number of beats per period.
  */
@@ -151,6 +348,28 @@ func (this *metronomeStruct) SetBeatsPerPeriod(count uint32) error {
 	return nil
 }
 
+/*
+ * Sets the number of bars the metronome plays before it stops and starts
+ * counting in from the beginning of a period. Zero makes the metronome play
+ * continuously.
+ */
+func (this *metronomeStruct) SetCountIn(bars uint32) error {
+
+	/*
+	 * Check if the count-in is too long.
+	 */
+	if bars > MAX_COUNT_IN {
+		return fmt.Errorf("Count-in must not be longer than %d bars.", MAX_COUNT_IN)
+	} else {
+		this.mutex.Lock()
+		this.countIn = bars
+		this.countInRequested++
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
 /*
  * Sets the sample rate. Note that the coefficients will also need to be
  * updated on a sample rate change.
@@ -171,6 +390,26 @@ func (this *metronomeStruct) SetSpeed(speed uint32) error {
 	return nil
 }
 
+/*
+ * Sets the number of clicks per beat, e. g. two for eighths, three for
+ * triplets or four for sixteenths.
+ */
+func (this *metronomeStruct) SetSubdivision(clicks uint32) error {
+
+	/*
+	 * Check if the subdivision is supported.
+	 */
+	if (clicks < MIN_SUBDIVISION) || (clicks > MAX_SUBDIVISION) {
+		return fmt.Errorf("Subdivision must be between %d and %d clicks per beat.", MIN_SUBDIVISION, MAX_SUBDIVISION)
+	} else {
+		this.mutex.Lock()
+		this.subdivision = clicks
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
 /*
  * Set the name and the coefficients for the 'tick' signal.
  */
@@ -200,6 +439,29 @@ func (this *metronomeStruct) SetTick(name string, coefficients []float64) {
 	this.mutex.Unlock()
 }
 
+/*
+ * Sets the pitch in Hz and the decay time in milliseconds of the 'tick' sound,
+ * in case it is synthesized.
+ */
+func (this *metronomeStruct) SetTickSynthesis(pitch float64, decay float64) error {
+
+	/*
+	 * Check if the parameters are within limits.
+	 */
+	if !(pitch >= MIN_PITCH) || (pitch > MAX_PITCH) {
+		return fmt.Errorf("Pitch must be within [%.0f, %.0f] Hz.", MIN_PITCH, MAX_PITCH)
+	} else if !(decay >= MIN_DECAY) || (decay > MAX_DECAY) {
+		return fmt.Errorf("Decay must be within [%.0f, %.0f] ms.", MIN_DECAY, MAX_DECAY)
+	} else {
+		this.mutex.Lock()
+		this.tickPitch = pitch
+		this.tickDecay = decay
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
 /*
  * Set the name and the coefficients for the 'tock' signal.
  */
@@ -229,6 +491,117 @@ func (this *metronomeStruct) SetTock(name string, coefficients []float64) {
 	this.mutex.Unlock()
 }
 
+/*
+ * Sets the pitch in Hz and the decay time in milliseconds of the 'tock' sound,
+ * in case it is synthesized.
+ */
+func (this *metronomeStruct) SetTockSynthesis(pitch float64, decay float64) error {
+
+	/*
+	 * Check if the parameters are within limits.
+	 */
+	if !(pitch >= MIN_PITCH) || (pitch > MAX_PITCH) {
+		return fmt.Errorf("Pitch must be within [%.0f, %.0f] Hz.", MIN_PITCH, MAX_PITCH)
+	} else if !(decay >= MIN_DECAY) || (decay > MAX_DECAY) {
+		return fmt.Errorf("Decay must be within [%.0f, %.0f] ms.", MIN_DECAY, MAX_DECAY)
+	} else {
+		this.mutex.Lock()
+		this.tockPitch = pitch
+		this.tockDecay = decay
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Returns the number of clicks per beat.
+ */
+func (this *metronomeStruct) Subdivision() uint32 {
+	this.mutex.RLock()
+	clicks := this.subdivision
+	this.mutex.RUnlock()
+	return clicks
+}
+
+/*
+ * Registers a tap at a certain point in time and sets the speed from the
+ * average interval between the most recent taps.
+ *
+ * A tap which follows the previous one after more than the timeout starts
+ * over. Returns the speed in beats per minute and whether it was updated,
+ * which requires at least two taps.
+ */
+func (this *metronomeStruct) Tap(t time.Time) (uint32, bool) {
+	this.mutex.Lock()
+	taps := this.taps
+	numTaps := len(taps)
+
+	/*
+	 * Start over if the previous tap is too long ago or the clock went
+	 * backwards.
+	 */
+	if numTaps > 0 {
+		lastTap := taps[numTaps-1]
+		elapsed := t.Sub(lastTap)
+
+		/*
+		 * Check if the tap continues the current sequence.
+		 */
+		if (elapsed <= 0) || (elapsed > TAP_TIMEOUT) {
+			taps = taps[0:0]
+		}
+
+	}
+
+	taps = append(taps, t)
+	numTaps = len(taps)
+
+	/*
+	 * Only keep the most recent taps.
+	 */
+	if numTaps > TAP_MAX_COUNT {
+		offset := numTaps - TAP_MAX_COUNT
+		copy(taps, taps[offset:numTaps])
+		taps = taps[0:TAP_MAX_COUNT]
+		numTaps = TAP_MAX_COUNT
+	}
+
+	this.taps = taps
+
+	/*
+	 * A single tap does not tell the speed yet.
+	 */
+	if numTaps < 2 {
+		bpm := this.bpmSpeed
+		this.mutex.Unlock()
+		return bpm, false
+	} else {
+		firstTap := taps[0]
+		lastTap := taps[numTaps-1]
+		duration := lastTap.Sub(firstTap)
+		durationSeconds := duration.Seconds()
+		numIntervals := float64(numTaps - 1)
+		interval := durationSeconds / numIntervals
+		bpmFloat := math.Round(60.0 / interval)
+
+		/*
+		 * Limit the speed to the supported range.
+		 */
+		if bpmFloat < MIN_BPM_SPEED {
+			bpmFloat = MIN_BPM_SPEED
+		} else if bpmFloat > MAX_BPM_SPEED {
+			bpmFloat = MAX_BPM_SPEED
+		}
+
+		bpm := uint32(bpmFloat)
+		this.bpmSpeed = bpm
+		this.mutex.Unlock()
+		return bpm, true
+	}
+
+}
+
 /*
  * Returns the name and the coefficients of the metronome 'tick' sound.
  */
@@ -242,6 +615,18 @@ func (this *metronomeStruct) Tick() (string, []float64) {
 	return this.nameTick, coeffsCopy
 }
 
+/*
+ * Returns the pitch in Hz and the decay time in milliseconds of the metronome
+ * 'tick' sound.
+ */
+func (this *metronomeStruct) TickSynthesis() (float64, float64) {
+	this.mutex.RLock()
+	pitch := this.tickPitch
+	decay := this.tickDecay
+	this.mutex.RUnlock()
+	return pitch, decay
+}
+
 /*
  * Returns the name and the coefficients of the metronome 'tock' sound.
  */
@@ -255,6 +640,18 @@ func (this *metronomeStruct) Tock() (string, []float64) {
 	return this.nameTock, coeffsCopy
 }
 
+/*
+ * Returns the pitch in Hz and the decay time in milliseconds of the metronome
+ * 'tock' sound.
+ */
+func (this *metronomeStruct) TockSynthesis() (float64, float64) {
+	this.mutex.RLock()
+	pitch := this.tockPitch
+	decay := this.tockDecay
+	this.mutex.RUnlock()
+	return pitch, decay
+}
+
 /*
  * Returns the metronome speed in beats per minute.
  */
@@ -274,13 +671,21 @@ func Create() Metronome {
 	 * Create a new metronome struct.
 	 */
 	m := metronomeStruct{
+		accents:          "",
 		beatsPerPeriod:   DEFAULT_BEATS_PER_PERIOD,
 		bpmSpeed:         DEFAULT_BPM_SPEED,
 		coefficientsTick: nil,
 		coefficientsTock: nil,
+		countIn:          0,
 		sampleCounter:    0,
 		sampleRate:       DEFAULT_SAMPLE_RATE,
+		subdivision:      DEFAULT_SUBDIVISION,
+		taps:             nil,
 		tickCounter:      0,
+		tickDecay:        DEFAULT_DECAY,
+		tickPitch:        DEFAULT_TICK_PITCH,
+		tockDecay:        DEFAULT_DECAY,
+		tockPitch:        DEFAULT_TOCK_PITCH,
 	}
 
 	return &m
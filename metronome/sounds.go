@@ -0,0 +1,164 @@
+package metronome
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+ * Constants for the synthesized sounds.
+ */
+const (
+	DEFAULT_DECAY      = 20.0
+	DEFAULT_TICK_PITCH = 1760.0
+	DEFAULT_TOCK_PITCH = 880.0
+	MAX_DECAY          = 500.0
+	MAX_PITCH          = 5000.0
+	MIN_DECAY          = 1.0
+	MIN_PITCH          = 100.0
+	SOUND_AMPLITUDE    = 0.5
+	SOUND_BEEP         = "- BEEP -"
+	SOUND_DECAY_LENGTH = 7.0
+	SOUND_FADE_TIME    = 0.002
+	SOUND_SINE_BURST   = "- SINE BURST -"
+	SOUND_WOODBLOCK    = "- WOODBLOCK -"
+)
+
+var g_woodblockRatios []float64 = []float64{1.0, 1.58, 2.76} // Frequency ratios of the modes of a woodblock.
+
+/*
+ * Checks whether a sound is synthesized by the metronome instead of being
+ * loaded from an impulse response.
+ */
+func IsSynthesized(name string) bool {
+	result := (name == SOUND_BEEP) || (name == SOUND_SINE_BURST) || (name == SOUND_WOODBLOCK)
+	return result
+}
+
+/*
+ * Returns the names of all synthesized sounds.
+ */
+func Sounds() []string {
+	sounds := []string{SOUND_BEEP, SOUND_SINE_BURST, SOUND_WOODBLOCK}
+	return sounds
+}
+
+/*
+ * Synthesizes a sound with a pitch in Hz and a decay time in milliseconds.
+ *
+ * A sine burst decays exponentially with the decay time as its time constant,
+ * a beep holds its level for the decay time and a woodblock decays like a
+ * sine burst, with its higher modes decaying faster.
+ */
+func Synthesize(name string, pitch float64, decay float64, sampleRate uint32) ([]float64, error) {
+
+	/*
+	 * Check if the parameters are within limits.
+	 */
+	if !IsSynthesized(name) {
+		return nil, fmt.Errorf("Sound '%s' is not synthesized.", name)
+	} else if !(pitch >= MIN_PITCH) || (pitch > MAX_PITCH) {
+		return nil, fmt.Errorf("Pitch must be within [%.0f, %.0f] Hz.", MIN_PITCH, MAX_PITCH)
+	} else if !(decay >= MIN_DECAY) || (decay > MAX_DECAY) {
+		return nil, fmt.Errorf("Decay must be within [%.0f, %.0f] ms.", MIN_DECAY, MAX_DECAY)
+	} else if sampleRate == 0 {
+		return nil, fmt.Errorf("%s", "Sample rate must not be zero.")
+	} else {
+		rate := float64(sampleRate)
+		decaySeconds := 0.001 * decay
+		duration := SOUND_DECAY_LENGTH * decaySeconds
+
+		/*
+		 * A beep ends after the decay time.
+		 */
+		if name == SOUND_BEEP {
+			duration = decaySeconds + SOUND_FADE_TIME
+		}
+
+		numSamples := int(math.Ceil(duration * rate))
+		buf := make([]float64, numSamples)
+		omega := 2.0 * math.Pi * pitch
+
+		/*
+		 * Calculate each sample.
+		 */
+		for i := range buf {
+			t := float64(i) / rate
+			sample := float64(0.0)
+
+			/*
+			 * Generate the waveform of the sound.
+			 */
+			switch name {
+			case SOUND_BEEP:
+				envelope := float64(1.0)
+
+				/*
+				 * Fade in and out to avoid clicks.
+				 */
+				if t < SOUND_FADE_TIME {
+					envelope = t / SOUND_FADE_TIME
+				} else if t > decaySeconds {
+					envelope = (duration - t) / SOUND_FADE_TIME
+				}
+
+				sample = envelope * math.Sin(omega*t)
+			case SOUND_SINE_BURST:
+				envelope := math.Exp(-t / decaySeconds)
+				sample = envelope * math.Sin(omega*t)
+			case SOUND_WOODBLOCK:
+				amplitude := float64(1.0)
+				timeConstant := decaySeconds
+
+				/*
+				 * Each mode is weaker and decays faster than the
+				 * previous one.
+				 */
+				for _, ratio := range g_woodblockRatios {
+					envelope := math.Exp(-t / timeConstant)
+					sample += amplitude * envelope * math.Sin(ratio*omega*t)
+					amplitude *= 0.5
+					timeConstant *= 0.5
+				}
+
+			}
+
+			buf[i] = sample
+		}
+
+		peak := float64(0.0)
+
+		/*
+		 * Find the peak amplitude of the sound.
+		 */
+		for _, sample := range buf {
+			magnitude := math.Abs(sample)
+
+			/*
+			 * Check if we found a new peak.
+			 */
+			if magnitude > peak {
+				peak = magnitude
+			}
+
+		}
+
+		/*
+		 * Normalize the sound to leave some headroom.
+		 */
+		if peak > 0.0 {
+			gain := SOUND_AMPLITUDE / peak
+
+			/*
+			 * Scale each sample.
+			 */
+			for i, sample := range buf {
+				buf[i] = gain * sample
+			}
+
+		}
+
+		return buf, nil
+	}
+
+}
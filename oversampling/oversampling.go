@@ -3,7 +3,9 @@ package oversampling
 import (
 	"fmt"
 	"github.com/andrepxx/go-dsp-guitar/filter"
+	"github.com/andrepxx/go-dsp-guitar/firdesign"
 	"github.com/andrepxx/go-dsp-guitar/resample"
+	"math"
 )
 
 /*
@@ -13,6 +15,10 @@ const (
 	ATTENUATION_HALF_DECIBEL     = 0.9440608762859234
 	LOOKAHEAD_SAMPLES_ONE_SIDE   = 4
 	LOOKAHEAD_SAMPLES_BOTH_SIDES = 2 * LOOKAHEAD_SAMPLES_ONE_SIDE
+	DESIGN_SAMPLE_RATE           = 1000
+	EIGHT_TIMES_ATTENUATION      = 120.0
+	EIGHT_TIMES_CUTOFF           = 450.0
+	EIGHT_TIMES_TRANSITION       = 100.1
 )
 
 /*
@@ -27,6 +33,7 @@ const (
 type OversamplerDecimator interface {
 	Oversample(in []float64, out []float64) error
 	Decimate(in []float64, out []float64) error
+	Latency() uint32
 }
 
 /*
@@ -183,10 +190,38 @@ func (this *oversamplerDecimatorStruct) Decimate(in []float64, out []float64) er
 
 }
 
+/*
+ * Returns the latency (in samples at the original sample rate) introduced by
+ * oversampling and subsequently decimating a signal.
+ *
+ * This is the lookahead required for oversampling plus the group delay of
+ * the anti-aliasing filter applied before decimation.
+ */
+func (this *oversamplerDecimatorStruct) Latency() uint32 {
+	factor := this.factor
+
+	/*
+	 * Check if signal is oversampled in time.
+	 */
+	if factor <= 1 {
+		return 0
+	} else {
+		flt := this.antiAliasingFilter
+		delay := flt.Delay()
+		delayFloat := float64(delay)
+		factorFloat := float64(factor)
+		filterLatencyFloat := math.Round(delayFloat / factorFloat)
+		filterLatency := uint32(filterLatencyFloat)
+		latency := LOOKAHEAD_SAMPLES_ONE_SIDE + filterLatency
+		return latency
+	}
+
+}
+
 /*
  * Creates an oversampler / decimator with the requested oversampling factor.
  *
- * The oversampling factor can be either 1, 2 or 4.
+ * The oversampling factor can be either 1, 2, 4 or 8.
  *
  * (An oversampler / decimator with an oversampling factor of one technically
  * just copies buffers when oversampling / decimating though.)
@@ -524,6 +559,48 @@ func CreateOversamplerDecimator(factor uint32) OversamplerDecimator {
 			attenuationFactor:  ATTENUATION_HALF_DECIBEL,
 		}
 
+		return &osd
+	case 8:
+
+		/*
+		 * Anti-aliasing filter for decimation after 8-times
+		 * oversampling.
+		 *
+		 * - Order: 624
+		 * - Passband: 0 to 0.4 * fs
+		 * - Stopband: from 0.5 * fs
+		 * - Attenuation: about 120 dB
+		 *
+		 * Where fs is the sample rate after decimation.
+		 *
+		 * Since the filter is this long, it is designed on creation
+		 * instead of being tabulated. Only the ratio of the
+		 * frequencies to the sample rate matters, so it is designed
+		 * at a nominal sample rate. The transition band is slightly
+		 * wider than a tenth of that rate, so that the order stays at
+		 * 624 and the filter delays the signal by a whole number of
+		 * samples after decimation.
+		 */
+		spec := firdesign.Specification{
+			Type:        firdesign.TYPE_LOWPASS,
+			Cutoff:      EIGHT_TIMES_CUTOFF,
+			Transition:  EIGHT_TIMES_TRANSITION,
+			Attenuation: EIGHT_TIMES_ATTENUATION,
+		}
+
+		coeffs, _ := firdesign.Coefficients(spec, 8*DESIGN_SAMPLE_RATE)
+		flt := filter.FromCoefficients(coeffs, 0, "Anti-aliasing filter for 8-times oversampling")
+
+		/*
+		 * An oversampler / decimator with an oversampling
+		 * factor of 8.
+		 */
+		osd := oversamplerDecimatorStruct{
+			factor:             8,
+			antiAliasingFilter: flt,
+			attenuationFactor:  ATTENUATION_HALF_DECIBEL,
+		}
+
 		return &osd
 	default:
 		return nil
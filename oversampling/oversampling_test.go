@@ -217,3 +217,133 @@ func TestFourTimesOversampling(t *testing.T) {
 	}
 
 }
+
+/*
+ * Perform a unit test for eight-times oversampling.
+ *
+ * A sine wave within the passband must pass (attenuated by half a decibel),
+ * while a sine wave within the stopband must be removed before decimation.
+ */
+func TestEightTimesOversampling(t *testing.T) {
+	n := 1024
+	factor := 8
+	osd := CreateOversamplerDecimator(8)
+	latency32 := osd.Latency()
+	latency := int(latency32)
+	in := make([]float64, n)
+
+	/*
+	 * Generate a sine wave at a tenth of the sample rate.
+	 */
+	for i := range in {
+		iFloat := float64(i)
+		arg := 0.2 * math.Pi * iFloat
+		in[i] = math.Sin(arg)
+	}
+
+	numOversampled := factor * n
+	oversampledBuffer := make([]float64, numOversampled)
+	decimatedBuffer := make([]float64, n)
+	osd.Oversample(in, oversampledBuffer)
+	osd.Decimate(oversampledBuffer, decimatedBuffer)
+
+	/*
+	 * Compare the output, once the filter settled, to the delayed input.
+	 */
+	for i := n / 2; i < n; i++ {
+		expected := ATTENUATION_HALF_DECIBEL * in[i-latency]
+		diff := math.Abs(decimatedBuffer[i] - expected)
+
+		/*
+		 * Check if we found a significant difference.
+		 */
+		if diff > 0.01 {
+			t.Errorf("Passband sample %d is incorrect. Expected %f, got %f.", i, expected, decimatedBuffer[i])
+			break
+		}
+
+	}
+
+	osd = CreateOversamplerDecimator(8)
+
+	/*
+	 * Generate a sine wave at 0.6 times the sample rate after
+	 * decimation, which would alias.
+	 */
+	for i := range oversampledBuffer {
+		iFloat := float64(i)
+		arg := (1.2 * math.Pi * iFloat) / 8.0
+		oversampledBuffer[i] = math.Sin(arg)
+	}
+
+	osd.Decimate(oversampledBuffer, decimatedBuffer)
+
+	/*
+	 * Verify that the output is silent once the filter settled.
+	 */
+	for i := n / 2; i < n; i++ {
+		sample := decimatedBuffer[i]
+
+		/*
+		 * Check if we found a significant sample.
+		 */
+		if math.Abs(sample) > 0.00001 {
+			t.Errorf("Stopband sample %d is incorrect. Expected %f, got %f.", i, 0.0, sample)
+			break
+		}
+
+	}
+
+}
+
+/*
+ * Perform a unit test for the latency reported by an oversampler / decimator.
+ */
+func TestLatency(t *testing.T) {
+	factors := []uint32{1, 2, 4, 8}
+	impulsePosition := 10
+
+	/*
+	 * Test each oversampling factor.
+	 */
+	for _, factor := range factors {
+		osd := CreateOversamplerDecimator(factor)
+		in := make([]float64, 256)
+		in[impulsePosition] = 1.0
+		numOversampled := len(in) * int(factor)
+		oversampledBuffer := make([]float64, numOversampled)
+		decimatedBuffer := make([]float64, 256)
+		osd.Oversample(in, oversampledBuffer)
+		osd.Decimate(oversampledBuffer, decimatedBuffer)
+		peak := 0.0
+		peakPosition := 0
+
+		/*
+		 * Find the position of the impulse in the output.
+		 */
+		for i, sample := range decimatedBuffer {
+
+			/*
+			 * If we found a larger sample, keep its position.
+			 */
+			if sample > peak {
+				peak = sample
+				peakPosition = i
+			}
+
+		}
+
+		delay := peakPosition - impulsePosition
+		delay32 := uint32(delay)
+		latency := osd.Latency()
+
+		/*
+		 * Verify that the latency matches the delay of the impulse.
+		 */
+		if latency != delay32 {
+			t.Errorf("Latency for oversampling by factor %d is incorrect. Expected %d, got %d.", factor, delay32, latency)
+		}
+
+	}
+
+}
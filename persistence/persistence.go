@@ -35,12 +35,20 @@ type NumericParam struct {
 
 /*
  * Data structure representing a signal processing unit.
+ *
+ * Only composite units have a name and hold units, instead of parameters.
  */
 type Unit struct {
 	Type           string
+	Name           string
+	Label          string
+	Note           string
 	Bypass         bool
+	InputTrim      int32
+	OutputLevel    int32
 	DiscreteParams []DiscreteParam
 	NumericParams  []NumericParam
+	Units          []Unit
 }
 
 /*
@@ -50,16 +58,32 @@ type Spatializer struct {
 	Azimuth  float64
 	Distance float64
 	Level    float64
+	Mute     bool
+	Solo     bool
+	Sends    []float64
 }
 
 /*
  * Data structure representing an audio channel.
  */
 type Channel struct {
+	Name        string
+	Color       string
+	Click       float64
+	ChannelTrim int32
+	ReampLevel  int32
 	Units       []Unit
 	Spatializer Spatializer
 }
 
+/*
+ * Data structure representing an aux bus.
+ */
+type Bus struct {
+	Units  []Unit
+	Return float64
+}
+
 /*
  * Data structure representing metronome settings.
  */
@@ -67,16 +91,122 @@ type Metronome struct {
 	Master         bool
 	BeatsPerPeriod uint32
 	Speed          uint32
+	Subdivision    uint32
+	Accents        string
+	CountIn        uint32
 	TickSound      string
+	TickPitch      float64
+	TickDecay      float64
 	TockSound      string
+	TockPitch      float64
+	TockDecay      float64
+}
+
+/*
+ * Data structure representing the settings of the master section.
+ */
+type Master struct {
+	Enabled       bool
+	NumericParams []NumericParam
+}
+
+/*
+ * Data structure representing a connection between two ports.
+ */
+type Connection struct {
+	From string
+	To   string
+}
+
+/*
+ * Data structure representing a point of an automation envelope, which
+ * reaches a value at a time (in milliseconds).
+ */
+type AutomationPoint struct {
+	Time  uint32
+	Value int32
+}
+
+/*
+ * Data structure representing an automation lane, which modulates a numeric
+ * parameter of a unit with an LFO or an envelope.
+ */
+type AutomationLane struct {
+	Chain    int
+	Unit     int
+	Param    string
+	Source   string
+	Waveform string
+	Rate     float64
+	Depth    int32
+	Base     int32
+	Loop     bool
+	Points   []AutomationPoint
 }
 
 /*
  * Data structure representing a configuration file.
+ *
+ * Connections and the speaker layout are only stored in patch files saved by
+ * the user, not in snapshots, scenes or the undo history, since they change
+ * the ports. Patches without them leave the wiring and the layout as they are.
  */
 type Configuration struct {
 	FileFormat      FileFormat
 	FramesPerPeriod uint32
 	Channels        []Channel
+	Buses           []Bus
 	Metronome       Metronome
+	Master          Master
+	Automation      []AutomationLane
+	SpeakerLayout   string
+	Connections     []Connection
+}
+
+/*
+ * Data structure representing a chain preset file, which holds the units of
+ * a single signal chain, so that they can be shared without the rest of the
+ * configuration.
+ */
+type ChainPreset struct {
+	FileFormat FileFormat
+	Name       string
+	Units      []Unit
+}
+
+/*
+ * Data structure representing a scene of a setlist.
+ *
+ * A program number of -1 means the scene is not bound to a MIDI program
+ * change.
+ */
+type Scene struct {
+	Name          string
+	Program       int32
+	Configuration Configuration
+}
+
+/*
+ * Data structure representing a setlist file.
+ */
+type Setlist struct {
+	FileFormat FileFormat
+	Scenes     []Scene
+}
+
+/*
+ * Data structure representing an entry of the unit library, which is a named
+ * sequence of units with their parameters.
+ */
+type LibraryEntry struct {
+	Name  string
+	Units []Unit
+}
+
+/*
+ * Data structure representing a unit library file.
+ */
+type Library struct {
+	FileFormat FileFormat
+	Entries    []LibraryEntry
 }
@@ -0,0 +1,651 @@
+package player
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/resample"
+	"math"
+	"sync"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	DEFAULT_LEVEL       = 1.0
+	DEFAULT_SAMPLE_RATE = 96000
+	OUTPUT_COUNT        = 2
+	TEMPO_FRAME_RATE    = 100
+	TEMPO_MAX           = 180.0
+	TEMPO_MIN           = 60.0
+	TEMPO_PEAK_RATIO    = 0.9
+)
+
+/*
+ * Data structure representing the status of a player.
+ */
+type statusStruct struct {
+	loaded   bool
+	playing  bool
+	length   float64
+	position float64
+	tempo    float64
+}
+
+/*
+ * The status of a player.
+ */
+type Status interface {
+	Length() float64
+	Loaded() bool
+	Playing() bool
+	Position() float64
+	Tempo() float64
+}
+
+/*
+ * Data structure representing a player, which loops a backing track.
+ *
+ * The track is converted to the sample rate it is played at without holding
+ * the mutex. Each time the track or the sample rate changes, the generation
+ * is incremented, so that the result of a conversion which was overtaken by
+ * a later change is discarded. While a new track is converted, the player is
+ * stopped and holds no buffers, so it reports that no track is loaded. While
+ * the track is converted to a new sample rate, the previous buffers keep
+ * playing at the buffer rate they were converted to.
+ */
+type playerStruct struct {
+	mutex       sync.Mutex
+	generation  uint64
+	level       float64
+	playing     bool
+	position    int
+	sampleRate  uint32
+	sourceLeft  []float64
+	sourceRight []float64
+	sourceRate  uint32
+	left        []float64
+	right       []float64
+	bufferRate  uint32
+	tempo       float64
+}
+
+/*
+ * Interface type representing a player, which loops a backing track.
+ */
+type Player interface {
+	Level() float64
+	Load(channels [][]float64, sampleRate uint32) error
+	Process(left []float64, right []float64)
+	Seek(seconds float64) error
+	SetLevel(level float64) error
+	SetSampleRate(rate uint32)
+	Start() error
+	Status() Status
+	Stop()
+}
+
+/*
+ * Returns the length of the track in seconds.
+ */
+func (this *statusStruct) Length() float64 {
+	return this.length
+}
+
+/*
+ * Returns whether a track is loaded.
+ */
+func (this *statusStruct) Loaded() bool {
+	return this.loaded
+}
+
+/*
+ * Returns whether the track is currently playing.
+ */
+func (this *statusStruct) Playing() bool {
+	return this.playing
+}
+
+/*
+ * Returns the playback position within the track in seconds.
+ */
+func (this *statusStruct) Position() float64 {
+	return this.position
+}
+
+/*
+ * Returns the tempo of the track in beats per minute, or zero if it is
+ * unknown.
+ */
+func (this *statusStruct) Tempo() float64 {
+	return this.tempo
+}
+
+/*
+ * Converts a track to a different sample rate.
+ */
+func convertRate(samples []float64, sourceRate uint32, targetRate uint32) []float64 {
+
+	/*
+	 * Only resample if the sample rates differ.
+	 */
+	if sourceRate == targetRate {
+		n := len(samples)
+		result := make([]float64, n)
+		copy(result, samples)
+		return result
+	} else {
+		result := resample.Time(samples, sourceRate, targetRate)
+		return result
+	}
+
+}
+
+/*
+ * Estimates the tempo of a looped track in beats per minute from the
+ * periodicity of its onsets. Returns zero if no tempo could be found.
+ */
+func estimateTempo(left []float64, right []float64, sampleRate uint32) float64 {
+	numSamples := len(left)
+	hop := int(sampleRate / TEMPO_FRAME_RATE)
+
+	/*
+	 * Prevent division by zero.
+	 */
+	if hop < 1 {
+		hop = 1
+	}
+
+	numFrames := numSamples / hop
+
+	/*
+	 * We need at least two frames to detect onsets.
+	 */
+	if numFrames < 2 {
+		return 0.0
+	} else {
+		energy := make([]float64, numFrames)
+
+		/*
+		 * Calculate the energy of each frame.
+		 */
+		for i := range energy {
+			offset := i * hop
+			uBound := offset + hop
+			sum := float64(0.0)
+
+			/*
+			 * Sum the energy of both channels.
+			 */
+			for j := offset; j < uBound; j++ {
+				sampleLeft := left[j]
+				sampleRight := right[j]
+				sum += (sampleLeft * sampleLeft) + (sampleRight * sampleRight)
+			}
+
+			energy[i] = sum
+		}
+
+		onsets := make([]float64, numFrames)
+		mean := float64(0.0)
+
+		/*
+		 * The onset strength is the increase in energy. Since the track
+		 * is looped, the first frame follows the last one.
+		 */
+		for i, current := range energy {
+			previousIdx := (i + numFrames - 1) % numFrames
+			previous := energy[previousIdx]
+			onset := math.Max(current-previous, 0.0)
+			onsets[i] = onset
+			mean += onset
+		}
+
+		numFramesFloat := float64(numFrames)
+		mean /= numFramesFloat
+
+		/*
+		 * Remove the mean from the onset strength.
+		 */
+		for i := range onsets {
+			onsets[i] -= mean
+		}
+
+		sampleRateFloat := float64(sampleRate)
+		hopFloat := float64(hop)
+		frameRate := sampleRateFloat / hopFloat
+		lagMin := int(math.Ceil((60.0 * frameRate) / TEMPO_MAX))
+		lagMax := int(math.Floor((60.0 * frameRate) / TEMPO_MIN))
+		lagLimit := numFrames / 2
+
+		/*
+		 * The track must contain at least two beats.
+		 */
+		if lagMax > lagLimit {
+			lagMax = lagLimit
+		}
+
+		/*
+		 * Check if there is any lag to examine.
+		 */
+		if (lagMin < 1) || (lagMax <= lagMin) {
+			return 0.0
+		} else {
+			numLags := lagMax + 2
+			correlation := make([]float64, numLags)
+
+			/*
+			 * Calculate the circular autocorrelation of the onset
+			 * strength around the lags of interest.
+			 */
+			for lag := lagMin - 1; lag < numLags; lag++ {
+				sum := float64(0.0)
+
+				/*
+				 * Correlate the onsets with their shifted copy.
+				 */
+				for i, onset := range onsets {
+					idx := (i + lag) % numFrames
+					sum += onset * onsets[idx]
+				}
+
+				correlation[lag] = sum
+			}
+
+			maximum := float64(0.0)
+
+			/*
+			 * Find the strongest periodicity.
+			 */
+			for lag := lagMin; lag <= lagMax; lag++ {
+				value := correlation[lag]
+
+				/*
+				 * Check if we found a new maximum.
+				 */
+				if value > maximum {
+					maximum = value
+				}
+
+			}
+
+			/*
+			 * Check if the onsets are periodic at all.
+			 */
+			if maximum <= 0.0 {
+				return 0.0
+			} else {
+				threshold := TEMPO_PEAK_RATIO * maximum
+				bestLag := 0
+
+				/*
+				 * The shortest period, which is almost as strong as
+				 * the strongest one, is the beat. Longer periods are
+				 * multiples of it.
+				 */
+				for lag := lagMin; (lag <= lagMax) && (bestLag == 0); lag++ {
+					value := correlation[lag]
+					previous := correlation[lag-1]
+					next := correlation[lag+1]
+
+					/*
+					 * Check if this is a local maximum above the
+					 * threshold.
+					 */
+					if (value >= threshold) && (value >= previous) && (value >= next) {
+						bestLag = lag
+					}
+
+				}
+
+				/*
+				 * Check if a beat was found.
+				 */
+				if bestLag == 0 {
+					return 0.0
+				} else {
+					previous := correlation[bestLag-1]
+					current := correlation[bestLag]
+					next := correlation[bestLag+1]
+					denominator := previous - (2.0 * current) + next
+					shift := float64(0.0)
+
+					/*
+					 * Interpolate the position of the peak.
+					 */
+					if denominator != 0.0 {
+						shift = 0.5 * (previous - next) / denominator
+					}
+
+					bestLagFloat := float64(bestLag)
+					lag := bestLagFloat + shift
+					tempo := (60.0 * frameRate) / lag
+					numSamplesFloat := float64(numSamples)
+					duration := numSamplesFloat / sampleRateFloat
+					beats := math.Round((duration * tempo) / 60.0)
+
+					/*
+					 * A loop contains a whole number of beats, which
+					 * tells the tempo more precisely.
+					 */
+					if beats > 0.0 {
+						tempo = (60.0 * beats) / duration
+					}
+
+					return tempo
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Returns the level at which the track is played.
+ */
+func (this *playerStruct) Level() float64 {
+	this.mutex.Lock()
+	level := this.level
+	this.mutex.Unlock()
+	return level
+}
+
+/*
+ * Loads a track from the channels of a wave file. A mono track is played on
+ * both sides, while channels beyond the second one are ignored.
+ *
+ * The player stops and the playback position returns to the beginning.
+ */
+func (this *playerStruct) Load(channels [][]float64, sampleRate uint32) error {
+	numChannels := len(channels)
+
+	/*
+	 * Check if the track is valid.
+	 */
+	if numChannels == 0 {
+		return fmt.Errorf("%s", "Track has no channels.")
+	} else if sampleRate == 0 {
+		return fmt.Errorf("%s", "Track has no sample rate.")
+	} else {
+		left := channels[0]
+		right := left
+
+		/*
+		 * Check if the track is stereo.
+		 */
+		if numChannels > 1 {
+			right = channels[1]
+		}
+
+		numSamples := len(left)
+
+		/*
+		 * Check if the track contains any samples.
+		 */
+		if numSamples == 0 {
+			return fmt.Errorf("%s", "Track contains no samples.")
+		} else if len(right) != numSamples {
+			return fmt.Errorf("%s", "Channels of track differ in length.")
+		} else {
+			sourceLeft := make([]float64, numSamples)
+			copy(sourceLeft, left)
+			sourceRight := make([]float64, numSamples)
+			copy(sourceRight, right)
+			tempo := estimateTempo(sourceLeft, sourceRight, sampleRate)
+			this.mutex.Lock()
+			this.generation++
+			generation := this.generation
+			targetRate := this.sampleRate
+			this.sourceLeft = sourceLeft
+			this.sourceRight = sourceRight
+			this.sourceRate = sampleRate
+			this.left = nil
+			this.right = nil
+			this.bufferRate = targetRate
+			this.tempo = tempo
+			this.playing = false
+			this.position = 0
+			this.mutex.Unlock()
+			convertedLeft := convertRate(sourceLeft, sampleRate, targetRate)
+			convertedRight := convertRate(sourceRight, sampleRate, targetRate)
+			this.mutex.Lock()
+
+			/*
+			 * Only use the converted track if neither the track
+			 * nor the sample rate changed in the meantime.
+			 */
+			if generation == this.generation {
+				this.left = convertedLeft
+				this.right = convertedRight
+			}
+
+			this.mutex.Unlock()
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Writes the next part of the track into the buffers, starting over at the
+ * end of the track. Writes silence while the player is stopped.
+ */
+func (this *playerStruct) Process(left []float64, right []float64) {
+	this.mutex.Lock()
+	trackLeft := this.left
+	trackRight := this.right
+	numSamples := len(trackLeft)
+	level := this.level
+	position := this.position
+
+	/*
+	 * Check if the track is playing.
+	 */
+	if !this.playing || (numSamples == 0) {
+
+		/*
+		 * Write silence.
+		 */
+		for i := range left {
+			left[i] = 0.0
+			right[i] = 0.0
+		}
+
+	} else {
+
+		/*
+		 * Write each sample.
+		 */
+		for i := range left {
+
+			/*
+			 * Start over at the end of the track.
+			 */
+			if position >= numSamples {
+				position = 0
+			}
+
+			left[i] = level * trackLeft[position]
+			right[i] = level * trackRight[position]
+			position++
+		}
+
+		this.position = position
+	}
+
+	this.mutex.Unlock()
+}
+
+/*
+ * Moves the playback position to a point within the track, given in seconds.
+ */
+func (this *playerStruct) Seek(seconds float64) error {
+	this.mutex.Lock()
+	numSamples := len(this.left)
+	sampleRate := float64(this.bufferRate)
+	length := float64(numSamples) / sampleRate
+
+	/*
+	 * Check if the position lies within the track.
+	 */
+	if numSamples == 0 {
+		this.mutex.Unlock()
+		return fmt.Errorf("%s", "No track loaded.")
+	} else if !(seconds >= 0.0) || (seconds >= length) {
+		this.mutex.Unlock()
+		return fmt.Errorf("Position must be within [0, %.3f) seconds.", length)
+	} else {
+		positionFloat := math.Floor(seconds * sampleRate)
+		this.position = int(positionFloat)
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Sets the level at which the track is played.
+ */
+func (this *playerStruct) SetLevel(level float64) error {
+
+	/*
+	 * Check if the level is within limits.
+	 */
+	if !(level >= 0.0) || (level > 1.0) {
+		return fmt.Errorf("%s", "Level must be within [0, 1].")
+	} else {
+		this.mutex.Lock()
+		this.level = level
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Sets the sample rate the track is played at and converts the track to it.
+ */
+func (this *playerStruct) SetSampleRate(rate uint32) {
+	this.mutex.Lock()
+
+	/*
+	 * Only convert the track if the sample rate changed.
+	 */
+	if (rate == 0) || (rate == this.sampleRate) {
+		this.mutex.Unlock()
+	} else {
+		this.generation++
+		generation := this.generation
+		this.sampleRate = rate
+		sourceLeft := this.sourceLeft
+		sourceRight := this.sourceRight
+		sourceRate := this.sourceRate
+		this.mutex.Unlock()
+		convertedLeft := []float64(nil)
+		convertedRight := []float64(nil)
+
+		/*
+		 * Check if a track is loaded.
+		 */
+		if sourceLeft != nil {
+			convertedLeft = convertRate(sourceLeft, sourceRate, rate)
+			convertedRight = convertRate(sourceRight, sourceRate, rate)
+		}
+
+		this.mutex.Lock()
+
+		/*
+		 * Only use the converted track if neither the track nor the
+		 * sample rate changed in the meantime.
+		 */
+		if generation == this.generation {
+			rateFloat := float64(rate)
+			bufferRateFloat := float64(this.bufferRate)
+			positionFloat := float64(this.position)
+			positionScaled := math.Floor((positionFloat * rateFloat) / bufferRateFloat)
+			this.position = int(positionScaled)
+			this.left = convertedLeft
+			this.right = convertedRight
+			this.bufferRate = rate
+		}
+
+		this.mutex.Unlock()
+	}
+
+}
+
+/*
+ * Starts playing the track from the current position.
+ */
+func (this *playerStruct) Start() error {
+	this.mutex.Lock()
+
+	/*
+	 * Check if a track is loaded.
+	 */
+	if len(this.left) == 0 {
+		this.mutex.Unlock()
+		return fmt.Errorf("%s", "No track loaded.")
+	} else {
+		this.playing = true
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Returns the current status of the player.
+ */
+func (this *playerStruct) Status() Status {
+	this.mutex.Lock()
+	numSamples := len(this.left)
+	sampleRate := float64(this.bufferRate)
+	numSamplesFloat := float64(numSamples)
+	positionFloat := float64(this.position)
+
+	/*
+	 * Create status.
+	 */
+	status := statusStruct{
+		loaded:   numSamples > 0,
+		playing:  this.playing,
+		length:   numSamplesFloat / sampleRate,
+		position: positionFloat / sampleRate,
+		tempo:    this.tempo,
+	}
+
+	this.mutex.Unlock()
+	return &status
+}
+
+/*
+ * Stops playing the track, keeping the current position.
+ */
+func (this *playerStruct) Stop() {
+	this.mutex.Lock()
+	this.playing = false
+	this.mutex.Unlock()
+}
+
+/*
+ * Creates a new player.
+ */
+func CreatePlayer() Player {
+
+	/*
+	 * Create a new player struct.
+	 */
+	p := playerStruct{
+		level:      DEFAULT_LEVEL,
+		playing:    false,
+		position:   0,
+		sampleRate: DEFAULT_SAMPLE_RATE,
+		bufferRate: DEFAULT_SAMPLE_RATE,
+	}
+
+	return &p
+}
@@ -0,0 +1,264 @@
+package player
+
+import (
+	"math"
+	"testing"
+)
+
+const (
+	TESTING_SAMPLE_RATE     = 48000
+	TESTING_TEMPO_TOLERANCE = 0.01
+	TESTING_TOLERANCE       = 1e-9
+)
+
+/*
+ * Generates a drum loop with a click on every beat for a certain number of
+ * beats.
+ */
+func clickTrack(tempo float64, numBeats int, sampleRate uint32) []float64 {
+	sampleRateFloat := float64(sampleRate)
+	samplesPerBeat := int(math.Round((60.0 * sampleRateFloat) / tempo))
+	numSamples := numBeats * samplesPerBeat
+	samples := make([]float64, numSamples)
+	clickLength := samplesPerBeat / 10
+
+	/*
+	 * Generate a decaying burst at the start of each beat.
+	 */
+	for beat := 0; beat < numBeats; beat++ {
+		offset := beat * samplesPerBeat
+
+		/*
+		 * Generate each sample of the click.
+		 */
+		for i := 0; i < clickLength; i++ {
+			iFloat := float64(i)
+			t := iFloat / sampleRateFloat
+			envelope := math.Exp(-t / 0.005)
+			arg := 2.0 * math.Pi * 1000.0 * t
+			samples[offset+i] = envelope * math.Sin(arg)
+		}
+
+	}
+
+	return samples
+}
+
+/*
+ * Check that the tempo of a drum loop is detected.
+ */
+func TestEstimateTempo(t *testing.T) {
+	tempos := []float64{80.0, 120.0, 150.0}
+
+	/*
+	 * Estimate each tempo.
+	 */
+	for _, expected := range tempos {
+		samples := clickTrack(expected, 16, TESTING_SAMPLE_RATE)
+		tempo := estimateTempo(samples, samples, TESTING_SAMPLE_RATE)
+		diff := math.Abs(tempo - expected)
+
+		/*
+		 * Check if the tempo was detected.
+		 */
+		if diff > TESTING_TEMPO_TOLERANCE {
+			t.Errorf("Tempo of %f BPM detected as %f BPM.", expected, tempo)
+		}
+
+	}
+
+	silence := make([]float64, TESTING_SAMPLE_RATE)
+	tempo := estimateTempo(silence, silence, TESTING_SAMPLE_RATE)
+
+	/*
+	 * Check that silence has no tempo.
+	 */
+	if tempo != 0.0 {
+		t.Errorf("Silence should have no tempo, but got %f BPM.", tempo)
+	}
+
+}
+
+/*
+ * Check that the track loops and is scaled by the level.
+ */
+func TestLoop(t *testing.T) {
+	track := []float64{1.0, 2.0, 3.0, 4.0, 5.0}
+	p := CreatePlayer()
+	p.SetSampleRate(TESTING_SAMPLE_RATE)
+	channels := [][]float64{track}
+	err := p.Load(channels, TESTING_SAMPLE_RATE)
+
+	/*
+	 * Check if the track was loaded.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Fatalf("Loading track failed: %s", msg)
+	}
+
+	left := make([]float64, 12)
+	right := make([]float64, 12)
+	p.Process(left, right)
+
+	/*
+	 * A stopped player must be silent.
+	 */
+	for i, sample := range left {
+
+		/*
+		 * Check if the sample is silent.
+		 */
+		if (sample != 0.0) || (right[i] != 0.0) {
+			t.Errorf("Sample %d should be silent, but is (%f, %f).", i, sample, right[i])
+		}
+
+	}
+
+	p.SetLevel(0.5)
+	p.Start()
+	p.Process(left, right)
+
+	/*
+	 * Compare each sample.
+	 */
+	for i, sample := range left {
+		idx := i % len(track)
+		expected := 0.5 * track[idx]
+		diffLeft := math.Abs(sample - expected)
+		diffRight := math.Abs(right[i] - expected)
+
+		/*
+		 * Check if the track was looped on both sides.
+		 */
+		if (diffLeft > TESTING_TOLERANCE) || (diffRight > TESTING_TOLERANCE) {
+			t.Errorf("Sample %d should be %f, but is (%f, %f).", i, expected, sample, right[i])
+		}
+
+	}
+
+}
+
+/*
+ * Check that seeking moves the playback position.
+ */
+func TestSeek(t *testing.T) {
+	track := make([]float64, TESTING_SAMPLE_RATE)
+
+	/*
+	 * The track is a ramp.
+	 */
+	for i := range track {
+		track[i] = float64(i)
+	}
+
+	p := CreatePlayer()
+	p.SetSampleRate(TESTING_SAMPLE_RATE)
+	err := p.Seek(0.0)
+
+	/*
+	 * Seeking without a track must fail.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Seeking without a track should fail.")
+	}
+
+	channels := [][]float64{track, track}
+	p.Load(channels, TESTING_SAMPLE_RATE)
+	err = p.Seek(1.0)
+
+	/*
+	 * Seeking beyond the end must fail.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Seeking beyond the end of the track should fail.")
+	}
+
+	err = p.Seek(0.5)
+
+	/*
+	 * Check if seeking succeeded.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Seeking failed: %s", msg)
+	}
+
+	p.Start()
+	left := make([]float64, 4)
+	right := make([]float64, 4)
+	p.Process(left, right)
+	expected := float64(TESTING_SAMPLE_RATE / 2)
+
+	/*
+	 * Check if playback started at the new position.
+	 */
+	if left[0] != expected {
+		t.Errorf("Playback should start at sample %f, but starts at %f.", expected, left[0])
+	}
+
+	status := p.Status()
+	position := status.Position()
+	expectedPosition := 0.5 + (4.0 / TESTING_SAMPLE_RATE)
+	diff := math.Abs(position - expectedPosition)
+
+	/*
+	 * Check if the status reports the position.
+	 */
+	if diff > TESTING_TOLERANCE {
+		t.Errorf("Position should be %f, but is %f.", expectedPosition, position)
+	}
+
+}
+
+/*
+ * Check that loading a track while the sample rate changes always leaves the
+ * most recent track, converted to the most recent sample rate.
+ */
+func TestLoadWhileChangingRate(t *testing.T) {
+	rates := []uint32{44100, 48000, 96000}
+	short := clickTrack(120.0, 2, TESTING_SAMPLE_RATE)
+	long := clickTrack(120.0, 4, TESTING_SAMPLE_RATE)
+	channelsShort := [][]float64{short}
+	channelsLong := [][]float64{long}
+
+	/*
+	 * Repeat to give the conversions a chance to overtake each other.
+	 */
+	for i := 0; i < 20; i++ {
+		p := CreatePlayer()
+		p.Load(channelsShort, TESTING_SAMPLE_RATE)
+		rate := rates[i%len(rates)]
+		done := make(chan bool)
+
+		/*
+		 * Change the sample rate while the next track is loaded.
+		 */
+		go func() {
+			p.SetSampleRate(rate)
+			done <- true
+		}()
+
+		p.Load(channelsLong, TESTING_SAMPLE_RATE)
+		<-done
+		status := p.Status()
+		length := status.Length()
+		expected := float64(len(long)) / TESTING_SAMPLE_RATE
+		ps := p.(*playerStruct)
+		ps.mutex.Lock()
+		bufferRate := ps.bufferRate
+		sampleRate := ps.sampleRate
+		ps.mutex.Unlock()
+
+		/*
+		 * The long track must be playing at the new sample rate.
+		 */
+		if bufferRate != rate || sampleRate != rate {
+			t.Errorf("Iteration %d: Track converted to %d Hz at a sample rate of %d Hz. Expected: %d Hz", i, bufferRate, sampleRate, rate)
+		} else if math.Abs(length-expected) > 0.001 {
+			t.Errorf("Iteration %d: Length of track incorrect. Expected: %f Got: %f", i, expected, length)
+		}
+
+	}
+
+}
@@ -0,0 +1,892 @@
+package recorder
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/flac"
+	"github.com/andrepxx/go-dsp-guitar/wave"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	BIT_DEPTH          = 32
+	BLOCK_SIZE_DEFAULT = 1024
+	FILE_EXTENSION     = ".wav"
+	FLAC_BIT_DEPTH     = 24
+	FLAC_EXTENSION     = ".flac"
+	FORMAT_FLAC        = "flac"
+	FORMAT_WAVE        = "wave"
+	QUEUE_LENGTH       = 1024
+	TIMESTAMP_TEMPLATE = "20060102-150405"
+	WRITE_BUFFER_SIZE  = 1 << 20
+)
+
+/*
+ * Data structure describing a track, which records one or more outputs into
+ * a file.
+ */
+type Track struct {
+	Name    string
+	Outputs []int
+}
+
+/*
+ * Data structure representing a file which is written through a buffer.
+ */
+type bufferedFileStruct struct {
+	file   *os.File
+	buffer *bufio.Writer
+}
+
+/*
+ * Data structure representing a file a track is recorded into.
+ */
+type trackFileStruct struct {
+	channels []int
+	file     *bufferedFileStruct
+	writer   wave.Writer
+}
+
+/*
+ * Data structure representing a period of the recorded outputs.
+ */
+type periodStruct struct {
+	channels [][]float64
+	frames   int
+}
+
+/*
+ * Data structure representing a recording in progress.
+ *
+ * The audio thread takes periods from the pool, fills them and queues them for
+ * writing. The writer returns them to the pool once they are on disk, so no
+ * memory is allocated while recording. The counters are accessed atomically
+ * and therefore come first, so that they are aligned on 32-bit platforms.
+ */
+type sessionStruct struct {
+	frames     uint64
+	dropped    uint64
+	outputs    []int
+	pool       chan *periodStruct
+	queue      chan *periodStruct
+	done       chan error
+	sampleRate uint32
+}
+
+/*
+ * Data structure representing the status of a recorder.
+ */
+type statusStruct struct {
+	recording bool
+	files     []string
+	frames    uint64
+	dropped   uint64
+	err       string
+}
+
+/*
+ * The status of a recorder.
+ */
+type Status interface {
+	Dropped() uint64
+	Error() string
+	Files() []string
+	Frames() uint64
+	Recording() bool
+}
+
+/*
+ * Data structure representing a recorder.
+ *
+ * The recording in progress is published atomically for the audio thread,
+ * while everything else is only accessed while holding the mutex.
+ */
+type recorderStruct struct {
+	mutex     sync.RWMutex
+	active    atomic.Value
+	session   *sessionStruct
+	recording bool
+	starting  bool
+	files     []string
+	blockSize uint32
+	format    string
+	err       string
+}
+
+/*
+ * Interface type representing a recorder, which writes signals to disk.
+ */
+type Recorder interface {
+	Process(buffers [][]float64, sampleRate uint32)
+	SetBlockSize(frames uint32)
+	SetFormat(format string) error
+	Start(directory string, tracks []Track, sampleRate uint32) error
+	Status() Status
+	Stop() error
+}
+
+/*
+ * Writes data to the buffer of the file.
+ */
+func (this *bufferedFileStruct) Write(p []byte) (int, error) {
+	return this.buffer.Write(p)
+}
+
+/*
+ * Writes the buffer to the file, then moves the offset for the next write.
+ */
+func (this *bufferedFileStruct) Seek(offset int64, whence int) (int64, error) {
+	err := this.buffer.Flush()
+
+	/*
+	 * Check if buffer was written to the file.
+	 */
+	if err != nil {
+		return 0, err
+	} else {
+		return this.file.Seek(offset, whence)
+	}
+
+}
+
+/*
+ * Writes the buffer to the file and closes it.
+ */
+func (this *bufferedFileStruct) Close() error {
+	errFlush := this.buffer.Flush()
+	errClose := this.file.Close()
+
+	/*
+	 * Report the first error.
+	 */
+	if errFlush != nil {
+		return errFlush
+	} else {
+		return errClose
+	}
+
+}
+
+/*
+ * Returns the number of periods which were dropped because the disk could not
+ * keep up.
+ */
+func (this *statusStruct) Dropped() uint64 {
+	return this.dropped
+}
+
+/*
+ * Returns the error which occured while writing to disk, if any.
+ */
+func (this *statusStruct) Error() string {
+	return this.err
+}
+
+/*
+ * Returns the paths of the files which are (or were last) recorded into.
+ */
+func (this *statusStruct) Files() []string {
+	n := len(this.files)
+	files := make([]string, n)
+	copy(files, this.files)
+	return files
+}
+
+/*
+ * Returns the number of frames written to disk so far.
+ */
+func (this *statusStruct) Frames() uint64 {
+	return this.frames
+}
+
+/*
+ * Returns whether the recorder is currently recording.
+ */
+func (this *statusStruct) Recording() bool {
+	return this.recording
+}
+
+/*
+ * Creates a period holding a buffer of the given size for each recorded
+ * output.
+ */
+func createPeriod(numOutputs int, frames uint32) *periodStruct {
+	channels := make([][]float64, numOutputs)
+
+	/*
+	 * Allocate a buffer for each output.
+	 */
+	for i := range channels {
+		channels[i] = make([]float64, frames)
+	}
+
+	/*
+	 * Create period.
+	 */
+	period := periodStruct{
+		channels: channels,
+	}
+
+	return &period
+}
+
+/*
+ * Checks whether the buffers of a period can hold the given number of frames.
+ */
+func (this *periodStruct) fits(frames int) bool {
+
+	/*
+	 * Check the capacity of each buffer.
+	 */
+	for _, channel := range this.channels {
+
+		/*
+		 * Check if buffer is too small.
+		 */
+		if cap(channel) < frames {
+			return false
+		}
+
+	}
+
+	return true
+}
+
+/*
+ * Returns a period to the pool of a recording, replacing it with a larger one
+ * if the block size grew in the meantime.
+ */
+func (this *recorderStruct) release(session *sessionStruct, period *periodStruct) {
+	blockSize := atomic.LoadUint32(&this.blockSize)
+	frames := int(blockSize)
+
+	/*
+	 * Check if period is too small for the current block size.
+	 */
+	if !period.fits(frames) {
+		numOutputs := len(session.outputs)
+		period = createPeriod(numOutputs, blockSize)
+	}
+
+	/*
+	 * The pool holds every period, so it never overflows.
+	 */
+	select {
+	case session.pool <- period:
+	default:
+	}
+
+}
+
+/*
+ * Writes all queued periods to disk until a nil period is queued, then
+ * finalizes the files.
+ */
+func (this *recorderStruct) write(session *sessionStruct, tracks []trackFileStruct) {
+	err := error(nil)
+	writing := true
+
+	/*
+	 * Write each period.
+	 */
+	for writing {
+		period := <-session.queue
+
+		/*
+		 * A nil period marks the end of the recording.
+		 */
+		if period == nil {
+			writing = false
+		} else {
+
+			/*
+			 * Stop writing after the first error, but keep
+			 * draining the queue.
+			 */
+			if err == nil {
+
+				/*
+				 * Write the outputs of each track.
+				 */
+				for _, track := range tracks {
+					indices := track.channels
+					n := len(indices)
+					channels := make([][]float64, n)
+
+					/*
+					 * Collect the outputs recorded by this track.
+					 */
+					for i, idx := range indices {
+						channels[i] = period.channels[idx]
+					}
+
+					errWrite := track.writer.Write(channels)
+
+					/*
+					 * Keep the first error.
+					 */
+					if errWrite != nil && err == nil {
+						err = errWrite
+					}
+
+				}
+
+				/*
+				 * Report errors while recording.
+				 */
+				if err != nil {
+					msg := err.Error()
+					this.mutex.Lock()
+					this.err = msg
+					this.mutex.Unlock()
+				} else {
+					frames64 := uint64(period.frames)
+					atomic.AddUint64(&session.frames, frames64)
+				}
+
+			}
+
+			this.release(session, period)
+		}
+
+	}
+
+	/*
+	 * Finalize each file.
+	 */
+	for _, track := range tracks {
+		errWriter := track.writer.Close()
+		errClose := track.file.Close()
+
+		/*
+		 * Keep the first error.
+		 */
+		if err == nil {
+
+			/*
+			 * Check which step failed.
+			 */
+			if errWriter != nil {
+				err = errWriter
+			} else if errClose != nil {
+				err = errClose
+			}
+
+		}
+
+	}
+
+	session.done <- err
+}
+
+/*
+ * Returns the recording in progress, or nil if the recorder is not recording.
+ *
+ * This does not lock, so that it can be called from the audio thread.
+ */
+func (this *recorderStruct) activeSession() *sessionStruct {
+	value := this.active.Load()
+	session, _ := value.(*sessionStruct)
+	return session
+}
+
+/*
+ * Copies a period of the outputs and queues it for writing to disk.
+ *
+ * This is called from the audio thread and neither locks nor allocates. If
+ * the disk cannot keep up or the period does not fit into the buffers
+ * allocated for the block size, the period is dropped.
+ */
+func (this *recorderStruct) Process(buffers [][]float64, sampleRate uint32) {
+	session := this.activeSession()
+
+	/*
+	 * Only queue periods while recording at the original sample rate.
+	 */
+	if session != nil && (sampleRate == session.sampleRate) {
+		frames := 0
+
+		/*
+		 * All buffers hold the same number of frames.
+		 */
+		if len(buffers) > 0 {
+			frames = len(buffers[0])
+		}
+
+		/*
+		 * Take a free period from the pool.
+		 */
+		select {
+		case period := <-session.pool:
+
+			/*
+			 * Check if period can hold the buffers.
+			 */
+			if !period.fits(frames) {
+
+				/*
+				 * Return the period. Since we took it out of the
+				 * pool, there is space for it.
+				 */
+				select {
+				case session.pool <- period:
+				default:
+				}
+
+				atomic.AddUint64(&session.dropped, 1)
+			} else {
+				numBuffers := len(buffers)
+
+				/*
+				 * Copy each recorded output.
+				 */
+				for i, output := range session.outputs {
+					channel := period.channels[i][:frames]
+
+					/*
+					 * Outputs which do not exist are silent.
+					 */
+					if output < numBuffers {
+						copy(channel, buffers[output])
+					} else {
+
+						/*
+						 * Clear the buffer.
+						 */
+						for j := range channel {
+							channel[j] = 0.0
+						}
+
+					}
+
+					period.channels[i] = channel
+				}
+
+				period.frames = frames
+				session.queue <- period
+			}
+
+		default:
+			atomic.AddUint64(&session.dropped, 1)
+		}
+
+	}
+
+}
+
+/*
+ * Sets the number of frames processed at once, so that the buffers of a
+ * recording can be allocated ahead of time instead of on the audio thread.
+ *
+ * Periods which are in use are replaced by the writer once they are on disk.
+ */
+func (this *recorderStruct) SetBlockSize(frames uint32) {
+	this.mutex.Lock()
+	atomic.StoreUint32(&this.blockSize, frames)
+	session := this.session
+	recording := this.recording
+	this.mutex.Unlock()
+
+	/*
+	 * Replace the free periods of a recording in progress.
+	 */
+	if recording {
+		numFree := len(session.pool)
+
+		/*
+		 * Take each free period out of the pool and put it back.
+		 */
+		for i := 0; i < numFree; i++ {
+
+			/*
+			 * The audio thread may have taken the period in the
+			 * meantime.
+			 */
+			select {
+			case period := <-session.pool:
+				this.release(session, period)
+			default:
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Sets the format of the files the next recording is written to, either
+ * 32-bit floating-point wave files or 24-bit FLAC files.
+ */
+func (this *recorderStruct) SetFormat(format string) error {
+
+	/*
+	 * Check if format is known.
+	 */
+	if (format != FORMAT_WAVE) && (format != FORMAT_FLAC) {
+		return fmt.Errorf("Unknown recording format: '%s'", format)
+	} else {
+		this.mutex.Lock()
+		this.format = format
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Closes the files of tracks which were already created.
+ */
+func closeTracks(tracks []trackFileStruct) {
+
+	/*
+	 * Close each file.
+	 */
+	for _, track := range tracks {
+		track.file.Close()
+	}
+
+}
+
+/*
+ * Returns the outputs recorded by any of the tracks, each one only once.
+ */
+func recordedOutputs(tracks []Track) []int {
+	outputs := []int{}
+	taken := map[int]bool{}
+
+	/*
+	 * Collect the outputs of each track.
+	 */
+	for _, track := range tracks {
+
+		/*
+		 * Add outputs we did not see before.
+		 */
+		for _, output := range track.Outputs {
+
+			/*
+			 * Check if output is already recorded.
+			 */
+			if !taken[output] {
+				taken[output] = true
+				outputs = append(outputs, output)
+			}
+
+		}
+
+	}
+
+	return outputs
+}
+
+/*
+ * Returns the position of each output of a track among all recorded outputs.
+ */
+func channelIndices(outputs []int, trackOutputs []int) []int {
+	n := len(trackOutputs)
+	indices := make([]int, n)
+
+	/*
+	 * Find each output of the track.
+	 */
+	for i, trackOutput := range trackOutputs {
+
+		/*
+		 * Search the recorded outputs.
+		 */
+		for j, output := range outputs {
+
+			/*
+			 * Check if we found the output.
+			 */
+			if output == trackOutput {
+				indices[i] = j
+			}
+
+		}
+
+	}
+
+	return indices
+}
+
+/*
+ * Creates a file for each track inside a directory.
+ *
+ * The files are named after the time the recording started and the name of
+ * the track.
+ */
+func createTrackFiles(directory string, tracks []Track, outputs []int, sampleRate uint32, format string) ([]trackFileStruct, []string, error) {
+	err := os.MkdirAll(directory, 0755)
+
+	/*
+	 * Check if directory exists.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, nil, fmt.Errorf("Failed to create recording directory: %s", msg)
+	} else {
+		now := time.Now()
+		timestamp := now.Format(TIMESTAMP_TEMPLATE)
+		trackFiles := []trackFileStruct{}
+		paths := []string{}
+		extension := FILE_EXTENSION
+
+		/*
+		 * FLAC files have an extension of their own.
+		 */
+		if format == FORMAT_FLAC {
+			extension = FLAC_EXTENSION
+		}
+
+		/*
+		 * Create a file for each track.
+		 */
+		for _, track := range tracks {
+			name := timestamp + "_" + track.Name + extension
+			path := filepath.Join(directory, name)
+			file, err := os.Create(path)
+
+			/*
+			 * Check if file was created.
+			 */
+			if err != nil {
+				closeTracks(trackFiles)
+				msg := err.Error()
+				return nil, nil, fmt.Errorf("Failed to create file '%s': %s", path, msg)
+			} else {
+				buffer := bufio.NewWriterSize(file, WRITE_BUFFER_SIZE)
+
+				/*
+				 * The file is written through the buffer.
+				 */
+				bufferedFile := &bufferedFileStruct{
+					file:   file,
+					buffer: buffer,
+				}
+
+				trackOutputs := track.Outputs
+				numOutputs := len(trackOutputs)
+				channelCount := uint16(numOutputs)
+				writer := wave.Writer(nil)
+
+				/*
+				 * Create a writer for the format.
+				 */
+				if format == FORMAT_FLAC {
+					writer, err = flac.CreateWriter(bufferedFile, sampleRate, FLAC_BIT_DEPTH, channelCount)
+				} else {
+					writer, err = wave.CreateWriter(bufferedFile, sampleRate, wave.AUDIO_IEEE_FLOAT, BIT_DEPTH, channelCount)
+				}
+
+				/*
+				 * Check if writer was created.
+				 */
+				if err != nil {
+					file.Close()
+					closeTracks(trackFiles)
+					msg := err.Error()
+					return nil, nil, fmt.Errorf("Failed to create writer for file '%s': %s", path, msg)
+				} else {
+
+					/*
+					 * Data structure representing the file of
+					 * this track.
+					 */
+					trackFile := trackFileStruct{
+						channels: channelIndices(outputs, trackOutputs),
+						file:     bufferedFile,
+						writer:   writer,
+					}
+
+					trackFiles = append(trackFiles, trackFile)
+					paths = append(paths, path)
+				}
+
+			}
+
+		}
+
+		return trackFiles, paths, nil
+	}
+
+}
+
+/*
+ * Starts recording a set of tracks into new files inside a directory.
+ *
+ * The files and buffers are created without holding the mutex, so that
+ * neither the audio thread nor status requests wait for the disk.
+ */
+func (this *recorderStruct) Start(directory string, tracks []Track, sampleRate uint32) error {
+	this.mutex.Lock()
+
+	/*
+	 * Check if we are already recording.
+	 */
+	if this.recording || this.starting {
+		this.mutex.Unlock()
+		return fmt.Errorf("%s", "Already recording.")
+	} else if len(tracks) == 0 {
+		this.mutex.Unlock()
+		return fmt.Errorf("%s", "No tracks to record.")
+	} else {
+		this.starting = true
+		blockSize := atomic.LoadUint32(&this.blockSize)
+		format := this.format
+		this.mutex.Unlock()
+
+		/*
+		 * Fall back to the default block size if none was set.
+		 */
+		if blockSize == 0 {
+			blockSize = BLOCK_SIZE_DEFAULT
+		}
+
+		outputs := recordedOutputs(tracks)
+		trackFiles, paths, err := createTrackFiles(directory, tracks, outputs, sampleRate, format)
+
+		/*
+		 * Check if files were created.
+		 */
+		if err != nil {
+			this.mutex.Lock()
+			this.starting = false
+			this.mutex.Unlock()
+			return err
+		} else {
+			numOutputs := len(outputs)
+			pool := make(chan *periodStruct, QUEUE_LENGTH)
+
+			/*
+			 * Allocate the periods of the recording.
+			 */
+			for i := 0; i < QUEUE_LENGTH; i++ {
+				pool <- createPeriod(numOutputs, blockSize)
+			}
+
+			/*
+			 * Data structure representing the recording. The
+			 * queue has space for each period and the nil period
+			 * marking the end of the recording.
+			 */
+			session := &sessionStruct{
+				outputs:    outputs,
+				pool:       pool,
+				queue:      make(chan *periodStruct, QUEUE_LENGTH+1),
+				done:       make(chan error, 1),
+				sampleRate: sampleRate,
+			}
+
+			go this.write(session, trackFiles)
+			this.mutex.Lock()
+			this.starting = false
+			this.recording = true
+			this.session = session
+			this.files = paths
+			this.err = ""
+			this.active.Store(session)
+			this.mutex.Unlock()
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Returns the status of the recorder.
+ */
+func (this *recorderStruct) Status() Status {
+	this.mutex.RLock()
+	session := this.session
+	frames := uint64(0)
+	dropped := uint64(0)
+
+	/*
+	 * Query the counters of the current or last recording.
+	 */
+	if session != nil {
+		frames = atomic.LoadUint64(&session.frames)
+		dropped = atomic.LoadUint64(&session.dropped)
+	}
+
+	/*
+	 * Create status structure.
+	 */
+	status := statusStruct{
+		recording: this.recording,
+		files:     this.files,
+		frames:    frames,
+		dropped:   dropped,
+		err:       this.err,
+	}
+
+	this.mutex.RUnlock()
+	return &status
+}
+
+/*
+ * Stops recording and waits until all queued periods are written to disk.
+ */
+func (this *recorderStruct) Stop() error {
+	this.mutex.Lock()
+
+	/*
+	 * Check if we are recording at all.
+	 */
+	if !this.recording {
+		this.mutex.Unlock()
+		return fmt.Errorf("%s", "Not recording.")
+	} else {
+		session := this.session
+		this.recording = false
+		this.active.Store((*sessionStruct)(nil))
+		this.mutex.Unlock()
+
+		/*
+		 * The audio thread may still queue the period it is working
+		 * on, which is not written, since the writer stops at the nil
+		 * period.
+		 */
+		session.queue <- nil
+		err := <-session.done
+
+		/*
+		 * Check if all files were written.
+		 */
+		if err != nil {
+			msg := err.Error()
+			this.mutex.Lock()
+			this.err = msg
+			this.mutex.Unlock()
+			return fmt.Errorf("Failed to write recording: %s", msg)
+		} else {
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Creates a recorder.
+ */
+func CreateRecorder() Recorder {
+	/*
+	 * Create recorder.
+	 */
+	r := recorderStruct{
+		format: FORMAT_WAVE,
+	}
+
+	return &r
+}
@@ -0,0 +1,471 @@
+package recorder
+
+import (
+	"github.com/andrepxx/go-dsp-guitar/flac"
+	"github.com/andrepxx/go-dsp-guitar/wave"
+	"math"
+	"os"
+	"testing"
+)
+
+/*
+ * Creates a temporary directory for recordings, which is removed after the
+ * test.
+ */
+func createTestDirectory(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "recorder")
+
+	/*
+	 * Check if temporary directory was created.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err.Error())
+	}
+
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	return dir
+}
+
+/*
+ * Verify that a recorder can only be started and stopped once at a time and
+ * reports its state.
+ */
+func TestStartStop(t *testing.T) {
+	dir := createTestDirectory(t)
+	rec := CreateRecorder()
+	err := rec.Stop()
+
+	/*
+	 * The recorder is not recording yet.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Stopping an idle recorder should fail, but it did not.")
+	}
+
+	err = rec.Start(dir, nil, 48000)
+
+	/*
+	 * There is nothing to record.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Starting without tracks should fail, but it did not.")
+	}
+
+	tracks := []Track{
+		Track{
+			Name:    "master",
+			Outputs: []int{0, 1},
+		},
+	}
+
+	err = rec.Start(dir, tracks, 48000)
+
+	/*
+	 * Check if recording was started.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to start recording: %s", err.Error())
+	}
+
+	status := rec.Status()
+
+	/*
+	 * The recorder must report that it is recording into one file.
+	 */
+	if !status.Recording() {
+		t.Errorf("%s", "Recorder should be recording, but it is not.")
+	}
+
+	files := status.Files()
+	numFiles := len(files)
+
+	/*
+	 * One file is recorded per track.
+	 */
+	if numFiles != 1 {
+		t.Errorf("Recorder should record into %d files, but records into %d.", 1, numFiles)
+	}
+
+	err = rec.Start(dir, tracks, 48000)
+
+	/*
+	 * The recorder is already recording.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Starting a second recording should fail, but it did not.")
+	}
+
+	err = rec.Stop()
+
+	/*
+	 * Check if recording was stopped.
+	 */
+	if err != nil {
+		t.Errorf("Failed to stop recording: %s", err.Error())
+	}
+
+	status = rec.Status()
+
+	/*
+	 * The recorder must report that it stopped.
+	 */
+	if status.Recording() {
+		t.Errorf("%s", "Recorder should have stopped, but it is still recording.")
+	}
+
+}
+
+/*
+ * Verify that periods which do not fit the block size are dropped and counted
+ * and that periods at another sample rate are ignored.
+ */
+func TestDropped(t *testing.T) {
+	dir := createTestDirectory(t)
+	rec := CreateRecorder()
+	rec.SetBlockSize(64)
+
+	/*
+	 * Record a single output.
+	 */
+	tracks := []Track{
+		Track{
+			Name:    "output",
+			Outputs: []int{0},
+		},
+	}
+
+	err := rec.Start(dir, tracks, 48000)
+
+	/*
+	 * Check if recording was started.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to start recording: %s", err.Error())
+	}
+
+	fitting := [][]float64{make([]float64, 64)}
+	oversized := [][]float64{make([]float64, 128)}
+	rec.Process(fitting, 48000)
+	rec.Process(oversized, 48000)
+	rec.Process(oversized, 48000)
+	rec.Process(fitting, 44100)
+	err = rec.Stop()
+
+	/*
+	 * Check if recording was stopped.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to stop recording: %s", err.Error())
+	}
+
+	status := rec.Status()
+	frames := status.Frames()
+	dropped := status.Dropped()
+
+	/*
+	 * Only the fitting period at the original sample rate is recorded.
+	 */
+	if frames != 64 {
+		t.Errorf("Recorder should have written %d frames, but wrote %d.", 64, frames)
+	}
+
+	/*
+	 * Both oversized periods are dropped.
+	 */
+	if dropped != 2 {
+		t.Errorf("Recorder should have dropped %d periods, but dropped %d.", 2, dropped)
+	}
+
+}
+
+/*
+ * Verify that recorded outputs are written to wave files and read back
+ * unchanged.
+ */
+func TestRoundTrip(t *testing.T) {
+	dir := createTestDirectory(t)
+	rec := CreateRecorder()
+	blockSize := 64
+	numPeriods := 10
+	rec.SetBlockSize(uint32(blockSize))
+
+	/*
+	 * Record both outputs into one track and the second output into
+	 * another one.
+	 */
+	tracks := []Track{
+		Track{
+			Name:    "stereo",
+			Outputs: []int{0, 1},
+		},
+		Track{
+			Name:    "right",
+			Outputs: []int{1},
+		},
+	}
+
+	err := rec.Start(dir, tracks, 48000)
+
+	/*
+	 * Check if recording was started.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to start recording: %s", err.Error())
+	}
+
+	n := blockSize * numPeriods
+	left := make([]float64, n)
+	right := make([]float64, n)
+
+	/*
+	 * Generate a sine wave on the left and a ramp on the right output.
+	 */
+	for i := range left {
+		iFloat := float64(i)
+		nFloat := float64(n)
+		arg := 2.0 * math.Pi * iFloat / 32.0
+		left[i] = 0.5 * math.Sin(arg)
+		right[i] = (iFloat / nFloat) - 0.5
+	}
+
+	/*
+	 * Process the signal period by period.
+	 */
+	for lBound := 0; lBound < n; lBound += blockSize {
+		uBound := lBound + blockSize
+		buffers := [][]float64{left[lBound:uBound], right[lBound:uBound]}
+		rec.Process(buffers, 48000)
+	}
+
+	files := rec.Status().Files()
+	err = rec.Stop()
+
+	/*
+	 * Check if recording was stopped.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to stop recording: %s", err.Error())
+	}
+
+	/*
+	 * The signals expected in each file.
+	 */
+	expected := [][][]float64{
+		[][]float64{left, right},
+		[][]float64{right},
+	}
+
+	/*
+	 * Read back each file.
+	 */
+	for i, path := range files {
+		buffer, err := os.ReadFile(path)
+
+		/*
+		 * Check if file was read.
+		 */
+		if err != nil {
+			t.Fatalf("Failed to read file '%s': %s", path, err.Error())
+		}
+
+		file, err := wave.FromBuffer(buffer)
+
+		/*
+		 * Check if file was decoded.
+		 */
+		if err != nil {
+			t.Fatalf("Failed to decode file '%s': %s", path, err.Error())
+		}
+
+		signals := expected[i]
+		numSignals := len(signals)
+		channelCount := int(file.ChannelCount())
+
+		/*
+		 * Each recorded output becomes a channel.
+		 */
+		if channelCount != numSignals {
+			t.Fatalf("File '%s' should have %d channels, but has %d.", path, numSignals, channelCount)
+		}
+
+		/*
+		 * Compare each channel with the recorded output.
+		 */
+		for j, signal := range signals {
+			channel, _ := file.Channel(uint16(j))
+			samples := channel.Floats()
+			numSamples := len(samples)
+
+			/*
+			 * Check if all frames were written.
+			 */
+			if numSamples != n {
+				t.Fatalf("Channel %d of file '%s' should have %d samples, but has %d.", j, path, n, numSamples)
+			}
+
+			/*
+			 * Compare each sample, which is stored with single
+			 * precision.
+			 */
+			for k, sample := range samples {
+				diff := math.Abs(sample - signal[k])
+
+				/*
+				 * Check if we found a significant difference.
+				 */
+				if diff > 1e-6 {
+					t.Errorf("Channel %d of file '%s' differs at sample %d. Expected: %f Got: %f", j, path, k, signal[k], sample)
+					break
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Verify that recordings can be written to FLAC files, which are read back
+ * with the precision of 24-bit samples, and that unknown formats are
+ * rejected.
+ */
+func TestFlac(t *testing.T) {
+	dir := createTestDirectory(t)
+	rec := CreateRecorder()
+	err := rec.SetFormat("mp3")
+
+	/*
+	 * Only wave and FLAC files are supported.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Setting an unknown format should fail, but it did not.")
+	}
+
+	err = rec.SetFormat(FORMAT_FLAC)
+
+	/*
+	 * Check if format was set.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to set format: %s", err.Error())
+	}
+
+	blockSize := 64
+	numPeriods := 100
+	rec.SetBlockSize(uint32(blockSize))
+
+	/*
+	 * Record both outputs into one track.
+	 */
+	tracks := []Track{
+		Track{
+			Name:    "stereo",
+			Outputs: []int{0, 1},
+		},
+	}
+
+	err = rec.Start(dir, tracks, 48000)
+
+	/*
+	 * Check if recording was started.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to start recording: %s", err.Error())
+	}
+
+	n := blockSize * numPeriods
+	left := make([]float64, n)
+	right := make([]float64, n)
+
+	/*
+	 * Generate a sine wave on the left and a ramp on the right output.
+	 */
+	for i := range left {
+		iFloat := float64(i)
+		nFloat := float64(n)
+		arg := 2.0 * math.Pi * iFloat / 32.0
+		left[i] = 0.5 * math.Sin(arg)
+		right[i] = (iFloat / nFloat) - 0.5
+	}
+
+	/*
+	 * Process the signal period by period.
+	 */
+	for lBound := 0; lBound < n; lBound += blockSize {
+		uBound := lBound + blockSize
+		buffers := [][]float64{left[lBound:uBound], right[lBound:uBound]}
+		rec.Process(buffers, 48000)
+	}
+
+	files := rec.Status().Files()
+	err = rec.Stop()
+
+	/*
+	 * Check if recording was stopped.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to stop recording: %s", err.Error())
+	}
+
+	path := files[0]
+	fd, err := os.Open(path)
+
+	/*
+	 * Check if file was opened.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to open file '%s': %s", path, err.Error())
+	}
+
+	defer fd.Close()
+	reader, err := flac.CreateReader(fd)
+
+	/*
+	 * Check if file was decoded.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to decode file '%s': %s", path, err.Error())
+	}
+
+	length := reader.Length()
+
+	/*
+	 * Check if all frames were written.
+	 */
+	if length != uint64(n) {
+		t.Fatalf("File '%s' should hold %d frames, but holds %d.", path, n, length)
+	}
+
+	decoded := [][]float64{make([]float64, n), make([]float64, n)}
+	reader.Read(decoded)
+	signals := [][]float64{left, right}
+
+	/*
+	 * Compare each channel with the recorded output.
+	 */
+	for i, signal := range signals {
+
+		/*
+		 * Compare each sample.
+		 */
+		for j, sample := range decoded[i] {
+			diff := math.Abs(sample - signal[j])
+
+			/*
+			 * Check if we found a significant difference.
+			 */
+			if diff > 1e-6 {
+				t.Errorf("Channel %d of file '%s' differs at sample %d. Expected: %f Got: %f", i, path, j, signal[j], sample)
+				break
+			}
+
+		}
+
+	}
+
+}
@@ -0,0 +1,420 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	API_PREFIX      = "/api/v2/"
+	DEFAULT_SERVER  = "https://localhost:8443"
+	REQUEST_TIMEOUT = 10 * time.Second
+)
+
+/*
+ * Data structure representing a response of the v2 API.
+ */
+type apiResponseStruct struct {
+	Success bool
+	Reason  string
+	Result  json.RawMessage
+}
+
+/*
+ * Data structure representing the response to a remote call.
+ */
+type responseStruct struct {
+	success bool
+	reason  string
+	result  []byte
+}
+
+/*
+ * The response to a remote call.
+ */
+type Response interface {
+	Reason() string
+	Result() []byte
+	Success() bool
+}
+
+/*
+ * Data structure representing a client of the v2 API.
+ */
+type clientStruct struct {
+	server string
+	client *http.Client
+}
+
+/*
+ * A client talking to a running instance through its v2 API.
+ */
+type Client interface {
+	Call(endpoint string, params map[string]string) (Response, error)
+}
+
+/*
+ * The reason the server gave for the result of the call.
+ */
+func (this *responseStruct) Reason() string {
+	return this.reason
+}
+
+/*
+ * The result returned by the endpoint, encoded as JSON, or nil if the endpoint
+ * only reports success or failure.
+ */
+func (this *responseStruct) Result() []byte {
+	return this.result
+}
+
+/*
+ * Whether the call was successful.
+ */
+func (this *responseStruct) Success() bool {
+	return this.success
+}
+
+/*
+ * Calls an endpoint of the v2 API, passing the parameters in the request body.
+ */
+func (this *clientStruct) Call(endpoint string, params map[string]string) (Response, error) {
+	body, err := json.Marshal(params)
+
+	/*
+	 * Check if the parameters could be encoded.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, fmt.Errorf("Failed to encode parameters: %s", msg)
+	} else {
+		server := strings.TrimSuffix(this.server, "/")
+		url := server + API_PREFIX + endpoint
+		reader := bytes.NewReader(body)
+		resp, err := this.client.Post(url, "application/json", reader)
+
+		/*
+		 * Check if the request could be sent.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to contact server: %s", msg)
+		} else {
+			content, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			/*
+			 * Check if the response could be read.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to read response: %s", msg)
+			} else {
+				apiResponse := apiResponseStruct{}
+				err = json.Unmarshal(content, &apiResponse)
+
+				/*
+				 * Check if the response could be decoded.
+				 */
+				if err != nil {
+					status := resp.Status
+					return nil, fmt.Errorf("Server responded with '%s' and a body which is not a valid API response.", status)
+				} else {
+					result := []byte(apiResponse.Result)
+					resultString := string(result)
+
+					/*
+					 * Endpoints which only report success or failure
+					 * return a null result.
+					 */
+					if resultString == "null" {
+						result = nil
+					}
+
+					/*
+					 * Create the response to the call.
+					 */
+					response := responseStruct{
+						success: apiResponse.Success,
+						reason:  apiResponse.Reason,
+						result:  result,
+					}
+
+					return &response, nil
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Decodes the parameters of a call, given as pairs of '--name value' or as
+ * '--name=value'.
+ */
+func parseParams(args []string) (map[string]string, error) {
+	params := make(map[string]string)
+	numArgs := len(args)
+
+	/*
+	 * Iterate over the arguments.
+	 */
+	for i := 0; i < numArgs; i++ {
+		arg := args[i]
+
+		/*
+		 * Each parameter has to start with one or two dashes.
+		 */
+		if !strings.HasPrefix(arg, "-") {
+			return nil, fmt.Errorf("Expected parameter name, got '%s'.", arg)
+		} else {
+			name := strings.TrimLeft(arg, "-")
+			value := ""
+			hasValue := false
+			idx := strings.Index(name, "=")
+
+			/*
+			 * Check if the value is part of the same argument.
+			 */
+			if idx >= 0 {
+				value = name[idx+1:]
+				name = name[0:idx]
+				hasValue = true
+			} else if i+1 < numArgs {
+				i++
+				value = args[i]
+				hasValue = true
+			}
+
+			/*
+			 * Make sure that the parameter has a name and a value.
+			 */
+			if name == "" {
+				return nil, fmt.Errorf("%s", "Empty parameter name.")
+			} else if !hasValue {
+				return nil, fmt.Errorf("No value given for parameter '%s'.", name)
+			} else {
+				params[name] = value
+			}
+
+		}
+
+	}
+
+	return params, nil
+}
+
+/*
+ * Prints usage information for the remote control mode.
+ */
+func printUsage(flags *flag.FlagSet) {
+	output := os.Stderr
+	flags.SetOutput(output)
+	fmt.Fprintf(output, "%s\n", "Usage: dsp ctl [options] ENDPOINT [--name value ...]")
+	fmt.Fprintf(output, "%s\n", "")
+	fmt.Fprintf(output, "%s\n", "Calls an endpoint of the v2 API of a running instance, for example:")
+	fmt.Fprintf(output, "%s\n", "")
+	fmt.Fprintf(output, "%s\n", "  dsp ctl -cert keys/public.pem set-numeric-value --chain 0 --unit 2 --param gain --value 30")
+	fmt.Fprintf(output, "%s\n", "")
+	fmt.Fprintf(output, "%s\n", "Options:")
+	flags.PrintDefaults()
+}
+
+/*
+ * Runs the remote control mode with the command line arguments following
+ * 'ctl' and returns the exit code of the program.
+ */
+func Run(args []string) int {
+	flags := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	server := flags.String("server", DEFAULT_SERVER, "Base URL of the running instance")
+	certFile := flags.String("cert", "", "File holding a PEM-encoded certificate to trust, e. g. keys/public.pem")
+	insecure := flags.Bool("insecure", false, "Do not verify the TLS certificate of the server")
+	flags.SetOutput(io.Discard)
+	err := flags.Parse(args)
+
+	/*
+	 * Check if the options could be parsed and an endpoint was given.
+	 */
+	if err == flag.ErrHelp {
+		printUsage(flags)
+		return 0
+	} else if err != nil {
+		msg := err.Error()
+		fmt.Fprintf(os.Stderr, "%s\n\n", msg)
+		printUsage(flags)
+		return 2
+	} else if flags.NArg() < 1 {
+		printUsage(flags)
+		return 2
+	} else {
+		remaining := flags.Args()
+		endpoint := remaining[0]
+		params, err := parseParams(remaining[1:])
+
+		/*
+		 * Check if the parameters could be parsed.
+		 */
+		if err != nil {
+			msg := err.Error()
+			fmt.Fprintf(os.Stderr, "%s\n", msg)
+			return 2
+		} else {
+			c, err := CreateClient(*server, *certFile, *insecure)
+			response := Response(nil)
+
+			/*
+			 * Only call the endpoint if the client was created.
+			 */
+			if err == nil {
+				response, err = c.Call(endpoint, params)
+			}
+
+			/*
+			 * Check if the call succeeded.
+			 */
+			if err != nil {
+				msg := err.Error()
+				fmt.Fprintf(os.Stderr, "%s\n", msg)
+				return 1
+			} else if !response.Success() {
+				reason := response.Reason()
+				fmt.Fprintf(os.Stderr, "Call to '%s' failed: %s\n", endpoint, reason)
+				return 1
+			} else {
+				result := response.Result()
+
+				/*
+				 * Print the result of the call, if there is one.
+				 */
+				if len(result) > 0 {
+					buf := bytes.Buffer{}
+					err = json.Indent(&buf, result, "", "\t")
+
+					/*
+					 * Print the result as is if it cannot be indented.
+					 */
+					if err != nil {
+						fmt.Printf("%s\n", result)
+					} else {
+						fmt.Printf("%s\n", buf.String())
+					}
+
+				}
+
+				return 0
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Refuses to follow redirects.
+ *
+ * Following a redirect would turn the POST request into a GET request without
+ * a body, so the server would see a different call than the one intended.
+ */
+func refuseRedirect(request *http.Request, via []*http.Request) error {
+	url := request.URL.String()
+	return fmt.Errorf("Server redirected the call to '%s'. Pass this base URL using -server instead.", url)
+}
+
+/*
+ * Creates a client talking to the instance at the base URL given.
+ *
+ * If a certificate file is given, the certificates in it are trusted in
+ * addition to those of the system, so that the self-signed certificate of
+ * the instance can be verified. Optionally, certificates are not verified at
+ * all.
+ */
+func CreateClient(server string, certFile string, insecure bool) (Client, error) {
+
+	/*
+	 * Configure TLS certificate verification.
+	 */
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecure,
+	}
+
+	/*
+	 * Trust the certificates from the file given.
+	 */
+	if certFile != "" {
+		content, err := os.ReadFile(certFile)
+
+		/*
+		 * Check if certificate file could be read.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to read certificate file '%s': %s", certFile, msg)
+		} else {
+			pool, err := x509.SystemCertPool()
+
+			/*
+			 * Start with an empty pool if the system pool is not
+			 * available.
+			 */
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+
+			ok := pool.AppendCertsFromPEM(content)
+
+			/*
+			 * Check if the file contained certificates.
+			 */
+			if !ok {
+				return nil, fmt.Errorf("Certificate file '%s' does not contain any PEM-encoded certificates.", certFile)
+			} else {
+				tlsConfig.RootCAs = pool
+			}
+
+		}
+
+	}
+
+	/*
+	 * Create a transport using the TLS configuration.
+	 */
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}
+
+	/*
+	 * Create an HTTP client with a timeout.
+	 */
+	client := &http.Client{
+		CheckRedirect: refuseRedirect,
+		Timeout:       REQUEST_TIMEOUT,
+		Transport:     transport,
+	}
+
+	/*
+	 * Create the API client.
+	 */
+	c := clientStruct{
+		server: server,
+		client: client,
+	}
+
+	return &c, nil
+}
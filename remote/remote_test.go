@@ -0,0 +1,83 @@
+package remote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+/*
+ * Verify that a call which the server redirects fails instead of silently
+ * turning into a request without parameters.
+ */
+func TestCallRedirect(t *testing.T) {
+
+	/*
+	 * This is called when the server receives a request.
+	 */
+	handler := func(writer http.ResponseWriter, request *http.Request) {
+
+		/*
+		 * Redirect calls, but answer anything else successfully.
+		 */
+		if request.Method == http.MethodPost {
+			http.Redirect(writer, request, "/elsewhere", http.StatusFound)
+		} else {
+			writer.Write([]byte(`{"Success": true, "Reason": "", "Result": null}`))
+		}
+
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+	c, err := CreateClient(server.URL, "", false)
+
+	/*
+	 * Check if client was created.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Fatalf("Creating client failed: %s", msg)
+	}
+
+	params := map[string]string{
+		"chain": "0",
+	}
+
+	response, err := c.Call("get-configuration", params)
+
+	/*
+	 * The call must fail and name the redirect.
+	 */
+	if err == nil {
+		success := response.Success()
+		t.Errorf("Redirected call should fail, but returned success = %t.", success)
+	} else {
+		msg := err.Error()
+
+		/*
+		 * Verify that the error mentions the target of the redirect.
+		 */
+		if !strings.Contains(msg, "/elsewhere") {
+			t.Errorf("Error should name the target of the redirect, but was: %s", msg)
+		}
+
+	}
+
+}
+
+/*
+ * Verify that a certificate file without certificates is rejected.
+ */
+func TestCreateClientCertificate(t *testing.T) {
+	_, err := CreateClient(DEFAULT_SERVER, "remote_test.go", false)
+
+	/*
+	 * Creating the client must fail.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Creating a client with an invalid certificate file should fail, but it did not.")
+	}
+
+}
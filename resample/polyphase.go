@@ -0,0 +1,473 @@
+package resample
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+ * Quality levels of the polyphase resampler.
+ */
+const (
+	QUALITY_FAST = iota
+	QUALITY_MEDIUM
+	QUALITY_BEST
+)
+
+/*
+ * Global constants.
+ */
+const (
+	POLYPHASE_MAX_PHASES = 4096
+)
+
+/*
+ * The quality of a polyphase resampler, trading computation time for a
+ * steeper anti-aliasing filter.
+ */
+type Quality int
+
+/*
+ * Data structure describing the filter used at a certain quality level.
+ *
+ * The number of zero crossings on each side of the sinc function determines
+ * the length of the filter, while the bandwidth moves the cutoff below the
+ * Nyquist frequency, so that the transition band does not alias.
+ */
+type qualityStruct struct {
+	name          string
+	zeroCrossings int
+	beta          float64
+	bandwidth     float64
+}
+
+/*
+ * Data structure implementing a streaming polyphase resampler.
+ *
+ * The ratio between the sample rates is reduced to a fraction, so that the
+ * position of the next output sample is an integer multiple of one phase,
+ * measured from the start of the buffer.
+ */
+type polyphaseResamplerStruct struct {
+	interpolation uint64
+	decimation    uint64
+	halfLength    int
+	numPhases     uint64
+	coefficients  [][]float64
+	position      uint64
+	buffer        []float64
+}
+
+/*
+ * The filters used at each quality level.
+ */
+var g_qualities = []qualityStruct{
+	qualityStruct{
+		name:          "fast",
+		zeroCrossings: 8,
+		beta:          6.0,
+		bandwidth:     0.9,
+	},
+	qualityStruct{
+		name:          "medium",
+		zeroCrossings: 16,
+		beta:          8.0,
+		bandwidth:     0.94,
+	},
+	qualityStruct{
+		name:          "best",
+		zeroCrossings: 32,
+		beta:          10.0,
+		bandwidth:     0.97,
+	},
+}
+
+/*
+ * Parses the name of a quality level.
+ */
+func ParseQuality(name string) (Quality, error) {
+
+	/*
+	 * Compare the name of each quality level.
+	 */
+	for i, quality := range g_qualities {
+
+		/*
+		 * Check if we found the quality level.
+		 */
+		if quality.name == name {
+			return Quality(i), nil
+		}
+
+	}
+
+	return QUALITY_MEDIUM, fmt.Errorf("Unknown resampling quality: '%s'", name)
+}
+
+/*
+ * Returns the description of the filter used at a quality level, falling
+ * back to medium quality for unknown levels.
+ */
+func qualityFilter(quality Quality) qualityStruct {
+	idx := int(quality)
+
+	/*
+	 * Check if quality level is known.
+	 */
+	if (idx < 0) || (idx >= len(g_qualities)) {
+		idx = QUALITY_MEDIUM
+	}
+
+	return g_qualities[idx]
+}
+
+/*
+ * Calculates the greatest common divisor of two numbers.
+ */
+func gcd(a uint64, b uint64) uint64 {
+
+	/*
+	 * Apply the Euclidean algorithm.
+	 */
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+/*
+ * The zeroth-order modified Bessel function of the first kind, I0(x).
+ */
+func besselI0(x float64) float64 {
+	halfX := 0.5 * x
+	term := 1.0
+	sum := 1.0
+
+	/*
+	 * Sum up the power series until the terms become negligible.
+	 */
+	for k := 1; term > (1e-12 * sum); k++ {
+		kFloat := float64(k)
+		fac := halfX / kFloat
+		term *= fac * fac
+		sum += term
+	}
+
+	return sum
+}
+
+/*
+ * The Kaiser window w(x, beta), for x from -1 to 1.
+ */
+func kaiserWindow(x float64, beta float64) float64 {
+
+	/*
+	 * The window vanishes outside its support.
+	 */
+	if (x <= -1.0) || (x >= 1.0) {
+		return 0.0
+	} else {
+		arg := beta * math.Sqrt(1.0-(x*x))
+		result := besselI0(arg) / besselI0(beta)
+		return result
+	}
+
+}
+
+/*
+ * The normalized sinc function sin(pi x) / (pi x).
+ */
+func sinc(x float64) float64 {
+
+	/*
+	 * Avoid division by zero.
+	 */
+	if x == 0.0 {
+		return 1.0
+	} else {
+		piX := math.Pi * x
+		result := math.Sin(piX) / piX
+		return result
+	}
+
+}
+
+/*
+ * Designs a linear-phase lowpass filter with an odd number of coefficients,
+ * using the windowed sinc method with a Kaiser window.
+ *
+ * The cutoff frequency is given as a fraction of the sample rate. The
+ * coefficients are normalized to unity gain at DC.
+ */
+func Lowpass(numCoeffs int, cutoff float64, beta float64) []float64 {
+	coeffs := make([]float64, numCoeffs)
+	center := (numCoeffs - 1) / 2
+	centerFloat := float64(center)
+	sum := 0.0
+
+	/*
+	 * Calculate each coefficient.
+	 */
+	for i := range coeffs {
+		iFloat := float64(i)
+		offset := iFloat - centerFloat
+		x := 0.0
+
+		/*
+		 * Avoid division by zero for a single coefficient.
+		 */
+		if center > 0 {
+			x = offset / (centerFloat + 1.0)
+		}
+
+		coeff := 2.0 * cutoff * sinc(2.0*cutoff*offset) * kaiserWindow(x, beta)
+		coeffs[i] = coeff
+		sum += coeff
+	}
+
+	/*
+	 * Normalize the coefficients to unity gain at DC.
+	 */
+	if sum != 0.0 {
+
+		/*
+		 * Scale each coefficient.
+		 */
+		for i := range coeffs {
+			coeffs[i] /= sum
+		}
+
+	}
+
+	return coeffs
+}
+
+/*
+ * Returns the reduced interpolation and decimation factors as well as the
+ * number of source samples on each side of an output sample the filter
+ * takes into account.
+ */
+func polyphaseParameters(sourceRate uint32, targetRate uint32, quality Quality) (uint64, uint64, int, float64) {
+	sourceRate64 := uint64(sourceRate)
+	targetRate64 := uint64(targetRate)
+	divisor := gcd(sourceRate64, targetRate64)
+	interpolation := targetRate64 / divisor
+	decimation := sourceRate64 / divisor
+	filter := qualityFilter(quality)
+	sourceRateFloat := float64(sourceRate)
+	targetRateFloat := float64(targetRate)
+	cutoff := filter.bandwidth * math.Min(1.0, targetRateFloat/sourceRateFloat)
+	zeroCrossingsFloat := float64(filter.zeroCrossings)
+	halfLengthFloat := math.Ceil(zeroCrossingsFloat / cutoff)
+	halfLength := int(halfLengthFloat)
+	return interpolation, decimation, halfLength, cutoff
+}
+
+/*
+ * Returns the number of source samples an output sample of a polyphase
+ * resampler lags behind the input.
+ */
+func StreamDelay(sourceRate uint32, targetRate uint32, quality Quality) int {
+	_, _, halfLength, _ := polyphaseParameters(sourceRate, targetRate, quality)
+	return halfLength
+}
+
+/*
+ * Returns the number of source samples an output sample lags behind the
+ * input, since the filter has to wait for the samples following it.
+ */
+func (this *polyphaseResamplerStruct) Delay() int {
+	return this.halfLength
+}
+
+/*
+ * Returns the number of samples the next call to Process will produce from
+ * a certain number of input samples.
+ */
+func (this *polyphaseResamplerStruct) Length(n int) int {
+	halfLength := this.halfLength
+	historyLength := 2 * halfLength
+	available := uint64(historyLength + n - halfLength)
+	limit := available * this.interpolation
+	position := this.position
+
+	/*
+	 * Check if any sample can be produced.
+	 */
+	if position >= limit {
+		return 0
+	} else {
+		decimation := this.decimation
+		distance := limit - position
+		length := (distance + decimation - 1) / decimation
+		return int(length)
+	}
+
+}
+
+/*
+ * Resamples a block of input samples and writes the result into the output
+ * buffer, which must be able to hold as many samples as Length reports.
+ *
+ * Returns the number of samples written.
+ */
+func (this *polyphaseResamplerStruct) Process(in []float64, out []float64) int {
+	halfLength := this.halfLength
+	historyLength := 2 * halfLength
+	n := len(in)
+	length := this.Length(n)
+	bufferSize := historyLength + n
+	buffer := this.buffer
+
+	/*
+	 * Make sure the buffer can hold the history and the input.
+	 */
+	if cap(buffer) < bufferSize {
+		bufferNew := make([]float64, bufferSize)
+		copy(bufferNew, buffer[0:historyLength])
+		buffer = bufferNew
+	}
+
+	buffer = buffer[0:bufferSize]
+	copy(buffer[historyLength:bufferSize], in)
+	position := this.position
+	interpolation := this.interpolation
+	decimation := this.decimation
+	numPhases := this.numPhases
+	coefficients := this.coefficients
+
+	/*
+	 * Calculate each output sample as the dot product of the input with
+	 * the filter of its phase.
+	 */
+	for i := 0; i < length; i++ {
+		idx := int(position / interpolation)
+		phase := ((position % interpolation) * numPhases) / interpolation
+		phaseCoefficients := coefficients[phase]
+		lBound := idx - halfLength + 1
+		sum := 0.0
+
+		/*
+		 * Apply each coefficient.
+		 */
+		for j, coefficient := range phaseCoefficients {
+			sum += coefficient * buffer[lBound+j]
+		}
+
+		out[i] = sum
+		position += decimation
+	}
+
+	nTarget := uint64(n) * interpolation
+	this.position = position - nTarget
+	tailStart := bufferSize - historyLength
+	copy(buffer[0:historyLength], buffer[tailStart:bufferSize])
+	this.buffer = buffer
+	return length
+}
+
+/*
+ * Creates a streaming polyphase resampler, which converts a stream of
+ * samples from a source to a target sampling rate using a windowed sinc
+ * filter of a certain quality.
+ */
+func NewStreamResampler(sourceRate uint32, targetRate uint32, quality Quality) Resampler {
+	interpolation, decimation, halfLength, cutoff := polyphaseParameters(sourceRate, targetRate, quality)
+	filter := qualityFilter(quality)
+	numPhases := interpolation
+
+	/*
+	 * Limit the size of the table of coefficients for odd ratios.
+	 */
+	if numPhases > POLYPHASE_MAX_PHASES {
+		numPhases = POLYPHASE_MAX_PHASES
+	}
+
+	numTaps := 2 * halfLength
+	coefficients := make([][]float64, numPhases)
+	numPhasesFloat := float64(numPhases)
+	halfLengthFloat := float64(halfLength)
+
+	/*
+	 * Calculate the coefficients of each phase.
+	 */
+	for phase := range coefficients {
+		phaseFloat := float64(phase)
+		offset := phaseFloat / numPhasesFloat
+		phaseCoefficients := make([]float64, numTaps)
+		sum := 0.0
+
+		/*
+		 * Calculate each coefficient of the phase.
+		 */
+		for j := range phaseCoefficients {
+			jFloat := float64(j)
+			distance := (halfLengthFloat - 1.0 - jFloat) + offset
+			window := kaiserWindow(distance/halfLengthFloat, filter.beta)
+			coefficient := cutoff * sinc(cutoff*distance) * window
+			phaseCoefficients[j] = coefficient
+			sum += coefficient
+		}
+
+		/*
+		 * Normalize the phase, so that it passes constant signals
+		 * unchanged.
+		 */
+		for j := range phaseCoefficients {
+			phaseCoefficients[j] /= sum
+		}
+
+		coefficients[phase] = phaseCoefficients
+	}
+
+	historyLength := 2 * halfLength
+	historyLength64 := uint64(historyLength)
+
+	/*
+	 * Create polyphase resampler.
+	 */
+	r := polyphaseResamplerStruct{
+		interpolation: interpolation,
+		decimation:    decimation,
+		halfLength:    halfLength,
+		numPhases:     numPhases,
+		coefficients:  coefficients,
+		position:      historyLength64 * interpolation,
+		buffer:        make([]float64, historyLength),
+	}
+
+	return &r
+}
+
+/*
+ * Resample time series data from a source to a target sampling rate using a
+ * polyphase windowed sinc filter of a certain quality.
+ *
+ * Unlike the Lanczos method, this removes content above the Nyquist
+ * frequency of the target rate, so that downsampling does not alias.
+ */
+func Sinc(samples []float64, sourceRate uint32, targetRate uint32, quality Quality) []float64 {
+	inputLength := len(samples)
+	inputLength64 := uint64(inputLength)
+	sourceRate64 := uint64(sourceRate)
+	targetRate64 := uint64(targetRate)
+	outputLength64 := (inputLength64 * targetRate64) / sourceRate64
+	outputLength := int(outputLength64)
+	r := NewStreamResampler(sourceRate, targetRate, quality)
+	delay := r.Delay()
+	tail := make([]float64, delay+1)
+	length := r.Length(inputLength)
+	outputBuffer := make([]float64, length+r.Length(0)+outputLength+1)
+	written := r.Process(samples, outputBuffer)
+	written += r.Process(tail, outputBuffer[written:])
+
+	/*
+	 * Never return more samples than were produced.
+	 */
+	if outputLength > written {
+		outputLength = written
+	}
+
+	return outputBuffer[0:outputLength]
+}
@@ -4,6 +4,37 @@ import (
 	"math"
 )
 
+/*
+ * Global constants.
+ */
+const (
+	LANCZOS_ORDER = 3
+)
+
+/*
+ * A resampler converting a continuous stream of samples, which is passed to
+ * it block by block, from a source to a target sampling rate.
+ */
+type Resampler interface {
+	Delay() int
+	Length(n int) int
+	Process(in []float64, out []float64) int
+}
+
+/*
+ * Data structure implementing a streaming Lanczos resampler.
+ *
+ * The position of the next output sample is kept as an integer multiple of
+ * one target period, measured from the start of the buffer, so that the
+ * number of samples produced never drifts.
+ */
+type resamplerStruct struct {
+	sourceRate uint64
+	targetRate uint64
+	position   uint64
+	buffer     []float64
+}
+
 /*
  * The Lanczos kernel function L(x, a).
  */
@@ -174,3 +205,104 @@ func Oversample(source []float64, target []float64, factor uint32) {
 	}
 
 }
+
+/*
+ * Returns the number of source samples an output sample lags behind the
+ * input, since interpolation has to wait for the samples following it.
+ */
+func (this *resamplerStruct) Delay() int {
+	return LANCZOS_ORDER
+}
+
+/*
+ * Returns the number of samples the next call to Process will produce from
+ * a certain number of input samples.
+ */
+func (this *resamplerStruct) Length(n int) int {
+	historyLength := 2 * LANCZOS_ORDER
+	available := uint64(historyLength + n - LANCZOS_ORDER)
+	limit := available * this.targetRate
+	position := this.position
+
+	/*
+	 * Check if any sample can be produced.
+	 */
+	if position >= limit {
+		return 0
+	} else {
+		sourceRate := this.sourceRate
+		distance := limit - position
+		length := (distance + sourceRate - 1) / sourceRate
+		return int(length)
+	}
+
+}
+
+/*
+ * Resamples a block of input samples and writes the result into the output
+ * buffer, which must be able to hold as many samples as Length reports.
+ *
+ * Returns the number of samples written.
+ */
+func (this *resamplerStruct) Process(in []float64, out []float64) int {
+	historyLength := 2 * LANCZOS_ORDER
+	n := len(in)
+	length := this.Length(n)
+	bufferSize := historyLength + n
+	buffer := this.buffer
+
+	/*
+	 * Make sure the buffer can hold the history and the input.
+	 */
+	if cap(buffer) < bufferSize {
+		bufferNew := make([]float64, bufferSize)
+		copy(bufferNew, buffer[0:historyLength])
+		buffer = bufferNew
+	}
+
+	buffer = buffer[0:bufferSize]
+	copy(buffer[historyLength:bufferSize], in)
+	position := this.position
+	sourceRate := this.sourceRate
+	targetRate := this.targetRate
+	targetRateFloat := float64(targetRate)
+
+	/*
+	 * Calculate output samples using Lanczos interpolation.
+	 */
+	for i := 0; i < length; i++ {
+		positionFloat := float64(position)
+		x := positionFloat / targetRateFloat
+		out[i] = lanczosInterpolate(buffer, x, LANCZOS_ORDER)
+		position += sourceRate
+	}
+
+	nTarget := uint64(n) * targetRate
+	this.position = position - nTarget
+	tailStart := bufferSize - historyLength
+	copy(buffer[0:historyLength], buffer[tailStart:bufferSize])
+	this.buffer = buffer
+	return length
+}
+
+/*
+ * Creates a resampler converting a stream of samples from a source to a
+ * target sampling rate.
+ */
+func CreateResampler(sourceRate uint32, targetRate uint32) Resampler {
+	historyLength := 2 * LANCZOS_ORDER
+	targetRate64 := uint64(targetRate)
+	historyLength64 := uint64(historyLength)
+
+	/*
+	 * Create resampler.
+	 */
+	r := resamplerStruct{
+		sourceRate: uint64(sourceRate),
+		targetRate: targetRate64,
+		position:   historyLength64 * targetRate64,
+		buffer:     make([]float64, historyLength),
+	}
+
+	return &r
+}
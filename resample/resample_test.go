@@ -213,3 +213,390 @@ func TestOversample(t *testing.T) {
 	}
 
 }
+
+/*
+ * Verify that a streaming resampler produces the same samples as resampling
+ * the entire signal at once, whatever the size of the blocks.
+ */
+func TestResampler(t *testing.T) {
+	n := 1000
+	signal := make([]float64, n)
+
+	/*
+	 * Generate a signal.
+	 */
+	for i := range signal {
+		iFloat := float64(i)
+		signal[i] = math.Sin(0.1*iFloat) + (0.5 * math.Cos(0.37*iFloat))
+	}
+
+	/*
+	 * Pairs of source and target rates.
+	 */
+	sourceRates := []uint32{44100, 48000, 96000, 48000}
+	targetRates := []uint32{96000, 96000, 48000, 44100}
+
+	/*
+	 * Sizes of the blocks passed to the resampler.
+	 */
+	blockSizes := []int{1, 7, 64, 100}
+
+	/*
+	 * Resample the signal with each pair of rates.
+	 */
+	for i, sourceRate := range sourceRates {
+		targetRate := targetRates[i]
+		expected := Time(signal, sourceRate, targetRate)
+
+		/*
+		 * Pass the signal in blocks of each size.
+		 */
+		for _, blockSize := range blockSizes {
+			r := CreateResampler(sourceRate, targetRate)
+			result := []float64{}
+
+			/*
+			 * Resample each block.
+			 */
+			for lBound := 0; lBound < n; lBound += blockSize {
+				uBound := lBound + blockSize
+
+				/*
+				 * The last block may be shorter.
+				 */
+				if uBound > n {
+					uBound = n
+				}
+
+				block := signal[lBound:uBound]
+				length := r.Length(len(block))
+				out := make([]float64, length)
+				written := r.Process(block, out)
+
+				/*
+				 * Check if the resampler wrote as many samples as it
+				 * announced.
+				 */
+				if written != length {
+					t.Errorf("Resampler should have written %d samples, but wrote %d.", length, written)
+				}
+
+				result = append(result, out...)
+			}
+
+			numResults := len(result)
+			ok, diff := areSlicesClose(result, expected[0:numResults])
+
+			/*
+			 * Verify the streamed samples.
+			 */
+			if !ok {
+				t.Errorf("Rates %d -> %d, blocks of %d: Result is incorrect, difference: %v", sourceRate, targetRate, blockSize, diff)
+			}
+
+			delay := r.Delay()
+			targetRateFloat := float64(targetRate)
+			sourceRateFloat := float64(sourceRate)
+			missing := float64(len(expected) - numResults)
+			maxMissing := math.Ceil(float64(delay) * (targetRateFloat / sourceRateFloat))
+
+			/*
+			 * Only the samples within the delay may be missing.
+			 */
+			if missing > maxMissing {
+				t.Errorf("Rates %d -> %d, blocks of %d: %f samples are missing, but at most %f should be.", sourceRate, targetRate, blockSize, missing, maxMissing)
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Verify that a polyphase resampler produces the same samples in blocks of
+ * any size, reproduces a sine wave at each quality level and removes content
+ * above the Nyquist frequency of the target rate.
+ */
+func TestStreamResampler(t *testing.T) {
+	n := 4800
+	signal := make([]float64, n)
+
+	/*
+	 * Generate a sine wave at 1 kHz.
+	 */
+	for i := range signal {
+		iFloat := float64(i)
+		arg := (2.0 * math.Pi * 1000.0 * iFloat) / 48000.0
+		signal[i] = 0.5 * math.Sin(arg)
+	}
+
+	/*
+	 * Names of the quality levels.
+	 */
+	names := []string{
+		"fast",
+		"medium",
+		"best",
+	}
+
+	/*
+	 * Maximum deviation from the sine wave at each quality level.
+	 */
+	tolerances := []float64{
+		1e-2,
+		1e-3,
+		1e-4,
+	}
+
+	/*
+	 * Pairs of source and target rates.
+	 */
+	sourceRates := []uint32{48000, 48000, 48000}
+	targetRates := []uint32{96000, 44100, 32000}
+
+	/*
+	 * Sizes of the blocks passed to the resampler.
+	 */
+	blockSizes := []int{1, 7, 64, 100}
+
+	/*
+	 * Resample the signal at each quality level.
+	 */
+	for i, name := range names {
+		quality, err := ParseQuality(name)
+
+		/*
+		 * Check if quality level was found.
+		 */
+		if err != nil {
+			t.Fatalf("Failed to parse quality: %s", err.Error())
+		}
+
+		tolerance := tolerances[i]
+
+		/*
+		 * Resample the signal with each pair of rates.
+		 */
+		for j, sourceRate := range sourceRates {
+			targetRate := targetRates[j]
+			expected := Sinc(signal, sourceRate, targetRate, quality)
+			numExpected := len(expected)
+			targetRateFloat := float64(targetRate)
+			delay := StreamDelay(targetRate, sourceRate, quality)
+
+			/*
+			 * Compare the result with the sine wave, apart from the
+			 * edges, where the filter lacks samples.
+			 */
+			for k := delay; k < (numExpected - delay); k++ {
+				kFloat := float64(k)
+				arg := (2.0 * math.Pi * 1000.0 * kFloat) / targetRateFloat
+				sample := 0.5 * math.Sin(arg)
+				diff := math.Abs(expected[k] - sample)
+
+				/*
+				 * Check if we found a significant difference.
+				 */
+				if diff > tolerance {
+					t.Errorf("Quality %s, rates %d -> %d: Sample %d should be %f, but is %f.", name, sourceRate, targetRate, k, sample, expected[k])
+					break
+				}
+
+			}
+
+			/*
+			 * Pass the signal in blocks of each size.
+			 */
+			for _, blockSize := range blockSizes {
+				r := NewStreamResampler(sourceRate, targetRate, quality)
+				result := []float64{}
+
+				/*
+				 * Resample each block.
+				 */
+				for lBound := 0; lBound < n; lBound += blockSize {
+					uBound := lBound + blockSize
+
+					/*
+					 * The last block may be shorter.
+					 */
+					if uBound > n {
+						uBound = n
+					}
+
+					block := signal[lBound:uBound]
+					length := r.Length(len(block))
+					out := make([]float64, length)
+					written := r.Process(block, out)
+
+					/*
+					 * Check if the resampler wrote as many samples
+					 * as it announced.
+					 */
+					if written != length {
+						t.Errorf("Resampler should have written %d samples, but wrote %d.", length, written)
+					}
+
+					result = append(result, out...)
+				}
+
+				numResults := len(result)
+				ok, diff := areSlicesClose(result, expected[0:numResults])
+
+				/*
+				 * Verify the streamed samples.
+				 */
+				if !ok {
+					t.Errorf("Quality %s, rates %d -> %d, blocks of %d: Result is incorrect, difference: %v", name, sourceRate, targetRate, blockSize, diff)
+				}
+
+			}
+
+		}
+
+	}
+
+	alias := make([]float64, n)
+
+	/*
+	 * Generate a sine wave at 30 kHz, which lies above the Nyquist
+	 * frequency of the target rate.
+	 */
+	for i := range alias {
+		iFloat := float64(i)
+		arg := (2.0 * math.Pi * 30000.0 * iFloat) / 96000.0
+		alias[i] = 0.5 * math.Sin(arg)
+	}
+
+	result := Sinc(alias, 96000, 48000, QUALITY_MEDIUM)
+	delay := StreamDelay(48000, 96000, QUALITY_MEDIUM)
+	numResults := len(result)
+
+	/*
+	 * The sine wave must not alias into the audible range.
+	 */
+	for i := delay; i < (numResults - delay); i++ {
+
+		/*
+		 * Check if we found a significant sample.
+		 */
+		if math.Abs(result[i]) > 1e-3 {
+			t.Errorf("Sample %d should be suppressed, but is %f.", i, result[i])
+			break
+		}
+
+	}
+
+	_, err := ParseQuality("ultra")
+
+	/*
+	 * Unknown quality levels must be rejected.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Parsing an unknown quality should fail, but it did not.")
+	}
+
+}
+
+/*
+ * Creates one second of a test signal for the benchmarks.
+ */
+func createBenchmarkSignal() []float64 {
+	signal := make([]float64, 44100)
+
+	/*
+	 * Generate a sum of sine waves.
+	 */
+	for i := range signal {
+		iFloat := float64(i)
+		signal[i] = math.Sin(0.1*iFloat) + (0.5 * math.Cos(0.37*iFloat))
+	}
+
+	return signal
+}
+
+/*
+ * Measure the time it takes to resample one second of audio from 44.1 kHz to
+ * 48 kHz using the Lanczos algorithm.
+ */
+func BenchmarkTime(b *testing.B) {
+	signal := createBenchmarkSignal()
+	b.ResetTimer()
+
+	/*
+	 * Resample the signal b.N times.
+	 */
+	for i := 0; i < b.N; i++ {
+		Time(signal, 44100, 48000)
+	}
+
+}
+
+/*
+ * Measure the time it takes to resample one second of audio from 44.1 kHz to
+ * 48 kHz in periods of 256 frames using a streaming resampler.
+ */
+func benchmarkResampler(b *testing.B, r Resampler) {
+	signal := createBenchmarkSignal()
+	n := len(signal)
+	blockSize := 256
+	out := make([]float64, 2*blockSize)
+	b.ResetTimer()
+
+	/*
+	 * Resample the signal b.N times.
+	 */
+	for i := 0; i < b.N; i++ {
+
+		/*
+		 * Resample each period.
+		 */
+		for lBound := 0; lBound < n; lBound += blockSize {
+			uBound := lBound + blockSize
+
+			/*
+			 * The last period may be shorter.
+			 */
+			if uBound > n {
+				uBound = n
+			}
+
+			r.Process(signal[lBound:uBound], out)
+		}
+
+	}
+
+}
+
+/*
+ * Benchmark the streaming Lanczos resampler.
+ */
+func BenchmarkResampler(b *testing.B) {
+	r := CreateResampler(44100, 48000)
+	benchmarkResampler(b, r)
+}
+
+/*
+ * Benchmark the polyphase resampler at fast quality.
+ */
+func BenchmarkStreamResamplerFast(b *testing.B) {
+	r := NewStreamResampler(44100, 48000, QUALITY_FAST)
+	benchmarkResampler(b, r)
+}
+
+/*
+ * Benchmark the polyphase resampler at medium quality.
+ */
+func BenchmarkStreamResamplerMedium(b *testing.B) {
+	r := NewStreamResampler(44100, 48000, QUALITY_MEDIUM)
+	benchmarkResampler(b, r)
+}
+
+/*
+ * Benchmark the polyphase resampler at best quality.
+ */
+func BenchmarkStreamResamplerBest(b *testing.B) {
+	r := NewStreamResampler(44100, 48000, QUALITY_BEST)
+	benchmarkResampler(b, r)
+}
@@ -0,0 +1,1406 @@
+// gRPC control interface of go-dsp-guitar.
+//
+// Regenerate the Go code in this directory after changing this file:
+//
+// protoc --go_out=. --go_opt=paths=source_relative \
+//        --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//        rpc/dsp.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        (unknown)
+// source: rpc/dsp.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// An empty message.
+type Empty struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{0}
+}
+
+// Tells whether an operation was successful or not.
+type Status struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Reason  string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *Status) Reset() {
+	*x = Status{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Status) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Status) ProtoMessage() {}
+
+func (x *Status) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Status.ProtoReflect.Descriptor instead.
+func (*Status) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Status) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *Status) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// A call to an endpoint of the v2 API.
+type InvokeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Endpoint string            `protobuf:"bytes,1,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Params   map[string]string `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *InvokeRequest) Reset() {
+	*x = InvokeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InvokeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvokeRequest) ProtoMessage() {}
+
+func (x *InvokeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvokeRequest.ProtoReflect.Descriptor instead.
+func (*InvokeRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *InvokeRequest) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *InvokeRequest) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+// The response of an endpoint of the v2 API.
+//
+// The result is encoded as JSON and empty if the endpoint only reports
+// success or failure.
+type InvokeResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Reason  string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Result  string `protobuf:"bytes,3,opt,name=result,proto3" json:"result,omitempty"`
+}
+
+func (x *InvokeResponse) Reset() {
+	*x = InvokeResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InvokeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvokeResponse) ProtoMessage() {}
+
+func (x *InvokeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvokeResponse.ProtoReflect.Descriptor instead.
+func (*InvokeResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *InvokeResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *InvokeResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *InvokeResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+// The types of units which may be added to a chain.
+type UnitTypes struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Types []string `protobuf:"bytes,1,rep,name=types,proto3" json:"types,omitempty"`
+}
+
+func (x *UnitTypes) Reset() {
+	*x = UnitTypes{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UnitTypes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnitTypes) ProtoMessage() {}
+
+func (x *UnitTypes) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnitTypes.ProtoReflect.Descriptor instead.
+func (*UnitTypes) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UnitTypes) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+// A request to add a unit to a chain.
+type AddUnitRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain uint32 `protobuf:"varint,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Type  uint32 `protobuf:"varint,2,opt,name=type,proto3" json:"type,omitempty"`
+}
+
+func (x *AddUnitRequest) Reset() {
+	*x = AddUnitRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AddUnitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddUnitRequest) ProtoMessage() {}
+
+func (x *AddUnitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddUnitRequest.ProtoReflect.Descriptor instead.
+func (*AddUnitRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AddUnitRequest) GetChain() uint32 {
+	if x != nil {
+		return x.Chain
+	}
+	return 0
+}
+
+func (x *AddUnitRequest) GetType() uint32 {
+	if x != nil {
+		return x.Type
+	}
+	return 0
+}
+
+// A request identifying a unit in a chain.
+type UnitRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain uint32 `protobuf:"varint,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Unit  uint32 `protobuf:"varint,2,opt,name=unit,proto3" json:"unit,omitempty"`
+}
+
+func (x *UnitRequest) Reset() {
+	*x = UnitRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UnitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnitRequest) ProtoMessage() {}
+
+func (x *UnitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnitRequest.ProtoReflect.Descriptor instead.
+func (*UnitRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UnitRequest) GetChain() uint32 {
+	if x != nil {
+		return x.Chain
+	}
+	return 0
+}
+
+func (x *UnitRequest) GetUnit() uint32 {
+	if x != nil {
+		return x.Unit
+	}
+	return 0
+}
+
+// A request to put a unit into or out of bypass mode.
+type SetBypassRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain uint32 `protobuf:"varint,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Unit  uint32 `protobuf:"varint,2,opt,name=unit,proto3" json:"unit,omitempty"`
+	Value bool   `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *SetBypassRequest) Reset() {
+	*x = SetBypassRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetBypassRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBypassRequest) ProtoMessage() {}
+
+func (x *SetBypassRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBypassRequest.ProtoReflect.Descriptor instead.
+func (*SetBypassRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SetBypassRequest) GetChain() uint32 {
+	if x != nil {
+		return x.Chain
+	}
+	return 0
+}
+
+func (x *SetBypassRequest) GetUnit() uint32 {
+	if x != nil {
+		return x.Unit
+	}
+	return 0
+}
+
+func (x *SetBypassRequest) GetValue() bool {
+	if x != nil {
+		return x.Value
+	}
+	return false
+}
+
+// A request to set a discrete parameter of a unit.
+type SetDiscreteValueRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain uint32 `protobuf:"varint,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Unit  uint32 `protobuf:"varint,2,opt,name=unit,proto3" json:"unit,omitempty"`
+	Param string `protobuf:"bytes,3,opt,name=param,proto3" json:"param,omitempty"`
+	Value string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *SetDiscreteValueRequest) Reset() {
+	*x = SetDiscreteValueRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetDiscreteValueRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetDiscreteValueRequest) ProtoMessage() {}
+
+func (x *SetDiscreteValueRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetDiscreteValueRequest.ProtoReflect.Descriptor instead.
+func (*SetDiscreteValueRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SetDiscreteValueRequest) GetChain() uint32 {
+	if x != nil {
+		return x.Chain
+	}
+	return 0
+}
+
+func (x *SetDiscreteValueRequest) GetUnit() uint32 {
+	if x != nil {
+		return x.Unit
+	}
+	return 0
+}
+
+func (x *SetDiscreteValueRequest) GetParam() string {
+	if x != nil {
+		return x.Param
+	}
+	return ""
+}
+
+func (x *SetDiscreteValueRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+// A request to set a numeric parameter of a unit.
+type SetNumericValueRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain uint32 `protobuf:"varint,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Unit  uint32 `protobuf:"varint,2,opt,name=unit,proto3" json:"unit,omitempty"`
+	Param string `protobuf:"bytes,3,opt,name=param,proto3" json:"param,omitempty"`
+	Value int32  `protobuf:"varint,4,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *SetNumericValueRequest) Reset() {
+	*x = SetNumericValueRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetNumericValueRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetNumericValueRequest) ProtoMessage() {}
+
+func (x *SetNumericValueRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetNumericValueRequest.ProtoReflect.Descriptor instead.
+func (*SetNumericValueRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SetNumericValueRequest) GetChain() uint32 {
+	if x != nil {
+		return x.Chain
+	}
+	return 0
+}
+
+func (x *SetNumericValueRequest) GetUnit() uint32 {
+	if x != nil {
+		return x.Unit
+	}
+	return 0
+}
+
+func (x *SetNumericValueRequest) GetParam() string {
+	if x != nil {
+		return x.Param
+	}
+	return ""
+}
+
+func (x *SetNumericValueRequest) GetValue() int32 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+// A request to stream updates at a certain interval (in milliseconds).
+type StreamRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Interval uint32 `protobuf:"varint,1,opt,name=interval,proto3" json:"interval,omitempty"`
+}
+
+func (x *StreamRequest) Reset() {
+	*x = StreamRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRequest) ProtoMessage() {}
+
+func (x *StreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamRequest.ProtoReflect.Descriptor instead.
+func (*StreamRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StreamRequest) GetInterval() uint32 {
+	if x != nil {
+		return x.Interval
+	}
+	return 0
+}
+
+// The level of a channel (in decibels).
+type ChannelLevel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ChannelName string `protobuf:"bytes,1,opt,name=channel_name,json=channelName,proto3" json:"channel_name,omitempty"`
+	Level       int32  `protobuf:"varint,2,opt,name=level,proto3" json:"level,omitempty"`
+	Peak        int32  `protobuf:"varint,3,opt,name=peak,proto3" json:"peak,omitempty"`
+}
+
+func (x *ChannelLevel) Reset() {
+	*x = ChannelLevel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ChannelLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChannelLevel) ProtoMessage() {}
+
+func (x *ChannelLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChannelLevel.ProtoReflect.Descriptor instead.
+func (*ChannelLevel) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ChannelLevel) GetChannelName() string {
+	if x != nil {
+		return x.ChannelName
+	}
+	return ""
+}
+
+func (x *ChannelLevel) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *ChannelLevel) GetPeak() int32 {
+	if x != nil {
+		return x.Peak
+	}
+	return 0
+}
+
+// The gain reduction (in decibels) applied by a unit.
+type GainReduction struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain     uint32 `protobuf:"varint,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Unit      uint32 `protobuf:"varint,2,opt,name=unit,proto3" json:"unit,omitempty"`
+	Reduction int32  `protobuf:"varint,3,opt,name=reduction,proto3" json:"reduction,omitempty"`
+}
+
+func (x *GainReduction) Reset() {
+	*x = GainReduction{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GainReduction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GainReduction) ProtoMessage() {}
+
+func (x *GainReduction) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GainReduction.ProtoReflect.Descriptor instead.
+func (*GainReduction) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GainReduction) GetChain() uint32 {
+	if x != nil {
+		return x.Chain
+	}
+	return 0
+}
+
+func (x *GainReduction) GetUnit() uint32 {
+	if x != nil {
+		return x.Unit
+	}
+	return 0
+}
+
+func (x *GainReduction) GetReduction() int32 {
+	if x != nil {
+		return x.Reduction
+	}
+	return 0
+}
+
+// A unit whose output clipped.
+type Clip struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain uint32 `protobuf:"varint,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Unit  uint32 `protobuf:"varint,2,opt,name=unit,proto3" json:"unit,omitempty"`
+}
+
+func (x *Clip) Reset() {
+	*x = Clip{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Clip) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Clip) ProtoMessage() {}
+
+func (x *Clip) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Clip.ProtoReflect.Descriptor instead.
+func (*Clip) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Clip) GetChain() uint32 {
+	if x != nil {
+		return x.Chain
+	}
+	return 0
+}
+
+func (x *Clip) GetUnit() uint32 {
+	if x != nil {
+		return x.Unit
+	}
+	return 0
+}
+
+// The results of the level meters.
+type LevelUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DspLoad       int32            `protobuf:"varint,1,opt,name=dsp_load,json=dspLoad,proto3" json:"dsp_load,omitempty"`
+	Channels      []*ChannelLevel  `protobuf:"bytes,2,rep,name=channels,proto3" json:"channels,omitempty"`
+	GainReduction []*GainReduction `protobuf:"bytes,3,rep,name=gain_reduction,json=gainReduction,proto3" json:"gain_reduction,omitempty"`
+	Clipping      []*Clip          `protobuf:"bytes,4,rep,name=clipping,proto3" json:"clipping,omitempty"`
+	Xruns         uint32           `protobuf:"varint,5,opt,name=xruns,proto3" json:"xruns,omitempty"`
+}
+
+func (x *LevelUpdate) Reset() {
+	*x = LevelUpdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LevelUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LevelUpdate) ProtoMessage() {}
+
+func (x *LevelUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LevelUpdate.ProtoReflect.Descriptor instead.
+func (*LevelUpdate) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *LevelUpdate) GetDspLoad() int32 {
+	if x != nil {
+		return x.DspLoad
+	}
+	return 0
+}
+
+func (x *LevelUpdate) GetChannels() []*ChannelLevel {
+	if x != nil {
+		return x.Channels
+	}
+	return nil
+}
+
+func (x *LevelUpdate) GetGainReduction() []*GainReduction {
+	if x != nil {
+		return x.GainReduction
+	}
+	return nil
+}
+
+func (x *LevelUpdate) GetClipping() []*Clip {
+	if x != nil {
+		return x.Clipping
+	}
+	return nil
+}
+
+func (x *LevelUpdate) GetXruns() uint32 {
+	if x != nil {
+		return x.Xruns
+	}
+	return 0
+}
+
+// The results of the tuner.
+type TunerUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cents     int32   `protobuf:"varint,1,opt,name=cents,proto3" json:"cents,omitempty"`
+	Frequency float64 `protobuf:"fixed64,2,opt,name=frequency,proto3" json:"frequency,omitempty"`
+	Note      string  `protobuf:"bytes,3,opt,name=note,proto3" json:"note,omitempty"`
+}
+
+func (x *TunerUpdate) Reset() {
+	*x = TunerUpdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_dsp_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TunerUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TunerUpdate) ProtoMessage() {}
+
+func (x *TunerUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_dsp_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TunerUpdate.ProtoReflect.Descriptor instead.
+func (*TunerUpdate) Descriptor() ([]byte, []int) {
+	return file_rpc_dsp_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *TunerUpdate) GetCents() int32 {
+	if x != nil {
+		return x.Cents
+	}
+	return 0
+}
+
+func (x *TunerUpdate) GetFrequency() float64 {
+	if x != nil {
+		return x.Frequency
+	}
+	return 0
+}
+
+func (x *TunerUpdate) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+var File_rpc_dsp_proto protoreflect.FileDescriptor
+
+var file_rpc_dsp_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x72, 0x70, 0x63, 0x2f, 0x64, 0x73, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x03, 0x64, 0x73, 0x70, 0x22, 0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x3a, 0x0a,
+	0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65,
+	0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73,
+	0x73, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x9e, 0x01, 0x0a, 0x0d, 0x49, 0x6e,
+	0x76, 0x6f, 0x6b, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x65,
+	0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x65,
+	0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x36, 0x0a, 0x06, 0x70, 0x61, 0x72, 0x61, 0x6d,
+	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x49, 0x6e,
+	0x76, 0x6f, 0x6b, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x50, 0x61, 0x72, 0x61,
+	0x6d, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x1a,
+	0x39, 0x0a, 0x0b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
+	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
+	0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x5a, 0x0a, 0x0e, 0x49, 0x6e,
+	0x76, 0x6f, 0x6b, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73,
+	0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x16,
+	0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0x21, 0x0a, 0x09, 0x55, 0x6e, 0x69, 0x74, 0x54, 0x79,
+	0x70, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x79, 0x70, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x05, 0x74, 0x79, 0x70, 0x65, 0x73, 0x22, 0x3a, 0x0a, 0x0e, 0x41, 0x64, 0x64,
+	0x55, 0x6e, 0x69, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63,
+	0x68, 0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x63, 0x68, 0x61, 0x69,
+	0x6e, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x04, 0x74, 0x79, 0x70, 0x65, 0x22, 0x37, 0x0a, 0x0b, 0x55, 0x6e, 0x69, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x6e,
+	0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x22, 0x52,
+	0x0a, 0x10, 0x53, 0x65, 0x74, 0x42, 0x79, 0x70, 0x61, 0x73, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x6e, 0x69, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x12, 0x14, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x22, 0x6f, 0x0a, 0x17, 0x53, 0x65, 0x74, 0x44, 0x69, 0x73, 0x63, 0x72, 0x65, 0x74,
+	0x65, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a,
+	0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x63, 0x68,
+	0x61, 0x69, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x61, 0x72, 0x61, 0x6d,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x12, 0x14, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x22, 0x6e, 0x0a, 0x16, 0x53, 0x65, 0x74, 0x4e, 0x75, 0x6d, 0x65, 0x72, 0x69,
+	0x63, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a,
+	0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x63, 0x68,
+	0x61, 0x69, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x61, 0x72, 0x61, 0x6d,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x12, 0x14, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x22, 0x2b, 0x0a, 0x0d, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c,
+	0x22, 0x5b, 0x0a, 0x0c, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4c, 0x65, 0x76, 0x65, 0x6c,
+	0x12, 0x21, 0x0a, 0x0c, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x5f, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4e,
+	0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x65, 0x61,
+	0x6b, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x65, 0x61, 0x6b, 0x22, 0x57, 0x0a,
+	0x0d, 0x47, 0x61, 0x69, 0x6e, 0x52, 0x65, 0x64, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14,
+	0x0a, 0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x63,
+	0x68, 0x61, 0x69, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x64, 0x75,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x72, 0x65, 0x64,
+	0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x30, 0x0a, 0x04, 0x43, 0x6c, 0x69, 0x70, 0x12, 0x14,
+	0x0a, 0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x63,
+	0x68, 0x61, 0x69, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x22, 0xcf, 0x01, 0x0a, 0x0b, 0x4c, 0x65, 0x76,
+	0x65, 0x6c, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12, 0x19, 0x0a, 0x08, 0x64, 0x73, 0x70, 0x5f,
+	0x6c, 0x6f, 0x61, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x64, 0x73, 0x70, 0x4c,
+	0x6f, 0x61, 0x64, 0x12, 0x2d, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x43, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x52, 0x08, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65,
+	0x6c, 0x73, 0x12, 0x39, 0x0a, 0x0e, 0x67, 0x61, 0x69, 0x6e, 0x5f, 0x72, 0x65, 0x64, 0x75, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x64, 0x73, 0x70,
+	0x2e, 0x47, 0x61, 0x69, 0x6e, 0x52, 0x65, 0x64, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0d,
+	0x67, 0x61, 0x69, 0x6e, 0x52, 0x65, 0x64, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x25, 0x0a,
+	0x08, 0x63, 0x6c, 0x69, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x09, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x43, 0x6c, 0x69, 0x70, 0x52, 0x08, 0x63, 0x6c, 0x69, 0x70,
+	0x70, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x78, 0x72, 0x75, 0x6e, 0x73, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x05, 0x78, 0x72, 0x75, 0x6e, 0x73, 0x22, 0x55, 0x0a, 0x0b, 0x54, 0x75,
+	0x6e, 0x65, 0x72, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x65, 0x6e,
+	0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x63, 0x65, 0x6e, 0x74, 0x73, 0x12,
+	0x1c, 0x0a, 0x09, 0x66, 0x72, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x09, 0x66, 0x72, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x12, 0x0a,
+	0x04, 0x6e, 0x6f, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x6f, 0x74,
+	0x65, 0x32, 0xae, 0x04, 0x0a, 0x03, 0x44, 0x53, 0x50, 0x12, 0x31, 0x0a, 0x06, 0x49, 0x6e, 0x76,
+	0x6f, 0x6b, 0x65, 0x12, 0x12, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x49, 0x6e, 0x76, 0x6f, 0x6b, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x49, 0x6e,
+	0x76, 0x6f, 0x6b, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2a, 0x0a, 0x0c,
+	0x47, 0x65, 0x74, 0x55, 0x6e, 0x69, 0x74, 0x54, 0x79, 0x70, 0x65, 0x73, 0x12, 0x0a, 0x2e, 0x64,
+	0x73, 0x70, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0e, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x55,
+	0x6e, 0x69, 0x74, 0x54, 0x79, 0x70, 0x65, 0x73, 0x12, 0x2b, 0x0a, 0x07, 0x41, 0x64, 0x64, 0x55,
+	0x6e, 0x69, 0x74, 0x12, 0x13, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x41, 0x64, 0x64, 0x55, 0x6e, 0x69,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x2b, 0x0a, 0x0a, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x55,
+	0x6e, 0x69, 0x74, 0x12, 0x10, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x55, 0x6e, 0x69, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x12, 0x27, 0x0a, 0x06, 0x4d, 0x6f, 0x76, 0x65, 0x55, 0x70, 0x12, 0x10, 0x2e, 0x64,
+	0x73, 0x70, 0x2e, 0x55, 0x6e, 0x69, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b,
+	0x2e, 0x64, 0x73, 0x70, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x29, 0x0a, 0x08, 0x4d,
+	0x6f, 0x76, 0x65, 0x44, 0x6f, 0x77, 0x6e, 0x12, 0x10, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x55, 0x6e,
+	0x69, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x64, 0x73, 0x70, 0x2e,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x2f, 0x0a, 0x09, 0x53, 0x65, 0x74, 0x42, 0x79, 0x70,
+	0x61, 0x73, 0x73, 0x12, 0x15, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x53, 0x65, 0x74, 0x42, 0x79, 0x70,
+	0x61, 0x73, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x64, 0x73, 0x70,
+	0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x3d, 0x0a, 0x10, 0x53, 0x65, 0x74, 0x44, 0x69,
+	0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x1c, 0x2e, 0x64, 0x73,
+	0x70, 0x2e, 0x53, 0x65, 0x74, 0x44, 0x69, 0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x56, 0x61, 0x6c,
+	0x75, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x64, 0x73, 0x70, 0x2e,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x3b, 0x0a, 0x0f, 0x53, 0x65, 0x74, 0x4e, 0x75, 0x6d,
+	0x65, 0x72, 0x69, 0x63, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x1b, 0x2e, 0x64, 0x73, 0x70, 0x2e,
+	0x53, 0x65, 0x74, 0x4e, 0x75, 0x6d, 0x65, 0x72, 0x69, 0x63, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x53, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x12, 0x36, 0x0a, 0x0c, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4c, 0x65, 0x76,
+	0x65, 0x6c, 0x73, 0x12, 0x12, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x64, 0x73, 0x70, 0x2e, 0x4c, 0x65,
+	0x76, 0x65, 0x6c, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x30, 0x01, 0x12, 0x35, 0x0a, 0x0b, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x54, 0x75, 0x6e, 0x65, 0x72, 0x12, 0x12, 0x2e, 0x64, 0x73, 0x70,
+	0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10,
+	0x2e, 0x64, 0x73, 0x70, 0x2e, 0x54, 0x75, 0x6e, 0x65, 0x72, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x30, 0x01, 0x42, 0x27, 0x5a, 0x25, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
+	0x2f, 0x61, 0x6e, 0x64, 0x72, 0x65, 0x70, 0x78, 0x78, 0x2f, 0x67, 0x6f, 0x2d, 0x64, 0x73, 0x70,
+	0x2d, 0x67, 0x75, 0x69, 0x74, 0x61, 0x72, 0x2f, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
+}
+
+var (
+	file_rpc_dsp_proto_rawDescOnce sync.Once
+	file_rpc_dsp_proto_rawDescData = file_rpc_dsp_proto_rawDesc
+)
+
+func file_rpc_dsp_proto_rawDescGZIP() []byte {
+	file_rpc_dsp_proto_rawDescOnce.Do(func() {
+		file_rpc_dsp_proto_rawDescData = protoimpl.X.CompressGZIP(file_rpc_dsp_proto_rawDescData)
+	})
+	return file_rpc_dsp_proto_rawDescData
+}
+
+var file_rpc_dsp_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_rpc_dsp_proto_goTypes = []interface{}{
+	(*Empty)(nil),                   // 0: dsp.Empty
+	(*Status)(nil),                  // 1: dsp.Status
+	(*InvokeRequest)(nil),           // 2: dsp.InvokeRequest
+	(*InvokeResponse)(nil),          // 3: dsp.InvokeResponse
+	(*UnitTypes)(nil),               // 4: dsp.UnitTypes
+	(*AddUnitRequest)(nil),          // 5: dsp.AddUnitRequest
+	(*UnitRequest)(nil),             // 6: dsp.UnitRequest
+	(*SetBypassRequest)(nil),        // 7: dsp.SetBypassRequest
+	(*SetDiscreteValueRequest)(nil), // 8: dsp.SetDiscreteValueRequest
+	(*SetNumericValueRequest)(nil),  // 9: dsp.SetNumericValueRequest
+	(*StreamRequest)(nil),           // 10: dsp.StreamRequest
+	(*ChannelLevel)(nil),            // 11: dsp.ChannelLevel
+	(*GainReduction)(nil),           // 12: dsp.GainReduction
+	(*Clip)(nil),                    // 13: dsp.Clip
+	(*LevelUpdate)(nil),             // 14: dsp.LevelUpdate
+	(*TunerUpdate)(nil),             // 15: dsp.TunerUpdate
+	nil,                             // 16: dsp.InvokeRequest.ParamsEntry
+}
+var file_rpc_dsp_proto_depIdxs = []int32{
+	16, // 0: dsp.InvokeRequest.params:type_name -> dsp.InvokeRequest.ParamsEntry
+	11, // 1: dsp.LevelUpdate.channels:type_name -> dsp.ChannelLevel
+	12, // 2: dsp.LevelUpdate.gain_reduction:type_name -> dsp.GainReduction
+	13, // 3: dsp.LevelUpdate.clipping:type_name -> dsp.Clip
+	2,  // 4: dsp.DSP.Invoke:input_type -> dsp.InvokeRequest
+	0,  // 5: dsp.DSP.GetUnitTypes:input_type -> dsp.Empty
+	5,  // 6: dsp.DSP.AddUnit:input_type -> dsp.AddUnitRequest
+	6,  // 7: dsp.DSP.RemoveUnit:input_type -> dsp.UnitRequest
+	6,  // 8: dsp.DSP.MoveUp:input_type -> dsp.UnitRequest
+	6,  // 9: dsp.DSP.MoveDown:input_type -> dsp.UnitRequest
+	7,  // 10: dsp.DSP.SetBypass:input_type -> dsp.SetBypassRequest
+	8,  // 11: dsp.DSP.SetDiscreteValue:input_type -> dsp.SetDiscreteValueRequest
+	9,  // 12: dsp.DSP.SetNumericValue:input_type -> dsp.SetNumericValueRequest
+	10, // 13: dsp.DSP.StreamLevels:input_type -> dsp.StreamRequest
+	10, // 14: dsp.DSP.StreamTuner:input_type -> dsp.StreamRequest
+	3,  // 15: dsp.DSP.Invoke:output_type -> dsp.InvokeResponse
+	4,  // 16: dsp.DSP.GetUnitTypes:output_type -> dsp.UnitTypes
+	1,  // 17: dsp.DSP.AddUnit:output_type -> dsp.Status
+	1,  // 18: dsp.DSP.RemoveUnit:output_type -> dsp.Status
+	1,  // 19: dsp.DSP.MoveUp:output_type -> dsp.Status
+	1,  // 20: dsp.DSP.MoveDown:output_type -> dsp.Status
+	1,  // 21: dsp.DSP.SetBypass:output_type -> dsp.Status
+	1,  // 22: dsp.DSP.SetDiscreteValue:output_type -> dsp.Status
+	1,  // 23: dsp.DSP.SetNumericValue:output_type -> dsp.Status
+	14, // 24: dsp.DSP.StreamLevels:output_type -> dsp.LevelUpdate
+	15, // 25: dsp.DSP.StreamTuner:output_type -> dsp.TunerUpdate
+	15, // [15:26] is the sub-list for method output_type
+	4,  // [4:15] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_rpc_dsp_proto_init() }
+func file_rpc_dsp_proto_init() {
+	if File_rpc_dsp_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_rpc_dsp_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Empty); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Status); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InvokeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InvokeResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UnitTypes); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AddUnitRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UnitRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetBypassRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetDiscreteValueRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetNumericValueRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ChannelLevel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GainReduction); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Clip); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LevelUpdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_dsp_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TunerUpdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_rpc_dsp_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   17,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_rpc_dsp_proto_goTypes,
+		DependencyIndexes: file_rpc_dsp_proto_depIdxs,
+		MessageInfos:      file_rpc_dsp_proto_msgTypes,
+	}.Build()
+	File_rpc_dsp_proto = out.File
+	file_rpc_dsp_proto_rawDesc = nil
+	file_rpc_dsp_proto_goTypes = nil
+	file_rpc_dsp_proto_depIdxs = nil
+}
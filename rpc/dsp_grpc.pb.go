@@ -0,0 +1,542 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: rpc/dsp.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// DSPClient is the client API for DSP service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DSPClient interface {
+	// Calls an arbitrary endpoint of the v2 API.
+	Invoke(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (*InvokeResponse, error)
+	// Returns the types of units which may be added to a chain.
+	GetUnitTypes(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*UnitTypes, error)
+	// Adds a unit to the end of a chain.
+	AddUnit(ctx context.Context, in *AddUnitRequest, opts ...grpc.CallOption) (*Status, error)
+	// Removes a unit from a chain.
+	RemoveUnit(ctx context.Context, in *UnitRequest, opts ...grpc.CallOption) (*Status, error)
+	// Moves a unit one position towards the start of a chain.
+	MoveUp(ctx context.Context, in *UnitRequest, opts ...grpc.CallOption) (*Status, error)
+	// Moves a unit one position towards the end of a chain.
+	MoveDown(ctx context.Context, in *UnitRequest, opts ...grpc.CallOption) (*Status, error)
+	// Puts a unit into or out of bypass mode.
+	SetBypass(ctx context.Context, in *SetBypassRequest, opts ...grpc.CallOption) (*Status, error)
+	// Sets a discrete parameter of a unit.
+	SetDiscreteValue(ctx context.Context, in *SetDiscreteValueRequest, opts ...grpc.CallOption) (*Status, error)
+	// Sets a numeric parameter of a unit.
+	SetNumericValue(ctx context.Context, in *SetNumericValueRequest, opts ...grpc.CallOption) (*Status, error)
+	// Streams the results of the level meters until the call is cancelled.
+	StreamLevels(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (DSP_StreamLevelsClient, error)
+	// Streams the results of the tuner until the call is cancelled.
+	StreamTuner(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (DSP_StreamTunerClient, error)
+}
+
+type dSPClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDSPClient(cc grpc.ClientConnInterface) DSPClient {
+	return &dSPClient{cc}
+}
+
+func (c *dSPClient) Invoke(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (*InvokeResponse, error) {
+	out := new(InvokeResponse)
+	err := c.cc.Invoke(ctx, "/dsp.DSP/Invoke", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dSPClient) GetUnitTypes(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*UnitTypes, error) {
+	out := new(UnitTypes)
+	err := c.cc.Invoke(ctx, "/dsp.DSP/GetUnitTypes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dSPClient) AddUnit(ctx context.Context, in *AddUnitRequest, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/dsp.DSP/AddUnit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dSPClient) RemoveUnit(ctx context.Context, in *UnitRequest, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/dsp.DSP/RemoveUnit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dSPClient) MoveUp(ctx context.Context, in *UnitRequest, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/dsp.DSP/MoveUp", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dSPClient) MoveDown(ctx context.Context, in *UnitRequest, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/dsp.DSP/MoveDown", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dSPClient) SetBypass(ctx context.Context, in *SetBypassRequest, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/dsp.DSP/SetBypass", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dSPClient) SetDiscreteValue(ctx context.Context, in *SetDiscreteValueRequest, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/dsp.DSP/SetDiscreteValue", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dSPClient) SetNumericValue(ctx context.Context, in *SetNumericValueRequest, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/dsp.DSP/SetNumericValue", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dSPClient) StreamLevels(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (DSP_StreamLevelsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DSP_ServiceDesc.Streams[0], "/dsp.DSP/StreamLevels", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dSPStreamLevelsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DSP_StreamLevelsClient interface {
+	Recv() (*LevelUpdate, error)
+	grpc.ClientStream
+}
+
+type dSPStreamLevelsClient struct {
+	grpc.ClientStream
+}
+
+func (x *dSPStreamLevelsClient) Recv() (*LevelUpdate, error) {
+	m := new(LevelUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dSPClient) StreamTuner(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (DSP_StreamTunerClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DSP_ServiceDesc.Streams[1], "/dsp.DSP/StreamTuner", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dSPStreamTunerClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DSP_StreamTunerClient interface {
+	Recv() (*TunerUpdate, error)
+	grpc.ClientStream
+}
+
+type dSPStreamTunerClient struct {
+	grpc.ClientStream
+}
+
+func (x *dSPStreamTunerClient) Recv() (*TunerUpdate, error) {
+	m := new(TunerUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DSPServer is the server API for DSP service.
+// All implementations must embed UnimplementedDSPServer
+// for forward compatibility
+type DSPServer interface {
+	// Calls an arbitrary endpoint of the v2 API.
+	Invoke(context.Context, *InvokeRequest) (*InvokeResponse, error)
+	// Returns the types of units which may be added to a chain.
+	GetUnitTypes(context.Context, *Empty) (*UnitTypes, error)
+	// Adds a unit to the end of a chain.
+	AddUnit(context.Context, *AddUnitRequest) (*Status, error)
+	// Removes a unit from a chain.
+	RemoveUnit(context.Context, *UnitRequest) (*Status, error)
+	// Moves a unit one position towards the start of a chain.
+	MoveUp(context.Context, *UnitRequest) (*Status, error)
+	// Moves a unit one position towards the end of a chain.
+	MoveDown(context.Context, *UnitRequest) (*Status, error)
+	// Puts a unit into or out of bypass mode.
+	SetBypass(context.Context, *SetBypassRequest) (*Status, error)
+	// Sets a discrete parameter of a unit.
+	SetDiscreteValue(context.Context, *SetDiscreteValueRequest) (*Status, error)
+	// Sets a numeric parameter of a unit.
+	SetNumericValue(context.Context, *SetNumericValueRequest) (*Status, error)
+	// Streams the results of the level meters until the call is cancelled.
+	StreamLevels(*StreamRequest, DSP_StreamLevelsServer) error
+	// Streams the results of the tuner until the call is cancelled.
+	StreamTuner(*StreamRequest, DSP_StreamTunerServer) error
+	mustEmbedUnimplementedDSPServer()
+}
+
+// UnimplementedDSPServer must be embedded to have forward compatible implementations.
+type UnimplementedDSPServer struct {
+}
+
+func (UnimplementedDSPServer) Invoke(context.Context, *InvokeRequest) (*InvokeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Invoke not implemented")
+}
+func (UnimplementedDSPServer) GetUnitTypes(context.Context, *Empty) (*UnitTypes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUnitTypes not implemented")
+}
+func (UnimplementedDSPServer) AddUnit(context.Context, *AddUnitRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddUnit not implemented")
+}
+func (UnimplementedDSPServer) RemoveUnit(context.Context, *UnitRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveUnit not implemented")
+}
+func (UnimplementedDSPServer) MoveUp(context.Context, *UnitRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveUp not implemented")
+}
+func (UnimplementedDSPServer) MoveDown(context.Context, *UnitRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveDown not implemented")
+}
+func (UnimplementedDSPServer) SetBypass(context.Context, *SetBypassRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBypass not implemented")
+}
+func (UnimplementedDSPServer) SetDiscreteValue(context.Context, *SetDiscreteValueRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDiscreteValue not implemented")
+}
+func (UnimplementedDSPServer) SetNumericValue(context.Context, *SetNumericValueRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetNumericValue not implemented")
+}
+func (UnimplementedDSPServer) StreamLevels(*StreamRequest, DSP_StreamLevelsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLevels not implemented")
+}
+func (UnimplementedDSPServer) StreamTuner(*StreamRequest, DSP_StreamTunerServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTuner not implemented")
+}
+func (UnimplementedDSPServer) mustEmbedUnimplementedDSPServer() {}
+
+// UnsafeDSPServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DSPServer will
+// result in compilation errors.
+type UnsafeDSPServer interface {
+	mustEmbedUnimplementedDSPServer()
+}
+
+func RegisterDSPServer(s grpc.ServiceRegistrar, srv DSPServer) {
+	s.RegisterService(&DSP_ServiceDesc, srv)
+}
+
+func _DSP_Invoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InvokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DSPServer).Invoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dsp.DSP/Invoke",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DSPServer).Invoke(ctx, req.(*InvokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DSP_GetUnitTypes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DSPServer).GetUnitTypes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dsp.DSP/GetUnitTypes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DSPServer).GetUnitTypes(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DSP_AddUnit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddUnitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DSPServer).AddUnit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dsp.DSP/AddUnit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DSPServer).AddUnit(ctx, req.(*AddUnitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DSP_RemoveUnit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DSPServer).RemoveUnit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dsp.DSP/RemoveUnit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DSPServer).RemoveUnit(ctx, req.(*UnitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DSP_MoveUp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DSPServer).MoveUp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dsp.DSP/MoveUp",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DSPServer).MoveUp(ctx, req.(*UnitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DSP_MoveDown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DSPServer).MoveDown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dsp.DSP/MoveDown",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DSPServer).MoveDown(ctx, req.(*UnitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DSP_SetBypass_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBypassRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DSPServer).SetBypass(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dsp.DSP/SetBypass",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DSPServer).SetBypass(ctx, req.(*SetBypassRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DSP_SetDiscreteValue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDiscreteValueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DSPServer).SetDiscreteValue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dsp.DSP/SetDiscreteValue",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DSPServer).SetDiscreteValue(ctx, req.(*SetDiscreteValueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DSP_SetNumericValue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetNumericValueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DSPServer).SetNumericValue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dsp.DSP/SetNumericValue",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DSPServer).SetNumericValue(ctx, req.(*SetNumericValueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DSP_StreamLevels_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DSPServer).StreamLevels(m, &dSPStreamLevelsServer{stream})
+}
+
+type DSP_StreamLevelsServer interface {
+	Send(*LevelUpdate) error
+	grpc.ServerStream
+}
+
+type dSPStreamLevelsServer struct {
+	grpc.ServerStream
+}
+
+func (x *dSPStreamLevelsServer) Send(m *LevelUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DSP_StreamTuner_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DSPServer).StreamTuner(m, &dSPStreamTunerServer{stream})
+}
+
+type DSP_StreamTunerServer interface {
+	Send(*TunerUpdate) error
+	grpc.ServerStream
+}
+
+type dSPStreamTunerServer struct {
+	grpc.ServerStream
+}
+
+func (x *dSPStreamTunerServer) Send(m *TunerUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// DSP_ServiceDesc is the grpc.ServiceDesc for DSP service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DSP_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dsp.DSP",
+	HandlerType: (*DSPServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Invoke",
+			Handler:    _DSP_Invoke_Handler,
+		},
+		{
+			MethodName: "GetUnitTypes",
+			Handler:    _DSP_GetUnitTypes_Handler,
+		},
+		{
+			MethodName: "AddUnit",
+			Handler:    _DSP_AddUnit_Handler,
+		},
+		{
+			MethodName: "RemoveUnit",
+			Handler:    _DSP_RemoveUnit_Handler,
+		},
+		{
+			MethodName: "MoveUp",
+			Handler:    _DSP_MoveUp_Handler,
+		},
+		{
+			MethodName: "MoveDown",
+			Handler:    _DSP_MoveDown_Handler,
+		},
+		{
+			MethodName: "SetBypass",
+			Handler:    _DSP_SetBypass_Handler,
+		},
+		{
+			MethodName: "SetDiscreteValue",
+			Handler:    _DSP_SetDiscreteValue_Handler,
+		},
+		{
+			MethodName: "SetNumericValue",
+			Handler:    _DSP_SetNumericValue_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamLevels",
+			Handler:       _DSP_StreamLevels_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamTuner",
+			Handler:       _DSP_StreamTuner_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rpc/dsp.proto",
+}
@@ -0,0 +1,340 @@
+package signal
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/effects"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	COMPOSITE_SEPARATOR = "/"
+)
+
+/*
+ * Interface type for a composite unit, which holds a named sequence of units
+ * inside a signal chain of its own and appears as a single unit in the chain
+ * it is part of.
+ */
+type Composite interface {
+	Name() string
+	SetName(name string)
+	Units() Chain
+}
+
+/*
+ * Data structure representing a composite unit.
+ *
+ * The parameters of the units inside are exposed as parameters of the
+ * composite unit, prefixed by the index of the unit they belong to, like
+ * '0/gain'.
+ */
+type compositeStruct struct {
+	mutex sync.RWMutex
+	name  string
+	chain *chainStruct
+}
+
+/*
+ * Splits the name of a parameter of a composite unit into the index of the
+ * unit inside and the name of its parameter.
+ */
+func splitParameterName(name string) (int, string, error) {
+	idx := strings.Index(name, COMPOSITE_SEPARATOR)
+
+	/*
+	 * Check if the name is prefixed by a unit index.
+	 */
+	if idx < 0 {
+		return -1, "", fmt.Errorf("Composite unit has no parameter '%s'.", name)
+	} else {
+		prefix := name[:idx]
+		offset := idx + len(COMPOSITE_SEPARATOR)
+		paramName := name[offset:]
+		id64, err := strconv.ParseUint(prefix, 10, 32)
+
+		/*
+		 * Check if the unit index could be parsed.
+		 */
+		if err != nil {
+			return -1, "", fmt.Errorf("Composite unit has no parameter '%s'.", name)
+		} else {
+			id := int(id64)
+			return id, paramName, nil
+		}
+
+	}
+
+}
+
+/*
+ * Returns the name of this composite unit.
+ */
+func (this *compositeStruct) Name() string {
+	this.mutex.RLock()
+	name := this.name
+	this.mutex.RUnlock()
+	return name
+}
+
+/*
+ * Sets the name of this composite unit.
+ */
+func (this *compositeStruct) SetName(name string) {
+	this.mutex.Lock()
+	this.name = name
+	this.mutex.Unlock()
+}
+
+/*
+ * Returns the signal chain holding the units inside this composite unit.
+ */
+func (this *compositeStruct) Units() Chain {
+	return this.chain
+}
+
+/*
+ * Returns the parameters of all units inside this composite unit, prefixed by
+ * the index of the unit they belong to.
+ */
+func (this *compositeStruct) Parameters() []effects.Parameter {
+	inner := this.chain
+	numUnits := inner.Length()
+	result := []effects.Parameter{}
+
+	/*
+	 * Collect the parameters of each unit.
+	 */
+	for id := 0; id < numUnits; id++ {
+		params, err := inner.Parameters(id)
+
+		/*
+		 * The units may have changed in the meantime.
+		 */
+		if err == nil {
+
+			/*
+			 * Prefix each parameter by the index of its unit.
+			 */
+			for _, param := range params {
+				param.Name = fmt.Sprintf("%d%s%s", id, COMPOSITE_SEPARATOR, param.Name)
+				result = append(result, param)
+			}
+
+		}
+
+	}
+
+	return result
+}
+
+/*
+ * Passes a signal through the units inside this composite unit.
+ */
+func (this *compositeStruct) Process(in []float64, out []float64, sampleRate uint32) {
+	this.chain.Process(in, out, sampleRate)
+}
+
+/*
+ * Passes a stereo signal through the units inside this composite unit.
+ */
+func (this *compositeStruct) ProcessStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32) {
+	this.chain.ProcessStereo(inLeft, inRight, outLeft, outRight, sampleRate)
+}
+
+/*
+ * Returns the type of this unit.
+ */
+func (this *compositeStruct) Type() int {
+	return effects.UNIT_COMPOSITE
+}
+
+/*
+ * Sets a discrete value for a unit inside this composite unit.
+ */
+func (this *compositeStruct) SetDiscreteValue(name string, value string) error {
+	id, paramName, err := splitParameterName(name)
+
+	/*
+	 * Check if the parameter name could be split.
+	 */
+	if err != nil {
+		return err
+	} else {
+		err = this.chain.SetDiscreteValue(id, paramName, value)
+		return err
+	}
+
+}
+
+/*
+ * Retrieves a discrete value from a unit inside this composite unit.
+ */
+func (this *compositeStruct) GetDiscreteValue(name string) (string, error) {
+	id, paramName, err := splitParameterName(name)
+
+	/*
+	 * Check if the parameter name could be split.
+	 */
+	if err != nil {
+		return "", err
+	} else {
+		value, err := this.chain.GetDiscreteValue(id, paramName)
+		return value, err
+	}
+
+}
+
+/*
+ * Sets a numeric value for a unit inside this composite unit.
+ */
+func (this *compositeStruct) SetNumericValue(name string, value int32) error {
+	id, paramName, err := splitParameterName(name)
+
+	/*
+	 * Check if the parameter name could be split.
+	 */
+	if err != nil {
+		return err
+	} else {
+		err = this.chain.SetNumericValue(id, paramName, value)
+		return err
+	}
+
+}
+
+/*
+ * Retrieves a numeric value from a unit inside this composite unit.
+ */
+func (this *compositeStruct) GetNumericValue(name string) (int32, error) {
+	id, paramName, err := splitParameterName(name)
+
+	/*
+	 * Check if the parameter name could be split.
+	 */
+	if err != nil {
+		return 0, err
+	} else {
+		value, err := this.chain.GetNumericValue(id, paramName)
+		return value, err
+	}
+
+}
+
+/*
+ * Sets the tempo (in beats per minute) for the units inside this composite
+ * unit.
+ */
+func (this *compositeStruct) SetTempo(bpm uint32) {
+	this.chain.SetTempo(bpm)
+}
+
+/*
+ * Sets the time (in milliseconds) over which the units inside this composite
+ * unit ramp changes to their numeric parameters. A time of zero disables
+ * smoothing, like in the chain the composite unit is part of.
+ */
+func (this *compositeStruct) SetSmoothingTime(ms uint32) {
+	inner := this.chain
+	inner.mutex.Lock()
+	inner.smoothing = ms != 0
+
+	/*
+	 * Keep the previous time while smoothing is disabled.
+	 */
+	if ms != 0 {
+		inner.smoothingTime = ms
+	}
+
+	inner.publish()
+	inner.passSmoothingTime(ms)
+	inner.mutex.Unlock()
+}
+
+/*
+ * Sets the number of frames processed at once for the units inside this
+ * composite unit.
+ */
+func (this *compositeStruct) SetBlockSize(frames uint32) {
+	this.chain.SetBlockSize(frames)
+}
+
+/*
+ * Returns the latency (in samples) of the units inside this composite unit.
+ */
+func (this *compositeStruct) Latency(sampleRate uint32) uint32 {
+	latency := this.chain.Latency(sampleRate)
+	return latency
+}
+
+/*
+ * Creates a new, empty composite unit for this signal chain.
+ *
+ * The units inside process the same number of channels as this chain and
+ * share its impulse responses. Changes to their latency are passed on to
+ * this chain.
+ */
+func (this *chainStruct) createComposite() *compositeStruct {
+	slots := make([]slotStruct, 0)
+
+	/*
+	 * The signal chain holding the units inside.
+	 */
+	inner := chainStruct{
+		responses:     this.responses,
+		slots:         slots,
+		stereo:        this.stereo,
+		smoothing:     true,
+		smoothingTime: effects.SMOOTHING_TIME_DEFAULT,
+		spillover:     this.spillover,
+		parent:        this,
+	}
+
+	inner.publish()
+
+	/*
+	 * The new composite unit.
+	 */
+	composite := compositeStruct{
+		name:  "",
+		chain: &inner,
+	}
+
+	return &composite
+}
+
+/*
+ * Returns the composite unit at a certain position inside the signal chain.
+ */
+func (this *chainStruct) Composite(id int) (Composite, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return nil, fmt.Errorf("Cannot get composite unit: No unit %d.", id)
+	} else {
+		unit := slots[id].unit
+		this.mutex.RUnlock()
+		composite, isComposite := unit.(*compositeStruct)
+
+		/*
+		 * Check if the unit is a composite unit.
+		 */
+		if !isComposite {
+			return nil, fmt.Errorf("Unit %d is not a composite unit.", id)
+		} else {
+			return composite, nil
+		}
+
+	}
+
+}
@@ -3,16 +3,114 @@ package signal
 import (
 	"fmt"
 	"github.com/andrepxx/go-dsp-guitar/effects"
+	"github.com/andrepxx/go-dsp-guitar/fft"
 	"github.com/andrepxx/go-dsp-guitar/filter"
+	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+/*
+ * Global constants.
+ */
+const (
+	CLIP_LEVEL             = 1.0
+	GAIN_MAXIMUM           = 24
+	GAIN_MINIMUM           = -24
+	GAIN_NEUTRAL           = 0
+	UNITY_FACTOR           = 1.0
+	TIME_AVERAGE           = 0.1
+	RESPONSE_IMPULSE       = 0.01
+	RESPONSE_LENGTH        = 32768
+	RESPONSE_MAX_BLOCK     = 8192
+	SPILLOVER_TIME_MAXIMUM = 10000
+	TAILS_MAXIMUM          = 16
+	CROSSFADE_TIME         = 5
+	REMOVED_MAXIMUM        = 64
+)
+
+/*
+ * Data structure keeping track of the time spent processing a block.
+ *
+ * The average is only touched by the audio thread, which publishes it
+ * atomically (in nanoseconds) for others to read.
+ */
+type processingTimeStruct struct {
+	nanoseconds uint32
+	average     float64
+}
+
+/*
+ * Data structure holding the state of a slot which is shared by all snapshots
+ * of the slot.
+ *
+ * The clip indicator and the flag telling that the tail of a removed unit
+ * has rung out are accessed atomically. All other fields are only ever
+ * touched by the audio thread and hold the gain factors and bypass state the
+ * last block was processed with, so that changes can be smoothed, as well as
+ * the number of samples a unit still rings out for.
+ *
+ * The mix tells how far a unit is crossfaded in, from zero (unprocessed
+ * signal only) to one (processed signal only).
+ */
+type slotStateStruct struct {
+	clipped      int32
+	finished     int32
+	active       bool
+	spill        int
+	mix          float64
+	inputFactor  float64
+	outputFactor float64
+	time         processingTimeStruct
+}
+
 /*
  * Data structure representing a slot in a signal chain.
+ *
+ * The input trim is applied to the signal before it enters the unit, the
+ * output level to the signal the unit produces. Both are given in decibels.
+ * The label and note are chosen by the user to describe the unit.
+ *
+ * A slot which was removed from the chain stays in place, bypassed, until
+ * its unit is faded out.
  */
 type slotStruct struct {
-	unit   effects.Unit
-	bypass bool
+	unit         effects.Unit
+	unitRight    effects.Unit
+	bypass       bool
+	inputTrim    int32
+	inputFactor  float64
+	outputLevel  int32
+	outputFactor float64
+	label        string
+	note         string
+	removed      bool
+	state        *slotStateStruct
+}
+
+/*
+ * Data structure representing a slot which was removed from a signal chain
+ * and is faded out at the position it was removed from.
+ */
+type removedSlotStruct struct {
+	slot     slotStruct
+	position int
+}
+
+/*
+ * Data structure representing an immutable snapshot of a signal chain, as
+ * seen by the audio thread.
+ *
+ * The tails are slots of units which were removed from the chain, but still
+ * ring out.
+ */
+type chainSnapshotStruct struct {
+	slots        []slotStruct
+	tails        []slotStruct
+	compensation uint32
+	smoothing    bool
+	spillover    uint32
 }
 
 /*
@@ -21,6 +119,7 @@ type slotStruct struct {
 type Chain interface {
 	AppendUnit(unitType int) (int, error)
 	RemoveUnit(id int) error
+	Composite(id int) (Composite, error)
 	MoveUp(id int) error
 	MoveDown(id int) error
 	UnitType(id int) (int, error)
@@ -31,317 +130,2071 @@ type Chain interface {
 	SetNumericValue(id int, name string, value int32) error
 	GetNumericValue(id int, name string) (int32, error)
 	Parameters(id int) ([]effects.Parameter, error)
+	GainReduction(id int) (int32, bool, error)
+	FrequencyResponse(id int, frequencies []float64, sampleRate uint32) ([]complex128, error)
+	ChainResponse(frequencies []float64, sampleRate uint32) ([]complex128, error)
+	SetInputTrim(id int, value int32) error
+	GetInputTrim(id int) (int32, error)
+	SetOutputLevel(id int, value int32) error
+	GetOutputLevel(id int) (int32, error)
+	SetLabel(id int, label string) error
+	GetLabel(id int) (string, error)
+	SetNote(id int, note string) error
+	GetNote(id int) (string, error)
+	Clipped(id int) (bool, error)
+	Latency(sampleRate uint32) uint32
+	ProcessingTime() uint32
+	UnitProcessingTime(id int) (uint32, error)
+	SetCompensation(samples uint32)
+	Compensation() uint32
+	SetSmoothing(enabled bool)
+	Smoothing() bool
+	SetSmoothingTime(ms uint32) error
+	SmoothingTime() uint32
+	SetSpilloverTime(ms uint32) error
+	SpilloverTime() uint32
+	SetBlockSize(frames uint32)
+	UpdateImpulseResponses() error
+	SetTempo(bpm uint32)
 	Length() int
+	Stereo() bool
 	Process(in []float64, out []float64, sampleRate uint32)
+	ProcessStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32)
 }
 
 /*
  * Data structure representing a signal chain.
+ *
+ * The slots, compensation and smoothing setting are only modified while
+ * holding the mutex. After each modification, an immutable copy of them is
+ * published as a snapshot, which the audio thread picks up at the start of
+ * each block without locking. Buffers and delay lines belong to the audio
+ * thread.
+ *
+ * The latency of the units is cached together with the sample rate it was
+ * calculated for, so that the audio thread can query it without locking. It
+ * is accessed atomically and therefore comes first, so that it is aligned on
+ * 32-bit platforms.
+ *
+ * The chain holding the units inside a composite unit refers to the chain the
+ * composite unit is part of as its parent.
  */
 type chainStruct struct {
-	bufferIn  []float64
-	bufferOut []float64
-	responses filter.ImpulseResponses
-	mutex     sync.RWMutex
-	slots     []slotStruct
+	latency           uint64
+	latencyMutex      sync.Mutex
+	sampleRate        uint32
+	bufferIn          []float64
+	bufferOut         []float64
+	bufferInRight     []float64
+	bufferOutRight    []float64
+	bufferDry         []float64
+	bufferDryRight    []float64
+	responses         filter.ImpulseResponses
+	mutex             sync.RWMutex
+	slots             []slotStruct
+	removed           []removedSlotStruct
+	tails             []slotStruct
+	stereo            bool
+	tempo             uint32
+	compensation      uint32
+	smoothing         bool
+	smoothingTime     uint32
+	spillover         uint32
+	blockSize         uint32
+	snapshot          atomic.Value
+	delayLine         []float64
+	delayLineRight    []float64
+	delayLinePosition int
+	time              processingTimeStruct
+	parent            *chainStruct
 }
 
 /*
- * Appends a new effects unit to the end of the signal chain.
+ * Records the time it took to process a block, averaging over several blocks
+ * so that the value does not jitter.
+ *
+ * This is called from the audio thread.
  */
-func (this *chainStruct) AppendUnit(unitType int) (int, error) {
-	unit := effects.CreateUnit(unitType)
+func (this *processingTimeStruct) record(elapsed time.Duration) {
+	elapsedFloat := float64(elapsed)
+	this.average += TIME_AVERAGE * (elapsedFloat - this.average)
+	average := math.Round(this.average)
 
 	/*
-	 * Check whether unit was successfully created.
+	 * Make sure the average fits into the published value.
 	 */
-	if unit == nil {
-		return -1, fmt.Errorf("%s", "Failed to create effects unit.")
-	} else {
-
-		/*
-		 * If unit is a power amp, prepare it.
-		 */
-		if unitType == effects.UNIT_POWERAMP {
-			effects.PreparePowerAmp(unit, this.responses)
-		}
+	if average > math.MaxUint32 {
+		average = math.MaxUint32
+	}
 
-		/*
-		 * Create new slot in the signal chain.
-		 */
-		slot := slotStruct{
-			unit:   unit,
-			bypass: true,
-		}
+	nanoseconds := uint32(average)
+	atomic.StoreUint32(&this.nanoseconds, nanoseconds)
+}
 
-		this.mutex.Lock()
-		slots := this.slots
-		nPre := len(slots)
-		slots = append(slots, slot)
-		this.slots = slots
-		this.mutex.Unlock()
-		return nPre, nil
-	}
+/*
+ * Returns the average time (in nanoseconds) it took to process a block.
+ */
+func (this *processingTimeStruct) get() uint32 {
+	nanoseconds := atomic.LoadUint32(&this.nanoseconds)
+	return nanoseconds
+}
 
+/*
+ * Converts a gain (in decibels) into a linear factor.
+ */
+func decibelsToFactor(gain int32) float64 {
+	gainFloat := float64(gain)
+	exponent := gainFloat / 20.0
+	factor := math.Pow(10.0, exponent)
+	return factor
 }
 
 /*
- * Removes an effects unit from the signal chain.
+ * Multiplies all samples in a buffer by a factor, unless it is unity.
  */
-func (this *chainStruct) RemoveUnit(id int) error {
-	this.mutex.Lock()
-	slots := this.slots
-	n := len(slots)
+func applyGain(buffer []float64, factor float64) {
 
 	/*
-	 * Check if index is out of range.
+	 * Only touch the buffer if the gain changes the signal.
 	 */
-	if id < 0 || id >= n {
-		this.mutex.Unlock()
-		return fmt.Errorf("Cannot remove unit %d.", id)
-	} else {
-		idInc := id + 1
-		slots = append(slots[:id], slots[idInc:]...)
-		this.slots = slots
-		this.mutex.Unlock()
-		return nil
+	if factor != UNITY_FACTOR {
+
+		/*
+		 * Scale each sample.
+		 */
+		for i, sample := range buffer {
+			buffer[i] = factor * sample
+		}
+
 	}
 
 }
 
 /*
- * Moves an effects unit up (towards the front of) the signal chain.
+ * Multiplies the samples in a buffer by a factor which changes linearly from
+ * one value to another across the buffer.
  */
-func (this *chainStruct) MoveUp(id int) error {
-	this.mutex.Lock()
-	slots := this.slots
-	n := len(slots)
+func applyGainRamp(buffer []float64, from float64, to float64) {
 
 	/*
-	 * Check if index is out of range.
+	 * Only ramp the gain if it actually changes.
 	 */
-	if id <= 0 || id >= n {
-		this.mutex.Unlock()
-		return fmt.Errorf("Cannot move unit %d up.", id)
+	if from == to {
+		applyGain(buffer, to)
 	} else {
-		idDec := id - 1
-		slots[id], slots[idDec] = slots[idDec], slots[id]
-		this.mutex.Unlock()
-		return nil
+		n := len(buffer)
+		nFloat := float64(n)
+		delta := (to - from) / nFloat
+
+		/*
+		 * Scale each sample.
+		 */
+		for i, sample := range buffer {
+			iInc := i + 1
+			iFloat := float64(iInc)
+			factor := from + (iFloat * delta)
+			buffer[i] = factor * sample
+		}
+
 	}
 
 }
 
 /*
- * Moves an effects unit down (towards the end of) the signal chain.
+ * Crossfades between an unprocessed and a processed signal, moving the mix
+ * towards a target by a certain step per sample, and returns the mix reached
+ * at the end of the buffer. The result is written to the buffer holding the
+ * processed signal.
+ *
+ * The crossfade keeps the power of uncorrelated signals constant, so that the
+ * level does not dip half way through.
  */
-func (this *chainStruct) MoveDown(id int) error {
-	this.mutex.Lock()
-	slots := this.slots
-	n := len(slots)
-	nDec := n - 1
+func crossfade(dry []float64, wet []float64, mix float64, target float64, step float64) float64 {
 
 	/*
-	 * Check if index is out of range.
+	 * Mix each pair of samples.
 	 */
-	if id < 0 || id >= nDec {
-		this.mutex.Unlock()
-		return fmt.Errorf("Cannot move unit %d down.", id)
-	} else {
-		idInc := id + 1
-		slots[id], slots[idInc] = slots[idInc], slots[id]
-		this.mutex.Unlock()
-		return nil
+	for i, sample := range wet {
+
+		/*
+		 * Move the mix towards its target without overshooting it.
+		 */
+		if mix < target {
+			mix = math.Min(mix+step, target)
+		} else if mix > target {
+			mix = math.Max(mix-step, target)
+		}
+
+		angle := 0.5 * math.Pi * mix
+		weightDry := math.Cos(angle)
+		weightWet := math.Sin(angle)
+		wet[i] = (weightDry * dry[i]) + (weightWet * sample)
 	}
 
+	return mix
 }
 
 /*
- * Returns the type of an effects unit.
+ * Returns whether a unit keeps producing a signal after its input fell
+ * silent. A composite unit does so if any unit inside does.
+ *
+ * This may be called from the audio thread and does not lock.
  */
-func (this *chainStruct) UnitType(id int) (int, error) {
-	this.mutex.RLock()
-	slots := this.slots
-	n := len(slots)
+func hasTail(unit effects.Unit) bool {
+	composite, isComposite := unit.(*compositeStruct)
 
 	/*
-	 * Check if index is out of range.
+	 * Check if this is a composite unit.
 	 */
-	if id < 0 || id >= n {
-		this.mutex.RUnlock()
-		return -1, fmt.Errorf("Cannot get unit type: No unit %d.", id)
-	} else {
-		unit := slots[id].unit
+	if !isComposite {
 		unitType := unit.Type()
-		this.mutex.RUnlock()
-		return unitType, nil
+		result := effects.HasTail(unitType)
+		return result
+	} else {
+		snapshot := composite.chain.processingSnapshot()
+		result := false
+
+		/*
+		 * Check the units inside.
+		 */
+		for _, slot := range snapshot.slots {
+
+			/*
+			 * Check if the unit has a tail.
+			 */
+			if hasTail(slot.unit) {
+				result = true
+			}
+
+		}
+
+		return result
 	}
 
 }
 
 /*
- * Enables or disables bypass of an effects unit inside the signal chain.
+ * Lets the unit in a slot ring out, adding what it produces from silence to
+ * the signal in a buffer.
+ *
+ * When the unit was active until now and changes are smoothed, its input is
+ * faded out across the buffer instead, while the signal passing by the unit is
+ * faded in. The other buffers are used to feed the unit and take its output.
  */
-func (this *chainStruct) SetBypass(id int, bypass bool) error {
-	this.mutex.Lock()
-	slots := this.slots
-	n := len(slots)
+func spillMono(slot slotStruct, buffer []float64, bufferUnitIn []float64, bufferUnitOut []float64, fade bool, sampleRate uint32) {
 
 	/*
-	 * Check if index is out of range.
+	 * Check whether the unit should be faded out.
 	 */
-	if id < 0 || id >= n {
-		this.mutex.Unlock()
-		action := "disable"
+	if fade {
+		copy(bufferUnitIn, buffer)
+		applyGainRamp(bufferUnitIn, slot.inputFactor, 0.0)
+		applyGainRamp(buffer, 0.0, UNITY_FACTOR)
+	} else {
 
 		/*
-		 * Check whether bypass should be enabled.
+		 * Feed the unit with silence.
 		 */
-		if bypass {
-			action = "enable"
+		for i := range bufferUnitIn {
+			bufferUnitIn[i] = 0.0
 		}
 
-		return fmt.Errorf("Cannot %s bypass: No unit %d.", action, id)
-	} else {
-		slots[id].bypass = bypass
-		this.mutex.Unlock()
-		return nil
 	}
 
-}
-
-/*
- * Retrieves whether an effects unit inside the signal chain is in bypass mode or not.
- */
-func (this *chainStruct) GetBypass(id int) (bool, error) {
-	this.mutex.RLock()
-	slots := this.slots
-	n := len(slots)
+	unit := slot.unit
+	unit.Process(bufferUnitIn, bufferUnitOut, sampleRate)
+	applyGain(bufferUnitOut, slot.outputFactor)
 
 	/*
-	 * Check if index is out of range.
+	 * Add the tail to the signal.
 	 */
-	if id < 0 || id >= n {
-		this.mutex.RUnlock()
-		return false, fmt.Errorf("Cannot get bypass value: No unit %d.", id)
-	} else {
-		bypass := slots[id].bypass
-		this.mutex.RUnlock()
-		return bypass, nil
+	for i, sample := range bufferUnitOut {
+		buffer[i] += sample
 	}
 
 }
 
 /*
- * Sets a discrete value for an effects unit inside the signal chain.
+ * Lets the unit in a slot of a stereo chain ring out, adding what it produces
+ * from silence to the signal in a pair of buffers.
+ *
+ * This works like spillMono, but on both channels.
  */
-func (this *chainStruct) SetDiscreteValue(id int, name string, value string) error {
-	this.mutex.RLock()
-	slots := this.slots
-	n := len(slots)
+func spillStereo(slot slotStruct, bufferLeft []float64, bufferRight []float64, unitInLeft []float64, unitInRight []float64, unitOutLeft []float64, unitOutRight []float64, fade bool, sampleRate uint32) {
 
 	/*
-	 * Check if index is out of range.
+	 * Check whether the unit should be faded out.
 	 */
-	if id < 0 || id >= n {
-		this.mutex.RUnlock()
-		return fmt.Errorf("Cannot set discrete value: No unit %d.", id)
+	if fade {
+		copy(unitInLeft, bufferLeft)
+		copy(unitInRight, bufferRight)
+		applyGainRamp(unitInLeft, slot.inputFactor, 0.0)
+		applyGainRamp(unitInRight, slot.inputFactor, 0.0)
+		applyGainRamp(bufferLeft, 0.0, UNITY_FACTOR)
+		applyGainRamp(bufferRight, 0.0, UNITY_FACTOR)
 	} else {
-		unit := slots[id].unit
-		this.mutex.RUnlock()
-		err := unit.SetDiscreteValue(name, value)
-		return err
-	}
 
-}
+		/*
+		 * Feed the unit with silence.
+		 */
+		for i := range unitInLeft {
+			unitInLeft[i] = 0.0
+			unitInRight[i] = 0.0
+		}
 
-/*
- * Retrieves a discrete value from an effects unit inside the signal chain.
- */
-func (this *chainStruct) GetDiscreteValue(id int, name string) (string, error) {
-	this.mutex.RLock()
-	slots := this.slots
-	n := len(slots)
+	}
+
+	unit := slot.unit
+	stereoUnit, isStereoUnit := unit.(effects.StereoUnit)
 
 	/*
-	 * Check if index is out of range.
+	 * Stereo units process both channels at once, other units have a
+	 * separate instance for the right channel.
 	 */
-	if id < 0 || id >= n {
-		this.mutex.RUnlock()
-		return "", fmt.Errorf("Cannot get discrete value: No unit %d.", id)
+	if isStereoUnit {
+		stereoUnit.ProcessStereo(unitInLeft, unitInRight, unitOutLeft, unitOutRight, sampleRate)
 	} else {
-		unit := slots[id].unit
-		this.mutex.RUnlock()
-		value, err := unit.GetDiscreteValue(name)
-		return value, err
+		unit.Process(unitInLeft, unitOutLeft, sampleRate)
+		slot.unitRight.Process(unitInRight, unitOutRight, sampleRate)
+	}
+
+	applyGain(unitOutLeft, slot.outputFactor)
+	applyGain(unitOutRight, slot.outputFactor)
+
+	/*
+	 * Add the tail to the signal.
+	 */
+	for i, sample := range unitOutLeft {
+		bufferLeft[i] += sample
+		bufferRight[i] += unitOutRight[i]
 	}
 
 }
 
 /*
- * Sets a numeric value for an effects unit inside the signal chain.
+ * Checks whether any sample in a buffer exceeds full scale.
  */
-func (this *chainStruct) SetNumericValue(id int, name string, value int32) error {
-	this.mutex.RLock()
-	slots := this.slots
-	n := len(slots)
+func exceedsClipLevel(buffer []float64) bool {
 
 	/*
-	 * Check if index is out of range.
+	 * Check each sample.
 	 */
-	if id < 0 || id >= n {
-		return fmt.Errorf("Cannot set numeric value: No unit %d.", id)
-	} else {
-		unit := slots[id].unit
-		this.mutex.RUnlock()
-		err := unit.SetNumericValue(name, value)
-		return err
+	for _, sample := range buffer {
+		abs := math.Abs(sample)
+
+		/*
+		 * Check if sample exceeds full scale.
+		 */
+		if abs > CLIP_LEVEL {
+			return true
+		}
+
 	}
 
+	return false
 }
 
 /*
- * Retrieves a numeric value from an effects unit inside the signal chain.
+ * Passes the samples in a buffer through a delay line, starting at a certain
+ * position inside the delay line, and returns the position after the last
+ * sample.
  */
-func (this *chainStruct) GetNumericValue(id int, name string) (int32, error) {
-	this.mutex.RLock()
-	slots := this.slots
-	n := len(slots)
+func delaySignal(buffer []float64, delayLine []float64, position int) int {
+	n := len(delayLine)
 
 	/*
-	 * Check if index is out of range.
+	 * Only delay the signal if there is a delay line.
 	 */
-	if id < 0 || id >= n {
-		return 0, fmt.Errorf("Cannot get numeric value: No unit %d.", id)
-	} else {
-		unit := slots[id].unit
-		this.mutex.RUnlock()
-		value, err := unit.GetNumericValue(name)
-		return value, err
+	if n > 0 {
+
+		/*
+		 * Exchange each sample with the one in the delay line.
+		 */
+		for i, sample := range buffer {
+			buffer[i] = delayLine[position]
+			delayLine[position] = sample
+			position++
+
+			/*
+			 * Wrap around at the end of the delay line.
+			 */
+			if position >= n {
+				position = 0
+			}
+
+		}
+
 	}
 
+	return position
 }
 
 /*
- * Returns the parameters of an effects unit inside a signal chain.
+ * Publishes a snapshot of the slots, tails, compensation, smoothing and
+ * spillover setting to the audio thread.
+ *
+ * Slots which were removed are placed where they were removed from until
+ * their units are faded out. Tails which have rung out are dropped. If too
+ * many units are faded out or ring out at once, the oldest ones are cut.
+ *
+ * The caller must hold the mutex for writing.
  */
-func (this *chainStruct) Parameters(id int) ([]effects.Parameter, error) {
-	this.mutex.RLock()
-	slots := this.slots
-	n := len(slots)
+func (this *chainStruct) publish() {
+	removed := []removedSlotStruct{}
 
 	/*
-	 * Check if index is out of range.
+	 * Keep the removed slots which are still faded out.
 	 */
-	if id < 0 || id >= n {
-		return nil, fmt.Errorf("Cannot get parameters: No unit %d.", id)
+	for _, entry := range this.removed {
+		state := entry.slot.state
+		finished := atomic.LoadInt32(&state.finished)
+
+		/*
+		 * Check if the unit has faded out.
+		 */
+		if finished == 0 {
+			removed = append(removed, entry)
+		}
+
+	}
+
+	numRemoved := len(removed)
+
+	/*
+	 * Cut the oldest removed slots if there are too many.
+	 */
+	if numRemoved > REMOVED_MAXIMUM {
+		offset := numRemoved - REMOVED_MAXIMUM
+		removed = removed[offset:]
+		numRemoved = REMOVED_MAXIMUM
+	}
+
+	this.removed = removed
+	slots := this.slots
+	n := len(slots)
+	capacity := n + numRemoved
+	slotsCopy := make([]slotStruct, 0, capacity)
+
+	/*
+	 * Place the removed slots in front of the slot which followed them.
+	 * Slots removed later were in front of those removed earlier.
+	 */
+	for position := 0; position <= n; position++ {
+
+		/*
+		 * Insert the slots removed at this position.
+		 */
+		for i := numRemoved - 1; i >= 0; i-- {
+			entry := removed[i]
+
+			/*
+			 * Slots removed beyond the end of the chain go last.
+			 */
+			if (entry.position == position) || ((position == n) && (entry.position > n)) {
+				slotsCopy = append(slotsCopy, entry.slot)
+			}
+
+		}
+
+		/*
+		 * Append the slot at this position.
+		 */
+		if position < n {
+			slot := slots[position]
+			slotsCopy = append(slotsCopy, slot)
+		}
+
+	}
+
+	tails := []slotStruct{}
+
+	/*
+	 * Keep the tails which still ring out.
+	 */
+	for _, tail := range this.tails {
+		state := tail.state
+		finished := atomic.LoadInt32(&state.finished)
+
+		/*
+		 * Check if the tail has rung out.
+		 */
+		if finished == 0 {
+			tails = append(tails, tail)
+		}
+
+	}
+
+	numTails := len(tails)
+
+	/*
+	 * Cut the oldest tails if there are too many.
+	 */
+	if numTails > TAILS_MAXIMUM {
+		offset := numTails - TAILS_MAXIMUM
+		tails = tails[offset:]
+	}
+
+	this.tails = tails
+
+	/*
+	 * The new snapshot.
+	 */
+	snapshot := chainSnapshotStruct{
+		slots:        slotsCopy,
+		tails:        tails,
+		compensation: this.compensation,
+		smoothing:    this.smoothing,
+		spillover:    this.spillover,
+	}
+
+	this.snapshot.Store(&snapshot)
+}
+
+/*
+ * Returns the most recently published snapshot of this signal chain without
+ * locking.
+ */
+func (this *chainStruct) processingSnapshot() *chainSnapshotStruct {
+	value := this.snapshot.Load()
+	snapshot, _ := value.(*chainSnapshotStruct)
+	return snapshot
+}
+
+/*
+ * Creates a new effects unit and prepares it if it depends on impulse responses.
+ */
+func (this *chainStruct) createUnit(unitType int) (effects.Unit, error) {
+	unit := effects.Unit(nil)
+
+	/*
+	 * Composite units hold a signal chain, so they are created here.
+	 */
+	if unitType == effects.UNIT_COMPOSITE {
+		unit = this.createComposite()
+	} else {
+		unit = effects.CreateUnit(unitType)
+	}
+
+	/*
+	 * Check whether unit was successfully created.
+	 */
+	if unit == nil {
+		return nil, fmt.Errorf("%s", "Failed to create effects unit.")
+	} else {
+
+		/*
+		 * If unit depends on impulse responses, prepare it.
+		 */
+		switch unitType {
+		case effects.UNIT_POWERAMP:
+			effects.PreparePowerAmp(unit, this.responses)
+		case effects.UNIT_CONVOLUTION_REVERB:
+			effects.PrepareConvolutionReverb(unit, this.responses)
+		}
+
+		tempoUnit, isTempoUnit := unit.(effects.TempoUnit)
+
+		/*
+		 * If unit synchronizes to a tempo, pass it the current one.
+		 */
+		if isTempoUnit {
+			this.mutex.RLock()
+			tempo := this.tempo
+			this.mutex.RUnlock()
+			tempoUnit.SetTempo(tempo)
+		}
+
+		smoothingUnit, isSmoothingUnit := unit.(effects.SmoothingUnit)
+
+		/*
+		 * If unit ramps parameter changes, pass it the current ramp time.
+		 */
+		if isSmoothingUnit {
+			this.mutex.RLock()
+			ms := this.effectiveSmoothingTime()
+			this.mutex.RUnlock()
+			smoothingUnit.SetSmoothingTime(ms)
+		}
+
+		blockSizeUnit, isBlockSizeUnit := unit.(effects.BlockSizeUnit)
+
+		/*
+		 * If unit prepares filters, pass it the current block size.
+		 */
+		if isBlockSizeUnit {
+			this.mutex.RLock()
+			frames := this.blockSize
+			this.mutex.RUnlock()
+			blockSizeUnit.SetBlockSize(frames)
+		}
+
+		return unit, nil
+	}
+
+}
+
+/*
+ * Appends a new effects unit to the end of the signal chain.
+ */
+func (this *chainStruct) AppendUnit(unitType int) (int, error) {
+	unit, err := this.createUnit(unitType)
+
+	/*
+	 * Check whether unit was successfully created.
+	 */
+	if err != nil {
+		return -1, err
+	} else {
+		unitRight := effects.Unit(nil)
+		_, isStereoUnit := unit.(effects.StereoUnit)
+
+		/*
+		 * A unit which cannot process stereo signals needs a second
+		 * instance for the right channel of a stereo chain.
+		 */
+		if this.stereo && !isStereoUnit {
+			unitRight, err = this.createUnit(unitType)
+
+			/*
+			 * Check whether unit was successfully created.
+			 */
+			if err != nil {
+				return -1, err
+			}
+
+		}
+
+		/*
+		 * State of the new slot.
+		 */
+		state := slotStateStruct{
+			clipped:      0,
+			active:       false,
+			inputFactor:  UNITY_FACTOR,
+			outputFactor: UNITY_FACTOR,
+		}
+
+		/*
+		 * Create new slot in the signal chain.
+		 */
+		slot := slotStruct{
+			unit:         unit,
+			unitRight:    unitRight,
+			bypass:       true,
+			inputTrim:    GAIN_NEUTRAL,
+			inputFactor:  UNITY_FACTOR,
+			outputLevel:  GAIN_NEUTRAL,
+			outputFactor: UNITY_FACTOR,
+			state:        &state,
+		}
+
+		this.mutex.Lock()
+		slots := this.slots
+		nPre := len(slots)
+		slots = append(slots, slot)
+		this.slots = slots
+		this.publish()
+		this.mutex.Unlock()
+		this.updateLatency()
+		return nPre, nil
+	}
+
+}
+
+/*
+ * Removes an effects unit from the signal chain.
+ */
+func (this *chainStruct) RemoveUnit(id int) error {
+	this.mutex.Lock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.Unlock()
+		return fmt.Errorf("Cannot remove unit %d.", id)
+	} else {
+		slot := slots[id]
+		idInc := id + 1
+		slots = append(slots[:id], slots[idInc:]...)
+		this.slots = slots
+
+		/*
+		 * Slots removed behind this one move up by one position.
+		 */
+		for i, entry := range this.removed {
+
+			/*
+			 * Check if the slot was removed behind this one.
+			 */
+			if entry.position > id {
+				this.removed[i].position = entry.position - 1
+			}
+
+		}
+
+		/*
+		 * Units with a tail ring out after they were removed, other
+		 * units are faded out in place.
+		 */
+		if (this.spillover > 0) && hasTail(slot.unit) {
+			this.tails = append(this.tails, slot)
+		} else if this.smoothing {
+			slot.bypass = true
+			slot.removed = true
+
+			/*
+			 * The removed slot.
+			 */
+			entry := removedSlotStruct{
+				slot:     slot,
+				position: id,
+			}
+
+			this.removed = append(this.removed, entry)
+		}
+
+		this.publish()
+		this.mutex.Unlock()
+		this.updateLatency()
+		return nil
+	}
+
+}
+
+/*
+ * Moves an effects unit up (towards the front of) the signal chain.
+ */
+func (this *chainStruct) MoveUp(id int) error {
+	this.mutex.Lock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id <= 0 || id >= n {
+		this.mutex.Unlock()
+		return fmt.Errorf("Cannot move unit %d up.", id)
+	} else {
+		idDec := id - 1
+		slots[id], slots[idDec] = slots[idDec], slots[id]
+		this.publish()
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Moves an effects unit down (towards the end of) the signal chain.
+ */
+func (this *chainStruct) MoveDown(id int) error {
+	this.mutex.Lock()
+	slots := this.slots
+	n := len(slots)
+	nDec := n - 1
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= nDec {
+		this.mutex.Unlock()
+		return fmt.Errorf("Cannot move unit %d down.", id)
+	} else {
+		idInc := id + 1
+		slots[id], slots[idInc] = slots[idInc], slots[id]
+		this.publish()
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Returns the type of an effects unit.
+ */
+func (this *chainStruct) UnitType(id int) (int, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return -1, fmt.Errorf("Cannot get unit type: No unit %d.", id)
 	} else {
 		unit := slots[id].unit
+		unitType := unit.Type()
 		this.mutex.RUnlock()
-		params := unit.Parameters()
-		return params, nil
+		return unitType, nil
+	}
+
+}
+
+/*
+ * Enables or disables bypass of an effects unit inside the signal chain.
+ */
+func (this *chainStruct) SetBypass(id int, bypass bool) error {
+	this.mutex.Lock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.Unlock()
+		action := "disable"
+
+		/*
+		 * Check whether bypass should be enabled.
+		 */
+		if bypass {
+			action = "enable"
+		}
+
+		return fmt.Errorf("Cannot %s bypass: No unit %d.", action, id)
+	} else {
+		slots[id].bypass = bypass
+		this.publish()
+		this.mutex.Unlock()
+		this.updateLatency()
+		return nil
+	}
+
+}
+
+/*
+ * Retrieves whether an effects unit inside the signal chain is in bypass mode or not.
+ */
+func (this *chainStruct) GetBypass(id int) (bool, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return false, fmt.Errorf("Cannot get bypass value: No unit %d.", id)
+	} else {
+		bypass := slots[id].bypass
+		this.mutex.RUnlock()
+		return bypass, nil
+	}
+
+}
+
+/*
+ * Sets a discrete value for an effects unit inside the signal chain.
+ */
+func (this *chainStruct) SetDiscreteValue(id int, name string, value string) error {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return fmt.Errorf("Cannot set discrete value: No unit %d.", id)
+	} else {
+		unit := slots[id].unit
+		unitRight := slots[id].unitRight
+		this.mutex.RUnlock()
+		err := unit.SetDiscreteValue(name, value)
+
+		/*
+		 * Keep the unit for the right channel in sync.
+		 */
+		if err == nil && unitRight != nil {
+			err = unitRight.SetDiscreteValue(name, value)
+		}
+
+		this.updateLatency()
+		return err
+	}
+
+}
+
+/*
+ * Retrieves a discrete value from an effects unit inside the signal chain.
+ */
+func (this *chainStruct) GetDiscreteValue(id int, name string) (string, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return "", fmt.Errorf("Cannot get discrete value: No unit %d.", id)
+	} else {
+		unit := slots[id].unit
+		this.mutex.RUnlock()
+		value, err := unit.GetDiscreteValue(name)
+		return value, err
+	}
+
+}
+
+/*
+ * Sets a numeric value for an effects unit inside the signal chain.
+ */
+func (this *chainStruct) SetNumericValue(id int, name string, value int32) error {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return fmt.Errorf("Cannot set numeric value: No unit %d.", id)
+	} else {
+		unit := slots[id].unit
+		unitRight := slots[id].unitRight
+		this.mutex.RUnlock()
+		err := unit.SetNumericValue(name, value)
+
+		/*
+		 * Keep the unit for the right channel in sync.
+		 */
+		if err == nil && unitRight != nil {
+			err = unitRight.SetNumericValue(name, value)
+		}
+
+		this.updateLatency()
+		return err
+	}
+
+}
+
+/*
+ * Retrieves a numeric value from an effects unit inside the signal chain.
+ */
+func (this *chainStruct) GetNumericValue(id int, name string) (int32, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return 0, fmt.Errorf("Cannot get numeric value: No unit %d.", id)
+	} else {
+		unit := slots[id].unit
+		this.mutex.RUnlock()
+		value, err := unit.GetNumericValue(name)
+		return value, err
+	}
+
+}
+
+/*
+ * Returns the parameters of an effects unit inside a signal chain.
+ */
+func (this *chainStruct) Parameters(id int) ([]effects.Parameter, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return nil, fmt.Errorf("Cannot get parameters: No unit %d.", id)
+	} else {
+		unit := slots[id].unit
+		this.mutex.RUnlock()
+		params := unit.Parameters()
+		return params, nil
+	}
+
+}
+
+/*
+ * Returns the gain reduction (in decibels) an effects unit inside a signal
+ * chain applies and whether the unit reports its gain reduction at all.
+ *
+ * In a stereo chain, the larger gain reduction of both channels is returned.
+ */
+func (this *chainStruct) GainReduction(id int) (int32, bool, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return 0, false, fmt.Errorf("Cannot get gain reduction: No unit %d.", id)
+	} else {
+		slot := slots[id]
+		this.mutex.RUnlock()
+		units := []effects.Unit{slot.unit, slot.unitRight}
+		reduction := int32(0)
+		metering := false
+
+		/*
+		 * Query the units for both channels.
+		 */
+		for _, unit := range units {
+			meteringUnit, isMeteringUnit := unit.(effects.MeteringUnit)
+
+			/*
+			 * Check if unit reports its gain reduction.
+			 */
+			if isMeteringUnit {
+				value := meteringUnit.GainReduction()
+				metering = true
+
+				/*
+				 * Keep the larger gain reduction.
+				 */
+				if value > reduction {
+					reduction = value
+				}
+
+			}
+
+		}
+
+		return reduction, metering, nil
+	}
+
+}
+
+/*
+ * Measures the complex frequency response of an effects unit at certain
+ * frequencies.
+ *
+ * A fresh unit of the same type and with the same parameters is created, so
+ * that the unit processing the signal is not disturbed. An impulse is passed
+ * through it and the spectrum of the resulting impulse response is
+ * interpolated at each frequency. For units which are not linear, this is
+ * only an approximation, which holds for small signals.
+ */
+func (this *chainStruct) measureResponse(unit effects.Unit, frequencies []float64, sampleRate uint32) ([]complex128, error) {
+	unitType := unit.Type()
+	probe, err := this.createUnit(unitType)
+
+	/*
+	 * Check whether unit was successfully created.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, fmt.Errorf("Failed to measure frequency response: %s", msg)
+	} else {
+		params := unit.Parameters()
+
+		/*
+		 * Copy the value of each parameter.
+		 */
+		for _, param := range params {
+			name := param.Name
+
+			/*
+			 * Set the value according to the type of the parameter.
+			 */
+			switch param.Type {
+			case effects.PARAMETER_TYPE_DISCRETE:
+				idx := param.DiscreteValueIndex
+
+				/*
+				 * Check if a value is selected.
+				 */
+				if idx >= 0 && idx < len(param.DiscreteValues) {
+					value := param.DiscreteValues[idx]
+					err = probe.SetDiscreteValue(name, value)
+				}
+
+			case effects.PARAMETER_TYPE_NUMERIC:
+				value := param.NumericValue
+				err = probe.SetNumericValue(name, value)
+			}
+
+			/*
+			 * Check if value was successfully copied.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to measure frequency response: %s", msg)
+			}
+
+		}
+
+		impulse := make([]float64, RESPONSE_LENGTH)
+		impulse[0] = RESPONSE_IMPULSE
+		response := make([]float64, RESPONSE_LENGTH)
+		this.mutex.RLock()
+		blockSize := int(this.blockSize)
+		this.mutex.RUnlock()
+
+		/*
+		 * Process in blocks no larger than the audio thread would.
+		 */
+		if blockSize <= 0 || blockSize > RESPONSE_MAX_BLOCK {
+			blockSize = RESPONSE_MAX_BLOCK
+		}
+
+		/*
+		 * Pass the impulse through the unit.
+		 */
+		for offset := 0; offset < RESPONSE_LENGTH; offset += blockSize {
+			end := offset + blockSize
+
+			/*
+			 * Make sure the last block does not exceed the buffer.
+			 */
+			if end > RESPONSE_LENGTH {
+				end = RESPONSE_LENGTH
+			}
+
+			probe.Process(impulse[offset:end], response[offset:end], sampleRate)
+		}
+
+		spectrum := make([]complex128, RESPONSE_LENGTH)
+		ft := fft.CreateFourierTransform()
+		err = ft.RealFourier(response, spectrum, fft.SCALING_DEFAULT)
+
+		/*
+		 * Check if Fourier transform was successful.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to measure frequency response: %s", msg)
+		} else {
+			n := len(frequencies)
+			results := make([]complex128, n)
+			sampleRateFloat := float64(sampleRate)
+			lengthFloat := float64(RESPONSE_LENGTH)
+			maxBin := RESPONSE_LENGTH / 2
+			scaling := complex(1.0/RESPONSE_IMPULSE, 0.0)
+
+			/*
+			 * Interpolate the spectrum at each frequency.
+			 */
+			for i, frequency := range frequencies {
+				position := (frequency * lengthFloat) / sampleRateFloat
+				position = math.Max(position, 0.0)
+				lower := int(position)
+
+				/*
+				 * Make sure the bins are below the Nyquist frequency.
+				 */
+				if lower >= maxBin {
+					lower = maxBin - 1
+					position = float64(maxBin)
+				}
+
+				upper := lower + 1
+				lowerFloat := float64(lower)
+				weight := position - lowerFloat
+				weightUpper := complex(weight, 0.0)
+				weightLower := complex(1.0-weight, 0.0)
+				value := (weightLower * spectrum[lower]) + (weightUpper * spectrum[upper])
+				results[i] = scaling * value
+			}
+
+			return results, nil
+		}
+
+	}
+
+}
+
+/*
+ * Returns the complex frequency response of the unit in a slot, either
+ * calculated analytically, if the unit supports it, or measured.
+ */
+func (this *chainStruct) slotResponse(slot slotStruct, frequencies []float64, sampleRate uint32) ([]complex128, error) {
+	unit := slot.unit
+	composite, isComposite := unit.(*compositeStruct)
+	responseUnit, isResponseUnit := unit.(effects.ResponseUnit)
+
+	/*
+	 * Check if unit calculates its frequency response. The response of
+	 * a composite unit is that of the units inside.
+	 */
+	if isComposite {
+		response, err := composite.chain.ChainResponse(frequencies, sampleRate)
+		return response, err
+	} else if isResponseUnit {
+		response := responseUnit.FrequencyResponse(frequencies, sampleRate)
+		return response, nil
+	} else {
+		response, err := this.measureResponse(unit, frequencies, sampleRate)
+		return response, err
+	}
+
+}
+
+/*
+ * Returns the complex frequency response of an effects unit inside the signal
+ * chain at the given frequencies (in hertz).
+ *
+ * The input trim and output level of the unit are not included.
+ */
+func (this *chainStruct) FrequencyResponse(id int, frequencies []float64, sampleRate uint32) ([]complex128, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return nil, fmt.Errorf("Cannot get frequency response: No unit %d.", id)
+	} else {
+		slot := slots[id]
+		this.mutex.RUnlock()
+		response, err := this.slotResponse(slot, frequencies, sampleRate)
+		return response, err
+	}
+
+}
+
+/*
+ * Returns the complex frequency response of the entire signal chain at the
+ * given frequencies (in hertz).
+ *
+ * The responses of all units which are not bypassed are multiplied, together
+ * with their input trim and output level.
+ */
+func (this *chainStruct) ChainResponse(frequencies []float64, sampleRate uint32) ([]complex128, error) {
+	this.mutex.RLock()
+	numSlots := len(this.slots)
+	slots := make([]slotStruct, numSlots)
+	copy(slots, this.slots)
+	this.mutex.RUnlock()
+	n := len(frequencies)
+	results := make([]complex128, n)
+
+	/*
+	 * Start with a flat response.
+	 */
+	for i := range results {
+		results[i] = complex(UNITY_FACTOR, 0.0)
+	}
+
+	/*
+	 * Multiply the responses of all active units.
+	 */
+	for id, slot := range slots {
+
+		/*
+		 * Bypassed units do not alter the signal.
+		 */
+		if !slot.bypass {
+			response, err := this.slotResponse(slot, frequencies, sampleRate)
+
+			/*
+			 * Check if response could be obtained.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Cannot get frequency response of unit %d: %s", id, msg)
+			} else {
+				gain := slot.inputFactor * slot.outputFactor
+				gainComplex := complex(gain, 0.0)
+
+				/*
+				 * Apply the response of the unit.
+				 */
+				for i, value := range response {
+					results[i] *= gainComplex * value
+				}
+
+			}
+
+		}
+
+	}
+
+	return results, nil
+}
+
+/*
+ * Sets the input trim (in decibels) of an effects unit inside the signal chain.
+ */
+func (this *chainStruct) SetInputTrim(id int, value int32) error {
+
+	/*
+	 * Check if value is out of range.
+	 */
+	if value < GAIN_MINIMUM || value > GAIN_MAXIMUM {
+		return fmt.Errorf("Cannot set input trim: Value must be between %d dB and %d dB.", GAIN_MINIMUM, GAIN_MAXIMUM)
+	} else {
+		this.mutex.Lock()
+		slots := this.slots
+		n := len(slots)
+
+		/*
+		 * Check if index is out of range.
+		 */
+		if id < 0 || id >= n {
+			this.mutex.Unlock()
+			return fmt.Errorf("Cannot set input trim: No unit %d.", id)
+		} else {
+			factor := decibelsToFactor(value)
+			slots[id].inputTrim = value
+			slots[id].inputFactor = factor
+			this.publish()
+			this.mutex.Unlock()
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Retrieves the input trim (in decibels) of an effects unit inside the signal
+ * chain.
+ */
+func (this *chainStruct) GetInputTrim(id int) (int32, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return GAIN_NEUTRAL, fmt.Errorf("Cannot get input trim: No unit %d.", id)
+	} else {
+		value := slots[id].inputTrim
+		this.mutex.RUnlock()
+		return value, nil
+	}
+
+}
+
+/*
+ * Sets the output level (in decibels) of an effects unit inside the signal
+ * chain.
+ */
+func (this *chainStruct) SetOutputLevel(id int, value int32) error {
+
+	/*
+	 * Check if value is out of range.
+	 */
+	if value < GAIN_MINIMUM || value > GAIN_MAXIMUM {
+		return fmt.Errorf("Cannot set output level: Value must be between %d dB and %d dB.", GAIN_MINIMUM, GAIN_MAXIMUM)
+	} else {
+		this.mutex.Lock()
+		slots := this.slots
+		n := len(slots)
+
+		/*
+		 * Check if index is out of range.
+		 */
+		if id < 0 || id >= n {
+			this.mutex.Unlock()
+			return fmt.Errorf("Cannot set output level: No unit %d.", id)
+		} else {
+			factor := decibelsToFactor(value)
+			slots[id].outputLevel = value
+			slots[id].outputFactor = factor
+			this.publish()
+			this.mutex.Unlock()
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Retrieves the output level (in decibels) of an effects unit inside the
+ * signal chain.
+ */
+func (this *chainStruct) GetOutputLevel(id int) (int32, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return GAIN_NEUTRAL, fmt.Errorf("Cannot get output level: No unit %d.", id)
+	} else {
+		value := slots[id].outputLevel
+		this.mutex.RUnlock()
+		return value, nil
+	}
+
+}
+
+/*
+ * Sets the label of an effects unit inside the signal chain.
+ */
+func (this *chainStruct) SetLabel(id int, label string) error {
+	this.mutex.Lock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.Unlock()
+		return fmt.Errorf("Cannot set label: No unit %d.", id)
+	} else {
+		slots[id].label = label
+		this.publish()
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Retrieves the label of an effects unit inside the signal chain.
+ */
+func (this *chainStruct) GetLabel(id int) (string, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return "", fmt.Errorf("Cannot get label: No unit %d.", id)
+	} else {
+		label := slots[id].label
+		this.mutex.RUnlock()
+		return label, nil
+	}
+
+}
+
+/*
+ * Sets the note attached to an effects unit inside the signal chain.
+ */
+func (this *chainStruct) SetNote(id int, note string) error {
+	this.mutex.Lock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.Unlock()
+		return fmt.Errorf("Cannot set note: No unit %d.", id)
+	} else {
+		slots[id].note = note
+		this.publish()
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Retrieves the note attached to an effects unit inside the signal chain.
+ */
+func (this *chainStruct) GetNote(id int) (string, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return "", fmt.Errorf("Cannot get note: No unit %d.", id)
+	} else {
+		note := slots[id].note
+		this.mutex.RUnlock()
+		return note, nil
+	}
+
+}
+
+/*
+ * Returns whether the output of an effects unit inside the signal chain
+ * exceeded full scale since the last call and resets the clip indicator.
+ */
+func (this *chainStruct) Clipped(id int) (bool, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return false, fmt.Errorf("Cannot get clip indicator: No unit %d.", id)
+	} else {
+		state := slots[id].state
+		this.mutex.RUnlock()
+		value := atomic.SwapInt32(&state.clipped, 0)
+		clipped := value != 0
+		return clipped, nil
+	}
+
+}
+
+/*
+ * Returns the average time (in nanoseconds) it took to pass a block through
+ * this signal chain.
+ */
+func (this *chainStruct) ProcessingTime() uint32 {
+	nanoseconds := this.time.get()
+	return nanoseconds
+}
+
+/*
+ * Returns the average time (in nanoseconds) it took to pass a block through
+ * an effects unit inside the signal chain, including both channels of a
+ * stereo chain. Units in bypass mode take no time.
+ */
+func (this *chainStruct) UnitProcessingTime(id int) (uint32, error) {
+	this.mutex.RLock()
+	slots := this.slots
+	n := len(slots)
+
+	/*
+	 * Check if index is out of range.
+	 */
+	if id < 0 || id >= n {
+		this.mutex.RUnlock()
+		return 0, fmt.Errorf("Cannot get processing time: No unit %d.", id)
+	} else {
+		state := slots[id].state
+		this.mutex.RUnlock()
+		nanoseconds := state.time.get()
+		return nanoseconds, nil
+	}
+
+}
+
+/*
+ * Returns the latency (in samples) of a unit, or zero if the unit does not
+ * delay the signal.
+ */
+func unitLatency(unit effects.Unit, sampleRate uint32) uint32 {
+	latencyUnit, isLatencyUnit := unit.(effects.LatencyUnit)
+
+	/*
+	 * Check if unit delays the signal.
+	 */
+	if !isLatencyUnit {
+		return 0
+	} else {
+		latency := latencyUnit.Latency(sampleRate)
+		return latency
+	}
+
+}
+
+/*
+ * Calculates the latency (in samples) of all units inside this signal chain
+ * which are not bypassed.
+ *
+ * Units which compile filters may lock while this is calculated.
+ */
+func (this *chainStruct) calculateLatency(sampleRate uint32) uint32 {
+	snapshot := this.processingSnapshot()
+	slots := snapshot.slots
+	latency := uint32(0)
+
+	/*
+	 * Add up the latency of each unit.
+	 */
+	for _, slot := range slots {
+
+		/*
+		 * Units in bypass mode do not delay the signal.
+		 */
+		if !slot.bypass {
+			slotLatency := unitLatency(slot.unit, sampleRate)
+			latencyRight := unitLatency(slot.unitRight, sampleRate)
+
+			/*
+			 * Keep the larger latency of both channels.
+			 */
+			if latencyRight > slotLatency {
+				slotLatency = latencyRight
+			}
+
+			latency += slotLatency
+		}
+
+	}
+
+	return latency
+}
+
+/*
+ * Packs a latency together with the sample rate it was calculated for, so
+ * that both can be cached atomically.
+ */
+func packLatency(sampleRate uint32, latency uint32) uint64 {
+	sampleRateWide := uint64(sampleRate)
+	latencyWide := uint64(latency)
+	packed := (sampleRateWide << 32) | latencyWide
+	return packed
+}
+
+/*
+ * Recalculates the cached latency for the sample rate the chain was last
+ * processed at, after the units or their parameters changed.
+ *
+ * This is called outside of the audio thread, so that the audio thread does
+ * not have to calculate the latency itself.
+ */
+func (this *chainStruct) updateLatency() {
+	this.latencyMutex.Lock()
+	sampleRate := atomic.LoadUint32(&this.sampleRate)
+	latency := this.calculateLatency(sampleRate)
+	packed := packLatency(sampleRate, latency)
+	atomic.StoreUint64(&this.latency, packed)
+	this.latencyMutex.Unlock()
+	parent := this.parent
+
+	/*
+	 * The latency of a composite unit adds to that of its chain.
+	 */
+	if parent != nil {
+		parent.updateLatency()
+	}
+
+}
+
+/*
+ * Returns the latency (in samples) of all units inside this signal chain
+ * which are not bypassed, excluding any compensation.
+ *
+ * In a stereo chain, the larger latency of both channels is taken for each
+ * unit.
+ *
+ * This may be called from the audio thread. It returns the cached latency
+ * without locking or allocating, unless the sample rate changed.
+ */
+func (this *chainStruct) Latency(sampleRate uint32) uint32 {
+	packed := atomic.LoadUint64(&this.latency)
+	cachedSampleRate := uint32(packed >> 32)
+
+	/*
+	 * Check if cached latency is valid for this sample rate.
+	 */
+	if sampleRate != 0 && cachedSampleRate == sampleRate {
+		latency := uint32(packed)
+		return latency
+	} else {
+		latency := this.calculateLatency(sampleRate)
+
+		/*
+		 * Cache the latency unless it was updated in the meantime.
+		 */
+		if sampleRate != 0 {
+			packedNew := packLatency(sampleRate, latency)
+			atomic.CompareAndSwapUint64(&this.latency, packed, packedNew)
+		}
+
+		return latency
+	}
+
+}
+
+/*
+ * Sets the number of samples by which the output of this signal chain is
+ * delayed in addition to the latency of its units, so that it is aligned
+ * with other signal chains.
+ *
+ * The delay lines are resized by the audio thread once it picks up the new
+ * compensation.
+ */
+func (this *chainStruct) SetCompensation(samples uint32) {
+	snapshot := this.processingSnapshot()
+
+	/*
+	 * Avoid locking if the compensation did not change, since this is
+	 * called from the audio thread for each block.
+	 */
+	if samples == snapshot.compensation {
+		return
+	}
+
+	this.mutex.Lock()
+
+	/*
+	 * Only publish a new snapshot if the compensation actually changed.
+	 */
+	if samples != this.compensation {
+		this.compensation = samples
+		this.publish()
+	}
+
+	this.mutex.Unlock()
+}
+
+/*
+ * Returns the number of samples by which the output of this signal chain is
+ * delayed in addition to the latency of its units.
+ */
+func (this *chainStruct) Compensation() uint32 {
+	this.mutex.RLock()
+	compensation := this.compensation
+	this.mutex.RUnlock()
+	return compensation
+}
+
+/*
+ * Enables or disables smoothing of changes to the input trim, output level,
+ * bypass state and numeric parameters of units inside this signal chain.
+ *
+ * With smoothing enabled, gain changes are ramped across one block and units
+ * which are bypassed, brought back or removed are crossfaded with the
+ * unprocessed signal over a few milliseconds instead of changing abruptly at
+ * the block boundary, while units ramp their numeric parameters over the
+ * smoothing time.
+ */
+func (this *chainStruct) SetSmoothing(enabled bool) {
+	this.mutex.Lock()
+	this.smoothing = enabled
+	this.publish()
+	ms := this.effectiveSmoothingTime()
+	this.passSmoothingTime(ms)
+	this.mutex.Unlock()
+}
+
+/*
+ * Returns whether changes inside this signal chain are smoothed.
+ */
+func (this *chainStruct) Smoothing() bool {
+	this.mutex.RLock()
+	enabled := this.smoothing
+	this.mutex.RUnlock()
+	return enabled
+}
+
+/*
+ * Sets the time (in milliseconds) over which units inside this signal chain
+ * ramp changes to their numeric parameters.
+ */
+func (this *chainStruct) SetSmoothingTime(ms uint32) error {
+
+	/*
+	 * Check if value is out of range.
+	 */
+	if ms < effects.SMOOTHING_TIME_MINIMUM || ms > effects.SMOOTHING_TIME_MAXIMUM {
+		return fmt.Errorf("Cannot set smoothing time: Value must be between %d ms and %d ms.", effects.SMOOTHING_TIME_MINIMUM, effects.SMOOTHING_TIME_MAXIMUM)
+	} else {
+		this.mutex.Lock()
+		this.smoothingTime = ms
+		effectiveMs := this.effectiveSmoothingTime()
+		this.passSmoothingTime(effectiveMs)
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Returns the time (in milliseconds) over which units inside this signal
+ * chain ramp changes to their numeric parameters.
+ */
+func (this *chainStruct) SmoothingTime() uint32 {
+	this.mutex.RLock()
+	ms := this.smoothingTime
+	this.mutex.RUnlock()
+	return ms
+}
+
+/*
+ * Sets the time (in milliseconds) for which units with a tail, like delays and
+ * reverbs, ring out after they were bypassed or removed from this signal
+ * chain. A time of zero cuts them off at once.
+ *
+ * The setting is passed on to the units inside composite units.
+ */
+func (this *chainStruct) SetSpilloverTime(ms uint32) error {
+
+	/*
+	 * Check if value is out of range.
+	 */
+	if ms > SPILLOVER_TIME_MAXIMUM {
+		return fmt.Errorf("Cannot set spillover time: Value must be between %d ms and %d ms.", 0, SPILLOVER_TIME_MAXIMUM)
+	} else {
+		this.mutex.Lock()
+		this.spillover = ms
+
+		/*
+		 * Cut all tails if spillover is disabled.
+		 */
+		if ms == 0 {
+			this.tails = nil
+		}
+
+		this.publish()
+		slots := this.slots
+		this.mutex.Unlock()
+
+		/*
+		 * Pass the setting on to composite units.
+		 */
+		for _, slot := range slots {
+			composite, isComposite := slot.unit.(*compositeStruct)
+
+			/*
+			 * Check if this is a composite unit.
+			 */
+			if isComposite {
+				composite.chain.SetSpilloverTime(ms)
+			}
+
+		}
+
+		return nil
+	}
+
+}
+
+/*
+ * Returns the time (in milliseconds) for which units with a tail ring out
+ * after they were bypassed or removed from this signal chain.
+ */
+func (this *chainStruct) SpilloverTime() uint32 {
+	this.mutex.RLock()
+	ms := this.spillover
+	this.mutex.RUnlock()
+	return ms
+}
+
+/*
+ * Returns the time (in milliseconds) over which units ramp changes to their
+ * numeric parameters, which is zero when smoothing is disabled.
+ *
+ * The caller must hold the mutex.
+ */
+func (this *chainStruct) effectiveSmoothingTime() uint32 {
+
+	/*
+	 * Check whether smoothing is enabled.
+	 */
+	if this.smoothing {
+		return this.smoothingTime
+	} else {
+		return 0
+	}
+
+}
+
+/*
+ * Passes the time (in milliseconds) over which changes to numeric parameters
+ * are ramped to each unit inside this signal chain.
+ *
+ * The caller must hold the mutex.
+ */
+func (this *chainStruct) passSmoothingTime(ms uint32) {
+	slots := this.slots
+
+	/*
+	 * Pass the time to each unit.
+	 */
+	for _, slot := range slots {
+		units := []effects.Unit{slot.unit, slot.unitRight}
+
+		/*
+		 * Pass the time to the units for both channels.
+		 */
+		for _, unit := range units {
+			smoothingUnit, isSmoothingUnit := unit.(effects.SmoothingUnit)
+
+			/*
+			 * Check if unit ramps parameter changes.
+			 */
+			if isSmoothingUnit {
+				smoothingUnit.SetSmoothingTime(ms)
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Sets the number of frames processed at once, so that units can prepare
+ * their filters for it ahead of time instead of on the audio thread.
+ */
+func (this *chainStruct) SetBlockSize(frames uint32) {
+	this.mutex.Lock()
+	this.blockSize = frames
+	slots := this.slots
+	this.mutex.Unlock()
+
+	/*
+	 * Pass the block size to each unit. Units prepare their filters outside
+	 * of the chain lock and swap them in once they are ready, so neither
+	 * the audio thread nor other requests wait for this.
+	 */
+	for _, slot := range slots {
+		units := []effects.Unit{slot.unit, slot.unitRight}
+
+		/*
+		 * Pass the block size to the units for both channels.
+		 */
+		for _, unit := range units {
+			blockSizeUnit, isBlockSizeUnit := unit.(effects.BlockSizeUnit)
+
+			/*
+			 * Check if unit prepares filters.
+			 */
+			if isBlockSizeUnit {
+				blockSizeUnit.SetBlockSize(frames)
+			}
+
+		}
+
+	}
+
+	this.updateLatency()
+}
+
+/*
+ * Resizes the delay lines if the compensation changed. Starts over with
+ * silence in that case.
+ */
+func (this *chainStruct) prepareDelayLines(compensation uint32) {
+	n := int(compensation)
+
+	/*
+	 * Check if the size of the delay lines does not match.
+	 */
+	if len(this.delayLine) != n {
+		this.delayLine = make([]float64, n)
+		this.delayLinePosition = 0
+
+		/*
+		 * A stereo chain requires a delay line for the right channel.
+		 */
+		if this.stereo {
+			this.delayLineRight = make([]float64, n)
+		}
+
+	}
+
+}
+
+/*
+ * Updates all units inside this signal chain after the collection of impulse
+ * responses has been reloaded.
+ */
+func (this *chainStruct) UpdateImpulseResponses() error {
+	responses := this.responses
+	this.mutex.RLock()
+	slots := this.slots
+	units := []effects.Unit{}
+
+	/*
+	 * Collect all units in the chain.
+	 */
+	for _, slot := range slots {
+		units = append(units, slot.unit)
+
+		/*
+		 * Also collect the unit for the right channel, if any.
+		 */
+		if slot.unitRight != nil {
+			units = append(units, slot.unitRight)
+		}
+
+	}
+
+	this.mutex.RUnlock()
+	errResult := error(nil)
+
+	/*
+	 * Update each unit and keep the first error.
+	 */
+	for _, unit := range units {
+		err := error(nil)
+		composite, isComposite := unit.(*compositeStruct)
+
+		/*
+		 * Composite units pass the impulse responses on to the units
+		 * inside.
+		 */
+		if isComposite {
+			err = composite.chain.UpdateImpulseResponses()
+		} else {
+			err = effects.UpdateImpulseResponses(unit, responses)
+		}
+
+		/*
+		 * Check if an error occured.
+		 */
+		if err != nil && errResult == nil {
+			errResult = err
+		}
+
+	}
+
+	this.updateLatency()
+	return errResult
+}
+
+/*
+ * Sets the tempo (in beats per minute) for all units inside this signal chain
+ * which synchronize to a tempo.
+ */
+func (this *chainStruct) SetTempo(bpm uint32) {
+	this.mutex.Lock()
+	this.tempo = bpm
+	slots := this.slots
+
+	/*
+	 * Pass the tempo to each unit.
+	 */
+	for _, slot := range slots {
+		units := []effects.Unit{slot.unit, slot.unitRight}
+
+		/*
+		 * Pass the tempo to the units for both channels.
+		 */
+		for _, unit := range units {
+			tempoUnit, isTempoUnit := unit.(effects.TempoUnit)
+
+			/*
+			 * Check if unit synchronizes to a tempo.
+			 */
+			if isTempoUnit {
+				tempoUnit.SetTempo(bpm)
+			}
+
+		}
+
 	}
 
+	this.mutex.Unlock()
 }
 
 /*
@@ -356,61 +2209,583 @@ func (this *chainStruct) Length() int {
 }
 
 /*
- * Passes a signal through the signal chain.
+ * Returns whether this signal chain processes a stereo signal.
  */
-func (this *chainStruct) Process(in []float64, out []float64, sampleRate uint32) {
+func (this *chainStruct) Stereo() bool {
+	return this.stereo
+}
+
+/*
+ * Passes a mono signal through all units of the signal chain.
+ */
+func (this *chainStruct) processMono(in []float64, out []float64, sampleRate uint32) {
+	start := time.Now()
+	n := len(in)
+	bufferIn := this.bufferIn
 
 	/*
-	 * Verify that input and output buffers are the same size.
+	 * If size of input buffer does not match, reallocate it.
 	 */
-	if len(in) == len(out) {
-		n := len(in)
-		bufferIn := this.bufferIn
+	if len(bufferIn) != n {
+		bufferIn = make([]float64, n)
+		this.bufferIn = bufferIn
+	}
+
+	bufferOut := this.bufferOut
+
+	/*
+	 * If size of output buffer does not match, reallocate it.
+	 */
+	if len(bufferOut) != n {
+		bufferOut = make([]float64, n)
+		this.bufferOut = bufferOut
+	}
+
+	bufferDry := this.bufferDry
+
+	/*
+	 * If size of dry buffer does not match, reallocate it.
+	 */
+	if len(bufferDry) != n {
+		bufferDry = make([]float64, n)
+		this.bufferDry = bufferDry
+	}
+
+	copy(bufferIn, in)
+	snapshot := this.processingSnapshot()
+	slots := snapshot.slots
+	smoothing := snapshot.smoothing
+	spillover := uint64(snapshot.spillover)
+	spillSamples := int((spillover * uint64(sampleRate)) / 1000)
+	crossfadeSamples := (CROSSFADE_TIME * sampleRate) / 1000
+	crossfadeSamplesFloat := float64(crossfadeSamples)
+	step := UNITY_FACTOR / crossfadeSamplesFloat
+
+	/*
+	 * Iterate over the slots.
+	 */
+	for _, slot := range slots {
+		state := slot.state
+		active := !slot.bypass
+		target := 0.0
 
 		/*
-		 * If size of input buffer does not match, reallocate it.
+		 * An active unit is faded in completely.
 		 */
-		if len(bufferIn) != n {
-			bufferIn = make([]float64, n)
-			this.bufferIn = bufferIn
+		if active {
+			target = UNITY_FACTOR
 		}
 
-		bufferOut := this.bufferOut
-
 		/*
-		 * If size of output buffer does not match, reallocate it.
+		 * Without smoothing, units are switched at once.
 		 */
-		if len(bufferOut) != n {
-			bufferOut = make([]float64, n)
-			this.bufferOut = bufferOut
+		if !smoothing {
+			state.mix = target
 		}
 
-		copy(bufferIn, in)
-		this.mutex.RLock()
-		slots := this.slots
+		fading := state.mix != target
+		stopping := state.active && !active
 
 		/*
-		 * Iterate over the slots.
+		 * Units with a tail ring out after they were bypassed instead
+		 * of being faded out.
 		 */
-		for _, slot := range slots {
+		if stopping && (spillSamples > 0) && hasTail(slot.unit) {
+			state.spill = spillSamples
+			state.mix = 0.0
+			fading = false
+		} else if active {
+			state.spill = 0
+		}
+
+		/*
+		 * Process the unit if it is not in bypass mode or is still
+		 * being faded out.
+		 */
+		if active || fading {
+			unitStart := time.Now()
+
+			/*
+			 * A unit which was inactive starts with its current gains.
+			 */
+			if !state.active {
+				state.inputFactor = slot.inputFactor
+				state.outputFactor = slot.outputFactor
+			}
+
+			/*
+			 * Keep the unprocessed signal for the crossfade.
+			 */
+			if fading {
+				copy(bufferDry, bufferIn)
+			}
+
+			unit := slot.unit
+			inputFactor := slot.inputFactor
+			outputFactor := slot.outputFactor
 
 			/*
-			 * Verify that slot is not in bypass mode.
+			 * Check whether gain changes should be ramped.
 			 */
-			if !slot.bypass {
-				unit := slot.unit
+			if smoothing {
+				applyGainRamp(bufferIn, state.inputFactor, inputFactor)
 				unit.Process(bufferIn, bufferOut, sampleRate)
-				bufferIn, bufferOut = bufferOut, bufferIn
+				applyGainRamp(bufferOut, state.outputFactor, outputFactor)
+			} else {
+				applyGain(bufferIn, inputFactor)
+				unit.Process(bufferIn, bufferOut, sampleRate)
+				applyGain(bufferOut, outputFactor)
+			}
+
+			state.inputFactor = inputFactor
+			state.outputFactor = outputFactor
+
+			/*
+			 * Check if the output of the unit clipped.
+			 */
+			if exceedsClipLevel(bufferOut) {
+				atomic.StoreInt32(&state.clipped, 1)
+			}
+
+			/*
+			 * Fade the unit in or out.
+			 */
+			if fading {
+				state.mix = crossfade(bufferDry, bufferOut, state.mix, target, step)
 			}
 
+			bufferIn, bufferOut = bufferOut, bufferIn
+			unitElapsed := time.Since(unitStart)
+			state.time.record(unitElapsed)
+		} else if state.spill > 0 {
+			unitStart := time.Now()
+			fade := stopping && smoothing
+			spillMono(slot, bufferIn, bufferDry, bufferOut, fade, sampleRate)
+			state.spill -= n
+			unitElapsed := time.Since(unitStart)
+			state.time.record(unitElapsed)
+		} else {
+			state.time.record(0)
+		}
+
+		state.active = active
+
+		/*
+		 * A removed unit may be dropped once it is faded out and has
+		 * rung out.
+		 */
+		if slot.removed && (state.mix == 0.0) && (state.spill <= 0) {
+			atomic.StoreInt32(&state.finished, 1)
+		}
+
+	}
+
+	/*
+	 * Let the units removed from the chain ring out.
+	 */
+	for _, tail := range snapshot.tails {
+		state := tail.state
+
+		/*
+		 * A unit which was active until now starts to ring out.
+		 */
+		if state.active {
+			state.active = false
+			state.spill = spillSamples
+		}
+
+		/*
+		 * Check if the unit still rings out.
+		 */
+		if state.spill > 0 {
+			spillMono(tail, bufferIn, bufferDry, bufferOut, false, sampleRate)
+			state.spill -= n
+		} else {
+			atomic.StoreInt32(&state.finished, 1)
 		}
 
+	}
+
+	this.bufferIn = bufferIn
+	this.bufferOut = bufferOut
+	this.prepareDelayLines(snapshot.compensation)
+	position := this.delayLinePosition
+	this.delayLinePosition = delaySignal(bufferIn, this.delayLine, position)
+	copy(out, this.bufferIn)
+	elapsed := time.Since(start)
+	this.time.record(elapsed)
+}
+
+/*
+ * Passes a stereo signal through all units of the signal chain.
+ */
+func (this *chainStruct) processStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32) {
+	start := time.Now()
+	n := len(inLeft)
+	bufferIn := this.bufferIn
+
+	/*
+	 * If size of left input buffer does not match, reallocate it.
+	 */
+	if len(bufferIn) != n {
+		bufferIn = make([]float64, n)
 		this.bufferIn = bufferIn
+	}
+
+	bufferOut := this.bufferOut
+
+	/*
+	 * If size of left output buffer does not match, reallocate it.
+	 */
+	if len(bufferOut) != n {
+		bufferOut = make([]float64, n)
 		this.bufferOut = bufferOut
-		this.mutex.RUnlock()
-		copy(out, this.bufferIn)
 	}
 
+	bufferInRight := this.bufferInRight
+
+	/*
+	 * If size of right input buffer does not match, reallocate it.
+	 */
+	if len(bufferInRight) != n {
+		bufferInRight = make([]float64, n)
+		this.bufferInRight = bufferInRight
+	}
+
+	bufferOutRight := this.bufferOutRight
+
+	/*
+	 * If size of right output buffer does not match, reallocate it.
+	 */
+	if len(bufferOutRight) != n {
+		bufferOutRight = make([]float64, n)
+		this.bufferOutRight = bufferOutRight
+	}
+
+	bufferDry := this.bufferDry
+
+	/*
+	 * If size of left dry buffer does not match, reallocate it.
+	 */
+	if len(bufferDry) != n {
+		bufferDry = make([]float64, n)
+		this.bufferDry = bufferDry
+	}
+
+	bufferDryRight := this.bufferDryRight
+
+	/*
+	 * If size of right dry buffer does not match, reallocate it.
+	 */
+	if len(bufferDryRight) != n {
+		bufferDryRight = make([]float64, n)
+		this.bufferDryRight = bufferDryRight
+	}
+
+	copy(bufferIn, inLeft)
+	copy(bufferInRight, inRight)
+	snapshot := this.processingSnapshot()
+	slots := snapshot.slots
+	smoothing := snapshot.smoothing
+	spillover := uint64(snapshot.spillover)
+	spillSamples := int((spillover * uint64(sampleRate)) / 1000)
+	crossfadeSamples := (CROSSFADE_TIME * sampleRate) / 1000
+	crossfadeSamplesFloat := float64(crossfadeSamples)
+	step := UNITY_FACTOR / crossfadeSamplesFloat
+
+	/*
+	 * Iterate over the slots.
+	 */
+	for _, slot := range slots {
+		state := slot.state
+		active := !slot.bypass
+		target := 0.0
+
+		/*
+		 * An active unit is faded in completely.
+		 */
+		if active {
+			target = UNITY_FACTOR
+		}
+
+		/*
+		 * Without smoothing, units are switched at once.
+		 */
+		if !smoothing {
+			state.mix = target
+		}
+
+		fading := state.mix != target
+		stopping := state.active && !active
+
+		/*
+		 * Units with a tail ring out after they were bypassed instead
+		 * of being faded out.
+		 */
+		if stopping && (spillSamples > 0) && hasTail(slot.unit) {
+			state.spill = spillSamples
+			state.mix = 0.0
+			fading = false
+		} else if active {
+			state.spill = 0
+		}
+
+		/*
+		 * Process the unit if it is not in bypass mode or is still
+		 * being faded out.
+		 */
+		if active || fading {
+			unitStart := time.Now()
+
+			/*
+			 * A unit which was inactive starts with its current gains.
+			 */
+			if !state.active {
+				state.inputFactor = slot.inputFactor
+				state.outputFactor = slot.outputFactor
+			}
+
+			/*
+			 * Keep the unprocessed signal for the crossfade.
+			 */
+			if fading {
+				copy(bufferDry, bufferIn)
+				copy(bufferDryRight, bufferInRight)
+			}
+
+			unit := slot.unit
+			unitRight := slot.unitRight
+			inputFactor := slot.inputFactor
+			outputFactor := slot.outputFactor
+
+			/*
+			 * Check whether gain changes should be ramped.
+			 */
+			if smoothing {
+				applyGainRamp(bufferIn, state.inputFactor, inputFactor)
+				applyGainRamp(bufferInRight, state.inputFactor, inputFactor)
+			} else {
+				applyGain(bufferIn, inputFactor)
+				applyGain(bufferInRight, inputFactor)
+			}
+
+			stereoUnit, isStereoUnit := unit.(effects.StereoUnit)
+
+			/*
+			 * Stereo units process both channels at once, other units
+			 * have a separate instance for the right channel.
+			 */
+			if isStereoUnit {
+				stereoUnit.ProcessStereo(bufferIn, bufferInRight, bufferOut, bufferOutRight, sampleRate)
+			} else {
+				unit.Process(bufferIn, bufferOut, sampleRate)
+				unitRight.Process(bufferInRight, bufferOutRight, sampleRate)
+			}
+
+			/*
+			 * Check whether gain changes should be ramped.
+			 */
+			if smoothing {
+				applyGainRamp(bufferOut, state.outputFactor, outputFactor)
+				applyGainRamp(bufferOutRight, state.outputFactor, outputFactor)
+			} else {
+				applyGain(bufferOut, outputFactor)
+				applyGain(bufferOutRight, outputFactor)
+			}
+
+			state.inputFactor = inputFactor
+			state.outputFactor = outputFactor
+
+			/*
+			 * Check if the output of the unit clipped on either channel.
+			 */
+			if exceedsClipLevel(bufferOut) || exceedsClipLevel(bufferOutRight) {
+				atomic.StoreInt32(&state.clipped, 1)
+			}
+
+			/*
+			 * Fade the unit in or out.
+			 */
+			if fading {
+				mix := state.mix
+				state.mix = crossfade(bufferDry, bufferOut, mix, target, step)
+				crossfade(bufferDryRight, bufferOutRight, mix, target, step)
+			}
+
+			bufferIn, bufferOut = bufferOut, bufferIn
+			bufferInRight, bufferOutRight = bufferOutRight, bufferInRight
+			unitElapsed := time.Since(unitStart)
+			state.time.record(unitElapsed)
+		} else if state.spill > 0 {
+			unitStart := time.Now()
+			fade := stopping && smoothing
+			spillStereo(slot, bufferIn, bufferInRight, bufferDry, bufferDryRight, bufferOut, bufferOutRight, fade, sampleRate)
+			state.spill -= n
+			unitElapsed := time.Since(unitStart)
+			state.time.record(unitElapsed)
+		} else {
+			state.time.record(0)
+		}
+
+		state.active = active
+
+		/*
+		 * A removed unit may be dropped once it is faded out and has
+		 * rung out.
+		 */
+		if slot.removed && (state.mix == 0.0) && (state.spill <= 0) {
+			atomic.StoreInt32(&state.finished, 1)
+		}
+
+	}
+
+	/*
+	 * Let the units removed from the chain ring out.
+	 */
+	for _, tail := range snapshot.tails {
+		state := tail.state
+
+		/*
+		 * A unit which was active until now starts to ring out.
+		 */
+		if state.active {
+			state.active = false
+			state.spill = spillSamples
+		}
+
+		/*
+		 * Check if the unit still rings out.
+		 */
+		if state.spill > 0 {
+			spillStereo(tail, bufferIn, bufferInRight, bufferDry, bufferDryRight, bufferOut, bufferOutRight, false, sampleRate)
+			state.spill -= n
+		} else {
+			atomic.StoreInt32(&state.finished, 1)
+		}
+
+	}
+
+	this.bufferIn = bufferIn
+	this.bufferOut = bufferOut
+	this.bufferInRight = bufferInRight
+	this.bufferOutRight = bufferOutRight
+	this.prepareDelayLines(snapshot.compensation)
+	position := this.delayLinePosition
+	delaySignal(bufferInRight, this.delayLineRight, position)
+	this.delayLinePosition = delaySignal(bufferIn, this.delayLine, position)
+	copy(outLeft, this.bufferIn)
+	copy(outRight, this.bufferInRight)
+	elapsed := time.Since(start)
+	this.time.record(elapsed)
+}
+
+/*
+ * Remembers the sample rate the chain is processed at, so that the latency
+ * can be cached for it.
+ *
+ * Returns whether the sample rate changed.
+ */
+func (this *chainStruct) trackSampleRate(sampleRate uint32) bool {
+	previous := atomic.LoadUint32(&this.sampleRate)
+
+	/*
+	 * Check if sample rate changed.
+	 */
+	if sampleRate == previous {
+		return false
+	} else {
+		atomic.StoreUint32(&this.sampleRate, sampleRate)
+		return true
+	}
+
+}
+
+/*
+ * Drops the cached latency after the sample rate changed, since units may
+ * have compiled new filters for it while processing. It is calculated again
+ * on the next query.
+ */
+func (this *chainStruct) invalidateLatency(sampleRateChanged bool) {
+
+	/*
+	 * Check if sample rate changed.
+	 */
+	if sampleRateChanged {
+		atomic.StoreUint64(&this.latency, 0)
+	}
+
+}
+
+/*
+ * Passes a signal through the signal chain. A stereo chain is fed with the
+ * same signal on both channels and its output is mixed down to mono.
+ */
+func (this *chainStruct) Process(in []float64, out []float64, sampleRate uint32) {
+	sampleRateChanged := this.trackSampleRate(sampleRate)
+
+	/*
+	 * Verify that input and output buffers are the same size.
+	 */
+	if len(in) == len(out) {
+
+		/*
+		 * Check whether this is a stereo chain.
+		 */
+		if !this.stereo {
+			this.processMono(in, out, sampleRate)
+		} else {
+			this.processStereo(in, in, out, out, sampleRate)
+			resultLeft := this.bufferIn
+			resultRight := this.bufferInRight
+
+			/*
+			 * Mix the left and right channel.
+			 */
+			for i, sample := range resultLeft {
+				out[i] = 0.5 * (sample + resultRight[i])
+			}
+
+		}
+
+	}
+
+	this.invalidateLatency(sampleRateChanged)
+}
+
+/*
+ * Passes a stereo signal through the signal chain. A mono chain is fed with
+ * a mix of both channels and its output is written to both channels.
+ */
+func (this *chainStruct) ProcessStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32) {
+	sampleRateChanged := this.trackSampleRate(sampleRate)
+	n := len(inLeft)
+
+	/*
+	 * Verify that all buffers are the same size.
+	 */
+	if (len(inRight) == n) && (len(outLeft) == n) && (len(outRight) == n) {
+
+		/*
+		 * Check whether this is a stereo chain.
+		 */
+		if this.stereo {
+			this.processStereo(inLeft, inRight, outLeft, outRight, sampleRate)
+		} else {
+
+			/*
+			 * Mix the left and right channel.
+			 */
+			for i, sample := range inLeft {
+				outLeft[i] = 0.5 * (sample + inRight[i])
+			}
+
+			this.processMono(outLeft, outLeft, sampleRate)
+			copy(outRight, outLeft)
+		}
+
+	}
+
+	this.invalidateLatency(sampleRateChanged)
 }
 
 /*
@@ -423,9 +2798,34 @@ func CreateChain(responses filter.ImpulseResponses) Chain {
 	 * The new signal chain.
 	 */
 	chain := chainStruct{
-		responses: responses,
-		slots:     slots,
+		responses:     responses,
+		slots:         slots,
+		stereo:        false,
+		smoothing:     true,
+		smoothingTime: effects.SMOOTHING_TIME_DEFAULT,
+	}
+
+	chain.publish()
+	return &chain
+}
+
+/*
+ * Creates a new signal chain processing a stereo signal.
+ */
+func CreateStereoChain(responses filter.ImpulseResponses) Chain {
+	slots := make([]slotStruct, 0)
+
+	/*
+	 * The new signal chain.
+	 */
+	chain := chainStruct{
+		responses:     responses,
+		slots:         slots,
+		stereo:        true,
+		smoothing:     true,
+		smoothingTime: effects.SMOOTHING_TIME_DEFAULT,
 	}
 
+	chain.publish()
 	return &chain
 }
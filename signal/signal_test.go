@@ -0,0 +1,799 @@
+package signal
+
+import (
+	"github.com/andrepxx/go-dsp-guitar/effects"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+/*
+ * Verify that processing a signal chain does not allocate memory once its
+ * units have seen the block size.
+ */
+func TestProcessAllocations(t *testing.T) {
+
+	/*
+	 * Units in the signal chain.
+	 */
+	unitTypes := []int{
+		effects.UNIT_NOISEGATE,
+		effects.UNIT_COMPRESSOR,
+		effects.UNIT_OVERDRIVE,
+		effects.UNIT_TONESTACK,
+		effects.UNIT_CHORUS,
+		effects.UNIT_DELAY,
+		effects.UNIT_REVERB,
+	}
+
+	/*
+	 * Whether the chain processes a stereo signal.
+	 */
+	stereo := []bool{
+		false,
+		true,
+	}
+
+	n := 256
+	frames := uint32(n)
+	sampleRate := uint32(48000)
+	inLeft := make([]float64, n)
+	inRight := make([]float64, n)
+	outLeft := make([]float64, n)
+	outRight := make([]float64, n)
+
+	/*
+	 * Generate a sine wave.
+	 */
+	for i := range inLeft {
+		iFloat := float64(i)
+		arg := 2.0 * math.Pi * iFloat / 64.0
+		inLeft[i] = 0.5 * math.Sin(arg)
+		inRight[i] = 0.25 * math.Sin(arg)
+	}
+
+	/*
+	 * Test mono and stereo chains.
+	 */
+	for _, isStereo := range stereo {
+		chain := CreateChain(nil)
+
+		/*
+		 * Check if we need a stereo chain.
+		 */
+		if isStereo {
+			chain = CreateStereoChain(nil)
+		}
+
+		/*
+		 * Add the units to the chain.
+		 */
+		for _, unitType := range unitTypes {
+			id, err := chain.AppendUnit(unitType)
+
+			/*
+			 * Check if unit was added.
+			 */
+			if err != nil {
+				t.Fatalf("Failed to append unit of type %d: %s", unitType, err.Error())
+			}
+
+			chain.SetBypass(id, false)
+		}
+
+		chain.SetBlockSize(frames)
+
+		/*
+		 * Process a single block.
+		 */
+		process := func() {
+
+			/*
+			 * Check if chain processes a stereo signal.
+			 */
+			if isStereo {
+				chain.ProcessStereo(inLeft, inRight, outLeft, outRight, sampleRate)
+			} else {
+				chain.Process(inLeft, outLeft, sampleRate)
+			}
+
+		}
+
+		process()
+		allocs := testing.AllocsPerRun(100, process)
+
+		/*
+		 * Processing must not allocate.
+		 */
+		if allocs != 0 {
+			t.Errorf("Processing chain (stereo: %t) allocates %f times per run.", isStereo, allocs)
+		}
+
+	}
+
+}
+
+/*
+ * Verify that the latency of a signal chain follows changes to its units and
+ * that querying it and setting an unchanged compensation does not allocate.
+ */
+func TestLatency(t *testing.T) {
+	n := 256
+	sampleRate := uint32(48000)
+	in := make([]float64, n)
+	out := make([]float64, n)
+	chain := CreateStereoChain(nil)
+	id, err := chain.AppendUnit(effects.UNIT_STUDIO_COMPRESSOR)
+
+	/*
+	 * Check if unit was added.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to append unit: %s", err.Error())
+	}
+
+	chain.Process(in, out, sampleRate)
+	chain.SetNumericValue(id, "lookahead", 5)
+	latency := chain.Latency(sampleRate)
+
+	/*
+	 * A bypassed unit does not delay the signal.
+	 */
+	if latency != 0 {
+		t.Errorf("Latency of bypassed unit should be %d, but is %d.", 0, latency)
+	}
+
+	chain.SetBypass(id, false)
+	latency = chain.Latency(sampleRate)
+
+	/*
+	 * Five milliseconds of lookahead at 48 kHz.
+	 */
+	if latency != 240 {
+		t.Errorf("Latency should be %d, but is %d.", 240, latency)
+	}
+
+	chain.SetNumericValue(id, "lookahead", 1)
+	latency = chain.Latency(sampleRate)
+
+	/*
+	 * One millisecond of lookahead at 48 kHz.
+	 */
+	if latency != 48 {
+		t.Errorf("Latency should be %d, but is %d.", 48, latency)
+	}
+
+	latency = chain.Latency(44100)
+
+	/*
+	 * One millisecond of lookahead at 44.1 kHz.
+	 */
+	if latency != 44 {
+		t.Errorf("Latency should be %d, but is %d.", 44, latency)
+	}
+
+	chain.SetCompensation(16)
+
+	/*
+	 * Query the latency and compensate it, as the audio thread does.
+	 */
+	compensate := func() {
+		chain.Latency(sampleRate)
+		chain.SetCompensation(16)
+	}
+
+	allocs := testing.AllocsPerRun(100, compensate)
+
+	/*
+	 * Compensating latency must not allocate.
+	 */
+	if allocs != 0 {
+		t.Errorf("Compensating latency allocates %f times per run.", allocs)
+	}
+
+}
+
+/*
+ * Verify that the measured frequency response of a unit matches the one it
+ * calculates and that the response of a chain includes the gains of its
+ * active units only.
+ */
+func TestFrequencyResponse(t *testing.T) {
+	sampleRate := uint32(48000)
+	chain := CreateChain(nil)
+	id, err := chain.AppendUnit(effects.UNIT_PARAMETRIC_EQ)
+
+	/*
+	 * Check if unit was added.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to append unit: %s", err.Error())
+	}
+
+	chain.SetNumericValue(id, "bands", 1)
+	chain.SetNumericValue(id, "band_1_frequency", 1000)
+	chain.SetNumericValue(id, "band_1_gain", 12)
+	chain.SetDiscreteValue(id, "low_cut", "12")
+
+	/*
+	 * Frequencies to evaluate the response at.
+	 */
+	frequencies := []float64{
+		50.0,
+		80.0,
+		1000.0,
+		4000.0,
+	}
+
+	calculated, err := chain.FrequencyResponse(id, frequencies, sampleRate)
+
+	/*
+	 * Check if response was calculated.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to calculate frequency response: %s", err.Error())
+	}
+
+	chainInternal := chain.(*chainStruct)
+	unit := chainInternal.slots[id].unit
+	measured, err := chainInternal.measureResponse(unit, frequencies, sampleRate)
+
+	/*
+	 * Check if response was measured.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to measure frequency response: %s", err.Error())
+	}
+
+	/*
+	 * The measured response should match the calculated one.
+	 */
+	for i, frequency := range frequencies {
+		magnitudeCalculated := 20.0 * math.Log10(cmplx.Abs(calculated[i]))
+		magnitudeMeasured := 20.0 * math.Log10(cmplx.Abs(measured[i]))
+		phaseCalculated := cmplx.Phase(calculated[i])
+		phaseMeasured := cmplx.Phase(measured[i])
+
+		/*
+		 * Compare the magnitudes.
+		 */
+		if math.Abs(magnitudeMeasured-magnitudeCalculated) > 0.1 {
+			t.Errorf("Measured magnitude at %f Hz should be %f dB, but is %f dB.", frequency, magnitudeCalculated, magnitudeMeasured)
+		}
+
+		/*
+		 * Compare the phases.
+		 */
+		if math.Abs(phaseMeasured-phaseCalculated) > 0.01 {
+			t.Errorf("Measured phase at %f Hz should be %f, but is %f.", frequency, phaseCalculated, phaseMeasured)
+		}
+
+	}
+
+	idOverdrive, err := chain.AppendUnit(effects.UNIT_OVERDRIVE)
+
+	/*
+	 * Check if unit was added.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to append unit: %s", err.Error())
+	}
+
+	chain.SetNumericValue(idOverdrive, "gain", 30)
+	chain.SetBypass(id, false)
+	chain.SetInputTrim(id, -6)
+	chain.SetOutputLevel(id, -3)
+	response, err := chain.ChainResponse(frequencies, sampleRate)
+
+	/*
+	 * Check if response of chain was obtained.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to get frequency response of chain: %s", err.Error())
+	}
+
+	magnitude := 20.0 * math.Log10(cmplx.Abs(response[2]))
+
+	/*
+	 * The bypassed overdrive does not alter the signal.
+	 */
+	if math.Abs(magnitude-3.0) > 0.1 {
+		t.Errorf("Magnitude of chain should be %f dB, but is %f dB.", 3.0, magnitude)
+	}
+
+}
+
+/*
+ * Verify that a composite unit exposes the parameters of the units inside,
+ * prefixed by their index, and passes changes to their latency on to the
+ * chain it is part of.
+ */
+func TestComposite(t *testing.T) {
+	n := 256
+	sampleRate := uint32(48000)
+	in := make([]float64, n)
+	out := make([]float64, n)
+	chain := CreateStereoChain(nil)
+	id, err := chain.AppendUnit(effects.UNIT_COMPOSITE)
+
+	/*
+	 * Check if composite unit was added.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to append composite unit: %s", err.Error())
+	}
+
+	composite, err := chain.Composite(id)
+
+	/*
+	 * Check if composite unit can be accessed.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to get composite unit: %s", err.Error())
+	}
+
+	composite.SetName("Squash")
+	inner := composite.Units()
+	innerId, err := inner.AppendUnit(effects.UNIT_STUDIO_COMPRESSOR)
+
+	/*
+	 * Check if unit was added inside the composite unit.
+	 */
+	if err != nil {
+		t.Fatalf("Failed to append unit: %s", err.Error())
+	}
+
+	chain.SetBypass(id, false)
+	chain.Process(in, out, sampleRate)
+	inner.SetNumericValue(innerId, "lookahead", 5)
+	inner.SetBypass(innerId, false)
+	latency := chain.Latency(sampleRate)
+
+	/*
+	 * Five milliseconds of lookahead at 48 kHz.
+	 */
+	if latency != 240 {
+		t.Errorf("Latency should be %d, but is %d.", 240, latency)
+	}
+
+	err = chain.SetNumericValue(id, "0/lookahead", 1)
+	lookahead, _ := inner.GetNumericValue(innerId, "lookahead")
+	latency = chain.Latency(sampleRate)
+
+	/*
+	 * Prefixed parameters address the units inside.
+	 */
+	if err != nil {
+		t.Errorf("Failed to set prefixed parameter: %s", err.Error())
+	} else if (lookahead != 1) || (latency != 48) {
+		t.Errorf("Lookahead and latency should be %d and %d, but are %d and %d.", 1, 48, lookahead, latency)
+	}
+
+	params, _ := chain.Parameters(id)
+	found := false
+
+	/*
+	 * Search for the prefixed parameter.
+	 */
+	for _, param := range params {
+
+		/*
+		 * Check if we found the parameter.
+		 */
+		if param.Name == "0/lookahead" {
+			found = true
+		}
+
+	}
+
+	/*
+	 * The composite unit lists the parameters of the units inside.
+	 */
+	if !found {
+		t.Errorf("Composite unit should list parameter '%s'.", "0/lookahead")
+	}
+
+	err = chain.SetNumericValue(id, "lookahead", 1)
+
+	/*
+	 * Parameters without a prefix do not exist.
+	 */
+	if err == nil {
+		t.Errorf("Setting parameter '%s' should fail.", "lookahead")
+	}
+
+	chain.AppendUnit(effects.UNIT_DELAY)
+	_, err = chain.Composite(1)
+
+	/*
+	 * Other units are not composite units.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Unit 1 should not be a composite unit.")
+	}
+
+	name := composite.Name()
+
+	/*
+	 * Check if the name was kept.
+	 */
+	if name != "Squash" {
+		t.Errorf("Composite unit should be named '%s', but is named '%s'.", "Squash", name)
+	}
+
+}
+
+/*
+ * Verify that a delay rings out for the spillover time after it was bypassed
+ * or removed, is cut off at once without spillover and that ringing out does
+ * not allocate.
+ */
+func TestSpillover(t *testing.T) {
+
+	/*
+	 * Spillover times to test.
+	 */
+	spilloverTimes := []uint32{
+		300,
+		300,
+		0,
+		0,
+	}
+
+	/*
+	 * Whether the delay is removed instead of bypassed.
+	 */
+	removals := []bool{
+		false,
+		true,
+		false,
+		true,
+	}
+
+	n := 256
+	sampleRate := uint32(48000)
+	in := make([]float64, n)
+	silence := make([]float64, n)
+	out := make([]float64, n)
+
+	/*
+	 * Generate a sine wave.
+	 */
+	for i := range in {
+		iFloat := float64(i)
+		arg := 2.0 * math.Pi * iFloat / 64.0
+		in[i] = 0.5 * math.Sin(arg)
+	}
+
+	/*
+	 * Bypass or remove the delay with each spillover time.
+	 */
+	for i, spilloverTime := range spilloverTimes {
+		removal := removals[i]
+		chain := CreateChain(nil)
+		err := chain.SetSpilloverTime(spilloverTime)
+
+		/*
+		 * Check if spillover time was set.
+		 */
+		if err != nil {
+			t.Fatalf("Failed to set spillover time: %s", err.Error())
+		}
+
+		id, _ := chain.AppendUnit(effects.UNIT_DELAY)
+		chain.SetBypass(id, false)
+		chain.Process(in, out, sampleRate)
+
+		/*
+		 * Check whether the delay should be removed or bypassed.
+		 */
+		if removal {
+			chain.RemoveUnit(id)
+		} else {
+			chain.SetBypass(id, true)
+		}
+
+		blocks := 0
+
+		/*
+		 * Process a block of silence.
+		 */
+		process := func() {
+			chain.Process(silence, out, sampleRate)
+			blocks++
+		}
+
+		allocs := testing.AllocsPerRun(10, process)
+
+		/*
+		 * Ringing out must not allocate.
+		 */
+		if allocs != 0 {
+			t.Errorf("Ringing out (spillover: %d ms, removal: %t) allocates %f times per run.", spilloverTime, removal, allocs)
+		}
+
+		peakTail := 0.0
+		peakAfter := 0.0
+
+		/*
+		 * Process up to one second of silence, in which the delay
+		 * repeats after 200 ms, 400 ms and so on.
+		 */
+		for blocks < 187 {
+			process()
+			position := blocks * n
+			peak := 0.0
+
+			/*
+			 * Find the peak of this block.
+			 */
+			for _, sample := range out {
+				magnitude := math.Abs(sample)
+
+				/*
+				 * Check if this is the highest magnitude so far.
+				 */
+				if magnitude > peak {
+					peak = magnitude
+				}
+
+			}
+
+			/*
+			 * Separate the first repeat from the ones after the
+			 * spillover time.
+			 */
+			if position <= 14400 {
+				peakTail = math.Max(peakTail, peak)
+			} else {
+				peakAfter = math.Max(peakAfter, peak)
+			}
+
+		}
+
+		/*
+		 * The first repeat only rings out with spillover and nothing
+		 * rings out after the spillover time.
+		 */
+		if (spilloverTime > 0) && (peakTail < 0.1) {
+			t.Errorf("Delay (removal: %t) should ring out, but peak is %f.", removal, peakTail)
+		} else if (spilloverTime == 0) && (peakTail != 0.0) {
+			t.Errorf("Delay (removal: %t) should be cut off, but peak is %f.", removal, peakTail)
+		} else if peakAfter != 0.0 {
+			t.Errorf("Delay (spillover: %d ms, removal: %t) should be silent after spillover time, but peak is %f.", spilloverTime, removal, peakAfter)
+		}
+
+	}
+
+	chain := CreateChain(nil)
+	err := chain.SetSpilloverTime(SPILLOVER_TIME_MAXIMUM + 1)
+
+	/*
+	 * Spillover times beyond the maximum are rejected.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Setting spillover time beyond maximum should fail.")
+	}
+
+}
+
+/*
+ * Verify that units which are bypassed or removed are crossfaded with the
+ * unprocessed signal over several short blocks instead of switching at once
+ * and that removed units are dropped once they are faded out.
+ */
+func TestCrossfade(t *testing.T) {
+
+	/*
+	 * Whether the unit is removed instead of bypassed.
+	 */
+	removals := []bool{
+		false,
+		true,
+	}
+
+	n := 64
+	sampleRate := uint32(48000)
+	in := make([]float64, n)
+	out := make([]float64, n)
+
+	/*
+	 * Feed the chain with a constant signal.
+	 */
+	for i := range in {
+		in[i] = 0.1
+	}
+
+	/*
+	 * Bypass or remove the unit.
+	 */
+	for _, removal := range removals {
+		chain := CreateChain(nil)
+		id, _ := chain.AppendUnit(effects.UNIT_PARAMETRIC_EQ)
+		chain.SetOutputLevel(id, 12)
+		chain.SetBypass(id, false)
+
+		/*
+		 * Let the unit fade in completely.
+		 */
+		for block := 0; block < 8; block++ {
+			chain.Process(in, out, sampleRate)
+		}
+
+		/*
+		 * Check whether the unit should be removed or bypassed.
+		 */
+		if removal {
+			chain.RemoveUnit(id)
+		} else {
+			chain.SetBypass(id, true)
+		}
+
+		previous := out[n-1]
+		maxStep := 0.0
+		samplesFading := 0
+
+		/*
+		 * Process the crossfade and some blocks after it.
+		 */
+		for block := 0; block < 8; block++ {
+			chain.Process(in, out, sampleRate)
+
+			/*
+			 * Find the largest change between samples and count the
+			 * samples which still contain the processed signal.
+			 */
+			for _, sample := range out {
+				step := math.Abs(sample - previous)
+				maxStep = math.Max(maxStep, step)
+
+				/*
+				 * Check if the sample still differs from the
+				 * unprocessed signal.
+				 */
+				if math.Abs(sample-0.1) > 1e-9 {
+					samplesFading++
+				}
+
+				previous = sample
+			}
+
+		}
+
+		/*
+		 * The crossfade takes five milliseconds at 48 kHz and has no
+		 * steps.
+		 */
+		if (samplesFading < 200) || (samplesFading > 240) {
+			t.Errorf("Crossfade (removal: %t) should take about %d samples, but takes %d.", removal, 240, samplesFading)
+		} else if maxStep > 0.01 {
+			t.Errorf("Crossfade (removal: %t) should be smooth, but has a step of %f.", removal, maxStep)
+		}
+
+	}
+
+	chain := CreateChain(nil)
+	id, _ := chain.AppendUnit(effects.UNIT_PARAMETRIC_EQ)
+	chain.SetBypass(id, false)
+	chain.Process(in, out, sampleRate)
+	chain.RemoveUnit(id)
+	chainInternal := chain.(*chainStruct)
+	snapshot := chainInternal.processingSnapshot()
+	numSlots := len(snapshot.slots)
+
+	/*
+	 * The removed unit is faded out in place.
+	 */
+	if numSlots != 1 {
+		t.Errorf("Snapshot should hold %d slot, but holds %d.", 1, numSlots)
+	}
+
+	/*
+	 * Let the unit fade out.
+	 */
+	for block := 0; block < 8; block++ {
+		chain.Process(in, out, sampleRate)
+	}
+
+	chain.SetSmoothing(true)
+	snapshot = chainInternal.processingSnapshot()
+	numSlots = len(snapshot.slots)
+
+	/*
+	 * The unit is dropped after it faded out.
+	 */
+	if numSlots != 0 {
+		t.Errorf("Snapshot should hold %d slots, but holds %d.", 0, numSlots)
+	}
+
+}
+
+/*
+ * Verify that the units of a signal chain may be changed while blocks are
+ * processed.
+ *
+ * Run with 'go test -race' to detect unsynchronized access.
+ */
+func TestConcurrentChanges(t *testing.T) {
+	n := 256
+	frames := uint32(n)
+	sampleRate := uint32(48000)
+	inLeft := make([]float64, n)
+	inRight := make([]float64, n)
+	outLeft := make([]float64, n)
+	outRight := make([]float64, n)
+	chain := CreateStereoChain(nil)
+	chain.SetBlockSize(frames)
+	done := make(chan bool)
+	stopped := make(chan bool)
+
+	/*
+	 * Process blocks until the changes are done.
+	 */
+	go func() {
+		running := true
+
+		/*
+		 * Process a block unless the changes are done.
+		 */
+		for running {
+
+			select {
+			case <-done:
+				running = false
+			default:
+				chain.ProcessStereo(inLeft, inRight, outLeft, outRight, sampleRate)
+			}
+
+		}
+
+		stopped <- true
+	}()
+
+	/*
+	 * Change the units many times while blocks are processed.
+	 */
+	for round := 0; round < 100; round++ {
+		first, errFirst := chain.AppendUnit(effects.UNIT_OVERDRIVE)
+		second, errSecond := chain.AppendUnit(effects.UNIT_DELAY)
+
+		/*
+		 * Check if units were added.
+		 */
+		if errFirst != nil {
+			t.Fatalf("Round %d: Failed to append unit: %s", round, errFirst.Error())
+		} else if errSecond != nil {
+			t.Fatalf("Round %d: Failed to append unit: %s", round, errSecond.Error())
+		}
+
+		chain.SetBypass(first, false)
+		chain.SetBypass(second, false)
+		err := chain.SetNumericValue(second, "feedback", -6)
+
+		/*
+		 * Check if value was set.
+		 */
+		if err != nil {
+			t.Errorf("Round %d: Failed to set numeric value: %s", round, err.Error())
+		}
+
+		chain.SetInputTrim(first, 6)
+		chain.SetOutputLevel(second, -6)
+		chain.MoveUp(second)
+		chain.SetSmoothing(round%2 == 0)
+		chain.RemoveUnit(1)
+		chain.RemoveUnit(0)
+	}
+
+	done <- true
+	<-stopped
+	length := chain.Length()
+
+	/*
+	 * All units must have been removed.
+	 */
+	if length != 0 {
+		t.Errorf("Chain should hold %d units, but holds %d.", 0, length)
+	}
+
+}
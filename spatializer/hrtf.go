@@ -0,0 +1,363 @@
+package spatializer
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/resample"
+	"github.com/andrepxx/go-dsp-guitar/wave"
+	"math"
+	"os"
+)
+
+/*
+ * Constants for the spherical head model (Brown and Duda, 1998).
+ */
+const (
+	HEAD_RADIUS        = 0.0875
+	SPEED_OF_SOUND     = 343.0
+	HEAD_SHADOW_ALPHA  = 0.1
+	HEAD_SHADOW_THETA  = 150.0
+	HRTF_AZIMUTH_STEP  = 5.0
+	HRTF_DURATION      = 0.005
+	HRTF_CHANNEL_COUNT = 2
+)
+
+/*
+ * Data structure describing a head-related transfer function for one
+ * direction, loaded from a stereo wave file.
+ */
+type hrtfDescriptorStruct struct {
+	Azimuth float64
+	Path    string
+}
+
+/*
+ * Data structure representing a set of head-related impulse responses loaded
+ * from wave files.
+ */
+type hrtfSetStruct struct {
+	azimuths   []float64
+	sampleRate uint32
+	left       [][]float64
+	right      [][]float64
+}
+
+/*
+ * Data structure representing a set of head-related impulse responses derived
+ * from a spherical head model.
+ */
+type sphericalHeadStruct struct {
+}
+
+/*
+ * Interface type for a set of head-related transfer functions, one for each
+ * of a number of directions in the horizontal plane.
+ */
+type Hrtf interface {
+	Azimuths() []float64
+	Responses(sampleRate uint32) ([][]float64, [][]float64)
+}
+
+/*
+ * Returns the azimuth (in degrees) of each direction.
+ */
+func (this *hrtfSetStruct) Azimuths() []float64 {
+	azimuths := this.azimuths
+	numAzimuths := len(azimuths)
+	result := make([]float64, numAzimuths)
+	copy(result, azimuths)
+	return result
+}
+
+/*
+ * Returns the impulse responses for the left and right ear of each direction
+ * at a certain sample rate.
+ */
+func (this *hrtfSetStruct) Responses(sampleRate uint32) ([][]float64, [][]float64) {
+	numAzimuths := len(this.azimuths)
+	left := make([][]float64, numAzimuths)
+	right := make([][]float64, numAzimuths)
+	sourceRate := this.sampleRate
+
+	/*
+	 * Convert the responses of each direction.
+	 */
+	for i := range left {
+		sourceLeft := this.left[i]
+		sourceRight := this.right[i]
+
+		/*
+		 * Only resample if the sample rates differ.
+		 */
+		if sourceRate == sampleRate {
+			numSamples := len(sourceLeft)
+			left[i] = make([]float64, numSamples)
+			right[i] = make([]float64, numSamples)
+			copy(left[i], sourceLeft)
+			copy(right[i], sourceRight)
+		} else {
+			left[i] = resample.Time(sourceLeft, sourceRate, sampleRate)
+			right[i] = resample.Time(sourceRight, sourceRate, sampleRate)
+		}
+
+	}
+
+	return left, right
+}
+
+/*
+ * Returns the azimuth (in degrees) of each direction.
+ */
+func (this *sphericalHeadStruct) Azimuths() []float64 {
+	numAzimuthsFloat := 360.0 / HRTF_AZIMUTH_STEP
+	numAzimuths := int(numAzimuthsFloat)
+	azimuths := make([]float64, numAzimuths)
+
+	/*
+	 * Calculate each azimuth, from -180 to 180 degrees.
+	 */
+	for i := range azimuths {
+		iFloat := float64(i)
+		azimuths[i] = (HRTF_AZIMUTH_STEP * iFloat) - 180.0
+	}
+
+	return azimuths
+}
+
+/*
+ * Calculates the impulse response of the spherical head model for an ear,
+ * given the angle (in degrees) between the ear and the source.
+ *
+ * The head shadow is a first-order shelving filter, which attenuates high
+ * frequencies as the source moves behind the ear, and the interaural time
+ * difference is a fractional delay.
+ */
+func (this *sphericalHeadStruct) response(incidence float64, sampleRate uint32) []float64 {
+	sampleRateFloat := float64(sampleRate)
+	lengthFloat := math.Ceil(HRTF_DURATION * sampleRateFloat)
+	length := int(lengthFloat)
+	response := make([]float64, length)
+	theta := MATH_DEGREE_TO_RADIANS * incidence
+	shadowArg := (incidence / HEAD_SHADOW_THETA) * math.Pi
+	alpha := (1.0 + (0.5 * HEAD_SHADOW_ALPHA)) + ((1.0 - (0.5 * HEAD_SHADOW_ALPHA)) * math.Cos(shadowArg))
+	beta := 2.0 * SPEED_OF_SOUND / HEAD_RADIUS
+	k := 2.0 * sampleRateFloat
+	b0 := (beta + (alpha * k)) / (beta + k)
+	b1 := (beta - (alpha * k)) / (beta + k)
+	a1 := (beta - k) / (beta + k)
+	delayTime := HEAD_RADIUS / SPEED_OF_SOUND
+
+	/*
+	 * Sound travels around the head to reach an ear facing away from
+	 * the source.
+	 */
+	if theta < 0.5*math.Pi {
+		delayTime *= 1.0 - math.Cos(theta)
+	} else {
+		delayTime *= 1.0 + theta - (0.5 * math.Pi)
+	}
+
+	delaySamples := delayTime * sampleRateFloat
+	delayEarly := math.Floor(delaySamples)
+	delayEarlyInt := int(delayEarly)
+	weightLate := delaySamples - delayEarly
+	weightEarly := 1.0 - weightLate
+	previousInput := 0.0
+	previousOutput := 0.0
+
+	/*
+	 * Feed a delayed impulse through the head shadow filter.
+	 */
+	for i := range response {
+		input := 0.0
+
+		/*
+		 * The impulse is split between two samples.
+		 */
+		if i == delayEarlyInt {
+			input = weightEarly
+		} else if i == delayEarlyInt+1 {
+			input = weightLate
+		}
+
+		output := (b0 * input) + (b1 * previousInput) - (a1 * previousOutput)
+		response[i] = output
+		previousInput = input
+		previousOutput = output
+	}
+
+	return response
+}
+
+/*
+ * Returns the impulse responses for the left and right ear of each direction
+ * at a certain sample rate.
+ */
+func (this *sphericalHeadStruct) Responses(sampleRate uint32) ([][]float64, [][]float64) {
+	azimuths := this.Azimuths()
+	numAzimuths := len(azimuths)
+	left := make([][]float64, numAzimuths)
+	right := make([][]float64, numAzimuths)
+
+	/*
+	 * The ears are at -90 and 90 degrees.
+	 */
+	for i, azimuth := range azimuths {
+		incidenceLeft := wrapAzimuth(azimuth + 90.0)
+		incidenceRight := wrapAzimuth(azimuth - 90.0)
+		left[i] = this.response(math.Abs(incidenceLeft), sampleRate)
+		right[i] = this.response(math.Abs(incidenceRight), sampleRate)
+	}
+
+	return left, right
+}
+
+/*
+ * Wraps an azimuth (in degrees) into the range from -180 to 180 degrees.
+ */
+func wrapAzimuth(azimuth float64) float64 {
+	wrapped := math.Mod(azimuth+180.0, 360.0)
+
+	/*
+	 * The remainder keeps the sign of the dividend.
+	 */
+	if wrapped < 0.0 {
+		wrapped += 360.0
+	}
+
+	result := wrapped - 180.0
+	return result
+}
+
+/*
+ * Finds the direction closest to an azimuth (in degrees).
+ */
+func nearestDirection(azimuths []float64, azimuth float64) int {
+	nearest := -1
+	nearestDistance := math.Inf(1)
+
+	/*
+	 * Compare the angle to each direction.
+	 */
+	for i, current := range azimuths {
+		distance := math.Abs(wrapAzimuth(current - azimuth))
+
+		/*
+		 * Check if this direction is closer.
+		 */
+		if distance < nearestDistance {
+			nearest = i
+			nearestDistance = distance
+		}
+
+	}
+
+	return nearest
+}
+
+/*
+ * Creates a set of head-related transfer functions from a spherical head
+ * model. It is used unless another set is loaded.
+ */
+func SphericalHead() Hrtf {
+	hrtf := sphericalHeadStruct{}
+	return &hrtf
+}
+
+/*
+ * Imports a set of head-related transfer functions using a descriptor file,
+ * which lists a stereo wave file (left and right ear) for each azimuth.
+ *
+ * Measurements stored in other formats, like SOFA, have to be exported to
+ * wave files for each direction in the horizontal plane first.
+ */
+func ImportHrtf(descriptorFilePath string) (Hrtf, error) {
+	content, err := os.ReadFile(descriptorFilePath)
+
+	/*
+	 * Check if file could be read.
+	 */
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read HRTF descriptor file: '%s'", descriptorFilePath)
+	} else {
+		descriptors := []hrtfDescriptorStruct{}
+		err = json.Unmarshal(content, &descriptors)
+		numDescriptors := len(descriptors)
+
+		/*
+		 * Check if file failed to unmarshal.
+		 */
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode HRTF descriptor file: '%s'", descriptorFilePath)
+		} else if numDescriptors == 0 {
+			return nil, fmt.Errorf("HRTF descriptor file '%s' does not list any directions.", descriptorFilePath)
+		} else {
+			azimuths := make([]float64, numDescriptors)
+			left := make([][]float64, numDescriptors)
+			right := make([][]float64, numDescriptors)
+			sampleRate := uint32(0)
+
+			/*
+			 * Load the responses of each direction.
+			 */
+			for i, descriptor := range descriptors {
+				wavePath := descriptor.Path
+				waveBuffer, err := os.ReadFile(wavePath)
+
+				/*
+				 * Check if file was read successfully.
+				 */
+				if err != nil {
+					return nil, fmt.Errorf("Failed to read HRTF file: '%s'", wavePath)
+				} else {
+					waveFile, err := wave.FromBuffer(waveBuffer)
+
+					/*
+					 * Check if file was parsed successfully.
+					 */
+					if err != nil {
+						msg := err.Error()
+						return nil, fmt.Errorf("Failed to decode HRTF file '%s': %s", wavePath, msg)
+					} else {
+						channelCount := waveFile.ChannelCount()
+						fileRate := waveFile.SampleRate()
+
+						/*
+						 * A head-related impulse response has one channel
+						 * for each ear and all of them share a sample rate.
+						 */
+						if channelCount != HRTF_CHANNEL_COUNT {
+							return nil, fmt.Errorf("HRTF file '%s' contains %d channels, expected: %d", wavePath, channelCount, HRTF_CHANNEL_COUNT)
+						} else if (sampleRate != 0) && (fileRate != sampleRate) {
+							return nil, fmt.Errorf("HRTF file '%s' has a sample rate of %d Hz, expected: %d Hz", wavePath, fileRate, sampleRate)
+						} else {
+							sampleRate = fileRate
+							channelLeft, _ := waveFile.Channel(0)
+							channelRight, _ := waveFile.Channel(1)
+							azimuths[i] = wrapAzimuth(descriptor.Azimuth)
+							left[i] = channelLeft.Floats()
+							right[i] = channelRight.Floats()
+						}
+
+					}
+
+				}
+
+			}
+
+			/*
+			 * Create the set of responses.
+			 */
+			hrtf := hrtfSetStruct{
+				azimuths:   azimuths,
+				sampleRate: sampleRate,
+				left:       left,
+				right:      right,
+			}
+
+			return &hrtf, nil
+		}
+
+	}
+
+}
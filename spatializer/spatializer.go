@@ -2,7 +2,9 @@ package spatializer
 
 import (
 	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/filter"
 	"math"
+	"sort"
 	"sync"
 )
 
@@ -22,22 +24,66 @@ const (
 	HALF_EFFECTIVE_DISTANCE = 0.5 * EFFECTIVE_DISTANCE
 	GROUP_DELAY             = 6.3e-4
 	OUTPUT_COUNT            = 2
+	STEREO_SPREAD           = 30.0
+	AUX_BUS_COUNT           = 2
+	MODE_STEREO             = "stereo"
+	MODE_BINAURAL           = "binaural"
+	LAYOUT_STEREO           = "stereo"
+	LAYOUT_QUAD             = "quad"
+	LAYOUT_SURROUND         = "5.1"
+	LFE_CUTOFF              = 120.0
 )
 
+/*
+ * Interface type for the processor of an aux bus, e.g. a signal chain.
+ */
+type BusProcessor interface {
+	ProcessStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32)
+}
+
 /*
  * Interface type for a spatializer.
  */
 type Spatializer interface {
+	AddChannel(stereo bool) uint32
 	GetAzimuth(inputChannel uint32) (float64, error)
+	GetBusCount() uint32
 	GetDistance(inputChannel uint32) (float64, error)
 	GetLevel(inputChannel uint32) (float64, error)
 	GetInputCount() uint32
+	GetMode() string
+	GetMute(inputChannel uint32) (bool, error)
 	GetOutputCount() uint32
+	GetReturn(bus uint32) (float64, error)
+	GetSend(inputChannel uint32, bus uint32) (float64, error)
+	GetSolo(inputChannel uint32) (bool, error)
+	GetSpeakerLayout() string
+	GetStereo(inputChannel uint32) (bool, error)
 	Process(inputBuffers [][]float64, auxInputBuffer []float64, outputBuffers [][]float64)
+	RemoveChannel(inputChannel uint32) error
 	SetAzimuth(inputChannel uint32, azimuth float64) error
+	SetBlockSize(frames uint32)
+	SetBusProcessor(bus uint32, processor BusProcessor) error
 	SetDistance(inputChannel uint32, distance float64) error
+	SetHrtf(hrtf Hrtf)
 	SetLevel(inputChannel uint32, level float64) error
+	SetMode(mode string) error
+	SetMute(inputChannel uint32, mute bool) error
+	SetReturn(bus uint32, level float64) error
 	SetSampleRate(rate uint32)
+	SetSend(inputChannel uint32, bus uint32, level float64) error
+	SetSolo(inputChannel uint32, solo bool) error
+	SetSpeakerLayout(layout string) error
+	SetStereo(inputChannel uint32, stereo bool) error
+}
+
+/*
+ * Data structure representing a loudspeaker of a speaker layout.
+ */
+type speakerStruct struct {
+	name    string
+	azimuth float64
+	lfe     bool
 }
 
 /*
@@ -47,17 +93,90 @@ type position struct {
 	azimuth  float64
 	distance float64
 	level    float64
+	mute     bool
+	sends    []float64
+	solo     bool
+	stereo   bool
+}
+
+/*
+ * Data structure representing an aux bus, which collects the signals sent
+ * from the channels, processes them and returns them to the master outputs.
+ */
+type busStruct struct {
+	processor      BusProcessor
+	returnLevel    float64
+	bufferInLeft   []float64
+	bufferInRight  []float64
+	bufferOutLeft  []float64
+	bufferOutRight []float64
+}
+
+/*
+ * Data structure representing a direction of the binaural renderer. The
+ * sources closest to it are mixed together and convolved with the
+ * head-related impulse responses of both ears.
+ */
+type directionStruct struct {
+	filterLeft  filter.Filter
+	filterRight filter.Filter
+	buffer      []float64
+	length      int
+	tail        int
+	used        bool
 }
 
 /*
  * Data structure representing a spatializer.
  */
 type spatializerStruct struct {
-	buffers    [][]float64
-	inputCount uint32
-	sampleRate uint32
-	mutex      sync.RWMutex
-	positions  []position
+	buffers     [][]float64
+	buses       []busStruct
+	inputCount  uint32
+	sampleRate  uint32
+	blockSize   uint32
+	mutex       sync.RWMutex
+	positions   []position
+	binaural    bool
+	layout      string
+	speakers    []speakerStruct
+	ring        []int
+	lfe         int
+	lfeState    float64
+	hrtf        Hrtf
+	azimuths    []float64
+	directions  []directionStruct
+	bufferLeft  []float64
+	bufferRight []float64
+}
+
+/*
+ * Adds an input channel after the existing ones and returns its index.
+ */
+func (this *spatializerStruct) AddChannel(stereo bool) uint32 {
+	sends := make([]float64, AUX_BUS_COUNT)
+
+	/*
+	 * The new channel starts at full level without any sends.
+	 */
+	p := position{
+		level:  1.0,
+		sends:  sends,
+		stereo: stereo,
+	}
+
+	this.mutex.Lock()
+	sampleRateFloat := float64(this.sampleRate)
+	bufferSizeFloat := math.Ceil(sampleRateFloat * GROUP_DELAY)
+	bufferSize := int(bufferSizeFloat)
+	bufferLeft := make([]float64, bufferSize)
+	bufferRight := make([]float64, bufferSize)
+	this.positions = append(this.positions, p)
+	this.buffers = append(this.buffers, bufferLeft, bufferRight)
+	inputChannel := this.inputCount
+	this.inputCount++
+	this.mutex.Unlock()
+	return inputChannel
 }
 
 /*
@@ -80,6 +199,13 @@ func (this *spatializerStruct) GetAzimuth(inputChannel uint32) (float64, error)
 
 }
 
+/*
+ * Returns the number of aux buses.
+ */
+func (this *spatializerStruct) GetBusCount() uint32 {
+	return AUX_BUS_COUNT
+}
+
 /*
  * Returns the distance value associated with a channel.
  */
@@ -120,6 +246,46 @@ func (this *spatializerStruct) GetLevel(inputChannel uint32) (float64, error) {
 
 }
 
+/*
+ * Returns whether the spatializer renders the sources for loudspeakers
+ * ("stereo") or for headphones ("binaural").
+ */
+func (this *spatializerStruct) GetMode() string {
+	this.mutex.RLock()
+	binaural := this.binaural
+	this.mutex.RUnlock()
+
+	/*
+	 * Check which mode is active.
+	 */
+	if binaural {
+		return MODE_BINAURAL
+	} else {
+		return MODE_STEREO
+	}
+
+}
+
+/*
+ * Returns whether a channel is muted.
+ */
+func (this *spatializerStruct) GetMute(inputChannel uint32) (bool, error) {
+	inputCount := this.inputCount
+
+	/*
+	 * Verify that the channel exists.
+	 */
+	if inputChannel >= inputCount {
+		return false, fmt.Errorf("Cannot get mute flag for channel %d: Only %d channels exist.", inputChannel, inputCount)
+	} else {
+		this.mutex.RLock()
+		mute := this.positions[inputChannel].mute
+		this.mutex.RUnlock()
+		return mute, nil
+	}
+
+}
+
 /*
  * Returns the number of input streams this spatializer processes.
  */
@@ -131,201 +297,341 @@ func (this *spatializerStruct) GetInputCount() uint32 {
  * Returns the number of output streams this spatializer generates.
  */
 func (this *spatializerStruct) GetOutputCount() uint32 {
-	return OUTPUT_COUNT
+	this.mutex.RLock()
+	numSpeakers := len(this.speakers)
+	this.mutex.RUnlock()
+	numSpeakers32 := uint32(numSpeakers)
+	return numSpeakers32
 }
 
 /*
- * Perform the spatializer audio processing.
+ * Returns the return level of an aux bus.
  */
-func (this *spatializerStruct) Process(inputBuffers [][]float64, auxInputBuffer []float64, outputBuffers [][]float64) {
-	nInputBuffers := len(inputBuffers)
-	nInputBuffers32 := uint32(nInputBuffers)
-	nOutputBuffers := len(outputBuffers)
+func (this *spatializerStruct) GetReturn(bus uint32) (float64, error) {
 
 	/*
-	 * Verify that we have as many input and output buffers as we expect.
+	 * Verify that the bus exists.
 	 */
-	if (nInputBuffers32 == this.inputCount) && (nOutputBuffers == OUTPUT_COUNT) {
-		sampleRateFloat := float64(this.sampleRate)
+	if bus >= AUX_BUS_COUNT {
+		return 0.0, fmt.Errorf("Cannot get return level for bus %d: Only %d buses exist.", bus, AUX_BUS_COUNT)
+	} else {
+		this.mutex.RLock()
+		level := this.buses[bus].returnLevel
+		this.mutex.RUnlock()
+		return level, nil
+	}
 
-		/*
-		 * Iterate over the output buffers.
-		 */
-		for _, buffer := range outputBuffers {
+}
 
-			/*
-			 * Iterate over the current buffer and zero it.
-			 */
-			for i, _ := range buffer {
-				buffer[i] = 0.0
-			}
+/*
+ * Returns the level at which a channel is sent to an aux bus.
+ */
+func (this *spatializerStruct) GetSend(inputChannel uint32, bus uint32) (float64, error) {
+	inputCount := this.inputCount
 
-		}
+	/*
+	 * Verify that the channel and the bus exist.
+	 */
+	if inputChannel >= inputCount {
+		return 0.0, fmt.Errorf("Cannot get send level for channel %d: Only %d channels exist.", inputChannel, inputCount)
+	} else if bus >= AUX_BUS_COUNT {
+		return 0.0, fmt.Errorf("Cannot get send level for bus %d: Only %d buses exist.", bus, AUX_BUS_COUNT)
+	} else {
+		this.mutex.RLock()
+		level := this.positions[inputChannel].sends[bus]
+		this.mutex.RUnlock()
+		return level, nil
+	}
+
+}
+
+/*
+ * Returns whether a channel is soloed.
+ */
+func (this *spatializerStruct) GetSolo(inputChannel uint32) (bool, error) {
+	inputCount := this.inputCount
+
+	/*
+	 * Verify that the channel exists.
+	 */
+	if inputChannel >= inputCount {
+		return false, fmt.Errorf("Cannot get solo flag for channel %d: Only %d channels exist.", inputChannel, inputCount)
+	} else {
+		this.mutex.RLock()
+		solo := this.positions[inputChannel].solo
+		this.mutex.RUnlock()
+		return solo, nil
+	}
+
+}
+
+/*
+ * Returns the speaker layout the sources are rendered for.
+ */
+func (this *spatializerStruct) GetSpeakerLayout() string {
+	this.mutex.RLock()
+	layout := this.layout
+	this.mutex.RUnlock()
+	return layout
+}
+
+/*
+ * Returns whether a channel carries a stereo signal.
+ */
+func (this *spatializerStruct) GetStereo(inputChannel uint32) (bool, error) {
+	inputCount := this.inputCount
 
+	/*
+	 * Verify that the channel exists.
+	 */
+	if inputChannel >= inputCount {
+		return false, fmt.Errorf("Cannot get stereo flag for channel %d: Only %d channels exist.", inputChannel, inputCount)
+	} else {
 		this.mutex.RLock()
+		stereo := this.positions[inputChannel].stereo
+		this.mutex.RUnlock()
+		return stereo, nil
+	}
+
+}
+
+/*
+ * Places a single audio source in space and mixes it into the output buffers.
+ */
+func (this *spatializerStruct) processSource(inputBuffer []float64, delayBuffer []float64, azimuthDegrees float64, distance float64, level float64, outputBuffers [][]float64) {
+	sampleRateFloat := float64(this.sampleRate)
+	azimuth := MATH_DEGREE_TO_RADIANS * azimuthDegrees
+	bufferSize := len(delayBuffer)
+	sinAz, cosAz := math.Sincos(azimuth)
+	xPosition := distance * sinAz
+	yPosition := distance * cosAz
+	xDistLeft := math.Abs(xPosition + (HALF_EFFECTIVE_DISTANCE))
+	xDistRight := math.Abs(xPosition - (HALF_EFFECTIVE_DISTANCE))
+	yDist := math.Abs(yPosition)
+	yDistSquared := yDist * yDist
+	xDistLeftSquared := xDistLeft * xDistLeft
+	distLeft := math.Sqrt(xDistLeftSquared + yDistSquared)
+	preLeft := 1.0 / distLeft
+
+	/*
+	 * Factors should not exceed unity.
+	 */
+	if preLeft > 1.0 {
+		preLeft = 1.0
+	}
+
+	facLeft := level * preLeft
+	xDistRightSquared := xDistRight * xDistRight
+	distRight := math.Sqrt(xDistRightSquared + yDistSquared)
+	preRight := 1.0 / distRight
+
+	/*
+	 * Factors should not exceed unity.
+	 */
+	if preRight > 1.0 {
+		preRight = 1.0
+	}
+
+	facRight := level * preRight
+	distDiff := distLeft - distRight
+	delayTime := (GROUP_DELAY / EFFECTIVE_DISTANCE) * distDiff
+	delayTimeAbs := math.Abs(delayTime)
+	delaySamples := delayTimeAbs * sampleRateFloat
+	delaySamplesEarly := math.Floor(delaySamples)
+	delaySamplesEarlyInt := int(delaySamplesEarly)
+
+	/*
+	 * Ensure that the delay does not exceed the buffer size.
+	 */
+	if delaySamplesEarlyInt >= bufferSize {
+		delaySamplesEarlyInt = bufferSize - 1
+	}
+
+	delaySamplesLate := math.Ceil(delaySamples)
+	delaySamplesLateInt := int(delaySamplesLate)
+
+	/*
+	 * Ensure that the delay does not exceed the buffer size.
+	 */
+	if delaySamplesLateInt >= bufferSize {
+		delaySamplesLateInt = bufferSize - 1
+	}
+
+	/*
+	 * Process each sample.
+	 */
+	for j, currentSample := range inputBuffer {
 
 		/*
-		 * Iterate over the input channels.
+		 * Perform simplified processing if delay time is exactly zero.
 		 */
-		for i, inputBuffer := range inputBuffers {
-			position := this.positions[i]
-			azimuth := MATH_DEGREE_TO_RADIANS * position.azimuth
-			distance := position.distance
-			level := position.level
-			currentBuffer := this.buffers[i]
-			bufferSize := len(currentBuffer)
-			sinAz, cosAz := math.Sincos(azimuth)
-			xPosition := distance * sinAz
-			yPosition := distance * cosAz
-			xDistLeft := math.Abs(xPosition + (HALF_EFFECTIVE_DISTANCE))
-			xDistRight := math.Abs(xPosition - (HALF_EFFECTIVE_DISTANCE))
-			yDist := math.Abs(yPosition)
-			yDistSquared := yDist * yDist
-			xDistLeftSquared := xDistLeft * xDistLeft
-			distLeft := math.Sqrt(xDistLeftSquared + yDistSquared)
-			preLeft := 1.0 / distLeft
+		if delayTime == 0.0 {
+			outputBuffers[0][j] += facLeft * currentSample
+			outputBuffers[1][j] += facRight * currentSample
+		} else {
+			delayedIdxEarly := j - delaySamplesEarlyInt
+			delayedIdxLate := j - delaySamplesLateInt
+			delayedSampleEarly := float64(0.0)
+			delayedSampleLate := float64(0.0)
 
 			/*
-			 * Factors should not exceed unity.
+			 * Check whether the delayed sample can be found in the current input
+			 * signal or the delay buffer.
 			 */
-			if preLeft > 1.0 {
-				preLeft = 1.0
+			if delayedIdxEarly >= 0 {
+				delayedSampleEarly = inputBuffer[delayedIdxEarly]
+			} else {
+				bufferPtr := bufferSize + delayedIdxEarly
+				delayedSampleEarly = delayBuffer[bufferPtr]
 			}
 
-			facLeft := level * preLeft
-			xDistRightSquared := xDistRight * xDistRight
-			distRight := math.Sqrt(xDistRightSquared + yDistSquared)
-			preRight := 1.0 / distRight
-
 			/*
-			 * Factors should not exceed unity.
+			 * Check whether the delayed sample can be found in the current input
+			 * signal or the delay buffer.
 			 */
-			if preRight > 1.0 {
-				preRight = 1.0
+			if delayedIdxLate >= 0 {
+				delayedSampleLate = inputBuffer[delayedIdxLate]
+			} else {
+				bufferPtr := bufferSize + delayedIdxLate
+				delayedSampleLate = delayBuffer[bufferPtr]
 			}
 
-			facRight := level * preRight
-			distDiff := distLeft - distRight
-			delayTime := (GROUP_DELAY / EFFECTIVE_DISTANCE) * distDiff
-			delayTimeAbs := math.Abs(delayTime)
-			delaySamples := delayTimeAbs * sampleRateFloat
-			delaySamplesEarly := math.Floor(delaySamples)
-			delaySamplesEarlyInt := int(delaySamplesEarly)
+			weightEarly := 1.0 - (delaySamples - delaySamplesEarly)
+			weightLate := 1.0 - (delaySamplesLate - delaySamples)
+			earlySample := weightEarly * delayedSampleEarly
+			lateSample := weightLate * delayedSampleLate
+			delayedSample := earlySample + lateSample
 
 			/*
-			 * Ensure that the delay does not exceed the buffer size.
+			 * When the delay time is positive, the left channel is delayed.
+			 * When the delay time is negative, the right channel is delayed.
 			 */
-			if delaySamplesEarlyInt >= bufferSize {
-				delaySamplesEarlyInt = bufferSize - 1
+			if delayTime > 0.0 {
+				outputBuffers[0][j] += facLeft * delayedSample
+				outputBuffers[1][j] += facRight * inputBuffer[j]
+			} else {
+				outputBuffers[0][j] += facLeft * inputBuffer[j]
+				outputBuffers[1][j] += facRight * delayedSample
 			}
 
-			delaySamplesLate := math.Ceil(delaySamples)
-			delaySamplesLateInt := int(delaySamplesLate)
+		}
 
-			/*
-			 * Ensure that the delay does not exceed the buffer size.
-			 */
-			if delaySamplesLateInt >= bufferSize {
-				delaySamplesLateInt = bufferSize - 1
-			}
+	}
 
-			/*
-			 * Process each sample.
-			 */
-			for j, currentSample := range inputBuffer {
+	numSamples := len(inputBuffer)
+	boundary := bufferSize - numSamples
 
-				/*
-				 * Perform simplified processing if delay time is exactly zero.
-				 */
-				if delayTime == 0.0 {
-					outputBuffers[0][j] += facLeft * currentSample
-					outputBuffers[1][j] += facRight * currentSample
-				} else {
-					delayedIdxEarly := j - delaySamplesEarlyInt
-					delayedIdxLate := j - delaySamplesLateInt
-					delayedSampleEarly := float64(0.0)
-					delayedSampleLate := float64(0.0)
+	/*
+	 * Check whether our buffer is larger than the number of samples processed.
+	 */
+	if boundary >= 0 {
+		copy(delayBuffer[0:boundary], delayBuffer[numSamples:bufferSize])
+		copy(delayBuffer[boundary:bufferSize], inputBuffer)
+	} else {
+		copy(delayBuffer, inputBuffer[-boundary:numSamples])
+	}
 
-					/*
-					 * Check whether the delayed sample can be found in the current input
-					 * signal or the delay buffer.
-					 */
-					if delayedIdxEarly >= 0 {
-						delayedSampleEarly = inputBuffer[delayedIdxEarly]
-					} else {
-						bufferPtr := bufferSize + delayedIdxEarly
-						delayedSampleEarly = currentBuffer[bufferPtr]
-					}
+}
 
-					/*
-					 * Check whether the delayed sample can be found in the current input
-					 * signal or the delay buffer.
-					 */
-					if delayedIdxLate >= 0 {
-						delayedSampleLate = inputBuffer[delayedIdxLate]
-					} else {
-						bufferPtr := bufferSize + delayedIdxLate
-						delayedSampleLate = currentBuffer[bufferPtr]
-					}
+/*
+ * Places a single audio source between the two loudspeakers of a surround
+ * layout enclosing its azimuth, using vector base amplitude panning (VBAP),
+ * and sends it to the subwoofer, if there is one.
+ */
+func (this *spatializerStruct) processSurroundSource(inputBuffer []float64, azimuthDegrees float64, distance float64, level float64, outputBuffers [][]float64) {
+	gain := level
 
-					weightEarly := 1.0 - (delaySamples - delaySamplesEarly)
-					weightLate := 1.0 - (delaySamplesLate - delaySamples)
-					earlySample := weightEarly * delayedSampleEarly
-					lateSample := weightLate * delayedSampleLate
-					delayedSample := earlySample + lateSample
+	/*
+	 * Sources further away than one meter are attenuated.
+	 */
+	if distance > 1.0 {
+		gain = level / distance
+	}
 
-					/*
-					 * When the delay time is positive, the left channel is delayed.
-					 * When the delay time is negative, the right channel is delayed.
-					 */
-					if delayTime > 0.0 {
-						outputBuffers[0][j] += facLeft * delayedSample
-						outputBuffers[1][j] += facRight * inputBuffer[j]
-					} else {
-						outputBuffers[0][j] += facLeft * inputBuffer[j]
-						outputBuffers[1][j] += facRight * delayedSample
-					}
+	/*
+	 * Only mix the source if it is audible.
+	 */
+	if gain > 0.0 {
+		speakers := this.speakers
+		ring := this.ring
+		numRing := len(ring)
+		first := -1
+		second := -1
 
-				}
+		/*
+		 * Find the pair of adjacent speakers enclosing the source.
+		 */
+		for i := 0; (i < numRing) && (first < 0); i++ {
+			current := ring[i]
+			next := ring[(i+1)%numRing]
+			azimuthCurrent := speakers[current].azimuth
+			azimuthNext := speakers[next].azimuth
+			span := positiveAngle(azimuthNext - azimuthCurrent)
+			offset := positiveAngle(azimuthDegrees - azimuthCurrent)
 
+			/*
+			 * Check if the source is between these speakers.
+			 */
+			if offset <= span {
+				first = current
+				second = next
 			}
 
 		}
 
-		this.mutex.RUnlock()
-
 		/*
-		 * If we have an aux input, mix it in as well.
+		 * Pan the source between the pair of speakers.
 		 */
-		if auxInputBuffer != nil {
+		if first >= 0 {
+			azimuth := MATH_DEGREE_TO_RADIANS * azimuthDegrees
+			azimuthFirst := MATH_DEGREE_TO_RADIANS * speakers[first].azimuth
+			azimuthSecond := MATH_DEGREE_TO_RADIANS * speakers[second].azimuth
+			sinAz, cosAz := math.Sincos(azimuth)
+			sinFirst, cosFirst := math.Sincos(azimuthFirst)
+			sinSecond, cosSecond := math.Sincos(azimuthSecond)
+			det := (sinFirst * cosSecond) - (sinSecond * cosFirst)
+			gainFirst := ((sinAz * cosSecond) - (sinSecond * cosAz)) / det
+			gainSecond := ((sinFirst * cosAz) - (sinAz * cosFirst)) / det
+			gainFirst = math.Max(gainFirst, 0.0)
+			gainSecond = math.Max(gainSecond, 0.0)
+			norm := math.Hypot(gainFirst, gainSecond)
 
 			/*
-			 * Process each sample.
+			 * Keep the power of the source constant.
 			 */
-			for j, sample := range auxInputBuffer {
-				outputBuffers[0][j] += sample
-				outputBuffers[1][j] += sample
+			if norm > 0.0 {
+				gainFirst /= norm
+				gainSecond /= norm
+			}
+
+			facFirst := gain * gainFirst
+			facSecond := gain * gainSecond
+			outputFirst := outputBuffers[first]
+			outputSecond := outputBuffers[second]
+
+			/*
+			 * Mix the signal into both speakers.
+			 */
+			for i, sample := range inputBuffer {
+				outputFirst[i] += facFirst * sample
+				outputSecond[i] += facSecond * sample
 			}
 
 		}
 
+		lfe := this.lfe
+
 		/*
-		 * Iterate over the input channels again to update all buffers.
+		 * Send the source to the subwoofer.
 		 */
-		for i, inputBuffer := range inputBuffers {
-			numSamples := len(inputBuffer)
-			currentBuffer := this.buffers[i]
-			bufferSize := len(currentBuffer)
-			boundary := bufferSize - numSamples
+		if lfe >= 0 {
+			outputLfe := outputBuffers[lfe]
 
 			/*
-			 * Check whether our buffer is larger than the number of samples processed.
+			 * Mix the signal into the subwoofer.
 			 */
-			if boundary >= 0 {
-				copy(currentBuffer[0:boundary], currentBuffer[numSamples:bufferSize])
-				copy(currentBuffer[boundary:bufferSize], inputBuffer)
-			} else {
-				copy(currentBuffer, inputBuffer[-boundary:numSamples])
+			for i, sample := range inputBuffer {
+				outputLfe[i] += gain * sample
 			}
 
 		}
@@ -335,64 +641,661 @@ func (this *spatializerStruct) Process(inputBuffers [][]float64, auxInputBuffer
 }
 
 /*
- * Sets the azimuth of the audio source associated with a certain channel.
+ * Passes the signal of the subwoofer, if there is one, through a low-pass
+ * filter.
  */
-func (this *spatializerStruct) SetAzimuth(inputChannel uint32, azimuth float64) error {
-	inputCount := this.inputCount
+func (this *spatializerStruct) filterLfe(outputBuffers [][]float64) {
+	lfe := this.lfe
 
 	/*
-	 * Verify that the channel exists.
+	 * Check if the layout has a subwoofer.
 	 */
-	if inputChannel > inputCount {
-		return fmt.Errorf("Cannot set azimuth for channel %d: Only %d channels exist.", inputChannel, inputCount)
-	} else {
-		this.mutex.Lock()
-		this.positions[inputChannel].azimuth = azimuth
-		this.mutex.Unlock()
-		return nil
+	if lfe >= 0 {
+		sampleRateFloat := float64(this.sampleRate)
+		coefficient := math.Exp(-2.0 * math.Pi * LFE_CUTOFF / sampleRateFloat)
+		state := this.lfeState
+		outputLfe := outputBuffers[lfe]
+
+		/*
+		 * Filter each sample.
+		 */
+		for i, sample := range outputLfe {
+			state = ((1.0 - coefficient) * sample) + (coefficient * state)
+			outputLfe[i] = state
+		}
+
+		this.lfeState = state
 	}
 
 }
 
 /*
- * Sets the distance of the audio source associated with a certain channel.
+ * Places a single audio source using the renderer for the current mode and
+ * speaker layout.
  */
-func (this *spatializerStruct) SetDistance(inputChannel uint32, distance float64) error {
-	inputCount := this.inputCount
+func (this *spatializerStruct) placeSource(inputBuffer []float64, delayBuffer []float64, azimuth float64, distance float64, level float64, outputBuffers [][]float64) {
 
 	/*
-	 * Verify that the channel exists.
+	 * Check how sources are rendered.
 	 */
-	if inputChannel > inputCount {
-		return fmt.Errorf("Cannot set distance for channel %d: Only %d channels exist.", inputChannel, inputCount)
+	if this.binaural {
+		this.mixBinauralSource(inputBuffer, azimuth, distance, level)
+	} else if len(this.speakers) > OUTPUT_COUNT {
+		this.processSurroundSource(inputBuffer, azimuth, distance, level, outputBuffers)
 	} else {
+		this.processSource(inputBuffer, delayBuffer, azimuth, distance, level, outputBuffers)
+	}
+
+}
+
+/*
+ * Mixes a single audio source into the direction of the binaural renderer
+ * closest to its azimuth.
+ */
+func (this *spatializerStruct) mixBinauralSource(inputBuffer []float64, azimuth float64, distance float64, level float64) {
+	idx := nearestDirection(this.azimuths, azimuth)
+	gain := level
+
+	/*
+	 * Sources further away than one meter are attenuated.
+	 */
+	if distance > 1.0 {
+		gain = level / distance
+	}
+
+	/*
+	 * Only mix the source if there is a direction and it is audible.
+	 */
+	if (idx >= 0) && (gain > 0.0) {
+		direction := &this.directions[idx]
 
 		/*
-		 * Verify that the distance is within limits.
+		 * Mix the signal into the direction.
 		 */
-		if distance < 0.0 || distance > 10.0 {
-			return fmt.Errorf("%s", "Failed to set distance: Value must be within [0, 10].")
-		} else {
-			this.mutex.Lock()
-			this.positions[inputChannel].distance = distance
-			this.mutex.Unlock()
-			return nil
+		for i, sample := range inputBuffer {
+			direction.buffer[i] += gain * sample
 		}
 
+		direction.used = true
 	}
 
 }
 
 /*
- * Sets the level of the audio source associated with a certain channel.
+ * Convolves the signal of each direction of the binaural renderer with the
+ * head-related impulse responses and mixes it into the output buffers.
+ *
+ * Directions without any sources are processed until the tails of their
+ * filters decayed.
  */
-func (this *spatializerStruct) SetLevel(inputChannel uint32, level float64) error {
-	inputCount := this.inputCount
+func (this *spatializerStruct) renderBinaural(outputBuffers [][]float64) {
+	bufferLeft := this.bufferLeft
+	bufferRight := this.bufferRight
+	numSamples := len(bufferLeft)
 
 	/*
-	 * Verify that the channel exists.
+	 * Process each direction.
 	 */
-	if inputChannel > inputCount {
+	for i := range this.directions {
+		direction := &this.directions[i]
+
+		/*
+		 * Sources restart the tail of the filters.
+		 */
+		if direction.used {
+			direction.tail = direction.length + numSamples
+		}
+
+		/*
+		 * Only process directions which are audible.
+		 */
+		if direction.tail > 0 {
+			direction.filterLeft.Process(direction.buffer, bufferLeft)
+			direction.filterRight.Process(direction.buffer, bufferRight)
+
+			/*
+			 * Mix the signal into the outputs.
+			 */
+			for j, sample := range bufferLeft {
+				outputBuffers[0][j] += sample
+				outputBuffers[1][j] += bufferRight[j]
+			}
+
+			/*
+			 * Without sources, the tail decays.
+			 */
+			if !direction.used {
+				direction.tail -= numSamples
+			}
+
+		}
+
+		direction.used = false
+	}
+
+}
+
+/*
+ * Perform the spatializer audio processing.
+ *
+ * There is one input buffer for each mono channel and two consecutive input
+ * buffers (left and right) for each stereo channel.
+ */
+func (this *spatializerStruct) Process(inputBuffers [][]float64, auxInputBuffer []float64, outputBuffers [][]float64) {
+	nInputBuffers := len(inputBuffers)
+	nOutputBuffers := len(outputBuffers)
+	this.mutex.RLock()
+	nPorts := 0
+
+	/*
+	 * Count the number of input buffers we expect.
+	 */
+	for _, position := range this.positions {
+
+		/*
+		 * Stereo channels need two input buffers.
+		 */
+		if position.stereo {
+			nPorts += 2
+		} else {
+			nPorts++
+		}
+
+	}
+
+	/*
+	 * Verify that we have as many input and output buffers as we expect.
+	 */
+	if (nInputBuffers == nPorts) && (nOutputBuffers == len(this.speakers)) {
+
+		/*
+		 * Iterate over the output buffers.
+		 */
+		for _, buffer := range outputBuffers {
+
+			/*
+			 * Iterate over the current buffer and zero it.
+			 */
+			for i, _ := range buffer {
+				buffer[i] = 0.0
+			}
+
+		}
+
+		numSamples := 0
+
+		/*
+		 * Find the number of samples to process.
+		 */
+		if nInputBuffers > 0 {
+			numSamples = len(inputBuffers[0])
+		}
+
+		/*
+		 * Prepare the inputs of each aux bus.
+		 */
+		for i := range this.buses {
+			bus := &this.buses[i]
+
+			/*
+			 * Make sure that the buffers of the bus have the appropriate
+			 * size. They are allocated by SetBlockSize, so this only
+			 * happens if the size changed without it being called.
+			 */
+			if len(bus.bufferInLeft) != numSamples {
+				bus.bufferInLeft = make([]float64, numSamples)
+				bus.bufferInRight = make([]float64, numSamples)
+				bus.bufferOutLeft = make([]float64, numSamples)
+				bus.bufferOutRight = make([]float64, numSamples)
+			} else {
+
+				/*
+				 * Clear the input buffers.
+				 */
+				for j := range bus.bufferInLeft {
+					bus.bufferInLeft[j] = 0.0
+					bus.bufferInRight[j] = 0.0
+				}
+
+			}
+
+		}
+
+		binaural := this.binaural
+
+		/*
+		 * Prepare the buffers of the binaural renderer.
+		 */
+		if binaural {
+
+			/*
+			 * Make sure that the buffers have the appropriate size.
+			 * They are allocated for the block size, so this only
+			 * happens if the size changed without it being set.
+			 */
+			if len(this.bufferLeft) != numSamples {
+				this.bufferLeft = make([]float64, numSamples)
+				this.bufferRight = make([]float64, numSamples)
+			}
+
+			/*
+			 * Prepare the buffer of each direction.
+			 */
+			for i := range this.directions {
+				direction := &this.directions[i]
+
+				/*
+				 * Make sure that the buffer has the appropriate size,
+				 * otherwise clear it.
+				 */
+				if len(direction.buffer) != numSamples {
+					direction.buffer = make([]float64, numSamples)
+				} else {
+
+					/*
+					 * Clear the buffer.
+					 */
+					for j := range direction.buffer {
+						direction.buffer[j] = 0.0
+					}
+
+				}
+
+			}
+
+		}
+
+		soloed := false
+
+		/*
+		 * Find out whether any channel is soloed.
+		 */
+		for _, position := range this.positions {
+			soloed = soloed || position.solo
+		}
+
+		port := 0
+
+		/*
+		 * Iterate over the input channels.
+		 */
+		for i, position := range this.positions {
+			portRight := port
+			level := position.level
+
+			/*
+			 * Silence muted channels and, if any channel is soloed, all
+			 * channels which are not. They are still processed, so that
+			 * their delay lines stay up to date.
+			 */
+			if position.mute || (soloed && !position.solo) {
+				level = 0.0
+			}
+
+			/*
+			 * The right channel of a stereo source is on the next port.
+			 */
+			if position.stereo {
+				portRight = port + 1
+			}
+
+			/*
+			 * Send the signal of the channel to the aux buses.
+			 */
+			for j, send := range position.sends {
+
+				/*
+				 * Only mix the signal into the bus if it is actually sent.
+				 */
+				if (send > 0.0) && (level > 0.0) {
+					fac := send * level
+					bus := &this.buses[j]
+					inLeft := inputBuffers[port]
+					inRight := inputBuffers[portRight]
+
+					/*
+					 * Mix the signal into the bus.
+					 */
+					for k, sample := range inLeft {
+						bus.bufferInLeft[k] += fac * sample
+						bus.bufferInRight[k] += fac * inRight[k]
+					}
+
+				}
+
+			}
+
+			azimuth := position.azimuth
+			distance := position.distance
+			idxLeft := 2 * i
+			idxRight := idxLeft + 1
+
+			/*
+			 * Stereo channels are placed as two sources, spread around
+			 * the azimuth of the channel.
+			 */
+			if position.stereo {
+				azimuthLeft := azimuth - STEREO_SPREAD
+				azimuthRight := azimuth + STEREO_SPREAD
+
+				this.placeSource(inputBuffers[port], this.buffers[idxLeft], azimuthLeft, distance, level, outputBuffers)
+				this.placeSource(inputBuffers[portRight], this.buffers[idxRight], azimuthRight, distance, level, outputBuffers)
+				port += 2
+			} else {
+				this.placeSource(inputBuffers[port], this.buffers[idxLeft], azimuth, distance, level, outputBuffers)
+				port++
+			}
+
+		}
+
+		/*
+		 * Only the low frequencies reach the subwoofer.
+		 */
+		if !binaural {
+			this.filterLfe(outputBuffers)
+		}
+
+		/*
+		 * Convolve the directions of the binaural renderer.
+		 */
+		if binaural {
+			this.renderBinaural(outputBuffers)
+		}
+
+		/*
+		 * Process each aux bus and mix its return into the outputs.
+		 */
+		for i := range this.buses {
+			bus := &this.buses[i]
+			processor := bus.processor
+
+			/*
+			 * Only buses with a processor return a signal.
+			 */
+			if processor != nil {
+				returnLevel := bus.returnLevel
+				processor.ProcessStereo(bus.bufferInLeft, bus.bufferInRight, bus.bufferOutLeft, bus.bufferOutRight, this.sampleRate)
+
+				/*
+				 * Mix the return signal into the outputs.
+				 */
+				for j, sample := range bus.bufferOutLeft {
+					outputBuffers[0][j] += returnLevel * sample
+					outputBuffers[1][j] += returnLevel * bus.bufferOutRight[j]
+				}
+
+			}
+
+		}
+
+		/*
+		 * If we have an aux input, mix it in as well.
+		 */
+		if auxInputBuffer != nil {
+
+			/*
+			 * Process each sample.
+			 */
+			for j, sample := range auxInputBuffer {
+				outputBuffers[0][j] += sample
+				outputBuffers[1][j] += sample
+			}
+
+		}
+
+	} else {
+
+		/*
+		 * The buffers do not match the channels, e.g. while channels
+		 * are added or removed, so output silence.
+		 */
+		for _, buffer := range outputBuffers {
+
+			/*
+			 * Iterate over the current buffer and zero it.
+			 */
+			for i := range buffer {
+				buffer[i] = 0.0
+			}
+
+		}
+
+	}
+
+	this.mutex.RUnlock()
+}
+
+/*
+ * Creates the filters of the binaural renderer, if it is enabled, and
+ * switches between loudspeaker and headphone rendering.
+ *
+ * Creating the filters takes time, so it happens before they are handed to
+ * the audio thread.
+ */
+func (this *spatializerStruct) prepareBinaural(binaural bool) {
+	this.mutex.RLock()
+	hrtf := this.hrtf
+	sampleRate := this.sampleRate
+	frames := this.blockSize
+	this.mutex.RUnlock()
+	n := int(frames)
+	azimuths := []float64(nil)
+	directions := []directionStruct(nil)
+	bufferLeft := []float64(nil)
+	bufferRight := []float64(nil)
+
+	/*
+	 * Only create filters if sources are rendered for headphones.
+	 */
+	if binaural {
+		azimuths = hrtf.Azimuths()
+		left, right := hrtf.Responses(sampleRate)
+		numDirections := len(azimuths)
+		directions = make([]directionStruct, numDirections)
+		bufferLeft = make([]float64, n)
+		bufferRight = make([]float64, n)
+
+		/*
+		 * Create the filters of each direction.
+		 */
+		for i := range directions {
+			coefficientsLeft := left[i]
+			coefficientsRight := right[i]
+			length := len(coefficientsLeft)
+			lengthRight := len(coefficientsRight)
+
+			/*
+			 * The tail lasts as long as the longer response.
+			 */
+			if lengthRight > length {
+				length = lengthRight
+			}
+
+			filterLeft := filter.FromCoefficients(coefficientsLeft, sampleRate, "hrtf_left")
+			filterLeft.Prepare(n)
+			filterRight := filter.FromCoefficients(coefficientsRight, sampleRate, "hrtf_right")
+			filterRight.Prepare(n)
+			buffer := make([]float64, n)
+
+			/*
+			 * Create the direction.
+			 */
+			directions[i] = directionStruct{
+				filterLeft:  filterLeft,
+				filterRight: filterRight,
+				buffer:      buffer,
+				length:      length,
+			}
+
+		}
+
+	}
+
+	this.mutex.Lock()
+	this.binaural = binaural
+	this.azimuths = azimuths
+	this.directions = directions
+	this.bufferLeft = bufferLeft
+	this.bufferRight = bufferRight
+	this.mutex.Unlock()
+}
+
+/*
+ * Removes an input channel. The channels after it move down by one.
+ */
+func (this *spatializerStruct) RemoveChannel(inputChannel uint32) error {
+	inputCount := this.inputCount
+
+	/*
+	 * Verify that the channel exists.
+	 */
+	if inputChannel >= inputCount {
+		return fmt.Errorf("Cannot remove channel %d: Only %d channels exist.", inputChannel, inputCount)
+	} else {
+		this.mutex.Lock()
+		positions := []position{}
+		positions = append(positions, this.positions[:inputChannel]...)
+		positions = append(positions, this.positions[inputChannel+1:]...)
+		idxLeft := 2 * inputChannel
+		idxNext := idxLeft + 2
+		buffers := [][]float64{}
+		buffers = append(buffers, this.buffers[:idxLeft]...)
+		buffers = append(buffers, this.buffers[idxNext:]...)
+		this.positions = positions
+		this.buffers = buffers
+		this.inputCount--
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Sets the azimuth of the audio source associated with a certain channel.
+ */
+func (this *spatializerStruct) SetAzimuth(inputChannel uint32, azimuth float64) error {
+	inputCount := this.inputCount
+
+	/*
+	 * Verify that the channel exists.
+	 */
+	if inputChannel > inputCount {
+		return fmt.Errorf("Cannot set azimuth for channel %d: Only %d channels exist.", inputChannel, inputCount)
+	} else {
+		this.mutex.Lock()
+		this.positions[inputChannel].azimuth = azimuth
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Sets the number of frames processed at once and allocates the buffers of
+ * the aux buses for it, so that they need not be allocated while processing.
+ */
+func (this *spatializerStruct) SetBlockSize(frames uint32) {
+	n := int(frames)
+	numBuses := len(this.buses)
+	buffers := make([][]float64, 4*numBuses)
+
+	/*
+	 * Allocate the buffers outside of the lock.
+	 */
+	for i := range buffers {
+		buffers[i] = make([]float64, n)
+	}
+
+	this.mutex.Lock()
+
+	/*
+	 * Assign the buffers to each aux bus.
+	 */
+	for i := range this.buses {
+		bus := &this.buses[i]
+		offset := 4 * i
+		bus.bufferInLeft = buffers[offset]
+		bus.bufferInRight = buffers[offset+1]
+		bus.bufferOutLeft = buffers[offset+2]
+		bus.bufferOutRight = buffers[offset+3]
+	}
+
+	this.blockSize = frames
+	binaural := this.binaural
+	this.mutex.Unlock()
+	this.prepareBinaural(binaural)
+}
+
+/*
+ * Sets the processor of an aux bus.
+ */
+func (this *spatializerStruct) SetBusProcessor(bus uint32, processor BusProcessor) error {
+
+	/*
+	 * Verify that the bus exists.
+	 */
+	if bus >= AUX_BUS_COUNT {
+		return fmt.Errorf("Cannot set processor for bus %d: Only %d buses exist.", bus, AUX_BUS_COUNT)
+	} else {
+		this.mutex.Lock()
+		this.buses[bus].processor = processor
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Sets the distance of the audio source associated with a certain channel.
+ */
+func (this *spatializerStruct) SetDistance(inputChannel uint32, distance float64) error {
+	inputCount := this.inputCount
+
+	/*
+	 * Verify that the channel exists.
+	 */
+	if inputChannel > inputCount {
+		return fmt.Errorf("Cannot set distance for channel %d: Only %d channels exist.", inputChannel, inputCount)
+	} else {
+
+		/*
+		 * Verify that the distance is within limits.
+		 */
+		if distance < 0.0 || distance > 10.0 {
+			return fmt.Errorf("%s", "Failed to set distance: Value must be within [0, 10].")
+		} else {
+			this.mutex.Lock()
+			this.positions[inputChannel].distance = distance
+			this.mutex.Unlock()
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Sets the head-related transfer functions used to render the sources for
+ * headphones.
+ */
+func (this *spatializerStruct) SetHrtf(hrtf Hrtf) {
+	this.mutex.Lock()
+	this.hrtf = hrtf
+	binaural := this.binaural
+	this.mutex.Unlock()
+	this.prepareBinaural(binaural)
+}
+
+/*
+ * Sets the level of the audio source associated with a certain channel.
+ */
+func (this *spatializerStruct) SetLevel(inputChannel uint32, level float64) error {
+	inputCount := this.inputCount
+
+	/*
+	 * Verify that the channel exists.
+	 */
+	if inputChannel > inputCount {
 		return fmt.Errorf("Cannot set distance for channel %d: Only %d channels exist.", inputChannel, inputCount)
 	} else {
 
@@ -412,6 +1315,78 @@ func (this *spatializerStruct) SetLevel(inputChannel uint32, level float64) erro
 
 }
 
+/*
+ * Selects whether the sources are rendered for loudspeakers ("stereo"), using
+ * level and time differences between the outputs, or for headphones
+ * ("binaural"), using head-related transfer functions.
+ */
+func (this *spatializerStruct) SetMode(mode string) error {
+
+	/*
+	 * Check which mode should be selected.
+	 */
+	switch mode {
+	case MODE_STEREO:
+		this.prepareBinaural(false)
+		return nil
+	case MODE_BINAURAL:
+		this.prepareBinaural(true)
+		return nil
+	default:
+		return fmt.Errorf("Spatializer mode must be '%s' or '%s', got '%s'.", MODE_STEREO, MODE_BINAURAL, mode)
+	}
+
+}
+
+/*
+ * Sets whether a channel is muted. Muted channels are neither heard on the
+ * master outputs nor sent to the aux buses.
+ */
+func (this *spatializerStruct) SetMute(inputChannel uint32, mute bool) error {
+	inputCount := this.inputCount
+
+	/*
+	 * Verify that the channel exists.
+	 */
+	if inputChannel >= inputCount {
+		return fmt.Errorf("Cannot set mute flag for channel %d: Only %d channels exist.", inputChannel, inputCount)
+	} else {
+		this.mutex.Lock()
+		this.positions[inputChannel].mute = mute
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Sets the return level of an aux bus.
+ */
+func (this *spatializerStruct) SetReturn(bus uint32, level float64) error {
+
+	/*
+	 * Verify that the bus exists.
+	 */
+	if bus >= AUX_BUS_COUNT {
+		return fmt.Errorf("Cannot set return level for bus %d: Only %d buses exist.", bus, AUX_BUS_COUNT)
+	} else {
+
+		/*
+		 * Verify that the level is within limits.
+		 */
+		if level < 0.0 || level > 1.0 {
+			return fmt.Errorf("%s", "Failed to set return level: Value must be within [0, 1].")
+		} else {
+			this.mutex.Lock()
+			this.buses[bus].returnLevel = level
+			this.mutex.Unlock()
+			return nil
+		}
+
+	}
+
+}
+
 /*
  * Changes the sample rate and recreates all inner buffers.
  */
@@ -419,15 +1394,244 @@ func (this *spatializerStruct) SetSampleRate(rate uint32) {
 	sampleRateFloat := float64(rate)
 	bufferSizeFloat := math.Ceil(sampleRateFloat * GROUP_DELAY)
 	bufferSize := int(bufferSizeFloat)
-	inputChannels := this.inputCount
+	this.mutex.Lock()
+	this.sampleRate = rate
 
 	/*
 	 * Create each inner buffer.
 	 */
-	for i := uint32(0); i < inputChannels; i++ {
+	for i := range this.buffers {
 		this.buffers[i] = make([]float64, bufferSize)
 	}
 
+	binaural := this.binaural
+	this.mutex.Unlock()
+	this.prepareBinaural(binaural)
+}
+
+/*
+ * Sets the level at which a channel is sent to an aux bus.
+ */
+func (this *spatializerStruct) SetSend(inputChannel uint32, bus uint32, level float64) error {
+	inputCount := this.inputCount
+
+	/*
+	 * Verify that the channel and the bus exist.
+	 */
+	if inputChannel >= inputCount {
+		return fmt.Errorf("Cannot set send level for channel %d: Only %d channels exist.", inputChannel, inputCount)
+	} else if bus >= AUX_BUS_COUNT {
+		return fmt.Errorf("Cannot set send level for bus %d: Only %d buses exist.", bus, AUX_BUS_COUNT)
+	} else {
+
+		/*
+		 * Verify that the level is within limits.
+		 */
+		if level < 0.0 || level > 1.0 {
+			return fmt.Errorf("%s", "Failed to set send level: Value must be within [0, 1].")
+		} else {
+			this.mutex.Lock()
+			this.positions[inputChannel].sends[bus] = level
+			this.mutex.Unlock()
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Sets whether a channel is soloed. As long as any channel is soloed, only
+ * the soloed channels are heard.
+ */
+func (this *spatializerStruct) SetSolo(inputChannel uint32, solo bool) error {
+	inputCount := this.inputCount
+
+	/*
+	 * Verify that the channel exists.
+	 */
+	if inputChannel >= inputCount {
+		return fmt.Errorf("Cannot set solo flag for channel %d: Only %d channels exist.", inputChannel, inputCount)
+	} else {
+		this.mutex.Lock()
+		this.positions[inputChannel].solo = solo
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Selects the speaker layout the sources are rendered for, which determines
+ * the number of outputs. The first two outputs are always the front left and
+ * right speaker.
+ */
+func (this *spatializerStruct) SetSpeakerLayout(layout string) error {
+	speakers, err := layoutSpeakers(layout)
+
+	/*
+	 * Check if the layout is supported.
+	 */
+	if err != nil {
+		return err
+	} else {
+		ring := []int{}
+		lfe := -1
+
+		/*
+		 * Separate the subwoofer from the other speakers.
+		 */
+		for i, speaker := range speakers {
+
+			/*
+			 * Check if this is the subwoofer.
+			 */
+			if speaker.lfe {
+				lfe = i
+			} else {
+				ring = append(ring, i)
+			}
+
+		}
+
+		/*
+		 * Sort the other speakers by their azimuth.
+		 */
+		sort.Slice(ring, func(i int, j int) bool {
+			azimuthA := speakers[ring[i]].azimuth
+			azimuthB := speakers[ring[j]].azimuth
+			return azimuthA < azimuthB
+		})
+
+		this.mutex.Lock()
+		this.layout = layout
+		this.speakers = speakers
+		this.ring = ring
+		this.lfe = lfe
+		this.lfeState = 0.0
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Sets whether a channel carries a stereo signal.
+ */
+func (this *spatializerStruct) SetStereo(inputChannel uint32, stereo bool) error {
+	inputCount := this.inputCount
+
+	/*
+	 * Verify that the channel exists.
+	 */
+	if inputChannel >= inputCount {
+		return fmt.Errorf("Cannot set stereo flag for channel %d: Only %d channels exist.", inputChannel, inputCount)
+	} else {
+		this.mutex.Lock()
+		this.positions[inputChannel].stereo = stereo
+		this.mutex.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Returns an angle (in degrees) within the range from 0 to 360 degrees.
+ */
+func positiveAngle(angle float64) float64 {
+	result := math.Mod(angle, 360.0)
+
+	/*
+	 * The remainder keeps the sign of the dividend.
+	 */
+	if result < 0.0 {
+		result += 360.0
+	}
+
+	return result
+}
+
+/*
+ * Returns the loudspeakers of a speaker layout in the order of the outputs.
+ */
+func layoutSpeakers(layout string) ([]speakerStruct, error) {
+
+	/*
+	 * Check which layout is requested.
+	 */
+	switch layout {
+	case LAYOUT_STEREO:
+
+		/*
+		 * Loudspeakers of a stereo layout.
+		 */
+		speakers := []speakerStruct{
+			speakerStruct{name: "left", azimuth: -STEREO_SPREAD},
+			speakerStruct{name: "right", azimuth: STEREO_SPREAD},
+		}
+
+		return speakers, nil
+	case LAYOUT_QUAD:
+
+		/*
+		 * Loudspeakers of a quadraphonic layout.
+		 */
+		speakers := []speakerStruct{
+			speakerStruct{name: "left", azimuth: -45.0},
+			speakerStruct{name: "right", azimuth: 45.0},
+			speakerStruct{name: "rear_left", azimuth: -135.0},
+			speakerStruct{name: "rear_right", azimuth: 135.0},
+		}
+
+		return speakers, nil
+	case LAYOUT_SURROUND:
+
+		/*
+		 * Loudspeakers of a 5.1 layout (ITU-R BS.775).
+		 */
+		speakers := []speakerStruct{
+			speakerStruct{name: "left", azimuth: -30.0},
+			speakerStruct{name: "right", azimuth: 30.0},
+			speakerStruct{name: "center", azimuth: 0.0},
+			speakerStruct{name: "lfe", lfe: true},
+			speakerStruct{name: "surround_left", azimuth: -110.0},
+			speakerStruct{name: "surround_right", azimuth: 110.0},
+		}
+
+		return speakers, nil
+	default:
+		return nil, fmt.Errorf("Speaker layout must be '%s', '%s' or '%s', got '%s'.", LAYOUT_STEREO, LAYOUT_QUAD, LAYOUT_SURROUND, layout)
+	}
+
+}
+
+/*
+ * Returns the names of the loudspeakers of a speaker layout in the order of
+ * the outputs, e. g. "left", "right", "center", "lfe", "surround_left" and
+ * "surround_right".
+ */
+func SpeakerNames(layout string) ([]string, error) {
+	speakers, err := layoutSpeakers(layout)
+
+	/*
+	 * Check if the layout is supported.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		numSpeakers := len(speakers)
+		names := make([]string, numSpeakers)
+
+		/*
+		 * Obtain the name of each speaker.
+		 */
+		for i, speaker := range speakers {
+			names[i] = speaker.name
+		}
+
+		return names, nil
+	}
+
 }
 
 /*
@@ -437,13 +1641,24 @@ func Create(inputChannels uint32) Spatializer {
 	positions := make([]position, inputChannels)
 
 	/*
-	 * Set the levels to one by default.
+	 * Set the levels to one and the sends to zero by default.
 	 */
 	for i, _ := range positions {
 		positions[i].level = 1.0
+		positions[i].sends = make([]float64, AUX_BUS_COUNT)
+	}
+
+	buses := make([]busStruct, AUX_BUS_COUNT)
+
+	/*
+	 * Set the return levels to one by default.
+	 */
+	for i, _ := range buses {
+		buses[i].returnLevel = 1.0
 	}
 
-	buffers := make([][]float64, inputChannels)
+	numBuffers := 2 * inputChannels
+	buffers := make([][]float64, numBuffers)
 	sampleRateFloat := float64(DEFAULT_SAMPLE_RATE)
 	bufferSizeFloat := math.Ceil(sampleRateFloat * GROUP_DELAY)
 	bufferSize := int(bufferSizeFloat)
@@ -463,7 +1678,10 @@ func Create(inputChannels uint32) Spatializer {
 		sampleRate: DEFAULT_SAMPLE_RATE,
 		positions:  positions,
 		buffers:    buffers,
+		buses:      buses,
+		hrtf:       SphericalHead(),
 	}
 
+	s.SetSpeakerLayout(LAYOUT_STEREO)
 	return &s
 }
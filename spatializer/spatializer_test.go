@@ -0,0 +1,527 @@
+package spatializer
+
+import (
+	"math"
+	"testing"
+)
+
+/*
+ * Data structure representing an aux bus processor which passes its input
+ * through unchanged.
+ */
+type passThroughStruct struct {
+}
+
+/*
+ * Pass the input of the bus through to its output.
+ */
+func (this *passThroughStruct) ProcessStereo(inLeft []float64, inRight []float64, outLeft []float64, outRight []float64, sampleRate uint32) {
+	copy(outLeft, inLeft)
+	copy(outRight, inRight)
+}
+
+/*
+ * Verify that processing does not allocate memory once the buffers of the aux
+ * buses were allocated for the block size.
+ */
+func TestProcessAllocations(t *testing.T) {
+
+	/*
+	 * Number of frames per block.
+	 */
+	blockSizes := []uint32{
+		64,
+		256,
+		1000,
+	}
+
+	/*
+	 * Process blocks of each size.
+	 */
+	for _, frames := range blockSizes {
+		spat := Create(2)
+		idxStereo := spat.AddChannel(true)
+		spat.SetBlockSize(frames)
+		spat.SetAzimuth(0, -30.0)
+		spat.SetAzimuth(1, 30.0)
+		spat.SetDistance(1, 2.0)
+		spat.SetSend(0, 0, 0.5)
+		spat.SetSend(idxStereo, 0, 0.5)
+		processor := &passThroughStruct{}
+		spat.SetBusProcessor(0, processor)
+		n := int(frames)
+		numInputs := 4
+		inputBuffers := make([][]float64, numInputs)
+
+		/*
+		 * Generate a sine wave for each input.
+		 */
+		for i := range inputBuffers {
+			buffer := make([]float64, n)
+
+			/*
+			 * Generate data series.
+			 */
+			for j := range buffer {
+				jFloat := float64(j)
+				arg := 2.0 * math.Pi * jFloat / 64.0
+				buffer[j] = 0.25 * math.Sin(arg)
+			}
+
+			inputBuffers[i] = buffer
+		}
+
+		auxBuffer := make([]float64, n)
+
+		/*
+		 * Output buffers for the left and right channel.
+		 */
+		outputBuffers := [][]float64{
+			make([]float64, n),
+			make([]float64, n),
+		}
+
+		/*
+		 * Process a single block.
+		 */
+		process := func() {
+			spat.Process(inputBuffers, auxBuffer, outputBuffers)
+		}
+
+		allocs := testing.AllocsPerRun(100, process)
+
+		/*
+		 * Processing must not allocate.
+		 */
+		if allocs != 0 {
+			t.Errorf("Processing blocks of %d frames allocates %f times per run.", frames, allocs)
+		}
+
+		peak := 0.0
+
+		/*
+		 * Find the peak of the left output.
+		 */
+		for _, sample := range outputBuffers[0] {
+			peak = math.Max(peak, math.Abs(sample))
+		}
+
+		/*
+		 * The inputs must reach the output.
+		 */
+		if peak == 0.0 {
+			t.Errorf("Processing blocks of %d frames produced silence.", frames)
+		}
+
+	}
+
+}
+
+/*
+ * Verify that the binaural renderer places a source on the side of the ear
+ * facing it, both by level and by arrival time, and that it does not
+ * allocate memory while processing.
+ */
+func TestBinaural(t *testing.T) {
+	frames := uint32(256)
+	n := int(frames)
+	spat := Create(1)
+	spat.SetBlockSize(frames)
+	err := spat.SetMode("surround")
+
+	/*
+	 * Unsupported modes must be rejected.
+	 */
+	if err == nil {
+		t.Errorf("Setting spatializer mode to '%s' did not return error.", "surround")
+	}
+
+	err = spat.SetMode(MODE_BINAURAL)
+
+	/*
+	 * Check if binaural rendering could be enabled.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Enabling binaural rendering returned error: %s", msg)
+	} else if spat.GetMode() != MODE_BINAURAL {
+		t.Errorf("Spatializer mode does not match! Expected '%s', got '%s'.", MODE_BINAURAL, spat.GetMode())
+	} else {
+		spat.SetAzimuth(0, 90.0)
+		impulse := make([]float64, n)
+		impulse[0] = 1.0
+		silence := make([]float64, n)
+
+		/*
+		 * Output buffers for the left and right channel.
+		 */
+		outputBuffers := [][]float64{
+			make([]float64, n),
+			make([]float64, n),
+		}
+
+		/*
+		 * Input buffers with an impulse.
+		 */
+		inputBuffers := [][]float64{
+			impulse,
+		}
+
+		spat.Process(inputBuffers, nil, outputBuffers)
+		peakLeft := 0.0
+		peakRight := 0.0
+		arrivalLeft := -1
+		arrivalRight := -1
+
+		/*
+		 * Find the peak and the arrival time of each output.
+		 */
+		for i := range impulse {
+			sampleLeft := math.Abs(outputBuffers[0][i])
+			sampleRight := math.Abs(outputBuffers[1][i])
+
+			/*
+			 * Check if the signal arrived at the left ear.
+			 */
+			if arrivalLeft < 0 && sampleLeft > 0.01 {
+				arrivalLeft = i
+			}
+
+			/*
+			 * Check if the signal arrived at the right ear.
+			 */
+			if arrivalRight < 0 && sampleRight > 0.01 {
+				arrivalRight = i
+			}
+
+			peakLeft = math.Max(peakLeft, sampleLeft)
+			peakRight = math.Max(peakRight, sampleRight)
+		}
+
+		/*
+		 * A source on the right must be louder in the right ear.
+		 */
+		if peakRight <= peakLeft {
+			t.Errorf("Source on the right is not louder in the right ear. Peak left: %f, peak right: %f", peakLeft, peakRight)
+		}
+
+		/*
+		 * A source on the right must arrive at the right ear first.
+		 */
+		if arrivalLeft <= arrivalRight {
+			t.Errorf("Source on the right does not arrive at the right ear first. Arrival left: %d, arrival right: %d", arrivalLeft, arrivalRight)
+		}
+
+		/*
+		 * Input buffers without a signal.
+		 */
+		inputBuffers = [][]float64{
+			silence,
+		}
+
+		/*
+		 * Process a single block.
+		 */
+		process := func() {
+			spat.Process(inputBuffers, nil, outputBuffers)
+		}
+
+		allocs := testing.AllocsPerRun(100, process)
+
+		/*
+		 * Processing must not allocate.
+		 */
+		if allocs != 0 {
+			t.Errorf("Binaural processing of blocks of %d frames allocates %f times per run.", frames, allocs)
+		}
+
+		peak := 0.0
+
+		/*
+		 * Find the peak of the left output.
+		 */
+		for _, sample := range outputBuffers[0] {
+			peak = math.Max(peak, math.Abs(sample))
+		}
+
+		/*
+		 * The tail of the impulse must have decayed.
+		 */
+		if peak != 0.0 {
+			t.Errorf("Binaural renderer did not decay to silence. Peak: %f", peak)
+		}
+
+	}
+
+}
+
+/*
+ * Renders a constant signal from a certain azimuth and returns the last
+ * sample of each output.
+ */
+func surroundGains(spat Spatializer, azimuth float64, frames int) []float64 {
+	numOutputs := spat.GetOutputCount()
+	outputBuffers := make([][]float64, numOutputs)
+
+	/*
+	 * Allocate the output buffers.
+	 */
+	for i := range outputBuffers {
+		outputBuffers[i] = make([]float64, frames)
+	}
+
+	signal := make([]float64, frames)
+
+	/*
+	 * Generate a constant signal.
+	 */
+	for i := range signal {
+		signal[i] = 1.0
+	}
+
+	/*
+	 * Input buffers with the signal.
+	 */
+	inputBuffers := [][]float64{
+		signal,
+	}
+
+	spat.SetAzimuth(0, azimuth)
+	spat.Process(inputBuffers, nil, outputBuffers)
+	gains := make([]float64, numOutputs)
+
+	/*
+	 * Obtain the last sample of each output.
+	 */
+	for i, outputBuffer := range outputBuffers {
+		gains[i] = outputBuffer[frames-1]
+	}
+
+	return gains
+}
+
+/*
+ * Verify that surround layouts have the expected outputs and that sources
+ * are panned between the speakers enclosing them.
+ */
+func TestSurround(t *testing.T) {
+	frames := 4096
+	spat := Create(1)
+	err := spat.SetSpeakerLayout("7.1")
+
+	/*
+	 * Unsupported layouts must be rejected.
+	 */
+	if err == nil {
+		t.Errorf("Setting speaker layout to '%s' did not return error.", "7.1")
+	}
+
+	numOutputs := spat.GetOutputCount()
+
+	/*
+	 * The stereo layout is the default.
+	 */
+	if numOutputs != OUTPUT_COUNT {
+		t.Errorf("Stereo layout should have %d outputs, but has %d.", OUTPUT_COUNT, numOutputs)
+	}
+
+	err = spat.SetSpeakerLayout(LAYOUT_SURROUND)
+
+	/*
+	 * Check if the layout could be selected.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Selecting speaker layout '%s' returned error: %s", LAYOUT_SURROUND, msg)
+	} else if spat.GetOutputCount() != 6 {
+		t.Errorf("Speaker layout '%s' should have %d outputs, but has %d.", LAYOUT_SURROUND, 6, spat.GetOutputCount())
+	} else {
+		gains := surroundGains(spat, 0.0, frames)
+
+		/*
+		 * Expected gain of each speaker for a source in front.
+		 */
+		expected := []float64{
+			0.0,
+			0.0,
+			1.0,
+			1.0,
+			0.0,
+			0.0,
+		}
+
+		/*
+		 * A source in front plays from the center speaker only, while
+		 * the subwoofer receives the full signal.
+		 */
+		for i, gain := range gains {
+
+			/*
+			 * Check if the gain matches our expectations.
+			 */
+			if math.Abs(gain-expected[i]) > 0.001 {
+				t.Errorf("Gain of output %d for source in front does not match! Expected %f, got %f.", i, expected[i], gain)
+			}
+
+		}
+
+		gains = surroundGains(spat, 70.0, frames)
+
+		/*
+		 * A source halfway between right and right surround plays from
+		 * both at equal power.
+		 */
+		if math.Abs(gains[1]-math.Sqrt2/2.0) > 0.001 || math.Abs(gains[5]-math.Sqrt2/2.0) > 0.001 {
+			t.Errorf("Source between speakers is not panned at equal power. Right: %f, surround right: %f", gains[1], gains[5])
+		}
+
+	}
+
+	err = spat.SetSpeakerLayout(LAYOUT_QUAD)
+
+	/*
+	 * Check if the layout could be selected.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Selecting speaker layout '%s' returned error: %s", LAYOUT_QUAD, msg)
+	} else {
+		gains := surroundGains(spat, 180.0, frames)
+
+		/*
+		 * A source behind plays from both rear speakers only.
+		 */
+		if gains[0] != 0.0 || gains[1] != 0.0 || math.Abs(gains[2]-gains[3]) > 0.001 || gains[2] < 0.7 {
+			t.Errorf("Source behind is not panned to the rear speakers. Gains: %v", gains)
+		}
+
+	}
+
+	names, err := SpeakerNames(LAYOUT_SURROUND)
+
+	/*
+	 * Check if the speakers are named in the order of the outputs.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Obtaining speaker names returned error: %s", msg)
+	} else if len(names) != 6 || names[3] != "lfe" {
+		t.Errorf("Speaker names of layout '%s' do not match: %v", LAYOUT_SURROUND, names)
+	}
+
+}
+
+/*
+ * Verify that the settings and channels of the spatializer may be changed
+ * while blocks are processed.
+ *
+ * Run with 'go test -race' to detect unsynchronized access.
+ */
+func TestConcurrentChanges(t *testing.T) {
+	n := 256
+	frames := uint32(n)
+	spat := Create(2)
+	spat.SetBlockSize(frames)
+	processor := &passThroughStruct{}
+	spat.SetBusProcessor(0, processor)
+	inputBuffers := make([][]float64, 4)
+
+	/*
+	 * Allocate each input, including those of a stereo channel.
+	 */
+	for i := range inputBuffers {
+		inputBuffers[i] = make([]float64, n)
+	}
+
+	auxBuffer := make([]float64, n)
+	outputBuffers := make([][]float64, 6)
+
+	/*
+	 * Allocate each output, including those of a surround layout.
+	 */
+	for i := range outputBuffers {
+		outputBuffers[i] = make([]float64, n)
+	}
+
+	done := make(chan bool)
+	stopped := make(chan bool)
+
+	/*
+	 * Process blocks until the changes are done.
+	 */
+	go func() {
+		running := true
+
+		/*
+		 * Process a block unless the changes are done.
+		 */
+		for running {
+
+			select {
+			case <-done:
+				running = false
+			default:
+				spat.Process(inputBuffers, auxBuffer, outputBuffers)
+			}
+
+		}
+
+		stopped <- true
+	}()
+
+	/*
+	 * Speaker layouts to switch between.
+	 */
+	layouts := []string{
+		"quad",
+		"stereo",
+	}
+
+	/*
+	 * Change the settings many times while blocks are processed.
+	 */
+	for round := 0; round < 100; round++ {
+		roundFloat := float64(round)
+		spat.SetAzimuth(0, roundFloat)
+		spat.SetDistance(1, 1.0+(0.01*roundFloat))
+		spat.SetLevel(0, 0.5)
+		spat.SetSend(1, 0, 0.5)
+		spat.SetReturn(0, 0.5)
+		spat.SetMute(1, round%2 == 0)
+		spat.SetSolo(0, round%3 == 0)
+		spat.SetStereo(1, round%2 == 1)
+		idx := spat.AddChannel(true)
+		spat.SetAzimuth(idx, -roundFloat)
+		err := spat.RemoveChannel(idx)
+
+		/*
+		 * Check if channel was removed.
+		 */
+		if err != nil {
+			t.Errorf("Round %d: Failed to remove channel: %s", round, err.Error())
+		}
+
+		layout := layouts[round%2]
+		err = spat.SetSpeakerLayout(layout)
+
+		/*
+		 * Check if layout was set.
+		 */
+		if err != nil {
+			t.Errorf("Round %d: Failed to set speaker layout '%s': %s", round, layout, err.Error())
+		}
+
+	}
+
+	done <- true
+	<-stopped
+	numInputs := spat.GetInputCount()
+
+	/*
+	 * The channels added must have been removed.
+	 */
+	if numInputs != 2 {
+		t.Errorf("Spatializer should have %d inputs, but has %d.", 2, numInputs)
+	}
+
+}
@@ -0,0 +1,485 @@
+package spectrum
+
+import (
+	"fmt"
+	"github.com/andrepxx/go-dsp-guitar/circular"
+	"github.com/andrepxx/go-dsp-guitar/fft"
+	"math"
+	"math/cmplx"
+	"sync"
+)
+
+/*
+ * Global constants.
+ */
+const (
+	DEFAULT_FFT_SIZE   = 4096
+	MIN_FFT_SIZE       = 256
+	MAX_FFT_SIZE       = 32768
+	MIN_LEVEL          = -200.0
+	WINDOW_RECTANGULAR = "rectangular"
+	WINDOW_HANN        = "hann"
+	WINDOW_HAMMING     = "hamming"
+	WINDOW_BLACKMAN    = "blackman"
+	DEFAULT_WINDOW     = WINDOW_HANN
+)
+
+/*
+ * Data structure representing the result of a spectral analysis.
+ */
+type resultStruct struct {
+	sampleRate uint32
+	binWidth   float64
+	magnitudes []float64
+}
+
+/*
+ * The result of a spectral analysis.
+ */
+type Result interface {
+	BinWidth() float64
+	Magnitudes() []float64
+	SampleRate() uint32
+}
+
+/*
+ * Data structure representing a spectrum analyzer for a single channel.
+ */
+type channelAnalyzerStruct struct {
+	channelName string
+	mutex       sync.RWMutex
+	enabled     bool
+	buffer      circular.Buffer
+	sampleRate  uint32
+}
+
+/*
+ * Data structure representing spectrum analyzers for multiple channels.
+ */
+type analyzerStruct struct {
+	channelAnalyzers []*channelAnalyzerStruct
+	mutex            sync.RWMutex
+	enabled          bool
+	mutexAnalyze     sync.Mutex
+	fourierTransform fft.FourierTransform
+}
+
+/*
+ * Interface type representing a spectrum analyzer for multiple channels.
+ */
+type Analyzer interface {
+	Analyze(channelId uint32, fftSize uint32, window string) (Result, error)
+	ChannelCount() uint32
+	ChannelName(channelId uint32) (string, error)
+	Enabled() bool
+	Process(inputBuffers [][]float64, sampleRate uint32) error
+	SetEnabled(value bool)
+}
+
+/*
+ * Returns the width of a frequency bin in hertz.
+ */
+func (this *resultStruct) BinWidth() float64 {
+	value := this.binWidth
+	return value
+}
+
+/*
+ * Returns the magnitude (in decibels relative to full scale) of each
+ * frequency bin, from zero up to half the sample rate.
+ */
+func (this *resultStruct) Magnitudes() []float64 {
+	n := len(this.magnitudes)
+	magnitudes := make([]float64, n)
+	copy(magnitudes, this.magnitudes)
+	return magnitudes
+}
+
+/*
+ * Returns the sample rate of the analyzed signal.
+ */
+func (this *resultStruct) SampleRate() uint32 {
+	value := this.sampleRate
+	return value
+}
+
+/*
+ * Returns the coefficients of a window function of a certain size.
+ */
+func windowCoefficients(window string, size int) ([]float64, error) {
+	coefficients := make([]float64, size)
+	sizeFloat := float64(size)
+
+	/*
+	 * Calculate each coefficient.
+	 */
+	for i := range coefficients {
+		iFloat := float64(i)
+		x := (2.0 * math.Pi * iFloat) / sizeFloat
+
+		/*
+		 * Decide which window function to use.
+		 */
+		switch window {
+		case WINDOW_RECTANGULAR:
+			coefficients[i] = 1.0
+		case WINDOW_HANN:
+			coefficients[i] = 0.5 - (0.5 * math.Cos(x))
+		case WINDOW_HAMMING:
+			coefficients[i] = 0.54 - (0.46 * math.Cos(x))
+		case WINDOW_BLACKMAN:
+			coefficients[i] = 0.42 - (0.5 * math.Cos(x)) + (0.08 * math.Cos(2.0*x))
+		default:
+			return nil, fmt.Errorf("Unknown window function: '%s'", window)
+		}
+
+	}
+
+	return coefficients, nil
+}
+
+/*
+ * Returns the name of the channel analyzed by this channel analyzer.
+ */
+func (this *channelAnalyzerStruct) name() string {
+	name := this.channelName
+	return name
+}
+
+/*
+ * Feed the signal from an input buffer into a single-channel analyzer.
+ */
+func (this *channelAnalyzerStruct) process(buffer []float64, sampleRate uint32) {
+	this.mutex.Lock()
+
+	/*
+	 * Only store signal if this channel is enabled.
+	 */
+	if this.enabled {
+		this.buffer.Enqueue(buffer...)
+		this.sampleRate = sampleRate
+	}
+
+	this.mutex.Unlock()
+}
+
+/*
+ * Returns the most recent samples fed into this channel analyzer.
+ */
+func (this *channelAnalyzerStruct) samples(n int) ([]float64, uint32, error) {
+	this.mutex.RLock()
+	buffer := this.buffer
+	sampleRate := this.sampleRate
+	size := buffer.Length()
+	history := make([]float64, size)
+	err := buffer.Retrieve(history)
+	this.mutex.RUnlock()
+
+	/*
+	 * Check if samples could be retrieved.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, 0, fmt.Errorf("Failed to retrieve contents of circular buffer: %s", msg)
+	} else {
+		offset := size - n
+		result := history[offset:size]
+		return result, sampleRate, nil
+	}
+
+}
+
+/*
+ * Enables or disables spectral analysis for this channel.
+ */
+func (this *channelAnalyzerStruct) setEnabled(value bool) {
+	this.mutex.Lock()
+	enabled := this.enabled
+
+	/*
+	 * Check if status of analyzer must be changed.
+	 */
+	if value != enabled {
+
+		/*
+		 * If analyzer should be disabled, clear state.
+		 */
+		if !value {
+			this.buffer = circular.CreateBuffer(MAX_FFT_SIZE)
+		}
+
+		this.enabled = value
+	}
+
+	this.mutex.Unlock()
+}
+
+/*
+ * Analyze the spectrum of a certain channel, using a Fourier transform of a
+ * certain size and a window function.
+ *
+ * The magnitudes are scaled, so that a sine wave at full scale, which is
+ * centered in a frequency bin, reads zero decibels.
+ */
+func (this *analyzerStruct) Analyze(channelId uint32, fftSize uint32, window string) (Result, error) {
+	channelAnalyzers := this.channelAnalyzers
+	numAnalyzers := len(channelAnalyzers)
+	numAnalyzers32 := uint32(numAnalyzers)
+	fftSize64 := uint64(fftSize)
+	powerOfTwo, _ := fft.NextPowerOfTwo(fftSize64)
+
+	/*
+	 * Check if channel number and size of transform are valid.
+	 */
+	if channelId >= numAnalyzers32 {
+		return nil, fmt.Errorf("Requested analysis for channel %d, but spectrum analyzer only has %d channels.", channelId, numAnalyzers)
+	} else if (powerOfTwo != fftSize64) || (fftSize < MIN_FFT_SIZE) || (fftSize > MAX_FFT_SIZE) {
+		return nil, fmt.Errorf("Size of transform must be a power of two between %d and %d.", MIN_FFT_SIZE, MAX_FFT_SIZE)
+	} else {
+		n := int(fftSize)
+		coefficients, err := windowCoefficients(window, n)
+
+		/*
+		 * Check if window function is valid.
+		 */
+		if err != nil {
+			return nil, err
+		} else {
+			channelAnalyzer := channelAnalyzers[channelId]
+			samples, sampleRate, err := channelAnalyzer.samples(n)
+
+			/*
+			 * Check if samples could be obtained.
+			 */
+			if err != nil {
+				return nil, err
+			} else {
+				windowed := make([]float64, n)
+				coefficientSum := 0.0
+
+				/*
+				 * Apply the window function.
+				 */
+				for i, sample := range samples {
+					coefficient := coefficients[i]
+					windowed[i] = coefficient * sample
+					coefficientSum += coefficient
+				}
+
+				spectrum := make([]complex128, n)
+				this.mutexAnalyze.Lock()
+				fourierTransform := this.fourierTransform
+				err = fourierTransform.RealFourier(windowed, spectrum, fft.SCALING_DEFAULT)
+				this.mutexAnalyze.Unlock()
+
+				/*
+				 * Check if Fourier transform was successful.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return nil, fmt.Errorf("Failed to calculate Fourier transform: %s", msg)
+				} else {
+					nHalf := n / 2
+					numBins := nHalf + 1
+					magnitudes := make([]float64, numBins)
+					scale := 2.0 / coefficientSum
+
+					/*
+					 * Calculate the magnitude of each frequency bin.
+					 */
+					for i := range magnitudes {
+						elem := spectrum[i]
+						factor := scale
+
+						/*
+						 * The energy of the DC and Nyquist bins is not
+						 * split across positive and negative
+						 * frequencies.
+						 */
+						if (i == 0) || (i == nHalf) {
+							factor = 0.5 * scale
+						}
+
+						magnitude := factor * cmplx.Abs(elem)
+						level := 20.0 * math.Log10(magnitude)
+						levelNaN := math.IsNaN(level)
+
+						/*
+						 * Ensure that the minimum level is not exceeded.
+						 */
+						if levelNaN || level < MIN_LEVEL {
+							level = MIN_LEVEL
+						}
+
+						magnitudes[i] = level
+					}
+
+					sampleRateFloat := float64(sampleRate)
+					nFloat := float64(n)
+					binWidth := sampleRateFloat / nFloat
+
+					/*
+					 * Create result structure.
+					 */
+					result := resultStruct{
+						sampleRate: sampleRate,
+						binWidth:   binWidth,
+						magnitudes: magnitudes,
+					}
+
+					return &result, nil
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Returns the number of channels this analyzer is able to process.
+ */
+func (this *analyzerStruct) ChannelCount() uint32 {
+	channelAnalyzers := this.channelAnalyzers
+	numChannels := len(channelAnalyzers)
+	numChannels32 := uint32(numChannels)
+	return numChannels32
+}
+
+/*
+ * Returns the name of the channel with the provided id.
+ */
+func (this *analyzerStruct) ChannelName(channelId uint32) (string, error) {
+	channelAnalyzers := this.channelAnalyzers
+	numAnalyzers := len(channelAnalyzers)
+	numAnalyzers32 := uint32(numAnalyzers)
+
+	/*
+	 * Check if channel number is within range.
+	 */
+	if channelId >= numAnalyzers32 {
+		return "", fmt.Errorf("Requested name of channel %d, but spectrum analyzer only has %d channels.", channelId, numAnalyzers)
+	} else {
+		channelAnalyzer := channelAnalyzers[channelId]
+		name := channelAnalyzer.name()
+		return name, nil
+	}
+
+}
+
+/*
+ * Returns whether this spectrum analyzer is enabled.
+ */
+func (this *analyzerStruct) Enabled() bool {
+	this.mutex.RLock()
+	enabled := this.enabled
+	this.mutex.RUnlock()
+	return enabled
+}
+
+/*
+ * Process input buffers for multiple channels.
+ */
+func (this *analyzerStruct) Process(buffers [][]float64, sampleRate uint32) error {
+	channelAnalyzers := this.channelAnalyzers
+	numChannels := len(channelAnalyzers)
+	numBuffers := len(buffers)
+
+	/*
+	 * Make sure that the correct number of buffers is provided.
+	 */
+	if numChannels != numBuffers {
+		return fmt.Errorf("Number of input buffers (%d) does not match number of channels (%d) for this spectrum analyzer.", numBuffers, numChannels)
+	} else {
+
+		/*
+		 * Feed input from each channel to the corresponding analyzer.
+		 */
+		for i, buffer := range buffers {
+			channelAnalyzer := channelAnalyzers[i]
+			channelAnalyzer.process(buffer, sampleRate)
+		}
+
+		return nil
+	}
+
+}
+
+/*
+ * Enables or disables this spectrum analyzer.
+ */
+func (this *analyzerStruct) SetEnabled(value bool) {
+	this.mutex.Lock()
+	enabled := this.enabled
+
+	/*
+	 * Check if value must be changed.
+	 */
+	if value != enabled {
+		channelAnalyzers := this.channelAnalyzers
+
+		/*
+		 * Enable or disable each channel analyzer.
+		 */
+		for _, channelAnalyzer := range channelAnalyzers {
+			channelAnalyzer.setEnabled(value)
+		}
+
+		this.enabled = value
+	}
+
+	this.mutex.Unlock()
+}
+
+/*
+ * Creates a new spectrum analyzer for a certain number of channels.
+ */
+func CreateAnalyzer(numChannels uint32, names []string) (Analyzer, error) {
+	numNames := len(names)
+	numNames32 := uint32(numNames)
+
+	/*
+	 * Check if number of channel names matches number of channels.
+	 */
+	if numChannels != numNames32 {
+		return nil, fmt.Errorf("Failed to create spectrum analyzer. Requested spectrum analyzer for %d channels, but provided %d channel names.", numChannels, numNames)
+	} else {
+		channelAnalyzers := make([]*channelAnalyzerStruct, numChannels)
+
+		/*
+		 * Create the channel analyzers.
+		 */
+		for i := range channelAnalyzers {
+			name := names[i]
+			buffer := circular.CreateBuffer(MAX_FFT_SIZE)
+
+			/*
+			 * Create a new channel analyzer.
+			 */
+			channelAnalyzer := &channelAnalyzerStruct{
+				channelName: name,
+				enabled:     false,
+				buffer:      buffer,
+				sampleRate:  0,
+			}
+
+			channelAnalyzers[i] = channelAnalyzer
+		}
+
+		fourierTransform := fft.CreateFourierTransform()
+
+		/*
+		 * Create a new spectrum analyzer.
+		 */
+		analyzer := analyzerStruct{
+			channelAnalyzers: channelAnalyzers,
+			enabled:          false,
+			fourierTransform: fourierTransform,
+		}
+
+		return &analyzer, nil
+	}
+
+}
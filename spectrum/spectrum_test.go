@@ -0,0 +1,182 @@
+package spectrum
+
+import (
+	"math"
+	"testing"
+)
+
+const (
+	DEFAULT_SAMPLE_RATE = 96000
+	TESTING_BIN         = 64
+	TESTING_OTHER_BIN   = 512
+	TWO_PI              = 2.0 * math.Pi
+)
+
+/*
+ * Perform a unit test on the spectrum analyzer.
+ */
+func TestAnalyzer(t *testing.T) {
+	sampleRate := uint32(DEFAULT_SAMPLE_RATE)
+	sampleRateFloat := float64(sampleRate)
+	binWidth := sampleRateFloat / DEFAULT_FFT_SIZE
+	frequency := TESTING_BIN * binWidth
+	buf := make([]float64, sampleRate)
+
+	/*
+	 * Generate a sine wave centered in a frequency bin.
+	 */
+	for i := range buf {
+		iFloat := float64(i)
+		t := iFloat / sampleRateFloat
+		arg := TWO_PI * frequency * t
+		buf[i] = 0.5 * math.Sin(arg)
+	}
+
+	/*
+	 * Channel buffers.
+	 */
+	bufs := [][]float64{
+		buf,
+	}
+
+	/*
+	 * Channel names.
+	 */
+	names := []string{
+		"channel_a",
+	}
+
+	a, err := CreateAnalyzer(1, names)
+
+	/*
+	 * Check if spectrum analyzer was sucessfully created.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Creating %d channel spectrum analyzer failed: %s", 1, msg)
+	} else {
+		a.Process(bufs, sampleRate)
+		resSilent, err := a.Analyze(0, DEFAULT_FFT_SIZE, DEFAULT_WINDOW)
+
+		/*
+		 * Check if spectral analysis returned error.
+		 */
+		if err != nil {
+			msg := err.Error()
+			t.Errorf("Spectral analysis of disabled analyzer returned error: %s", msg)
+		} else {
+			magnitudes := resSilent.Magnitudes()
+			magnitude := magnitudes[TESTING_BIN]
+
+			/*
+			 * A disabled analyzer must not record any signal.
+			 */
+			if magnitude != MIN_LEVEL {
+				t.Errorf("Disabled analyzer recorded signal. Expected %f dB, got %f dB.", MIN_LEVEL, magnitude)
+			}
+
+		}
+
+		a.SetEnabled(true)
+		a.Process(bufs, sampleRate)
+
+		/*
+		 * The window functions to test.
+		 */
+		windows := []string{
+			WINDOW_RECTANGULAR,
+			WINDOW_HANN,
+			WINDOW_HAMMING,
+			WINDOW_BLACKMAN,
+		}
+
+		/*
+		 * Analyze the spectrum using each window function.
+		 */
+		for _, window := range windows {
+			res, err := a.Analyze(0, DEFAULT_FFT_SIZE, window)
+
+			/*
+			 * Check if spectral analysis returned error.
+			 */
+			if err != nil {
+				msg := err.Error()
+				t.Errorf("Spectral analysis with window '%s' returned error: %s", window, msg)
+			} else {
+				magnitudes := res.Magnitudes()
+				numBins := len(magnitudes)
+				expectedBins := (DEFAULT_FFT_SIZE / 2) + 1
+
+				/*
+				 * Check if the number of bins matches our expectations.
+				 */
+				if numBins != expectedBins {
+					t.Errorf("Number of bins does not match! Expected %d, got %d.", expectedBins, numBins)
+				} else {
+					expected := 20.0 * math.Log10(0.5)
+					magnitude := magnitudes[TESTING_BIN]
+					difference := math.Abs(magnitude - expected)
+
+					/*
+					 * Check if the magnitude of the sine wave matches
+					 * our expectations.
+					 */
+					if difference > 0.1 {
+						t.Errorf("Magnitude with window '%s' does not match! Expected %f dB, got %f dB.", window, expected, magnitude)
+					}
+
+					other := magnitudes[TESTING_OTHER_BIN]
+
+					/*
+					 * Check that there is no energy far from the
+					 * frequency of the sine wave.
+					 */
+					if other > -100.0 {
+						t.Errorf("Magnitude of bin %d with window '%s' too large: %f dB", TESTING_OTHER_BIN, window, other)
+					}
+
+				}
+
+				resultBinWidth := res.BinWidth()
+
+				/*
+				 * Check if the bin width matches our expectations.
+				 */
+				if resultBinWidth != binWidth {
+					t.Errorf("Bin width does not match! Expected %f Hz, got %f Hz.", binWidth, resultBinWidth)
+				}
+
+			}
+
+		}
+
+		_, err = a.Analyze(0, 1000, DEFAULT_WINDOW)
+
+		/*
+		 * Sizes which are not powers of two must be rejected.
+		 */
+		if err == nil {
+			t.Errorf("%s", "Spectral analysis with invalid size of transform did not return error.")
+		}
+
+		_, err = a.Analyze(0, DEFAULT_FFT_SIZE, "unknown")
+
+		/*
+		 * Unknown window functions must be rejected.
+		 */
+		if err == nil {
+			t.Errorf("%s", "Spectral analysis with unknown window function did not return error.")
+		}
+
+		_, err = a.Analyze(1, DEFAULT_FFT_SIZE, DEFAULT_WINDOW)
+
+		/*
+		 * Channels out of range must be rejected.
+		 */
+		if err == nil {
+			t.Errorf("%s", "Spectral analysis of nonexistent channel did not return error.")
+		}
+
+	}
+
+}
@@ -13,7 +13,26 @@ import (
  * Global constants.
  */
 const (
-	NUM_SAMPLES = 96000
+	NOTE_COUNT          = 61
+	NOTE_INDEX_A        = 9
+	NOTE_INDEX_FIRST    = 11
+	NOTATION_DEFAULT    = "german"
+	NOTES_PER_OCTAVE    = 12
+	NUM_SAMPLES         = 96000
+	OCTAVE_FIRST        = 1
+	OCTAVE_REFERENCE    = 4
+	REFERENCE_DEFAULT   = 440.0
+	REFERENCE_MAXIMUM   = 480.0
+	REFERENCE_MINIMUM   = 400.0
+	STRING_RANGE_CENTS  = 100.0
+	STROBE_ITERATIONS   = 40
+	STROBE_PEAK_RATIO   = 0.9
+	STROBE_RANGE_CENTS  = 50.0
+	STROBE_SAMPLES      = 16384
+	STRING_THRESHOLD    = 0.1
+	TEMPERAMENT_DEFAULT = "equal"
+	TUNING_DEFAULT      = "chromatic"
+	TUNING_STRINGS      = "standard"
 )
 
 /*
@@ -21,9 +40,34 @@ const (
  */
 type noteStruct struct {
 	name      string
+	label     string
 	frequency float64
 }
 
+/*
+ * Data structure representing a temperament.
+ */
+type temperamentStruct struct {
+	name  string
+	cents [NOTES_PER_OCTAVE]float64
+}
+
+/*
+ * Data structure representing a convention for naming the notes.
+ */
+type notationStruct struct {
+	name  string
+	names [NOTES_PER_OCTAVE]string
+}
+
+/*
+ * Data structure representing the tuning of an instrument.
+ */
+type tuningStruct struct {
+	name  string
+	notes []string
+}
+
 /*
  * Data structure representing the result of a spectral analysis.
  */
@@ -33,6 +77,16 @@ type resultStruct struct {
 	note      string
 }
 
+/*
+ * Data structure representing the result of a high-resolution analysis.
+ */
+type strobeResultStruct struct {
+	cents     float64
+	frequency float64
+	note      string
+	phase     float64
+}
+
 /*
  * The result of a spectral analysis.
  */
@@ -42,18 +96,38 @@ type Result interface {
 	Note() string
 }
 
+/*
+ * The result of a high-resolution analysis, which is suitable for driving a
+ * strobe display.
+ */
+type StrobeResult interface {
+	Cents() float64
+	Frequency() float64
+	Note() string
+	Phase() float64
+}
+
 /*
  * Data structure representing a tuner.
  */
 type tunerStruct struct {
+	reference        float64
+	temperament      temperamentStruct
+	notation         notationStruct
+	tuning           tuningStruct
 	notes            []noteStruct
+	candidates       []noteStruct
+	strings          []noteStruct
 	mutexBuffer      sync.RWMutex
 	buffer           circular.Buffer
 	sampleRate       uint32
+	sampleCount      uint64
 	mutexAnalyze     sync.Mutex
 	fourierTransform fft.FourierTransform
 	bufCorrelation   []float64
 	bufFFT           []complex128
+	bufStrobe        []float64
+	bufStrobeFFT     []complex128
 }
 
 /*
@@ -61,271 +135,318 @@ type tunerStruct struct {
  */
 type Tuner interface {
 	Analyze() (Result, error)
+	AnalyzeStrings() ([]Result, error)
+	AnalyzeStrobe() (StrobeResult, error)
 	Process(samples []float64, sampleRate uint32)
+	Notation() string
+	Reference() float64
+	SetNotation(name string) error
+	SetReference(frequency float64) error
+	SetTemperament(name string) error
+	SetTuning(name string) error
+	Temperament() string
+	Tuning() string
 }
 
 /*
- * Generates a list of notes and their frequencies.
- *
- * f(n) = 2^(n / 12) * 440
+ * Returns the temperaments the tuner supports.
  *
- * Where n is the number of half-tone steps relative to A4.
+ * Each temperament lists the position of each note of the chromatic scale,
+ * starting at C, in cents above C.
  */
-func generateNotes() []noteStruct {
+func temperaments() []temperamentStruct {
 
 	/*
-	 * Create a list of appropriate notes.
+	 * The supported temperaments.
 	 */
-	notes := []noteStruct{
-		noteStruct{
-			name:      "H1",
-			frequency: 61.7354,
-		},
-		noteStruct{
-			name:      "C2",
-			frequency: 65.4064,
-		},
-		noteStruct{
-			name:      "C#2",
-			frequency: 69.2957,
-		},
-		noteStruct{
-			name:      "D2",
-			frequency: 73.4162,
-		},
-		noteStruct{
-			name:      "D#2",
-			frequency: 77.7817,
-		},
-		noteStruct{
-			name:      "E2",
-			frequency: 82.4069,
-		},
-		noteStruct{
-			name:      "F2",
-			frequency: 87.3071,
-		},
-		noteStruct{
-			name:      "F#2",
-			frequency: 92.4986,
-		},
-		noteStruct{
-			name:      "G2",
-			frequency: 97.9989,
-		},
-		noteStruct{
-			name:      "G#2",
-			frequency: 103.8262,
-		},
-		noteStruct{
-			name:      "A2",
-			frequency: 110.0000,
-		},
-		noteStruct{
-			name:      "A#2",
-			frequency: 116.5409,
-		},
-		noteStruct{
-			name:      "H2",
-			frequency: 123.4708,
-		},
-		noteStruct{
-			name:      "C3",
-			frequency: 130.8128,
-		},
-		noteStruct{
-			name:      "C#3",
-			frequency: 138.5913,
-		},
-		noteStruct{
-			name:      "D3",
-			frequency: 146.8324,
-		},
-		noteStruct{
-			name:      "D#3",
-			frequency: 155.5635,
-		},
-		noteStruct{
-			name:      "E3",
-			frequency: 164.8138,
-		},
-		noteStruct{
-			name:      "F3",
-			frequency: 174.6141,
-		},
-		noteStruct{
-			name:      "F#3",
-			frequency: 184.9972,
-		},
-		noteStruct{
-			name:      "G3",
-			frequency: 195.9978,
-		},
-		noteStruct{
-			name:      "G#3",
-			frequency: 207.6523,
-		},
-		noteStruct{
-			name:      "A3",
-			frequency: 220.0000,
-		},
-		noteStruct{
-			name:      "A#3",
-			frequency: 233.0819,
-		},
-		noteStruct{
-			name:      "H3",
-			frequency: 246.9417,
-		},
-		noteStruct{
-			name:      "C4",
-			frequency: 261.6256,
-		},
-		noteStruct{
-			name:      "C#4",
-			frequency: 277.1826,
-		},
-		noteStruct{
-			name:      "D4",
-			frequency: 293.6648,
-		},
-		noteStruct{
-			name:      "D#4",
-			frequency: 311.1270,
-		},
-		noteStruct{
-			name:      "E4",
-			frequency: 329.6276,
-		},
-		noteStruct{
-			name:      "F4",
-			frequency: 349.2282,
-		},
-		noteStruct{
-			name:      "F#4",
-			frequency: 369.9944,
+	temps := []temperamentStruct{
+		temperamentStruct{
+			name:  "equal",
+			cents: [NOTES_PER_OCTAVE]float64{0.0, 100.0, 200.0, 300.0, 400.0, 500.0, 600.0, 700.0, 800.0, 900.0, 1000.0, 1100.0},
 		},
-		noteStruct{
-			name:      "G4",
-			frequency: 391.9954,
+		temperamentStruct{
+			name:  "just",
+			cents: [NOTES_PER_OCTAVE]float64{0.0, 111.731, 203.910, 315.641, 386.314, 498.045, 590.224, 701.955, 813.686, 884.359, 1017.596, 1088.269},
 		},
-		noteStruct{
-			name:      "G#4",
-			frequency: 415.3047,
+		temperamentStruct{
+			name:  "meantone",
+			cents: [NOTES_PER_OCTAVE]float64{0.0, 76.049, 193.157, 310.265, 386.314, 503.422, 579.471, 696.578, 772.627, 889.735, 1006.843, 1082.892},
 		},
-		noteStruct{
-			name:      "A4",
-			frequency: 440.0000,
+		temperamentStruct{
+			name:  "pythagorean",
+			cents: [NOTES_PER_OCTAVE]float64{0.0, 113.685, 203.910, 294.135, 407.820, 498.045, 611.730, 701.955, 815.640, 905.865, 996.090, 1109.775},
 		},
-		noteStruct{
-			name:      "A#4",
-			frequency: 466.1638,
+		temperamentStruct{
+			name:  "werckmeister",
+			cents: [NOTES_PER_OCTAVE]float64{0.0, 90.225, 192.180, 294.135, 390.225, 498.045, 588.270, 696.090, 792.180, 888.270, 996.090, 1092.180},
 		},
-		noteStruct{
-			name:      "H4",
-			frequency: 493.8833,
-		},
-		noteStruct{
-			name:      "C5",
-			frequency: 523.2511,
-		},
-		noteStruct{
-			name:      "C#5",
-			frequency: 554.3653,
-		},
-		noteStruct{
-			name:      "D5",
-			frequency: 587.3295,
-		},
-		noteStruct{
-			name:      "D#5",
-			frequency: 622.2540,
-		},
-		noteStruct{
-			name:      "E5",
-			frequency: 659.2551,
-		},
-		noteStruct{
-			name:      "F5",
-			frequency: 698.4565,
-		},
-		noteStruct{
-			name:      "F#5",
-			frequency: 739.9888,
-		},
-		noteStruct{
-			name:      "G5",
-			frequency: 783.9909,
-		},
-		noteStruct{
-			name:      "G#5",
-			frequency: 830.6094,
-		},
-		noteStruct{
-			name:      "A5",
-			frequency: 880.0000,
-		},
-		noteStruct{
-			name:      "A#5",
-			frequency: 932.3275,
-		},
-		noteStruct{
-			name:      "H5",
-			frequency: 987.7666,
-		},
-		noteStruct{
-			name:      "C6",
-			frequency: 1046.5023,
+	}
+
+	return temps
+}
+
+/*
+ * Returns the conventions for naming the notes the tuner supports.
+ *
+ * Each notation lists the names of the notes of the chromatic scale, starting
+ * at C. The German notation calls the note a semitone below C 'H', while the
+ * international notation calls it 'B'.
+ */
+func notations() []notationStruct {
+
+	/*
+	 * The supported notations.
+	 */
+	nots := []notationStruct{
+		notationStruct{
+			name:  "german",
+			names: [NOTES_PER_OCTAVE]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "H"},
 		},
-		noteStruct{
-			name:      "C#6",
-			frequency: 1108.7305,
+		notationStruct{
+			name:  "international",
+			names: [NOTES_PER_OCTAVE]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"},
 		},
-		noteStruct{
-			name:      "D6",
-			frequency: 1174.6591,
+	}
+
+	return nots
+}
+
+/*
+ * Returns the tunings the tuner supports.
+ *
+ * Each tuning lists the notes of the open strings. The chromatic tuning has no
+ * list of notes and matches any note of the chromatic scale.
+ */
+func tunings() []tuningStruct {
+
+	/*
+	 * The supported tunings.
+	 */
+	tuns := []tuningStruct{
+		tuningStruct{
+			name:  "chromatic",
+			notes: nil,
 		},
-		noteStruct{
-			name:      "D#6",
-			frequency: 1244.5079,
+		tuningStruct{
+			name:  "standard",
+			notes: []string{"E2", "A2", "D3", "G3", "H3", "E4"},
 		},
-		noteStruct{
-			name:      "E6",
-			frequency: 1318.5102,
+		tuningStruct{
+			name:  "drop_d",
+			notes: []string{"D2", "A2", "D3", "G3", "H3", "E4"},
 		},
-		noteStruct{
-			name:      "F6",
-			frequency: 1396.9129,
+		tuningStruct{
+			name:  "half_step_down",
+			notes: []string{"D#2", "G#2", "C#3", "F#3", "A#3", "D#4"},
 		},
-		noteStruct{
-			name:      "F#6",
-			frequency: 1479.9777,
+		tuningStruct{
+			name:  "d_standard",
+			notes: []string{"D2", "G2", "C3", "F3", "A3", "D4"},
 		},
-		noteStruct{
-			name:      "G6",
-			frequency: 1567.9817,
+		tuningStruct{
+			name:  "drop_c",
+			notes: []string{"C2", "G2", "C3", "F3", "A3", "D4"},
 		},
-		noteStruct{
-			name:      "G#6",
-			frequency: 1661.2188,
+		tuningStruct{
+			name:  "open_d",
+			notes: []string{"D2", "A2", "D3", "F#3", "A3", "D4"},
 		},
-		noteStruct{
-			name:      "A6",
-			frequency: 1760.0000,
+		tuningStruct{
+			name:  "open_g",
+			notes: []string{"D2", "G2", "D3", "G3", "H3", "D4"},
 		},
-		noteStruct{
-			name:      "A#6",
-			frequency: 1864.6550,
+		tuningStruct{
+			name:  "dadgad",
+			notes: []string{"D2", "A2", "D3", "G3", "A3", "D4"},
 		},
-		noteStruct{
-			name:      "H6",
-			frequency: 1975.5332,
+		tuningStruct{
+			name:  "seven_string",
+			notes: []string{"H1", "E2", "A2", "D3", "G3", "H3", "E4"},
 		},
 	}
 
+	return tuns
+}
+
+/*
+ * Returns the index of a notation or -1 if there is no such notation.
+ */
+func notationIndex(nots []notationStruct, name string) int {
+
+	/*
+	 * Iterate over the notations.
+	 */
+	for i, not := range nots {
+
+		/*
+		 * Check if we found the notation.
+		 */
+		if not.name == name {
+			return i
+		}
+
+	}
+
+	return -1
+}
+
+/*
+ * Returns the index of a temperament or -1 if there is no such temperament.
+ */
+func temperamentIndex(temps []temperamentStruct, name string) int {
+
+	/*
+	 * Compare the name of each temperament.
+	 */
+	for i, temp := range temps {
+
+		/*
+		 * Check if we found the temperament.
+		 */
+		if temp.name == name {
+			return i
+		}
+
+	}
+
+	return -1
+}
+
+/*
+ * Returns the index of a tuning or -1 if there is no such tuning.
+ */
+func tuningIndex(tuns []tuningStruct, name string) int {
+
+	/*
+	 * Compare the name of each tuning.
+	 */
+	for i, tun := range tuns {
+
+		/*
+		 * Check if we found the tuning.
+		 */
+		if tun.name == name {
+			return i
+		}
+
+	}
+
+	return -1
+}
+
+/*
+ * Generates a list of notes and their frequencies from H1 to H6.
+ *
+ * f(n) = 2^(c(n) / 1200) * reference
+ *
+ * Where c(n) is the distance of the note n from A4 in cents according to the
+ * temperament.
+ */
+func generateNotes(reference float64, temp temperamentStruct, not notationStruct) []noteStruct {
+	nots := notations()
+	idx := notationIndex(nots, NOTATION_DEFAULT)
+	names := nots[idx].names
+	labels := not.names
+	centsA := temp.cents[NOTE_INDEX_A]
+	notes := make([]noteStruct, 0, NOTE_COUNT)
+
+	/*
+	 * Generate each note, starting at the last note of the lowest octave.
+	 */
+	for i := 0; i < NOTE_COUNT; i++ {
+		idx := NOTE_INDEX_FIRST + i
+		octave := OCTAVE_FIRST + (idx / NOTES_PER_OCTAVE)
+		pitchClass := idx % NOTES_PER_OCTAVE
+		name := fmt.Sprintf("%s%d", names[pitchClass], octave)
+		label := fmt.Sprintf("%s%d", labels[pitchClass], octave)
+		octaveOffset := float64(octave - OCTAVE_REFERENCE)
+		cents := (1200.0 * octaveOffset) + temp.cents[pitchClass] - centsA
+		exponent := cents / 1200.0
+		frequency := math.Pow(2.0, exponent) * reference
+
+		/*
+		 * Create the note.
+		 */
+		note := noteStruct{
+			name:      name,
+			label:     label,
+			frequency: frequency,
+		}
+
+		notes = append(notes, note)
+	}
+
 	return notes
 }
 
+/*
+ * Selects the notes an instrument in a certain tuning may be tuned to.
+ */
+func selectNotes(notes []noteStruct, tun tuningStruct) []noteStruct {
+	names := tun.notes
+
+	/*
+	 * The chromatic tuning matches all notes.
+	 */
+	if names == nil {
+		return notes
+	} else {
+		selected := []noteStruct{}
+
+		/*
+		 * Keep the notes which are part of the tuning.
+		 */
+		for _, note := range notes {
+
+			/*
+			 * Check if this note is part of the tuning.
+			 */
+			for _, name := range names {
+
+				/*
+				 * If this is a note of the tuning, keep it.
+				 */
+				if note.name == name {
+					selected = append(selected, note)
+					break
+				}
+
+			}
+
+		}
+
+		return selected
+	}
+
+}
+
+/*
+ * Regenerates the list of notes from the reference pitch, the temperament and
+ * the tuning.
+ *
+ * The analysis mutex must be held when calling this.
+ */
+func (this *tunerStruct) updateNotes() {
+	notes := generateNotes(this.reference, this.temperament, this.notation)
+	tun := this.tuning
+	candidates := selectNotes(notes, tun)
+
+	/*
+	 * The chromatic tuning has no strings, so check the strings of the
+	 * standard tuning instead.
+	 */
+	if tun.notes == nil {
+		tuns := tunings()
+		idx := tuningIndex(tuns, TUNING_STRINGS)
+		tun = tuns[idx]
+	}
+
+	strings := selectNotes(notes, tun)
+	this.notes = notes
+	this.candidates = candidates
+	this.strings = strings
+}
+
 /*
  * Find the maximum value in a buffer.
  */
@@ -334,54 +455,572 @@ func findMaximum(buf []float64) (float64, int) {
 	maxIdx := int(-1)
 
 	/*
-	 * Iterate over the buffer and find the maximum value.
+	 * Iterate over the buffer and find the maximum value.
+	 */
+	for idx, value := range buf {
+
+		/*
+		 * If we found a value which is greater than any value we
+		 * encountered so far, make it the new candidate.
+		 */
+		if value > maxVal {
+			maxVal = value
+			maxIdx = idx
+		}
+
+	}
+
+	return maxVal, maxIdx
+}
+
+/*
+ * Find the period of a signal from its auto-correlation.
+ *
+ * This skips the peak at zero lag and returns the first local maximum which
+ * comes close to the largest correlation after it. When the period does not
+ * fall on a whole number of samples, the auto-correlation may peak at a
+ * multiple of the period instead of the period itself, so this prefers the
+ * shortest lag with a similar correlation.
+ */
+func findFirstPeak(buf []float64) int {
+	n := len(buf)
+	lastIdx := n - 2
+	startIdx := 1
+
+	/*
+	 * Skip the descent from the peak at zero lag.
+	 */
+	for (startIdx < lastIdx) && (buf[startIdx] <= buf[startIdx-1]) {
+		startIdx++
+	}
+
+	subBuf := buf[startIdx:n]
+	maxVal, maxIdx := findMaximum(subBuf)
+	maxIdx += startIdx
+	threshold := STROBE_PEAK_RATIO * maxVal
+
+	/*
+	 * Only look for local maxima before the global one.
+	 */
+	for idx := startIdx; idx < maxIdx; idx++ {
+		value := buf[idx]
+
+		/*
+		 * Check if this is a local maximum which is large enough.
+		 */
+		if (value >= threshold) && (value >= buf[idx-1]) && (value >= buf[idx+1]) {
+			return idx
+		}
+
+	}
+
+	return maxIdx
+}
+
+/*
+ * Calculates the auto-correlation of a signal.
+ *
+ * The first n samples of the correlation buffer hold the signal. The rest of
+ * the buffer, which must be at least twice as long as the signal to avoid
+ * circular wrap-around, is zero-padded. On return, the buffer holds the
+ * (unnormalized) auto-correlation of the signal. The spectrum buffer must be
+ * able to hold the spectrum of the correlation buffer.
+ */
+func Autocorrelate(ft fft.FourierTransform, bufCorrelation []float64, n int, bufFFT []complex128) error {
+	size := len(bufCorrelation)
+	tailBuffer := bufCorrelation[n:size]
+	fft.ZeroFloat(tailBuffer)
+	err := ft.RealFourier(bufCorrelation, bufFFT, fft.SCALING_DEFAULT)
+
+	/*
+	 * Verify that the forward FFT was calculated successfully.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return fmt.Errorf("Failed to calculate forward FFT: %s", msg)
+	} else {
+
+		/*
+		 * Multiply each element of the spectrum with its complex conjugate.
+		 */
+		for i, elem := range bufFFT {
+			elemConj := cmplx.Conj(elem)
+			bufFFT[i] = elem * elemConj
+		}
+
+		err = ft.RealInverseFourier(bufFFT, bufCorrelation, fft.SCALING_DEFAULT)
+
+		/*
+		 * Verify that the inverse FFT was calculated successfully.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to calculate inverse FFT: %s", msg)
+		} else {
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Estimates the period of a signal from its auto-correlation.
+ *
+ * Looks for the largest correlation at lags from lowIdx (inclusive) to highIdx
+ * (exclusive) and refines its position by parabolic interpolation. Returns the
+ * period in (fractional) samples and the correlation at the peak.
+ */
+func EstimatePeriod(correlation []float64, lowIdx int, highIdx int) (float64, float64) {
+	n := len(correlation)
+	lastIdx := n - 1
+	subCorrelation := correlation[lowIdx:highIdx]
+	maxVal, maxIdx := findMaximum(subCorrelation)
+	idx := lowIdx + maxIdx
+	idxUp := idx + 1
+
+	/*
+	 * Prevent overrun.
+	 */
+	if idxUp > lastIdx {
+		idxUp = lastIdx
+	}
+
+	idxDown := idx - 1
+
+	/*
+	 * Prevent underrun.
+	 */
+	if idxDown < 0 {
+		idxDown = 0
+	}
+
+	valueLeft := correlation[idxDown]
+	valueRight := correlation[idxUp]
+	idxFloat := float64(idx)
+	valueDiff := valueRight - valueLeft
+	valueSum := valueRight + valueLeft
+	halfDiff := 0.5 * valueDiff
+	doubleMaxVal := 2.0 * maxVal
+	denominatorDiff := doubleMaxVal - valueSum
+	shiftEstimation := halfDiff / denominatorDiff
+
+	/*
+	 * Limit shift estimation to plus/minus half a sample.
+	 */
+	if shiftEstimation < -0.5 {
+		shiftEstimation = -0.5
+	} else if shiftEstimation > 0.5 {
+		shiftEstimation = 0.5
+	}
+
+	idxFloat += shiftEstimation
+	return idxFloat, maxVal
+}
+
+/*
+ * Returns the deviation from the reference note in cents.
+ */
+func (this *resultStruct) Cents() int8 {
+	return this.cents
+}
+
+/*
+ * Returns the fundamental frequency of the signal.
+ */
+func (this *resultStruct) Frequency() float64 {
+	return this.frequency
+}
+
+/*
+ * Returns the name of the closest note on the chromatic scale.
+ */
+func (this *resultStruct) Note() string {
+	return this.note
+}
+
+/*
+ * Apply a Hann window to a signal in place to reduce spectral leakage.
+ */
+func applyHannWindow(buf []float64) {
+	n := len(buf)
+	lastSample := float64(n - 1)
+
+	/*
+	 * Weight each sample.
+	 */
+	for i, sample := range buf {
+		iFloat := float64(i)
+		arg := 2.0 * math.Pi * (iFloat / lastSample)
+		window := 0.5 - (0.5 * math.Cos(arg))
+		buf[i] = window * sample
+	}
+
+}
+
+/*
+ * Calculates the discrete-time Fourier transform of a signal at a single
+ * frequency, given in cycles per sample.
+ */
+func dtft(buf []float64, cyclesPerSample float64) complex128 {
+	arg := -2.0 * math.Pi * cyclesPerSample
+	rotation := cmplx.Rect(1.0, arg)
+	phasor := complex(1.0, 0.0)
+	sum := complex(0.0, 0.0)
+
+	/*
+	 * Correlate each sample with the rotating phasor.
+	 */
+	for _, sample := range buf {
+		sampleComplex := complex(sample, 0.0)
+		sum += sampleComplex * phasor
+		phasor *= rotation
+	}
+
+	return sum
+}
+
+/*
+ * Finds the frequency, in cycles per sample, at which the magnitude of the
+ * spectrum of a signal peaks within an interval, using golden-section search.
+ *
+ * The interval must contain a single peak.
+ */
+func refinePeak(buf []float64, low float64, high float64) float64 {
+	ratio := 0.5 * (math.Sqrt(5.0) - 1.0)
+	left := high - (ratio * (high - low))
+	right := low + (ratio * (high - low))
+	valueLeft := cmplx.Abs(dtft(buf, left))
+	valueRight := cmplx.Abs(dtft(buf, right))
+
+	/*
+	 * Narrow the interval down around the peak.
+	 */
+	for i := 0; i < STROBE_ITERATIONS; i++ {
+
+		/*
+		 * Keep the part of the interval which contains the peak.
+		 */
+		if valueLeft < valueRight {
+			low = left
+			left = right
+			valueLeft = valueRight
+			right = low + (ratio * (high - low))
+			valueRight = cmplx.Abs(dtft(buf, right))
+		} else {
+			high = right
+			right = left
+			valueRight = valueLeft
+			left = high - (ratio * (high - low))
+			valueLeft = cmplx.Abs(dtft(buf, left))
+		}
+
+	}
+
+	center := 0.5 * (low + high)
+	return center
+}
+
+/*
+ * Converts the position of a bin into an index in the range [1, lastBin].
+ *
+ * The position might not be finite, e. g. when the sample rate is not yet
+ * known.
+ */
+func binIndex(position float64, lastBin int) int {
+	lastBinFloat := float64(lastBin)
+
+	/*
+	 * Limit the position to the range of the spectrum.
+	 */
+	if !(position >= 1.0) {
+		return 1
+	} else if !(position <= lastBinFloat) {
+		return lastBin
+	} else {
+		return int(position)
+	}
+
+}
+
+/*
+ * Find the bin with the largest magnitude in a range of the spectrum.
+ */
+func findPeak(spectrum []complex128, lowIdx int, highIdx int) (float64, int) {
+	maxVal := float64(0.0)
+	maxIdx := lowIdx
+
+	/*
+	 * Iterate over the range and find the largest magnitude.
+	 */
+	for idx := lowIdx; idx <= highIdx; idx++ {
+		value := cmplx.Abs(spectrum[idx])
+
+		/*
+		 * If we found a magnitude which is greater than any magnitude we
+		 * encountered so far, make it the new candidate.
+		 */
+		if value > maxVal {
+			maxVal = value
+			maxIdx = idx
+		}
+
+	}
+
+	return maxVal, maxIdx
+}
+
+/*
+ * Estimates the position of a spectral peak between two bins by fitting a
+ * parabola through the logarithmic magnitudes around it.
+ */
+func interpolatePeak(spectrum []complex128, idx int) float64 {
+	idxFloat := float64(idx)
+	lastIdx := len(spectrum) - 1
+
+	/*
+	 * The peak cannot be interpolated at the edges of the spectrum.
+	 */
+	if (idx < 1) || (idx >= lastIdx) {
+		return idxFloat
+	} else {
+		magLeft := cmplx.Abs(spectrum[idx-1])
+		magCenter := cmplx.Abs(spectrum[idx])
+		magRight := cmplx.Abs(spectrum[idx+1])
+		valueLeft := math.Log(magLeft + math.SmallestNonzeroFloat64)
+		valueCenter := math.Log(magCenter + math.SmallestNonzeroFloat64)
+		valueRight := math.Log(magRight + math.SmallestNonzeroFloat64)
+		denominator := valueLeft - (2.0 * valueCenter) + valueRight
+		shiftEstimation := float64(0.0)
+
+		/*
+		 * Only estimate the shift if the parabola is not degenerate.
+		 */
+		if denominator != 0.0 {
+			shiftEstimation = 0.5 * (valueLeft - valueRight) / denominator
+		}
+
+		/*
+		 * Limit shift estimation to plus/minus half a bin.
+		 */
+		if shiftEstimation < -0.5 {
+			shiftEstimation = -0.5
+		} else if shiftEstimation > 0.5 {
+			shiftEstimation = 0.5
+		}
+
+		return idxFloat + shiftEstimation
+	}
+
+}
+
+/*
+ * Converts a deviation in cents into the range of a result.
+ */
+func limitCents(cents float64) int8 {
+	centsInfinite := math.IsInf(cents, 0)
+	centsNaN := math.IsNaN(cents)
+	centsInt := int8(0)
+
+	/*
+	 * If cents are finite, use them.
+	 */
+	if !(centsInfinite || centsNaN) {
+
+		/*
+		 * Limit the deviation to the range of the result.
+		 */
+		if cents < math.MinInt8 {
+			cents = math.MinInt8
+		} else if cents > math.MaxInt8 {
+			cents = math.MaxInt8
+		}
+
+		centsInt = int8(cents)
+	}
+
+	return centsInt
+}
+
+/*
+ * Returns the deviation from the reference note in cents.
+ */
+func (this *strobeResultStruct) Cents() float64 {
+	return this.cents
+}
+
+/*
+ * Returns the fundamental frequency of the signal.
+ */
+func (this *strobeResultStruct) Frequency() float64 {
+	return this.frequency
+}
+
+/*
+ * Returns the name of the closest note.
+ */
+func (this *strobeResultStruct) Note() string {
+	return this.note
+}
+
+/*
+ * Returns the phase of the signal relative to an oscillator running at the
+ * frequency of the reference note, as a fraction of a period in [0, 1).
+ *
+ * The phase advances by the difference between both frequencies each second,
+ * so a strobe display shifted by it moves in the direction of the deviation.
+ */
+func (this *strobeResultStruct) Phase() float64 {
+	return this.phase
+}
+
+/*
+ * Analyze buffered stream for spectral content.
+ */
+func (this *tunerStruct) Analyze() (Result, error) {
+	this.mutexAnalyze.Lock()
+	circularBuffer := this.buffer
+	bufCorrelation := this.bufCorrelation
+	bufCorrrlationLength := len(bufCorrelation)
+	bufCorrelationLength64 := uint64(bufCorrrlationLength)
+	bufFFT := this.bufFFT
+	bufFFTLength := len(bufFFT)
+	bufFFTLength64 := uint64(bufFFTLength)
+	n := circularBuffer.Length()
+	twoN := uint64(2 * n)
+	fftSize, _ := fft.NextPowerOfTwo(twoN)
+
+	/*
+	 * Ensure that correlation buffer is of correct length.
+	 */
+	if bufCorrelationLength64 != fftSize {
+		bufCorrelation = make([]float64, fftSize)
+		this.bufCorrelation = bufCorrelation
+	}
+
+	/*
+	 * Ensure that FFT buffer is of correct length.
+	 */
+	if bufFFTLength64 != fftSize {
+		bufFFT = make([]complex128, fftSize)
+		this.bufFFT = bufFFT
+	}
+
+	signalBuffer := bufCorrelation[0:n]
+	this.mutexBuffer.RLock()
+	sampleRate := this.sampleRate
+	err := circularBuffer.Retrieve(signalBuffer)
+	this.mutexBuffer.RUnlock()
+
+	/*
+	 * Verify that buffer contents could be retrieved.
 	 */
-	for idx, value := range buf {
+	if err != nil {
+		msg := err.Error()
+		this.mutexAnalyze.Unlock()
+		return nil, fmt.Errorf("Failed to retrieve contents of circular buffer: %s", msg)
+	} else {
+		ft := this.fourierTransform
+		err = Autocorrelate(ft, bufCorrelation, n, bufFFT)
 
 		/*
-		 * If we found a value which is greater than any value we
-		 * encountered so far, make it the new candidate.
+		 * Verify that the auto-correlation was calculated successfully.
 		 */
-		if value > maxVal {
-			maxVal = value
-			maxIdx = idx
-		}
+		if err != nil {
+			this.mutexAnalyze.Unlock()
+			return nil, err
+		} else {
+			notes := this.notes
+			noteCount := len(notes)
+			lastNote := noteCount - 1
+			lowFreq := notes[0].frequency
+			highFreq := notes[lastNote].frequency
+			sampleRateFloat := float64(sampleRate)
+			lowIdx := int((sampleRateFloat / highFreq) + 0.5)
+			lowIdx64 := uint64(lowIdx)
 
-	}
+			/*
+			 * This might happen when the float value is infinite.
+			 */
+			if (lowIdx < 0) || (lowIdx64 >= twoN) {
+				lowIdx = 0
+				lowIdx64 = 0
+			}
 
-	return maxVal, maxIdx
-}
+			highIdx := int((sampleRateFloat / lowFreq) + 0.5)
+			highIdx64 := uint64(highIdx)
 
-/*
- * Returns the deviation from the reference note in cents.
- */
-func (this *resultStruct) Cents() int8 {
-	return this.cents
-}
+			/*
+			 * This might happen when the float value is infinite.
+			 */
+			if (highIdx < 0) || (highIdx64 >= twoN) {
+				maxIdx := twoN - 1
+				highIdx = int(maxIdx)
+				highIdx64 = maxIdx
+			}
 
-/*
- * Returns the fundamental frequency of the signal.
- */
-func (this *resultStruct) Frequency() float64 {
-	return this.frequency
-}
+			idxFloat, _ := EstimatePeriod(bufCorrelation, lowIdx, highIdx)
+			actualFrequency := sampleRateFloat / idxFloat
+			actualNote := "Unknown"
+			actualCents := math.Inf(1)
+			actualCentsAbs := math.Abs(actualCents)
+
+			candidates := this.candidates
+
+			/*
+			 * Iterate over all candidate notes and find the closest match.
+			 */
+			for _, note := range candidates {
+				freq := note.frequency
+				freqRatio := actualFrequency / freq
+				diffCents := 1200.0 * math.Log2(freqRatio)
+				diffCentsAbs := math.Abs(diffCents)
+
+				/*
+				 * If this is the closest we've seen so far, make this the best match.
+				 */
+				if diffCentsAbs < actualCentsAbs {
+					actualNote = note.label
+					actualCents = diffCents
+					actualCentsAbs = diffCentsAbs
+				}
+
+			}
+
+			actualCentsInt := limitCents(actualCents)
+
+			/*
+			 * Create result of signal analysis.
+			 */
+			result := resultStruct{
+				cents:     actualCentsInt,
+				frequency: actualFrequency,
+				note:      actualNote,
+			}
+
+			this.mutexAnalyze.Unlock()
+			return &result, nil
+		}
+
+	}
 
-/*
- * Returns the name of the closest note on the chromatic scale.
- */
-func (this *resultStruct) Note() string {
-	return this.note
 }
 
 /*
- * Analyze buffered stream for spectral content.
+ * Analyze buffered stream for the pitch of each string of the instrument.
+ *
+ * The strings are taken from the selected tuning or from the standard tuning
+ * if the chromatic tuning is selected. A string is only reported if the
+ * spectral peak closest to its pitch is no more than 20 dB weaker than the
+ * peak of the loudest string. Since the overtones of one string may coincide
+ * with the pitch of another one, this works best when all strings are struck.
  */
-func (this *tunerStruct) Analyze() (Result, error) {
+func (this *tunerStruct) AnalyzeStrings() ([]Result, error) {
 	this.mutexAnalyze.Lock()
 	circularBuffer := this.buffer
 	bufCorrelation := this.bufCorrelation
-	bufCorrrlationLength := len(bufCorrelation)
-	bufCorrelationLength64 := uint64(bufCorrrlationLength)
+	bufCorrelationLength := len(bufCorrelation)
+	bufCorrelationLength64 := uint64(bufCorrelationLength)
 	bufFFT := this.bufFFT
 	bufFFTLength := len(bufFFT)
 	bufFFTLength64 := uint64(bufFFTLength)
@@ -419,6 +1058,7 @@ func (this *tunerStruct) Analyze() (Result, error) {
 		this.mutexAnalyze.Unlock()
 		return nil, fmt.Errorf("Failed to retrieve contents of circular buffer: %s", msg)
 	} else {
+		applyHannWindow(signalBuffer)
 		ft := this.fourierTransform
 		tailBuffer := bufCorrelation[n:fftSize]
 		fft.ZeroFloat(tailBuffer)
@@ -432,104 +1072,232 @@ func (this *tunerStruct) Analyze() (Result, error) {
 			this.mutexAnalyze.Unlock()
 			return nil, fmt.Errorf("Failed to calculate forward FFT: %s", msg)
 		} else {
+			strings := this.strings
+			numStrings := len(strings)
+			peakValues := make([]float64, numStrings)
+			peakIndices := make([]int, numStrings)
+			sampleRateFloat := float64(sampleRate)
+			fftSizeFloat := float64(fftSize)
+			binWidth := sampleRateFloat / fftSizeFloat
+			lastBin := int(fftSize/2) - 1
+			rangeFactor := math.Pow(2.0, STRING_RANGE_CENTS/1200.0)
+			maxVal := float64(0.0)
 
 			/*
-			 * Multiply each element of the spectrum with its complex conjugate.
+			 * Find the spectral peak closest to the pitch of each string.
 			 */
-			for i, elem := range bufFFT {
-				elemConj := cmplx.Conj(elem)
-				bufFFT[i] = elem * elemConj
+			for i, str := range strings {
+				freq := str.frequency
+				lowIdx := binIndex(math.Ceil((freq/rangeFactor)/binWidth), lastBin)
+				highIdx := binIndex(math.Floor((freq*rangeFactor)/binWidth), lastBin)
+				value, idx := findPeak(bufFFT, lowIdx, highIdx)
+				peakValues[i] = value
+				peakIndices[i] = idx
+
+				/*
+				 * Keep track of the loudest string.
+				 */
+				if value > maxVal {
+					maxVal = value
+				}
+
 			}
 
-			err = ft.RealInverseFourier(bufFFT, bufCorrelation, fft.SCALING_DEFAULT)
+			threshold := STRING_THRESHOLD * maxVal
+			results := []Result{}
 
 			/*
-			 * Verify that the inverse FFT was calculated successfully.
+			 * Report each string which is loud enough.
 			 */
-			if err != nil {
-				msg := err.Error()
-				this.mutexAnalyze.Unlock()
-				return nil, fmt.Errorf("Failed to calculate inverse FFT: %s", msg)
-			} else {
-				notes := this.notes
-				noteCount := len(notes)
-				lastNote := noteCount - 1
-				lowFreq := notes[0].frequency
-				highFreq := notes[lastNote].frequency
-				sampleRateFloat := float64(sampleRate)
-				lowIdx := int((sampleRateFloat / highFreq) + 0.5)
-				lowIdx64 := uint64(lowIdx)
+			for i, str := range strings {
+				value := peakValues[i]
 
 				/*
-				 * This might happen when the float value is infinite.
+				 * Check if the string was detected.
 				 */
-				if (lowIdx < 0) || (lowIdx64 >= twoN) {
-					lowIdx = 0
-					lowIdx64 = 0
-				}
+				if (value > 0.0) && (value >= threshold) {
+					idx := peakIndices[i]
+					idxFloat := interpolatePeak(bufFFT, idx)
+					actualFrequency := idxFloat * binWidth
+					freqRatio := actualFrequency / str.frequency
+					diffCents := 1200.0 * math.Log2(freqRatio)
+					cents := limitCents(diffCents)
 
-				highIdx := int((sampleRateFloat / lowFreq) + 0.5)
-				highIdx64 := uint64(highIdx)
+					/*
+					 * Create result of signal analysis.
+					 */
+					result := &resultStruct{
+						cents:     cents,
+						frequency: actualFrequency,
+						note:      str.label,
+					}
 
-				/*
-				 * This might happen when the float value is infinite.
-				 */
-				if (highIdx < 0) || (highIdx64 >= twoN) {
-					maxIdx := twoN - 1
-					highIdx = int(maxIdx)
-					highIdx64 = maxIdx
+					results = append(results, result)
 				}
 
-				subCorrelation := bufCorrelation[lowIdx:highIdx]
-				maxVal, maxIdx := findMaximum(subCorrelation)
-				idx := lowIdx + maxIdx
-				idxUp := idx + 1
+			}
 
-				/*
-				 * Prevent overrun.
-				 */
-				if idxUp > n {
-					idxUp = n
-				}
+			this.mutexAnalyze.Unlock()
+			return results, nil
+		}
 
-				idxDown := idx - 1
+	}
 
-				/*
-				 * Prevent underrun.
-				 */
-				if idxDown < 0 {
-					idxDown = 0
-				}
+}
+
+/*
+ * Analyze the most recent samples of the buffered stream with high resolution.
+ *
+ * This looks at a much shorter window than Analyze, so it reacts faster to
+ * changes in pitch. After the auto-correlation has determined the note, the
+ * frequency is refined by locating the peak of the spectrum between bins, so
+ * the deviation is reported with fractional cents, along with a phase value
+ * for a strobe display.
+ */
+func (this *tunerStruct) AnalyzeStrobe() (StrobeResult, error) {
+	this.mutexAnalyze.Lock()
+	circularBuffer := this.buffer
+	n := circularBuffer.Length()
+	bufSignal := this.bufCorrelation
+	bufSignalLength := len(bufSignal)
+
+	/*
+	 * Ensure that the signal buffer is large enough.
+	 */
+	if bufSignalLength < n {
+		twoN := uint64(2 * n)
+		fftSize, _ := fft.NextPowerOfTwo(twoN)
+		bufSignal = make([]float64, fftSize)
+		this.bufCorrelation = bufSignal
+	}
+
+	windowSize := STROBE_SAMPLES
+
+	/*
+	 * The window cannot be larger than the buffer.
+	 */
+	if windowSize > n {
+		windowSize = n
+	}
+
+	twoWindowSize := uint64(2 * windowSize)
+	fftSize, _ := fft.NextPowerOfTwo(twoWindowSize)
+	bufStrobe := this.bufStrobe
+	bufStrobeLength := len(bufStrobe)
+	bufStrobeLength64 := uint64(bufStrobeLength)
+
+	/*
+	 * Ensure that the strobe buffer is of correct length.
+	 */
+	if bufStrobeLength64 != fftSize {
+		bufStrobe = make([]float64, fftSize)
+		this.bufStrobe = bufStrobe
+	}
+
+	bufStrobeFFT := this.bufStrobeFFT
+	bufStrobeFFTLength := len(bufStrobeFFT)
+	bufStrobeFFTLength64 := uint64(bufStrobeFFTLength)
+
+	/*
+	 * Ensure that the strobe FFT buffer is of correct length.
+	 */
+	if bufStrobeFFTLength64 != fftSize {
+		bufStrobeFFT = make([]complex128, fftSize)
+		this.bufStrobeFFT = bufStrobeFFT
+	}
+
+	signalBuffer := bufSignal[0:n]
+	this.mutexBuffer.RLock()
+	sampleRate := this.sampleRate
+	sampleCount := this.sampleCount
+	err := circularBuffer.Retrieve(signalBuffer)
+	this.mutexBuffer.RUnlock()
+
+	/*
+	 * Verify that buffer contents could be retrieved.
+	 */
+	if err != nil {
+		msg := err.Error()
+		this.mutexAnalyze.Unlock()
+		return nil, fmt.Errorf("Failed to retrieve contents of circular buffer: %s", msg)
+	} else {
+		windowStart := n - windowSize
+		window := signalBuffer[windowStart:n]
+		windowed := bufStrobe[0:windowSize]
+		tailBuffer := bufStrobe[windowSize:fftSize]
+		copy(windowed, window)
+		ft := this.fourierTransform
+		err = Autocorrelate(ft, bufStrobe, windowSize, bufStrobeFFT)
+
+		/*
+		 * Verify that the auto-correlation was calculated successfully.
+		 */
+		if err != nil {
+			this.mutexAnalyze.Unlock()
+			return nil, err
+		} else {
+			notes := this.notes
+			lowFreq := notes[0].frequency
+			sampleRateFloat := float64(sampleRate)
+			highIdx := int((sampleRateFloat / lowFreq) + 0.5)
+			maxIdx := windowSize - 1
+
+			/*
+			 * This might happen when the float value is infinite or
+			 * the window is too short for the lowest note.
+			 */
+			if (highIdx < 3) || (highIdx > maxIdx) {
+				highIdx = maxIdx
+			}
+
+			correlation := bufStrobe[0:highIdx]
+			idx := findFirstPeak(correlation)
+			idxFloat := float64(idx)
+			coarseFrequency := sampleRateFloat / idxFloat
+			copy(windowed, window)
+			applyHannWindow(windowed)
+			fft.ZeroFloat(tailBuffer)
+			err = ft.RealFourier(bufStrobe, bufStrobeFFT, fft.SCALING_DEFAULT)
 
-				valueLeft := bufCorrelation[idxDown]
-				valueRight := bufCorrelation[idxUp]
-				idxFloat := float64(idx)
-				valueDiff := valueRight - valueLeft
-				valueSum := valueRight + valueLeft
-				halfDiff := 0.5 * valueDiff
-				doubleMaxVal := 2.0 * maxVal
-				denominatorDiff := doubleMaxVal - valueSum
-				shiftEstimation := halfDiff / denominatorDiff
+			/*
+			 * Verify that the forward FFT was calculated successfully.
+			 */
+			if err != nil {
+				msg := err.Error()
+				this.mutexAnalyze.Unlock()
+				return nil, fmt.Errorf("Failed to calculate forward FFT: %s", msg)
+			} else {
+				fftSizeFloat := float64(fftSize)
+				binWidth := sampleRateFloat / fftSizeFloat
+				rangeFactor := math.Pow(2.0, STROBE_RANGE_CENTS/1200.0)
+				lastBin := int(fftSize/2) - 2
+				lowBin := binIndex(math.Ceil((coarseFrequency/rangeFactor)/binWidth), lastBin)
+				highBin := binIndex(math.Floor((coarseFrequency*rangeFactor)/binWidth), lastBin)
 
 				/*
-				 * Limit shift estimation to plus/minus half a sample.
+				 * Make sure the search range is not empty.
 				 */
-				if shiftEstimation < -0.5 {
-					shiftEstimation = -0.5
-				} else if shiftEstimation > 0.5 {
-					shiftEstimation = 0.5
+				if lowBin > highBin {
+					lowBin = highBin
 				}
 
-				idxFloat += shiftEstimation
-				actualFrequency := sampleRateFloat / idxFloat
+				_, bin := findPeak(bufStrobeFFT, lowBin, highBin)
+				binLow := float64(bin - 1)
+				binHigh := float64(bin + 1)
+				cyclesLow := binLow / fftSizeFloat
+				cyclesHigh := binHigh / fftSizeFloat
+				cyclesPerSample := refinePeak(windowed, cyclesLow, cyclesHigh)
+				actualFrequency := cyclesPerSample * sampleRateFloat
 				actualNote := "Unknown"
 				actualCents := math.Inf(1)
 				actualCentsAbs := math.Abs(actualCents)
+				noteFrequency := float64(0.0)
+				candidates := this.candidates
 
 				/*
-				 * Iterate over all notes and find the closest match.
+				 * Iterate over all candidate notes and find the closest match.
 				 */
-				for _, note := range notes {
+				for _, note := range candidates {
 					freq := note.frequency
 					freqRatio := actualFrequency / freq
 					diffCents := 1200.0 * math.Log2(freqRatio)
@@ -539,31 +1307,59 @@ func (this *tunerStruct) Analyze() (Result, error) {
 					 * If this is the closest we've seen so far, make this the best match.
 					 */
 					if diffCentsAbs < actualCentsAbs {
-						actualNote = note.name
+						actualNote = note.label
 						actualCents = diffCents
 						actualCentsAbs = diffCentsAbs
+						noteFrequency = freq
 					}
 
 				}
 
 				actualCentsInfinite := math.IsInf(actualCents, 0)
 				actualCentsNaN := math.IsNaN(actualCents)
-				actualCentsInt := int8(0)
 
 				/*
-				 * If cents are finite, use them.
+				 * Report no deviation if it cannot be determined.
+				 */
+				if actualCentsInfinite || actualCentsNaN {
+					actualCents = 0.0
+				}
+
+				sampleCountFloat := float64(sampleCount)
+				windowSizeFloat := float64(windowSize)
+				startCount := sampleCountFloat - windowSizeFloat
+				noteCycles := noteFrequency / sampleRateFloat
+				startPhase := math.Mod(noteCycles*startCount, 1.0)
+				startArg := -2.0 * math.Pi * startPhase
+				startRotation := cmplx.Rect(1.0, startArg)
+				correlation := dtft(windowed, noteCycles)
+				correlation *= startRotation
+				angle := cmplx.Phase(correlation)
+				phase := angle / (2.0 * math.Pi)
+
+				/*
+				 * Map the phase into the interval [0, 1).
+				 */
+				if phase < 0.0 {
+					phase += 1.0
+				}
+
+				/*
+				 * Guard against rounding up to a full period and against
+				 * an unknown sample rate.
 				 */
-				if !(actualCentsInfinite || actualCentsNaN) {
-					actualCentsInt = int8(actualCents)
+				if !(phase < 1.0) {
+					phase = 0.0
 				}
 
 				/*
 				 * Create result of signal analysis.
 				 */
-				result := resultStruct{
-					cents:     actualCentsInt,
+				result := strobeResultStruct{
+					cents:     actualCents,
 					frequency: actualFrequency,
 					note:      actualNote,
+					phase:     phase,
 				}
 
 				this.mutexAnalyze.Unlock()
@@ -583,14 +1379,205 @@ func (this *tunerStruct) Process(samples []float64, sampleRate uint32) {
 	this.mutexBuffer.Lock()
 	this.buffer.Enqueue(samples...)
 	this.sampleRate = sampleRate
+	numSamples := len(samples)
+	this.sampleCount += uint64(numSamples)
 	this.mutexBuffer.Unlock()
 }
 
 /*
- * Creates an instrument tuner.
+ * Returns the frequency of the reference pitch A4.
+ */
+func (this *tunerStruct) Reference() float64 {
+	this.mutexAnalyze.Lock()
+	reference := this.reference
+	this.mutexAnalyze.Unlock()
+	return reference
+}
+
+/*
+ * Returns the name of the selected notation.
+ */
+func (this *tunerStruct) Notation() string {
+	this.mutexAnalyze.Lock()
+	name := this.notation.name
+	this.mutexAnalyze.Unlock()
+	return name
+}
+
+/*
+ * Selects the convention for naming the notes and regenerates the notes.
+ */
+func (this *tunerStruct) SetNotation(name string) error {
+	nots := notations()
+	idx := notationIndex(nots, name)
+
+	/*
+	 * Check if the notation exists.
+	 */
+	if idx < 0 {
+		return fmt.Errorf("Unknown notation: '%s'", name)
+	} else {
+		this.mutexAnalyze.Lock()
+		this.notation = nots[idx]
+		this.updateNotes()
+		this.mutexAnalyze.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Sets the frequency of the reference pitch A4 and regenerates the notes.
+ */
+func (this *tunerStruct) SetReference(frequency float64) error {
+
+	/*
+	 * Check if the frequency is in range.
+	 */
+	if !((frequency >= REFERENCE_MINIMUM) && (frequency <= REFERENCE_MAXIMUM)) {
+		return fmt.Errorf("Reference pitch must be in [%.1f, %.1f] Hz.", REFERENCE_MINIMUM, REFERENCE_MAXIMUM)
+	} else {
+		this.mutexAnalyze.Lock()
+		this.reference = frequency
+		this.updateNotes()
+		this.mutexAnalyze.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Selects a temperament and regenerates the notes.
+ */
+func (this *tunerStruct) SetTemperament(name string) error {
+	temps := temperaments()
+	idx := temperamentIndex(temps, name)
+
+	/*
+	 * Check if the temperament exists.
+	 */
+	if idx < 0 {
+		return fmt.Errorf("Unknown temperament: '%s'", name)
+	} else {
+		this.mutexAnalyze.Lock()
+		this.temperament = temps[idx]
+		this.updateNotes()
+		this.mutexAnalyze.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Selects the tuning of the instrument and regenerates the notes.
+ */
+func (this *tunerStruct) SetTuning(name string) error {
+	tuns := tunings()
+	idx := tuningIndex(tuns, name)
+
+	/*
+	 * Check if the tuning exists.
+	 */
+	if idx < 0 {
+		return fmt.Errorf("Unknown tuning: '%s'", name)
+	} else {
+		this.mutexAnalyze.Lock()
+		this.tuning = tuns[idx]
+		this.updateNotes()
+		this.mutexAnalyze.Unlock()
+		return nil
+	}
+
+}
+
+/*
+ * Returns the name of the selected temperament.
+ */
+func (this *tunerStruct) Temperament() string {
+	this.mutexAnalyze.Lock()
+	name := this.temperament.name
+	this.mutexAnalyze.Unlock()
+	return name
+}
+
+/*
+ * Returns the name of the selected tuning.
+ */
+func (this *tunerStruct) Tuning() string {
+	this.mutexAnalyze.Lock()
+	name := this.tuning.name
+	this.mutexAnalyze.Unlock()
+	return name
+}
+
+/*
+ * Returns the names of all notations the tuner supports.
+ */
+func Notations() []string {
+	nots := notations()
+	numNots := len(nots)
+	names := make([]string, numNots)
+
+	/*
+	 * Collect the name of each notation.
+	 */
+	for i, not := range nots {
+		names[i] = not.name
+	}
+
+	return names
+}
+
+/*
+ * Returns the names of all temperaments the tuner supports.
+ */
+func Temperaments() []string {
+	temps := temperaments()
+	numTemps := len(temps)
+	names := make([]string, numTemps)
+
+	/*
+	 * Collect the name of each temperament.
+	 */
+	for i, temp := range temps {
+		names[i] = temp.name
+	}
+
+	return names
+}
+
+/*
+ * Returns the names of all tunings the tuner supports.
+ */
+func Tunings() []string {
+	tuns := tunings()
+	numTuns := len(tuns)
+	names := make([]string, numTuns)
+
+	/*
+	 * Collect the name of each tuning.
+	 */
+	for i, tun := range tuns {
+		names[i] = tun.name
+	}
+
+	return names
+}
+
+/*
+ * Creates an instrument tuner, which uses equal temperament with A4 at 440 Hz
+ * and matches any note of the chromatic scale.
  */
 func Create() Tuner {
-	notes := generateNotes()
+	temps := temperaments()
+	tempIdx := temperamentIndex(temps, TEMPERAMENT_DEFAULT)
+	temp := temps[tempIdx]
+	nots := notations()
+	notIdx := notationIndex(nots, NOTATION_DEFAULT)
+	not := nots[notIdx]
+	tuns := tunings()
+	tunIdx := tuningIndex(tuns, TUNING_DEFAULT)
+	tun := tuns[tunIdx]
 	buffer := circular.CreateBuffer(NUM_SAMPLES)
 	ft := fft.CreateFourierTransform()
 
@@ -598,10 +1585,14 @@ func Create() Tuner {
 	 * Create data structure for a guitar tuner.
 	 */
 	t := tunerStruct{
-		notes:            notes,
+		reference:        REFERENCE_DEFAULT,
+		temperament:      temp,
+		notation:         not,
+		tuning:           tun,
 		buffer:           buffer,
 		fourierTransform: ft,
 	}
 
+	t.updateNotes()
 	return &t
 }
@@ -129,3 +129,670 @@ func TestTuner(t *testing.T) {
 	}
 
 }
+
+/*
+ * Check that streaming samples into the tuner does not allocate memory.
+ */
+func TestTunerAllocations(t *testing.T) {
+	tn := Create()
+
+	/*
+	 * Number of samples per buffer.
+	 */
+	bufferSizes := []int{
+		64,
+		256,
+		8192,
+	}
+
+	/*
+	 * Sample rates at which the buffers are fed.
+	 */
+	sampleRates := []uint32{
+		44100,
+		48000,
+		96000,
+	}
+
+	/*
+	 * Feed buffers of each size.
+	 */
+	for i, bufferSize := range bufferSizes {
+		sampleRate := sampleRates[i]
+		samples := make([]float64, bufferSize)
+
+		/*
+		 * Generate a sine wave.
+		 */
+		for j := range samples {
+			jFloat := float64(j)
+			arg := 2.0 * math.Pi * jFloat / 64.0
+			samples[j] = math.Sin(arg)
+		}
+
+		/*
+		 * Feed the samples to the tuner once.
+		 */
+		process := func() {
+			tn.Process(samples, sampleRate)
+		}
+
+		process()
+		allocs := testing.AllocsPerRun(100, process)
+
+		/*
+		 * Check if processing allocated memory.
+		 */
+		if allocs != 0 {
+			t.Errorf("Feeding %d samples at %d Hz allocated memory. Expected %d allocations, got %f.", bufferSize, sampleRate, 0, allocs)
+		}
+
+	}
+
+}
+
+/*
+ * Check that the generated notes match the equal temperament with A4 at 440 Hz.
+ */
+func TestGenerateNotes(t *testing.T) {
+	temps := temperaments()
+	idx := temperamentIndex(temps, TEMPERAMENT_DEFAULT)
+	nots := notations()
+	notIdx := notationIndex(nots, NOTATION_DEFAULT)
+	notes := generateNotes(REFERENCE_DEFAULT, temps[idx], nots[notIdx])
+	numNotes := len(notes)
+
+	/*
+	 * Check if the correct number of notes was generated.
+	 */
+	if numNotes != NOTE_COUNT {
+		t.Errorf("Number of notes is incorrect. Expected %d, got %d.", NOTE_COUNT, numNotes)
+	} else {
+
+		/*
+		 * Expected frequencies of some of the notes.
+		 */
+		expected := map[string]float64{
+			"H1":  61.7354,
+			"E2":  82.4069,
+			"A#2": 116.5409,
+			"A4":  440.0000,
+			"H6":  1975.5332,
+		}
+
+		/*
+		 * Compare each note against the expected frequencies.
+		 */
+		for _, note := range notes {
+			name := note.name
+			frequency, ok := expected[name]
+
+			/*
+			 * Check if the frequency of this note is known.
+			 */
+			if ok {
+				diff := math.Abs(note.frequency - frequency)
+
+				/*
+				 * Check if the frequency is correct.
+				 */
+				if diff > 1e-4 {
+					t.Errorf("Frequency of note '%s' is incorrect. Expected %f, got %f.", name, frequency, note.frequency)
+				}
+
+				delete(expected, name)
+			}
+
+		}
+
+		/*
+		 * Check if all notes were generated.
+		 */
+		for name := range expected {
+			t.Errorf("Note '%s' was not generated.", name)
+		}
+
+	}
+
+}
+
+/*
+ * Check that the reference pitch is kept in every temperament.
+ */
+func TestTemperaments(t *testing.T) {
+	temps := temperaments()
+	nots := notations()
+	notIdx := notationIndex(nots, NOTATION_DEFAULT)
+
+	/*
+	 * Generate the notes in each temperament.
+	 */
+	for _, temp := range temps {
+		notes := generateNotes(432.0, temp, nots[notIdx])
+
+		/*
+		 * Look for the reference pitch.
+		 */
+		for _, note := range notes {
+
+			/*
+			 * Check if the reference pitch has the correct frequency.
+			 */
+			if (note.name == "A4") && (math.Abs(note.frequency-432.0) > 1e-9) {
+				t.Errorf("Reference pitch is incorrect in temperament '%s'. Expected %f, got %f.", temp.name, 432.0, note.frequency)
+			}
+
+		}
+
+	}
+
+	tn := Create()
+	err := tn.SetTemperament("werckmeister")
+
+	/*
+	 * Check if the temperament was selected.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Selecting temperament failed: %s", msg)
+	} else {
+		name := tn.Temperament()
+
+		/*
+		 * Check if the temperament was stored.
+		 */
+		if name != "werckmeister" {
+			t.Errorf("Temperament incorrect. Expected '%s', got '%s'.", "werckmeister", name)
+		}
+
+	}
+
+	err = tn.SetTemperament("kirnberger")
+
+	/*
+	 * Check if the temperament was rejected.
+	 */
+	if err == nil {
+		t.Errorf("Selecting temperament '%s' should fail.", "kirnberger")
+	}
+
+	err = tn.SetReference(REFERENCE_MAXIMUM + 1.0)
+
+	/*
+	 * Check if the reference pitch was rejected.
+	 */
+	if err == nil {
+		t.Errorf("Setting reference pitch to %f should fail.", REFERENCE_MAXIMUM+1.0)
+	}
+
+	err = tn.SetReference(432.0)
+
+	/*
+	 * Check if the reference pitch was set.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Setting reference pitch failed: %s", msg)
+	} else {
+		reference := tn.Reference()
+
+		/*
+		 * Check if the reference pitch was stored.
+		 */
+		if reference != 432.0 {
+			t.Errorf("Reference pitch incorrect. Expected %f, got %f.", 432.0, reference)
+		}
+
+	}
+
+}
+
+/*
+ * Check that the tuner only matches the strings of the selected tuning.
+ */
+func TestTunings(t *testing.T) {
+	temps := temperaments()
+	tempIdx := temperamentIndex(temps, TEMPERAMENT_DEFAULT)
+	nots := notations()
+	notIdx := notationIndex(nots, "international")
+	notes := generateNotes(REFERENCE_DEFAULT, temps[tempIdx], nots[notIdx])
+	tuns := tunings()
+
+	/*
+	 * Select the notes of each tuning.
+	 */
+	for _, tun := range tuns {
+		selected := selectNotes(notes, tun)
+		numSelected := len(selected)
+		numExpected := len(tun.notes)
+
+		/*
+		 * The chromatic tuning matches all notes.
+		 */
+		if tun.notes == nil {
+			numExpected = NOTE_COUNT
+		}
+
+		/*
+		 * Check if all strings of the tuning were found.
+		 */
+		if numSelected != numExpected {
+			t.Errorf("Tuning '%s' selects %d notes, expected %d.", tun.name, numSelected, numExpected)
+		}
+
+	}
+
+	tn := Create()
+	err := tn.SetTuning("drop_d")
+
+	/*
+	 * Check if the tuning was selected.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Selecting tuning failed: %s", msg)
+	} else {
+		name := tn.Tuning()
+
+		/*
+		 * Check if the tuning was stored.
+		 */
+		if name != "drop_d" {
+			t.Errorf("Tuning incorrect. Expected '%s', got '%s'.", "drop_d", name)
+		}
+
+	}
+
+	err = tn.SetTuning("open_x")
+
+	/*
+	 * Check if the tuning was rejected.
+	 */
+	if err == nil {
+		t.Errorf("Selecting tuning '%s' should fail.", "open_x")
+	}
+
+}
+
+/*
+ * Check that the notes are named according to the selected notation.
+ */
+func TestNotations(t *testing.T) {
+	temps := temperaments()
+	tempIdx := temperamentIndex(temps, TEMPERAMENT_DEFAULT)
+	nots := notations()
+
+	/*
+	 * The name of the note a semitone below C4 in each notation.
+	 */
+	expected := map[string]string{
+		"german":        "H3",
+		"international": "B3",
+	}
+
+	/*
+	 * Generate the notes in each notation.
+	 */
+	for _, not := range nots {
+		notes := generateNotes(REFERENCE_DEFAULT, temps[tempIdx], not)
+		label := ""
+
+		/*
+		 * Find the note a semitone below C4.
+		 */
+		for _, note := range notes {
+
+			/*
+			 * Notes are always matched by their German name.
+			 */
+			if note.name == "H3" {
+				label = note.label
+			}
+
+		}
+
+		/*
+		 * Check the name displayed for the note.
+		 */
+		if label != expected[not.name] {
+			t.Errorf("Note is named '%s' in notation '%s', expected '%s'.", label, not.name, expected[not.name])
+		}
+
+	}
+
+	tn := Create()
+	notation := tn.Notation()
+
+	/*
+	 * Check the default notation.
+	 */
+	if notation != NOTATION_DEFAULT {
+		t.Errorf("Notation incorrect. Expected '%s', got '%s'.", NOTATION_DEFAULT, notation)
+	}
+
+	err := tn.SetNotation("solfege")
+
+	/*
+	 * Check if the notation was rejected.
+	 */
+	if err == nil {
+		t.Errorf("Selecting notation '%s' should fail.", "solfege")
+	}
+
+}
+
+/*
+ * Check that the tuner determines the deviation of each string when all
+ * strings are struck at once.
+ */
+func TestAnalyzeStrings(t *testing.T) {
+	tn := Create()
+	sampleRate := uint32(48000)
+	sampleRateFloat := float64(sampleRate)
+	samples := make([]float64, NUM_SAMPLES)
+
+	/*
+	 * Strings of the instrument in standard tuning.
+	 */
+	notes := []string{
+		"E2",
+		"A2",
+		"D3",
+		"G3",
+		"H3",
+		"E4",
+	}
+
+	/*
+	 * Frequencies of the strings in equal temperament.
+	 */
+	frequencies := []float64{
+		82.4069,
+		110.0000,
+		146.8324,
+		195.9977,
+		246.9417,
+		329.6276,
+	}
+
+	/*
+	 * Deviation of each string in cents.
+	 */
+	deviations := []float64{
+		-20.0,
+		-8.0,
+		0.0,
+		5.0,
+		12.0,
+		30.0,
+	}
+
+	/*
+	 * Add the fundamental and some overtones of each string to the signal.
+	 */
+	for i, frequency := range frequencies {
+		exponent := deviations[i] / 1200.0
+		actualFrequency := math.Pow(2.0, exponent) * frequency
+
+		/*
+		 * Generate the samples.
+		 */
+		for j := range samples {
+			jFloat := float64(j)
+			arg := 2.0 * math.Pi * actualFrequency * (jFloat / sampleRateFloat)
+			samples[j] += 0.1*math.Sin(arg) + 0.03*math.Sin(2.0*arg) + 0.01*math.Sin(3.0*arg)
+		}
+
+	}
+
+	tn.Process(samples, sampleRate)
+	results, err := tn.AnalyzeStrings()
+
+	/*
+	 * Check if analysis could be performed.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Failed to analyze strings: %s", msg)
+	} else {
+		numResults := len(results)
+		numNotes := len(notes)
+
+		/*
+		 * Check if all strings were detected.
+		 */
+		if numResults != numNotes {
+			t.Errorf("Number of detected strings is incorrect. Expected %d, got %d.", numNotes, numResults)
+		} else {
+
+			/*
+			 * Check the result for each string.
+			 */
+			for i, res := range results {
+				note := res.Note()
+				expectedNote := notes[i]
+
+				/*
+				 * Check if the string was identified correctly.
+				 */
+				if note != expectedNote {
+					t.Errorf("String %d identified incorrectly. Expected '%s', got '%s'.", i, expectedNote, note)
+				}
+
+				cents := float64(res.Cents())
+				diff := math.Abs(cents - deviations[i])
+
+				/*
+				 * Check if the deviation was determined correctly.
+				 */
+				if diff > 1.0 {
+					t.Errorf("Deviation of string '%s' is incorrect. Expected %f, got %f.", expectedNote, deviations[i], cents)
+				}
+
+			}
+
+		}
+
+	}
+
+	tn.Process(make([]float64, NUM_SAMPLES), sampleRate)
+	results, err = tn.AnalyzeStrings()
+
+	/*
+	 * Check if analysis could be performed.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Failed to analyze strings: %s", msg)
+	} else {
+		numResults := len(results)
+
+		/*
+		 * Check that no strings are detected in silence.
+		 */
+		if numResults != 0 {
+			t.Errorf("Detected %d strings in silence, expected %d.", numResults, 0)
+		}
+
+	}
+
+}
+
+/*
+ * Generates a sine wave, continuing from a certain position in the stream.
+ */
+func sineWave(frequency float64, sampleRate uint32, offset int, length int) []float64 {
+	sampleRateFloat := float64(sampleRate)
+	samples := make([]float64, length)
+
+	/*
+	 * Generate the samples.
+	 */
+	for i := range samples {
+		position := float64(offset + i)
+		arg := 2.0 * math.Pi * frequency * (position / sampleRateFloat)
+		samples[i] = 0.5 * math.Sin(arg)
+	}
+
+	return samples
+}
+
+/*
+ * Check that the high-resolution analysis determines fractional deviations.
+ */
+func TestAnalyzeStrobe(t *testing.T) {
+	sampleRate := uint32(48000)
+
+	/*
+	 * Deviations from A2 in cents.
+	 */
+	deviations := []float64{
+		-12.3,
+		-0.4,
+		0.0,
+		3.7,
+		25.5,
+	}
+
+	/*
+	 * Analyze a sine wave for each deviation.
+	 */
+	for _, deviation := range deviations {
+		tn := Create()
+		exponent := deviation / 1200.0
+		frequency := math.Pow(2.0, exponent) * 110.0
+		samples := sineWave(frequency, sampleRate, 0, STROBE_SAMPLES)
+		tn.Process(samples, sampleRate)
+		res, err := tn.AnalyzeStrobe()
+
+		/*
+		 * Check if analysis could be performed.
+		 */
+		if err != nil {
+			msg := err.Error()
+			t.Errorf("Failed to perform high-resolution analysis: %s", msg)
+		} else {
+			note := res.Note()
+
+			/*
+			 * Check if note was determined correctly.
+			 */
+			if note != "A2" {
+				t.Errorf("Tuner failed to determine correct note. Expected '%s', got '%s'.", "A2", note)
+			}
+
+			cents := res.Cents()
+			diff := math.Abs(cents - deviation)
+
+			/*
+			 * Check if the deviation was determined accurately.
+			 */
+			if diff > 0.05 {
+				t.Errorf("Deviation incorrect. Expected %f, got %f.", deviation, cents)
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Check that the phase of the strobe display advances with the difference
+ * between the frequency of the signal and the frequency of the note.
+ */
+func TestStrobePhase(t *testing.T) {
+	sampleRate := uint32(48000)
+	step := 12000
+
+	/*
+	 * Frequency offsets from A4 in Hz and the expected change in phase per
+	 * step.
+	 */
+	offsets := []float64{
+		0.0,
+		1.0,
+		-0.5,
+	}
+
+	/*
+	 * Expected change in phase per step.
+	 */
+	advances := []float64{
+		0.0,
+		0.25,
+		-0.125,
+	}
+
+	/*
+	 * Check the phase for each frequency.
+	 */
+	for i, offset := range offsets {
+		tn := Create()
+		frequency := 440.0 + offset
+		samples := sineWave(frequency, sampleRate, 0, STROBE_SAMPLES)
+		tn.Process(samples, sampleRate)
+		first, errFirst := tn.AnalyzeStrobe()
+		samples = sineWave(frequency, sampleRate, STROBE_SAMPLES, step)
+		tn.Process(samples, sampleRate)
+		second, errSecond := tn.AnalyzeStrobe()
+
+		/*
+		 * Check if analysis could be performed.
+		 */
+		if (errFirst != nil) || (errSecond != nil) {
+			t.Errorf("Failed to perform high-resolution analysis at %f Hz.", frequency)
+		} else {
+			advance := second.Phase() - first.Phase()
+			diff := math.Abs(advance - advances[i])
+			diff = math.Min(diff, 1.0-diff)
+
+			/*
+			 * Check if the phase advanced as expected.
+			 */
+			if diff > 0.01 {
+				t.Errorf("Phase at %f Hz advanced by %f, expected %f.", frequency, advance, advances[i])
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Check that the tuner can be queried before any samples were processed.
+ */
+func TestAnalyzeWithoutSamples(t *testing.T) {
+	tn := Create()
+	_, err := tn.AnalyzeStrings()
+
+	/*
+	 * Check if analysis could be performed.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Failed to analyze strings: %s", msg)
+	}
+
+	res, err := tn.AnalyzeStrobe()
+
+	/*
+	 * Check if analysis could be performed.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Failed to perform high-resolution analysis: %s", msg)
+	} else {
+		phase := res.Phase()
+
+		/*
+		 * Check if the phase is in range.
+		 */
+		if !((phase >= 0.0) && (phase < 1.0)) {
+			t.Errorf("Phase out of range: %f", phase)
+		}
+
+	}
+
+}
@@ -0,0 +1,519 @@
+package wave
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+/*
+ * AIFF header constants.
+ */
+const (
+	AIFC_VERSION          = 0xa2805140 // uint32
+	COMPRESSION_FL32      = 0x666c3332 // uint32
+	COMPRESSION_FL32_UP   = 0x464c3332 // uint32
+	COMPRESSION_FL64      = 0x666c3634 // uint32
+	COMPRESSION_FL64_UP   = 0x464c3634 // uint32
+	COMPRESSION_NONE      = 0x4e4f4e45 // uint32
+	COMPRESSION_SOWT      = 0x736f7774 // uint32
+	EXTENDED_BIAS         = 0x3fff     // int
+	EXTENDED_MANTISSA     = 0x40       // int
+	FORMAT_AIFC           = 0x41494643 // uint32
+	FORMAT_AIFF           = 0x41494646 // uint32
+	ID_COMMON             = 0x434f4d4d // uint32
+	ID_FORM               = 0x464f524d // uint32
+	ID_SOUND              = 0x53534e44 // uint32
+	ID_VERSION            = 0x46564552 // uint32
+	MAX_DATA_SIZE_AIFF    = 0xffffff00 // uint32
+	MIN_CHUNK_SIZE_COMMON = 0x00000012 // uint32
+	SIZE_EXTENDED         = 10
+	SIZE_SOUND_HEADER     = 8
+	SIZE_VERSION          = 4
+)
+
+/*
+ * The structure of an AIFF file's form header.
+ */
+type formHeader struct {
+	ChunkID   uint32
+	ChunkSize uint32
+	FormType  uint32
+}
+
+/*
+ * The structure of an AIFF file's common header.
+ */
+type commonHeader struct {
+	ChunkID      uint32
+	ChunkSize    uint32
+	ChannelCount uint16
+	FrameCount   uint32
+	BitDepth     uint16
+	SampleRate   [SIZE_EXTENDED]byte
+}
+
+/*
+ * The structure of an AIFF-C file's format version header.
+ */
+type versionHeader struct {
+	ChunkID   uint32
+	ChunkSize uint32
+	Timestamp uint32
+}
+
+/*
+ * The structure of an AIFF file's sound data header.
+ */
+type soundHeader struct {
+	ChunkID   uint32
+	ChunkSize uint32
+	Offset    uint32
+	BlockSize uint32
+}
+
+/*
+ * Converts a number into the 80-bit extended precision format AIFF files
+ * store their sample rate in.
+ */
+func float64ToExtended(value float64) [SIZE_EXTENDED]byte {
+	result := [SIZE_EXTENDED]byte{}
+
+	/*
+	 * Zero is represented by all bits cleared.
+	 */
+	if value != 0.0 {
+		sign := uint16(0)
+
+		/*
+		 * Store the sign separately.
+		 */
+		if value < 0.0 {
+			sign = 0x8000
+			value = -value
+		}
+
+		fraction, exponent := math.Frexp(value)
+		mantissa := math.Ldexp(fraction, EXTENDED_MANTISSA)
+		mantissa64 := uint64(mantissa)
+		biased := uint16(exponent - 1 + EXTENDED_BIAS)
+		binary.BigEndian.PutUint16(result[0:2], sign|biased)
+		binary.BigEndian.PutUint64(result[2:SIZE_EXTENDED], mantissa64)
+	}
+
+	return result
+}
+
+/*
+ * Converts a number from the 80-bit extended precision format AIFF files
+ * store their sample rate in.
+ */
+func extendedToFloat64(value [SIZE_EXTENDED]byte) float64 {
+	signExponent := binary.BigEndian.Uint16(value[0:2])
+	mantissa := binary.BigEndian.Uint64(value[2:SIZE_EXTENDED])
+	exponent := int(signExponent & 0x7fff)
+	mantissaFloat := float64(mantissa)
+	result := math.Ldexp(mantissaFloat, exponent-EXTENDED_BIAS-(EXTENDED_MANTISSA-1))
+
+	/*
+	 * Apply the sign.
+	 */
+	if (signExponent & 0x8000) != 0 {
+		result = -result
+	}
+
+	return result
+}
+
+/*
+ * Returns a four character code as a string.
+ */
+func fourCC(id uint32) string {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, id)
+	s := string(buf)
+	return s
+}
+
+/*
+ * Encodes the headers of an AIFF file holding a certain number of samples.
+ *
+ * Samples in LPCM format are stored in a plain AIFF file, samples in IEEE
+ * floating-point format in an AIFF-C file. The size of the headers does not
+ * depend on the number of samples.
+ */
+func createHeaderAIFF(sampleRate uint32, sampleFormat uint16, bitDepth uint16, channelCount uint16, numSamples uint64) []byte {
+	sampleSize := uint64(bitDepth / BITS_PER_BYTE)
+	dataBytes := sampleSize * numSamples
+	padding := dataBytes % 2
+	numFrames := uint64(0)
+
+	/*
+	 * Avoid division by zero.
+	 */
+	if channelCount > 0 {
+		channelCount64 := uint64(channelCount)
+		numFrames = numSamples / channelCount64
+	}
+
+	isFloat := sampleFormat == AUDIO_IEEE_FLOAT
+	formType := uint32(FORMAT_AIFF)
+	compressionType := uint32(COMPRESSION_NONE)
+	compressionName := []byte{}
+	commonSize := uint32(MIN_CHUNK_SIZE_COMMON)
+
+	/*
+	 * Floating-point samples require an AIFF-C file, which names the
+	 * compression type as a Pascal string of even size.
+	 */
+	if isFloat {
+		name := fmt.Sprintf("%d-bit floating point", bitDepth)
+		nameLength := len(name)
+		formType = FORMAT_AIFC
+		compressionType = COMPRESSION_FL32
+
+		/*
+		 * Select compression type for 64-bit samples.
+		 */
+		if bitDepth == 64 {
+			compressionType = COMPRESSION_FL64
+		}
+
+		compressionName = append(compressionName, byte(nameLength))
+		compressionName = append(compressionName, name...)
+
+		/*
+		 * Pad the name to an even size.
+		 */
+		if (len(compressionName) % 2) != 0 {
+			compressionName = append(compressionName, 0)
+		}
+
+		compressionNameSize := len(compressionName)
+		commonSize += 4 + uint32(compressionNameSize)
+	}
+
+	commonSize64 := uint64(commonSize)
+	formSize := 4 + MIN_CHUNK_HEADER_SIZE + commonSize64 + MIN_CHUNK_HEADER_SIZE + SIZE_SOUND_HEADER + dataBytes + padding
+
+	/*
+	 * The version chunk is part of the form chunk of AIFF-C files.
+	 */
+	if isFloat {
+		formSize += MIN_CHUNK_HEADER_SIZE + SIZE_VERSION
+	}
+
+	soundSize := SIZE_SOUND_HEADER + dataBytes
+
+	/*
+	 * Sizes are limited to 32 bits.
+	 */
+	if formSize > math.MaxUint32 {
+		formSize = math.MaxUint32
+	}
+
+	/*
+	 * Sizes are limited to 32 bits.
+	 */
+	if soundSize > math.MaxUint32 {
+		soundSize = math.MaxUint32
+	}
+
+	sampleRateFloat := float64(sampleRate)
+
+	/*
+	 * Create form header.
+	 */
+	hdrForm := formHeader{
+		ChunkID:   ID_FORM,
+		ChunkSize: uint32(formSize),
+		FormType:  formType,
+	}
+
+	/*
+	 * Create version header.
+	 */
+	hdrVersion := versionHeader{
+		ChunkID:   ID_VERSION,
+		ChunkSize: SIZE_VERSION,
+		Timestamp: AIFC_VERSION,
+	}
+
+	/*
+	 * Create common header.
+	 */
+	hdrCommon := commonHeader{
+		ChunkID:      ID_COMMON,
+		ChunkSize:    commonSize,
+		ChannelCount: channelCount,
+		FrameCount:   uint32(numFrames),
+		BitDepth:     bitDepth,
+		SampleRate:   float64ToExtended(sampleRateFloat),
+	}
+
+	/*
+	 * Create sound data header.
+	 */
+	hdrSound := soundHeader{
+		ChunkID:   ID_SOUND,
+		ChunkSize: uint32(soundSize),
+		Offset:    0,
+		BlockSize: 0,
+	}
+
+	buf := createBuffer()
+	binary.Write(buf, binary.BigEndian, hdrForm)
+
+	/*
+	 * AIFF-C files carry a version and the compression type.
+	 */
+	if isFloat {
+		binary.Write(buf, binary.BigEndian, hdrVersion)
+		binary.Write(buf, binary.BigEndian, hdrCommon)
+		binary.Write(buf, binary.BigEndian, compressionType)
+		buf.Write(compressionName)
+	} else {
+		binary.Write(buf, binary.BigEndian, hdrCommon)
+	}
+
+	binary.Write(buf, binary.BigEndian, hdrSound)
+	content := buf.Bytes()
+	return content
+}
+
+/*
+ * Reads and validates the headers of an AIFF or AIFF-C file, leaving the
+ * reader at the beginning of the sample data.
+ */
+func readHeadersAIFF(reader io.ReadSeeker, totalSize uint64) (*audioHeader, error) {
+	hdrForm := formHeader{}
+	err := binary.Read(reader, binary.BigEndian, &hdrForm)
+
+	/*
+	 * Check if form header was read.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, fmt.Errorf("Failed to read form header: %s", msg)
+	} else if hdrForm.ChunkID != ID_FORM {
+		return nil, fmt.Errorf("Form header contains invalid chunk id. Expected %#08x, found %#08x.", ID_FORM, hdrForm.ChunkID)
+	} else if hdrForm.FormType != FORMAT_AIFF && hdrForm.FormType != FORMAT_AIFC {
+		return nil, fmt.Errorf("Form header contains invalid form type. Expected %#08x or %#08x, found %#08x.", FORMAT_AIFF, FORMAT_AIFC, hdrForm.FormType)
+	} else {
+		formSize := uint64(hdrForm.ChunkSize)
+		end := MIN_CHUNK_HEADER_SIZE + formSize
+
+		/*
+		 * Do not read beyond the end of a truncated file.
+		 */
+		if end > totalSize {
+			end = totalSize
+		}
+
+		offset := uint64(MIN_CHUNK_HEADER_SIZE + 4)
+		hdrCommon := commonHeader{}
+		compressionType := uint32(COMPRESSION_NONE)
+		foundCommon := false
+		foundSound := false
+		dataOffset := uint64(0)
+		dataSize := uint64(0)
+
+		/*
+		 * Iterate over all chunks inside the form chunk.
+		 */
+		for (offset + MIN_CHUNK_HEADER_SIZE) <= end {
+			offset64 := int64(offset)
+			_, err := reader.Seek(offset64, io.SeekStart)
+
+			/*
+			 * Check if we seeked to the chunk.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to seek to chunk: %s", msg)
+			}
+
+			hdrChunk := chunkHeader{}
+			err = binary.Read(reader, binary.BigEndian, &hdrChunk)
+
+			/*
+			 * Check if chunk header was read.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to read chunk header: %s", msg)
+			}
+
+			chunkSize := uint64(hdrChunk.ChunkSize)
+
+			/*
+			 * Decide on the chunk.
+			 */
+			switch hdrChunk.ChunkID {
+			case ID_COMMON:
+				reader.Seek(offset64, io.SeekStart)
+				err = binary.Read(reader, binary.BigEndian, &hdrCommon)
+
+				/*
+				 * Check if common header was read.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return nil, fmt.Errorf("Failed to read common header: %s", msg)
+				} else if hdrCommon.ChunkSize < MIN_CHUNK_SIZE_COMMON {
+					return nil, fmt.Errorf("Common header contains invalid chunk size. Expected at least %#08x, found %#08x.", MIN_CHUNK_SIZE_COMMON, hdrCommon.ChunkSize)
+				}
+
+				/*
+				 * AIFF-C files carry the compression type.
+				 */
+				if hdrForm.FormType == FORMAT_AIFC {
+					err = binary.Read(reader, binary.BigEndian, &compressionType)
+
+					/*
+					 * Check if compression type was read.
+					 */
+					if err != nil {
+						msg := err.Error()
+						return nil, fmt.Errorf("Failed to read compression type: %s", msg)
+					}
+
+				}
+
+				foundCommon = true
+			case ID_SOUND:
+				hdrSound := soundHeader{}
+				reader.Seek(offset64, io.SeekStart)
+				err = binary.Read(reader, binary.BigEndian, &hdrSound)
+
+				/*
+				 * Check if sound data header was read.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return nil, fmt.Errorf("Failed to read sound data header: %s", msg)
+				} else {
+					soundOffset := uint64(hdrSound.Offset)
+					skip := SIZE_SOUND_HEADER + soundOffset
+					dataOffset = offset + MIN_CHUNK_HEADER_SIZE + skip
+
+					/*
+					 * The sample data may be preceded by an offset.
+					 */
+					if chunkSize > skip {
+						dataSize = chunkSize - skip
+					}
+
+					foundSound = true
+				}
+
+			}
+
+			/*
+			 * If chunk size is not even, we have to skip one
+			 * additional byte of padding.
+			 */
+			if (chunkSize % 2) != 0 {
+				chunkSize += 1
+			}
+
+			offset += MIN_CHUNK_HEADER_SIZE + chunkSize
+		}
+
+		/*
+		 * Make sure we found both the format and the sample data.
+		 */
+		if !foundCommon {
+			return nil, fmt.Errorf("%s", "Failed to locate common chunk.")
+		} else if !foundSound {
+			return nil, fmt.Errorf("%s", "Failed to locate sound data chunk.")
+		} else {
+			sampleFormat := uint16(AUDIO_PCM)
+			bitDepth := hdrCommon.BitDepth
+			bigEndian := true
+
+			/*
+			 * Decide on the compression type.
+			 */
+			switch compressionType {
+			case COMPRESSION_NONE:
+
+				/*
+				 * Samples are stored in whole bytes.
+				 */
+				bitDepth = ((bitDepth + (BITS_PER_BYTE - 1)) / BITS_PER_BYTE) * BITS_PER_BYTE
+
+			case COMPRESSION_SOWT:
+				bitDepth = ((bitDepth + (BITS_PER_BYTE - 1)) / BITS_PER_BYTE) * BITS_PER_BYTE
+				bigEndian = false
+			case COMPRESSION_FL32, COMPRESSION_FL32_UP:
+				sampleFormat = AUDIO_IEEE_FLOAT
+				bitDepth = 32
+			case COMPRESSION_FL64, COMPRESSION_FL64_UP:
+				sampleFormat = AUDIO_IEEE_FLOAT
+				bitDepth = 64
+			default:
+				name := fourCC(compressionType)
+				return nil, fmt.Errorf("Unsupported compression type: '%s'", name)
+			}
+
+			channelCount := hdrCommon.ChannelCount
+			sampleRateFloat := extendedToFloat64(hdrCommon.SampleRate)
+			sampleRateFloat = math.Round(sampleRateFloat)
+
+			/*
+			 * Check format for validity.
+			 */
+			if sampleFormat == AUDIO_PCM && bitDepth != 8 && bitDepth != 16 && bitDepth != 24 && bitDepth != 32 {
+				return nil, fmt.Errorf("Common header contains invalid bit depth for PCM format. Expected %d to %d, found %d.", 1, 32, hdrCommon.BitDepth)
+			} else if sampleRateFloat < 1.0 || sampleRateFloat > math.MaxUint32 {
+				return nil, fmt.Errorf("Common header contains invalid sample rate: %f", sampleRateFloat)
+			} else {
+				sampleSize := uint64(bitDepth / BITS_PER_BYTE)
+				channelCount64 := uint64(channelCount)
+				numFrames := uint64(hdrCommon.FrameCount)
+				frameBytes := numFrames * channelCount64 * sampleSize
+
+				/*
+				 * Do not read beyond the frames announced.
+				 */
+				if frameBytes < dataSize {
+					dataSize = frameBytes
+				}
+
+				dataOffset64 := int64(dataOffset)
+				_, err = reader.Seek(dataOffset64, io.SeekStart)
+
+				/*
+				 * Check if we seeked to the sample data.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return nil, fmt.Errorf("Failed to locate sample data: %s", msg)
+				} else {
+
+					/*
+					 * Create audio header structure.
+					 */
+					hdr := audioHeader{
+						container:    CONTAINER_AIFF,
+						sampleFormat: sampleFormat,
+						bitDepth:     bitDepth,
+						channelCount: channelCount,
+						sampleRate:   uint32(sampleRateFloat),
+						dataSize:     dataSize,
+						bigEndian:    bigEndian,
+						signedBytes:  true,
+					}
+
+					return &hdr, nil
+				}
+
+			}
+
+		}
+
+	}
+
+}
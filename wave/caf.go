@@ -0,0 +1,323 @@
+package wave
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+/*
+ * CAF header constants.
+ */
+const (
+	CAF_FLAG_FLOAT         = 0x00000001 // uint32
+	CAF_FLAG_LITTLE_ENDIAN = 0x00000002 // uint32
+	CAF_VERSION            = 0x0001     // uint16
+	FORMAT_LPCM            = 0x6c70636d // uint32
+	ID_CAFF                = 0x63616666 // uint32
+	ID_DATA_CAF            = 0x64617461 // uint32
+	ID_DESCRIPTION         = 0x64657363 // uint32
+	SIZE_CAF_CHUNK_HEADER  = 12
+	SIZE_CAF_FILE_HEADER   = 8
+	SIZE_DESCRIPTION       = 32
+	SIZE_EDIT_COUNT        = 4
+	SIZE_UNKNOWN           = -1 // int64
+)
+
+/*
+ * The structure of a CAF file's file header.
+ */
+type cafFileHeader struct {
+	FileType uint32
+	Version  uint16
+	Flags    uint16
+}
+
+/*
+ * The structure of a CAF file's chunk header.
+ */
+type cafChunkHeader struct {
+	ChunkType uint32
+	ChunkSize int64
+}
+
+/*
+ * The structure of a CAF file's audio description.
+ */
+type cafDescription struct {
+	SampleRate       float64
+	FormatID         uint32
+	FormatFlags      uint32
+	BytesPerPacket   uint32
+	FramesPerPacket  uint32
+	ChannelsPerFrame uint32
+	BitsPerChannel   uint32
+}
+
+/*
+ * Encodes the headers of a CAF file holding a certain number of samples.
+ *
+ * Samples are stored in little-endian byte order. If space for the data size
+ * is reserved and no samples were written yet, the size of the data chunk is
+ * left unknown, so that the sample data extends to the end of the file, even
+ * if the headers are never updated.
+ */
+func createHeaderCAF(sampleRate uint32, sampleFormat uint16, bitDepth uint16, channelCount uint16, numSamples uint64, reserveDataSize bool) []byte {
+	sampleSize := uint64(bitDepth / BITS_PER_BYTE)
+	dataBytes := sampleSize * numSamples
+	dataSize := int64(dataBytes) + SIZE_EDIT_COUNT
+
+	/*
+	 * Leave the size of the data chunk unknown while writing.
+	 */
+	if reserveDataSize && numSamples == 0 {
+		dataSize = SIZE_UNKNOWN
+	}
+
+	formatFlags := uint32(CAF_FLAG_LITTLE_ENDIAN)
+
+	/*
+	 * Mark floating-point samples.
+	 */
+	if sampleFormat == AUDIO_IEEE_FLOAT {
+		formatFlags |= CAF_FLAG_FLOAT
+	}
+
+	channelCount32 := uint32(channelCount)
+	sampleSize32 := uint32(sampleSize)
+
+	/*
+	 * Create file header.
+	 */
+	hdrFile := cafFileHeader{
+		FileType: ID_CAFF,
+		Version:  CAF_VERSION,
+		Flags:    0,
+	}
+
+	/*
+	 * Create description header.
+	 */
+	hdrDescription := cafChunkHeader{
+		ChunkType: ID_DESCRIPTION,
+		ChunkSize: SIZE_DESCRIPTION,
+	}
+
+	/*
+	 * Create audio description.
+	 */
+	description := cafDescription{
+		SampleRate:       float64(sampleRate),
+		FormatID:         FORMAT_LPCM,
+		FormatFlags:      formatFlags,
+		BytesPerPacket:   sampleSize32 * channelCount32,
+		FramesPerPacket:  1,
+		ChannelsPerFrame: channelCount32,
+		BitsPerChannel:   uint32(bitDepth),
+	}
+
+	/*
+	 * Create data header.
+	 */
+	hdrData := cafChunkHeader{
+		ChunkType: ID_DATA_CAF,
+		ChunkSize: dataSize,
+	}
+
+	editCount := uint32(0)
+	buf := createBuffer()
+	binary.Write(buf, binary.BigEndian, hdrFile)
+	binary.Write(buf, binary.BigEndian, hdrDescription)
+	binary.Write(buf, binary.BigEndian, description)
+	binary.Write(buf, binary.BigEndian, hdrData)
+	binary.Write(buf, binary.BigEndian, editCount)
+	content := buf.Bytes()
+	return content
+}
+
+/*
+ * Reads and validates the headers of a CAF file, leaving the reader at the
+ * beginning of the sample data.
+ *
+ * Only uncompressed samples in LPCM or IEEE floating-point format are
+ * supported.
+ */
+func readHeadersCAF(reader io.ReadSeeker, totalSize uint64) (*audioHeader, error) {
+	hdrFile := cafFileHeader{}
+	err := binary.Read(reader, binary.BigEndian, &hdrFile)
+
+	/*
+	 * Check if file header was read.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, fmt.Errorf("Failed to read file header: %s", msg)
+	} else if hdrFile.FileType != ID_CAFF {
+		return nil, fmt.Errorf("File header contains invalid file type. Expected %#08x, found %#08x.", ID_CAFF, hdrFile.FileType)
+	} else if hdrFile.Version != CAF_VERSION {
+		return nil, fmt.Errorf("File header contains unsupported version. Expected %d, found %d.", CAF_VERSION, hdrFile.Version)
+	} else {
+		offset := uint64(SIZE_CAF_FILE_HEADER)
+		description := cafDescription{}
+		foundDescription := false
+		foundData := false
+		dataOffset := uint64(0)
+		dataSize := uint64(0)
+
+		/*
+		 * Iterate over all chunks until we find the sample data.
+		 */
+		for !foundData && (offset+SIZE_CAF_CHUNK_HEADER) <= totalSize {
+			offset64 := int64(offset)
+			_, err := reader.Seek(offset64, io.SeekStart)
+
+			/*
+			 * Check if we seeked to the chunk.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to seek to chunk: %s", msg)
+			}
+
+			hdrChunk := cafChunkHeader{}
+			err = binary.Read(reader, binary.BigEndian, &hdrChunk)
+
+			/*
+			 * Check if chunk header was read.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to read chunk header: %s", msg)
+			}
+
+			chunkSize := hdrChunk.ChunkSize
+			body := offset + SIZE_CAF_CHUNK_HEADER
+
+			/*
+			 * Only the data chunk may have an unknown size.
+			 */
+			if chunkSize < 0 && (hdrChunk.ChunkType != ID_DATA_CAF || chunkSize != SIZE_UNKNOWN) {
+				return nil, fmt.Errorf("Chunk '%s' has invalid size: %d", fourCC(hdrChunk.ChunkType), chunkSize)
+			}
+
+			/*
+			 * Decide on the chunk.
+			 */
+			switch hdrChunk.ChunkType {
+			case ID_DESCRIPTION:
+				err = binary.Read(reader, binary.BigEndian, &description)
+
+				/*
+				 * Check if audio description was read.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return nil, fmt.Errorf("Failed to read audio description: %s", msg)
+				}
+
+				foundDescription = true
+			case ID_DATA_CAF:
+				dataOffset = body + SIZE_EDIT_COUNT
+
+				/*
+				 * Sample data of unknown size extends to the end of the file.
+				 */
+				if chunkSize == SIZE_UNKNOWN {
+
+					/*
+					 * Check if the file is long enough.
+					 */
+					if totalSize > dataOffset {
+						dataSize = totalSize - dataOffset
+					}
+
+				} else if chunkSize > SIZE_EDIT_COUNT {
+					dataSize = uint64(chunkSize) - SIZE_EDIT_COUNT
+				}
+
+				foundData = true
+			}
+
+			offset = body + uint64(chunkSize)
+		}
+
+		/*
+		 * Make sure we found both the format and the sample data.
+		 */
+		if !foundDescription {
+			return nil, fmt.Errorf("%s", "Failed to locate audio description chunk.")
+		} else if !foundData {
+			return nil, fmt.Errorf("%s", "Failed to locate audio data chunk.")
+		} else {
+			formatId := description.FormatID
+			flags := description.FormatFlags
+			channelCount := description.ChannelsPerFrame
+			bitDepth := description.BitsPerChannel
+			expectedBytesPerPacket := (channelCount * bitDepth) / BITS_PER_BYTE
+			sampleRateFloat := math.Round(description.SampleRate)
+			isFloat := (flags & CAF_FLAG_FLOAT) != 0
+			sampleFormat := uint16(AUDIO_PCM)
+
+			/*
+			 * Check for floating-point samples.
+			 */
+			if isFloat {
+				sampleFormat = AUDIO_IEEE_FLOAT
+			}
+
+			/*
+			 * Check audio description for validity.
+			 */
+			if formatId != FORMAT_LPCM {
+				name := fourCC(formatId)
+				return nil, fmt.Errorf("Unsupported audio format: '%s'", name)
+			} else if description.FramesPerPacket != 1 {
+				return nil, fmt.Errorf("Audio description contains invalid frames per packet. Expected %d, found %d.", 1, description.FramesPerPacket)
+			} else if channelCount > math.MaxUint16 {
+				return nil, fmt.Errorf("Audio description contains too many channels: %d", channelCount)
+			} else if description.BytesPerPacket != expectedBytesPerPacket {
+				return nil, fmt.Errorf("Audio description contains invalid bytes per packet. Expected %d, found %d.", expectedBytesPerPacket, description.BytesPerPacket)
+			} else if !isFloat && bitDepth != 8 && bitDepth != 16 && bitDepth != 24 && bitDepth != 32 {
+				return nil, fmt.Errorf("Audio description contains invalid bit depth for PCM format. Expected %d or %d or %d or %d, found %d.", 8, 16, 24, 32, bitDepth)
+			} else if isFloat && bitDepth != 32 && bitDepth != 64 {
+				return nil, fmt.Errorf("Audio description contains invalid bit depth for IEEE floating-point format. Expected %d or %d, found %d.", 32, 64, bitDepth)
+			} else if sampleRateFloat < 1.0 || sampleRateFloat > math.MaxUint32 {
+				return nil, fmt.Errorf("Audio description contains invalid sample rate: %f", description.SampleRate)
+			} else {
+				dataOffset64 := int64(dataOffset)
+				_, err = reader.Seek(dataOffset64, io.SeekStart)
+
+				/*
+				 * Check if we seeked to the sample data.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return nil, fmt.Errorf("Failed to locate sample data: %s", msg)
+				} else {
+					littleEndian := (flags & CAF_FLAG_LITTLE_ENDIAN) != 0
+
+					/*
+					 * Create audio header structure.
+					 */
+					hdr := audioHeader{
+						container:    CONTAINER_CAF,
+						sampleFormat: sampleFormat,
+						bitDepth:     uint16(bitDepth),
+						channelCount: uint16(channelCount),
+						sampleRate:   uint32(sampleRateFloat),
+						dataSize:     dataSize,
+						bigEndian:    !littleEndian,
+						signedBytes:  true,
+					}
+
+					return &hdr, nil
+				}
+
+			}
+
+		}
+
+	}
+
+}
@@ -0,0 +1,497 @@
+package wave
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+/*
+ * Marker chunk constants.
+ */
+const (
+	DEFAULT_UNITY_NOTE   = 0x0000003c // uint32
+	ID_CUE               = 0x20657563 // uint32
+	ID_LABEL             = 0x6c62616c // uint32
+	ID_LIST              = 0x5453494c // uint32
+	ID_SAMPLER           = 0x6c706d73 // uint32
+	LOOP_FORWARD         = 0x00000000 // uint32
+	NANOSECONDS          = 1000000000
+	SIZE_CUE_POINT       = 24
+	SIZE_CUE_POINT_COUNT = 4
+	SIZE_LABEL_ID        = 4
+	SIZE_LIST_TYPE       = 4
+	SIZE_SAMPLER_HEADER  = 36
+	SIZE_SAMPLER_LOOP    = 24
+	TYPE_ADTL            = 0x6c746461 // uint32
+)
+
+/*
+ * A marker at a certain frame of a wave file, e. g. the beginning of a section
+ * of a song.
+ */
+type Marker struct {
+	Position uint32
+	Label    string
+}
+
+/*
+ * A loop inside a wave file, which spans from its start to its end frame,
+ * both inclusive.
+ */
+type Loop struct {
+	Start uint32
+	End   uint32
+}
+
+/*
+ * The structure of a cue point inside a wave file's cue chunk.
+ */
+type cuePoint struct {
+	ID           uint32
+	Position     uint32
+	ChunkID      uint32
+	ChunkStart   uint32
+	BlockStart   uint32
+	SampleOffset uint32
+}
+
+/*
+ * The structure of a wave file's sampler header.
+ */
+type samplerHeader struct {
+	Manufacturer    uint32
+	Product         uint32
+	SamplePeriod    uint32
+	UnityNote       uint32
+	PitchFraction   uint32
+	SMPTEFormat     uint32
+	SMPTEOffset     uint32
+	LoopCount       uint32
+	SamplerDataSize uint32
+}
+
+/*
+ * The structure of a loop inside a wave file's sampler chunk.
+ */
+type samplerLoop struct {
+	ID        uint32
+	Type      uint32
+	Start     uint32
+	End       uint32
+	Fraction  uint32
+	PlayCount uint32
+}
+
+/*
+ * Pads a chunk to an even size.
+ */
+func padChunk(buf *bytes.Buffer, size int) {
+
+	/*
+	 * If chunk size is not even, add one byte of padding.
+	 */
+	if (size % 2) != 0 {
+		buf.WriteByte(0)
+	}
+
+}
+
+/*
+ * Encodes the chunks describing markers and loops of a wave file.
+ *
+ * Markers are stored as cue points, their labels in an associated data list.
+ * Loops are stored in a sampler chunk. Returns an empty slice if there are
+ * neither markers nor loops.
+ */
+func createMarkerChunks(markers []Marker, loops []Loop, sampleRate uint32) []byte {
+	buf := createBuffer()
+	numMarkers := len(markers)
+	numLoops := len(loops)
+
+	/*
+	 * Write cue points and labels of the markers.
+	 */
+	if numMarkers > 0 {
+		cueSize := SIZE_CUE_POINT_COUNT + (SIZE_CUE_POINT * numMarkers)
+
+		/*
+		 * Create cue header.
+		 */
+		hdrCue := chunkHeader{
+			ChunkID:   ID_CUE,
+			ChunkSize: uint32(cueSize),
+		}
+
+		numMarkers32 := uint32(numMarkers)
+		binary.Write(buf, binary.LittleEndian, hdrCue)
+		binary.Write(buf, binary.LittleEndian, numMarkers32)
+		labels := createBuffer()
+
+		/*
+		 * Write a cue point and a label for each marker.
+		 */
+		for i, marker := range markers {
+			id := uint32(i + 1)
+
+			/*
+			 * Create cue point.
+			 */
+			point := cuePoint{
+				ID:           id,
+				Position:     marker.Position,
+				ChunkID:      ID_DATA,
+				ChunkStart:   0,
+				BlockStart:   0,
+				SampleOffset: marker.Position,
+			}
+
+			binary.Write(buf, binary.LittleEndian, point)
+			label := marker.Label
+
+			/*
+			 * Only markers with a label need a label chunk.
+			 */
+			if label != "" {
+				labelSize := SIZE_LABEL_ID + len(label) + 1
+
+				/*
+				 * Create label header.
+				 */
+				hdrLabel := chunkHeader{
+					ChunkID:   ID_LABEL,
+					ChunkSize: uint32(labelSize),
+				}
+
+				binary.Write(labels, binary.LittleEndian, hdrLabel)
+				binary.Write(labels, binary.LittleEndian, id)
+				labels.WriteString(label)
+				labels.WriteByte(0)
+				padChunk(labels, labelSize)
+			}
+
+		}
+
+		labelBytes := labels.Bytes()
+		numLabelBytes := len(labelBytes)
+
+		/*
+		 * Write associated data list if any marker has a label.
+		 */
+		if numLabelBytes > 0 {
+			listSize := SIZE_LIST_TYPE + numLabelBytes
+
+			/*
+			 * Create list header.
+			 */
+			hdrList := chunkHeader{
+				ChunkID:   ID_LIST,
+				ChunkSize: uint32(listSize),
+			}
+
+			listType := uint32(TYPE_ADTL)
+			binary.Write(buf, binary.LittleEndian, hdrList)
+			binary.Write(buf, binary.LittleEndian, listType)
+			buf.Write(labelBytes)
+		}
+
+	}
+
+	/*
+	 * Write sampler chunk holding the loops.
+	 */
+	if numLoops > 0 {
+		samplerSize := SIZE_SAMPLER_HEADER + (SIZE_SAMPLER_LOOP * numLoops)
+		samplePeriod := uint32(0)
+
+		/*
+		 * Avoid division by zero.
+		 */
+		if sampleRate > 0 {
+			samplePeriod = NANOSECONDS / sampleRate
+		}
+
+		/*
+		 * Create sampler chunk header.
+		 */
+		hdrChunk := chunkHeader{
+			ChunkID:   ID_SAMPLER,
+			ChunkSize: uint32(samplerSize),
+		}
+
+		/*
+		 * Create sampler header.
+		 */
+		hdrSampler := samplerHeader{
+			Manufacturer:    0,
+			Product:         0,
+			SamplePeriod:    samplePeriod,
+			UnityNote:       DEFAULT_UNITY_NOTE,
+			PitchFraction:   0,
+			SMPTEFormat:     0,
+			SMPTEOffset:     0,
+			LoopCount:       uint32(numLoops),
+			SamplerDataSize: 0,
+		}
+
+		binary.Write(buf, binary.LittleEndian, hdrChunk)
+		binary.Write(buf, binary.LittleEndian, hdrSampler)
+
+		/*
+		 * Write each loop.
+		 */
+		for i, loop := range loops {
+
+			/*
+			 * Create sampler loop. A play count of zero loops
+			 * infinitely.
+			 */
+			l := samplerLoop{
+				ID:        uint32(i),
+				Type:      LOOP_FORWARD,
+				Start:     loop.Start,
+				End:       loop.End,
+				Fraction:  0,
+				PlayCount: 0,
+			}
+
+			binary.Write(buf, binary.LittleEndian, l)
+		}
+
+	}
+
+	content := buf.Bytes()
+	return content
+}
+
+/*
+ * Decodes the labels inside an associated data list.
+ */
+func readLabels(body []byte, labels map[uint32]string) {
+	size := len(body)
+	offset := SIZE_LIST_TYPE
+
+	/*
+	 * Iterate over all sub-chunks of the list.
+	 */
+	for (offset + MIN_CHUNK_HEADER_SIZE) <= size {
+		id := binary.LittleEndian.Uint32(body[offset:])
+		chunkSize32 := binary.LittleEndian.Uint32(body[offset+4:])
+		chunkSize := int(chunkSize32)
+		start := offset + MIN_CHUNK_HEADER_SIZE
+		end := start + chunkSize
+
+		/*
+		 * Stop at a truncated sub-chunk.
+		 */
+		if chunkSize < 0 || end > size {
+			return
+		}
+
+		/*
+		 * Decode labels and skip everything else.
+		 */
+		if id == ID_LABEL && chunkSize >= SIZE_LABEL_ID {
+			cueId := binary.LittleEndian.Uint32(body[start:])
+			text := body[start+SIZE_LABEL_ID : end]
+			terminator := bytes.IndexByte(text, 0)
+
+			/*
+			 * Strip the terminating zero.
+			 */
+			if terminator >= 0 {
+				text = text[0:terminator]
+			}
+
+			labels[cueId] = string(text)
+		}
+
+		/*
+		 * If chunk size is not even, we have to skip one
+		 * additional byte of padding.
+		 */
+		if (chunkSize % 2) != 0 {
+			end++
+		}
+
+		offset = end
+	}
+
+}
+
+/*
+ * Reads the markers and loops of a wave file with sample data of a certain
+ * size.
+ *
+ * Since markers and loops are optional, chunks which cannot be decoded are
+ * ignored.
+ */
+func readMarkerChunks(reader io.ReadSeeker, totalSize uint64, dataSize uint64) ([]Marker, []Loop) {
+	points := []cuePoint{}
+	labels := make(map[uint32]string)
+	loops := []Loop{}
+	offset := uint64(MIN_CHUNK_HEADER_SIZE + 4)
+
+	/*
+	 * Iterate over all chunks of the file.
+	 */
+	for (offset + MIN_CHUNK_HEADER_SIZE) <= totalSize {
+		offset64 := int64(offset)
+		reader.Seek(offset64, io.SeekStart)
+		hdrChunk := chunkHeader{}
+		err := binary.Read(reader, binary.LittleEndian, &hdrChunk)
+
+		/*
+		 * Stop if the chunk header cannot be read.
+		 */
+		if err != nil {
+			break
+		}
+
+		id := hdrChunk.ChunkID
+		chunkSize := uint64(hdrChunk.ChunkSize)
+		start := offset + MIN_CHUNK_HEADER_SIZE
+
+		/*
+		 * The size of the data chunk of RF64 files is only found in the
+		 * data size chunk.
+		 */
+		if id == ID_DATA {
+			chunkSize = dataSize
+		}
+
+		end := start + chunkSize
+
+		/*
+		 * Stop at a chunk reaching beyond what can be addressed.
+		 */
+		if end < start {
+			break
+		}
+
+		isMarkerChunk := id == ID_CUE || id == ID_LIST || id == ID_SAMPLER
+
+		/*
+		 * Decode the chunks holding markers and loops.
+		 */
+		if isMarkerChunk && end <= totalSize {
+			body := make([]byte, chunkSize)
+			_, err = io.ReadFull(reader, body)
+
+			/*
+			 * Only decode chunks which were read completely.
+			 */
+			if err == nil {
+				bodyReader := bytes.NewReader(body)
+
+				/*
+				 * Decide on the chunk.
+				 */
+				switch id {
+				case ID_CUE:
+					numPoints := uint32(0)
+					binary.Read(bodyReader, binary.LittleEndian, &numPoints)
+					numPoints64 := uint64(numPoints)
+					maxPoints := (chunkSize - SIZE_CUE_POINT_COUNT) / SIZE_CUE_POINT
+
+					/*
+					 * Do not read beyond the end of the chunk.
+					 */
+					if chunkSize < SIZE_CUE_POINT_COUNT {
+						numPoints64 = 0
+					} else if numPoints64 > maxPoints {
+						numPoints64 = maxPoints
+					}
+
+					/*
+					 * Read each cue point.
+					 */
+					for i := uint64(0); i < numPoints64; i++ {
+						point := cuePoint{}
+						binary.Read(bodyReader, binary.LittleEndian, &point)
+						points = append(points, point)
+					}
+
+				case ID_LIST:
+
+					/*
+					 * Only associated data lists carry labels.
+					 */
+					if chunkSize >= SIZE_LIST_TYPE && binary.LittleEndian.Uint32(body) == TYPE_ADTL {
+						readLabels(body, labels)
+					}
+
+				case ID_SAMPLER:
+					hdrSampler := samplerHeader{}
+
+					/*
+					 * Check if the sampler header is complete.
+					 */
+					if chunkSize >= SIZE_SAMPLER_HEADER {
+						binary.Read(bodyReader, binary.LittleEndian, &hdrSampler)
+						numLoops := uint64(hdrSampler.LoopCount)
+						maxLoops := (chunkSize - SIZE_SAMPLER_HEADER) / SIZE_SAMPLER_LOOP
+
+						/*
+						 * Do not read beyond the end of the chunk.
+						 */
+						if numLoops > maxLoops {
+							numLoops = maxLoops
+						}
+
+						/*
+						 * Read each loop.
+						 */
+						for i := uint64(0); i < numLoops; i++ {
+							l := samplerLoop{}
+							binary.Read(bodyReader, binary.LittleEndian, &l)
+
+							/*
+							 * Create loop.
+							 */
+							loop := Loop{
+								Start: l.Start,
+								End:   l.End,
+							}
+
+							loops = append(loops, loop)
+						}
+
+					}
+
+				}
+
+			}
+
+		}
+
+		/*
+		 * If chunk size is not even, we have to skip one
+		 * additional byte of padding.
+		 */
+		if (chunkSize % 2) != 0 {
+			end++
+		}
+
+		offset = end
+	}
+
+	markers := make([]Marker, len(points))
+
+	/*
+	 * Combine cue points and labels into markers.
+	 */
+	for i, point := range points {
+
+		/*
+		 * Create marker.
+		 */
+		markers[i] = Marker{
+			Position: point.SampleOffset,
+			Label:    labels[point.ID],
+		}
+
+	}
+
+	return markers, loops
+}
@@ -40,12 +40,22 @@ const (
 	ID_DATA               = 0x61746164 // uint32
 	ID_DATASIZE           = 0x34367364 // uint32
 	ID_FORMAT             = 0x20746d66 // uint32
+	ID_JUNK               = 0x4b4e554a // uint32
 	ID_RIFF               = 0x46464952 // uint32
 	ID_RIFF64             = 0x34364652 // uint32
 	MIN_CHUNK_SIZE_FORMAT = 0x00000010 // uint32
 	MIN_TOTAL_HEADER_SIZE = 0x0000002c // uint32
 )
 
+/*
+ * Containers audio files may be stored in.
+ */
+const (
+	CONTAINER_RIFF = 0x0000 // uint16
+	CONTAINER_AIFF = 0x0001 // uint16
+	CONTAINER_CAF  = 0x0002 // uint16
+)
+
 /*
  * An interface type representing the channels inside a RIFF wave file.
  */
@@ -66,10 +76,15 @@ type channelStruct struct {
  * An interface type representing a RIFF wave file.
  */
 type File interface {
+	AddLoop(start uint32, end uint32) error
+	AddMarker(position uint32, label string)
 	BitDepth() uint16
 	Bytes() ([]byte, error)
 	Channel(id uint16) (Channel, error)
 	ChannelCount() uint16
+	Container() uint16
+	Loops() []Loop
+	Markers() []Marker
 	SampleFormat() uint16
 	SampleRate() uint32
 }
@@ -78,10 +93,82 @@ type File interface {
  * The internal data structure representing a RIFF wave file.
  */
 type fileStruct struct {
+	container    uint16
 	bitDepth     uint16
 	sampleFormat uint16
 	sampleRate   uint32
 	channels     []Channel
+	markers      []Marker
+	loops        []Loop
+}
+
+/*
+ * An interface type representing a wave file which is written incrementally.
+ */
+type Writer interface {
+	Close() error
+	Write(channels [][]float64) error
+}
+
+/*
+ * The internal data structure representing a wave file which is written
+ * incrementally.
+ */
+type writerStruct struct {
+	writer       io.Writer
+	container    uint16
+	bitDepth     uint16
+	sampleFormat uint16
+	sampleRate   uint32
+	channelCount uint16
+	numSamples   uint64
+	samples      []float64
+}
+
+/*
+ * An interface type representing a wave file which is decoded block by block.
+ */
+type Reader interface {
+	BitDepth() uint16
+	ChannelCount() uint16
+	Length() uint64
+	Read(channels [][]float64) (int, error)
+	SampleFormat() uint16
+	SampleRate() uint32
+	Seek(frame uint64) error
+}
+
+/*
+ * The internal data structure representing a wave file which is decoded
+ * block by block.
+ */
+type readerStruct struct {
+	reader       io.ReadSeeker
+	bitDepth     uint16
+	sampleFormat uint16
+	sampleRate   uint32
+	channelCount uint16
+	bigEndian    bool
+	signedBytes  bool
+	dataOffset   int64
+	numFrames    uint64
+	position     uint64
+	data         []byte
+}
+
+/*
+ * The format and the location of the sample data of an audio file, no matter
+ * which container it is stored in.
+ */
+type audioHeader struct {
+	container    uint16
+	sampleFormat uint16
+	bitDepth     uint16
+	channelCount uint16
+	sampleRate   uint32
+	dataSize     uint64
+	bigEndian    bool
+	signedBytes  bool
 }
 
 /*
@@ -239,7 +326,14 @@ func samplesToChannels(samples []float64, channelCount uint16) []Channel {
 	channelCount32 := uint32(channelCount)
 	size := len(samples)
 	size32 := uint32(size)
-	samplesPerChannel := size32 / channelCount32
+	samplesPerChannel := uint32(0)
+
+	/*
+	 * Without channels, there are no samples per channel.
+	 */
+	if channelCount32 != 0 {
+		samplesPerChannel = size32 / channelCount32
+	}
 
 	/*
 	 * Extract each channel from the sample data.
@@ -838,275 +932,896 @@ func bytesToSamples(data []byte, sampleFormat uint16, bitDepth uint16) ([]float6
 }
 
 /*
- * Returns the sample depth of this wave file in bits.
+ * Returns how a container lays out sample data, i. e. whether samples are
+ * stored in big-endian byte order and whether 8-bit samples are signed.
  */
-func (this *fileStruct) BitDepth() uint16 {
-	return this.bitDepth
+func containerLayout(container uint16) (bool, bool) {
+
+	/*
+	 * Decide on the container.
+	 */
+	switch container {
+	case CONTAINER_AIFF:
+		return true, true
+	case CONTAINER_CAF:
+		return false, true
+	default:
+		return false, false
+	}
+
 }
 
 /*
- * Returns the contents of this wave file as a byte slice.
+ * Converts sample data between the layout of RIFF wave files, which store
+ * samples in little-endian byte order and 8-bit samples as unsigned values,
+ * and the layout of another container.
+ *
+ * The conversion is its own inverse, so it serves for encoding as well as for
+ * decoding. The data is converted in place.
  */
-func (this *fileStruct) Bytes() ([]byte, error) {
-	channelCount := len(this.channels)
-	channelCount16 := uint16(channelCount)
-	channelCount32 := uint32(channelCount)
-	bitDepth := this.bitDepth
-	sampleFormat := this.sampleFormat
-	sampleRate := this.sampleRate
-	sampleSize32 := uint32(bitDepth / BITS_PER_BYTE)
-	sampleSize64 := uint64(sampleSize32)
-	blockAlign := sampleSize32 * channelCount32
-	blockAlign16 := uint16(blockAlign)
-	byteRate := sampleRate * blockAlign
-	samples := channelsToSamples(this.channels)
-	numSamples := len(samples)
-	data, err := samplesToBytes(samples, sampleFormat, bitDepth)
+func convertLayout(data []byte, bitDepth uint16, bigEndian bool, signedBytes bool) {
+	sampleSize := int(bitDepth / BITS_PER_BYTE)
+	size := len(data)
 
 	/*
-	 * Check if conversion was successful.
+	 * Only 8-bit samples differ in signedness, only wider samples differ in
+	 * byte order.
 	 */
-	if err != nil {
-		return nil, err
-	} else {
-		idRIFF := uint32(ID_RIFF)
-		numSamples32 := uint32(numSamples)
-		numSamples64 := uint64(numSamples)
-		dataBytes32 := sampleSize32 * numSamples32
-		dataBytes64 := sampleSize64 * numSamples64
-		riffSize64 := dataBytes64 + (MIN_TOTAL_HEADER_SIZE - MIN_CHUNK_HEADER_SIZE)
-		riffSize32 := uint32(riffSize64)
-		requiresRF64 := riffSize64 > math.MaxUint32
+	if sampleSize == 1 {
 
 		/*
-		 * If we write an RF64 file, replace RIFF chunk ID with 'RF64' and set 32-bit size to math.MaxUint32 (0xffffffff).
+		 * Flip the sign bit of each sample.
 		 */
-		if requiresRF64 {
-			idRIFF = uint32(ID_RIFF64)
-			riffSize32 = math.MaxUint32
-		}
+		if signedBytes {
 
-		/*
-		 * Create RIFF header.
-		 */
-		hdrRiff := riffHeader{
-			ChunkID:   idRIFF,
-			ChunkSize: riffSize32,
-			Format:    FORMAT_WAVE,
-		}
+			/*
+			 * Iterate over all samples.
+			 */
+			for i := range data {
+				data[i] ^= 0x80
+			}
 
-		/*
-		 * Create data size header.
-		 */
-		hdrDataSize := dataSizeHeader{
-			ChunkID:     ID_DATASIZE,
-			ChunkSize:   MIN_DATASIZE_CHUNK_SIZE,
-			SizeRIFF:    riffSize64,
-			SizeData:    dataBytes64,
-			SampleCount: numSamples64,
-			TableLength: 0,
 		}
 
-		/*
-		 * Create format header.
-		 */
-		hdrFormat := formatHeader{
-			ChunkID:      ID_FORMAT,
-			ChunkSize:    MIN_CHUNK_SIZE_FORMAT,
-			AudioFormat:  sampleFormat,
-			ChannelCount: channelCount16,
-			SampleRate:   sampleRate,
-			ByteRate:     byteRate,
-			BlockAlign:   blockAlign16,
-			BitDepth:     bitDepth,
-		}
+	} else if bigEndian && sampleSize > 1 {
 
 		/*
-		 * Create data header.
+		 * Reverse the bytes of each sample.
 		 */
-		hdrData := dataHeader{
-			ChunkID:   ID_DATA,
-			ChunkSize: dataBytes32,
-		}
+		for offset := 0; offset+sampleSize <= size; offset += sampleSize {
+			sample := data[offset : offset+sampleSize]
 
-		buf := createBuffer()
-		binary.Write(buf, binary.LittleEndian, hdrRiff)
+			/*
+			 * Swap bytes from both ends of the sample.
+			 */
+			for i, j := 0, sampleSize-1; i < j; i, j = i+1, j-1 {
+				sample[i], sample[j] = sample[j], sample[i]
+			}
 
-		/*
-		 * If we write an RF64 file, write mandatory data size chunk.
-		 */
-		if requiresRF64 {
-			binary.Write(buf, binary.LittleEndian, hdrDataSize)
 		}
 
-		binary.Write(buf, binary.LittleEndian, hdrFormat)
-		binary.Write(buf, binary.LittleEndian, hdrData)
-		buf.Write(data)
-		content := buf.Bytes()
-		return content, nil
 	}
 
 }
 
 /*
- * Returns a reference to the requested channel.
+ * Convert samples to bytes laid out as required by a container, given a
+ * sample format and bit depth.
  */
-func (this *fileStruct) Channel(id uint16) (Channel, error) {
-	channelCount := this.ChannelCount()
+func encodeSamples(samples []float64, container uint16, sampleFormat uint16, bitDepth uint16) ([]byte, error) {
+	data, err := samplesToBytes(samples, sampleFormat, bitDepth)
 
 	/*
-	 * Check whether the requested channel is available in this wave file.
+	 * Check if conversion was successful.
 	 */
-	if id >= channelCount {
-		return nil, fmt.Errorf("No channel with id = %d in this wave file with channel count %d.", id, channelCount)
+	if err != nil {
+		return nil, err
 	} else {
-		c := this.channels[id]
-		return c, nil
+		bigEndian, signedBytes := containerLayout(container)
+		convertLayout(data, bitDepth, bigEndian, signedBytes)
+		return data, nil
 	}
 
 }
 
 /*
- * Returns the number of channels available in this wave file.
+ * Adds a loop to this wave file, which spans from its start to its end
+ * frame, both inclusive.
  */
-func (this *fileStruct) ChannelCount() uint16 {
-	n := len(this.channels)
-	n16 := uint16(n)
-	return n16
-}
+func (this *fileStruct) AddLoop(start uint32, end uint32) error {
+
+	/*
+	 * Check if the loop is not empty.
+	 */
+	if end < start {
+		return fmt.Errorf("Loop must not end (at frame %d) before it starts (at frame %d).", end, start)
+	} else {
+
+		/*
+		 * Create loop.
+		 */
+		loop := Loop{
+			Start: start,
+			End:   end,
+		}
+
+		this.loops = append(this.loops, loop)
+		return nil
+	}
 
-/*
- * Returns the format code of the sample format of this wave file.
- */
-func (this *fileStruct) SampleFormat() uint16 {
-	return this.sampleFormat
 }
 
 /*
- * Returns the sample rate of this wave file in Hertz.
+ * Adds a marker at a certain frame to this wave file.
  */
-func (this *fileStruct) SampleRate() uint32 {
-	return this.sampleRate
+func (this *fileStruct) AddMarker(position uint32, label string) {
+
+	/*
+	 * Create marker.
+	 */
+	marker := Marker{
+		Position: position,
+		Label:    label,
+	}
+
+	this.markers = append(this.markers, marker)
 }
 
 /*
- * Creates an empty channel.
+ * Returns the sample depth of this wave file in bits.
  */
-func createChannel() Channel {
-	channel := channelStruct{}
-	return &channel
+func (this *fileStruct) BitDepth() uint16 {
+	return this.bitDepth
 }
 
 /*
- * Skips over a number of bytes in the file.
+ * Encodes the headers of a wave file holding a certain number of samples,
+ * followed by chunks of a certain total size after the sample data.
+ *
+ * If space for the data size chunk is reserved, the headers contain either
+ * the data size chunk (for RF64 files) or a 'JUNK' chunk of the same size
+ * (for RIFF files), so that their size does not depend on the number of
+ * samples.
  */
-func skipData(reader *bytes.Reader, numBytes uint64) error {
-	max := uint64(math.MaxInt32)
+func createHeader(sampleRate uint32, sampleFormat uint16, bitDepth uint16, channelCount uint16, numSamples uint64, trailerSize uint64, reserveDataSize bool) []byte {
+	channelCount32 := uint32(channelCount)
+	sampleSize32 := uint32(bitDepth / BITS_PER_BYTE)
+	sampleSize64 := uint64(sampleSize32)
+	blockAlign := sampleSize32 * channelCount32
+	blockAlign16 := uint16(blockAlign)
+	byteRate := sampleRate * blockAlign
+	idRIFF := uint32(ID_RIFF)
+	numSamples32 := uint32(numSamples)
+	dataBytes32 := sampleSize32 * numSamples32
+	dataBytes64 := sampleSize64 * numSamples
+	riffSize64 := dataBytes64 + trailerSize + (MIN_TOTAL_HEADER_SIZE - MIN_CHUNK_HEADER_SIZE)
 
 	/*
-	 * Check if we can seek this far.
+	 * The reserved space is part of the RIFF chunk.
 	 */
-	if numBytes > max {
-		return fmt.Errorf("Cannot skip more than %d bytes.", max)
-	} else {
-		signedBytes := int64(numBytes)
-		mode := io.SeekCurrent
-		reader.Seek(signedBytes, mode)
-		return nil
+	if reserveDataSize {
+		riffSize64 += MIN_CHUNK_HEADER_SIZE + MIN_DATASIZE_CHUNK_SIZE
 	}
 
-}
-
-/*
- * Look ahead to the next chunk.
- */
-func lookaheadChunk(reader *bytes.Reader) (*chunkHeader, error) {
-	hdrChunk := chunkHeader{}
-	err := binary.Read(reader, binary.LittleEndian, &hdrChunk)
+	riffSize32 := uint32(riffSize64)
+	requiresRF64 := riffSize64 > math.MaxUint32
 
 	/*
-	 * Check if chunk header was read.
+	 * If we write an RF64 file, replace RIFF chunk ID with 'RF64' and set 32-bit size to math.MaxUint32 (0xffffffff).
 	 */
-	if err != nil {
-		msg := err.Error()
-		return nil, fmt.Errorf("Failed to read chunk header: %s", msg)
-	} else {
-		mode := io.SeekCurrent
-		_, err = reader.Seek(-MIN_CHUNK_HEADER_SIZE, mode)
-		return &hdrChunk, err
+	if requiresRF64 {
+		idRIFF = uint32(ID_RIFF64)
+		riffSize32 = math.MaxUint32
 	}
 
-}
+	/*
+	 * Create RIFF header.
+	 */
+	hdrRiff := riffHeader{
+		ChunkID:   idRIFF,
+		ChunkSize: riffSize32,
+		Format:    FORMAT_WAVE,
+	}
 
-/*
- * Skip over chunks until you find one with a certain ID.
- */
-func skipToChunk(reader *bytes.Reader, chunkId uint32) error {
-	abort := false
+	/*
+	 * Create data size header.
+	 */
+	hdrDataSize := dataSizeHeader{
+		ChunkID:     ID_DATASIZE,
+		ChunkSize:   MIN_DATASIZE_CHUNK_SIZE,
+		SizeRIFF:    riffSize64,
+		SizeData:    dataBytes64,
+		SampleCount: numSamples,
+		TableLength: 0,
+	}
 
 	/*
-	 * Skip over chunks until we find the one we expect.
+	 * Create format header.
 	 */
-	for !abort {
-		hdrChunk, err := lookaheadChunk(reader)
+	hdrFormat := formatHeader{
+		ChunkID:      ID_FORMAT,
+		ChunkSize:    MIN_CHUNK_SIZE_FORMAT,
+		AudioFormat:  sampleFormat,
+		ChannelCount: channelCount,
+		SampleRate:   sampleRate,
+		ByteRate:     byteRate,
+		BlockAlign:   blockAlign16,
+		BitDepth:     bitDepth,
+	}
 
-		/*
-		 * Check if lookahead was successful.
-		 */
-		if err != nil {
-			return err
-		} else {
-			id := hdrChunk.ChunkID
+	/*
+	 * Create data header.
+	 */
+	hdrData := dataHeader{
+		ChunkID:   ID_DATA,
+		ChunkSize: dataBytes32,
+	}
 
-			/*
-			 * If we found the right chunk, abort, otherwise skip over it.
-			 */
-			if id == chunkId {
-				abort = true
-			} else {
-				size := hdrChunk.ChunkSize
-				sizeLSB := size % 2
+	buf := createBuffer()
+	binary.Write(buf, binary.LittleEndian, hdrRiff)
 
-				/*
-				 * If chunk size is not even, we have to read one
-				 * additional byte of padding.
-				 */
-				if sizeLSB != 0 {
-					size += 1
-				}
+	/*
+	 * If we write an RF64 file, write mandatory data size chunk, otherwise
+	 * fill the reserved space with a 'JUNK' chunk.
+	 */
+	if requiresRF64 {
+		binary.Write(buf, binary.LittleEndian, hdrDataSize)
+	} else if reserveDataSize {
 
-				amount := uint64(size) + MIN_CHUNK_HEADER_SIZE
-				err = skipData(reader, amount)
+		/*
+		 * Create junk header.
+		 */
+		hdrJunk := chunkHeader{
+			ChunkID:   ID_JUNK,
+			ChunkSize: MIN_DATASIZE_CHUNK_SIZE,
+		}
 
-				/*
-				 * Check if skipping failed.
-				 */
-				if err != nil {
-					return err
-				}
+		junk := make([]byte, MIN_DATASIZE_CHUNK_SIZE)
+		binary.Write(buf, binary.LittleEndian, hdrJunk)
+		buf.Write(junk)
+	}
 
-			}
+	binary.Write(buf, binary.LittleEndian, hdrFormat)
+	binary.Write(buf, binary.LittleEndian, hdrData)
+	content := buf.Bytes()
+	return content
+}
 
-		}
+/*
+ * Encodes the headers of an audio file in a certain container, holding a
+ * certain number of samples.
+ */
+func createContainerHeader(container uint16, sampleRate uint32, sampleFormat uint16, bitDepth uint16, channelCount uint16, numSamples uint64, reserveDataSize bool) []byte {
 
+	/*
+	 * Decide on the container.
+	 */
+	switch container {
+	case CONTAINER_AIFF:
+		return createHeaderAIFF(sampleRate, sampleFormat, bitDepth, channelCount, numSamples)
+	case CONTAINER_CAF:
+		return createHeaderCAF(sampleRate, sampleFormat, bitDepth, channelCount, numSamples, reserveDataSize)
+	default:
+		return createHeader(sampleRate, sampleFormat, bitDepth, channelCount, numSamples, 0, reserveDataSize)
 	}
 
-	return nil
 }
 
 /*
- * Read RIFF header from file and validate it.
+ * Returns the contents of this wave file as a byte slice.
  */
-func readHeaderRIFF(reader *bytes.Reader, totalSize uint64) (*riffHeader, error) {
-	hdrRiff := riffHeader{}
-	err := binary.Read(reader, binary.LittleEndian, &hdrRiff)
+func (this *fileStruct) Bytes() ([]byte, error) {
+	channelCount := len(this.channels)
+	channelCount16 := uint16(channelCount)
+	container := this.container
+	bitDepth := this.bitDepth
+	sampleFormat := this.sampleFormat
+	sampleRate := this.sampleRate
+	samples := channelsToSamples(this.channels)
+	numSamples := len(samples)
+	data, err := encodeSamples(samples, container, sampleFormat, bitDepth)
 
 	/*
-	 * Check if RIFF header was read.
+	 * Check if conversion was successful.
 	 */
 	if err != nil {
-		msg := err.Error()
-		return nil, fmt.Errorf("Failed to read RIFF header: %s", msg)
+		return nil, err
+	} else {
+		numSamples64 := uint64(numSamples)
+		header := []byte(nil)
+		trailer := []byte(nil)
+
+		/*
+		 * Only RIFF wave files carry markers and loops, which follow
+		 * the sample data.
+		 */
+		if container == CONTAINER_RIFF {
+			trailer = createMarkerChunks(this.markers, this.loops, sampleRate)
+			trailerSize := len(trailer)
+
+			/*
+			 * The data chunk must be padded to an even size if
+			 * other chunks follow.
+			 */
+			if trailerSize > 0 && (len(data)%2) != 0 {
+				padding := []byte{0}
+				trailer = append(padding, trailer...)
+				trailerSize++
+			}
+
+			trailerSize64 := uint64(trailerSize)
+			header = createHeader(sampleRate, sampleFormat, bitDepth, channelCount16, numSamples64, trailerSize64, false)
+		} else {
+			header = createContainerHeader(container, sampleRate, sampleFormat, bitDepth, channelCount16, numSamples64, false)
+		}
+
+		buf := createBuffer()
+		buf.Write(header)
+		buf.Write(data)
+
+		/*
+		 * Chunks of AIFF files are padded to an even size.
+		 */
+		if container == CONTAINER_AIFF && (len(data)%2) != 0 {
+			buf.WriteByte(0)
+		}
+
+		buf.Write(trailer)
+		content := buf.Bytes()
+		return content, nil
+	}
+
+}
+
+/*
+ * Updates the headers of the wave file, if the underlying writer can seek.
+ *
+ * This does not close the underlying writer.
+ */
+func (this *writerStruct) Close() error {
+	sampleSize := uint64(this.bitDepth / BITS_PER_BYTE)
+	dataBytes := sampleSize * this.numSamples
+
+	/*
+	 * Chunks of AIFF files are padded to an even size.
+	 */
+	if this.container == CONTAINER_AIFF && (dataBytes%2) != 0 {
+		padding := []byte{0}
+		_, err := this.writer.Write(padding)
+
+		/*
+		 * Check if padding was written.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to write padding: %s", msg)
+		}
+
+	}
+
+	seeker, isSeeker := this.writer.(io.WriteSeeker)
+
+	/*
+	 * The headers can only be updated if we can seek back to them.
+	 */
+	if !isSeeker {
+		return nil
+	} else {
+		header := createContainerHeader(this.container, this.sampleRate, this.sampleFormat, this.bitDepth, this.channelCount, this.numSamples, true)
+		_, err := seeker.Seek(0, io.SeekStart)
+
+		/*
+		 * Check if we seeked to the headers.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to seek to headers: %s", msg)
+		} else {
+			_, err = seeker.Write(header)
+
+			/*
+			 * Check if headers were written.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return fmt.Errorf("Failed to update headers: %s", msg)
+			} else {
+				_, err = seeker.Seek(0, io.SeekEnd)
+
+				/*
+				 * Check if we seeked back to the end.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return fmt.Errorf("Failed to seek to end of file: %s", msg)
+				} else {
+					return nil
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Encodes a block of samples, one slice for each channel, and appends it to
+ * the wave file.
+ *
+ * All channels must hold the same number of samples.
+ */
+func (this *writerStruct) Write(channels [][]float64) error {
+	channelCount := len(channels)
+	expectedChannelCount := int(this.channelCount)
+
+	/*
+	 * Check if we got the right number of channels.
+	 */
+	if channelCount != expectedChannelCount {
+		return fmt.Errorf("Expected %d channels, but got %d.", expectedChannelCount, channelCount)
+	} else {
+		blockLength := 0
+
+		/*
+		 * Find the number of samples in the block.
+		 */
+		if channelCount > 0 {
+			blockLength = len(channels[0])
+		}
+
+		/*
+		 * Make sure all channels hold the same number of samples.
+		 */
+		for i, channel := range channels {
+			channelLength := len(channel)
+
+			/*
+			 * Check if channel holds the right number of samples.
+			 */
+			if channelLength != blockLength {
+				return fmt.Errorf("Channel %d holds %d samples, but channel 0 holds %d samples.", i, channelLength, blockLength)
+			}
+
+		}
+
+		numSamples := blockLength * channelCount
+		numSamples64 := uint64(numSamples)
+		sampleSize := uint64(this.bitDepth / BITS_PER_BYTE)
+		dataBytes := sampleSize * (this.numSamples + numSamples64)
+
+		/*
+		 * The sizes in AIFF files are limited to 32 bits.
+		 */
+		if this.container == CONTAINER_AIFF && dataBytes > MAX_DATA_SIZE_AIFF {
+			return fmt.Errorf("AIFF files cannot hold more than %d bytes of sample data.", uint32(MAX_DATA_SIZE_AIFF))
+		}
+
+		samples := this.samples
+
+		/*
+		 * Make sure the interleaving buffer has the correct size.
+		 */
+		if len(samples) != numSamples {
+			samples = make([]float64, numSamples)
+			this.samples = samples
+		}
+
+		/*
+		 * Interleave the samples of all channels.
+		 */
+		for i, channel := range channels {
+
+			/*
+			 * Write each sample of the channel.
+			 */
+			for j, sample := range channel {
+				offset := (channelCount * j) + i
+				samples[offset] = sample
+			}
+
+		}
+
+		data, err := encodeSamples(samples, this.container, this.sampleFormat, this.bitDepth)
+
+		/*
+		 * Check if conversion was successful.
+		 */
+		if err != nil {
+			return err
+		} else {
+			_, err = this.writer.Write(data)
+
+			/*
+			 * Check if data was written.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return fmt.Errorf("Failed to write sample data: %s", msg)
+			} else {
+				this.numSamples += numSamples64
+				return nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Returns the bit depth of the wave file.
+ */
+func (this *readerStruct) BitDepth() uint16 {
+	return this.bitDepth
+}
+
+/*
+ * Returns the number of channels of the wave file.
+ */
+func (this *readerStruct) ChannelCount() uint16 {
+	return this.channelCount
+}
+
+/*
+ * Returns the number of sample frames in the wave file.
+ */
+func (this *readerStruct) Length() uint64 {
+	return this.numFrames
+}
+
+/*
+ * Decodes the next block of samples into a slice for each channel.
+ *
+ * All channels must hold the same number of samples, which determines the
+ * size of the block. Returns the number of samples decoded into each channel,
+ * which is less than the size of the block at the end of the file. Returns
+ * io.EOF when no samples are left.
+ */
+func (this *readerStruct) Read(channels [][]float64) (int, error) {
+	channelCount := len(channels)
+	expectedChannelCount := int(this.channelCount)
+
+	/*
+	 * Check if we got the right number of channels.
+	 */
+	if channelCount != expectedChannelCount {
+		return 0, fmt.Errorf("Expected %d channels, but got %d.", expectedChannelCount, channelCount)
+	} else {
+		blockLength := 0
+
+		/*
+		 * Find the number of samples in the block.
+		 */
+		if channelCount > 0 {
+			blockLength = len(channels[0])
+		}
+
+		/*
+		 * Make sure all channels hold the same number of samples.
+		 */
+		for i, channel := range channels {
+			channelLength := len(channel)
+
+			/*
+			 * Check if channel holds the right number of samples.
+			 */
+			if channelLength != blockLength {
+				return 0, fmt.Errorf("Channel %d holds %d samples, but channel 0 holds %d samples.", i, channelLength, blockLength)
+			}
+
+		}
+
+		remaining := this.numFrames - this.position
+		numFrames := uint64(blockLength)
+
+		/*
+		 * Do not read beyond the end of the sample data.
+		 */
+		if numFrames > remaining {
+			numFrames = remaining
+		}
+
+		/*
+		 * Check if there are any samples left.
+		 */
+		if numFrames == 0 && blockLength > 0 {
+			return 0, io.EOF
+		} else {
+			bytesPerSample := uint64(this.bitDepth / 8)
+			channelCount64 := uint64(channelCount)
+			numBytes := numFrames * channelCount64 * bytesPerSample
+			data := this.data
+
+			/*
+			 * Make sure the data buffer has the correct size.
+			 */
+			if uint64(len(data)) != numBytes {
+				data = make([]byte, numBytes)
+				this.data = data
+			}
+
+			_, err := io.ReadFull(this.reader, data)
+
+			/*
+			 * Check if sample data was read.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return 0, fmt.Errorf("Failed to read sample data: %s", msg)
+			} else {
+				convertLayout(data, this.bitDepth, this.bigEndian, this.signedBytes)
+				samples, err := bytesToSamples(data, this.sampleFormat, this.bitDepth)
+
+				/*
+				 * Check if sample data was decoded.
+				 */
+				if err != nil {
+					msg := err.Error()
+					return 0, fmt.Errorf("Failed to decode sample data: %s", msg)
+				} else {
+					numFramesInt := int(numFrames)
+
+					/*
+					 * De-interleave the samples into the channels.
+					 */
+					for i, channel := range channels {
+
+						/*
+						 * Read each sample of the channel.
+						 */
+						for j := 0; j < numFramesInt; j++ {
+							offset := (channelCount * j) + i
+							channel[j] = samples[offset]
+						}
+
+					}
+
+					this.position += numFrames
+					return numFramesInt, nil
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Returns the sample format of the wave file.
+ */
+func (this *readerStruct) SampleFormat() uint16 {
+	return this.sampleFormat
+}
+
+/*
+ * Returns the sample rate of the wave file.
+ */
+func (this *readerStruct) SampleRate() uint32 {
+	return this.sampleRate
+}
+
+/*
+ * Moves to a certain sample frame, so that the next block is decoded starting
+ * from there.
+ */
+func (this *readerStruct) Seek(frame uint64) error {
+
+	/*
+	 * Check if the frame is inside the file.
+	 */
+	if frame > this.numFrames {
+		return fmt.Errorf("Cannot seek to frame %d, since file only holds %d frames.", frame, this.numFrames)
+	} else {
+		bytesPerSample := uint64(this.bitDepth / 8)
+		channelCount64 := uint64(this.channelCount)
+		frameOffset := frame * channelCount64 * bytesPerSample
+		frameOffset64 := int64(frameOffset)
+		offset := this.dataOffset + frameOffset64
+		_, err := this.reader.Seek(offset, io.SeekStart)
+
+		/*
+		 * Check if we seeked to the frame.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return fmt.Errorf("Failed to seek to frame %d: %s", frame, msg)
+		} else {
+			this.position = frame
+			return nil
+		}
+
+	}
+
+}
+
+/*
+ * Returns a reference to the requested channel.
+ */
+func (this *fileStruct) Channel(id uint16) (Channel, error) {
+	channelCount := this.ChannelCount()
+
+	/*
+	 * Check whether the requested channel is available in this wave file.
+	 */
+	if id >= channelCount {
+		return nil, fmt.Errorf("No channel with id = %d in this wave file with channel count %d.", id, channelCount)
+	} else {
+		c := this.channels[id]
+		return c, nil
+	}
+
+}
+
+/*
+ * Returns the number of channels available in this wave file.
+ */
+func (this *fileStruct) ChannelCount() uint16 {
+	n := len(this.channels)
+	n16 := uint16(n)
+	return n16
+}
+
+/*
+ * Returns the container this wave file is stored in.
+ */
+func (this *fileStruct) Container() uint16 {
+	return this.container
+}
+
+/*
+ * Returns the loops of this wave file.
+ */
+func (this *fileStruct) Loops() []Loop {
+	loops := make([]Loop, len(this.loops))
+	copy(loops, this.loops)
+	return loops
+}
+
+/*
+ * Returns the markers of this wave file.
+ */
+func (this *fileStruct) Markers() []Marker {
+	markers := make([]Marker, len(this.markers))
+	copy(markers, this.markers)
+	return markers
+}
+
+/*
+ * Returns the format code of the sample format of this wave file.
+ */
+func (this *fileStruct) SampleFormat() uint16 {
+	return this.sampleFormat
+}
+
+/*
+ * Returns the sample rate of this wave file in Hertz.
+ */
+func (this *fileStruct) SampleRate() uint32 {
+	return this.sampleRate
+}
+
+/*
+ * Creates an empty channel.
+ */
+func createChannel() Channel {
+	channel := channelStruct{}
+	return &channel
+}
+
+/*
+ * Skips over a number of bytes in the file.
+ */
+func skipData(reader io.ReadSeeker, numBytes uint64) error {
+	max := uint64(math.MaxInt32)
+
+	/*
+	 * Check if we can seek this far.
+	 */
+	if numBytes > max {
+		return fmt.Errorf("Cannot skip more than %d bytes.", max)
+	} else {
+		signedBytes := int64(numBytes)
+		mode := io.SeekCurrent
+		reader.Seek(signedBytes, mode)
+		return nil
+	}
+
+}
+
+/*
+ * Look ahead to the next chunk.
+ */
+func lookaheadChunk(reader io.ReadSeeker) (*chunkHeader, error) {
+	hdrChunk := chunkHeader{}
+	err := binary.Read(reader, binary.LittleEndian, &hdrChunk)
+
+	/*
+	 * Check if chunk header was read.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, fmt.Errorf("Failed to read chunk header: %s", msg)
+	} else {
+		mode := io.SeekCurrent
+		_, err = reader.Seek(-MIN_CHUNK_HEADER_SIZE, mode)
+		return &hdrChunk, err
+	}
+
+}
+
+/*
+ * Skip over chunks until you find one with a certain ID.
+ */
+func skipToChunk(reader io.ReadSeeker, chunkId uint32) error {
+	abort := false
+
+	/*
+	 * Skip over chunks until we find the one we expect.
+	 */
+	for !abort {
+		hdrChunk, err := lookaheadChunk(reader)
+
+		/*
+		 * Check if lookahead was successful.
+		 */
+		if err != nil {
+			return err
+		} else {
+			id := hdrChunk.ChunkID
+
+			/*
+			 * If we found the right chunk, abort, otherwise skip over it.
+			 */
+			if id == chunkId {
+				abort = true
+			} else {
+				size := hdrChunk.ChunkSize
+				sizeLSB := size % 2
+
+				/*
+				 * If chunk size is not even, we have to read one
+				 * additional byte of padding.
+				 */
+				if sizeLSB != 0 {
+					size += 1
+				}
+
+				amount := uint64(size) + MIN_CHUNK_HEADER_SIZE
+				err = skipData(reader, amount)
+
+				/*
+				 * Check if skipping failed.
+				 */
+				if err != nil {
+					return err
+				}
+
+			}
+
+		}
+
+	}
+
+	return nil
+}
+
+/*
+ * Read RIFF header from file and validate it.
+ */
+func readHeaderRIFF(reader io.ReadSeeker, totalSize uint64) (*riffHeader, error) {
+	hdrRiff := riffHeader{}
+	err := binary.Read(reader, binary.LittleEndian, &hdrRiff)
+
+	/*
+	 * Check if RIFF header was read.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, fmt.Errorf("Failed to read RIFF header: %s", msg)
 	} else {
 		expectedRiffChunkSize64 := totalSize - 8
 		expectedRiffChunkSize32 := uint32(expectedRiffChunkSize64)
@@ -1134,7 +1849,7 @@ func readHeaderRIFF(reader *bytes.Reader, totalSize uint64) (*riffHeader, error)
 /*
  * Read data size header from file and validate it.
  */
-func readHeaderDataSize(reader *bytes.Reader, totalSize uint64) (*dataSizeHeader, error) {
+func readHeaderDataSize(reader io.ReadSeeker, totalSize uint64) (*dataSizeHeader, error) {
 	hdrDataSize := dataSizeHeader{}
 	err := binary.Read(reader, binary.LittleEndian, &hdrDataSize)
 
@@ -1170,7 +1885,7 @@ func readHeaderDataSize(reader *bytes.Reader, totalSize uint64) (*dataSizeHeader
 /*
  * Read format header from file and validate it.
  */
-func readHeaderFormat(reader *bytes.Reader) (*formatHeader, error) {
+func readHeaderFormat(reader io.ReadSeeker) (*formatHeader, error) {
 	hdrFormat := formatHeader{}
 	err := binary.Read(reader, binary.LittleEndian, &hdrFormat)
 
@@ -1202,9 +1917,10 @@ func readHeaderFormat(reader *bytes.Reader) (*formatHeader, error) {
 		if numBytesSkip > 0 {
 
 			/*
-			 * If this is even, we need to skip one more.
+			 * If this is odd, we need to skip one more byte of
+			 * padding.
 			 */
-			if (numBytesSkip % 2) == 0 {
+			if (numBytesSkip % 2) != 0 {
 				numBytesSkip += 1
 			}
 
@@ -1240,7 +1956,7 @@ func readHeaderFormat(reader *bytes.Reader) (*formatHeader, error) {
 /*
  * Read data header from file and validate it.
  */
-func readHeaderData(reader *bytes.Reader, totalSize uint64) (*dataHeader, error) {
+func readHeaderData(reader io.ReadSeeker, totalSize uint64) (*dataHeader, error) {
 	hdrData := dataHeader{}
 	err := binary.Read(reader, binary.LittleEndian, &hdrData)
 
@@ -1304,10 +2020,13 @@ func CreateEmpty(sampleRate uint32, sampleFormat uint16, bitDepth uint16, channe
 			 * Create wave file structure.
 			 */
 			file := fileStruct{
+				container:    CONTAINER_RIFF,
 				bitDepth:     bitDepth,
 				sampleFormat: sampleFormat,
 				sampleRate:   sampleRate,
 				channels:     channels,
+				markers:      []Marker{},
+				loops:        []Loop{},
 			}
 
 			return &file, nil
@@ -1318,35 +2037,34 @@ func CreateEmpty(sampleRate uint32, sampleFormat uint16, bitDepth uint16, channe
 }
 
 /*
- * Creates a wave file from the contents of a byte buffer.
+ * Reads and validates the headers of a wave file, leaving the reader at the
+ * beginning of the sample data. Returns the format header and the size of the
+ * sample data in bytes.
  */
-func FromBuffer(buffer []byte) (File, error) {
-	totalSize := len(buffer)
-	totalSize64 := uint64(totalSize)
-	reader := bytes.NewReader(buffer)
-	hdrRiff, err := readHeaderRIFF(reader, totalSize64)
-	riffChunkId := hdrRiff.ChunkID
+func readHeaders(reader io.ReadSeeker, totalSize uint64) (*formatHeader, uint64, error) {
+	hdrRiff, err := readHeaderRIFF(reader, totalSize)
 
 	/*
 	 * Check if RIFF header was successfully read.
 	 */
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	} else {
+		riffChunkId := hdrRiff.ChunkID
 		hdrDataSize := &dataSizeHeader{}
 
 		/*
 		 * If this is an 'RF64' or 'BW64' file, read data size header.
 		 */
 		if riffChunkId == ID_RIFF64 || riffChunkId == ID_BW64 {
-			hdrDataSize, err = readHeaderDataSize(reader, totalSize64)
+			hdrDataSize, err = readHeaderDataSize(reader, totalSize)
 
 			/*
 			 * If data size header was successfully read, skip over optional table entries.
 			 */
 			if err != nil {
 				msg := err.Error()
-				return nil, fmt.Errorf("Failed to read data size chunk: %s", msg)
+				return nil, 0, fmt.Errorf("Failed to read data size chunk: %s", msg)
 			} else {
 				numEntries := hdrDataSize.TableLength
 				numEntries64 := uint64(numEntries)
@@ -1358,7 +2076,7 @@ func FromBuffer(buffer []byte) (File, error) {
 				 */
 				if err != nil {
 					msg := err.Error()
-					return nil, fmt.Errorf("Failed to skip over data size table entries: %s", msg)
+					return nil, 0, fmt.Errorf("Failed to skip over data size table entries: %s", msg)
 				}
 
 			}
@@ -1372,7 +2090,7 @@ func FromBuffer(buffer []byte) (File, error) {
 		 */
 		if err != nil {
 			msg := err.Error()
-			return nil, fmt.Errorf("Failed to locate format chunk: %s", msg)
+			return nil, 0, fmt.Errorf("Failed to locate format chunk: %s", msg)
 		} else {
 			hdrFormat, err := readHeaderFormat(reader)
 
@@ -1380,10 +2098,8 @@ func FromBuffer(buffer []byte) (File, error) {
 			 * Check if format header was successfully read.
 			 */
 			if err != nil {
-				return nil, err
+				return nil, 0, err
 			} else {
-				bitDepth := hdrFormat.BitDepth
-				sampleFormat := hdrFormat.AudioFormat
 				err := skipToChunk(reader, ID_DATA)
 
 				/*
@@ -1391,62 +2107,380 @@ func FromBuffer(buffer []byte) (File, error) {
 				 */
 				if err != nil {
 					msg := err.Error()
-					return nil, fmt.Errorf("Failed to locate data chunk: %s", msg)
+					return nil, 0, fmt.Errorf("Failed to locate data chunk: %s", msg)
 				} else {
-					hdrData, err := readHeaderData(reader, totalSize64)
-					chunkSize32 := hdrData.ChunkSize
-					chunkSize64 := uint64(chunkSize32)
+					hdrData, err := readHeaderData(reader, totalSize)
 
 					/*
-					 * If this is an 'RF64' or 'BW64' file, take chunk size from data size header.
+					 * Check if data header was successfully read.
 					 */
-					if riffChunkId == ID_RIFF64 || riffChunkId == ID_BW64 {
-						chunkSize64 = hdrDataSize.SizeData
+					if err != nil {
+						return nil, 0, err
+					} else {
+						chunkSize32 := hdrData.ChunkSize
+						chunkSize64 := uint64(chunkSize32)
+
+						/*
+						 * If this is an 'RF64' or 'BW64' file, take chunk size from data size header.
+						 */
+						if riffChunkId == ID_RIFF64 || riffChunkId == ID_BW64 {
+							chunkSize64 = hdrDataSize.SizeData
+						}
+
+						return hdrFormat, chunkSize64, nil
 					}
 
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Finds out which container an audio file is stored in by looking at its
+ * first four bytes, leaving the reader where it was. Files which are neither
+ * AIFF nor CAF files are treated as RIFF wave files.
+ */
+func detectContainer(reader io.ReadSeeker) uint16 {
+	magic := make([]byte, 4)
+	n, _ := io.ReadFull(reader, magic)
+	offset := int64(-n)
+	reader.Seek(offset, io.SeekCurrent)
+
+	/*
+	 * Check if the magic number was read.
+	 */
+	if n != len(magic) {
+		return CONTAINER_RIFF
+	} else {
+		id := binary.BigEndian.Uint32(magic)
+
+		/*
+		 * Decide on the container.
+		 */
+		switch id {
+		case ID_FORM:
+			return CONTAINER_AIFF
+		case ID_CAFF:
+			return CONTAINER_CAF
+		default:
+			return CONTAINER_RIFF
+		}
+
+	}
+
+}
+
+/*
+ * Reads and validates the headers of an audio file in any supported
+ * container, leaving the reader at the beginning of the sample data.
+ */
+func readAudioHeader(reader io.ReadSeeker, totalSize uint64) (*audioHeader, error) {
+	container := detectContainer(reader)
+
+	/*
+	 * Decide on the container.
+	 */
+	switch container {
+	case CONTAINER_AIFF:
+		hdr, err := readHeadersAIFF(reader, totalSize)
+		return hdr, err
+	case CONTAINER_CAF:
+		hdr, err := readHeadersCAF(reader, totalSize)
+		return hdr, err
+	default:
+		hdrFormat, chunkSize64, err := readHeaders(reader, totalSize)
+
+		/*
+		 * Check if headers were successfully read.
+		 */
+		if err != nil {
+			return nil, err
+		} else {
+
+			/*
+			 * Create audio header structure.
+			 */
+			hdr := audioHeader{
+				container:    CONTAINER_RIFF,
+				sampleFormat: hdrFormat.AudioFormat,
+				bitDepth:     hdrFormat.BitDepth,
+				channelCount: hdrFormat.ChannelCount,
+				sampleRate:   hdrFormat.SampleRate,
+				dataSize:     chunkSize64,
+				bigEndian:    false,
+				signedBytes:  false,
+			}
+
+			return &hdr, nil
+		}
+
+	}
+
+}
+
+/*
+ * Creates a wave file from the contents of a byte buffer.
+ *
+ * Besides RIFF wave files, AIFF and CAF files are accepted. Their container
+ * is detected automatically.
+ */
+func FromBuffer(buffer []byte) (File, error) {
+	totalSize := len(buffer)
+	totalSize64 := uint64(totalSize)
+	reader := bytes.NewReader(buffer)
+	hdr, err := readAudioHeader(reader, totalSize64)
+
+	/*
+	 * Check if headers were successfully read.
+	 */
+	if err != nil {
+		return nil, err
+	} else {
+		bitDepth := hdr.bitDepth
+		sampleFormat := hdr.sampleFormat
+		channelCount := hdr.channelCount
+		dataSize := hdr.dataSize
+		remaining := reader.Len()
+		available := uint64(remaining)
+
+		/*
+		 * Do not trust the size of the sample data announced in the
+		 * headers beyond the end of the buffer.
+		 */
+		if dataSize > available {
+			dataSize = available
+		}
+
+		sampleData := make([]byte, dataSize)
+		_, err = io.ReadFull(reader, sampleData)
+
+		/*
+		 * Check if there are channels and sample data was read.
+		 */
+		if channelCount == 0 {
+			return nil, fmt.Errorf("%s", "File has no channels.")
+		} else if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to read sample data: %s", msg)
+		} else {
+			convertLayout(sampleData, bitDepth, hdr.bigEndian, hdr.signedBytes)
+			samples, err := bytesToSamples(sampleData, sampleFormat, bitDepth)
+
+			/*
+			 * Check if sample data was decoded.
+			 */
+			if err != nil {
+				msg := err.Error()
+				return nil, fmt.Errorf("Failed to decode sample data: %s", msg)
+			} else {
+				channels := samplesToChannels(samples, channelCount)
+				markers := []Marker{}
+				loops := []Loop{}
+
+				/*
+				 * Only RIFF wave files carry markers and loops.
+				 */
+				if hdr.container == CONTAINER_RIFF {
+					markers, loops = readMarkerChunks(reader, totalSize64, dataSize)
+				}
+
+				/*
+				 * Create a new data structure representing the contents of the wave file.
+				 */
+				file := fileStruct{
+					container:    hdr.container,
+					bitDepth:     bitDepth,
+					sampleFormat: sampleFormat,
+					sampleRate:   hdr.sampleRate,
+					channels:     channels,
+					markers:      markers,
+					loops:        loops,
+				}
+
+				return &file, nil
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Creates a wave file, which is written incrementally to an underlying
+ * writer, with the desired sample rate, sample format, bit depth and channel
+ * count.
+ *
+ * The headers are written immediately. Since their size only becomes known
+ * after all samples are written, they are updated on close, which requires
+ * the underlying writer to be able to seek. Files longer than 4 GiB are
+ * turned into RF64 files.
+ */
+func CreateWriter(w io.Writer, sampleRate uint32, sampleFormat uint16, bitDepth uint16, channelCount uint16) (Writer, error) {
+	writer, err := CreateContainerWriter(w, CONTAINER_RIFF, sampleRate, sampleFormat, bitDepth, channelCount)
+	return writer, err
+}
+
+/*
+ * Creates an audio file in a certain container, which is written
+ * incrementally to an underlying writer, with the desired sample rate, sample
+ * format, bit depth and channel count.
+ *
+ * Like for wave files, the headers are updated on close. AIFF files cannot
+ * hold more than 4 GiB of sample data. CAF files whose headers are never
+ * updated remain valid, since their sample data extends to the end of the
+ * file.
+ */
+func CreateContainerWriter(w io.Writer, container uint16, sampleRate uint32, sampleFormat uint16, bitDepth uint16, channelCount uint16) (Writer, error) {
+	_, err := CreateEmpty(sampleRate, sampleFormat, bitDepth, channelCount)
+
+	/*
+	 * Check if the format and the container are valid.
+	 */
+	if err != nil {
+		return nil, err
+	} else if container != CONTAINER_RIFF && container != CONTAINER_AIFF && container != CONTAINER_CAF {
+		return nil, fmt.Errorf("Unknown container: %#04x - Expected either %#04x or %#04x or %#04x.", container, CONTAINER_RIFF, CONTAINER_AIFF, CONTAINER_CAF)
+	} else {
+		header := createContainerHeader(container, sampleRate, sampleFormat, bitDepth, channelCount, 0, true)
+		_, err = w.Write(header)
+
+		/*
+		 * Check if headers were written.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to write headers: %s", msg)
+		} else {
+
+			/*
+			 * Create wave writer structure.
+			 */
+			writer := writerStruct{
+				writer:       w,
+				container:    container,
+				bitDepth:     bitDepth,
+				sampleFormat: sampleFormat,
+				sampleRate:   sampleRate,
+				channelCount: channelCount,
+				numSamples:   0,
+				samples:      nil,
+			}
+
+			return &writer, nil
+		}
+
+	}
+
+}
+
+/*
+ * Creates a wave file, which is decoded block by block from an underlying
+ * reader.
+ *
+ * Only the headers are read immediately. Sample data is read and decoded on
+ * demand, so that even very long files can be processed without holding all
+ * of their samples in memory. Like FromBuffer, this accepts AIFF and CAF files
+ * as well.
+ */
+func CreateReader(r io.ReadSeeker) (Reader, error) {
+	totalSize, err := r.Seek(0, io.SeekEnd)
+
+	/*
+	 * Check if we found the size of the file.
+	 */
+	if err != nil {
+		msg := err.Error()
+		return nil, fmt.Errorf("Failed to determine file size: %s", msg)
+	} else {
+		_, err = r.Seek(0, io.SeekStart)
+
+		/*
+		 * Check if we seeked back to the beginning.
+		 */
+		if err != nil {
+			msg := err.Error()
+			return nil, fmt.Errorf("Failed to seek to beginning of file: %s", msg)
+		} else {
+			totalSize64 := uint64(totalSize)
+			hdr, err := readAudioHeader(r, totalSize64)
+
+			/*
+			 * Check if headers were successfully read.
+			 */
+			if err != nil {
+				return nil, err
+			} else {
+				bitDepth := hdr.bitDepth
+				sampleFormat := hdr.sampleFormat
+				sampleRate := hdr.sampleRate
+				channelCount := hdr.channelCount
+				chunkSize64 := hdr.dataSize
+				_, err := CreateEmpty(sampleRate, sampleFormat, bitDepth, channelCount)
+
+				/*
+				 * Check if the format is valid.
+				 */
+				if err != nil {
+					return nil, err
+				} else if channelCount == 0 {
+					return nil, fmt.Errorf("%s", "File has no channels.")
+				} else {
+					dataOffset, err := r.Seek(0, io.SeekCurrent)
+
 					/*
-					 * Check if data header was successfully read.
+					 * Check if we found the beginning of the sample data.
 					 */
 					if err != nil {
-						return nil, err
+						msg := err.Error()
+						return nil, fmt.Errorf("Failed to locate sample data: %s", msg)
 					} else {
-						sampleData := make([]byte, chunkSize64)
-						_, err = reader.Read(sampleData)
+						dataOffset64 := uint64(dataOffset)
+						available := uint64(0)
 
 						/*
-						 * Check if sample data was read.
+						 * The sample data may start beyond the end of a
+						 * truncated file.
 						 */
-						if err != nil {
-							msg := err.Error()
-							return nil, fmt.Errorf("Failed to read sample data: %s", msg)
-						} else {
-							samples, err := bytesToSamples(sampleData, sampleFormat, bitDepth)
-
-							/*
-							 * Check if sample data was decoded.
-							 */
-							if err != nil {
-								msg := err.Error()
-								return nil, fmt.Errorf("Failed to decode sample data: %s", msg)
-							} else {
-								channelCount := hdrFormat.ChannelCount
-								channels := samplesToChannels(samples, channelCount)
-
-								/*
-								 * Create a new data structure representing the contents of the wave file.
-								 */
-								file := fileStruct{
-									bitDepth:     bitDepth,
-									sampleFormat: sampleFormat,
-									sampleRate:   hdrFormat.SampleRate,
-									channels:     channels,
-								}
-
-								return &file, nil
-							}
+						if dataOffset64 < totalSize64 {
+							available = totalSize64 - dataOffset64
+						}
+
+						/*
+						 * Do not read beyond the end of a truncated file.
+						 */
+						if chunkSize64 > available {
+							chunkSize64 = available
+						}
+
+						bytesPerSample := uint64(bitDepth / 8)
+						channelCount64 := uint64(channelCount)
+						bytesPerFrame := channelCount64 * bytesPerSample
+						numFrames := chunkSize64 / bytesPerFrame
 
+						/*
+						 * Create wave reader structure.
+						 */
+						reader := readerStruct{
+							reader:       r,
+							bitDepth:     bitDepth,
+							sampleFormat: sampleFormat,
+							sampleRate:   sampleRate,
+							channelCount: channelCount,
+							bigEndian:    hdr.bigEndian,
+							signedBytes:  hdr.signedBytes,
+							dataOffset:   dataOffset,
+							numFrames:    numFrames,
+							position:     0,
+							data:         nil,
 						}
 
+						return &reader, nil
 					}
 
 				}
@@ -0,0 +1,177 @@
+//go:build go1.18
+// +build go1.18
+
+package wave
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+/*
+ * Adds well-formed and malformed files in each container to the corpus of a
+ * fuzz target.
+ */
+func addSeedFiles(f *testing.F) {
+	containers := []uint16{CONTAINER_RIFF, CONTAINER_AIFF, CONTAINER_CAF}
+
+	/*
+	 * Add a well-formed file in each container.
+	 */
+	for _, container := range containers {
+		file := createShortFile(f, container)
+		f.Add(file)
+	}
+
+	_, files := createMalformedFiles(f)
+
+	/*
+	 * Add each malformed file.
+	 */
+	for _, file := range files {
+		f.Add(file)
+	}
+
+}
+
+/*
+ * Verify that reading arbitrary contents into a file never crashes and either
+ * fails or produces channels holding no more samples than were stored.
+ *
+ * Run with 'go test -fuzz FuzzFromBuffer ./wave'.
+ */
+func FuzzFromBuffer(f *testing.F) {
+	addSeedFiles(f)
+
+	/*
+	 * Read the contents into a file.
+	 */
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := FromBuffer(data)
+
+		/*
+		 * Only check files which were read.
+		 */
+		if err == nil {
+			channelCount := file.ChannelCount()
+			numBytes := len(data)
+
+			/*
+			 * A file needs at least one channel.
+			 */
+			if channelCount == 0 {
+				t.Errorf("%s", "File has no channels.")
+			}
+
+			/*
+			 * Check the samples of each channel.
+			 */
+			for id := uint16(0); id < channelCount; id++ {
+				c, err := file.Channel(id)
+
+				/*
+				 * Check if channel exists.
+				 */
+				if err != nil {
+					t.Errorf("Channel %d missing in file.", id)
+				} else {
+					samples := c.Floats()
+					numSamples := len(samples)
+
+					/*
+					 * Each sample occupies at least one byte.
+					 */
+					if numSamples > numBytes {
+						t.Errorf("Channel %d holds %d samples, but file only holds %d bytes.", id, numSamples, numBytes)
+					}
+
+				}
+
+			}
+
+		}
+
+	})
+
+}
+
+/*
+ * Verify that reading arbitrary contents block by block never crashes and
+ * either fails or yields no more frames than the reader reported.
+ *
+ * Run with 'go test -fuzz FuzzCreateReader ./wave'.
+ */
+func FuzzCreateReader(f *testing.F) {
+	addSeedFiles(f)
+
+	/*
+	 * Read the contents block by block.
+	 */
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := bytes.NewReader(data)
+		r, err := CreateReader(reader)
+
+		/*
+		 * Only check readers which were created.
+		 */
+		if err == nil {
+			channelCount := r.ChannelCount()
+			length := r.Length()
+			numBytes := uint64(len(data))
+			channels := make([][]float64, channelCount)
+
+			/*
+			 * Allocate a short block for each channel.
+			 */
+			for i := range channels {
+				channels[i] = make([]float64, 16)
+			}
+
+			/*
+			 * Each frame occupies at least one byte.
+			 */
+			if length > numBytes {
+				t.Fatalf("Reader reports %d frames, but file only holds %d bytes.", length, numBytes)
+			}
+
+			total := uint64(0)
+			done := false
+
+			/*
+			 * Read blocks until the end of the sample data.
+			 */
+			for !done {
+				n, err := r.Read(channels)
+
+				/*
+				 * Stop at the end or at the first error.
+				 */
+				if (err != nil) || (n == 0) {
+					done = true
+
+					/*
+					 * Only the end of the data may stop reading.
+					 */
+					if (err != nil) && (err != io.EOF) {
+						t.Errorf("Failed to read block: %s", err.Error())
+					}
+
+				} else {
+					total += uint64(n)
+				}
+
+			}
+
+			/*
+			 * The reader must yield the frames it reported.
+			 */
+			if total != length {
+				t.Errorf("Reader should yield %d frames, but yields %d.", length, total)
+			}
+
+		}
+
+	})
+
+}
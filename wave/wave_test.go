@@ -1,7 +1,10 @@
 package wave
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
 	"testing"
 )
@@ -1628,3 +1631,999 @@ func TestImportIEEE64Mono(t *testing.T) {
 	}
 
 }
+
+/*
+ * An in-memory buffer which can be written to and seeked in.
+ */
+type seekableBufferStruct struct {
+	data   []byte
+	offset int64
+}
+
+/*
+ * Writes data at the current offset, extending the buffer if required.
+ */
+func (this *seekableBufferStruct) Write(p []byte) (int, error) {
+	n := len(p)
+	end := this.offset + int64(n)
+
+	/*
+	 * Extend the buffer if required.
+	 */
+	if end > int64(len(this.data)) {
+		data := make([]byte, end)
+		copy(data, this.data)
+		this.data = data
+	}
+
+	copy(this.data[this.offset:end], p)
+	this.offset = end
+	return n, nil
+}
+
+/*
+ * Moves the offset for the next write.
+ */
+func (this *seekableBufferStruct) Seek(offset int64, whence int) (int64, error) {
+
+	/*
+	 * Find the position the offset is relative to.
+	 */
+	switch whence {
+	case io.SeekStart:
+		this.offset = offset
+	case io.SeekCurrent:
+		this.offset += offset
+	case io.SeekEnd:
+		size := int64(len(this.data))
+		this.offset = size + offset
+	default:
+		return 0, fmt.Errorf("Invalid whence: %d", whence)
+	}
+
+	return this.offset, nil
+}
+
+/*
+ * Perform a test of writing a stereo wave file incrementally.
+ */
+func TestWriterIEEE32Stereo(t *testing.T) {
+
+	/*
+	 * Samples for the left channel.
+	 */
+	samplesLeft := []float64{
+		0.0, 0.25, 0.5, 0.75, 1.0, -0.25,
+	}
+
+	/*
+	 * Samples for the right channel.
+	 */
+	samplesRight := []float64{
+		-1.0, -0.75, -0.5, -0.25, 0.0, 0.125,
+	}
+
+	buf := &seekableBufferStruct{}
+	w, err := CreateWriter(buf, 44100, AUDIO_IEEE_FLOAT, 32, 2)
+
+	/*
+	 * Check if writer was created.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Failed to create writer: %s", msg)
+	} else {
+		err = w.Write([][]float64{samplesLeft[0:2]})
+
+		/*
+		 * Writing the wrong number of channels must fail.
+		 */
+		if err == nil {
+			t.Errorf("%s", "Writing the wrong number of channels did not return error.")
+		}
+
+		/*
+		 * Write the samples in three blocks.
+		 */
+		for i := 0; i < 6; i += 2 {
+			end := i + 2
+			err = w.Write([][]float64{samplesLeft[i:end], samplesRight[i:end]})
+
+			/*
+			 * Check if block was written.
+			 */
+			if err != nil {
+				msg := err.Error()
+				t.Errorf("Failed to write block: %s", msg)
+			}
+
+		}
+
+		err = w.Close()
+
+		/*
+		 * Check if writer was closed.
+		 */
+		if err != nil {
+			msg := err.Error()
+			t.Errorf("Failed to close writer: %s", msg)
+		} else {
+			f, err := FromBuffer(buf.data)
+
+			/*
+			 * Check if the file can be read back.
+			 */
+			if err != nil {
+				msg := err.Error()
+				t.Errorf("Failed to read back file: %s", msg)
+			} else {
+				expected := [][]float64{samplesLeft, samplesRight}
+
+				/*
+				 * Compare the samples of each channel.
+				 */
+				for i, expectedSamples := range expected {
+					id := uint16(i)
+					c, err := f.Channel(id)
+
+					/*
+					 * Check if channel exists.
+					 */
+					if err != nil {
+						t.Errorf("Channel %d missing in file.", i)
+					} else {
+						samples := c.Floats()
+						equal, diff := areSlicesClose(samples, expectedSamples, 1.0e-7)
+
+						/*
+						 * If buffers are not equal, report failure.
+						 */
+						if !equal {
+							t.Errorf("Sample buffers are not similar. Expected: %v Got: %v Difference: %v", expectedSamples, samples, diff)
+						}
+
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Perform a test of the wave reader with a stereo IEEE 32 file.
+ */
+func TestReaderIEEE32Stereo(t *testing.T) {
+
+	/*
+	 * Samples for the left channel.
+	 */
+	samplesLeft := []float64{
+		0.0, 0.25, 0.5, 0.75, 1.0,
+	}
+
+	/*
+	 * Samples for the right channel.
+	 */
+	samplesRight := []float64{
+		-1.0, -0.75, -0.5, -0.25, 0.125,
+	}
+
+	buf := &seekableBufferStruct{}
+	w, err := CreateWriter(buf, 48000, AUDIO_IEEE_FLOAT, 32, 2)
+
+	/*
+	 * Check if writer was created.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Failed to create writer: %s", msg)
+	} else {
+		w.Write([][]float64{samplesLeft, samplesRight})
+		w.Close()
+		reader := bytes.NewReader(buf.data)
+		r, err := CreateReader(reader)
+
+		/*
+		 * Check if reader was created.
+		 */
+		if err != nil {
+			msg := err.Error()
+			t.Errorf("Failed to create reader: %s", msg)
+		} else {
+			sampleRate := r.SampleRate()
+			channelCount := r.ChannelCount()
+			length := r.Length()
+
+			/*
+			 * Check the format of the file.
+			 */
+			if sampleRate != 48000 {
+				t.Errorf("Sample rate does not match. Expected %d, got %d.", 48000, sampleRate)
+			} else if channelCount != 2 {
+				t.Errorf("Channel count does not match. Expected %d, got %d.", 2, channelCount)
+			} else if length != 5 {
+				t.Errorf("Length does not match. Expected %d, got %d.", 5, length)
+			}
+
+			left := make([]float64, 2)
+			right := make([]float64, 2)
+			channels := [][]float64{left, right}
+			resultLeft := []float64{}
+			resultRight := []float64{}
+			err = nil
+
+			/*
+			 * Read the samples in blocks until the end of the file.
+			 */
+			for err == nil {
+				n := 0
+				n, err = r.Read(channels)
+				resultLeft = append(resultLeft, left[0:n]...)
+				resultRight = append(resultRight, right[0:n]...)
+			}
+
+			/*
+			 * Reading must end with io.EOF.
+			 */
+			if err != io.EOF {
+				msg := err.Error()
+				t.Errorf("Expected end of file, got: %s", msg)
+			}
+
+			equalLeft, diffLeft := areSlicesClose(resultLeft, samplesLeft, 1.0e-7)
+			equalRight, diffRight := areSlicesClose(resultRight, samplesRight, 1.0e-7)
+
+			/*
+			 * If buffers are not equal, report failure.
+			 */
+			if !equalLeft {
+				t.Errorf("Left channel is not similar. Expected: %v Got: %v Difference: %v", samplesLeft, resultLeft, diffLeft)
+			} else if !equalRight {
+				t.Errorf("Right channel is not similar. Expected: %v Got: %v Difference: %v", samplesRight, resultRight, diffRight)
+			}
+
+			err = r.Seek(3)
+
+			/*
+			 * Check if we can seek back into the file.
+			 */
+			if err != nil {
+				msg := err.Error()
+				t.Errorf("Failed to seek: %s", msg)
+			} else {
+				n, err := r.Read(channels)
+
+				/*
+				 * Check if the block after the seek is correct.
+				 */
+				if err != nil {
+					msg := err.Error()
+					t.Errorf("Failed to read after seek: %s", msg)
+				} else if n != 2 || left[0] != samplesLeft[3] || right[1] != samplesRight[4] {
+					t.Errorf("Block after seek does not match. Got %d frames: %v %v", n, left, right)
+				}
+
+			}
+
+			err = r.Seek(6)
+
+			/*
+			 * Seeking beyond the end must fail.
+			 */
+			if err == nil {
+				t.Errorf("%s", "Seeking beyond the end did not return error.")
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Perform a test of converting sample rates to and from the 80-bit extended
+ * precision format of AIFF files.
+ */
+func TestExtended(t *testing.T) {
+
+	/*
+	 * Sample rates to convert.
+	 */
+	values := []float64{
+		0.0, 8000.0, 22050.0, 44100.0, 48000.0, 96000.0, 192000.0, -0.5,
+	}
+
+	/*
+	 * Expected encoding of 44.1 kHz.
+	 */
+	expected44100 := []byte{
+		0x40, 0x0e, 0xac, 0x44, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	/*
+	 * Convert each value back and forth.
+	 */
+	for _, value := range values {
+		encoded := float64ToExtended(value)
+		decoded := extendedToFloat64(encoded)
+
+		/*
+		 * Check if we got back the original value.
+		 */
+		if decoded != value {
+			t.Errorf("Value %f should be %f after conversion, but is %f.", value, value, decoded)
+		}
+
+	}
+
+	encoded := float64ToExtended(44100.0)
+	equal := areSlicesEqual(encoded[:], expected44100)
+
+	/*
+	 * Check if 44.1 kHz is encoded correctly.
+	 */
+	if !equal {
+		encodedHex := bufferToHex(encoded[:])
+		expectedHex := bufferToHex(expected44100)
+		t.Errorf("Encoding of 44100 should be %s, but is %s.", expectedHex, encodedHex)
+	}
+
+}
+
+/*
+ * Perform a test of writing and reading AIFF and CAF files in all supported
+ * sample formats.
+ */
+func TestContainers(t *testing.T) {
+
+	/*
+	 * Samples for the left channel.
+	 */
+	samplesLeft := []float64{
+		0.0, 0.25, 0.5, 0.75, 1.0, -0.25, -1.0,
+	}
+
+	/*
+	 * Samples for the right channel.
+	 */
+	samplesRight := []float64{
+		-1.0, -0.75, -0.5, -0.25, 0.0, 0.125, 0.5,
+	}
+
+	containers := []uint16{CONTAINER_AIFF, CONTAINER_CAF}
+	sampleFormats := []uint16{AUDIO_PCM, AUDIO_PCM, AUDIO_PCM, AUDIO_PCM, AUDIO_IEEE_FLOAT, AUDIO_IEEE_FLOAT}
+	bitDepths := []uint16{8, 16, 24, 32, 32, 64}
+	tolerances := []float64{1.0e-2, 1.0e-4, 1.0e-6, 1.0e-9, 1.0e-7, 1.0e-15}
+	expected := [][]float64{samplesLeft, samplesRight}
+
+	/*
+	 * Test each container.
+	 */
+	for _, container := range containers {
+
+		/*
+		 * Test each sample format.
+		 */
+		for i, sampleFormat := range sampleFormats {
+			bitDepth := bitDepths[i]
+			tolerance := tolerances[i]
+			buf := &seekableBufferStruct{}
+			w, err := CreateContainerWriter(buf, container, 44100, sampleFormat, bitDepth, 2)
+
+			/*
+			 * Check if writer was created.
+			 */
+			if err != nil {
+				msg := err.Error()
+				t.Fatalf("Failed to create writer for container %#04x with format %#04x and bit depth %d: %s", container, sampleFormat, bitDepth, msg)
+			}
+
+			w.Write([][]float64{samplesLeft[0:3], samplesRight[0:3]})
+			w.Write([][]float64{samplesLeft[3:7], samplesRight[3:7]})
+			err = w.Close()
+
+			/*
+			 * Check if writer was closed.
+			 */
+			if err != nil {
+				msg := err.Error()
+				t.Fatalf("Failed to close writer: %s", msg)
+			}
+
+			f, err := FromBuffer(buf.data)
+
+			/*
+			 * Check if the file can be read back.
+			 */
+			if err != nil {
+				msg := err.Error()
+				t.Fatalf("Failed to read back file in container %#04x with format %#04x and bit depth %d: %s", container, sampleFormat, bitDepth, msg)
+			}
+
+			fileContainer := f.Container()
+			fileFormat := f.SampleFormat()
+			fileBitDepth := f.BitDepth()
+			fileRate := f.SampleRate()
+
+			/*
+			 * Check the format of the file.
+			 */
+			if fileContainer != container {
+				t.Errorf("Container should be %#04x, but is %#04x.", container, fileContainer)
+			} else if fileFormat != sampleFormat {
+				t.Errorf("Sample format should be %#04x, but is %#04x.", sampleFormat, fileFormat)
+			} else if fileBitDepth != bitDepth {
+				t.Errorf("Bit depth should be %d, but is %d.", bitDepth, fileBitDepth)
+			} else if fileRate != 44100 {
+				t.Errorf("Sample rate should be %d, but is %d.", 44100, fileRate)
+			}
+
+			/*
+			 * Compare the samples of each channel.
+			 */
+			for j, expectedSamples := range expected {
+				id := uint16(j)
+				c, err := f.Channel(id)
+
+				/*
+				 * Check if channel exists.
+				 */
+				if err != nil {
+					t.Errorf("Channel %d missing in file.", j)
+				} else {
+					samples := c.Floats()
+					equal, diff := areSlicesClose(samples, expectedSamples, tolerance)
+
+					/*
+					 * If buffers are not equal, report failure.
+					 */
+					if !equal {
+						t.Errorf("Sample buffers in container %#04x with bit depth %d are not similar. Expected: %v Got: %v Difference: %v", container, bitDepth, expectedSamples, samples, diff)
+					}
+
+				}
+
+			}
+
+			content, err := f.Bytes()
+
+			/*
+			 * Serializing the file must reproduce the written file.
+			 */
+			if err != nil {
+				msg := err.Error()
+				t.Errorf("Failed to serialize file: %s", msg)
+			} else if !areSlicesEqual(content, buf.data) {
+				t.Errorf("Serialized file in container %#04x with bit depth %d differs from written file.", container, bitDepth)
+			}
+
+			reader := bytes.NewReader(buf.data)
+			r, err := CreateReader(reader)
+
+			/*
+			 * Check if reader was created.
+			 */
+			if err != nil {
+				msg := err.Error()
+				t.Fatalf("Failed to create reader: %s", msg)
+			}
+
+			length := r.Length()
+
+			/*
+			 * Check the length of the file.
+			 */
+			if length != 7 {
+				t.Errorf("Length should be %d, but is %d.", 7, length)
+			}
+
+			r.Seek(5)
+			left := make([]float64, 4)
+			right := make([]float64, 4)
+			n, err := r.Read([][]float64{left, right})
+
+			/*
+			 * Check the block after the seek.
+			 */
+			if err != nil {
+				msg := err.Error()
+				t.Errorf("Failed to read after seek: %s", msg)
+			} else if n != 2 {
+				t.Errorf("Number of frames read should be %d, but is %d.", 2, n)
+			} else {
+				equalLeft, diffLeft := areSlicesClose(left[0:n], samplesLeft[5:7], tolerance)
+				equalRight, diffRight := areSlicesClose(right[0:n], samplesRight[5:7], tolerance)
+
+				/*
+				 * If buffers are not equal, report failure.
+				 */
+				if !equalLeft || !equalRight {
+					t.Errorf("Block after seek is not similar. Difference: %v %v", diffLeft, diffRight)
+				}
+
+			}
+
+		}
+
+	}
+
+	buf := &seekableBufferStruct{}
+	w, _ := CreateContainerWriter(buf, CONTAINER_AIFF, 44100, AUDIO_PCM, 8, 1)
+	w.Write([][]float64{samplesLeft})
+	w.Close()
+	size := len(buf.data)
+
+	/*
+	 * Chunks of AIFF files must be padded to an even size.
+	 */
+	if (size % 2) != 0 {
+		t.Errorf("Size of AIFF file with odd number of bytes of sample data should be even, but is %d.", size)
+	} else {
+		f, err := FromBuffer(buf.data)
+
+		/*
+		 * Check if the padding is not taken for a sample.
+		 */
+		if err != nil {
+			msg := err.Error()
+			t.Errorf("Failed to read back padded file: %s", msg)
+		} else {
+			c, _ := f.Channel(0)
+			samples := c.Floats()
+			numSamples := len(samples)
+
+			/*
+			 * Check the number of samples.
+			 */
+			if numSamples != 7 {
+				t.Errorf("Number of samples should be %d, but is %d.", 7, numSamples)
+			}
+
+		}
+
+	}
+
+	_, err := CreateContainerWriter(buf, 0x0003, 44100, AUDIO_PCM, 16, 1)
+
+	/*
+	 * Creating a writer for an unknown container must fail.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Creating a writer for an unknown container did not return error.")
+	}
+
+}
+
+/*
+ * Perform a test of reading files in layouts other than the ones written,
+ * i. e. little-endian AIFF-C files and big-endian CAF files.
+ */
+func TestContainerLayouts(t *testing.T) {
+
+	/*
+	 * An AIFF-C file with two 16-bit samples in little-endian byte order.
+	 */
+	aifc := []byte{
+		0x46, 0x4f, 0x52, 0x4d, 0x00, 0x00, 0x00, 0x44, 0x41, 0x49, 0x46, 0x43,
+		0x43, 0x4f, 0x4d, 0x4d, 0x00, 0x00, 0x00, 0x18, 0x00, 0x01, 0x00, 0x00,
+		0x00, 0x02, 0x00, 0x10, 0x40, 0x0e, 0xac, 0x44, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x73, 0x6f, 0x77, 0x74, 0x00, 0x00, 0x46, 0x56, 0x45, 0x52,
+		0x00, 0x00, 0x00, 0x04, 0xa2, 0x80, 0x51, 0x40, 0x53, 0x53, 0x4e, 0x44,
+		0x00, 0x00, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x40, 0x00, 0xc0,
+	}
+
+	/*
+	 * A CAF file with two 16-bit samples in big-endian byte order, whose
+	 * data chunk extends to the end of the file.
+	 */
+	caf := []byte{
+		0x63, 0x61, 0x66, 0x66, 0x00, 0x01, 0x00, 0x00, 0x64, 0x65, 0x73, 0x63,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x20, 0x40, 0xe5, 0x88, 0x80,
+		0x00, 0x00, 0x00, 0x00, 0x6c, 0x70, 0x63, 0x6d, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x10, 0x64, 0x61, 0x74, 0x61, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0x40, 0x00, 0xc0, 0x00,
+	}
+
+	buffers := [][]byte{aifc, caf}
+	containers := []uint16{CONTAINER_AIFF, CONTAINER_CAF}
+	expectedSamples := []float64{0.5, -0.5}
+
+	/*
+	 * Read each file.
+	 */
+	for i, buffer := range buffers {
+		f, err := FromBuffer(buffer)
+
+		/*
+		 * Check if file was parsed.
+		 */
+		if err != nil {
+			msg := err.Error()
+			t.Errorf("Failed to parse file %d: %s", i, msg)
+		} else {
+			container := f.Container()
+			sampleRate := f.SampleRate()
+
+			/*
+			 * Check the format of the file.
+			 */
+			if container != containers[i] {
+				t.Errorf("Container of file %d should be %#04x, but is %#04x.", i, containers[i], container)
+			} else if sampleRate != 44100 {
+				t.Errorf("Sample rate of file %d should be %d, but is %d.", i, 44100, sampleRate)
+			}
+
+			c, err := f.Channel(0)
+
+			/*
+			 * Check if channel exists.
+			 */
+			if err != nil {
+				t.Errorf("Channel %d missing in file %d.", 0, i)
+			} else {
+				samples := c.Floats()
+				equal, diff := areSlicesClose(samples, expectedSamples, 1.0e-4)
+
+				/*
+				 * If buffers are not equal, report failure.
+				 */
+				if !equal {
+					t.Errorf("Samples of file %d are not similar. Expected: %v Got: %v Difference: %v", i, expectedSamples, samples, diff)
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+/*
+ * Perform a test of storing markers and loops in a wave file.
+ */
+func TestMarkers(t *testing.T) {
+
+	/*
+	 * Samples of the file, which occupy an odd number of bytes.
+	 */
+	samples := []float64{
+		0.0, 0.25, 0.5, 0.75, 1.0, -0.25, -0.5,
+	}
+
+	/*
+	 * Markers to store in the file.
+	 */
+	markers := []Marker{
+		Marker{Position: 0, Label: "Intro"},
+		Marker{Position: 3, Label: ""},
+		Marker{Position: 5, Label: "Chorus"},
+	}
+
+	f, err := CreateEmpty(44100, AUDIO_PCM, 8, 1)
+
+	/*
+	 * Check if file was created.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Fatalf("Failed to create file: %s", msg)
+	}
+
+	c, _ := f.Channel(0)
+	c.WriteFloats(samples)
+
+	/*
+	 * Add the markers to the file.
+	 */
+	for _, marker := range markers {
+		f.AddMarker(marker.Position, marker.Label)
+	}
+
+	err = f.AddLoop(4, 2)
+
+	/*
+	 * A loop must not end before it starts.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Adding a loop which ends before it starts did not return error.")
+	}
+
+	err = f.AddLoop(2, 6)
+
+	/*
+	 * Check if loop was added.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Fatalf("Failed to add loop: %s", msg)
+	}
+
+	buf, err := f.Bytes()
+
+	/*
+	 * Check if file was serialized.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Fatalf("Failed to serialize file: %s", msg)
+	}
+
+	g, err := FromBuffer(buf)
+
+	/*
+	 * Check if the file can be read back.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Fatalf("Failed to read back file: %s", msg)
+	}
+
+	d, _ := g.Channel(0)
+	result := d.Floats()
+	equal, diff := areSlicesClose(result, samples, 1.0e-2)
+
+	/*
+	 * The marker chunks must not be taken for samples.
+	 */
+	if !equal {
+		t.Errorf("Sample buffers are not similar. Expected: %v Got: %v Difference: %v", samples, result, diff)
+	}
+
+	resultMarkers := g.Markers()
+	numMarkers := len(resultMarkers)
+	expectedNumMarkers := len(markers)
+
+	/*
+	 * Compare the markers.
+	 */
+	if numMarkers != expectedNumMarkers {
+		t.Errorf("Number of markers should be %d, but is %d.", expectedNumMarkers, numMarkers)
+	} else {
+
+		/*
+		 * Compare each marker.
+		 */
+		for i, marker := range markers {
+			resultMarker := resultMarkers[i]
+
+			/*
+			 * Check if marker matches.
+			 */
+			if resultMarker != marker {
+				t.Errorf("Marker %d should be %v, but is %v.", i, marker, resultMarker)
+			}
+
+		}
+
+	}
+
+	loops := g.Loops()
+	numLoops := len(loops)
+
+	/*
+	 * Compare the loops.
+	 */
+	if numLoops != 1 {
+		t.Errorf("Number of loops should be %d, but is %d.", 1, numLoops)
+	} else if loops[0].Start != 2 || loops[0].End != 6 {
+		t.Errorf("Loop should span frames %d to %d, but spans frames %d to %d.", 2, 6, loops[0].Start, loops[0].End)
+	}
+
+	again, err := g.Bytes()
+
+	/*
+	 * Serializing the file again must reproduce it.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Failed to serialize file again: %s", msg)
+	} else if !areSlicesEqual(again, buf) {
+		t.Errorf("%s", "Serializing the file again does not reproduce it.")
+	}
+
+}
+
+/*
+ * Encodes a short mono file with 16-bit samples in a certain container.
+ *
+ * RIFF wave files are encoded without reserving space for a data size chunk,
+ * so that their headers occupy exactly 44 bytes.
+ */
+func createShortFile(tb testing.TB, container uint16) []byte {
+	samples := []float64{0.0, 0.25, 0.5, -0.5}
+
+	/*
+	 * Decide on the container.
+	 */
+	if container == CONTAINER_RIFF {
+		f, err := CreateEmpty(44100, AUDIO_PCM, 16, 1)
+
+		/*
+		 * Check if file was created.
+		 */
+		if err != nil {
+			msg := err.Error()
+			tb.Fatalf("Failed to create file: %s", msg)
+		}
+
+		c, _ := f.Channel(0)
+		c.WriteFloats(samples)
+		buf, err := f.Bytes()
+
+		/*
+		 * Check if file was serialized.
+		 */
+		if err != nil {
+			msg := err.Error()
+			tb.Fatalf("Failed to serialize file: %s", msg)
+		}
+
+		return buf
+	} else {
+		buf := &seekableBufferStruct{}
+		w, err := CreateContainerWriter(buf, container, 44100, AUDIO_PCM, 16, 1)
+
+		/*
+		 * Check if writer was created.
+		 */
+		if err != nil {
+			msg := err.Error()
+			tb.Fatalf("Failed to create writer for container %#04x: %s", container, msg)
+		}
+
+		w.Write([][]float64{samples})
+		err = w.Close()
+
+		/*
+		 * Check if writer was closed.
+		 */
+		if err != nil {
+			msg := err.Error()
+			tb.Fatalf("Failed to close writer: %s", msg)
+		}
+
+		return buf.data
+	}
+
+}
+
+/*
+ * Creates files with headers which are damaged, announce more data than they
+ * hold or use features rarely found in files, along with their names.
+ */
+func createMalformedFiles(tb testing.TB) ([]string, [][]byte) {
+	riff := createShortFile(tb, CONTAINER_RIFF)
+	aiff := createShortFile(tb, CONTAINER_AIFF)
+	caf := createShortFile(tb, CONTAINER_CAF)
+	numRiff := len(riff)
+	noChannels := append([]byte{}, riff...)
+	binary.LittleEndian.PutUint16(noChannels[22:], 0)
+	binary.LittleEndian.PutUint32(noChannels[28:], 0)
+	binary.LittleEndian.PutUint16(noChannels[32:], 0)
+	oversized := append([]byte{}, riff...)
+	binary.LittleEndian.PutUint32(oversized[40:], math.MaxUint32)
+	truncated := riff[0 : numRiff-4]
+	extended := append([]byte{}, riff[0:36]...)
+	extended = append(extended, 0, 0)
+	extended = append(extended, riff[36:]...)
+	numExtended := len(extended)
+	binary.LittleEndian.PutUint32(extended[4:], uint32(numExtended-8))
+	binary.LittleEndian.PutUint32(extended[16:], 18)
+	aiffNoChannels := append([]byte{}, aiff...)
+	binary.BigEndian.PutUint16(aiffNoChannels[20:], 0)
+	cafNoChannels := append([]byte{}, caf...)
+	binary.BigEndian.PutUint32(cafNoChannels[36:], 0)
+	binary.BigEndian.PutUint32(cafNoChannels[44:], 0)
+	cafOversized := append([]byte{}, caf...)
+	binary.BigEndian.PutUint64(cafOversized[56:], math.MaxInt64)
+
+	/*
+	 * Names of the files.
+	 */
+	names := []string{
+		"riff_no_channels",
+		"riff_oversized_data",
+		"riff_truncated",
+		"riff_extended_format",
+		"aiff_no_channels",
+		"caf_no_channels",
+		"caf_oversized_data",
+	}
+
+	/*
+	 * Contents of the files.
+	 */
+	files := [][]byte{
+		noChannels,
+		oversized,
+		truncated,
+		extended,
+		aiffNoChannels,
+		cafNoChannels,
+		cafOversized,
+	}
+
+	return names, files
+}
+
+/*
+ * Verify that malformed files are either rejected or read up to the end of
+ * their sample data, instead of crashing the parser or allocating memory for
+ * data they do not hold.
+ */
+func TestMalformedFiles(t *testing.T) {
+	names, files := createMalformedFiles(t)
+
+	/*
+	 * Number of samples expected in each file, or -1 if it should be
+	 * rejected.
+	 */
+	expectedLengths := []int{
+		-1,
+		4,
+		-1,
+		4,
+		-1,
+		-1,
+		4,
+	}
+
+	/*
+	 * Read each file.
+	 */
+	for i, name := range names {
+		file := files[i]
+		expectedLength := expectedLengths[i]
+		f, err := FromBuffer(file)
+		reader := bytes.NewReader(file)
+		r, errReader := CreateReader(reader)
+
+		/*
+		 * Check if the file was rejected or read as expected.
+		 */
+		if expectedLength < 0 {
+
+			/*
+			 * The file must be rejected.
+			 */
+			if err == nil {
+				t.Errorf("%s: Reading file should fail.", name)
+			} else if errReader == nil {
+				t.Errorf("%s: Creating reader should fail.", name)
+			}
+
+		} else if err != nil {
+			msg := err.Error()
+			t.Errorf("%s: Failed to read file: %s", name, msg)
+		} else if errReader != nil {
+			msg := errReader.Error()
+			t.Errorf("%s: Failed to create reader: %s", name, msg)
+		} else {
+			c, _ := f.Channel(0)
+			samples := c.Floats()
+			numSamples := len(samples)
+			length := r.Length()
+			expectedLength64 := uint64(expectedLength)
+
+			/*
+			 * Only the samples present in the file may be read.
+			 */
+			if numSamples != expectedLength {
+				t.Errorf("%s: File should hold %d samples, but holds %d.", name, expectedLength, numSamples)
+			} else if length != expectedLength64 {
+				t.Errorf("%s: Reader should report %d frames, but reports %d.", name, expectedLength64, length)
+			}
+
+		}
+
+	}
+
+}
@@ -6,6 +6,11 @@
 function Globals() {
 	this.cgi = '/cgi-bin/dsp';
 	this.mimeDefault = 'application/x-www-form-urlencoded';
+	this.playerTimer = null;
+	this.processTimer = null;
+	this.upload = '/cgi-bin/dsp-upload';
+	this.tunerStrings = false;
+	this.tunerStrobe = false;
 	this.unitTypes = [];
 }
 
@@ -238,6 +243,59 @@ function Ajax() {
 		xhr.send(data);
 	};
 
+	/*
+	 * Sends an Ajax request to the server, which returns binary data.
+	 *
+	 * Parameters:
+	 * - method (string): The request method (e. g. 'GET', 'POST', ...).
+	 * - url (string): The request URL.
+	 * - data (string): Data to be passed along the request (e. g. form data).
+	 * - callback (function): The function to be called with the response
+	 *	                  (as a blob) when it is returned from the server.
+	 * - block (boolean): Whether the site should be blocked.
+	 *
+	 * Returns: Nothing.
+	 */
+	this.requestBlob = function(method, url, data, callback, block) {
+		const xhr = new XMLHttpRequest();
+
+		/*
+		 * Event handler for ReadyStateChange event.
+		 */
+		xhr.onreadystatechange = function() {
+			helper.blockSite(block);
+
+			/*
+			 * If we got a response, pass it to the callback
+			 * function.
+			 */
+			if (this.readyState === 4) {
+
+				/*
+				 * If we blocked the site on the request,
+				 * unblock it on the response.
+				 */
+				if (block) {
+					helper.blockSite(false);
+				}
+
+				/*
+				 * Check if callback is registered.
+				 */
+				if (callback !== null) {
+					const content = xhr.response;
+					callback(content);
+				}
+
+			}
+
+		};
+
+		xhr.open(method, url, true);
+		xhr.responseType = 'blob';
+		xhr.send(data);
+	};
+
 }
 
 /*
@@ -324,30 +382,77 @@ function UI() {
 	 */
 	const strings = {
 		'add': 'Add',
+		'add_channel': 'Add channel',
+		'accents': 'Accents',
 		'add_unit': 'Add unit',
+		'algorithmic_reverb': 'Algorithmic reverb',
+		'amp_model': 'Amp model',
+		'attack_time': 'Attack time',
 		'auto_wah': 'Auto wah',
 		'auto_yoy': 'Auto yoy',
+		'aux_return': 'Aux return',
+		'aux_send': 'Aux send',
 		'azimuth': 'Azimuth',
+		'backing_track': 'Backing track',
+		'balance': 'Balance',
+		'band_1_frequency': 'Band 1 frequency',
+		'band_1_gain': 'Band 1 gain',
+		'band_1_q': 'Band 1 Q',
+		'band_2_frequency': 'Band 2 frequency',
+		'band_2_gain': 'Band 2 gain',
+		'band_2_q': 'Band 2 Q',
+		'band_3_frequency': 'Band 3 frequency',
+		'band_3_gain': 'Band 3 gain',
+		'band_3_q': 'Band 3 Q',
+		'band_4_frequency': 'Band 4 frequency',
+		'band_4_gain': 'Band 4 gain',
+		'band_4_q': 'Band 4 Q',
+		'band_5_frequency': 'Band 5 frequency',
+		'band_5_gain': 'Band 5 gain',
+		'band_5_q': 'Band 5 Q',
+		'band_6_frequency': 'Band 6 frequency',
+		'band_6_gain': 'Band 6 gain',
+		'band_6_q': 'Band 6 Q',
+		'band_7_frequency': 'Band 7 frequency',
+		'band_7_gain': 'Band 7 gain',
+		'band_7_q': 'Band 7 Q',
+		'band_8_frequency': 'Band 8 frequency',
+		'band_8_gain': 'Band 8 gain',
+		'band_8_q': 'Band 8 Q',
 		'bandpass': 'Bandpass',
+		'bands': 'Bands',
+		'bass': 'Bass',
 		'batch_processing': 'Batch processing',
 		'beats_per_period': 'Beats per period',
 		'bias': 'Bias',
+		'blend': 'Blend',
 		'boost': 'Boost',
 		'bpm': 'BPM',
 		'bypass': 'Bypass',
 		'cabinet': 'Cabinet',
+		'cancel': 'Cancel',
 		'cents': 'Cents',
 		'channel': 'Channel',
+		'channel_color': 'Channel color',
+		'channel_name': 'Channel name',
 		'chorus': 'Chorus',
+		'click': 'Click',
+		'composite': 'Composite',
 		'compressor': 'Compressor',
+		'convolution_reverb': 'Convolution reverb',
+		'count_in': 'Count-in',
+		'decay': 'Decay',
+		'decay_trim': 'Decay trim',
 		'delay': 'Delay',
 		'delay_time': 'Delay time',
 		'depth': 'Depth',
+		'direction': 'Direction',
 		'distance': 'Distance',
 		'distortion': 'Distortion',
 		'drive': 'Drive',
 		'dsp_load': 'DSP load',
 		'enabled': 'Enabled',
+		'envelope_filter': 'Envelope filter',
 		'excess': 'Excess',
 		'feedback': 'Feedback',
 		'file_transfer_instructions': 'Right-click here and select \'Save link / target as ...\' to save current patch. Drop patch file here to restore patch.',
@@ -360,20 +465,35 @@ function UI() {
 		'filter_7': 'Filter 7',
 		'filter_8': 'Filter 8',
 		'filter_order': 'Filter order',
+		'filter_type': 'Filter type',
 		'flanger': 'Flanger',
 		'follow': 'Follow',
 		'frames_per_period': 'Frames per period',
 		'frequency': 'Frequency',
 		'frequency_1': 'Frequency 1',
 		'frequency_2': 'Frequency 2',
+		'from_aux_bus': 'From: Aux bus',
 		'from_input': 'From: Input',
 		'fuzz': 'Fuzz',
 		'gain': 'Gain',
 		'gain_limit': 'Gain limit',
+		'harmony': 'Harmony',
+		'harmony_interval': 'Harmony interval',
+		'harmony_level': 'Harmony level',
 		'high': 'High',
+		'high_cut': 'High cut',
+		'high_cut_frequency': 'High cut frequency',
+		'high_shelf_frequency': 'High shelf frequency',
+		'high_shelf_gain': 'High shelf gain',
 		'hold_time': 'Hold time',
+		'impulse_response': 'Impulse response',
 		'input_amplitude': 'Input amplitude',
 		'input_gain': 'Input gain',
+		'input_trim': 'Input trim',
+		'invert_left': 'Invert left',
+		'invert_right': 'Invert right',
+		'knee': 'Knee',
+		'last_xrun': 'last',
 		'latency': 'Latency',
 		'level': 'Level',
 		'level_1': 'Level 1',
@@ -390,47 +510,138 @@ function UI() {
 		'level_octave_down_first': 'Level octave down (I)',
 		'level_octave_down_second': 'Level octave down (II)',
 		'level_octave_up': 'Level octave up',
+		'lookahead': 'Lookahead',
 		'low': 'Low',
+		'low_cut': 'Low cut',
+		'low_cut_frequency': 'Low cut frequency',
+		'low_shelf_frequency': 'Low shelf frequency',
+		'low_shelf_gain': 'Low shelf gain',
+		'makeup': 'Makeup',
+		'makeup_gain': 'Makeup gain',
 		'master': 'Master',
+		'master_section': 'Master section',
 		'metronome': 'Metronome',
+		'mid_gain': 'Mid gain',
+		'mid_high': 'Mid high',
+		'mid_low': 'Mid low',
 		'middle': 'Middle',
+		'minimum_level': 'Minimum level',
 		'mix': 'Mix',
+		'mode': 'Mode',
+		'model': 'Model',
+		'mono': 'Mono',
 		'move_down': 'Move down',
 		'move_up': 'Move up',
+		'multitap_delay': 'Multi-tap delay',
+		'mute': 'Mute',
 		'noise_gate': 'Noise gate',
+		'notation': 'Note names',
 		'note': 'Note',
 		'octaver': 'Octaver',
 		'overdrive': 'Overdrive',
+		'output_level': 'Output level',
 		'oversampling': 'Oversampling',
+		'parametric_eq': 'Parametric EQ',
 		'persistence': 'Persistence',
 		'phase': 'Phase',
 		'phaser': 'Phaser',
+		'pitch_shifter': 'Pitch shifter',
+		'play': 'Play',
+		'plugin': 'Plugin',
 		'polarity': 'Polarity',
 		'power_amp': 'Power amp',
+		'pre_delay': 'Pre-delay',
 		'presence': 'Presence',
+		'preview_instructions': 'Drop a wave file here to hear it through this channel.',
 		'process_now': 'Process now',
+		'ratio': 'Ratio',
+		'reference': 'Reference',
+		'release_time': 'Release time',
 		'remove': 'Remove',
+		'remove_channel': 'Remove channel',
+		'remaining': 'remaining',
+		'rendering': 'Rendering',
+		'rendering_cancelled': 'Rendering cancelled.',
+		'rendering_failed': 'Rendering failed',
+		'rendering_finished': 'Rendering finished.',
+		'resonance': 'Resonance',
 		'reverb': 'Reverb',
+		'rewind': 'Rewind',
 		'ring_modulator': 'Ring modulator',
+		'semitones': 'Semitones',
+		'sensitivity': 'Sensitivity',
+		'side_gain': 'Side gain',
+		'side_high': 'Side high',
+		'side_low': 'Side low',
+		'sidechain_cutoff': 'Sidechain cutoff',
 		'signal_amplitude': 'Signal amplitude',
 		'signal_frequency': 'Signal frequency',
 		'signal_gain': 'Signal gain',
 		'signal_generator': 'Signal generator',
 		'signal_levels': 'Signal levels',
 		'signal_type': 'Signal type',
+		'slow_gear': 'Slow gear',
+		'soft_clip': 'Soft clip',
+		'solo': 'Solo',
 		'spatializer': 'Spatializer',
 		'speed': 'Speed',
+		'stages': 'Stages',
+		'start_count_in': 'Start',
+		'stereo': 'Stereo',
+		'stop': 'Stop',
+		'strings': 'Strings',
+		'strobe': 'Strobe',
+		'studio_compressor': 'Studio compressor',
+		'sub_octaver': 'Sub-octaver',
+		'subdivision': 'Subdivision',
+		'subdivision_eighths': 'Eighths',
+		'subdivision_quarters': 'Quarters',
+		'subdivision_sixteenths': 'Sixteenths',
+		'subdivision_triplets': 'Triplets',
+		'sweep_end': 'Sweep end',
+		'sweep_start': 'Sweep start',
+		'sweep_time': 'Sweep time',
+		'swell_time': 'Swell time',
+		'sync': 'Sync',
+		'tap_1_feedback': 'Tap 1 feedback',
+		'tap_1_level': 'Tap 1 level',
+		'tap_1_time': 'Tap 1 time',
+		'tap_2_feedback': 'Tap 2 feedback',
+		'tap_2_level': 'Tap 2 level',
+		'tap_2_time': 'Tap 2 time',
+		'tap_3_feedback': 'Tap 3 feedback',
+		'tap_3_level': 'Tap 3 level',
+		'tap_3_time': 'Tap 3 time',
+		'tap_4_feedback': 'Tap 4 feedback',
+		'tap_4_level': 'Tap 4 level',
+		'tap_4_time': 'Tap 4 time',
+		'tap_tempo': 'Tap',
 		'target_level': 'Target level',
+		'temperament': 'Temperament',
+		'threshold': 'Threshold',
 		'threshold_close': 'Threshold close',
 		'threshold_open': 'Threshold open',
+		'tick_decay': 'Tick decay',
+		'tick_pitch': 'Tick pitch',
 		'tick_sound': 'Tick sound',
+		'tock_decay': 'Tock decay',
+		'tock_pitch': 'Tock pitch',
 		'tock_sound': 'Tock sound',
+		'to_aux_return': 'To: Aux return',
 		'to_output': 'To: Output',
+		'tone': 'Tone',
 		'tone_stack': 'Tone stack',
+		'track_instructions': 'Drop a wave file here to load it as backing track.',
+		'track_not_loaded': 'No track loaded.',
+		'treble': 'Treble',
 		'tremolo': 'Tremolo',
 		'tuner': 'Tuner',
+		'tuning': 'Tuning',
 		'type': 'Type',
-		'valve': 'Valve'
+		'valve': 'Valve',
+		'waveform': 'Waveform',
+		'width': 'Width',
+		'xruns': 'Xruns'
 	};
 
 	/*
@@ -781,6 +992,15 @@ function UI() {
 		const unitType = unitTypes[unitTypeId];
 		const unitTypeString = ui.getString(unitType);
 		const bypassActive = description.Bypass;
+		const label = description.Label;
+		let unitTitle = unitTypeString;
+
+		/*
+		 * Show the label chosen by the user along with the unit type.
+		 */
+		if (label) {
+			unitTitle = label + ' (' + unitTypeString + ')';
+		}
 
 		/*
 		 * Buttons for this unit.
@@ -808,7 +1028,7 @@ function UI() {
 		 * Parameters for the unit UI element.
 		 */
 		const paramsUnit = {
-			'type': unitTypeString,
+			'type': unitTitle,
 			'buttons': buttons
 		};
 
@@ -1102,14 +1322,101 @@ function UI() {
 
 		}
 
+		const inputTrim = description.InputTrim;
+		const outputLevel = description.OutputLevel;
+		const inputTrimLabel = ui.getString('input_trim');
+		const outputLevelLabel = ui.getString('output_level');
+
+		/*
+		 * Parameters for the input trim knob.
+		 */
+		const inputTrimParams = {
+			'label': inputTrimLabel,
+			'physicalUnit': 'dB',
+			'valueMin': -24,
+			'valueMax': 24,
+			'valueDefault': inputTrim,
+			'valueWidth': 150,
+			'valueHeight': 150,
+			'angle': 270,
+			'cursor': false,
+			'colorScheme': 'default',
+			'readonly': false
+		};
+
+		/*
+		 * Parameters for the output level knob.
+		 */
+		const outputLevelParams = {
+			'label': outputLevelLabel,
+			'physicalUnit': 'dB',
+			'valueMin': -24,
+			'valueMax': 24,
+			'valueDefault': outputLevel,
+			'valueWidth': 150,
+			'valueHeight': 150,
+			'angle': 270,
+			'cursor': false,
+			'colorScheme': 'default',
+			'readonly': false
+		};
+
+		const inputTrimKnob = ui.createKnob(inputTrimParams);
+		unit.addControl(inputTrimKnob);
+		const outputLevelKnob = ui.createKnob(outputLevelParams);
+		unit.addControl(outputLevelKnob);
+		const inputTrimKnobNode = inputTrimKnob.node;
+		const outputLevelKnobNode = outputLevelKnob.node;
+		storage.put(inputTrimKnobNode, 'chain', chainId);
+		storage.put(inputTrimKnobNode, 'unit', unitId);
+		storage.put(outputLevelKnobNode, 'chain', chainId);
+		storage.put(outputLevelKnobNode, 'unit', unitId);
+
+		/*
+		 * This is called when the input trim changes.
+		 */
+		const inputTrimHandler = function(knob, value) {
+			const knobNode = knob.node();
+			const chain = storage.get(knobNode, 'chain');
+			const unit = storage.get(knobNode, 'unit');
+			handler.setInputTrim(chain, unit, value);
+		};
+
+		/*
+		 * This is called when the output level changes.
+		 */
+		const outputLevelHandler = function(knob, value) {
+			const knobNode = knob.node();
+			const chain = storage.get(knobNode, 'chain');
+			const unit = storage.get(knobNode, 'unit');
+			handler.setOutputLevel(chain, unit, value);
+		};
+
+		const inputTrimKnobObj = inputTrimKnob.obj;
+		inputTrimKnobObj.addListener(inputTrimHandler);
+		const outputLevelKnobObj = outputLevelKnob.obj;
+		outputLevelKnobObj.addListener(outputLevelHandler);
 		return unit;
 	};
 
 	/*
 	 * Renders a signal chain, given its ID and a chain description returned from the server.
 	 */
-	this.renderSignalChain = function(id, description) {
-		const idString = id.toString();
+	this.renderSignalChain = function(id, description, bus) {
+		const isBus = (bus !== undefined);
+		let labelIdString = id.toString();
+		let labelFromKey = 'from_input';
+		let labelToKey = 'to_output';
+
+		/*
+		 * The signal chain of an aux bus is labeled with the bus ID.
+		 */
+		if (isBus) {
+			labelIdString = bus.toString();
+			labelFromKey = 'from_aux_bus';
+			labelToKey = 'to_aux_return';
+		}
+
 		const chainDiv = document.createElement('div');
 		const beginDiv = document.createElement('div');
 		beginDiv.classList.add('contentdiv');
@@ -1118,13 +1425,110 @@ function UI() {
 		beginHeaderDiv.classList.add('headerdiv');
 		const beginLabelDiv = document.createElement('div');
 		beginLabelDiv.classList.add('labeldiv');
-		const labelFromInput = ui.getString('from_input');
-		const beginLabelText = labelFromInput + ' ' + idString;
+		const labelFrom = ui.getString(labelFromKey);
+		const beginLabelText = labelFrom + ' ' + labelIdString;
 		const beginLabelNode = document.createTextNode(beginLabelText);
 		beginLabelDiv.appendChild(beginLabelNode);
 		beginHeaderDiv.appendChild(beginLabelDiv);
 		beginDiv.appendChild(beginHeaderDiv);
 		chainDiv.appendChild(beginDiv);
+		const endDiv = document.createElement('div');
+
+		/*
+		 * Channels may be given a name and a color.
+		 */
+		if (!isBus) {
+			const name = description.Name;
+			const color = description.Color;
+			const nameLabel = ui.getString('channel_name');
+			const nameInput = document.createElement('input');
+			nameInput.classList.add('textfield');
+			nameInput.classList.add('namefield');
+			nameInput.setAttribute('type', 'text');
+			nameInput.setAttribute('maxlength', '64');
+			nameInput.setAttribute('placeholder', nameLabel);
+			nameInput.setAttribute('title', nameLabel);
+			nameInput.value = name;
+
+			/*
+			 * What happens when the name of the channel is changed.
+			 */
+			nameInput.onchange = function(e) {
+				const value = this.value;
+				handler.setChannelName(id, value);
+			};
+
+			const colorLabel = ui.getString('channel_color');
+			const colorInput = document.createElement('input');
+			colorInput.classList.add('colorinput');
+			colorInput.setAttribute('type', 'color');
+			colorInput.setAttribute('title', colorLabel);
+
+			/*
+			 * Show the color of the channel, if it has one.
+			 */
+			if (color !== '') {
+				colorInput.value = color;
+				beginDiv.style.borderColor = color;
+				endDiv.style.borderColor = color;
+			}
+
+			/*
+			 * What happens when the color of the channel is changed.
+			 */
+			colorInput.onchange = function(e) {
+				const value = this.value;
+				beginDiv.style.borderColor = value;
+				endDiv.style.borderColor = value;
+				handler.setChannelColor(id, value);
+			};
+
+			const removeLabel = ui.getString('remove');
+			const removeChannelLabel = ui.getString('remove_channel');
+
+			/*
+			 * Parameters for the 'remove' button.
+			 */
+			const paramsRemove = {
+				'caption': removeLabel,
+				'active': false
+			};
+
+			const removeButton = ui.createButton(paramsRemove);
+			const removeElem = removeButton.input;
+			removeElem.classList.add('buttonremove');
+			removeElem.setAttribute('title', removeChannelLabel);
+
+			/*
+			 * What happens when the channel is removed.
+			 */
+			removeElem.onclick = function(e) {
+				handler.removeChannel(id);
+			};
+
+			beginHeaderDiv.appendChild(nameInput);
+			beginHeaderDiv.appendChild(colorInput);
+			beginHeaderDiv.appendChild(removeElem);
+			const audioElem = document.createElement('audio');
+			audioElem.classList.add('previewaudio');
+			audioElem.setAttribute('controls', '');
+			const previewAreaDiv = document.createElement('div');
+			previewAreaDiv.classList.add('uploadarea');
+			previewAreaDiv.classList.add('previewarea');
+			storage.put(previewAreaDiv, 'chain', id);
+			storage.put(previewAreaDiv, 'audio', audioElem);
+			previewAreaDiv.addEventListener('dragend', handler.dragLeave);
+			previewAreaDiv.addEventListener('dragenter', handler.dragEnter);
+			previewAreaDiv.addEventListener('dragleave', handler.dragLeave);
+			previewAreaDiv.addEventListener('dragover', handler.absorbEvent);
+			previewAreaDiv.addEventListener('drop', handler.previewChannel);
+			const previewString = ui.getString('preview_instructions');
+			const previewNode = document.createTextNode(previewString);
+			previewAreaDiv.appendChild(previewNode);
+			beginDiv.appendChild(previewAreaDiv);
+			beginDiv.appendChild(audioElem);
+		}
+
 		const units = description.Units;
 		const numUnits = units.length;
 
@@ -1193,15 +1597,14 @@ function UI() {
 		dropDownDiv.appendChild(dropDown.div);
 		dropDownDiv.appendChild(buttonElem);
 		chainDiv.appendChild(dropDownDiv);
-		const endDiv = document.createElement('div');
 		endDiv.classList.add('contentdiv');
 		endDiv.classList.add('iodiv');
 		const endHeaderDiv = document.createElement('div');
 		endHeaderDiv.classList.add('headerdiv');
 		const endLabelDiv = document.createElement('div');
 		endLabelDiv.classList.add('labeldiv');
-		const labelToOutput = ui.getString('to_output');
-		const endLabelText = labelToOutput + ' ' + idString;
+		const labelTo = ui.getString(labelToKey);
+		const endLabelText = labelTo + ' ' + labelIdString;
 		const endLabelNode = document.createTextNode(endLabelText);
 		endLabelDiv.appendChild(endLabelNode);
 		endHeaderDiv.appendChild(endLabelDiv);
@@ -1218,6 +1621,55 @@ function UI() {
 		return chain;
 	}
 
+	/*
+	 * Renders the controls for adding a channel.
+	 */
+	this.renderAddChannel = function() {
+		const labelDropdown = ui.getString('add_channel');
+		const labelButton = ui.getString('add');
+		const labelMono = ui.getString('mono');
+		const labelStereo = ui.getString('stereo');
+
+		/*
+		 * Parameters for the drop down menu.
+		 */
+		const paramsDropDown = {
+			'label': labelDropdown,
+			'options': [labelMono, labelStereo],
+			'selectedIndex': 0
+		};
+
+		const dropDown = ui.createDropDown(paramsDropDown);
+
+		/*
+		 * Parameters for the 'add' button.
+		 */
+		const paramsButton = {
+			'caption': labelButton,
+			'active': false
+		};
+
+		const button = ui.createButton(paramsButton);
+		const buttonElem = button.input;
+		storage.put(buttonElem, 'dropdown', dropDown.input);
+
+		/*
+		 * What happens when we click on the 'add' button.
+		 */
+		buttonElem.onclick = function(e) {
+			const dropdown = storage.get(this, 'dropdown');
+			const stereo = (dropdown.selectedIndex === 1);
+			handler.addChannel(stereo);
+		};
+
+		const addChannelDiv = document.createElement('div');
+		addChannelDiv.classList.add('contentdiv');
+		addChannelDiv.classList.add('addchanneldiv');
+		addChannelDiv.appendChild(dropDown.div);
+		addChannelDiv.appendChild(buttonElem);
+		return addChannelDiv;
+	};
+
 	/*
 	 * Renders the signal chains given a configuration returned from the server.
 	 */
@@ -1240,6 +1692,31 @@ function UI() {
 			elem.appendChild(spacerDiv);
 		}
 
+		const addChannelDiv = this.renderAddChannel();
+		elem.appendChild(addChannelDiv);
+		const addChannelSpacerDiv = document.createElement('div');
+		addChannelSpacerDiv.classList.add('spacerdiv');
+		elem.appendChild(addChannelSpacerDiv);
+		const spatializer = configuration.Spatializer;
+		const buses = spatializer.Buses;
+		const numBuses = buses.length;
+
+		/*
+		 * Iterate over the aux buses. Their chains follow the chains of
+		 * the channels.
+		 */
+		for (let i = 0; i < numBuses; i++) {
+			const bus = buses[i];
+			const chain = bus.Chain;
+			const chainId = numChains + i;
+			const result = this.renderSignalChain(chainId, chain, i);
+			const chainDiv = result.div;
+			elem.append(chainDiv);
+			const spacerDiv = document.createElement('div');
+			spacerDiv.classList.add('spacerdiv');
+			elem.appendChild(spacerDiv);
+		}
+
 	};
 
 	/*
@@ -1490,6 +1967,68 @@ function UI() {
 		noteDiv.innerHTML = noteString;
 	};
 
+	/*
+	 * Updates the strobe display of the tuner based on information returned from the server.
+	 */
+	this.updateTunerStrobe = function(result) {
+		const cents = result.Cents;
+		const phase = result.Phase;
+		let centsString = cents.toFixed(1);
+
+		/*
+		 * Show the sign of positive deviations as well.
+		 */
+		if (cents > 0) {
+			centsString = '+' + centsString;
+		}
+
+		const centsDiv = document.querySelector('.tunerstrobecentsdiv');
+		centsDiv.innerHTML = centsString;
+		const strobeDiv = document.querySelector('.tunerstrobediv');
+		const offset = phase * 24.0;
+		const offsetString = offset.toFixed(1);
+		strobeDiv.style.backgroundPosition = offsetString + 'px 0px';
+	};
+
+	/*
+	 * Updates the display of the strings of the tuner based on information returned from the server.
+	 */
+	this.updateTunerStrings = function(results) {
+		const stringsDiv = document.querySelector('.tunerstringsdiv');
+		helper.clearElement(stringsDiv);
+
+		/*
+		 * Display the deviation of each detected string.
+		 */
+		for (let i = 0; i < results.length; i++) {
+			const result = results[i];
+			const cents = result.Cents;
+			let centsString = cents.toString();
+
+			/*
+			 * Show the sign of positive deviations as well.
+			 */
+			if (cents > 0) {
+				centsString = '+' + centsString;
+			}
+
+			const stringDiv = document.createElement('div');
+			stringDiv.classList.add('tunerstringdiv');
+			const noteDiv = document.createElement('div');
+			noteDiv.classList.add('tunernotediv');
+			const noteNode = document.createTextNode(result.Note);
+			noteDiv.appendChild(noteNode);
+			stringDiv.appendChild(noteDiv);
+			const centsDiv = document.createElement('div');
+			centsDiv.classList.add('tunerfrequencydiv');
+			const centsNode = document.createTextNode(centsString);
+			centsDiv.appendChild(centsNode);
+			stringDiv.appendChild(centsDiv);
+			stringsDiv.appendChild(stringDiv);
+		}
+
+	};
+
 	/*
 	 * Renders the tuner given a configuration returned from the server.
 	 */
@@ -1625,7 +2164,16 @@ function UI() {
 				if (value === '- NONE -') {
 					value = '-1';
 				} else {
-					const intervalNew = window.setInterval(callback, 250);
+					let period = 250;
+
+					/*
+					 * The strobe display needs faster updates.
+					 */
+					if (globals.tunerStrobe) {
+						period = 50;
+					}
+
+					const intervalNew = window.setInterval(callback, period);
 					storage.put(this, 'interval', intervalNew);
 				}
 
@@ -1641,78 +2189,325 @@ function UI() {
 			dropDownChannelElem.onchange(null);
 			const dropDownChannelDiv = dropDownChannel.div;
 			channelRow.appendChild(dropDownChannelDiv);
-			controlsDiv.appendChild(channelRow);
+			const muteActive = tunerConfiguration.Mute;
+			const labelMute = ui.getString('mute');
 
 			/*
-			 * Create unit object.
+			 * Parameters for the mute button.
 			 */
-			const unit = {
-				'controls': controlsDiv,
-				'expanded': false
+			const paramsMute = {
+				caption: labelMute,
+				active: muteActive
 			};
 
+			const muteButton = ui.createButton(paramsMute);
+			const muteButtonElem = muteButton.input;
+			storage.put(muteButtonElem, 'active', muteActive);
+
 			/*
-			 * Expands or collapses a unit.
+			 * This is called when the user clicks on the 'mute' button of the tuner.
 			 */
-			unit.setExpanded = function(value) {
-				const controlsDiv = this.controls;
-				let displayValue = '';
+			muteButtonElem.onclick = function(e) {
+				const active = !storage.get(this, 'active');
 
 				/*
-				 * Check whether we should expand or collapse the unit.
+				 * Check whether the control should be active.
 				 */
-				if (value) {
-					displayValue = 'block';
+				if (active) {
+					this.classList.remove('buttonnormal');
+					this.classList.add('buttonactive');
 				} else {
-					displayValue = 'none';
+					this.classList.remove('buttonactive');
+					this.classList.add('buttonnormal');
 				}
 
-				controlsDiv.style.display = displayValue;
-				this.expanded = value;
+				storage.put(this, 'active', active);
+				handler.setTunerValue('mute', active);
 			};
 
+			channelRow.appendChild(muteButtonElem);
+			controlsDiv.appendChild(channelRow);
+			const referenceString = ui.getString('reference');
+
 			/*
-			 * Returns whether a unit is expanded.
+			 * Parameters for the reference pitch knob.
 			 */
-			unit.getExpanded = function() {
-				return this.expanded;
+			const referenceParams = {
+				'label': referenceString,
+				'physicalUnit': 'Hz',
+				'valueMin': 400,
+				'valueMax': 480,
+				'valueDefault': tunerConfiguration.Reference,
+				'valueWidth': 150,
+				'valueHeight': 150,
+				'angle': 270,
+				'cursor': true,
+				'colorScheme': 'green',
+				'readonly': false
 			};
 
+			const referenceKnob = ui.createKnob(referenceParams);
+			const referenceKnobDiv = referenceKnob.div;
+			controlsDiv.appendChild(referenceKnobDiv);
+
 			/*
-			 * Toggles a unit between expanded and collapsed state.
+			 * This gets executed when the reference pitch changes.
 			 */
-			unit.toggleExpanded = function() {
-				const state = this.getExpanded();
-				this.setExpanded(!state);
+			const referenceHandler = function(knob, value) {
+				handler.setTunerValue('reference', value);
 			};
 
+			const referenceKnobObj = referenceKnob.obj;
+			referenceKnobObj.addListener(referenceHandler);
+			const temperamentRow = document.createElement('div');
+			const labelTemperament = ui.getString('temperament');
+			const temperaments = tunerConfiguration.Temperaments;
+			const temperamentIdx = temperaments.indexOf(tunerConfiguration.Temperament);
+
 			/*
-			 * This is called when a user clicks on the label div.
+			 * Parameters for the temperament drop down menu.
 			 */
-			labelDiv.onclick = function(e) {
-				const unit = storage.get(this, 'unit');
-				unit.toggleExpanded();
-			}
+			const paramsTemperament = {
+				'label': labelTemperament,
+				'options': temperaments,
+				'selectedIndex': temperamentIdx
+			};
 
-			storage.put(labelDiv, 'unit', unit);
-		}
+			const dropDownTemperament = ui.createDropDown(paramsTemperament);
+			const dropDownTemperamentElem = dropDownTemperament.input;
 
-	}
+			/*
+			 * This is called when the temperament changes.
+			 */
+			dropDownTemperamentElem.onchange = function(e) {
+				const idx = this.selectedIndex;
+				const option = this.options[idx];
+				const value = option.text;
+				handler.setTunerValue('temperament', value);
+			};
 
-	/*
-	 * Renders the spatializer given a configuration returned from the server.
-	 */
-	this.renderSpatializer = function(configuration) {
-		const spatializer = configuration.Spatializer;
-		const channels = spatializer.Channels;
-		const numChannels = channels.length;
-		const elem = document.getElementById('spatializer');
-		helper.clearElement(elem);
-		const unitDiv = document.createElement('div');
-		unitDiv.classList.add('contentdiv');
-		unitDiv.classList.add('masterunitdiv');
-		const headerDiv = document.createElement('div');
-		const labelDiv = document.createElement('div');
+			const dropDownTemperamentDiv = dropDownTemperament.div;
+			temperamentRow.appendChild(dropDownTemperamentDiv);
+			controlsDiv.appendChild(temperamentRow);
+			const tuningRow = document.createElement('div');
+			const labelTuning = ui.getString('tuning');
+			const tunings = tunerConfiguration.Tunings;
+			const tuningIdx = tunings.indexOf(tunerConfiguration.Tuning);
+
+			/*
+			 * Parameters for the tuning drop down menu.
+			 */
+			const paramsTuning = {
+				'label': labelTuning,
+				'options': tunings,
+				'selectedIndex': tuningIdx
+			};
+
+			const dropDownTuning = ui.createDropDown(paramsTuning);
+			const dropDownTuningElem = dropDownTuning.input;
+
+			/*
+			 * This is called when the tuning changes.
+			 */
+			dropDownTuningElem.onchange = function(e) {
+				const idx = this.selectedIndex;
+				const option = this.options[idx];
+				const value = option.text;
+				handler.setTunerValue('tuning', value);
+			};
+
+			const dropDownTuningDiv = dropDownTuning.div;
+			tuningRow.appendChild(dropDownTuningDiv);
+			controlsDiv.appendChild(tuningRow);
+			const notationRow = document.createElement('div');
+			const labelNotation = ui.getString('notation');
+			const notations = tunerConfiguration.Notations;
+			const notationIdx = notations.indexOf(tunerConfiguration.Notation);
+
+			/*
+			 * Parameters for the notation drop down menu.
+			 */
+			const paramsNotation = {
+				'label': labelNotation,
+				'options': notations,
+				'selectedIndex': notationIdx
+			};
+
+			const dropDownNotation = ui.createDropDown(paramsNotation);
+			const dropDownNotationElem = dropDownNotation.input;
+
+			/*
+			 * This is called when the notation changes.
+			 */
+			dropDownNotationElem.onchange = function(e) {
+				const idx = this.selectedIndex;
+				const option = this.options[idx];
+				const value = option.text;
+				handler.setTunerValue('notation', value);
+			};
+
+			const dropDownNotationDiv = dropDownNotation.div;
+			notationRow.appendChild(dropDownNotationDiv);
+			controlsDiv.appendChild(notationRow);
+			const stringsRow = document.createElement('div');
+			const stringsActive = globals.tunerStrings;
+			const labelStrings = ui.getString('strings');
+
+			/*
+			 * Parameters for the strings button.
+			 */
+			const paramsStrings = {
+				caption: labelStrings,
+				active: stringsActive
+			};
+
+			const stringsButton = ui.createButton(paramsStrings);
+			const stringsButtonElem = stringsButton.input;
+
+			/*
+			 * This is called when the user clicks on the 'strings' button of the tuner.
+			 */
+			stringsButtonElem.onclick = function(e) {
+				const active = !globals.tunerStrings;
+
+				/*
+				 * Check whether the control should be active.
+				 */
+				if (active) {
+					this.classList.remove('buttonnormal');
+					this.classList.add('buttonactive');
+				} else {
+					this.classList.remove('buttonactive');
+					this.classList.add('buttonnormal');
+					const stringsDiv = document.querySelector('.tunerstringsdiv');
+					helper.clearElement(stringsDiv);
+				}
+
+				globals.tunerStrings = active;
+			};
+
+			stringsRow.appendChild(stringsButtonElem);
+			const stringsDiv = document.createElement('div');
+			stringsDiv.classList.add('tunerstringsdiv');
+			stringsRow.appendChild(stringsDiv);
+			controlsDiv.appendChild(stringsRow);
+			const strobeRow = document.createElement('div');
+			const strobeActive = globals.tunerStrobe;
+			const labelStrobe = ui.getString('strobe');
+
+			/*
+			 * Parameters for the strobe button.
+			 */
+			const paramsStrobe = {
+				caption: labelStrobe,
+				active: strobeActive
+			};
+
+			const strobeButton = ui.createButton(paramsStrobe);
+			const strobeButtonElem = strobeButton.input;
+			storage.put(strobeButtonElem, 'channel', dropDownChannelElem);
+
+			/*
+			 * This is called when the user clicks on the 'strobe' button of the tuner.
+			 */
+			strobeButtonElem.onclick = function(e) {
+				const active = !globals.tunerStrobe;
+
+				/*
+				 * Check whether the control should be active.
+				 */
+				if (active) {
+					this.classList.remove('buttonnormal');
+					this.classList.add('buttonactive');
+				} else {
+					this.classList.remove('buttonactive');
+					this.classList.add('buttonnormal');
+				}
+
+				globals.tunerStrobe = active;
+				const channelElem = storage.get(this, 'channel');
+				channelElem.onchange(null);
+			};
+
+			strobeRow.appendChild(strobeButtonElem);
+			const strobeDiv = document.createElement('div');
+			strobeDiv.classList.add('tunerstrobediv');
+			strobeRow.appendChild(strobeDiv);
+			const strobeCentsDiv = document.createElement('div');
+			strobeCentsDiv.classList.add('tunerstrobecentsdiv');
+			strobeRow.appendChild(strobeCentsDiv);
+			controlsDiv.appendChild(strobeRow);
+
+			/*
+			 * Create unit object.
+			 */
+			const unit = {
+				'controls': controlsDiv,
+				'expanded': false
+			};
+
+			/*
+			 * Expands or collapses a unit.
+			 */
+			unit.setExpanded = function(value) {
+				const controlsDiv = this.controls;
+				let displayValue = '';
+
+				/*
+				 * Check whether we should expand or collapse the unit.
+				 */
+				if (value) {
+					displayValue = 'block';
+				} else {
+					displayValue = 'none';
+				}
+
+				controlsDiv.style.display = displayValue;
+				this.expanded = value;
+			};
+
+			/*
+			 * Returns whether a unit is expanded.
+			 */
+			unit.getExpanded = function() {
+				return this.expanded;
+			};
+
+			/*
+			 * Toggles a unit between expanded and collapsed state.
+			 */
+			unit.toggleExpanded = function() {
+				const state = this.getExpanded();
+				this.setExpanded(!state);
+			};
+
+			/*
+			 * This is called when a user clicks on the label div.
+			 */
+			labelDiv.onclick = function(e) {
+				const unit = storage.get(this, 'unit');
+				unit.toggleExpanded();
+			}
+
+			storage.put(labelDiv, 'unit', unit);
+		}
+
+	}
+
+	/*
+	 * Renders the spatializer given a configuration returned from the server.
+	 */
+	this.renderSpatializer = function(configuration) {
+		const spatializer = configuration.Spatializer;
+		const channels = spatializer.Channels;
+		const numChannels = channels.length;
+		const elem = document.getElementById('spatializer');
+		helper.clearElement(elem);
+		const unitDiv = document.createElement('div');
+		unitDiv.classList.add('contentdiv');
+		unitDiv.classList.add('masterunitdiv');
+		const headerDiv = document.createElement('div');
+		const labelDiv = document.createElement('div');
 		labelDiv.classList.add('labeldiv');
 		labelDiv.classList.add('active');
 		labelDiv.classList.add('io');
@@ -1847,6 +2642,187 @@ function UI() {
 			distanceKnobObj.addListener(distanceHandler);
 			const levelKnobObj = levelKnob.obj;
 			levelKnobObj.addListener(levelHandler);
+			const muteString = ui.getString('mute');
+			const muteLabel = muteString + ' ' + iString;
+			const mute = channel.Mute;
+
+			/*
+			 * Parameters for the mute button.
+			 */
+			const paramsMute = {
+				caption: muteLabel,
+				active: mute
+			};
+
+			const muteButton = ui.createButton(paramsMute);
+			const muteButtonElem = muteButton.input;
+			storage.put(muteButtonElem, 'channel', i);
+			storage.put(muteButtonElem, 'active', mute);
+
+			/*
+			 * This is called when the user clicks on the 'mute' button of a channel.
+			 */
+			muteButtonElem.onclick = function(e) {
+				const channel = storage.get(this, 'channel');
+				const active = !storage.get(this, 'active');
+
+				/*
+				 * Check whether the control should be active.
+				 */
+				if (active) {
+					this.classList.remove('buttonnormal');
+					this.classList.add('buttonactive');
+				} else {
+					this.classList.remove('buttonactive');
+					this.classList.add('buttonnormal');
+				}
+
+				storage.put(this, 'active', active);
+				handler.setMute(channel, active);
+			};
+
+			controlsDiv.append(muteButtonElem);
+			const soloString = ui.getString('solo');
+			const soloLabel = soloString + ' ' + iString;
+			const solo = channel.Solo;
+
+			/*
+			 * Parameters for the solo button.
+			 */
+			const paramsSolo = {
+				caption: soloLabel,
+				active: solo
+			};
+
+			const soloButton = ui.createButton(paramsSolo);
+			const soloButtonElem = soloButton.input;
+			storage.put(soloButtonElem, 'channel', i);
+			storage.put(soloButtonElem, 'active', solo);
+
+			/*
+			 * This is called when the user clicks on the 'solo' button of a channel.
+			 */
+			soloButtonElem.onclick = function(e) {
+				const channel = storage.get(this, 'channel');
+				const active = !storage.get(this, 'active');
+
+				/*
+				 * Check whether the control should be active.
+				 */
+				if (active) {
+					this.classList.remove('buttonnormal');
+					this.classList.add('buttonactive');
+				} else {
+					this.classList.remove('buttonactive');
+					this.classList.add('buttonnormal');
+				}
+
+				storage.put(this, 'active', active);
+				handler.setSolo(channel, active);
+			};
+
+			controlsDiv.append(soloButtonElem);
+			const sends = channel.Sends;
+			const numSends = sends.length;
+
+			/*
+			 * Iterate over the aux sends of this channel.
+			 */
+			for (let j = 0; j < numSends; j++) {
+				const jString = j.toString();
+				const send = 100 * sends[j];
+				const sendString = ui.getString('aux_send');
+				const sendLabel = sendString + ' ' + jString + ' / ' + iString;
+
+				/*
+				 * Parameters for the send knob.
+				 */
+				const sendParams = {
+					'label': sendLabel,
+					'physicalUnit': '%',
+					'valueMin': 0,
+					'valueMax': 100,
+					'valueDefault': send,
+					'valueWidth': 150,
+					'valueHeight': 150,
+					'angle': 270,
+					'cursor': false,
+					'colorScheme': 'blue',
+					'readonly': false
+				};
+
+				const sendKnob = ui.createKnob(sendParams);
+				const sendKnobDiv = sendKnob.div;
+				controlsDiv.append(sendKnobDiv);
+				const sendKnobNode = sendKnob.node;
+				storage.put(sendKnobNode, 'channel', i);
+				storage.put(sendKnobNode, 'bus', j);
+
+				/*
+				 * This gets executed when the send value changes.
+				 */
+				const sendHandler = function(knob, value) {
+					const node = knob.node();
+					const channel = storage.get(node, 'channel');
+					const bus = storage.get(node, 'bus');
+					const sendValue = (0.01 * value).toFixed(2);
+					handler.setSend(channel, bus, sendValue);
+				};
+
+				const sendKnobObj = sendKnob.obj;
+				sendKnobObj.addListener(sendHandler);
+			}
+
+		}
+
+		const buses = spatializer.Buses;
+		const numBuses = buses.length;
+
+		/*
+		 * Iterate over the aux buses.
+		 */
+		for (let i = 0; i < numBuses; i++) {
+			const iString = i.toString();
+			const bus = buses[i];
+			const returnLevel = 100 * bus.Return;
+			const returnString = ui.getString('aux_return');
+			const returnLabel = returnString + ' ' + iString;
+
+			/*
+			 * Parameters for the return knob.
+			 */
+			const returnParams = {
+				'label': returnLabel,
+				'physicalUnit': '%',
+				'valueMin': 0,
+				'valueMax': 100,
+				'valueDefault': returnLevel,
+				'valueWidth': 150,
+				'valueHeight': 150,
+				'angle': 270,
+				'cursor': false,
+				'colorScheme': 'blue',
+				'readonly': false
+			};
+
+			const returnKnob = ui.createKnob(returnParams);
+			const returnKnobDiv = returnKnob.div;
+			controlsDiv.append(returnKnobDiv);
+			const returnKnobNode = returnKnob.node;
+			storage.put(returnKnobNode, 'bus', i);
+
+			/*
+			 * This gets executed when the return value changes.
+			 */
+			const returnHandler = function(knob, value) {
+				const node = knob.node();
+				const bus = storage.get(node, 'bus');
+				const returnValue = (0.01 * value).toFixed(2);
+				handler.setReturn(bus, returnValue);
+			};
+
+			const returnKnobObj = returnKnob.obj;
+			returnKnobObj.addListener(returnHandler);
 		}
 
 		/*
@@ -1904,33 +2880,35 @@ function UI() {
 	};
 
 	/*
-	 * Renders the metronome given a configuration returned from the server.
+	 * Renders the master section given a configuration returned from the server.
 	 */
-	this.renderMetronome = function(configuration) {
-		const metronomeConfiguration = configuration.Metronome;
-		const masterOutput = metronomeConfiguration.MasterOutput;
-		const elem = document.getElementById('metronome');
+	this.renderMaster = function(configuration) {
+		const masterConfiguration = configuration.Master;
+		const enabled = masterConfiguration.Enabled;
+		const params = masterConfiguration.Parameters;
+		const numParams = params.length;
+		const elem = document.getElementById('master_section');
 		helper.clearElement(elem);
 		const unitDiv = document.createElement('div');
 		unitDiv.classList.add('contentdiv');
 		unitDiv.classList.add('masterunitdiv');
 		const headerDiv = document.createElement('div');
-		const masterString = ui.getString('master');
+		const enabledString = ui.getString('enabled');
 
 		/*
-		 * Parameters for metronome button.
+		 * Parameters for the enable button.
 		 */
 		const paramsButton = {
-			caption: masterString,
-			active: masterOutput
+			caption: enabledString,
+			active: enabled
 		};
 
 		const button = ui.createButton(paramsButton);
 		const buttonElem = button.input;
-		storage.put(buttonElem, 'active', masterOutput);
+		storage.put(buttonElem, 'active', enabled);
 
 		/*
-		 * This is called when the user clicks on the 'master' button of the metronome.
+		 * This is called when the user clicks on the 'enabled' button of the master section.
 		 */
 		buttonElem.onclick = function(e) {
 			const active = !storage.get(this, 'active');
@@ -1947,7 +2925,7 @@ function UI() {
 			}
 
 			storage.put(this, 'active', active);
-			handler.setMetronomeValue('master-output', active);
+			handler.setMasterValue('enabled', active);
 		};
 
 		headerDiv.appendChild(buttonElem);
@@ -1955,7 +2933,7 @@ function UI() {
 		labelDiv.classList.add('labeldiv');
 		labelDiv.classList.add('active');
 		labelDiv.classList.add('io');
-		const label = ui.getString('metronome');
+		const label = ui.getString('master_section');
 		const labelNode = document.createTextNode(label);
 		labelDiv.appendChild(labelNode);
 		headerDiv.appendChild(labelDiv);
@@ -1965,17 +2943,122 @@ function UI() {
 		controlsDiv.classList.add('controlsdiv');
 		unitDiv.appendChild(controlsDiv);
 		elem.appendChild(unitDiv);
-		const beatsString = ui.getString('beats_per_period');
-		const beatsValue = metronomeConfiguration.BeatsPerPeriod;
 
 		/*
-		 * Parameters for the beats per period knob.
+		 * Create a knob for each parameter.
 		 */
-		var beatsParams = {
-			'label': beatsString,
-			'physicalUnit': '',
-			'valueMin': 1,
-			'valueMax': 16,
+		for (let i = 0; i < numParams; i++) {
+			const param = params[i];
+			const name = param.Name;
+			const paramLabel = ui.getString(name);
+
+			/*
+			 * Parameters for the knob.
+			 */
+			const knobParams = {
+				'label': paramLabel,
+				'physicalUnit': param.PhysicalUnit,
+				'valueMin': param.Minimum,
+				'valueMax': param.Maximum,
+				'valueDefault': param.Value,
+				'valueWidth': 150,
+				'valueHeight': 150,
+				'angle': 270,
+				'cursor': true,
+				'colorScheme': 'blue',
+				'readonly': false
+			};
+
+			const knob = ui.createKnob(knobParams);
+			const knobDiv = knob.div;
+			controlsDiv.appendChild(knobDiv);
+
+			/*
+			 * This gets executed when the value of the parameter changes.
+			 */
+			const knobHandler = function(knob, value) {
+				handler.setMasterValue(name, value);
+			};
+
+			const knobObj = knob.obj;
+			knobObj.addListener(knobHandler);
+		}
+
+	};
+
+	/*
+	 * Renders the metronome given a configuration returned from the server.
+	 */
+	this.renderMetronome = function(configuration) {
+		const metronomeConfiguration = configuration.Metronome;
+		const masterOutput = metronomeConfiguration.MasterOutput;
+		const elem = document.getElementById('metronome');
+		helper.clearElement(elem);
+		const unitDiv = document.createElement('div');
+		unitDiv.classList.add('contentdiv');
+		unitDiv.classList.add('masterunitdiv');
+		const headerDiv = document.createElement('div');
+		const masterString = ui.getString('master');
+
+		/*
+		 * Parameters for metronome button.
+		 */
+		const paramsButton = {
+			caption: masterString,
+			active: masterOutput
+		};
+
+		const button = ui.createButton(paramsButton);
+		const buttonElem = button.input;
+		storage.put(buttonElem, 'active', masterOutput);
+
+		/*
+		 * This is called when the user clicks on the 'master' button of the metronome.
+		 */
+		buttonElem.onclick = function(e) {
+			const active = !storage.get(this, 'active');
+
+			/*
+			 * Check whether the control should be active.
+			 */
+			if (active) {
+				this.classList.remove('buttonnormal');
+				this.classList.add('buttonactive');
+			} else {
+				this.classList.remove('buttonactive');
+				this.classList.add('buttonnormal');
+			}
+
+			storage.put(this, 'active', active);
+			handler.setMetronomeValue('master-output', active);
+		};
+
+		headerDiv.appendChild(buttonElem);
+		const labelDiv = document.createElement('div');
+		labelDiv.classList.add('labeldiv');
+		labelDiv.classList.add('active');
+		labelDiv.classList.add('io');
+		const label = ui.getString('metronome');
+		const labelNode = document.createTextNode(label);
+		labelDiv.appendChild(labelNode);
+		headerDiv.appendChild(labelDiv);
+		headerDiv.classList.add('headerdiv');
+		unitDiv.appendChild(headerDiv);
+		const controlsDiv = document.createElement('div');
+		controlsDiv.classList.add('controlsdiv');
+		unitDiv.appendChild(controlsDiv);
+		elem.appendChild(unitDiv);
+		const beatsString = ui.getString('beats_per_period');
+		const beatsValue = metronomeConfiguration.BeatsPerPeriod;
+
+		/*
+		 * Parameters for the beats per period knob.
+		 */
+		var beatsParams = {
+			'label': beatsString,
+			'physicalUnit': '',
+			'valueMin': 1,
+			'valueMax': 16,
 			'valueDefault': beatsValue,
 			'valueWidth': 150,
 			'valueHeight': 150,
@@ -2031,6 +3114,188 @@ function UI() {
 		beatsKnobObj.addListener(beatsHandler);
 		const speedKnobObj = speedKnob.obj;
 		speedKnobObj.addListener(speedHandler);
+		const tapString = ui.getString('tap_tempo');
+
+		/*
+		 * Parameters for the tap tempo button.
+		 */
+		const paramsTapButton = {
+			caption: tapString,
+			active: false
+		};
+
+		const tapButton = ui.createButton(paramsTapButton);
+		const tapButtonElem = tapButton.input;
+
+		/*
+		 * This is called when the user taps the tempo.
+		 */
+		tapButtonElem.onclick = function(e) {
+			handler.tapTempo(speedKnobObj);
+		};
+
+		controlsDiv.appendChild(tapButtonElem);
+		const labelSubdivision = ui.getString('subdivision');
+		const subdivisionValue = metronomeConfiguration.Subdivision;
+
+		/*
+		 * Names of the subdivisions, starting at one click per beat.
+		 */
+		const subdivisionOptions = [
+			ui.getString('subdivision_quarters'),
+			ui.getString('subdivision_eighths'),
+			ui.getString('subdivision_triplets'),
+			ui.getString('subdivision_sixteenths')
+		];
+
+		/*
+		 * Parameters for the subdivision drop down menu.
+		 */
+		const paramsSubdivision = {
+			'label': labelSubdivision,
+			'options': subdivisionOptions,
+			'selectedIndex': subdivisionValue - 1
+		};
+
+		const dropDownSubdivision = ui.createDropDown(paramsSubdivision);
+		const dropDownSubdivisionElem = dropDownSubdivision.input;
+
+		/*
+		 * This is called when the subdivision changes.
+		 */
+		dropDownSubdivisionElem.onchange = function(e) {
+			const value = this.selectedIndex + 1;
+			handler.setMetronomeValue('subdivision', value);
+		};
+
+		const controlRowSubdivision = document.createElement('div');
+		controlRowSubdivision.appendChild(dropDownSubdivision.div);
+		controlsDiv.appendChild(controlRowSubdivision);
+		const accentsLabel = ui.getString('accents');
+		const accentsValue = metronomeConfiguration.Accents;
+		const accentsInput = document.createElement('input');
+		accentsInput.classList.add('textfield');
+		accentsInput.setAttribute('type', 'text');
+		accentsInput.setAttribute('maxlength', '32');
+		accentsInput.setAttribute('placeholder', accentsLabel);
+		accentsInput.setAttribute('title', accentsLabel);
+		accentsInput.value = accentsValue;
+
+		/*
+		 * This is called when the accent pattern changes.
+		 */
+		accentsInput.onchange = function(e) {
+			const value = this.value;
+			handler.setMetronomeValue('accents', value);
+		};
+
+		const controlRowAccents = document.createElement('div');
+		controlRowAccents.appendChild(accentsInput);
+		controlsDiv.appendChild(controlRowAccents);
+		const countInString = ui.getString('count_in');
+		const countInValue = metronomeConfiguration.CountIn;
+
+		/*
+		 * Parameters for the count-in knob.
+		 */
+		const countInParams = {
+			'label': countInString,
+			'physicalUnit': '',
+			'valueMin': 0,
+			'valueMax': 16,
+			'valueDefault': countInValue,
+			'valueWidth': 150,
+			'valueHeight': 150,
+			'angle': 270,
+			'cursor': false,
+			'colorScheme': 'blue',
+			'readonly': false
+		};
+
+		const countInKnob = ui.createKnob(countInParams);
+		const countInKnobDiv = countInKnob.div;
+		controlsDiv.appendChild(countInKnobDiv);
+		const countInKnobObj = countInKnob.obj;
+
+		/*
+		 * This gets executed when the count-in value changes.
+		 */
+		const countInHandler = function(knob, value) {
+			handler.setMetronomeValue('count-in', value);
+		};
+
+		countInKnobObj.addListener(countInHandler);
+		const startString = ui.getString('start_count_in');
+
+		/*
+		 * Parameters for the count-in start button.
+		 */
+		const paramsStartButton = {
+			caption: startString,
+			active: false
+		};
+
+		const startButton = ui.createButton(paramsStartButton);
+		const startButtonElem = startButton.input;
+
+		/*
+		 * This is called when the user starts the count-in over.
+		 */
+		startButtonElem.onclick = function(e) {
+			const value = countInKnobObj.getValue();
+			handler.setMetronomeValue('count-in', value);
+		};
+
+		controlsDiv.appendChild(startButtonElem);
+		const outputs = metronomeConfiguration.Outputs;
+		const numOutputs = outputs.length;
+		const clickString = ui.getString('click');
+
+		/*
+		 * Create a knob for the click level of each channel.
+		 */
+		for (let i = 0; i < numOutputs; i++) {
+			const iString = i.toString();
+			const clickLabel = clickString + ' ' + iString;
+			const clickValue = 100 * outputs[i];
+
+			/*
+			 * Parameters for the click level knob.
+			 */
+			const clickParams = {
+				'label': clickLabel,
+				'physicalUnit': '%',
+				'valueMin': 0,
+				'valueMax': 100,
+				'valueDefault': clickValue,
+				'valueWidth': 150,
+				'valueHeight': 150,
+				'angle': 270,
+				'cursor': false,
+				'colorScheme': 'blue',
+				'readonly': false
+			};
+
+			const clickKnob = ui.createKnob(clickParams);
+			const clickKnobDiv = clickKnob.div;
+			controlsDiv.appendChild(clickKnobDiv);
+			const clickKnobNode = clickKnob.node;
+			storage.put(clickKnobNode, 'channel', i);
+
+			/*
+			 * This gets executed when the click level changes.
+			 */
+			const clickHandler = function(knob, value) {
+				const node = knob.node();
+				const channel = storage.get(node, 'channel');
+				const clickLevel = (0.01 * value).toFixed(2);
+				handler.setMetronomeOutput(channel, clickLevel);
+			};
+
+			const clickKnobObj = clickKnob.obj;
+			clickKnobObj.addListener(clickHandler);
+		}
+
 		const sounds = metronomeConfiguration.Sounds;
 		const numSounds = sounds.length;
 		const tickSound = metronomeConfiguration.TickSound;
@@ -2112,6 +3377,136 @@ function UI() {
 		const controlRowTock = document.createElement('div');
 		controlRowTock.appendChild(dropDownTock.div);
 		controlsDiv.appendChild(controlRowTock);
+		const tickPitchString = ui.getString('tick_pitch');
+		const tickPitchValue = metronomeConfiguration.TickPitch;
+
+		/*
+		 * Parameters for the tick pitch knob.
+		 */
+		const tickPitchParams = {
+			'label': tickPitchString,
+			'physicalUnit': 'Hz',
+			'valueMin': 100,
+			'valueMax': 5000,
+			'valueDefault': tickPitchValue,
+			'valueWidth': 150,
+			'valueHeight': 150,
+			'angle': 270,
+			'cursor': false,
+			'colorScheme': 'blue',
+			'readonly': false
+		};
+
+		const tickPitchKnob = ui.createKnob(tickPitchParams);
+		const tickPitchKnobDiv = tickPitchKnob.div;
+		controlsDiv.appendChild(tickPitchKnobDiv);
+		const tickDecayString = ui.getString('tick_decay');
+		const tickDecayValue = metronomeConfiguration.TickDecay;
+
+		/*
+		 * Parameters for the tick decay knob.
+		 */
+		const tickDecayParams = {
+			'label': tickDecayString,
+			'physicalUnit': 'ms',
+			'valueMin': 1,
+			'valueMax': 500,
+			'valueDefault': tickDecayValue,
+			'valueWidth': 150,
+			'valueHeight': 150,
+			'angle': 270,
+			'cursor': false,
+			'colorScheme': 'blue',
+			'readonly': false
+		};
+
+		const tickDecayKnob = ui.createKnob(tickDecayParams);
+		const tickDecayKnobDiv = tickDecayKnob.div;
+		controlsDiv.appendChild(tickDecayKnobDiv);
+
+		/*
+		 * This gets executed when the tick pitch changes.
+		 */
+		const tickPitchHandler = function(knob, value) {
+			handler.setMetronomeValue('tick-pitch', value);
+		};
+
+		/*
+		 * This gets executed when the tick decay changes.
+		 */
+		const tickDecayHandler = function(knob, value) {
+			handler.setMetronomeValue('tick-decay', value);
+		};
+
+		const tickPitchKnobObj = tickPitchKnob.obj;
+		tickPitchKnobObj.addListener(tickPitchHandler);
+		const tickDecayKnobObj = tickDecayKnob.obj;
+		tickDecayKnobObj.addListener(tickDecayHandler);
+		const tockPitchString = ui.getString('tock_pitch');
+		const tockPitchValue = metronomeConfiguration.TockPitch;
+
+		/*
+		 * Parameters for the tock pitch knob.
+		 */
+		const tockPitchParams = {
+			'label': tockPitchString,
+			'physicalUnit': 'Hz',
+			'valueMin': 100,
+			'valueMax': 5000,
+			'valueDefault': tockPitchValue,
+			'valueWidth': 150,
+			'valueHeight': 150,
+			'angle': 270,
+			'cursor': false,
+			'colorScheme': 'blue',
+			'readonly': false
+		};
+
+		const tockPitchKnob = ui.createKnob(tockPitchParams);
+		const tockPitchKnobDiv = tockPitchKnob.div;
+		controlsDiv.appendChild(tockPitchKnobDiv);
+		const tockDecayString = ui.getString('tock_decay');
+		const tockDecayValue = metronomeConfiguration.TockDecay;
+
+		/*
+		 * Parameters for the tock decay knob.
+		 */
+		const tockDecayParams = {
+			'label': tockDecayString,
+			'physicalUnit': 'ms',
+			'valueMin': 1,
+			'valueMax': 500,
+			'valueDefault': tockDecayValue,
+			'valueWidth': 150,
+			'valueHeight': 150,
+			'angle': 270,
+			'cursor': false,
+			'colorScheme': 'blue',
+			'readonly': false
+		};
+
+		const tockDecayKnob = ui.createKnob(tockDecayParams);
+		const tockDecayKnobDiv = tockDecayKnob.div;
+		controlsDiv.appendChild(tockDecayKnobDiv);
+
+		/*
+		 * This gets executed when the tock pitch changes.
+		 */
+		const tockPitchHandler = function(knob, value) {
+			handler.setMetronomeValue('tock-pitch', value);
+		};
+
+		/*
+		 * This gets executed when the tock decay changes.
+		 */
+		const tockDecayHandler = function(knob, value) {
+			handler.setMetronomeValue('tock-decay', value);
+		};
+
+		const tockPitchKnobObj = tockPitchKnob.obj;
+		tockPitchKnobObj.addListener(tockPitchHandler);
+		const tockDecayKnobObj = tockDecayKnob.obj;
+		tockDecayKnobObj.addListener(tockDecayHandler);
 
 		/*
 		 * Create unit object.
@@ -2168,20 +3563,354 @@ function UI() {
 	};
 
 	/*
-	 * Renders the signal level analysis section given a configuration returned from the server.
+	 * Renders the backing track player given a configuration returned from the server.
 	 */
-	this.renderSignalLevels = function(configuration) {
-		const batchProcessing = configuration.BatchProcessing;
-		const elem = document.getElementById('levels');
+	this.renderPlayer = function(configuration) {
+		const playerConfiguration = configuration.Player;
+		const masterOutput = playerConfiguration.MasterOutput;
+		const elem = document.getElementById('player');
 		helper.clearElement(elem);
+		const unitDiv = document.createElement('div');
+		unitDiv.classList.add('contentdiv');
+		unitDiv.classList.add('masterunitdiv');
+		const headerDiv = document.createElement('div');
+		const masterString = ui.getString('master');
 
 		/*
-		 * Only display levels if batch processing is disabled on the server.
+		 * Parameters for the master button.
 		 */
-		if (batchProcessing === false) {
-			const unitDiv = document.createElement('div');
-			unitDiv.classList.add('contentdiv');
-			unitDiv.classList.add('masterunitdiv');
+		const paramsButton = {
+			caption: masterString,
+			active: masterOutput
+		};
+
+		const button = ui.createButton(paramsButton);
+		const buttonElem = button.input;
+		storage.put(buttonElem, 'active', masterOutput);
+
+		/*
+		 * This is called when the user clicks on the 'master' button of the player.
+		 */
+		buttonElem.onclick = function(e) {
+			const active = !storage.get(this, 'active');
+
+			/*
+			 * Check whether the control should be active.
+			 */
+			if (active) {
+				this.classList.remove('buttonnormal');
+				this.classList.add('buttonactive');
+			} else {
+				this.classList.remove('buttonactive');
+				this.classList.add('buttonnormal');
+			}
+
+			storage.put(this, 'active', active);
+			handler.setPlayerValue('master-output', active);
+		};
+
+		headerDiv.appendChild(buttonElem);
+		const labelDiv = document.createElement('div');
+		labelDiv.classList.add('labeldiv');
+		labelDiv.classList.add('active');
+		labelDiv.classList.add('io');
+		const label = ui.getString('backing_track');
+		const labelNode = document.createTextNode(label);
+		labelDiv.appendChild(labelNode);
+		headerDiv.appendChild(labelDiv);
+		headerDiv.classList.add('headerdiv');
+		unitDiv.appendChild(headerDiv);
+		const controlsDiv = document.createElement('div');
+		controlsDiv.classList.add('controlsdiv');
+		unitDiv.appendChild(controlsDiv);
+		elem.appendChild(unitDiv);
+		const uploadAreaDiv = document.createElement('div');
+		uploadAreaDiv.classList.add('uploadarea');
+		uploadAreaDiv.addEventListener('dragend', handler.dragLeave);
+		uploadAreaDiv.addEventListener('dragenter', handler.dragEnter);
+		uploadAreaDiv.addEventListener('dragleave', handler.dragLeave);
+		uploadAreaDiv.addEventListener('dragover', handler.absorbEvent);
+		uploadAreaDiv.addEventListener('drop', handler.uploadTrack);
+		const instructionsString = ui.getString('track_instructions');
+		const instructionsNode = document.createTextNode(instructionsString);
+		uploadAreaDiv.appendChild(instructionsNode);
+		controlsDiv.appendChild(uploadAreaDiv);
+		const statusDiv = document.createElement('div');
+		statusDiv.classList.add('labeldiv');
+		const statusString = ui.getString('track_not_loaded');
+		const statusNode = document.createTextNode(statusString);
+		statusDiv.appendChild(statusNode);
+		controlsDiv.appendChild(statusDiv);
+		const playString = ui.getString('play');
+
+		/*
+		 * Parameters for the play button.
+		 */
+		const paramsPlayButton = {
+			caption: playString,
+			active: false
+		};
+
+		const playButton = ui.createButton(paramsPlayButton);
+		const playButtonElem = playButton.input;
+
+		/*
+		 * This is called when the user starts playback.
+		 */
+		playButtonElem.onclick = function(e) {
+			handler.startPlayer();
+		};
+
+		controlsDiv.appendChild(playButtonElem);
+		const stopString = ui.getString('stop');
+
+		/*
+		 * Parameters for the stop button.
+		 */
+		const paramsStopButton = {
+			caption: stopString,
+			active: false
+		};
+
+		const stopButton = ui.createButton(paramsStopButton);
+		const stopButtonElem = stopButton.input;
+
+		/*
+		 * This is called when the user stops playback.
+		 */
+		stopButtonElem.onclick = function(e) {
+			handler.stopPlayer();
+		};
+
+		controlsDiv.appendChild(stopButtonElem);
+		const rewindString = ui.getString('rewind');
+
+		/*
+		 * Parameters for the rewind button.
+		 */
+		const paramsRewindButton = {
+			caption: rewindString,
+			active: false
+		};
+
+		const rewindButton = ui.createButton(paramsRewindButton);
+		const rewindButtonElem = rewindButton.input;
+
+		/*
+		 * This is called when the user moves playback to the start of the track.
+		 */
+		rewindButtonElem.onclick = function(e) {
+			handler.seekPlayer(0.0);
+		};
+
+		controlsDiv.appendChild(rewindButtonElem);
+		const levelString = ui.getString('level');
+		const levelValue = 100 * playerConfiguration.Level;
+
+		/*
+		 * Parameters for the level knob.
+		 */
+		const levelParams = {
+			'label': levelString,
+			'physicalUnit': '%',
+			'valueMin': 0,
+			'valueMax': 100,
+			'valueDefault': levelValue,
+			'valueWidth': 150,
+			'valueHeight': 150,
+			'angle': 270,
+			'cursor': false,
+			'colorScheme': 'blue',
+			'readonly': false
+		};
+
+		const levelKnob = ui.createKnob(levelParams);
+		const levelKnobDiv = levelKnob.div;
+		controlsDiv.appendChild(levelKnobDiv);
+
+		/*
+		 * This gets executed when the level changes.
+		 */
+		const levelHandler = function(knob, value) {
+			const level = 0.01 * value;
+			handler.setPlayerValue('level', level);
+		};
+
+		const levelKnobObj = levelKnob.obj;
+		levelKnobObj.addListener(levelHandler);
+		const timer = globals.playerTimer;
+
+		/*
+		 * If a timer from a previous rendering is registered, clear it.
+		 */
+		if (timer !== null) {
+			window.clearInterval(timer);
+		}
+
+		/*
+		 * This gets executed whenever the timer ticks.
+		 */
+		const callback = function() {
+			handler.refreshPlayer(statusDiv);
+		};
+
+		globals.playerTimer = window.setInterval(callback, 500);
+
+		/*
+		 * Create unit object.
+		 */
+		const unit = {
+			'controls': controlsDiv,
+			'expanded': false
+		};
+
+		/*
+		 * Expands or collapses a unit.
+		 */
+		unit.setExpanded = function(value) {
+			const controlsDiv = this.controls;
+			let displayValue = '';
+
+			/*
+			 * Check whether we should expand or collapse the unit.
+			 */
+			if (value) {
+				displayValue = 'block';
+			} else {
+				displayValue = 'none';
+			}
+
+			controlsDiv.style.display = displayValue;
+			this.expanded = value;
+		};
+
+		/*
+		 * Returns whether a unit is expanded.
+		 */
+		unit.getExpanded = function() {
+			return this.expanded;
+		};
+
+		/*
+		 * Toggles a unit between expanded and collapsed state.
+		 */
+		unit.toggleExpanded = function() {
+			const state = this.getExpanded();
+			this.setExpanded(!state);
+		};
+
+		/*
+		 * This is called when a user clicks on the label div.
+		 */
+		labelDiv.onclick = function(e) {
+			const unit = storage.get(this, 'unit');
+			unit.toggleExpanded();
+		};
+
+		storage.put(labelDiv, 'unit', unit);
+	};
+
+	/*
+	 * Formats a time in seconds as minutes and seconds.
+	 */
+	this.formatTime = function(seconds) {
+		const totalSeconds = Math.floor(seconds);
+		const minutes = Math.floor(totalSeconds / 60);
+		const remainder = totalSeconds % 60;
+		const minutesString = minutes.toString();
+		const remainderString = remainder.toString();
+		const paddedString = remainderString.padStart(2, '0');
+		const result = minutesString + ':' + paddedString;
+		return result;
+	};
+
+	/*
+	 * Displays the status of the backing track player.
+	 */
+	this.updatePlayer = function(statusDiv, status) {
+		let statusString = '';
+
+		/*
+		 * Check if a track is loaded.
+		 */
+		if (status.Loaded !== true) {
+			statusString = ui.getString('track_not_loaded');
+		} else {
+			const positionString = ui.formatTime(status.Position);
+			const lengthString = ui.formatTime(status.Length);
+			statusString = positionString + ' / ' + lengthString;
+			const tempo = status.Tempo;
+
+			/*
+			 * Only show the tempo if it was detected.
+			 */
+			if (tempo > 0.0) {
+				const tempoString = tempo.toFixed(1);
+				const bpmString = ui.getString('bpm');
+				statusString += ' - ' + tempoString + ' ' + bpmString;
+			}
+
+		}
+
+		helper.clearElement(statusDiv);
+		const statusNode = document.createTextNode(statusString);
+		statusDiv.appendChild(statusNode);
+	};
+
+	/*
+	 * Displays the progress of the rendering in batch processing mode.
+	 */
+	this.updateProcessing = function(statusDiv, status) {
+		let statusString = '';
+
+		/*
+		 * Describe the progress or the outcome of the rendering.
+		 */
+		if (status.Running === true) {
+			const renderingString = ui.getString('rendering');
+			const percentString = status.Percent.toFixed(0);
+			const elapsedString = ui.formatTime(status.Elapsed);
+			statusString = renderingString + ': ' + percentString + ' % - ' + elapsedString;
+			const remaining = status.Remaining;
+
+			/*
+			 * Only show the remaining time once it is estimated.
+			 */
+			if (remaining > 0.0) {
+				const remainingString = ui.formatTime(remaining);
+				const remainingLabel = ui.getString('remaining');
+				statusString += ' (' + remainingString + ' ' + remainingLabel + ')';
+			}
+
+		} else if (status.Cancelled === true) {
+			statusString = ui.getString('rendering_cancelled');
+		} else if (status.Error !== '') {
+			const failedString = ui.getString('rendering_failed');
+			statusString = failedString + ': ' + status.Error;
+		} else if (status.Started === true) {
+			statusString = ui.getString('rendering_finished');
+		}
+
+		helper.clearElement(statusDiv);
+		const statusNode = document.createTextNode(statusString);
+		statusDiv.appendChild(statusNode);
+	};
+
+	/*
+	 * Renders the signal level analysis section given a configuration returned from the server.
+	 */
+	this.renderSignalLevels = function(configuration) {
+		const batchProcessing = configuration.BatchProcessing;
+		const elem = document.getElementById('levels');
+		helper.clearElement(elem);
+
+		/*
+		 * Only display levels if batch processing is disabled on the server.
+		 */
+		if (batchProcessing === false) {
+			const unitDiv = document.createElement('div');
+			unitDiv.classList.add('contentdiv');
+			unitDiv.classList.add('masterunitdiv');
 			const headerDiv = document.createElement('div');
 			const enabledString = ui.getString('enabled');
 			const levelMeter = configuration.LevelMeter;
@@ -2272,6 +4001,7 @@ function UI() {
 						 */
 						const responseListener = function(response) {
 							let dspLoadControl = unit.dspLoadControl;
+							let xrunsDiv = unit.xrunsDiv;
 							let channelNames = unit.channelNames;
 							const numNames = channelNames.length;
 							let channelControls = unit.channelControls;
@@ -2332,6 +4062,8 @@ function UI() {
 								const container = document.createElement('div');
 								container.appendChild(dspLoadLabelDiv);
 								container.appendChild(nodeWrapper);
+								xrunsDiv = document.createElement('div');
+								container.appendChild(xrunsDiv);
 								controlsDiv.appendChild(container);
 								channelNames = [];
 								channelControls = [];
@@ -2375,6 +4107,29 @@ function UI() {
 								dspLoadControl.setValue(dspLoad);
 							}
 
+							const xruns = response.Xruns;
+
+							/*
+							 * Display number of xruns and time of the last one.
+							 */
+							if ((xrunsDiv !== undefined) && (xrunsDiv !== null) && (xruns !== undefined)) {
+								const xrunsString = ui.getString('xruns');
+								let text = xrunsString + ': ' + xruns.Xruns;
+								const lastXrun = xruns.LastXrun;
+
+								/*
+								 * Only show the time of the last xrun if there was one.
+								 */
+								if (lastXrun !== '') {
+									const lastXrunString = ui.getString('last_xrun');
+									const lastXrunDate = new Date(lastXrun);
+									const lastXrunTime = lastXrunDate.toLocaleTimeString();
+									text += ' (' + lastXrunString + ': ' + lastXrunTime + ')';
+								}
+
+								xrunsDiv.textContent = text;
+							}
+
 							/*
 							 * Iterate over all channels in the response.
 							 */
@@ -2383,11 +4138,22 @@ function UI() {
 								const channelLevel = channel.Level;
 								const channelPeak = channel.Peak;
 								const channelControl = channelControls[i];
+								let channelColor = channel.Color;
+
+								/*
+								 * Channels without a color are shown in green.
+								 */
+								if ((channelColor === undefined) || (channelColor === '')) {
+									channelColor = '#44ff44';
+								}
+
+								channelControl.setProperty('colorFG', channelColor);
 								channelControl.setValue(channelLevel);
 								channelControl.setPeaks([channelPeak]);
 							}
 
 							unit.dspLoadControl = dspLoadControl;
+							unit.xrunsDiv = xrunsDiv;
 							unit.channelNames = channelNames;
 							unit.channelControls = channelControls;
 						};
@@ -2530,7 +4296,8 @@ function UI() {
 				 * Trigger batch processing if the control is active.
 				 */
 				if (active) {
-					handler.process();
+					unit.setExpanded(true);
+					handler.process(statusDiv);
 				}
 
 			};
@@ -2548,6 +4315,30 @@ function UI() {
 			unitDiv.appendChild(headerDiv);
 			const controlsDiv = document.createElement('div');
 			controlsDiv.classList.add('controlsdiv');
+			const statusDiv = document.createElement('div');
+			statusDiv.classList.add('labeldiv');
+			controlsDiv.appendChild(statusDiv);
+			const cancelString = ui.getString('cancel');
+
+			/*
+			 * Parameters for the cancel button.
+			 */
+			const paramsCancelButton = {
+				caption: cancelString,
+				active: false
+			};
+
+			const cancelButton = ui.createButton(paramsCancelButton);
+			const cancelButtonElem = cancelButton.input;
+
+			/*
+			 * This is called when the user cancels the rendering.
+			 */
+			cancelButtonElem.onclick = function(e) {
+				handler.cancelProcess();
+			};
+
+			controlsDiv.appendChild(cancelButtonElem);
 			unitDiv.appendChild(controlsDiv);
 			elem.appendChild(unitDiv);
 
@@ -2607,20 +4398,638 @@ function UI() {
 
 	};
 
-}
+}
+
+const ui = new UI();
+
+/*
+ * This class implements all handler functions for user interaction.
+ */
+function Handler() {
+	const self = this;
+
+	/*
+	 * This is called when a channel should be added.
+	 */
+	this.addChannel = function(stereo) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Adding channel failed: ' + reason;
+					console.log(msg);
+				} else {
+					self.refresh();
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const stereoString = stereo.toString();
+		const request = new Request();
+		request.append('cgi', 'add-channel');
+		request.append('stereo', stereoString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a new effects unit should be added.
+	 */
+	this.addUnit = function(unitType, chain) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt, otherwise refresh rack.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Adding new unit failed: ' + reason;
+					console.log(msg);
+				} else {
+					self.refresh();
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const unitTypeString = unitType.toString();
+		const chainString = chain.toString();
+		const request = new Request();
+		request.append('cgi', 'add-unit');
+		request.append('type', unitTypeString);
+		request.append('chain', chainString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a new level analysis should be obtained.
+	 */
+	this.getLevelAnalysis = function(callback) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const levels = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (levels !== null) {
+				callback(levels);
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const request = new Request();
+		request.append('cgi', 'get-level-analysis');
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, false);
+	};
+
+	/*
+	 * This is called when a unit should be moved down the chain.
+	 */
+	this.moveDown = function(chain, unit) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		var responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Moving unit down failed: ' + reason;
+					console.log(msg);
+				} else {
+					self.refresh();
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const chainString = chain.toString();
+		const unitString = unit.toString();
+		const request = new Request();
+		request.append('cgi', 'move-down');
+		request.append('chain', chainString);
+		request.append('unit', unitString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a unit should be moved up the chain.
+	 */
+	this.moveUp = function(chain, unit) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Moving unit up failed: ' + reason;
+					console.log(msg);
+				} else {
+					self.refresh();
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const chainString = chain.toString();
+		const unitString = unit.toString();
+		const request = new Request();
+		request.append('cgi', 'move-up');
+		request.append('chain', chainString);
+		request.append('unit', unitString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a channel should be removed.
+	 */
+	this.removeChannel = function(channel) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Removing channel failed: ' + reason;
+					console.log(msg);
+				} else {
+					self.refresh();
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const channelString = channel.toString();
+		const request = new Request();
+		request.append('cgi', 'remove-channel');
+		request.append('channel', channelString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a unit should be removed from a chain.
+	 */
+	this.removeUnit = function(chain, unit) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Removing unit failed: ' + reason;
+					console.log(msg);
+				} else {
+					self.refresh();
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const chainString = chain.toString();
+		const unitString = unit.toString();
+		const request = new Request();
+		request.append('cgi', 'remove-unit');
+		request.append('chain', chainString);
+		request.append('unit', unitString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a new azimuth value should be set.
+	 */
+	this.setAzimuth = function(chain, value) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Setting azimuth value failed: ' + reason;
+					console.log(msg);
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const chainString = chain.toString();
+		const valueString = value.toString()
+		const request = new Request();
+		request.append('cgi', 'set-azimuth');
+		request.append('chain', chainString);
+		request.append('value', valueString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a unit should be bypassed or bypass should be disabled for a unit.
+	 */
+	this.setBypass = function(chain, unit, value) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Setting bypass value failed: ' + reason;
+					console.log(msg);
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const chainString = chain.toString();
+		const unitString = unit.toString();
+		const valueString = value.toString();
+		const request = new Request();
+		request.append('cgi', 'set-bypass');
+		request.append('chain', chainString);
+		request.append('unit', unitString);
+		request.append('value', valueString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when the color of a channel should be set.
+	 */
+	this.setChannelColor = function(channel, value) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Setting channel color failed: ' + reason;
+					console.log(msg);
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const channelString = channel.toString();
+		const valueString = value.toString();
+		const request = new Request();
+		request.append('cgi', 'set-channel-color');
+		request.append('channel', channelString);
+		request.append('value', valueString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when the name of a channel should be set.
+	 */
+	this.setChannelName = function(channel, value) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Setting channel name failed: ' + reason;
+					console.log(msg);
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const channelString = channel.toString();
+		const valueString = value.toString();
+		const request = new Request();
+		request.append('cgi', 'set-channel-name');
+		request.append('channel', channelString);
+		request.append('value', valueString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a new distance value should be set.
+	 */
+	this.setDistance = function(chain, value) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Setting distance value failed: ' + reason;
+					console.log(msg);
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const chainString = chain.toString();
+		const valueString = value.toString();
+		const request = new Request();
+		request.append('cgi', 'set-distance');
+		request.append('chain', chainString);
+		request.append('value', valueString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a discrete value should be set.
+	 */
+	this.setDiscreteValue = function(chain, unit, param, value) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt, otherwise
+				 * refresh rack if a plugin was loaded, since its controls
+				 * replace those of the previous one.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Setting discrete value failed: ' + reason;
+					console.log(msg);
+				} else if (param === 'plugin') {
+					self.refresh();
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const chainString = chain.toString();
+		const unitString = unit.toString();
+		const paramString = param.toString();
+		const valueString = value.toString();
+		const request = new Request();
+		request.append('cgi', 'set-discrete-value');
+		request.append('chain', chainString);
+		request.append('unit', unitString);
+		request.append('param', paramString);
+		request.append('value', valueString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when period size should be changed.
+	 */
+	this.setFramesPerPeriod = function(value) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Setting frames per period failed: ' + reason;
+					console.log(msg);
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const valueString = value.toString();
+		const request = new Request();
+		request.append('cgi', 'set-frames-per-period');
+		request.append('value', valueString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a new level value should be set.
+	 */
+	this.setLevel = function(chain, value) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Setting level value failed: ' + reason;
+					console.log(msg);
+				}
+
+			}
 
-const ui = new UI();
+		};
 
-/*
- * This class implements all handler functions for user interaction.
- */
-function Handler() {
-	const self = this;
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const chainString = chain.toString();
+		const valueString = value.toString();
+		const request = new Request();
+		request.append('cgi', 'set-level');
+		request.append('chain', chainString);
+		request.append('value', valueString);
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
 
 	/*
-	 * This is called when a new effects unit should be added.
+	 * This is called when a channel should be muted or not.
 	 */
-	this.addUnit = function(unitType, chain) {
+	this.setMute = function(chain, value) {
 
 		/*
 		 * This gets called when the server returns a response.
@@ -2634,14 +5043,12 @@ function Handler() {
 			if (webResponse !== null) {
 
 				/*
-				 * If we were not successful, log failed attempt, otherwise refresh rack.
+				 * If we were not successful, log failed attempt.
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Adding new unit failed: ' + reason;
+					const msg = 'Setting mute flag failed: ' + reason;
 					console.log(msg);
-				} else {
-					self.refresh();
 				}
 
 			}
@@ -2650,53 +5057,66 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
-		const unitTypeString = unitType.toString();
 		const chainString = chain.toString();
+		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'add-unit');
-		request.append('type', unitTypeString);
+		request.append('cgi', 'set-mute');
 		request.append('chain', chainString);
+		request.append('value', valueString);
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when a new level analysis should be obtained.
+	 * This is called when a channel should be soloed or not.
 	 */
-	this.getLevelAnalysis = function(callback) {
+	this.setSolo = function(chain, value) {
 
 		/*
 		 * This gets called when the server returns a response.
 		 */
 		const responseHandler = function(response) {
-			const levels = helper.parseJSON(response);
+			const webResponse = helper.parseJSON(response);
 
 			/*
 			 * Check if the response is valid JSON.
 			 */
-			if (levels !== null) {
-				callback(levels);
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Setting solo flag failed: ' + reason;
+					console.log(msg);
+				}
+
 			}
 
 		};
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
+		const chainString = chain.toString();
+		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'get-level-analysis');
+		request.append('cgi', 'set-solo');
+		request.append('chain', chainString);
+		request.append('value', valueString);
 		const requestBody = request.getData();
-		ajax.request('POST', url, requestBody, mimeType, responseHandler, false);
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when a unit should be moved down the chain.
+	 * This is called when the return level of an aux bus should be changed.
 	 */
-	this.moveDown = function(chain, unit) {
+	this.setReturn = function(bus, value) {
 
 		/*
 		 * This gets called when the server returns a response.
 		 */
-		var responseHandler = function(response) {
+		const responseHandler = function(response) {
 			const webResponse = helper.parseJSON(response);
 
 			/*
@@ -2709,10 +5129,8 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Moving unit down failed: ' + reason;
+					const msg = 'Setting return level failed: ' + reason;
 					console.log(msg);
-				} else {
-					self.refresh();
 				}
 
 			}
@@ -2721,20 +5139,21 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
-		const chainString = chain.toString();
-		const unitString = unit.toString();
+		const busString = bus.toString();
+		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'move-down');
-		request.append('chain', chainString);
-		request.append('unit', unitString);
+		request.append('cgi', 'set-return');
+		request.append('bus', busString);
+		request.append('value', valueString);
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when a unit should be moved up the chain.
+	 * This is called when the level at which a channel is sent to an aux bus
+	 * should be changed.
 	 */
-	this.moveUp = function(chain, unit) {
+	this.setSend = function(chain, bus, value) {
 
 		/*
 		 * This gets called when the server returns a response.
@@ -2752,10 +5171,8 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Moving unit up failed: ' + reason;
+					const msg = 'Setting send level failed: ' + reason;
 					console.log(msg);
-				} else {
-					self.refresh();
 				}
 
 			}
@@ -2765,24 +5182,26 @@ function Handler() {
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
 		const chainString = chain.toString();
-		const unitString = unit.toString();
+		const busString = bus.toString();
+		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'move-up');
+		request.append('cgi', 'set-send');
 		request.append('chain', chainString);
-		request.append('unit', unitString);
+		request.append('bus', busString);
+		request.append('value', valueString);
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when a unit should be removed from a chain.
+	 * This is called when the level meter should be enabled or disabled.
 	 */
-	this.removeUnit = function(chain, unit) {
+	this.setLevelMeterEnabled = function(value) {
 
 		/*
 		 * This gets called when the server returns a response.
 		 */
-		const responseHandler = function(response) {
+		var responseHandler = function(response) {
 			const webResponse = helper.parseJSON(response);
 
 			/*
@@ -2795,10 +5214,17 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Removing unit failed: ' + reason;
+					const action = 'Enabling';
+
+					/*
+					 * Check if we should disable the level meter.
+					 */
+					if (!value) {
+						action = 'Disabling';
+					}
+
+					const msg = action + ' level meter failed: ' + reason;
 					console.log(msg);
-				} else {
-					self.refresh();
 				}
 
 			}
@@ -2807,20 +5233,18 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
-		const chainString = chain.toString();
-		const unitString = unit.toString();
+		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'remove-unit');
-		request.append('chain', chainString);
-		request.append('unit', unitString);
+		request.append('cgi', 'set-level-meter-enabled');
+		request.append('value', valueString);
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when a new azimuth value should be set.
+	 * This is called when the click level of a channel should be changed.
 	 */
-	this.setAzimuth = function(chain, value) {
+	this.setMetronomeOutput = function(channel, value) {
 
 		/*
 		 * This gets called when the server returns a response.
@@ -2838,7 +5262,7 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Setting azimuth value failed: ' + reason;
+					const msg = 'Setting click level failed: ' + reason;
 					console.log(msg);
 				}
 
@@ -2848,20 +5272,20 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
-		const chainString = chain.toString();
-		const valueString = value.toString()
+		const channelString = channel.toString();
+		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'set-azimuth');
-		request.append('chain', chainString);
+		request.append('cgi', 'set-metronome-output');
+		request.append('channel', channelString);
 		request.append('value', valueString);
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when a unit should be bypassed or bypass should be disabled for a unit.
+	 * This is called when a metronome value should be changed.
 	 */
-	this.setBypass = function(chain, unit, value) {
+	this.setMetronomeValue = function(param, value) {
 
 		/*
 		 * This gets called when the server returns a response.
@@ -2879,7 +5303,7 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Setting bypass value failed: ' + reason;
+					const msg = 'Setting metronome value failed: ' + reason;
 					console.log(msg);
 				}
 
@@ -2889,22 +5313,20 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
-		const chainString = chain.toString();
-		const unitString = unit.toString();
+		const paramString = param.toString();
 		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'set-bypass');
-		request.append('chain', chainString);
-		request.append('unit', unitString);
+		request.append('cgi', 'set-metronome-value');
+		request.append('param', paramString);
 		request.append('value', valueString);
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when a new distance value should be set.
+	 * This is called when a value of the master section should be set.
 	 */
-	this.setDistance = function(chain, value) {
+	this.setMasterValue = function(param, value) {
 
 		/*
 		 * This gets called when the server returns a response.
@@ -2922,7 +5344,7 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Setting distance value failed: ' + reason;
+					const msg = 'Setting master section value failed: ' + reason;
 					console.log(msg);
 				}
 
@@ -2932,20 +5354,20 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
-		const chainString = chain.toString();
+		const paramString = param.toString();
 		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'set-distance');
-		request.append('chain', chainString);
+		request.append('cgi', 'set-master-value');
+		request.append('param', paramString);
 		request.append('value', valueString);
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when a discrete value should be set.
+	 * This is called when a value of the backing track player should be set.
 	 */
-	this.setDiscreteValue = function(chain, unit, param, value) {
+	this.setPlayerValue = function(param, value) {
 
 		/*
 		 * This gets called when the server returns a response.
@@ -2963,7 +5385,7 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Setting discrete value failed: ' + reason;
+					const msg = 'Setting player value failed: ' + reason;
 					console.log(msg);
 				}
 
@@ -2973,14 +5395,10 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
-		const chainString = chain.toString();
-		const unitString = unit.toString();
 		const paramString = param.toString();
 		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'set-discrete-value');
-		request.append('chain', chainString);
-		request.append('unit', unitString);
+		request.append('cgi', 'set-player-value');
 		request.append('param', paramString);
 		request.append('value', valueString);
 		const requestBody = request.getData();
@@ -2988,9 +5406,9 @@ function Handler() {
 	};
 
 	/*
-	 * This is called when period size should be changed.
+	 * This is called when the playback position of the backing track should be changed.
 	 */
-	this.setFramesPerPeriod = function(value) {
+	this.seekPlayer = function(position) {
 
 		/*
 		 * This gets called when the server returns a response.
@@ -3008,7 +5426,7 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Setting frames per period failed: ' + reason;
+					const msg = 'Seeking backing track failed: ' + reason;
 					console.log(msg);
 				}
 
@@ -3018,18 +5436,18 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
-		const valueString = value.toString();
+		const positionString = position.toString();
 		const request = new Request();
-		request.append('cgi', 'set-frames-per-period');
-		request.append('value', valueString);
+		request.append('cgi', 'seek-player');
+		request.append('position', positionString);
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when a new level value should be set.
+	 * This is called when the backing track should start playing.
 	 */
-	this.setLevel = function(chain, value) {
+	this.startPlayer = function() {
 
 		/*
 		 * This gets called when the server returns a response.
@@ -3047,7 +5465,7 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Setting level value failed: ' + reason;
+					const msg = 'Starting backing track failed: ' + reason;
 					console.log(msg);
 				}
 
@@ -3057,25 +5475,21 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
-		const chainString = chain.toString();
-		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'set-level');
-		request.append('chain', chainString);
-		request.append('value', valueString);
+		request.append('cgi', 'start-player');
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when the level meter should be enabled or disabled.
+	 * This is called when the backing track should stop playing.
 	 */
-	this.setLevelMeterEnabled = function(value) {
+	this.stopPlayer = function() {
 
 		/*
 		 * This gets called when the server returns a response.
 		 */
-		var responseHandler = function(response) {
+		const responseHandler = function(response) {
 			const webResponse = helper.parseJSON(response);
 
 			/*
@@ -3088,16 +5502,85 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const action = 'Enabling';
+					const msg = 'Stopping backing track failed: ' + reason;
+					console.log(msg);
+				}
 
-					/*
-					 * Check if we should disable the level meter.
-					 */
-					if (!value) {
-						action = 'Disabling';
-					}
+			}
 
-					const msg = action + ' level meter failed: ' + reason;
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const request = new Request();
+		request.append('cgi', 'stop-player');
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when the user taps the tempo.
+	 */
+	this.tapTempo = function(speedKnob) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt,
+				 * otherwise show the new speed.
+				 */
+				if (webResponse.Success === false) {
+					const reason = webResponse.Reason;
+					const msg = 'Tapping tempo failed: ' + reason;
+					console.log(msg);
+				} else if (webResponse.Updated === true) {
+					const speed = webResponse.Speed;
+					speedKnob.setValue(speed);
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const request = new Request();
+		request.append('cgi', 'tap-tempo');
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a tuner value should be changed.
+	 */
+	this.setTunerValue = function(param, value) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Setting tuner value failed: ' + reason;
 					console.log(msg);
 				}
 
@@ -3107,18 +5590,20 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
+		const paramString = param.toString();
 		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'set-level-meter-enabled');
+		request.append('cgi', 'set-tuner-value');
+		request.append('param', paramString);
 		request.append('value', valueString);
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when a metronome value should be changed.
+	 * This is called when a numeric value should be set.
 	 */
-	this.setMetronomeValue = function(param, value) {
+	this.setNumericValue = function(chain, unit, param, value) {
 
 		/*
 		 * This gets called when the server returns a response.
@@ -3136,7 +5621,7 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Setting metronome value failed: ' + reason;
+					const msg = 'Setting numeric value failed: ' + reason;
 					console.log(msg);
 				}
 
@@ -3146,10 +5631,14 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
+		const chainString = chain.toString();
+		const unitString = unit.toString();
 		const paramString = param.toString();
 		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'set-metronome-value');
+		request.append('cgi', 'set-numeric-value');
+		request.append('chain', chainString);
+		request.append('unit', unitString);
 		request.append('param', paramString);
 		request.append('value', valueString);
 		const requestBody = request.getData();
@@ -3157,9 +5646,9 @@ function Handler() {
 	};
 
 	/*
-	 * This is called when a tuner value should be changed.
+	 * This is called when the input trim of a unit should be set.
 	 */
-	this.setTunerValue = function(param, value) {
+	this.setInputTrim = function(chain, unit, value) {
 
 		/*
 		 * This gets called when the server returns a response.
@@ -3177,7 +5666,7 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Setting tuner value failed: ' + reason;
+					const msg = 'Setting input trim failed: ' + reason;
 					console.log(msg);
 				}
 
@@ -3187,20 +5676,22 @@ function Handler() {
 
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
-		const paramString = param.toString();
+		const chainString = chain.toString();
+		const unitString = unit.toString();
 		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'set-tuner-value');
-		request.append('param', paramString);
+		request.append('cgi', 'set-input-trim');
+		request.append('chain', chainString);
+		request.append('unit', unitString);
 		request.append('value', valueString);
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
 	};
 
 	/*
-	 * This is called when a numeric value should be set.
+	 * This is called when the output level of a unit should be set.
 	 */
-	this.setNumericValue = function(chain, unit, param, value) {
+	this.setOutputLevel = function(chain, unit, value) {
 
 		/*
 		 * This gets called when the server returns a response.
@@ -3218,7 +5709,7 @@ function Handler() {
 				 */
 				if (webResponse.Success !== true) {
 					const reason = webResponse.Reason;
-					const msg = 'Setting numeric value failed: ' + reason;
+					const msg = 'Setting output level failed: ' + reason;
 					console.log(msg);
 				}
 
@@ -3230,13 +5721,11 @@ function Handler() {
 		const mimeType = globals.mimeDefault;
 		const chainString = chain.toString();
 		const unitString = unit.toString();
-		const paramString = param.toString();
 		const valueString = value.toString();
 		const request = new Request();
-		request.append('cgi', 'set-numeric-value');
+		request.append('cgi', 'set-output-level');
 		request.append('chain', chainString);
 		request.append('unit', unitString);
-		request.append('param', paramString);
 		request.append('value', valueString);
 		const requestBody = request.getData();
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
@@ -3262,7 +5751,9 @@ function Handler() {
 				ui.renderLatency(configuration);
 				ui.renderTuner(configuration);
 				ui.renderSpatializer(configuration);
+				ui.renderMaster(configuration);
 				ui.renderMetronome(configuration);
+				ui.renderPlayer(configuration);
 				ui.renderSignalLevels(configuration);
 				ui.renderProcessing(configuration);
 			}
@@ -3278,21 +5769,21 @@ function Handler() {
 	};
 
 	/*
-	 * This is called when a new analysis should be performed by the tuner.
+	 * This is called when the status of the backing track player should be updated.
 	 */
-	this.refreshTuner = function() {
+	this.refreshPlayer = function(statusDiv) {
 
 		/*
 		 * This gets called when the server returns a response.
 		 */
 		const responseHandler = function(response) {
-			const analysis = helper.parseJSON(response);
+			const status = helper.parseJSON(response);
 
 			/*
 			 * Check if the response is valid JSON.
 			 */
-			if (analysis !== null) {
-				ui.updateTuner(analysis);
+			if (status !== null) {
+				ui.updatePlayer(statusDiv, status);
 			}
 
 		};
@@ -3300,21 +5791,145 @@ function Handler() {
 		const url = globals.cgi;
 		const mimeType = globals.mimeDefault;
 		const request = new Request();
-		request.append('cgi', 'get-tuner-analysis');
+		request.append('cgi', 'get-player-status');
 		const requestBody = request.getData();
-		ajax.request('POST', url, requestBody, mimeType, responseHandler, false);
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when a new analysis should be performed by the tuner.
+	 */
+	this.refreshTuner = function() {
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+
+		/*
+		 * Perform the high-resolution analysis for the strobe display or
+		 * the regular analysis.
+		 */
+		if (globals.tunerStrobe) {
+
+			/*
+			 * This gets called when the server returns a response.
+			 */
+			const strobeHandler = function(response) {
+				const result = helper.parseJSON(response);
+
+				/*
+				 * Check if the response is valid JSON.
+				 */
+				if (result !== null) {
+					ui.updateTuner(result);
+					ui.updateTunerStrobe(result);
+				}
+
+			};
+
+			const strobeRequest = new Request();
+			strobeRequest.append('cgi', 'get-tuner-strobe');
+			const strobeRequestBody = strobeRequest.getData();
+			ajax.request('POST', url, strobeRequestBody, mimeType, strobeHandler, false);
+		} else {
+
+			/*
+			 * This gets called when the server returns a response.
+			 */
+			const responseHandler = function(response) {
+				const analysis = helper.parseJSON(response);
+
+				/*
+				 * Check if the response is valid JSON.
+				 */
+				if (analysis !== null) {
+					ui.updateTuner(analysis);
+				}
+
+			};
+
+			const request = new Request();
+			request.append('cgi', 'get-tuner-analysis');
+			const requestBody = request.getData();
+			ajax.request('POST', url, requestBody, mimeType, responseHandler, false);
+
+			/*
+			 * Also analyze each string when the user wants to check all strings at once.
+			 */
+			if (globals.tunerStrings) {
+
+				/*
+				 * This gets called when the server returns a response.
+				 */
+				const stringsHandler = function(response) {
+					const results = helper.parseJSON(response);
+
+					/*
+					 * Check if the response is valid JSON.
+					 */
+					if (results !== null) {
+						ui.updateTunerStrings(results);
+					}
+
+				};
+
+				const stringsRequest = new Request();
+				stringsRequest.append('cgi', 'get-tuner-strings');
+				const stringsRequestBody = stringsRequest.getData();
+				ajax.request('POST', url, stringsRequestBody, mimeType, stringsHandler, false);
+			}
+
+		}
+
 	};
 
 	/*
 	 * This is called when the user clicks on the 'process' button.
+	 *
+	 * The site stays blocked while the files are selected on the server,
+	 * then the progress of the rendering is displayed.
 	 */
-	this.process = function() {
+	this.process = function(statusDiv) {
 
 		/*
 		 * This gets called when the server returns a response.
 		 */
 		const responseHandler = function(response) {
-			helper.blockSite(true);
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Starting batch processing failed: ' + reason;
+					console.log(msg);
+				} else {
+					helper.blockSite(true);
+					const timer = globals.processTimer;
+
+					/*
+					 * If a timer from a previous rendering is registered, clear it.
+					 */
+					if (timer !== null) {
+						window.clearInterval(timer);
+					}
+
+					/*
+					 * This gets executed whenever the timer ticks.
+					 */
+					const callback = function() {
+						handler.refreshProcessing(statusDiv);
+					};
+
+					globals.processTimer = window.setInterval(callback, 1000);
+				}
+
+			}
+
 		};
 
 		const url = globals.cgi;
@@ -3325,6 +5940,89 @@ function Handler() {
 		ajax.request('POST', url, requestBody, mimeType, responseHandler, false);
 	};
 
+	/*
+	 * This is called regularly to display the progress of the rendering.
+	 */
+	this.refreshProcessing = function(statusDiv) {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const status = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (status !== null) {
+				helper.blockSite(false);
+				ui.updateProcessing(statusDiv, status);
+
+				/*
+				 * Stop watching once the rendering is over.
+				 */
+				if (status.Running !== true) {
+					const timer = globals.processTimer;
+
+					/*
+					 * Check if a timer is registered.
+					 */
+					if (timer !== null) {
+						window.clearInterval(timer);
+						globals.processTimer = null;
+					}
+
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const request = new Request();
+		request.append('cgi', 'get-process-status');
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
+	/*
+	 * This is called when the user cancels the rendering.
+	 */
+	this.cancelProcess = function() {
+
+		/*
+		 * This gets called when the server returns a response.
+		 */
+		const responseHandler = function(response) {
+			const webResponse = helper.parseJSON(response);
+
+			/*
+			 * Check if the response is valid JSON.
+			 */
+			if (webResponse !== null) {
+
+				/*
+				 * If we were not successful, log failed attempt.
+				 */
+				if (webResponse.Success !== true) {
+					const reason = webResponse.Reason;
+					const msg = 'Cancelling batch processing failed: ' + reason;
+					console.log(msg);
+				}
+
+			}
+
+		};
+
+		const url = globals.cgi;
+		const mimeType = globals.mimeDefault;
+		const request = new Request();
+		request.append('cgi', 'cancel-process');
+		const requestBody = request.getData();
+		ajax.request('POST', url, requestBody, mimeType, responseHandler, true);
+	};
+
 	/*
 	 * This is used to prevent the default action from occuring.
 	 */
@@ -3389,6 +6087,137 @@ function Handler() {
 		return false;
 	};
 
+	/*
+	 * This is called when the user drops a wave file into the upload area of the player.
+	 */
+	this.uploadTrack = function(e) {
+		e.stopPropagation();
+		e.preventDefault();
+		const elem = e.target;
+		elem.classList.remove('dragover');
+		const transfer = e.dataTransfer;
+		const files = transfer.files;
+		const numFiles = files.length;
+
+		/*
+		 * Check if there is a file.
+		 */
+		if (numFiles > 0) {
+			const file = files[0];
+
+			/*
+			 * This gets called when the server returns a response.
+			 */
+			const responseHandler = function(response) {
+				const webResponse = helper.parseJSON(response);
+
+				/*
+				 * Check if the response is valid JSON.
+				 */
+				if (webResponse !== null) {
+
+					/*
+					 * If we were not successful, log failed attempt.
+					 */
+					if (webResponse.Success !== true) {
+						const reason = webResponse.Reason;
+						const msg = 'Loading backing track failed: ' + reason;
+						console.log(msg);
+					}
+
+				}
+
+			};
+
+			const url = globals.upload;
+			const data = new FormData();
+			data.append('cgi', 'load-player-track');
+			data.append('trackfile', file);
+			ajax.request('POST', url, data, null, responseHandler, true);
+		}
+
+		return false;
+	};
+
+	/*
+	 * This is called when the user drops a wave file into the preview area
+	 * of a channel.
+	 */
+	this.previewChannel = function(e) {
+		e.stopPropagation();
+		e.preventDefault();
+		const elem = e.target;
+		elem.classList.remove('dragover');
+		const chainId = storage.get(elem, 'chain');
+		const audioElem = storage.get(elem, 'audio');
+		const transfer = e.dataTransfer;
+		const files = transfer.files;
+		const numFiles = files.length;
+
+		/*
+		 * Check if there is a file.
+		 */
+		if (numFiles > 0) {
+			const file = files[0];
+
+			/*
+			 * This gets called when the server returns a response.
+			 */
+			const responseHandler = function(response) {
+				const mimeType = response.type;
+
+				/*
+				 * Play the rendered audio or log why it failed.
+				 */
+				if (mimeType === 'audio/wav') {
+					const oldUrl = audioElem.src;
+
+					/*
+					 * Release the previous preview.
+					 */
+					if (oldUrl !== '') {
+						URL.revokeObjectURL(oldUrl);
+					}
+
+					const url = URL.createObjectURL(response);
+					audioElem.src = url;
+					audioElem.play();
+				} else {
+
+					/*
+					 * This gets called when the response was read.
+					 */
+					const textHandler = function(text) {
+						const webResponse = helper.parseJSON(text);
+
+						/*
+						 * Check if the response is valid JSON.
+						 */
+						if (webResponse !== null) {
+							const reason = webResponse.Reason;
+							const msg = 'Previewing channel failed: ' + reason;
+							console.log(msg);
+						}
+
+					};
+
+					response.text().then(textHandler);
+				}
+
+			};
+
+			const chainIdString = chainId.toString();
+			const url = globals.upload;
+			const data = new FormData();
+			data.append('cgi', 'preview-channel');
+			data.append('chain', chainIdString);
+			data.append('clipfile', file);
+			ajax.requestBlob('POST', url, data, responseHandler, true);
+		}
+
+		return false;
+	};
+
 	/*
 	 * This is called when the user interface initializes.
 	 */
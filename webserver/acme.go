@@ -0,0 +1,145 @@
+package webserver
+
+import (
+	"crypto/tls"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+/*
+ * Constants for automatic certificate management.
+ */
+const (
+	DEFAULT_ACME_CACHE = "keys/acme"
+)
+
+/*
+ * Data structure providing the certificates of the TLS server.
+ *
+ * Certificates are obtained via ACME, if it is enabled, while the key pair
+ * from the configuration serves all other requests, like those addressing
+ * the server by IP address or by a name not listed in the configuration.
+ */
+type certificatesStruct struct {
+	manager     *autocert.Manager
+	fallback    *tls.Certificate
+	fallbackErr error
+}
+
+/*
+ * Returns the certificate to present to a client.
+ */
+func (this *certificatesStruct) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	manager := this.manager
+	err := this.fallbackErr
+
+	/*
+	 * Try to obtain a certificate via ACME first.
+	 */
+	if manager != nil {
+		certificate, errAcme := manager.GetCertificate(hello)
+
+		/*
+		 * Check if a certificate was obtained.
+		 */
+		if errAcme == nil {
+			return certificate, nil
+		} else if this.fallback == nil {
+			err = errAcme
+		}
+
+	}
+
+	/*
+	 * Fall back to the key pair from the configuration.
+	 */
+	if this.fallback == nil {
+		return nil, err
+	} else {
+		return this.fallback, nil
+	}
+
+}
+
+/*
+ * Loads the key pair from the configuration and sets up automatic
+ * certificate management, if it is enabled.
+ */
+func (this *webServerStruct) createCertificates() *certificatesStruct {
+	cfg := this.config
+	publicKey := cfg.TLSPublicKey
+	privateKey := cfg.TLSPrivateKey
+	fallback, err := tls.LoadX509KeyPair(publicKey, privateKey)
+
+	/*
+	 * Create certificates.
+	 */
+	certificates := certificatesStruct{
+		fallbackErr: err,
+	}
+
+	/*
+	 * Check if the key pair could be loaded.
+	 */
+	if err == nil {
+		certificates.fallback = &fallback
+	}
+
+	acmeCfg := cfg.Acme
+
+	/*
+	 * Check if automatic certificate management is enabled.
+	 */
+	if acmeCfg.Enabled {
+		cache := acmeCfg.Cache
+
+		/*
+		 * Use the default cache directory.
+		 */
+		if cache == "" {
+			cache = DEFAULT_ACME_CACHE
+		}
+
+		acceptTOS := acmeCfg.AcceptTOS
+
+		/*
+		 * Certificates are only issued once the terms of service are
+		 * accepted.
+		 */
+		prompt := func(tosURL string) bool {
+			return acceptTOS
+		}
+
+		domains := acmeCfg.Domains
+
+		/*
+		 * Create certificate manager.
+		 */
+		manager := autocert.Manager{
+			Cache:      autocert.DirCache(cache),
+			Email:      acmeCfg.Email,
+			HostPolicy: autocert.HostWhitelist(domains...),
+			Prompt:     prompt,
+		}
+
+		directory := acmeCfg.Directory
+
+		/*
+		 * Use another certificate authority than Let's Encrypt.
+		 */
+		if directory != "" {
+
+			/*
+			 * Create ACME client.
+			 */
+			manager.Client = &acme.Client{
+				DirectoryURL: directory,
+			}
+
+		}
+
+		certificates.manager = &manager
+	}
+
+	return &certificates
+}
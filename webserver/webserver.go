@@ -3,17 +3,25 @@ package webserver
 import (
 	"crypto/tls"
 	"fmt"
+	"golang.org/x/crypto/acme"
 	"io"
 	"log"
+	"math"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
+	HTTPS_PORT   = "443"
+	MAX_CLIENTS  = 1024
 	REQUEST_SIZE = 1 << 20
+	RETRY_AFTER  = "1"
+	UPLOAD_SIZE  = 1 << 28
 )
 
 /*
@@ -26,6 +34,7 @@ type HttpRequest struct {
 	Host     string
 	Params   map[string]string
 	Files    map[string][]multipart.File
+	Body     []byte
 	Respond  chan<- HttpResponse
 }
 
@@ -33,6 +42,7 @@ type HttpRequest struct {
  * Exchange format for HTTP responses.
  */
 type HttpResponse struct {
+	Status int
 	Header map[string]string
 	Body   []byte
 }
@@ -59,6 +69,44 @@ type Timeouts struct {
 	TLS  ProtocolTimeouts
 }
 
+/*
+ * Data structure representing limits on the requests to CGIs and APIs.
+ *
+ * The request size is given in bytes, the request rate in requests per second
+ * and client, the burst size in requests a client may issue at once before
+ * being limited to the request rate. The upload size in bytes applies to
+ * requests to CGIs registered for uploading files instead of the request
+ * size.
+ *
+ * A request or upload size of zero selects the default size. A request rate
+ * of zero disables rate limiting. A burst size of zero allows as many requests
+ * at once as the request rate allows per second.
+ */
+type Limits struct {
+	RequestSize  uint32
+	RequestRate  uint32
+	RequestBurst uint32
+	UploadSize   uint32
+}
+
+/*
+ * Data structure representing the configuration of automatic certificate
+ * management via ACME (e. g. Let's Encrypt).
+ *
+ * Certificates are obtained for the domains listed and stored in the cache
+ * directory. The directory URL selects the certificate authority, Let's
+ * Encrypt if it is empty. Certificates are only issued if the terms of
+ * service of the certificate authority are accepted.
+ */
+type Acme struct {
+	Enabled   bool
+	Domains   []string
+	Email     string
+	Cache     string
+	Directory string
+	AcceptTOS bool
+}
+
 /*
  * Data structure for web server configuration.
  */
@@ -75,21 +123,55 @@ type Config struct {
 	DefaultMime   string
 	ErrorMime     string
 	Timeouts      Timeouts
+	Limits        Limits
+	Acme          Acme
+}
+
+/*
+ * Data structure representing the requests a client may still issue, which
+ * are replenished at the request rate.
+ */
+type clientBucketStruct struct {
+	tokens  float64
+	updated time.Time
+}
+
+/*
+ * Data structure representing the request rate limits of all clients.
+ */
+type limiterStruct struct {
+	rate    uint32
+	burst   uint32
+	mutex   sync.Mutex
+	buckets map[string]*clientBucketStruct
+}
+
+/*
+ * Interface type for a rate limiter, which decides whether a client may
+ * issue another request.
+ */
+type Limiter interface {
+	Allow(client string) bool
 }
 
 /*
  * Data structure holding the web server's internal state.
  */
 type webServerStruct struct {
-	cgis   map[string]chan<- HttpRequest
-	config Config
+	apis    map[string]chan<- HttpRequest
+	cgis    map[string]chan<- HttpRequest
+	uploads map[string]bool
+	config  Config
+	limiter Limiter
 }
 
 /*
  * The public interface of the web server.
  */
 type WebServer interface {
+	RegisterApi(prefix string) <-chan HttpRequest
 	RegisterCgi(path string) <-chan HttpRequest
+	RegisterUploadCgi(path string) <-chan HttpRequest
 	GetCgis() []string
 	RemoveCgi(path string)
 	Run()
@@ -111,21 +193,276 @@ func (this *webServerStruct) setDefaultHeaders(writer http.ResponseWriter) {
 	hdr.Set("Pragma", "no-cache")
 }
 
+/*
+ * Returns the maximum size (in bytes) of a request.
+ */
+func (this *webServerStruct) requestSize() int64 {
+	cfg := this.config
+	limits := cfg.Limits
+	size := limits.RequestSize
+
+	/*
+	 * Check whether the default size should be used.
+	 */
+	if size == 0 {
+		return REQUEST_SIZE
+	} else {
+		size64 := int64(size)
+		return size64
+	}
+
+}
+
+/*
+ * Returns the maximum size (in bytes) of a request carrying files.
+ */
+func (this *webServerStruct) uploadSize() int64 {
+	cfg := this.config
+	limits := cfg.Limits
+	size := limits.UploadSize
+
+	/*
+	 * Check whether the default size should be used.
+	 */
+	if size == 0 {
+		return UPLOAD_SIZE
+	} else {
+		size64 := int64(size)
+		return size64
+	}
+
+}
+
+/*
+ * Returns the maximum size (in bytes) of a CGI request, which is larger for
+ * requests to CGIs registered for uploading files.
+ *
+ * The limit only depends on the path of the CGI, never on what the client
+ * claims to send.
+ */
+func (this *webServerStruct) cgiRequestSize(request *http.Request) int64 {
+	url := request.URL
+	path := url.Path
+	isUpload := this.uploads[path]
+
+	/*
+	 * Check if the CGI accepts uploads.
+	 */
+	if isUpload {
+		return this.uploadSize()
+	} else {
+		return this.requestSize()
+	}
+
+}
+
 /*
  * Limits the size of an incoming request.
  */
-func (this *webServerStruct) limitRequestSize(writer http.ResponseWriter, request *http.Request) {
+func (this *webServerStruct) limitRequestSize(writer http.ResponseWriter, request *http.Request, size int64) {
 	requestBody := request.Body
-	limitedBody := http.MaxBytesReader(writer, requestBody, REQUEST_SIZE)
+	limitedBody := http.MaxBytesReader(writer, requestBody, size)
 	request.Body = limitedBody
 }
 
 /*
- * A handler for CGI requests.
+ * Refills the bucket of a client at a certain rate (in requests per second),
+ * up to a certain burst size.
+ */
+func (this *clientBucketStruct) refill(now time.Time, rate float64, burst float64) {
+	updated := this.updated
+	elapsed := now.Sub(updated)
+	elapsedSeconds := elapsed.Seconds()
+	tokens := this.tokens + (elapsedSeconds * rate)
+	this.tokens = math.Min(tokens, burst)
+	this.updated = now
+}
+
+/*
+ * Removes the buckets of all clients which have not issued requests for long
+ * enough that their buckets are full again.
+ *
+ * The caller must hold the mutex.
+ */
+func (this *limiterStruct) pruneBuckets(now time.Time, rate float64, burst float64) {
+	buckets := this.buckets
+
+	/*
+	 * Iterate over the buckets of all clients.
+	 */
+	for client, bucket := range buckets {
+		bucket.refill(now, rate, burst)
+
+		/*
+		 * Check if the bucket is full.
+		 */
+		if bucket.tokens >= burst {
+			delete(buckets, client)
+		}
+
+	}
+
+}
+
+/*
+ * Checks whether a client may issue another request and, if so, takes one
+ * from the requests it may still issue.
+ */
+func (this *limiterStruct) Allow(client string) bool {
+	rate := this.rate
+
+	/*
+	 * Check whether rate limiting is enabled.
+	 */
+	if rate == 0 {
+		return true
+	} else {
+		burst := this.burst
+
+		/*
+		 * By default, allow one second worth of requests at once.
+		 */
+		if burst == 0 {
+			burst = rate
+		}
+
+		rateFloat := float64(rate)
+		burstFloat := float64(burst)
+		now := time.Now()
+		this.mutex.Lock()
+		buckets := this.buckets
+
+		/*
+		 * If no bucket map exists, create one.
+		 */
+		if buckets == nil {
+			buckets = make(map[string]*clientBucketStruct)
+			this.buckets = buckets
+		}
+
+		bucket, present := buckets[client]
+
+		/*
+		 * A new client starts with a full bucket.
+		 */
+		if !present {
+
+			/*
+			 * Make room by forgetting about idle clients.
+			 */
+			if len(buckets) >= MAX_CLIENTS {
+				this.pruneBuckets(now, rateFloat, burstFloat)
+			}
+
+			/*
+			 * Create bucket for the client.
+			 */
+			bucket = &clientBucketStruct{
+				tokens:  burstFloat,
+				updated: now,
+			}
+
+			buckets[client] = bucket
+		}
+
+		bucket.refill(now, rateFloat, burstFloat)
+		allowed := bucket.tokens >= 1.0
+
+		/*
+		 * Take a request from the bucket.
+		 */
+		if allowed {
+			bucket.tokens -= 1.0
+		}
+
+		this.mutex.Unlock()
+		return allowed
+	}
+
+}
+
+/*
+ * Checks whether the client issuing a request may issue another one.
+ */
+func (this *webServerStruct) allowRequest(request *http.Request) bool {
+	remoteAddr := request.RemoteAddr
+	client, _, err := net.SplitHostPort(remoteAddr)
+
+	/*
+	 * If the address carries no port, take it as is.
+	 */
+	if err != nil {
+		client = remoteAddr
+	}
+
+	limiter := this.limiter
+	allowed := limiter.Allow(client)
+	return allowed
+}
+
+/*
+ * Checks an incoming request against the limits and returns the status code
+ * to reject it with, or http.StatusOK if it may be processed.
+ */
+func (this *webServerStruct) checkLimits(request *http.Request, size int64) int {
+	contentLength := request.ContentLength
+
+	/*
+	 * Check if request is too large or the client issues too many.
+	 */
+	if contentLength > size {
+		return http.StatusRequestEntityTooLarge
+	} else if !this.allowRequest(request) {
+		return http.StatusTooManyRequests
+	} else {
+		return http.StatusOK
+	}
+
+}
+
+/*
+ * Rejects a request with a certain status code.
+ */
+func (this *webServerStruct) reject(writer http.ResponseWriter, status int) {
+	this.setDefaultHeaders(writer)
+
+	/*
+	 * Tell rate-limited clients when to try again.
+	 */
+	if status == http.StatusTooManyRequests {
+		hdr := writer.Header()
+		hdr.Set("Retry-After", RETRY_AFTER)
+	}
+
+	writer.WriteHeader(status)
+}
+
+/*
+ * A handler for CGI requests, which rejects requests exceeding the limits.
  */
 func (this *webServerStruct) cgiHandler(writer http.ResponseWriter, request *http.Request) {
-	this.limitRequestSize(writer, request)
-	request.ParseMultipartForm(REQUEST_SIZE)
+	size := this.cgiRequestSize(request)
+	status := this.checkLimits(request, size)
+
+	/*
+	 * Check if request may be processed.
+	 */
+	if status != http.StatusOK {
+		this.reject(writer, status)
+	} else {
+		this.handleCgi(writer, request)
+	}
+
+}
+
+/*
+ * Processes a CGI request.
+ */
+func (this *webServerStruct) handleCgi(writer http.ResponseWriter, request *http.Request) {
+	size := this.cgiRequestSize(request)
+	this.limitRequestSize(writer, request, size)
+	memorySize := this.requestSize()
+	request.ParseMultipartForm(memorySize)
 	protocol := request.Proto
 	method := request.Method
 	url := request.URL
@@ -222,6 +559,114 @@ func (this *webServerStruct) cgiHandler(writer http.ResponseWriter, request *htt
 	cgi := cgis[path]
 	cgi <- hrequest
 	response := <-responseChannel
+	this.writeResponse(writer, response)
+}
+
+/*
+ * A handler for API requests, which rejects requests exceeding the limits.
+ */
+func (this *webServerStruct) apiHandler(writer http.ResponseWriter, request *http.Request) {
+	size := this.requestSize()
+	status := this.checkLimits(request, size)
+
+	/*
+	 * Check if request may be processed.
+	 */
+	if status != http.StatusOK {
+		this.reject(writer, status)
+	} else {
+		this.handleApi(writer, request)
+	}
+
+}
+
+/*
+ * Processes an API request.
+ *
+ * Unlike CGI requests, API requests carry their parameters in the request
+ * body, which is passed on unparsed. Only the query string is parsed into
+ * parameters.
+ */
+func (this *webServerStruct) handleApi(writer http.ResponseWriter, request *http.Request) {
+	size := this.requestSize()
+	this.limitRequestSize(writer, request, size)
+	protocol := request.Proto
+	method := request.Method
+	url := request.URL
+	path := url.Path
+	host := request.Host
+	query := url.Query()
+	params := make(map[string]string)
+
+	/*
+	 * Iterate over all query values and parse parameters.
+	 */
+	for key, values := range query {
+		ps := strings.Join(values, ",")
+		params[key] = ps
+	}
+
+	requestBody := request.Body
+	body, err := io.ReadAll(requestBody)
+	apis := this.apis
+	api := chan<- HttpRequest(nil)
+	apiPrefix := ""
+
+	/*
+	 * Find the API with the longest prefix matching the path.
+	 */
+	for prefix, currentApi := range apis {
+
+		/*
+		 * Check if this API matches better than the current one.
+		 */
+		if strings.HasPrefix(path, prefix) && (len(prefix) > len(apiPrefix)) {
+			api = currentApi
+			apiPrefix = prefix
+		}
+
+	}
+
+	/*
+	 * Check if request body could be read and an API was found.
+	 */
+	if err != nil {
+		this.setDefaultHeaders(writer)
+		writer.WriteHeader(http.StatusRequestEntityTooLarge)
+	} else if api == nil {
+		this.setDefaultHeaders(writer)
+		writer.WriteHeader(http.StatusNotFound)
+	} else {
+		responseChannel := make(chan HttpResponse)
+
+		/*
+		 * The parsed HTTP request.
+		 */
+		hrequest := HttpRequest{
+			Protocol: protocol,
+			Method:   method,
+			Path:     path,
+			Host:     host,
+			Params:   params,
+			Files:    nil,
+			Body:     body,
+			Respond:  responseChannel,
+		}
+
+		/*
+		 * Interact with the API via channels to send request, fetch response.
+		 */
+		api <- hrequest
+		response := <-responseChannel
+		this.writeResponse(writer, response)
+	}
+
+}
+
+/*
+ * Writes the headers, status code and body of a response.
+ */
+func (this *webServerStruct) writeResponse(writer http.ResponseWriter, response HttpResponse) {
 	this.setDefaultHeaders(writer)
 	hdr := writer.Header()
 
@@ -232,6 +677,16 @@ func (this *webServerStruct) cgiHandler(writer http.ResponseWriter, request *htt
 		hdr.Set(key, value)
 	}
 
+	status := response.Status
+
+	/*
+	 * Only write a status code if one was set, otherwise it defaults to
+	 * 200 OK.
+	 */
+	if status != 0 {
+		writer.WriteHeader(status)
+	}
+
 	body := response.Body
 	writer.Write(body)
 }
@@ -317,9 +772,40 @@ func (this *webServerStruct) redirect(writer http.ResponseWriter, request *http.
 	cfg := this.config
 	tlsPort := cfg.TLSPort
 	url := fmt.Sprintf("https://%s:%s%s", host, tlsPort, uri)
+
+	/*
+	 * Leave out the default port, so that certificates obtained via ACME
+	 * match the URL.
+	 */
+	if tlsPort == HTTPS_PORT {
+		url = fmt.Sprintf("https://%s%s", host, uri)
+	}
+
 	http.Redirect(writer, request, url, http.StatusFound)
 }
 
+/*
+ * Registers an API with the web server. The 'prefix' given specifies the URL
+ * prefix under which the API is available and should end with a slash. When
+ * the API is called, the web server generates a WebRequest and puts it into
+ * the request queue.
+ */
+func (this *webServerStruct) RegisterApi(prefix string) <-chan HttpRequest {
+	requests := make(chan HttpRequest)
+	apis := this.apis
+
+	/*
+	 * If no API map exists, create one.
+	 */
+	if apis == nil {
+		apis = make(map[string]chan<- HttpRequest)
+		this.apis = apis
+	}
+
+	apis[prefix] = requests
+	return requests
+}
+
 /*
  * Registers a CGI with the web server. The 'path' given specifies the URL
  * under which the CGI is available. When the CGI is called, the web server
@@ -341,6 +827,27 @@ func (this *webServerStruct) RegisterCgi(path string) <-chan HttpRequest {
 	return requests
 }
 
+/*
+ * Registers a CGI for uploading files with the web server. Requests to this
+ * CGI may be up to the upload size instead of the request size, but it is
+ * otherwise handled like any other CGI.
+ */
+func (this *webServerStruct) RegisterUploadCgi(path string) <-chan HttpRequest {
+	requests := this.RegisterCgi(path)
+	uploads := this.uploads
+
+	/*
+	 * If no upload map exists, create one.
+	 */
+	if uploads == nil {
+		uploads = make(map[string]bool)
+		this.uploads = uploads
+	}
+
+	uploads[path] = true
+	return requests
+}
+
 /*
  * Returns a list of the URLs of all currently registered CGIs.
  */
@@ -364,6 +871,8 @@ func (this *webServerStruct) GetCgis() []string {
 func (this *webServerStruct) RemoveCgi(path string) {
 	cgis := this.cgis
 	delete(cgis, path)
+	uploads := this.uploads
+	delete(uploads, path)
 }
 
 /*
@@ -375,7 +884,9 @@ func (this *webServerStruct) Run() {
 	httpMux := http.NewServeMux()
 	cfg := this.config
 	tlsDisabled := cfg.TLSDisabled
+	apis := this.apis
 	cgis := this.cgis
+	apiHandler := this.apiHandler
 	cgiHandler := this.cgiHandler
 	fileHandler := this.fileHandler
 
@@ -392,12 +903,31 @@ func (this *webServerStruct) Run() {
 			httpMux.HandleFunc(path, cgiHandler)
 		}
 
+		/*
+		 * Register all API prefixes to HTTP server.
+		 */
+		for prefix, _ := range apis {
+			httpMux.HandleFunc(prefix, apiHandler)
+		}
+
 		httpMux.HandleFunc("/", fileHandler)
 	} else {
 		redirectHandler := this.redirect
 		httpMux.HandleFunc("/", redirectHandler)
 	}
 
+	certificates := this.createCertificates()
+	manager := certificates.manager
+	httpHandler := http.Handler(httpMux)
+
+	/*
+	 * Answer the challenges of the certificate authority on the HTTP
+	 * server.
+	 */
+	if !tlsDisabled && (manager != nil) {
+		httpHandler = manager.HTTPHandler(httpMux)
+	}
+
 	discard := io.Discard
 	logger := log.New(discard, "", log.LstdFlags)
 	httpPort := cfg.Port
@@ -423,7 +953,7 @@ func (this *webServerStruct) Run() {
 	httpServer := http.Server{
 		Addr:              httpAddr,
 		ErrorLog:          logger,
-		Handler:           httpMux,
+		Handler:           httpHandler,
 		IdleTimeout:       httpTimeoutIdle,
 		ReadHeaderTimeout: httpTimeoutHeader,
 		ReadTimeout:       httpTimeoutRead,
@@ -445,6 +975,13 @@ func (this *webServerStruct) Run() {
 			tlsMux.HandleFunc(path, cgiHandler)
 		}
 
+		/*
+		 * Register all API prefixes to TLS server.
+		 */
+		for prefix, _ := range apis {
+			tlsMux.HandleFunc(prefix, apiHandler)
+		}
+
 		tlsMux.HandleFunc("/", fileHandler)
 		tlsPort := cfg.TLSPort
 		tlsAddr := fmt.Sprintf(":%s", tlsPort)
@@ -477,10 +1014,19 @@ func (this *webServerStruct) Run() {
 		tlsConfig := tls.Config{
 			CipherSuites:             ciphersuites,
 			CurvePreferences:         curves,
+			GetCertificate:           certificates.getCertificate,
 			MinVersion:               tls.VersionTLS12,
 			PreferServerCipherSuites: true,
 		}
 
+		/*
+		 * Answer the challenges of the certificate authority during the
+		 * TLS handshake.
+		 */
+		if manager != nil {
+			tlsConfig.NextProtos = []string{"h2", "http/1.1", acme.ALPNProto}
+		}
+
 		tlsTimeouts := timeouts.TLS
 		tlsTimeoutHeaderSec := tlsTimeouts.Header
 		tlsTimeoutHeaderDur := time.Duration(tlsTimeoutHeaderSec)
@@ -509,9 +1055,7 @@ func (this *webServerStruct) Run() {
 			WriteTimeout:      tlsTimeoutWrite,
 		}
 
-		publicKey := cfg.TLSPublicKey
-		privateKey := cfg.TLSPrivateKey
-		go tlsServer.ListenAndServeTLS(publicKey, privateKey)
+		go tlsServer.ListenAndServeTLS("", "")
 	}
 
 }
@@ -520,6 +1064,33 @@ func (this *webServerStruct) Run() {
  * Creates a new web server.
  */
 func CreateWebServer(cfg Config) WebServer {
-	server := webServerStruct{config: cfg}
+	limits := cfg.Limits
+	limiter := CreateLimiter(limits)
+
+	/*
+	 * Create web server.
+	 */
+	server := webServerStruct{
+		config:  cfg,
+		limiter: limiter,
+	}
+
 	return &server
 }
+
+/*
+ * Creates a rate limiter, which allows each client the request rate and burst
+ * size given in the limits.
+ */
+func CreateLimiter(limits Limits) Limiter {
+
+	/*
+	 * Create rate limiter.
+	 */
+	limiter := limiterStruct{
+		rate:  limits.RequestRate,
+		burst: limits.RequestBurst,
+	}
+
+	return &limiter
+}
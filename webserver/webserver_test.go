@@ -0,0 +1,309 @@
+package webserver
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+/*
+ * Verify that the size limit of a CGI request depends on the CGI it is sent
+ * to and not on the content type the client claims.
+ */
+func TestCgiRequestSize(t *testing.T) {
+
+	/*
+	 * Limits to test with.
+	 */
+	limits := Limits{
+		RequestSize: 1024,
+		UploadSize:  4096,
+	}
+
+	/*
+	 * Web server configuration.
+	 */
+	cfg := Config{
+		Limits: limits,
+	}
+
+	server := &webServerStruct{
+		config:  cfg,
+		limiter: CreateLimiter(limits),
+	}
+
+	server.RegisterCgi("/cgi-bin/dsp")
+	server.RegisterUploadCgi("/cgi-bin/dsp-upload")
+
+	/*
+	 * Data structure describing a test case.
+	 */
+	type testStruct struct {
+		path        string
+		contentType string
+		size        int64
+	}
+
+	/*
+	 * Test cases.
+	 */
+	tests := []testStruct{
+		testStruct{
+			path:        "/cgi-bin/dsp",
+			contentType: "application/x-www-form-urlencoded",
+			size:        1024,
+		},
+		testStruct{
+			path:        "/cgi-bin/dsp",
+			contentType: "multipart/form-data; boundary=x",
+			size:        1024,
+		},
+		testStruct{
+			path:        "/cgi-bin/dsp-upload",
+			contentType: "multipart/form-data; boundary=x",
+			size:        4096,
+		},
+	}
+
+	/*
+	 * Run each test case.
+	 */
+	for _, test := range tests {
+		request := httptest.NewRequest("POST", test.path, nil)
+		request.Header.Set("Content-Type", test.contentType)
+		size := server.cgiRequestSize(request)
+
+		/*
+		 * Verify the size limit.
+		 */
+		if size != test.size {
+			t.Errorf("Size limit for '%s' with content type '%s' incorrect. Expected: %d Got: %d", test.path, test.contentType, test.size, size)
+		}
+
+	}
+
+	server.RemoveCgi("/cgi-bin/dsp-upload")
+	request := httptest.NewRequest("POST", "/cgi-bin/dsp-upload", nil)
+	size := server.cgiRequestSize(request)
+
+	/*
+	 * Verify that removed CGIs no longer accept uploads.
+	 */
+	if size != 1024 {
+		t.Errorf("Size limit for removed upload CGI incorrect. Expected: %d Got: %d", 1024, size)
+	}
+
+}
+
+/*
+ * Verify that a large multipart body sent to a CGI which is not registered for
+ * uploads is rejected.
+ */
+func TestCgiRejectsLargeMultipart(t *testing.T) {
+
+	/*
+	 * Limits to test with.
+	 */
+	limits := Limits{
+		RequestSize: 1024,
+		UploadSize:  1 << 20,
+	}
+
+	/*
+	 * Web server configuration.
+	 */
+	cfg := Config{
+		Limits: limits,
+	}
+
+	server := &webServerStruct{
+		config:  cfg,
+		limiter: CreateLimiter(limits),
+	}
+
+	server.RegisterCgi("/cgi-bin/dsp")
+	server.RegisterUploadCgi("/cgi-bin/dsp-upload")
+	body := strings.Repeat("x", 4096)
+	reader := strings.NewReader(body)
+	request := httptest.NewRequest("POST", "/cgi-bin/dsp", reader)
+	request.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	recorder := httptest.NewRecorder()
+	server.cgiHandler(recorder, request)
+	status := recorder.Code
+
+	/*
+	 * The request must be rejected as too large.
+	 */
+	if status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Large multipart request to CGI not registered for uploads returned status %d. Expected: %d", status, http.StatusRequestEntityTooLarge)
+	}
+
+}
+
+/*
+ * Verify that a rate limiter allows each client the burst size at once and
+ * limits clients independently.
+ */
+func TestLimiter(t *testing.T) {
+
+	/*
+	 * Allow two requests at once and one per second.
+	 */
+	limits := Limits{
+		RequestRate:  1,
+		RequestBurst: 2,
+	}
+
+	limiter := CreateLimiter(limits)
+
+	/*
+	 * Requests issued by the clients and whether they are allowed.
+	 */
+	clients := []string{"a", "a", "a", "b"}
+	expected := []bool{true, true, false, true}
+
+	/*
+	 * Issue each request.
+	 */
+	for i, client := range clients {
+		allowed := limiter.Allow(client)
+
+		/*
+		 * Check if the request was handled as expected.
+		 */
+		if allowed != expected[i] {
+			t.Errorf("Request %d of client '%s' should be allowed: %t, but is allowed: %t", i, client, expected[i], allowed)
+		}
+
+	}
+
+	unlimited := CreateLimiter(Limits{})
+
+	/*
+	 * Without a request rate, all requests are allowed.
+	 */
+	for i := 0; i < 100; i++ {
+
+		/*
+		 * Check if the request was allowed.
+		 */
+		if !unlimited.Allow("a") {
+			t.Errorf("Request %d should be allowed without rate limiting, but is not.", i)
+			break
+		}
+
+	}
+
+}
+
+/*
+ * Verify that clients not addressing one of the domains configured for ACME
+ * get the key pair from the configuration, while the certificate authority is
+ * not contacted.
+ */
+func TestCertificateFallback(t *testing.T) {
+	cache := t.TempDir()
+
+	/*
+	 * ACME configuration.
+	 */
+	acmeCfg := Acme{
+		Enabled:   true,
+		Domains:   []string{"dsp.example.org"},
+		Cache:     cache,
+		AcceptTOS: true,
+	}
+
+	/*
+	 * Web server configuration without a key pair.
+	 */
+	cfg := Config{
+		TLSPrivateKey: "nonexistent/private.pem",
+		TLSPublicKey:  "nonexistent/public.pem",
+		Acme:          acmeCfg,
+	}
+
+	server := &webServerStruct{
+		config: cfg,
+	}
+
+	certificates := server.createCertificates()
+
+	/*
+	 * A client addressing another name.
+	 */
+	hello := &tls.ClientHelloInfo{
+		ServerName: "other.example.org",
+	}
+
+	_, err := certificates.getCertificate(hello)
+
+	/*
+	 * Without a key pair, there is no certificate to fall back to.
+	 */
+	if err == nil {
+		t.Errorf("%s", "Certificate for unknown name returned without a key pair to fall back to.")
+	}
+
+	fallback := &tls.Certificate{}
+	certificates.fallback = fallback
+	certificate, err := certificates.getCertificate(hello)
+
+	/*
+	 * With a key pair, it is used for names not configured.
+	 */
+	if err != nil {
+		msg := err.Error()
+		t.Errorf("Failed to fall back to key pair: %s", msg)
+	} else if certificate != fallback {
+		t.Errorf("%s", "Certificate for unknown name is not the key pair from the configuration.")
+	}
+
+}
+
+/*
+ * Verify that insecure requests are redirected to the TLS port, which is left
+ * out of the URL if it is the default port.
+ */
+func TestRedirect(t *testing.T) {
+
+	/*
+	 * Ports to redirect to and the resulting locations.
+	 */
+	ports := []string{"8443", "443"}
+	expected := []string{"https://dsp.example.org:8443/index.xhtml", "https://dsp.example.org/index.xhtml"}
+
+	/*
+	 * Redirect a request to each port.
+	 */
+	for i, port := range ports {
+
+		/*
+		 * Web server configuration.
+		 */
+		cfg := Config{
+			TLSPort: port,
+		}
+
+		server := &webServerStruct{
+			config: cfg,
+		}
+
+		request := httptest.NewRequest("GET", "/index.xhtml", nil)
+		request.Host = "dsp.example.org:8080"
+		recorder := httptest.NewRecorder()
+		server.redirect(recorder, request)
+		location := recorder.Header().Get("Location")
+
+		/*
+		 * Check the location redirected to.
+		 */
+		if location != expected[i] {
+			t.Errorf("Request redirected to '%s'. Expected: '%s'", location, expected[i])
+		}
+
+	}
+
+}